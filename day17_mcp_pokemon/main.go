@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"strings"
 	"sync"
@@ -12,6 +13,11 @@ import (
 )
 
 func main() {
+	transport := flag.String("transport", transportStdio, "transport to serve MCP (stdio or sse)")
+	addr := flag.String("addr", ":8080", "listen address for the sse transport")
+	baseURL := flag.String("base-url", "", "public base URL of the sse server (e.g. http://example.com:8080)")
+	flag.Parse()
+
 	// Create a new MCP server
 	s := server.NewMCPServer(
 		"Pokemon Demo",
@@ -29,6 +35,7 @@ func main() {
 	}
 
 	pokemons := []string{}
+	jaToEn := map[string]string{} // 日本語名 -> PokeAPI の英語名 (追加ツールで日本語名を受け付けるため)
 	mu := sync.Mutex{}
 	wg := &sync.WaitGroup{}
 
@@ -52,6 +59,7 @@ func main() {
 			}
 			mu.Lock()
 			pokemons = append(pokemons, jaName)
+			jaToEn[jaName] = pokemonName
 			mu.Unlock()
 			wg.Done()
 		}(pokemon.Name)
@@ -81,8 +89,11 @@ func main() {
 		return mcp.NewToolResultText(strings.Join(result, "\n")), nil
 	})
 
+	// タイプ・進化・わざは PokeAPI に都度問い合わせる (151 匹目以降も対象)
+	addPokeAPITools(s, jaToEn)
+
 	// Start the server
-	if err := server.ServeStdio(s); err != nil {
+	if err := serve(s, *transport, *addr, *baseURL); err != nil {
 		fmt.Printf("Server error: %v\n", err)
 	}
 }
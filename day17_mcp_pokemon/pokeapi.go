@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const pokeAPIBaseURL = "https://pokeapi.co/api/v2/"
+
+// pokeAPI は PokeAPI を都度問い合わせるクライアント (同じ URL のレスポンスはキャッシュする)
+type pokeAPI struct {
+	httpClient *http.Client
+	cache      sync.Map // URL -> []byte
+}
+
+func newPokeAPI() *pokeAPI {
+	return &pokeAPI{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type namedResource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type localizedName struct {
+	Name     string        `json:"name"`
+	Language namedResource `json:"language"`
+}
+
+type speciesResponse struct {
+	Name           string          `json:"name"`
+	Names          []localizedName `json:"names"`
+	EvolutionChain struct {
+		URL string `json:"url"`
+	} `json:"evolution_chain"`
+	Varieties []struct {
+		IsDefault bool          `json:"is_default"`
+		Pokemon   namedResource `json:"pokemon"`
+	} `json:"varieties"`
+}
+
+type pokemonResponse struct {
+	Name  string `json:"name"`
+	Types []struct {
+		Slot int           `json:"slot"`
+		Type namedResource `json:"type"`
+	} `json:"types"`
+	Moves []struct {
+		Move                namedResource `json:"move"`
+		VersionGroupDetails []struct {
+			LevelLearnedAt  int           `json:"level_learned_at"`
+			MoveLearnMethod namedResource `json:"move_learn_method"`
+			VersionGroup    namedResource `json:"version_group"`
+		} `json:"version_group_details"`
+	} `json:"moves"`
+}
+
+type typeResponse struct {
+	Name            string `json:"name"`
+	DamageRelations struct {
+		DoubleDamageFrom []namedResource `json:"double_damage_from"`
+		DoubleDamageTo   []namedResource `json:"double_damage_to"`
+		HalfDamageFrom   []namedResource `json:"half_damage_from"`
+		HalfDamageTo     []namedResource `json:"half_damage_to"`
+		NoDamageFrom     []namedResource `json:"no_damage_from"`
+		NoDamageTo       []namedResource `json:"no_damage_to"`
+	} `json:"damage_relations"`
+}
+
+type evolutionChainResponse struct {
+	Chain chainLink `json:"chain"`
+}
+
+type chainLink struct {
+	Species          namedResource `json:"species"`
+	EvolutionDetails []struct {
+		Trigger      namedResource  `json:"trigger"`
+		MinLevel     *int           `json:"min_level"`
+		Item         *namedResource `json:"item"`
+		HeldItem     *namedResource `json:"held_item"`
+		MinHappiness *int           `json:"min_happiness"`
+		TimeOfDay    string         `json:"time_of_day"`
+		KnownMove    *namedResource `json:"known_move"`
+	} `json:"evolution_details"`
+	EvolvesTo []chainLink `json:"evolves_to"`
+}
+
+type moveResponse struct {
+	Name        string          `json:"name"`
+	Names       []localizedName `json:"names"`
+	Type        namedResource   `json:"type"`
+	DamageClass namedResource   `json:"damage_class"`
+	Power       *int            `json:"power"`
+	Accuracy    *int            `json:"accuracy"`
+	PP          *int            `json:"pp"`
+}
+
+// get は PokeAPI からデータを取得する。path は "pokemon/pikachu" のような相対パスか完全な URL
+func (p *pokeAPI) get(ctx context.Context, path string, v any) error {
+	url := path
+	if !strings.HasPrefix(path, "http") {
+		url = pokeAPIBaseURL + path
+	}
+
+	if body, ok := p.cache.Load(url); ok {
+		return json.Unmarshal(body.([]byte), v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("not found: %s", path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+	p.cache.Store(url, []byte(body))
+	return json.Unmarshal(body, v)
+}
+
+func (p *pokeAPI) species(ctx context.Context, name string) (*speciesResponse, error) {
+	var species speciesResponse
+	if err := p.get(ctx, "pokemon-species/"+name, &species); err != nil {
+		return nil, err
+	}
+	return &species, nil
+}
+
+// pokemon はポケモン (種族のデフォルトの姿) を取得する
+func (p *pokeAPI) pokemon(ctx context.Context, name string) (*pokemonResponse, error) {
+	species, err := p.species(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// デオキシスなどは種族名と姿の名前が異なるため、デフォルトの姿を引く
+	pokemonName := species.Name
+	for _, v := range species.Varieties {
+		if v.IsDefault {
+			pokemonName = v.Pokemon.Name
+			break
+		}
+	}
+
+	var pokemon pokemonResponse
+	if err := p.get(ctx, "pokemon/"+pokemonName, &pokemon); err != nil {
+		return nil, err
+	}
+	return &pokemon, nil
+}
+
+// parallel は fn を最大 10 並列で実行し、最初に発生したエラーを返す
+func parallel(n int, fn func(i int) error) error {
+	sem := make(chan struct{}, 10)
+	errs := make([]error, n)
+	wg := &sync.WaitGroup{}
+
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			errs[i] = fn(i)
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// japaneseName は names から日本語名を探す。見つからない場合は fallback を返す
+func japaneseName(names []localizedName, fallback string) string {
+	for _, lang := range []string{"ja-Hrkt", "ja"} {
+		for _, n := range names {
+			if n.Language.Name == lang {
+				return n.Name
+			}
+		}
+	}
+	return fallback
+}
+
+// typeNames はタイプの英語名と日本語名の対応
+var typeNames = map[string]string{
+	"normal":   "ノーマル",
+	"fire":     "ほのお",
+	"water":    "みず",
+	"electric": "でんき",
+	"grass":    "くさ",
+	"ice":      "こおり",
+	"fighting": "かくとう",
+	"poison":   "どく",
+	"ground":   "じめん",
+	"flying":   "ひこう",
+	"psychic":  "エスパー",
+	"bug":      "むし",
+	"rock":     "いわ",
+	"ghost":    "ゴースト",
+	"dragon":   "ドラゴン",
+	"dark":     "あく",
+	"steel":    "はがね",
+	"fairy":    "フェアリー",
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// name は日本語名と英語名の組
+type name struct {
+	Ja string `json:"ja"`
+	En string `json:"en"`
+}
+
+func typeName(en string) name {
+	ja, ok := typeNames[en]
+	if !ok {
+		ja = en
+	}
+	return name{Ja: ja, En: en}
+}
+
+func typeNameList(resources []namedResource) []name {
+	names := make([]name, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, typeName(r.Name))
+	}
+	return names
+}
+
+// pokeAPITools は PokeAPI を都度問い合わせる MCP ツール群
+type pokeAPITools struct {
+	api *pokeAPI
+	// jaToEn は事前取得したポケモンの日本語名から英語名への対応
+	jaToEn map[string]string
+}
+
+// addPokeAPITools はタイプ・進化・わざのツールをサーバーに登録する
+func addPokeAPITools(s *server.MCPServer, jaToEn map[string]string) {
+	t := &pokeAPITools{api: newPokeAPI(), jaToEn: jaToEn}
+
+	s.AddTool(mcp.NewTool("pokemon_type",
+		mcp.WithDescription("return type effectiveness (damage relations) of a type, or of each type of a Pokémon. Names are returned in Japanese and English"),
+		mcp.WithString("type",
+			mcp.Description("Type name in Japanese or English (e.g. ほのお, fire)"),
+		),
+		mcp.WithString("pokemon",
+			mcp.Description("Pokémon name in Japanese or English. Used when type is omitted"),
+		),
+	), t.handleType)
+
+	s.AddTool(mcp.NewTool("evolution_chain",
+		mcp.WithDescription("return the evolution chain of a Pokémon with evolution conditions"),
+		mcp.WithString("pokemon",
+			mcp.Required(),
+			mcp.Description("Pokémon name in Japanese or English, or Pokédex number"),
+		),
+	), t.handleEvolutionChain)
+
+	s.AddTool(mcp.NewTool("pokemon_moves",
+		mcp.WithDescription("return moves a Pokémon can learn with type, power and accuracy"),
+		mcp.WithString("pokemon",
+			mcp.Required(),
+			mcp.Description("Pokémon name in Japanese or English, or Pokédex number"),
+		),
+		mcp.WithString("method",
+			mcp.Description("How the move is learned"),
+			mcp.Enum("level-up", "machine", "egg", "tutor"),
+			mcp.DefaultString("level-up"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of moves to return"),
+			mcp.DefaultNumber(30),
+			mcp.Min(1),
+			mcp.Max(200),
+		),
+	), t.handleMoves)
+}
+
+// resolvePokemon は日本語名を PokeAPI で使う英語名に変換する
+func (t *pokeAPITools) resolvePokemon(input string) string {
+	input = strings.TrimSpace(input)
+	if en, ok := t.jaToEn[input]; ok {
+		return en
+	}
+	return strings.ReplaceAll(strings.ToLower(input), " ", "-")
+}
+
+// resolveType は日本語のタイプ名を英語名に変換する
+func resolveType(input string) string {
+	input = strings.TrimSpace(input)
+	for en, ja := range typeNames {
+		if ja == input {
+			return en
+		}
+	}
+	return strings.ToLower(input)
+}
+
+func stringArg(request mcp.CallToolRequest, key string) string {
+	v, _ := request.Params.Arguments[key].(string)
+	return v
+}
+
+func jsonResult(v any) (*mcp.CallToolResult, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(b)), nil
+}
+
+type damageRelations struct {
+	DoubleDamageFrom []name `json:"double_damage_from"`
+	DoubleDamageTo   []name `json:"double_damage_to"`
+	HalfDamageFrom   []name `json:"half_damage_from"`
+	HalfDamageTo     []name `json:"half_damage_to"`
+	NoDamageFrom     []name `json:"no_damage_from"`
+	NoDamageTo       []name `json:"no_damage_to"`
+}
+
+type typeResult struct {
+	Type            name            `json:"type"`
+	DamageRelations damageRelations `json:"damage_relations"`
+}
+
+func (t *pokeAPITools) typeResult(ctx context.Context, typ string) (*typeResult, error) {
+	var resp typeResponse
+	if err := t.api.get(ctx, "type/"+typ, &resp); err != nil {
+		return nil, err
+	}
+
+	r := resp.DamageRelations
+	return &typeResult{
+		Type: typeName(resp.Name),
+		DamageRelations: damageRelations{
+			DoubleDamageFrom: typeNameList(r.DoubleDamageFrom),
+			DoubleDamageTo:   typeNameList(r.DoubleDamageTo),
+			HalfDamageFrom:   typeNameList(r.HalfDamageFrom),
+			HalfDamageTo:     typeNameList(r.HalfDamageTo),
+			NoDamageFrom:     typeNameList(r.NoDamageFrom),
+			NoDamageTo:       typeNameList(r.NoDamageTo),
+		},
+	}, nil
+}
+
+func (t *pokeAPITools) handleType(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if typ := stringArg(request, "type"); typ != "" {
+		result, err := t.typeResult(ctx, resolveType(typ))
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("failed to get type", err), nil
+		}
+		return jsonResult(result)
+	}
+
+	input := stringArg(request, "pokemon")
+	if input == "" {
+		return mcp.NewToolResultError("either type or pokemon is required"), nil
+	}
+
+	species, err := t.api.species(ctx, t.resolvePokemon(input))
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get pokemon", err), nil
+	}
+	pokemon, err := t.api.pokemon(ctx, species.Name)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get pokemon", err), nil
+	}
+
+	sort.Slice(pokemon.Types, func(i, j int) bool { return pokemon.Types[i].Slot < pokemon.Types[j].Slot })
+	types := make([]*typeResult, len(pokemon.Types))
+	err = parallel(len(pokemon.Types), func(i int) error {
+		var err error
+		types[i], err = t.typeResult(ctx, pokemon.Types[i].Type.Name)
+		return err
+	})
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get type", err), nil
+	}
+
+	return jsonResult(struct {
+		Pokemon name          `json:"pokemon"`
+		Types   []*typeResult `json:"types"`
+	}{
+		Pokemon: name{Ja: japaneseName(species.Names, species.Name), En: species.Name},
+		Types:   types,
+	})
+}
+
+type evolution struct {
+	From         name   `json:"from"`
+	To           name   `json:"to"`
+	Trigger      string `json:"trigger"`
+	MinLevel     *int   `json:"min_level,omitempty"`
+	Item         string `json:"item,omitempty"`
+	HeldItem     string `json:"held_item,omitempty"`
+	MinHappiness *int   `json:"min_happiness,omitempty"`
+	TimeOfDay    string `json:"time_of_day,omitempty"`
+	KnownMove    string `json:"known_move,omitempty"`
+}
+
+type evolutionStage struct {
+	Stage int `json:"stage"`
+	name
+}
+
+func (t *pokeAPITools) handleEvolutionChain(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	species, err := t.api.species(ctx, t.resolvePokemon(stringArg(request, "pokemon")))
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get pokemon", err), nil
+	}
+
+	var chain evolutionChainResponse
+	if err := t.api.get(ctx, species.EvolutionChain.URL, &chain); err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get evolution chain", err), nil
+	}
+
+	// 進化の系統を幅優先でたどり、各段階の種族を集める
+	var links []*chainLink
+	var stages []evolutionStage
+	queue := []*chainLink{&chain.Chain}
+	for stage := 1; len(queue) > 0; stage++ {
+		var next []*chainLink
+		for _, link := range queue {
+			links = append(links, link)
+			stages = append(stages, evolutionStage{Stage: stage})
+			for i := range link.EvolvesTo {
+				next = append(next, &link.EvolvesTo[i])
+			}
+		}
+		queue = next
+	}
+
+	// 日本語名は種族ごとに取得する
+	err = parallel(len(links), func(i int) error {
+		s, err := t.api.species(ctx, links[i].Species.Name)
+		if err != nil {
+			return err
+		}
+		stages[i].name = name{Ja: japaneseName(s.Names, s.Name), En: s.Name}
+		return nil
+	})
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get pokemon species", err), nil
+	}
+	names := make(map[string]name, len(links))
+	for i, link := range links {
+		names[link.Species.Name] = stages[i].name
+	}
+
+	evolutions := []evolution{}
+	for _, link := range links {
+		for _, next := range link.EvolvesTo {
+			for _, d := range next.EvolutionDetails {
+				e := evolution{
+					From:         names[link.Species.Name],
+					To:           names[next.Species.Name],
+					Trigger:      d.Trigger.Name,
+					MinLevel:     d.MinLevel,
+					MinHappiness: d.MinHappiness,
+					TimeOfDay:    d.TimeOfDay,
+				}
+				if d.Item != nil {
+					e.Item = d.Item.Name
+				}
+				if d.HeldItem != nil {
+					e.HeldItem = d.HeldItem.Name
+				}
+				if d.KnownMove != nil {
+					e.KnownMove = d.KnownMove.Name
+				}
+				evolutions = append(evolutions, e)
+			}
+		}
+	}
+
+	return jsonResult(struct {
+		Chain      []evolutionStage `json:"chain"`
+		Evolutions []evolution      `json:"evolutions"`
+	}{
+		Chain:      stages,
+		Evolutions: evolutions,
+	})
+}
+
+type move struct {
+	name
+	Level       int    `json:"level,omitempty"`
+	Type        name   `json:"type"`
+	DamageClass string `json:"damage_class"`
+	Power       *int   `json:"power"`
+	Accuracy    *int   `json:"accuracy"`
+	PP          *int   `json:"pp"`
+}
+
+func (t *pokeAPITools) handleMoves(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	method := stringArg(request, "method")
+	if method == "" {
+		method = "level-up"
+	}
+	limit := 30
+	if v, ok := request.Params.Arguments["limit"].(float64); ok && v >= 1 {
+		limit = int(v)
+	}
+
+	pokemon, err := t.api.pokemon(ctx, t.resolvePokemon(stringArg(request, "pokemon")))
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get pokemon", err), nil
+	}
+
+	// 覚え方が一致するわざを集める。レベルは最後 (新しい) バージョンのものを使う
+	var moves []*move
+	for _, m := range pokemon.Moves {
+		found := false
+		level := 0
+		for _, d := range m.VersionGroupDetails {
+			if d.MoveLearnMethod.Name == method {
+				found = true
+				level = d.LevelLearnedAt
+			}
+		}
+		if found {
+			moves = append(moves, &move{name: name{En: m.Move.Name}, Level: level})
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		if moves[i].Level != moves[j].Level {
+			return moves[i].Level < moves[j].Level
+		}
+		return moves[i].En < moves[j].En
+	})
+	total := len(moves)
+	if len(moves) > limit {
+		moves = moves[:limit]
+	}
+
+	err = parallel(len(moves), func(i int) error {
+		var resp moveResponse
+		if err := t.api.get(ctx, "move/"+moves[i].En, &resp); err != nil {
+			return err
+		}
+		moves[i].Ja = japaneseName(resp.Names, resp.Name)
+		moves[i].Type = typeName(resp.Type.Name)
+		moves[i].DamageClass = resp.DamageClass.Name
+		moves[i].Power = resp.Power
+		moves[i].Accuracy = resp.Accuracy
+		moves[i].PP = resp.PP
+		return nil
+	})
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("failed to get move", err), nil
+	}
+
+	return jsonResult(struct {
+		Pokemon string  `json:"pokemon"`
+		Method  string  `json:"method"`
+		Total   int     `json:"total"`
+		Moves   []*move `json:"moves"`
+	}{
+		Pokemon: pokemon.Name,
+		Method:  method,
+		Total:   total,
+		Moves:   moves,
+	})
+}
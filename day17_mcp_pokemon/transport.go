@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 対応しているトランスポート
+const (
+	transportStdio = "stdio"
+	transportSSE   = "sse"
+)
+
+// shutdownTimeout は SSE サーバーの終了時に接続中のセッションを待つ時間
+const shutdownTimeout = 5 * time.Second
+
+// serve は指定されたトランスポートで MCP サーバーを起動する
+func serve(s *server.MCPServer, transport, addr, baseURL string) error {
+	switch transport {
+	case transportStdio:
+		return server.ServeStdio(s)
+	case transportSSE:
+		return serveSSE(s, addr, baseURL)
+	default:
+		return fmt.Errorf("unknown transport %q (expected %s or %s)", transport, transportStdio, transportSSE)
+	}
+}
+
+// serveSSE は HTTP (SSE) で MCP サーバーを公開し、SIGINT / SIGTERM で graceful shutdown する
+func serveSSE(s *server.MCPServer, addr, baseURL string) error {
+	opts := []server.SSEOption{server.WithKeepAlive(true)}
+	if baseURL != "" {
+		opts = append(opts, server.WithBaseURL(baseURL))
+	} else {
+		// ベース URL が未指定の場合、メッセージ送信先はクライアントが接続したホストからの相対パスにする
+		opts = append(opts, server.WithUseFullURLForMessageEndpoint(false))
+	}
+	sseServer := server.NewSSEServer(s, opts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("MCP SSE server listening on %s (SSE endpoint: %s)", addr, sseServer.CompleteSsePath())
+		errCh <- sseServer.Start(addr)
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down MCP SSE server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := sseServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown server: %w", err)
+	}
+	return nil
+}
@@ -8,9 +8,13 @@ import (
 
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// seedPassword は投入するユーザー共通のログインパスワードです
+const seedPassword = "password"
+
 func main() {
 	fmt.Println("Starting database seeder...")
 
@@ -37,12 +41,19 @@ func main() {
 		dbname = "app"
 	}
 
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	if sqlitePath == "" {
+		sqlitePath = "todo_app.db"
+	}
+
 	config := datastore.DBConfig{
-		User:     user,
-		Password: password,
-		Host:     host,
-		Port:     port,
-		DBName:   dbname,
+		Driver:     os.Getenv("DB_DRIVER"), // 未設定の場合は MySQL
+		SQLitePath: sqlitePath,
+		User:       user,
+		Password:   password,
+		Host:       host,
+		Port:       port,
+		DBName:     dbname,
 	}
 
 	// データベース接続
@@ -64,6 +75,11 @@ func main() {
 
 // seedUsers は初期ユーザーデータを投入します
 func seedUsers(db *gorm.DB) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(seedPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Fatalf("failed to hash seed password: %v", err)
+	}
+
 	users := []model.User{
 		{Name: "User A"}, // ID: 1 になる想定
 		{Name: "User B"}, // ID: 2
@@ -77,6 +93,7 @@ func seedUsers(db *gorm.DB) {
 			fmt.Printf("User '%s' (ID: %d) already exists, skipping.\n", existingUser.Name, existingUser.ID)
 			continue // 存在すればスキップ
 		}
+		user.PasswordHash = string(hash)
 
 		// 存在しない場合のみ作成
 		if err := db.Create(&user).Error; err != nil {
@@ -109,7 +126,6 @@ func seedTodos(db *gorm.DB, userID int64, count int) {
 			Description: fmt.Sprintf("This is the description for test todo %d.", i),
 			Status:      model.TodoStatusInProgress, // デフォルトステータス
 			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
 			// ArchivedAt は NULL (gorm.DeletedAt のゼロ値)
 		})
 	}
@@ -7,9 +7,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	// 追加
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/notifier"
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infrastructure/server" // infra/server を使う
 )
 
@@ -25,19 +27,36 @@ func main() {
 
 	// === 設定の読み込み (環境変数から) ===
 	dbCfg := datastore.DBConfig{
-		User:     getEnv("DB_USER", "user"),
-		Password: getEnv("DB_PASSWORD", "password"),
-		Host:     getEnv("DB_HOST", "127.0.0.1"), // Docker Compose のサービス名ではなく localhost を参照
-		Port:     getEnv("DB_PORT", "3306"),      // docker-compose.yml で公開したポート
-		DBName:   getEnv("DB_NAME", "todo_app_db"),
-		Charset:  getEnv("DB_CHARSET", "utf8mb4"),
-		Loc:      getEnv("DB_LOC", "Local"),
+		Driver:     getEnv("DB_DRIVER", datastore.DriverMySQL), // MySQL を用意できない場合は "sqlite"
+		SQLitePath: getEnv("SQLITE_PATH", "todo_app.db"),
+		User:       getEnv("DB_USER", "user"),
+		Password:   getEnv("DB_PASSWORD", "password"),
+		Host:       getEnv("DB_HOST", "127.0.0.1"), // Docker Compose のサービス名ではなく localhost を参照
+		Port:       getEnv("DB_PORT", "3306"),      // docker-compose.yml で公開したポート
+		DBName:     getEnv("DB_NAME", "todo_app_db"),
+		Charset:    getEnv("DB_CHARSET", "utf8mb4"),
+		Loc:        getEnv("DB_LOC", "Local"),
 	}
 	serverCfg := server.Config{
 		Addr:   ":" + getEnv("PORT", "8080"),
 		DBConf: dbCfg,
+		Reminder: server.ReminderConfig{
+			Interval:       getEnvDuration("REMINDER_INTERVAL", time.Minute),
+			WebhookTimeout: getEnvDuration("REMINDER_WEBHOOK_TIMEOUT", 10*time.Second),
+			SMTP: notifier.SMTPConfig{
+				Host:     getEnv("SMTP_HOST", ""), // 未設定の場合はメール通知を行わない
+				Port:     getEnv("SMTP_PORT", "587"),
+				Username: getEnv("SMTP_USERNAME", ""),
+				Password: getEnv("SMTP_PASSWORD", ""),
+				From:     getEnv("SMTP_FROM", "todo-app@localhost"),
+			},
+		},
+		Auth: server.AuthConfig{
+			JWTSecret: getEnv("JWT_SECRET", ""), // 未設定の場合は起動ごとにランダムな鍵を使う
+			TokenTTL:  getEnvDuration("JWT_TTL", 24*time.Hour),
+		},
 	}
-	slog.Info("configuration loaded", "serverAddr", serverCfg.Addr, "dbHost", dbCfg.Host, "dbPort", dbCfg.Port, "dbName", dbCfg.DBName)
+	slog.Info("configuration loaded", "serverAddr", serverCfg.Addr, "dbHost", dbCfg.Host, "dbPort", dbCfg.Port, "dbName", dbCfg.DBName, "reminderInterval", serverCfg.Reminder.Interval, "smtpHost", serverCfg.Reminder.SMTP.Host)
 
 	// === サーバーの初期化 (依存性注入) ===
 	srv, err := server.NewServer(serverCfg) // DI コンテナがあればそれを使うのが望ましい
@@ -67,3 +86,17 @@ func getEnv(key, fallback string) string {
 	slog.Debug("environment variable not set, using fallback", "key", key, "fallback", fallback)
 	return fallback
 }
+
+// getEnvDuration は環境変数を time.Duration として取得し、なければ (または不正な値なら) デフォルト値を返します。
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		slog.Warn("invalid duration in environment variable, using fallback", "key", key, "value", value, "fallback", fallback)
+		return fallback
+	}
+	return d
+}
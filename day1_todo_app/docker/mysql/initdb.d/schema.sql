@@ -6,6 +6,7 @@
 CREATE TABLE IF NOT EXISTS users (
   id BIGINT AUTO_INCREMENT PRIMARY KEY COMMENT 'ユーザーID',
   name VARCHAR(255) NOT NULL UNIQUE COMMENT 'ユーザー名',
+  password_hash VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'パスワードハッシュ (bcrypt)',
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '作成日時'
 ) COMMENT = 'ユーザー';
 -- ToDo テーブル
@@ -24,12 +25,46 @@ CREATE TABLE IF NOT EXISTS todos (
   sort_order DOUBLE NOT NULL DEFAULT 0 COMMENT 'ソート順',
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '作成日時',
   updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新日時',
+  due_at TIMESTAMP NULL DEFAULT NULL COMMENT '期限',
+  reminded_at TIMESTAMP NULL DEFAULT NULL COMMENT 'リマインダー送信日時',
   archived_at TIMESTAMP NULL DEFAULT NULL COMMENT 'アーカイブ日時',
   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
   INDEX idx_user_archived_sort (user_id, archived_at, sort_order),
   -- 検索用インデックス
-  INDEX idx_user_created (user_id, created_at) -- デフォルトソート用インデックス
+  INDEX idx_user_created (user_id, created_at), -- デフォルトソート用インデックス
+  INDEX idx_user_due (user_id, due_at) -- 期限ソート用インデックス
 ) COMMENT = 'ToDo';
+-- リマインダー設定テーブル (ユーザーごと)
+CREATE TABLE IF NOT EXISTS reminder_settings (
+  user_id BIGINT PRIMARY KEY COMMENT 'ユーザーID',
+  enabled BOOLEAN NOT NULL DEFAULT FALSE COMMENT 'リマインダーを送信するか',
+  minutes_before INT NOT NULL DEFAULT 30 COMMENT '期限の何分前に通知するか',
+  webhook_url VARCHAR(2048) NULL DEFAULT NULL COMMENT '通知先 Webhook URL',
+  email VARCHAR(255) NULL DEFAULT NULL COMMENT '通知先メールアドレス',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '作成日時',
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新日時',
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) COMMENT = 'リマインダー設定';
+-- タグテーブル (ユーザーごと)
+CREATE TABLE IF NOT EXISTS tags (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY COMMENT 'タグID',
+  user_id BIGINT NOT NULL COMMENT 'ユーザーID',
+  name VARCHAR(50) NOT NULL COMMENT 'タグ名',
+  color VARCHAR(7) NULL DEFAULT NULL COMMENT 'タグの色 (#RRGGBB)',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '作成日時',
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  UNIQUE KEY uk_user_name (user_id, name) -- 同じユーザー内でタグ名は一意
+) COMMENT = 'タグ';
+-- ToDo とタグの関連テーブル (多対多)
+CREATE TABLE IF NOT EXISTS todo_tags (
+  todo_id BIGINT NOT NULL COMMENT 'ToDo ID',
+  tag_id BIGINT NOT NULL COMMENT 'タグID',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '作成日時',
+  PRIMARY KEY (todo_id, tag_id),
+  FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+  FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE,
+  INDEX idx_tag (tag_id) -- タグでの絞り込み用インデックス
+) COMMENT = 'ToDo とタグの関連';
 -- 初期ユーザーデータ投入
 INSERT INTO users (name)
 VALUES ('User A'),
@@ -0,0 +1,32 @@
+// internal/domain/model/reminder.go
+package model
+
+// リマインダーの通知タイミング (期限の何分前か) の範囲
+const (
+	DefaultReminderMinutesBefore = 30
+	MinReminderMinutesBefore     = 1
+	MaxReminderMinutesBefore     = 7 * 24 * 60 // 1週間
+)
+
+// ReminderSetting はユーザーごとのリマインダー設定を表します。
+type ReminderSetting struct {
+	UserID        int64
+	Enabled       bool
+	MinutesBefore int    // 期限の何分前に通知するか
+	WebhookURL    string // 空の場合は Webhook 通知しない
+	Email         string // 空の場合はメール通知しない
+}
+
+// NewDefaultReminderSetting は設定が未登録のユーザー向けのデフォルト設定 (無効) を返します。
+func NewDefaultReminderSetting(userID int64) *ReminderSetting {
+	return &ReminderSetting{
+		UserID:        userID,
+		Enabled:       false,
+		MinutesBefore: DefaultReminderMinutesBefore,
+	}
+}
+
+// HasDestination は通知先が1つ以上設定されているか判定します。
+func (s *ReminderSetting) HasDestination() bool {
+	return s.WebhookURL != "" || s.Email != ""
+}
@@ -0,0 +1,33 @@
+// internal/domain/model/tag.go
+package model
+
+import (
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxTagNameLength はタグ名の最大文字数です。
+const MaxTagNameLength = 50
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Tag はユーザーが ToDo に付けるタグ (ラベル) を表します。
+type Tag struct {
+	ID        int64
+	UserID    int64
+	Name      string // ユーザー内で一意
+	Color     string // #RRGGBB 形式。未設定の場合は空文字
+	CreatedAt time.Time
+}
+
+// IsValidTagName はタグ名が有効 (1〜MaxTagNameLength 文字) か検証します。
+func IsValidTagName(name string) bool {
+	n := utf8.RuneCountInString(name)
+	return n > 0 && n <= MaxTagNameLength
+}
+
+// IsValidTagColor はタグの色が空または #RRGGBB 形式か検証します。
+func IsValidTagColor(color string) bool {
+	return color == "" || tagColorPattern.MatchString(color)
+}
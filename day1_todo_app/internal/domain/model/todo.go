@@ -24,6 +24,11 @@ func (s TodoStatus) IsValid() bool {
 	}
 }
 
+// IsClosed は完了・キャンセルなど、これ以上作業が発生しないステータスか判定します。
+func (s TodoStatus) IsClosed() bool {
+	return s == TodoStatusDone || s == TodoStatusCancel
+}
+
 // Todo はドメイン層の ToDo モデルを表します。
 type Todo struct {
 	ID          int64
@@ -33,10 +38,22 @@ type Todo struct {
 	Status      TodoStatus // Status フィールドを復活
 	SortOrder   float64
 	CreatedAt   time.Time
+	DueAt       *time.Time // 期限が設定されていない場合は nil
+	RemindedAt  *time.Time // リマインダー送信済みの場合はその日時
 	ArchivedAt  *time.Time // アーカイブされていない場合は nil
+	Tags        []*Tag     `gorm:"-"` // 付与されているタグ (名前順)。seeder が GORM で直接保存するため関連として扱わせない
 }
 
 // IsArchived は ToDo がアーカイブされているか判定します。
 func (t *Todo) IsArchived() bool {
 	return t.ArchivedAt != nil
 }
+
+// IsOverdue は ToDo が期限切れか判定します。
+// 期限が過ぎていて、完了・キャンセル・アーカイブされていないものを期限切れとみなします。
+func (t *Todo) IsOverdue(now time.Time) bool {
+	if t.DueAt == nil || t.IsArchived() || t.Status.IsClosed() {
+		return false
+	}
+	return t.DueAt.Before(now)
+}
@@ -1,11 +1,35 @@
 // internal/domain/model/user.go
 package model
 
-import "time"
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// ユーザー名・パスワードの長さの制限
+const (
+	MaxUserNameLength = 255
+	MinPasswordLength = 8
+	MaxPasswordLength = 72 // bcrypt が扱える最大バイト数
+)
 
 // User はドメイン層のユーザーモデルを表します。
 type User struct {
-	ID        int64
-	Name      string
-	CreatedAt time.Time
+	ID   int64
+	Name string
+	// PasswordHash は bcrypt でハッシュ化したパスワードです。空の場合はログインできません。
+	// Web ページにユーザー一覧を JSON で埋め込むことがあるため、JSON には出力しません。
+	PasswordHash string `json:"-"`
+	CreatedAt    time.Time
+}
+
+// IsValidUserName はユーザー名が有効 (1〜MaxUserNameLength 文字) か検証します。
+func IsValidUserName(name string) bool {
+	n := utf8.RuneCountInString(name)
+	return n > 0 && n <= MaxUserNameLength
+}
+
+// IsValidPassword はパスワードの長さが有効か検証します。
+func IsValidPassword(password string) bool {
+	return utf8.RuneCountInString(password) >= MinPasswordLength && len(password) <= MaxPasswordLength
 }
@@ -0,0 +1,20 @@
+// internal/domain/repository/reminder.go
+package repository
+
+import (
+	"context"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+)
+
+// ReminderSettingRepository はリマインダー設定へのアクセスを抽象化するインターフェースです。
+type ReminderSettingRepository interface {
+	// FindByUserID は指定されたユーザーのリマインダー設定を取得します。未登録の場合は nil を返します。
+	FindByUserID(ctx context.Context, userID int64) (*model.ReminderSetting, error)
+
+	// FindEnabled はリマインダーが有効なすべての設定を取得します。
+	FindEnabled(ctx context.Context) ([]*model.ReminderSetting, error)
+
+	// Save はリマインダー設定を作成または更新します。
+	Save(ctx context.Context, setting *model.ReminderSetting) error
+}
@@ -0,0 +1,35 @@
+// internal/domain/repository/tag.go
+package repository
+
+import (
+	"context"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+)
+
+// TagRepository はタグと ToDo へのタグ付けへのアクセスを抽象化するインターフェースです。
+type TagRepository interface {
+	// FindByUserID は指定されたユーザーのタグを名前順で取得します。
+	FindByUserID(ctx context.Context, userID int64) ([]*model.Tag, error)
+
+	// FindByID は指定されたIDのタグを取得します。存在しない場合は nil を返します。
+	FindByID(ctx context.Context, id int64) (*model.Tag, error)
+
+	// FindByName は指定されたユーザーの同名のタグを取得します。存在しない場合は nil を返します。
+	FindByName(ctx context.Context, userID int64, name string) (*model.Tag, error)
+
+	// Create は新しいタグを作成します。
+	Create(ctx context.Context, tag *model.Tag) error
+
+	// Update はタグの名前と色を更新します。
+	Update(ctx context.Context, tag *model.Tag) error
+
+	// Delete は指定された ID と UserID のタグを削除します。ToDo へのタグ付けも削除されます。
+	Delete(ctx context.Context, id int64, userID int64) error
+
+	// Attach は ToDo にタグを付与します。すでに付与されている場合は何もしません。
+	Attach(ctx context.Context, todoID int64, tagID int64) error
+
+	// Detach は ToDo からタグを外します。付与されていない場合は何もしません。
+	Detach(ctx context.Context, todoID int64, tagID int64) error
+}
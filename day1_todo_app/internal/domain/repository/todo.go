@@ -3,16 +3,44 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
 )
 
+// TodoSortKey は ToDo 一覧のソートキーです。
+type TodoSortKey string
+
+const (
+	// TodoSortDefault は sort_order 昇順, created_at 降順 (手動並び替え順) でソートします。
+	TodoSortDefault TodoSortKey = ""
+	// TodoSortCreatedAt は作成日時の新しい順でソートします。
+	TodoSortCreatedAt TodoSortKey = "created_at"
+	// TodoSortDueDate は期限の近い順でソートします。期限なしの ToDo は末尾になります。
+	TodoSortDueDate TodoSortKey = "due_date"
+)
+
+// IsValid はソートキーが有効な値か検証します。
+func (k TodoSortKey) IsValid() bool {
+	switch k {
+	case TodoSortDefault, TodoSortCreatedAt, TodoSortDueDate:
+		return true
+	default:
+		return false
+	}
+}
+
 // FindTodosParams は ToDo 検索のパラメータです。
 type FindTodosParams struct {
 	UserID          int64
 	Limit           int
-	Page            int  // ページ番号 (1-indexed)
-	IncludeArchived bool // アーカイブ済みを含めるか
+	Page            int               // ページ番号 (1-indexed)
+	IncludeArchived bool              // アーカイブ済みを含めるか
+	Status          *model.TodoStatus // 指定された場合はそのステータスのみ
+	Query           string            // タイトル・詳細の部分一致検索キーワード
+	Sort            TodoSortKey
+	OverdueAt       *time.Time // 指定された場合はこの時刻の時点で期限切れの ToDo のみ
+	TagID           *int64     // 指定された場合はこのタグが付いた ToDo のみ
 }
 
 // UpdateTodoOrderParams は ToDo の並び替えパラメータです。
@@ -23,10 +51,11 @@ type UpdateTodoOrderParams struct {
 
 // TodoRepository は ToDo データへのアクセスを抽象化するインターフェースです。
 type TodoRepository interface {
-	// FindByUserID は指定されたユーザーの ToDo を検索します。
-	// ページネーションとアーカイブ済みを含めるかのオプションがあります。
-	// デフォルトソートは CreatedAt 降順、ユーザー定義ソートがあればそれを優先します。
-	Find(ctx context.Context, params FindTodosParams) ([]*model.Todo, error)
+	// Find は指定されたユーザーの ToDo を検索します。
+	// Find と FindByID が返す ToDo には付与されているタグも設定されます。
+	// ステータス・キーワードによる絞り込み、ソートキー、ページネーションのオプションがあります。
+	// 戻り値の int64 はページネーション適用前の総件数です。
+	Find(ctx context.Context, params FindTodosParams) ([]*model.Todo, int64, error)
 
 	// FindByID は指定されたIDの ToDo を取得します。
 	FindByID(ctx context.Context, id int64) (*model.Todo, error)
@@ -49,4 +78,11 @@ type TodoRepository interface {
 
 	// Unarchive はアーカイブされた ToDo を元に戻します。
 	Unarchive(ctx context.Context, id int64) error
+
+	// FindReminderTargets は期限が from より後かつ to 以前で、まだリマインダーを送信していない
+	// 未完了の ToDo を取得します。
+	FindReminderTargets(ctx context.Context, userID int64, from, to time.Time) ([]*model.Todo, error)
+
+	// MarkReminded は ToDo のリマインダー送信日時を記録します。
+	MarkReminded(ctx context.Context, id int64, at time.Time) error
 }
@@ -15,4 +15,6 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id int64) (*model.User, error)
 	// FindByName は指定された名前のユーザーを取得します。
 	FindByName(ctx context.Context, name string) (*model.User, error)
+	// Create は新しいユーザーを作成します。
+	Create(ctx context.Context, user *model.User) error
 }
@@ -0,0 +1,67 @@
+// internal/infra/auth/jwt.go
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/m-mizutani/goerr"
+)
+
+// issuer は発行するトークンの iss クレームです。
+const issuer = "day1_todo_app"
+
+// JWTManager は HS256 で署名した JWT アクセストークンの発行と検証を行います。
+// sub クレームにユーザー ID を格納します。
+type JWTManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewJWTManager は新しい JWTManager を生成します。
+func NewJWTManager(secret []byte, ttl time.Duration) *JWTManager {
+	return &JWTManager{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// Issue はユーザーのアクセストークンと有効期限を返します。
+func (m *JWTManager) Issue(userID int64) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   strconv.FormatInt(userID, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, goerr.Wrap(err, "failed to sign token").With("userID", userID)
+	}
+	return token, expiresAt, nil
+}
+
+// Verify はトークンの署名・有効期限・発行者を検証し、ユーザー ID を返します。
+func (m *JWTManager) Verify(token string) (int64, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return 0, goerr.Wrap(err, "invalid token")
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, goerr.Wrap(err, "invalid subject in token").With("sub", claims.Subject)
+	}
+	return userID, nil
+}
@@ -2,39 +2,57 @@
 package datastore
 
 import (
+	_ "embed"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// 対応しているデータベースドライバー
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
+// sqliteSchema は SQLite 用のテーブル定義です (MySQL の docker/mysql/initdb.d/schema.sql に相当)。
+//
+//go:embed sqlite/schema.sql
+var sqliteSchema string
+
 // DBConfig はデータベース接続設定です。
+// Driver が DriverSQLite の場合は SQLitePath のみを使い、MySQL 用の項目は無視します。
 type DBConfig struct {
-	User     string
-	Password string
-	Host     string
-	Port     string
-	DBName   string
-	Charset  string
-	Loc      string
+	Driver     string // 空の場合は DriverMySQL
+	SQLitePath string // SQLite のデータベースファイルのパス
+	User       string
+	Password   string
+	Host       string
+	Port       string
+	DBName     string
+	Charset    string
+	Loc        string
 }
 
 // NewDB は新しい Gorm DB 接続を確立します。
+// リポジトリはドライバーに依存しない GORM Gen のクエリだけを使うため、どちらのドライバーでも同じように動作します。
 func NewDB(cfg DBConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=%s",
-		cfg.User,
-		cfg.Password,
-		cfg.Host,
-		cfg.Port,
-		cfg.DBName,
-		cfg.Charset,
-		cfg.Loc,
-	)
-
-	slog.Info("connecting to database", "dsn", fmt.Sprintf("%s:****@tcp(%s:%s)/%s?...", cfg.User, cfg.Host, cfg.Port, cfg.DBName)) // パスワードはログに出さない
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverMySQL, "":
+		dialector = mysql.Open(mysqlDSN(cfg))
+	case DriverSQLite:
+		// 外部キー制約 (ON DELETE CASCADE) は接続ごとに有効化が必要
+		dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", cfg.SQLitePath)
+		slog.Info("connecting to database", "driver", cfg.Driver, "path", cfg.SQLitePath)
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", cfg.Driver)
+	}
 
 	// Gorm ロガー設定 (SQL ログを出力)
 	gormLogger := logger.New(
@@ -47,7 +65,7 @@ func NewDB(cfg DBConfig) (*gorm.DB, error) {
 		},
 	)
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormLogger, // 設定したロガーを使用
 	})
 	if err != nil {
@@ -55,6 +73,38 @@ func NewDB(cfg DBConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
 
+	if cfg.Driver == DriverSQLite {
+		if err := migrateSQLite(db); err != nil {
+			return nil, err
+		}
+	}
+
 	slog.Info("database connection established")
 	return db, nil
 }
+
+// mysqlDSN は MySQL の接続文字列を組み立てます。
+func mysqlDSN(cfg DBConfig) string {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=%s",
+		cfg.User,
+		cfg.Password,
+		cfg.Host,
+		cfg.Port,
+		cfg.DBName,
+		cfg.Charset,
+		cfg.Loc,
+	)
+
+	slog.Info("connecting to database", "driver", DriverMySQL, "dsn", fmt.Sprintf("%s:****@tcp(%s:%s)/%s?...", cfg.User, cfg.Host, cfg.Port, cfg.DBName)) // パスワードはログに出さない
+	return dsn
+}
+
+// migrateSQLite は SQLite にテーブルを作成します。テーブル定義はすべて IF NOT EXISTS なので毎回実行しても問題ありません。
+func migrateSQLite(db *gorm.DB) error {
+	slog.Info("applying sqlite schema")
+	if err := db.Exec(sqliteSchema).Error; err != nil {
+		slog.Error("failed to apply sqlite schema", "error", err)
+		return fmt.Errorf("failed to apply sqlite schema: %w", err)
+	}
+	return nil
+}
@@ -18,8 +18,11 @@ const dsn = "user:password@tcp(127.0.0.1:3306)/todo_app_db?charset=utf8mb4&parse
 
 // 生成するモデルに対応するテーブル名
 const (
-	UsersTableName = "users"
-	TodosTableName = "todos"
+	UsersTableName            = "users"
+	TodosTableName            = "todos"
+	ReminderSettingsTableName = "reminder_settings"
+	TagsTableName             = "tags"
+	TodoTagsTableName         = "todo_tags"
 )
 
 // カスタムカラム型マッピング (必要に応じて)
@@ -75,6 +78,9 @@ func GenerateModels() {
 	// テーブル名を指定して全カラムからモデルを生成
 	usersModel := g.GenerateModel(UsersTableName)
 	todosModel := g.GenerateModel(TodosTableName)
+	reminderSettingsModel := g.GenerateModel(ReminderSettingsTableName)
+	tagsModel := g.GenerateModel(TagsTableName)
+	todoTagsModel := g.GenerateModel(TodoTagsTableName)
 
 	// (オプション) 特定のカラムだけを選択したり、リレーションを設定したりも可能
 	// usersModel := g.GenerateModel("users",
@@ -88,7 +94,7 @@ func GenerateModels() {
 	// g.ApplyBasic(g.GenerateAllTable()...)
 
 	// 指定したモデルを生成対象に追加
-	g.ApplyBasic(usersModel, todosModel)
+	g.ApplyBasic(usersModel, todosModel, reminderSettingsModel, tagsModel, todoTagsModel)
 
 	// コード生成を実行
 	g.Execute()
@@ -0,0 +1,27 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameReminderSetting = "reminder_settings"
+
+// ReminderSetting ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼è¨­å®š
+type ReminderSetting struct {
+	UserID        int64      `gorm:"column:user_id;type:bigint;primaryKey;comment:ãƒ¦ãƒ¼ã‚¶ãƒ¼ID" json:"user_id"`                                           // ãƒ¦ãƒ¼ã‚¶ãƒ¼ID
+	Enabled       bool       `gorm:"column:enabled;type:tinyint(1);not null;default:0;comment:ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼ã‚’é€ä¿¡ã™ã‚‹ã‹" json:"enabled"`         // ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼ã‚’é€ä¿¡ã™ã‚‹ã‹
+	MinutesBefore int32      `gorm:"column:minutes_before;type:int;not null;default:30;comment:æœŸé™ã®ä½•åˆ†å‰ã«é€šçŸ¥ã™ã‚‹ã‹" json:"minutes_before"` // æœŸé™ã®ä½•åˆ†å‰ã«é€šçŸ¥ã™ã‚‹ã‹
+	WebhookURL    *string    `gorm:"column:webhook_url;type:varchar(2048);comment:é€šçŸ¥å…ˆ Webhook URL" json:"webhook_url"`                                // é€šçŸ¥å…ˆ Webhook URL
+	Email         *string    `gorm:"column:email;type:varchar(255);comment:é€šçŸ¥å…ˆãƒ¡ãƒ¼ãƒ«ã‚¢ãƒ‰ãƒ¬ã‚¹" json:"email"`                                    // é€šçŸ¥å…ˆãƒ¡ãƒ¼ãƒ«ã‚¢ãƒ‰ãƒ¬ã‚¹
+	CreatedAt     *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;comment:ä½œæˆæ—¥æ™‚" json:"created_at"`                     // ä½œæˆæ—¥æ™‚
+	UpdatedAt     time.Time  `gorm:"column:updated_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:æ›´æ–°æ—¥æ™‚" json:"updated_at"`             // æ›´æ–°æ—¥æ™‚
+}
+
+// TableName ReminderSetting's table name
+func (*ReminderSetting) TableName() string {
+	return TableNameReminderSetting
+}
@@ -0,0 +1,25 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameTag = "tags"
+
+// Tag ã‚¿ã‚°
+type Tag struct {
+	ID        int64      `gorm:"column:id;type:bigint;primaryKey;autoIncrement:true;comment:ã‚¿ã‚°ID" json:"id"`                                // ã‚¿ã‚°ID
+	UserID    int64      `gorm:"column:user_id;type:bigint;not null;uniqueIndex:uk_user_name,priority:1;comment:ãƒ¦ãƒ¼ã‚¶ãƒ¼ID" json:"user_id"` // ãƒ¦ãƒ¼ã‚¶ãƒ¼ID
+	Name      string     `gorm:"column:name;type:varchar(50);not null;uniqueIndex:uk_user_name,priority:2;comment:ã‚¿ã‚°å" json:"name"`       // ã‚¿ã‚°å
+	Color     *string    `gorm:"column:color;type:varchar(7);comment:ã‚¿ã‚°ã®è‰² (#RRGGBB)" json:"color"`                                      // ã‚¿ã‚°ã®è‰² (#RRGGBB)
+	CreatedAt *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;comment:ä½œæˆæ—¥æ™‚" json:"created_at"`             // ä½œæˆæ—¥æ™‚
+}
+
+// TableName Tag's table name
+func (*Tag) TableName() string {
+	return TableNameTag
+}
@@ -0,0 +1,23 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameTodoTag = "todo_tags"
+
+// TodoTag ToDo ã¨ã‚¿ã‚°ã®é–¢é€£
+type TodoTag struct {
+	TodoID    int64      `gorm:"column:todo_id;type:bigint;primaryKey;comment:ToDo ID" json:"todo_id"`                              // ToDo ID
+	TagID     int64      `gorm:"column:tag_id;type:bigint;primaryKey;index:idx_tag,priority:1;comment:ã‚¿ã‚°ID" json:"tag_id"`      // ã‚¿ã‚°ID
+	CreatedAt *time.Time `gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP;comment:ä½œæˆæ—¥æ™‚" json:"created_at"` // ä½œæˆæ—¥æ™‚
+}
+
+// TableName TodoTag's table name
+func (*TodoTag) TableName() string {
+	return TableNameTodoTag
+}
@@ -12,15 +12,17 @@ const TableNameTodo = "todos"
 
 // Todo ToDo
 type Todo struct {
-	ID          int64      `gorm:"column:id;type:bigint;primaryKey;autoIncrement:true;comment:ToDo ID" json:"id"`                                                                       // ToDo ID
-	UserID      int64      `gorm:"column:user_id;type:bigint;not null;index:idx_user_archived_sort,priority:1;index:idx_user_created,priority:1;comment:ãƒ¦ãƒ¼ã‚¶ãƒ¼ID" json:"user_id"` // ãƒ¦ãƒ¼ã‚¶ãƒ¼ID
-	Title       string     `gorm:"column:title;type:varchar(255);not null;comment:ã‚¿ã‚¤ãƒˆãƒ«" json:"title"`                                                                           // ã‚¿ã‚¤ãƒˆãƒ«
-	Description *string    `gorm:"column:description;type:text;comment:è©³ç´°" json:"description"`                                                                                      // è©³ç´°
-	Status      string     `gorm:"column:status;type:enum('not started','in progress','done','pending','cancel');not null;default:not started;comment:çŠ¶æ…‹" json:"status"`            // çŠ¶æ…‹
-	SortOrder   float64    `gorm:"column:sort_order;type:double;not null;index:idx_user_archived_sort,priority:3;comment:ã‚½ãƒ¼ãƒˆé †" json:"sort_order"`                               // ã‚½ãƒ¼ãƒˆé †
-	CreatedAt   *time.Time `gorm:"column:created_at;type:timestamp;index:idx_user_created,priority:2;default:CURRENT_TIMESTAMP;comment:ä½œæˆæ—¥æ™‚" json:"created_at"`                 // ä½œæˆæ—¥æ™‚
-	UpdatedAt   time.Time  `gorm:"column:updated_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:æ›´æ–°æ—¥æ™‚" json:"updated_at"`                                           // æ›´æ–°æ—¥æ™‚
-	ArchivedAt  *time.Time `gorm:"column:archived_at;type:timestamp;index:idx_user_archived_sort,priority:2;comment:ã‚¢ãƒ¼ã‚«ã‚¤ãƒ–æ—¥æ™‚" json:"archived_at"`                          // ã‚¢ãƒ¼ã‚«ã‚¤ãƒ–æ—¥æ™‚
+	ID          int64      `gorm:"column:id;type:bigint;primaryKey;autoIncrement:true;comment:ToDo ID" json:"id"`                                                                                                     // ToDo ID
+	UserID      int64      `gorm:"column:user_id;type:bigint;not null;index:idx_user_archived_sort,priority:1;index:idx_user_created,priority:1;index:idx_user_due,priority:1;comment:ãƒ¦ãƒ¼ã‚¶ãƒ¼ID" json:"user_id"` // ãƒ¦ãƒ¼ã‚¶ãƒ¼ID
+	Title       string     `gorm:"column:title;type:varchar(255);not null;comment:ã‚¿ã‚¤ãƒˆãƒ«" json:"title"`                                                                                                         // ã‚¿ã‚¤ãƒˆãƒ«
+	Description *string    `gorm:"column:description;type:text;comment:è©³ç´°" json:"description"`                                                                                                                    // è©³ç´°
+	Status      string     `gorm:"column:status;type:enum('not started','in progress','done','pending','cancel');not null;default:not started;comment:çŠ¶æ…‹" json:"status"`                                          // çŠ¶æ…‹
+	SortOrder   float64    `gorm:"column:sort_order;type:double;not null;index:idx_user_archived_sort,priority:3;comment:ã‚½ãƒ¼ãƒˆé †" json:"sort_order"`                                                             // ã‚½ãƒ¼ãƒˆé †
+	CreatedAt   *time.Time `gorm:"column:created_at;type:timestamp;index:idx_user_created,priority:2;default:CURRENT_TIMESTAMP;comment:ä½œæˆæ—¥æ™‚" json:"created_at"`                                               // ä½œæˆæ—¥æ™‚
+	UpdatedAt   time.Time  `gorm:"column:updated_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:æ›´æ–°æ—¥æ™‚" json:"updated_at"`                                                                         // æ›´æ–°æ—¥æ™‚
+	DueAt       *time.Time `gorm:"column:due_at;type:timestamp;index:idx_user_due,priority:2;comment:æœŸé™" json:"due_at"`                                                                                           // æœŸé™
+	RemindedAt  *time.Time `gorm:"column:reminded_at;type:timestamp;comment:ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼é€ä¿¡æ—¥æ™‚" json:"reminded_at"`                                                                                       // ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼é€ä¿¡æ—¥æ™‚
+	ArchivedAt  *time.Time `gorm:"column:archived_at;type:timestamp;index:idx_user_archived_sort,priority:2;comment:ã‚¢ãƒ¼ã‚«ã‚¤ãƒ–æ—¥æ™‚" json:"archived_at"`                                                        // ã‚¢ãƒ¼ã‚«ã‚¤ãƒ–æ—¥æ™‚
 }
 
 // TableName Todo's table name
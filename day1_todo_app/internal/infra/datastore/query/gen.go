@@ -16,39 +16,54 @@ import (
 )
 
 var (
-	Q    = new(Query)
-	Todo *todo
-	User *user
+	Q               = new(Query)
+	ReminderSetting *reminderSetting
+	Tag             *tag
+	Todo            *todo
+	TodoTag         *todoTag
+	User            *user
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
+	ReminderSetting = &Q.ReminderSetting
+	Tag = &Q.Tag
 	Todo = &Q.Todo
+	TodoTag = &Q.TodoTag
 	User = &Q.User
 }
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:   db,
-		Todo: newTodo(db, opts...),
-		User: newUser(db, opts...),
+		db:              db,
+		ReminderSetting: newReminderSetting(db, opts...),
+		Tag:             newTag(db, opts...),
+		Todo:            newTodo(db, opts...),
+		TodoTag:         newTodoTag(db, opts...),
+		User:            newUser(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	Todo todo
-	User user
+	ReminderSetting reminderSetting
+	Tag             tag
+	Todo            todo
+	TodoTag         todoTag
+	User            user
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:   db,
-		Todo: q.Todo.clone(db),
-		User: q.User.clone(db),
+		db:              db,
+		ReminderSetting: q.ReminderSetting.clone(db),
+		Tag:             q.Tag.clone(db),
+		Todo:            q.Todo.clone(db),
+		TodoTag:         q.TodoTag.clone(db),
+		User:            q.User.clone(db),
 	}
 }
 
@@ -62,21 +77,30 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:   db,
-		Todo: q.Todo.replaceDB(db),
-		User: q.User.replaceDB(db),
+		db:              db,
+		ReminderSetting: q.ReminderSetting.replaceDB(db),
+		Tag:             q.Tag.replaceDB(db),
+		Todo:            q.Todo.replaceDB(db),
+		TodoTag:         q.TodoTag.replaceDB(db),
+		User:            q.User.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	Todo ITodoDo
-	User IUserDo
+	ReminderSetting IReminderSettingDo
+	Tag             ITagDo
+	Todo            ITodoDo
+	TodoTag         ITodoTagDo
+	User            IUserDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		Todo: q.Todo.WithContext(ctx),
-		User: q.User.WithContext(ctx),
+		ReminderSetting: q.ReminderSetting.WithContext(ctx),
+		Tag:             q.Tag.WithContext(ctx),
+		Todo:            q.Todo.WithContext(ctx),
+		TodoTag:         q.TodoTag.WithContext(ctx),
+		User:            q.User.WithContext(ctx),
 	}
 }
 
@@ -0,0 +1,420 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore/model"
+)
+
+func newReminderSetting(db *gorm.DB, opts ...gen.DOOption) reminderSetting {
+	_reminderSetting := reminderSetting{}
+
+	_reminderSetting.reminderSettingDo.UseDB(db, opts...)
+	_reminderSetting.reminderSettingDo.UseModel(&model.ReminderSetting{})
+
+	tableName := _reminderSetting.reminderSettingDo.TableName()
+	_reminderSetting.ALL = field.NewAsterisk(tableName)
+	_reminderSetting.UserID = field.NewInt64(tableName, "user_id")
+	_reminderSetting.Enabled = field.NewBool(tableName, "enabled")
+	_reminderSetting.MinutesBefore = field.NewInt32(tableName, "minutes_before")
+	_reminderSetting.WebhookURL = field.NewString(tableName, "webhook_url")
+	_reminderSetting.Email = field.NewString(tableName, "email")
+	_reminderSetting.CreatedAt = field.NewTime(tableName, "created_at")
+	_reminderSetting.UpdatedAt = field.NewTime(tableName, "updated_at")
+
+	_reminderSetting.fillFieldMap()
+
+	return _reminderSetting
+}
+
+// reminderSetting ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼è¨­å®š
+type reminderSetting struct {
+	reminderSettingDo reminderSettingDo
+
+	ALL           field.Asterisk
+	UserID        field.Int64  // ãƒ¦ãƒ¼ã‚¶ãƒ¼ID
+	Enabled       field.Bool   // ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼ã‚’é€ä¿¡ã™ã‚‹ã‹
+	MinutesBefore field.Int32  // æœŸé™ã®ä½•åˆ†å‰ã«é€šçŸ¥ã™ã‚‹ã‹
+	WebhookURL    field.String // é€šçŸ¥å…ˆ Webhook URL
+	Email         field.String // é€šçŸ¥å…ˆãƒ¡ãƒ¼ãƒ«ã‚¢ãƒ‰ãƒ¬ã‚¹
+	CreatedAt     field.Time   // ä½œæˆæ—¥æ™‚
+	UpdatedAt     field.Time   // æ›´æ–°æ—¥æ™‚
+
+	fieldMap map[string]field.Expr
+}
+
+func (r reminderSetting) Table(newTableName string) *reminderSetting {
+	r.reminderSettingDo.UseTable(newTableName)
+	return r.updateTableName(newTableName)
+}
+
+func (r reminderSetting) As(alias string) *reminderSetting {
+	r.reminderSettingDo.DO = *(r.reminderSettingDo.As(alias).(*gen.DO))
+	return r.updateTableName(alias)
+}
+
+func (r *reminderSetting) updateTableName(table string) *reminderSetting {
+	r.ALL = field.NewAsterisk(table)
+	r.UserID = field.NewInt64(table, "user_id")
+	r.Enabled = field.NewBool(table, "enabled")
+	r.MinutesBefore = field.NewInt32(table, "minutes_before")
+	r.WebhookURL = field.NewString(table, "webhook_url")
+	r.Email = field.NewString(table, "email")
+	r.CreatedAt = field.NewTime(table, "created_at")
+	r.UpdatedAt = field.NewTime(table, "updated_at")
+
+	r.fillFieldMap()
+
+	return r
+}
+
+func (r *reminderSetting) WithContext(ctx context.Context) IReminderSettingDo {
+	return r.reminderSettingDo.WithContext(ctx)
+}
+
+func (r reminderSetting) TableName() string { return r.reminderSettingDo.TableName() }
+
+func (r reminderSetting) Alias() string { return r.reminderSettingDo.Alias() }
+
+func (r reminderSetting) Columns(cols ...field.Expr) gen.Columns {
+	return r.reminderSettingDo.Columns(cols...)
+}
+
+func (r *reminderSetting) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := r.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (r *reminderSetting) fillFieldMap() {
+	r.fieldMap = make(map[string]field.Expr, 7)
+	r.fieldMap["user_id"] = r.UserID
+	r.fieldMap["enabled"] = r.Enabled
+	r.fieldMap["minutes_before"] = r.MinutesBefore
+	r.fieldMap["webhook_url"] = r.WebhookURL
+	r.fieldMap["email"] = r.Email
+	r.fieldMap["created_at"] = r.CreatedAt
+	r.fieldMap["updated_at"] = r.UpdatedAt
+}
+
+func (r reminderSetting) clone(db *gorm.DB) reminderSetting {
+	r.reminderSettingDo.ReplaceConnPool(db.Statement.ConnPool)
+	return r
+}
+
+func (r reminderSetting) replaceDB(db *gorm.DB) reminderSetting {
+	r.reminderSettingDo.ReplaceDB(db)
+	return r
+}
+
+type reminderSettingDo struct{ gen.DO }
+
+type IReminderSettingDo interface {
+	gen.SubQuery
+	Debug() IReminderSettingDo
+	WithContext(ctx context.Context) IReminderSettingDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IReminderSettingDo
+	WriteDB() IReminderSettingDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IReminderSettingDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IReminderSettingDo
+	Not(conds ...gen.Condition) IReminderSettingDo
+	Or(conds ...gen.Condition) IReminderSettingDo
+	Select(conds ...field.Expr) IReminderSettingDo
+	Where(conds ...gen.Condition) IReminderSettingDo
+	Order(conds ...field.Expr) IReminderSettingDo
+	Distinct(cols ...field.Expr) IReminderSettingDo
+	Omit(cols ...field.Expr) IReminderSettingDo
+	Join(table schema.Tabler, on ...field.Expr) IReminderSettingDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IReminderSettingDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IReminderSettingDo
+	Group(cols ...field.Expr) IReminderSettingDo
+	Having(conds ...gen.Condition) IReminderSettingDo
+	Limit(limit int) IReminderSettingDo
+	Offset(offset int) IReminderSettingDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IReminderSettingDo
+	Unscoped() IReminderSettingDo
+	Create(values ...*model.ReminderSetting) error
+	CreateInBatches(values []*model.ReminderSetting, batchSize int) error
+	Save(values ...*model.ReminderSetting) error
+	First() (*model.ReminderSetting, error)
+	Take() (*model.ReminderSetting, error)
+	Last() (*model.ReminderSetting, error)
+	Find() ([]*model.ReminderSetting, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.ReminderSetting, err error)
+	FindInBatches(result *[]*model.ReminderSetting, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.ReminderSetting) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IReminderSettingDo
+	Assign(attrs ...field.AssignExpr) IReminderSettingDo
+	Joins(fields ...field.RelationField) IReminderSettingDo
+	Preload(fields ...field.RelationField) IReminderSettingDo
+	FirstOrInit() (*model.ReminderSetting, error)
+	FirstOrCreate() (*model.ReminderSetting, error)
+	FindByPage(offset int, limit int) (result []*model.ReminderSetting, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Rows() (*sql.Rows, error)
+	Row() *sql.Row
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IReminderSettingDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (r reminderSettingDo) Debug() IReminderSettingDo {
+	return r.withDO(r.DO.Debug())
+}
+
+func (r reminderSettingDo) WithContext(ctx context.Context) IReminderSettingDo {
+	return r.withDO(r.DO.WithContext(ctx))
+}
+
+func (r reminderSettingDo) ReadDB() IReminderSettingDo {
+	return r.Clauses(dbresolver.Read)
+}
+
+func (r reminderSettingDo) WriteDB() IReminderSettingDo {
+	return r.Clauses(dbresolver.Write)
+}
+
+func (r reminderSettingDo) Session(config *gorm.Session) IReminderSettingDo {
+	return r.withDO(r.DO.Session(config))
+}
+
+func (r reminderSettingDo) Clauses(conds ...clause.Expression) IReminderSettingDo {
+	return r.withDO(r.DO.Clauses(conds...))
+}
+
+func (r reminderSettingDo) Returning(value interface{}, columns ...string) IReminderSettingDo {
+	return r.withDO(r.DO.Returning(value, columns...))
+}
+
+func (r reminderSettingDo) Not(conds ...gen.Condition) IReminderSettingDo {
+	return r.withDO(r.DO.Not(conds...))
+}
+
+func (r reminderSettingDo) Or(conds ...gen.Condition) IReminderSettingDo {
+	return r.withDO(r.DO.Or(conds...))
+}
+
+func (r reminderSettingDo) Select(conds ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.Select(conds...))
+}
+
+func (r reminderSettingDo) Where(conds ...gen.Condition) IReminderSettingDo {
+	return r.withDO(r.DO.Where(conds...))
+}
+
+func (r reminderSettingDo) Order(conds ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.Order(conds...))
+}
+
+func (r reminderSettingDo) Distinct(cols ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.Distinct(cols...))
+}
+
+func (r reminderSettingDo) Omit(cols ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.Omit(cols...))
+}
+
+func (r reminderSettingDo) Join(table schema.Tabler, on ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.Join(table, on...))
+}
+
+func (r reminderSettingDo) LeftJoin(table schema.Tabler, on ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.LeftJoin(table, on...))
+}
+
+func (r reminderSettingDo) RightJoin(table schema.Tabler, on ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.RightJoin(table, on...))
+}
+
+func (r reminderSettingDo) Group(cols ...field.Expr) IReminderSettingDo {
+	return r.withDO(r.DO.Group(cols...))
+}
+
+func (r reminderSettingDo) Having(conds ...gen.Condition) IReminderSettingDo {
+	return r.withDO(r.DO.Having(conds...))
+}
+
+func (r reminderSettingDo) Limit(limit int) IReminderSettingDo {
+	return r.withDO(r.DO.Limit(limit))
+}
+
+func (r reminderSettingDo) Offset(offset int) IReminderSettingDo {
+	return r.withDO(r.DO.Offset(offset))
+}
+
+func (r reminderSettingDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IReminderSettingDo {
+	return r.withDO(r.DO.Scopes(funcs...))
+}
+
+func (r reminderSettingDo) Unscoped() IReminderSettingDo {
+	return r.withDO(r.DO.Unscoped())
+}
+
+func (r reminderSettingDo) Create(values ...*model.ReminderSetting) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return r.DO.Create(values)
+}
+
+func (r reminderSettingDo) CreateInBatches(values []*model.ReminderSetting, batchSize int) error {
+	return r.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (r reminderSettingDo) Save(values ...*model.ReminderSetting) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return r.DO.Save(values)
+}
+
+func (r reminderSettingDo) First() (*model.ReminderSetting, error) {
+	if result, err := r.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ReminderSetting), nil
+	}
+}
+
+func (r reminderSettingDo) Take() (*model.ReminderSetting, error) {
+	if result, err := r.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ReminderSetting), nil
+	}
+}
+
+func (r reminderSettingDo) Last() (*model.ReminderSetting, error) {
+	if result, err := r.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ReminderSetting), nil
+	}
+}
+
+func (r reminderSettingDo) Find() ([]*model.ReminderSetting, error) {
+	result, err := r.DO.Find()
+	return result.([]*model.ReminderSetting), err
+}
+
+func (r reminderSettingDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.ReminderSetting, err error) {
+	buf := make([]*model.ReminderSetting, 0, batchSize)
+	err = r.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (r reminderSettingDo) FindInBatches(result *[]*model.ReminderSetting, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return r.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (r reminderSettingDo) Attrs(attrs ...field.AssignExpr) IReminderSettingDo {
+	return r.withDO(r.DO.Attrs(attrs...))
+}
+
+func (r reminderSettingDo) Assign(attrs ...field.AssignExpr) IReminderSettingDo {
+	return r.withDO(r.DO.Assign(attrs...))
+}
+
+func (r reminderSettingDo) Joins(fields ...field.RelationField) IReminderSettingDo {
+	for _, _f := range fields {
+		r = *r.withDO(r.DO.Joins(_f))
+	}
+	return &r
+}
+
+func (r reminderSettingDo) Preload(fields ...field.RelationField) IReminderSettingDo {
+	for _, _f := range fields {
+		r = *r.withDO(r.DO.Preload(_f))
+	}
+	return &r
+}
+
+func (r reminderSettingDo) FirstOrInit() (*model.ReminderSetting, error) {
+	if result, err := r.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ReminderSetting), nil
+	}
+}
+
+func (r reminderSettingDo) FirstOrCreate() (*model.ReminderSetting, error) {
+	if result, err := r.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ReminderSetting), nil
+	}
+}
+
+func (r reminderSettingDo) FindByPage(offset int, limit int) (result []*model.ReminderSetting, count int64, err error) {
+	result, err = r.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = r.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (r reminderSettingDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = r.Count()
+	if err != nil {
+		return
+	}
+
+	err = r.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (r reminderSettingDo) Scan(result interface{}) (err error) {
+	return r.DO.Scan(result)
+}
+
+func (r reminderSettingDo) Delete(models ...*model.ReminderSetting) (result gen.ResultInfo, err error) {
+	return r.DO.Delete(models)
+}
+
+func (r *reminderSettingDo) withDO(do gen.Dao) *reminderSettingDo {
+	r.DO = *do.(*gen.DO)
+	return r
+}
@@ -0,0 +1,400 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore/model"
+)
+
+func newTodoTag(db *gorm.DB, opts ...gen.DOOption) todoTag {
+	_todoTag := todoTag{}
+
+	_todoTag.todoTagDo.UseDB(db, opts...)
+	_todoTag.todoTagDo.UseModel(&model.TodoTag{})
+
+	tableName := _todoTag.todoTagDo.TableName()
+	_todoTag.ALL = field.NewAsterisk(tableName)
+	_todoTag.TodoID = field.NewInt64(tableName, "todo_id")
+	_todoTag.TagID = field.NewInt64(tableName, "tag_id")
+	_todoTag.CreatedAt = field.NewTime(tableName, "created_at")
+
+	_todoTag.fillFieldMap()
+
+	return _todoTag
+}
+
+// todoTag ToDo ã¨ã‚¿ã‚°ã®é–¢é€£
+type todoTag struct {
+	todoTagDo todoTagDo
+
+	ALL       field.Asterisk
+	TodoID    field.Int64 // ToDo ID
+	TagID     field.Int64 // ã‚¿ã‚°ID
+	CreatedAt field.Time  // ä½œæˆæ—¥æ™‚
+
+	fieldMap map[string]field.Expr
+}
+
+func (t todoTag) Table(newTableName string) *todoTag {
+	t.todoTagDo.UseTable(newTableName)
+	return t.updateTableName(newTableName)
+}
+
+func (t todoTag) As(alias string) *todoTag {
+	t.todoTagDo.DO = *(t.todoTagDo.As(alias).(*gen.DO))
+	return t.updateTableName(alias)
+}
+
+func (t *todoTag) updateTableName(table string) *todoTag {
+	t.ALL = field.NewAsterisk(table)
+	t.TodoID = field.NewInt64(table, "todo_id")
+	t.TagID = field.NewInt64(table, "tag_id")
+	t.CreatedAt = field.NewTime(table, "created_at")
+
+	t.fillFieldMap()
+
+	return t
+}
+
+func (t *todoTag) WithContext(ctx context.Context) ITodoTagDo { return t.todoTagDo.WithContext(ctx) }
+
+func (t todoTag) TableName() string { return t.todoTagDo.TableName() }
+
+func (t todoTag) Alias() string { return t.todoTagDo.Alias() }
+
+func (t todoTag) Columns(cols ...field.Expr) gen.Columns { return t.todoTagDo.Columns(cols...) }
+
+func (t *todoTag) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := t.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (t *todoTag) fillFieldMap() {
+	t.fieldMap = make(map[string]field.Expr, 3)
+	t.fieldMap["todo_id"] = t.TodoID
+	t.fieldMap["tag_id"] = t.TagID
+	t.fieldMap["created_at"] = t.CreatedAt
+}
+
+func (t todoTag) clone(db *gorm.DB) todoTag {
+	t.todoTagDo.ReplaceConnPool(db.Statement.ConnPool)
+	return t
+}
+
+func (t todoTag) replaceDB(db *gorm.DB) todoTag {
+	t.todoTagDo.ReplaceDB(db)
+	return t
+}
+
+type todoTagDo struct{ gen.DO }
+
+type ITodoTagDo interface {
+	gen.SubQuery
+	Debug() ITodoTagDo
+	WithContext(ctx context.Context) ITodoTagDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() ITodoTagDo
+	WriteDB() ITodoTagDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) ITodoTagDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) ITodoTagDo
+	Not(conds ...gen.Condition) ITodoTagDo
+	Or(conds ...gen.Condition) ITodoTagDo
+	Select(conds ...field.Expr) ITodoTagDo
+	Where(conds ...gen.Condition) ITodoTagDo
+	Order(conds ...field.Expr) ITodoTagDo
+	Distinct(cols ...field.Expr) ITodoTagDo
+	Omit(cols ...field.Expr) ITodoTagDo
+	Join(table schema.Tabler, on ...field.Expr) ITodoTagDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) ITodoTagDo
+	RightJoin(table schema.Tabler, on ...field.Expr) ITodoTagDo
+	Group(cols ...field.Expr) ITodoTagDo
+	Having(conds ...gen.Condition) ITodoTagDo
+	Limit(limit int) ITodoTagDo
+	Offset(offset int) ITodoTagDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) ITodoTagDo
+	Unscoped() ITodoTagDo
+	Create(values ...*model.TodoTag) error
+	CreateInBatches(values []*model.TodoTag, batchSize int) error
+	Save(values ...*model.TodoTag) error
+	First() (*model.TodoTag, error)
+	Take() (*model.TodoTag, error)
+	Last() (*model.TodoTag, error)
+	Find() ([]*model.TodoTag, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.TodoTag, err error)
+	FindInBatches(result *[]*model.TodoTag, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.TodoTag) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) ITodoTagDo
+	Assign(attrs ...field.AssignExpr) ITodoTagDo
+	Joins(fields ...field.RelationField) ITodoTagDo
+	Preload(fields ...field.RelationField) ITodoTagDo
+	FirstOrInit() (*model.TodoTag, error)
+	FirstOrCreate() (*model.TodoTag, error)
+	FindByPage(offset int, limit int) (result []*model.TodoTag, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Rows() (*sql.Rows, error)
+	Row() *sql.Row
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) ITodoTagDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (t todoTagDo) Debug() ITodoTagDo {
+	return t.withDO(t.DO.Debug())
+}
+
+func (t todoTagDo) WithContext(ctx context.Context) ITodoTagDo {
+	return t.withDO(t.DO.WithContext(ctx))
+}
+
+func (t todoTagDo) ReadDB() ITodoTagDo {
+	return t.Clauses(dbresolver.Read)
+}
+
+func (t todoTagDo) WriteDB() ITodoTagDo {
+	return t.Clauses(dbresolver.Write)
+}
+
+func (t todoTagDo) Session(config *gorm.Session) ITodoTagDo {
+	return t.withDO(t.DO.Session(config))
+}
+
+func (t todoTagDo) Clauses(conds ...clause.Expression) ITodoTagDo {
+	return t.withDO(t.DO.Clauses(conds...))
+}
+
+func (t todoTagDo) Returning(value interface{}, columns ...string) ITodoTagDo {
+	return t.withDO(t.DO.Returning(value, columns...))
+}
+
+func (t todoTagDo) Not(conds ...gen.Condition) ITodoTagDo {
+	return t.withDO(t.DO.Not(conds...))
+}
+
+func (t todoTagDo) Or(conds ...gen.Condition) ITodoTagDo {
+	return t.withDO(t.DO.Or(conds...))
+}
+
+func (t todoTagDo) Select(conds ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.Select(conds...))
+}
+
+func (t todoTagDo) Where(conds ...gen.Condition) ITodoTagDo {
+	return t.withDO(t.DO.Where(conds...))
+}
+
+func (t todoTagDo) Order(conds ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.Order(conds...))
+}
+
+func (t todoTagDo) Distinct(cols ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.Distinct(cols...))
+}
+
+func (t todoTagDo) Omit(cols ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.Omit(cols...))
+}
+
+func (t todoTagDo) Join(table schema.Tabler, on ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.Join(table, on...))
+}
+
+func (t todoTagDo) LeftJoin(table schema.Tabler, on ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.LeftJoin(table, on...))
+}
+
+func (t todoTagDo) RightJoin(table schema.Tabler, on ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.RightJoin(table, on...))
+}
+
+func (t todoTagDo) Group(cols ...field.Expr) ITodoTagDo {
+	return t.withDO(t.DO.Group(cols...))
+}
+
+func (t todoTagDo) Having(conds ...gen.Condition) ITodoTagDo {
+	return t.withDO(t.DO.Having(conds...))
+}
+
+func (t todoTagDo) Limit(limit int) ITodoTagDo {
+	return t.withDO(t.DO.Limit(limit))
+}
+
+func (t todoTagDo) Offset(offset int) ITodoTagDo {
+	return t.withDO(t.DO.Offset(offset))
+}
+
+func (t todoTagDo) Scopes(funcs ...func(gen.Dao) gen.Dao) ITodoTagDo {
+	return t.withDO(t.DO.Scopes(funcs...))
+}
+
+func (t todoTagDo) Unscoped() ITodoTagDo {
+	return t.withDO(t.DO.Unscoped())
+}
+
+func (t todoTagDo) Create(values ...*model.TodoTag) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.DO.Create(values)
+}
+
+func (t todoTagDo) CreateInBatches(values []*model.TodoTag, batchSize int) error {
+	return t.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (t todoTagDo) Save(values ...*model.TodoTag) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.DO.Save(values)
+}
+
+func (t todoTagDo) First() (*model.TodoTag, error) {
+	if result, err := t.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TodoTag), nil
+	}
+}
+
+func (t todoTagDo) Take() (*model.TodoTag, error) {
+	if result, err := t.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TodoTag), nil
+	}
+}
+
+func (t todoTagDo) Last() (*model.TodoTag, error) {
+	if result, err := t.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TodoTag), nil
+	}
+}
+
+func (t todoTagDo) Find() ([]*model.TodoTag, error) {
+	result, err := t.DO.Find()
+	return result.([]*model.TodoTag), err
+}
+
+func (t todoTagDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.TodoTag, err error) {
+	buf := make([]*model.TodoTag, 0, batchSize)
+	err = t.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (t todoTagDo) FindInBatches(result *[]*model.TodoTag, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return t.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (t todoTagDo) Attrs(attrs ...field.AssignExpr) ITodoTagDo {
+	return t.withDO(t.DO.Attrs(attrs...))
+}
+
+func (t todoTagDo) Assign(attrs ...field.AssignExpr) ITodoTagDo {
+	return t.withDO(t.DO.Assign(attrs...))
+}
+
+func (t todoTagDo) Joins(fields ...field.RelationField) ITodoTagDo {
+	for _, _f := range fields {
+		t = *t.withDO(t.DO.Joins(_f))
+	}
+	return &t
+}
+
+func (t todoTagDo) Preload(fields ...field.RelationField) ITodoTagDo {
+	for _, _f := range fields {
+		t = *t.withDO(t.DO.Preload(_f))
+	}
+	return &t
+}
+
+func (t todoTagDo) FirstOrInit() (*model.TodoTag, error) {
+	if result, err := t.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TodoTag), nil
+	}
+}
+
+func (t todoTagDo) FirstOrCreate() (*model.TodoTag, error) {
+	if result, err := t.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TodoTag), nil
+	}
+}
+
+func (t todoTagDo) FindByPage(offset int, limit int) (result []*model.TodoTag, count int64, err error) {
+	result, err = t.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = t.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (t todoTagDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = t.Count()
+	if err != nil {
+		return
+	}
+
+	err = t.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (t todoTagDo) Scan(result interface{}) (err error) {
+	return t.DO.Scan(result)
+}
+
+func (t todoTagDo) Delete(models ...*model.TodoTag) (result gen.ResultInfo, err error) {
+	return t.DO.Delete(models)
+}
+
+func (t *todoTagDo) withDO(do gen.Dao) *todoTagDo {
+	t.DO = *do.(*gen.DO)
+	return t
+}
@@ -36,6 +36,8 @@ func newTodo(db *gorm.DB, opts ...gen.DOOption) todo {
 	_todo.SortOrder = field.NewFloat64(tableName, "sort_order")
 	_todo.CreatedAt = field.NewTime(tableName, "created_at")
 	_todo.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_todo.DueAt = field.NewTime(tableName, "due_at")
+	_todo.RemindedAt = field.NewTime(tableName, "reminded_at")
 	_todo.ArchivedAt = field.NewTime(tableName, "archived_at")
 
 	_todo.fillFieldMap()
@@ -56,6 +58,8 @@ type todo struct {
 	SortOrder   field.Float64 // ã‚½ãƒ¼ãƒˆé †
 	CreatedAt   field.Time    // ä½œæˆæ—¥æ™‚
 	UpdatedAt   field.Time    // æ›´æ–°æ—¥æ™‚
+	DueAt       field.Time    // æœŸé™
+	RemindedAt  field.Time    // ãƒªãƒžã‚¤ãƒ³ãƒ€ãƒ¼é€ä¿¡æ—¥æ™‚
 	ArchivedAt  field.Time    // ã‚¢ãƒ¼ã‚«ã‚¤ãƒ–æ—¥æ™‚
 
 	fieldMap map[string]field.Expr
@@ -81,6 +85,8 @@ func (t *todo) updateTableName(table string) *todo {
 	t.SortOrder = field.NewFloat64(table, "sort_order")
 	t.CreatedAt = field.NewTime(table, "created_at")
 	t.UpdatedAt = field.NewTime(table, "updated_at")
+	t.DueAt = field.NewTime(table, "due_at")
+	t.RemindedAt = field.NewTime(table, "reminded_at")
 	t.ArchivedAt = field.NewTime(table, "archived_at")
 
 	t.fillFieldMap()
@@ -106,7 +112,7 @@ func (t *todo) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (t *todo) fillFieldMap() {
-	t.fieldMap = make(map[string]field.Expr, 9)
+	t.fieldMap = make(map[string]field.Expr, 11)
 	t.fieldMap["id"] = t.ID
 	t.fieldMap["user_id"] = t.UserID
 	t.fieldMap["title"] = t.Title
@@ -115,6 +121,8 @@ func (t *todo) fillFieldMap() {
 	t.fieldMap["sort_order"] = t.SortOrder
 	t.fieldMap["created_at"] = t.CreatedAt
 	t.fieldMap["updated_at"] = t.UpdatedAt
+	t.fieldMap["due_at"] = t.DueAt
+	t.fieldMap["reminded_at"] = t.RemindedAt
 	t.fieldMap["archived_at"] = t.ArchivedAt
 }
 
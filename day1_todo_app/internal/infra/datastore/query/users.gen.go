@@ -30,6 +30,7 @@ func newUser(db *gorm.DB, opts ...gen.DOOption) user {
 	_user.ALL = field.NewAsterisk(tableName)
 	_user.ID = field.NewInt64(tableName, "id")
 	_user.Name = field.NewString(tableName, "name")
+	_user.PasswordHash = field.NewString(tableName, "password_hash")
 	_user.CreatedAt = field.NewTime(tableName, "created_at")
 
 	_user.fillFieldMap()
@@ -41,10 +42,11 @@ func newUser(db *gorm.DB, opts ...gen.DOOption) user {
 type user struct {
 	userDo userDo
 
-	ALL       field.Asterisk
-	ID        field.Int64  // ãƒ¦ãƒ¼ã‚¶ãƒ¼ID
-	Name      field.String // ãƒ¦ãƒ¼ã‚¶ãƒ¼å
-	CreatedAt field.Time   // ä½œæˆæ—¥æ™‚
+	ALL          field.Asterisk
+	ID           field.Int64  // ãƒ¦ãƒ¼ã‚¶ãƒ¼ID
+	Name         field.String // ãƒ¦ãƒ¼ã‚¶ãƒ¼å
+	PasswordHash field.String // ãƒ‘ã‚¹ãƒ¯ãƒ¼ãƒ‰ãƒãƒƒã‚·ãƒ¥ (bcrypt)
+	CreatedAt    field.Time   // ä½œæˆæ—¥æ™‚
 
 	fieldMap map[string]field.Expr
 }
@@ -63,6 +65,7 @@ func (u *user) updateTableName(table string) *user {
 	u.ALL = field.NewAsterisk(table)
 	u.ID = field.NewInt64(table, "id")
 	u.Name = field.NewString(table, "name")
+	u.PasswordHash = field.NewString(table, "password_hash")
 	u.CreatedAt = field.NewTime(table, "created_at")
 
 	u.fillFieldMap()
@@ -88,9 +91,10 @@ func (u *user) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (u *user) fillFieldMap() {
-	u.fieldMap = make(map[string]field.Expr, 3)
+	u.fieldMap = make(map[string]field.Expr, 4)
 	u.fieldMap["id"] = u.ID
 	u.fieldMap["name"] = u.Name
+	u.fieldMap["password_hash"] = u.PasswordHash
 	u.fieldMap["created_at"] = u.CreatedAt
 }
 
@@ -0,0 +1,121 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	domainModel "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+	domainRepo "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore/model"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore/query"
+	"github.com/m-mizutani/goerr"
+)
+
+// reminderSettingRepository は domainRepo.ReminderSettingRepository の実装です。
+type reminderSettingRepository struct {
+	q      *query.Query
+	logger *slog.Logger
+}
+
+// NewReminderSettingRepository は新しい reminderSettingRepository を生成します。
+func NewReminderSettingRepository(db *gorm.DB) domainRepo.ReminderSettingRepository {
+	return &reminderSettingRepository{
+		q:      query.Use(db),
+		logger: slog.Default().WithGroup("repository.reminder_setting"),
+	}
+}
+
+// FindByUserID は指定されたユーザーのリマインダー設定を取得します。
+func (repo *reminderSettingRepository) FindByUserID(ctx context.Context, userID int64) (*domainModel.ReminderSetting, error) {
+	repo.logger.DebugContext(ctx, "finding reminder setting by user id in repository", "userID", userID)
+
+	r := repo.q.ReminderSetting
+	result, err := repo.q.ReminderSetting.WithContext(ctx).Where(r.UserID.Eq(userID)).First()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			repo.logger.InfoContext(ctx, "reminder setting not found in repository", "userID", userID)
+			return nil, nil
+		}
+		repo.logger.ErrorContext(ctx, "failed to execute find reminder setting query", "error", err, "userID", userID)
+		return nil, goerr.Wrap(err, "failed to find reminder setting in DB").With("userID", userID)
+	}
+	return toDomainReminderSetting(result), nil
+}
+
+// FindEnabled はリマインダーが有効なすべての設定を取得します。
+func (repo *reminderSettingRepository) FindEnabled(ctx context.Context) ([]*domainModel.ReminderSetting, error) {
+	repo.logger.DebugContext(ctx, "finding enabled reminder settings in repository")
+
+	r := repo.q.ReminderSetting
+	results, err := repo.q.ReminderSetting.WithContext(ctx).Where(r.Enabled.Is(true)).Find()
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute find enabled reminder settings query", "error", err)
+		return nil, goerr.Wrap(err, "failed to find enabled reminder settings in DB")
+	}
+
+	settings := make([]*domainModel.ReminderSetting, 0, len(results))
+	for _, m := range results {
+		settings = append(settings, toDomainReminderSetting(m))
+	}
+	repo.logger.DebugContext(ctx, "found enabled reminder settings successfully in repository", "count", len(settings))
+	return settings, nil
+}
+
+// Save はリマインダー設定を作成または更新 (upsert) します。
+func (repo *reminderSettingRepository) Save(ctx context.Context, setting *domainModel.ReminderSetting) error {
+	repo.logger.DebugContext(ctx, "saving reminder setting in repository", "userID", setting.UserID)
+
+	m := toGormReminderSetting(setting)
+	err := repo.q.ReminderSetting.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "minutes_before", "webhook_url", "email"}),
+	}).Create(m)
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute save reminder setting query", "error", err, "userID", setting.UserID)
+		return goerr.Wrap(err, "failed to save reminder setting in DB").With("userID", setting.UserID)
+	}
+	return nil
+}
+
+// toDomainReminderSetting は GORM Gen モデルをドメインモデルに変換します。
+func toDomainReminderSetting(m *model.ReminderSetting) *domainModel.ReminderSetting {
+	if m == nil {
+		return nil
+	}
+	var webhookURL, email string
+	if m.WebhookURL != nil {
+		webhookURL = *m.WebhookURL
+	}
+	if m.Email != nil {
+		email = *m.Email
+	}
+	return &domainModel.ReminderSetting{
+		UserID:        m.UserID,
+		Enabled:       m.Enabled,
+		MinutesBefore: int(m.MinutesBefore),
+		WebhookURL:    webhookURL,
+		Email:         email,
+	}
+}
+
+// toGormReminderSetting はドメインモデルを GORM Gen モデルに変換します。
+func toGormReminderSetting(d *domainModel.ReminderSetting) *model.ReminderSetting {
+	m := &model.ReminderSetting{
+		UserID:        d.UserID,
+		Enabled:       d.Enabled,
+		MinutesBefore: int32(d.MinutesBefore),
+	}
+	if d.WebhookURL != "" {
+		url := d.WebhookURL
+		m.WebhookURL = &url
+	}
+	if d.Email != "" {
+		email := d.Email
+		m.Email = &email
+	}
+	return m
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
+	"gorm.io/gen/field"
 	"gorm.io/gorm"
 
 	domainModel "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
@@ -83,11 +85,17 @@ func (repo *todoRepository) FindByID(ctx context.Context, id int64) (*domainMode
 		return nil, goerr.Wrap(err, "failed to find todo by id in DB").With("id", id)
 	}
 	repo.logger.DebugContext(ctx, "todo found successfully by id in repository", "id", id, "result", result)
-	return toDomainTodo(result), nil
+
+	todo := toDomainTodo(result)
+	if err := repo.loadTags(ctx, []*domainModel.Todo{todo}); err != nil {
+		return nil, err
+	}
+	return todo, nil
 }
 
 // Find は指定されたユーザーの ToDo を検索します。
-func (repo *todoRepository) Find(ctx context.Context, params domainRepo.FindTodosParams) ([]*domainModel.Todo, error) {
+// 戻り値の int64 はページネーション適用前の総件数です。
+func (repo *todoRepository) Find(ctx context.Context, params domainRepo.FindTodosParams) ([]*domainModel.Todo, int64, error) {
 	repo.logger.DebugContext(ctx, "finding todos in repository", "params", params)
 
 	t := repo.q.Todo
@@ -98,27 +106,107 @@ func (repo *todoRepository) Find(ctx context.Context, params domainRepo.FindTodo
 		query = query.Where(t.ArchivedAt.IsNull())
 	}
 
-	// ページネーション
-	if params.Limit > 0 {
-		query = query.Limit(params.Limit)
+	// ステータスでの絞り込み
+	if params.Status != nil {
+		query = query.Where(t.Status.Eq(string(*params.Status)))
 	}
-	if params.Page > 0 {
-		// 1-indexed page to 0-indexed offset
-		offset := (params.Page - 1) * params.Limit
-		query = query.Offset(offset)
+
+	// 期限切れ (期限を過ぎた未完了の ToDo) での絞り込み
+	if params.OverdueAt != nil {
+		query = query.Where(t.DueAt.Lt(*params.OverdueAt), t.Status.NotIn(closedStatuses()...))
 	}
 
-	// ソート順: sort_order 昇順, created_at 降順 (仮)
-	// TODO: デフォルトソート順を UseCase/Domain 層で決定できるようにする
-	query = query.Order(t.SortOrder.Asc(), t.CreatedAt.Desc())
+	// タグでの絞り込み (todo_tags のサブクエリ)
+	if params.TagID != nil {
+		tt := repo.q.TodoTag
+		query = query.Where(t.Columns(t.ID).In(repo.q.TodoTag.WithContext(ctx).Select(tt.TodoID).Where(tt.TagID.Eq(*params.TagID))))
+	}
+
+	// キーワード検索 (タイトル or 詳細の部分一致)
+	if params.Query != "" {
+		pattern := "%" + escapeLike(params.Query) + "%"
+		query = query.Where(field.Or(likeEscaped(t.Title, pattern), likeEscaped(t.Description, pattern)))
+	}
+
+	// ソート順
+	switch params.Sort {
+	case domainRepo.TodoSortCreatedAt:
+		query = query.Order(t.CreatedAt.Desc(), t.ID.Desc())
+	case domainRepo.TodoSortDueDate:
+		// 期限なし (NULL) は末尾に回す
+		query = query.Order(t.DueAt.IsNull(), t.DueAt, t.ID)
+	default:
+		// sort_order 昇順, created_at 降順 (手動並び替え順)
+		query = query.Order(t.SortOrder.Asc(), t.CreatedAt.Desc())
+	}
 
-	results, err := query.Find()
+	// ページネーション (FindByPage は limit <= 0 の場合は全件取得し、総件数も返す)
+	offset := 0
+	if params.Page > 0 && params.Limit > 0 {
+		// 1-indexed page to 0-indexed offset
+		offset = (params.Page - 1) * params.Limit
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+
+	results, total, err := query.FindByPage(offset, limit)
 	if err != nil {
 		repo.logger.ErrorContext(ctx, "failed to execute find query", "error", err, "params", params)
-		return nil, goerr.Wrap(err, "failed to find todos in DB").With("params", params)
+		return nil, 0, goerr.Wrap(err, "failed to find todos in DB").With("params", params)
 	}
-	repo.logger.DebugContext(ctx, "found todos successfully in repository", "count", len(results))
-	return toDomainTodos(results), nil
+	repo.logger.DebugContext(ctx, "found todos successfully in repository", "count", len(results), "total", total)
+
+	todos := toDomainTodos(results)
+	if err := repo.loadTags(ctx, todos); err != nil {
+		return nil, 0, err
+	}
+	return todos, total, nil
+}
+
+// todoTagRow は ToDo ID 付きでタグを読み込むための行です。
+type todoTagRow struct {
+	model.Tag
+	TodoID int64
+}
+
+// loadTags は ToDo に付与されているタグをまとめて読み込み、各 ToDo の Tags に設定します。
+func (repo *todoRepository) loadTags(ctx context.Context, todos []*domainModel.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(todos))
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+	}
+
+	tg := repo.q.Tag
+	tt := repo.q.TodoTag
+	var rows []todoTagRow
+	err := repo.q.Tag.WithContext(ctx).
+		Select(tg.ALL, tt.TodoID).
+		Join(tt, tt.TagID.EqCol(tg.ID)).
+		Where(tt.TodoID.In(ids...)).
+		Order(tg.Name).
+		Scan(&rows)
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute load tags query", "error", err, "todoIDs", ids)
+		return goerr.Wrap(err, "failed to load todo tags from DB")
+	}
+
+	tagsByTodo := make(map[int64][]*domainModel.Tag, len(todos))
+	for i := range rows {
+		tagsByTodo[rows[i].TodoID] = append(tagsByTodo[rows[i].TodoID], toDomainTag(&rows[i].Tag))
+	}
+	for _, todo := range todos {
+		todo.Tags = tagsByTodo[todo.ID]
+		if todo.Tags == nil {
+			todo.Tags = []*domainModel.Tag{}
+		}
+	}
+	return nil
 }
 
 // Create は新しい ToDo を作成します。
@@ -190,6 +278,39 @@ func (repo *todoRepository) Unarchive(ctx context.Context, id int64) error {
 	return nil
 }
 
+// FindReminderTargets は期限が (from, to] の範囲にあり、リマインダー未送信の未完了 ToDo を取得します。
+func (repo *todoRepository) FindReminderTargets(ctx context.Context, userID int64, from, to time.Time) ([]*domainModel.Todo, error) {
+	repo.logger.DebugContext(ctx, "finding reminder targets in repository", "userID", userID, "from", from, "to", to)
+
+	t := repo.q.Todo
+	results, err := repo.q.Todo.WithContext(ctx).Where(
+		t.UserID.Eq(userID),
+		t.DueAt.Gt(from),
+		t.DueAt.Lte(to),
+		t.RemindedAt.IsNull(),
+		t.ArchivedAt.IsNull(),
+		t.Status.NotIn(closedStatuses()...),
+	).Order(t.DueAt).Find()
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute find reminder targets query", "error", err, "userID", userID)
+		return nil, goerr.Wrap(err, "failed to find reminder targets in DB").With("userID", userID)
+	}
+	repo.logger.DebugContext(ctx, "found reminder targets successfully in repository", "userID", userID, "count", len(results))
+	return toDomainTodos(results), nil
+}
+
+// MarkReminded は ToDo のリマインダー送信日時を記録します。
+func (repo *todoRepository) MarkReminded(ctx context.Context, id int64, at time.Time) error {
+	repo.logger.DebugContext(ctx, "marking todo as reminded in repository", "id", id, "at", at)
+
+	t := repo.q.Todo
+	if _, err := repo.q.Todo.WithContext(ctx).Where(t.ID.Eq(id)).Update(t.RemindedAt, at); err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute mark reminded query", "error", err, "id", id)
+		return goerr.Wrap(err, "failed to mark todo as reminded in DB").With("id", id)
+	}
+	return nil
+}
+
 // UpdateSortOrders は複数の ToDo の sort_order を一括で更新します。
 func (repo *todoRepository) UpdateSortOrders(ctx context.Context, userID int64, orders []domainRepo.UpdateTodoOrderParams) error {
 	repo.logger.DebugContext(ctx, "updating todo sort orders in repository", "userID", userID, "orderCount", len(orders))
@@ -281,6 +402,27 @@ func (repo *userRepository) FindByName(ctx context.Context, name string) (*domai
 	return toDomainUser(result), nil
 }
 
+// Create は新しいユーザーを作成します。
+func (repo *userRepository) Create(ctx context.Context, user *domainModel.User) error {
+	repo.logger.DebugContext(ctx, "creating user in repository", "name", user.Name)
+
+	m := &model.User{
+		Name:         user.Name,
+		PasswordHash: user.PasswordHash,
+	}
+	if err := repo.q.User.WithContext(ctx).Create(m); err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute create user query", "error", err, "name", user.Name)
+		return goerr.Wrap(err, "failed to create user in DB").With("name", user.Name)
+	}
+
+	user.ID = m.ID
+	if m.CreatedAt != nil {
+		user.CreatedAt = *m.CreatedAt
+	}
+	repo.logger.DebugContext(ctx, "user created successfully in repository", "id", user.ID)
+	return nil
+}
+
 // --- ヘルパー関数 ---
 
 // toDomainTodo は GORM Gen モデルをドメインモデルに変換します。
@@ -318,6 +460,8 @@ func toDomainTodo(m *model.Todo) *domainModel.Todo {
 		Status:      status, // 変換した Status を設定
 		SortOrder:   m.SortOrder,
 		CreatedAt:   createdAt,
+		DueAt:       m.DueAt,
+		RemindedAt:  m.RemindedAt,
 		ArchivedAt:  m.ArchivedAt,
 	}
 }
@@ -352,6 +496,8 @@ func toGormTodo(d *domainModel.Todo) *model.Todo {
 		Status:      status, // 変換した Status (string) を設定
 		SortOrder:   d.SortOrder,
 		CreatedAt:   createdAt,
+		DueAt:       d.DueAt,
+		RemindedAt:  d.RemindedAt,
 		ArchivedAt:  d.ArchivedAt,
 	}
 }
@@ -371,15 +517,40 @@ func toDomainTodos(ms []*model.Todo) []*domainModel.Todo {
 	return ds
 }
 
+// closedStatuses は期限切れ・リマインダーの対象外となるステータスの一覧を返します。
+func closedStatuses() []string {
+	return []string{string(domainModel.TodoStatusDone), string(domainModel.TodoStatusCancel)}
+}
+
+// escapeLike は LIKE 句のワイルドカード文字をエスケープします。
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likeEscaped は escapeLike でエスケープしたパターンで column を LIKE 検索する条件を返します。
+// MySQL は \ を暗黙のエスケープ文字として扱いますが SQLite にはないため、ESCAPE 句で明示します。
+// '\' は MySQL と SQLite で文字列リテラルの解釈が異なるため、エスケープ文字もパラメータで渡します。
+func likeEscaped(column field.String, pattern string) field.Expr {
+	return field.NewUnsafeFieldRaw("? LIKE ? ESCAPE ?", column.RawExpr(), pattern, `\`)
+}
+
 // toDomainUser は GORM Gen の User モデルをドメインモデルに変換します。
 func toDomainUser(m *model.User) *domainModel.User {
 	if m == nil {
 		return nil
 	}
 	// Name は string 型なのでポインタチェックは不要
+	var createdAt time.Time
+	if m.CreatedAt != nil {
+		createdAt = *m.CreatedAt
+	}
 	return &domainModel.User{
-		ID:   m.ID,
-		Name: m.Name,
+		ID:           m.ID,
+		Name:         m.Name,
+		PasswordHash: m.PasswordHash,
+		CreatedAt:    createdAt,
 	}
 }
 
@@ -0,0 +1,66 @@
+package datastore
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	domainModel "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+	domainRepo "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
+)
+
+// TestTodoRepository_Find_QueryEscapesWildcards は SQLite でキーワードの % と _ が文字として検索されることを確認します。
+func TestTodoRepository_Find_QueryEscapesWildcards(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDB(DBConfig{Driver: DriverSQLite, SQLitePath: filepath.Join(t.TempDir(), "todo.db")})
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+
+	user := &domainModel.User{Name: "alice", PasswordHash: "hash"}
+	if err := NewUserRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	repo := NewTodoRepository(db)
+	for _, title := range []string{"50% off", "500 items", "snake_case", "snakeXcase", `back\slash`} {
+		todo := &domainModel.Todo{UserID: user.ID, Title: title, Status: domainModel.TodoStatusNotStarted}
+		if err := repo.Create(ctx, todo); err != nil {
+			t.Fatalf("create todo %q: %v", title, err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "50%", want: []string{"50% off"}},
+		{query: "_", want: []string{"snake_case"}},
+		{query: "e_c", want: []string{"snake_case"}},
+		{query: `\`, want: []string{`back\slash`}},
+		{query: "snake", want: []string{"snakeXcase", "snake_case"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			todos, total, err := repo.Find(ctx, domainRepo.FindTodosParams{UserID: user.ID, Limit: 10, Page: 1, Query: tt.query})
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			got := make([]string, len(todos))
+			for i, todo := range todos {
+				got[i] = todo.Title
+			}
+			sort.Strings(got)
+			if int(total) != len(tt.want) || len(got) != len(tt.want) {
+				t.Fatalf("Find(%q) = %v (total %d), want %v", tt.query, got, total, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Find(%q) = %v, want %v", tt.query, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
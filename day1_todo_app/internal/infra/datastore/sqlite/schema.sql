@@ -0,0 +1,86 @@
+-- day1_todo_app/internal/infra/datastore/sqlite/schema.sql
+-- docker/mysql/initdb.d/schema.sql と同じテーブルを SQLite 向けに定義したもの
+-- DB_DRIVER=sqlite の場合、起動時に毎回実行される (すべて IF NOT EXISTS なので冪等)
+-- MySQL との違い:
+--   - AUTO_INCREMENT は INTEGER PRIMARY KEY AUTOINCREMENT、ENUM は CHECK 制約で表現する
+--   - ON UPDATE CURRENT_TIMESTAMP はトリガーで表現する
+--   - カラム・テーブルのコメントは SQL コメントで残す
+-- ユーザーテーブル
+CREATE TABLE IF NOT EXISTS users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT, -- ユーザーID
+  name VARCHAR(255) NOT NULL UNIQUE, -- ユーザー名
+  password_hash VARCHAR(255) NOT NULL DEFAULT '', -- パスワードハッシュ (bcrypt)
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- 作成日時
+);
+-- ToDo テーブル
+CREATE TABLE IF NOT EXISTS todos (
+  id INTEGER PRIMARY KEY AUTOINCREMENT, -- ToDo ID
+  user_id BIGINT NOT NULL, -- ユーザーID
+  title VARCHAR(255) NOT NULL, -- タイトル
+  description TEXT, -- 詳細
+  status VARCHAR(20) NOT NULL DEFAULT 'not started' CHECK (
+    status IN (
+      'not started',
+      'in progress',
+      'done',
+      'pending',
+      'cancel'
+    )
+  ), -- 状態
+  sort_order DOUBLE NOT NULL DEFAULT 0, -- ソート順
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- 作成日時
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, -- 更新日時
+  due_at TIMESTAMP NULL DEFAULT NULL, -- 期限
+  reminded_at TIMESTAMP NULL DEFAULT NULL, -- リマインダー送信日時
+  archived_at TIMESTAMP NULL DEFAULT NULL, -- アーカイブ日時
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_archived_sort ON todos (user_id, archived_at, sort_order);
+CREATE INDEX IF NOT EXISTS idx_user_created ON todos (user_id, created_at); -- デフォルトソート用インデックス
+CREATE INDEX IF NOT EXISTS idx_user_due ON todos (user_id, due_at); -- 期限ソート用インデックス
+CREATE TRIGGER IF NOT EXISTS trg_todos_updated_at
+AFTER UPDATE ON todos FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+  UPDATE todos SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+-- リマインダー設定テーブル (ユーザーごと)
+CREATE TABLE IF NOT EXISTS reminder_settings (
+  user_id BIGINT PRIMARY KEY, -- ユーザーID
+  enabled BOOLEAN NOT NULL DEFAULT FALSE, -- リマインダーを送信するか
+  minutes_before INT NOT NULL DEFAULT 30, -- 期限の何分前に通知するか
+  webhook_url VARCHAR(2048) NULL DEFAULT NULL, -- 通知先 Webhook URL
+  email VARCHAR(255) NULL DEFAULT NULL, -- 通知先メールアドレス
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- 作成日時
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, -- 更新日時
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE TRIGGER IF NOT EXISTS trg_reminder_settings_updated_at
+AFTER UPDATE ON reminder_settings FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+  UPDATE reminder_settings SET updated_at = CURRENT_TIMESTAMP WHERE user_id = NEW.user_id;
+END;
+-- タグテーブル (ユーザーごと)
+CREATE TABLE IF NOT EXISTS tags (
+  id INTEGER PRIMARY KEY AUTOINCREMENT, -- タグID
+  user_id BIGINT NOT NULL, -- ユーザーID
+  name VARCHAR(50) NOT NULL, -- タグ名
+  color VARCHAR(7) NULL DEFAULT NULL, -- タグの色 (#RRGGBB)
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- 作成日時
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  UNIQUE (user_id, name) -- 同じユーザー内でタグ名は一意
+);
+-- ToDo とタグの関連テーブル (多対多)
+CREATE TABLE IF NOT EXISTS todo_tags (
+  todo_id BIGINT NOT NULL, -- ToDo ID
+  tag_id BIGINT NOT NULL, -- タグID
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- 作成日時
+  PRIMARY KEY (todo_id, tag_id),
+  FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+  FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_tag ON todo_tags (tag_id); -- タグでの絞り込み用インデックス
+-- 初期ユーザーデータ投入
+INSERT OR IGNORE INTO users (name)
+VALUES ('User A'),
+  ('User B'),
+  ('User C');
@@ -0,0 +1,211 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	domainModel "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+	domainRepo "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore/model"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore/query"
+	"github.com/m-mizutani/goerr"
+)
+
+// tagRepository は domainRepo.TagRepository の実装です。
+type tagRepository struct {
+	q      *query.Query
+	logger *slog.Logger
+}
+
+// NewTagRepository は新しい tagRepository を生成します。
+func NewTagRepository(db *gorm.DB) domainRepo.TagRepository {
+	return &tagRepository{
+		q:      query.Use(db),
+		logger: slog.Default().WithGroup("repository.tag"),
+	}
+}
+
+// FindByUserID は指定されたユーザーのタグを名前順で取得します。
+func (repo *tagRepository) FindByUserID(ctx context.Context, userID int64) ([]*domainModel.Tag, error) {
+	repo.logger.DebugContext(ctx, "finding tags by user id in repository", "userID", userID)
+
+	t := repo.q.Tag
+	results, err := repo.q.Tag.WithContext(ctx).Where(t.UserID.Eq(userID)).Order(t.Name).Find()
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute find tags query", "error", err, "userID", userID)
+		return nil, goerr.Wrap(err, "failed to find tags in DB").With("userID", userID)
+	}
+	return toDomainTags(results), nil
+}
+
+// FindByID は指定されたIDのタグを取得します。
+func (repo *tagRepository) FindByID(ctx context.Context, id int64) (*domainModel.Tag, error) {
+	repo.logger.DebugContext(ctx, "finding tag by id in repository", "id", id)
+
+	t := repo.q.Tag
+	result, err := repo.q.Tag.WithContext(ctx).Where(t.ID.Eq(id)).First()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			repo.logger.InfoContext(ctx, "tag not found in repository", "id", id)
+			return nil, nil
+		}
+		repo.logger.ErrorContext(ctx, "failed to execute find tag by id query", "error", err, "id", id)
+		return nil, goerr.Wrap(err, "failed to find tag by id in DB").With("id", id)
+	}
+	return toDomainTag(result), nil
+}
+
+// FindByName は指定されたユーザーの同名のタグを取得します。
+func (repo *tagRepository) FindByName(ctx context.Context, userID int64, name string) (*domainModel.Tag, error) {
+	repo.logger.DebugContext(ctx, "finding tag by name in repository", "userID", userID, "name", name)
+
+	t := repo.q.Tag
+	result, err := repo.q.Tag.WithContext(ctx).Where(t.UserID.Eq(userID), t.Name.Eq(name)).First()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		repo.logger.ErrorContext(ctx, "failed to execute find tag by name query", "error", err, "userID", userID, "name", name)
+		return nil, goerr.Wrap(err, "failed to find tag by name in DB").With("userID", userID).With("name", name)
+	}
+	return toDomainTag(result), nil
+}
+
+// Create は新しいタグを作成します。
+func (repo *tagRepository) Create(ctx context.Context, tag *domainModel.Tag) error {
+	repo.logger.DebugContext(ctx, "creating tag in repository", "userID", tag.UserID, "name", tag.Name)
+
+	m := toGormTag(tag)
+	if err := repo.q.Tag.WithContext(ctx).Create(m); err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute create tag query", "error", err, "userID", tag.UserID, "name", tag.Name)
+		return goerr.Wrap(err, "failed to create tag in DB").With("userID", tag.UserID).With("name", tag.Name)
+	}
+
+	tag.ID = m.ID
+	if m.CreatedAt != nil {
+		tag.CreatedAt = *m.CreatedAt
+	}
+	repo.logger.DebugContext(ctx, "tag created successfully in repository", "id", tag.ID)
+	return nil
+}
+
+// Update はタグの名前と色を更新します。
+func (repo *tagRepository) Update(ctx context.Context, tag *domainModel.Tag) error {
+	repo.logger.DebugContext(ctx, "updating tag in repository", "id", tag.ID, "userID", tag.UserID)
+
+	t := repo.q.Tag
+	m := toGormTag(tag)
+	_, err := repo.q.Tag.WithContext(ctx).Where(t.ID.Eq(tag.ID), t.UserID.Eq(tag.UserID)).
+		Updates(map[string]interface{}{"name": m.Name, "color": m.Color})
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute update tag query", "error", err, "id", tag.ID)
+		return goerr.Wrap(err, "failed to update tag in DB").With("id", tag.ID)
+	}
+	return nil
+}
+
+// Delete は指定された ID と UserID のタグを、ToDo へのタグ付けとあわせて削除します。
+func (repo *tagRepository) Delete(ctx context.Context, id int64, userID int64) error {
+	repo.logger.DebugContext(ctx, "deleting tag in repository", "id", id, "userID", userID)
+
+	err := repo.q.Transaction(func(tx *query.Query) error {
+		t := tx.Tag
+		tt := tx.TodoTag
+		if _, err := tx.TodoTag.WithContext(ctx).Where(tt.TagID.Eq(id)).Delete(); err != nil {
+			return goerr.Wrap(err, "failed to delete todo tags").With("tagID", id)
+		}
+		result, err := tx.Tag.WithContext(ctx).Where(t.ID.Eq(id), t.UserID.Eq(userID)).Delete()
+		if err != nil {
+			return goerr.Wrap(err, "failed to delete tag").With("id", id)
+		}
+		if result.RowsAffected == 0 {
+			return goerr.New("tag not found or delete permission denied").With("id", id).With("userID", userID)
+		}
+		return nil
+	})
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "transaction failed for deleting tag", "error", err, "id", id, "userID", userID)
+		return err
+	}
+	return nil
+}
+
+// Attach は ToDo にタグを付与します。すでに付与されている場合は何もしません。
+func (repo *tagRepository) Attach(ctx context.Context, todoID int64, tagID int64) error {
+	repo.logger.DebugContext(ctx, "attaching tag in repository", "todoID", todoID, "tagID", tagID)
+
+	now := time.Now()
+	err := repo.q.TodoTag.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.TodoTag{TodoID: todoID, TagID: tagID, CreatedAt: &now})
+	if err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute attach tag query", "error", err, "todoID", todoID, "tagID", tagID)
+		return goerr.Wrap(err, "failed to attach tag in DB").With("todoID", todoID).With("tagID", tagID)
+	}
+	return nil
+}
+
+// Detach は ToDo からタグを外します。付与されていない場合は何もしません。
+func (repo *tagRepository) Detach(ctx context.Context, todoID int64, tagID int64) error {
+	repo.logger.DebugContext(ctx, "detaching tag in repository", "todoID", todoID, "tagID", tagID)
+
+	tt := repo.q.TodoTag
+	if _, err := repo.q.TodoTag.WithContext(ctx).Where(tt.TodoID.Eq(todoID), tt.TagID.Eq(tagID)).Delete(); err != nil {
+		repo.logger.ErrorContext(ctx, "failed to execute detach tag query", "error", err, "todoID", todoID, "tagID", tagID)
+		return goerr.Wrap(err, "failed to detach tag in DB").With("todoID", todoID).With("tagID", tagID)
+	}
+	return nil
+}
+
+// toDomainTag は GORM Gen モデルをドメインモデルに変換します。
+func toDomainTag(m *model.Tag) *domainModel.Tag {
+	if m == nil {
+		return nil
+	}
+	var color string
+	if m.Color != nil {
+		color = *m.Color
+	}
+	var createdAt time.Time
+	if m.CreatedAt != nil {
+		createdAt = *m.CreatedAt
+	}
+	return &domainModel.Tag{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Name:      m.Name,
+		Color:     color,
+		CreatedAt: createdAt,
+	}
+}
+
+// toDomainTags は GORM Gen モデルのスライスをドメインモデルのスライスに変換します。
+func toDomainTags(ms []*model.Tag) []*domainModel.Tag {
+	ds := make([]*domainModel.Tag, 0, len(ms))
+	for _, m := range ms {
+		ds = append(ds, toDomainTag(m))
+	}
+	return ds
+}
+
+// toGormTag はドメインモデルを GORM Gen モデルに変換します。
+func toGormTag(d *domainModel.Tag) *model.Tag {
+	m := &model.Tag{
+		ID:     d.ID,
+		UserID: d.UserID,
+		Name:   d.Name,
+	}
+	if d.Color != "" {
+		color := d.Color
+		m.Color = &color
+	}
+	if !d.CreatedAt.IsZero() {
+		createdAt := d.CreatedAt
+		m.CreatedAt = &createdAt
+	}
+	return m
+}
@@ -0,0 +1,57 @@
+// internal/infra/notifier/email.go
+package notifier
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/m-mizutani/goerr"
+)
+
+// SMTPConfig は SMTP サーバーの接続設定です。
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string // 空の場合は認証しない
+	Password string
+	From     string
+}
+
+// SMTPSender は SMTP でテキストメールを送信します。
+type SMTPSender struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPSender は新しい SMTPSender を生成します。Host が空の場合は nil を返します。
+func NewSMTPSender(cfg SMTPConfig) *SMTPSender {
+	if cfg.Host == "" {
+		return nil
+	}
+	return &SMTPSender{cfg: cfg}
+}
+
+// Send は to 宛てにメールを送信します。
+func (s *SMTPSender) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	if err := smtp.SendMail(addr, auth, s.cfg.From, []string{to}, []byte(msg.String())); err != nil {
+		return goerr.Wrap(err, "failed to send email").With("to", to).With("smtpAddr", addr)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// internal/infra/notifier/notifier.go
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	domainModel "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+	"github.com/m-mizutani/goerr"
+)
+
+// ReminderNotifier は usecase.ReminderNotifier の実装です。
+// リマインダー設定に登録された Webhook とメールアドレスの両方に通知します。
+type ReminderNotifier struct {
+	webhook *WebhookSender
+	email   *SMTPSender // SMTP が設定されていない場合は nil
+	logger  *slog.Logger
+}
+
+// NewReminderNotifier は新しい ReminderNotifier を生成します。
+// email が nil の場合、メールの通知先は無視されます。
+func NewReminderNotifier(webhook *WebhookSender, email *SMTPSender) *ReminderNotifier {
+	return &ReminderNotifier{
+		webhook: webhook,
+		email:   email,
+		logger:  slog.Default().WithGroup("notifier.reminder"),
+	}
+}
+
+// reminderPayload は Webhook に POST するリマインダーの JSON です。
+type reminderPayload struct {
+	Event       string    `json:"event"`
+	TodoID      int64     `json:"todo_id"`
+	UserID      int64     `json:"user_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Status      string    `json:"status"`
+	DueAt       time.Time `json:"due_at"`
+}
+
+// NotifyReminder は ToDo のリマインダーを通知します。
+// どちらかの通知先で失敗した場合はエラーを返します。
+func (n *ReminderNotifier) NotifyReminder(ctx context.Context, setting *domainModel.ReminderSetting, todo *domainModel.Todo) error {
+	if todo.DueAt == nil {
+		return goerr.New("todo has no due date").With("todoID", todo.ID)
+	}
+
+	var errs []error
+	if setting.WebhookURL != "" {
+		payload := reminderPayload{
+			Event:       "todo.reminder",
+			TodoID:      todo.ID,
+			UserID:      todo.UserID,
+			Title:       todo.Title,
+			Description: todo.Description,
+			Status:      string(todo.Status),
+			DueAt:       *todo.DueAt,
+		}
+		if err := n.webhook.Send(ctx, setting.WebhookURL, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if setting.Email != "" {
+		if n.email == nil {
+			n.logger.WarnContext(ctx, "smtp is not configured, skipping email reminder", "todoID", todo.ID, "userID", setting.UserID)
+		} else if err := n.email.Send(setting.Email, reminderSubject(todo), reminderBody(todo)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func reminderSubject(todo *domainModel.Todo) string {
+	return fmt.Sprintf("[ToDo] Reminder: %s", todo.Title)
+}
+
+func reminderBody(todo *domainModel.Todo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ToDo \"%s\" is due at %s.\n", todo.Title, todo.DueAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Status: %s\n", todo.Status)
+	if todo.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", todo.Description)
+	}
+	return b.String()
+}
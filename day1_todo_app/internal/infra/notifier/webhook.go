@@ -0,0 +1,51 @@
+// internal/infra/notifier/webhook.go
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/m-mizutani/goerr"
+)
+
+// WebhookSender は JSON を Webhook URL に POST します。
+type WebhookSender struct {
+	client *http.Client
+}
+
+// NewWebhookSender は新しい WebhookSender を生成します。
+func NewWebhookSender(timeout time.Duration) *WebhookSender {
+	return &WebhookSender{
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Send は payload を JSON にして url に POST します。2xx 以外のレスポンスはエラーとします。
+func (s *WebhookSender) Send(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return goerr.Wrap(err, "failed to marshal webhook payload")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return goerr.Wrap(err, "failed to create webhook request").With("url", url)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return goerr.Wrap(err, "failed to send webhook").With("url", url)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) // コネクション再利用のため読み捨てる
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return goerr.New("webhook returned non-2xx status").With("url", url).With("status", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/usecase"
+)
+
+// ReminderScheduler は一定間隔でリマインダー送信処理を実行するバックグラウンドジョブです。
+type ReminderScheduler struct {
+	reminderUsecase usecase.ReminderUsecase
+	interval        time.Duration
+	logger          *slog.Logger
+}
+
+// NewReminderScheduler は新しい ReminderScheduler を生成します。
+func NewReminderScheduler(ru usecase.ReminderUsecase, interval time.Duration) *ReminderScheduler {
+	return &ReminderScheduler{
+		reminderUsecase: ru,
+		interval:        interval,
+		logger:          slog.Default().WithGroup("scheduler.reminder"),
+	}
+}
+
+// Run は ctx がキャンセルされるまでリマインダー送信処理を繰り返します。
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	s.logger.Info("reminder scheduler started", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("reminder scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ReminderScheduler) tick(ctx context.Context) {
+	if _, err := s.reminderUsecase.SendDueReminders(ctx, time.Now()); err != nil {
+		// 失敗しても次の実行で再試行するため、ログに残して継続する
+		s.logger.ErrorContext(ctx, "failed to send due reminders", "error", err)
+	}
+}
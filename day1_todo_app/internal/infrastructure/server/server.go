@@ -2,11 +2,15 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/auth"
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/datastore"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infra/notifier"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/infrastructure/scheduler"
 	apiHandler "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/interface/handler"
 	webHandler "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/interface/handler"
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/usecase"
@@ -17,11 +21,27 @@ import (
 type Server struct {
 	httpServer *http.Server
 	db         *gorm.DB
+	scheduler  *scheduler.ReminderScheduler
 }
 
 type Config struct {
-	Addr   string
-	DBConf datastore.DBConfig
+	Addr     string
+	DBConf   datastore.DBConfig
+	Reminder ReminderConfig
+	Auth     AuthConfig
+}
+
+// AuthConfig は JWT 認証の設定です。
+type AuthConfig struct {
+	JWTSecret string        // 空の場合は起動ごとにランダムな鍵を生成する (再起動でトークンは無効になる)
+	TokenTTL  time.Duration // アクセストークンの有効期間
+}
+
+// ReminderConfig はリマインダー送信の設定です。
+type ReminderConfig struct {
+	Interval       time.Duration // 送信対象をチェックする間隔
+	WebhookTimeout time.Duration
+	SMTP           notifier.SMTPConfig // Host が空の場合はメール通知を行わない
 }
 
 func NewServer(cfg Config) (*Server, error) {
@@ -33,15 +53,36 @@ func NewServer(cfg Config) (*Server, error) {
 	userRepo := datastore.NewUserRepository(db)
 	todoRepo := datastore.NewTodoRepository(db)
 
-	todoUsecase := usecase.NewTodoUsecase(todoRepo, userRepo)
+	reminderSettingRepo := datastore.NewReminderSettingRepository(db)
+	tagRepo := datastore.NewTagRepository(db)
 
-	apiH := apiHandler.NewTodoAPIHandler(todoUsecase, userRepo)
-	ogenServer, err := apiHandler.NewServer(apiH)
+	jwtSecret := []byte(cfg.Auth.JWTSecret)
+	if len(jwtSecret) == 0 {
+		slog.Warn("JWT secret is not configured; using a random secret (tokens are invalidated on restart)")
+		jwtSecret = make([]byte, 32)
+		if _, err := rand.Read(jwtSecret); err != nil {
+			return nil, goerr.Wrap(err, "failed to generate JWT secret")
+		}
+	}
+	tokenManager := auth.NewJWTManager(jwtSecret, cfg.Auth.TokenTTL)
+
+	todoUsecase := usecase.NewTodoUsecase(todoRepo, userRepo)
+	reminderNotifier := notifier.NewReminderNotifier(
+		notifier.NewWebhookSender(cfg.Reminder.WebhookTimeout),
+		notifier.NewSMTPSender(cfg.Reminder.SMTP),
+	)
+	reminderUsecase := usecase.NewReminderUsecase(todoRepo, reminderSettingRepo, reminderNotifier)
+	tagUsecase := usecase.NewTagUsecase(tagRepo, todoRepo)
+	authUsecase := usecase.NewAuthUsecase(userRepo, tokenManager)
+
+	apiH := apiHandler.NewTodoAPIHandler(todoUsecase, reminderUsecase, tagUsecase, authUsecase, userRepo)
+	secH := apiHandler.NewBearerAuthHandler(tokenManager, userRepo)
+	ogenServer, err := apiHandler.NewServer(apiH, secH)
 	if err != nil {
 		return nil, goerr.Wrap(err, "failed to create ogen server")
 	}
 
-	webH, err := webHandler.NewWebHandler(todoUsecase, userRepo)
+	webH, err := webHandler.NewWebHandler(todoUsecase, tagUsecase, tokenManager, userRepo)
 	if err != nil {
 		return nil, goerr.Wrap(err, "failed to create web handler")
 	}
@@ -49,9 +90,12 @@ func NewServer(cfg Config) (*Server, error) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", webH.Index)
 	mux.Handle("/todos", ogenServer)
-	mux.Handle("/session", ogenServer)
+	mux.Handle("/auth/", ogenServer)
 	mux.Handle("/users", ogenServer)
 	mux.Handle("/todos/", ogenServer)
+	mux.Handle("/reminder-settings", ogenServer)
+	mux.Handle("/tags", ogenServer)
+	mux.Handle("/tags/", ogenServer)
 
 	srv := &http.Server{
 		Addr:         cfg.Addr,
@@ -63,6 +107,7 @@ func NewServer(cfg Config) (*Server, error) {
 	return &Server{
 		httpServer: srv,
 		db:         db,
+		scheduler:  scheduler.NewReminderScheduler(reminderUsecase, cfg.Reminder.Interval),
 	}, nil
 }
 
@@ -74,10 +119,20 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}()
 
+	// リマインダー送信のバックグラウンドジョブ (ctx のキャンセルで停止する)
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		s.scheduler.Run(ctx)
+	}()
+
 	<-ctx.Done()
 
 	slog.Info("shutting down server...")
 
+	// DB をクローズする前にスケジューラーの停止を待つ
+	<-schedulerDone
+
 	sqlDB, err := s.db.DB()
 	if err == nil {
 		if err := sqlDB.Close(); err != nil {
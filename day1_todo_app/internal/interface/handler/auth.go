@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+
+	domainRepo "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/usecase"
+	myerrors "github.com/m-mizutani/goerr"
+)
+
+// TokenVerifier はアクセストークンを検証してユーザーIDを返すインターフェースです。
+type TokenVerifier interface {
+	Verify(token string) (int64, error)
+}
+
+// BearerAuthHandler は ogen SecurityHandler インターフェースの実装です。
+// Authorization: Bearer <token> の JWT を検証し、現在のユーザーIDをコンテキストに設定します。
+type BearerAuthHandler struct {
+	verifier TokenVerifier
+	userRepo domainRepo.UserRepository
+	logger   *slog.Logger
+}
+
+// NewBearerAuthHandler は新しい BearerAuthHandler を生成します。
+func NewBearerAuthHandler(v TokenVerifier, ur domainRepo.UserRepository) SecurityHandler {
+	return &BearerAuthHandler{
+		verifier: v,
+		userRepo: ur,
+		logger:   slog.Default().WithGroup("handler.auth"),
+	}
+}
+
+// HandleBearerAuth implements bearerAuth security.
+func (h *BearerAuthHandler) HandleBearerAuth(ctx context.Context, operationName OperationName, t BearerAuth) (context.Context, error) {
+	userID, err := h.verifier.Verify(t.Token)
+	if err != nil {
+		h.logger.WarnContext(ctx, "invalid access token", "operation", operationName, "error", err)
+		return ctx, myerrors.Wrap(usecase.ErrUnauthenticated, "invalid access token")
+	}
+
+	// トークン発行後に削除されたユーザーは認証しない
+	user, err := h.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return ctx, myerrors.Wrap(err, "failed to find authenticated user").With("userID", userID)
+	}
+	if user == nil {
+		h.logger.WarnContext(ctx, "access token for unknown user", "operation", operationName, "userID", userID)
+		return ctx, myerrors.Wrap(usecase.ErrUnauthenticated, "user not found").With("userID", userID)
+	}
+
+	return context.WithValue(ctx, userIDKey{}, userID), nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/go-faster/errors"
-	"github.com/google/uuid"
+	"github.com/ogen-go/ogen/ogenerrors"
 
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
 	domainRepo "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
@@ -15,53 +17,42 @@ import (
 )
 
 // userIDKey はコンテキストからユーザーIDを取得するためのキーです。
+// BearerAuthHandler がトークンを検証した後に設定します。
 type userIDKey struct{}
 
-// sessionKey はコンテキストからセッションIDを取得するためのキーです。
-type sessionKey struct{}
-
 // TodoAPIHandler は ogen Handler インターフェースの実装です。
 type TodoAPIHandler struct {
-	todoUsecase usecase.TodoUsecase
-	userRepo    domainRepo.UserRepository
-	logger      *slog.Logger
-	sessions    map[string]int64
+	todoUsecase     usecase.TodoUsecase
+	reminderUsecase usecase.ReminderUsecase
+	tagUsecase      usecase.TagUsecase
+	authUsecase     usecase.AuthUsecase
+	userRepo        domainRepo.UserRepository
+	logger          *slog.Logger
 }
 
 // NewTodoAPIHandler は新しい TodoAPIHandler を生成します。
-func NewTodoAPIHandler(tu usecase.TodoUsecase, ur domainRepo.UserRepository) Handler {
+func NewTodoAPIHandler(tu usecase.TodoUsecase, ru usecase.ReminderUsecase, tgu usecase.TagUsecase, au usecase.AuthUsecase, ur domainRepo.UserRepository) Handler {
 	return &TodoAPIHandler{
-		todoUsecase: tu,
-		userRepo:    ur,
-		logger:      slog.Default().WithGroup("handler.api"),
-		sessions:    make(map[string]int64),
+		todoUsecase:     tu,
+		reminderUsecase: ru,
+		tagUsecase:      tgu,
+		authUsecase:     au,
+		userRepo:        ur,
+		logger:          slog.Default().WithGroup("handler.api"),
 	}
 }
 
 // --- 補助関数 ---
 
 // getCurrentUserID はコンテキストから現在のユーザーIDを取得します。
-// SetSession で設定されたセッション情報を利用します。
-// middleware で処理する方がクリーンだが、今回は handler 内で簡易的に実装。
+// ユーザーIDは BearerAuthHandler が JWT から解決してコンテキストに設定します。
+// クライアントが指定したユーザーIDは信頼しません。
 func (h *TodoAPIHandler) getCurrentUserID(ctx context.Context) (int64, error) {
-	// TODO: 本物のセッション管理 (Cookie など) を実装する
-	// 今回はテストのため、常にユーザー ID 1 を返す
-	return 1, nil
-
-	/* // 元の実装
-	// 本来は HTTP リクエストから Cookie 等を読み取り、セッションストアを検索する
-	// 今回は簡易的にインメモリマップから取得
-	sessionID, ok := ctx.Value(sessionKey{}).(string)
-	if !ok || sessionID == "" {
-		return 0, myerrors.New("session not started or invalid").With("reason", "no session id in context")
-	}
-
-	userID, exists := h.sessions[sessionID]
-	if !exists {
-		return 0, myerrors.New("session not found or expired").With("sessionID", sessionID)
+	userID, ok := ctx.Value(userIDKey{}).(int64)
+	if !ok {
+		return 0, myerrors.Wrap(usecase.ErrUnauthenticated, "no authenticated user in context")
 	}
 	return userID, nil
-	*/
 }
 
 // --- Handler 実装 ---
@@ -101,7 +92,7 @@ func (h *TodoAPIHandler) CreateTodo(ctx context.Context, req *CreateTodoRequest)
 	output, err := h.todoUsecase.CreateTodo(ctx, input)
 	if err != nil {
 		h.logger.ErrorContext(ctx, "createTodo usecase failed", "error", err, "input", input)
-		return nil, ``myerrors``.Wrap(err, "failed to create todo")
+		return nil, myerrors.Wrap(err, "failed to create todo")
 	}
 
 	return toSchemaTodo(output.Todo), nil
@@ -136,18 +127,31 @@ func (h *TodoAPIHandler) GetArchivedTodos(ctx context.Context, params GetArchive
 }
 
 // GetTodos implements getTodos operation.
-func (h *TodoAPIHandler) GetTodos(ctx context.Context, params GetTodosParams) ([]Todo, error) {
+func (h *TodoAPIHandler) GetTodos(ctx context.Context, params GetTodosParams) (*TodoList, error) {
 	h.logger.InfoContext(ctx, "handling getTodos", "params", params)
 	userID, err := h.getCurrentUserID(ctx)
 	if err != nil {
 		return nil, myerrors.Wrap(err, "failed to get current user for get todos")
 	}
 
+	// per_page を優先し、指定がなければ旧パラメータの limit を使う
+	perPage := params.PerPage.Or(params.Limit.Or(20))
+
 	input := usecase.GetTodosInput{
 		UserID:          userID,
-		Limit:           int(params.Limit.Or(20)),
+		Limit:           int(perPage),
 		Page:            int(params.Page.Or(1)),
-		IncludeArchived: false,
+		IncludeArchived: params.IncludeArchived.Or(false),
+		Query:           params.Q.Or(""),
+		Sort:            domainRepo.TodoSortKey(params.Sort.Or("")),
+		Overdue:         params.Overdue.Or(false),
+	}
+	if tagID, ok := params.TagID.Get(); ok {
+		input.TagID = &tagID
+	}
+	if status, ok := params.Status.Get(); ok {
+		domainStatus := model.TodoStatus(status)
+		input.Status = &domainStatus
 	}
 
 	output, err := h.todoUsecase.GetTodos(ctx, input)
@@ -160,7 +164,12 @@ func (h *TodoAPIHandler) GetTodos(ctx context.Context, params GetTodosParams) ([
 	for i, t := range output.Todos {
 		schemaTodos[i] = *toSchemaTodo(t)
 	}
-	return schemaTodos, nil
+	return &TodoList{
+		Items:   schemaTodos,
+		Total:   output.Total,
+		Page:    int32(output.Page),
+		PerPage: int32(output.Limit),
+	}, nil
 }
 
 // GetUsers implements getUsers operation.
@@ -179,26 +188,28 @@ func (h *TodoAPIHandler) GetUsers(ctx context.Context) ([]User, error) {
 	return schemaUsers, nil
 }
 
-// SetSession implements setSession operation.
-func (h *TodoAPIHandler) SetSession(ctx context.Context, req *SetSessionRequest) error {
-	h.logger.InfoContext(ctx, "handling setSession", "userID", req.UserID)
+// Register implements register operation.
+func (h *TodoAPIHandler) Register(ctx context.Context, req *AuthRequest) (*AuthResponse, error) {
+	h.logger.InfoContext(ctx, "handling register", "name", req.Name)
 
-	_, err := h.userRepo.FindByID(ctx, req.UserID)
+	output, err := h.authUsecase.Register(ctx, usecase.RegisterInput{Name: req.Name, Password: req.Password})
 	if err != nil {
-		h.logger.WarnContext(ctx, "attempted to set session for non-existent user", "userID", req.UserID, "error", err)
-		return &ErrorResponseStatusCode{
-			StatusCode: http.StatusNotFound,
-			Response:   Error{Code: "USER_NOT_FOUND", Message: "User not found"},
-		}
+		h.logger.WarnContext(ctx, "register usecase failed", "error", err, "name", req.Name)
+		return nil, myerrors.Wrap(err, "failed to register user")
 	}
+	return toSchemaAuthResponse(output), nil
+}
 
-	sessionID := uuid.NewString()
-
-	h.sessions[sessionID] = req.UserID
-
-	h.logger.InfoContext(ctx, "session set successfully", "userID", req.UserID, "sessionID", sessionID)
+// Login implements login operation.
+func (h *TodoAPIHandler) Login(ctx context.Context, req *AuthRequest) (*AuthResponse, error) {
+	h.logger.InfoContext(ctx, "handling login", "name", req.Name)
 
-	return nil
+	output, err := h.authUsecase.Login(ctx, usecase.LoginInput{Name: req.Name, Password: req.Password})
+	if err != nil {
+		h.logger.WarnContext(ctx, "login usecase failed", "error", err, "name", req.Name)
+		return nil, myerrors.Wrap(err, "failed to log in")
+	}
+	return toSchemaAuthResponse(output), nil
 }
 
 // UnarchiveTodo implements unarchiveTodo operation.
@@ -318,10 +329,229 @@ func (h *TodoAPIHandler) UpdateTodoStatus(ctx context.Context, req *UpdateTodoSt
 	return toSchemaTodo(output.Todo), nil
 }
 
+// SetTodoDue implements setTodoDue operation.
+func (h *TodoAPIHandler) SetTodoDue(ctx context.Context, req *SetTodoDueRequest, params SetTodoDueParams) (*Todo, error) {
+	h.logger.InfoContext(ctx, "handling setTodoDue", "todoID", params.TodoId, "dueAt", req.DueAt)
+	return h.setTodoDue(ctx, params.TodoId, &req.DueAt)
+}
+
+// ClearTodoDue implements clearTodoDue operation.
+func (h *TodoAPIHandler) ClearTodoDue(ctx context.Context, params ClearTodoDueParams) (*Todo, error) {
+	h.logger.InfoContext(ctx, "handling clearTodoDue", "todoID", params.TodoId)
+	return h.setTodoDue(ctx, params.TodoId, nil)
+}
+
+// setTodoDue は期限の設定・クリアの共通処理です。dueAt が nil の場合はクリアします。
+func (h *TodoAPIHandler) setTodoDue(ctx context.Context, todoID int64, dueAt *time.Time) (*Todo, error) {
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for set due")
+	}
+
+	input := usecase.SetTodoDueInput{
+		ID:     todoID,
+		UserID: userID,
+		DueAt:  dueAt,
+	}
+	output, err := h.todoUsecase.SetTodoDue(ctx, input)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "setTodoDue usecase failed", "error", err, "input", input)
+		return nil, myerrors.Wrap(err, "failed to set todo due date")
+	}
+
+	return toSchemaTodo(output.Todo), nil
+}
+
+// GetReminderSettings implements getReminderSettings operation.
+func (h *TodoAPIHandler) GetReminderSettings(ctx context.Context) (*ReminderSettings, error) {
+	h.logger.InfoContext(ctx, "handling getReminderSettings")
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for get reminder settings")
+	}
+
+	output, err := h.reminderUsecase.GetReminderSetting(ctx, usecase.GetReminderSettingInput{UserID: userID})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "getReminderSettings usecase failed", "error", err, "userID", userID)
+		return nil, myerrors.Wrap(err, "failed to get reminder settings")
+	}
+
+	return toSchemaReminderSettings(output.Setting), nil
+}
+
+// UpdateReminderSettings implements updateReminderSettings operation.
+func (h *TodoAPIHandler) UpdateReminderSettings(ctx context.Context, req *ReminderSettings) (*ReminderSettings, error) {
+	h.logger.InfoContext(ctx, "handling updateReminderSettings", "enabled", req.Enabled, "minutesBefore", req.MinutesBefore)
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for update reminder settings")
+	}
+
+	input := usecase.UpdateReminderSettingInput{
+		UserID:        userID,
+		Enabled:       req.Enabled,
+		MinutesBefore: int(req.MinutesBefore),
+		Email:         req.Email.Or(""),
+	}
+	if webhookURL, ok := req.WebhookURL.Get(); ok {
+		input.WebhookURL = webhookURL.String()
+	}
+
+	output, err := h.reminderUsecase.UpdateReminderSetting(ctx, input)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "updateReminderSettings usecase failed", "error", err, "userID", userID)
+		return nil, myerrors.Wrap(err, "failed to update reminder settings")
+	}
+
+	return toSchemaReminderSettings(output.Setting), nil
+}
+
+// GetTags implements getTags operation.
+func (h *TodoAPIHandler) GetTags(ctx context.Context) ([]Tag, error) {
+	h.logger.InfoContext(ctx, "handling getTags")
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for get tags")
+	}
+
+	output, err := h.tagUsecase.ListTags(ctx, usecase.ListTagsInput{UserID: userID})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "getTags usecase failed", "error", err, "userID", userID)
+		return nil, myerrors.Wrap(err, "failed to get tags")
+	}
+
+	schemaTags := make([]Tag, len(output.Tags))
+	for i, t := range output.Tags {
+		schemaTags[i] = *toSchemaTag(t)
+	}
+	return schemaTags, nil
+}
+
+// CreateTag implements createTag operation.
+func (h *TodoAPIHandler) CreateTag(ctx context.Context, req *TagRequest) (*Tag, error) {
+	h.logger.InfoContext(ctx, "handling createTag", "name", req.Name)
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for create tag")
+	}
+
+	input := usecase.CreateTagInput{
+		UserID: userID,
+		Name:   req.Name,
+		Color:  req.Color.Or(""),
+	}
+	output, err := h.tagUsecase.CreateTag(ctx, input)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "createTag usecase failed", "error", err, "input", input)
+		return nil, myerrors.Wrap(err, "failed to create tag")
+	}
+
+	return toSchemaTag(output.Tag), nil
+}
+
+// UpdateTag implements updateTag operation.
+func (h *TodoAPIHandler) UpdateTag(ctx context.Context, req *TagRequest, params UpdateTagParams) (*Tag, error) {
+	h.logger.InfoContext(ctx, "handling updateTag", "tagID", params.TagId, "name", req.Name)
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for update tag")
+	}
+
+	input := usecase.UpdateTagInput{
+		ID:     params.TagId,
+		UserID: userID,
+		Name:   req.Name,
+		Color:  req.Color.Or(""),
+	}
+	output, err := h.tagUsecase.UpdateTag(ctx, input)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "updateTag usecase failed", "error", err, "input", input)
+		return nil, myerrors.Wrap(err, "failed to update tag")
+	}
+
+	return toSchemaTag(output.Tag), nil
+}
+
+// DeleteTag implements deleteTag operation.
+func (h *TodoAPIHandler) DeleteTag(ctx context.Context, params DeleteTagParams) error {
+	h.logger.InfoContext(ctx, "handling deleteTag", "tagID", params.TagId)
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return myerrors.Wrap(err, "failed to get current user for delete tag")
+	}
+
+	input := usecase.DeleteTagInput{
+		ID:     params.TagId,
+		UserID: userID,
+	}
+	if err := h.tagUsecase.DeleteTag(ctx, input); err != nil {
+		h.logger.ErrorContext(ctx, "deleteTag usecase failed", "error", err, "input", input)
+		return myerrors.Wrap(err, "failed to delete tag")
+	}
+	return nil
+}
+
+// AttachTodoTag implements attachTodoTag operation.
+func (h *TodoAPIHandler) AttachTodoTag(ctx context.Context, params AttachTodoTagParams) (*Todo, error) {
+	h.logger.InfoContext(ctx, "handling attachTodoTag", "todoID", params.TodoId, "tagID", params.TagId)
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for attach tag")
+	}
+
+	input := usecase.TodoTagInput{
+		TodoID: params.TodoId,
+		TagID:  params.TagId,
+		UserID: userID,
+	}
+	output, err := h.tagUsecase.AttachTag(ctx, input)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "attachTodoTag usecase failed", "error", err, "input", input)
+		return nil, myerrors.Wrap(err, "failed to attach tag")
+	}
+
+	return toSchemaTodo(output.Todo), nil
+}
+
+// DetachTodoTag implements detachTodoTag operation.
+func (h *TodoAPIHandler) DetachTodoTag(ctx context.Context, params DetachTodoTagParams) (*Todo, error) {
+	h.logger.InfoContext(ctx, "handling detachTodoTag", "todoID", params.TodoId, "tagID", params.TagId)
+	userID, err := h.getCurrentUserID(ctx)
+	if err != nil {
+		return nil, myerrors.Wrap(err, "failed to get current user for detach tag")
+	}
+
+	input := usecase.TodoTagInput{
+		TodoID: params.TodoId,
+		TagID:  params.TagId,
+		UserID: userID,
+	}
+	output, err := h.tagUsecase.DetachTag(ctx, input)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "detachTodoTag usecase failed", "error", err, "input", input)
+		return nil, myerrors.Wrap(err, "failed to detach tag")
+	}
+
+	return toSchemaTodo(output.Todo), nil
+}
+
 // NewError implements NewError operation.
 func (h *TodoAPIHandler) NewError(ctx context.Context, err error) *ErrorResponseStatusCode {
 	h.logger.WarnContext(ctx, "handler error occurred", "error", err)
 
+	// 認証・認可などユースケースが返すエラーの種類に応じてステータスコードを変える
+	var secErr *ogenerrors.SecurityError
+	switch {
+	case errors.As(err, &secErr), errors.Is(err, usecase.ErrUnauthenticated):
+		return newErrorResponse(http.StatusUnauthorized, "UNAUTHENTICATED", err)
+	case errors.Is(err, usecase.ErrPermissionDenied):
+		return newErrorResponse(http.StatusForbidden, "PERMISSION_DENIED", err)
+	case errors.Is(err, usecase.ErrNotFound):
+		return newErrorResponse(http.StatusNotFound, "NOT_FOUND", err)
+	case errors.Is(err, usecase.ErrAlreadyExists):
+		return newErrorResponse(http.StatusConflict, "ALREADY_EXISTS", err)
+	}
+
 	var goErr *myerrors.Error
 	if errors.As(err, &goErr) {
 		return &ErrorResponseStatusCode{
@@ -342,6 +572,17 @@ func (h *TodoAPIHandler) NewError(ctx context.Context, err error) *ErrorResponse
 	}
 }
 
+// newErrorResponse は指定したステータスコードのエラーレスポンスを生成します。
+func newErrorResponse(statusCode int, code string, err error) *ErrorResponseStatusCode {
+	return &ErrorResponseStatusCode{
+		StatusCode: statusCode,
+		Response: Error{
+			Code:    code,
+			Message: err.Error(),
+		},
+	}
+}
+
 // --- スキーマモデル変換ヘルパー ---
 
 func toSchemaUser(u *model.User) *User {
@@ -363,6 +604,11 @@ func toSchemaTodo(t *model.Todo) *Todo {
 	if t.Description != "" {
 		description.SetTo(t.Description)
 	}
+	// DueAt を OptNilDateTime に変換
+	var dueAt OptNilDateTime
+	if t.DueAt != nil {
+		dueAt.SetTo(*t.DueAt)
+	}
 	// ArchivedAt を OptNilDateTime に変換
 	var archivedAt OptNilDateTime
 	if t.ArchivedAt != nil {
@@ -372,6 +618,11 @@ func toSchemaTodo(t *model.Todo) *Todo {
 	// Status を OpenAPI の Enum 型 (string) に変換
 	status := TodoStatus(string(t.Status))
 
+	tags := make([]Tag, len(t.Tags))
+	for i, tag := range t.Tags {
+		tags[i] = *toSchemaTag(tag)
+	}
+
 	return &Todo{
 		ID:          t.ID,
 		UserID:      t.UserID,
@@ -380,6 +631,47 @@ func toSchemaTodo(t *model.Todo) *Todo {
 		Status:      status, // 変換した Status を設定
 		SortOrder:   t.SortOrder,
 		CreatedAt:   t.CreatedAt,
+		DueAt:       dueAt,
+		Overdue:     t.IsOverdue(time.Now()),
 		ArchivedAt:  archivedAt,
+		Tags:        tags,
+	}
+}
+
+func toSchemaAuthResponse(o *usecase.AuthOutput) *AuthResponse {
+	return &AuthResponse{
+		Token:     o.Token,
+		ExpiresAt: o.ExpiresAt,
+		User:      *toSchemaUser(o.User),
+	}
+}
+
+func toSchemaTag(t *model.Tag) *Tag {
+	if t == nil {
+		return nil
+	}
+	return &Tag{
+		ID:    t.ID,
+		Name:  t.Name,
+		Color: t.Color,
+	}
+}
+
+func toSchemaReminderSettings(s *model.ReminderSetting) *ReminderSettings {
+	if s == nil {
+		return nil
+	}
+	settings := &ReminderSettings{
+		Enabled:       s.Enabled,
+		MinutesBefore: int32(s.MinutesBefore),
+	}
+	if s.WebhookURL != "" {
+		if u, err := url.Parse(s.WebhookURL); err == nil {
+			settings.WebhookURL.SetTo(*u)
+		}
+	}
+	if s.Email != "" {
+		settings.Email.SetTo(s.Email)
 	}
+	return settings
 }
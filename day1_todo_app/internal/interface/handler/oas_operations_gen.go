@@ -6,14 +6,25 @@ package handler
 type OperationName = string
 
 const (
-	ArchiveTodoOperation      OperationName = "ArchiveTodo"
-	CreateTodoOperation       OperationName = "CreateTodo"
-	GetArchivedTodosOperation OperationName = "GetArchivedTodos"
-	GetTodosOperation         OperationName = "GetTodos"
-	GetUsersOperation         OperationName = "GetUsers"
-	SetSessionOperation       OperationName = "SetSession"
-	UnarchiveTodoOperation    OperationName = "UnarchiveTodo"
-	UpdateTodoOperation       OperationName = "UpdateTodo"
-	UpdateTodoOrderOperation  OperationName = "UpdateTodoOrder"
-	UpdateTodoStatusOperation OperationName = "UpdateTodoStatus"
+	ArchiveTodoOperation            OperationName = "ArchiveTodo"
+	AttachTodoTagOperation          OperationName = "AttachTodoTag"
+	ClearTodoDueOperation           OperationName = "ClearTodoDue"
+	CreateTagOperation              OperationName = "CreateTag"
+	CreateTodoOperation             OperationName = "CreateTodo"
+	DeleteTagOperation              OperationName = "DeleteTag"
+	DetachTodoTagOperation          OperationName = "DetachTodoTag"
+	GetArchivedTodosOperation       OperationName = "GetArchivedTodos"
+	GetReminderSettingsOperation    OperationName = "GetReminderSettings"
+	GetTagsOperation                OperationName = "GetTags"
+	GetTodosOperation               OperationName = "GetTodos"
+	GetUsersOperation               OperationName = "GetUsers"
+	LoginOperation                  OperationName = "Login"
+	RegisterOperation               OperationName = "Register"
+	SetTodoDueOperation             OperationName = "SetTodoDue"
+	UnarchiveTodoOperation          OperationName = "UnarchiveTodo"
+	UpdateReminderSettingsOperation OperationName = "UpdateReminderSettings"
+	UpdateTagOperation              OperationName = "UpdateTag"
+	UpdateTodoOperation             OperationName = "UpdateTodo"
+	UpdateTodoOrderOperation        OperationName = "UpdateTodoOrder"
+	UpdateTodoStatusOperation       OperationName = "UpdateTodoStatus"
 )
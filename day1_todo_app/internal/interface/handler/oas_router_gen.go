@@ -40,7 +40,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.notFound(w, r)
 		return
 	}
-	args := [1]string{}
+	args := [2]string{}
 
 	// Static code generated router with unwrapped path search.
 	switch {
@@ -61,123 +61,168 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 			switch elem[0] {
-			case 's': // Prefix: "session"
+			case 'a': // Prefix: "auth/"
 
-				if l := len("session"); len(elem) >= l && elem[0:l] == "session" {
+				if l := len("auth/"); len(elem) >= l && elem[0:l] == "auth/" {
 					elem = elem[l:]
 				} else {
 					break
 				}
 
 				if len(elem) == 0 {
-					// Leaf node.
-					switch r.Method {
-					case "POST":
-						s.handleSetSessionRequest([0]string{}, elemIsEscaped, w, r)
-					default:
-						s.notAllowed(w, r, "POST")
+					break
+				}
+				switch elem[0] {
+				case 'l': // Prefix: "login"
+
+					if l := len("login"); len(elem) >= l && elem[0:l] == "login" {
+						elem = elem[l:]
+					} else {
+						break
+					}
+
+					if len(elem) == 0 {
+						// Leaf node.
+						switch r.Method {
+						case "POST":
+							s.handleLoginRequest([0]string{}, elemIsEscaped, w, r)
+						default:
+							s.notAllowed(w, r, "POST")
+						}
+
+						return
+					}
+
+				case 'r': // Prefix: "register"
+
+					if l := len("register"); len(elem) >= l && elem[0:l] == "register" {
+						elem = elem[l:]
+					} else {
+						break
+					}
+
+					if len(elem) == 0 {
+						// Leaf node.
+						switch r.Method {
+						case "POST":
+							s.handleRegisterRequest([0]string{}, elemIsEscaped, w, r)
+						default:
+							s.notAllowed(w, r, "POST")
+						}
+
+						return
 					}
 
-					return
 				}
 
-			case 't': // Prefix: "todos"
+			case 'r': // Prefix: "reminder-settings"
 
-				if l := len("todos"); len(elem) >= l && elem[0:l] == "todos" {
+				if l := len("reminder-settings"); len(elem) >= l && elem[0:l] == "reminder-settings" {
 					elem = elem[l:]
 				} else {
 					break
 				}
 
 				if len(elem) == 0 {
+					// Leaf node.
 					switch r.Method {
 					case "GET":
-						s.handleGetTodosRequest([0]string{}, elemIsEscaped, w, r)
-					case "POST":
-						s.handleCreateTodoRequest([0]string{}, elemIsEscaped, w, r)
+						s.handleGetReminderSettingsRequest([0]string{}, elemIsEscaped, w, r)
+					case "PUT":
+						s.handleUpdateReminderSettingsRequest([0]string{}, elemIsEscaped, w, r)
 					default:
-						s.notAllowed(w, r, "GET,POST")
+						s.notAllowed(w, r, "GET,PUT")
 					}
 
 					return
 				}
+
+			case 't': // Prefix: "t"
+
+				if l := len("t"); len(elem) >= l && elem[0:l] == "t" {
+					elem = elem[l:]
+				} else {
+					break
+				}
+
+				if len(elem) == 0 {
+					break
+				}
 				switch elem[0] {
-				case '/': // Prefix: "/"
+				case 'a': // Prefix: "ags"
 
-					if l := len("/"); len(elem) >= l && elem[0:l] == "/" {
+					if l := len("ags"); len(elem) >= l && elem[0:l] == "ags" {
 						elem = elem[l:]
 					} else {
 						break
 					}
 
 					if len(elem) == 0 {
-						break
+						switch r.Method {
+						case "GET":
+							s.handleGetTagsRequest([0]string{}, elemIsEscaped, w, r)
+						case "POST":
+							s.handleCreateTagRequest([0]string{}, elemIsEscaped, w, r)
+						default:
+							s.notAllowed(w, r, "GET,POST")
+						}
+
+						return
 					}
 					switch elem[0] {
-					case 'a': // Prefix: "archived"
-						origElem := elem
-						if l := len("archived"); len(elem) >= l && elem[0:l] == "archived" {
+					case '/': // Prefix: "/"
+
+						if l := len("/"); len(elem) >= l && elem[0:l] == "/" {
 							elem = elem[l:]
 						} else {
 							break
 						}
 
-						if len(elem) == 0 {
-							// Leaf node.
-							switch r.Method {
-							case "GET":
-								s.handleGetArchivedTodosRequest([0]string{}, elemIsEscaped, w, r)
-							default:
-								s.notAllowed(w, r, "GET")
-							}
-
-							return
-						}
-
-						elem = origElem
-					case 'o': // Prefix: "order"
-						origElem := elem
-						if l := len("order"); len(elem) >= l && elem[0:l] == "order" {
-							elem = elem[l:]
-						} else {
+						// Param: "tagId"
+						// Leaf parameter, slashes are prohibited
+						idx := strings.IndexByte(elem, '/')
+						if idx >= 0 {
 							break
 						}
+						args[0] = elem
+						elem = ""
 
 						if len(elem) == 0 {
 							// Leaf node.
 							switch r.Method {
-							case "PATCH":
-								s.handleUpdateTodoOrderRequest([0]string{}, elemIsEscaped, w, r)
+							case "DELETE":
+								s.handleDeleteTagRequest([1]string{
+									args[0],
+								}, elemIsEscaped, w, r)
+							case "PUT":
+								s.handleUpdateTagRequest([1]string{
+									args[0],
+								}, elemIsEscaped, w, r)
 							default:
-								s.notAllowed(w, r, "PATCH")
+								s.notAllowed(w, r, "DELETE,PUT")
 							}
 
 							return
 						}
 
-						elem = origElem
 					}
-					// Param: "todoId"
-					// Match until "/"
-					idx := strings.IndexByte(elem, '/')
-					if idx < 0 {
-						idx = len(elem)
+
+				case 'o': // Prefix: "odos"
+
+					if l := len("odos"); len(elem) >= l && elem[0:l] == "odos" {
+						elem = elem[l:]
+					} else {
+						break
 					}
-					args[0] = elem[:idx]
-					elem = elem[idx:]
 
 					if len(elem) == 0 {
 						switch r.Method {
-						case "DELETE":
-							s.handleArchiveTodoRequest([1]string{
-								args[0],
-							}, elemIsEscaped, w, r)
-						case "PUT":
-							s.handleUpdateTodoRequest([1]string{
-								args[0],
-							}, elemIsEscaped, w, r)
+						case "GET":
+							s.handleGetTodosRequest([0]string{}, elemIsEscaped, w, r)
+						case "POST":
+							s.handleCreateTodoRequest([0]string{}, elemIsEscaped, w, r)
 						default:
-							s.notAllowed(w, r, "DELETE,PUT")
+							s.notAllowed(w, r, "GET,POST")
 						}
 
 						return
@@ -195,9 +240,9 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 							break
 						}
 						switch elem[0] {
-						case 's': // Prefix: "status"
-
-							if l := len("status"); len(elem) >= l && elem[0:l] == "status" {
+						case 'a': // Prefix: "archived"
+							origElem := elem
+							if l := len("archived"); len(elem) >= l && elem[0:l] == "archived" {
 								elem = elem[l:]
 							} else {
 								break
@@ -206,20 +251,19 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 							if len(elem) == 0 {
 								// Leaf node.
 								switch r.Method {
-								case "PATCH":
-									s.handleUpdateTodoStatusRequest([1]string{
-										args[0],
-									}, elemIsEscaped, w, r)
+								case "GET":
+									s.handleGetArchivedTodosRequest([0]string{}, elemIsEscaped, w, r)
 								default:
-									s.notAllowed(w, r, "PATCH")
+									s.notAllowed(w, r, "GET")
 								}
 
 								return
 							}
 
-						case 'u': // Prefix: "unarchive"
-
-							if l := len("unarchive"); len(elem) >= l && elem[0:l] == "unarchive" {
+							elem = origElem
+						case 'o': // Prefix: "order"
+							origElem := elem
+							if l := len("order"); len(elem) >= l && elem[0:l] == "order" {
 								elem = elem[l:]
 							} else {
 								break
@@ -229,9 +273,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 								// Leaf node.
 								switch r.Method {
 								case "PATCH":
-									s.handleUnarchiveTodoRequest([1]string{
-										args[0],
-									}, elemIsEscaped, w, r)
+									s.handleUpdateTodoOrderRequest([0]string{}, elemIsEscaped, w, r)
 								default:
 									s.notAllowed(w, r, "PATCH")
 								}
@@ -239,6 +281,155 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 								return
 							}
 
+							elem = origElem
+						}
+						// Param: "todoId"
+						// Match until "/"
+						idx := strings.IndexByte(elem, '/')
+						if idx < 0 {
+							idx = len(elem)
+						}
+						args[0] = elem[:idx]
+						elem = elem[idx:]
+
+						if len(elem) == 0 {
+							switch r.Method {
+							case "DELETE":
+								s.handleArchiveTodoRequest([1]string{
+									args[0],
+								}, elemIsEscaped, w, r)
+							case "PUT":
+								s.handleUpdateTodoRequest([1]string{
+									args[0],
+								}, elemIsEscaped, w, r)
+							default:
+								s.notAllowed(w, r, "DELETE,PUT")
+							}
+
+							return
+						}
+						switch elem[0] {
+						case '/': // Prefix: "/"
+
+							if l := len("/"); len(elem) >= l && elem[0:l] == "/" {
+								elem = elem[l:]
+							} else {
+								break
+							}
+
+							if len(elem) == 0 {
+								break
+							}
+							switch elem[0] {
+							case 'd': // Prefix: "due"
+
+								if l := len("due"); len(elem) >= l && elem[0:l] == "due" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch r.Method {
+									case "DELETE":
+										s.handleClearTodoDueRequest([1]string{
+											args[0],
+										}, elemIsEscaped, w, r)
+									case "PUT":
+										s.handleSetTodoDueRequest([1]string{
+											args[0],
+										}, elemIsEscaped, w, r)
+									default:
+										s.notAllowed(w, r, "DELETE,PUT")
+									}
+
+									return
+								}
+
+							case 's': // Prefix: "status"
+
+								if l := len("status"); len(elem) >= l && elem[0:l] == "status" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch r.Method {
+									case "PATCH":
+										s.handleUpdateTodoStatusRequest([1]string{
+											args[0],
+										}, elemIsEscaped, w, r)
+									default:
+										s.notAllowed(w, r, "PATCH")
+									}
+
+									return
+								}
+
+							case 't': // Prefix: "tags/"
+
+								if l := len("tags/"); len(elem) >= l && elem[0:l] == "tags/" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								// Param: "tagId"
+								// Leaf parameter, slashes are prohibited
+								idx := strings.IndexByte(elem, '/')
+								if idx >= 0 {
+									break
+								}
+								args[1] = elem
+								elem = ""
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch r.Method {
+									case "DELETE":
+										s.handleDetachTodoTagRequest([2]string{
+											args[0],
+											args[1],
+										}, elemIsEscaped, w, r)
+									case "PUT":
+										s.handleAttachTodoTagRequest([2]string{
+											args[0],
+											args[1],
+										}, elemIsEscaped, w, r)
+									default:
+										s.notAllowed(w, r, "DELETE,PUT")
+									}
+
+									return
+								}
+
+							case 'u': // Prefix: "unarchive"
+
+								if l := len("unarchive"); len(elem) >= l && elem[0:l] == "unarchive" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch r.Method {
+									case "PATCH":
+										s.handleUnarchiveTodoRequest([1]string{
+											args[0],
+										}, elemIsEscaped, w, r)
+									default:
+										s.notAllowed(w, r, "PATCH")
+									}
+
+									return
+								}
+
+							}
+
 						}
 
 					}
@@ -279,7 +470,7 @@ type Route struct {
 	operationID string
 	pathPattern string
 	count       int
-	args        [1]string
+	args        [2]string
 }
 
 // Name returns ogen operation name.
@@ -359,53 +550,92 @@ func (s *Server) FindPath(method string, u *url.URL) (r Route, _ bool) {
 				break
 			}
 			switch elem[0] {
-			case 's': // Prefix: "session"
+			case 'a': // Prefix: "auth/"
 
-				if l := len("session"); len(elem) >= l && elem[0:l] == "session" {
+				if l := len("auth/"); len(elem) >= l && elem[0:l] == "auth/" {
 					elem = elem[l:]
 				} else {
 					break
 				}
 
 				if len(elem) == 0 {
-					// Leaf node.
-					switch method {
-					case "POST":
-						r.name = SetSessionOperation
-						r.summary = "Set current user session"
-						r.operationID = "setSession"
-						r.pathPattern = "/session"
-						r.args = args
-						r.count = 0
-						return r, true
-					default:
-						return
+					break
+				}
+				switch elem[0] {
+				case 'l': // Prefix: "login"
+
+					if l := len("login"); len(elem) >= l && elem[0:l] == "login" {
+						elem = elem[l:]
+					} else {
+						break
+					}
+
+					if len(elem) == 0 {
+						// Leaf node.
+						switch method {
+						case "POST":
+							r.name = LoginOperation
+							r.summary = "Log in and issue an access token"
+							r.operationID = "login"
+							r.pathPattern = "/auth/login"
+							r.args = args
+							r.count = 0
+							return r, true
+						default:
+							return
+						}
+					}
+
+				case 'r': // Prefix: "register"
+
+					if l := len("register"); len(elem) >= l && elem[0:l] == "register" {
+						elem = elem[l:]
+					} else {
+						break
+					}
+
+					if len(elem) == 0 {
+						// Leaf node.
+						switch method {
+						case "POST":
+							r.name = RegisterOperation
+							r.summary = "Register a new user and issue an access token"
+							r.operationID = "register"
+							r.pathPattern = "/auth/register"
+							r.args = args
+							r.count = 0
+							return r, true
+						default:
+							return
+						}
 					}
+
 				}
 
-			case 't': // Prefix: "todos"
+			case 'r': // Prefix: "reminder-settings"
 
-				if l := len("todos"); len(elem) >= l && elem[0:l] == "todos" {
+				if l := len("reminder-settings"); len(elem) >= l && elem[0:l] == "reminder-settings" {
 					elem = elem[l:]
 				} else {
 					break
 				}
 
 				if len(elem) == 0 {
+					// Leaf node.
 					switch method {
 					case "GET":
-						r.name = GetTodosOperation
-						r.summary = "Get list of ToDos for the current user"
-						r.operationID = "getTodos"
-						r.pathPattern = "/todos"
+						r.name = GetReminderSettingsOperation
+						r.summary = "Get the reminder settings of the current user"
+						r.operationID = "getReminderSettings"
+						r.pathPattern = "/reminder-settings"
 						r.args = args
 						r.count = 0
 						return r, true
-					case "POST":
-						r.name = CreateTodoOperation
-						r.summary = "Create a new ToDo"
-						r.operationID = "createTodo"
-						r.pathPattern = "/todos"
+					case "PUT":
+						r.name = UpdateReminderSettingsOperation
+						r.summary = "Update the reminder settings of the current user"
+						r.operationID = "updateReminderSettings"
+						r.pathPattern = "/reminder-settings"
 						r.args = args
 						r.count = 0
 						return r, true
@@ -413,96 +643,118 @@ func (s *Server) FindPath(method string, u *url.URL) (r Route, _ bool) {
 						return
 					}
 				}
+
+			case 't': // Prefix: "t"
+
+				if l := len("t"); len(elem) >= l && elem[0:l] == "t" {
+					elem = elem[l:]
+				} else {
+					break
+				}
+
+				if len(elem) == 0 {
+					break
+				}
 				switch elem[0] {
-				case '/': // Prefix: "/"
+				case 'a': // Prefix: "ags"
 
-					if l := len("/"); len(elem) >= l && elem[0:l] == "/" {
+					if l := len("ags"); len(elem) >= l && elem[0:l] == "ags" {
 						elem = elem[l:]
 					} else {
 						break
 					}
 
 					if len(elem) == 0 {
-						break
+						switch method {
+						case "GET":
+							r.name = GetTagsOperation
+							r.summary = "Get the tags of the current user"
+							r.operationID = "getTags"
+							r.pathPattern = "/tags"
+							r.args = args
+							r.count = 0
+							return r, true
+						case "POST":
+							r.name = CreateTagOperation
+							r.summary = "Create a new tag"
+							r.operationID = "createTag"
+							r.pathPattern = "/tags"
+							r.args = args
+							r.count = 0
+							return r, true
+						default:
+							return
+						}
 					}
 					switch elem[0] {
-					case 'a': // Prefix: "archived"
-						origElem := elem
-						if l := len("archived"); len(elem) >= l && elem[0:l] == "archived" {
+					case '/': // Prefix: "/"
+
+						if l := len("/"); len(elem) >= l && elem[0:l] == "/" {
 							elem = elem[l:]
 						} else {
 							break
 						}
 
-						if len(elem) == 0 {
-							// Leaf node.
-							switch method {
-							case "GET":
-								r.name = GetArchivedTodosOperation
-								r.summary = "Get list of archived ToDos for the current user"
-								r.operationID = "getArchivedTodos"
-								r.pathPattern = "/todos/archived"
-								r.args = args
-								r.count = 0
-								return r, true
-							default:
-								return
-							}
-						}
-
-						elem = origElem
-					case 'o': // Prefix: "order"
-						origElem := elem
-						if l := len("order"); len(elem) >= l && elem[0:l] == "order" {
-							elem = elem[l:]
-						} else {
+						// Param: "tagId"
+						// Leaf parameter, slashes are prohibited
+						idx := strings.IndexByte(elem, '/')
+						if idx >= 0 {
 							break
 						}
+						args[0] = elem
+						elem = ""
 
 						if len(elem) == 0 {
 							// Leaf node.
 							switch method {
-							case "PATCH":
-								r.name = UpdateTodoOrderOperation
-								r.summary = "Update the sort order of multiple ToDos"
-								r.operationID = "updateTodoOrder"
-								r.pathPattern = "/todos/order"
+							case "DELETE":
+								r.name = DeleteTagOperation
+								r.summary = "Delete a tag (also removes it from all ToDos)"
+								r.operationID = "deleteTag"
+								r.pathPattern = "/tags/{tagId}"
+								r.args = args
+								r.count = 1
+								return r, true
+							case "PUT":
+								r.name = UpdateTagOperation
+								r.summary = "Update a tag"
+								r.operationID = "updateTag"
+								r.pathPattern = "/tags/{tagId}"
 								r.args = args
-								r.count = 0
+								r.count = 1
 								return r, true
 							default:
 								return
 							}
 						}
 
-						elem = origElem
 					}
-					// Param: "todoId"
-					// Match until "/"
-					idx := strings.IndexByte(elem, '/')
-					if idx < 0 {
-						idx = len(elem)
+
+				case 'o': // Prefix: "odos"
+
+					if l := len("odos"); len(elem) >= l && elem[0:l] == "odos" {
+						elem = elem[l:]
+					} else {
+						break
 					}
-					args[0] = elem[:idx]
-					elem = elem[idx:]
 
 					if len(elem) == 0 {
 						switch method {
-						case "DELETE":
-							r.name = ArchiveTodoOperation
-							r.summary = "Archive a ToDo"
-							r.operationID = "archiveTodo"
-							r.pathPattern = "/todos/{todoId}"
+						case "GET":
+							r.name = GetTodosOperation
+							r.summary = "Get list of ToDos for the current user"
+							r.operationID = "getTodos"
+							r.pathPattern = "/todos"
 							r.args = args
-							r.count = 1
+							r.count = 0
 							return r, true
-						case "PUT":
-							r.name = UpdateTodoOperation
-							r.summary = "Update an existing ToDo"
-							r.operationID = "updateTodo"
-							r.pathPattern = "/todos/{todoId}"
+						case "POST":
+							r.name = CreateTodoOperation
+							r.summary = "Create a new ToDo"
+							r.operationID = "createTodo"
+							r.pathPattern = "/todos"
 							r.args = args
-							r.count = 1
+							r.count = 0
 							return r, true
 						default:
 							return
@@ -521,9 +773,9 @@ func (s *Server) FindPath(method string, u *url.URL) (r Route, _ bool) {
 							break
 						}
 						switch elem[0] {
-						case 's': // Prefix: "status"
-
-							if l := len("status"); len(elem) >= l && elem[0:l] == "status" {
+						case 'a': // Prefix: "archived"
+							origElem := elem
+							if l := len("archived"); len(elem) >= l && elem[0:l] == "archived" {
 								elem = elem[l:]
 							} else {
 								break
@@ -532,22 +784,23 @@ func (s *Server) FindPath(method string, u *url.URL) (r Route, _ bool) {
 							if len(elem) == 0 {
 								// Leaf node.
 								switch method {
-								case "PATCH":
-									r.name = UpdateTodoStatusOperation
-									r.summary = "Update the status of a ToDo"
-									r.operationID = "updateTodoStatus"
-									r.pathPattern = "/todos/{todoId}/status"
+								case "GET":
+									r.name = GetArchivedTodosOperation
+									r.summary = "Get list of archived ToDos for the current user"
+									r.operationID = "getArchivedTodos"
+									r.pathPattern = "/todos/archived"
 									r.args = args
-									r.count = 1
+									r.count = 0
 									return r, true
 								default:
 									return
 								}
 							}
 
-						case 'u': // Prefix: "unarchive"
-
-							if l := len("unarchive"); len(elem) >= l && elem[0:l] == "unarchive" {
+							elem = origElem
+						case 'o': // Prefix: "order"
+							origElem := elem
+							if l := len("order"); len(elem) >= l && elem[0:l] == "order" {
 								elem = elem[l:]
 							} else {
 								break
@@ -557,18 +810,187 @@ func (s *Server) FindPath(method string, u *url.URL) (r Route, _ bool) {
 								// Leaf node.
 								switch method {
 								case "PATCH":
-									r.name = UnarchiveTodoOperation
-									r.summary = "Unarchive a ToDo"
-									r.operationID = "unarchiveTodo"
-									r.pathPattern = "/todos/{todoId}/unarchive"
+									r.name = UpdateTodoOrderOperation
+									r.summary = "Update the sort order of multiple ToDos"
+									r.operationID = "updateTodoOrder"
+									r.pathPattern = "/todos/order"
 									r.args = args
-									r.count = 1
+									r.count = 0
 									return r, true
 								default:
 									return
 								}
 							}
 
+							elem = origElem
+						}
+						// Param: "todoId"
+						// Match until "/"
+						idx := strings.IndexByte(elem, '/')
+						if idx < 0 {
+							idx = len(elem)
+						}
+						args[0] = elem[:idx]
+						elem = elem[idx:]
+
+						if len(elem) == 0 {
+							switch method {
+							case "DELETE":
+								r.name = ArchiveTodoOperation
+								r.summary = "Archive a ToDo"
+								r.operationID = "archiveTodo"
+								r.pathPattern = "/todos/{todoId}"
+								r.args = args
+								r.count = 1
+								return r, true
+							case "PUT":
+								r.name = UpdateTodoOperation
+								r.summary = "Update an existing ToDo"
+								r.operationID = "updateTodo"
+								r.pathPattern = "/todos/{todoId}"
+								r.args = args
+								r.count = 1
+								return r, true
+							default:
+								return
+							}
+						}
+						switch elem[0] {
+						case '/': // Prefix: "/"
+
+							if l := len("/"); len(elem) >= l && elem[0:l] == "/" {
+								elem = elem[l:]
+							} else {
+								break
+							}
+
+							if len(elem) == 0 {
+								break
+							}
+							switch elem[0] {
+							case 'd': // Prefix: "due"
+
+								if l := len("due"); len(elem) >= l && elem[0:l] == "due" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch method {
+									case "DELETE":
+										r.name = ClearTodoDueOperation
+										r.summary = "Clear the due date of a ToDo"
+										r.operationID = "clearTodoDue"
+										r.pathPattern = "/todos/{todoId}/due"
+										r.args = args
+										r.count = 1
+										return r, true
+									case "PUT":
+										r.name = SetTodoDueOperation
+										r.summary = "Set the due date of a ToDo"
+										r.operationID = "setTodoDue"
+										r.pathPattern = "/todos/{todoId}/due"
+										r.args = args
+										r.count = 1
+										return r, true
+									default:
+										return
+									}
+								}
+
+							case 's': // Prefix: "status"
+
+								if l := len("status"); len(elem) >= l && elem[0:l] == "status" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch method {
+									case "PATCH":
+										r.name = UpdateTodoStatusOperation
+										r.summary = "Update the status of a ToDo"
+										r.operationID = "updateTodoStatus"
+										r.pathPattern = "/todos/{todoId}/status"
+										r.args = args
+										r.count = 1
+										return r, true
+									default:
+										return
+									}
+								}
+
+							case 't': // Prefix: "tags/"
+
+								if l := len("tags/"); len(elem) >= l && elem[0:l] == "tags/" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								// Param: "tagId"
+								// Leaf parameter, slashes are prohibited
+								idx := strings.IndexByte(elem, '/')
+								if idx >= 0 {
+									break
+								}
+								args[1] = elem
+								elem = ""
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch method {
+									case "DELETE":
+										r.name = DetachTodoTagOperation
+										r.summary = "Detach a tag from a ToDo"
+										r.operationID = "detachTodoTag"
+										r.pathPattern = "/todos/{todoId}/tags/{tagId}"
+										r.args = args
+										r.count = 2
+										return r, true
+									case "PUT":
+										r.name = AttachTodoTagOperation
+										r.summary = "Attach a tag to a ToDo"
+										r.operationID = "attachTodoTag"
+										r.pathPattern = "/todos/{todoId}/tags/{tagId}"
+										r.args = args
+										r.count = 2
+										return r, true
+									default:
+										return
+									}
+								}
+
+							case 'u': // Prefix: "unarchive"
+
+								if l := len("unarchive"); len(elem) >= l && elem[0:l] == "unarchive" {
+									elem = elem[l:]
+								} else {
+									break
+								}
+
+								if len(elem) == 0 {
+									// Leaf node.
+									switch method {
+									case "PATCH":
+										r.name = UnarchiveTodoOperation
+										r.summary = "Unarchive a ToDo"
+										r.operationID = "unarchiveTodo"
+										r.pathPattern = "/todos/{todoId}/unarchive"
+										r.args = args
+										r.count = 1
+										return r, true
+									default:
+										return
+									}
+								}
+
+							}
+
 						}
 
 					}
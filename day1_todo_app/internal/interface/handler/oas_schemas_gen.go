@@ -4,6 +4,7 @@ package handler
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/go-faster/errors"
@@ -16,6 +17,87 @@ func (s *ErrorResponseStatusCode) Error() string {
 // ArchiveTodoNoContent is response for ArchiveTodo operation.
 type ArchiveTodoNoContent struct{}
 
+// Ref: #/components/schemas/AuthRequest
+type AuthRequest struct {
+	// User name.
+	Name string `json:"name"`
+	// Password.
+	Password string `json:"password"`
+}
+
+// GetName returns the value of Name.
+func (s *AuthRequest) GetName() string {
+	return s.Name
+}
+
+// GetPassword returns the value of Password.
+func (s *AuthRequest) GetPassword() string {
+	return s.Password
+}
+
+// SetName sets the value of Name.
+func (s *AuthRequest) SetName(val string) {
+	s.Name = val
+}
+
+// SetPassword sets the value of Password.
+func (s *AuthRequest) SetPassword(val string) {
+	s.Password = val
+}
+
+// Ref: #/components/schemas/AuthResponse
+type AuthResponse struct {
+	// JWT access token to send in the Authorization header as a Bearer token.
+	Token string `json:"token"`
+	// Expiration time of the token.
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+// GetToken returns the value of Token.
+func (s *AuthResponse) GetToken() string {
+	return s.Token
+}
+
+// GetExpiresAt returns the value of ExpiresAt.
+func (s *AuthResponse) GetExpiresAt() time.Time {
+	return s.ExpiresAt
+}
+
+// GetUser returns the value of User.
+func (s *AuthResponse) GetUser() User {
+	return s.User
+}
+
+// SetToken sets the value of Token.
+func (s *AuthResponse) SetToken(val string) {
+	s.Token = val
+}
+
+// SetExpiresAt sets the value of ExpiresAt.
+func (s *AuthResponse) SetExpiresAt(val time.Time) {
+	s.ExpiresAt = val
+}
+
+// SetUser sets the value of User.
+func (s *AuthResponse) SetUser(val User) {
+	s.User = val
+}
+
+type BearerAuth struct {
+	Token string
+}
+
+// GetToken returns the value of Token.
+func (s *BearerAuth) GetToken() string {
+	return s.Token
+}
+
+// SetToken sets the value of Token.
+func (s *BearerAuth) SetToken(val string) {
+	s.Token = val
+}
+
 // Ref: #/components/schemas/CreateTodoRequest
 type CreateTodoRequest struct {
 	// ToDo title.
@@ -44,6 +126,9 @@ func (s *CreateTodoRequest) SetDescription(val OptString) {
 	s.Description = val
 }
 
+// DeleteTagNoContent is response for DeleteTag operation.
+type DeleteTagNoContent struct{}
+
 // Ref: #/components/schemas/Error
 type Error struct {
 	// An error code.
@@ -190,6 +275,52 @@ func (o OptInt32) Or(d int32) int32 {
 	return d
 }
 
+// NewOptInt64 returns new OptInt64 with value set to v.
+func NewOptInt64(v int64) OptInt64 {
+	return OptInt64{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptInt64 is optional int64.
+type OptInt64 struct {
+	Value int64
+	Set   bool
+}
+
+// IsSet returns true if OptInt64 was set.
+func (o OptInt64) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptInt64) Reset() {
+	var v int64
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptInt64) SetTo(v int64) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptInt64) Get() (v int64, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptInt64) Or(d int64) int64 {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
 // NewOptNilDateTime returns new OptNilDateTime with value set to v.
 func NewOptNilDateTime(v time.Time) OptNilDateTime {
 	return OptNilDateTime{
@@ -299,23 +430,278 @@ func (o OptString) Or(d string) string {
 	return d
 }
 
-// SetSessionNoContent is response for SetSession operation.
-type SetSessionNoContent struct{}
+// NewOptTodoSortKey returns new OptTodoSortKey with value set to v.
+func NewOptTodoSortKey(v TodoSortKey) OptTodoSortKey {
+	return OptTodoSortKey{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptTodoSortKey is optional TodoSortKey.
+type OptTodoSortKey struct {
+	Value TodoSortKey
+	Set   bool
+}
+
+// IsSet returns true if OptTodoSortKey was set.
+func (o OptTodoSortKey) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptTodoSortKey) Reset() {
+	var v TodoSortKey
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptTodoSortKey) SetTo(v TodoSortKey) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptTodoSortKey) Get() (v TodoSortKey, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptTodoSortKey) Or(d TodoSortKey) TodoSortKey {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptTodoStatus returns new OptTodoStatus with value set to v.
+func NewOptTodoStatus(v TodoStatus) OptTodoStatus {
+	return OptTodoStatus{
+		Value: v,
+		Set:   true,
+	}
+}
 
-// Ref: #/components/schemas/SetSessionRequest
-type SetSessionRequest struct {
-	// The ID of the user to set in the session.
-	UserID int64 `json:"user_id"`
+// OptTodoStatus is optional TodoStatus.
+type OptTodoStatus struct {
+	Value TodoStatus
+	Set   bool
 }
 
-// GetUserID returns the value of UserID.
-func (s *SetSessionRequest) GetUserID() int64 {
-	return s.UserID
+// IsSet returns true if OptTodoStatus was set.
+func (o OptTodoStatus) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptTodoStatus) Reset() {
+	var v TodoStatus
+	o.Value = v
+	o.Set = false
 }
 
-// SetUserID sets the value of UserID.
-func (s *SetSessionRequest) SetUserID(val int64) {
-	s.UserID = val
+// SetTo sets value to v.
+func (o *OptTodoStatus) SetTo(v TodoStatus) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptTodoStatus) Get() (v TodoStatus, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptTodoStatus) Or(d TodoStatus) TodoStatus {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// NewOptURI returns new OptURI with value set to v.
+func NewOptURI(v url.URL) OptURI {
+	return OptURI{
+		Value: v,
+		Set:   true,
+	}
+}
+
+// OptURI is optional url.URL.
+type OptURI struct {
+	Value url.URL
+	Set   bool
+}
+
+// IsSet returns true if OptURI was set.
+func (o OptURI) IsSet() bool { return o.Set }
+
+// Reset unsets value.
+func (o *OptURI) Reset() {
+	var v url.URL
+	o.Value = v
+	o.Set = false
+}
+
+// SetTo sets value to v.
+func (o *OptURI) SetTo(v url.URL) {
+	o.Set = true
+	o.Value = v
+}
+
+// Get returns value and boolean that denotes whether value was set.
+func (o OptURI) Get() (v url.URL, ok bool) {
+	if !o.Set {
+		return v, false
+	}
+	return o.Value, true
+}
+
+// Or returns value if set, or given parameter if does not.
+func (o OptURI) Or(d url.URL) url.URL {
+	if v, ok := o.Get(); ok {
+		return v
+	}
+	return d
+}
+
+// Ref: #/components/schemas/ReminderSettings
+type ReminderSettings struct {
+	// Whether reminders are sent.
+	Enabled bool `json:"enabled"`
+	// How many minutes before the due date the reminder is sent.
+	MinutesBefore int32 `json:"minutes_before"`
+	// URL that receives a JSON POST for each reminder (optional).
+	WebhookURL OptURI `json:"webhook_url"`
+	// Email address that receives reminders (optional).
+	Email OptString `json:"email"`
+}
+
+// GetEnabled returns the value of Enabled.
+func (s *ReminderSettings) GetEnabled() bool {
+	return s.Enabled
+}
+
+// GetMinutesBefore returns the value of MinutesBefore.
+func (s *ReminderSettings) GetMinutesBefore() int32 {
+	return s.MinutesBefore
+}
+
+// GetWebhookURL returns the value of WebhookURL.
+func (s *ReminderSettings) GetWebhookURL() OptURI {
+	return s.WebhookURL
+}
+
+// GetEmail returns the value of Email.
+func (s *ReminderSettings) GetEmail() OptString {
+	return s.Email
+}
+
+// SetEnabled sets the value of Enabled.
+func (s *ReminderSettings) SetEnabled(val bool) {
+	s.Enabled = val
+}
+
+// SetMinutesBefore sets the value of MinutesBefore.
+func (s *ReminderSettings) SetMinutesBefore(val int32) {
+	s.MinutesBefore = val
+}
+
+// SetWebhookURL sets the value of WebhookURL.
+func (s *ReminderSettings) SetWebhookURL(val OptURI) {
+	s.WebhookURL = val
+}
+
+// SetEmail sets the value of Email.
+func (s *ReminderSettings) SetEmail(val OptString) {
+	s.Email = val
+}
+
+// Ref: #/components/schemas/SetTodoDueRequest
+type SetTodoDueRequest struct {
+	// New due date.
+	DueAt time.Time `json:"due_at"`
+}
+
+// GetDueAt returns the value of DueAt.
+func (s *SetTodoDueRequest) GetDueAt() time.Time {
+	return s.DueAt
+}
+
+// SetDueAt sets the value of DueAt.
+func (s *SetTodoDueRequest) SetDueAt(val time.Time) {
+	s.DueAt = val
+}
+
+// Ref: #/components/schemas/Tag
+type Tag struct {
+	// Tag ID.
+	ID int64 `json:"id"`
+	// Tag name (unique per user).
+	Name string `json:"name"`
+	// Display color as a hex code with a leading hash (empty if not set).
+	Color string `json:"color"`
+}
+
+// GetID returns the value of ID.
+func (s *Tag) GetID() int64 {
+	return s.ID
+}
+
+// GetName returns the value of Name.
+func (s *Tag) GetName() string {
+	return s.Name
+}
+
+// GetColor returns the value of Color.
+func (s *Tag) GetColor() string {
+	return s.Color
+}
+
+// SetID sets the value of ID.
+func (s *Tag) SetID(val int64) {
+	s.ID = val
+}
+
+// SetName sets the value of Name.
+func (s *Tag) SetName(val string) {
+	s.Name = val
+}
+
+// SetColor sets the value of Color.
+func (s *Tag) SetColor(val string) {
+	s.Color = val
+}
+
+// Ref: #/components/schemas/TagRequest
+type TagRequest struct {
+	// Tag name (unique per user).
+	Name string `json:"name"`
+	// Display color as a hex code with a leading hash (optional).
+	Color OptString `json:"color"`
+}
+
+// GetName returns the value of Name.
+func (s *TagRequest) GetName() string {
+	return s.Name
+}
+
+// GetColor returns the value of Color.
+func (s *TagRequest) GetColor() OptString {
+	return s.Color
+}
+
+// SetName sets the value of Name.
+func (s *TagRequest) SetName(val string) {
+	s.Name = val
+}
+
+// SetColor sets the value of Color.
+func (s *TagRequest) SetColor(val OptString) {
+	s.Color = val
 }
 
 // Ref: #/components/schemas/Todo
@@ -333,8 +719,14 @@ type Todo struct {
 	SortOrder float64 `json:"sort_order"`
 	// Timestamp when the ToDo was created.
 	CreatedAt time.Time `json:"created_at"`
+	// Due date of the ToDo (null if not set).
+	DueAt OptNilDateTime `json:"due_at"`
+	// Whether the ToDo is past due and not done/cancelled (derived).
+	Overdue bool `json:"overdue"`
 	// Timestamp when the ToDo was archived (null if not archived).
 	ArchivedAt OptNilDateTime `json:"archived_at"`
+	// Tags attached to the ToDo, ordered by name.
+	Tags []Tag `json:"tags"`
 }
 
 // GetID returns the value of ID.
@@ -372,11 +764,26 @@ func (s *Todo) GetCreatedAt() time.Time {
 	return s.CreatedAt
 }
 
+// GetDueAt returns the value of DueAt.
+func (s *Todo) GetDueAt() OptNilDateTime {
+	return s.DueAt
+}
+
+// GetOverdue returns the value of Overdue.
+func (s *Todo) GetOverdue() bool {
+	return s.Overdue
+}
+
 // GetArchivedAt returns the value of ArchivedAt.
 func (s *Todo) GetArchivedAt() OptNilDateTime {
 	return s.ArchivedAt
 }
 
+// GetTags returns the value of Tags.
+func (s *Todo) GetTags() []Tag {
+	return s.Tags
+}
+
 // SetID sets the value of ID.
 func (s *Todo) SetID(val int64) {
 	s.ID = val
@@ -412,11 +819,120 @@ func (s *Todo) SetCreatedAt(val time.Time) {
 	s.CreatedAt = val
 }
 
+// SetDueAt sets the value of DueAt.
+func (s *Todo) SetDueAt(val OptNilDateTime) {
+	s.DueAt = val
+}
+
+// SetOverdue sets the value of Overdue.
+func (s *Todo) SetOverdue(val bool) {
+	s.Overdue = val
+}
+
 // SetArchivedAt sets the value of ArchivedAt.
 func (s *Todo) SetArchivedAt(val OptNilDateTime) {
 	s.ArchivedAt = val
 }
 
+// SetTags sets the value of Tags.
+func (s *Todo) SetTags(val []Tag) {
+	s.Tags = val
+}
+
+// Ref: #/components/schemas/TodoList
+type TodoList struct {
+	Items []Todo `json:"items"`
+	// Total number of ToDos matching the filters.
+	Total int64 `json:"total"`
+	// Current page number (1-indexed).
+	Page int32 `json:"page"`
+	// Number of items per page.
+	PerPage int32 `json:"per_page"`
+}
+
+// GetItems returns the value of Items.
+func (s *TodoList) GetItems() []Todo {
+	return s.Items
+}
+
+// GetTotal returns the value of Total.
+func (s *TodoList) GetTotal() int64 {
+	return s.Total
+}
+
+// GetPage returns the value of Page.
+func (s *TodoList) GetPage() int32 {
+	return s.Page
+}
+
+// GetPerPage returns the value of PerPage.
+func (s *TodoList) GetPerPage() int32 {
+	return s.PerPage
+}
+
+// SetItems sets the value of Items.
+func (s *TodoList) SetItems(val []Todo) {
+	s.Items = val
+}
+
+// SetTotal sets the value of Total.
+func (s *TodoList) SetTotal(val int64) {
+	s.Total = val
+}
+
+// SetPage sets the value of Page.
+func (s *TodoList) SetPage(val int32) {
+	s.Page = val
+}
+
+// SetPerPage sets the value of PerPage.
+func (s *TodoList) SetPerPage(val int32) {
+	s.PerPage = val
+}
+
+// Sort key for the ToDo list.
+// Ref: #/components/schemas/TodoSortKey
+type TodoSortKey string
+
+const (
+	TodoSortKeyCreatedAt TodoSortKey = "created_at"
+	TodoSortKeyDueDate   TodoSortKey = "due_date"
+)
+
+// AllValues returns all TodoSortKey values.
+func (TodoSortKey) AllValues() []TodoSortKey {
+	return []TodoSortKey{
+		TodoSortKeyCreatedAt,
+		TodoSortKeyDueDate,
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s TodoSortKey) MarshalText() ([]byte, error) {
+	switch s {
+	case TodoSortKeyCreatedAt:
+		return []byte(s), nil
+	case TodoSortKeyDueDate:
+		return []byte(s), nil
+	default:
+		return nil, errors.Errorf("invalid value: %q", s)
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *TodoSortKey) UnmarshalText(data []byte) error {
+	switch TodoSortKey(data) {
+	case TodoSortKeyCreatedAt:
+		*s = TodoSortKeyCreatedAt
+		return nil
+	case TodoSortKeyDueDate:
+		*s = TodoSortKeyDueDate
+		return nil
+	default:
+		return errors.Errorf("invalid value: %q", data)
+	}
+}
+
 // The status of the ToDo item.
 // Ref: #/components/schemas/TodoStatus
 type TodoStatus string
@@ -14,42 +14,108 @@ type Handler interface {
 	//
 	// DELETE /todos/{todoId}
 	ArchiveTodo(ctx context.Context, params ArchiveTodoParams) error
+	// AttachTodoTag implements attachTodoTag operation.
+	//
+	// Attach a tag to a ToDo.
+	//
+	// PUT /todos/{todoId}/tags/{tagId}
+	AttachTodoTag(ctx context.Context, params AttachTodoTagParams) (*Todo, error)
+	// ClearTodoDue implements clearTodoDue operation.
+	//
+	// Clear the due date of a ToDo.
+	//
+	// DELETE /todos/{todoId}/due
+	ClearTodoDue(ctx context.Context, params ClearTodoDueParams) (*Todo, error)
+	// CreateTag implements createTag operation.
+	//
+	// Create a new tag.
+	//
+	// POST /tags
+	CreateTag(ctx context.Context, req *TagRequest) (*Tag, error)
 	// CreateTodo implements createTodo operation.
 	//
 	// Create a new ToDo.
 	//
 	// POST /todos
 	CreateTodo(ctx context.Context, req *CreateTodoRequest) (*Todo, error)
+	// DeleteTag implements deleteTag operation.
+	//
+	// Delete a tag (also removes it from all ToDos).
+	//
+	// DELETE /tags/{tagId}
+	DeleteTag(ctx context.Context, params DeleteTagParams) error
+	// DetachTodoTag implements detachTodoTag operation.
+	//
+	// Detach a tag from a ToDo.
+	//
+	// DELETE /todos/{todoId}/tags/{tagId}
+	DetachTodoTag(ctx context.Context, params DetachTodoTagParams) (*Todo, error)
 	// GetArchivedTodos implements getArchivedTodos operation.
 	//
 	// Get list of archived ToDos for the current user.
 	//
 	// GET /todos/archived
 	GetArchivedTodos(ctx context.Context, params GetArchivedTodosParams) ([]Todo, error)
+	// GetReminderSettings implements getReminderSettings operation.
+	//
+	// Get the reminder settings of the current user.
+	//
+	// GET /reminder-settings
+	GetReminderSettings(ctx context.Context) (*ReminderSettings, error)
+	// GetTags implements getTags operation.
+	//
+	// Get the tags of the current user.
+	//
+	// GET /tags
+	GetTags(ctx context.Context) ([]Tag, error)
 	// GetTodos implements getTodos operation.
 	//
 	// Get list of ToDos for the current user.
 	//
 	// GET /todos
-	GetTodos(ctx context.Context, params GetTodosParams) ([]Todo, error)
+	GetTodos(ctx context.Context, params GetTodosParams) (*TodoList, error)
 	// GetUsers implements getUsers operation.
 	//
 	// Get list of users.
 	//
 	// GET /users
 	GetUsers(ctx context.Context) ([]User, error)
-	// SetSession implements setSession operation.
+	// Login implements login operation.
+	//
+	// Log in and issue an access token.
+	//
+	// POST /auth/login
+	Login(ctx context.Context, req *AuthRequest) (*AuthResponse, error)
+	// Register implements register operation.
+	//
+	// Register a new user and issue an access token.
 	//
-	// Set current user session.
+	// POST /auth/register
+	Register(ctx context.Context, req *AuthRequest) (*AuthResponse, error)
+	// SetTodoDue implements setTodoDue operation.
 	//
-	// POST /session
-	SetSession(ctx context.Context, req *SetSessionRequest) error
+	// Set the due date of a ToDo.
+	//
+	// PUT /todos/{todoId}/due
+	SetTodoDue(ctx context.Context, req *SetTodoDueRequest, params SetTodoDueParams) (*Todo, error)
 	// UnarchiveTodo implements unarchiveTodo operation.
 	//
 	// Unarchive a ToDo.
 	//
 	// PATCH /todos/{todoId}/unarchive
 	UnarchiveTodo(ctx context.Context, params UnarchiveTodoParams) (*Todo, error)
+	// UpdateReminderSettings implements updateReminderSettings operation.
+	//
+	// Update the reminder settings of the current user.
+	//
+	// PUT /reminder-settings
+	UpdateReminderSettings(ctx context.Context, req *ReminderSettings) (*ReminderSettings, error)
+	// UpdateTag implements updateTag operation.
+	//
+	// Update a tag.
+	//
+	// PUT /tags/{tagId}
+	UpdateTag(ctx context.Context, req *TagRequest, params UpdateTagParams) (*Tag, error)
 	// UpdateTodo implements updateTodo operation.
 	//
 	// Update an existing ToDo.
@@ -77,18 +143,20 @@ type Handler interface {
 // Server implements http server based on OpenAPI v3 specification and
 // calls Handler to handle requests.
 type Server struct {
-	h Handler
+	h   Handler
+	sec SecurityHandler
 	baseServer
 }
 
 // NewServer creates new Server.
-func NewServer(h Handler, opts ...ServerOption) (*Server, error) {
+func NewServer(h Handler, sec SecurityHandler, opts ...ServerOption) (*Server, error) {
 	s, err := newServerConfig(opts...).baseServer()
 	if err != nil {
 		return nil, err
 	}
 	return &Server{
 		h:          h,
+		sec:        sec,
 		baseServer: s,
 	}, nil
 }
@@ -22,6 +22,33 @@ func (UnimplementedHandler) ArchiveTodo(ctx context.Context, params ArchiveTodoP
 	return ht.ErrNotImplemented
 }
 
+// AttachTodoTag implements attachTodoTag operation.
+//
+// Attach a tag to a ToDo.
+//
+// PUT /todos/{todoId}/tags/{tagId}
+func (UnimplementedHandler) AttachTodoTag(ctx context.Context, params AttachTodoTagParams) (r *Todo, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// ClearTodoDue implements clearTodoDue operation.
+//
+// Clear the due date of a ToDo.
+//
+// DELETE /todos/{todoId}/due
+func (UnimplementedHandler) ClearTodoDue(ctx context.Context, params ClearTodoDueParams) (r *Todo, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// CreateTag implements createTag operation.
+//
+// Create a new tag.
+//
+// POST /tags
+func (UnimplementedHandler) CreateTag(ctx context.Context, req *TagRequest) (r *Tag, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // CreateTodo implements createTodo operation.
 //
 // Create a new ToDo.
@@ -31,6 +58,24 @@ func (UnimplementedHandler) CreateTodo(ctx context.Context, req *CreateTodoReque
 	return r, ht.ErrNotImplemented
 }
 
+// DeleteTag implements deleteTag operation.
+//
+// Delete a tag (also removes it from all ToDos).
+//
+// DELETE /tags/{tagId}
+func (UnimplementedHandler) DeleteTag(ctx context.Context, params DeleteTagParams) error {
+	return ht.ErrNotImplemented
+}
+
+// DetachTodoTag implements detachTodoTag operation.
+//
+// Detach a tag from a ToDo.
+//
+// DELETE /todos/{todoId}/tags/{tagId}
+func (UnimplementedHandler) DetachTodoTag(ctx context.Context, params DetachTodoTagParams) (r *Todo, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // GetArchivedTodos implements getArchivedTodos operation.
 //
 // Get list of archived ToDos for the current user.
@@ -40,12 +85,30 @@ func (UnimplementedHandler) GetArchivedTodos(ctx context.Context, params GetArch
 	return r, ht.ErrNotImplemented
 }
 
+// GetReminderSettings implements getReminderSettings operation.
+//
+// Get the reminder settings of the current user.
+//
+// GET /reminder-settings
+func (UnimplementedHandler) GetReminderSettings(ctx context.Context) (r *ReminderSettings, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// GetTags implements getTags operation.
+//
+// Get the tags of the current user.
+//
+// GET /tags
+func (UnimplementedHandler) GetTags(ctx context.Context) (r []Tag, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // GetTodos implements getTodos operation.
 //
 // Get list of ToDos for the current user.
 //
 // GET /todos
-func (UnimplementedHandler) GetTodos(ctx context.Context, params GetTodosParams) (r []Todo, _ error) {
+func (UnimplementedHandler) GetTodos(ctx context.Context, params GetTodosParams) (r *TodoList, _ error) {
 	return r, ht.ErrNotImplemented
 }
 
@@ -58,13 +121,31 @@ func (UnimplementedHandler) GetUsers(ctx context.Context) (r []User, _ error) {
 	return r, ht.ErrNotImplemented
 }
 
-// SetSession implements setSession operation.
+// Login implements login operation.
 //
-// Set current user session.
+// Log in and issue an access token.
 //
-// POST /session
-func (UnimplementedHandler) SetSession(ctx context.Context, req *SetSessionRequest) error {
-	return ht.ErrNotImplemented
+// POST /auth/login
+func (UnimplementedHandler) Login(ctx context.Context, req *AuthRequest) (r *AuthResponse, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// Register implements register operation.
+//
+// Register a new user and issue an access token.
+//
+// POST /auth/register
+func (UnimplementedHandler) Register(ctx context.Context, req *AuthRequest) (r *AuthResponse, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// SetTodoDue implements setTodoDue operation.
+//
+// Set the due date of a ToDo.
+//
+// PUT /todos/{todoId}/due
+func (UnimplementedHandler) SetTodoDue(ctx context.Context, req *SetTodoDueRequest, params SetTodoDueParams) (r *Todo, _ error) {
+	return r, ht.ErrNotImplemented
 }
 
 // UnarchiveTodo implements unarchiveTodo operation.
@@ -76,6 +157,24 @@ func (UnimplementedHandler) UnarchiveTodo(ctx context.Context, params UnarchiveT
 	return r, ht.ErrNotImplemented
 }
 
+// UpdateReminderSettings implements updateReminderSettings operation.
+//
+// Update the reminder settings of the current user.
+//
+// PUT /reminder-settings
+func (UnimplementedHandler) UpdateReminderSettings(ctx context.Context, req *ReminderSettings) (r *ReminderSettings, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
+// UpdateTag implements updateTag operation.
+//
+// Update a tag.
+//
+// PUT /tags/{tagId}
+func (UnimplementedHandler) UpdateTag(ctx context.Context, req *TagRequest, params UpdateTagParams) (r *Tag, _ error) {
+	return r, ht.ErrNotImplemented
+}
+
 // UpdateTodo implements updateTodo operation.
 //
 // Update an existing ToDo.
@@ -5,7 +5,9 @@ import (
 	"html/template"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"strconv"
 
 	domainModel "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
 	domainRepo "github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
@@ -17,12 +19,18 @@ import (
 type WebHandler struct {
 	tmpl        *template.Template
 	todoUsecase usecase.TodoUsecase
+	tagUsecase  usecase.TagUsecase
+	verifier    TokenVerifier
 	userRepo    domainRepo.UserRepository
 	logger      *slog.Logger
 }
 
+// tokenCookieName はログイン後にブラウザが JWT を保存する Cookie の名前です。
+// ページの JavaScript も同じ値を Authorization ヘッダーに付けて API を呼び出します。
+const tokenCookieName = "token"
+
 // NewWebHandler は新しい WebHandler を生成します。
-func NewWebHandler(tu usecase.TodoUsecase, ur domainRepo.UserRepository) (*WebHandler, error) {
+func NewWebHandler(tu usecase.TodoUsecase, tgu usecase.TagUsecase, v TokenVerifier, ur domainRepo.UserRepository) (*WebHandler, error) {
 	layoutPath := filepath.Join("web", "templates", "layout.html")
 	indexPath := filepath.Join("web", "templates", "index.html")
 	itemPath := filepath.Join("web", "templates", "_todo_item.html")
@@ -48,6 +56,8 @@ func NewWebHandler(tu usecase.TodoUsecase, ur domainRepo.UserRepository) (*WebHa
 	return &WebHandler{
 		tmpl:        tmpl,
 		todoUsecase: tu,
+		tagUsecase:  tgu,
+		verifier:    v,
 		userRepo:    ur,
 		logger:      slog.Default().WithGroup("handler.web"),
 	}, nil
@@ -58,22 +68,42 @@ func (h *WebHandler) Index(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	h.logger.InfoContext(ctx, "serving index page")
 
-	// TODO: ユーザー選択機能から現在のユーザーIDを取得する
-	currentUserID := int64(1) // 仮で ID 1 のユーザーとする
+	// Cookie のトークンから現在のユーザーを取得する。未ログインの場合はログインフォームを表示する
+	currentUser := h.currentUser(r)
+	if currentUser == nil {
+		if err := h.tmpl.ExecuteTemplate(w, "base", map[string]interface{}{"CurrentUser": nil}); err != nil {
+			h.logger.ErrorContext(ctx, "failed to execute template", "error", err)
+			http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		}
+		return
+	}
+	currentUserID := currentUser.ID
 
-	// ユーザーリストを取得 (ユーザー選択用)
-	users, err := h.userRepo.FindAll(ctx)
-	if err != nil {
-		h.logger.ErrorContext(ctx, "failed to get users for index page", "error", err)
-		users = []*domainModel.User{}
+	// タグ一覧を取得 (絞り込み用)
+	var tags []*domainModel.Tag
+	if tagsOutput, err := h.tagUsecase.ListTags(ctx, usecase.ListTagsInput{UserID: currentUserID}); err != nil {
+		h.logger.ErrorContext(ctx, "failed to get tags for index page", "error", err)
+	} else {
+		tags = tagsOutput.Tags
 	}
 
 	// 現在のユーザーの ToDo リストを取得 (アーカイブ済みは除く)
+	// ページ番号・絞り込み・ソートはクエリパラメータから受け取る
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
 	getTodosInput := usecase.GetTodosInput{
 		UserID:          currentUserID,
-		Limit:           100, // とりあえず100件
-		Page:            1,
+		Page:            page,
 		IncludeArchived: false,
+		Query:           q.Get("q"),
+		Sort:            domainRepo.TodoSortKey(q.Get("sort")),
+		Overdue:         q.Get("overdue") == "true",
+	}
+	if status := domainModel.TodoStatus(q.Get("status")); status != "" {
+		getTodosInput.Status = &status
+	}
+	if tagID, err := strconv.ParseInt(q.Get("tag_id"), 10, 64); err == nil {
+		getTodosInput.TagID = &tagID
 	}
 	output, err := h.todoUsecase.GetTodos(ctx, getTodosInput)
 	if err != nil {
@@ -82,27 +112,29 @@ func (h *WebHandler) Index(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Todos と Users を JSON 文字列にマーシャリング
+	// Todos を JSON 文字列にマーシャリング
 	todosJSON, err := json.Marshal(output.Todos)
 	if err != nil {
 		h.logger.ErrorContext(ctx, "failed to marshal todos to JSON", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	usersJSON, err := json.Marshal(users)
-	if err != nil {
-		h.logger.ErrorContext(ctx, "failed to marshal users to JSON", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
 
 	// テンプレートに渡すデータ
 	data := map[string]interface{}{
-		"TodosJSON":     string(todosJSON), // JSON 文字列として渡す
-		"UsersJSON":     string(usersJSON), // JSON 文字列として渡す
-		"CurrentUserID": currentUserID,
+		"TodosJSON":   string(todosJSON), // JSON 文字列として渡す
+		"CurrentUser": currentUser,
+		"Filter": map[string]string{
+			"Status":  q.Get("status"),
+			"Q":       q.Get("q"),
+			"Sort":    q.Get("sort"),
+			"Overdue": q.Get("overdue"),
+			"TagID":   q.Get("tag_id"),
+		},
+		"Tags":       tags,
+		"Statuses":   []domainModel.TodoStatus{domainModel.TodoStatusNotStarted, domainModel.TodoStatusInProgress, domainModel.TodoStatusDone, domainModel.TodoStatusPending, domainModel.TodoStatusCancel},
+		"Pagination": newPagination(r.URL, output),
 		// "Todos": output.Todos, // 元のデータも必要なら渡す (今回は JSON のみ)
-		// "Users": users,
 	}
 
 	// base テンプレートを起点として実行
@@ -111,3 +143,60 @@ func (h *WebHandler) Index(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to render page", http.StatusInternalServerError)
 	}
 }
+
+// currentUser は Cookie のトークンを検証し、ログイン中のユーザーを返します。未ログインまたは無効なトークンの場合は nil を返します。
+func (h *WebHandler) currentUser(r *http.Request) *domainModel.User {
+	ctx := r.Context()
+
+	cookie, err := r.Cookie(tokenCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	userID, err := h.verifier.Verify(cookie.Value)
+	if err != nil {
+		h.logger.InfoContext(ctx, "invalid token cookie", "error", err)
+		return nil
+	}
+	user, err := h.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "failed to find current user", "error", err, "userID", userID)
+		return nil
+	}
+	return user
+}
+
+// pagination はページ送りリンクの表示用データです。
+type pagination struct {
+	Page       int
+	TotalPages int
+	Total      int64
+	PrevURL    string // 前のページがない場合は空
+	NextURL    string // 次のページがない場合は空
+}
+
+// newPagination は現在の URL のクエリを引き継いだページ送りリンクを生成します。
+func newPagination(current *url.URL, output *usecase.GetTodosOutput) pagination {
+	totalPages := int((output.Total + int64(output.Limit) - 1) / int64(output.Limit))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	pageURL := func(page int) string {
+		q := current.Query()
+		q.Set("page", strconv.Itoa(page))
+		return "/?" + q.Encode()
+	}
+
+	p := pagination{
+		Page:       output.Page,
+		TotalPages: totalPages,
+		Total:      output.Total,
+	}
+	if output.Page > 1 {
+		p.PrevURL = pageURL(output.Page - 1)
+	}
+	if output.Page < totalPages {
+		p.NextURL = pageURL(output.Page + 1)
+	}
+	return p
+}
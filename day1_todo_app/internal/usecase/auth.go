@@ -0,0 +1,140 @@
+// internal/usecase/auth.go
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
+	"github.com/m-mizutani/goerr"
+)
+
+// TokenIssuer はログインしたユーザーのアクセストークンを発行するインターフェースです。
+type TokenIssuer interface {
+	Issue(userID int64) (token string, expiresAt time.Time, err error)
+}
+
+// RegisterInput はユーザー登録の入力です。
+type RegisterInput struct {
+	Name     string
+	Password string
+}
+
+// LoginInput はログインの入力です。
+type LoginInput struct {
+	Name     string
+	Password string
+}
+
+// AuthOutput はユーザー登録・ログインの出力です。
+type AuthOutput struct {
+	User      *model.User
+	Token     string
+	ExpiresAt time.Time
+}
+
+// AuthUsecase は認証に関連するユースケースを定義するインターフェースです。
+type AuthUsecase interface {
+	Register(ctx context.Context, input RegisterInput) (*AuthOutput, error)
+	Login(ctx context.Context, input LoginInput) (*AuthOutput, error)
+}
+
+// authUsecase は AuthUsecase の実装です。
+type authUsecase struct {
+	userRepo repository.UserRepository
+	issuer   TokenIssuer
+	logger   *slog.Logger
+}
+
+// NewAuthUsecase は新しい authUsecase を生成します。
+func NewAuthUsecase(userRepo repository.UserRepository, issuer TokenIssuer) AuthUsecase {
+	return &authUsecase{
+		userRepo: userRepo,
+		issuer:   issuer,
+		logger:   slog.Default().WithGroup("usecase.auth"),
+	}
+}
+
+// Register は新しいユーザーを登録し、アクセストークンを発行します。
+func (uc *authUsecase) Register(ctx context.Context, input RegisterInput) (*AuthOutput, error) {
+	name := strings.TrimSpace(input.Name)
+	uc.logger.InfoContext(ctx, "registering user", "name", name)
+
+	if !model.IsValidUserName(name) {
+		uc.logger.WarnContext(ctx, "invalid user name provided", "name", name)
+		return nil, goerr.New("invalid user name").With("name", name)
+	}
+	if !model.IsValidPassword(input.Password) {
+		uc.logger.WarnContext(ctx, "invalid password provided", "name", name)
+		return nil, goerr.New("invalid password").With("minLength", model.MinPasswordLength).With("maxBytes", model.MaxPasswordLength)
+	}
+
+	existing, err := uc.userRepo.FindByName(ctx, name)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find user by name", "error", err, "name", name)
+		return nil, goerr.Wrap(err, "failed to find user by name")
+	}
+	if existing != nil {
+		uc.logger.WarnContext(ctx, "user name already exists", "name", name)
+		return nil, goerr.Wrap(ErrAlreadyExists, "user name is already taken").With("name", name)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to hash password")
+	}
+
+	user := &model.User{
+		Name:         name,
+		PasswordHash: string(hash),
+	}
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to create user", "error", err, "name", name)
+		return nil, goerr.Wrap(err, "failed to create user in repository")
+	}
+
+	uc.logger.InfoContext(ctx, "user registered successfully", "userID", user.ID)
+	return uc.issue(user)
+}
+
+// Login はユーザー名とパスワードを検証し、アクセストークンを発行します。
+// ユーザーが存在しない場合とパスワードが違う場合は区別せず ErrUnauthenticated を返します。
+func (uc *authUsecase) Login(ctx context.Context, input LoginInput) (*AuthOutput, error) {
+	name := strings.TrimSpace(input.Name)
+	uc.logger.InfoContext(ctx, "logging in", "name", name)
+
+	user, err := uc.userRepo.FindByName(ctx, name)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find user by name", "error", err, "name", name)
+		return nil, goerr.Wrap(err, "failed to find user by name")
+	}
+	if user == nil || user.PasswordHash == "" {
+		uc.logger.WarnContext(ctx, "login failed: user not found or password not set", "name", name)
+		return nil, goerr.Wrap(ErrUnauthenticated, "invalid name or password")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
+		uc.logger.WarnContext(ctx, "login failed: password mismatch", "userID", user.ID)
+		return nil, goerr.Wrap(ErrUnauthenticated, "invalid name or password")
+	}
+
+	uc.logger.InfoContext(ctx, "user logged in successfully", "userID", user.ID)
+	return uc.issue(user)
+}
+
+// issue はユーザーのアクセストークンを発行します。
+func (uc *authUsecase) issue(user *model.User) (*AuthOutput, error) {
+	token, expiresAt, err := uc.issuer.Issue(user.ID)
+	if err != nil {
+		return nil, goerr.Wrap(err, "failed to issue access token").With("userID", user.ID)
+	}
+	return &AuthOutput{
+		User:      user,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
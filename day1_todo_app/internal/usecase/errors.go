@@ -0,0 +1,18 @@
+// internal/usecase/errors.go
+package usecase
+
+import "github.com/m-mizutani/goerr"
+
+// ユースケースが返すエラーの種類です。
+// handler は errors.Is で判定して HTTP ステータスコードに変換します。
+// 詳細を付ける場合は goerr.Wrap(ErrXxx, "...").With(...) のようにラップして返します。
+var (
+	// ErrUnauthenticated は認証に失敗したことを表します。
+	ErrUnauthenticated = goerr.New("unauthenticated").ID("unauthenticated")
+	// ErrPermissionDenied は他のユーザーのリソースを操作しようとしたことを表します。
+	ErrPermissionDenied = goerr.New("permission denied").ID("permission_denied")
+	// ErrNotFound は対象のリソースが存在しないことを表します。
+	ErrNotFound = goerr.New("not found").ID("not_found")
+	// ErrAlreadyExists は同じ名前のリソースがすでに存在することを表します。
+	ErrAlreadyExists = goerr.New("already exists").ID("already_exists")
+)
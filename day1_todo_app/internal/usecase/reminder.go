@@ -0,0 +1,178 @@
+// internal/usecase/reminder.go
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
+	"github.com/m-mizutani/goerr"
+)
+
+// ReminderNotifier はリマインダーを設定された通知先 (Webhook / メール) に送信するインターフェースです。
+type ReminderNotifier interface {
+	NotifyReminder(ctx context.Context, setting *model.ReminderSetting, todo *model.Todo) error
+}
+
+// GetReminderSettingInput はリマインダー設定取得の入力です。
+type GetReminderSettingInput struct {
+	UserID int64
+}
+
+// UpdateReminderSettingInput はリマインダー設定更新の入力です。
+type UpdateReminderSettingInput struct {
+	UserID        int64
+	Enabled       bool
+	MinutesBefore int
+	WebhookURL    string
+	Email         string
+}
+
+// ReminderSettingOutput はリマインダー設定の取得・更新の出力です。
+type ReminderSettingOutput struct {
+	Setting *model.ReminderSetting
+}
+
+// SendDueRemindersOutput はリマインダー送信処理の出力です。
+type SendDueRemindersOutput struct {
+	Sent   int // 送信に成功した件数
+	Failed int // 送信に失敗した件数 (次回の実行で再送される)
+}
+
+// ReminderUsecase はリマインダーに関連するユースケースを定義するインターフェースです。
+type ReminderUsecase interface {
+	GetReminderSetting(ctx context.Context, input GetReminderSettingInput) (*ReminderSettingOutput, error)
+	UpdateReminderSetting(ctx context.Context, input UpdateReminderSettingInput) (*ReminderSettingOutput, error)
+	// SendDueReminders は now の時点で通知タイミングを迎えた ToDo のリマインダーを送信します。
+	SendDueReminders(ctx context.Context, now time.Time) (*SendDueRemindersOutput, error)
+}
+
+// reminderUsecase は ReminderUsecase の実装です。
+type reminderUsecase struct {
+	todoRepo    repository.TodoRepository
+	settingRepo repository.ReminderSettingRepository
+	notifier    ReminderNotifier
+	logger      *slog.Logger
+}
+
+// NewReminderUsecase は新しい reminderUsecase を生成します。
+func NewReminderUsecase(todoRepo repository.TodoRepository, settingRepo repository.ReminderSettingRepository, notifier ReminderNotifier) ReminderUsecase {
+	return &reminderUsecase{
+		todoRepo:    todoRepo,
+		settingRepo: settingRepo,
+		notifier:    notifier,
+		logger:      slog.Default().WithGroup("usecase.reminder"),
+	}
+}
+
+// GetReminderSetting はユーザーのリマインダー設定を取得します。未登録の場合はデフォルト設定を返します。
+func (uc *reminderUsecase) GetReminderSetting(ctx context.Context, input GetReminderSettingInput) (*ReminderSettingOutput, error) {
+	uc.logger.InfoContext(ctx, "getting reminder setting", "userID", input.UserID)
+
+	setting, err := uc.settingRepo.FindByUserID(ctx, input.UserID)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find reminder setting", "error", err, "userID", input.UserID)
+		return nil, goerr.Wrap(err, "failed to get reminder setting from repository")
+	}
+	if setting == nil {
+		setting = model.NewDefaultReminderSetting(input.UserID)
+	}
+	return &ReminderSettingOutput{Setting: setting}, nil
+}
+
+// UpdateReminderSetting はユーザーのリマインダー設定を更新します。
+func (uc *reminderUsecase) UpdateReminderSetting(ctx context.Context, input UpdateReminderSettingInput) (*ReminderSettingOutput, error) {
+	uc.logger.InfoContext(ctx, "updating reminder setting", "userID", input.UserID, "enabled", input.Enabled, "minutesBefore", input.MinutesBefore)
+
+	setting := &model.ReminderSetting{
+		UserID:        input.UserID,
+		Enabled:       input.Enabled,
+		MinutesBefore: input.MinutesBefore,
+		WebhookURL:    strings.TrimSpace(input.WebhookURL),
+		Email:         strings.TrimSpace(input.Email),
+	}
+	if err := validateReminderSetting(setting); err != nil {
+		uc.logger.WarnContext(ctx, "invalid reminder setting provided", "error", err, "userID", input.UserID)
+		return nil, err
+	}
+
+	if err := uc.settingRepo.Save(ctx, setting); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to save reminder setting", "error", err, "userID", input.UserID)
+		return nil, goerr.Wrap(err, "failed to save reminder setting in repository")
+	}
+
+	uc.logger.InfoContext(ctx, "reminder setting updated successfully", "userID", input.UserID)
+	return &ReminderSettingOutput{Setting: setting}, nil
+}
+
+// SendDueReminders はリマインダーが有効なユーザーごとに、期限の MinutesBefore 分前を過ぎた
+// 未送信の ToDo へリマインダーを送信します。送信に失敗した ToDo は記録せず、次回の実行で再送します。
+func (uc *reminderUsecase) SendDueReminders(ctx context.Context, now time.Time) (*SendDueRemindersOutput, error) {
+	settings, err := uc.settingRepo.FindEnabled(ctx)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find enabled reminder settings", "error", err)
+		return nil, goerr.Wrap(err, "failed to get enabled reminder settings from repository")
+	}
+
+	output := &SendDueRemindersOutput{}
+	for _, setting := range settings {
+		if !setting.HasDestination() {
+			continue
+		}
+
+		until := now.Add(time.Duration(setting.MinutesBefore) * time.Minute)
+		todos, err := uc.todoRepo.FindReminderTargets(ctx, setting.UserID, now, until)
+		if err != nil {
+			uc.logger.ErrorContext(ctx, "failed to find reminder targets", "error", err, "userID", setting.UserID)
+			return output, goerr.Wrap(err, "failed to get reminder targets from repository").With("userID", setting.UserID)
+		}
+
+		for _, todo := range todos {
+			if err := uc.notifier.NotifyReminder(ctx, setting, todo); err != nil {
+				uc.logger.WarnContext(ctx, "failed to send reminder", "error", err, "todoID", todo.ID, "userID", setting.UserID)
+				output.Failed++
+				continue
+			}
+			if err := uc.todoRepo.MarkReminded(ctx, todo.ID, now); err != nil {
+				uc.logger.ErrorContext(ctx, "failed to mark todo as reminded", "error", err, "todoID", todo.ID)
+				return output, goerr.Wrap(err, "failed to mark todo as reminded").With("todoID", todo.ID)
+			}
+			output.Sent++
+		}
+	}
+
+	if output.Sent > 0 || output.Failed > 0 {
+		uc.logger.InfoContext(ctx, "due reminders processed", "sent", output.Sent, "failed", output.Failed)
+	}
+	return output, nil
+}
+
+// validateReminderSetting はリマインダー設定の値を検証します。
+func validateReminderSetting(s *model.ReminderSetting) error {
+	if s.MinutesBefore < model.MinReminderMinutesBefore || s.MinutesBefore > model.MaxReminderMinutesBefore {
+		return goerr.New("minutes_before is out of range").
+			With("minutesBefore", s.MinutesBefore).
+			With("min", model.MinReminderMinutesBefore).
+			With("max", model.MaxReminderMinutesBefore)
+	}
+	if s.WebhookURL != "" {
+		u, err := url.Parse(s.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return goerr.New("invalid webhook url").With("webhookURL", s.WebhookURL)
+		}
+	}
+	if s.Email != "" {
+		if _, err := mail.ParseAddress(s.Email); err != nil {
+			return goerr.Wrap(err, "invalid email address").With("email", s.Email)
+		}
+	}
+	if s.Enabled && !s.HasDestination() {
+		return goerr.New("webhook url or email is required to enable reminders")
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+// internal/usecase/tag.go
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
+	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/repository"
+	"github.com/m-mizutani/goerr"
+)
+
+// ListTagsInput はタグ一覧取得の入力です。
+type ListTagsInput struct {
+	UserID int64
+}
+
+// ListTagsOutput はタグ一覧取得の出力です。
+type ListTagsOutput struct {
+	Tags []*model.Tag
+}
+
+// CreateTagInput はタグ作成の入力です。
+type CreateTagInput struct {
+	UserID int64
+	Name   string
+	Color  string
+}
+
+// UpdateTagInput はタグ更新の入力です。
+type UpdateTagInput struct {
+	ID     int64
+	UserID int64 // 権限チェック用
+	Name   string
+	Color  string
+}
+
+// TagOutput はタグ作成・更新の出力です。
+type TagOutput struct {
+	Tag *model.Tag
+}
+
+// DeleteTagInput はタグ削除の入力です。
+type DeleteTagInput struct {
+	ID     int64
+	UserID int64 // 権限チェック用
+}
+
+// TodoTagInput は ToDo へのタグ付与・解除の入力です。
+type TodoTagInput struct {
+	TodoID int64
+	TagID  int64
+	UserID int64 // 権限チェック用
+}
+
+// TodoTagOutput は ToDo へのタグ付与・解除の出力です。
+type TodoTagOutput struct {
+	Todo *model.Todo
+}
+
+// TagUsecase はタグに関連するユースケースを定義するインターフェースです。
+type TagUsecase interface {
+	ListTags(ctx context.Context, input ListTagsInput) (*ListTagsOutput, error)
+	CreateTag(ctx context.Context, input CreateTagInput) (*TagOutput, error)
+	UpdateTag(ctx context.Context, input UpdateTagInput) (*TagOutput, error)
+	DeleteTag(ctx context.Context, input DeleteTagInput) error
+	AttachTag(ctx context.Context, input TodoTagInput) (*TodoTagOutput, error)
+	DetachTag(ctx context.Context, input TodoTagInput) (*TodoTagOutput, error)
+}
+
+// tagUsecase は TagUsecase の実装です。
+type tagUsecase struct {
+	tagRepo  repository.TagRepository
+	todoRepo repository.TodoRepository
+	logger   *slog.Logger
+}
+
+// NewTagUsecase は新しい tagUsecase を生成します。
+func NewTagUsecase(tagRepo repository.TagRepository, todoRepo repository.TodoRepository) TagUsecase {
+	return &tagUsecase{
+		tagRepo:  tagRepo,
+		todoRepo: todoRepo,
+		logger:   slog.Default().WithGroup("usecase.tag"),
+	}
+}
+
+// ListTags はユーザーのタグ一覧を取得します。
+func (uc *tagUsecase) ListTags(ctx context.Context, input ListTagsInput) (*ListTagsOutput, error) {
+	uc.logger.InfoContext(ctx, "listing tags", "userID", input.UserID)
+
+	tags, err := uc.tagRepo.FindByUserID(ctx, input.UserID)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find tags", "error", err, "userID", input.UserID)
+		return nil, goerr.Wrap(err, "failed to get tags from repository")
+	}
+	return &ListTagsOutput{Tags: tags}, nil
+}
+
+// CreateTag は新しいタグを作成します。同じ名前のタグがすでにある場合はエラーを返します。
+func (uc *tagUsecase) CreateTag(ctx context.Context, input CreateTagInput) (*TagOutput, error) {
+	uc.logger.InfoContext(ctx, "creating tag", "userID", input.UserID, "name", input.Name)
+
+	name := strings.TrimSpace(input.Name)
+	if err := uc.validate(ctx, input.UserID, 0, name, input.Color); err != nil {
+		return nil, err
+	}
+
+	tag := &model.Tag{
+		UserID: input.UserID,
+		Name:   name,
+		Color:  input.Color,
+	}
+	if err := uc.tagRepo.Create(ctx, tag); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to create tag", "error", err, "input", input)
+		return nil, goerr.Wrap(err, "failed to create tag in repository")
+	}
+
+	uc.logger.InfoContext(ctx, "tag created successfully", "tagID", tag.ID)
+	return &TagOutput{Tag: tag}, nil
+}
+
+// UpdateTag はタグの名前と色を更新します。
+func (uc *tagUsecase) UpdateTag(ctx context.Context, input UpdateTagInput) (*TagOutput, error) {
+	uc.logger.InfoContext(ctx, "updating tag", "tagID", input.ID, "userID", input.UserID)
+
+	tag, err := uc.findOwnTag(ctx, input.ID, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if err := uc.validate(ctx, input.UserID, tag.ID, name, input.Color); err != nil {
+		return nil, err
+	}
+
+	tag.Name = name
+	tag.Color = input.Color
+	if err := uc.tagRepo.Update(ctx, tag); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to update tag", "error", err, "input", input)
+		return nil, goerr.Wrap(err, "failed to update tag in repository")
+	}
+
+	uc.logger.InfoContext(ctx, "tag updated successfully", "tagID", tag.ID)
+	return &TagOutput{Tag: tag}, nil
+}
+
+// DeleteTag はタグを削除します。ToDo へのタグ付けもあわせて削除されます。
+func (uc *tagUsecase) DeleteTag(ctx context.Context, input DeleteTagInput) error {
+	uc.logger.InfoContext(ctx, "deleting tag", "tagID", input.ID, "userID", input.UserID)
+
+	if _, err := uc.findOwnTag(ctx, input.ID, input.UserID); err != nil {
+		return err
+	}
+
+	if err := uc.tagRepo.Delete(ctx, input.ID, input.UserID); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to delete tag", "error", err, "input", input)
+		return goerr.Wrap(err, "failed to delete tag in repository")
+	}
+
+	uc.logger.InfoContext(ctx, "tag deleted successfully", "tagID", input.ID)
+	return nil
+}
+
+// AttachTag は ToDo にタグを付与し、更新後の ToDo を返します。
+func (uc *tagUsecase) AttachTag(ctx context.Context, input TodoTagInput) (*TodoTagOutput, error) {
+	uc.logger.InfoContext(ctx, "attaching tag to todo", "todoID", input.TodoID, "tagID", input.TagID, "userID", input.UserID)
+
+	if err := uc.checkTodoTag(ctx, input); err != nil {
+		return nil, err
+	}
+
+	if err := uc.tagRepo.Attach(ctx, input.TodoID, input.TagID); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to attach tag", "error", err, "input", input)
+		return nil, goerr.Wrap(err, "failed to attach tag in repository")
+	}
+
+	return uc.todoOutput(ctx, input.TodoID)
+}
+
+// DetachTag は ToDo からタグを外し、更新後の ToDo を返します。
+func (uc *tagUsecase) DetachTag(ctx context.Context, input TodoTagInput) (*TodoTagOutput, error) {
+	uc.logger.InfoContext(ctx, "detaching tag from todo", "todoID", input.TodoID, "tagID", input.TagID, "userID", input.UserID)
+
+	if err := uc.checkTodoTag(ctx, input); err != nil {
+		return nil, err
+	}
+
+	if err := uc.tagRepo.Detach(ctx, input.TodoID, input.TagID); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to detach tag", "error", err, "input", input)
+		return nil, goerr.Wrap(err, "failed to detach tag in repository")
+	}
+
+	return uc.todoOutput(ctx, input.TodoID)
+}
+
+// validate はタグ名と色を検証し、同じユーザーに同名の別タグがないか確認します。
+func (uc *tagUsecase) validate(ctx context.Context, userID, tagID int64, name, color string) error {
+	if !model.IsValidTagName(name) {
+		uc.logger.WarnContext(ctx, "invalid tag name provided", "name", name)
+		return goerr.New("invalid tag name").With("name", name)
+	}
+	if !model.IsValidTagColor(color) {
+		uc.logger.WarnContext(ctx, "invalid tag color provided", "color", color)
+		return goerr.New("invalid tag color").With("color", color)
+	}
+
+	existing, err := uc.tagRepo.FindByName(ctx, userID, name)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find tag by name", "error", err, "userID", userID, "name", name)
+		return goerr.Wrap(err, "failed to find tag by name")
+	}
+	if existing != nil && existing.ID != tagID {
+		uc.logger.WarnContext(ctx, "tag name already exists", "userID", userID, "name", name)
+		return goerr.Wrap(ErrAlreadyExists, "tag name already exists").With("name", name)
+	}
+	return nil
+}
+
+// findOwnTag はタグを取得し、存在と所有権を確認します。
+func (uc *tagUsecase) findOwnTag(ctx context.Context, tagID, userID int64) (*model.Tag, error) {
+	tag, err := uc.tagRepo.FindByID(ctx, tagID)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find tag", "error", err, "tagID", tagID)
+		return nil, goerr.Wrap(err, "failed to find tag by id")
+	}
+	if tag == nil {
+		return nil, goerr.Wrap(ErrNotFound, "tag not found").With("id", tagID)
+	}
+	if tag.UserID != userID {
+		uc.logger.WarnContext(ctx, "permission denied to access tag", "tagID", tagID, "ownerUserID", tag.UserID, "requestUserID", userID)
+		return nil, goerr.Wrap(ErrPermissionDenied, "tag is owned by another user").With("tagID", tagID)
+	}
+	return tag, nil
+}
+
+// checkTodoTag は ToDo とタグがどちらもリクエストユーザーのものであり、ToDo がアーカイブされていないことを確認します。
+func (uc *tagUsecase) checkTodoTag(ctx context.Context, input TodoTagInput) error {
+	todo, err := uc.todoRepo.FindByID(ctx, input.TodoID)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find todo for tagging", "error", err, "todoID", input.TodoID)
+		return goerr.Wrap(err, "failed to find todo by id")
+	}
+	if todo == nil {
+		return goerr.Wrap(ErrNotFound, "todo not found").With("id", input.TodoID)
+	}
+	if todo.UserID != input.UserID {
+		uc.logger.WarnContext(ctx, "permission denied to tag todo", "todoID", input.TodoID, "ownerUserID", todo.UserID, "requestUserID", input.UserID)
+		return goerr.Wrap(ErrPermissionDenied, "todo is owned by another user").With("todoID", input.TodoID)
+	}
+	if todo.IsArchived() {
+		uc.logger.WarnContext(ctx, "cannot change tags of archived todo", "todoID", input.TodoID)
+		return goerr.New("cannot change tags of archived todo").With("todoID", input.TodoID)
+	}
+
+	_, err = uc.findOwnTag(ctx, input.TagID, input.UserID)
+	return err
+}
+
+// todoOutput はタグ付け変更後の ToDo を取得します。
+func (uc *tagUsecase) todoOutput(ctx context.Context, todoID int64) (*TodoTagOutput, error) {
+	todo, err := uc.todoRepo.FindByID(ctx, todoID)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find todo after tagging", "error", err, "todoID", todoID)
+		return nil, goerr.Wrap(err, "failed to fetch todo after tagging")
+	}
+	return &TodoTagOutput{Todo: todo}, nil
+}
@@ -4,6 +4,7 @@ package usecase
 import (
 	"context"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/lirlia/100day_challenge_backend/day1_todo_app/internal/domain/model"
@@ -14,17 +15,31 @@ import (
 // TodoUsecaseInput は ToDo ユースケースの入力パラメータを表すインターフェースです。(メソッドごとに定義)
 // TodoUsecaseOutput は ToDo ユースケースの出力パラメータを表すインターフェースです。(メソッドごとに定義)
 
+// ToDo 一覧のページサイズ
+const (
+	defaultTodosPerPage = 20
+	maxTodosPerPage     = 100
+)
+
 // GetTodosInput は ToDo 一覧取得の入力です。
 type GetTodosInput struct {
 	UserID          int64
-	Limit           int
-	Page            int
+	Limit           int // 1ページあたりの件数 (0 の場合はデフォルト値)
+	Page            int // ページ番号 (1-indexed)
 	IncludeArchived bool
+	Status          *model.TodoStatus      // 指定された場合はそのステータスのみ
+	Query           string                 // タイトル・詳細の部分一致検索キーワード
+	Sort            repository.TodoSortKey // 空の場合は手動並び替え順
+	Overdue         bool                   // true の場合は期限切れの ToDo のみ
+	TagID           *int64                 // 指定された場合はそのタグが付いた ToDo のみ
 }
 
 // GetTodosOutput は ToDo 一覧取得の出力です。
 type GetTodosOutput struct {
 	Todos []*model.Todo
+	Total int64 // 絞り込み条件に一致する総件数
+	Page  int   // 実際に適用されたページ番号
+	Limit int   // 実際に適用された1ページあたりの件数
 }
 
 // CreateTodoInput は ToDo 作成の入力です。
@@ -74,6 +89,18 @@ type UpdateTodoOrderInput struct {
 	}
 }
 
+// SetTodoDueInput は ToDo 期限設定の入力です。
+type SetTodoDueInput struct {
+	ID     int64
+	UserID int64      // 権限チェック用
+	DueAt  *time.Time // nil の場合は期限をクリアする
+}
+
+// SetTodoDueOutput は ToDo 期限設定の出力です。
+type SetTodoDueOutput struct {
+	Todo *model.Todo
+}
+
 // ArchiveTodoInput は ToDo アーカイブの入力です。
 type ArchiveTodoInput struct {
 	ID     int64
@@ -98,6 +125,7 @@ type TodoUsecase interface {
 	UpdateTodo(ctx context.Context, input UpdateTodoInput) (*UpdateTodoOutput, error)
 	UpdateTodoStatus(ctx context.Context, input UpdateTodoStatusInput) (*UpdateTodoStatusOutput, error)
 	UpdateTodoOrder(ctx context.Context, input UpdateTodoOrderInput) error
+	SetTodoDue(ctx context.Context, input SetTodoDueInput) (*SetTodoDueOutput, error)
 	ArchiveTodo(ctx context.Context, input ArchiveTodoInput) error
 	UnarchiveTodo(ctx context.Context, input UnarchiveTodoInput) (*UnarchiveTodoOutput, error)
 	// GetArchivedTodos ユースケース (GetTodos で includeArchived=true を使うので不要かも？)
@@ -121,23 +149,58 @@ func NewTodoUsecase(todoRepo repository.TodoRepository, userRepo repository.User
 
 // GetTodos は ToDo リストを取得します。
 func (uc *todoUsecase) GetTodos(ctx context.Context, input GetTodosInput) (*GetTodosOutput, error) {
-	uc.logger.InfoContext(ctx, "getting todos", "userID", input.UserID, "page", input.Page, "limit", input.Limit, "includeArchived", input.IncludeArchived)
+	uc.logger.InfoContext(ctx, "getting todos", "userID", input.UserID, "page", input.Page, "limit", input.Limit, "includeArchived", input.IncludeArchived, "status", input.Status, "query", input.Query, "sort", input.Sort, "overdue", input.Overdue, "tagID", input.TagID)
+
+	if input.Status != nil && !input.Status.IsValid() {
+		uc.logger.WarnContext(ctx, "invalid todo status filter provided", "status", *input.Status)
+		return nil, goerr.New("invalid status").With("status", *input.Status)
+	}
+	if !input.Sort.IsValid() {
+		uc.logger.WarnContext(ctx, "invalid sort key provided", "sort", input.Sort)
+		return nil, goerr.New("invalid sort key").With("sort", input.Sort)
+	}
+
+	// ページネーションの正規化
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultTodosPerPage
+	}
+	if limit > maxTodosPerPage {
+		limit = maxTodosPerPage
+	}
 
 	params := repository.FindTodosParams{
 		UserID:          input.UserID,
-		Limit:           input.Limit,
-		Page:            input.Page,
+		Limit:           limit,
+		Page:            page,
 		IncludeArchived: input.IncludeArchived,
+		Status:          input.Status,
+		Query:           strings.TrimSpace(input.Query),
+		Sort:            input.Sort,
+		TagID:           input.TagID,
+	}
+	if input.Overdue {
+		now := time.Now()
+		params.OverdueAt = &now
 	}
 
-	todos, err := uc.todoRepo.Find(ctx, params)
+	todos, total, err := uc.todoRepo.Find(ctx, params)
 	if err != nil {
 		uc.logger.ErrorContext(ctx, "failed to find todos", "error", err, "params", params)
 		return nil, goerr.Wrap(err, "failed to get todos from repository")
 	}
 
-	uc.logger.InfoContext(ctx, "found todos", "count", len(todos))
-	return &GetTodosOutput{Todos: todos}, nil
+	uc.logger.InfoContext(ctx, "found todos", "count", len(todos), "total", total)
+	return &GetTodosOutput{
+		Todos: todos,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
 }
 
 // CreateTodo は新しい ToDo を作成します。
@@ -173,11 +236,11 @@ func (uc *todoUsecase) UpdateTodo(ctx context.Context, input UpdateTodoInput) (*
 		return nil, goerr.Wrap(err, "failed to find todo by id")
 	}
 	if existingTodo == nil {
-		return nil, goerr.New("todo not found for update").With("id", input.ID)
+		return nil, goerr.Wrap(ErrNotFound, "todo not found for update").With("id", input.ID)
 	}
 	if existingTodo.UserID != input.UserID {
 		uc.logger.WarnContext(ctx, "permission denied to update todo", "todoID", input.ID, "ownerUserID", existingTodo.UserID, "requestUserID", input.UserID)
-		return nil, goerr.New("permission denied").With("todoID", input.ID)
+		return nil, goerr.Wrap(ErrPermissionDenied, "todo is owned by another user").With("todoID", input.ID)
 	}
 	if existingTodo.IsArchived() {
 		uc.logger.WarnContext(ctx, "cannot update archived todo", "todoID", input.ID)
@@ -220,11 +283,11 @@ func (uc *todoUsecase) UpdateTodoStatus(ctx context.Context, input UpdateTodoSta
 		return nil, goerr.Wrap(err, "failed to find todo by id")
 	}
 	if existingTodo == nil {
-		return nil, goerr.New("todo not found").With("id", input.ID)
+		return nil, goerr.Wrap(ErrNotFound, "todo not found").With("id", input.ID)
 	}
 	if existingTodo.UserID != input.UserID {
 		uc.logger.WarnContext(ctx, "permission denied to update todo status", "todoID", input.ID, "ownerUserID", existingTodo.UserID, "requestUserID", input.UserID)
-		return nil, goerr.New("permission denied").With("todoID", input.ID)
+		return nil, goerr.Wrap(ErrPermissionDenied, "todo is owned by another user").With("todoID", input.ID)
 	}
 	if existingTodo.IsArchived() {
 		uc.logger.WarnContext(ctx, "cannot update status of archived todo", "todoID", input.ID)
@@ -288,6 +351,59 @@ func (uc *todoUsecase) UpdateTodoOrder(ctx context.Context, input UpdateTodoOrde
 	return nil
 }
 
+// SetTodoDue は ToDo の期限を設定またはクリアします。
+// 期限が変わった場合は再度リマインダーを送信できるよう、送信済み記録もクリアします。
+func (uc *todoUsecase) SetTodoDue(ctx context.Context, input SetTodoDueInput) (*SetTodoDueOutput, error) {
+	uc.logger.InfoContext(ctx, "setting todo due date", "todoID", input.ID, "userID", input.UserID, "dueAt", input.DueAt)
+
+	existingTodo, err := uc.todoRepo.FindByID(ctx, input.ID)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find todo for due date update", "error", err, "todoID", input.ID)
+		return nil, goerr.Wrap(err, "failed to find todo by id")
+	}
+	if existingTodo == nil {
+		return nil, goerr.Wrap(ErrNotFound, "todo not found").With("id", input.ID)
+	}
+	if existingTodo.UserID != input.UserID {
+		uc.logger.WarnContext(ctx, "permission denied to update todo due date", "todoID", input.ID, "ownerUserID", existingTodo.UserID, "requestUserID", input.UserID)
+		return nil, goerr.Wrap(ErrPermissionDenied, "todo is owned by another user").With("todoID", input.ID)
+	}
+	if existingTodo.IsArchived() {
+		uc.logger.WarnContext(ctx, "cannot update due date of archived todo", "todoID", input.ID)
+		return nil, goerr.New("cannot update due date of archived todo").With("todoID", input.ID)
+	}
+
+	// DB の TIMESTAMP は秒精度のため、比較の前に揃えておく
+	if input.DueAt != nil {
+		dueAt := input.DueAt.Truncate(time.Second)
+		input.DueAt = &dueAt
+	}
+
+	// 変更がない場合は更新しない (MySQL は変更のない UPDATE を 0 件として返すため)
+	if sameTime(existingTodo.DueAt, input.DueAt) && existingTodo.RemindedAt == nil {
+		return &SetTodoDueOutput{Todo: existingTodo}, nil
+	}
+
+	updates := map[string]interface{}{
+		"due_at":      input.DueAt,
+		"reminded_at": nil,
+	}
+
+	if err := uc.todoRepo.Update(ctx, input.ID, input.UserID, updates); err != nil {
+		uc.logger.ErrorContext(ctx, "failed to update todo due date", "error", err, "input", input)
+		return nil, goerr.Wrap(err, "failed to update todo due date in repository")
+	}
+
+	updatedTodo, err := uc.todoRepo.FindByID(ctx, input.ID)
+	if err != nil {
+		uc.logger.ErrorContext(ctx, "failed to find todo after due date update", "error", err, "todoID", input.ID)
+		return nil, goerr.Wrap(err, "failed to fetch todo after due date update")
+	}
+
+	uc.logger.InfoContext(ctx, "todo due date updated successfully", "todoID", input.ID)
+	return &SetTodoDueOutput{Todo: updatedTodo}, nil
+}
+
 // ArchiveTodo は ToDo をアーカイブします。
 func (uc *todoUsecase) ArchiveTodo(ctx context.Context, input ArchiveTodoInput) error {
 	uc.logger.InfoContext(ctx, "archiving todo", "todoID", input.ID, "userID", input.UserID)
@@ -298,9 +414,12 @@ func (uc *todoUsecase) ArchiveTodo(ctx context.Context, input ArchiveTodoInput)
 		uc.logger.ErrorContext(ctx, "failed to find todo for archive", "error", err, "todoID", input.ID)
 		return goerr.Wrap(err, "failed to find todo by id")
 	}
+	if existingTodo == nil {
+		return goerr.Wrap(ErrNotFound, "todo not found").With("id", input.ID)
+	}
 	if existingTodo.UserID != input.UserID {
 		uc.logger.WarnContext(ctx, "permission denied to archive todo", "todoID", input.ID, "ownerUserID", existingTodo.UserID, "requestUserID", input.UserID)
-		return goerr.New("permission denied").With("todoID", input.ID)
+		return goerr.Wrap(ErrPermissionDenied, "todo is owned by another user").With("todoID", input.ID)
 	}
 	if existingTodo.IsArchived() {
 		uc.logger.WarnContext(ctx, "todo is already archived", "todoID", input.ID)
@@ -328,9 +447,12 @@ func (uc *todoUsecase) UnarchiveTodo(ctx context.Context, input UnarchiveTodoInp
 		uc.logger.ErrorContext(ctx, "failed to find todo for unarchive", "error", err, "todoID", input.ID)
 		return nil, goerr.Wrap(err, "failed to find todo by id")
 	}
+	if existingTodo == nil {
+		return nil, goerr.Wrap(ErrNotFound, "todo not found").With("id", input.ID)
+	}
 	if existingTodo.UserID != input.UserID {
 		uc.logger.WarnContext(ctx, "permission denied to unarchive todo", "todoID", input.ID, "ownerUserID", existingTodo.UserID, "requestUserID", input.UserID)
-		return nil, goerr.New("permission denied").With("todoID", input.ID)
+		return nil, goerr.Wrap(ErrPermissionDenied, "todo is owned by another user").With("todoID", input.ID)
 	}
 	if !existingTodo.IsArchived() {
 		uc.logger.WarnContext(ctx, "todo is not archived", "todoID", input.ID)
@@ -358,3 +480,11 @@ func (uc *todoUsecase) UnarchiveTodo(ctx context.Context, input UnarchiveTodoInp
 
 	return &UnarchiveTodoOutput{Todo: unarchivedTodo}, nil
 }
+
+// sameTime は2つの日時 (nil 許容) が同じか判定します。
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// --- find の結果を CSV / JSON で出力 ---
+
+// outputFormats は output オプションで指定できる形式です。
+var outputFormats = []string{"csv", "json"}
+
+// exportColumn は出力するフィールドと、その見出しに使うカラム名です。
+type exportColumn struct {
+	index  int
+	column string
+}
+
+// exportColumns はモデルのカラムに対応するフィールドを定義順に返します (リレーションフィールドは除く)。
+func exportColumns(structType reflect.Type) []exportColumn {
+	var columns []exportColumn
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if _, column, ok := resolveField(structType, field.Name); ok {
+			columns = append(columns, exportColumn{index: i, column: column})
+		}
+	}
+	return columns
+}
+
+// exportValue はフィールドの値を出力用の値に変換します。
+// sql.Null* 型は中身の値 (NULL なら nil)、ポインタは指す先の値、[]byte は文字列になります。
+func exportValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	value := v.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil || dv == nil {
+			return nil
+		}
+		value = dv
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+// writeStructsOutput は構造体のスライスを format (csv / json) で file に書き出します。file が空なら標準出力に書き出します。
+func writeStructsOutput(sliceVal reflect.Value, format, file string) error {
+	structType := sliceVal.Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	columns := exportColumns(structType)
+
+	rows := make([][]interface{}, 0, sliceVal.Len())
+	for i := 0; i < sliceVal.Len(); i++ {
+		rowVal := sliceVal.Index(i)
+		if rowVal.Kind() == reflect.Ptr {
+			rowVal = rowVal.Elem()
+		}
+		row := make([]interface{}, len(columns))
+		for j, col := range columns {
+			row[j] = exportValue(rowVal.Field(col.index))
+		}
+		rows = append(rows, row)
+	}
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch format {
+	case "csv":
+		return writeCSV(w, columns, rows)
+	case "json":
+		return writeJSON(w, columns, rows)
+	default:
+		return fmt.Errorf("unknown output format %q (expected %s)", format, strings.Join(outputFormats, " or "))
+	}
+}
+
+// writeCSV はカラム名のヘッダー行に続けて各行を書き出します。NULL は空文字列になります。
+func writeCSV(w io.Writer, columns []exportColumn, rows [][]interface{}) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.column
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			switch v := value.(type) {
+			case nil:
+				record[i] = ""
+			case time.Time:
+				record[i] = v.Format(time.RFC3339)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSON は各行をカラム名をキーにしたオブジェクトの配列として書き出します。カラムはモデルの定義順に並びます。
+func writeJSON(w io.Writer, columns []exportColumn, rows [][]interface{}) error {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, value := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			key, _ := json.Marshal(columns[j].column)
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", columns[j].column, err)
+			}
+			b.Write(key)
+			b.WriteString(": ")
+			b.Write(encoded)
+		}
+		b.WriteString("}")
+	}
+	if len(rows) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// --- gen コマンド: 既存の DB からモデル定義を生成 ---
+
+// genColumn は PRAGMA table_info で取得したカラム情報です。
+type genColumn struct {
+	name     string
+	declType string
+	notNull  bool
+	pk       bool
+}
+
+// commonInitialisms はフィールド名で大文字にする略語です (golint の慣習に合わせる)。
+var commonInitialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "JSON": true,
+	"SQL": true, "UUID": true, "IP": true, "HTML": true, "UID": true,
+}
+
+// generateModels は指定したテーブル (空ならすべて) の Go の構造体定義を生成します。
+func generateModels(ctx context.Context, db *sql.DB, tables []string) (string, error) {
+	if len(tables) == 0 {
+		var err error
+		tables, err = listTables(ctx, db)
+		if err != nil {
+			return "", err
+		}
+		if len(tables) == 0 {
+			return "", fmt.Errorf("no tables found")
+		}
+	}
+
+	var body strings.Builder
+	imports := make(map[string]bool)
+	for _, table := range tables {
+		columns, err := tableColumns(ctx, db, table)
+		if err != nil {
+			return "", err
+		}
+		if len(columns) == 0 {
+			return "", fmt.Errorf("table %s not found", table)
+		}
+		writeStruct(&body, table, columns, imports)
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// orm_shell の gen コマンドで %s から生成したモデル定義です。\n\n", currentDBFile)
+	src.WriteString("package models\n\n")
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		src.WriteString("import (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&src, "\t%q\n", path)
+		}
+		src.WriteString(")\n\n")
+	}
+	src.WriteString(body.String())
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// writeStruct は 1 テーブル分の構造体定義を書き出します。
+func writeStruct(w *strings.Builder, table string, columns []genColumn, imports map[string]bool) {
+	structName := modelNameForTable(table)
+	fmt.Fprintf(w, "// %s は %s テーブルのモデルです。\n", structName, table)
+	if ormTableName(structName) != table {
+		// ORM は構造体名からテーブル名を推測するため、推測結果が一致しない場合は注意を残す
+		fmt.Fprintf(w, "// 注意: ORM はこの構造体のテーブル名を %s と推測するため、Model() では %s テーブルを操作できません。\n", ormTableName(structName), table)
+	}
+	fmt.Fprintf(w, "type %s struct {\n", structName)
+
+	hasIDColumn := false
+	for _, col := range columns {
+		if col.name == "id" {
+			hasIDColumn = true
+		}
+	}
+	for _, col := range columns {
+		goType, importPath := goTypeForColumn(col)
+		if importPath != "" {
+			imports[importPath] = true
+		}
+		tag := fmt.Sprintf("db:%q", col.name)
+		if col.pk && !(hasIDColumn && col.name == "id") {
+			tag += ` orm:"pk"`
+		}
+		fmt.Fprintf(w, "\t%s %s `%s`\n", goFieldName(col.name), goType, tag)
+	}
+	w.WriteString("}\n\n")
+}
+
+// goTypeForColumn は SQLite の型宣言 (型アフィニティの規則) から Go の型と必要な import を返します。
+// NOT NULL でないカラム (主キーを除く) は sql.Null* 型になります。
+func goTypeForColumn(col genColumn) (goType string, importPath string) {
+	declType := strings.ToUpper(col.declType)
+	nullable := !col.notNull && !col.pk
+
+	switch {
+	case strings.Contains(declType, "BOOL"):
+		if nullable {
+			return "sql.NullBool", "database/sql"
+		}
+		return "bool", ""
+	case strings.Contains(declType, "INT"):
+		if nullable {
+			return "sql.NullInt64", "database/sql"
+		}
+		return "int64", ""
+	case strings.Contains(declType, "DATE") || strings.Contains(declType, "TIME"):
+		if nullable {
+			return "sql.NullTime", "database/sql"
+		}
+		return "time.Time", "time"
+	case strings.Contains(declType, "CHAR") || strings.Contains(declType, "CLOB") || strings.Contains(declType, "TEXT"):
+		if nullable {
+			return "sql.NullString", "database/sql"
+		}
+		return "string", ""
+	case declType == "" || strings.Contains(declType, "BLOB"):
+		return "[]byte", "" // []byte は nil で NULL を表せる
+	default:
+		// REAL, FLOAT, DOUBLE, NUMERIC, DECIMAL など
+		if nullable {
+			return "sql.NullFloat64", "database/sql"
+		}
+		return "float64", ""
+	}
+}
+
+// modelNameForTable はテーブル名から構造体名を決めます。ORM の複数形化の規則で元のテーブル名に戻る単数形を優先します。
+// status や address のように s で終わる単数形の名前はそのまま使います。
+func modelNameForTable(table string) string {
+	if singular := strings.TrimSuffix(table, "s"); singular != table && singular != "" &&
+		!strings.HasSuffix(singular, "s") && !strings.HasSuffix(singular, "u") && !strings.HasSuffix(singular, "i") {
+		if name := goFieldName(singular); ormTableName(name) == table {
+			return name
+		}
+	}
+	return goFieldName(table)
+}
+
+// ormTableName は ORM が構造体名から推測するテーブル名を返します (orm パッケージの getTableName と同じ規則)。
+func ormTableName(structName string) string {
+	snakeName := strcase.ToSnake(structName)
+	for _, suffix := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(snakeName, suffix) {
+			return snakeName
+		}
+	}
+	return snakeName + "s"
+}
+
+// goFieldName はカラム名を Go の識別子 (user_id -> UserID) に変換します。
+func goFieldName(column string) string {
+	words := strings.FieldsFunc(column, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	name := b.String()
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "X" + name
+	}
+	return name
+}
+
+// listTables は SQLite の内部テーブルを除くテーブル名を返します。
+func listTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// tableColumns はテーブルのカラム情報を定義順に返します。テーブルが存在しない場合は空のスライスを返します。
+func tableColumns(ctx context.Context, db *sql.DB, table string) ([]genColumn, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, type, \"notnull\", pk FROM pragma_table_info(?) ORDER BY cid", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []genColumn
+	for rows.Next() {
+		var col genColumn
+		var notNull, pk int
+		if err := rows.Scan(&col.name, &col.declType, &notNull, &pk); err != nil {
+			return nil, fmt.Errorf("failed to scan columns of %s: %w", table, err)
+		}
+		col.notNull = notNull != 0
+		col.pk = pk != 0
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// runGen は gen コマンドを実行します。into <file> が指定された場合はファイルに、そうでなければ標準出力に書き出します。
+func runGen(args []string) {
+	if currentDB == nil {
+		fmt.Println("Not connected to a database.")
+		return
+	}
+	var tables []string
+	outFile := ""
+	for i := 0; i < len(args); i++ {
+		if strings.ToLower(args[i]) == "into" {
+			if i+1 >= len(args) {
+				fmt.Println("Usage: gen [<table> ...] [into <file.go>]")
+				return
+			}
+			outFile = args[i+1]
+			i++
+			continue
+		}
+		tables = append(tables, args[i])
+	}
+
+	src, err := generateModels(context.Background(), currentDB.DB, tables)
+	if err != nil {
+		fmt.Printf("Error generating models: %v\n", err)
+		return
+	}
+	if outFile == "" {
+		fmt.Print(src)
+		return
+	}
+	if err := os.WriteFile(outFile, []byte(src), 0644); err != nil {
+		fmt.Printf("Error writing %s: %v\n", outFile, err)
+		return
+	}
+	fmt.Printf("Models written to %s\n", outFile)
+}
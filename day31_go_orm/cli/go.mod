@@ -4,12 +4,12 @@ go 1.24.2
 
 require (
 	github.com/c-bata/go-prompt v0.2.6
+	github.com/iancoleman/strcase v0.3.0
 	github.com/lirlia/100day_challenge_backend/day31_go_orm/orm v0.0.0-00010101000000-000000000000
 	github.com/mattn/go-sqlite3 v1.14.28
 )
 
 require (
-	github.com/iancoleman/strcase v0.3.0 // indirect
 	github.com/mattn/go-colorable v0.1.7 // indirect
 	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
@@ -7,8 +7,10 @@ import (
 	"os"
 	"reflect" // reflect を追加
 	"sort"    // カラムソート用
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	prompt "github.com/c-bata/go-prompt"                          // bufio の代わりに go-prompt を使う
 	"github.com/lirlia/100day_challenge_backend/day31_go_orm/orm" // DB接続用に必要
@@ -36,9 +38,22 @@ import (
 // --- ここまでモデル定義 ---
 
 var currentDB *orm.DB // グローバル変数名を db から currentDB に変更
-// var currentTX *orm.TX // 削除: CLI ではトランザクション管理はしない
+var currentTX *orm.TX // begin で開始したトランザクション (nil ならトランザクション外)
 var currentDBFile string
 
+// queryRunner は *orm.DB と *orm.TX に共通する QueryBuilder の起点です。
+type queryRunner interface {
+	Model(model interface{}) *orm.QueryBuilder
+}
+
+// currentRunner はトランザクション中なら currentTX、そうでなければ currentDB を返します。
+func currentRunner() queryRunner {
+	if currentTX != nil {
+		return currentTX
+	}
+	return currentDB
+}
+
 // --- モデルレジストリ --- START
 // 文字列のモデル名から reflect.Type を引くためのマップ
 // アプリケーションで利用するモデルをここに追加する
@@ -61,9 +76,16 @@ var commands = []prompt.Suggest{
 	{Text: "disconnect", Description: "Disconnect from the current database."},
 	{Text: "tables", Description: "List tables in the current database."},
 	{Text: "schema", Description: "<table_name> Show the schema of a table."},
-	{Text: "find", Description: "<model> [where <cond>] [order <col> [asc|desc]] [limit <n>] [offset <n>] Find records."},
+	{Text: "find", Description: "<model> [where <cond>] [order <col> [asc|desc]] [limit <n>] [offset <n>] [output csv|json [file]] Find records."},
 	{Text: "first", Description: "<model> [where <cond>] [order <col> [asc|desc]] Find first record."},
 	{Text: "count", Description: "<model> [where <cond>] Count records."},
+	{Text: "insert", Description: "<model> <field>=<value> ... Insert a record."},
+	{Text: "update", Description: "<model> set <field>=<value> ... where <cond> Update records."},
+	{Text: "delete", Description: "<model> where <cond> Delete records."},
+	{Text: "gen", Description: "[<table> ...] [into <file.go>] Generate Go model structs from the database schema."},
+	{Text: "begin", Description: "Begin a transaction."},
+	{Text: "commit", Description: "Commit the current transaction."},
+	{Text: "rollback", Description: "Roll back the current transaction."},
 	{Text: "help", Description: "Show this help message."},
 	{Text: "exit", Description: "Exit the shell."},
 	{Text: "quit", Description: "Exit the shell."},
@@ -79,6 +101,9 @@ func main() {
 		if currentDB != nil {
 			prefix = fmt.Sprintf("(%s) > ", currentDBFile)
 		}
+		if currentTX != nil {
+			prefix = fmt.Sprintf("(%s) [tx] > ", currentDBFile)
+		}
 		return prefix, true // 常にライブプレフィックスを有効にする
 	}
 
@@ -101,6 +126,10 @@ func executor(in string) {
 	if in == "" {
 		return
 	} else if in == "quit" || in == "exit" {
+		if currentTX != nil {
+			// コミットされていない変更は破棄する
+			rollbackTx()
+		}
 		if currentDB != nil {
 			currentDB.Close()
 		}
@@ -175,6 +204,7 @@ func completer(d prompt.Document) []prompt.Suggest {
 					if command == "find" {
 						availableKeywords["limit"] = true
 						availableKeywords["offset"] = true
+						availableKeywords["output"] = true
 					}
 
 					// 既に使用されたキーワードを除外
@@ -209,6 +239,23 @@ func completer(d prompt.Document) []prompt.Suggest {
 						} else if (strings.ToLower(prevArg) == "limit" || strings.ToLower(prevArg) == "offset") && isNumber(lastArg) && strings.HasSuffix(currentLine, " ") {
 							contextAllowsKeywords = true
 						}
+						// "output " の後は形式 (csv / json) を提案する
+						if strings.ToLower(lastArg) == "output" && strings.HasSuffix(currentLine, " ") {
+							for _, format := range outputFormats {
+								suggestions = append(suggestions, prompt.Suggest{Text: format})
+							}
+							return suggestions
+						}
+						if strings.ToLower(prevArg) == "output" && !strings.HasSuffix(currentLine, " ") {
+							for _, format := range outputFormats {
+								suggestions = append(suggestions, prompt.Suggest{Text: format})
+							}
+							return prompt.FilterHasPrefix(suggestions, wordBeforeCursor, true)
+						}
+						// "output csv|json " の後はファイル名なので補完しない
+						if strings.ToLower(prevArg) == "output" && strings.HasSuffix(currentLine, " ") {
+							return suggestions
+						}
 						// 'where'句の終わりを判定するのは難しいので、'where'の後には常にキーワードを許可する（簡易的）
 						// if strings.ToLower(prevArg) == "where" { contextAllowsKeywords = true }
 						// Consider suggesting keywords if the last argument doesn't seem like part of a where clause
@@ -246,20 +293,29 @@ func completer(d prompt.Document) []prompt.Suggest {
 
 					// --- 特定のキーワード引数の提案 ---
 
-					// "where" の後にカラム名を提案
-					if len(args) == 3 && strings.ToLower(args[2]) == "where" && strings.HasSuffix(currentLine, " ") {
-						modelType := modelRegistry[modelName]
-						columnSuggestions := getModelColumnSuggestions(modelType)
-						return columnSuggestions
-					}
-					// "where" の後にカラム名を入力中にフィルタリング
-					if len(args) == 4 && strings.ToLower(args[2]) == "where" && !strings.HasSuffix(currentLine, " ") {
-						modelType := modelRegistry[modelName]
-						columnSuggestions := getModelColumnSuggestions(modelType)
-						return prompt.FilterHasPrefix(columnSuggestions, wordBeforeCursor, true)
+					// "where" の後 (次のキーワードまで) はカラム名・演算子・プレースホルダを提案
+					if len(args) >= 3 && strings.ToLower(args[2]) == "where" {
+						done := args[3:]
+						if !strings.HasSuffix(currentLine, " ") {
+							done = done[:len(done)-1]
+						}
+						inWhere := true
+						for _, arg := range done {
+							if isKeyword(arg) {
+								inWhere = false
+							}
+						}
+						if inWhere {
+							var keywords []prompt.Suggest
+							for keyword := range availableKeywords {
+								if keyword != "where" {
+									keywords = append(keywords, prompt.Suggest{Text: keyword})
+								}
+							}
+							sort.Slice(keywords, func(i, j int) bool { return keywords[i].Text < keywords[j].Text })
+							return completeWhere(modelRegistry[modelName], done, wordBeforeCursor, keywords...)
+						}
 					}
-					// TODO: "where <col> " の後に演算子 (=, !=, >, <, like など) を提案する
-					// TODO: "where <col> = " の後に値の型に応じた提案 (文字列？数値？)
 
 					// "order" の後にカラム名 or asc/desc
 					if len(args) == 3 && strings.ToLower(args[2]) == "order" && strings.HasSuffix(currentLine, " ") {
@@ -286,6 +342,42 @@ func completer(d prompt.Document) []prompt.Suggest {
 				}
 			}
 
+		case "insert", "update", "delete":
+			// コマンド名の直後 + スペースでモデル名を提案
+			if len(args) == 1 && strings.HasSuffix(currentLine, " ") {
+				for _, name := range getRegisteredModelNames() {
+					suggestions = append(suggestions, prompt.Suggest{Text: name})
+				}
+				return suggestions
+			}
+			// モデル名を入力中にフィルタリング
+			if len(args) == 2 && !strings.HasSuffix(currentLine, " ") {
+				for _, name := range getRegisteredModelNames() {
+					suggestions = append(suggestions, prompt.Suggest{Text: name})
+				}
+				return prompt.FilterHasPrefix(suggestions, wordBeforeCursor, true)
+			}
+			if modelType, isValidModel := modelRegistry[args[1]]; isValidModel {
+				return completeWriteCommand(command, modelType, args, currentLine, wordBeforeCursor)
+			}
+
+		case "gen":
+			// テーブル名と into を提案 (into の後はファイル名なので補完しない)
+			if len(args) >= 2 && strings.ToLower(args[len(args)-1]) == "into" && strings.HasSuffix(currentLine, " ") {
+				return suggestions
+			}
+			if len(args) >= 3 && strings.ToLower(args[len(args)-2]) == "into" && !strings.HasSuffix(currentLine, " ") {
+				return suggestions
+			}
+			if currentDB != nil {
+				tables, _ := listTables(context.Background(), currentDB.DB)
+				for _, table := range tables {
+					suggestions = append(suggestions, prompt.Suggest{Text: table})
+				}
+			}
+			suggestions = append(suggestions, prompt.Suggest{Text: "into", Description: "Write the generated code to a file"})
+			return prompt.FilterHasPrefix(suggestions, wordBeforeCursor, true)
+
 		case "connect":
 			// ファイル名の補完は省略
 			return []prompt.Suggest{}
@@ -296,6 +388,115 @@ func completer(d prompt.Document) []prompt.Suggest {
 	return suggestions
 }
 
+// completeWriteCommand は insert / update / delete のモデル名以降の補完候補を返します。
+func completeWriteCommand(command string, modelType reflect.Type, args []string, currentLine, wordBeforeCursor string) []prompt.Suggest {
+	// 入力済みの単語 (入力途中の単語は除く)
+	done := args[2:]
+	if !strings.HasSuffix(currentLine, " ") {
+		done = done[:len(done)-1]
+	}
+
+	// where の後はカラム名を提案 (find の where と同様、where の直後のみ)
+	whereIndex := -1
+	for i, arg := range done {
+		if strings.ToLower(arg) == "where" {
+			whereIndex = i
+			break
+		}
+	}
+	if whereIndex >= 0 {
+		return completeWhere(modelType, done[whereIndex+1:], wordBeforeCursor)
+	}
+
+	var suggestions []prompt.Suggest
+	switch command {
+	case "insert":
+		suggestions = getAssignmentSuggestions(modelType, done, true)
+	case "update":
+		if len(done) == 0 {
+			suggestions = []prompt.Suggest{{Text: "set"}}
+			break
+		}
+		suggestions = getAssignmentSuggestions(modelType, done[1:], false)
+		if len(done) > 1 {
+			suggestions = append(suggestions, prompt.Suggest{Text: "where"})
+		}
+	case "delete":
+		if len(done) == 0 {
+			suggestions = []prompt.Suggest{{Text: "where"}}
+		}
+	}
+	return prompt.FilterHasPrefix(suggestions, wordBeforeCursor, true)
+}
+
+// whereOperators は where 句でカラム名の後に提案する演算子です。
+var whereOperators = []prompt.Suggest{
+	{Text: "=", Description: "equal"},
+	{Text: "!=", Description: "not equal"},
+	{Text: ">", Description: "greater than"},
+	{Text: ">=", Description: "greater than or equal"},
+	{Text: "<", Description: "less than"},
+	{Text: "<=", Description: "less than or equal"},
+	{Text: "like", Description: "pattern match (% and _)"},
+	{Text: "in", Description: "one of the values"},
+	{Text: "is", Description: "is null / is not null"},
+}
+
+// completeWhere は where 以降の入力済みの単語 (done) から次の候補を返します。
+// 条件の先頭 (where / and / or の直後) ではカラム名、カラム名の後では演算子、演算子の後ではプレースホルダ ? を提案します。
+// 値の後では and / or と keywords (where 句の後に続けられるキーワード) を提案します。
+func completeWhere(modelType reflect.Type, done []string, wordBeforeCursor string, keywords ...prompt.Suggest) []prompt.Suggest {
+	var suggestions []prompt.Suggest
+	last := ""
+	if len(done) > 0 {
+		last = strings.ToLower(done[len(done)-1])
+	}
+	switch {
+	case last == "" || last == "and" || last == "or":
+		suggestions = getModelColumnSuggestions(modelType)
+	case isWhereOperator(last):
+		if last == "is" {
+			suggestions = []prompt.Suggest{{Text: "null"}, {Text: "not"}}
+		} else {
+			suggestions = []prompt.Suggest{{Text: "?", Description: "placeholder (put the value at the end of the where clause)"}}
+		}
+	default:
+		if _, _, ok := resolveField(modelType, last); ok {
+			suggestions = whereOperators
+		} else {
+			suggestions = append([]prompt.Suggest{{Text: "and"}, {Text: "or"}}, keywords...)
+		}
+	}
+	return prompt.FilterHasPrefix(suggestions, wordBeforeCursor, true)
+}
+
+func isWhereOperator(s string) bool {
+	for _, op := range whereOperators {
+		if op.Text == s {
+			return true
+		}
+	}
+	return false
+}
+
+// getAssignmentSuggestions は "<field>=" 形式の候補を返します。既に指定済みのフィールドは除外します。
+func getAssignmentSuggestions(modelType reflect.Type, assigned []string, excludeID bool) []prompt.Suggest {
+	used := make(map[string]bool)
+	for _, a := range assigned {
+		name, _, _ := strings.Cut(a, "=")
+		used[strings.ToLower(name)] = true
+	}
+
+	suggestions := []prompt.Suggest{}
+	for _, s := range getModelColumnSuggestions(modelType) {
+		if used[strings.ToLower(s.Text)] || (excludeID && s.Text == "ID") {
+			continue
+		}
+		suggestions = append(suggestions, prompt.Suggest{Text: s.Text + "="})
+	}
+	return suggestions
+}
+
 // --- ヘルパー関数 (カラム名取得を追加) ---
 // モデルの型情報からカラム名（フィールド名）の Suggestion リストを取得
 func getModelColumnSuggestions(modelType reflect.Type) []prompt.Suggest {
@@ -352,10 +553,13 @@ func printHelp() {
 
 func connectDB(filename string) {
 	var err error
+	if currentTX != nil {
+		rollbackTx()
+	}
 	if currentDB != nil {
 		currentDB.Close()
 	}
-	dbInstance, err := orm.Open(filename)
+	dbInstance, err := orm.Open("sqlite3", filename)
 	if err != nil {
 		fmt.Printf("Error connecting to database %s: %v\n", filename, err)
 		return
@@ -376,6 +580,9 @@ func disconnectDB() {
 		fmt.Println("Not connected.")
 		return
 	}
+	if currentTX != nil {
+		rollbackTx()
+	}
 	err := currentDB.Close()
 	if err != nil {
 		fmt.Println("Error closing database:", err)
@@ -387,7 +594,7 @@ func disconnectDB() {
 }
 
 func processCommand(line string) {
-	parts := strings.Fields(line)
+	parts := splitCommandLine(line)
 	if len(parts) == 0 {
 		return
 	}
@@ -404,6 +611,14 @@ func processCommand(line string) {
 		connectDB(parts[1])
 	case "disconnect":
 		disconnectDB()
+	case "gen":
+		runGen(parts[1:])
+	case "begin":
+		beginTx()
+	case "commit":
+		commitTx()
+	case "rollback":
+		rollbackTx()
 	case "tables":
 		showTables() // 実装は getRegisteredModelNames を使うように変更しても良い
 	case "schema":
@@ -433,9 +648,12 @@ func processCommand(line string) {
 
 		// オプションのパース (簡易版)
 		var whereClause string
+		var whereArgs []interface{}
 		var orderClause string
 		var limit *int
 		var offset *int
+		var outputFormat string
+		var outputFile string
 
 		remainingParts := parts[2:]
 		i := 0
@@ -450,8 +668,12 @@ func processCommand(line string) {
 					i++
 				}
 				if whereStartIndex < i {
-					whereClause = strings.Join(remainingParts[whereStartIndex:i], " ")
-					fmt.Printf("DEBUG: Parsed WHERE clause: %s\n", whereClause) // デバッグ用
+					var err error
+					whereClause, whereArgs, err = parseWhere(remainingParts[whereStartIndex:i])
+					if err != nil {
+						fmt.Printf("Error: %v\n", err)
+						return
+					}
 				} else {
 					fmt.Println("Error: Missing condition after 'where'.")
 					return
@@ -507,6 +729,26 @@ func processCommand(line string) {
 					fmt.Println("Error: Missing number after 'offset'.")
 					return
 				}
+			case "output":
+				if command != "find" {
+					fmt.Printf("Error: 'output' is only supported for 'find' command.\n")
+					return
+				}
+				if i >= len(remainingParts) {
+					fmt.Println("Error: Missing format after 'output' (csv or json).")
+					return
+				}
+				outputFormat = strings.ToLower(remainingParts[i])
+				if outputFormat != "csv" && outputFormat != "json" {
+					fmt.Printf("Error: Unknown output format '%s' (csv or json).\n", remainingParts[i])
+					return
+				}
+				i++
+				// 続く引数がキーワードでなければ出力先のファイル名
+				if i < len(remainingParts) && !isKeyword(remainingParts[i]) {
+					outputFile = remainingParts[i]
+					i++
+				}
 			default:
 				fmt.Printf("Unknown option or keyword: %s\n", remainingParts[i-1])
 				return
@@ -516,11 +758,61 @@ func processCommand(line string) {
 		// パース結果を使って実行
 		switch command {
 		case "find":
-			executeFind(context.Background(), modelType, whereClause, orderClause, limit, offset)
+			executeFind(context.Background(), modelType, whereClause, whereArgs, orderClause, limit, offset, outputFormat, outputFile)
 		case "first":
-			executeFirst(context.Background(), modelType, whereClause, orderClause)
+			executeFirst(context.Background(), modelType, whereClause, whereArgs, orderClause)
 		case "count":
-			executeCount(context.Background(), modelType, whereClause)
+			executeCount(context.Background(), modelType, whereClause, whereArgs)
+		}
+
+	case "insert", "update", "delete":
+		if currentDB == nil {
+			fmt.Println("Not connected to a database.")
+			return
+		}
+		if len(parts) < 2 {
+			fmt.Printf("Usage: %s <model> [options...]\n", command)
+			return
+		}
+		modelName := parts[1]
+		modelType, ok := modelRegistry[modelName]
+		if !ok {
+			fmt.Printf("Unknown model: %s. Registered models: %v\n", modelName, getRegisteredModelNames())
+			return
+		}
+
+		switch command {
+		case "insert":
+			if len(parts) < 3 {
+				fmt.Println("Usage: insert <model> <field>=<value> ...")
+				return
+			}
+			executeInsert(context.Background(), modelType, parts[2:])
+		case "update":
+			// update <model> set <assignments...> where <cond>
+			whereIndex := indexOfKeyword(parts, "where")
+			if len(parts) < 4 || strings.ToLower(parts[2]) != "set" || whereIndex < 4 || whereIndex == len(parts)-1 {
+				fmt.Println("Usage: update <model> set <field>=<value> ... where <cond>")
+				return
+			}
+			whereClause, whereArgs, err := parseWhere(parts[whereIndex+1:])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			executeUpdate(context.Background(), modelType, parts[3:whereIndex], whereClause, whereArgs)
+		case "delete":
+			// delete <model> where <cond>
+			if len(parts) < 4 || strings.ToLower(parts[2]) != "where" {
+				fmt.Println("Usage: delete <model> where <cond>")
+				return
+			}
+			whereClause, whereArgs, err := parseWhere(parts[3:])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return
+			}
+			executeDelete(context.Background(), modelType, whereClause, whereArgs)
 		}
 
 	default:
@@ -529,10 +821,297 @@ func processCommand(line string) {
 	}
 }
 
+// --- トランザクション操作 ---
+func beginTx() {
+	if currentDB == nil {
+		fmt.Println("Not connected to a database.")
+		return
+	}
+	if currentTX != nil {
+		fmt.Println("Transaction already in progress. Use 'commit' or 'rollback' first.")
+		return
+	}
+	tx, err := currentDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		fmt.Printf("Error beginning transaction: %v\n", err)
+		return
+	}
+	currentTX = tx
+	fmt.Println("Transaction started.")
+}
+
+func commitTx() {
+	if currentTX == nil {
+		fmt.Println("No transaction in progress.")
+		return
+	}
+	err := currentTX.Commit()
+	// 失敗した場合もトランザクションは終了しているので参照はクリアする
+	currentTX = nil
+	if err != nil {
+		fmt.Printf("Error committing transaction: %v\n", err)
+		return
+	}
+	fmt.Println("Transaction committed.")
+}
+
+func rollbackTx() {
+	if currentTX == nil {
+		fmt.Println("No transaction in progress.")
+		return
+	}
+	err := currentTX.Rollback()
+	currentTX = nil
+	if err != nil {
+		fmt.Printf("Error rolling back transaction: %v\n", err)
+		return
+	}
+	fmt.Println("Transaction rolled back.")
+}
+
 // --- ヘルパー関数 (追加) ---
 func isKeyword(s string) bool {
 	lower := strings.ToLower(s)
-	return lower == "where" || lower == "order" || lower == "limit" || lower == "offset"
+	return lower == "where" || lower == "order" || lower == "limit" || lower == "offset" || lower == "output"
+}
+
+// indexOfKeyword は parts の中で最初に keyword (大文字小文字を区別しない) が現れる位置を返します。見つからなければ -1 を返します。
+func indexOfKeyword(parts []string, keyword string) int {
+	for i, p := range parts {
+		if strings.ToLower(p) == keyword {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitCommandLine は入力行を空白で分割します。クォート (' or ") で囲まれた部分は空白を含んでいても 1 つの単語として扱い、
+// where 句でそのまま使えるようにクォート自体も残します。
+func splitCommandLine(line string) []string {
+	var parts []string
+	var current strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			current.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			current.WriteRune(r)
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				parts = append(parts, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
+
+// unquote は値の前後のクォート (' or ") を取り除きます。
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseWhere は where 以降の単語から WHERE 句とバインドする値を取り出します。
+// 句の中の ? の数だけ末尾の単語を値とみなします (例: name = ? 'Alice Smith' -> "name = ?", ["Alice Smith"])。
+// ? を含まない場合は単語をつなげた句をそのまま使います。
+func parseWhere(tokens []string) (string, []interface{}, error) {
+	placeholders := 0
+	for split := 0; split <= len(tokens); split++ {
+		if placeholders == len(tokens)-split {
+			args := make([]interface{}, 0, placeholders)
+			for _, token := range tokens[split:] {
+				args = append(args, parseWhereValue(token))
+			}
+			return strings.Join(tokens[:split], " "), args, nil
+		}
+		if split < len(tokens) {
+			placeholders += countPlaceholders(tokens[split])
+		}
+	}
+	return "", nil, fmt.Errorf("where clause has %d placeholder(s) but the number of values does not match", placeholders)
+}
+
+// countPlaceholders はクォートされた文字列の外にある ? の数を返します。
+func countPlaceholders(s string) int {
+	count := 0
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			count++
+		}
+	}
+	return count
+}
+
+// parseWhereValue はバインドする値を変換します。クォートされた値は文字列、null は NULL、数値は数値として扱います。
+func parseWhereValue(token string) interface{} {
+	if unquoted := unquote(token); unquoted != token {
+		return unquoted
+	}
+	if strings.EqualFold(token, "null") {
+		return nil
+	}
+	if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return f
+	}
+	return token
+}
+
+// resolveField はフィールド名またはカラム名 (大文字小文字を区別しない) からモデルのフィールドとカラム名を取得します。
+func resolveField(modelType reflect.Type, name string) (reflect.StructField, string, bool) {
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		if !field.IsExported() || isRelationField(field) {
+			continue // リレーションフィールドは対象外
+		}
+		column := field.Tag.Get("db")
+		if column == "-" {
+			continue
+		}
+		if column == "" {
+			column = strings.ToLower(field.Name)
+		}
+		if strings.EqualFold(field.Name, name) || strings.EqualFold(column, name) {
+			return field, column, true
+		}
+	}
+	return reflect.StructField{}, "", false
+}
+
+// isRelationField は orm タグが hasmany / belongsTo / many2many のリレーションフィールドかどうかを判定します。
+func isRelationField(field reflect.StructField) bool {
+	tag := field.Tag.Get("orm")
+	return strings.HasPrefix(tag, "hasmany") || strings.HasPrefix(tag, "belongsTo") || strings.HasPrefix(tag, "many2many")
+}
+
+// parseAssignments は "<field>=<value>" のリストをカラム名 -> 値のマップに変換します。値はフィールドの型に変換されます。
+func parseAssignments(modelType reflect.Type, assignments []string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, a := range assignments {
+		name, raw, ok := strings.Cut(a, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid assignment %q (expected <field>=<value>)", a)
+		}
+		field, column, found := resolveField(modelType, name)
+		if !found {
+			return nil, fmt.Errorf("unknown field %q for model %s", name, modelType.Name())
+		}
+		v := reflect.New(field.Type).Elem()
+		if err := setFieldFromString(v, unquote(raw)); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", field.Name, err)
+		}
+		values[column] = v.Interface()
+	}
+	return values, nil
+}
+
+// timeLayouts は日時の値として受け付けるフォーマットです。
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, error) {
+	if strings.ToLower(s) == "now" {
+		return time.Now(), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as time (use RFC3339, 'YYYY-MM-DD HH:MM:SS', 'YYYY-MM-DD' or 'now')", s)
+}
+
+// setFieldFromString は文字列の値をフィールドの型に変換して設定します。"null" はゼロ値 (NULL) として扱います。
+func setFieldFromString(v reflect.Value, raw string) error {
+	if strings.ToLower(raw) == "null" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Interface().(type) {
+	case time.Time:
+		t, err := parseTime(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case sql.NullTime:
+		t, err := parseTime(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(sql.NullTime{Time: t, Valid: true}))
+		return nil
+	}
+	// sql.NullString などの Scanner はドライバーの値と同様に文字列から変換させる
+	if scanner, ok := v.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(raw)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setFieldFromString(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
 }
 
 func parseInt(s string) (int, error) {
@@ -542,16 +1121,16 @@ func parseInt(s string) (int, error) {
 }
 
 // --- ORM 実行関数 (新規) ---
-func executeFind(ctx context.Context, modelType reflect.Type, whereClause, orderClause string, limit, offset *int) {
+func executeFind(ctx context.Context, modelType reflect.Type, whereClause string, whereArgs []interface{}, orderClause string, limit, offset *int, outputFormat, outputFile string) {
 	// モデルのポインタのスライスを作成 (例: *[]orm.User)
 	sliceType := reflect.SliceOf(reflect.PtrTo(modelType))
 	destSlice := reflect.New(sliceType)
 
 	// QueryBuilder を構築
 	modelPtr := reflect.New(modelType).Interface() // Model() にはポインタを渡す
-	qb := currentDB.Model(modelPtr)
+	qb := currentRunner().Model(modelPtr)
 	if whereClause != "" {
-		qb = qb.Where(whereClause) // 引数なし
+		qb = qb.Where(whereClause, whereArgs...)
 	}
 	if orderClause != "" {
 		qb = qb.Order(orderClause)
@@ -571,15 +1150,15 @@ func executeFind(ctx context.Context, modelType reflect.Type, whereClause, order
 	}
 
 	// 結果表示
-	printStructs(destSlice.Elem())
+	printStructs(destSlice.Elem(), outputFormat, outputFile)
 }
 
-func executeFirst(ctx context.Context, modelType reflect.Type, whereClause, orderClause string) {
+func executeFirst(ctx context.Context, modelType reflect.Type, whereClause string, whereArgs []interface{}, orderClause string) {
 	dest := reflect.New(modelType).Interface() // ポインタを作成 (例: *orm.User)
 
-	qb := currentDB.Model(dest) // dest を直接 Model に渡せる
+	qb := currentRunner().Model(dest) // dest を直接 Model に渡せる
 	if whereClause != "" {
-		qb = qb.Where(whereClause)
+		qb = qb.Where(whereClause, whereArgs...)
 	}
 	if orderClause != "" {
 		qb = qb.Order(orderClause)
@@ -599,12 +1178,12 @@ func executeFirst(ctx context.Context, modelType reflect.Type, whereClause, orde
 	printStruct(reflect.ValueOf(dest))
 }
 
-func executeCount(ctx context.Context, modelType reflect.Type, whereClause string) {
+func executeCount(ctx context.Context, modelType reflect.Type, whereClause string, whereArgs []interface{}) {
 	var count int64
 	modelPtr := reflect.New(modelType).Interface()
-	qb := currentDB.Model(modelPtr)
+	qb := currentRunner().Model(modelPtr)
 	if whereClause != "" {
-		qb = qb.Where(whereClause)
+		qb = qb.Where(whereClause, whereArgs...)
 	}
 
 	err := qb.Count(&count)
@@ -616,13 +1195,91 @@ func executeCount(ctx context.Context, modelType reflect.Type, whereClause strin
 	fmt.Printf("Count: %d\n", count)
 }
 
+func executeInsert(ctx context.Context, modelType reflect.Type, assignments []string) {
+	values, err := parseAssignments(modelType, assignments)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	// 値を構造体に設定する
+	dest := reflect.New(modelType)
+	for column, value := range values {
+		field, _, _ := resolveField(modelType, column)
+		dest.Elem().FieldByIndex(field.Index).Set(reflect.ValueOf(value))
+	}
+	// 指定されていない created_at / updated_at は現在時刻にする
+	now := time.Now()
+	for _, column := range []string{"created_at", "updated_at"} {
+		if _, ok := values[column]; ok {
+			continue
+		}
+		if field, _, found := resolveField(modelType, column); found && field.Type == reflect.TypeOf(time.Time{}) {
+			dest.Elem().FieldByIndex(field.Index).Set(reflect.ValueOf(now))
+		}
+	}
+
+	if _, err := currentRunner().Model(dest.Interface()).WithContext(ctx).Insert(dest.Interface()); err != nil {
+		fmt.Printf("Error executing insert: %v\n", err)
+		return
+	}
+
+	printStruct(dest)
+}
+
+func executeUpdate(ctx context.Context, modelType reflect.Type, assignments []string, whereClause string, whereArgs []interface{}) {
+	values, err := parseAssignments(modelType, assignments)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	// updated_at が指定されていなければ現在時刻で更新する
+	if _, ok := values["updated_at"]; !ok {
+		if field, _, found := resolveField(modelType, "updated_at"); found && field.Type == reflect.TypeOf(time.Time{}) {
+			values["updated_at"] = time.Now()
+		}
+	}
+
+	modelPtr := reflect.New(modelType).Interface()
+	result, err := currentRunner().Model(modelPtr).WithContext(ctx).Where(whereClause, whereArgs...).Update(values)
+	if err != nil {
+		fmt.Printf("Error executing update: %v\n", err)
+		return
+	}
+	rowsAffected, _ := result.RowsAffected()
+	fmt.Printf("Updated %d row(s)\n", rowsAffected)
+}
+
+func executeDelete(ctx context.Context, modelType reflect.Type, whereClause string, whereArgs []interface{}) {
+	modelPtr := reflect.New(modelType).Interface()
+	result, err := currentRunner().Model(modelPtr).WithContext(ctx).Where(whereClause, whereArgs...).Delete()
+	if err != nil {
+		fmt.Printf("Error executing delete: %v\n", err)
+		return
+	}
+	rowsAffected, _ := result.RowsAffected()
+	fmt.Printf("Deleted %d row(s)\n", rowsAffected)
+}
+
 // --- 結果表示関数 (新規) ---
 // 構造体のスライスを表形式で表示
-func printStructs(sliceVal reflect.Value) {
+// printStructs は構造体のスライスを表示します。
+// outputFormat (csv / json) を指定した場合は表ではなくその形式で、outputFile が指定されていればファイルに書き出します。
+func printStructs(sliceVal reflect.Value, outputFormat, outputFile string) {
 	if sliceVal.Kind() != reflect.Slice {
 		fmt.Println("[printStructs] Error: input is not a slice")
 		return
 	}
+	if outputFormat != "" {
+		if err := writeStructsOutput(sliceVal, outputFormat, outputFile); err != nil {
+			fmt.Printf("Error writing %s output: %v\n", outputFormat, err)
+			return
+		}
+		if outputFile != "" {
+			fmt.Printf("%d rows written to %s\n", sliceVal.Len(), outputFile)
+		}
+		return
+	}
 	if sliceVal.Len() == 0 {
 		fmt.Println("(no rows)")
 		return
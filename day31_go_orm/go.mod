@@ -3,6 +3,6 @@ module github.com/lirlia/100day_challenge_backend/day31_go_orm
 go 1.24.2
 
 require (
-	github.com/mattn/go-sqlite3 v1.14.28 // indirect
+	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/stoewer/go-strcase v1.3.0 // indirect
 )
@@ -0,0 +1,270 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxQueryCacheEntries はクエリキャッシュに保持するエントリ数の上限です。
+// 上限に達したら期限切れのエントリを削除し、それでも空きがなければすべて破棄します。
+const maxQueryCacheEntries = 1000
+
+// queryCache は QueryBuilder の読み取り結果を保持するインメモリキャッシュです。
+// キーは生成された SQL と引数 (と結果の型・Preload) で、同じテーブルへの書き込みで無効化されます。
+type queryCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration                  // 0 なら期限なし
+	entries    map[string]*cacheEntry         // キャッシュキー -> エントリ
+	byTable    map[string]map[string]struct{} // テーブル名 -> そのテーブルを参照するキャッシュキー
+	generation uint64                         // 無効化のたびに増える (読み取り中の無効化を検出するため)
+}
+
+// cacheEntry はキャッシュされた結果 (dest が指す値のコピー) です。
+type cacheEntry struct {
+	value   reflect.Value
+	tables  []string
+	expires time.Time // ゼロ値なら期限なし
+}
+
+func newQueryCache(ttl time.Duration) *queryCache {
+	return &queryCache{
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+		byTable: make(map[string]map[string]struct{}),
+	}
+}
+
+// EnableQueryCache はクエリ結果のキャッシュを有効にします。ttl が 0 の場合、エントリは無効化されるまで保持されます。
+// キャッシュは Cache() を指定したクエリにだけ使われ、Model() / Table() 経由の Insert / Update / Delete で
+// 同じテーブルへの書き込みがあると自動的に無効化されます (トランザクション内の書き込みはコミット時に無効化)。
+// Open の直後、クエリを実行する前に呼び出してください。
+func (db *DB) EnableQueryCache(ttl time.Duration) {
+	db.cache = newQueryCache(ttl)
+}
+
+// InvalidateCache は指定したテーブルを参照するキャッシュを破棄します。テーブルを指定しない場合はすべて破棄します。
+// ExecContext などで QueryBuilder を経由せずに書き込んだ場合に呼び出してください。
+func (db *DB) InvalidateCache(tables ...string) {
+	if len(tables) == 0 {
+		db.cache.clear()
+		return
+	}
+	db.cache.invalidate(tables...)
+}
+
+// Cache は SELECT / Count / ScanMaps の結果をキャッシュするように指定します。
+// DB で EnableQueryCache を呼んでいない場合や、トランザクション内のクエリでは効果がありません。
+// キャッシュから返す結果はスライスと map をコピーしたものですが、Preload した関連データなどの内部は共有されるため書き換えないでください。
+func (qb *QueryBuilder) Cache() *QueryBuilder {
+	qb.useCache = true
+	return qb
+}
+
+// cacheStore はクエリに使うキャッシュを返します。キャッシュを使わない場合は nil を返します。
+func (qb *QueryBuilder) cacheStore() *queryCache {
+	if !qb.useCache {
+		return nil
+	}
+	// トランザクション内では未コミットのデータが見えるのでキャッシュを使わない
+	if db, ok := qb.executor.(*DB); ok {
+		return db.cache
+	}
+	return nil
+}
+
+// cached はキャッシュにあれば dest に結果をコピーし、なければ run を実行して結果をキャッシュします。
+func (qb *QueryBuilder) cached(op string, dest interface{}, query string, args []interface{}, run func() error) error {
+	cache := qb.cacheStore()
+	if cache == nil {
+		return run()
+	}
+
+	preloads := make([]string, 0, len(qb.preloads))
+	for path := range qb.preloads {
+		preloads = append(preloads, path)
+	}
+	sort.Strings(preloads)
+	key := fmt.Sprintf("%s|%T|%s|%#v|%s", op, dest, query, args, strings.Join(preloads, ","))
+
+	if cache.load(key, dest) {
+		return nil
+	}
+	generation := cache.currentGeneration()
+	if err := run(); err != nil {
+		return err
+	}
+	cache.store(key, generation, qb.cacheTables(preloads), dest)
+	return nil
+}
+
+// cacheTables はクエリが参照するテーブル (Preload するリレーションのテーブルを含む) を返します。
+func (qb *QueryBuilder) cacheTables(preloads []string) []string {
+	tables := []string{qb.tableName}
+	for _, path := range preloads {
+		structType := qb.modelType
+		for _, fieldName := range strings.Split(path, ".") {
+			structInfo, err := getStructInfo(structType)
+			if err != nil {
+				break
+			}
+			relation, ok := structInfo.relations[fieldName]
+			if !ok {
+				break
+			}
+			tables = append(tables, getTableName(relation.RelatedType))
+			if relation.JoinTable != "" {
+				tables = append(tables, relation.JoinTable)
+			}
+			structType = relation.RelatedType
+		}
+	}
+	return tables
+}
+
+// invalidateCache は書き込みのあったテーブルのキャッシュを無効化します。
+func (qb *QueryBuilder) invalidateCache() {
+	switch e := qb.executor.(type) {
+	case *DB:
+		e.cache.invalidate(qb.tableName)
+	case *TX:
+		// コミットされるまで他の接続からは見えないので、コミット時に無効化する
+		if e.written == nil {
+			e.written = make(map[string]bool)
+		}
+		e.written[qb.tableName] = true
+	}
+}
+
+// load はキャッシュが有効期限内であれば dest に結果をコピーして true を返します。
+func (c *queryCache) load(key string, dest interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(key)
+		return false
+	}
+	reflect.ValueOf(dest).Elem().Set(cloneResult(entry.value))
+	return true
+}
+
+// currentGeneration は現在の世代を返します。
+func (c *queryCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// store は dest が指す結果をキャッシュします。
+// クエリの実行中に無効化があった場合 (generation が変わった場合) は古い結果の可能性があるので保存しません。
+func (c *queryCache) store(key string, generation uint64, tables []string, dest interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	if len(c.entries) >= maxQueryCacheEntries {
+		c.evict()
+	}
+
+	entry := &cacheEntry{
+		value:  cloneResult(reflect.ValueOf(dest).Elem()),
+		tables: tables,
+	}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	c.entries[key] = entry
+	for _, table := range tables {
+		if c.byTable[table] == nil {
+			c.byTable[table] = make(map[string]struct{})
+		}
+		c.byTable[table][key] = struct{}{}
+	}
+}
+
+// invalidate は指定したテーブルを参照するエントリを削除します。キャッシュが無効 (nil) の場合は何もしません。
+func (c *queryCache) invalidate(tables ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, table := range tables {
+		for key := range c.byTable[table] {
+			c.remove(key)
+		}
+	}
+}
+
+// clear はすべてのエントリを削除します。
+func (c *queryCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]*cacheEntry)
+	c.byTable = make(map[string]map[string]struct{})
+}
+
+// evict は期限切れのエントリを削除し、それでも上限に達していればすべて削除します。c.mu を保持して呼び出します。
+func (c *queryCache) evict() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if !entry.expires.IsZero() && now.After(entry.expires) {
+			c.remove(key)
+		}
+	}
+	if len(c.entries) >= maxQueryCacheEntries {
+		c.entries = make(map[string]*cacheEntry)
+		c.byTable = make(map[string]map[string]struct{})
+	}
+}
+
+// remove はエントリを削除します。c.mu を保持して呼び出します。
+func (c *queryCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	for _, table := range entry.tables {
+		delete(c.byTable[table], key)
+		if len(c.byTable[table]) == 0 {
+			delete(c.byTable, table)
+		}
+	}
+}
+
+// cloneResult は結果の値をコピーします。スライスは新しい配列に、要素の map (ScanMaps の結果) は新しい map にコピーします。
+func cloneResult(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Slice || v.IsNil() {
+		clone := reflect.New(v.Type()).Elem()
+		clone.Set(v)
+		return clone
+	}
+	clone := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Map && !elem.IsNil() {
+			m := reflect.MakeMapWithSize(elem.Type(), elem.Len())
+			iter := elem.MapRange()
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
+			elem = m
+		}
+		clone.Index(i).Set(elem)
+	}
+	return clone
+}
@@ -0,0 +1,246 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ColumnKind は AutoMigrate が扱うカラムの抽象的な型です。Dialect が DB ごとの型名に変換します。
+type ColumnKind string
+
+const (
+	KindInteger  ColumnKind = "integer"
+	KindFloat    ColumnKind = "float"
+	KindText     ColumnKind = "text"
+	KindBlob     ColumnKind = "blob"
+	KindBool     ColumnKind = "bool"
+	KindDatetime ColumnKind = "datetime"
+)
+
+// Dialect は DB ごとに異なる SQL の書き方を吸収します。
+// QueryBuilder はプレースホルダを "?" で組み立て、実行前に Dialect の形式に置き換えます。
+type Dialect interface {
+	// Name は Dialect の名前を返します (例: "sqlite3")。
+	Name() string
+	// Placeholder は n 番目 (1 始まり) のバインド変数のプレースホルダを返します。
+	Placeholder(n int) string
+	// Quote はテーブル名・カラム名をクォートします。
+	Quote(identifier string) string
+	// LimitOffset は LIMIT / OFFSET 句を返します (先頭の空白を含む。どちらも nil なら空文字列)。
+	LimitOffset(limit, offset *int) string
+	// SupportsReturning は INSERT ... RETURNING で採番された ID を取得するかどうかを返します。
+	SupportsReturning() bool
+	// ColumnType は AutoMigrate で使うカラムの型名を返します。
+	ColumnType(kind ColumnKind) string
+	// AutoIncrementPrimaryKey は自動採番の整数主キーのカラム定義 (型と制約) を返します。
+	AutoIncrementPrimaryKey() string
+	// ColumnsQuery はテーブルのカラム名を 1 列目に返すクエリと引数を返します (プレースホルダは "?")。
+	ColumnsQuery(table string) (string, []interface{})
+}
+
+var (
+	dialects = map[string]Dialect{
+		"sqlite3":  SQLite,
+		"postgres": Postgres,
+		"pgx":      Postgres,
+		"mysql":    MySQL,
+	}
+	dialectsMu sync.RWMutex
+)
+
+// RegisterDialect はドライバー名に対応する Dialect を登録します。組み込み以外のドライバーを Open で使う場合に呼び出します。
+func RegisterDialect(driverName string, d Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[driverName] = d
+}
+
+// dialectFor はドライバー名に対応する Dialect を返します。
+func dialectFor(driverName string) (Dialect, error) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	d, ok := dialects[driverName]
+	if !ok {
+		return nil, fmt.Errorf("orm: no dialect registered for driver %q (use RegisterDialect)", driverName)
+	}
+	return d, nil
+}
+
+// Rebind はクエリ中の "?" を Dialect のプレースホルダに置き換えます。クォートされた文字列内の "?" は置き換えません。
+func Rebind(d Dialect, query string) string {
+	if d.Placeholder(1) == "?" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString(d.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// quoteIdentifier は識別子として安全な名前だけをクォートします。
+// Table("pragma_table_info('users')") のように式が指定された場合はそのまま使います。
+func quoteIdentifier(d Dialect, name string) string {
+	if !safeIdentifierPattern.MatchString(name) {
+		return name
+	}
+	return d.Quote(name)
+}
+
+// --- SQLite ---
+
+type sqliteDialect struct{}
+
+// SQLite は SQLite (github.com/mattn/go-sqlite3) 用の Dialect です。
+var SQLite Dialect = sqliteDialect{}
+
+func (sqliteDialect) Name() string             { return "sqlite3" }
+func (sqliteDialect) Placeholder(int) string   { return "?" }
+func (sqliteDialect) Quote(name string) string { return doubleQuote(name) }
+func (sqliteDialect) SupportsReturning() bool  { return false }
+
+func (sqliteDialect) LimitOffset(limit, offset *int) string {
+	// SQLite は LIMIT なしの OFFSET を書けないので LIMIT -1 (無制限) を補う
+	return limitOffset(limit, offset, "-1")
+}
+
+func (sqliteDialect) ColumnType(kind ColumnKind) string {
+	switch kind {
+	case KindInteger, KindBool:
+		return "INTEGER"
+	case KindFloat:
+		return "REAL"
+	case KindBlob:
+		return "BLOB"
+	case KindDatetime:
+		return "DATETIME"
+	default:
+		return "TEXT"
+	}
+}
+
+func (sqliteDialect) AutoIncrementPrimaryKey() string { return "INTEGER PRIMARY KEY AUTOINCREMENT" }
+
+func (sqliteDialect) ColumnsQuery(table string) (string, []interface{}) {
+	return "SELECT name FROM pragma_table_info(?)", []interface{}{table}
+}
+
+// --- PostgreSQL ---
+
+type postgresDialect struct{}
+
+// Postgres は PostgreSQL (lib/pq, pgx) 用の Dialect です。
+var Postgres Dialect = postgresDialect{}
+
+func (postgresDialect) Name() string             { return "postgres" }
+func (postgresDialect) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+func (postgresDialect) Quote(name string) string { return doubleQuote(name) }
+func (postgresDialect) SupportsReturning() bool  { return true }
+
+func (postgresDialect) LimitOffset(limit, offset *int) string {
+	return limitOffset(limit, offset, "")
+}
+
+func (postgresDialect) ColumnType(kind ColumnKind) string {
+	switch kind {
+	case KindInteger:
+		return "BIGINT"
+	case KindBool:
+		return "BOOLEAN"
+	case KindFloat:
+		return "DOUBLE PRECISION"
+	case KindBlob:
+		return "BYTEA"
+	case KindDatetime:
+		return "TIMESTAMPTZ"
+	default:
+		return "TEXT"
+	}
+}
+
+func (postgresDialect) AutoIncrementPrimaryKey() string { return "BIGSERIAL PRIMARY KEY" }
+
+func (postgresDialect) ColumnsQuery(table string) (string, []interface{}) {
+	return "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ?", []interface{}{table}
+}
+
+// --- MySQL ---
+
+type mysqlDialect struct{}
+
+// MySQL は MySQL (go-sql-driver/mysql) 用の Dialect です。
+var MySQL Dialect = mysqlDialect{}
+
+func (mysqlDialect) Name() string           { return "mysql" }
+func (mysqlDialect) Placeholder(int) string { return "?" }
+func (mysqlDialect) SupportsReturning() bool {
+	return false
+}
+
+func (mysqlDialect) Quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (mysqlDialect) LimitOffset(limit, offset *int) string {
+	// MySQL も LIMIT なしの OFFSET を書けないので最大値を補う
+	return limitOffset(limit, offset, "18446744073709551615")
+}
+
+func (mysqlDialect) ColumnType(kind ColumnKind) string {
+	switch kind {
+	case KindInteger:
+		return "BIGINT"
+	case KindBool:
+		return "BOOLEAN"
+	case KindFloat:
+		return "DOUBLE"
+	case KindBlob:
+		return "LONGBLOB"
+	case KindDatetime:
+		return "DATETIME(6)"
+	default:
+		// UNIQUE 制約を付けられるように TEXT ではなく VARCHAR にする
+		return "VARCHAR(255)"
+	}
+}
+
+func (mysqlDialect) AutoIncrementPrimaryKey() string { return "BIGINT AUTO_INCREMENT PRIMARY KEY" }
+
+func (mysqlDialect) ColumnsQuery(table string) (string, []interface{}) {
+	return "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?", []interface{}{table}
+}
+
+// --- 共通ヘルパー ---
+
+func doubleQuote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// limitOffset は LIMIT / OFFSET 句を組み立てます。noLimit が空でなければ、OFFSET のみ指定された場合に LIMIT noLimit を補います。
+func limitOffset(limit, offset *int, noLimit string) string {
+	var b strings.Builder
+	if limit != nil {
+		fmt.Fprintf(&b, " LIMIT %d", *limit)
+	} else if offset != nil && noLimit != "" {
+		fmt.Fprintf(&b, " LIMIT %s", noLimit)
+	}
+	if offset != nil {
+		fmt.Fprintf(&b, " OFFSET %d", *offset)
+	}
+	return b.String()
+}
@@ -0,0 +1,245 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// columnDef は AutoMigrate で生成するカラムの定義です。
+type columnDef struct {
+	name         string
+	kind         ColumnKind // Dialect が DB ごとの型名に変換する
+	primaryKey   bool
+	notNull      bool
+	unique       bool
+	defaultValue string // orm:"default:..." で指定された DEFAULT 式 (空なら指定なし)
+}
+
+// AutoMigrate は構造体の db タグからテーブル定義を生成し、スキーマを同期します。
+// テーブルが存在しない場合は CREATE TABLE を、存在する場合は足りないカラムを ALTER TABLE ADD COLUMN で追加します。
+// 既存カラムの型変更や削除は行いません。
+//
+// カラムの型は Go の型から決まり (DB ごとの型名は Dialect が決める)、ポインタ型と sql.Null* 型は NULL 許容、それ以外は NOT NULL になります。
+// "id" カラム (または orm:"pk" タグのフィールド) は自動採番の主キー (SQLite では INTEGER PRIMARY KEY AUTOINCREMENT) になります。
+// orm タグで以下のオプションを指定できます (カンマ区切り)。
+//
+//	pk                主キーにする
+//	unique            UNIQUE 制約を付ける
+//	default:<expr>    DEFAULT 句を付ける (例: default:CURRENT_TIMESTAMP)
+func (db *DB) AutoMigrate(models ...interface{}) error {
+	ctx := context.Background()
+	for _, model := range models {
+		modelType := reflect.TypeOf(model)
+		if modelType == nil || modelType.Kind() != reflect.Ptr || modelType.Elem().Kind() != reflect.Struct {
+			return fmt.Errorf("orm: AutoMigrate expects pointers to structs, got %T", model)
+		}
+		if err := db.migrateModel(ctx, modelType.Elem()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateModel は 1 つのモデルのテーブルを作成、またはカラムを追加します。
+func (db *DB) migrateModel(ctx context.Context, modelType reflect.Type) error {
+	tableName := getTableName(modelType)
+	dialect := db.dialect
+	columns, err := columnDefs(modelType)
+	if err != nil {
+		return err
+	}
+
+	existing, err := db.existingColumns(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	if existing == nil {
+		defs := make([]string, 0, len(columns))
+		for _, col := range columns {
+			defs = append(defs, col.definition(dialect, false))
+		}
+		query := fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", dialect.Quote(tableName), strings.Join(defs, ",\n\t"))
+		log.Printf("INFO: AutoMigrate: %s", query)
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("orm: failed to create table %s: %w", tableName, err)
+		}
+		return nil
+	}
+
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
+		}
+		if dialect == SQLite && (col.primaryKey || col.unique) {
+			// SQLite は PRIMARY KEY / UNIQUE 制約付きのカラムを ALTER TABLE で追加できない
+			return fmt.Errorf("orm: cannot add column %s to existing table %s (PRIMARY KEY and UNIQUE columns cannot be added by ALTER TABLE)", col.name, tableName)
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", dialect.Quote(tableName), col.definition(dialect, true))
+		log.Printf("INFO: AutoMigrate: %s", query)
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("orm: failed to add column %s to table %s: %w", col.name, tableName, err)
+		}
+	}
+	return nil
+}
+
+// existingColumns はテーブルのカラム名の集合を返します。テーブルが存在しない場合は nil を返します。
+func (db *DB) existingColumns(ctx context.Context, tableName string) (map[string]bool, error) {
+	query, args := db.dialect.ColumnsQuery(tableName)
+	rows, err := db.QueryContext(ctx, Rebind(db.dialect, query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("orm: failed to get table info for %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	var columns map[string]bool
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("orm: failed to scan table info for %s: %w", tableName, err)
+		}
+		if columns == nil {
+			columns = make(map[string]bool)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("orm: error iterating table info for %s: %w", tableName, err)
+	}
+	return columns, nil
+}
+
+// columnDefs は構造体のフィールドからカラム定義を構築します (フィールドの定義順)。
+func columnDefs(modelType reflect.Type) ([]columnDef, error) {
+	var columns []columnDef
+	hasPK := false
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		columnName, ok := fieldColumnName(field)
+		if !ok {
+			continue
+		}
+
+		kind, nullable, ok := columnKind(field.Type)
+		if !ok {
+			return nil, fmt.Errorf("orm: unsupported type %s for field %s in %s", field.Type, field.Name, modelType.Name())
+		}
+
+		col := columnDef{name: columnName, kind: kind, notNull: !nullable}
+		for _, opt := range strings.Split(field.Tag.Get("orm"), ",") {
+			opt = strings.TrimSpace(opt)
+			switch {
+			case opt == "pk":
+				col.primaryKey = true
+			case opt == "unique":
+				col.unique = true
+			case strings.HasPrefix(opt, "default:"):
+				col.defaultValue = strings.TrimPrefix(opt, "default:")
+			}
+		}
+		if col.primaryKey {
+			hasPK = true
+		}
+		columns = append(columns, col)
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("orm: no columns found in %s", modelType.Name())
+	}
+	// orm:"pk" の指定がなければ id カラムを主キーとする (Insert が id を自動採番として扱うのに合わせる)
+	if !hasPK {
+		for i := range columns {
+			if columns[i].name == "id" {
+				columns[i].primaryKey = true
+			}
+		}
+	}
+	return columns, nil
+}
+
+// definition はカラム定義の SQL を返します。
+// forAlter が true の場合、既存の行があっても追加できるように NOT NULL カラムにはゼロ値のデフォルトを付けます。
+func (c columnDef) definition(dialect Dialect, forAlter bool) string {
+	name := dialect.Quote(c.name)
+	if c.primaryKey {
+		if c.kind == KindInteger {
+			return name + " " + dialect.AutoIncrementPrimaryKey()
+		}
+		return name + " " + dialect.ColumnType(c.kind) + " PRIMARY KEY"
+	}
+	parts := []string{name, dialect.ColumnType(c.kind)}
+	if c.notNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if c.unique {
+		parts = append(parts, "UNIQUE")
+	}
+	switch {
+	case c.defaultValue != "":
+		parts = append(parts, "DEFAULT "+c.defaultValue)
+	case forAlter && c.notNull:
+		parts = append(parts, "DEFAULT "+zeroValueLiteral(c.kind))
+	}
+	return strings.Join(parts, " ")
+}
+
+// zeroValueLiteral は型ごとのゼロ値リテラルを返します。
+func zeroValueLiteral(kind ColumnKind) string {
+	switch kind {
+	case KindInteger, KindFloat:
+		return "0"
+	case KindBool:
+		return "FALSE"
+	case KindDatetime:
+		return "'0001-01-01 00:00:00'"
+	default:
+		return "''"
+	}
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// columnKind は Go の型に対応するカラムの型と、NULL 許容かどうかを返します。
+func columnKind(t reflect.Type) (kind ColumnKind, nullable bool, ok bool) {
+	switch t {
+	case reflect.TypeOf(sql.NullString{}):
+		return KindText, true, true
+	case reflect.TypeOf(sql.NullInt64{}), reflect.TypeOf(sql.NullInt32{}), reflect.TypeOf(sql.NullInt16{}), reflect.TypeOf(sql.NullByte{}):
+		return KindInteger, true, true
+	case reflect.TypeOf(sql.NullBool{}):
+		return KindBool, true, true
+	case reflect.TypeOf(sql.NullFloat64{}):
+		return KindFloat, true, true
+	case reflect.TypeOf(sql.NullTime{}):
+		return KindDatetime, true, true
+	case timeType:
+		return KindDatetime, false, true
+	case bytesType:
+		return KindBlob, true, true
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		kind, _, ok := columnKind(t.Elem())
+		return kind, true, ok
+	case reflect.Bool:
+		return KindBool, false, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindInteger, false, true
+	case reflect.Float32, reflect.Float64:
+		return KindFloat, false, true
+	case reflect.String:
+		return KindText, false, true
+	}
+	return "", false, false
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time" // time パッケージを追加
@@ -39,21 +40,44 @@ type Post struct {
 // DB は ORM のメイン構造体で、*sql.DB をラップします。
 type DB struct {
 	*sql.DB
-	mu sync.RWMutex // 将来的な拡張のため (今回は未使用)
+	dialect Dialect      // ドライバーに対応する SQL の方言
+	cache   *queryCache  // クエリ結果のキャッシュ (EnableQueryCache で有効化、nil なら無効)
+	stmts   *stmtCache   // QueryBuilder が再利用するプリペアドステートメント
+	mu      sync.RWMutex // 将来的な拡張のため (今回は未使用)
 }
 
 // TX はトランザクションを表す構造体で、*sql.Tx をラップします。
 type TX struct {
 	*sql.Tx
-	db *DB // トランザクションが属する DB への参照 (将来的な利用のため)
+	db      *DB             // トランザクションが属する DB への参照
+	written map[string]bool // 書き込みのあったテーブル (コミット時にキャッシュを無効化する)
 }
 
-// RelationInfo はリレーション情報を保持します。
+// RelationKind はリレーションの種類です。
+type RelationKind string
+
+const (
+	HasMany   RelationKind = "hasmany"
+	BelongsTo RelationKind = "belongsTo"
+	Many2Many RelationKind = "many2many"
+)
+
+// RelationInfo はリレーション情報を保持します。キーはすべてカラム名です。
+//
+//	hasmany   (User.Posts):   posts.<ForeignKey> = users.<AssociationForeignKey>
+//	belongsTo (Post.User):    posts.<ForeignKey> = users.<AssociationForeignKey>
+//	many2many (Post.Tags):    posts.<AssociationForeignKey> = <JoinTable>.<JoinForeignKey>
+//	                          AND <JoinTable>.<JoinReferences> = tags.<References>
 type RelationInfo struct {
 	FieldName             string       // User 構造体の Posts フィールド名
+	Kind                  RelationKind // リレーションの種類
 	RelatedType           reflect.Type // Post 構造体の型
-	ForeignKey            string       // Post 構造体の UserID フィールド名 (users テーブルの ID を参照)
-	AssociationForeignKey string       // User 構造体の ID フィールド名 (posts テーブルの user_id から参照される)
+	ForeignKey            string       // 外部キーのカラム名 (hasmany: 関連側, belongsTo: 自分側)
+	AssociationForeignKey string       // 外部キーが参照するカラム名 (hasmany / many2many: 自分側, belongsTo: 関連側)
+	JoinTable             string       // many2many の中間テーブル名
+	JoinForeignKey        string       // 中間テーブルで自分側を参照するカラム名
+	JoinReferences        string       // 中間テーブルで関連側を参照するカラム名
+	References            string       // many2many で中間テーブルから参照される関連側のカラム名
 }
 
 // executor は *sql.DB または *sql.Tx の共通インターフェースを定義します。
@@ -65,6 +89,7 @@ type executor interface {
 	// QueryBuilder を返すメソッド
 	Model(model interface{}) *QueryBuilder
 	Table(tableName string) *QueryBuilder
+	Dialect() Dialect
 }
 
 // executor インターフェースを *DB と *TX が満たすようにコンパイル時にチェック
@@ -72,9 +97,15 @@ var _ executor = (*DB)(nil)
 var _ executor = (*TX)(nil)
 
 // Open は新しい DB 接続を開きます。
-// dataSourceName は SQLite ファイルのパスなど、ドライバー固有の接続文字列です。
-func Open(dataSourceName string) (*DB, error) {
-	db, err := sql.Open("sqlite3", dataSourceName)
+// driverName は database/sql に登録されたドライバー名 ("sqlite3", "postgres", "pgx", "mysql" など) で、
+// 対応する Dialect で SQL を生成します。dataSourceName は SQLite ファイルのパスなど、ドライバー固有の接続文字列です。
+// ドライバー自体は呼び出し側でインポートしてください。
+func Open(driverName, dataSourceName string) (*DB, error) {
+	dialect, err := dialectFor(driverName)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, fmt.Errorf("orm: failed to open database: %w", err)
 	}
@@ -83,7 +114,17 @@ func Open(dataSourceName string) (*DB, error) {
 		db.Close() // Ping に失敗したら閉じる
 		return nil, fmt.Errorf("orm: failed to ping database: %w", err)
 	}
-	return &DB{DB: db}, nil
+	return &DB{DB: db, dialect: dialect, stmts: newStmtCache(defaultStatementCacheSize)}, nil
+}
+
+// Dialect は DB の SQL の方言を返します。
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// Dialect はトランザクションが属する DB の SQL の方言を返します。
+func (tx *TX) Dialect() Dialect {
+	return tx.db.dialect
 }
 
 // PingContext はデータベースへの接続を確認します。
@@ -94,8 +135,11 @@ func (db *DB) PingContext(ctx context.Context) error {
 	return nil
 }
 
-// Close はデータベース接続を閉じます。
+// Close はキャッシュしているプリペアドステートメントとデータベース接続を閉じます。
 func (db *DB) Close() error {
+	if db.stmts != nil {
+		db.stmts.close()
+	}
 	if err := db.DB.Close(); err != nil {
 		return fmt.Errorf("orm: failed to close database: %w", err)
 	}
@@ -116,6 +160,9 @@ func (tx *TX) Commit() error {
 	if err := tx.Tx.Commit(); err != nil {
 		return fmt.Errorf("orm: failed to commit transaction: %w", err)
 	}
+	for table := range tx.written {
+		tx.db.cache.invalidate(table)
+	}
 	return nil
 }
 
@@ -150,6 +197,8 @@ type QueryBuilder struct {
 	limit     *int             // LIMIT 条件
 	offset    *int             // OFFSET 条件
 	preloads  map[string]bool  // Preload するフィールド名を格納 (キー: フィールド名, 値: true)
+	useCache  bool             // 結果をキャッシュするか (Cache() で設定)
+	unscoped  bool             // 論理削除されたレコードも対象にするか (Unscoped() で設定)
 	ctx       context.Context  // クエリ実行時のコンテキスト
 }
 
@@ -227,6 +276,12 @@ func (qb *QueryBuilder) Where(query string, args ...interface{}) *QueryBuilder {
 	return qb
 }
 
+// ErrMissingWhereClause は Where なしで Update / Delete を実行しようとした場合に返されます。
+var ErrMissingWhereClause = errors.New("orm: Where() is required for Update() and Delete()")
+
+// 正規表現: カラム名として安全な識別子のみを許可
+var safeIdentifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
 // 正規表現: ORDER BY句として安全な文字のみを許可
 var stricterSafeOrderByPattern = regexp.MustCompile(`^\s*[a-zA-Z0-9_.]+(\s+(?i:asc|desc))?(\s*,\s*[a-zA-Z0-9_.]+(\s+(?i:asc|desc))?)*\s*$`)
 
@@ -264,6 +319,24 @@ func (qb *QueryBuilder) Preload(field string) *QueryBuilder {
 	return qb
 }
 
+// Unscoped は論理削除 (DeletedAt) されたレコードも検索・更新の対象にし、Delete() で物理削除するように指定します。
+func (qb *QueryBuilder) Unscoped() *QueryBuilder {
+	qb.unscoped = true
+	return qb
+}
+
+// softDeleteColumn は論理削除を適用する場合に DeletedAt のカラム名を返します。適用しない場合は空文字列を返します。
+func (qb *QueryBuilder) softDeleteColumn() string {
+	if qb.modelType == nil || qb.unscoped {
+		return ""
+	}
+	structInfo, err := getStructInfo(qb.modelType)
+	if err != nil {
+		return ""
+	}
+	return structInfo.softDeleteColumn
+}
+
 // Select は構築されたクエリを実行し、結果を dest (構造体のスライスへのポインタ) にスキャンします。
 // Preload が指定されている場合、関連データも取得します。
 func (qb *QueryBuilder) Select(dest interface{}) error {
@@ -271,17 +344,19 @@ func (qb *QueryBuilder) Select(dest interface{}) error {
 		return fmt.Errorf("orm: Select() requires QueryBuilder created with Model(), use ScanMaps() for QueryBuilder created with Table()")
 	}
 	query, args := qb.buildSelectQuery()
-	err := selectMulti(qb.ctx, qb.executor, dest, query, args...)
-	if err != nil {
-		return err
-	}
-	// Preload 処理
-	if len(qb.preloads) > 0 {
-		if err := processPreloads(qb.ctx, qb.executor, dest, qb.preloads); err != nil {
-			return fmt.Errorf("orm: failed during preload: %w", err)
+	return qb.cached("select", dest, query, args, func() error {
+		err := selectMulti(qb.ctx, qb.executor, dest, query, args...)
+		if err != nil {
+			return err
 		}
-	}
-	return nil
+		// Preload 処理
+		if len(qb.preloads) > 0 {
+			if err := processPreloads(qb.ctx, qb.executor, dest, qb.preloads); err != nil {
+				return fmt.Errorf("orm: failed during preload: %w", err)
+			}
+		}
+		return nil
+	})
 }
 
 // SelectOne は構築されたクエリを実行し、最初の結果を dest (構造体へのポインタ) にスキャンします。
@@ -297,24 +372,26 @@ func (qb *QueryBuilder) SelectOne(dest interface{}) error {
 	defer func() { qb.limit = originalLimit }()
 
 	query, args := qb.buildSelectQuery()
-	err := selectOne(qb.ctx, qb.executor, dest, query, args...)
-	if err != nil {
-		// sql.ErrNoRows はエラーとして扱わない場合もあるが、ここでは返す
-		return err
-	}
-	// Preload 処理 (単一レコードに対しても行う)
-	if len(qb.preloads) > 0 {
-		// SelectOne の結果はポインタなので、一時的なスライスに入れる
-		sliceDest := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(dest)), 0, 1)
-		sliceDest = reflect.Append(sliceDest, reflect.ValueOf(dest))
-		sliceDestPtr := reflect.New(sliceDest.Type())
-		sliceDestPtr.Elem().Set(sliceDest)
-
-		if err := processPreloads(qb.ctx, qb.executor, sliceDestPtr.Interface(), qb.preloads); err != nil {
-			return fmt.Errorf("orm: failed during preload for SelectOne: %w", err)
+	return qb.cached("selectOne", dest, query, args, func() error {
+		err := selectOne(qb.ctx, qb.executor, dest, query, args...)
+		if err != nil {
+			// sql.ErrNoRows はエラーとして扱わない場合もあるが、ここでは返す
+			return err
 		}
-	}
-	return nil
+		// Preload 処理 (単一レコードに対しても行う)
+		if len(qb.preloads) > 0 {
+			// SelectOne の結果はポインタなので、一時的なスライスに入れる
+			sliceDest := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(dest)), 0, 1)
+			sliceDest = reflect.Append(sliceDest, reflect.ValueOf(dest))
+			sliceDestPtr := reflect.New(sliceDest.Type())
+			sliceDestPtr.Elem().Set(sliceDest)
+
+			if err := processPreloads(qb.ctx, qb.executor, sliceDestPtr.Interface(), qb.preloads); err != nil {
+				return fmt.Errorf("orm: failed during preload for SelectOne: %w", err)
+			}
+		}
+		return nil
+	})
 }
 
 // ScanMaps は構築されたクエリを実行し、結果を map のスライス (dest: *[]map[string]interface{}) にスキャンします。
@@ -324,7 +401,14 @@ func (qb *QueryBuilder) ScanMaps(dest *[]map[string]interface{}) error {
 		return fmt.Errorf("orm: ScanMaps requires a non-nil destination pointer")
 	}
 	query, args := qb.buildSelectQuery()
-	rows, err := qb.executor.QueryContext(qb.ctx, query, args...)
+	return qb.cached("scanMaps", dest, query, args, func() error {
+		return qb.scanMaps(dest, query, args)
+	})
+}
+
+// scanMaps は ScanMaps のクエリを実行して結果をスキャンします。
+func (qb *QueryBuilder) scanMaps(dest *[]map[string]interface{}, query string, args []interface{}) error {
+	rows, err := queryContext(qb.ctx, qb.executor, query, args...)
 	if err != nil {
 		return fmt.Errorf("orm: failed to execute query for ScanMaps: %w", err)
 	}
@@ -374,17 +458,19 @@ func (qb *QueryBuilder) Count(dest *int64) error {
 		return fmt.Errorf("orm: Count requires a non-nil destination pointer")
 	}
 	query, args := qb.buildCountQuery()
-	row := qb.executor.QueryRowContext(qb.ctx, query, args...)
-	err := row.Scan(dest)
-	if err != nil {
-		// 結果がない場合は count が 0 なので ErrNoRows は無視してよい
-		if errors.Is(err, sql.ErrNoRows) {
-			*dest = 0
-			return nil
+	return qb.cached("count", dest, query, args, func() error {
+		row := queryRowContext(qb.ctx, qb.executor, query, args...)
+		err := row.Scan(dest)
+		if err != nil {
+			// 結果がない場合は count が 0 なので ErrNoRows は無視してよい
+			if errors.Is(err, sql.ErrNoRows) {
+				*dest = 0
+				return nil
+			}
+			return fmt.Errorf("orm: failed to scan count result: %w", err)
 		}
-		return fmt.Errorf("orm: failed to scan count result: %w", err)
-	}
-	return nil
+		return nil
+	})
 }
 
 // Insert は指定されたデータ (構造体のポインタ) をデータベースに挿入します。
@@ -409,6 +495,7 @@ func (qb *QueryBuilder) Insert(data interface{}) (sql.Result, error) {
 	if err != nil {
 		return result, err // insert 内でエラーフォーマット済み
 	}
+	qb.invalidateCache()
 
 	// --- 追加: LastInsertId を取得して ID フィールドに設定 --- START
 	lastID, err := result.LastInsertId()
@@ -447,64 +534,147 @@ func (qb *QueryBuilder) Insert(data interface{}) (sql.Result, error) {
 	return result, nil
 }
 
+// Update は Where で指定した条件に一致するレコードを values (カラム名 -> 値) の内容で更新します。
+// 全件更新を防ぐため、Where が指定されていない場合は ErrMissingWhereClause を返します。
+func (qb *QueryBuilder) Update(values map[string]interface{}) (sql.Result, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("orm: Update() requires at least one column to update")
+	}
+	if len(qb.wheres) == 0 {
+		return nil, ErrMissingWhereClause
+	}
+
+	columns := make([]string, 0, len(values))
+	for col := range values {
+		if err := qb.validateColumn(col); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	sort.Strings(columns) // SQL を安定させるためにカラム順を固定する
+
+	dialect := qb.executor.Dialect()
+	var query strings.Builder
+	args := make([]interface{}, 0, len(values))
+	fmt.Fprintf(&query, "UPDATE %s SET ", quoteIdentifier(dialect, qb.tableName))
+	for i, col := range columns {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		fmt.Fprintf(&query, "%s = ?", dialect.Quote(col))
+		args = append(args, values[col])
+	}
+	whereSQL, whereArgs := qb.buildWhereClause()
+	query.WriteString(whereSQL)
+	args = append(args, whereArgs...)
+
+	result, err := execContext(qb.ctx, qb.executor, Rebind(dialect, query.String()), args...)
+	if err != nil {
+		return nil, fmt.Errorf("orm: failed to execute update: %w", err)
+	}
+	qb.invalidateCache()
+	return result, nil
+}
+
+// Delete は Where で指定した条件に一致するレコードを削除します。
+// モデルに DeletedAt フィールドがある場合は行を削除せずに DeletedAt に現在時刻を設定します (論理削除)。
+// Unscoped() を指定すると論理削除の対象でも物理削除します。
+// 全件削除を防ぐため、Where が指定されていない場合は ErrMissingWhereClause を返します。
+func (qb *QueryBuilder) Delete() (sql.Result, error) {
+	if len(qb.wheres) == 0 {
+		return nil, ErrMissingWhereClause
+	}
+
+	dialect := qb.executor.Dialect()
+	whereSQL, args := qb.buildWhereClause()
+	query := fmt.Sprintf("DELETE FROM %s%s", quoteIdentifier(dialect, qb.tableName), whereSQL)
+	if col := qb.softDeleteColumn(); col != "" {
+		query = fmt.Sprintf("UPDATE %s SET %s = ?%s", quoteIdentifier(dialect, qb.tableName), dialect.Quote(col), whereSQL)
+		args = append([]interface{}{time.Now()}, args...)
+	}
+
+	result, err := execContext(qb.ctx, qb.executor, Rebind(dialect, query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("orm: failed to execute delete: %w", err)
+	}
+	qb.invalidateCache()
+	return result, nil
+}
+
+// validateColumn は Update で指定されたカラム名を検証します。
+// Model() の場合はモデルに存在するカラムのみ、Table() の場合は識別子として安全な名前のみ許可します。
+func (qb *QueryBuilder) validateColumn(col string) error {
+	if !safeIdentifierPattern.MatchString(col) {
+		return fmt.Errorf("orm: invalid column name %q", col)
+	}
+	if qb.modelType == nil {
+		return nil
+	}
+	structInfo, err := getStructInfo(qb.modelType)
+	if err != nil {
+		return fmt.Errorf("orm: failed to get struct info for %s: %w", qb.modelType.Name(), err)
+	}
+	if _, ok := structInfo.columnToField[col]; !ok {
+		return fmt.Errorf("orm: unknown column %q for model %s", col, qb.modelType.Name())
+	}
+	return nil
+}
+
+// buildWhereClause は WHERE 句 (先頭の空白を含む) と引数を構築します。条件がない場合は空文字列を返します。
+// 論理削除のモデルでは、Unscoped() でなければ DeletedAt が NULL のレコードに限定する条件を加えます。
+func (qb *QueryBuilder) buildWhereClause() (string, []interface{}) {
+	conditions := make([]string, 0, len(qb.wheres)+1)
+	args := make([]interface{}, 0)
+	for _, w := range qb.wheres {
+		conditions = append(conditions, "("+w.query+")")
+		args = append(args, w.args...)
+	}
+	if col := qb.softDeleteColumn(); col != "" {
+		conditions = append(conditions, qb.executor.Dialect().Quote(col)+" IS NULL")
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
 // buildSelectQuery は QueryBuilder の状態から SELECT 文と引数を構築します。
+// プレースホルダは Dialect の形式に置き換え済みです。
 func (qb *QueryBuilder) buildSelectQuery() (string, []interface{}) {
+	dialect := qb.executor.Dialect()
 	var query strings.Builder
 	args := make([]interface{}, 0)
 
-	fmt.Fprintf(&query, "SELECT %s FROM %s", qb.fields, qb.tableName)
+	fmt.Fprintf(&query, "SELECT %s FROM %s", qb.fields, quoteIdentifier(dialect, qb.tableName))
 
-	if len(qb.wheres) > 0 {
-		query.WriteString(" WHERE ")
-		for i, w := range qb.wheres {
-			if i > 0 {
-				query.WriteString(" AND ")
-			}
-			query.WriteString("(")
-			query.WriteString(w.query)
-			query.WriteString(")")
-			args = append(args, w.args...)
-		}
-	}
+	whereSQL, whereArgs := qb.buildWhereClause()
+	query.WriteString(whereSQL)
+	args = append(args, whereArgs...)
 
 	if len(qb.orders) > 0 {
 		query.WriteString(" ORDER BY ")
 		query.WriteString(strings.Join(qb.orders, ", "))
 	}
 
-	if qb.limit != nil {
-		fmt.Fprintf(&query, " LIMIT %d", *qb.limit)
-	}
-
-	if qb.offset != nil {
-		fmt.Fprintf(&query, " OFFSET %d", *qb.offset)
-	}
+	query.WriteString(dialect.LimitOffset(qb.limit, qb.offset))
 
-	return query.String(), args
+	return Rebind(dialect, query.String()), args
 }
 
 // buildCountQuery は QueryBuilder の状態から SELECT COUNT(*) 文と引数を構築します。
 func (qb *QueryBuilder) buildCountQuery() (string, []interface{}) {
+	dialect := qb.executor.Dialect()
 	var query strings.Builder
 	args := make([]interface{}, 0)
 
-	fmt.Fprintf(&query, "SELECT COUNT(*) FROM %s", qb.tableName)
+	fmt.Fprintf(&query, "SELECT COUNT(*) FROM %s", quoteIdentifier(dialect, qb.tableName))
 
-	if len(qb.wheres) > 0 {
-		query.WriteString(" WHERE ")
-		for i, w := range qb.wheres {
-			if i > 0 {
-				query.WriteString(" AND ")
-			}
-			query.WriteString("(")
-			query.WriteString(w.query)
-			query.WriteString(")")
-			args = append(args, w.args...)
-		}
-	}
+	whereSQL, whereArgs := qb.buildWhereClause()
+	query.WriteString(whereSQL)
+	args = append(args, whereArgs...)
 	// COUNT では ORDER BY, LIMIT, OFFSET は不要
 
-	return query.String(), args
+	return Rebind(dialect, query.String()), args
 }
 
 // --- executorInternal (インターフェース定義を追加) ---
@@ -513,6 +683,7 @@ type executorInternal interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Dialect() Dialect
 }
 
 // --- 既存の CRUD 操作 (QueryBuilder を内部で使うように変更も検討) ---
@@ -538,10 +709,12 @@ func insert(ctx context.Context, exec executorInternal, data interface{}) (sql.R
 
 	// テーブル名の決定ロジックを共通化
 	tableName := getTableName(elem.Type())
+	dialect := exec.Dialect()
 
 	var columns []string
 	var values []interface{}
 	var placeholders []string
+	_, hasID := structInfo.columnToField["id"]
 
 	for dbCol, fieldName := range structInfo.columnToField {
 		fieldIndex, ok := structInfo.fieldIndex[fieldName]
@@ -559,7 +732,7 @@ func insert(ctx context.Context, exec executorInternal, data interface{}) (sql.R
 			continue
 		}
 
-		columns = append(columns, dbCol)
+		columns = append(columns, dialect.Quote(dbCol))
 		values = append(values, fieldVal.Interface())
 		placeholders = append(placeholders, "?")
 	}
@@ -569,12 +742,24 @@ func insert(ctx context.Context, exec executorInternal, data interface{}) (sql.R
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		tableName,
+		dialect.Quote(tableName),
 		strings.Join(columns, ", "),
 		strings.Join(placeholders, ", "),
 	)
+	query = Rebind(dialect, query)
+
+	if hasID && dialect.SupportsReturning() {
+		// LastInsertId をサポートしないドライバー (PostgreSQL) は RETURNING で採番された ID を受け取る
+		query += " RETURNING " + dialect.Quote("id")
+		var id int64
+		if err := queryRowContext(ctx, exec, query, values...).Scan(&id); err != nil {
+			log.Printf("ERROR: Insert failed for query: %s, args: %v, error: %v", query, values, err)
+			return nil, fmt.Errorf("orm: failed to execute insert: %w", err)
+		}
+		return returningResult{id: id}, nil
+	}
 
-	result, err := exec.ExecContext(ctx, query, values...)
+	result, err := execContext(ctx, exec, query, values...)
 	if err != nil {
 		// エラー内容をもう少し具体的にログ出力する
 		log.Printf("ERROR: Insert failed for query: %s, args: %v, error: %v", query, values, err)
@@ -583,9 +768,17 @@ func insert(ctx context.Context, exec executorInternal, data interface{}) (sql.R
 	return result, nil
 }
 
+// returningResult は INSERT ... RETURNING で取得した ID を sql.Result として返すための型です。
+type returningResult struct {
+	id int64
+}
+
+func (r returningResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r returningResult) RowsAffected() (int64, error) { return 1, nil }
+
 // selectOne は SELECT クエリを実行し、最初の行を構造体にスキャンします。
 func selectOne(ctx context.Context, exec executorInternal, dest interface{}, query string, args ...interface{}) error {
-	rows, err := exec.QueryContext(ctx, query, args...)
+	rows, err := queryContext(ctx, exec, query, args...)
 	if err != nil {
 		return fmt.Errorf("orm: query failed for selectOne: %w", err)
 	}
@@ -614,7 +807,7 @@ func selectOne(ctx context.Context, exec executorInternal, dest interface{}, que
 
 // selectMulti は SELECT クエリを実行し、複数の行を構造体のスライスにスキャンします。
 func selectMulti(ctx context.Context, exec executorInternal, dest interface{}, query string, args ...interface{}) error {
-	rows, err := exec.QueryContext(ctx, query, args...)
+	rows, err := queryContext(ctx, exec, query, args...)
 	if err != nil {
 		return fmt.Errorf("orm: query failed for select: %w", err)
 	}
@@ -656,6 +849,8 @@ type cachedStructInfo struct {
 	// DBカラム名 -> フィールド名
 	columnToField map[string]string
 	relations     map[string]RelationInfo // リレーションフィールド名 -> RelationInfo
+	// 論理削除に使う DeletedAt フィールドのカラム名 (なければ空文字列)
+	softDeleteColumn string
 }
 
 // getStructInfo は構造体の型情報をキャッシュするための構造体です。
@@ -686,206 +881,31 @@ func getStructInfo(structType reflect.Type) (*cachedStructInfo, error) {
 	for i := 0; i < numFields; i++ {
 		field := structType.Field(i)
 
-		if !field.IsExported() {
-			continue
-		}
-
-		dbTag := field.Tag.Get("db")
-
-		if dbTag == "-" {
+		columnName, ok := fieldColumnName(field)
+		if !ok {
 			continue
 		}
 
-		columnName := strcase.ToSnake(field.Name)
-		if dbTag != "" {
-			columnName = dbTag
-		}
-
 		info.fieldIndex[field.Name] = i
 		info.columnToField[columnName] = field.Name
+		if isSoftDeleteField(field) {
+			info.softDeleteColumn = columnName
+		}
 	}
 	// --- 1. DB カラムとフィールドのマッピングを作成 --- END
 
 	// --- 2. リレーション情報を解析・構築 --- START
 	for i := 0; i < numFields; i++ {
 		field := structType.Field(i)
-
-		if !field.IsExported() {
+		if !field.IsExported() || !isRelationTag(field.Tag.Get("orm")) {
 			continue
 		}
-
-		ormTag := field.Tag.Get("orm")
-
-		if ormTag == "-" || ormTag == "" {
+		relation, err := parseRelation(structType, field)
+		if err != nil {
+			log.Printf("WARN: %v, skipping relation.", err)
 			continue
 		}
-
-		parts := strings.Split(ormTag, ",")
-		relationTypeRaw := strings.TrimSpace(parts[0])
-		relationTypeParts := strings.SplitN(relationTypeRaw, ":", 2)
-		relationType := relationTypeParts[0]
-
-		if relationType == "hasmany" {
-			if field.Type.Kind() != reflect.Slice {
-				log.Printf("WARN: hasmany relation field '%s' in %s must be a slice, skipping relation.", field.Name, structType.Name())
-				continue
-			}
-			relatedType := field.Type.Elem()
-			if relatedType.Kind() == reflect.Ptr {
-				relatedType = relatedType.Elem()
-			}
-			if relatedType.Kind() != reflect.Struct {
-				log.Printf("WARN: hasmany relation field '%s' in %s must be a slice of structs or pointers to structs, skipping relation.", field.Name, structType.Name())
-				continue
-			}
-
-			var foreignKey string
-			var assocForeignKey string
-			params := make(map[string]string)
-
-			if len(parts) > 1 {
-				firstParam := strings.TrimSpace(parts[1])
-				startIndex := 2
-				if !strings.Contains(firstParam, ":") {
-					foreignKey = firstParam
-				} else {
-					// 最初のパラメータが key:value 形式だった場合、解析対象に含めるため startIndex を 1 に戻す
-					startIndex = 1
-				}
-				// params マップ解析
-				for _, part := range parts[startIndex:] {
-					kv := strings.SplitN(part, ":", 2)
-					if len(kv) == 2 {
-						params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
-					}
-				}
-			}
-
-			if foreignKey == "" {
-				foreignKey = strcase.ToSnake(structType.Name()) + "_id"
-				log.Printf("DEBUG: Using default ForeignKey '%s' for relation '%s' in %s", foreignKey, field.Name, structType.Name())
-			}
-
-			assocForeignKey = params["association_foreignkey"]
-
-			if assocForeignKey == "" {
-				assocKeyFieldName := "ID"
-				idField, idFound := structType.FieldByName(assocKeyFieldName)
-				if idFound {
-					idDbTag := idField.Tag.Get("db")
-					if idDbTag != "" && idDbTag != "-" {
-						assocForeignKey = idDbTag
-					} else {
-						assocForeignKey = strcase.ToSnake(assocKeyFieldName)
-					}
-				} else {
-					assocForeignKey = "id"
-				}
-				log.Printf("DEBUG: Using default AssociationForeignKey '%s' for relation '%s' in %s", assocForeignKey, field.Name, structType.Name())
-			} else {
-				assocKeyField, found := structType.FieldByNameFunc(func(name string) bool { return name == assocForeignKey })
-				if found {
-					dbTag := assocKeyField.Tag.Get("db")
-					if dbTag != "" && dbTag != "-" {
-						assocForeignKey = dbTag
-					} else {
-						assocForeignKey = strcase.ToSnake(assocKeyField.Name)
-					}
-				}
-			}
-
-			info.relations[field.Name] = RelationInfo{
-				FieldName:             field.Name,
-				RelatedType:           relatedType,
-				ForeignKey:            foreignKey,
-				AssociationForeignKey: assocForeignKey,
-			}
-		}
-
-		if relationType == "belongsTo" {
-			if !(field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) && field.Type.Kind() != reflect.Struct {
-				log.Printf("WARN: belongsTo relation field '%s' in %s must be a struct or a pointer to a struct, skipping relation.", field.Name, structType.Name())
-				continue
-			}
-			relatedType := field.Type
-			if relatedType.Kind() == reflect.Ptr {
-				relatedType = relatedType.Elem()
-			}
-
-			var foreignKey string
-			var assocForeignKey string
-			params := make(map[string]string)
-
-			if len(parts) > 1 {
-				firstParam := strings.TrimSpace(parts[1])
-				startIndex := 2
-				if !strings.Contains(firstParam, ":") {
-					foreignKey = firstParam
-				} else {
-					startIndex = 1
-				}
-				for _, part := range parts[startIndex:] {
-					kv := strings.SplitN(part, ":", 2)
-					if len(kv) == 2 {
-						params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
-					}
-				}
-			}
-
-			if foreignKey == "" {
-				// デフォルト: 関連モデル名 + ID (例: User -> UserID)
-				foreignKey = relatedType.Name() + "ID"
-				// 現在の構造体にその名前のフィールドがあるか確認し、なければスネークケースも試す
-				_, fkFound := structType.FieldByName(foreignKey)
-				if !fkFound {
-					fkSnake := strcase.ToSnake(foreignKey)
-					_, fkSnakeFound := structType.FieldByNameFunc(func(name string) bool { return strcase.ToSnake(name) == fkSnake })
-					if fkSnakeFound {
-						// UserID or user_id フィールドが実際に存在するか確認する方がより正確だが、一旦命名規則ベースで進める
-						// foreignKey = ... // 必要に応じて実際のフィールド名に合わせる
-					}
-				}
-				log.Printf("DEBUG: Using default ForeignKey '%s' for relation '%s' in %s", foreignKey, field.Name, structType.Name())
-			}
-
-			assocForeignKey = params["association_foreignkey"]
-
-			if assocForeignKey == "" {
-				// デフォルト: 関連モデルのプライマリキーカラム名 (通常は "id")
-				assocKeyFieldName := "ID"
-				// 関連モデル(relatedType)からフィールドを探す
-				idField, idFound := relatedType.FieldByName(assocKeyFieldName)
-				if idFound {
-					idDbTag := idField.Tag.Get("db")
-					if idDbTag != "" && idDbTag != "-" {
-						assocForeignKey = idDbTag
-					} else {
-						assocForeignKey = strcase.ToSnake(assocKeyFieldName)
-					}
-				} else {
-					assocForeignKey = "id"
-				}
-				log.Printf("DEBUG: Using default AssociationForeignKey '%s' for relation '%s' in %s", assocForeignKey, field.Name, structType.Name())
-			} else {
-				// タグで association_foreignkey がフィールド名で指定された場合、関連モデルからカラム名に変換する試み
-				assocKeyField, found := relatedType.FieldByNameFunc(func(name string) bool { return name == assocForeignKey })
-				if found {
-					dbTag := assocKeyField.Tag.Get("db")
-					if dbTag != "" && dbTag != "-" {
-						assocForeignKey = dbTag
-					} else {
-						assocForeignKey = strcase.ToSnake(assocKeyField.Name)
-					}
-				}
-			}
-
-			info.relations[field.Name] = RelationInfo{
-				FieldName:             field.Name,
-				RelatedType:           relatedType,
-				ForeignKey:            foreignKey,      // 例: posts テーブルの user_id カラム (FK がある方)
-				AssociationForeignKey: assocForeignKey, // 例: users テーブルの id カラム (参照される方)
-			}
-		}
+		info.relations[field.Name] = relation
 	}
 	// --- 2. リレーション情報を解析・構築 --- END
 
@@ -893,6 +913,40 @@ func getStructInfo(structType reflect.Type) (*cachedStructInfo, error) {
 	return &info, nil
 }
 
+// isSoftDeleteField は論理削除に使う DeletedAt フィールド (sql.NullTime または *time.Time) かどうかを返します。
+func isSoftDeleteField(field reflect.StructField) bool {
+	if field.Name != "DeletedAt" {
+		return false
+	}
+	return field.Type == reflect.TypeOf(sql.NullTime{}) || field.Type == reflect.TypeOf((*time.Time)(nil))
+}
+
+// fieldColumnName はフィールドに対応する DB カラム名を返します。
+// 非公開フィールド、db:"-" のフィールド、リレーションフィールドはカラムではないので false を返します。
+func fieldColumnName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	dbTag := field.Tag.Get("db")
+	if dbTag == "-" {
+		return "", false
+	}
+	// リレーションフィールドはカラムではないのでマッピングしない
+	if isRelationTag(field.Tag.Get("orm")) {
+		return "", false
+	}
+	if dbTag != "" {
+		return dbTag, true
+	}
+	return strcase.ToSnake(field.Name), true
+}
+
+// isRelationTag は orm タグがリレーション (hasmany / belongsTo / many2many) を表すかどうかを判定します。
+func isRelationTag(ormTag string) bool {
+	relationType := RelationKind(strings.SplitN(strings.TrimSpace(strings.SplitN(ormTag, ",", 2)[0]), ":", 2)[0])
+	return relationType == HasMany || relationType == BelongsTo || relationType == Many2Many
+}
+
 // scanRow は sql.Rows から単一のレコードを dest (構造体へのポインタ) にスキャンします。
 func scanRow(rows *sql.Rows, dest interface{}) error {
 	val := reflect.ValueOf(dest)
@@ -1020,160 +1074,3 @@ func isValidOrderByClause(clause string) bool {
 	// TrimSpace は不要になった（正規表現が前後の空白も許容するため）が、念のため残しても良い
 	return stricterSafeOrderByPattern.MatchString(clause)
 }
-
-// --- Preload 処理ヘルパー (関数定義を追加) ---
-
-// processPreloads は取得済みのデータ (dest: 構造体のスライスへのポインタ) に対して、
-// 指定されたリレーション (preloads map) のデータを取得し、関連付けます。
-func processPreloads(ctx context.Context, exec executorInternal, dest interface{}, preloads map[string]bool) error {
-	destVal := reflect.ValueOf(dest)
-	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
-		return fmt.Errorf("orm: processPreloads expects a non-nil pointer to slice destination, got %T", dest)
-	}
-	sliceVal := destVal.Elem()
-	if sliceVal.Kind() != reflect.Slice {
-		return fmt.Errorf("orm: processPreloads destination must be a pointer to slice, got pointer to %s", sliceVal.Kind())
-	}
-	if sliceVal.Len() == 0 {
-		return nil // データがなければ何もしない
-	}
-
-	// スライスの要素の型を取得
-	elemType := sliceVal.Type().Elem()
-	isPtrElem := false
-	if elemType.Kind() == reflect.Ptr {
-		elemType = elemType.Elem()
-		isPtrElem = true
-	}
-	if elemType.Kind() != reflect.Struct {
-		return fmt.Errorf("orm: processPreloads slice element must be a struct or pointer to struct, got %s", sliceVal.Type().Elem().Kind())
-	}
-
-	structInfo, err := getStructInfo(elemType)
-	if err != nil {
-		return fmt.Errorf("orm: failed to get struct info for preload base type %s: %w", elemType.Name(), err)
-	}
-
-	for fieldName := range preloads {
-		relation, ok := structInfo.relations[fieldName]
-		if !ok {
-			return fmt.Errorf("orm: preload field '%s' not found or not a valid relation in struct %s", fieldName, elemType.Name())
-		}
-
-		// 1. 関連付けに必要なキー (親 ID) を収集
-		assocKeys := make([]interface{}, 0, sliceVal.Len())
-		keyToParentMap := make(map[interface{}][]reflect.Value) // 親ID -> 親要素のリスト
-
-		for i := 0; i < sliceVal.Len(); i++ {
-			parentElem := sliceVal.Index(i) // 親要素 (ポインタまたは値)
-			if isPtrElem {
-				parentElem = parentElem.Elem() // ポインタの場合は中身を取得
-			}
-			// 親の AssociationForeignKey (通常は ID) の値を取得
-			assocKeyField := parentElem.FieldByNameFunc(func(name string) bool {
-				return name == relation.AssociationForeignKey || strcase.ToSnake(name) == relation.AssociationForeignKey
-			})
-
-			if !assocKeyField.IsValid() {
-				found := false
-				for j := 0; j < parentElem.NumField(); j++ {
-					f := parentElem.Type().Field(j)
-					colName := f.Tag.Get("db")
-					if colName == "" {
-						colName = strcase.ToSnake(f.Name)
-					}
-					if colName == relation.AssociationForeignKey {
-						assocKeyField = parentElem.Field(j)
-						found = true
-						break
-					}
-				}
-				if !found {
-					return fmt.Errorf("orm: association key field '%s' not found in parent struct %s for relation %s", relation.AssociationForeignKey, elemType.Name(), fieldName)
-				}
-			}
-			assocKeyValue := assocKeyField.Interface()
-			assocKeys = append(assocKeys, assocKeyValue)
-			parentValueToMap := sliceVal.Index(i)
-			keyToParentMap[assocKeyValue] = append(keyToParentMap[assocKeyValue], parentValueToMap)
-		}
-
-		if len(assocKeys) == 0 {
-			continue
-		}
-
-		// 2. 関連データを一括取得
-		relatedTableName := getTableName(relation.RelatedType)
-		inPlaceholders := strings.Repeat("?,", len(assocKeys)-1) + "?"
-		query := fmt.Sprintf("SELECT * FROM %s WHERE %s IN (%s)", relatedTableName, relation.ForeignKey, inPlaceholders)
-
-		relatedSliceType := reflect.SliceOf(relation.RelatedType)
-		relatedResultsSlice := reflect.MakeSlice(relatedSliceType, 0, 0)
-		relatedResultsPtr := reflect.New(relatedResultsSlice.Type())
-		relatedResultsPtr.Elem().Set(relatedResultsSlice)
-
-		err := selectMulti(ctx, exec, relatedResultsPtr.Interface(), query, assocKeys...)
-		if err != nil {
-			return fmt.Errorf("orm: failed to fetch related data for %s: %w", fieldName, err)
-		}
-
-		relatedData := relatedResultsPtr.Elem()
-
-		// 3. 関連データを親要素にセット
-		for i := 0; i < relatedData.Len(); i++ {
-			relatedElem := relatedData.Index(i)
-			foreignKeyField := relatedElem.FieldByNameFunc(func(name string) bool {
-				return name == relation.ForeignKey || strcase.ToSnake(name) == relation.ForeignKey
-			})
-
-			if !foreignKeyField.IsValid() {
-				found := false
-				for j := 0; j < relatedElem.NumField(); j++ {
-					f := relatedElem.Type().Field(j)
-					colName := f.Tag.Get("db")
-					if colName == "" {
-						colName = strcase.ToSnake(f.Name)
-					}
-					if colName == relation.ForeignKey {
-						foreignKeyField = relatedElem.Field(j)
-						found = true
-						break
-					}
-				}
-				if !found {
-					return fmt.Errorf("orm: foreign key field '%s' not found in related struct %s for relation %s", relation.ForeignKey, relation.RelatedType.Name(), fieldName)
-				}
-			}
-			foreignKeyValue := foreignKeyField.Interface()
-
-			if parents, found := keyToParentMap[foreignKeyValue]; found {
-				for _, parentVal := range parents {
-					parentField := parentVal
-					if parentVal.Kind() == reflect.Ptr {
-						parentField = parentVal.Elem()
-					}
-					relationSliceField := parentField.FieldByName(relation.FieldName)
-					if !relationSliceField.IsValid() || !relationSliceField.CanSet() {
-						log.Printf("WARN: Cannot find or set relation field %s in parent %s", relation.FieldName, parentField.Type().Name())
-						continue
-					}
-					if relationSliceField.Kind() != reflect.Slice {
-						log.Printf("WARN: Relation field %s in parent %s is not a slice", relation.FieldName, parentField.Type().Name())
-						continue
-					}
-
-					sliceElemKind := relationSliceField.Type().Elem().Kind()
-					if sliceElemKind == reflect.Ptr {
-						relatedElemPtr := reflect.New(relatedElem.Type())
-						relatedElemPtr.Elem().Set(relatedElem)
-						relationSliceField.Set(reflect.Append(relationSliceField, relatedElemPtr))
-					} else {
-						relationSliceField.Set(reflect.Append(relationSliceField, relatedElem))
-					}
-				}
-			}
-		}
-	}
-
-	return nil
-}
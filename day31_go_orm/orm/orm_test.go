@@ -20,7 +20,7 @@ const testDBFile = "./test_orm.db"
 
 func setupTestDB(t *testing.T) (*orm.DB, func()) {
 	_ = os.Remove(testDBFile)
-	db, err := orm.Open(testDBFile)
+	db, err := orm.Open("sqlite3", testDBFile)
 	if err != nil {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
@@ -448,6 +448,156 @@ func TestQueryBuilder(t *testing.T) {
 	})
 }
 
+func TestQueryBuilderUpdateAndDelete(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+	u1 := orm.User{Name: "QB Update 1", Email: sql.NullString{String: "qbu1@e.com", Valid: true}}
+	u2 := orm.User{Name: "QB Update 2", Email: sql.NullString{String: "qbu2@e.com", Valid: true}}
+	if _, err := db.Model(&orm.User{}).Insert(&u1); err != nil {
+		t.Fatalf("Insert u1 failed: %v", err)
+	}
+	if _, err := db.Model(&orm.User{}).Insert(&u2); err != nil {
+		t.Fatalf("Insert u2 failed: %v", err)
+	}
+
+	t.Run("Update with Where", func(t *testing.T) {
+		result, err := db.Model(&orm.User{}).
+			Where("id = ?", u1.ID).
+			Update(map[string]interface{}{"name": "QB Updated", "email": sql.NullString{}})
+		if err != nil {
+			t.Fatalf("QB Update failed: %v", err)
+		}
+		if n, _ := result.RowsAffected(); n != 1 {
+			t.Errorf("Expected 1 row affected by update, got %d", n)
+		}
+
+		var user orm.User
+		if err := db.Model(&orm.User{}).Where("id = ?", u1.ID).SelectOne(&user); err != nil {
+			t.Fatalf("SelectOne after update failed: %v", err)
+		}
+		if user.Name != "QB Updated" || user.Email.Valid {
+			t.Errorf("User not updated: %+v", user)
+		}
+	})
+
+	t.Run("Update rejects unknown column", func(t *testing.T) {
+		_, err := db.Model(&orm.User{}).Where("id = ?", u1.ID).Update(map[string]interface{}{"posts": "x"})
+		if err == nil {
+			t.Fatal("Expected error for unknown column, got nil")
+		}
+	})
+
+	t.Run("Update and Delete require Where", func(t *testing.T) {
+		_, err := db.Model(&orm.User{}).Update(map[string]interface{}{"name": "all"})
+		if !errors.Is(err, orm.ErrMissingWhereClause) {
+			t.Errorf("Expected ErrMissingWhereClause from Update, got %v", err)
+		}
+		_, err = db.Model(&orm.User{}).Delete()
+		if !errors.Is(err, orm.ErrMissingWhereClause) {
+			t.Errorf("Expected ErrMissingWhereClause from Delete, got %v", err)
+		}
+	})
+
+	t.Run("Delete with Where", func(t *testing.T) {
+		result, err := db.Model(&orm.User{}).Where("id = ?", u2.ID).Delete()
+		if err != nil {
+			t.Fatalf("QB Delete failed: %v", err)
+		}
+		if n, _ := result.RowsAffected(); n != 1 {
+			t.Errorf("Expected 1 row affected by delete, got %d", n)
+		}
+
+		var count int64
+		if err := db.Model(&orm.User{}).Count(&count); err != nil {
+			t.Fatalf("Count after delete failed: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 user after delete, got %d", count)
+		}
+	})
+}
+
+func TestAutoMigrate(t *testing.T) {
+	_ = os.Remove(testDBFile)
+	db, err := orm.Open("sqlite3", testDBFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+	orm.ClearStructInfoCache()
+
+	// users は一部のカラムだけ手動で作成し、posts は存在しない状態から始める
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL)`); err != nil {
+		t.Fatalf("Failed to create users table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (name) VALUES ('Existing')`); err != nil {
+		t.Fatalf("Failed to insert existing user: %v", err)
+	}
+
+	if err := db.AutoMigrate(&orm.User{}, &orm.Post{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	// 2 回目はスキーマが同期済みなので何もしない
+	if err := db.AutoMigrate(&orm.User{}, &orm.Post{}); err != nil {
+		t.Fatalf("Second AutoMigrate failed: %v", err)
+	}
+
+	t.Run("Missing columns are added", func(t *testing.T) {
+		var cols []map[string]interface{}
+		if err := db.Table("pragma_table_info('users')").ScanMaps(&cols); err != nil {
+			t.Fatalf("Failed to get users columns: %v", err)
+		}
+		got := make(map[string]bool)
+		for _, c := range cols {
+			got[fmt.Sprint(c["name"])] = true
+		}
+		for _, want := range []string{"id", "name", "email", "created_at", "updated_at"} {
+			if !got[want] {
+				t.Errorf("Column %s not found in users after AutoMigrate", want)
+			}
+		}
+		if got["posts"] {
+			t.Errorf("Relation field Posts should not be migrated as a column")
+		}
+
+		var existing orm.User
+		if err := db.Model(&orm.User{}).Where("name = ?", "Existing").SelectOne(&existing); err != nil {
+			t.Fatalf("Failed to select existing user after AutoMigrate: %v", err)
+		}
+		if existing.Email.Valid {
+			t.Errorf("Expected NULL email for existing user, got %v", existing.Email)
+		}
+	})
+
+	t.Run("Created table is usable", func(t *testing.T) {
+		user := orm.User{Name: "Migrated", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if _, err := db.Model(&orm.User{}).Insert(&user); err != nil {
+			t.Fatalf("Insert user failed: %v", err)
+		}
+		post := orm.Post{UserID: user.ID, Title: "Migrated Post", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if _, err := db.Model(&orm.Post{}).Insert(&post); err != nil {
+			t.Fatalf("Insert post failed: %v", err)
+		}
+		if post.ID == 0 {
+			t.Errorf("Expected auto increment ID for post, got 0")
+		}
+
+		var posts []orm.Post
+		if err := db.Model(&orm.Post{}).Where("user_id = ?", user.ID).Select(&posts); err != nil {
+			t.Fatalf("Select posts failed: %v", err)
+		}
+		if len(posts) != 1 || posts[0].Title != post.Title {
+			t.Errorf("Unexpected posts: %+v", posts)
+		}
+	})
+
+	t.Run("Non-struct model is rejected", func(t *testing.T) {
+		if err := db.AutoMigrate(orm.User{}); err == nil {
+			t.Errorf("Expected error for non-pointer model, got nil")
+		}
+	})
+}
+
 func TestPreload(t *testing.T) {
 	db, teardown := setupTestDB(t)
 	defer teardown()
@@ -532,6 +682,164 @@ func TestPreload(t *testing.T) {
 		}
 		fmt.Printf("User with Preload (SelectOne): %+v\n", user)
 	})
+
+	t.Run("Select posts with User preloaded (belongsTo)", func(t *testing.T) {
+		var posts []orm.Post
+		err := db.Model(&orm.Post{}).Preload("User").Order("id").Select(&posts)
+		if err != nil {
+			t.Fatalf("Preload belongsTo failed: %v", err)
+		}
+		if len(posts) != 3 {
+			t.Fatalf("Expected 3 posts, got %d", len(posts))
+		}
+		wantNames := []string{u1.Name, u1.Name, u2.Name}
+		for i, post := range posts {
+			if post.User == nil {
+				t.Errorf("Post %d: User not preloaded", post.ID)
+				continue
+			}
+			if post.User.Name != wantNames[i] {
+				t.Errorf("Post %d: expected user %s, got %s", post.ID, wantNames[i], post.User.Name)
+			}
+		}
+	})
+}
+
+// --- Models for nested / many2many preload tests ---
+type Author struct {
+	ID        int64      `db:"id"`
+	Name      string     `db:"name"`
+	BlogPosts []BlogPost `orm:"hasmany:author_id"`
+}
+
+type BlogPost struct {
+	ID       int64     `db:"id"`
+	AuthorID int64     `db:"author_id"`
+	Title    string    `db:"title"`
+	Author   *Author   `orm:"belongsTo:AuthorID"`
+	Comments []Comment `orm:"hasmany:blog_post_id"`
+	Tags     []*Tag    `orm:"many2many:blog_post_tags"` // 中間テーブル blog_post_tags (blog_post_id, tag_id)
+}
+
+type Comment struct {
+	ID         int64  `db:"id"`
+	BlogPostID int64  `db:"blog_post_id"`
+	Body       string `db:"body"`
+}
+
+type Tag struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+type BlogPostTag struct {
+	BlogPostID int64 `db:"blog_post_id"`
+	TagID      int64 `db:"tag_id"`
+}
+
+func TestNestedPreload(t *testing.T) {
+	_ = os.Remove(testDBFile)
+	db, err := orm.Open("sqlite3", testDBFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+	orm.ClearStructInfoCache()
+
+	if err := db.AutoMigrate(&Author{}, &BlogPost{}, &Comment{}, &Tag{}, &BlogPostTag{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+
+	insert := func(model, data interface{}) {
+		t.Helper()
+		if _, err := db.Model(model).Insert(data); err != nil {
+			t.Fatalf("Insert %T failed: %v", data, err)
+		}
+	}
+	alice := Author{Name: "Alice"}
+	bob := Author{Name: "Bob"}
+	insert(&Author{}, &alice)
+	insert(&Author{}, &bob)
+	post1 := BlogPost{AuthorID: alice.ID, Title: "Go"}
+	post2 := BlogPost{AuthorID: alice.ID, Title: "SQLite"}
+	post3 := BlogPost{AuthorID: bob.ID, Title: "ORM"}
+	insert(&BlogPost{}, &post1)
+	insert(&BlogPost{}, &post2)
+	insert(&BlogPost{}, &post3)
+	insert(&Comment{}, &Comment{BlogPostID: post1.ID, Body: "nice"})
+	insert(&Comment{}, &Comment{BlogPostID: post1.ID, Body: "great"})
+	insert(&Comment{}, &Comment{BlogPostID: post3.ID, Body: "hmm"})
+	golang := Tag{Name: "golang"}
+	database := Tag{Name: "database"}
+	insert(&Tag{}, &golang)
+	insert(&Tag{}, &database)
+	insert(&BlogPostTag{}, &BlogPostTag{BlogPostID: post1.ID, TagID: golang.ID})
+	insert(&BlogPostTag{}, &BlogPostTag{BlogPostID: post2.ID, TagID: database.ID})
+	insert(&BlogPostTag{}, &BlogPostTag{BlogPostID: post3.ID, TagID: golang.ID})
+	insert(&BlogPostTag{}, &BlogPostTag{BlogPostID: post3.ID, TagID: database.ID})
+
+	t.Run("many2many", func(t *testing.T) {
+		var posts []BlogPost
+		if err := db.Model(&BlogPost{}).Preload("Tags").Order("id").Select(&posts); err != nil {
+			t.Fatalf("Preload many2many failed: %v", err)
+		}
+		wantTags := [][]string{{"golang"}, {"database"}, {"golang", "database"}}
+		for i, post := range posts {
+			var got []string
+			for _, tag := range post.Tags {
+				got = append(got, tag.Name)
+			}
+			if !reflect.DeepEqual(got, wantTags[i]) {
+				t.Errorf("Post %s: expected tags %v, got %v", post.Title, wantTags[i], got)
+			}
+		}
+	})
+
+	t.Run("Nested hasmany and many2many", func(t *testing.T) {
+		var authors []Author
+		err := db.Model(&Author{}).
+			Preload("BlogPosts.Comments").
+			Preload("BlogPosts.Tags").
+			Order("id").
+			Select(&authors)
+		if err != nil {
+			t.Fatalf("Nested preload failed: %v", err)
+		}
+		if len(authors) != 2 || len(authors[0].BlogPosts) != 2 || len(authors[1].BlogPosts) != 1 {
+			t.Fatalf("Unexpected authors/posts: %+v", authors)
+		}
+		if got := len(authors[0].BlogPosts[0].Comments); got != 2 {
+			t.Errorf("Expected 2 comments on %s, got %d", authors[0].BlogPosts[0].Title, got)
+		}
+		if got := len(authors[0].BlogPosts[1].Comments); got != 0 {
+			t.Errorf("Expected 0 comments on %s, got %d", authors[0].BlogPosts[1].Title, got)
+		}
+		if got := len(authors[1].BlogPosts[0].Tags); got != 2 {
+			t.Errorf("Expected 2 tags on %s, got %d", authors[1].BlogPosts[0].Title, got)
+		}
+	})
+
+	t.Run("Nested belongsTo from SelectOne", func(t *testing.T) {
+		var post BlogPost
+		err := db.Model(&BlogPost{}).Where("id = ?", post3.ID).Preload("Author.BlogPosts").SelectOne(&post)
+		if err != nil {
+			t.Fatalf("SelectOne with nested preload failed: %v", err)
+		}
+		if post.Author == nil || post.Author.Name != "Bob" {
+			t.Fatalf("Author not preloaded: %+v", post.Author)
+		}
+		if len(post.Author.BlogPosts) != 1 || post.Author.BlogPosts[0].Title != "ORM" {
+			t.Errorf("Author.BlogPosts not preloaded: %+v", post.Author.BlogPosts)
+		}
+	})
+
+	t.Run("Unknown nested relation", func(t *testing.T) {
+		var authors []Author
+		err := db.Model(&Author{}).Preload("BlogPosts.Unknown").Select(&authors)
+		if err == nil {
+			t.Errorf("Expected error for unknown nested relation, got nil")
+		}
+	})
 }
 
 func TestTableQueryBuilder(t *testing.T) {
@@ -609,3 +917,423 @@ func TestTableQueryBuilder(t *testing.T) {
 		}
 	})
 }
+
+func TestDialects(t *testing.T) {
+	limit, offset := 10, 20
+
+	t.Run("Placeholders and quoting", func(t *testing.T) {
+		query := "SELECT * FROM users WHERE name = ? AND note = 'what?' AND id > ?"
+		tests := []struct {
+			dialect     orm.Dialect
+			rebound     string
+			quoted      string
+			limitOffset string
+			offsetOnly  string
+		}{
+			{orm.SQLite, query, `"users"`, " LIMIT 10 OFFSET 20", " LIMIT -1 OFFSET 20"},
+			{orm.Postgres, "SELECT * FROM users WHERE name = $1 AND note = 'what?' AND id > $2", `"users"`, " LIMIT 10 OFFSET 20", " OFFSET 20"},
+			{orm.MySQL, query, "`users`", " LIMIT 10 OFFSET 20", " LIMIT 18446744073709551615 OFFSET 20"},
+		}
+		for _, tt := range tests {
+			d := tt.dialect
+			if got := orm.Rebind(d, query); got != tt.rebound {
+				t.Errorf("%s: Rebind() = %q, want %q", d.Name(), got, tt.rebound)
+			}
+			if got := d.Quote("users"); got != tt.quoted {
+				t.Errorf("%s: Quote() = %q, want %q", d.Name(), got, tt.quoted)
+			}
+			if got := d.LimitOffset(&limit, &offset); got != tt.limitOffset {
+				t.Errorf("%s: LimitOffset(10, 20) = %q, want %q", d.Name(), got, tt.limitOffset)
+			}
+			if got := d.LimitOffset(nil, &offset); got != tt.offsetOnly {
+				t.Errorf("%s: LimitOffset(nil, 20) = %q, want %q", d.Name(), got, tt.offsetOnly)
+			}
+			if got := d.LimitOffset(nil, nil); got != "" {
+				t.Errorf("%s: LimitOffset(nil, nil) = %q, want empty", d.Name(), got)
+			}
+		}
+	})
+
+	t.Run("Open with unknown driver", func(t *testing.T) {
+		_, err := orm.Open("unknown-driver", "dsn")
+		if err == nil {
+			t.Fatal("Expected error for driver without dialect, got nil")
+		}
+	})
+
+	t.Run("RegisterDialect", func(t *testing.T) {
+		// 登録した Dialect が Open で使われること
+		orm.RegisterDialect("sqlite3", orm.SQLite)
+		db, err := orm.Open("sqlite3", testDBFile)
+		if err != nil {
+			t.Fatalf("Open after RegisterDialect failed: %v", err)
+		}
+		defer db.Close()
+		if db.Dialect().Name() != "sqlite3" {
+			t.Errorf("Expected sqlite3 dialect, got %s", db.Dialect().Name())
+		}
+	})
+
+	t.Run("Offset without Limit on SQLite", func(t *testing.T) {
+		db, teardown := setupTestDB(t)
+		defer teardown()
+		for i := 1; i <= 3; i++ {
+			u := orm.User{Name: fmt.Sprintf("Offset User %d", i)}
+			if _, err := db.Model(&orm.User{}).Insert(&u); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+		}
+
+		var users []orm.User
+		if err := db.Model(&orm.User{}).Order("id ASC").Offset(1).Select(&users); err != nil {
+			t.Fatalf("Select with Offset failed: %v", err)
+		}
+		if len(users) != 2 || users[0].Name != "Offset User 2" {
+			t.Errorf("Expected users 2 and 3, got %+v", users)
+		}
+	})
+}
+
+func TestQueryCache(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+	db.EnableQueryCache(0)
+
+	u1 := orm.User{Name: "Cache User 1"}
+	if _, err := db.Model(&orm.User{}).Insert(&u1); err != nil {
+		t.Fatalf("Insert u1 failed: %v", err)
+	}
+
+	countUsers := func(t *testing.T) int64 {
+		t.Helper()
+		var count int64
+		if err := db.Model(&orm.User{}).Cache().Count(&count); err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		return count
+	}
+
+	t.Run("Cached result is returned until a write through the ORM", func(t *testing.T) {
+		var users []orm.User
+		if err := db.Model(&orm.User{}).Cache().Order("id ASC").Select(&users); err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		if countUsers(t) != 1 {
+			t.Fatalf("Expected count 1")
+		}
+
+		// QueryBuilder を経由しない書き込みはキャッシュを無効化しない
+		if _, err := db.Exec("INSERT INTO users (name) VALUES (?)", "Raw User"); err != nil {
+			t.Fatalf("Raw insert failed: %v", err)
+		}
+		var cached []orm.User
+		if err := db.Model(&orm.User{}).Cache().Order("id ASC").Select(&cached); err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		if len(cached) != 1 || countUsers(t) != 1 {
+			t.Errorf("Expected cached results (1 user), got %d users", len(cached))
+		}
+		var uncached []orm.User
+		if err := db.Model(&orm.User{}).Order("id ASC").Select(&uncached); err != nil {
+			t.Fatalf("Select without Cache failed: %v", err)
+		}
+		if len(uncached) != 2 {
+			t.Errorf("Expected 2 users without Cache(), got %d", len(uncached))
+		}
+
+		// キャッシュから返した結果を書き換えてもキャッシュには影響しない
+		cached[0].Name = "Modified"
+		var again []orm.User
+		if err := db.Model(&orm.User{}).Cache().Order("id ASC").Select(&again); err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		if again[0].Name != "Cache User 1" {
+			t.Errorf("Expected cached name 'Cache User 1', got %q", again[0].Name)
+		}
+
+		u3 := orm.User{Name: "Cache User 3"}
+		if _, err := db.Model(&orm.User{}).Insert(&u3); err != nil {
+			t.Fatalf("Insert u3 failed: %v", err)
+		}
+		var fresh []orm.User
+		if err := db.Model(&orm.User{}).Cache().Order("id ASC").Select(&fresh); err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		if len(fresh) != 3 || countUsers(t) != 3 {
+			t.Errorf("Expected 3 users after Insert invalidated the cache, got %d", len(fresh))
+		}
+	})
+
+	t.Run("Writes to other tables keep the cache", func(t *testing.T) {
+		before := countUsers(t)
+		if _, err := db.Exec("INSERT INTO users (name) VALUES (?)", "Raw User 2"); err != nil {
+			t.Fatalf("Raw insert failed: %v", err)
+		}
+		p := orm.Post{UserID: u1.ID, Title: "Cache Post", Content: "c"}
+		if _, err := db.Model(&orm.Post{}).Insert(&p); err != nil {
+			t.Fatalf("Insert post failed: %v", err)
+		}
+		if got := countUsers(t); got != before {
+			t.Errorf("Expected users count to stay cached at %d, got %d", before, got)
+		}
+		db.InvalidateCache("users")
+		if got := countUsers(t); got != before+1 {
+			t.Errorf("Expected %d users after InvalidateCache, got %d", before+1, got)
+		}
+	})
+
+	t.Run("Preloaded tables invalidate the cache", func(t *testing.T) {
+		loadPosts := func() int {
+			var user orm.User
+			if err := db.Model(&orm.User{}).Cache().Where("id = ?", u1.ID).Preload("Posts").SelectOne(&user); err != nil {
+				t.Fatalf("SelectOne with Preload failed: %v", err)
+			}
+			return len(user.Posts)
+		}
+		before := loadPosts()
+		p := orm.Post{UserID: u1.ID, Title: "Cache Post 2", Content: "c"}
+		if _, err := db.Model(&orm.Post{}).Insert(&p); err != nil {
+			t.Fatalf("Insert post failed: %v", err)
+		}
+		if got := loadPosts(); got != before+1 {
+			t.Errorf("Expected %d preloaded posts after insert, got %d", before+1, got)
+		}
+	})
+
+	t.Run("Transaction writes invalidate on commit", func(t *testing.T) {
+		before := countUsers(t)
+		tx, err := db.BeginTx(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("BeginTx failed: %v", err)
+		}
+		u := orm.User{Name: "Cache TX User"}
+		if _, err := tx.Model(&orm.User{}).Insert(&u); err != nil {
+			tx.Rollback()
+			t.Fatalf("Insert in TX failed: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+		if got := countUsers(t); got != before+1 {
+			t.Errorf("Expected %d users after commit, got %d", before+1, got)
+		}
+	})
+
+	t.Run("Entries expire after TTL", func(t *testing.T) {
+		db.EnableQueryCache(10 * time.Millisecond)
+		before := countUsers(t)
+		if _, err := db.Exec("INSERT INTO users (name) VALUES (?)", "Raw User 3"); err != nil {
+			t.Fatalf("Raw insert failed: %v", err)
+		}
+		if got := countUsers(t); got != before {
+			t.Errorf("Expected cached count %d before TTL, got %d", before, got)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if got := countUsers(t); got != before+1 {
+			t.Errorf("Expected %d users after TTL, got %d", before+1, got)
+		}
+	})
+}
+
+func TestStatementCache(t *testing.T) {
+	db, teardown := setupTestDB(t)
+	defer teardown()
+	db.SetMaxOpenConns(2)
+	db.SetMaxIdleConns(2)
+	db.SetConnMaxLifetime(time.Minute)
+
+	u := orm.User{Name: "Stmt User"}
+	if _, err := db.Model(&orm.User{}).Insert(&u); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	findUser := func(t *testing.T, id int64) {
+		t.Helper()
+		var found orm.User
+		if err := db.Model(&orm.User{}).Where("id = ?", id).SelectOne(&found); err != nil {
+			t.Fatalf("SelectOne failed: %v", err)
+		}
+	}
+
+	t.Run("Repeated queries reuse statements", func(t *testing.T) {
+		before := db.Stats()
+		for i := 0; i < 3; i++ {
+			findUser(t, u.ID)
+		}
+		after := db.Stats()
+		if misses := after.StmtCacheMisses - before.StmtCacheMisses; misses != 1 {
+			t.Errorf("Expected 1 prepare for repeated query, got %d", misses)
+		}
+		if hits := after.StmtCacheHits - before.StmtCacheHits; hits != 2 {
+			t.Errorf("Expected 2 cache hits for repeated query, got %d", hits)
+		}
+		if rate := after.StmtCacheHitRate(); rate <= 0 || rate > 1 {
+			t.Errorf("Expected hit rate in (0, 1], got %f", rate)
+		}
+		if after.MaxOpenConnections != 2 {
+			t.Errorf("Expected MaxOpenConnections 2, got %d", after.MaxOpenConnections)
+		}
+	})
+
+	t.Run("Least recently used statements are evicted", func(t *testing.T) {
+		db.SetStatementCacheSize(1)
+		findUser(t, u.ID)
+		var count int64
+		if err := db.Model(&orm.User{}).Count(&count); err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		if size := db.Stats().StmtCacheSize; size != 1 {
+			t.Errorf("Expected cache size 1, got %d", size)
+		}
+		// 追い出されたステートメントは再度 Prepare される
+		before := db.Stats().StmtCacheMisses
+		findUser(t, u.ID)
+		if misses := db.Stats().StmtCacheMisses - before; misses != 1 {
+			t.Errorf("Expected evicted statement to be prepared again, got %d misses", misses)
+		}
+	})
+
+	t.Run("Disabled cache executes queries directly", func(t *testing.T) {
+		db.SetStatementCacheSize(0)
+		before := db.Stats()
+		findUser(t, u.ID)
+		after := db.Stats()
+		if after.StmtCacheSize != 0 || after.StmtCacheMisses != before.StmtCacheMisses {
+			t.Errorf("Expected no cached statements, got size %d", after.StmtCacheSize)
+		}
+	})
+}
+
+// --- Models for soft delete tests ---
+type Folder struct {
+	ID    int64  `db:"id"`
+	Name  string `db:"name"`
+	Notes []Note `orm:"hasmany:folder_id"`
+}
+
+type Note struct {
+	ID        int64        `db:"id"`
+	FolderID  int64        `db:"folder_id"`
+	Title     string       `db:"title"`
+	DeletedAt sql.NullTime `db:"deleted_at"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	_ = os.Remove(testDBFile)
+	db, err := orm.Open("sqlite3", testDBFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+	orm.ClearStructInfoCache()
+
+	if err := db.AutoMigrate(&Folder{}, &Note{}); err != nil {
+		t.Fatalf("AutoMigrate failed: %v", err)
+	}
+	folder := Folder{Name: "Inbox"}
+	if _, err := db.Model(&Folder{}).Insert(&folder); err != nil {
+		t.Fatalf("Insert folder failed: %v", err)
+	}
+	var notes []Note
+	for _, title := range []string{"keep", "trash", "purge"} {
+		note := Note{FolderID: folder.ID, Title: title}
+		if _, err := db.Model(&Note{}).Insert(&note); err != nil {
+			t.Fatalf("Insert note failed: %v", err)
+		}
+		notes = append(notes, note)
+	}
+
+	t.Run("Delete sets DeletedAt instead of removing the row", func(t *testing.T) {
+		result, err := db.Model(&Note{}).Where("id = ?", notes[1].ID).Delete()
+		if err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if affected, _ := result.RowsAffected(); affected != 1 {
+			t.Errorf("Expected 1 row affected, got %d", affected)
+		}
+
+		var raw int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM notes WHERE deleted_at IS NOT NULL").Scan(&raw); err != nil {
+			t.Fatalf("Raw count failed: %v", err)
+		}
+		if raw != 1 {
+			t.Errorf("Expected 1 soft-deleted row in the table, got %d", raw)
+		}
+
+		// 論理削除済みのレコードをもう一度削除しても対象にならない
+		result, err = db.Model(&Note{}).Where("id = ?", notes[1].ID).Delete()
+		if err != nil {
+			t.Fatalf("Second Delete failed: %v", err)
+		}
+		if affected, _ := result.RowsAffected(); affected != 0 {
+			t.Errorf("Expected 0 rows affected for already deleted note, got %d", affected)
+		}
+	})
+
+	t.Run("Queries exclude soft-deleted rows", func(t *testing.T) {
+		var found []Note
+		if err := db.Model(&Note{}).Order("id").Select(&found); err != nil {
+			t.Fatalf("Select failed: %v", err)
+		}
+		if len(found) != 2 || found[0].Title != "keep" || found[1].Title != "purge" {
+			t.Errorf("Expected notes keep and purge, got %+v", found)
+		}
+		var count int64
+		if err := db.Model(&Note{}).Count(&count); err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("Expected count 2, got %d", count)
+		}
+		var note Note
+		err := db.Model(&Note{}).Where("id = ?", notes[1].ID).SelectOne(&note)
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows for soft-deleted note, got %v", err)
+		}
+		if _, err := db.Model(&Note{}).Where("id = ?", notes[1].ID).Update(map[string]interface{}{"title": "changed"}); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if err := db.Model(&Note{}).Unscoped().Where("id = ?", notes[1].ID).SelectOne(&note); err != nil {
+			t.Fatalf("Unscoped SelectOne failed: %v", err)
+		}
+		if note.Title != "trash" {
+			t.Errorf("Expected soft-deleted note not to be updated, got title %q", note.Title)
+		}
+	})
+
+	t.Run("Unscoped includes soft-deleted rows", func(t *testing.T) {
+		var found []Note
+		if err := db.Model(&Note{}).Unscoped().Order("id").Select(&found); err != nil {
+			t.Fatalf("Unscoped Select failed: %v", err)
+		}
+		if len(found) != 3 {
+			t.Fatalf("Expected 3 notes with Unscoped, got %d", len(found))
+		}
+		if !found[1].DeletedAt.Valid || found[0].DeletedAt.Valid {
+			t.Errorf("Expected only note 'trash' to have DeletedAt, got %+v", found)
+		}
+	})
+
+	t.Run("Preload excludes soft-deleted rows", func(t *testing.T) {
+		var f Folder
+		if err := db.Model(&Folder{}).Where("id = ?", folder.ID).Preload("Notes").SelectOne(&f); err != nil {
+			t.Fatalf("SelectOne with Preload failed: %v", err)
+		}
+		if len(f.Notes) != 2 {
+			t.Errorf("Expected 2 preloaded notes, got %d", len(f.Notes))
+		}
+	})
+
+	t.Run("Unscoped Delete removes the row", func(t *testing.T) {
+		if _, err := db.Model(&Note{}).Unscoped().Where("id = ?", notes[2].ID).Delete(); err != nil {
+			t.Fatalf("Unscoped Delete failed: %v", err)
+		}
+		var raw int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM notes").Scan(&raw); err != nil {
+			t.Fatalf("Raw count failed: %v", err)
+		}
+		if raw != 2 {
+			t.Errorf("Expected 2 rows left in the table, got %d", raw)
+		}
+	})
+}
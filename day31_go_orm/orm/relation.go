@@ -0,0 +1,487 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// --- リレーション定義の解析 ---
+
+// parseRelation は orm タグからリレーション情報を構築します。
+//
+//	orm:"hasmany:user_id"             関連側 (posts) の user_id が自分 (users) の id を参照する
+//	orm:"belongsTo:UserID"            自分 (posts) の user_id が関連側 (users) の id を参照する
+//	orm:"many2many:post_tags"         中間テーブル post_tags (post_id, tag_id) で関連付ける
+//
+// カンマ区切りで association_foreignkey (参照されるキー)、many2many では join_foreignkey / join_references
+// (中間テーブルのカラム) と references (関連側のキー) を指定できます。キーはフィールド名・カラム名のどちらでも構いません。
+func parseRelation(structType reflect.Type, field reflect.StructField) (RelationInfo, error) {
+	parts := strings.Split(field.Tag.Get("orm"), ",")
+	kindAndValue := strings.SplitN(strings.TrimSpace(parts[0]), ":", 2)
+	kind := RelationKind(kindAndValue[0])
+	value := ""
+	if len(kindAndValue) == 2 {
+		value = strings.TrimSpace(kindAndValue[1])
+	}
+	params := make(map[string]string)
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, ":", 2)
+		if len(kv) == 2 {
+			params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		} else if value == "" {
+			// 旧形式 (hasmany,user_id) のサポート
+			value = strings.TrimSpace(part)
+		}
+	}
+
+	relatedType, err := relatedStructType(kind, field)
+	if err != nil {
+		return RelationInfo{}, fmt.Errorf("orm: %s relation field '%s' in %s %v", kind, field.Name, structType.Name(), err)
+	}
+
+	relation := RelationInfo{
+		FieldName:   field.Name,
+		Kind:        kind,
+		RelatedType: relatedType,
+	}
+	switch kind {
+	case HasMany:
+		relation.ForeignKey = columnFor(relatedType, defaultString(value, strcase.ToSnake(structType.Name())+"_id"))
+		relation.AssociationForeignKey = columnFor(structType, defaultString(params["association_foreignkey"], "ID"))
+	case BelongsTo:
+		relation.ForeignKey = columnFor(structType, defaultString(value, relatedType.Name()+"ID"))
+		relation.AssociationForeignKey = columnFor(relatedType, defaultString(params["association_foreignkey"], "ID"))
+	case Many2Many:
+		relation.JoinTable = defaultString(value, strcase.ToSnake(structType.Name())+"_"+getTableName(relatedType))
+		relation.JoinForeignKey = defaultString(params["join_foreignkey"], strcase.ToSnake(structType.Name())+"_id")
+		relation.JoinReferences = defaultString(params["join_references"], strcase.ToSnake(relatedType.Name())+"_id")
+		relation.AssociationForeignKey = columnFor(structType, defaultString(params["association_foreignkey"], "ID"))
+		relation.References = columnFor(relatedType, defaultString(params["references"], "ID"))
+	}
+	return relation, nil
+}
+
+// relatedStructType はリレーションフィールドの型から関連モデルの構造体の型を取り出します。
+func relatedStructType(kind RelationKind, field reflect.StructField) (reflect.Type, error) {
+	t := field.Type
+	if kind == HasMany || kind == Many2Many {
+		if t.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("must be a slice")
+		}
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("must refer to a struct or a pointer to a struct, got %s", field.Type)
+	}
+	return t, nil
+}
+
+// columnFor はフィールド名であればカラム名に変換し、そうでなければカラム名とみなしてそのまま (スネークケースで) 返します。
+func columnFor(structType reflect.Type, name string) string {
+	if field, ok := structType.FieldByName(name); ok {
+		if column, ok := fieldColumnName(field); ok {
+			return column
+		}
+	}
+	return strcase.ToSnake(name)
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// --- Preload 処理 ---
+
+// processPreloads は取得済みのデータ (dest: 構造体のスライスへのポインタ) に対して、
+// 指定されたリレーション (preloads map) のデータを取得し、関連付けます。
+// "Posts.Comments" のようにドット区切りで指定すると、ネストしたリレーションも順に読み込みます。
+func processPreloads(ctx context.Context, exec executorInternal, dest interface{}, preloads map[string]bool) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
+		return fmt.Errorf("orm: processPreloads expects a non-nil pointer to slice destination, got %T", dest)
+	}
+	sliceVal := destVal.Elem()
+	if sliceVal.Kind() != reflect.Slice {
+		return fmt.Errorf("orm: processPreloads destination must be a pointer to slice, got pointer to %s", sliceVal.Kind())
+	}
+
+	elemType := sliceVal.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("orm: processPreloads slice element must be a struct or pointer to struct, got %s", sliceVal.Type().Elem().Kind())
+	}
+
+	// 親要素をアドレス可能な構造体の値として集める (関連データを直接セットするため)
+	parents := make([]reflect.Value, 0, sliceVal.Len())
+	for i := 0; i < sliceVal.Len(); i++ {
+		elem := sliceVal.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		parents = append(parents, elem)
+	}
+
+	paths := make([]string, 0, len(preloads))
+	for path := range preloads {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return preloadPaths(ctx, exec, elemType, parents, paths)
+}
+
+// preloadPaths は parents (structType の値) に対して paths のリレーションを読み込みます。
+func preloadPaths(ctx context.Context, exec executorInternal, structType reflect.Type, parents []reflect.Value, paths []string) error {
+	if len(parents) == 0 {
+		return nil // データがなければ何もしない
+	}
+
+	structInfo, err := getStructInfo(structType)
+	if err != nil {
+		return fmt.Errorf("orm: failed to get struct info for preload base type %s: %w", structType.Name(), err)
+	}
+
+	// 先頭のフィールド名ごとにネストしたパスをまとめる (例: Posts, Posts.Comments -> Posts: [Comments])
+	nested := make(map[string][]string)
+	var fieldNames []string
+	for _, path := range paths {
+		head, rest, hasRest := strings.Cut(path, ".")
+		if _, seen := nested[head]; !seen {
+			fieldNames = append(fieldNames, head)
+			nested[head] = nil
+		}
+		if hasRest {
+			nested[head] = append(nested[head], rest)
+		}
+	}
+
+	for _, fieldName := range fieldNames {
+		relation, ok := structInfo.relations[fieldName]
+		if !ok {
+			return fmt.Errorf("orm: preload field '%s' not found or not a valid relation in struct %s", fieldName, structType.Name())
+		}
+
+		switch relation.Kind {
+		case HasMany:
+			err = loadHasMany(ctx, exec, relation, parents)
+		case BelongsTo:
+			err = loadBelongsTo(ctx, exec, relation, parents)
+		case Many2Many:
+			err = loadMany2Many(ctx, exec, relation, parents)
+		}
+		if err != nil {
+			return fmt.Errorf("orm: failed to fetch related data for %s: %w", fieldName, err)
+		}
+
+		if len(nested[fieldName]) > 0 {
+			children := relatedValues(parents, relation)
+			if err := preloadPaths(ctx, exec, relation.RelatedType, children, nested[fieldName]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadHasMany は関連側の外部キーで関連データを取得し、親のスライスフィールドに追加します。
+func loadHasMany(ctx context.Context, exec executorInternal, relation RelationInfo, parents []reflect.Value) error {
+	keys, keyToParents, err := collectKeys(parents, relation.AssociationForeignKey)
+	if err != nil {
+		return err
+	}
+	for _, parent := range parents {
+		resetRelationField(parent.FieldByName(relation.FieldName))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	related, err := fetchRelated(ctx, exec, relation.RelatedType, relation.ForeignKey, keys)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < related.Len(); i++ {
+		child := related.Index(i)
+		key, ok, err := columnKey(child, relation.ForeignKey)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		for _, parent := range keyToParents[key] {
+			appendRelated(parent.FieldByName(relation.FieldName), child)
+		}
+	}
+	return nil
+}
+
+// loadBelongsTo は親の外部キーが参照する関連データを取得し、親のフィールドにセットします。
+func loadBelongsTo(ctx context.Context, exec executorInternal, relation RelationInfo, parents []reflect.Value) error {
+	keys, keyToParents, err := collectKeys(parents, relation.ForeignKey)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	related, err := fetchRelated(ctx, exec, relation.RelatedType, relation.AssociationForeignKey, keys)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < related.Len(); i++ {
+		child := related.Index(i)
+		key, ok, err := columnKey(child, relation.AssociationForeignKey)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		for _, parent := range keyToParents[key] {
+			field := parent.FieldByName(relation.FieldName)
+			if field.Kind() == reflect.Ptr {
+				ptr := reflect.New(child.Type())
+				ptr.Elem().Set(child)
+				field.Set(ptr)
+			} else {
+				field.Set(child)
+			}
+		}
+	}
+	return nil
+}
+
+// loadMany2Many は中間テーブルを経由して関連データを取得し、親のスライスフィールドに追加します。
+func loadMany2Many(ctx context.Context, exec executorInternal, relation RelationInfo, parents []reflect.Value) error {
+	keys, keyToParents, err := collectKeys(parents, relation.AssociationForeignKey)
+	if err != nil {
+		return err
+	}
+	for _, parent := range parents {
+		resetRelationField(parent.FieldByName(relation.FieldName))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	// 1. 中間テーブルから (親のキー, 関連側のキー) の組を取得
+	dialect := exec.Dialect()
+	query := fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IN (%s)",
+		dialect.Quote(relation.JoinForeignKey), dialect.Quote(relation.JoinReferences), dialect.Quote(relation.JoinTable),
+		dialect.Quote(relation.JoinForeignKey), placeholders(len(keys)))
+	rows, err := queryContext(ctx, exec, Rebind(dialect, query), keys...)
+	if err != nil {
+		return fmt.Errorf("orm: query failed for join table %s: %w", relation.JoinTable, err)
+	}
+	defer rows.Close()
+
+	type joinRow struct{ parentKey, relatedKey interface{} }
+	var joins []joinRow
+	var relatedKeys []interface{}
+	seen := make(map[interface{}]bool)
+	for rows.Next() {
+		var parentKey, relatedKey interface{}
+		if err := rows.Scan(&parentKey, &relatedKey); err != nil {
+			return fmt.Errorf("orm: failed to scan join table %s: %w", relation.JoinTable, err)
+		}
+		pk, ok1 := normalizeKey(parentKey)
+		rk, ok2 := normalizeKey(relatedKey)
+		if !ok1 || !ok2 {
+			continue
+		}
+		joins = append(joins, joinRow{pk, rk})
+		if !seen[rk] {
+			seen[rk] = true
+			relatedKeys = append(relatedKeys, rk)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("orm: error iterating join table %s: %w", relation.JoinTable, err)
+	}
+	rows.Close()
+	if len(relatedKeys) == 0 {
+		return nil
+	}
+
+	// 2. 関連データを一括取得
+	related, err := fetchRelated(ctx, exec, relation.RelatedType, relation.References, relatedKeys)
+	if err != nil {
+		return err
+	}
+	relatedByKey := make(map[interface{}]reflect.Value, related.Len())
+	for i := 0; i < related.Len(); i++ {
+		child := related.Index(i)
+		key, ok, err := columnKey(child, relation.References)
+		if err != nil {
+			return err
+		}
+		if ok {
+			relatedByKey[key] = child
+		}
+	}
+
+	// 3. 中間テーブルの順に親要素に追加
+	for _, j := range joins {
+		child, ok := relatedByKey[j.relatedKey]
+		if !ok {
+			continue
+		}
+		for _, parent := range keyToParents[j.parentKey] {
+			appendRelated(parent.FieldByName(relation.FieldName), child)
+		}
+	}
+	return nil
+}
+
+// collectKeys は親要素から column の値を集め、重複を除いたキーの一覧とキーごとの親要素を返します。NULL は除外します。
+func collectKeys(parents []reflect.Value, column string) ([]interface{}, map[interface{}][]reflect.Value, error) {
+	keys := make([]interface{}, 0, len(parents))
+	keyToParents := make(map[interface{}][]reflect.Value)
+	for _, parent := range parents {
+		key, ok, err := columnKey(parent, column)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		if _, exists := keyToParents[key]; !exists {
+			keys = append(keys, key)
+		}
+		keyToParents[key] = append(keyToParents[key], parent)
+	}
+	return keys, keyToParents, nil
+}
+
+// columnKey は構造体の値から column に対応するフィールドの値を、キーとして比較できる形で返します。
+func columnKey(structVal reflect.Value, column string) (interface{}, bool, error) {
+	structInfo, err := getStructInfo(structVal.Type())
+	if err != nil {
+		return nil, false, err
+	}
+	fieldName, ok := structInfo.columnToField[column]
+	if !ok {
+		return nil, false, fmt.Errorf("orm: column '%s' not found in struct %s", column, structVal.Type().Name())
+	}
+	field := structVal.Field(structInfo.fieldIndex[fieldName])
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil, false, nil
+		}
+		field = field.Elem()
+	}
+	value := field.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		// sql.NullInt64 などは driver の値に変換して比較する
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, false, err
+		}
+		value = v
+	}
+	key, ok := normalizeKey(value)
+	return key, ok, nil
+}
+
+// normalizeKey は整数型を int64 に、[]byte を string に揃えて、異なる型同士でもキーとして一致させます。
+func normalizeKey(value interface{}) (interface{}, bool) {
+	if value == nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	if b, ok := value.([]byte); ok {
+		return string(b), true
+	}
+	return value, true
+}
+
+// fetchRelated は relatedType のテーブルから column が keys のいずれかに一致するレコードを取得します (論理削除されたレコードは除く)。
+func fetchRelated(ctx context.Context, exec executorInternal, relatedType reflect.Type, column string, keys []interface{}) (reflect.Value, error) {
+	dialect := exec.Dialect()
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s IN (%s)", dialect.Quote(getTableName(relatedType)), dialect.Quote(column), placeholders(len(keys)))
+	// 論理削除された関連データは読み込まない
+	if structInfo, err := getStructInfo(relatedType); err == nil && structInfo.softDeleteColumn != "" {
+		query += fmt.Sprintf(" AND %s IS NULL", dialect.Quote(structInfo.softDeleteColumn))
+	}
+	resultsPtr := reflect.New(reflect.SliceOf(relatedType))
+	if err := selectMulti(ctx, exec, resultsPtr.Interface(), Rebind(dialect, query), keys...); err != nil {
+		return reflect.Value{}, err
+	}
+	return resultsPtr.Elem(), nil
+}
+
+// relatedValues は読み込み済みの関連データを、さらに Preload できるようにアドレス可能な構造体の値として集めます。
+func relatedValues(parents []reflect.Value, relation RelationInfo) []reflect.Value {
+	var children []reflect.Value
+	for _, parent := range parents {
+		field := parent.FieldByName(relation.FieldName)
+		switch field.Kind() {
+		case reflect.Slice:
+			for i := 0; i < field.Len(); i++ {
+				elem := field.Index(i)
+				if elem.Kind() == reflect.Ptr {
+					if elem.IsNil() {
+						continue
+					}
+					elem = elem.Elem()
+				}
+				children = append(children, elem)
+			}
+		case reflect.Ptr:
+			if !field.IsNil() {
+				children = append(children, field.Elem())
+			}
+		case reflect.Struct:
+			children = append(children, field)
+		}
+	}
+	return children
+}
+
+// resetRelationField はスライスのリレーションフィールドを空にします (再度 Preload した場合に重複させないため)。
+func resetRelationField(field reflect.Value) {
+	if field.Kind() == reflect.Slice && field.CanSet() {
+		field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+	}
+}
+
+// appendRelated はスライスのリレーションフィールドに関連データを追加します (ポインタのスライスの場合はコピーのポインタ)。
+func appendRelated(field reflect.Value, child reflect.Value) {
+	if field.Type().Elem().Kind() == reflect.Ptr {
+		ptr := reflect.New(child.Type())
+		ptr.Elem().Set(child)
+		field.Set(reflect.Append(field, ptr))
+		return
+	}
+	field.Set(reflect.Append(field, child))
+}
+
+// placeholders は IN 句用のプレースホルダ (?,?,...) を返します。
+func placeholders(n int) string {
+	return strings.Repeat("?,", n-1) + "?"
+}
@@ -0,0 +1,201 @@
+package orm
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// defaultStatementCacheSize はプリペアドステートメントのキャッシュに保持する数のデフォルト値です。
+const defaultStatementCacheSize = 100
+
+// stmtCache は SQL 文をキーにプリペアドステートメントを保持する LRU キャッシュです。
+// 上限を超えると最も長く使われていないステートメントを Close します。
+type stmtCache struct {
+	mu       sync.Mutex
+	capacity int                      // 0 ならキャッシュしない
+	ll       *list.List               // 先頭が最近使われたもの (要素は *stmtEntry)
+	items    map[string]*list.Element // SQL 文 -> リストの要素
+	hits     uint64
+	misses   uint64
+}
+
+type stmtEntry struct {
+	query string
+	stmt  *sql.Stmt
+}
+
+func newStmtCache(capacity int) *stmtCache {
+	return &stmtCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// prepare はキャッシュ済みのステートメントを返し、なければ Prepare してキャッシュします。
+// キャッシュが無効な場合や Prepare に失敗した場合は false を返します。
+func (c *stmtCache) prepare(ctx context.Context, db *sql.DB, query string) (*sql.Stmt, bool) {
+	c.mu.Lock()
+	if c.capacity == 0 {
+		c.mu.Unlock()
+		return nil, false
+	}
+	if el, ok := c.items[query]; ok {
+		c.ll.MoveToFront(el)
+		c.hits++
+		c.mu.Unlock()
+		return el.Value.(*stmtEntry).stmt, true
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Prepare は DB へのアクセスを伴うのでロックの外で行う
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[query]; ok {
+		// 並行して同じ SQL が Prepare された場合は先にキャッシュされた方を使う
+		stmt.Close()
+		c.ll.MoveToFront(el)
+		return el.Value.(*stmtEntry).stmt, true
+	}
+	if c.capacity == 0 {
+		// Prepare 中にキャッシュが無効化された
+		stmt.Close()
+		return nil, false
+	}
+	c.items[query] = c.ll.PushFront(&stmtEntry{query: query, stmt: stmt})
+	c.evict()
+	return stmt, true
+}
+
+// resize はキャッシュの上限を変更し、超えた分のステートメントを Close します。
+func (c *stmtCache) resize(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	c.evict()
+}
+
+// evict は上限を超えた分のステートメントを古い順に Close します。c.mu を保持して呼び出します。
+// 実行中のクエリが使っているステートメントは、database/sql がクエリの終了後に閉じます。
+func (c *stmtCache) evict() {
+	for c.ll.Len() > c.capacity {
+		el := c.ll.Back()
+		entry := el.Value.(*stmtEntry)
+		c.ll.Remove(el)
+		delete(c.items, entry.query)
+		entry.stmt.Close()
+	}
+}
+
+// close はすべてのステートメントを Close します。
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, el := range c.items {
+		el.Value.(*stmtEntry).stmt.Close()
+	}
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// --- プリペアドステートメントを使うクエリ実行ヘルパー ---
+// exec が *DB の場合はキャッシュしたステートメントで実行し、*TX の場合やキャッシュが無効な場合はそのまま実行します。
+// Prepare に失敗した場合もそのまま実行し、エラーは通常の実行結果として返します。
+
+func (db *DB) cachedStmt(ctx context.Context, query string) (*sql.Stmt, bool) {
+	if db.stmts == nil {
+		return nil, false
+	}
+	return db.stmts.prepare(ctx, db.DB, query)
+}
+
+func execContext(ctx context.Context, exec executorInternal, query string, args ...interface{}) (sql.Result, error) {
+	if db, ok := exec.(*DB); ok {
+		if stmt, ok := db.cachedStmt(ctx, query); ok {
+			return stmt.ExecContext(ctx, args...)
+		}
+	}
+	return exec.ExecContext(ctx, query, args...)
+}
+
+func queryContext(ctx context.Context, exec executorInternal, query string, args ...interface{}) (*sql.Rows, error) {
+	if db, ok := exec.(*DB); ok {
+		if stmt, ok := db.cachedStmt(ctx, query); ok {
+			return stmt.QueryContext(ctx, args...)
+		}
+	}
+	return exec.QueryContext(ctx, query, args...)
+}
+
+func queryRowContext(ctx context.Context, exec executorInternal, query string, args ...interface{}) *sql.Row {
+	if db, ok := exec.(*DB); ok {
+		if stmt, ok := db.cachedStmt(ctx, query); ok {
+			return stmt.QueryRowContext(ctx, args...)
+		}
+	}
+	return exec.QueryRowContext(ctx, query, args...)
+}
+
+// --- コネクションプールの設定と統計 ---
+
+// SetMaxOpenConns はコネクションプールの最大接続数を設定します (0 以下で無制限)。
+func (db *DB) SetMaxOpenConns(n int) {
+	db.DB.SetMaxOpenConns(n)
+}
+
+// SetMaxIdleConns はコネクションプールに保持するアイドル接続の最大数を設定します (0 以下で保持しない)。
+func (db *DB) SetMaxIdleConns(n int) {
+	db.DB.SetMaxIdleConns(n)
+}
+
+// SetConnMaxLifetime は接続を再利用できる最大時間を設定します (0 以下で無制限)。
+func (db *DB) SetConnMaxLifetime(d time.Duration) {
+	db.DB.SetConnMaxLifetime(d)
+}
+
+// SetStatementCacheSize は QueryBuilder が再利用するプリペアドステートメントの最大数を設定します (デフォルト 100)。
+// 0 を指定するとキャッシュを無効にし、保持しているステートメントを Close します。
+func (db *DB) SetStatementCacheSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	db.stmts.resize(n)
+}
+
+// Stats は DB の統計情報です。コネクションプールの統計 (sql.DBStats) に加え、
+// プリペアドステートメントのキャッシュの利用状況を含みます。
+type Stats struct {
+	sql.DBStats
+	StmtCacheSize   int    // キャッシュしているステートメントの数
+	StmtCacheHits   uint64 // キャッシュ済みのステートメントを再利用した回数
+	StmtCacheMisses uint64 // ステートメントを新しく Prepare した回数
+}
+
+// StmtCacheHitRate はプリペアドステートメントのキャッシュのヒット率 (0〜1) を返します。まだ実行がなければ 0 を返します。
+func (s Stats) StmtCacheHitRate() float64 {
+	total := s.StmtCacheHits + s.StmtCacheMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.StmtCacheHits) / float64(total)
+}
+
+// Stats はコネクションプールとプリペアドステートメントのキャッシュの統計情報を返します。
+func (db *DB) Stats() Stats {
+	stats := Stats{DBStats: db.DB.Stats()}
+	db.stmts.mu.Lock()
+	defer db.stmts.mu.Unlock()
+	stats.StmtCacheSize = db.stmts.ll.Len()
+	stats.StmtCacheHits = db.stmts.hits
+	stats.StmtCacheMisses = db.stmts.misses
+	return stats
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"database/sql"
 	"html/template"
 	"log"
@@ -13,6 +12,7 @@ import (
 
 	// 作成した ORM パッケージをインポート
 	"github.com/lirlia/100day_challenge_backend/day31_go_orm/orm"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 const webappDBFile = "./webapp.db"
@@ -22,9 +22,9 @@ const templatesDir = "templates"
 type User struct {
 	ID        int64          `db:"id"`
 	Name      string         `db:"name"`
-	Email     sql.NullString `db:"email"`
-	CreatedAt time.Time      `db:"created_at"`
-	UpdatedAt time.Time      `db:"updated_at"`
+	Email     sql.NullString `db:"email" orm:"unique"`
+	CreatedAt time.Time      `db:"created_at" orm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time      `db:"updated_at" orm:"default:CURRENT_TIMESTAMP"`
 }
 
 var db *orm.DB
@@ -37,15 +37,17 @@ func main() {
 	// 既存ファイルがあれば削除 (開発用)
 	_ = os.Remove(webappDBFile)
 
-	db, err = orm.Open(webappDBFile)
+	db, err = orm.Open("sqlite3", webappDBFile)
 	if err != nil {
 		log.Fatalf("FATAL: Failed to open database: %v", err)
 	}
 	defer db.Close()
 	log.Println("Database connected.")
+	// 一覧ページのクエリ結果をキャッシュする (追加・削除で自動的に無効化される)
+	db.EnableQueryCache(time.Minute)
 
-	// テーブル作成 (存在しない場合)
-	if err := setupSchema(context.Background()); err != nil {
+	// 構造体の定義からテーブルを作成・同期
+	if err := db.AutoMigrate(&User{}); err != nil {
 		log.Fatalf("FATAL: Failed to setup database schema: %v", err)
 	}
 	log.Println("Database schema ready.")
@@ -70,20 +72,6 @@ func main() {
 	}
 }
 
-// setupSchema はデータベーススキーマ (users テーブル) を作成します。
-func setupSchema(ctx context.Context) error {
-	_, err := db.Exec(ctx, `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		name TEXT NOT NULL,
-		email TEXT UNIQUE,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`)
-	return err
-}
-
 // --- HTTP ハンドラ関数 ---
 
 // indexHandler はユーザー一覧を表示します。
@@ -96,7 +84,7 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	var users []User
-	err := db.Model(&User{}).WithContext(ctx).Order("id DESC").Select(&users)
+	err := db.Model(&User{}).WithContext(ctx).Cache().Order("id DESC").Select(&users)
 	if err != nil {
 		log.Printf("ERROR: Failed to fetch users: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -136,13 +124,14 @@ func addUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newUser := User{Name: name}
+	now := time.Now()
+	newUser := User{Name: name, CreatedAt: now, UpdatedAt: now}
 	if email != "" {
 		newUser.Email = sql.NullString{String: email, Valid: true}
 	}
 
 	ctx := r.Context()
-	_, err := db.Insert(ctx, &newUser)
+	_, err := db.Model(&User{}).WithContext(ctx).Insert(&newUser)
 	if err != nil {
 		log.Printf("ERROR: Failed to insert user: %v", err)
 		// email UNIQUE 制約違反の可能性など
@@ -182,7 +171,7 @@ func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	result, err := db.Delete(ctx, "DELETE FROM users WHERE id = ?", id)
+	result, err := db.Model(&User{}).WithContext(ctx).Where("id = ?", id).Delete()
 	if err != nil {
 		log.Printf("ERROR: Failed to delete user with ID %d: %v", id, err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
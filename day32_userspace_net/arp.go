@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/songgao/water"
+)
+
+// ARPPacket represents an ARP packet for IPv4 over Ethernet.
+// Reference: RFC 826
+type ARPPacket struct {
+	HardwareType uint16           // Hardware Type (1 = Ethernet)
+	ProtocolType uint16           // Protocol Type (0x0800 = IPv4)
+	HardwareLen  uint8            // Hardware Address Length (6)
+	ProtocolLen  uint8            // Protocol Address Length (4)
+	Operation    uint16           // Operation (1 = Request, 2 = Reply)
+	SenderMAC    net.HardwareAddr // Sender Hardware Address
+	SenderIP     net.IP           // Sender Protocol Address
+	TargetMAC    net.HardwareAddr // Target Hardware Address
+	TargetIP     net.IP           // Target Protocol Address
+}
+
+const (
+	ARPPacketLengthBytes = 28
+	ARPHardwareEthernet  = 1
+	ARPOperationRequest  = 1
+	ARPOperationReply    = 2
+
+	// ARPCacheTTL is how long a learned MAC address is kept in the ARP cache
+	ARPCacheTTL = 5 * time.Minute
+	// ARPMaxPendingPackets is the maximum number of packets queued per unresolved IP address
+	ARPMaxPendingPackets = 16
+)
+
+// ARPCache maps IP addresses to MAC addresses, and holds the packets waiting for an ARP reply
+// (or a Neighbor Advertisement for IPv6 addresses).
+type ARPCache struct {
+	mu      sync.Mutex
+	entries map[string]arpCacheEntry // Keyed by IP address string
+	pending map[string][][]byte      // IP packets waiting for the MAC address of the key
+}
+
+type arpCacheEntry struct {
+	MAC       net.HardwareAddr
+	UpdatedAt time.Time
+}
+
+var arpCache = NewARPCache()
+
+// NewARPCache creates an empty ARP cache.
+func NewARPCache() *ARPCache {
+	return &ARPCache{
+		entries: make(map[string]arpCacheEntry),
+		pending: make(map[string][][]byte),
+	}
+}
+
+// Lookup returns the MAC address of the IP address if it is cached and not expired.
+func (c *ARPCache) Lookup(ip net.IP) (net.HardwareAddr, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := ip.String()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(entry.UpdatedAt) > ARPCacheTTL {
+		log.Printf("%s%sCache entry expired: %s -> %s%s", ColorTeal, PrefixARP, key, entry.MAC, ColorReset)
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.MAC, true
+}
+
+// Update stores the MAC address of the IP address and returns the packets that were waiting for it.
+func (c *ARPCache) Update(ip net.IP, mac net.HardwareAddr) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 受信バッファを指しているためコピーして保持する
+	key := ip.String()
+	macCopy := make(net.HardwareAddr, len(mac))
+	copy(macCopy, mac)
+
+	if old, ok := c.entries[key]; !ok || !macEqual(old.MAC, macCopy) {
+		log.Printf("%s%sCache update: %s -> %s%s", ColorTeal, PrefixARP, key, macCopy, ColorReset)
+	}
+	c.entries[key] = arpCacheEntry{MAC: macCopy, UpdatedAt: time.Now()}
+
+	pending := c.pending[key]
+	delete(c.pending, key)
+	return pending
+}
+
+// Enqueue queues an IP packet until the MAC address of the IP address is resolved.
+// The oldest packet is dropped if the queue is full.
+func (c *ARPCache) Enqueue(ip net.IP, packet []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := ip.String()
+	queue := c.pending[key]
+	if len(queue) >= ARPMaxPendingPackets {
+		log.Printf("%s%sPending queue for %s is full, dropping the oldest packet.%s", ColorYellow, PrefixWarn, key, ColorReset)
+		queue = queue[1:]
+	}
+	c.pending[key] = append(queue, append([]byte(nil), packet...))
+}
+
+// parseARPPacket parses an ARP packet for IPv4 over Ethernet.
+func parseARPPacket(data []byte) (*ARPPacket, error) {
+	if len(data) < ARPPacketLengthBytes {
+		return nil, fmt.Errorf("ARP packet too short: %d bytes", len(data))
+	}
+	packet := &ARPPacket{
+		HardwareType: binary.BigEndian.Uint16(data[0:2]),
+		ProtocolType: binary.BigEndian.Uint16(data[2:4]),
+		HardwareLen:  data[4],
+		ProtocolLen:  data[5],
+		Operation:    binary.BigEndian.Uint16(data[6:8]),
+	}
+	if packet.HardwareType != ARPHardwareEthernet || packet.ProtocolType != EtherTypeIPv4 ||
+		packet.HardwareLen != 6 || packet.ProtocolLen != 4 {
+		return nil, fmt.Errorf("unsupported ARP packet (HType: %d, PType: 0x%04x, HLen: %d, PLen: %d)",
+			packet.HardwareType, packet.ProtocolType, packet.HardwareLen, packet.ProtocolLen)
+	}
+	packet.SenderMAC = net.HardwareAddr(data[8:14])
+	packet.SenderIP = net.IP(data[14:18])
+	packet.TargetMAC = net.HardwareAddr(data[18:24])
+	packet.TargetIP = net.IP(data[24:28])
+	return packet, nil
+}
+
+// buildARPPacket creates an ARP packet byte slice for IPv4 over Ethernet.
+func buildARPPacket(operation uint16, senderMAC net.HardwareAddr, senderIP net.IP, targetMAC net.HardwareAddr, targetIP net.IP) []byte {
+	packet := make([]byte, ARPPacketLengthBytes)
+	binary.BigEndian.PutUint16(packet[0:2], ARPHardwareEthernet)
+	binary.BigEndian.PutUint16(packet[2:4], EtherTypeIPv4)
+	packet[4] = 6 // Hardware Address Length
+	packet[5] = 4 // Protocol Address Length
+	binary.BigEndian.PutUint16(packet[6:8], operation)
+	copy(packet[8:14], senderMAC)
+	copy(packet[14:18], senderIP.To4())
+	copy(packet[18:24], targetMAC)
+	copy(packet[24:28], targetIP.To4())
+	return packet
+}
+
+// handleARPPacket updates the ARP cache and answers ARP requests for the stack's IP address.
+func handleARPPacket(ifce *water.Interface, ethHeader *EthernetHeader, data []byte) {
+	arpPacket, err := parseARPPacket(data)
+	if err != nil {
+		log.Printf("%s%sFailed to parse ARP packet: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+
+	if arpPacket.Operation == ARPOperationReply {
+		log.Printf("%s%sRCV Reply: %s is at %s%s", ColorTeal, PrefixARP, arpPacket.SenderIP, arpPacket.SenderMAC, ColorReset)
+	} else {
+		log.Printf("%s%sRCV Request: Who has %s? Tell %s (%s)%s", ColorTeal, PrefixARP, arpPacket.TargetIP, arpPacket.SenderIP, arpPacket.SenderMAC, ColorReset)
+	}
+	pauseIfNeeded("arp")
+
+	isForUs := arpPacket.TargetIP.Equal(stackIP)
+	// RFC 826: 自分宛て、または既にキャッシュにある送信者の情報を更新する
+	_, cached := arpCache.Lookup(arpPacket.SenderIP)
+	if !arpPacket.SenderIP.IsUnspecified() && (isForUs || cached) {
+		learnMAC(ifce, arpPacket.SenderIP, arpPacket.SenderMAC)
+	}
+
+	if arpPacket.Operation != ARPOperationRequest || !isForUs {
+		return
+	}
+
+	log.Printf("%s%sSND Reply: %s is at %s (to %s)%s", ColorTeal, PrefixARP, stackIP, stackMAC, arpPacket.SenderIP, ColorReset)
+	reply := buildARPPacket(ARPOperationReply, stackMAC, stackIP, arpPacket.SenderMAC, arpPacket.SenderIP)
+	if err := sendEthernetFrame(ifce, ethHeader.SrcMAC, EtherTypeARP, reply); err != nil {
+		log.Printf("%s%sFailed to send ARP reply: %v%s", ColorRed, PrefixError, err, ColorReset)
+	}
+}
+
+// learnMAC stores the MAC address of the IP address in the ARP cache and sends the packets
+// that were waiting for it.
+func learnMAC(ifce *water.Interface, ip net.IP, mac net.HardwareAddr) {
+	pending := arpCache.Update(ip, mac)
+	for _, packet := range pending {
+		etherType := uint16(EtherTypeIPv4)
+		if packet[0]>>4 == IPv6Version {
+			etherType = EtherTypeIPv6
+		}
+		if err := sendEthernetFrame(ifce, mac, etherType, packet); err != nil {
+			log.Printf("%s%sFailed to send queued packet to %s: %v%s", ColorRed, PrefixError, ip, err, ColorReset)
+		}
+	}
+	if len(pending) > 0 {
+		log.Printf("%s%sSent %d queued packets to %s%s", ColorTeal, PrefixARP, len(pending), ip, ColorReset)
+	}
+}
+
+// sendARPRequest broadcasts an ARP request for the IP address.
+func sendARPRequest(ifce *water.Interface, targetIP net.IP) error {
+	log.Printf("%s%sSND Request: Who has %s? Tell %s%s", ColorTeal, PrefixARP, targetIP, stackIP, ColorReset)
+	request := buildARPPacket(ARPOperationRequest, stackMAC, stackIP, net.HardwareAddr{0, 0, 0, 0, 0, 0}, targetIP)
+	return sendEthernetFrame(ifce, broadcastMAC, EtherTypeARP, request)
+}
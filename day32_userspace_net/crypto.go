@@ -334,3 +334,258 @@ func computeFinishedHash(masterSecret []byte, finishedLabel string, handshakeHas
 	}
 	return verifyData, nil
 }
+
+// --- TLS 1.3 Key Schedule (HKDF) --- RFC 5869, RFC 8446 Section 7.1 ---
+
+// hkdfExtract implements HKDF-Extract with SHA-256. A nil salt is treated as a string of zeros.
+func hkdfExtract(salt, ikm []byte) []byte {
+	if salt == nil {
+		salt = make([]byte, sha256.Size)
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand implements HKDF-Expand with SHA-256.
+// T(0) = empty, T(i) = HMAC(prk, T(i-1) + info + i), OKM = first length bytes of T(1) + T(2) + ...
+func hkdfExpand(prk, info []byte, length int) []byte {
+	mac := hmac.New(sha256.New, prk)
+	var result, t []byte
+	for i := byte(1); len(result) < length; i++ {
+		mac.Reset()
+		mac.Write(t)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+		result = append(result, t...)
+	}
+	return result[:length]
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label.
+// HkdfLabel = uint16 length + opaque label<7..255> ("tls13 " + Label) + opaque context<0..255>
+func hkdfExpandLabel(secret []byte, label string, context []byte, length int) []byte {
+	fullLabel := "tls13 " + label
+	hkdfLabel := make([]byte, 0, 2+1+len(fullLabel)+1+len(context))
+	hkdfLabel = binary.BigEndian.AppendUint16(hkdfLabel, uint16(length))
+	hkdfLabel = append(hkdfLabel, byte(len(fullLabel)))
+	hkdfLabel = append(hkdfLabel, fullLabel...)
+	hkdfLabel = append(hkdfLabel, byte(len(context)))
+	hkdfLabel = append(hkdfLabel, context...)
+	return hkdfExpand(secret, hkdfLabel, length)
+}
+
+// deriveSecret implements Derive-Secret(Secret, Label, Messages) = HKDF-Expand-Label(Secret, Label, Transcript-Hash(Messages), Hash.length).
+func deriveSecret(secret []byte, label string, messages []byte) []byte {
+	transcriptHash := sha256.Sum256(messages)
+	return hkdfExpandLabel(secret, label, transcriptHash[:], sha256.Size)
+}
+
+// logKeyScheduleStage logs a secret derived in the TLS 1.3 key schedule (only the first bytes unless debugging).
+func logKeyScheduleStage(stage string, secret []byte) {
+	if isDebug {
+		log.Printf("%s%sKey Schedule: %s (%d bytes): %x%s", ColorOrange, PrefixTLS, stage, len(secret), secret, ColorReset)
+		return
+	}
+	log.Printf("%s%sKey Schedule: %s (%d bytes): %x...%s", ColorOrange, PrefixTLS, stage, len(secret), secret[:8], ColorReset)
+}
+
+// trafficKeys13 derives the AES-128-GCM write key and the 12-byte write IV from a traffic secret.
+func trafficKeys13(trafficSecret []byte) (key, iv []byte) {
+	return hkdfExpandLabel(trafficSecret, "key", nil, 16), hkdfExpandLabel(trafficSecret, "iv", nil, aesGcmNonceLength)
+}
+
+// deriveHandshakeKeys13 runs the key schedule from the Early Secret up to the handshake traffic
+// secrets (transcript: ClientHello...ServerHello) and installs the handshake traffic keys for both directions.
+func deriveHandshakeKeys13(conn *TCPConnection, sharedSecret []byte) {
+	conn.Mutex.Lock()
+	defer conn.Mutex.Unlock()
+
+	// PSK は未対応のため 0 を入力とする (0-RTT 用の early traffic secret は導出しない)
+	earlySecret := hkdfExtract(nil, make([]byte, sha256.Size))
+	logKeyScheduleStage("Early Secret", earlySecret)
+
+	derivedSecret := deriveSecret(earlySecret, "derived", nil)
+	handshakeSecret := hkdfExtract(derivedSecret, sharedSecret)
+	logKeyScheduleStage("Handshake Secret", handshakeSecret)
+
+	transcript := conn.HandshakeMessages.Bytes()
+	conn.TLS13HandshakeSecret = handshakeSecret
+	conn.TLS13ClientHandshakeSecret = deriveSecret(handshakeSecret, "c hs traffic", transcript)
+	conn.TLS13ServerHandshakeSecret = deriveSecret(handshakeSecret, "s hs traffic", transcript)
+	logKeyScheduleStage("client_handshake_traffic_secret", conn.TLS13ClientHandshakeSecret)
+	logKeyScheduleStage("server_handshake_traffic_secret", conn.TLS13ServerHandshakeSecret)
+
+	conn.ClientWriteKey, conn.ClientWriteIV = trafficKeys13(conn.TLS13ClientHandshakeSecret)
+	conn.ServerWriteKey, conn.ServerWriteIV = trafficKeys13(conn.TLS13ServerHandshakeSecret)
+	conn.ClientSequenceNum = 0
+	conn.ServerSequenceNum = 0
+	conn.EncryptionEnabled = true
+	log.Printf("%s%sInstalled handshake traffic keys (both directions).%s", ColorOrange, PrefixTLS, ColorReset)
+}
+
+// deriveApplicationKeys13 derives the Master Secret and the application traffic secrets
+// (transcript: ClientHello...server Finished) and installs the server application traffic keys.
+// The client keys are switched after the client Finished has been verified.
+func deriveApplicationKeys13(conn *TCPConnection) {
+	conn.Mutex.Lock()
+	defer conn.Mutex.Unlock()
+
+	derivedSecret := deriveSecret(conn.TLS13HandshakeSecret, "derived", nil)
+	masterSecret := hkdfExtract(derivedSecret, make([]byte, sha256.Size))
+	conn.MasterSecret = masterSecret
+	logKeyScheduleStage("Master Secret", masterSecret)
+
+	transcript := conn.HandshakeMessages.Bytes()
+	conn.TLS13ClientAppSecret = deriveSecret(masterSecret, "c ap traffic", transcript)
+	serverAppSecret := deriveSecret(masterSecret, "s ap traffic", transcript)
+	logKeyScheduleStage("client_application_traffic_secret_0", conn.TLS13ClientAppSecret)
+	logKeyScheduleStage("server_application_traffic_secret_0", serverAppSecret)
+
+	conn.ServerWriteKey, conn.ServerWriteIV = trafficKeys13(serverAppSecret)
+	conn.ServerSequenceNum = 0
+	log.Printf("%s%sInstalled server application traffic keys.%s", ColorOrange, PrefixTLS, ColorReset)
+}
+
+// installClientApplicationKeys13 switches the keys for receiving to the client application traffic secret.
+func installClientApplicationKeys13(conn *TCPConnection) {
+	conn.Mutex.Lock()
+	defer conn.Mutex.Unlock()
+	conn.ClientWriteKey, conn.ClientWriteIV = trafficKeys13(conn.TLS13ClientAppSecret)
+	conn.ClientSequenceNum = 0
+	log.Printf("%s%sInstalled client application traffic keys.%s", ColorOrange, PrefixTLS, ColorReset)
+}
+
+// computeFinishedVerifyData13 calculates the verify_data of a TLS 1.3 Finished message.
+// finished_key = HKDF-Expand-Label(BaseKey, "finished", "", Hash.length)
+// verify_data = HMAC(finished_key, Transcript-Hash(Messages))
+func computeFinishedVerifyData13(baseKey []byte, messages []byte) []byte {
+	finishedKey := hkdfExpandLabel(baseKey, "finished", nil, sha256.Size)
+	transcriptHash := sha256.Sum256(messages)
+	mac := hmac.New(sha256.New, finishedKey)
+	mac.Write(transcriptHash[:])
+	return mac.Sum(nil)
+}
+
+// --- TLS 1.3 Record Protection --- RFC 8446 Section 5.2 ---
+
+// errTLS13EarlyDataSkipped is returned by decryptRecord13 for a rejected 0-RTT record that was discarded.
+var errTLS13EarlyDataSkipped = errors.New("rejected 0-RTT early data record skipped")
+
+// buildNonce13 constructs the per-record nonce: the 64-bit sequence number (left-padded to the IV length) XOR write IV.
+func buildNonce13(writeIV []byte, seqNum uint64) ([]byte, error) {
+	if len(writeIV) != aesGcmNonceLength {
+		return nil, fmt.Errorf("invalid TLS 1.3 write IV length: %d", len(writeIV))
+	}
+	nonce := make([]byte, aesGcmNonceLength)
+	binary.BigEndian.PutUint64(nonce[aesGcmNonceLength-8:], seqNum)
+	for i := range nonce {
+		nonce[i] ^= writeIV[i]
+	}
+	return nonce, nil
+}
+
+// encryptRecord13 encrypts a record payload as TLSInnerPlaintext (content + real content type).
+// It returns the encrypted payload and the outer record type (application_data once encryption is enabled).
+// ChangeCipherSpec (middlebox compatibility) and records before the ServerHello are sent in plaintext.
+func encryptRecord13(conn *TCPConnection, plaintext []byte, recordType uint8) ([]byte, uint8, error) {
+	if recordType == TLSRecordTypeChangeCipherSpec {
+		return plaintext, recordType, nil
+	}
+
+	conn.Mutex.Lock()
+	defer conn.Mutex.Unlock()
+
+	if !conn.EncryptionEnabled {
+		return plaintext, recordType, nil
+	}
+	if isDebug {
+		log.Printf("%s%sEncrypting TLS 1.3 record. Inner Type: %d, Plaintext Len: %d, SeqNum: %d%s", ColorGray, PrefixTLS, recordType, len(plaintext), conn.ServerSequenceNum, ColorReset)
+	}
+
+	aead, err := buildAEAD(conn.ServerWriteKey)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build server AEAD for encryption: %w", err)
+	}
+	nonce, err := buildNonce13(conn.ServerWriteIV, conn.ServerSequenceNum)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	innerPlaintext := append(append([]byte(nil), plaintext...), recordType) // パディングなし
+	aad := make([]byte, TLSRecordHeaderLength)
+	aad[0] = TLSRecordTypeApplicationData
+	binary.BigEndian.PutUint16(aad[1:3], 0x0303)
+	binary.BigEndian.PutUint16(aad[3:5], uint16(len(innerPlaintext)+aesGcmTagLength))
+
+	ciphertext := aead.Seal(nil, nonce, innerPlaintext, aad)
+	conn.ServerSequenceNum++
+	return ciphertext, TLSRecordTypeApplicationData, nil
+}
+
+// decryptRecord13 decrypts a TLS 1.3 record and returns the content with the real content type.
+// While rejected 0-RTT data may still arrive, records that cannot be decrypted (or application data
+// before the handshake keys are installed) are skipped and errTLS13EarlyDataSkipped is returned.
+func decryptRecord13(conn *TCPConnection, ciphertext []byte, recordHeader []byte) ([]byte, uint8, error) {
+	recordType := recordHeader[0]
+	if recordType == TLSRecordTypeChangeCipherSpec {
+		return ciphertext, recordType, nil
+	}
+
+	conn.Mutex.Lock()
+	defer conn.Mutex.Unlock()
+
+	if !conn.EncryptionEnabled {
+		if recordType == TLSRecordTypeApplicationData && conn.TLS13SkipEarlyData {
+			// HelloRetryRequest 送信後の 0-RTT データは application_data をすべて読み飛ばす
+			return nil, 0, skipEarlyData13(conn, len(ciphertext))
+		}
+		return ciphertext, recordType, nil
+	}
+	if recordType != TLSRecordTypeApplicationData {
+		return nil, 0, fmt.Errorf("unexpected unprotected record type %d after key exchange", recordType)
+	}
+
+	aead, err := buildAEAD(conn.ClientWriteKey)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build client AEAD for decryption: %w", err)
+	}
+	nonce, err := buildNonce13(conn.ClientWriteIV, conn.ClientSequenceNum)
+	if err != nil {
+		return nil, 0, err
+	}
+	innerPlaintext, err := aead.Open(nil, nonce, ciphertext, recordHeader)
+	if err != nil {
+		if conn.TLS13SkipEarlyData {
+			// 0-RTT を拒否した場合、early traffic key で暗号化されたレコードは復号できないので読み飛ばす
+			return nil, 0, skipEarlyData13(conn, len(ciphertext))
+		}
+		return nil, 0, fmt.Errorf("AEAD decryption failed: %w", err)
+	}
+	conn.ClientSequenceNum++
+	conn.TLS13SkipEarlyData = false // handshake key で復号できた時点で early data は終わり
+
+	// TLSInnerPlaintext: content + type + zero padding
+	i := len(innerPlaintext) - 1
+	for i >= 0 && innerPlaintext[i] == 0 {
+		i--
+	}
+	if i < 0 {
+		return nil, 0, errors.New("TLSInnerPlaintext has no content type")
+	}
+	if isDebug {
+		log.Printf("%s%sDecrypted TLS 1.3 record. Inner Type: %d, Plaintext Len: %d%s", ColorGray, PrefixTLS, innerPlaintext[i], i, ColorReset)
+	}
+	return innerPlaintext[:i], innerPlaintext[i], nil
+}
+
+// skipEarlyData13 accounts for a skipped 0-RTT record. The caller must hold conn.Mutex.
+func skipEarlyData13(conn *TCPConnection, length int) error {
+	conn.TLS13SkippedEarlyData += length
+	if conn.TLS13SkippedEarlyData > TLS13MaxSkippedEarlyDataBytes {
+		return fmt.Errorf("too much rejected 0-RTT data (%d bytes)", conn.TLS13SkippedEarlyData)
+	}
+	log.Printf("%s%sSkipped rejected 0-RTT record (%d bytes, total %d).%s", ColorOrange, PrefixTLS, length, conn.TLS13SkippedEarlyData, ColorReset)
+	return errTLS13EarlyDataSkipped
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/songgao/water"
+)
+
+// --- Toy DNS Server (UDP Port 53) ---
+// Answers A record queries from a static zone file. Reference: RFC 1035
+
+const (
+	DNSHeaderLengthBytes = 12
+	DNSTypeA             = 1
+	DNSClassIN           = 1
+	DNSDefaultTTL        = 60
+
+	// Header flags
+	DNSFlagQR     = 0x8000 // Response
+	DNSFlagAA     = 0x0400 // Authoritative Answer
+	DNSFlagRD     = 0x0100 // Recursion Desired
+	DNSOpcodeMask = 0x7800
+
+	// Response codes
+	DNSRcodeNoError  = 0
+	DNSRcodeFormErr  = 1
+	DNSRcodeNXDomain = 3
+	DNSRcodeNotImp   = 4
+)
+
+// DNSRecord is an A record of the zone.
+type DNSRecord struct {
+	IP  net.IP
+	TTL uint32
+}
+
+// DNSQuestion represents the question section of a DNS query.
+type DNSQuestion struct {
+	Name  string // Lower-case, without the trailing dot
+	Type  uint16
+	Class uint16
+	Raw   []byte // Wire format of the question (echoed in the response)
+}
+
+var (
+	dnsZone     map[string][]DNSRecord // Keyed by lower-case name without the trailing dot
+	dnsServerIP net.IP                 // Address the DNS server answers on (nil = disabled)
+)
+
+// loadDNSZone loads a zone file. Each line is "<name> [ttl] A <ipv4>";
+// empty lines and lines starting with '#' or ';' are ignored.
+func loadDNSZone(path string) (map[string][]DNSRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zone := make(map[string][]DNSRecord)
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		ttl := uint32(DNSDefaultTTL)
+		switch len(fields) {
+		case 3:
+		case 4:
+			v, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid TTL %q", path, lineNum, fields[1])
+			}
+			ttl = uint32(v)
+			fields = []string{fields[0], fields[2], fields[3]}
+		default:
+			return nil, fmt.Errorf("%s:%d: expected \"<name> [ttl] A <ipv4>\"", path, lineNum)
+		}
+		if !strings.EqualFold(fields[1], "A") {
+			return nil, fmt.Errorf("%s:%d: unsupported record type %q (only A is supported)", path, lineNum, fields[1])
+		}
+		ip := net.ParseIP(fields[2]).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("%s:%d: invalid IPv4 address %q", path, lineNum, fields[2])
+		}
+
+		name := normalizeDNSName(fields[0])
+		zone[name] = append(zone[name], DNSRecord{IP: ip, TTL: ttl})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// normalizeDNSName lower-cases the name and removes the trailing dot.
+func normalizeDNSName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// handleDNSQuery answers a DNS query received on UDP port 53.
+func handleDNSQuery(ifce *water.Interface, srcIP, dstIP net.IP, udpHeader *UDPHeader, query []byte) {
+	if dnsServerIP == nil || !(dstIP.Equal(dnsServerIP) || (stackIP6 != nil && dstIP.Equal(stackIP6))) {
+		log.Printf("%s%sDNS server is not listening on %s, dropping query.%s", ColorGray, PrefixDNS, dstIP, ColorReset)
+		return
+	}
+	if len(query) < DNSHeaderLengthBytes {
+		log.Printf("%s%sDNS query too short: %d bytes%s", ColorRed, PrefixError, len(query), ColorReset)
+		return
+	}
+
+	id := binary.BigEndian.Uint16(query[0:2])
+	flags := binary.BigEndian.Uint16(query[2:4])
+	qdCount := binary.BigEndian.Uint16(query[4:6])
+	if flags&DNSFlagQR != 0 {
+		// 応答パケットには応答しない (ループ防止)
+		log.Printf("%s%sIgnoring DNS response packet (ID: 0x%04x)%s", ColorGray, PrefixDNS, id, ColorReset)
+		return
+	}
+
+	var response []byte
+	switch {
+	case flags&DNSOpcodeMask != 0: // Only standard queries (opcode 0) are supported
+		log.Printf("%s%sUnsupported DNS opcode %d (ID: 0x%04x)%s", ColorYellow, PrefixWarn, (flags&DNSOpcodeMask)>>11, id, ColorReset)
+		response = buildDNSResponse(id, flags, DNSRcodeNotImp, nil, nil)
+	case qdCount != 1:
+		log.Printf("%s%sDNS query has %d questions (ID: 0x%04x)%s", ColorYellow, PrefixWarn, qdCount, id, ColorReset)
+		response = buildDNSResponse(id, flags, DNSRcodeFormErr, nil, nil)
+	default:
+		question, err := parseDNSQuestion(query[DNSHeaderLengthBytes:])
+		if err != nil {
+			log.Printf("%s%sFailed to parse DNS question (ID: 0x%04x): %v%s", ColorRed, PrefixError, id, err, ColorReset)
+			response = buildDNSResponse(id, flags, DNSRcodeFormErr, nil, nil)
+			break
+		}
+		log.Printf("%s%sQuery: %s type %d class %d (ID: 0x%04x) from %s:%d%s",
+			ColorGreen, PrefixDNS, question.Name, question.Type, question.Class, id, srcIP, udpHeader.SrcPort, ColorReset)
+		pauseIfNeeded("dns")
+
+		records, found := dnsZone[question.Name]
+		switch {
+		case !found:
+			log.Printf("%s%sAnswer: %s NXDOMAIN%s", ColorGreen, PrefixDNS, question.Name, ColorReset)
+			response = buildDNSResponse(id, flags, DNSRcodeNXDomain, question, nil)
+		case question.Type != DNSTypeA || question.Class != DNSClassIN:
+			// 名前は存在するが A/IN 以外のレコードはない (NODATA)
+			log.Printf("%s%sAnswer: %s has no type %d records%s", ColorGreen, PrefixDNS, question.Name, question.Type, ColorReset)
+			response = buildDNSResponse(id, flags, DNSRcodeNoError, question, nil)
+		default:
+			for _, record := range records {
+				log.Printf("%s%sAnswer: %s A %s (TTL %d)%s", ColorGreen, PrefixDNS, question.Name, record.IP, record.TTL, ColorReset)
+			}
+			response = buildDNSResponse(id, flags, DNSRcodeNoError, question, records)
+		}
+	}
+
+	// Swap source and destination for the response
+	err := sendUDPPacket(ifce, dstIP, srcIP, udpHeader.DstPort, udpHeader.SrcPort, response)
+	if err != nil {
+		log.Printf("%s%sFailed to send DNS response: %v%s", ColorRed, PrefixError, err, ColorReset)
+	}
+}
+
+// parseDNSQuestion parses the first question of a DNS query.
+// Compression pointers are not allowed in the question of a query.
+func parseDNSQuestion(data []byte) (*DNSQuestion, error) {
+	var labels []string
+	offset := 0
+	for {
+		if offset >= len(data) {
+			return nil, fmt.Errorf("question name is truncated")
+		}
+		labelLen := int(data[offset])
+		offset++
+		if labelLen == 0 {
+			break
+		}
+		if labelLen&0xC0 != 0 {
+			return nil, fmt.Errorf("compressed or extended label in question is not supported")
+		}
+		if offset+labelLen > len(data) {
+			return nil, fmt.Errorf("label exceeds question length")
+		}
+		labels = append(labels, string(data[offset:offset+labelLen]))
+		offset += labelLen
+	}
+	if offset+4 > len(data) {
+		return nil, fmt.Errorf("question type/class is truncated")
+	}
+
+	question := &DNSQuestion{
+		Name:  normalizeDNSName(strings.Join(labels, ".")),
+		Type:  binary.BigEndian.Uint16(data[offset : offset+2]),
+		Class: binary.BigEndian.Uint16(data[offset+2 : offset+4]),
+		Raw:   data[:offset+4],
+	}
+	return question, nil
+}
+
+// buildDNSResponse builds a DNS response with the question (if any) and an A record answer per record.
+func buildDNSResponse(id, queryFlags uint16, rcode uint16, question *DNSQuestion, records []DNSRecord) []byte {
+	// QR=1, AA=1, Opcode と RD はクエリからコピー。再帰問い合わせはしないので RA=0
+	flags := DNSFlagQR | DNSFlagAA | (queryFlags & (DNSOpcodeMask | DNSFlagRD)) | rcode
+
+	header := make([]byte, DNSHeaderLengthBytes)
+	binary.BigEndian.PutUint16(header[0:2], id)
+	binary.BigEndian.PutUint16(header[2:4], flags)
+	if question != nil {
+		binary.BigEndian.PutUint16(header[4:6], 1) // QDCOUNT
+	}
+	binary.BigEndian.PutUint16(header[6:8], uint16(len(records))) // ANCOUNT
+	// NSCOUNT, ARCOUNT = 0
+
+	response := header
+	if question == nil {
+		return response
+	}
+	response = append(response, question.Raw...)
+
+	for _, record := range records {
+		answer := make([]byte, 16)
+		binary.BigEndian.PutUint16(answer[0:2], 0xC000|DNSHeaderLengthBytes) // Name: pointer to the question name
+		binary.BigEndian.PutUint16(answer[2:4], DNSTypeA)
+		binary.BigEndian.PutUint16(answer[4:6], DNSClassIN)
+		binary.BigEndian.PutUint32(answer[6:10], record.TTL)
+		binary.BigEndian.PutUint16(answer[10:12], 4) // RDLENGTH
+		copy(answer[12:16], record.IP.To4())
+		response = append(response, answer...)
+	}
+	return response
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/songgao/water"
+)
+
+// EthernetHeader represents the Ethernet II frame header.
+// Reference: IEEE 802.3
+type EthernetHeader struct {
+	DstMAC    net.HardwareAddr // Destination MAC Address (6 bytes)
+	SrcMAC    net.HardwareAddr // Source MAC Address (6 bytes)
+	EtherType uint16           // EtherType (e.g., 0x0800 for IPv4, 0x0806 for ARP)
+}
+
+const (
+	EthernetHeaderLengthBytes = 14
+	EtherTypeIPv4             = 0x0800
+	EtherTypeARP              = 0x0806
+	EtherTypeIPv6             = 0x86DD
+)
+
+var (
+	// stackMAC is the MAC address of the userspace stack in TAP mode
+	stackMAC net.HardwareAddr
+	// stackIP is the IPv4 address the userspace stack answers ARP requests for in TAP mode
+	stackIP net.IP
+
+	broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+)
+
+// parseEthernetFrame parses the Ethernet header and returns it with the frame payload.
+func parseEthernetFrame(frame []byte) (*EthernetHeader, []byte, error) {
+	if len(frame) < EthernetHeaderLengthBytes {
+		return nil, nil, fmt.Errorf("Ethernet frame too short: %d bytes", len(frame))
+	}
+	header := &EthernetHeader{
+		DstMAC:    net.HardwareAddr(frame[0:6]),
+		SrcMAC:    net.HardwareAddr(frame[6:12]),
+		EtherType: binary.BigEndian.Uint16(frame[12:14]),
+	}
+	return header, frame[EthernetHeaderLengthBytes:], nil
+}
+
+// buildEthernetFrame creates an Ethernet frame byte slice with the payload.
+func buildEthernetFrame(dstMAC, srcMAC net.HardwareAddr, etherType uint16, payload []byte) []byte {
+	frame := make([]byte, EthernetHeaderLengthBytes, EthernetHeaderLengthBytes+len(payload))
+	copy(frame[0:6], dstMAC)
+	copy(frame[6:12], srcMAC)
+	binary.BigEndian.PutUint16(frame[12:14], etherType)
+	return append(frame, payload...)
+}
+
+// etherTypeToString converts an EtherType to a readable string.
+func etherTypeToString(etherType uint16) string {
+	switch etherType {
+	case EtherTypeIPv4:
+		return "IPv4"
+	case EtherTypeARP:
+		return "ARP"
+	case EtherTypeIPv6:
+		return "IPv6"
+	default:
+		return fmt.Sprintf("Unknown (0x%04x)", etherType)
+	}
+}
+
+// writeIPPacket writes an IP packet to the interface.
+// In TAP mode the packet is wrapped in an Ethernet frame addressed to the MAC address of the
+// destination resolved via ARP (the packet is queued until the ARP reply arrives if needed).
+// It returns the number of bytes of the IP packet written.
+func writeIPPacket(ifce *water.Interface, packet []byte) (int, error) {
+	if !ifce.IsTAP() {
+		n, err := ifce.Write(packet)
+		if err == nil {
+			capturePacket(packet, true)
+		}
+		return n, err
+	}
+	if len(packet) < IPv4HeaderMinLengthBytes {
+		return 0, fmt.Errorf("IP packet too short to send: %d bytes", len(packet))
+	}
+
+	etherType := uint16(EtherTypeIPv4)
+	dstIP := net.IP(packet[16:20])
+	if packet[0]>>4 == IPv6Version {
+		if len(packet) < IPv6HeaderLengthBytes {
+			return 0, fmt.Errorf("IPv6 packet too short to send: %d bytes", len(packet))
+		}
+		etherType = EtherTypeIPv6
+		dstIP = net.IP(packet[24:40])
+	}
+
+	var dstMAC net.HardwareAddr
+	if etherType == EtherTypeIPv6 && dstIP.IsMulticast() {
+		dstMAC = ipv6MulticastMAC(dstIP)
+	} else {
+		var ok bool
+		dstMAC, ok = arpCache.Lookup(dstIP)
+		if !ok {
+			// 宛先MACアドレスが未解決の場合はARP (IPv6はNDP) で解決し、応答が来たら送信する
+			arpCache.Enqueue(dstIP, packet)
+			var err error
+			if etherType == EtherTypeIPv6 {
+				err = sendNeighborSolicitation(ifce, dstIP)
+			} else {
+				err = sendARPRequest(ifce, dstIP)
+			}
+			if err != nil {
+				return 0, fmt.Errorf("failed to resolve MAC address of %s: %w", dstIP, err)
+			}
+			return len(packet), nil
+		}
+	}
+
+	if err := sendEthernetFrame(ifce, dstMAC, etherType, packet); err != nil {
+		return 0, err
+	}
+	return len(packet), nil
+}
+
+// sendEthernetFrame builds and writes an Ethernet frame from the stack's MAC address.
+func sendEthernetFrame(ifce *water.Interface, dstMAC net.HardwareAddr, etherType uint16, payload []byte) error {
+	frame := buildEthernetFrame(dstMAC, stackMAC, etherType, payload)
+	if isDebug {
+		log.Printf("%s%sSND: %s -> %s Type: %s Len: %d%s", ColorTeal, PrefixEth, stackMAC, dstMAC, etherTypeToString(etherType), len(frame), ColorReset)
+	}
+	n, err := ifce.Write(frame)
+	if err != nil {
+		return fmt.Errorf("failed to write Ethernet frame to TAP device: %w", err)
+	}
+	capturePacket(frame, true)
+	if n != len(frame) {
+		return fmt.Errorf("short write to TAP device: wrote %d bytes, expected %d", n, len(frame))
+	}
+	return nil
+}
+
+// processFrames reads Ethernet frames from the TAP device and dispatches them by EtherType.
+func processFrames(ifce *water.Interface) {
+	frame := make([]byte, *mtu+EthernetHeaderLengthBytes)
+	for {
+		n, err := ifce.Read(frame)
+		if err != nil {
+			if opErr, ok := err.(*net.OpError); ok && opErr.Err.Error() == "file already closed" {
+				log.Println("TAP interface closed, stopping frame processing.")
+				break
+			}
+			if err.Error() == "invalid argument" {
+				log.Println("TAP interface error (invalid argument), stopping frame processing.")
+				break
+			}
+			log.Printf("Error reading from TAP device: %v", err)
+			continue
+		}
+		// 他ホスト宛てのフレームも含め、読み込んだフレームはすべてキャプチャする
+		capturePacket(frame[:n], false)
+
+		ethHeader, payload, err := parseEthernetFrame(frame[:n])
+		if err != nil {
+			log.Printf("%s%sError parsing Ethernet frame: %v%s", ColorRed, PrefixError, err, ColorReset)
+			continue
+		}
+
+		// 自分宛てとブロードキャスト (IPv6有効時はIPv6マルチキャスト) 以外のフレームは無視する
+		// (ブリッジ時に他ホスト宛てのフレームも届くため)
+		isIPv6Multicast := stackIP6 != nil && ethHeader.DstMAC[0] == 0x33 && ethHeader.DstMAC[1] == 0x33
+		if !macEqual(ethHeader.DstMAC, stackMAC) && !macEqual(ethHeader.DstMAC, broadcastMAC) && !isIPv6Multicast {
+			continue
+		}
+
+		log.Printf("%s%sRCV: %s -> %s Type: %s Len: %d%s", ColorTeal, PrefixEth, ethHeader.SrcMAC, ethHeader.DstMAC, etherTypeToString(ethHeader.EtherType), n, ColorReset)
+		pauseIfNeeded("eth")
+
+		switch ethHeader.EtherType {
+		case EtherTypeARP:
+			handleARPPacket(ifce, ethHeader, payload)
+		case EtherTypeIPv4:
+			ipHeader, ipPayload, err := parseIPv4Header(payload)
+			if err != nil {
+				log.Printf("Error parsing IPv4 header: %v (Packet length: %d)", err, len(payload))
+				continue
+			}
+			// 送信元のMACアドレスを学習しておき、応答時のARPを省く
+			learnMAC(ifce, ipHeader.SrcIP, ethHeader.SrcMAC)
+
+			printHeaderInfo(ipHeader, len(payload))
+			dispatchIPPacket(ifce, ipHeader, ipPayload)
+		case EtherTypeIPv6:
+			ipHeader, ipPayload, err := parseIPv6Header(payload)
+			if err != nil {
+				log.Printf("Error parsing IPv6 header: %v (Packet length: %d)", err, len(payload))
+				continue
+			}
+			// リンク層アドレスはNDPで学習する (IPv4と違い送信元MACからは学習しない)
+			dispatchIPv6Packet(ifce, ipHeader, ipPayload, len(payload))
+		default:
+			// Other EtherTypes are not handled
+		}
+	}
+}
+
+// macEqual reports whether the two MAC addresses are the same.
+func macEqual(a, b net.HardwareAddr) bool {
+	return a.String() == b.String()
+}
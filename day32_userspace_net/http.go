@@ -4,15 +4,60 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
 	"strings"
-
-	"github.com/songgao/water"
+	"time"
 )
 
 // --- HTTP Handling (Port 80) / Also used for HTTPS after handshake ---
 
-// parseHTTPRequest parses a simple HTTP/1.x request.
+const (
+	// Maximum number of requests served on a persistent connection before it is closed
+	HTTPMaxKeepAliveRequests = 100
+	// Maximum size of a buffered request (headers + body)
+	HTTPMaxRequestBytes = 64 * 1024
+)
+
+// HTTPRequest represents a parsed HTTP/1.x request.
+type HTTPRequest struct {
+	Method  string
+	URI     string
+	Path    string // URI without the query string
+	Version string
+	Headers map[string]string // Keyed by canonical header name (e.g. "Content-Length")
+	Body    []byte            // Decoded body (Content-Length or chunked)
+}
+
+// HTTPResponse represents an HTTP/1.x response produced by a route handler.
+type HTTPResponse struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       []byte
+	Chunks     [][]byte // If set, the body is sent with Transfer-Encoding: chunked (one chunk per element)
+}
+
+// httpHandlerFunc handles a request routed to a path.
+type httpHandlerFunc func(conn *TCPConnection, req *HTTPRequest) *HTTPResponse
+
+// httpRoutes is the routing table of the userspace HTTP/1.1 server, keyed by path.
+var httpRoutes map[string]httpHandlerFunc
+
+func init() {
+	httpRoutes = map[string]httpHandlerFunc{
+		"/":        handleHTTPIndex,
+		"/echo":    handleHTTPEcho,
+		"/chunked": handleHTTPChunked,
+		"/conn":    handleHTTPConnInfo,
+	}
+}
+
+// parseHTTPRequest parses the request line and headers of a simple HTTP/1.x request.
+// Header names are canonicalized (e.g. "content-length" -> "Content-Length").
 func parseHTTPRequest(payload []byte) (method, uri, version string, headers map[string]string, err error) {
 	headers = make(map[string]string)
 	reader := bufio.NewReader(bytes.NewReader(payload))
@@ -51,210 +96,449 @@ func parseHTTPRequest(payload []byte) (method, uri, version string, headers map[
 			log.Printf("Skipping malformed header line: %q", line)
 			continue
 		}
-		headerName := strings.TrimSpace(headerParts[0])
+		headerName := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(headerParts[0]))
 		headerValue := strings.TrimSpace(headerParts[1])
 		headers[headerName] = headerValue
 	}
 	return
 }
 
-// handleHTTPData handles received HTTP data for a connection.
-func handleHTTPData(ifce *water.Interface, conn *TCPConnection, payload []byte) {
-	log.Printf("Handling HTTP data (%d bytes) for %s (Port: %d)", len(payload), conn.ConnectionKey(), conn.ServerPort)
-
-	// Parse the HTTP request from the payload.
-	method, uri, _, headers, err := parseHTTPRequest(payload)
-	if err != nil {
-		log.Printf("Failed to parse HTTP request for %s: %v", conn.ConnectionKey(), err)
-		// TODO: Send HTTP 400 Bad Request response.
-		//       For port 443, this needs to be wrapped in a TLS record.
-		//       Consider closing the connection after sending the error.
-		return
+// readHTTPRequest reads one complete request from the buffer and consumes it.
+// It returns nil without consuming anything if the request is not complete yet,
+// so the remaining bytes of a pipelined request stay in the buffer.
+func readHTTPRequest(buf *bytes.Buffer) (*HTTPRequest, error) {
+	data := buf.Bytes()
+	headerEnd := bytes.Index(data, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		if len(data) > HTTPMaxRequestBytes {
+			return nil, fmt.Errorf("request headers exceed %d bytes", HTTPMaxRequestBytes)
+		}
+		return nil, nil // Need more data
 	}
+	headerLen := headerEnd + 4
 
-	log.Printf("HTTP Request Parsed: [%s %s ...] from %s:%d", method, uri, conn.ClientIP, conn.ClientPort)
-	for k, v := range headers {
-		log.Printf("  HTTP Header: %s: %s", k, v)
+	method, uri, version, headers, err := parseHTTPRequest(data[:headerLen])
+	if err != nil {
+		return nil, err
 	}
 
-	// --- Construct the HTTP 200 OK response ---
-	var responseText string
-	if conn.ServerPort == 443 {
-		responseText = "<html><body><h1>Hello from userspace HTTPS/1.1! (Port 443)</h1></body></html>"
-	} else {
-		responseText = "<html><body><h1>Hello from userspace HTTP/1.1! (Port 80)</h1></body></html>"
+	var body []byte
+	bodyLen := 0
+	if te, ok := headers["Transfer-Encoding"]; ok {
+		if !strings.EqualFold(strings.TrimSpace(te), "chunked") {
+			return nil, fmt.Errorf("unsupported Transfer-Encoding: %s", te)
+		}
+		var complete bool
+		body, bodyLen, complete, err = decodeChunkedBody(data[headerLen:])
+		if err != nil {
+			return nil, err
+		}
+		if !complete {
+			if len(data) > HTTPMaxRequestBytes {
+				return nil, fmt.Errorf("chunked request exceeds %d bytes", HTTPMaxRequestBytes)
+			}
+			return nil, nil // Need more data
+		}
+	} else if cl, ok := headers["Content-Length"]; ok {
+		contentLength, err := strconv.Atoi(cl)
+		if err != nil || contentLength < 0 {
+			return nil, fmt.Errorf("invalid Content-Length: %q", cl)
+		}
+		if headerLen+contentLength > HTTPMaxRequestBytes {
+			return nil, fmt.Errorf("request body of %d bytes exceeds %d bytes", contentLength, HTTPMaxRequestBytes)
+		}
+		if len(data)-headerLen < contentLength {
+			return nil, nil // Need more data
+		}
+		bodyLen = contentLength
+		body = append([]byte(nil), data[headerLen:headerLen+contentLength]...)
 	}
 
-	body := []byte(responseText)
-	responseHeaders := map[string]string{
-		"Content-Type":   "text/html; charset=utf-8",
-		"Content-Length": fmt.Sprintf("%d", len(body)),
-		// "Connection":     "close", // Let the client decide or rely on TLS close_notify
+	req := &HTTPRequest{
+		Method:  method,
+		URI:     uri,
+		Path:    uri,
+		Version: version,
+		Headers: headers,
+		Body:    body,
 	}
-	statusLine := "HTTP/1.1 200 OK"
-
-	var respBuilder strings.Builder
-	respBuilder.WriteString(statusLine + "\r\n")
-	for k, v := range responseHeaders {
-		respBuilder.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		req.Path = uri[:i]
 	}
-	respBuilder.WriteString("\r\n")
-	respBuilder.Write(body) // Write body bytes directly
-	httpRespBytes := []byte(respBuilder.String())
 
-	// --- Send the response based on the connection type/port and TLS state ---
-	conn.Mutex.Lock() // Lock to check mode and TLS state safely
-	isTCPMode := conn.TCPConn != nil
-	isTunMode := conn.TunIFCE != nil
-	isTLSEnabled := conn.EncryptionEnabled // Check if handshake is complete and encryption is on
-	serverPort := conn.ServerPort          // Get port for logging/context
-	serverIP := conn.ServerIP
-	clientIP := conn.ClientIP
-	clientPort := conn.ClientPort
-	// currentTCPState := conn.State // Get current state before potential change // Removed as state change logic is moved inside TUN block
-	conn.Mutex.Unlock() // Unlock before potentially blocking send operations
+	buf.Next(headerLen + bodyLen) // Consume the request
+	return req, nil
+}
 
-	connKey := fmt.Sprintf("%s:%d-%s:%d", clientIP, clientPort, serverIP, serverPort) // Reconstruct key outside lock
+// decodeChunkedBody decodes a chunked request body.
+// It returns the decoded body and the number of bytes consumed, or complete=false if the
+// terminating chunk (and trailer section) has not been received yet.
+func decodeChunkedBody(data []byte) (body []byte, consumed int, complete bool, err error) {
+	offset := 0
+	for {
+		lineEnd := bytes.Index(data[offset:], []byte("\r\n"))
+		if lineEnd < 0 {
+			return nil, 0, false, nil
+		}
+		sizeStr := string(data[offset : offset+lineEnd])
+		if i := strings.IndexByte(sizeStr, ';'); i >= 0 {
+			sizeStr = sizeStr[:i] // Ignore chunk extensions
+		}
+		size, errParse := strconv.ParseInt(strings.TrimSpace(sizeStr), 16, 32)
+		if errParse != nil || size < 0 {
+			return nil, 0, false, fmt.Errorf("invalid chunk size: %q", sizeStr)
+		}
+		offset += lineEnd + 2
 
-	if isTCPMode {
-		if isTLSEnabled { // Send via TLS if enabled
-			// TCP Mode with TLS: Send response via TLS Application Data record
-			log.Printf("[TLS AppData Send - %s - TCP Mode] Sending HTTP response (%d bytes) as Application Data.", connKey, len(httpRespBytes))
-			appDataRecord, err := buildTLSRecord(TLSRecordTypeApplicationData, 0x0303, httpRespBytes)
-			if err != nil {
-				log.Printf("[TLS Error - %s - TCP Mode] Failed to build Application Data record: %v", connKey, err)
-				return
-			}
-			// Pass nil for ifce in TCP mode. sendRawTLSRecord handles the mode check.
-			_, err = sendRawTLSRecord(nil, conn, appDataRecord)
-			if err != nil {
-				log.Printf("[TLS Error - %s - TCP Mode] Failed to send Application Data record: %v", connKey, err)
-			}
-		} else {
-			// TCP Mode without TLS: Send raw via net.Conn
-			log.Printf("[HTTP Info - %s - TCP Mode] Sending HTTP response (%d bytes) via raw net.Conn.", connKey, len(httpRespBytes))
-			// Re-lock to access conn.TCPConn safely
-			conn.Mutex.Lock()
-			tcpConn := conn.TCPConn
-			conn.Mutex.Unlock()
-			if tcpConn != nil {
-				_, err := tcpConn.Write(httpRespBytes) // Write directly
-				if err != nil {
-					log.Printf("[HTTP Error - %s - TCP Mode] Error writing raw HTTP response: %v", connKey, err)
+		if size == 0 {
+			// Skip the trailer section (ends with an empty line)
+			for {
+				trailerEnd := bytes.Index(data[offset:], []byte("\r\n"))
+				if trailerEnd < 0 {
+					return nil, 0, false, nil
+				}
+				offset += trailerEnd + 2
+				if trailerEnd == 0 {
+					return body, offset, true, nil
 				}
-				// Optionally close connection here or rely on client closing for HTTP/1.1
-			} else {
-				log.Printf("[HTTP Error - %s - TCP Mode] TCPConn is nil when trying to write raw response.", connKey)
 			}
 		}
-	} else if isTunMode {
-		// TUN Mode logic needs similar check for isTLSEnabled
-		tunIFCE := ifce // Get ifce from argument
-		if isTLSEnabled {
-			// TUN Mode with TLS: Send response via TLS Application Data record
-			log.Printf("[TLS AppData Send - %s - TUN Mode] Sending HTTP response (%d bytes) as Application Data.", connKey, len(httpRespBytes))
-			appDataRecord, err := buildTLSRecord(TLSRecordTypeApplicationData, 0x0303, httpRespBytes)
-			if err != nil {
-				log.Printf("[TLS Error - %s - TUN Mode] Failed to build Application Data record: %v", connKey, err)
-				return
+
+		if len(data)-offset < int(size)+2 {
+			return nil, 0, false, nil
+		}
+		if !bytes.Equal(data[offset+int(size):offset+int(size)+2], []byte("\r\n")) {
+			return nil, 0, false, fmt.Errorf("chunk of %d bytes is not terminated by CRLF", size)
+		}
+		body = append(body, data[offset:offset+int(size)]...)
+		offset += int(size) + 2
+	}
+}
+
+// handleHTTPData handles received HTTP data for a connection.
+// Data is buffered until a complete request is received, and every complete request in the
+// buffer is answered in order (pipelining). The connection stays open unless the client asks
+// to close it (Connection: close / HTTP/1.0) or HTTPMaxKeepAliveRequests is reached.
+func handleHTTPData(conn *TCPConnection, payload []byte) {
+	connKey := conn.ConnectionKey()
+	log.Printf("%s%s Handling HTTP data (%d bytes) for %s (Port: %d)%s", ColorWhite, PrefixHTTP, len(payload), connKey, conn.ServerPort, ColorReset)
+
+	conn.Mutex.Lock()
+	if conn.HTTPClosing {
+		conn.Mutex.Unlock()
+		log.Printf("%s%s Connection %s is closing, ignoring %d bytes.%s", ColorGray, PrefixHTTP, connKey, len(payload), ColorReset)
+		return
+	}
+	conn.HTTPReceiveBuffer.Write(payload)
+	conn.Mutex.Unlock()
+
+	for {
+		conn.Mutex.Lock()
+		req, err := readHTTPRequest(&conn.HTTPReceiveBuffer)
+		buffered := conn.HTTPReceiveBuffer.Len()
+		conn.Mutex.Unlock()
+
+		if err != nil {
+			log.Printf("%s%sFailed to parse HTTP request for %s: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
+			resp := textHTTPResponse(http.StatusBadRequest, "Bad Request\n")
+			if err := sendHTTPResponse(conn, buildHttpResponse(nil, resp, false, 0)); err != nil {
+				log.Printf("%s%sFailed to send HTTP 400 response for %s: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
 			}
-			// Pass non-nil ifce. sendRawTLSRecord handles the mode check.
-			sentBytes, err := sendRawTLSRecord(tunIFCE, conn, appDataRecord)
-			if err != nil {
-				log.Printf("[TLS Error - %s - TUN Mode] Failed to send Application Data record: %v", connKey, err)
-			} else {
-				// Update sequence number after successful send in TUN mode
-				conn.Mutex.Lock()
-				conn.ServerNextSeq += uint32(sentBytes)
-				log.Printf("[SeqNum Update - %s] After HTTP AppData: ServerNextSeq = %d (added %d)", connKey, conn.ServerNextSeq, sentBytes)
-				conn.Mutex.Unlock()
+			closeHTTPConnection(conn)
+			return
+		}
+		if req == nil {
+			if buffered > 0 {
+				log.Printf("%s%s Partial HTTP request buffered (%d bytes) for %s. Waiting for more data.%s", ColorGray, PrefixHTTP, buffered, connKey, ColorReset)
 			}
-		} else {
-			// TUN Mode without TLS (e.g., Port 80): Send raw TCP packets
-			log.Printf("TUN Mode: Sending raw HTTP response (%d bytes) directly via TCP packet.", len(httpRespBytes))
-			// Send raw HTTP response without TLS
-			flags := uint8(TCPFlagPSH | TCPFlagACK)
-			// MODIFIED: Assign sentBytes to _ and update sequence number
-			sentBytes, err := sendTCPPacket(tunIFCE, conn.ServerIP, conn.ClientIP, uint16(conn.ServerPort), uint16(conn.ClientPort),
-				conn.ServerNextSeq, conn.ClientNextSeq, flags, httpRespBytes)
-			if err != nil {
-				log.Printf("[HTTP Error - %s - TUN Mode, No TLS] Failed to send raw HTTP response packet: %v", connKey, err)
-			} else {
-				// Update sequence number after successful send
-				conn.Mutex.Lock()
-				conn.ServerNextSeq += uint32(sentBytes)
-				log.Printf("[SeqNum Update - %s] After Raw HTTP: ServerNextSeq = %d (added %d)", connKey, conn.ServerNextSeq, sentBytes)
-				conn.Mutex.Unlock()
+			return
+		}
+
+		conn.Mutex.Lock()
+		conn.HTTPRequestCount++
+		requestNum := conn.HTTPRequestCount
+		conn.Mutex.Unlock()
+
+		log.Printf("%s%s Request #%d: [%s %s %s] Body: %d bytes from %s:%d%s", ColorWhite, PrefixHTTP, requestNum, req.Method, req.URI, req.Version, len(req.Body), conn.ClientIP, conn.ClientPort, ColorReset)
+		if isDebug {
+			for k, v := range req.Headers {
+				log.Printf("%s%s   Header: %s: %s%s", ColorGray, PrefixHTTP, k, v, ColorReset)
 			}
 		}
+		pauseIfNeeded("http")
+
+		keepAlive := httpKeepAlive(req) && requestNum < HTTPMaxKeepAliveRequests
+		resp := routeHTTPRequest(conn, req)
+		log.Printf("%s%s Response #%d: %d %s (keep-alive: %v)%s", ColorWhite, PrefixHTTP, requestNum, resp.StatusCode, http.StatusText(resp.StatusCode), keepAlive, ColorReset)
+
+		respBytes := buildHttpResponse(req, resp, keepAlive, HTTPMaxKeepAliveRequests-requestNum)
+		if err := sendHTTPResponse(conn, respBytes); err != nil {
+			log.Printf("%s%sFailed to send HTTP response for %s: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
+			return
+		}
+
+		if !keepAlive {
+			closeHTTPConnection(conn)
+			return
+		}
+	}
+}
+
+// httpKeepAlive reports whether the connection should stay open after answering the request.
+// HTTP/1.1 connections are persistent by default, HTTP/1.0 ones only with "Connection: keep-alive".
+func httpKeepAlive(req *HTTPRequest) bool {
+	connection := strings.ToLower(req.Headers["Connection"])
+	if req.Version == "HTTP/1.0" {
+		return strings.Contains(connection, "keep-alive")
+	}
+	return !strings.Contains(connection, "close")
+}
+
+// routeHTTPRequest dispatches the request to the handler registered for its path.
+func routeHTTPRequest(conn *TCPConnection, req *HTTPRequest) *HTTPResponse {
+	handler, ok := httpRoutes[req.Path]
+	if !ok {
+		return textHTTPResponse(http.StatusNotFound, fmt.Sprintf("Not Found: %s\n", req.Path))
+	}
+	return handler(conn, req)
+}
+
+// --- Route Handlers ---
+
+func handleHTTPIndex(conn *TCPConnection, req *HTTPRequest) *HTTPResponse {
+	if resp := allowHTTPMethods(req, "GET", "HEAD"); resp != nil {
+		return resp
+	}
+
+	var responseText string
+	if conn.ServerPort == 443 {
+		responseText = "<html><body><h1>Hello from userspace HTTPS/1.1! (Port 443)</h1>"
 	} else {
-		log.Printf("[Error - %s] handleHTTPData called with connection in invalid state (no TCPConn or TunIFCE)", connKey)
+		responseText = "<html><body><h1>Hello from userspace HTTP/1.1! (Port 80)</h1>"
 	}
 
-	// Potentially Close Connection (e.g., HTTP/1.0 or Connection: close header)
-	// Modified logic: Initiate FIN sequence for non-TLS HTTP connection closure.
-	if !isTLSEnabled {
-		log.Printf("Initiating close sequence for %s after non-TLS HTTP response.", connKey)
+	paths := make([]string, 0, len(httpRoutes))
+	for path := range httpRoutes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	responseText += "<ul>"
+	for _, path := range paths {
+		responseText += fmt.Sprintf("<li><a href=\"%s\">%s</a></li>", path, path)
+	}
+	responseText += "</ul></body></html>"
 
-		conn.Mutex.Lock()
-		if conn.State == TCPStateEstablished { // Only initiate close if still established
-			conn.State = TCPStateFinWait1
-			log.Printf("Connection state for %s changed to %v", connKey, conn.State)
-			// Capture necessary values before unlocking
-			serverIP := conn.ServerIP
-			clientIP := conn.ClientIP
-			serverPort := conn.ServerPort
-			clientPort := conn.ClientPort
-			serverNextSeq := conn.ServerNextSeq
-			clientNextSeq := conn.ClientNextSeq
-			tunIFCE := conn.TunIFCE // Capture TunIFCE if needed for sendTCPPacket
-			conn.Mutex.Unlock()
-
-			// Send FIN+ACK packet
-			flags := uint8(TCPFlagFIN | TCPFlagACK)
-			sentBytes, err := sendTCPPacket(tunIFCE, serverIP, clientIP, uint16(serverPort), uint16(clientPort),
-				serverNextSeq, clientNextSeq, flags, nil)
-
-			if err != nil {
-				log.Printf("[TCP Close Error - %s] Failed to send FIN+ACK packet: %v", connKey, err)
-				// If sending FIN fails, we might need to reconsider just deleting the state
-				conn.Mutex.Lock()
-				delete(tcpConnections, connKey) // Fallback to delete if send fails?
-				conn.Mutex.Unlock()
-			} else {
-				// Update sequence number for the sent FIN
-				conn.Mutex.Lock()
-				if conn.State == TCPStateFinWait1 { // Check state again before incrementing
-					conn.ServerNextSeq += uint32(sentBytes) // FIN counts as 1 seq num if no payload
-					// If sentBytes is payload length, we need +1 for FIN
-					// Let's assume sendTCPPacket returns payload length, so we add 1 for FIN
-					if sentBytes == 0 { // If no payload, FIN is 1 byte
-						conn.ServerNextSeq++
-					}
-					log.Printf("[SeqNum Update - %s] After FIN+ACK: ServerNextSeq = %d (FIN sent)", connKey, conn.ServerNextSeq)
-				}
-				conn.Mutex.Unlock()
-				// Now wait for client's ACK and potentially FIN in handleTCPPacket
-			}
-		} else {
-			// Connection wasn't established anymore, maybe already closing? Unlock.
-			log.Printf("Skipping FIN initiation for %s as state is %v", connKey, conn.State)
-			conn.Mutex.Unlock()
+	return &HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "text/html; charset=utf-8"},
+		Body:       []byte(responseText),
+	}
+}
+
+// handleHTTPEcho returns the request body (sent with Content-Length or chunked).
+func handleHTTPEcho(conn *TCPConnection, req *HTTPRequest) *HTTPResponse {
+	if resp := allowHTTPMethods(req, "POST", "PUT"); resp != nil {
+		return resp
+	}
+
+	contentType := req.Headers["Content-Type"]
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": contentType},
+		Body:       req.Body,
+	}
+}
+
+// handleHTTPChunked returns a response with Transfer-Encoding: chunked.
+func handleHTTPChunked(conn *TCPConnection, req *HTTPRequest) *HTTPResponse {
+	if resp := allowHTTPMethods(req, "GET", "HEAD"); resp != nil {
+		return resp
+	}
+
+	var chunks [][]byte
+	for i := 1; i <= 3; i++ {
+		chunks = append(chunks, []byte(fmt.Sprintf("chunk %d from the userspace stack\n", i)))
+	}
+	return &HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Chunks:     chunks,
+	}
+}
+
+// handleHTTPConnInfo shows how many requests have been served on the current connection.
+func handleHTTPConnInfo(conn *TCPConnection, req *HTTPRequest) *HTTPResponse {
+	if resp := allowHTTPMethods(req, "GET", "HEAD"); resp != nil {
+		return resp
+	}
+
+	connKey := conn.ConnectionKey()
+	conn.Mutex.Lock()
+	body := fmt.Sprintf("connection: %s\nrequests: %d\ntls: %v\nalpn: %q\n",
+		connKey, conn.HTTPRequestCount, conn.EncryptionEnabled, conn.NegotiatedProtocol)
+	conn.Mutex.Unlock()
+
+	return textHTTPResponse(http.StatusOK, body)
+}
+
+// allowHTTPMethods returns a 405 response if the request method is not one of the allowed methods.
+func allowHTTPMethods(req *HTTPRequest, methods ...string) *HTTPResponse {
+	for _, method := range methods {
+		if req.Method == method {
+			return nil
 		}
 	}
+	resp := textHTTPResponse(http.StatusMethodNotAllowed, "Method Not Allowed\n")
+	resp.Headers["Allow"] = strings.Join(methods, ", ")
+	return resp
 }
 
-// buildHttpResponse constructs a basic HTTP response.
-func buildHttpResponse(statusCode int, statusText string, body string) []byte {
+// textHTTPResponse builds a plain text response.
+func textHTTPResponse(statusCode int, body string) *HTTPResponse {
+	return &HTTPResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		Body:       []byte(body),
+	}
+}
+
+// buildHttpResponse serializes the response for the request (req is nil if the request could not be parsed).
+// Chunked responses fall back to Content-Length for HTTP/1.0 clients, and HEAD responses have no body.
+func buildHttpResponse(req *HTTPRequest, resp *HTTPResponse, keepAlive bool, remainingRequests int) []byte {
+	headers := make(map[string]string, len(resp.Headers)+3)
+	for k, v := range resp.Headers {
+		headers[k] = v
+	}
+
+	chunked := resp.Chunks != nil && (req == nil || req.Version != "HTTP/1.0")
+	body := resp.Body
+	if chunked {
+		headers["Transfer-Encoding"] = "chunked"
+		var chunkBuf bytes.Buffer
+		for _, chunk := range resp.Chunks {
+			if len(chunk) == 0 {
+				continue // A zero-length chunk would terminate the body
+			}
+			fmt.Fprintf(&chunkBuf, "%x\r\n", len(chunk))
+			chunkBuf.Write(chunk)
+			chunkBuf.WriteString("\r\n")
+		}
+		chunkBuf.WriteString("0\r\n\r\n") // Last chunk, no trailers
+		body = chunkBuf.Bytes()
+	} else {
+		if resp.Chunks != nil {
+			body = bytes.Join(resp.Chunks, nil)
+		}
+		headers["Content-Length"] = strconv.Itoa(len(body))
+	}
+
+	if keepAlive {
+		headers["Connection"] = "keep-alive"
+		headers["Keep-Alive"] = fmt.Sprintf("max=%d", remainingRequests)
+	} else {
+		headers["Connection"] = "close"
+	}
+
 	var builder strings.Builder
 	// Status Line
-	builder.WriteString(fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, statusText))
-	// Headers
-	builder.WriteString(fmt.Sprintf("Content-Type: text/html; charset=utf-8\r\n"))
-	builder.WriteString(fmt.Sprintf("Content-Length: %d\r\n", len(body)))
-	builder.WriteString("Connection: close\r\n") // Simple: always close
-	builder.WriteString("\r\n")                  // End of headers
+	builder.WriteString(fmt.Sprintf("HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode)))
+	// Headers (sorted for stable output)
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		builder.WriteString(fmt.Sprintf("%s: %s\r\n", k, headers[k]))
+	}
+	builder.WriteString("\r\n") // End of headers
 	// Body
-	builder.WriteString(body)
+	if req == nil || req.Method != "HEAD" {
+		builder.Write(body)
+	}
 	return []byte(builder.String())
 }
+
+// sendHTTPResponse sends the serialized response, as TLS Application Data once the handshake is done.
+func sendHTTPResponse(conn *TCPConnection, httpRespBytes []byte) error {
+	conn.Mutex.Lock() // Lock to check TLS state safely
+	tcpConn := conn.TCPConn
+	isTLSEnabled := conn.EncryptionEnabled // Check if handshake is complete and encryption is on
+	connKey := fmt.Sprintf("%s:%d-%s:%d", conn.ClientIP, conn.ClientPort, conn.ServerIP, conn.ServerPort)
+	conn.Mutex.Unlock() // Unlock before potentially blocking send operations
+
+	if isTLSEnabled {
+		log.Printf("[TLS AppData Send - %s] Sending HTTP response (%d bytes) as Application Data.", connKey, len(httpRespBytes))
+		appDataRecord, err := buildTLSRecord(TLSRecordTypeApplicationData, 0x0303, httpRespBytes)
+		if err != nil {
+			return fmt.Errorf("failed to build Application Data record: %w", err)
+		}
+		if err := sendRawTLSRecord(conn, appDataRecord); err != nil {
+			return fmt.Errorf("failed to send Application Data record: %w", err)
+		}
+		return nil
+	}
+
+	if tcpConn == nil {
+		return fmt.Errorf("connection %s in invalid state (no TCPConn)", connKey)
+	}
+	log.Printf("[HTTP Info - %s] Sending raw HTTP response (%d bytes).", connKey, len(httpRespBytes))
+	if _, err := tcpConn.Write(httpRespBytes); err != nil {
+		return fmt.Errorf("error writing raw HTTP response: %w", err)
+	}
+	return nil
+}
+
+// closeHTTPConnection closes the connection after the last response.
+// TLS connections send a close_notify alert first. Closing the net.Conn sends the FIN and ends
+// the read loop of the connection.
+func closeHTTPConnection(conn *TCPConnection) {
+	conn.Mutex.Lock()
+	conn.HTTPClosing = true
+	isTLSEnabled := conn.EncryptionEnabled
+	tcpConn := conn.TCPConn
+	connKey := fmt.Sprintf("%s:%d-%s:%d", conn.ClientIP, conn.ClientPort, conn.ServerIP, conn.ServerPort)
+	conn.Mutex.Unlock()
+
+	if isTLSEnabled {
+		log.Printf("%s%sSending close_notify alert for %s.%s", ColorOrange, PrefixTLS, connKey, ColorReset)
+		alertRecord, err := buildTLSRecord(TLSRecordTypeAlert, 0x0303, []byte{1, 0}) // warning, close_notify
+		if err == nil {
+			err = sendRawTLSRecord(conn, alertRecord)
+		}
+		if err != nil {
+			log.Printf("%s%sFailed to send close_notify alert for %s: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
+		}
+	}
+
+	if tcpConn != nil {
+		log.Printf("Closing connection %s after HTTP response.", connKey)
+		tcpConn.Close()
+	}
+}
+
+// runHTTPClient fetches the URL with net/http over a connection of the userspace stack
+// (an example of an application built on the netstack socket API).
+func runHTTPClient(rawURL string) {
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{DialContext: socketStack.DialContext},
+	}
+	log.Printf("%s%s Client: GET %s%s", ColorWhite, PrefixHTTP, rawURL, ColorReset)
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		log.Printf("%s%sHTTP client request to %s failed: %v%s", ColorRed, PrefixError, rawURL, err, ColorReset)
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("%s%sFailed to read HTTP response body from %s: %v%s", ColorRed, PrefixError, rawURL, err, ColorReset)
+		return
+	}
+	log.Printf("%s%s Client: %s (%d bytes)%s", ColorWhite, PrefixHTTP, resp.Status, len(body), ColorReset)
+	fmt.Printf("%s\n", body)
+}
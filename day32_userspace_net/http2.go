@@ -411,24 +411,13 @@ func sendHTTP2Frame(conn *TCPConnection, frameType uint8, flags uint8, streamID
 
 	// 3. Send the TLS record
 	// Note: sendRawTLSRecord handles encryption if enabled
-	sentBytes, err := sendRawTLSRecord(conn.TunIFCE, conn, tlsRecord) // Pass TUN interface if needed
+	err = sendRawTLSRecord(conn, tlsRecord)
 	if err != nil {
 		// Add more detail to error log
 		log.Printf("%s%ssendRawTLSRecord failed for H2 frame (Type: %d): %v%s", ColorRed, PrefixError, frameType, err, ColorReset)
 		return fmt.Errorf("failed to send TLS record containing H2 frame (Type: %d): %w", frameType, err)
 	}
-	// Add log after successful sendRawTLSRecord
-	// log.Printf("[HTTP/2 Send Debug - %s] sendRawTLSRecord successful for H2 Frame. Type: %d, StreamID: %d, SentBytes (TLS): %d", PrefixH2, frameType, streamID, sentBytes)
 
-	// 4. Update sequence numbers (crucial for TUN mode)
-	if conn.TunIFCE != nil {
-		conn.Mutex.Lock()
-		conn.ServerNextSeq += uint32(sentBytes) // Increment by TUN payload bytes sent
-		if isDebug {
-			log.Printf("[SeqNum Update - %s] After H2 Frame (Type %d): ServerNextSeq = %d (added %d)", PrefixH2, frameType, conn.ServerNextSeq, sentBytes)
-		}
-		conn.Mutex.Unlock()
-	}
 	if isDebug {
 		log.Printf("[HTTP/2 Send OK - %s] Sent H2 Frame. Type: %d, Flags: 0x%x, StreamID: %d, PayloadLen: %d, TLS Record Len: %d", PrefixH2, frameType, flags, streamID, len(payload), len(tlsRecord))
 	}
@@ -81,7 +81,7 @@ func sendICMPEchoReply(ifce *water.Interface, reqIPHeader *IPv4Header, reqICMPHe
 	// binary.BigEndian.PutUint32(afInetBytes, 2) // AF_INET
 	// fullPacket := append(afInetBytes, replyPacket...)
 
-	n, err := ifce.Write(replyPacket) // Write the IP packet directly
+	n, err := writeIPPacket(ifce, replyPacket) // Write the IP packet (framed in TAP mode)
 	if err != nil {
 		return fmt.Errorf("failed to write packet to TUN device: %w", err)
 	}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/songgao/water"
+)
+
+// ICMPv6 and Neighbor Discovery (NDP).
+// Reference: RFC 4443 (ICMPv6), RFC 4861 (Neighbor Discovery)
+
+const (
+	ICMPv6ProtocolNumber = 58
+
+	ICMPv6TypeEchoRequest           = 128
+	ICMPv6TypeEchoReply             = 129
+	ICMPv6TypeNeighborSolicitation  = 135
+	ICMPv6TypeNeighborAdvertisement = 136
+
+	ICMPv6HeaderLengthBytes = 4  // Type, Code, Checksum
+	NDPMessageLengthBytes   = 24 // ICMPv6 header + Reserved/Flags (4) + Target Address (16)
+	NDPHopLimit             = 255
+
+	// NDP Options
+	NDPOptionSourceLinkLayerAddress = 1
+	NDPOptionTargetLinkLayerAddress = 2
+
+	// Neighbor Advertisement Flags
+	NDPFlagRouter    = 0x80
+	NDPFlagSolicited = 0x40
+	NDPFlagOverride  = 0x20
+)
+
+// ipv6AllNodes is the all-nodes multicast address (ff02::1)
+var ipv6AllNodes = net.ParseIP("ff02::1")
+
+// handleICMPv6Packet handles Echo Requests and Neighbor Discovery messages.
+func handleICMPv6Packet(ifce *water.Interface, ipHeader *IPv6Header, icmpPayload []byte) {
+	if len(icmpPayload) < ICMPv6HeaderLengthBytes {
+		log.Printf("ICMPv6 payload too short: %d bytes", len(icmpPayload))
+		return
+	}
+	// ICMPv6 のチェックサムは必須 (IPv4 の ICMP と違い疑似ヘッダを含む)
+	if checksum, err := calculateICMPv6Checksum(ipHeader.SrcIP, ipHeader.DstIP, icmpPayload); err != nil || checksum != binary.BigEndian.Uint16(icmpPayload[2:4]) {
+		log.Printf("%s%sInvalid ICMPv6 checksum from %s, dropping packet.%s", ColorYellow, PrefixWarn, ipHeader.SrcIP, ColorReset)
+		return
+	}
+
+	icmpType := icmpPayload[0]
+	switch icmpType {
+	case ICMPv6TypeEchoRequest:
+		if len(icmpPayload) < 8 {
+			log.Printf("ICMPv6 Echo Request too short: %d bytes", len(icmpPayload))
+			return
+		}
+		id := binary.BigEndian.Uint16(icmpPayload[4:6])
+		seq := binary.BigEndian.Uint16(icmpPayload[6:8])
+		log.Printf("Received ICMPv6 Echo Request (ID: %d, Seq: %d) from %s", id, seq, ipHeader.SrcIP)
+		if !ipHeader.DstIP.Equal(stackIP6) {
+			return // マルチキャスト宛てなどには応答しない
+		}
+
+		reply := append([]byte{ICMPv6TypeEchoReply, 0, 0, 0}, icmpPayload[4:]...)
+		if err := sendICMPv6Message(ifce, stackIP6, ipHeader.SrcIP, IPv6DefaultHopLimit, reply); err != nil {
+			log.Printf("Failed to send ICMPv6 Echo Reply: %v", err)
+		}
+	case ICMPv6TypeNeighborSolicitation:
+		handleNeighborSolicitation(ifce, ipHeader, icmpPayload)
+	case ICMPv6TypeNeighborAdvertisement:
+		handleNeighborAdvertisement(ifce, ipHeader, icmpPayload)
+	default:
+		// Router Solicitation, MLD, etc. are not handled
+	}
+}
+
+// handleNeighborSolicitation answers Neighbor Solicitations for the stack's IPv6 address.
+func handleNeighborSolicitation(ifce *water.Interface, ipHeader *IPv6Header, message []byte) {
+	target, options, err := parseNDPMessage(ipHeader, message)
+	if err != nil {
+		log.Printf("%s%sInvalid Neighbor Solicitation from %s: %v%s", ColorYellow, PrefixWarn, ipHeader.SrcIP, err, ColorReset)
+		return
+	}
+	log.Printf("%s%sRCV Neighbor Solicitation: Who has %s? Tell %s%s", ColorTeal, PrefixNDP, target, ipHeader.SrcIP, ColorReset)
+	pauseIfNeeded("ndp")
+
+	if !target.Equal(stackIP6) {
+		return
+	}
+
+	// 送信元のリンク層アドレスを学習する (重複アドレス検出の NS は送信元が :: なので学習しない)
+	if mac := ndpLinkLayerOption(options, NDPOptionSourceLinkLayerAddress); mac != nil && ifce.IsTAP() && !ipHeader.SrcIP.IsUnspecified() {
+		learnMAC(ifce, ipHeader.SrcIP, mac)
+	}
+
+	dstIP := ipHeader.SrcIP
+	flags := uint8(NDPFlagSolicited | NDPFlagOverride)
+	if dstIP.IsUnspecified() {
+		// Reply to Duplicate Address Detection goes to all nodes, without the Solicited flag
+		dstIP = ipv6AllNodes
+		flags = NDPFlagOverride
+	}
+
+	advertisement := make([]byte, NDPMessageLengthBytes)
+	advertisement[0] = ICMPv6TypeNeighborAdvertisement
+	advertisement[4] = flags
+	copy(advertisement[8:24], stackIP6)
+	if ifce.IsTAP() {
+		// TUN (ポイントツーポイント) ではリンク層アドレスがないためオプションを付けない
+		advertisement = append(advertisement, NDPOptionTargetLinkLayerAddress, 1)
+		advertisement = append(advertisement, stackMAC...)
+	}
+
+	log.Printf("%s%sSND Neighbor Advertisement: %s (to %s)%s", ColorTeal, PrefixNDP, stackIP6, dstIP, ColorReset)
+	if err := sendICMPv6Message(ifce, stackIP6, dstIP, NDPHopLimit, advertisement); err != nil {
+		log.Printf("%s%sFailed to send Neighbor Advertisement: %v%s", ColorRed, PrefixError, err, ColorReset)
+	}
+}
+
+// handleNeighborAdvertisement stores the link-layer address of the advertised target in the neighbor cache.
+func handleNeighborAdvertisement(ifce *water.Interface, ipHeader *IPv6Header, message []byte) {
+	target, options, err := parseNDPMessage(ipHeader, message)
+	if err != nil {
+		log.Printf("%s%sInvalid Neighbor Advertisement from %s: %v%s", ColorYellow, PrefixWarn, ipHeader.SrcIP, err, ColorReset)
+		return
+	}
+	mac := ndpLinkLayerOption(options, NDPOptionTargetLinkLayerAddress)
+	log.Printf("%s%sRCV Neighbor Advertisement: %s is at %s%s", ColorTeal, PrefixNDP, target, mac, ColorReset)
+	pauseIfNeeded("ndp")
+
+	if mac != nil && ifce.IsTAP() {
+		learnMAC(ifce, target, mac)
+	}
+}
+
+// parseNDPMessage validates a Neighbor Solicitation/Advertisement and returns its target address and options.
+func parseNDPMessage(ipHeader *IPv6Header, message []byte) (net.IP, []byte, error) {
+	if len(message) < NDPMessageLengthBytes {
+		return nil, nil, fmt.Errorf("message too short: %d bytes", len(message))
+	}
+	if ipHeader.HopLimit != NDPHopLimit {
+		return nil, nil, fmt.Errorf("hop limit is %d, must be %d", ipHeader.HopLimit, NDPHopLimit)
+	}
+	if message[1] != 0 {
+		return nil, nil, fmt.Errorf("code is %d, must be 0", message[1])
+	}
+	target := append(net.IP(nil), message[8:24]...)
+	if target.IsMulticast() {
+		return nil, nil, fmt.Errorf("target %s is a multicast address", target)
+	}
+	return target, message[NDPMessageLengthBytes:], nil
+}
+
+// ndpLinkLayerOption returns the Ethernet address in the link-layer address option of the type, or nil.
+func ndpLinkLayerOption(options []byte, optionType uint8) net.HardwareAddr {
+	for len(options) >= 2 {
+		optionLen := int(options[1]) * 8 // Length is in units of 8 bytes
+		if optionLen == 0 || optionLen > len(options) {
+			return nil
+		}
+		if options[0] == optionType && optionLen >= 8 {
+			return append(net.HardwareAddr(nil), options[2:8]...)
+		}
+		options = options[optionLen:]
+	}
+	return nil
+}
+
+// sendNeighborSolicitation sends a Neighbor Solicitation for the target to its solicited-node
+// multicast address (TAP mode). It is written as an Ethernet frame directly, since the
+// destination MAC address of a multicast address does not need to be resolved.
+func sendNeighborSolicitation(ifce *water.Interface, target net.IP) error {
+	log.Printf("%s%sSND Neighbor Solicitation: Who has %s? Tell %s%s", ColorTeal, PrefixNDP, target, stackIP6, ColorReset)
+
+	dstIP := solicitedNodeAddress(target)
+	solicitation := make([]byte, NDPMessageLengthBytes)
+	solicitation[0] = ICMPv6TypeNeighborSolicitation
+	copy(solicitation[8:24], target)
+	solicitation = append(solicitation, NDPOptionSourceLinkLayerAddress, 1)
+	solicitation = append(solicitation, stackMAC...)
+
+	message, err := buildICMPv6Message(stackIP6, dstIP, solicitation)
+	if err != nil {
+		return err
+	}
+	headerBytes, err := buildIPv6Header(stackIP6, dstIP, ICMPv6ProtocolNumber, NDPHopLimit, len(message))
+	if err != nil {
+		return err
+	}
+	return sendEthernetFrame(ifce, ipv6MulticastMAC(dstIP), EtherTypeIPv6, append(headerBytes, message...))
+}
+
+// sendICMPv6Message computes the checksum of the ICMPv6 message and sends it.
+func sendICMPv6Message(ifce *water.Interface, srcIP, dstIP net.IP, hopLimit uint8, message []byte) error {
+	message, err := buildICMPv6Message(srcIP, dstIP, message)
+	if err != nil {
+		return err
+	}
+	return sendIPv6Packet(ifce, srcIP, dstIP, ICMPv6ProtocolNumber, hopLimit, message)
+}
+
+// buildICMPv6Message sets the checksum of the ICMPv6 message.
+func buildICMPv6Message(srcIP, dstIP net.IP, message []byte) ([]byte, error) {
+	checksum, err := calculateICMPv6Checksum(srcIP, dstIP, message)
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(message[2:4], checksum)
+	return message, nil
+}
+
+// calculateICMPv6Checksum calculates the ICMPv6 checksum over the pseudo header and the message.
+// The checksum field of the message is treated as zero.
+func calculateICMPv6Checksum(srcIP, dstIP net.IP, message []byte) (uint16, error) {
+	pseudoHeader, err := buildPseudoHeader(srcIP, dstIP, ICMPv6ProtocolNumber, len(message))
+	if err != nil {
+		return 0, err
+	}
+	dataForChecksum := append(pseudoHeader, message...)
+	// Zero out checksum field within the combined data for calculation
+	binary.BigEndian.PutUint16(dataForChecksum[len(pseudoHeader)+2:len(pseudoHeader)+4], 0)
+	return calculateChecksum(dataForChecksum), nil
+}
+
+// solicitedNodeAddress returns the solicited-node multicast address (ff02::1:ffXX:XXXX) of the address.
+func solicitedNodeAddress(ip net.IP) net.IP {
+	addr := net.ParseIP("ff02::1:ff00:0")
+	copy(addr[13:16], ip.To16()[13:16])
+	return addr
+}
+
+// ipv6MulticastMAC returns the Ethernet multicast address of an IPv6 multicast address (33:33 + last 32 bits).
+func ipv6MulticastMAC(ip net.IP) net.HardwareAddr {
+	ip16 := ip.To16()
+	return net.HardwareAddr{0x33, 0x33, ip16[12], ip16[13], ip16[14], ip16[15]}
+}
@@ -150,9 +150,9 @@ func parseIPv4Header(packet []byte) (*IPv4Header, []byte, error) {
 	header.Protocol = packet[9]
 	header.Checksum = binary.BigEndian.Uint16(packet[10:12])
 
-	// Parse IP addresses
-	header.SrcIP = net.IP(packet[12:16])
-	header.DstIP = net.IP(packet[16:20])
+	// Parse IP addresses (copied, since the receive buffer is reused for the next packet)
+	header.SrcIP = append(net.IP(nil), packet[12:16]...)
+	header.DstIP = append(net.IP(nil), packet[16:20]...)
 
 	// Extract options if IHL > 5
 	if headerLengthBytes > IPv4HeaderMinLengthBytes {
@@ -235,14 +235,13 @@ func handleIPPacket(ifce *water.Interface, packet []byte) {
 	switch ipHeader.Protocol {
 	case IPProtocolTCP:
 		// TCP Handling (assuming handleTCPPacket is defined elsewhere)
-		handleTCPPacket(ifce, ipHeader, payload)
+		handleTCPPacket(ifce, ipHeader.SrcIP, ipHeader.DstIP, payload)
 	case IPProtocolICMP:
 		// ICMP Handling (optional, placeholder)
 		log.Printf("%s%sReceived ICMP packet from %s%s", ColorGray, PrefixIP, ipHeader.SrcIP, ColorReset)
 		// TODO: Implement basic ICMP handling (e.g., echo reply) if needed
 	case IPProtocolUDP:
-		// UDP Handling (optional, placeholder)
-		log.Printf("%s%sReceived UDP packet from %s%s", ColorGray, PrefixIP, ipHeader.SrcIP, ColorReset)
+		handleUDPPacket(ifce, ipHeader.SrcIP, ipHeader.DstIP, payload)
 	default:
 		// Use gray for unhandled protocols
 		log.Printf("%s%sReceived packet with unhandled protocol %d from %s%s", ColorGray, PrefixIP, ipHeader.Protocol, ipHeader.SrcIP, ColorReset)
@@ -251,6 +250,10 @@ func handleIPPacket(ifce *water.Interface, packet []byte) {
 
 // sendIPPacket constructs and sends an IPv4 packet over the TUN interface.
 func sendIPPacket(ifce *water.Interface, srcIP, dstIP net.IP, protocol uint8, payload []byte) error {
+	if dstIP.To4() == nil {
+		return sendIPv6Packet(ifce, srcIP, dstIP, protocol, IPv6DefaultHopLimit, payload)
+	}
+
 	// Simplified IPv4 header construction
 	// More robust implementation would handle options, fragmentation, etc.
 	header := IPv4Header{
@@ -272,7 +275,7 @@ func sendIPPacket(ifce *water.Interface, srcIP, dstIP net.IP, protocol uint8, pa
 	headerBytes[1] = header.TOS // Assign TOS field directly
 	binary.BigEndian.PutUint16(headerBytes[2:4], header.TotalLength)
 	binary.BigEndian.PutUint16(headerBytes[4:6], header.ID)
-	binary.BigEndian.PutUint16(headerBytes[6:8], uint16(header.Flags<<13)|header.FragmentOffset)
+	binary.BigEndian.PutUint16(headerBytes[6:8], uint16(header.Flags)<<13|header.FragmentOffset)
 	headerBytes[8] = header.TTL
 	headerBytes[9] = header.Protocol
 	copy(headerBytes[12:16], header.SrcIP.To4())
@@ -298,7 +301,7 @@ func sendIPPacket(ifce *water.Interface, srcIP, dstIP net.IP, protocol uint8, pa
 		ColorReset,
 	)
 
-	_, err := ifce.Write(packet)
+	_, err := writeIPPacket(ifce, packet)
 	if err != nil {
 		log.Printf("%s%sError sending IP packet: %v%s", ColorRed, PrefixError, err, ColorReset)
 		return fmt.Errorf("failed to write packet to TUN device: %w", err)
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/songgao/water"
+)
+
+// IPv6Header represents the IPv6 fixed header structure.
+// Reference: RFC 8200
+type IPv6Header struct {
+	Version       uint8  // 4 bits
+	TrafficClass  uint8  // Traffic Class
+	FlowLabel     uint32 // 20 bits
+	PayloadLength uint16 // Length of the payload (everything after the fixed header)
+	NextHeader    uint8  // Next Header (same values as the IPv4 protocol field, e.g., 6 for TCP)
+	HopLimit      uint8  // Hop Limit
+	SrcIP         net.IP // Source IP Address (16 bytes)
+	DstIP         net.IP // Destination IP Address (16 bytes)
+}
+
+const (
+	IPv6Version           = 6
+	IPv6HeaderLengthBytes = 40
+	IPv6DefaultHopLimit   = 64
+)
+
+// stackIP6 is the IPv6 address of the userspace stack (nil = IPv6 disabled)
+var stackIP6 net.IP
+
+// buildIPv6Header creates an IPv6 header byte slice (IPv6 has no header checksum).
+func buildIPv6Header(srcIP, dstIP net.IP, nextHeader uint8, hopLimit uint8, payloadLength int) ([]byte, error) {
+	if srcIP == nil || dstIP == nil {
+		return nil, fmt.Errorf("source or destination IP is nil")
+	}
+	if srcIP.To4() != nil || dstIP.To4() != nil || len(srcIP) != net.IPv6len || len(dstIP) != net.IPv6len {
+		return nil, fmt.Errorf("source or destination IP is not IPv6")
+	}
+	if payloadLength > 0xFFFF {
+		return nil, fmt.Errorf("IPv6 payload too large: %d bytes", payloadLength)
+	}
+
+	headerBytes := make([]byte, IPv6HeaderLengthBytes)
+	headerBytes[0] = IPv6Version << 4 // Traffic Class and Flow Label are 0
+	binary.BigEndian.PutUint16(headerBytes[4:6], uint16(payloadLength))
+	headerBytes[6] = nextHeader
+	headerBytes[7] = hopLimit
+	copy(headerBytes[8:24], srcIP)
+	copy(headerBytes[24:40], dstIP)
+	return headerBytes, nil
+}
+
+// parseIPv6Header parses the byte slice into an IPv6Header struct.
+// Extension headers are not supported; the payload is returned as the data of NextHeader.
+func parseIPv6Header(packet []byte) (*IPv6Header, []byte, error) {
+	if len(packet) < IPv6HeaderLengthBytes {
+		return nil, nil, fmt.Errorf("packet too short for IPv6 header: %d bytes", len(packet))
+	}
+
+	header := &IPv6Header{}
+	header.Version = packet[0] >> 4
+	if header.Version != IPv6Version {
+		return nil, nil, fmt.Errorf("not an IPv6 packet (Version: %d)", header.Version)
+	}
+	versionClassFlow := binary.BigEndian.Uint32(packet[0:4])
+	header.TrafficClass = uint8(versionClassFlow >> 20)
+	header.FlowLabel = versionClassFlow & 0xFFFFF
+	header.PayloadLength = binary.BigEndian.Uint16(packet[4:6])
+	header.NextHeader = packet[6]
+	header.HopLimit = packet[7]
+	// 受信バッファは再利用されるためアドレスはコピーしておく
+	header.SrcIP = append(net.IP(nil), packet[8:24]...)
+	header.DstIP = append(net.IP(nil), packet[24:40]...)
+
+	payload := packet[IPv6HeaderLengthBytes:]
+	if int(header.PayloadLength) > len(payload) {
+		return nil, nil, fmt.Errorf("payload length (%d) is greater than available data length (%d)", header.PayloadLength, len(payload))
+	}
+	return header, payload[:header.PayloadLength], nil
+}
+
+// sendIPv6Packet constructs and sends an IPv6 packet over the TUN/TAP interface.
+func sendIPv6Packet(ifce *water.Interface, srcIP, dstIP net.IP, nextHeader uint8, hopLimit uint8, payload []byte) error {
+	headerBytes, err := buildIPv6Header(srcIP, dstIP, nextHeader, hopLimit, len(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build IPv6 header: %w", err)
+	}
+	packet := append(headerBytes, payload...)
+
+	log.Printf("%s%sSND: %s -> %s Next: %d(%s) HopLimit: %d Len(Payload/Total): %d/%d%s",
+		ColorPurple, PrefixIP,
+		srcIP, dstIP,
+		nextHeader, ipProtocolToString(nextHeader),
+		hopLimit, len(payload), len(packet),
+		ColorReset,
+	)
+
+	n, err := writeIPPacket(ifce, packet)
+	if err != nil {
+		return fmt.Errorf("failed to write packet to TUN device: %w", err)
+	}
+	if n != len(packet) {
+		return fmt.Errorf("short write to TUN device: wrote %d bytes, expected %d", n, len(packet))
+	}
+	return nil
+}
+
+// buildIPHeader creates an IPv4 or IPv6 header depending on the address family of the destination.
+func buildIPHeader(srcIP, dstIP net.IP, protocol uint8, payloadLength int) ([]byte, error) {
+	if dstIP.To4() == nil {
+		return buildIPv6Header(srcIP, dstIP, protocol, IPv6DefaultHopLimit, payloadLength)
+	}
+	return buildIPv4Header(srcIP, dstIP, protocol, payloadLength)
+}
+
+// buildPseudoHeader creates the pseudo header used in the TCP/UDP/ICMPv6 checksum.
+// IPv4: RFC 793 (12 bytes), IPv6: RFC 8200 Section 8.1 (40 bytes).
+func buildPseudoHeader(srcIP, dstIP net.IP, protocol uint8, length int) ([]byte, error) {
+	if srcIPv4, dstIPv4 := srcIP.To4(), dstIP.To4(); srcIPv4 != nil && dstIPv4 != nil {
+		pseudoHeader := make([]byte, 12)
+		copy(pseudoHeader[0:4], srcIPv4)
+		copy(pseudoHeader[4:8], dstIPv4)
+		pseudoHeader[8] = 0 // Reserved
+		pseudoHeader[9] = protocol
+		binary.BigEndian.PutUint16(pseudoHeader[10:12], uint16(length))
+		return pseudoHeader, nil
+	}
+	if srcIP.To4() == nil && dstIP.To4() == nil && len(srcIP) == net.IPv6len && len(dstIP) == net.IPv6len {
+		pseudoHeader := make([]byte, 40)
+		copy(pseudoHeader[0:16], srcIP)
+		copy(pseudoHeader[16:32], dstIP)
+		binary.BigEndian.PutUint32(pseudoHeader[32:36], uint32(length))
+		// 3 bytes zero + Next Header
+		pseudoHeader[39] = protocol
+		return pseudoHeader, nil
+	}
+	return nil, fmt.Errorf("mismatched or invalid addresses for pseudo header: %s -> %s", srcIP, dstIP)
+}
+
+// dispatchIPv6Packet logs the IPv6 header and hands the payload to the protocol handler.
+func dispatchIPv6Packet(ifce *water.Interface, ipHeader *IPv6Header, payload []byte, packetLen int) {
+	log.Printf("%s%sRCV: %s -> %s Next: %d(%s) HopLimit: %d Len: %d/%d%s",
+		ColorCyan, PrefixIP,
+		ipHeader.SrcIP, ipHeader.DstIP,
+		ipHeader.NextHeader, ipProtocolToString(ipHeader.NextHeader),
+		ipHeader.HopLimit,
+		ipHeader.PayloadLength, packetLen,
+		ColorReset,
+	)
+
+	if stackIP6 == nil {
+		log.Printf("%s%sIPv6 is disabled (no -remoteIP6), dropping packet.%s", ColorGray, PrefixIP, ColorReset)
+		return
+	}
+
+	switch ipHeader.NextHeader {
+	case ICMPv6ProtocolNumber:
+		handleICMPv6Packet(ifce, ipHeader, payload)
+	case TCPProtocolNumber:
+		handleTCPPacket(ifce, ipHeader.SrcIP, ipHeader.DstIP, payload)
+	case UDPProtocolNumber:
+		handleUDPPacket(ifce, ipHeader.SrcIP, ipHeader.DstIP, payload)
+	default:
+		// Extension headers (Hop-by-Hop, Routing, Fragment, ...) are not supported
+		log.Printf("%s%sUnhandled IPv6 next header %d from %s%s", ColorGray, PrefixIP, ipHeader.NextHeader, ipHeader.SrcIP, ColorReset)
+	}
+}
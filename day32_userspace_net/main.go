@@ -12,6 +12,9 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+
+	"github.com/lirlia/100day_challenge_backend/day32_userspace_net/netstack"
+	"github.com/songgao/water"
 	// For accessing packet layers
 	// For defining layers (IP, TCP)
 	// For TUN interface interaction
@@ -30,15 +33,21 @@ const (
 	ColorOrange  = "\033[38;5;214m" // Orange for TLS
 	ColorMagenta = "\033[95m"       // Magenta for H2 App Data
 	ColorGreen   = "\033[32m"       // Green for established/OK
+	ColorTeal    = "\033[96m"       // Teal for Ethernet/ARP (TAP mode)
 )
 
 // Log Prefixes
 const (
+	PrefixEth   = "[ETH] " // Ethernet/ARP are below the IP layer (TAP mode)
+	PrefixARP   = "[ARP] "
+	PrefixNDP   = "[NDP] "
 	PrefixIP    = "[IP] " // Keep original padding for alignment
 	PrefixTCP   = "  [TCP] "
+	PrefixUDP   = "  [UDP] "
 	PrefixTLS   = "    [TLS] "
 	PrefixHTTP  = "      [HTTP]"
 	PrefixH2    = "      [H2]  "
+	PrefixDNS   = "    [DNS] "
 	PrefixError = "[ERROR] "
 	PrefixWarn  = "[WARN]  "
 	PrefixInfo  = "[INFO]  "
@@ -63,9 +72,18 @@ var (
 	remoteIP   = flag.String("remoteIP", "10.0.0.2", "Remote IP address (peer) for the TUN device")
 	subnetMask = flag.String("subnet", "255.255.255.0", "Subnet mask for the TUN device")
 	mtu        = flag.Int("mtu", 1500, "MTU for the TUN device")
-	mode       = flag.String("mode", "tun", "Operating mode: 'tun' or 'tcp'")
+	mode       = flag.String("mode", "tun", "Operating mode: 'tun', 'tap' or 'tcp'")
+	macAddr    = flag.String("mac", "02:00:00:00:00:02", "MAC address of the userspace stack in tap mode")
 	listenPort = flag.Int("port", 443, "Port to listen on in tcp mode")
 	debug      = flag.Bool("debug", false, "Enable detailed debug logging")
+	zoneFile   = flag.String("zone", "zone.txt", "Zone file with A records served by the DNS server in tun/tap mode")
+	localIP6   = flag.String("localIP6", "", "Local IPv6 address for the TUN/TAP device (e.g., fd00::1)")
+	remoteIP6  = flag.String("remoteIP6", "", "IPv6 address of the userspace stack (e.g., fd00::2); IPv6 is disabled if empty")
+	prefixLen6 = flag.Int("prefix6", 64, "IPv6 prefix length for the TUN/TAP device")
+	tlsVersion = flag.String("tls", "1.2", "Highest TLS version to negotiate: '1.2' or '1.3' (1.3 falls back to 1.2 if the client does not offer it)")
+	tlsGroup   = flag.String("tlsGroup", "x25519", "Key exchange group for TLS 1.3: 'x25519' or 'p256' (HelloRetryRequest if the client sent no key share for it)")
+	httpGet    = flag.String("httpGet", "", "Fetch this URL over the userspace stack in tun/tap mode (e.g., http://10.0.0.1:8000/)")
+	pcapFile   = flag.String("pcap", "", "Write every packet sent/received on the TUN/TAP device to this file in pcapng format (e.g., out.pcap)")
 )
 
 // --- HTTP2State definitions moved to tcp.go ---
@@ -104,6 +122,19 @@ func main() {
 	serverCertDER = serverCert.Certificate
 	// --- End Load Certificate and Key ---
 
+	switch *tlsVersion {
+	case "1.2":
+	case "1.3":
+		tls13Enabled = true
+		tls13Group, err = parseTLS13Group(*tlsGroup)
+		if err != nil {
+			log.Fatalf("%s%sInvalid tlsGroup: %v%s", ColorRed, PrefixError, err, ColorReset)
+		}
+		log.Printf("%s%sTLS 1.3 enabled (key exchange group: %s).%s", ColorWhite, PrefixInfo, tls13GroupName(tls13Group), ColorReset)
+	default:
+		log.Fatalf("%s%sInvalid TLS version: %s. Choose '1.2' or '1.3'.%s", ColorRed, PrefixError, *tlsVersion, ColorReset)
+	}
+
 	switch *mode {
 	case "tun":
 		log.Printf("%s%sStarting in TUN mode...%s", ColorWhite, PrefixInfo, ColorReset)
@@ -129,16 +160,66 @@ func main() {
 
 		log.Printf("%s%sTUN device '%s' configured successfully.%s", ColorWhite, PrefixInfo, ifce.Name(), ColorReset)
 		log.Printf("%s%s Interface IP: %s, Peer IP: %s, Subnet Mask: %s%s", ColorWhite, PrefixInfo, localIPAddr, remoteIPAddr, *subnetMask, ColorReset)
+
+		setupPacketCapture(ifce.Name(), LinkTypeRaw)
+		defer closePacketCapture()
+		setupIPv6Stack(ifce)
+		setupDNSServer(remoteIPAddr)
+		setupSocketStack(ifce, remoteIPAddr)
 		log.Printf("%s%sListening for packets...%s", ColorWhite, PrefixInfo, ColorReset)
 
 		go processPackets(ifce)
 
+	case "tap":
+		log.Printf("%s%sStarting in TAP mode...%s", ColorWhite, PrefixInfo, ColorReset)
+		if *localIP == "" || *remoteIP == "" || *subnetMask == "" {
+			log.Fatalf("%s%slocalIP, remoteIP, and subnet flags are required for tap mode%s", ColorRed, PrefixError, ColorReset)
+		}
+		localIPAddr := net.ParseIP(*localIP)
+		remoteIPAddr := net.ParseIP(*remoteIP)
+		if localIPAddr == nil || remoteIPAddr == nil || remoteIPAddr.To4() == nil {
+			log.Fatalf("%s%sInvalid localIP or remoteIP address format%s", ColorRed, PrefixError, ColorReset)
+		}
+		stackMAC, err = net.ParseMAC(*macAddr)
+		if err != nil || len(stackMAC) != 6 {
+			log.Fatalf("%s%sInvalid MAC address '%s': %v%s", ColorRed, PrefixError, *macAddr, err, ColorReset)
+		}
+		// TUNモードと同様に、スタック自身のアドレスは remoteIP (ホスト側が localIP)
+		stackIP = remoteIPAddr.To4()
+
+		// Setup TAP device
+		log.Printf("%s%sSetting up TAP device '%s'...%s", ColorWhite, PrefixInfo, *devName, ColorReset)
+		ifce, err := setupTAP(*devName, localIPAddr.String(), *subnetMask, *mtu)
+		if err != nil {
+			log.Fatalf("%s%sFailed to setup TAP device: %v%s", ColorRed, PrefixError, err, ColorReset)
+		}
+		defer func() {
+			log.Printf("%s%sClosing TAP device '%s'...%s", ColorYellow, PrefixInfo, ifce.Name(), ColorReset)
+			ifce.Close()
+		}()
+
+		log.Printf("%s%sTAP device '%s' configured successfully.%s", ColorWhite, PrefixInfo, ifce.Name(), ColorReset)
+		log.Printf("%s%s Host IP: %s, Stack IP: %s, Stack MAC: %s, Subnet Mask: %s%s", ColorWhite, PrefixInfo, localIPAddr, stackIP, stackMAC, *subnetMask, ColorReset)
+
+		setupPacketCapture(ifce.Name(), LinkTypeEthernet)
+		defer closePacketCapture()
+		setupIPv6Stack(ifce)
+		setupDNSServer(remoteIPAddr)
+		setupSocketStack(ifce, remoteIPAddr)
+		log.Printf("%s%sListening for frames...%s", ColorWhite, PrefixInfo, ColorReset)
+
+		go processFrames(ifce)
+
 	case "tcp":
 		log.Printf("%s%sStarting in TCP mode, listening on port %d...%s", ColorWhite, PrefixInfo, *listenPort, ColorReset)
+		if *pcapFile != "" {
+			// TCPモードはOSのTCP/IPスタックを使うため、パケットを取得できない
+			log.Printf("%s%s-pcap is ignored in tcp mode (packets are handled by the OS network stack)%s", ColorYellow, PrefixWarn, ColorReset)
+		}
 		runTCPMode(*listenPort) // Call the TCP mode function (defined in tcp.go)
 
 	default:
-		log.Fatalf("%s%sInvalid mode: %s. Choose 'tun' or 'tcp'.%s", ColorRed, PrefixError, *mode, ColorReset)
+		log.Fatalf("%s%sInvalid mode: %s. Choose 'tun', 'tap' or 'tcp'.%s", ColorRed, PrefixError, *mode, ColorReset)
 	}
 
 	// Setup signal handling for graceful shutdown (common to both modes)
@@ -151,3 +232,82 @@ func main() {
 	log.Printf("\n%s%sShutting down signal received...%s", ColorYellow, PrefixInfo, ColorReset) // Add newline for clarity
 	// Cleanup (like closing TUN) is handled by defer or specific mode logic
 }
+
+// setupIPv6Stack enables IPv6 on the stack's address -remoteIP6 and configures -localIP6 on the
+// device. IPv6 stays disabled if -remoteIP6 is not set.
+func setupIPv6Stack(ifce *water.Interface) {
+	if *remoteIP6 == "" {
+		return
+	}
+	localIP6Addr := net.ParseIP(*localIP6)
+	remoteIP6Addr := net.ParseIP(*remoteIP6)
+	if localIP6Addr == nil || remoteIP6Addr == nil || localIP6Addr.To4() != nil || remoteIP6Addr.To4() != nil {
+		log.Fatalf("%s%sInvalid localIP6 or remoteIP6 address format (both are required for IPv6)%s", ColorRed, PrefixError, ColorReset)
+	}
+	if *prefixLen6 < 1 || *prefixLen6 > 128 {
+		log.Fatalf("%s%sInvalid IPv6 prefix length: %d%s", ColorRed, PrefixError, *prefixLen6, ColorReset)
+	}
+	if err := setupIPv6(ifce.Name(), localIP6Addr.String(), remoteIP6Addr.String(), *prefixLen6); err != nil {
+		log.Fatalf("%s%sFailed to setup IPv6: %v%s", ColorRed, PrefixError, err, ColorReset)
+	}
+	stackIP6 = remoteIP6Addr.To16()
+	log.Printf("%s%s IPv6 enabled: Host IP: %s, Stack IP: %s/%d%s", ColorWhite, PrefixInfo, localIP6Addr, stackIP6, *prefixLen6, ColorReset)
+}
+
+// setupPacketCapture starts writing every packet on the device to the -pcap file (if specified).
+// linkType is LinkTypeRaw for TUN mode (IP packets) and LinkTypeEthernet for TAP mode (Ethernet frames).
+func setupPacketCapture(ifName string, linkType uint16) {
+	if *pcapFile == "" {
+		return
+	}
+	capture, err := openPacketCapture(*pcapFile, linkType, ifName)
+	if err != nil {
+		log.Fatalf("%s%sFailed to open pcap file '%s': %v%s", ColorRed, PrefixError, *pcapFile, err, ColorReset)
+	}
+	packetCapture = capture
+	log.Printf("%s%sCapturing packets on '%s' to '%s' (pcapng)%s", ColorWhite, PrefixInfo, ifName, *pcapFile, ColorReset)
+}
+
+// setupSocketStack creates the TCP socket layer on the stack's addresses and starts the built-in
+// HTTP (port 80) and HTTPS (port 443) servers on it. With -httpGet, the URL is fetched through
+// the socket layer as well.
+func setupSocketStack(ifce *water.Interface, stackIPAddr net.IP) {
+	localIPs := []net.IP{stackIPAddr}
+	if stackIP6 != nil {
+		localIPs = append(localIPs, stackIP6)
+	}
+	socketStack = netstack.New(func(seg netstack.Segment) error {
+		return sendTCPSegment(ifce, seg)
+	}, netstack.Config{
+		LocalIPs: localIPs,
+		MTU:      *mtu,
+		Logf: func(format string, args ...any) {
+			log.Printf("%s%s%s%s", ColorYellow, PrefixState, fmt.Sprintf(format, args...), ColorReset)
+		},
+	})
+
+	for _, port := range []int{80, 443} {
+		listener, err := socketStack.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			log.Fatalf("%s%sFailed to listen on port %d: %v%s", ColorRed, PrefixError, port, err, ColorReset)
+		}
+		go serveTCPListener(listener, port == 443)
+	}
+
+	if *httpGet != "" {
+		go runHTTPClient(*httpGet)
+	}
+}
+
+// setupDNSServer loads the DNS zone and starts answering DNS queries on the stack's address
+// (like the TCP services). The DNS server is disabled if the zone file cannot be loaded.
+func setupDNSServer(serverIP net.IP) {
+	zone, err := loadDNSZone(*zoneFile)
+	if err != nil {
+		log.Printf("%s%sFailed to load DNS zone file '%s', DNS server disabled: %v%s", ColorYellow, PrefixWarn, *zoneFile, err, ColorReset)
+		return
+	}
+	dnsZone = zone
+	dnsServerIP = serverIP
+	log.Printf("%s%sDNS server listening on %s:%d with %d names from '%s'%s", ColorWhite, PrefixInfo, dnsServerIP, DNSPort, len(dnsZone), *zoneFile, ColorReset)
+}
@@ -0,0 +1,935 @@
+package netstack
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// State is the state of a TCP connection (RFC 9293 3.3.2).
+type State int
+
+const (
+	StateClosed State = iota
+	StateSynSent
+	StateSynReceived
+	StateEstablished
+	StateFinWait1
+	StateFinWait2
+	StateCloseWait
+	StateClosing
+	StateLastAck
+	StateTimeWait
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "CLOSED"
+	case StateSynSent:
+		return "SYN_SENT"
+	case StateSynReceived:
+		return "SYN_RECEIVED"
+	case StateEstablished:
+		return "ESTABLISHED"
+	case StateFinWait1:
+		return "FIN_WAIT_1"
+	case StateFinWait2:
+		return "FIN_WAIT_2"
+	case StateCloseWait:
+		return "CLOSE_WAIT"
+	case StateClosing:
+		return "CLOSING"
+	case StateLastAck:
+		return "LAST_ACK"
+	case StateTimeWait:
+		return "TIME_WAIT"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+const (
+	// ウィンドウスケールを使うとバッファ全体を受信ウィンドウとして通知できる
+	sendBufferSize    = 1024 * 1024
+	receiveBufferSize = 1024 * 1024
+	defaultPeerMSS    = 536 // RFC 9293 3.7.1: MSS when the peer sent no MSS option
+	initialRTO        = 1 * time.Second
+	maxRTO            = 60 * time.Second
+	maxRetransmits    = 8
+	dupAckThreshold   = 3 // RFC 5681 3.2: duplicate ACKs that trigger fast retransmit
+	// 本来は2MSL (数分) だが、デモ用に短くしている
+	timeWaitDuration = 2 * time.Second
+)
+
+// Conn is a TCP connection of the Stack. It implements net.Conn.
+type Conn struct {
+	stack    *Stack
+	id       connID
+	listener *Listener // listener that accepted the connection (nil for Dial)
+
+	mu    sync.Mutex
+	state State
+	err   error // set when the connection is reset or timed out
+
+	// Send sequence variables (RFC 9293 3.3.1)
+	iss    uint32
+	sndUna uint32 // oldest unacknowledged sequence number
+	sndNxt uint32 // next sequence number to send
+	sndWnd uint32 // window advertised by the peer
+	// Receive sequence variables
+	irs    uint32
+	rcvNxt uint32
+	rcvWnd uint32 // window advertised in the last segment sent
+
+	mss     int    // maximum payload size of a segment sent to the peer
+	sendBuf []byte // data not acknowledged yet; sendBuf[0] is the byte at sndUna
+	recvBuf []byte // received data not read by the application yet
+
+	// Options negotiated in the SYN exchange
+	windowScaling bool // both sides sent Window Scale (RFC 7323)
+	sndWndShift   int  // scale of the windows advertised by the peer
+	rcvWndShift   int  // scale of the windows we advertise
+	sackOK        bool // both sides sent SACK-Permitted (RFC 2018)
+
+	outOfOrder     []outOfOrderBlock // data received beyond rcvNxt, sorted and not overlapping
+	lastOutOfOrder uint32            // seq of the latest out-of-order segment (first SACK block)
+	sacked         []seqRange        // scoreboard: data above sndUna the peer reported via SACK
+
+	dupAcks    int    // consecutive duplicate ACKs
+	recovering bool   // in fast recovery until recover is acknowledged
+	recover    uint32 // sndNxt when fast recovery started
+	rexmitNxt  uint32 // next sequence number to retransmit during fast recovery
+
+	closing    bool // Close has been called; FIN is sent after all data in sendBuf
+	finSent    bool // FIN occupies the sequence number sndNxt-1
+	peerClosed bool // FIN received; Read returns io.EOF once recvBuf is drained
+
+	rto          time.Duration
+	retransmits  int
+	timer        *time.Timer
+	timerRunning bool
+
+	readDeadline  time.Time
+	writeDeadline time.Time
+	changed       chan struct{} // closed (and replaced) on every change to wake up blocked calls
+}
+
+// outOfOrderBlock is data received ahead of rcvNxt.
+type outOfOrderBlock struct {
+	seq  uint32
+	data []byte
+}
+
+func newConn(s *Stack, id connID, state State) *Conn {
+	return &Conn{
+		stack:   s,
+		id:      id,
+		state:   state,
+		mss:     defaultPeerMSS,
+		rto:     initialRTO,
+		changed: make(chan struct{}),
+	}
+}
+
+// --- net.Conn ---
+
+// Read reads data received from the peer. It returns io.EOF after the peer closed its side.
+func (c *Conn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		if len(c.recvBuf) > 0 {
+			n := copy(b, c.recvBuf)
+			c.recvBuf = c.recvBuf[n:]
+			if len(c.recvBuf) == 0 {
+				c.recvBuf = nil
+			}
+			// ウィンドウが閉じかけていた場合は、空いたことを通知する (window update)
+			if c.rcvWnd < uint32(c.mss) && c.receiveWindow() >= uint32(c.mss) && c.state != StateClosed {
+				c.sendAck()
+			}
+			return n, nil
+		}
+		switch {
+		case c.closing:
+			return 0, c.opError("read", net.ErrClosed)
+		case c.err != nil:
+			return 0, c.opError("read", c.err)
+		case c.peerClosed:
+			return 0, io.EOF
+		}
+		if err := c.wait(c.readDeadline); err != nil {
+			return 0, c.opError("read", err)
+		}
+	}
+}
+
+// Write queues the data for sending. It blocks while the send buffer is full.
+func (c *Conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	written := 0
+	for written < len(b) {
+		switch {
+		case c.closing:
+			return written, c.opError("write", net.ErrClosed)
+		case c.err != nil:
+			return written, c.opError("write", c.err)
+		case c.state != StateEstablished && c.state != StateCloseWait:
+			return written, c.opError("write", fmt.Errorf("connection is %s", c.state))
+		}
+		space := sendBufferSize - len(c.sendBuf)
+		if space <= 0 {
+			if err := c.wait(c.writeDeadline); err != nil {
+				return written, c.opError("write", err)
+			}
+			continue
+		}
+		n := min(space, len(b)-written)
+		c.sendBuf = append(c.sendBuf, b[written:written+n]...)
+		written += n
+		c.output()
+	}
+	return written, nil
+}
+
+// Close closes the connection. Data already written is still delivered before the FIN.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return c.opError("close", net.ErrClosed)
+	}
+	c.closing = true
+	c.recvBuf = nil
+
+	switch c.state {
+	case StateSynSent, StateSynReceived:
+		c.finish(nil)
+	case StateEstablished:
+		c.setState(StateFinWait1)
+		c.output()
+	case StateCloseWait:
+		c.setState(StateLastAck)
+		c.output()
+	}
+	c.wake()
+	return nil
+}
+
+// LocalAddr returns the local address of the connection.
+func (c *Conn) LocalAddr() net.Addr {
+	return net.TCPAddrFromAddrPort(c.id.local)
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return net.TCPAddrFromAddrPort(c.id.remote)
+}
+
+// SetDeadline sets the read and write deadlines.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	c.wake()
+	return nil
+}
+
+// SetReadDeadline sets the deadline for Read.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	c.wake()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for Write.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	c.wake()
+	return nil
+}
+
+// State returns the current state of the connection.
+func (c *Conn) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// --- Opening ---
+
+// openActive sends the SYN of Dial. c.mu must be held.
+func (c *Conn) openActive() {
+	c.iss = rand.Uint32()
+	c.sndUna = c.iss
+	c.sndNxt = c.iss + 1
+	c.stack.logf("%s: SYN_SENT", c.id)
+	c.sendSYN()
+}
+
+// openPassive answers the SYN received on a listening port with SYN-ACK. c.mu must be held.
+func (c *Conn) openPassive(syn Segment) {
+	c.irs = syn.Seq
+	c.rcvNxt = syn.Seq + 1
+	c.sndWnd = uint32(syn.Window)
+	c.setPeerOptions(syn)
+	c.iss = rand.Uint32()
+	c.sndUna = c.iss
+	c.sndNxt = c.iss + 1
+	c.stack.logf("%s: SYN_RECEIVED", c.id)
+	c.sendSYN()
+}
+
+// sendSYN (re)sends the SYN (SYN-SENT) or SYN-ACK (SYN-RECEIVED) with our options.
+func (c *Conn) sendSYN() {
+	flags := uint8(FlagSYN)
+	windowShift, sackPermitted := windowShiftFor(receiveBufferSize), true
+	if c.state == StateSynReceived {
+		// SYN-ACKでは相手のSYNにあったオプションだけを返す
+		flags |= FlagACK
+		if !c.windowScaling {
+			windowShift = -1
+		}
+		sackPermitted = c.sackOK
+	}
+	c.sendSegment(flags, c.iss, nil, buildSYNOptions(c.localMSS(), windowShift, sackPermitted))
+	c.startTimer()
+}
+
+// setPeerOptions applies the options of the peer's SYN. Window scaling and SACK are only used
+// when both sides sent the option; we always send them in our SYN.
+func (c *Conn) setPeerOptions(syn Segment) {
+	opts := parseOptions(syn.Options)
+	peerMSS := opts.mss
+	if peerMSS == 0 {
+		peerMSS = defaultPeerMSS
+	}
+	c.mss = min(peerMSS, c.localMSS())
+	if opts.windowShift >= 0 {
+		c.windowScaling = true
+		c.sndWndShift = opts.windowShift
+		c.rcvWndShift = windowShiftFor(receiveBufferSize)
+	}
+	c.sackOK = opts.sackPermitted
+	c.stack.logf("%s: MSS %d, window scale %v (send %d, receive %d), SACK %v", c.id, c.mss, c.windowScaling, c.sndWndShift, c.rcvWndShift, c.sackOK)
+}
+
+// localMSS is the largest payload that fits in one IP packet on the device.
+func (c *Conn) localMSS() int {
+	headers := 20 + 20 // IPv4 + TCP
+	if c.id.local.Addr().Is6() {
+		headers = 40 + 20 // IPv6 + TCP
+	}
+	return c.stack.config.MTU - headers
+}
+
+// --- Segment arrival (RFC 9293 3.10.7) ---
+
+// handleSegment processes a segment received for this connection.
+func (c *Conn) handleSegment(seg Segment) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if seg.Flags&FlagRST != 0 {
+		c.handleReset(seg)
+		return
+	}
+
+	switch c.state {
+	case StateClosed:
+		return
+	case StateSynSent:
+		c.handleSynSent(seg)
+		return
+	case StateSynReceived:
+		if seg.Flags&FlagSYN != 0 && seg.Flags&FlagACK == 0 {
+			// SYN-ACKが失われたため再送されたSYN
+			c.sendSYN()
+			return
+		}
+		if seg.Flags&FlagACK == 0 || seg.Ack != c.iss+1 {
+			return
+		}
+		c.sndUna = seg.Ack
+		c.sndWnd = uint32(seg.Window) << c.sndWndShift
+		c.stopTimer()
+		c.setState(StateEstablished)
+		c.stack.leaveSynReceived(c)
+		if !c.listener.enqueue(c) {
+			c.stack.logf("%s: listener is closed or its backlog is full, resetting", c.id)
+			c.sendSegment(FlagRST, c.sndNxt, nil, nil)
+			c.finish(nil)
+			return
+		}
+		// ACKと同じセグメントで届いたデータは以降で処理する
+	}
+
+	if seg.Flags&FlagACK != 0 {
+		if !c.handleAck(seg) {
+			return
+		}
+	}
+	c.handleData(seg)
+}
+
+// handleSynSent completes the active open when the SYN-ACK arrives.
+func (c *Conn) handleSynSent(seg Segment) {
+	if seg.Flags&FlagACK != 0 && seg.Ack != c.iss+1 {
+		c.sendSegment(FlagRST, seg.Ack, nil, nil)
+		return
+	}
+	if seg.Flags&(FlagSYN|FlagACK) != FlagSYN|FlagACK {
+		// 同時オープン (SYNのみ受信) には対応しない
+		return
+	}
+	c.irs = seg.Seq
+	c.rcvNxt = seg.Seq + 1
+	c.sndUna = seg.Ack
+	c.sndWnd = uint32(seg.Window) // SYNのウィンドウはスケールしない (RFC 7323 2.2)
+	c.setPeerOptions(seg)
+	c.stopTimer()
+	c.setState(StateEstablished)
+	c.sendAck()
+}
+
+// handleReset aborts the connection on RST.
+func (c *Conn) handleReset(seg Segment) {
+	if c.state == StateSynSent {
+		if seg.Flags&FlagACK == 0 || seg.Ack != c.iss+1 {
+			return
+		}
+		c.stack.logf("%s: connection refused", c.id)
+		c.finish(ErrConnectionRefused)
+		return
+	}
+	// RFC 5961: ウィンドウ外のRSTは無視する (簡易的に rcvNxt との一致のみ確認)
+	if seg.Seq != c.rcvNxt {
+		return
+	}
+	c.stack.logf("%s: connection reset by peer", c.id)
+	c.finish(ErrConnectionReset)
+}
+
+// handleAck processes the acknowledgment and window of the segment.
+// It reports false if the connection has been closed.
+func (c *Conn) handleAck(seg Segment) bool {
+	if seqLT(c.sndNxt, seg.Ack) {
+		// まだ送っていないデータへのACK: ACKを返して破棄する
+		c.sendAck()
+		return false
+	}
+	if c.sackOK {
+		c.updateScoreboard(parseOptions(seg.Options).sackBlocks)
+	}
+	window := uint32(seg.Window) << c.sndWndShift
+	if seqLT(c.sndUna, seg.Ack) {
+		acked := int(seg.Ack - c.sndUna)
+		c.sendBuf = c.sendBuf[min(acked, len(c.sendBuf)):]
+		c.sndUna = seg.Ack
+		c.retransmits = 0
+		c.rto = initialRTO
+		c.dupAcks = 0
+		c.trimScoreboard()
+		if c.sndUna == c.sndNxt {
+			c.stopTimer()
+		} else {
+			c.restartTimer()
+		}
+		if c.recovering {
+			if seqLT(c.sndUna, c.recover) {
+				// partial ACK: 次に失われたセグメントを再送する (RFC 6582)
+				c.retransmitLost()
+			} else {
+				c.recovering = false
+			}
+		}
+
+		if c.finSent && c.sndUna == c.sndNxt {
+			// 自分のFINがACKされた
+			switch c.state {
+			case StateFinWait1:
+				c.setState(StateFinWait2)
+			case StateClosing:
+				c.enterTimeWait()
+			case StateLastAck:
+				c.finish(nil)
+				return false
+			}
+		}
+		c.wake()
+	} else if c.isDuplicateAck(seg, window) {
+		c.dupAcks++
+		switch {
+		case c.dupAcks == dupAckThreshold && !c.recovering:
+			c.stack.logf("%s: %d duplicate ACKs for seq %d, fast retransmit (SACK %v)", c.id, c.dupAcks, c.sndUna, c.sackOK)
+			c.recovering = true
+			c.recover = c.sndNxt
+			c.rexmitNxt = c.sndUna
+			c.retransmitLost()
+		case c.recovering && c.sackOK:
+			// 新たにSACKされた範囲の手前の穴を再送する
+			c.retransmitLost()
+		}
+	}
+	if seqLEQ(c.sndUna, seg.Ack) {
+		c.sndWnd = window
+	}
+	c.output()
+	return true
+}
+
+// isDuplicateAck reports whether the segment is a duplicate ACK (RFC 5681 2): it acknowledges
+// nothing new while data is outstanding and carries no data and no window update.
+func (c *Conn) isDuplicateAck(seg Segment, window uint32) bool {
+	return seg.Ack == c.sndUna && c.sndUna != c.sndNxt &&
+		len(seg.Payload) == 0 && seg.Flags&(FlagSYN|FlagFIN) == 0 && window == c.sndWnd
+}
+
+// updateScoreboard records the SACK blocks of a received ACK. Blocks outside the data in
+// flight (e.g. D-SACK, RFC 2883) are ignored.
+func (c *Conn) updateScoreboard(blocks []seqRange) {
+	for _, b := range blocks {
+		if !seqLT(b.start, b.end) || !seqLT(c.sndUna, b.end) || seqLT(c.sndNxt, b.end) {
+			continue
+		}
+		if seqLT(b.start, c.sndUna) {
+			b.start = c.sndUna
+		}
+		c.sacked = addRange(c.sacked, b)
+	}
+}
+
+// trimScoreboard drops the SACKed ranges that are now cumulatively acknowledged.
+func (c *Conn) trimScoreboard() {
+	for len(c.sacked) > 0 && seqLEQ(c.sacked[0].end, c.sndUna) {
+		c.sacked = c.sacked[1:]
+	}
+	if len(c.sacked) > 0 && seqLT(c.sacked[0].start, c.sndUna) {
+		c.sacked[0].start = c.sndUna
+	}
+	if len(c.sacked) == 0 {
+		c.sacked = nil
+	}
+}
+
+// retransmitLost retransmits during fast recovery. With SACK, every hole below the highest
+// SACKed sequence number that has not been retransmitted yet is sent; without SACK only the
+// segment at sndUna (NewReno).
+func (c *Conn) retransmitLost() {
+	sentEnd := c.sndNxt
+	if c.finSent {
+		sentEnd--
+	}
+	if !c.sackOK || len(c.sacked) == 0 {
+		if n := min(int(sentEnd-c.sndUna), c.mss); n > 0 {
+			c.sendData(c.sndUna, n)
+		}
+		return
+	}
+	seq := c.rexmitNxt
+	if seqLT(seq, c.sndUna) {
+		seq = c.sndUna
+	}
+	for _, r := range c.sacked {
+		for seqLT(seq, r.start) {
+			n := min(int(r.start-seq), c.mss)
+			c.sendData(seq, n)
+			seq += uint32(n)
+		}
+		if seqLT(seq, r.end) {
+			seq = r.end
+		}
+	}
+	c.rexmitNxt = seq
+}
+
+// handleData buffers in-order data and processes the FIN of the segment.
+func (c *Conn) handleData(seg Segment) {
+	fin := seg.Flags&FlagFIN != 0
+	if len(seg.Payload) == 0 && !fin {
+		return
+	}
+	switch c.state {
+	case StateEstablished, StateFinWait1, StateFinWait2:
+	default:
+		// 相手のFINは受信済み: 再送されたセグメントにACKを返すだけ
+		c.sendAck()
+		return
+	}
+	seq, payload := seg.Seq, seg.Payload
+	if seqLT(seq, c.rcvNxt) {
+		// 再送などで受信済みの部分は捨てる
+		dup := int(c.rcvNxt - seq)
+		if dup > len(payload) || (dup == len(payload) && !fin) {
+			c.sendAck()
+			return
+		}
+		seq, payload = c.rcvNxt, payload[dup:]
+	}
+	if seq != c.rcvNxt {
+		// 順序外のセグメントはバッファし、受信済みの範囲をSACKで通知する (FINは再送を待つ)
+		c.queueOutOfOrder(seq, payload)
+		c.sendAck()
+		return
+	}
+
+	if window := int(c.receiveWindow()); len(payload) > window {
+		// 受信バッファに入りきらない分は破棄する (相手が再送する)
+		payload = payload[:window]
+		fin = false
+	}
+	if !c.closing {
+		c.recvBuf = append(c.recvBuf, payload...)
+	}
+	c.rcvNxt += uint32(len(payload))
+	if fin {
+		c.outOfOrder = nil
+	} else {
+		c.drainOutOfOrder()
+	}
+
+	if fin {
+		c.rcvNxt++
+		c.peerClosed = true
+		switch c.state {
+		case StateEstablished:
+			c.setState(StateCloseWait)
+		case StateFinWait1:
+			c.setState(StateClosing)
+		case StateFinWait2:
+			c.enterTimeWait()
+		}
+	}
+	c.sendAck()
+	c.wake()
+}
+
+// queueOutOfOrder keeps data received ahead of rcvNxt (within the receive window), merging it
+// with the blocks already queued.
+func (c *Conn) queueOutOfOrder(seq uint32, data []byte) {
+	limit := c.rcvNxt + c.receiveWindow()
+	if !seqLT(seq, limit) || len(data) == 0 {
+		return
+	}
+	if end := seq + uint32(len(data)); seqLT(limit, end) {
+		data = data[:limit-seq]
+	}
+	c.lastOutOfOrder = seq
+
+	block := outOfOrderBlock{seq: seq, data: append([]byte(nil), data...)}
+	var blocks []outOfOrderBlock
+	for _, b := range c.outOfOrder {
+		bEnd := b.seq + uint32(len(b.data))
+		end := block.seq + uint32(len(block.data))
+		if seqLT(bEnd, block.seq) || seqLT(end, b.seq) {
+			blocks = append(blocks, b)
+			continue
+		}
+		// 重なる (または隣接する) ブロックを1つにまとめる。重なった部分は新しいデータを使う
+		start := block.seq
+		if seqLT(b.seq, start) {
+			start = b.seq
+		}
+		if seqLT(end, bEnd) {
+			end = bEnd
+		}
+		merged := make([]byte, end-start)
+		copy(merged[b.seq-start:], b.data)
+		copy(merged[block.seq-start:], block.data)
+		block = outOfOrderBlock{seq: start, data: merged}
+	}
+	i := 0
+	for i < len(blocks) && seqLT(blocks[i].seq, block.seq) {
+		i++
+	}
+	c.outOfOrder = append(blocks[:i], append([]outOfOrderBlock{block}, blocks[i:]...)...)
+}
+
+// drainOutOfOrder moves the queued blocks that have become in-order to recvBuf.
+func (c *Conn) drainOutOfOrder() {
+	for len(c.outOfOrder) > 0 && seqLEQ(c.outOfOrder[0].seq, c.rcvNxt) {
+		b := c.outOfOrder[0]
+		c.outOfOrder = c.outOfOrder[1:]
+		if end := b.seq + uint32(len(b.data)); seqLT(c.rcvNxt, end) {
+			if !c.closing {
+				c.recvBuf = append(c.recvBuf, b.data[c.rcvNxt-b.seq:]...)
+			}
+			c.rcvNxt = end
+		}
+	}
+	if len(c.outOfOrder) == 0 {
+		c.outOfOrder = nil
+	}
+}
+
+// sackBlocks returns the out-of-order blocks to report, starting with the block containing
+// the latest received segment (RFC 2018 4) followed by the others from the highest.
+func (c *Conn) sackBlocks() []seqRange {
+	var first []seqRange
+	var rest []seqRange
+	for i := len(c.outOfOrder) - 1; i >= 0; i-- {
+		b := c.outOfOrder[i]
+		r := seqRange{start: b.seq, end: b.seq + uint32(len(b.data))}
+		if seqLEQ(r.start, c.lastOutOfOrder) && seqLT(c.lastOutOfOrder, r.end) {
+			first = append(first, r)
+		} else {
+			rest = append(rest, r)
+		}
+	}
+	return append(first, rest...)
+}
+
+// --- Sending ---
+
+// output sends the data of sendBuf that fits in the peer's window, followed by the FIN once
+// Close has been called and all data has been sent. c.mu must be held.
+func (c *Conn) output() {
+	switch c.state {
+	case StateEstablished, StateCloseWait, StateFinWait1, StateClosing, StateLastAck:
+	default:
+		return
+	}
+	for !c.finSent {
+		inFlight := int(c.sndNxt - c.sndUna)
+		unsent := len(c.sendBuf) - inFlight
+		window := int(c.sndWnd) - inFlight
+		if unsent > 0 && window > 0 {
+			n := min(unsent, window, c.mss)
+			flags := uint8(FlagACK)
+			if n == unsent {
+				flags |= FlagPSH
+			}
+			c.sendSegment(flags, c.sndNxt, c.sendBuf[inFlight:inFlight+n], nil)
+			c.sndNxt += uint32(n)
+			c.startTimer()
+			continue
+		}
+		if unsent == 0 && c.closing {
+			c.sendSegment(FlagFIN|FlagACK, c.sndNxt, nil, nil)
+			c.sndNxt++
+			c.finSent = true
+			c.startTimer()
+		} else if unsent > 0 {
+			// ゼロウィンドウ: タイマーでウィンドウプローブを送る (persist timer)
+			c.startTimer()
+		}
+		return
+	}
+}
+
+// sendAck sends an ACK for everything received so far, with SACK blocks for the data
+// received out of order.
+func (c *Conn) sendAck() {
+	var options []byte
+	if c.sackOK && len(c.outOfOrder) > 0 {
+		options = buildSACKOption(c.sackBlocks())
+	}
+	c.sendSegment(FlagACK, c.sndNxt, nil, options)
+}
+
+// sendData (re)sends n bytes of sendBuf starting at seq.
+func (c *Conn) sendData(seq uint32, n int) {
+	offset := int(seq - c.sndUna)
+	c.sendSegment(FlagACK, seq, c.sendBuf[offset:offset+n], nil)
+}
+
+// sendSegment builds a segment of this connection and hands it to the IP layer.
+func (c *Conn) sendSegment(flags uint8, seq uint32, payload []byte, options []byte) {
+	seg := Segment{
+		SrcIP:   c.id.local.Addr().AsSlice(),
+		DstIP:   c.id.remote.Addr().AsSlice(),
+		SrcPort: c.id.local.Port(),
+		DstPort: c.id.remote.Port(),
+		Seq:     seq,
+		Flags:   flags,
+		Options: options,
+		Payload: payload,
+	}
+	if flags&FlagACK != 0 {
+		seg.Ack = c.rcvNxt
+	}
+	if flags&FlagRST == 0 {
+		shift := c.rcvWndShift
+		if flags&FlagSYN != 0 {
+			shift = 0 // SYNのウィンドウはスケールしない (RFC 7323 2.2)
+		}
+		window := min(c.receiveWindow()>>shift, 0xFFFF)
+		c.rcvWnd = window << shift
+		seg.Window = uint16(window)
+	}
+	if err := c.stack.send(seg); err != nil {
+		c.stack.logf("%s: failed to send segment: %v", c.id, err)
+	}
+}
+
+// receiveWindow is the free space of the receive buffer.
+func (c *Conn) receiveWindow() uint32 {
+	return uint32(max(receiveBufferSize-len(c.recvBuf), 0))
+}
+
+// --- Retransmission ---
+
+func (c *Conn) startTimer() {
+	if !c.timerRunning {
+		c.restartTimer()
+	}
+}
+
+func (c *Conn) restartTimer() {
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.rto, c.onRetransmitTimeout)
+	} else {
+		c.timer.Reset(c.rto)
+	}
+	c.timerRunning = true
+}
+
+func (c *Conn) stopTimer() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timerRunning = false
+}
+
+// onRetransmitTimeout retransmits everything from sndUna (go-back-N) with exponential backoff.
+func (c *Conn) onRetransmitTimeout() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.timerRunning {
+		return
+	}
+	c.timerRunning = false
+
+	switch c.state {
+	case StateClosed, StateTimeWait, StateFinWait2:
+		return
+	}
+	c.retransmits++
+	if c.retransmits > maxRetransmits {
+		c.stack.logf("%s: retransmission limit reached in %s, giving up", c.id, c.state)
+		c.sendSegment(FlagRST, c.sndNxt, nil, nil)
+		c.finish(ErrConnectionTimedOut)
+		return
+	}
+	c.rto = min(c.rto*2, maxRTO)
+
+	switch c.state {
+	case StateSynSent, StateSynReceived:
+		c.stack.logf("%s: retransmitting SYN (attempt %d)", c.id, c.retransmits)
+		c.sendSYN()
+		return
+	}
+
+	if c.sndNxt == c.sndUna {
+		if len(c.sendBuf) > 0 {
+			// ウィンドウプローブとして1バイトだけ送る
+			c.sendSegment(FlagACK, c.sndNxt, c.sendBuf[:1], nil)
+			c.sndNxt++
+			c.startTimer()
+		}
+		return
+	}
+	c.stack.logf("%s: retransmitting from seq %d (%d bytes in flight, attempt %d)", c.id, c.sndUna, c.sndNxt-c.sndUna, c.retransmits)
+	// 受信側がSACKしたデータを破棄している可能性があるため、スコアボードも捨てる (RFC 2018 8)
+	c.sacked = nil
+	c.recovering = false
+	c.dupAcks = 0
+	c.sndNxt = c.sndUna
+	c.finSent = false
+	c.output()
+}
+
+// --- Closing ---
+
+// enterTimeWait moves to TIME-WAIT and removes the connection after timeWaitDuration.
+func (c *Conn) enterTimeWait() {
+	c.stopTimer()
+	c.setState(StateTimeWait)
+	time.AfterFunc(timeWaitDuration, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.finish(nil)
+	})
+}
+
+// finish closes the connection and removes it from the stack. c.mu must be held.
+func (c *Conn) finish(err error) {
+	if c.state == StateClosed {
+		return
+	}
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	c.stopTimer()
+	c.setState(StateClosed)
+	c.sendBuf = nil
+	c.outOfOrder = nil
+	c.sacked = nil
+	c.stack.leaveSynReceived(c)
+	c.stack.removeConn(c)
+	c.wake()
+}
+
+// abort resets a connection that was never accepted (listener closed).
+func (c *Conn) abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == StateClosed {
+		return
+	}
+	c.sendSegment(FlagRST, c.sndNxt, nil, nil)
+	c.finish(ErrConnectionReset)
+}
+
+// --- Helpers ---
+
+func (c *Conn) setState(state State) {
+	if c.state == state {
+		return
+	}
+	c.stack.logf("%s: %s -> %s", c.id, c.state, state)
+	c.state = state
+	c.wake()
+}
+
+// wake wakes up the goroutines blocked in wait.
+func (c *Conn) wake() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// wait releases c.mu until the connection changes or the deadline expires.
+func (c *Conn) wait(deadline time.Time) error {
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return os.ErrDeadlineExceeded
+	}
+	changed := c.changed
+	c.mu.Unlock()
+	defer c.mu.Lock()
+	if deadline.IsZero() {
+		<-changed
+		return nil
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-changed:
+	case <-timer.C:
+	}
+	return nil
+}
+
+func (c *Conn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "tcp", Source: c.LocalAddr(), Addr: c.RemoteAddr(), Err: err}
+}
@@ -0,0 +1,75 @@
+package netstack
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// Listener is a TCP listener of the Stack. It implements net.Listener.
+type Listener struct {
+	stack *Stack
+	ip    netip.Addr // invalid = any address of the stack
+	port  uint16
+
+	acceptCh    chan *Conn         // established connections waiting for Accept
+	synReceived map[*Conn]struct{} // connections in the three-way handshake (guarded by stack.mu)
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+// Accept waits for and returns the next connection whose three-way handshake has completed.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.acceptCh:
+		return c, nil
+	case <-l.closed:
+		return nil, &net.OpError{Op: "accept", Net: "tcp", Addr: l.Addr(), Err: net.ErrClosed}
+	}
+}
+
+// Close stops listening. Connections that have not been accepted yet are reset.
+func (l *Listener) Close() error {
+	first := false
+	l.closeOnce.Do(func() {
+		first = true
+		close(l.closed)
+	})
+	if !first {
+		return &net.OpError{Op: "close", Net: "tcp", Addr: l.Addr(), Err: net.ErrClosed}
+	}
+
+	for _, c := range l.stack.removeListener(l) {
+		c.abort()
+	}
+	for {
+		select {
+		case c := <-l.acceptCh:
+			c.abort()
+		default:
+			l.stack.logf("Stopped listening on %s", l.Addr())
+			return nil
+		}
+	}
+}
+
+// Addr returns the listening address.
+func (l *Listener) Addr() net.Addr {
+	return &net.TCPAddr{IP: l.ip.AsSlice(), Port: int(l.port)}
+}
+
+// enqueue hands an established connection to Accept. It reports false if the listener is closed
+// or its backlog is full.
+func (l *Listener) enqueue(c *Conn) bool {
+	select {
+	case <-l.closed:
+		return false
+	default:
+	}
+	select {
+	case l.acceptCh <- c:
+		return true
+	default:
+		return false
+	}
+}
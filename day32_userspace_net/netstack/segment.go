@@ -0,0 +1,216 @@
+package netstack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// TCP flags (lower 8 bits of the flags field, same layout as the TCP header).
+const (
+	FlagFIN = 1 << 0
+	FlagSYN = 1 << 1
+	FlagRST = 1 << 2
+	FlagPSH = 1 << 3
+	FlagACK = 1 << 4
+)
+
+// TCP option kinds (RFC 9293, RFC 7323, RFC 2018)
+const (
+	optionEndOfList     = 0
+	optionNOP           = 1
+	optionMSS           = 2
+	optionWindowScale   = 3
+	optionSACKPermitted = 4
+	optionSACK          = 5
+)
+
+const (
+	maxWindowShift = 14 // RFC 7323 2.3: larger shift counts are treated as 14
+	maxSACKBlocks  = 4  // 40 bytes of options hold at most 4 SACK blocks (without timestamps)
+)
+
+// Segment is a TCP segment exchanged between the IP layer of the userspace stack and the
+// socket layer. Options holds the raw TCP options (without padding).
+type Segment struct {
+	SrcIP   net.IP
+	DstIP   net.IP
+	SrcPort uint16
+	DstPort uint16
+	Seq     uint32
+	Ack     uint32
+	Flags   uint8
+	Window  uint16
+	Options []byte
+	Payload []byte
+}
+
+// seqLen returns the sequence space occupied by the segment (SYN and FIN count as one byte).
+func (s Segment) seqLen() uint32 {
+	n := uint32(len(s.Payload))
+	if s.Flags&FlagSYN != 0 {
+		n++
+	}
+	if s.Flags&FlagFIN != 0 {
+		n++
+	}
+	return n
+}
+
+// connID identifies a connection by its local and remote address.
+type connID struct {
+	local  netip.AddrPort
+	remote netip.AddrPort
+}
+
+// newConnID builds the connID of a received segment (local = destination of the segment).
+func newConnID(localIP net.IP, localPort uint16, remoteIP net.IP, remotePort uint16) (connID, bool) {
+	local, ok := netip.AddrFromSlice(localIP)
+	if !ok {
+		return connID{}, false
+	}
+	remote, ok := netip.AddrFromSlice(remoteIP)
+	if !ok {
+		return connID{}, false
+	}
+	return connID{
+		local:  netip.AddrPortFrom(local.Unmap(), localPort),
+		remote: netip.AddrPortFrom(remote.Unmap(), remotePort),
+	}, true
+}
+
+// String returns the connection in the "remote-local" format used by the stack's logs.
+func (id connID) String() string {
+	return fmt.Sprintf("%s-%s", id.remote, id.local)
+}
+
+// seqLT reports whether sequence number a is before b (modulo 2^32).
+func seqLT(a, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// seqLEQ reports whether sequence number a is before or equal to b (modulo 2^32).
+func seqLEQ(a, b uint32) bool {
+	return int32(a-b) <= 0
+}
+
+// seqRange is the sequence space [start, end).
+type seqRange struct {
+	start uint32
+	end   uint32
+}
+
+// options holds the TCP options of a received segment that the stack understands.
+type options struct {
+	mss           int // 0 = no MSS option
+	windowShift   int // -1 = no Window Scale option
+	sackPermitted bool
+	sackBlocks    []seqRange
+}
+
+// parseOptions decodes the TCP options of a segment. Unknown options are skipped and a
+// malformed option ends the parsing.
+func parseOptions(raw []byte) options {
+	opts := options{windowShift: -1}
+	for i := 0; i < len(raw); {
+		kind := raw[i]
+		switch kind {
+		case optionEndOfList:
+			return opts
+		case optionNOP:
+			i++
+			continue
+		}
+		if i+1 >= len(raw) {
+			return opts
+		}
+		length := int(raw[i+1])
+		if length < 2 || i+length > len(raw) {
+			return opts
+		}
+		value := raw[i+2 : i+length]
+		switch {
+		case kind == optionMSS && len(value) == 2:
+			opts.mss = int(binary.BigEndian.Uint16(value))
+		case kind == optionWindowScale && len(value) == 1:
+			opts.windowShift = min(int(value[0]), maxWindowShift)
+		case kind == optionSACKPermitted && len(value) == 0:
+			opts.sackPermitted = true
+		case kind == optionSACK && len(value) > 0 && len(value)%8 == 0:
+			for j := 0; j < len(value); j += 8 {
+				opts.sackBlocks = append(opts.sackBlocks, seqRange{
+					start: binary.BigEndian.Uint32(value[j : j+4]),
+					end:   binary.BigEndian.Uint32(value[j+4 : j+8]),
+				})
+			}
+		}
+		i += length
+	}
+	return opts
+}
+
+// buildSYNOptions encodes the options of a SYN or SYN-ACK segment: MSS, and optionally
+// SACK-Permitted and Window Scale (aligned with NOPs as common implementations do).
+func buildSYNOptions(mss int, windowShift int, sackPermitted bool) []byte {
+	option := []byte{optionMSS, 4, 0, 0}
+	binary.BigEndian.PutUint16(option[2:4], uint16(mss))
+	if sackPermitted {
+		option = append(option, optionNOP, optionNOP, optionSACKPermitted, 2)
+	}
+	if windowShift >= 0 {
+		option = append(option, optionNOP, optionWindowScale, 3, uint8(windowShift))
+	}
+	return option
+}
+
+// buildSACKOption encodes the SACK option (RFC 2018 3) for up to maxSACKBlocks blocks.
+func buildSACKOption(blocks []seqRange) []byte {
+	blocks = blocks[:min(len(blocks), maxSACKBlocks)]
+	option := []byte{optionNOP, optionNOP, optionSACK, uint8(2 + 8*len(blocks))}
+	for _, b := range blocks {
+		option = binary.BigEndian.AppendUint32(option, b.start)
+		option = binary.BigEndian.AppendUint32(option, b.end)
+	}
+	return option
+}
+
+// windowShiftFor returns the smallest window scale that lets a 16-bit window field advertise
+// a buffer of the given size.
+func windowShiftFor(size int) int {
+	shift := 0
+	for size>>shift > 0xFFFF && shift < maxWindowShift {
+		shift++
+	}
+	return shift
+}
+
+// addRange merges r into the sorted, non-overlapping ranges.
+func addRange(ranges []seqRange, r seqRange) []seqRange {
+	var merged []seqRange
+	inserted := false
+	for _, existing := range ranges {
+		switch {
+		case seqLT(existing.end, r.start):
+			merged = append(merged, existing)
+		case seqLT(r.end, existing.start):
+			if !inserted {
+				merged = append(merged, r)
+				inserted = true
+			}
+			merged = append(merged, existing)
+		default:
+			// 重なる (または隣接する) 範囲は1つにまとめる
+			if seqLT(existing.start, r.start) {
+				r.start = existing.start
+			}
+			if seqLT(r.end, existing.end) {
+				r.end = existing.end
+			}
+		}
+	}
+	if !inserted {
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,325 @@
+// Package netstack provides a socket-style API (Listen/Accept/Dial/Read/Write/Close) on top of
+// the TCP implementation of the userspace network stack.
+//
+// The package does not touch the TUN/TAP device: the IP layer passes received TCP segments to
+// Stack.HandleSegment and sends the segments handed to its SendFunc. Listeners and connections
+// implement net.Listener and net.Conn, so standard library code (net/http, crypto/tls, ...)
+// can run on the userspace stack.
+package netstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/netip"
+	"sync"
+)
+
+const (
+	defaultMTU         = 1500
+	ephemeralPortFirst = 49152
+	ephemeralPortLast  = 65535
+	listenBacklog      = 16
+)
+
+var (
+	// ErrConnectionRefused is returned by Dial when the peer answers the SYN with RST.
+	ErrConnectionRefused = errors.New("netstack: connection refused")
+	// ErrConnectionReset is returned by Read/Write after the peer reset the connection.
+	ErrConnectionReset = errors.New("netstack: connection reset by peer")
+	// ErrConnectionTimedOut is returned when a segment was retransmitted too many times.
+	ErrConnectionTimedOut = errors.New("netstack: connection timed out")
+)
+
+// SendFunc sends a TCP segment through the IP layer of the userspace stack.
+type SendFunc func(seg Segment) error
+
+// Config holds the settings of a Stack.
+type Config struct {
+	// LocalIPs are the addresses of the stack. Dial uses the one of the same address family as
+	// the destination as the source address.
+	LocalIPs []net.IP
+	// MTU of the device; the MSS announced in SYN segments is derived from it (default 1500).
+	MTU int
+	// Logf logs connection state changes (optional).
+	Logf func(format string, args ...any)
+}
+
+// Stack is the TCP socket layer of the userspace network stack.
+type Stack struct {
+	send   SendFunc
+	config Config
+
+	mu        sync.Mutex
+	listeners map[uint16]*Listener
+	conns     map[connID]*Conn
+	nextPort  uint16
+}
+
+// New creates a Stack that sends its segments with send.
+func New(send SendFunc, config Config) *Stack {
+	if config.MTU <= 0 {
+		config.MTU = defaultMTU
+	}
+	return &Stack{
+		send:      send,
+		config:    config,
+		listeners: make(map[uint16]*Listener),
+		conns:     make(map[connID]*Conn),
+		nextPort:  ephemeralPortFirst + uint16(rand.Intn(ephemeralPortLast-ephemeralPortFirst+1)),
+	}
+}
+
+func (s *Stack) logf(format string, args ...any) {
+	if s.config.Logf != nil {
+		s.config.Logf(format, args...)
+	}
+}
+
+// Listen announces on the local address (e.g. ":80"). Connections to any address of the stack
+// are accepted unless a specific IP is given.
+func (s *Stack) Listen(network, address string) (net.Listener, error) {
+	laddr, err := resolveTCPAddr(network, address)
+	if err != nil {
+		return nil, &net.OpError{Op: "listen", Net: network, Err: err}
+	}
+	var ip netip.Addr
+	if laddr.IP != nil && !laddr.IP.IsUnspecified() {
+		ip = laddr.AddrPort().Addr().Unmap()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	port := uint16(laddr.Port)
+	if port == 0 {
+		if port, err = s.allocatePortLocked(func(p uint16) bool { return s.listeners[p] == nil }); err != nil {
+			return nil, &net.OpError{Op: "listen", Net: network, Err: err}
+		}
+	}
+	if _, exists := s.listeners[port]; exists {
+		return nil, &net.OpError{Op: "listen", Net: network, Addr: laddr, Err: fmt.Errorf("port %d already in use", port)}
+	}
+
+	l := &Listener{
+		stack:       s,
+		ip:          ip,
+		port:        port,
+		acceptCh:    make(chan *Conn, listenBacklog),
+		closed:      make(chan struct{}),
+		synReceived: make(map[*Conn]struct{}),
+	}
+	s.listeners[port] = l
+	s.logf("Listening on %s", l.Addr())
+	return l, nil
+}
+
+// Dial connects to the address (e.g. "10.0.0.1:8080").
+func (s *Stack) Dial(network, address string) (net.Conn, error) {
+	return s.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address, giving up when ctx is done. It has the signature of
+// net/http.Transport.DialContext.
+func (s *Stack) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	raddr, err := resolveTCPAddr(network, address)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	remote := netip.AddrPortFrom(raddr.AddrPort().Addr().Unmap(), uint16(raddr.Port))
+	localIP, ok := s.localIPFor(remote.Addr())
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: raddr, Err: fmt.Errorf("no local address for %s", remote.Addr())}
+	}
+
+	s.mu.Lock()
+	port, err := s.allocatePortLocked(func(p uint16) bool {
+		return s.conns[connID{local: netip.AddrPortFrom(localIP, p), remote: remote}] == nil
+	})
+	if err != nil {
+		s.mu.Unlock()
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: raddr, Err: err}
+	}
+	c := newConn(s, connID{local: netip.AddrPortFrom(localIP, port), remote: remote}, StateSynSent)
+	s.conns[c.id] = c
+	s.mu.Unlock()
+
+	c.mu.Lock()
+	c.openActive()
+	for c.state == StateSynSent {
+		changed := c.changed
+		c.mu.Unlock()
+		select {
+		case <-changed:
+			c.mu.Lock()
+		case <-ctx.Done():
+			c.mu.Lock()
+			c.finish(nil)
+			c.mu.Unlock()
+			return nil, &net.OpError{Op: "dial", Net: network, Addr: raddr, Err: ctx.Err()}
+		}
+	}
+	err = c.err
+	c.mu.Unlock()
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: raddr, Err: err}
+	}
+	return c, nil
+}
+
+// HandleSegment processes a TCP segment received by the IP layer. It reports whether the
+// segment belonged to a connection or listener of the stack; otherwise it is answered with RST.
+func (s *Stack) HandleSegment(seg Segment) bool {
+	id, ok := newConnID(seg.DstIP, seg.DstPort, seg.SrcIP, seg.SrcPort)
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	c := s.conns[id]
+	var l *Listener
+	if c == nil {
+		l = s.listeners[id.local.Port()]
+		if l != nil && l.ip.IsValid() && l.ip != id.local.Addr() {
+			l = nil
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case c != nil:
+		c.handleSegment(seg)
+		return true
+	case l != nil && seg.Flags&(FlagSYN|FlagACK|FlagRST) == FlagSYN:
+		s.acceptSYN(l, id, seg)
+		return true
+	default:
+		// RFC 9293 3.10.7.1: CLOSED状態のポート宛てのセグメントにはRSTで応答する
+		if seg.Flags&FlagRST == 0 {
+			s.sendReset(seg)
+		}
+		return false
+	}
+}
+
+// acceptSYN creates a connection in SYN-RECEIVED for a SYN on a listening port.
+func (s *Stack) acceptSYN(l *Listener, id connID, seg Segment) {
+	select {
+	case <-l.closed:
+		s.sendReset(seg)
+		return
+	default:
+	}
+
+	s.mu.Lock()
+	if len(l.synReceived)+len(l.acceptCh) >= cap(l.acceptCh) {
+		s.mu.Unlock()
+		s.logf("Backlog of %s is full, dropping SYN from %s", l.Addr(), id.remote)
+		return
+	}
+	c := newConn(s, id, StateSynReceived)
+	c.listener = l
+	l.synReceived[c] = struct{}{}
+	s.conns[id] = c
+	s.mu.Unlock()
+
+	c.mu.Lock()
+	c.openPassive(seg)
+	c.mu.Unlock()
+}
+
+// sendReset answers a segment that does not belong to any connection (RFC 9293 3.10.7.1).
+func (s *Stack) sendReset(seg Segment) {
+	rst := Segment{
+		SrcIP:   seg.DstIP,
+		DstIP:   seg.SrcIP,
+		SrcPort: seg.DstPort,
+		DstPort: seg.SrcPort,
+	}
+	if seg.Flags&FlagACK != 0 {
+		rst.Seq = seg.Ack
+		rst.Flags = FlagRST
+	} else {
+		rst.Ack = seg.Seq + seg.seqLen()
+		rst.Flags = FlagRST | FlagACK
+	}
+	if err := s.send(rst); err != nil {
+		s.logf("Failed to send RST to %s:%d: %v", seg.SrcIP, seg.SrcPort, err)
+	}
+}
+
+// removeConn forgets a closed connection.
+func (s *Stack) removeConn(c *Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conns[c.id] == c {
+		delete(s.conns, c.id)
+	}
+}
+
+// removeListener forgets a closed listener and returns its connections that are still in the
+// three-way handshake.
+func (s *Stack) removeListener(l *Listener) []*Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listeners[l.port] == l {
+		delete(s.listeners, l.port)
+	}
+	var pending []*Conn
+	for c := range l.synReceived {
+		pending = append(pending, c)
+	}
+	return pending
+}
+
+// leaveSynReceived removes a passively opened connection from its listener's handshake queue.
+func (s *Stack) leaveSynReceived(c *Conn) {
+	if c.listener == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(c.listener.synReceived, c)
+}
+
+// localIPFor returns the address of the stack of the same family as remote.
+func (s *Stack) localIPFor(remote netip.Addr) (netip.Addr, bool) {
+	for _, ip := range s.config.LocalIPs {
+		local, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		if local = local.Unmap(); local.Is4() == remote.Is4() {
+			return local, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// allocatePortLocked returns the next ephemeral port for which free reports true.
+// s.mu must be held.
+func (s *Stack) allocatePortLocked(free func(port uint16) bool) (uint16, error) {
+	for i := 0; i <= ephemeralPortLast-ephemeralPortFirst; i++ {
+		port := s.nextPort
+		if s.nextPort == ephemeralPortLast {
+			s.nextPort = ephemeralPortFirst
+		} else {
+			s.nextPort++
+		}
+		if free(port) {
+			return port, nil
+		}
+	}
+	return 0, errors.New("no ephemeral port available")
+}
+
+// resolveTCPAddr resolves the address of a "tcp", "tcp4" or "tcp6" network.
+func resolveTCPAddr(network, address string) (*net.TCPAddr, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+	return net.ResolveTCPAddr(network, address)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// pcapngWriter writes the packets read from / written to the TUN/TAP device to a pcapng file
+// so that a session can be inspected in Wireshark.
+// Reference: https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-02.html
+type pcapngWriter struct {
+	mu    sync.Mutex
+	file  *os.File // nil after Close
+	path  string
+	count int
+}
+
+const (
+	pcapngBlockTypeSHB   = 0x0A0D0D0A // Section Header Block
+	pcapngBlockTypeIDB   = 0x00000001 // Interface Description Block
+	pcapngBlockTypeEPB   = 0x00000006 // Enhanced Packet Block
+	pcapngByteOrderMagic = 0x1A2B3C4D
+
+	pcapngOptEndOfOpt  = 0
+	pcapngOptSHBUserAp = 4 // shb_userappl
+	pcapngOptIfName    = 2 // if_name
+	pcapngOptEPBFlags  = 2 // epb_flags
+
+	// epb_flags の下位2ビットはパケットの方向
+	pcapngEPBFlagInbound  = 0x1
+	pcapngEPBFlagOutbound = 0x2
+
+	// Link types (https://www.tcpdump.org/linktypes.html)
+	LinkTypeEthernet = 1   // TAP mode: Ethernet frames
+	LinkTypeRaw      = 101 // TUN mode: raw IPv4/IPv6 packets
+)
+
+// packetCapture is the capture file of the -pcap flag (nil = capture disabled)
+var packetCapture *pcapngWriter
+
+// openPacketCapture creates the pcapng file and writes the section header and the description of
+// the single captured interface.
+func openPacketCapture(path string, linkType uint16, ifName string) (*pcapngWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pcapng file: %w", err)
+	}
+
+	// Section Header Block: byte order magic, version 1.0, section length unknown (-1)
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:4], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:6], 1)
+	binary.LittleEndian.PutUint16(shb[6:8], 0)
+	binary.LittleEndian.PutUint64(shb[8:16], 0xFFFFFFFFFFFFFFFF)
+	shb = append(shb, buildPcapngOption(pcapngOptSHBUserAp, []byte("day32_userspace_net"))...)
+
+	// Interface Description Block: link type, reserved, snap length (0 = no limit)
+	// タイムスタンプの分解能は if_tsresol を省略してデフォルトのマイクロ秒とする
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:2], linkType)
+	binary.LittleEndian.PutUint32(idb[4:8], 0)
+	idb = append(idb, buildPcapngOption(pcapngOptIfName, []byte(ifName))...)
+
+	header := append(buildPcapngBlock(pcapngBlockTypeSHB, shb), buildPcapngBlock(pcapngBlockTypeIDB, idb)...)
+	if _, err := file.Write(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write pcapng header: %w", err)
+	}
+	return &pcapngWriter{file: file, path: path}, nil
+}
+
+// WritePacket appends the packet as an Enhanced Packet Block with the current time and direction.
+func (p *pcapngWriter) WritePacket(data []byte, outbound bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.file == nil {
+		return nil
+	}
+
+	timestamp := uint64(time.Now().UnixMicro())
+	flags := make([]byte, 4)
+	if outbound {
+		binary.LittleEndian.PutUint32(flags, pcapngEPBFlagOutbound)
+	} else {
+		binary.LittleEndian.PutUint32(flags, pcapngEPBFlagInbound)
+	}
+
+	// Interface ID, Timestamp (High/Low), Captured Length, Original Length, Packet Data (padded to 32 bits)
+	epb := make([]byte, 20, 20+len(data)+3)
+	binary.LittleEndian.PutUint32(epb[0:4], 0)
+	binary.LittleEndian.PutUint32(epb[4:8], uint32(timestamp>>32))
+	binary.LittleEndian.PutUint32(epb[8:12], uint32(timestamp))
+	binary.LittleEndian.PutUint32(epb[12:16], uint32(len(data)))
+	binary.LittleEndian.PutUint32(epb[16:20], uint32(len(data)))
+	epb = append(epb, data...)
+	epb = append(epb, make([]byte, pcapngPadding(len(data)))...)
+	epb = append(epb, buildPcapngOption(pcapngOptEPBFlags, flags)...)
+
+	// Wiresharkで実行中にも開けるよう、バッファせずにブロック単位で書き込む
+	if _, err := p.file.Write(buildPcapngBlock(pcapngBlockTypeEPB, epb)); err != nil {
+		return fmt.Errorf("failed to write packet to pcapng file: %w", err)
+	}
+	p.count++
+	return nil
+}
+
+// Close closes the capture file. Packets written after Close are ignored.
+func (p *pcapngWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.file == nil {
+		return nil
+	}
+	err := p.file.Close()
+	p.file = nil
+	return err
+}
+
+// capturePacket writes the packet to the -pcap file if packet capture is enabled.
+func capturePacket(data []byte, outbound bool) {
+	if packetCapture == nil {
+		return
+	}
+	if err := packetCapture.WritePacket(data, outbound); err != nil {
+		log.Printf("%s%s%v%s", ColorYellow, PrefixWarn, err, ColorReset)
+	}
+}
+
+// buildPcapngBlock wraps the block body with the block type and the leading/trailing block total length.
+func buildPcapngBlock(blockType uint32, body []byte) []byte {
+	totalLength := 12 + len(body)
+	block := make([]byte, 8, totalLength)
+	binary.LittleEndian.PutUint32(block[0:4], blockType)
+	binary.LittleEndian.PutUint32(block[4:8], uint32(totalLength))
+	block = append(block, body...)
+	return binary.LittleEndian.AppendUint32(block, uint32(totalLength))
+}
+
+// buildPcapngOption encodes a single option (code, length, value padded to 32 bits) followed by opt_endofopt.
+func buildPcapngOption(code uint16, value []byte) []byte {
+	buf := binary.LittleEndian.AppendUint16(nil, code)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(value)))
+	buf = append(buf, value...)
+	buf = append(buf, make([]byte, pcapngPadding(len(value)))...)
+	buf = binary.LittleEndian.AppendUint16(buf, pcapngOptEndOfOpt)
+	return binary.LittleEndian.AppendUint16(buf, 0)
+}
+
+// pcapngPadding returns the number of bytes needed to pad length to a multiple of 4.
+func pcapngPadding(length int) int {
+	return (4 - length%4) % 4
+}
+
+// closePacketCapture closes the -pcap file and logs how many packets were captured.
+func closePacketCapture() {
+	if packetCapture == nil {
+		return
+	}
+	if err := packetCapture.Close(); err != nil {
+		log.Printf("%s%sFailed to close pcapng file '%s': %v%s", ColorYellow, PrefixWarn, packetCapture.path, err, ColorReset)
+		return
+	}
+	log.Printf("%s%sWrote %d packets to '%s'%s", ColorWhite, PrefixInfo, packetCapture.count, packetCapture.path, ColorReset)
+}
@@ -4,14 +4,16 @@ import (
 	"bytes"
 	"crypto/ecdh"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	mrand "math/rand"
 	"net"
 	"sync"
 	"time"
 
 	"github.com/google/gopacket/layers"
+	"github.com/lirlia/100day_challenge_backend/day32_userspace_net/netstack"
 	"github.com/songgao/water"
 )
 
@@ -31,22 +33,6 @@ type TCPHeader struct {
 	Options    []byte // Options (if DataOffset > 5)
 }
 
-// TCPState represents the state of a TCP connection
-type TCPState int
-
-const (
-	TCPStateListen TCPState = iota
-	TCPStateSynReceived
-	TCPStateEstablished
-	TCPStateFinWait1
-	TCPStateFinWait2
-	TCPStateCloseWait
-	TCPStateClosing
-	TCPStateLastAck
-	TCPStateTimeWait
-	TCPStateClosed
-)
-
 // Add HTTP2State definition
 type HTTP2State int
 
@@ -80,24 +66,21 @@ const (
 var (
 	tcpConnections = make(map[string]*TCPConnection)
 	connMutex      sync.Mutex // Mutex for the global connection map
+
+	// socketStack is the TCP socket layer used in tun/tap mode (nil in tcp mode)
+	socketStack *netstack.Stack
 )
 
-// TCPConnection represents the state of a TCP connection
+// TCPConnection represents the state of a connection served by the built-in HTTP/TLS server.
+// The TCP layer itself is handled by TCPConn: a socket of the OS (tcp mode) or of the
+// userspace stack's netstack package (tun/tap mode).
 type TCPConnection struct {
-	State         TCPState
-	ClientIP      net.IP
-	ClientPort    layers.TCPPort
-	ServerIP      net.IP
-	ServerPort    layers.TCPPort
-	ClientISN     uint32 // Initial Sequence Number from client
-	ServerISN     uint32 // Initial Sequence Number from server
-	ClientNextSeq uint32 // Next expected sequence number from client
-	ServerNextSeq uint32 // Next sequence number to send from server
-	// Add more fields as needed (e.g., window sizes, timers)
-
-	// Mode-specific connection info
-	TunIFCE *water.Interface // Interface for TUN mode
-	TCPConn net.Conn         // Underlying connection for TCP mode
+	ClientIP   net.IP
+	ClientPort layers.TCPPort
+	ServerIP   net.IP
+	ServerPort layers.TCPPort
+
+	TCPConn net.Conn // Underlying connection
 
 	// TLS specific state (References TLSState which will be in tls.go)
 	TLSState      TLSHandshakeState // <<< Defined in tls.go later
@@ -119,6 +102,16 @@ type TCPConnection struct {
 	ClientSequenceNum        uint64 // Sequence number for receiving records (for AEAD)
 	ServerSequenceNum        uint64 // Sequence number for sending records (for AEAD)
 
+	// TLS 1.3 specific state
+	TLSVersion                 uint16 // Negotiated version (TLSVersion13 for TLS 1.3, otherwise TLS 1.2)
+	TLS13HelloRetrySent        bool   // A HelloRetryRequest has been sent (the next ClientHello is the second one)
+	TLS13HandshakeSecret       []byte // Handshake Secret (input for the Master Secret)
+	TLS13ClientHandshakeSecret []byte // client_handshake_traffic_secret (base key of the client Finished)
+	TLS13ServerHandshakeSecret []byte // server_handshake_traffic_secret (base key of the server Finished)
+	TLS13ClientAppSecret       []byte // client_application_traffic_secret_0 (installed after the client Finished)
+	TLS13SkipEarlyData         bool   // 0-RTT was offered and rejected; undecryptable records are skipped
+	TLS13SkippedEarlyData      int    // Bytes of rejected 0-RTT data skipped so far
+
 	// Handshake message buffering
 	HandshakeMessages bytes.Buffer // Buffer to store handshake messages for Finished hash
 
@@ -135,6 +128,11 @@ type TCPConnection struct {
 	H2State            HTTP2State    // Current state of H2 processing
 	HTTP2ReceiveBuffer *bytes.Buffer // Buffer for decrypted HTTP/2 frames
 
+	// --- HTTP/1.1 Specific State ---
+	HTTPReceiveBuffer bytes.Buffer // Buffer for partial/pipelined HTTP/1.1 requests
+	HTTPRequestCount  int          // Number of requests served on this (keep-alive) connection
+	HTTPClosing       bool         // Set once the last response has been sent and the connection is closing
+
 	LastPacketTime time.Time
 }
 
@@ -143,12 +141,10 @@ func (c *TCPConnection) ConnectionKey() string {
 	c.Mutex.Lock()
 	defer c.Mutex.Unlock()
 
-	if c.TCPConn != nil { // TCP Mode
-		localAddr := c.TCPConn.LocalAddr().String()        // e.g., "127.0.0.1:443"
-		remoteAddr := c.TCPConn.RemoteAddr().String()      // e.g., "192.168.1.100:54321"
-		return fmt.Sprintf("%s-%s", remoteAddr, localAddr) // Use remote-local for consistency?
-	} else if c.ClientIP != nil { // TUN Mode
-		return fmt.Sprintf("%s:%d-%s:%d", c.ClientIP, c.ClientPort, c.ServerIP, c.ServerPort)
+	if c.TCPConn != nil {
+		localAddr := c.TCPConn.LocalAddr().String()   // e.g., "10.0.0.2:443"
+		remoteAddr := c.TCPConn.RemoteAddr().String() // e.g., "10.0.0.1:54321"
+		return fmt.Sprintf("%s-%s", remoteAddr, localAddr)
 	} else {
 		log.Println("Warning: ConnectionKey called on connection with no valid identifiers")
 		return ""
@@ -165,23 +161,31 @@ func runTCPMode(port int) {
 	defer listener.Close()
 	log.Printf("TCP server listening on %s", listenAddr)
 
+	serveTCPListener(listener, true)
+}
+
+// serveTCPListener accepts connections and serves the built-in HTTP server on them
+// (over TLS if useTLS). It is used for OS sockets (tcp mode) and netstack sockets (tun/tap mode).
+func serveTCPListener(listener net.Listener, useTLS bool) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			log.Printf("Error accepting connection: %v", err)
 			continue
 		}
 		log.Printf("Accepted TCP connection from %s", conn.RemoteAddr())
-		go handleTCPConnection(conn)
+		go handleTCPConnection(conn, useTLS)
 	}
 }
 
-// handleTCPConnection handles a single accepted TCP connection.
-func handleTCPConnection(netConn net.Conn) {
+// handleTCPConnection reads from an accepted connection and passes the data to the TLS
+// (useTLS) or HTTP/1.1 layer until the connection is closed.
+func handleTCPConnection(netConn net.Conn, useTLS bool) {
 	defer netConn.Close()
 
-	// Create a TCPConnection state object for this connection
-	// Note: IPs/Ports are derived from net.Conn
 	remoteAddr, ok := netConn.RemoteAddr().(*net.TCPAddr)
 	if !ok {
 		log.Printf("Could not get remote TCP address from %s", netConn.RemoteAddr())
@@ -194,40 +198,46 @@ func handleTCPConnection(netConn net.Conn) {
 	}
 
 	conn := &TCPConnection{
-		State:      TCPStateEstablished, // Assume established for TCP mode start
-		ClientIP:   remoteAddr.IP,
-		ClientPort: layers.TCPPort(remoteAddr.Port),
-		ServerIP:   localAddr.IP,
-		ServerPort: layers.TCPPort(localAddr.Port),
-		TCPConn:    netConn,
-		TLSState:   TLSStateExpectingClientHello, // Start TLS handshake
-		// Other fields (ISN, SeqNums) are less relevant in standard TCP mode
-		// but initialize buffer
+		ClientIP:           remoteAddr.IP,
+		ClientPort:         layers.TCPPort(remoteAddr.Port),
+		ServerIP:           localAddr.IP,
+		ServerPort:         layers.TCPPort(localAddr.Port),
+		TCPConn:            netConn,
+		TLSState:           TLSStateNone,
 		ReceiveBuffer:      *bytes.NewBuffer([]byte{}),
 		H2State:            H2StateExpectPreface, // Initialize H2 state
 		HTTP2ReceiveBuffer: new(bytes.Buffer),    // Initialize H2 buffer
 	}
+	if useTLS {
+		conn.TLSState = TLSStateExpectingClientHello
+	}
 
 	connKey := conn.ConnectionKey() // Use the method to get the key
 	connMutex.Lock()
 	tcpConnections[connKey] = conn
 	connMutex.Unlock()
 
-	log.Printf("Handling TCP connection: %s", connKey)
+	log.Printf("%s%sConnection %s ESTABLISHED. Port: %d, TLS State: %v%s", ColorGreen, PrefixState, connKey, conn.ServerPort, conn.TLSState, ColorReset)
+	pauseIfNeeded("tcp")
 
-	// Start the TLS handshake process for this connection
-	// This function will read from conn.TCPConn and process TLS records
-	// Note: startTLSHandshake function needs to be implemented, likely in tls.go
-	err := startTLSHandshake(conn)
-	if err != nil {
-		log.Printf("TLS handshake error for %s: %v", connKey, err)
-	} else {
-		log.Printf("TLS handshake successful for %s. Ready for application data.", connKey)
-		// TODO: Transition to application data handling phase for TCP mode
-		// This might involve reading TLS application data records and passing
-		// the decrypted payload to handleHTTPData or similar.
-		// For now, we just log success.
-		pauseIfNeeded("tcp")
+	readBuf := make([]byte, 4096)
+	for {
+		n, err := netConn.Read(readBuf)
+		if n > 0 {
+			if useTLS {
+				handleTLSData(conn, readBuf[:n])
+			} else {
+				handleHTTPData(conn, readBuf[:n])
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				log.Printf("%s%sPeer closed connection %s.%s", ColorYellow, PrefixState, connKey, ColorReset)
+			} else if !errors.Is(err, net.ErrClosed) {
+				log.Printf("%s%sError reading from connection %s: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
+			}
+			break
+		}
 	}
 
 	// Cleanup connection from map when done
@@ -237,8 +247,8 @@ func handleTCPConnection(netConn net.Conn) {
 	log.Printf("Finished handling TCP connection: %s", connKey)
 }
 
-// handleTCPPacket parses TCP header and manages TCP state transitions based on port.
-func handleTCPPacket(ifce *water.Interface, ipHeader *IPv4Header, tcpSegment []byte) {
+// handleTCPPacket parses the TCP header and hands the segment to the socket layer (netstack).
+func handleTCPPacket(ifce *water.Interface, srcIP, dstIP net.IP, tcpSegment []byte) {
 	tcpHeader, tcpPayload, err := parseTCPHeader(tcpSegment)
 	if err != nil {
 		log.Printf("%s%sError parsing TCP header: %v%s", ColorRed, PrefixError, err, ColorReset)
@@ -248,267 +258,32 @@ func handleTCPPacket(ifce *water.Interface, ipHeader *IPv4Header, tcpSegment []b
 	flagsStr := tcpFlagsToString(tcpHeader.Flags)
 	log.Printf("%s%sRCV: %s:%d -> %s:%d Seq: %d Ack: %d Flags: [%s] Win: %d Len: %d%s",
 		ColorBlue, PrefixTCP,
-		ipHeader.SrcIP, tcpHeader.SrcPort,
-		ipHeader.DstIP, tcpHeader.DstPort,
+		srcIP, tcpHeader.SrcPort,
+		dstIP, tcpHeader.DstPort,
 		tcpHeader.SeqNum, tcpHeader.AckNum,
 		flagsStr,
 		tcpHeader.WindowSize, len(tcpPayload),
 		ColorReset,
 	)
 
-	connMutex.Lock()
-	defer connMutex.Unlock()
-
-	connKey := fmt.Sprintf("%s:%d-%s:%d", ipHeader.SrcIP, tcpHeader.SrcPort, ipHeader.DstIP, tcpHeader.DstPort)
-	conn, exists := tcpConnections[connKey]
-
-	switch {
-	// Case 1: New SYN (Listen state is implicit)
-	case !exists && tcpHeader.Flags&TCPFlagSYN != 0 && tcpHeader.Flags&TCPFlagACK == 0:
-		if tcpHeader.DstPort == 80 || tcpHeader.DstPort == 443 {
-			log.Printf("%s%sHandling SYN for new connection %s on port %d%s", ColorYellow, PrefixState, connKey, tcpHeader.DstPort, ColorReset)
-
-			serverISN := mrand.Uint32() // Use mrand
-			newConn := &TCPConnection{
-				State:              TCPStateSynReceived,
-				ClientIP:           ipHeader.SrcIP,
-				ClientPort:         layers.TCPPort(tcpHeader.SrcPort),
-				ServerIP:           ipHeader.DstIP,
-				ServerPort:         layers.TCPPort(tcpHeader.DstPort),
-				ClientISN:          tcpHeader.SeqNum,
-				ServerISN:          serverISN,
-				ClientNextSeq:      tcpHeader.SeqNum + 1,
-				ServerNextSeq:      serverISN + 1,
-				TunIFCE:            ifce, // Store interface for TUN mode replies
-				TLSState:           TLSStateNone,
-				ReceiveBuffer:      *bytes.NewBuffer([]byte{}),
-				H2State:            H2StateExpectPreface, // Initialize H2 state
-				HTTP2ReceiveBuffer: new(bytes.Buffer),    // Initialize H2 buffer
-			}
-			if tcpHeader.DstPort == 443 {
-				newConn.TLSState = TLSStateExpectingClientHello
-			}
-			tcpConnections[connKey] = newConn
-
-			// Send SYN-ACK
-			_, err = sendTCPPacket(newConn.TunIFCE, newConn.ServerIP, newConn.ClientIP, uint16(newConn.ServerPort), uint16(newConn.ClientPort),
-				newConn.ServerISN, newConn.ClientNextSeq, TCPFlagSYN|TCPFlagACK, nil)
-			if err != nil {
-				log.Printf("Error sending SYN-ACK for %s: %v", connKey, err)
-				delete(tcpConnections, connKey)
-			}
-		} else {
-			log.Printf("%s%sIgnoring SYN for unhandled port %d from %s:%d%s", ColorGray, PrefixWarn, tcpHeader.DstPort, ipHeader.SrcIP, tcpHeader.SrcPort, ColorReset)
-		}
-
-	// Case 2: ACK for SYN-ACK
-	case exists && conn.State == TCPStateSynReceived && tcpHeader.Flags&TCPFlagACK != 0:
-		if tcpHeader.AckNum == conn.ServerNextSeq {
-			log.Printf("%s%sConnection %s ESTABLISHED. Port: %d, TLS State: %v%s", ColorGreen, PrefixState, connKey, conn.ServerPort, conn.TLSState, ColorReset)
-			pauseIfNeeded("tcp")
-			conn.State = TCPStateEstablished
-			conn.ClientNextSeq = tcpHeader.SeqNum
-		} else {
-			log.Printf("%s%sInvalid ACK for SYN-ACK on %s. AckNum: %d, Expected: %d%s", ColorYellow, PrefixWarn, connKey, tcpHeader.AckNum, conn.ServerNextSeq, ColorReset)
-		}
-
-	// Case 3: Packets on established connection
-	case exists && conn.State == TCPStateEstablished:
-		// Basic sequence number check (common for both HTTP and TLS data)
-		if !(len(tcpPayload) == 0 && tcpHeader.Flags&TCPFlagACK != 0) && tcpHeader.SeqNum != conn.ClientNextSeq {
-			// log.Printf("%s%sUnexpected sequence number for ESTABLISHED %s. Expected %d, got %d. Flags [%s]. Ignoring.%s", ColorYellow, PrefixWarn, connKey, conn.ClientNextSeq, tcpHeader.SeqNum, tcpFlagsToString(tcpHeader.Flags), ColorReset)
-			// Optionally send ACK with expected SeqNum? For now, ignore.
-			return
-		}
-
-		// Handle FIN first (common for both)
-		if tcpHeader.Flags&TCPFlagFIN != 0 {
-			handleFIN(conn, tcpHeader)
-			return
-		}
-
-		// Handle ACK only packets (common for both)
-		if len(tcpPayload) == 0 && tcpHeader.Flags&TCPFlagACK != 0 {
-			handlePureACK(conn, tcpHeader)
-			return
-		}
-
-		// Handle data payload based on port
-		if len(tcpPayload) > 0 {
-			expectedClientNextSeq := conn.ClientNextSeq + uint32(len(tcpPayload))
-			ackFlags := uint8(TCPFlagACK)
-			// Send ACK for received data (common for both)
-			_, err = sendTCPPacket(conn.TunIFCE, conn.ServerIP, conn.ClientIP, uint16(conn.ServerPort), uint16(conn.ClientPort),
-				conn.ServerNextSeq, expectedClientNextSeq, ackFlags, nil)
-			if err != nil {
-				log.Printf("%s%sError sending ACK for received data on %s (Port %d): %v%s", ColorRed, PrefixError, connKey, conn.ServerPort, err, ColorReset)
-				// Don't update ClientNextSeq if ACK fails?
-				return
-			}
-
-			// Dispatch data handling based on port
-			if conn.ServerPort == 80 {
-				handleHTTPData(conn.TunIFCE, conn, tcpPayload) // Pass TUN interface
-			} else if conn.ServerPort == 443 {
-				handleTLSData(conn.TunIFCE, conn, tcpPayload) // Pass TUN interface
-			} else {
-				log.Printf("%s%sReceived data on unexpected established port %d for %s%s", ColorYellow, PrefixWarn, conn.ServerPort, connKey, ColorReset)
-			}
-
-			// Update client sequence number after successful ACK and dispatch
-			conn.ClientNextSeq = expectedClientNextSeq
-		}
-
-	// Case 4: ACK for our FIN (closing connection)
-	case exists && conn.State == TCPStateLastAck:
-		handleFINACK(conn, tcpHeader)
-
-	// === NEW CASE: Handle packets when we are waiting for client's ACK/FIN ===
-	case exists && conn.State == TCPStateFinWait1:
-		log.Printf("%s%s[TCP State - %s] Received packet in FIN_WAIT_1. Flags: [%s], Seq: %d, Ack: %d%s", ColorYellow, PrefixState, connKey, tcpFlagsToString(tcpHeader.Flags), tcpHeader.SeqNum, tcpHeader.AckNum, ColorReset)
-
-		// Update client's next expected sequence number based on this packet
-		// (Important even if it's just an ACK)
-		// FIN counts as 1 sequence number if present
-		payloadLen := uint32(len(tcpPayload))
-		finIncrement := uint32(0)
-		if tcpHeader.Flags&TCPFlagFIN != 0 {
-			finIncrement = 1
-		}
-		expectedClientNextSeqAfterThis := tcpHeader.SeqNum + payloadLen + finIncrement
-		if tcpHeader.AckNum != conn.ServerNextSeq {
-			log.Printf("%s%s[Warning - %s] ACK number mismatch in FIN_WAIT_1. Expected %d, got %d. Continuing...%s", ColorYellow, PrefixWarn, connKey, conn.ServerNextSeq, tcpHeader.AckNum, ColorReset)
-		}
-
-		// Scenario A: Client sends only ACK for our FIN
-		if tcpHeader.Flags == TCPFlagACK {
-			log.Printf("%s%s[TCP State - %s] Received ACK for our FIN. Transitioning to FIN_WAIT_2.%s", ColorYellow, PrefixState, connKey, ColorReset)
-			conn.State = TCPStateFinWait2
-			// Update sequence numbers based on ACK received
-			conn.ClientNextSeq = expectedClientNextSeqAfterThis // Only update if ACK is valid? For now, assume valid.
-
-			// Scenario B: Client sends FIN (+ACK) while we are in FIN_WAIT_1 (Simultaneous Close or FIN after ACK)
-		} else if tcpHeader.Flags&(TCPFlagFIN|TCPFlagACK) != 0 {
-			log.Printf("%s%s[TCP State - %s] Received FIN+ACK (or just FIN) in FIN_WAIT_1. Sending ACK. Transitioning to CLOSING/TIME_WAIT.%s", ColorYellow, PrefixState, connKey, ColorReset)
-
-			// Update sequence numbers based on received FIN
-			conn.ClientNextSeq = expectedClientNextSeqAfterThis
-
-			// Send ACK for their FIN
-			ackFlags := uint8(TCPFlagACK)
-			_, err = sendTCPPacket(conn.TunIFCE, conn.ServerIP, conn.ClientIP, uint16(conn.ServerPort), uint16(conn.ClientPort),
-				conn.ServerNextSeq, conn.ClientNextSeq, ackFlags, nil)
-			if err != nil {
-				log.Printf("%s%s[TCP Close Error - %s] Failed to send ACK for client's FIN in FIN_WAIT_1: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
-				// Problematic state, maybe just delete?
-				delete(tcpConnections, connKey)
-			} else {
-				// Transition to TIME_WAIT (simplified, directly closing after ACK)
-				// A proper TIME_WAIT state would involve a timer.
-				log.Printf("%s%s[TCP State - %s] Sent ACK for client's FIN. Transitioning to TIME_WAIT (and deleting connection).%s", ColorYellow, PrefixState, connKey, ColorReset)
-				conn.State = TCPStateTimeWait // Mark as TimeWait just before delete for clarity
-				delete(tcpConnections, connKey)
-			}
-
-		} else {
-			log.Printf("%s%s[Warning - %s] Unexpected flags [%s] received in FIN_WAIT_1. Ignoring.%s", ColorYellow, PrefixWarn, connKey, tcpFlagsToString(tcpHeader.Flags), ColorReset)
-		}
-
-	// === NEW CASE: Handle packets when we are waiting for client's FIN ===
-	case exists && conn.State == TCPStateFinWait2:
-		log.Printf("%s%s[TCP State - %s] Received packet in FIN_WAIT_2. Flags: [%s], Seq: %d, Ack: %d%s", ColorYellow, PrefixState, connKey, tcpFlagsToString(tcpHeader.Flags), tcpHeader.SeqNum, tcpHeader.AckNum, ColorReset)
-
-		// Update client's next expected sequence number based on this packet
-		payloadLen := uint32(len(tcpPayload))
-		finIncrement := uint32(0)
-		if tcpHeader.Flags&TCPFlagFIN != 0 {
-			finIncrement = 1
-		}
-		expectedClientNextSeqAfterThis := tcpHeader.SeqNum + payloadLen + finIncrement
-
-		if tcpHeader.Flags&TCPFlagFIN != 0 {
-			log.Printf("%s%s[TCP State - %s] Received FIN from client in FIN_WAIT_2. Sending ACK and closing.%s", ColorYellow, PrefixState, connKey, ColorReset)
-			conn.ClientNextSeq = expectedClientNextSeqAfterThis
-
-			// Send ACK for their FIN
-			ackFlags := uint8(TCPFlagACK)
-			_, err = sendTCPPacket(conn.TunIFCE, conn.ServerIP, conn.ClientIP, uint16(conn.ServerPort), uint16(conn.ClientPort),
-				conn.ServerNextSeq, conn.ClientNextSeq, ackFlags, nil)
-			if err != nil {
-				log.Printf("%s%s[TCP Close Error - %s] Failed to send ACK for client's FIN in FIN_WAIT_2: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
-			} else {
-				log.Printf("%s%s[TCP State - %s] Sent ACK for client's FIN. Connection CLOSED.%s", ColorYellow, PrefixState, connKey, ColorReset)
-			}
-			// Transition to TIME_WAIT (simplified, directly closing after ACK)
-			conn.State = TCPStateTimeWait // Mark as TimeWait just before delete
-			delete(tcpConnections, connKey)
-
-		} else {
-			// Might receive other data or just ACKs, usually ignore in FIN_WAIT_2 for simplicity here
-			log.Printf("%s%s[Warning - %s] Received non-FIN packet [%s] in FIN_WAIT_2. Ignoring.%s", ColorYellow, PrefixWarn, connKey, tcpFlagsToString(tcpHeader.Flags), ColorReset)
-		}
-
-	default:
-		// if exists {
-		// log.Printf("%s%sUnhandled TCP packet for %s. State: %v, Flags: [%s]%s", ColorYellow, PrefixWarn, connKey, conn.State, tcpFlagsToString(tcpHeader.Flags), ColorReset)
-		// } else {
-		// log.Printf("%s%sUnhandled TCP packet for non-existent connection %s. Flags: [%s]%s", ColorYellow, PrefixWarn, connKey, tcpFlagsToString(tcpHeader.Flags), ColorReset)
-		// }
-		// Optionally send RST if the packet was unexpected for a non-existent connection?
-		// For now, just log.
-	}
-}
-
-// --- Helper functions for specific TCP packet handling (Modified to use conn.TunIFCE) ---
-
-func handleFIN(conn *TCPConnection, tcpHeader *TCPHeader) {
-	connKey := conn.ConnectionKey()
-	log.Printf("%s%sReceived FIN for connection %s. Entering CLOSE_WAIT.%s", ColorYellow, PrefixState, connKey, ColorReset)
-	conn.State = TCPStateCloseWait
-	conn.ClientNextSeq = tcpHeader.SeqNum + 1
-
-	// Send ACK for the FIN
-	ackFlags := uint8(TCPFlagACK)
-	_, err := sendTCPPacket(conn.TunIFCE, conn.ServerIP, conn.ClientIP, uint16(conn.ServerPort), uint16(conn.ClientPort),
-		conn.ServerNextSeq, conn.ClientNextSeq, ackFlags, nil)
-	if err != nil {
-		log.Printf("%s%sError sending ACK for FIN on %s: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
-	}
-
-	// Immediately send our FIN (since we are a simple server)
-	log.Printf("%s%sSending FIN for connection %s. Entering LAST_ACK.%s", ColorYellow, PrefixState, connKey, ColorReset)
-	finAckFlags := uint8(TCPFlagFIN | TCPFlagACK)
-	_, err = sendTCPPacket(conn.TunIFCE, conn.ServerIP, conn.ClientIP, uint16(conn.ServerPort), uint16(conn.ClientPort),
-		conn.ServerNextSeq, conn.ClientNextSeq, finAckFlags, nil)
-	if err != nil {
-		log.Printf("%s%sError sending FIN+ACK on %s: %v%s", ColorRed, PrefixError, connKey, err, ColorReset)
-	}
-	conn.ServerNextSeq++ // Increment seq for FIN
-	conn.State = TCPStateLastAck
-}
-
-func handlePureACK(conn *TCPConnection, tcpHeader *TCPHeader) {
-	connKey := conn.ConnectionKey()
-	// Acknowledge received data if ACK number advances
-	if tcpHeader.AckNum > conn.ServerNextSeq {
-		log.Printf("%s%sReceived ACK for %d bytes of data for %s. AckNum: %d -> %d%s", ColorYellow, PrefixState, tcpHeader.AckNum-conn.ServerNextSeq, connKey, conn.ServerNextSeq, tcpHeader.AckNum, ColorReset)
-		conn.ServerNextSeq = tcpHeader.AckNum // Update server sequence number based on client's ACK
-	} else if tcpHeader.AckNum < conn.ServerNextSeq {
-		// log.Printf("%s%sReceived duplicate/old ACK for %s. AckNum: %d, ServerNextSeq: %d%s", ColorYellow, PrefixWarn, connKey, tcpHeader.AckNum, conn.ServerNextSeq, ColorReset)
-	} else {
-		log.Printf("%s%sReceived ACK for %s (no new data acked). AckNum: %d%s", ColorYellow, PrefixState, connKey, tcpHeader.AckNum, ColorReset)
+	if socketStack == nil {
+		return
 	}
-	// TODO: Handle window updates, retransmissions based on ACKs
-}
-
-func handleFINACK(conn *TCPConnection, tcpHeader *TCPHeader) {
-	connKey := conn.ConnectionKey()
-	log.Printf("%s%sHandling ACK for FIN for connection %s%s", ColorYellow, PrefixState, connKey, ColorReset)
-	if tcpHeader.Flags&TCPFlagACK != 0 && tcpHeader.AckNum == conn.ServerNextSeq {
-		log.Printf("%s%sConnection %s CLOSED normally.%s", ColorYellow, PrefixState, connKey, ColorReset)
-		delete(tcpConnections, connKey) // Remove from map
-	} else {
-		log.Printf("%s%sUnexpected packet in LAST_ACK state for %s. Flags: [%s], AckNum: %d (expected %d)%s", ColorYellow, PrefixWarn, connKey, tcpFlagsToString(tcpHeader.Flags), tcpHeader.AckNum, conn.ServerNextSeq, ColorReset)
+	// 受信バッファは再利用されるため、ソケット層に渡すデータはコピーしておく
+	segment := netstack.Segment{
+		SrcIP:   srcIP,
+		DstIP:   dstIP,
+		SrcPort: tcpHeader.SrcPort,
+		DstPort: tcpHeader.DstPort,
+		Seq:     tcpHeader.SeqNum,
+		Ack:     tcpHeader.AckNum,
+		Flags:   tcpHeader.Flags,
+		Window:  tcpHeader.WindowSize,
+		Options: append([]byte(nil), tcpHeader.Options...),
+		Payload: append([]byte(nil), tcpPayload...),
+	}
+	if !socketStack.HandleSegment(segment) {
+		log.Printf("%s%sNo socket for %s:%d -> %s:%d Flags: [%s], answered with RST%s", ColorGray, PrefixWarn, srcIP, tcpHeader.SrcPort, dstIP, tcpHeader.DstPort, flagsStr, ColorReset)
 	}
 }
 
@@ -574,52 +349,56 @@ func tcpFlagsToString(flags uint8) string {
 	return fmt.Sprintf("%v", parts) // Use fmt for now, requires import "strings" later
 }
 
-// sendTCPPacket constructs and sends a TCP packet via the TUN interface.
-// Returns the length of the TCP payload sent on success.
-func sendTCPPacket(ifce *water.Interface, srcIP, dstIP net.IP, srcPort, dstPort uint16, seqNum, ackNum uint32, flags uint8, payload []byte) (int, error) {
+// sendTCPSegment constructs and sends a TCP segment of the socket layer via the TUN/TAP interface.
+func sendTCPSegment(ifce *water.Interface, seg netstack.Segment) error {
 	if ifce == nil {
-		return 0, fmt.Errorf("cannot send TCP packet: TUN interface is nil")
+		return fmt.Errorf("cannot send TCP packet: TUN interface is nil")
 	}
-	log.Printf("%s%sTCP SEND (TUN): %s:%d -> %s:%d Seq: %d Ack: %d Flags: [%s] Len: %d%s",
+	log.Printf("%s%sSND: %s:%d -> %s:%d Seq: %d Ack: %d Flags: [%s] Win: %d Len: %d%s",
 		ColorBlue, PrefixTCP,
-		srcIP, srcPort, dstIP, dstPort, seqNum, ackNum, tcpFlagsToString(flags), len(payload),
+		seg.SrcIP, seg.SrcPort, seg.DstIP, seg.DstPort, seg.Seq, seg.Ack, tcpFlagsToString(seg.Flags), seg.Window, len(seg.Payload),
 		ColorReset,
 	)
-	tcpHeaderBytes, err := buildTCPHeader(srcIP, dstIP, srcPort, dstPort, seqNum, ackNum, flags, payload)
+	tcpHeaderBytes, err := buildTCPHeader(seg)
 	if err != nil {
-		return 0, fmt.Errorf("failed to build TCP header: %w", err)
+		return fmt.Errorf("failed to build TCP header: %w", err)
 	}
 
-	ipHeaderBytes, err := buildIPv4Header(srcIP, dstIP, TCPProtocolNumber, len(tcpHeaderBytes)+len(payload))
+	ipHeaderBytes, err := buildIPHeader(seg.SrcIP, seg.DstIP, TCPProtocolNumber, len(tcpHeaderBytes)+len(seg.Payload))
 	if err != nil {
-		return 0, fmt.Errorf("failed to build IP header: %w", err)
+		return fmt.Errorf("failed to build IP header: %w", err)
 	}
 
 	fullPacket := append(ipHeaderBytes, tcpHeaderBytes...)
-	fullPacket = append(fullPacket, payload...)
-	n, err := ifce.Write(fullPacket)
+	fullPacket = append(fullPacket, seg.Payload...)
+	n, err := writeIPPacket(ifce, fullPacket)
 	if err != nil {
-		return 0, fmt.Errorf("failed to write TCP packet to TUN device: %w", err)
+		return fmt.Errorf("failed to write TCP packet to TUN device: %w", err)
 	}
 	if n != len(fullPacket) {
-		return 0, fmt.Errorf("short write for TCP packet: wrote %d bytes, expected %d", n, len(fullPacket))
+		return fmt.Errorf("short write for TCP packet: wrote %d bytes, expected %d", n, len(fullPacket))
 	}
-
-	return len(payload), nil // Return payload length on success
+	return nil
 }
 
-// buildTCPHeader creates a TCP header byte slice including the checksum.
-func buildTCPHeader(srcIP, dstIP net.IP, srcPort, dstPort uint16, seqNum, ackNum uint32, flags uint8, payload []byte) ([]byte, error) {
+// buildTCPHeader creates a TCP header byte slice (with the options padded to 32 bits)
+// including the checksum.
+func buildTCPHeader(seg netstack.Segment) ([]byte, error) {
+	options := seg.Options
+	if pad := len(options) % 4; pad != 0 {
+		options = append(append([]byte(nil), options...), make([]byte, 4-pad)...) // End of Option List
+	}
 	header := TCPHeader{
-		SrcPort:    srcPort,
-		DstPort:    dstPort,
-		SeqNum:     seqNum,
-		AckNum:     ackNum,
-		DataOffset: 5, // No options
-		Flags:      flags,
-		WindowSize: 65535,
+		SrcPort:    seg.SrcPort,
+		DstPort:    seg.DstPort,
+		SeqNum:     seg.Seq,
+		AckNum:     seg.Ack,
+		DataOffset: uint8((TCPHeaderMinLengthBytes + len(options)) / 4),
+		Flags:      seg.Flags,
+		WindowSize: seg.Window,
 		Checksum:   0, // Calculate later
 		UrgentPtr:  0,
+		Options:    options,
 	}
 	headerLengthBytes := int(header.DataOffset) * 4
 	headerBytes := make([]byte, headerLengthBytes)
@@ -632,8 +411,9 @@ func buildTCPHeader(srcIP, dstIP net.IP, srcPort, dstPort uint16, seqNum, ackNum
 	binary.BigEndian.PutUint16(headerBytes[14:16], header.WindowSize)
 	// Checksum (16-18) initially 0
 	binary.BigEndian.PutUint16(headerBytes[18:20], header.UrgentPtr)
+	copy(headerBytes[TCPHeaderMinLengthBytes:], header.Options)
 
-	checksum, err := calculateTCPChecksum(srcIP, dstIP, headerBytes, payload)
+	checksum, err := calculateTCPChecksum(seg.SrcIP, seg.DstIP, headerBytes, seg.Payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate TCP checksum: %w", err)
 	}
@@ -644,26 +424,17 @@ func buildTCPHeader(srcIP, dstIP net.IP, srcPort, dstPort uint16, seqNum, ackNum
 
 // calculateTCPChecksum computes the TCP checksum using a pseudo-header.
 func calculateTCPChecksum(srcIP, dstIP net.IP, tcpHeader, tcpPayload []byte) (uint16, error) {
-	srcIPv4 := srcIP.To4()
-	dstIPv4 := dstIP.To4()
-	if srcIPv4 == nil || dstIPv4 == nil {
-		return 0, fmt.Errorf("not IPv4 addresses for TCP checksum")
+	pseudoHeader, err := buildPseudoHeader(srcIP, dstIP, TCPProtocolNumber, len(tcpHeader)+len(tcpPayload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build pseudo header for TCP checksum: %w", err)
 	}
 
-	pseudoHeader := make([]byte, 12)
-	copy(pseudoHeader[0:4], srcIPv4)
-	copy(pseudoHeader[4:8], dstIPv4)
-	pseudoHeader[8] = 0 // Reserved
-	pseudoHeader[9] = TCPProtocolNumber
-	tcpLength := uint16(len(tcpHeader) + len(tcpPayload))
-	binary.BigEndian.PutUint16(pseudoHeader[10:12], tcpLength)
-
 	dataForChecksum := append(pseudoHeader, tcpHeader...)
 	dataForChecksum = append(dataForChecksum, tcpPayload...)
 
 	// Zero out checksum field within the combined data for calculation
 	if len(tcpHeader) >= 18 { // Ensure header is long enough
-		checksumOffsetInCombinedData := 12 + 16 // Pseudo header len + checksum offset in TCP header
+		checksumOffsetInCombinedData := len(pseudoHeader) + 16 // Pseudo header len + checksum offset in TCP header
 		binary.BigEndian.PutUint16(dataForChecksum[checksumOffsetInCombinedData:checksumOffsetInCombinedData+2], 0)
 	}
 
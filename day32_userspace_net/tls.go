@@ -12,9 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
-
 	// Required for TCPConnection type in function signatures (placeholder)
-	"github.com/songgao/water" // Required for water.Interface
 )
 
 // --- TLS Structures and Constants ---
@@ -84,6 +82,13 @@ type ClientHelloInfo struct {
 	CipherSuites       []uint16
 	CompressionMethods []uint8  // Parsed but usually ignored
 	ALPNProtocols      []string // Parsed from ALPN extension
+	// TLS 1.3 related extensions
+	SupportedVersions   []uint16      // supported_versions
+	SupportedGroups     []uint16      // supported_groups
+	SignatureAlgorithms []uint16      // signature_algorithms
+	KeyShares           []TLSKeyShare // key_share
+	EarlyData           bool          // early_data (0-RTT) offered
+	PreSharedKey        bool          // pre_shared_key offered
 	// rawExtensions    []byte // Store raw extensions for later use if needed
 }
 
@@ -100,7 +105,7 @@ func parseTLSRecordHeader(headerBytes []byte) (*TLSRecordHeader, error) {
 }
 
 // handleTLSBufferedData processes the data in the connection's ReceiveBuffer.
-func handleTLSBufferedData(ifce *water.Interface, conn *TCPConnection) {
+func handleTLSBufferedData(conn *TCPConnection) {
 	if isDebug {
 		log.Printf("%s%sBuffer len: %d", ColorGray, PrefixTLS, conn.ReceiveBuffer.Len())
 	}
@@ -149,14 +154,23 @@ func handleTLSBufferedData(ifce *water.Interface, conn *TCPConnection) {
 		}
 
 		// Decrypt payload if encryption is enabled
-		decryptedPayload, err := decryptRecord(conn, recordPayload, recordHeader.Type, recordHeader.Version)
+		var decryptedPayload []byte
+		if conn.TLSVersion == TLSVersion13 {
+			// TLS 1.3: 本当のレコードタイプは暗号化された TLSInnerPlaintext の末尾にある
+			decryptedPayload, recordHeader.Type, err = decryptRecord13(conn, recordPayload, fullRecordBytes[:TLSRecordHeaderLength])
+			if errors.Is(err, errTLS13EarlyDataSkipped) {
+				continue
+			}
+		} else {
+			decryptedPayload, err = decryptRecord(conn, recordPayload, recordHeader.Type, recordHeader.Version)
+		}
 		if err != nil {
 			log.Printf("%s%sFailed to decrypt record: %v. Closing connection.", ColorRed, PrefixError, err)
 			// TODO: Send Alert (decode_error or decrypt_error)
 			// TODO: Close connection gracefully
-			conn.ReceiveBuffer.Reset()                   // Clear buffer
-			delete(tcpConnections, conn.ConnectionKey()) // Remove connection (simplified closure)
-			return                                       // Stop processing this connection
+			conn.ReceiveBuffer.Reset() // Clear buffer
+			conn.TCPConn.Close()       // Ends the read loop of the connection (simplified closure)
+			return                     // Stop processing this connection
 		}
 		// Use decryptedPayload for subsequent processing
 		recordPayload = decryptedPayload // Replace original payload with decrypted one
@@ -167,10 +181,12 @@ func handleTLSBufferedData(ifce *water.Interface, conn *TCPConnection) {
 			if isDebug {
 				log.Printf("%s%sDispatching to handleTLSHandshakeRecord with decrypted payload (%d bytes).", ColorGray, PrefixTLS, len(recordPayload))
 			}
-			handleTLSHandshakeRecord(ifce, conn, recordPayload)
+			handleTLSHandshakeRecord(conn, recordPayload)
 		case TLSRecordTypeChangeCipherSpec:
 			log.Printf("%s%sReceived ChangeCipherSpec Record (Payload: %x)%s", ColorOrange, PrefixTLS, recordPayload, ColorReset)
-			if conn.TLSState == TLSStateExpectingChangeCipherSpec {
+			if conn.TLSVersion == TLSVersion13 {
+				log.Printf("%s%sIgnoring ChangeCipherSpec (TLS 1.3 middlebox compatibility).%s", ColorOrange, PrefixTLS, ColorReset)
+			} else if conn.TLSState == TLSStateExpectingChangeCipherSpec {
 				log.Printf("%s%sProcessing ChangeCipherSpec. Enabling encryption for receiving. TLS State -> TLSStateExpectingFinished%s", ColorOrange, PrefixTLS, ColorReset)
 				conn.TLSState = TLSStateExpectingFinished
 				conn.EncryptionEnabled = true // Enable encryption for incoming records
@@ -196,11 +212,15 @@ func handleTLSBufferedData(ifce *water.Interface, conn *TCPConnection) {
 
 				} else {
 					log.Printf("%s%sHandshake complete. Dispatching %d bytes to HTTP/1.1 handler.%s", ColorOrange, PrefixTLS, len(recordPayload), ColorReset)
-					handleHTTPData(ifce, conn, recordPayload) // Existing function for HTTP/1.1
+					handleHTTPData(conn, recordPayload) // Existing function for HTTP/1.1
 				}
 			} else {
 				log.Printf("%s%sReceived Application Data before handshake complete (State: %v). Ignoring.", ColorYellow, PrefixWarn, tlsState)
 			}
+		case TLSRecordTypeAlert:
+			if len(recordPayload) >= 2 {
+				log.Printf("%s%sReceived Alert (Level: %d, Description: %d)%s", ColorOrange, PrefixTLS, recordPayload[0], recordPayload[1], ColorReset)
+			}
 		default:
 			// log.Printf("%s%sReceived unknown TLS Record Type %d", ColorYellow, PrefixWarn, recordHeader.Type)
 		}
@@ -211,7 +231,7 @@ func handleTLSBufferedData(ifce *water.Interface, conn *TCPConnection) {
 }
 
 // handleTLSHandshakeRecord processes a received TLS Handshake record payload.
-func handleTLSHandshakeRecord(ifce *water.Interface, conn *TCPConnection, payload []byte) {
+func handleTLSHandshakeRecord(conn *TCPConnection, payload []byte) {
 	if isDebug {
 		log.Printf("%s%sEntering handleTLSHandshakeRecord. Payload len: %d", ColorGray, PrefixTLS, len(payload))
 	}
@@ -248,7 +268,7 @@ func handleTLSHandshakeRecord(ifce *water.Interface, conn *TCPConnection, payloa
 			log.Printf("%s%sDispatching to handleClientHello.", ColorGray, PrefixTLS)
 		}
 		if conn.TLSState == TLSStateExpectingClientHello {
-			handleClientHello(ifce, conn, message)
+			handleClientHello(conn, message)
 		} else {
 			log.Printf("%s%sUnexpected ClientHello received in state %v", ColorYellow, PrefixWarn, conn.TLSState)
 		}
@@ -291,7 +311,9 @@ func handleTLSHandshakeRecord(ifce *water.Interface, conn *TCPConnection, payloa
 		}
 	case TLSHandshakeTypeFinished:
 		log.Printf("%s%sReceived Finished Message (Length: %d)%s", ColorOrange, PrefixTLS, len(message), ColorReset)
-		if conn.TLSState == TLSStateExpectingFinished {
+		if conn.TLSState == TLSStateExpectingFinished && conn.TLSVersion == TLSVersion13 {
+			handleClientFinished13(conn, message, fullHandshakeMessage)
+		} else if conn.TLSState == TLSStateExpectingFinished {
 			// Verify the Finished message
 			conn.Mutex.Lock() // Lock for reading handshake messages and master secret
 			currentHandshakeBytes := conn.HandshakeMessages.Bytes()
@@ -340,7 +362,7 @@ func handleTLSHandshakeRecord(ifce *water.Interface, conn *TCPConnection, payloa
 			if isDebug {
 				log.Printf("%s%sClient Finished verified. Sending Server CCS & Finished.", ColorGray, PrefixTLS)
 			}
-			sendServerCCSAndFinished(ifce, conn)
+			sendServerCCSAndFinished(conn)
 		} else {
 			log.Printf("%s%sUnexpected Finished received in state %v", ColorYellow, PrefixWarn, conn.TLSState)
 		}
@@ -353,7 +375,7 @@ func handleTLSHandshakeRecord(ifce *water.Interface, conn *TCPConnection, payloa
 }
 
 // handleClientHello parses ClientHello and sends ServerHello, Certificate, SKE, ServerHelloDone.
-func handleClientHello(ifce *water.Interface, conn *TCPConnection, message []byte) {
+func handleClientHello(conn *TCPConnection, message []byte) {
 	if isDebug {
 		log.Printf("%s%sEntering handleClientHello. Message len: %d", ColorGray, PrefixTLS, len(message))
 	}
@@ -376,6 +398,15 @@ func handleClientHello(ifce *water.Interface, conn *TCPConnection, message []byt
 	log.Printf("%s%sCipher Suites (first %d): %v%s", ColorOrange, PrefixTLS, numSuitesToShow, info.CipherSuites[:numSuitesToShow], ColorReset)
 	log.Printf("%s%sALPN Protocols Offered: %v%s", ColorOrange, PrefixTLS, info.ALPNProtocols, ColorReset)
 
+	// TLS 1.3 は -tls 1.3 指定時かつクライアントが supported_versions で提示した場合のみ
+	if conn.TLSVersion == TLSVersion13 || (tls13Enabled && clientOffersTLS13(info)) {
+		handleClientHello13(conn, info)
+		return
+	}
+	if tls13Enabled {
+		log.Printf("%s%sClient does not offer TLS 1.3. Falling back to TLS 1.2.%s", ColorOrange, PrefixTLS, ColorReset)
+	}
+
 	// --- Server Parameter Selection ---
 	// Choose Cipher Suite (Simple example: prefer ECDHE_RSA_AES_128_GCM_SHA256 if offered)
 	chosenSuite := uint16(0)
@@ -460,18 +491,11 @@ func handleClientHello(ifce *water.Interface, conn *TCPConnection, message []byt
 	}
 
 	// Send the TLS record containing the ServerHello message
-	sentBytes, err := sendRawTLSRecord(ifce, conn, serverHelloRecord)
+	err = sendRawTLSRecord(conn, serverHelloRecord)
 	if err != nil {
 		log.Printf("%s%sFailed to send ServerHello record: %v", ColorRed, PrefixError, err)
 		return
 	}
-	// Update ServerNextSeq based on sent payload bytes (TUN mode)
-	conn.Mutex.Lock()
-	conn.ServerNextSeq += uint32(sentBytes)
-	if isDebug {
-		log.Printf("%s%sAfter ServerHello: ServerNextSeq = %d (added %d)", ColorGray, PrefixTLS, conn.ServerNextSeq, sentBytes)
-	}
-	conn.Mutex.Unlock()
 
 	// Update TLS State
 	conn.TLSState = TLSStateSentServerHello
@@ -503,18 +527,11 @@ func handleClientHello(ifce *water.Interface, conn *TCPConnection, message []byt
 		log.Printf("%s%sAdded Certificate (%d bytes). Total len: %d", ColorGray, PrefixTLS, len(certMsg), conn.HandshakeMessages.Len())
 	}
 
-	sentBytes, err = sendRawTLSRecord(ifce, conn, certRecord)
+	err = sendRawTLSRecord(conn, certRecord)
 	if err != nil {
 		log.Printf("%s%sFailed to send Certificate record: %v", ColorRed, PrefixError, err)
 		return // Certificate送信エラーならここで終了
 	}
-	// Update ServerNextSeq based on sent payload bytes (TUN mode)
-	conn.Mutex.Lock()
-	conn.ServerNextSeq += uint32(sentBytes)
-	if isDebug {
-		log.Printf("%s%sAfter Certificate: ServerNextSeq = %d (added %d)", ColorGray, PrefixTLS, conn.ServerNextSeq, sentBytes)
-	}
-	conn.Mutex.Unlock()
 
 	conn.TLSState = TLSStateSentCertificate
 	log.Printf("%s%sCertificate sent. TLS State -> %v%s", ColorOrange, PrefixTLS, conn.TLSState, ColorReset)
@@ -593,18 +610,11 @@ func handleClientHello(ifce *water.Interface, conn *TCPConnection, message []byt
 	if isDebug {
 		log.Printf("%s%sSending REAL ServerKeyExchange record (%d bytes).", ColorGray, PrefixTLS, len(skeRecord))
 	}
-	sentBytes, err = sendRawTLSRecord(ifce, conn, skeRecord)
+	err = sendRawTLSRecord(conn, skeRecord)
 	if err != nil {
 		log.Printf("%s%sFailed to send ServerKeyExchange record: %v", ColorRed, PrefixError, err)
 		return
 	}
-	// Update ServerNextSeq based on sent payload bytes (TUN mode)
-	conn.Mutex.Lock()
-	conn.ServerNextSeq += uint32(sentBytes)
-	if isDebug {
-		log.Printf("%s%sAfter SKE: ServerNextSeq = %d (added %d)", ColorGray, PrefixTLS, conn.ServerNextSeq, sentBytes)
-	}
-	conn.Mutex.Unlock()
 
 	conn.TLSState = TLSStateSentServerKeyExchange
 	log.Printf("%s%sREAL ServerKeyExchange sent. TLS State -> %v%s", ColorOrange, PrefixTLS, conn.TLSState, ColorReset)
@@ -635,18 +645,11 @@ func handleClientHello(ifce *water.Interface, conn *TCPConnection, message []byt
 	if isDebug {
 		log.Printf("%s%sSending ServerHelloDone record (%d bytes).", ColorGray, PrefixTLS, len(helloDoneRecord))
 	}
-	sentBytes, err = sendRawTLSRecord(ifce, conn, helloDoneRecord)
+	err = sendRawTLSRecord(conn, helloDoneRecord)
 	if err != nil {
 		log.Printf("%s%sFailed to send ServerHelloDone record: %v", ColorRed, PrefixError, err)
 		return
 	}
-	// Update ServerNextSeq based on sent payload bytes (TUN mode)
-	conn.Mutex.Lock()
-	conn.ServerNextSeq += uint32(sentBytes)
-	if isDebug {
-		log.Printf("%s%sAfter ServerHelloDone: ServerNextSeq = %d (added %d)", ColorGray, PrefixTLS, conn.ServerNextSeq, sentBytes)
-	}
-	conn.Mutex.Unlock()
 
 	conn.TLSState = TLSStateSentServerHelloDone
 	log.Printf("%s%sServerHelloDone sent. TLS State -> %v%s", ColorOrange, PrefixTLS, conn.TLSState, ColorReset)
@@ -761,8 +764,10 @@ func parseClientHello(message []byte) (*ClientHelloInfo, error) {
 			extData := message[offset : offset+extLen]
 			offset += extLen
 
-			// Parse specific extensions (ALPN)
-			if extType == TLSExtensionTypeALPN {
+			// Parse specific extensions (ALPN, TLS 1.3 negotiation)
+			var extErr error
+			switch extType {
+			case TLSExtensionTypeALPN:
 				if isDebug {
 					log.Printf("%s%s   Found ALPN Extension (Data: %x)", ColorGray, PrefixTLS, extData)
 				}
@@ -775,6 +780,21 @@ func parseClientHello(message []byte) (*ClientHelloInfo, error) {
 						log.Printf("%s%s   Parsed ALPN Protocols: %v", ColorGray, PrefixTLS, info.ALPNProtocols)
 					}
 				}
+			case TLSExtensionTypeSupportedVersions:
+				info.SupportedVersions, extErr = parseSupportedVersionsExtension(extData)
+			case TLSExtensionTypeSupportedGroups:
+				info.SupportedGroups, extErr = parseUint16ListExtension(extData)
+			case TLSExtensionTypeSignatureAlgorithms:
+				info.SignatureAlgorithms, extErr = parseUint16ListExtension(extData)
+			case TLSExtensionTypeKeyShare:
+				info.KeyShares, extErr = parseKeyShareExtension(extData)
+			case TLSExtensionTypeEarlyData:
+				info.EarlyData = true
+			case TLSExtensionTypePreSharedKey:
+				info.PreSharedKey = true
+			}
+			if extErr != nil {
+				return nil, fmt.Errorf("invalid extension (Type %d): %w", extType, extErr)
 			}
 		}
 		if offset != extensionsEnd {
@@ -901,15 +921,22 @@ func buildTLSRecord(recordType uint8, version uint16, payload []byte) ([]byte, e
 	return record, nil
 }
 
-// sendRawTLSRecord sends a raw TLS record over the connection (TUN or TCP).
-func sendRawTLSRecord(ifce *water.Interface, conn *TCPConnection, record []byte) (int, error) {
+// sendRawTLSRecord encrypts (once enabled) and sends a raw TLS record over the connection.
+func sendRawTLSRecord(conn *TCPConnection, record []byte) error {
 	outerRecordType := record[0]
 	recordVersion := uint16(0x0303)
 	plaintextPayload := record[TLSRecordHeaderLength:]
 
-	payloadToSend, err := encryptRecord(conn, plaintextPayload, outerRecordType, recordVersion)
+	var payloadToSend []byte
+	var err error
+	if conn.TLSVersion == TLSVersion13 {
+		// TLS 1.3: 暗号化後の外側のレコードタイプは application_data になる
+		payloadToSend, outerRecordType, err = encryptRecord13(conn, plaintextPayload, outerRecordType)
+	} else {
+		payloadToSend, err = encryptRecord(conn, plaintextPayload, outerRecordType, recordVersion)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("failed to encrypt record payload (Type: %d) for %s: %w", outerRecordType, conn.ConnectionKey(), err)
+		return fmt.Errorf("failed to encrypt record payload (Type: %d) for %s: %w", outerRecordType, conn.ConnectionKey(), err)
 	}
 
 	if isDebug {
@@ -924,56 +951,28 @@ func sendRawTLSRecord(ifce *water.Interface, conn *TCPConnection, record []byte)
 
 	conn.Mutex.Lock()
 	tcpConn := conn.TCPConn
-	tunIFCE := conn.TunIFCE
-	serverIP := conn.ServerIP
-	clientIP := conn.ClientIP
-	serverPort := conn.ServerPort
-	clientPort := conn.ClientPort
-	serverNextSeq := conn.ServerNextSeq
-	clientNextSeq := conn.ClientNextSeq
 	conn.Mutex.Unlock()
-
-	var sentPayloadBytes int
-
-	if tcpConn != nil { // TCP Mode
-		if isDebug {
-			log.Printf("%s%sSending %d bytes via net.Conn.", ColorGray, PrefixTLS, len(finalRecord))
-		}
-		n, err := tcpConn.Write(finalRecord)
-		if err != nil {
-			return 0, fmt.Errorf("TCPConn Write failed for TLS record (Type: %d) for %s: %w", outerRecordType, conn.ConnectionKey(), err)
-		}
-		if n != len(finalRecord) {
-			return 0, fmt.Errorf("TCPConn short write for TLS record (Type: %d) for %s: wrote %d, expected %d", outerRecordType, conn.ConnectionKey(), n, len(finalRecord))
-		}
-		if isDebug {
-			log.Printf("%s%sSent TLS record. Type: %d, Final Len: %d", ColorGray, PrefixTLS, outerRecordType, len(finalRecord))
-		}
-		sentPayloadBytes = 0
-
-	} else if tunIFCE != nil { // TUN Mode
-		if isDebug {
-			log.Printf("%s%sSending %d bytes via TUN interface.", ColorGray, PrefixTLS, len(finalRecord))
-		}
-		flags := uint8(TCPFlagPSH | TCPFlagACK)
-		sentBytes, err := sendTCPPacket(tunIFCE, serverIP, clientIP, uint16(serverPort), uint16(clientPort),
-			serverNextSeq, clientNextSeq, flags, finalRecord)
-		if err != nil {
-			return 0, fmt.Errorf("sendTCPPacket failed for TLS record (Type: %d) for %s: %w", outerRecordType, conn.ConnectionKey(), err)
-		}
-		sentPayloadBytes = sentBytes
-		if isDebug {
-			log.Printf("%s%sSent TLS record. Type: %d, Final Len: %d, PayloadBytes: %d", ColorGray, PrefixTLS, outerRecordType, len(finalRecord), sentPayloadBytes)
-		}
-
-	} else {
-		return 0, fmt.Errorf("sendRawTLSRecord called with invalid connection state for %s (no TCPConn or TunIFCE)", conn.ConnectionKey())
+	if tcpConn == nil {
+		return fmt.Errorf("sendRawTLSRecord called with invalid connection state for %s (no TCPConn)", conn.ConnectionKey())
 	}
 
-	return sentPayloadBytes, nil
+	if isDebug {
+		log.Printf("%s%sSending %d bytes via net.Conn.", ColorGray, PrefixTLS, len(finalRecord))
+	}
+	n, err := tcpConn.Write(finalRecord)
+	if err != nil {
+		return fmt.Errorf("TCPConn Write failed for TLS record (Type: %d) for %s: %w", outerRecordType, conn.ConnectionKey(), err)
+	}
+	if n != len(finalRecord) {
+		return fmt.Errorf("TCPConn short write for TLS record (Type: %d) for %s: wrote %d, expected %d", outerRecordType, conn.ConnectionKey(), n, len(finalRecord))
+	}
+	if isDebug {
+		log.Printf("%s%sSent TLS record. Type: %d, Final Len: %d", ColorGray, PrefixTLS, outerRecordType, len(finalRecord))
+	}
+	return nil
 }
 
-func handleTLSData(ifce *water.Interface, conn *TCPConnection, payload []byte) {
+func handleTLSData(conn *TCPConnection, payload []byte) {
 	if isDebug {
 		log.Printf("%s%sEntering handleTLSData with %d bytes payload.", ColorGray, PrefixTLS, len(payload))
 	}
@@ -988,7 +987,7 @@ func handleTLSData(ifce *water.Interface, conn *TCPConnection, payload []byte) {
 	if isDebug {
 		log.Printf("%s%sPayload written to buffer. Buffer length now: %d. Calling handleTLSBufferedData.", ColorGray, PrefixTLS, conn.ReceiveBuffer.Len())
 	}
-	handleTLSBufferedData(ifce, conn)
+	handleTLSBufferedData(conn)
 	if isDebug {
 		log.Printf("%s%sExiting handleTLSData.", ColorGray, PrefixTLS)
 	}
@@ -1086,7 +1085,7 @@ func buildServerKeyExchange(dataToSign []byte, skeParamsBytes []byte, privateKey
 }
 
 // sendServerCCSAndFinished sends the Server ChangeCipherSpec and Finished messages.
-func sendServerCCSAndFinished(ifce *water.Interface, conn *TCPConnection) {
+func sendServerCCSAndFinished(conn *TCPConnection) {
 	log.Printf("%s%sSending Server ChangeCipherSpec and Finished.%s", ColorOrange, PrefixTLS, ColorReset)
 
 	ccsPayload := []byte{0x01}
@@ -1098,17 +1097,11 @@ func sendServerCCSAndFinished(ifce *water.Interface, conn *TCPConnection) {
 	if isDebug {
 		log.Printf("%s%sSending ChangeCipherSpec record (%d bytes).", ColorGray, PrefixTLS, len(ccsRecord))
 	}
-	sentBytes, err := sendRawTLSRecord(ifce, conn, ccsRecord)
+	err = sendRawTLSRecord(conn, ccsRecord)
 	if err != nil {
 		log.Printf("%s%sFailed to send ChangeCipherSpec record: %v", ColorRed, PrefixError, err)
 		return
 	}
-	conn.Mutex.Lock()
-	conn.ServerNextSeq += uint32(sentBytes)
-	if isDebug {
-		log.Printf("%s%sAfter CCS: ServerNextSeq = %d (added %d)", ColorGray, PrefixTLS, conn.ServerNextSeq, sentBytes)
-	}
-	conn.Mutex.Unlock()
 
 	log.Printf("%s%sChangeCipherSpec sent.%s", ColorOrange, PrefixTLS, ColorReset)
 
@@ -1155,16 +1148,12 @@ func sendServerCCSAndFinished(ifce *water.Interface, conn *TCPConnection) {
 	if isDebug {
 		log.Printf("%s%sSending Finished record (%d bytes).", ColorGray, PrefixTLS, len(finishedRecord))
 	}
-	sentBytes, err = sendRawTLSRecord(ifce, conn, finishedRecord)
+	err = sendRawTLSRecord(conn, finishedRecord)
 	if err != nil {
 		log.Printf("%s%sFailed to send Finished record: %v", ColorRed, PrefixError, err)
 		return
 	}
 	conn.Mutex.Lock()
-	conn.ServerNextSeq += uint32(sentBytes)
-	if isDebug {
-		log.Printf("%s%sAfter Finished: ServerNextSeq = %d (added %d)", ColorGray, PrefixTLS, conn.ServerNextSeq, sentBytes)
-	}
 	conn.HandshakeMessages.Write(finishedMsg)
 	if isDebug {
 		log.Printf("%s%sAdded Sent Finished Msg (%d bytes). Total len: %d", ColorGray, PrefixTLS, len(finishedMsg), conn.HandshakeMessages.Len())
@@ -1193,58 +1182,4 @@ func buildFinishedMessage(verifyData []byte) ([]byte, error) {
 	return message, nil
 }
 
-// startTLSHandshake initiates the TLS handshake process for a TCP connection.
-func startTLSHandshake(conn *TCPConnection) error {
-	log.Printf("%s%sInitiating TLS handshake in TCP mode.%s", ColorOrange, PrefixInfo, ColorReset)
-
-	conn.Mutex.Lock()
-	if conn.TCPConn == nil {
-		conn.Mutex.Unlock()
-		return fmt.Errorf("startTLSHandshake called with nil TCPConn for %s", conn.ConnectionKey())
-	}
-	conn.TLSState = TLSStateExpectingClientHello
-	conn.Mutex.Unlock()
-
-	readBuf := make([]byte, 4096)
-
-	for {
-		log.Printf("%s%sWaiting to read data from TCP connection...%s", ColorGray, PrefixTLS, ColorReset)
-		n, err := conn.TCPConn.Read(readBuf)
-		if err != nil {
-			log.Printf("%s%sError reading from TCP connection: %v", ColorRed, PrefixError, err)
-			return fmt.Errorf("TCP read error during handshake for %s: %w", conn.ConnectionKey(), err)
-		}
-
-		if n > 0 {
-			log.Printf("%s%sRead %d bytes from TCP connection.%s", ColorGray, PrefixTLS, n, ColorReset)
-			handleTLSData(nil, conn, readBuf[:n])
-
-			conn.Mutex.Lock()
-			currentState := conn.TLSState
-			conn.Mutex.Unlock()
-			if currentState == TLSStateHandshakeComplete {
-				log.Printf("%s%sHandshake completed successfully in TCP mode.%s", ColorOrange, PrefixInfo, ColorReset)
-				break
-			}
-		}
-	}
-
-	log.Printf("%s%sHandshake complete. Entering Application Data phase.%s", ColorOrange, PrefixInfo, ColorReset)
-	for {
-		log.Printf("%s%sWaiting for Application Data...%s", ColorGray, PrefixTLS, ColorReset)
-		n, err := conn.TCPConn.Read(readBuf)
-		if err != nil {
-			log.Printf("%s%sError reading from TCP connection in AppData phase: %v. Closing connection.%s", ColorRed, PrefixError, err, ColorReset)
-			break
-		}
-
-		if n > 0 {
-			log.Printf("%s%sRead %d bytes (AppData phase).%s", ColorGray, PrefixTLS, n, ColorReset)
-			handleTLSData(nil, conn, readBuf[:n])
-		}
-	}
-
-	return nil
-}
-
 // --- Crypto functions removed, moved to crypto.go ---
@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// TLS 1.3 server handshake (full handshake only; no PSK/session resumption).
+// Reference: RFC 8446
+//
+//	ClientHello  --->
+//	             <--- HelloRetryRequest (if the client sent no key share for the server's group)
+//	ClientHello  --->
+//	             <--- ServerHello, {EncryptedExtensions}, {Certificate}, {CertificateVerify}, {Finished}
+//	{Finished}   --->
+
+// TLS Versions
+const (
+	TLSVersion12 uint16 = 0x0303
+	TLSVersion13 uint16 = 0x0304
+)
+
+// TLS 1.3 Cipher Suites
+const (
+	TLS_AES_128_GCM_SHA256 uint16 = 0x1301
+)
+
+// TLS 1.3 Handshake Message Types
+const (
+	TLSHandshakeTypeEncryptedExtensions uint8 = 8
+	TLSHandshakeTypeCertificateVerify   uint8 = 15
+	TLSHandshakeTypeMessageHash         uint8 = 254 // Synthetic message replacing ClientHello1 in the transcript after a HelloRetryRequest
+)
+
+// TLS 1.3 Extension Types
+const (
+	TLSExtensionTypeSupportedGroups     uint16 = 10
+	TLSExtensionTypeSignatureAlgorithms uint16 = 13
+	TLSExtensionTypePreSharedKey        uint16 = 41
+	TLSExtensionTypeEarlyData           uint16 = 42
+	TLSExtensionTypeSupportedVersions   uint16 = 43
+	TLSExtensionTypePSKKeyExchangeModes uint16 = 45
+	TLSExtensionTypeKeyShare            uint16 = 51
+)
+
+// TLS 1.3 Named Groups and Signature Schemes
+const (
+	TLSNamedGroupSecp256r1             uint16 = 0x0017
+	TLSNamedGroupX25519                uint16 = 0x001d
+	TLSSignatureSchemeRSAPSSRSAESHA256 uint16 = 0x0804
+)
+
+const (
+	// TLS13MaxSkippedEarlyDataBytes is the upper limit of rejected 0-RTT data that is skipped
+	TLS13MaxSkippedEarlyDataBytes = 1 << 16
+
+	tls13CertificateVerifyContext = "TLS 1.3, server CertificateVerify"
+)
+
+// TLS Alerts
+const (
+	TLSAlertLevelFatal       uint8 = 2
+	TLSAlertHandshakeFailure uint8 = 40
+	TLSAlertIllegalParameter uint8 = 47
+	TLSAlertDecryptError     uint8 = 51
+	TLSAlertProtocolVersion  uint8 = 70
+)
+
+// TLSKeyShare is a KeyShareEntry of the key_share extension.
+type TLSKeyShare struct {
+	Group       uint16
+	KeyExchange []byte
+}
+
+var (
+	// tls13Enabled is set by the -tls flag; TLS 1.3 is negotiated if the client offers it
+	tls13Enabled bool
+	// tls13Group is the only key exchange group the server accepts for TLS 1.3 (-tlsGroup)
+	tls13Group uint16
+
+	// helloRetryRequestRandom is the ServerHello.random that marks a HelloRetryRequest
+	helloRetryRequestRandom = sha256.Sum256([]byte("HelloRetryRequest"))
+)
+
+// parseTLS13Group converts the -tlsGroup flag value to a NamedGroup.
+func parseTLS13Group(name string) (uint16, error) {
+	switch name {
+	case "x25519":
+		return TLSNamedGroupX25519, nil
+	case "p256":
+		return TLSNamedGroupSecp256r1, nil
+	default:
+		return 0, fmt.Errorf("unsupported group '%s' (choose 'x25519' or 'p256')", name)
+	}
+}
+
+// tls13GroupName converts a NamedGroup to a readable string.
+func tls13GroupName(group uint16) string {
+	switch group {
+	case TLSNamedGroupX25519:
+		return "x25519"
+	case TLSNamedGroupSecp256r1:
+		return "secp256r1"
+	default:
+		return fmt.Sprintf("0x%04x", group)
+	}
+}
+
+// tls13Curve returns the ECDH curve of a supported NamedGroup.
+func tls13Curve(group uint16) (ecdh.Curve, error) {
+	switch group {
+	case TLSNamedGroupX25519:
+		return ecdh.X25519(), nil
+	case TLSNamedGroupSecp256r1:
+		return ecdh.P256(), nil
+	default:
+		return nil, fmt.Errorf("unsupported group %s", tls13GroupName(group))
+	}
+}
+
+// clientOffersTLS13 reports whether the supported_versions extension of the ClientHello contains TLS 1.3.
+func clientOffersTLS13(info *ClientHelloInfo) bool {
+	return containsUint16(info.SupportedVersions, TLSVersion13)
+}
+
+func containsUint16(list []uint16, value uint16) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// handleClientHello13 answers a ClientHello offering TLS 1.3 with a HelloRetryRequest or with the
+// whole server flight (ServerHello ... Finished), and waits for the client Finished.
+func handleClientHello13(conn *TCPConnection, info *ClientHelloInfo) {
+	secondHello := conn.TLS13HelloRetrySent
+	conn.TLSVersion = TLSVersion13
+
+	groupNames := make([]string, 0, len(info.KeyShares))
+	for _, keyShare := range info.KeyShares {
+		groupNames = append(groupNames, tls13GroupName(keyShare.Group))
+	}
+	log.Printf("%s%sRCV ClientHello (TLS 1.3, second: %t): KeyShares: %v, SupportedGroups: %d, SignatureAlgorithms: %d, EarlyData: %t, PreSharedKey: %t%s",
+		ColorOrange, PrefixTLS, secondHello, groupNames, len(info.SupportedGroups), len(info.SignatureAlgorithms), info.EarlyData, info.PreSharedKey, ColorReset)
+
+	if !clientOffersTLS13(info) {
+		log.Printf("%s%sSecond ClientHello does not offer TLS 1.3.%s", ColorRed, PrefixError, ColorReset)
+		sendTLSAlert(conn, TLSAlertProtocolVersion)
+		return
+	}
+	if !containsUint16(info.CipherSuites, TLS_AES_128_GCM_SHA256) {
+		log.Printf("%s%sClient does not offer TLS_AES_128_GCM_SHA256.%s", ColorRed, PrefixError, ColorReset)
+		sendTLSAlert(conn, TLSAlertHandshakeFailure)
+		return
+	}
+	if !containsUint16(info.SignatureAlgorithms, TLSSignatureSchemeRSAPSSRSAESHA256) {
+		log.Printf("%s%sClient does not accept rsa_pss_rsae_sha256 signatures.%s", ColorRed, PrefixError, ColorReset)
+		sendTLSAlert(conn, TLSAlertHandshakeFailure)
+		return
+	}
+
+	// --- 0-RTT ---
+	// PSK (セッション再開) を実装していないため 0-RTT は常に拒否する。
+	// EncryptedExtensions に early_data を含めないことで拒否を伝え、届いた early data は読み飛ばす。
+	if info.EarlyData {
+		if secondHello {
+			log.Printf("%s%sSecond ClientHello must not offer early_data.%s", ColorRed, PrefixError, ColorReset)
+			sendTLSAlert(conn, TLSAlertIllegalParameter)
+			return
+		}
+		conn.Mutex.Lock()
+		conn.TLS13SkipEarlyData = true
+		conn.Mutex.Unlock()
+		log.Printf("%s%s0-RTT: Client offered early data. Rejecting it (no PSK support); early data records will be skipped.%s", ColorOrange, PrefixTLS, ColorReset)
+	}
+	if info.PreSharedKey {
+		log.Printf("%s%sPSK: Ignoring pre_shared_key (session resumption is not supported), doing a full handshake.%s", ColorOrange, PrefixTLS, ColorReset)
+	}
+
+	// --- Key Share / HelloRetryRequest ---
+	var clientKeyShare *TLSKeyShare
+	for i := range info.KeyShares {
+		if info.KeyShares[i].Group == tls13Group {
+			clientKeyShare = &info.KeyShares[i]
+			break
+		}
+	}
+	if clientKeyShare == nil {
+		if secondHello || !containsUint16(info.SupportedGroups, tls13Group) {
+			log.Printf("%s%sClient cannot use the key exchange group %s.%s", ColorRed, PrefixError, tls13GroupName(tls13Group), ColorReset)
+			if secondHello {
+				sendTLSAlert(conn, TLSAlertIllegalParameter)
+			} else {
+				sendTLSAlert(conn, TLSAlertHandshakeFailure)
+			}
+			return
+		}
+		sendHelloRetryRequest13(conn, info)
+		return
+	}
+
+	curve, err := tls13Curve(clientKeyShare.Group)
+	if err != nil {
+		log.Printf("%s%s%v%s", ColorRed, PrefixError, err, ColorReset)
+		sendTLSAlert(conn, TLSAlertHandshakeFailure)
+		return
+	}
+	clientPublicKey, err := curve.NewPublicKey(clientKeyShare.KeyExchange)
+	if err != nil {
+		log.Printf("%s%sInvalid client key share (%s): %v%s", ColorRed, PrefixError, tls13GroupName(clientKeyShare.Group), err, ColorReset)
+		sendTLSAlert(conn, TLSAlertIllegalParameter)
+		return
+	}
+	serverPrivateKey, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Printf("%s%sFailed to generate key share: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	sharedSecret, err := serverPrivateKey.ECDH(clientPublicKey)
+	if err != nil {
+		log.Printf("%s%sECDHE shared secret computation failed: %v%s", ColorRed, PrefixError, err, ColorReset)
+		sendTLSAlert(conn, TLSAlertIllegalParameter)
+		return
+	}
+	conn.ServerECDHPrivateKey = serverPrivateKey
+	conn.ClientECDHPublicKeyBytes = append([]byte(nil), clientKeyShare.KeyExchange...)
+	conn.CipherSuite = TLS_AES_128_GCM_SHA256
+	log.Printf("%s%sKey exchange: %s, computed shared secret (%d bytes).%s", ColorOrange, PrefixTLS, tls13GroupName(clientKeyShare.Group), len(sharedSecret), ColorReset)
+
+	chosenALPN := ""
+	if containsString(info.ALPNProtocols, "h2") {
+		chosenALPN = "h2"
+		log.Printf("%s%sALPN: Client offered 'h2', server selected 'h2'.%s", ColorOrange, PrefixTLS, ColorReset)
+	} else {
+		log.Printf("%s%sALPN: Client offered %v, server selects no protocol (implies HTTP/1.1).%s", ColorOrange, PrefixTLS, info.ALPNProtocols, ColorReset)
+	}
+	conn.NegotiatedProtocol = chosenALPN
+
+	// --- ServerHello ---
+	serverRandom := make([]byte, 32)
+	if _, err := rand.Read(serverRandom); err != nil {
+		log.Printf("%s%sFailed to generate server random: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	conn.ClientRandom = append([]byte(nil), info.Random...)
+	conn.ServerRandom = serverRandom
+
+	serverPublicKey := serverPrivateKey.PublicKey().Bytes()
+	keyShareExt := binary.BigEndian.AppendUint16(nil, clientKeyShare.Group)
+	keyShareExt = binary.BigEndian.AppendUint16(keyShareExt, uint16(len(serverPublicKey)))
+	keyShareExt = append(keyShareExt, serverPublicKey...)
+	serverHello := buildServerHello13(serverRandom, info.SessionID, map[uint16][]byte{
+		TLSExtensionTypeSupportedVersions: binary.BigEndian.AppendUint16(nil, TLSVersion13),
+		TLSExtensionTypeKeyShare:          keyShareExt,
+	})
+	if err := sendHandshakeMessage13(conn, serverHello, "ServerHello"); err != nil {
+		log.Printf("%s%s%v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	if !secondHello && len(info.SessionID) > 0 {
+		sendChangeCipherSpec13(conn)
+	}
+
+	// --- Handshake Secret ---
+	deriveHandshakeKeys13(conn, sharedSecret)
+	conn.TLSState = TLSStateSentServerHello
+	log.Printf("%s%sServerHello sent. Handshake traffic keys installed. TLS State -> %v%s", ColorOrange, PrefixTLS, conn.TLSState, ColorReset)
+
+	// --- EncryptedExtensions ---
+	extensions := map[uint16][]byte{}
+	if chosenALPN != "" {
+		extensions[TLSExtensionTypeALPN] = buildALPNExtensionData(chosenALPN)
+	}
+	if err := sendHandshakeMessage13(conn, buildHandshakeMessage(TLSHandshakeTypeEncryptedExtensions, buildExtensions(extensions)), "EncryptedExtensions"); err != nil {
+		log.Printf("%s%s%v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+
+	// --- Certificate ---
+	certMsg, err := buildCertificateMessage13()
+	if err != nil {
+		log.Printf("%s%sFailed to build Certificate message: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	if err := sendHandshakeMessage13(conn, certMsg, "Certificate"); err != nil {
+		log.Printf("%s%s%v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	conn.TLSState = TLSStateSentCertificate
+
+	// --- CertificateVerify ---
+	certVerifyMsg, err := buildCertificateVerify13(handshakeTranscript(conn), serverCert.PrivateKey)
+	if err != nil {
+		log.Printf("%s%sFailed to build CertificateVerify message: %v%s", ColorRed, PrefixError, err, ColorReset)
+		sendTLSAlert(conn, TLSAlertHandshakeFailure)
+		return
+	}
+	if err := sendHandshakeMessage13(conn, certVerifyMsg, "CertificateVerify"); err != nil {
+		log.Printf("%s%s%v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+
+	// --- Finished ---
+	serverVerifyData := computeFinishedVerifyData13(conn.TLS13ServerHandshakeSecret, handshakeTranscript(conn))
+	finishedMsg, err := buildFinishedMessage(serverVerifyData)
+	if err != nil {
+		log.Printf("%s%sFailed to build Finished message: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	if err := sendHandshakeMessage13(conn, finishedMsg, "Finished"); err != nil {
+		log.Printf("%s%s%v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+
+	// --- Master Secret ---
+	deriveApplicationKeys13(conn)
+	conn.TLSState = TLSStateExpectingFinished
+	log.Printf("%s%sServer flight sent. Waiting for client Finished. TLS State -> %v%s", ColorOrange, PrefixTLS, conn.TLSState, ColorReset)
+}
+
+// sendHelloRetryRequest13 asks the client for a new ClientHello with a key share for tls13Group.
+// The first ClientHello is replaced by a message_hash message in the transcript.
+func sendHelloRetryRequest13(conn *TCPConnection, info *ClientHelloInfo) {
+	log.Printf("%s%sNo key share for %s in ClientHello. Sending HelloRetryRequest.%s", ColorOrange, PrefixTLS, tls13GroupName(tls13Group), ColorReset)
+
+	conn.Mutex.Lock()
+	clientHello1Hash := sha256.Sum256(conn.HandshakeMessages.Bytes())
+	conn.HandshakeMessages.Reset()
+	conn.HandshakeMessages.Write(buildHandshakeMessage(TLSHandshakeTypeMessageHash, clientHello1Hash[:]))
+	conn.Mutex.Unlock()
+
+	helloRetryRequest := buildServerHello13(helloRetryRequestRandom[:], info.SessionID, map[uint16][]byte{
+		TLSExtensionTypeSupportedVersions: binary.BigEndian.AppendUint16(nil, TLSVersion13),
+		TLSExtensionTypeKeyShare:          binary.BigEndian.AppendUint16(nil, tls13Group), // selected_group only
+	})
+	if err := sendHandshakeMessage13(conn, helloRetryRequest, "HelloRetryRequest"); err != nil {
+		log.Printf("%s%s%v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	if len(info.SessionID) > 0 {
+		sendChangeCipherSpec13(conn)
+	}
+
+	conn.TLS13HelloRetrySent = true
+	conn.TLSState = TLSStateExpectingClientHello
+	log.Printf("%s%sHelloRetryRequest sent. Waiting for the second ClientHello. TLS State -> %v%s", ColorOrange, PrefixTLS, conn.TLSState, ColorReset)
+}
+
+// handleClientFinished13 verifies the client Finished and switches to the client application traffic keys.
+func handleClientFinished13(conn *TCPConnection, verifyData []byte, fullHandshakeMessage []byte) {
+	transcript := handshakeTranscript(conn)
+	// 受信した Finished 自身は検証対象のトランスクリプトに含めない
+	transcript = transcript[:len(transcript)-len(fullHandshakeMessage)]
+	expectedVerifyData := computeFinishedVerifyData13(conn.TLS13ClientHandshakeSecret, transcript)
+	if !hmac.Equal(expectedVerifyData, verifyData) {
+		log.Printf("%s%sClient Finished verification failed! verify_data mismatch.%s", ColorRed, PrefixError, ColorReset)
+		sendTLSAlert(conn, TLSAlertDecryptError)
+		return
+	}
+	log.Printf("%s%sClient Finished verification successful.%s", ColorOrange, PrefixTLS, ColorReset)
+
+	installClientApplicationKeys13(conn)
+	conn.TLSState = TLSStateHandshakeComplete
+	log.Printf("%s%sTLS 1.3 Handshake complete (TLS_AES_128_GCM_SHA256, %s). TLS State -> %v%s", ColorOrange, PrefixTLS, tls13GroupName(tls13Group), conn.TLSState, ColorReset)
+	pauseIfNeeded("tls")
+}
+
+// handshakeTranscript returns a copy of the handshake messages sent and received so far.
+func handshakeTranscript(conn *TCPConnection) []byte {
+	conn.Mutex.Lock()
+	defer conn.Mutex.Unlock()
+	return append([]byte(nil), conn.HandshakeMessages.Bytes()...)
+}
+
+// sendHandshakeMessage13 adds the handshake message to the transcript and sends it in a record
+// (encrypted once the handshake traffic keys are installed).
+func sendHandshakeMessage13(conn *TCPConnection, message []byte, name string) error {
+	conn.Mutex.Lock()
+	conn.HandshakeMessages.Write(message)
+	encrypted := conn.EncryptionEnabled
+	conn.Mutex.Unlock()
+
+	record, err := buildTLSRecord(TLSRecordTypeHandshake, TLSVersion12, message)
+	if err != nil {
+		return fmt.Errorf("failed to build %s record: %w", name, err)
+	}
+	err = sendRawTLSRecord(conn, record)
+	if err != nil {
+		return fmt.Errorf("failed to send %s record: %w", name, err)
+	}
+
+	log.Printf("%s%sSND %s (%d bytes, encrypted: %t)%s", ColorOrange, PrefixTLS, name, len(message), encrypted, ColorReset)
+	return nil
+}
+
+// sendChangeCipherSpec13 sends the dummy ChangeCipherSpec of the middlebox compatibility mode
+// (used when the client sent a non-empty legacy_session_id).
+func sendChangeCipherSpec13(conn *TCPConnection) {
+	record, err := buildTLSRecord(TLSRecordTypeChangeCipherSpec, TLSVersion12, []byte{0x01})
+	if err != nil {
+		log.Printf("%s%sFailed to build ChangeCipherSpec record: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	err = sendRawTLSRecord(conn, record)
+	if err != nil {
+		log.Printf("%s%sFailed to send ChangeCipherSpec record: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	log.Printf("%s%sSND ChangeCipherSpec (middlebox compatibility)%s", ColorOrange, PrefixTLS, ColorReset)
+}
+
+// sendTLSAlert sends a fatal alert (encrypted if the keys are installed).
+func sendTLSAlert(conn *TCPConnection, description uint8) {
+	log.Printf("%s%sSND Alert (fatal, description: %d)%s", ColorOrange, PrefixTLS, description, ColorReset)
+	record, err := buildTLSRecord(TLSRecordTypeAlert, TLSVersion12, []byte{TLSAlertLevelFatal, description})
+	if err != nil {
+		log.Printf("%s%sFailed to build Alert record: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+	err = sendRawTLSRecord(conn, record)
+	if err != nil {
+		log.Printf("%s%sFailed to send Alert record: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+}
+
+// buildHandshakeMessage prepends the handshake header (type + 3-byte length) to the body.
+func buildHandshakeMessage(handshakeType uint8, body []byte) []byte {
+	message := make([]byte, 4, 4+len(body))
+	message[0] = handshakeType
+	message[1] = byte(len(body) >> 16)
+	message[2] = byte(len(body) >> 8)
+	message[3] = byte(len(body))
+	return append(message, body...)
+}
+
+// buildExtensions encodes the extensions (in ascending order of type) with the 2-byte total length.
+func buildExtensions(extensions map[uint16][]byte) []byte {
+	extTypes := make([]int, 0, len(extensions))
+	for extType := range extensions {
+		extTypes = append(extTypes, int(extType))
+	}
+	sort.Ints(extTypes)
+
+	var body []byte
+	for _, extType := range extTypes {
+		data := extensions[uint16(extType)]
+		body = binary.BigEndian.AppendUint16(body, uint16(extType))
+		body = binary.BigEndian.AppendUint16(body, uint16(len(data)))
+		body = append(body, data...)
+	}
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(body))), body...)
+}
+
+// buildALPNExtensionData encodes the ALPN extension data with the single selected protocol.
+func buildALPNExtensionData(protocol string) []byte {
+	data := binary.BigEndian.AppendUint16(nil, uint16(1+len(protocol)))
+	data = append(data, byte(len(protocol)))
+	return append(data, protocol...)
+}
+
+// buildServerHello13 constructs a TLS 1.3 ServerHello (or HelloRetryRequest) message.
+// legacy_version is 0x0303; the real version is in the supported_versions extension.
+func buildServerHello13(serverRandom []byte, sessionID []byte, extensions map[uint16][]byte) []byte {
+	body := binary.BigEndian.AppendUint16(nil, TLSVersion12)
+	body = append(body, serverRandom...)
+	body = append(body, byte(len(sessionID)))
+	body = append(body, sessionID...) // legacy_session_id_echo
+	body = binary.BigEndian.AppendUint16(body, TLS_AES_128_GCM_SHA256)
+	body = append(body, 0) // legacy_compression_method
+	body = append(body, buildExtensions(extensions)...)
+	return buildHandshakeMessage(TLSHandshakeTypeServerHello, body)
+}
+
+// buildCertificateMessage13 constructs the TLS 1.3 Certificate message
+// (empty certificate_request_context, and empty extensions for each certificate).
+func buildCertificateMessage13() ([]byte, error) {
+	if len(serverCertDER) == 0 {
+		return nil, errors.New("server certificate not loaded")
+	}
+
+	var certList bytes.Buffer
+	for _, certDER := range serverCertDER {
+		if len(certDER) == 0 || len(certDER) >= 1<<24 {
+			return nil, fmt.Errorf("invalid certificate DER length: %d", len(certDER))
+		}
+		certList.Write([]byte{byte(len(certDER) >> 16), byte(len(certDER) >> 8), byte(len(certDER))})
+		certList.Write(certDER)
+		certList.Write([]byte{0, 0}) // CertificateEntry extensions
+	}
+
+	body := []byte{0} // certificate_request_context
+	body = append(body, byte(certList.Len()>>16), byte(certList.Len()>>8), byte(certList.Len()))
+	body = append(body, certList.Bytes()...)
+	return buildHandshakeMessage(TLSHandshakeTypeCertificate, body), nil
+}
+
+// buildCertificateVerify13 signs the transcript (ClientHello...Certificate) with RSA-PSS.
+// Signed content = 64 spaces + "TLS 1.3, server CertificateVerify" + 0x00 + Transcript-Hash
+func buildCertificateVerify13(transcript []byte, privateKey crypto.PrivateKey) ([]byte, error) {
+	rsaKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("server key is not an RSA private key, cannot sign CertificateVerify")
+	}
+
+	transcriptHash := sha256.Sum256(transcript)
+	content := bytes.Repeat([]byte{0x20}, 64)
+	content = append(content, tls13CertificateVerifyContext...)
+	content = append(content, 0)
+	content = append(content, transcriptHash[:]...)
+	contentHash := sha256.Sum256(content)
+
+	signature, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, contentHash[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign CertificateVerify: %w", err)
+	}
+
+	body := binary.BigEndian.AppendUint16(nil, TLSSignatureSchemeRSAPSSRSAESHA256)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(signature)))
+	body = append(body, signature...)
+	return buildHandshakeMessage(TLSHandshakeTypeCertificateVerify, body), nil
+}
+
+// parseSupportedVersionsExtension parses the supported_versions extension of a ClientHello (1-byte length + versions).
+func parseSupportedVersionsExtension(data []byte) ([]uint16, error) {
+	if len(data) < 1 || int(data[0]) != len(data)-1 || data[0]%2 != 0 {
+		return nil, errors.New("malformed supported_versions extension")
+	}
+	return parseUint16List(data[1:]), nil
+}
+
+// parseUint16ListExtension parses an extension consisting of a 2-byte length and a uint16 list
+// (supported_groups, signature_algorithms).
+func parseUint16ListExtension(data []byte) ([]uint16, error) {
+	if len(data) < 2 || int(binary.BigEndian.Uint16(data[0:2])) != len(data)-2 || len(data)%2 != 0 {
+		return nil, errors.New("malformed list extension")
+	}
+	return parseUint16List(data[2:]), nil
+}
+
+func parseUint16List(data []byte) []uint16 {
+	list := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		list = append(list, binary.BigEndian.Uint16(data[i:i+2]))
+	}
+	return list
+}
+
+// parseKeyShareExtension parses the key_share extension of a ClientHello.
+func parseKeyShareExtension(data []byte) ([]TLSKeyShare, error) {
+	if len(data) < 2 || int(binary.BigEndian.Uint16(data[0:2])) != len(data)-2 {
+		return nil, errors.New("malformed key_share extension")
+	}
+	var keyShares []TLSKeyShare
+	offset := 2
+	for offset < len(data) {
+		if offset+4 > len(data) {
+			return nil, errors.New("key_share entry truncated")
+		}
+		group := binary.BigEndian.Uint16(data[offset : offset+2])
+		keyLen := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		offset += 4
+		if offset+keyLen > len(data) {
+			return nil, fmt.Errorf("key_share entry (group 0x%04x) truncated", group)
+		}
+		keyShares = append(keyShares, TLSKeyShare{Group: group, KeyExchange: data[offset : offset+keyLen]})
+		offset += keyLen
+	}
+	return keyShares, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
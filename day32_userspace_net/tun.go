@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"os/exec"
+	"runtime"
 	"strings"
 
 	"github.com/songgao/water"
@@ -73,6 +74,64 @@ func setupTUN(devName, localIP, remoteIP, subnetMask string, mtu int) (*water.In
 	return ifce, nil
 }
 
+// setupTAP creates and configures the TAP device.
+// Unlike TUN mode, the device is a broadcast interface: the host side gets localIP and the
+// userspace stack answers ARP for its own address on the same subnet.
+func setupTAP(devName, localIP, subnetMask string, mtu int) (*water.Interface, error) {
+	log.Printf("Attempting to setup TAP device '%s'...", devName)
+
+	// 1. Create TAP interface with water
+	config := water.Config{
+		DeviceType: water.TAP,
+	}
+	if devName != "" {
+		config.Name = devName
+	}
+
+	ifce, err := water.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TAP device: %w", err)
+	}
+	actualDevName := ifce.Name()
+	log.Printf("TAP device '%s' created by water.", actualDevName)
+
+	// 2. Configure the interface using os/exec
+	// The route for the subnet is added by the OS for a broadcast interface.
+	log.Printf("Configuring device '%s' with IP %s, Mask %s, MTU %d", actualDevName, localIP, subnetMask, mtu)
+
+	// ifconfig <dev> <local_ip> netmask <subnet_mask> mtu <mtu> up
+	cmdIfconfig := exec.Command("ifconfig", actualDevName, localIP, "netmask", subnetMask, "mtu", fmt.Sprintf("%d", mtu), "up")
+	output, err := cmdIfconfig.CombinedOutput()
+	if err != nil {
+		ifce.Close() // Close the interface if config fails
+		return nil, fmt.Errorf("ifconfig failed: %w Output: %s", err, string(output))
+	}
+	log.Printf("ifconfig output: %s", string(output))
+
+	return ifce, nil
+}
+
+// setupIPv6 adds the IPv6 address of the host side to the TUN/TAP device.
+// The userspace stack itself answers on remoteIP6 (Neighbor Discovery in TAP mode).
+func setupIPv6(devName, localIP6, remoteIP6 string, prefixLen int) error {
+	log.Printf("Configuring device '%s' with IPv6 %s/%d (stack: %s)", devName, localIP6, prefixLen, remoteIP6)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "linux" {
+		// ip -6 addr add <local_ip6>/<prefix_len> dev <dev>
+		cmd = exec.Command("ip", "-6", "addr", "add", fmt.Sprintf("%s/%d", localIP6, prefixLen), "dev", devName)
+	} else {
+		// ifconfig <dev> inet6 <local_ip6> prefixlen <prefix_len> alias
+		cmd = exec.Command("ifconfig", devName, "inet6", localIP6, "prefixlen", fmt.Sprintf("%d", prefixLen), "alias")
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("IPv6 address setup failed: %w Output: %s", err, string(output))
+	}
+	log.Printf("IPv6 address setup output: %s", string(output))
+	return nil
+}
+
 // processPackets reads packets from the TUN device and parses them.
 func processPackets(ifce *water.Interface) {
 	packet := make([]byte, *mtu+4) // Buffer needs to be large enough for MTU + potential headers (like macOS loopback header)
@@ -100,8 +159,8 @@ func processPackets(ifce *water.Interface) {
 		}
 
 		var ipPacketData []byte
-		// Check for macOS TUN header (AF_INET = 0x00000002, Big Endian)
-		if n > 4 && binary.BigEndian.Uint32(packet[:4]) == 2 {
+		// Check for macOS TUN header (AF_INET = 0x00000002 / AF_INET6 = 0x0000001e, Big Endian)
+		if af := binary.BigEndian.Uint32(packet[:4]); n > 4 && (af == 2 || af == 30) {
 			ipPacketData = packet[4:n]
 		} else {
 			// Assume no prefix header (might be needed for other OS or configurations)
@@ -111,6 +170,17 @@ func processPackets(ifce *water.Interface) {
 		if len(ipPacketData) == 0 {
 			continue
 		}
+		capturePacket(ipPacketData, false)
+
+		if ipPacketData[0]>>4 == IPv6Version {
+			ipv6Header, payload, err := parseIPv6Header(ipPacketData)
+			if err != nil {
+				log.Printf("Error parsing IPv6 header: %v (Packet length: %d)", err, len(ipPacketData))
+				continue
+			}
+			dispatchIPv6Packet(ifce, ipv6Header, payload, len(ipPacketData))
+			continue
+		}
 
 		// Try parsing the packet as IPv4
 		ipHeader, payload, err := parseIPv4Header(ipPacketData)
@@ -122,17 +192,22 @@ func processPackets(ifce *water.Interface) {
 		// Print parsed header information
 		printHeaderInfo(ipHeader, len(ipPacketData))
 
-		// Handle based on protocol
-		switch ipHeader.Protocol {
-		case ICMPProtocolNumber:
-			handleICMPPacket(ifce, ipHeader, payload)
-		case TCPProtocolNumber:
-			handleTCPPacket(ifce, ipHeader, payload)
-		// case 17: // UDP
-		//  log.Println("UDP Packet received (not handled)")
-		default:
-			// log.Printf("Unhandled IP protocol: %d", ipHeader.Protocol)
-		}
+		dispatchIPPacket(ifce, ipHeader, payload)
+	}
+}
+
+// dispatchIPPacket hands the IP payload to the protocol handler (common to TUN and TAP mode).
+func dispatchIPPacket(ifce *water.Interface, ipHeader *IPv4Header, payload []byte) {
+	// Handle based on protocol
+	switch ipHeader.Protocol {
+	case ICMPProtocolNumber:
+		handleICMPPacket(ifce, ipHeader, payload)
+	case TCPProtocolNumber:
+		handleTCPPacket(ifce, ipHeader.SrcIP, ipHeader.DstIP, payload)
+	case UDPProtocolNumber:
+		handleUDPPacket(ifce, ipHeader.SrcIP, ipHeader.DstIP, payload)
+	default:
+		// log.Printf("Unhandled IP protocol: %d", ipHeader.Protocol)
 	}
 }
 
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/songgao/water"
+)
+
+// UDPHeader represents the UDP header structure.
+// Reference: RFC 768
+type UDPHeader struct {
+	SrcPort  uint16 // Source Port
+	DstPort  uint16 // Destination Port
+	Length   uint16 // Length of UDP header + data
+	Checksum uint16 // Checksum (0 = not computed by the sender)
+}
+
+const (
+	UDPProtocolNumber    = 17
+	UDPHeaderLengthBytes = 8
+	DNSPort              = 53
+)
+
+// parseUDPHeader parses the UDP header and returns it with the UDP data.
+func parseUDPHeader(udpPayload []byte) (*UDPHeader, []byte, error) {
+	if len(udpPayload) < UDPHeaderLengthBytes {
+		return nil, nil, fmt.Errorf("UDP payload too short: %d bytes", len(udpPayload))
+	}
+
+	header := &UDPHeader{
+		SrcPort:  binary.BigEndian.Uint16(udpPayload[0:2]),
+		DstPort:  binary.BigEndian.Uint16(udpPayload[2:4]),
+		Length:   binary.BigEndian.Uint16(udpPayload[4:6]),
+		Checksum: binary.BigEndian.Uint16(udpPayload[6:8]),
+	}
+	if int(header.Length) < UDPHeaderLengthBytes || int(header.Length) > len(udpPayload) {
+		return nil, nil, fmt.Errorf("invalid UDP length %d (payload: %d bytes)", header.Length, len(udpPayload))
+	}
+
+	// IPパディングなどで余分なバイトがある場合は Length までを UDP データとする
+	return header, udpPayload[UDPHeaderLengthBytes:header.Length], nil
+}
+
+// handleUDPPacket parses the UDP header and dispatches the datagram based on the destination port.
+func handleUDPPacket(ifce *water.Interface, srcIP, dstIP net.IP, udpPayload []byte) {
+	udpHeader, data, err := parseUDPHeader(udpPayload)
+	if err != nil {
+		log.Printf("%s%sFailed to parse UDP header: %v%s", ColorRed, PrefixError, err, ColorReset)
+		return
+	}
+
+	// Checksum 0 means the sender did not compute it (allowed for IPv4)
+	if udpHeader.Checksum != 0 {
+		checksum, err := calculateUDPChecksum(srcIP, dstIP, udpPayload[:udpHeader.Length])
+		if err != nil {
+			log.Printf("%s%sFailed to verify UDP checksum: %v%s", ColorRed, PrefixError, err, ColorReset)
+			return
+		}
+		if checksum != udpHeader.Checksum {
+			log.Printf("%s%sInvalid UDP checksum (calculated: 0x%04x, header: 0x%04x), dropping datagram.%s", ColorYellow, PrefixWarn, checksum, udpHeader.Checksum, ColorReset)
+			return
+		}
+	}
+
+	log.Printf("%s%sRCV: %s:%d -> %s:%d Len: %d%s",
+		ColorBlue, PrefixUDP,
+		srcIP, udpHeader.SrcPort, dstIP, udpHeader.DstPort, len(data),
+		ColorReset,
+	)
+	pauseIfNeeded("udp")
+
+	switch udpHeader.DstPort {
+	case DNSPort:
+		handleDNSQuery(ifce, srcIP, dstIP, udpHeader, data)
+	default:
+		log.Printf("%s%sNo UDP service on port %d, dropping datagram from %s:%d%s", ColorGray, PrefixUDP, udpHeader.DstPort, srcIP, udpHeader.SrcPort, ColorReset)
+	}
+}
+
+// sendUDPPacket constructs and sends a UDP datagram over the TUN interface.
+func sendUDPPacket(ifce *water.Interface, srcIP, dstIP net.IP, srcPort, dstPort uint16, payload []byte) error {
+	if ifce == nil {
+		return fmt.Errorf("cannot send UDP packet: TUN interface is nil")
+	}
+	log.Printf("%s%sSND: %s:%d -> %s:%d Len: %d%s",
+		ColorPurple, PrefixUDP,
+		srcIP, srcPort, dstIP, dstPort, len(payload),
+		ColorReset,
+	)
+
+	udpPacket, err := buildUDPPacket(srcIP, dstIP, srcPort, dstPort, payload)
+	if err != nil {
+		return fmt.Errorf("failed to build UDP packet: %w", err)
+	}
+	return sendIPPacket(ifce, srcIP, dstIP, UDPProtocolNumber, udpPacket)
+}
+
+// buildUDPPacket creates a UDP header + data byte slice including the checksum.
+func buildUDPPacket(srcIP, dstIP net.IP, srcPort, dstPort uint16, payload []byte) ([]byte, error) {
+	length := UDPHeaderLengthBytes + len(payload)
+	if length > 0xFFFF {
+		return nil, fmt.Errorf("UDP payload too large: %d bytes", len(payload))
+	}
+
+	packet := make([]byte, UDPHeaderLengthBytes, length)
+	binary.BigEndian.PutUint16(packet[0:2], srcPort)
+	binary.BigEndian.PutUint16(packet[2:4], dstPort)
+	binary.BigEndian.PutUint16(packet[4:6], uint16(length))
+	// Checksum (bytes 6-7) is initially 0
+	packet = append(packet, payload...)
+
+	checksum, err := calculateUDPChecksum(srcIP, dstIP, packet)
+	if err != nil {
+		return nil, err
+	}
+	// 計算結果が0の場合は「チェックサムなし」と区別するため 0xFFFF を送る (RFC 768)
+	if checksum == 0 {
+		checksum = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(packet[6:8], checksum)
+
+	return packet, nil
+}
+
+// calculateUDPChecksum calculates the UDP checksum over the pseudo header and the UDP packet.
+// The checksum field of the packet is treated as zero.
+func calculateUDPChecksum(srcIP, dstIP net.IP, udpPacket []byte) (uint16, error) {
+	if len(udpPacket) < UDPHeaderLengthBytes {
+		return 0, fmt.Errorf("UDP packet too short for checksum: %d bytes", len(udpPacket))
+	}
+	pseudoHeader, err := buildPseudoHeader(srcIP, dstIP, UDPProtocolNumber, len(udpPacket))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build pseudo header for UDP checksum: %w", err)
+	}
+
+	dataForChecksum := append(pseudoHeader, udpPacket...)
+	// Zero out checksum field within the combined data for calculation
+	binary.BigEndian.PutUint16(dataForChecksum[len(pseudoHeader)+6:len(pseudoHeader)+8], 0)
+
+	return calculateChecksum(dataForChecksum), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	chip8 "github.com/lirlia/100day_challenge_backend/day37_chip8_emulator_go/internal/chip8"
+)
+
+// line is one disassembled instruction (or a trailing odd byte when inst is nil).
+type line struct {
+	addr  uint16
+	bytes []byte
+	inst  *chip8.Instruction
+}
+
+func main() {
+	romPath := flag.String("rom", "", "Path to the CHIP-8 ROM file")
+	variantSCHIP := flag.Bool("schip", false, "Decode SCHIP instructions")
+	variantXOCHIP := flag.Bool("xochip", false, "Decode XO-CHIP instructions")
+	flag.Parse()
+
+	if *romPath == "" {
+		fmt.Println("Usage: go run ./cmd/chip8_disasm -rom <path_to_rom>")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	rom, err := os.ReadFile(*romPath)
+	if err != nil {
+		log.Fatalf("Failed to read ROM '%s': %v", *romPath, err)
+	}
+
+	// The decoder belongs to the interpreter, so the listing always matches what the emulator executes
+	emu := chip8.New(0, *variantSCHIP)
+	if *variantXOCHIP {
+		emu.EnableXOCHIP()
+	}
+
+	lines := disassemble(emu, rom)
+	labels := collectLabels(lines)
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	fmt.Fprintf(w, "; %s (%d bytes, schip=%t, xochip=%t)\n", filepath.Base(*romPath), len(rom), *variantSCHIP, *variantXOCHIP)
+	labelOf := func(addr uint16) string {
+		if name, ok := labels[addr]; ok {
+			return name
+		}
+		return fmt.Sprintf("0x%03X", addr) // Not an instruction boundary (e.g. data, or outside the ROM)
+	}
+	for _, l := range lines {
+		if name, ok := labels[l.addr]; ok {
+			fmt.Fprintf(w, "\n%s:\n", name)
+		}
+		if l.inst == nil {
+			fmt.Fprintf(w, "    0x%03X  %02X         DB 0x%02X\n", l.addr, l.bytes[0], l.bytes[0])
+			continue
+		}
+		raw := fmt.Sprintf("%02X%02X", l.bytes[0], l.bytes[1])
+		if len(l.bytes) == 4 {
+			raw += fmt.Sprintf(" %02X%02X", l.bytes[2], l.bytes[3])
+		}
+		fmt.Fprintf(w, "    0x%03X  %-9s  %-20s ; %s\n", l.addr, raw, l.inst.Format(labelOf), l.inst.Description())
+	}
+}
+
+// disassemble decodes rom linearly from chip8.ROMStart.
+// CHIP-8 programs mix code and data (sprites), so data is decoded as instructions too.
+func disassemble(emu *chip8.Chip8, rom []byte) []line {
+	var lines []line
+	for i := 0; i < len(rom); {
+		addr := uint16(chip8.ROMStart + i)
+		if i+1 >= len(rom) {
+			lines = append(lines, line{addr: addr, bytes: rom[i : i+1]})
+			break
+		}
+		inst := emu.Decode(uint16(rom[i])<<8 | uint16(rom[i+1]))
+		if inst.Op == chip8.OpLDILong {
+			if i+3 >= len(rom) {
+				inst.Op = chip8.OpUnknown // The address word is cut off by the end of the ROM
+			} else {
+				inst.Long = uint16(rom[i+2])<<8 | uint16(rom[i+3])
+			}
+		}
+		size := int(inst.Size())
+		lines = append(lines, line{addr: addr, bytes: rom[i : i+size], inst: &inst})
+		i += size
+	}
+	return lines
+}
+
+// collectLabels names the JP and CALL targets that start a decoded instruction:
+// sub_XXX for subroutines and loc_XXX for other jump targets.
+func collectLabels(lines []line) map[uint16]string {
+	starts := make(map[uint16]bool, len(lines))
+	for _, l := range lines {
+		if l.inst != nil {
+			starts[l.addr] = true
+		}
+	}
+
+	labels := make(map[uint16]string)
+	for _, l := range lines {
+		if l.inst == nil {
+			continue
+		}
+		target, ok := l.inst.Target()
+		if !ok || !starts[target] {
+			continue
+		}
+		if l.inst.Op == chip8.OpCALL {
+			labels[target] = fmt.Sprintf("sub_%03X", target)
+		} else if _, exists := labels[target]; !exists {
+			labels[target] = fmt.Sprintf("loc_%03X", target)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// defaultKeyMap maps the keyboard to the CHIP-8 keypad:
+//
+//	1 2 3 C  =>  1 2 3 4
+//	4 5 6 D  =>  Q W E R
+//	7 8 9 E  =>  A S D F
+//	A 0 B F  =>  Z X C V
+var defaultKeyMap = map[ebiten.Key]byte{
+	ebiten.Key1: 0x1, ebiten.Key2: 0x2, ebiten.Key3: 0x3, ebiten.Key4: 0xC,
+	ebiten.KeyQ: 0x4, ebiten.KeyW: 0x5, ebiten.KeyE: 0x6, ebiten.KeyR: 0xD,
+	ebiten.KeyA: 0x7, ebiten.KeyS: 0x8, ebiten.KeyD: 0x9, ebiten.KeyF: 0xE,
+	ebiten.KeyZ: 0xA, ebiten.KeyX: 0x0, ebiten.KeyC: 0xB, ebiten.KeyV: 0xF,
+}
+
+// defaultGamepadMap maps a standard layout gamepad to the CHIP-8 keypad.
+// The D-pad follows the 2/4/6/8 arrow convention of many CHIP-8 games and the A button is 5 (the usual "fire").
+var defaultGamepadMap = map[ebiten.StandardGamepadButton]byte{
+	ebiten.StandardGamepadButtonLeftTop:     0x2,
+	ebiten.StandardGamepadButtonLeftLeft:    0x4,
+	ebiten.StandardGamepadButtonLeftRight:   0x6,
+	ebiten.StandardGamepadButtonLeftBottom:  0x8,
+	ebiten.StandardGamepadButtonRightBottom: 0x5,
+	ebiten.StandardGamepadButtonRightRight:  0x0,
+	ebiten.StandardGamepadButtonRightLeft:   0xA,
+	ebiten.StandardGamepadButtonRightTop:    0xB,
+	ebiten.StandardGamepadButtonCenterLeft:  0xE,
+	ebiten.StandardGamepadButtonCenterRight: 0xF,
+}
+
+// gamepadButtonNames are the names of the standard gamepad buttons in a keymap file.
+var gamepadButtonNames = map[string]ebiten.StandardGamepadButton{
+	"RightBottom":      ebiten.StandardGamepadButtonRightBottom,
+	"RightRight":       ebiten.StandardGamepadButtonRightRight,
+	"RightLeft":        ebiten.StandardGamepadButtonRightLeft,
+	"RightTop":         ebiten.StandardGamepadButtonRightTop,
+	"FrontTopLeft":     ebiten.StandardGamepadButtonFrontTopLeft,
+	"FrontTopRight":    ebiten.StandardGamepadButtonFrontTopRight,
+	"FrontBottomLeft":  ebiten.StandardGamepadButtonFrontBottomLeft,
+	"FrontBottomRight": ebiten.StandardGamepadButtonFrontBottomRight,
+	"CenterLeft":       ebiten.StandardGamepadButtonCenterLeft,
+	"CenterRight":      ebiten.StandardGamepadButtonCenterRight,
+	"LeftStick":        ebiten.StandardGamepadButtonLeftStick,
+	"RightStick":       ebiten.StandardGamepadButtonRightStick,
+	"LeftTop":          ebiten.StandardGamepadButtonLeftTop,
+	"LeftBottom":       ebiten.StandardGamepadButtonLeftBottom,
+	"LeftLeft":         ebiten.StandardGamepadButtonLeftLeft,
+	"LeftRight":        ebiten.StandardGamepadButtonLeftRight,
+	"CenterCenter":     ebiten.StandardGamepadButtonCenterCenter,
+}
+
+// KeyMap maps keyboard keys and gamepad buttons to CHIP-8 keys (0x0-0xF).
+type KeyMap struct {
+	Keyboard map[ebiten.Key]byte
+	Gamepad  map[ebiten.StandardGamepadButton]byte
+}
+
+// keyMapFile is the JSON layout of a keymap file. Each section maps a key or button name to a CHIP-8 key in hex:
+//
+//	{
+//	  "keyboard": {"ArrowUp": "2", "ArrowDown": "8", "Space": "5"},
+//	  "gamepad": {"RightBottom": "5", "LeftTop": "2"}
+//	}
+//
+// Keyboard names are those of ebiten.Key (e.g. "A", "Digit1", "ArrowUp", "Space").
+// A section replaces the corresponding default mapping; an omitted section keeps it.
+type keyMapFile struct {
+	Keyboard map[string]string `json:"keyboard"`
+	Gamepad  map[string]string `json:"gamepad"`
+}
+
+// DefaultKeyMap returns the built-in mapping.
+func DefaultKeyMap() KeyMap {
+	km := KeyMap{
+		Keyboard: make(map[ebiten.Key]byte, len(defaultKeyMap)),
+		Gamepad:  make(map[ebiten.StandardGamepadButton]byte, len(defaultGamepadMap)),
+	}
+	for k, v := range defaultKeyMap {
+		km.Keyboard[k] = v
+	}
+	for b, v := range defaultGamepadMap {
+		km.Gamepad[b] = v
+	}
+	return km
+}
+
+// LoadKeyMap reads a keymap file and applies it on top of DefaultKeyMap.
+func LoadKeyMap(path string) (KeyMap, error) {
+	km := DefaultKeyMap()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return km, fmt.Errorf("failed to read keymap '%s': %w", path, err)
+	}
+	var file keyMapFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return km, fmt.Errorf("failed to parse keymap '%s': %w", path, err)
+	}
+
+	if file.Keyboard != nil {
+		km.Keyboard = make(map[ebiten.Key]byte, len(file.Keyboard))
+		for name, value := range file.Keyboard {
+			var key ebiten.Key
+			if err := key.UnmarshalText([]byte(name)); err != nil {
+				return km, fmt.Errorf("keymap '%s': unknown keyboard key %q", path, name)
+			}
+			chip8Key, err := parseChip8Key(value)
+			if err != nil {
+				return km, fmt.Errorf("keymap '%s': keyboard key %q: %w", path, name, err)
+			}
+			km.Keyboard[key] = chip8Key
+		}
+	}
+	if file.Gamepad != nil {
+		km.Gamepad = make(map[ebiten.StandardGamepadButton]byte, len(file.Gamepad))
+		for name, value := range file.Gamepad {
+			button, ok := gamepadButtonNames[name]
+			if !ok {
+				return km, fmt.Errorf("keymap '%s': unknown gamepad button %q (valid: %s)", path, name, validGamepadButtonNames())
+			}
+			chip8Key, err := parseChip8Key(value)
+			if err != nil {
+				return km, fmt.Errorf("keymap '%s': gamepad button %q: %w", path, name, err)
+			}
+			km.Gamepad[button] = chip8Key
+		}
+	}
+	return km, nil
+}
+
+// parseChip8Key parses a CHIP-8 key written in hex ("0"-"F", "0x0"-"0xF").
+func parseChip8Key(s string) (byte, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 8)
+	if err != nil || v > 0xF {
+		return 0, fmt.Errorf("invalid CHIP-8 key %q: must be 0-F", s)
+	}
+	return byte(v), nil
+}
+
+func validGamepadButtonNames() string {
+	names := make([]string, 0, len(gamepadButtonNames))
+	for name := range gamepadButtonNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// PressedChip8Keys returns which CHIP-8 keys are held on the keyboard or on any connected standard layout gamepad.
+func (km KeyMap) PressedChip8Keys(gamepadIDs []ebiten.GamepadID) [16]bool {
+	var pressed [16]bool
+	for key, chip8Key := range km.Keyboard {
+		if ebiten.IsKeyPressed(key) {
+			pressed[chip8Key] = true
+		}
+	}
+	for _, id := range gamepadIDs {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue // Button positions of unknown gamepads cannot be mapped reliably
+		}
+		for button, chip8Key := range km.Gamepad {
+			if ebiten.IsStandardGamepadButtonPressed(id, button) {
+				pressed[chip8Key] = true
+			}
+		}
+	}
+	return pressed
+}
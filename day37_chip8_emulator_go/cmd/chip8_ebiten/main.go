@@ -3,8 +3,8 @@ package main
 import (
 	"flag"
 	"fmt"
+	"image/color"
 	"log"
-	"os"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -16,78 +16,152 @@ const (
 	chip8Width  = 64
 	chip8Height = 32
 
+	// SCHIP / XO-CHIP hi-res screen size
+	hiresWidth  = 128
+	hiresHeight = 64
+
 	// Window size (scaled)
 	defaultScale     = 10
 	defaultWinWidth  = chip8Width * defaultScale
 	defaultWinHeight = chip8Height * defaultScale
 )
 
+// palette maps the plane bits of a pixel to a color: off, plane 1 (the classic green), plane 2, both planes.
+var palette = [4]color.RGBA{
+	{0x00, 0x00, 0x00, 0xff},
+	{0x00, 0xff, 0x00, 0xff},
+	{0xff, 0x80, 0x00, 0xff},
+	{0xff, 0xff, 0xff, 0xff},
+}
+
 // Game struct holds the emulator and Ebiten specific state
 type Game struct {
-	emulator          *chip8.Chip8
-	offscreenImage    *ebiten.Image // Buffer for CHIP-8 gfx
+	emulator          *chip8.Chip8  // nil until a ROM is chosen in the menu
+	offscreenImage    *ebiten.Image // Buffer for CHIP-8 gfx, recreated when the resolution changes
 	needsScreenUpdate bool          // Flag to redraw the offscreen image
+	needsScreenClear  bool          // Flag to clear the window, e.g. after the menu was shown
 
-	// Key mapping from Ebiten keys to CHIP-8 keys (0x0-0xF)
-	keyMap map[ebiten.Key]byte
+	// Emulator settings, reused when another ROM is loaded from the menu
+	cyclesPerFrame uint
+	variantSCHIP   bool
+	variantXOCHIP  bool
 
-	// Store previous key states to detect release
-	lastPressedKeys map[ebiten.Key]bool
+	// ROM browser (F2), nil while a ROM is running
+	romDir string
+	menu   *romMenu
+
+	// Key mapping from Ebiten keys and gamepad buttons to CHIP-8 keys (0x0-0xF)
+	keyMap     KeyMap
+	gamepadIDs []ebiten.GamepadID // Reused buffer for the connected gamepads
+
+	// Save states (F5/F9)
+	romPath  string
+	stateDir string
+	saveSlot int
 }
 
-func NewGame(romPath string, cyclesPerFrame uint, variantSCHIP bool) (*Game, error) {
-	emu := chip8.New(cyclesPerFrame, variantSCHIP)
+func NewGame(romPath, romDir, stateDir string, keyMap KeyMap, cyclesPerFrame uint, variantSCHIP, variantXOCHIP bool) (*Game, error) {
+	g := &Game{
+		offscreenImage: ebiten.NewImage(chip8Width, chip8Height),
+		keyMap:         keyMap,
+		cyclesPerFrame: cyclesPerFrame,
+		variantSCHIP:   variantSCHIP,
+		variantXOCHIP:  variantXOCHIP,
+		romDir:         romDir,
+		stateDir:       stateDir,
+	}
+	if romPath == "" {
+		g.openMenu() // No ROM given: let the user pick one
+		return g, nil
+	}
+	if err := g.loadROM(romPath); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// loadROM replaces the running emulator with a fresh one running the ROM at romPath.
+// On error the current emulator keeps running.
+func (g *Game) loadROM(romPath string) error {
+	emu := chip8.New(g.cyclesPerFrame, g.variantSCHIP)
+	if g.variantXOCHIP {
+		emu.EnableXOCHIP()
+	}
 	if err := emu.LoadROM(romPath); err != nil {
-		return nil, fmt.Errorf("failed to load ROM '%s': %w", romPath, err)
+		return fmt.Errorf("failed to load ROM '%s': %w", romPath, err)
 	}
+	g.emulator = emu
+	g.romPath = romPath
+	g.needsScreenUpdate = true // Initial draw needed
+	g.needsScreenClear = true
+	g.updateWindowTitle()
+	return nil
+}
 
-	g := &Game{
-		emulator:          emu,
-		offscreenImage:    ebiten.NewImage(chip8Width, chip8Height),
-		needsScreenUpdate: true, // Initial draw needed
-		keyMap: map[ebiten.Key]byte{
-			ebiten.Key1: 0x1, ebiten.Key2: 0x2, ebiten.Key3: 0x3, ebiten.Key4: 0xC,
-			ebiten.KeyQ: 0x4, ebiten.KeyW: 0x5, ebiten.KeyE: 0x6, ebiten.KeyR: 0xD,
-			ebiten.KeyA: 0x7, ebiten.KeyS: 0x8, ebiten.KeyD: 0x9, ebiten.KeyF: 0xE,
-			ebiten.KeyZ: 0xA, ebiten.KeyX: 0x0, ebiten.KeyC: 0xB, ebiten.KeyV: 0xF,
-		},
-		lastPressedKeys: make(map[ebiten.Key]bool),
+func (g *Game) openMenu() {
+	if g.menu == nil {
+		g.menu = newROMMenu(g.romDir)
+	} else {
+		g.menu.rescan()
 	}
-	return g, nil
+	g.menu.hasGame = g.emulator != nil
+	if g.emulator != nil {
+		g.menu.selectROM(g.romPath)
+	}
+	g.updateWindowTitle()
 }
 
-func (g *Game) Update() error {
-	// Handle Key Input
-	currentPressedKeys := make(map[ebiten.Key]bool)
-	pressedEbitenKeys := inpututil.AppendPressedKeys(nil)
-	for _, key := range pressedEbitenKeys {
-		if chip8Key, ok := g.keyMap[key]; ok {
-			g.emulator.SetKey(int(chip8Key), true)
-			currentPressedKeys[key] = true
-			if !g.lastPressedKeys[key] {
-				// Optional: Log key press
-				// log.Printf("Key Press: %v -> CHIP-8 0x%X", key, chip8Key)
-			}
+func (g *Game) closeMenu() {
+	g.menu = nil
+	g.needsScreenUpdate = true
+	g.needsScreenClear = true
+	g.updateWindowTitle()
+}
+
+// updateMenu handles the ROM browser: Enter hot-swaps the chosen ROM, Escape returns to the running ROM or quits.
+func (g *Game) updateMenu() error {
+	romPath, closed := g.menu.Update(g.gamepadIDs)
+	switch {
+	case closed && g.emulator == nil:
+		return ebiten.Termination
+	case closed:
+		g.closeMenu()
+	case romPath != "":
+		if err := g.loadROM(romPath); err != nil {
+			log.Print(err)
+			g.menu.message = err.Error()
+			return nil
 		}
+		log.Printf("Loaded ROM %s", romPath)
+		g.closeMenu()
 	}
+	return nil
+}
 
-	// Check for released keys
-	for key, wasPressed := range g.lastPressedKeys {
-		if wasPressed && !currentPressedKeys[key] {
-			if chip8Key, ok := g.keyMap[key]; ok {
-				g.emulator.SetKey(int(chip8Key), false)
-				// Optional: Log key release
-				// log.Printf("Key Release: %v -> CHIP-8 0x%X", key, chip8Key)
-			}
-		}
+func (g *Game) Update() error {
+	g.gamepadIDs = ebiten.AppendGamepadIDs(g.gamepadIDs[:0])
+	if g.menu != nil {
+		return g.updateMenu()
+	}
+
+	// Open the ROM browser on F2
+	if inpututil.IsKeyJustPressed(ebiten.KeyF2) {
+		g.openMenu()
+		return nil
+	}
+
+	// Handle Key Input: a CHIP-8 key is down while any keyboard key or gamepad button mapped to it is held
+	for chip8Key, pressed := range g.keyMap.PressedChip8Keys(g.gamepadIDs) {
+		g.emulator.SetKey(chip8Key, pressed)
 	}
-	g.lastPressedKeys = currentPressedKeys
 
 	// Exit on Escape key
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
 		return ebiten.Termination
 	}
 
+	g.handleSaveStateKeys()
+
 	// Run CHIP-8 Cycles
 	for i := 0; i < int(g.emulator.CyclesPerFrame()); i++ {
 		redraw, _, halted := g.emulator.Cycle()
@@ -102,35 +176,48 @@ func (g *Game) Update() error {
 	// Update Timers (at 60Hz)
 	g.emulator.UpdateTimers()
 
+	// SCHIP EXIT (00FD) ends the program
+	if g.emulator.Exited() {
+		return ebiten.Termination
+	}
+
 	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
+	if g.menu != nil {
+		g.menu.Draw(screen)
+		return
+	}
+	if g.needsScreenClear {
+		screen.Fill(palette[0])
+		g.needsScreenClear = false
+	}
+
 	// Only update the offscreen texture if the CHIP-8 graphics changed
 	if g.needsScreenUpdate {
+		gfxWidth, gfxHeight := g.emulator.Resolution()
+		if g.offscreenImage.Bounds().Dx() != gfxWidth {
+			g.offscreenImage = ebiten.NewImage(gfxWidth, gfxHeight) // 00FE/00FF switched the resolution
+		}
 		gfx := g.emulator.Gfx()
-		pixels := make([]byte, chip8Width*chip8Height*4) // RGBA buffer
-		for i, v := range gfx {
-			if v == 1 {
-				pixels[i*4] = 0x00   // R
-				pixels[i*4+1] = 0xff // G
-				pixels[i*4+2] = 0x00 // B
-				pixels[i*4+3] = 0xff // A (Green)
-			} else {
-				pixels[i*4] = 0x00   // R
-				pixels[i*4+1] = 0x00 // G
-				pixels[i*4+2] = 0x00 // B
-				pixels[i*4+3] = 0xff // A (Black)
-			}
+		pixels := make([]byte, gfxWidth*gfxHeight*4) // RGBA buffer
+		for i, v := range gfx[:gfxWidth*gfxHeight] {
+			c := palette[v&0x3]
+			pixels[i*4] = c.R
+			pixels[i*4+1] = c.G
+			pixels[i*4+2] = c.B
+			pixels[i*4+3] = c.A
 		}
 		g.offscreenImage.WritePixels(pixels)
 		g.needsScreenUpdate = false
 	}
 
 	// Calculate scale based on window size
+	gfxWidth, gfxHeight := g.offscreenImage.Bounds().Dx(), g.offscreenImage.Bounds().Dy()
 	winWidth, winHeight := screen.Bounds().Dx(), screen.Bounds().Dy()
-	scaleX := float64(winWidth) / float64(chip8Width)
-	scaleY := float64(winHeight) / float64(chip8Height)
+	scaleX := float64(winWidth) / float64(gfxWidth)
+	scaleY := float64(winHeight) / float64(gfxHeight)
 	scale := scaleX // Assume square pixels, take the smaller scale if aspect ratios differ significantly
 	if scaleY < scaleX {
 		scale = scaleY
@@ -138,8 +225,8 @@ func (g *Game) Draw(screen *ebiten.Image) {
 
 	// Center the image
 	opts := &ebiten.DrawImageOptions{}
-	imgWidth := float64(chip8Width) * scale
-	imgHeight := float64(chip8Height) * scale
+	imgWidth := float64(gfxWidth) * scale
+	imgHeight := float64(gfxHeight) * scale
 	tx := (float64(winWidth) - imgWidth) / 2
 	ty := (float64(winHeight) - imgHeight) / 2
 	opts.GeoM.Scale(scale, scale)
@@ -151,27 +238,35 @@ func (g *Game) Draw(screen *ebiten.Image) {
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	// Returns the logical screen size CHIP-8 uses
-	// Ebiten scales this to the window size based on Draw options
-	// For simplicity, let's return the base size. Window scaling is handled in Draw.
-	// Alternatively, could return outsideWidth, outsideHeight for pixel-perfect scaling control.
-	return chip8Width, chip8Height
+	// The menu renders text at the window resolution
+	if g.menu != nil {
+		return outsideWidth, outsideHeight
+	}
+	// Returns the hi-res screen size, so both 64x32 and 128x64 framebuffers scale without blurring.
+	// Window scaling is handled in Draw.
+	return hiresWidth, hiresHeight
 }
 
 func main() {
-	romPath := flag.String("rom", "", "Path to the CHIP-8 ROM file")
+	romPath := flag.String("rom", "", "Path to the CHIP-8 ROM file (omit to choose one from -romdir)")
+	romDir := flag.String("romdir", "roms", "Directory listed by the ROM browser (F2)")
 	cycles := flag.Uint("cycles", 10, "CPU cycles per frame")
 	schip := flag.Bool("schip", false, "Enable SCHIP variant behavior")
+	xochip := flag.Bool("xochip", false, "Enable XO-CHIP instructions (hi-res, scrolling, planes, audio, 64KB memory)")
 	scale := flag.Float64("scale", defaultScale, "Window scale factor")
+	stateDir := flag.String("states", "states", "Directory for save states (F5: save, F9: load, F6/F7: select slot)")
+	keyMapPath := flag.String("keymap", "", "JSON file overriding the keyboard and gamepad mapping (see keymap.example.json)")
 	flag.Parse()
 
-	if *romPath == "" {
-		fmt.Println("Usage: go run ./cmd/chip8_ebiten -rom <path_to_rom>")
-		flag.PrintDefaults()
-		os.Exit(1)
+	keyMap := DefaultKeyMap()
+	if *keyMapPath != "" {
+		var err error
+		if keyMap, err = LoadKeyMap(*keyMapPath); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	game, err := NewGame(*romPath, *cycles, *schip)
+	game, err := NewGame(*romPath, *romDir, *stateDir, keyMap, *cycles, *schip, *xochip)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -179,11 +274,14 @@ func main() {
 	winWidth := int(chip8Width * (*scale))
 	winHeight := int(chip8Height * (*scale))
 	ebiten.SetWindowSize(winWidth, winHeight)
-	ebiten.SetWindowTitle(fmt.Sprintf("CHIP-8 Emulator (%s)", *romPath))
 	ebiten.SetMaxTPS(60)
 	ebiten.SetScreenClearedEveryFrame(false) // Important for performance and avoiding flicker
 
-	log.Printf("Starting emulator for %s... Press ESC to quit.", *romPath)
+	if *romPath != "" {
+		log.Printf("Starting emulator for %s... Press ESC to quit, F2 to choose another ROM.", *romPath)
+	} else {
+		log.Printf("Starting ROM browser for %s... Press ESC to quit.", *romDir)
+	}
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/basicfont"
+)
+
+const (
+	menuLineHeight = 16
+	menuMargin     = 8
+	menuHeaderRows = 2 // Title and blank line above the ROM list
+	menuFooterRows = 2 // Blank line and help or error line below the ROM list
+
+	// Key repeat for holding Up/Down in the menu (in ticks at 60 TPS)
+	menuRepeatDelay    = 20
+	menuRepeatInterval = 4
+)
+
+var (
+	menuFace          = text.NewGoXFace(basicfont.Face7x13)
+	menuTextColor     = color.RGBA{0xc0, 0xc0, 0xc0, 0xff}
+	menuSelectedColor = palette[1]
+	menuErrorColor    = color.RGBA{0xff, 0x40, 0x40, 0xff}
+)
+
+// romMenu is the ROM browser shown when the emulator starts without -rom or when F2 is pressed.
+// It lists the .ch8 files of a directory and lets the user pick one with the keyboard or a gamepad.
+type romMenu struct {
+	dir     string
+	roms    []string // File names relative to dir, sorted
+	cursor  int      // Index of the selected ROM
+	scroll  int      // Index of the first visible ROM
+	visible int      // Number of ROMs that fit on screen, updated by Draw
+	message string   // Error shown in the footer, e.g. when a ROM failed to load
+	hasGame bool     // Whether Escape returns to a running ROM (otherwise it quits)
+}
+
+func newROMMenu(dir string) *romMenu {
+	m := &romMenu{dir: dir, visible: 1}
+	m.rescan()
+	return m
+}
+
+// rescan reloads the ROM list from disk, so ROMs added while running show up when the menu is reopened.
+func (m *romMenu) rescan() {
+	m.roms = m.roms[:0]
+	m.message = ""
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to read '%s': %v", m.dir, err)
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".ch8") {
+			m.roms = append(m.roms, e.Name())
+		}
+	}
+	sort.Strings(m.roms)
+	if len(m.roms) == 0 {
+		m.message = fmt.Sprintf("No .ch8 files found in '%s'", m.dir)
+	}
+	m.moveCursor(0)
+}
+
+// selectROM moves the cursor to the ROM at path, if it is in the list.
+func (m *romMenu) selectROM(path string) {
+	for i, name := range m.roms {
+		if filepath.Join(m.dir, name) == filepath.Clean(path) {
+			m.cursor = i
+			m.moveCursor(0)
+			return
+		}
+	}
+}
+
+// moveCursor moves the cursor by delta, clamped to the list, and scrolls so that it stays visible.
+func (m *romMenu) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.roms) {
+		m.cursor = len(m.roms) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	if m.cursor < m.scroll {
+		m.scroll = m.cursor
+	}
+	if m.cursor >= m.scroll+m.visible {
+		m.scroll = m.cursor - m.visible + 1
+	}
+	if m.scroll < 0 {
+		m.scroll = 0
+	}
+}
+
+// Update handles menu input and returns the path of the chosen ROM, or "" while nothing has been chosen.
+// closed reports that the user left the menu with Escape.
+func (m *romMenu) Update(gamepadIDs []ebiten.GamepadID) (romPath string, closed bool) {
+	switch {
+	case menuKeyRepeated(ebiten.KeyArrowUp) || gamepadJustPressed(gamepadIDs, ebiten.StandardGamepadButtonLeftTop):
+		m.moveCursor(-1)
+	case menuKeyRepeated(ebiten.KeyArrowDown) || gamepadJustPressed(gamepadIDs, ebiten.StandardGamepadButtonLeftBottom):
+		m.moveCursor(1)
+	case menuKeyRepeated(ebiten.KeyPageUp):
+		m.moveCursor(-m.visible)
+	case menuKeyRepeated(ebiten.KeyPageDown):
+		m.moveCursor(m.visible)
+	case inpututil.IsKeyJustPressed(ebiten.KeyHome):
+		m.moveCursor(-len(m.roms))
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnd):
+		m.moveCursor(len(m.roms))
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || gamepadJustPressed(gamepadIDs, ebiten.StandardGamepadButtonRightBottom):
+		if len(m.roms) > 0 {
+			return filepath.Join(m.dir, m.roms[m.cursor]), false
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape) || gamepadJustPressed(gamepadIDs, ebiten.StandardGamepadButtonRightRight):
+		return "", true
+	}
+	return "", false
+}
+
+// Draw renders the menu at the native window resolution.
+func (m *romMenu) Draw(screen *ebiten.Image) {
+	screen.Fill(palette[0])
+	height := screen.Bounds().Dy()
+
+	m.visible = (height-2*menuMargin)/menuLineHeight - menuHeaderRows - menuFooterRows
+	if m.visible < 1 {
+		m.visible = 1
+	}
+	m.moveCursor(0) // The window may have been resized
+
+	drawMenuLine(screen, 0, fmt.Sprintf("CHIP-8 ROMs in %s (%d)", m.dir, len(m.roms)), menuTextColor)
+	for row := 0; row < m.visible && m.scroll+row < len(m.roms); row++ {
+		i := m.scroll + row
+		line, clr := "  "+m.roms[i], color.Color(menuTextColor)
+		if i == m.cursor {
+			line, clr = "> "+m.roms[i], menuSelectedColor
+		}
+		drawMenuLine(screen, menuHeaderRows+row, line, clr)
+	}
+
+	footerRow := menuHeaderRows + m.visible + 1
+	if m.message != "" {
+		drawMenuLine(screen, footerRow, m.message, menuErrorColor)
+		return
+	}
+	help := "Up/Down: select  Enter: play  Esc: quit"
+	if m.hasGame {
+		help = "Up/Down: select  Enter: play  Esc: back"
+	}
+	if m.scroll > 0 || m.scroll+m.visible < len(m.roms) {
+		help = fmt.Sprintf("%s  [%d-%d of %d]", help, m.scroll+1, min(m.scroll+m.visible, len(m.roms)), len(m.roms))
+	}
+	drawMenuLine(screen, footerRow, help, menuTextColor)
+}
+
+func drawMenuLine(screen *ebiten.Image, row int, s string, clr color.Color) {
+	opts := &text.DrawOptions{}
+	opts.GeoM.Translate(menuMargin, float64(menuMargin+row*menuLineHeight))
+	opts.ColorScale.ScaleWithColor(clr)
+	text.Draw(screen, s, menuFace, opts)
+}
+
+// menuKeyRepeated reports whether key was just pressed or is held long enough to repeat.
+func menuKeyRepeated(key ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || (d >= menuRepeatDelay && (d-menuRepeatDelay)%menuRepeatInterval == 0)
+}
+
+func gamepadJustPressed(gamepadIDs []ebiten.GamepadID, button ebiten.StandardGamepadButton) bool {
+	for _, id := range gamepadIDs {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+			return true
+		}
+	}
+	return false
+}
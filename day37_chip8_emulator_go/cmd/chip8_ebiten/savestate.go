@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// numSaveSlots is the number of save state slots per ROM (0-9).
+const numSaveSlots = 10
+
+// statePath returns the save state file of slot for the running ROM, e.g. states/pong.3.state.
+func (g *Game) statePath(slot int) string {
+	rom := strings.TrimSuffix(filepath.Base(g.romPath), filepath.Ext(g.romPath))
+	return filepath.Join(g.stateDir, fmt.Sprintf("%s.%d.state", rom, slot))
+}
+
+// handleSaveStateKeys handles the save state hotkeys:
+// F5 saves to the current slot, F9 loads it, F6/F7 select the previous/next slot.
+func (g *Game) handleSaveStateKeys() {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyF6):
+		g.saveSlot = (g.saveSlot + numSaveSlots - 1) % numSaveSlots
+		g.updateWindowTitle()
+	case inpututil.IsKeyJustPressed(ebiten.KeyF7):
+		g.saveSlot = (g.saveSlot + 1) % numSaveSlots
+		g.updateWindowTitle()
+	case inpututil.IsKeyJustPressed(ebiten.KeyF5):
+		if err := g.saveState(g.saveSlot); err != nil {
+			log.Printf("Failed to save state: %v", err)
+			return
+		}
+		log.Printf("Saved state to slot %d (%s)", g.saveSlot, g.statePath(g.saveSlot))
+	case inpututil.IsKeyJustPressed(ebiten.KeyF9):
+		if err := g.loadState(g.saveSlot); err != nil {
+			log.Printf("Failed to load state: %v", err)
+			return
+		}
+		log.Printf("Loaded state from slot %d", g.saveSlot)
+	}
+}
+
+func (g *Game) saveState(slot int) error {
+	if err := os.MkdirAll(g.stateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory '%s': %w", g.stateDir, err)
+	}
+	path := g.statePath(slot)
+	if err := os.WriteFile(path, g.emulator.Snapshot(), 0644); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", path, err)
+	}
+	return nil
+}
+
+func (g *Game) loadState(slot int) error {
+	path := g.statePath(slot)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	if err := g.emulator.Restore(data); err != nil {
+		return fmt.Errorf("failed to restore '%s': %w", path, err)
+	}
+	g.needsScreenUpdate = true
+	return nil
+}
+
+func (g *Game) updateWindowTitle() {
+	if g.menu != nil {
+		ebiten.SetWindowTitle("CHIP-8 Emulator - ROM browser")
+		return
+	}
+	ebiten.SetWindowTitle(fmt.Sprintf("CHIP-8 Emulator (%s) - slot %d", g.romPath, g.saveSlot))
+}
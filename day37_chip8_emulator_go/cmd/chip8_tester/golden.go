@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	chip8 "github.com/lirlia/100day_challenge_backend/day37_chip8_emulator_go/internal/chip8"
+)
+
+// goldenFileName is the file in the golden directory that maps each ROM to its expected framebuffer hash.
+// The expected images are stored next to it as <rom>.png so that a mismatch can be shown as a diff.
+const goldenFileName = "golden.json"
+
+// goldenEntry is the expected result of running a ROM for Frames frames at Cycles cycles per frame.
+type goldenEntry struct {
+	Frames int    `json:"frames"`
+	Cycles uint   `json:"cycles"`
+	Hash   string `json:"hash"`
+}
+
+// goldenConfig configures a regression run over a directory of ROMs.
+type goldenConfig struct {
+	romDir         string
+	goldenDir      string
+	diffDir        string
+	frames         int
+	cyclesPerFrame uint
+	variantSCHIP   bool
+	variantXOCHIP  bool
+	seed           int64
+	update         bool // Rewrite the golden hashes and images instead of comparing
+}
+
+// frameResult is the framebuffer of a ROM after the configured number of frames.
+type frameResult struct {
+	width, height int
+	pixels        []byte // width*height plane bits, see chip8.Chip8.Gfx
+}
+
+// hash returns the SHA-256 of the resolution and the pixels, so a mode switch alone changes the hash.
+func (r frameResult) hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%dx%d:", r.width, r.height)
+	h.Write(r.pixels)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (r frameResult) image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			img.Set(x, y, palette[r.pixels[y*r.width+x]&0x3])
+		}
+	}
+	return img
+}
+
+// runFrames runs a ROM for a fixed number of frames with a fixed random seed.
+// Unlike the snapshot mode it does not wait for real time, so the result only depends on the ROM and the settings.
+func runFrames(romPath string, cfg goldenConfig) (frameResult, error) {
+	emulator := chip8.NewWithSeed(cfg.cyclesPerFrame, cfg.variantSCHIP, cfg.seed)
+	if cfg.variantXOCHIP {
+		emulator.EnableXOCHIP()
+	}
+	if err := emulator.LoadROM(romPath); err != nil {
+		return frameResult{}, err
+	}
+	for frame := 0; frame < cfg.frames; frame++ {
+		for i := 0; i < int(emulator.CyclesPerFrame()); i++ {
+			if _, _, halted := emulator.Cycle(); halted {
+				break // Waiting for a key (Fx0A) or exited (00FD); nothing to press in the tester
+			}
+		}
+		emulator.UpdateTimers()
+	}
+
+	gfx := emulator.Gfx()
+	width, height := emulator.Resolution()
+	return frameResult{width: width, height: height, pixels: gfx[:width*height]}, nil
+}
+
+// runGolden runs every .ch8 ROM in cfg.romDir and compares the framebuffer hashes with the golden file.
+// For each mismatch a diff PNG is written to cfg.diffDir. It returns an error if any ROM failed.
+func runGolden(cfg goldenConfig) error {
+	roms, err := filepath.Glob(filepath.Join(cfg.romDir, "*.ch8"))
+	if err != nil {
+		return err
+	}
+	if len(roms) == 0 {
+		return fmt.Errorf("no .ch8 ROMs found in '%s'", cfg.romDir)
+	}
+	sort.Strings(roms)
+
+	goldenPath := filepath.Join(cfg.goldenDir, goldenFileName)
+	golden, err := loadGolden(goldenPath)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, romPath := range roms {
+		name := filepath.Base(romPath)
+		result, err := runFrames(romPath, cfg)
+		if err != nil {
+			log.Printf("ERROR %s: %v", name, err)
+			failed = append(failed, name)
+			continue
+		}
+
+		if cfg.update {
+			golden[name] = goldenEntry{Frames: cfg.frames, Cycles: cfg.cyclesPerFrame, Hash: result.hash()}
+			if err := writePNG(filepath.Join(cfg.goldenDir, goldenImageName(name)), result.image()); err != nil {
+				return err
+			}
+			log.Printf("UPDATED %s (%dx%d) %s", name, result.width, result.height, result.hash())
+			continue
+		}
+
+		want, ok := golden[name]
+		switch {
+		case !ok:
+			log.Printf("FAIL %s: no golden hash (run with -update to record it)", name)
+			failed = append(failed, name)
+		case want.Frames != cfg.frames || want.Cycles != cfg.cyclesPerFrame:
+			log.Printf("FAIL %s: golden hash was recorded with -frames %d -cycles %d, running -frames %d -cycles %d",
+				name, want.Frames, want.Cycles, cfg.frames, cfg.cyclesPerFrame)
+			failed = append(failed, name)
+		case want.Hash != result.hash():
+			diffPath := filepath.Join(cfg.diffDir, strings.TrimSuffix(name, filepath.Ext(name))+".diff.png")
+			if err := writeDiff(diffPath, filepath.Join(cfg.goldenDir, goldenImageName(name)), result); err != nil {
+				log.Printf("FAIL %s: hash mismatch (failed to write diff: %v)", name, err)
+			} else {
+				log.Printf("FAIL %s: hash mismatch, diff written to %s", name, diffPath)
+			}
+			failed = append(failed, name)
+		default:
+			log.Printf("ok   %s", name)
+		}
+	}
+
+	if cfg.update {
+		return saveGolden(goldenPath, golden)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d ROMs failed: %s", len(failed), len(roms), strings.Join(failed, ", "))
+	}
+	log.Printf("All %d ROMs match the golden hashes.", len(roms))
+	return nil
+}
+
+func goldenImageName(romName string) string {
+	return strings.TrimSuffix(romName, filepath.Ext(romName)) + ".png"
+}
+
+func loadGolden(path string) (map[string]goldenEntry, error) {
+	golden := make(map[string]goldenEntry)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return golden, nil // First run with -update
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden file '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return nil, fmt.Errorf("failed to parse golden file '%s': %w", path, err)
+	}
+	return golden, nil
+}
+
+func saveGolden(path string, golden map[string]goldenEntry) error {
+	data, err := json.MarshalIndent(golden, "", "  ") // Map keys are sorted, so the file diffs cleanly
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write golden file '%s': %w", path, err)
+	}
+	log.Printf("Golden hashes saved to %s", path)
+	return nil
+}
+
+// Colors of the diff image.
+var (
+	diffMissing = color.RGBA{0xff, 0x00, 0x00, 0xff} // Set in the golden image only
+	diffExtra   = color.RGBA{0x00, 0xff, 0x00, 0xff} // Set in the actual image only
+	diffSame    = color.RGBA{0x60, 0x60, 0x60, 0xff} // Set in both
+)
+
+// writeDiff writes a PNG comparing the golden image with the actual framebuffer.
+// Without a golden image (or with a different resolution) it writes the actual framebuffer instead.
+func writeDiff(diffPath, goldenImagePath string, actual frameResult) error {
+	want, err := readPNG(goldenImagePath)
+	if err != nil || want.Bounds().Dx() != actual.width || want.Bounds().Dy() != actual.height {
+		return writePNG(diffPath, actual.image())
+	}
+
+	diff := image.NewRGBA(image.Rect(0, 0, actual.width, actual.height))
+	for y := 0; y < actual.height; y++ {
+		for x := 0; x < actual.width; x++ {
+			wantOn := isOn(want.At(x, y))
+			gotOn := actual.pixels[y*actual.width+x] != 0
+			switch {
+			case wantOn && gotOn:
+				diff.Set(x, y, diffSame)
+			case wantOn:
+				diff.Set(x, y, diffMissing)
+			case gotOn:
+				diff.Set(x, y, diffExtra)
+			default:
+				diff.Set(x, y, color.Black)
+			}
+		}
+	}
+	return writePNG(diffPath, diff)
+}
+
+func isOn(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r|g|b != 0
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode PNG '%s': %w", path, err)
+	}
+	return f.Close()
+}
@@ -12,28 +12,60 @@ import (
 	chip8 "github.com/lirlia/100day_challenge_backend/day37_chip8_emulator_go/internal/chip8"
 )
 
-const (
-	screenWidth  = 64
-	screenHeight = 32
-)
+// palette maps the plane bits of a pixel to a color: off, plane 1, plane 2, both planes (XO-CHIP).
+var palette = [4]color.Color{
+	color.Black,
+	color.White,
+	color.RGBA{0xff, 0x80, 0x00, 0xff},
+	color.RGBA{0x80, 0x80, 0x80, 0xff},
+}
 
 func main() {
 	// コマンドラインフラグ
 	romPath := flag.String("rom", "", "Path to the CHIP-8 ROM file")
 	cyclesPerFrame := flag.Uint("cycles", 10, "CPU cycles per frame (adjust for speed)")
 	variantSCHIP := flag.Bool("schip", false, "Enable SCHIP variant behavior")
+	variantXOCHIP := flag.Bool("xochip", false, "Enable XO-CHIP instructions")
 	duration := flag.Duration("duration", 5*time.Second, "Duration to run the emulation for snapshot")
 	outputFile := flag.String("output", "snapshot.png", "Output PNG file name")
+	// ゴールデンイメージによる回帰テスト
+	romDir := flag.String("romdir", "", "Run every .ch8 ROM in this directory and compare against golden hashes (instead of -rom)")
+	goldenDir := flag.String("golden", "testdata/golden", "Directory of the golden hashes and images (with -romdir)")
+	diffDir := flag.String("diffdir", "golden_diff", "Directory for the diff PNGs of mismatching ROMs (with -romdir)")
+	frames := flag.Int("frames", 300, "Frames to run each ROM for (with -romdir)")
+	seed := flag.Int64("seed", 1, "Random seed (with -romdir)")
+	update := flag.Bool("update", false, "Record the current results as the golden hashes (with -romdir)")
 	flag.Parse()
 
+	if *romDir != "" {
+		err := runGolden(goldenConfig{
+			romDir:         *romDir,
+			goldenDir:      *goldenDir,
+			diffDir:        *diffDir,
+			frames:         *frames,
+			cyclesPerFrame: *cyclesPerFrame,
+			variantSCHIP:   *variantSCHIP,
+			variantXOCHIP:  *variantXOCHIP,
+			seed:           *seed,
+			update:         *update,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if *romPath == "" {
-		log.Fatal("ROM path must be specified with -rom flag")
+		log.Fatal("ROM path must be specified with -rom flag (or -romdir for the golden tests)")
 	}
 
 	// CHIP-8 インスタンスの作成
 	// New() uses time-based seed, good for general testing.
 	// Use NewWithSeed() for deterministic runs if needed.
 	emulator := chip8.New(*cyclesPerFrame, *variantSCHIP)
+	if *variantXOCHIP {
+		emulator.EnableXOCHIP()
+	}
 
 	// ROMのロード
 	if err := emulator.LoadROM(*romPath); err != nil {
@@ -89,19 +121,16 @@ loop:
 	log.Printf("Snapshot saved to %s", *outputFile)
 }
 
-// generateSnapshot generates a PNG image from the CHIP-8 Gfx buffer.
+// generateSnapshot generates a PNG image from the CHIP-8 Gfx buffer at the current resolution (64x32 or 128x64).
 func generateSnapshot(emulator *chip8.Chip8, filename string) {
-	gfx := emulator.Gfx() // [64*32]byte
+	gfx := emulator.Gfx()
+	screenWidth, screenHeight := emulator.Resolution()
 	img := image.NewRGBA(image.Rect(0, 0, screenWidth, screenHeight))
 
 	for y := 0; y < screenHeight; y++ {
 		for x := 0; x < screenWidth; x++ {
 			index := y*screenWidth + x
-			if gfx[index] == 1 {
-				img.Set(x, y, color.White) // On pixel
-			} else {
-				img.Set(x, y, color.Black) // Off pixel
-			}
+			img.Set(x, y, palette[gfx[index]&0x3])
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	// "embed" // Temporarily comment out due to build issue
 	"fmt"
 	"log" // Added for Cycle method logging
+	"math"
 	"math/rand"
 	"os"
 	"time"
@@ -13,16 +14,27 @@ import (
 // var embeddedFontSet []byte // Temporarily comment out due to build issue
 
 const (
-	memorySize   = 4096
-	numRegisters = 16
-	stackSize    = 16
-	gfxWidth     = 64
-	gfxHeight    = 32
-	gfxSize      = gfxWidth * gfxHeight
-	fontOffset   = 0x050
-	romOffset    = 0x200
+	memorySize    = 4096    // Addressable memory of CHIP-8 and SCHIP
+	xoMemorySize  = 0x10000 // XO-CHIP extends memory to the full 16-bit address space
+	numRegisters  = 16
+	stackSize     = 16
+	gfxWidth      = 64 // Low resolution (CHIP-8) screen size
+	gfxHeight     = 32
+	hiresWidth    = 128 // High resolution (SCHIP 00FF) screen size
+	hiresHeight   = 64
+	gfxSize       = hiresWidth * hiresHeight // The buffer is sized for hi-res; lo-res uses the first gfxWidth*gfxHeight pixels
+	numFlags      = 16                       // RPL user flags (Fx75/Fx85); SCHIP uses 8, XO-CHIP 16
+	fontOffset    = 0x050
+	bigFontOffset = fontOffset + len(fontSet) // SCHIP 8x10 font (Fx30)
+	romOffset     = 0x200
+
+	audioPatternSize = 16 // XO-CHIP audio pattern buffer: 128 1-bit samples
+	defaultPitch     = 64 // Pitch register value for a 4000Hz playback rate
 )
 
+// ROMStart is the address ROMs are loaded at and execution starts from.
+const ROMStart = romOffset
+
 // Standard CHIP-8 font set. Each character is 5 bytes.
 var fontSet = [80]byte{
 	0xF0, 0x90, 0x90, 0x90, 0xF0, // 0
@@ -43,14 +55,34 @@ var fontSet = [80]byte{
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
 
+// SCHIP big font set. Each character is 10 bytes (8x10 pixels).
+var bigFontSet = [160]byte{
+	0xFF, 0xFF, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, // 0
+	0x18, 0x78, 0x78, 0x18, 0x18, 0x18, 0x18, 0x18, 0xFF, 0xFF, // 1
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // 2
+	0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 3
+	0xC3, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0x03, 0x03, // 4
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 5
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 6
+	0xFF, 0xFF, 0x03, 0x03, 0x06, 0x0C, 0x18, 0x18, 0x18, 0x18, // 7
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, // 8
+	0xFF, 0xFF, 0xC3, 0xC3, 0xFF, 0xFF, 0x03, 0x03, 0xFF, 0xFF, // 9
+	0x7E, 0xFF, 0xC3, 0xC3, 0xC3, 0xFF, 0xFF, 0xC3, 0xC3, 0xC3, // A
+	0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, 0xC3, 0xC3, 0xFC, 0xFC, // B
+	0x3C, 0xFF, 0xC3, 0xC0, 0xC0, 0xC0, 0xC0, 0xC3, 0xFF, 0x3C, // C
+	0xFC, 0xFE, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xC3, 0xFE, 0xFC, // D
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, // E
+	0xFF, 0xFF, 0xC0, 0xC0, 0xFF, 0xFF, 0xC0, 0xC0, 0xC0, 0xC0, // F
+}
+
 type Chip8 struct {
-	memory [memorySize]byte
+	memory [xoMemorySize]byte // Only the first memorySize bytes are addressable unless XO-CHIP is enabled
 	V      [numRegisters]byte
 	I      uint16
 	PC     uint16
 	stack  [stackSize]uint16
 	SP     uint8
-	gfx    [gfxSize]byte // 1 for on, 0 for off
+	gfx    [gfxSize]byte // Bit 0: plane 1, bit 1: plane 2 (XO-CHIP). Without XO-CHIP a pixel is 1 for on, 0 for off
 	DT     byte          // Delay Timer
 	ST     byte          // Sound Timer
 	keys   [numRegisters]bool
@@ -60,9 +92,18 @@ type Chip8 struct {
 
 	rng *rand.Rand
 
+	// SCHIP / XO-CHIP state
+	hires        bool                   // 128x64 mode (00FF), 64x32 otherwise (00FE)
+	exited       bool                   // EXIT (00FD) was executed
+	flags        [numFlags]byte         // RPL user flags (Fx75/Fx85)
+	planes       byte                   // Planes selected for drawing (Fn01), bit 0: plane 1, bit 1: plane 2
+	audioPattern [audioPatternSize]byte // XO-CHIP audio pattern (F002)
+	pitch        byte                   // XO-CHIP playback rate register (Fx3A)
+
 	// Configuration
 	cyclesPerFrame uint // How many CPU cycles to run per display frame (e.g., per 1/60th second)
-	variantSCHIP   bool // Flag for SCHIP specific behaviors (e.g. Fx55/Fx65, SHL/SHR)
+	variantSCHIP   bool // Flag for SCHIP specific behaviors (e.g. Fx55/Fx65, SHL/SHR) and the SCHIP instructions
+	variantXOCHIP  bool // Flag for the XO-CHIP instructions (planes, audio, 64KB memory); implies the SCHIP instructions
 }
 
 // NewWithSeed creates a new Chip8 instance with a specific random seed.
@@ -103,11 +144,14 @@ func NewWithSeed(cyclesPerFrame uint, variantSCHIP bool, seed int64) *Chip8 {
 	c.ST = 0
 	c.waitingForKey = false
 	c.keyReg = 0
+	c.planes = 1 // Plane 1 only, which is plain CHIP-8 drawing
+	c.pitch = defaultPitch
 
 	// Load font set into memory
 	// We use the hardcoded fontSet for now.
 	// The embeddedFontSet is prepared for Step 3 if we want to load from an external file.
 	copy(c.memory[fontOffset:], fontSet[:])
+	copy(c.memory[bigFontOffset:], bigFontSet[:])
 
 	return c
 }
@@ -117,7 +161,51 @@ func New(cyclesPerFrame uint, variantSCHIP bool) *Chip8 {
 	return NewWithSeed(cyclesPerFrame, variantSCHIP, time.Now().UnixNano())
 }
 
+// EnableXOCHIP turns on the XO-CHIP instructions and 64KB memory.
+// Call it before LoadROM, since XO-CHIP ROMs may be larger than 4KB.
+func (c *Chip8) EnableXOCHIP() {
+	c.variantXOCHIP = true
+}
+
+// extended reports whether the SCHIP instructions (hi-res, scrolling, big font, flags) are available.
+func (c *Chip8) extended() bool {
+	return c.variantSCHIP || c.variantXOCHIP
+}
+
+// memSize returns the number of addressable bytes for the current variant.
+func (c *Chip8) memSize() int {
+	if c.variantXOCHIP {
+		return xoMemorySize
+	}
+	return memorySize
+}
+
+// Resolution returns the current screen size: 64x32, or 128x64 in hi-res mode.
+// Pixel (x, y) is at index y*width+x of the buffer returned by Gfx.
+func (c *Chip8) Resolution() (width, height int) {
+	if c.hires {
+		return hiresWidth, hiresHeight
+	}
+	return gfxWidth, gfxHeight
+}
+
+// Exited reports whether the ROM executed EXIT (00FD). The CPU stays halted afterwards.
+func (c *Chip8) Exited() bool {
+	return c.exited
+}
+
+// AudioPattern returns the XO-CHIP audio pattern: 128 1-bit samples, MSB first, played in a loop while ST > 0.
+func (c *Chip8) AudioPattern() [audioPatternSize]byte {
+	return c.audioPattern
+}
+
+// PlaybackRate returns the XO-CHIP sample rate in Hz derived from the pitch register (4000Hz by default).
+func (c *Chip8) PlaybackRate() float64 {
+	return 4000 * math.Pow(2, (float64(c.pitch)-64)/48)
+}
+
 // Gfx returns a copy of the graphics buffer.
+// Each byte holds the plane bits of a pixel (0-3); see Resolution for the layout.
 func (c *Chip8) Gfx() [gfxSize]byte {
 	// Return a copy to prevent direct modification from outside
 	var gfxCopy [gfxSize]byte
@@ -138,10 +226,10 @@ func (c *Chip8) LoadROM(romPath string) error {
 	}
 
 	// ROMs are loaded starting at address 0x200 (romOffset)
-	// Available memory for ROM is memorySize - romOffset
-	if len(romData) > (memorySize - romOffset) {
+	// Available memory for ROM is memSize() - romOffset
+	if len(romData) > (c.memSize() - romOffset) {
 		return fmt.Errorf("ROM file '%s' is too large: %d bytes (max %d bytes)",
-			romPath, len(romData), memorySize-romOffset)
+			romPath, len(romData), c.memSize()-romOffset)
 	}
 
 	// Copy ROM data into memory
@@ -158,7 +246,7 @@ func (c *Chip8) LoadROM(romPath string) error {
 // fetchOpcode reads the 2-byte opcode from memory at the current PC.
 // It does not advance the PC.
 func (c *Chip8) fetchOpcode() uint16 {
-	if c.PC+1 >= memorySize {
+	if int(c.PC)+1 >= c.memSize() {
 		// This is a critical error, likely a runaway PC or corrupted ROM.
 		// For now, log and return a NOP-like opcode (e.g., 0x0000) or panic.
 		// Returning 0x0000 might lead to infinite loops if not handled by SYS addr.
@@ -177,6 +265,9 @@ func (c *Chip8) fetchOpcode() uint16 {
 // Returns collision (bool): if a collision occurred during a DRW operation.
 // Returns halted (bool): if the CPU is waiting for a key press (Fx0A).
 func (c *Chip8) Cycle() (redraw bool, collision bool, halted bool) {
+	if c.exited {
+		return false, false, true
+	}
 	if c.waitingForKey {
 		for i := 0; i < numRegisters; i++ {
 			if c.keys[i] {
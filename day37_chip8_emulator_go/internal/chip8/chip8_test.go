@@ -1331,3 +1331,204 @@ func TestUpdateTimers(t *testing.T) {
 		}
 	})
 }
+
+func TestExtendedOpcodes(t *testing.T) {
+	// createChip loads the given opcodes at romOffset. variant is "chip8", "schip" or "xochip".
+	createChip := func(variant string, opcodes ...uint16) *Chip8 {
+		c := NewWithSeed(1, variant == "schip", 1)
+		if variant == "xochip" {
+			c.EnableXOCHIP()
+		}
+		for i, op := range opcodes {
+			c.memory[romOffset+2*i] = byte(op >> 8)
+			c.memory[romOffset+2*i+1] = byte(op & 0x00FF)
+		}
+		return c
+	}
+	pixel := func(c *Chip8, x, y int) byte {
+		width, _ := c.Resolution()
+		return c.gfx[y*width+x]
+	}
+
+	t.Run("00FF/00FE - HIGH/LOW switch resolution and clear the screen", func(t *testing.T) {
+		c := createChip("schip", 0x00FF, 0x00FE)
+		c.gfx[0] = 1
+		redraw, _, _ := c.Cycle()
+		if w, h := c.Resolution(); w != hiresWidth || h != hiresHeight {
+			t.Errorf("Resolution after 00FF: expected %dx%d, got %dx%d", hiresWidth, hiresHeight, w, h)
+		}
+		if !redraw || c.gfx[0] != 0 {
+			t.Error("00FF should clear the screen and request a redraw")
+		}
+		c.Cycle()
+		if w, h := c.Resolution(); w != gfxWidth || h != gfxHeight {
+			t.Errorf("Resolution after 00FE: expected %dx%d, got %dx%d", gfxWidth, gfxHeight, w, h)
+		}
+		if c.PC != romOffset+4 {
+			t.Errorf("PC expected 0x%X, got 0x%X", romOffset+4, c.PC)
+		}
+	})
+
+	t.Run("00FF - ignored without SCHIP", func(t *testing.T) {
+		c := createChip("chip8", 0x00FF)
+		c.Cycle()
+		if w, _ := c.Resolution(); w != gfxWidth {
+			t.Errorf("Plain CHIP-8 should stay in lo-res, got width %d", w)
+		}
+	})
+
+	t.Run("00CN/00FB/00FC - scroll down, right and left", func(t *testing.T) {
+		c := createChip("schip", 0x00C3, 0x00FB, 0x00FC)
+		c.gfx[1*gfxWidth+10] = 1
+		c.Cycle()
+		if pixel(c, 10, 1) != 0 || pixel(c, 10, 4) != 1 {
+			t.Error("00C3 should move the pixel from (10,1) to (10,4)")
+		}
+		c.Cycle()
+		if pixel(c, 10, 4) != 0 || pixel(c, 14, 4) != 1 {
+			t.Error("00FB should move the pixel from (10,4) to (14,4)")
+		}
+		c.Cycle()
+		if pixel(c, 14, 4) != 0 || pixel(c, 10, 4) != 1 {
+			t.Error("00FC should move the pixel from (14,4) back to (10,4)")
+		}
+	})
+
+	t.Run("Dxy0 - 16x16 sprite in hi-res", func(t *testing.T) {
+		c := createChip("schip", 0x00FF, 0xD010)
+		c.I = 0x300
+		for i := 0; i < 32; i++ {
+			c.memory[0x300+i] = 0xFF
+		}
+		c.V[0] = 100
+		c.V[1] = 40
+		c.Cycle()
+		redraw, collision, _ := c.Cycle()
+		if !redraw || collision {
+			t.Errorf("Dxy0 expected redraw without collision, got redraw=%t collision=%t", redraw, collision)
+		}
+		if pixel(c, 100, 40) != 1 || pixel(c, 115, 55) != 1 || pixel(c, 116, 40) != 0 {
+			t.Error("Dxy0 should draw a 16x16 block at (100,40)")
+		}
+	})
+
+	t.Run("Dxy0 - draws nothing on plain CHIP-8", func(t *testing.T) {
+		c := createChip("chip8", 0xD010)
+		c.I = 0x300
+		c.memory[0x300] = 0xFF
+		redraw, _, _ := c.Cycle()
+		if redraw || c.gfx[0] != 0 {
+			t.Error("Dxy0 should be a no-op on plain CHIP-8")
+		}
+	})
+
+	t.Run("Fx30 - LD HF, Vx", func(t *testing.T) {
+		c := createChip("schip", 0xF330)
+		c.V[3] = 7
+		c.Cycle()
+		if c.I != uint16(bigFontOffset+7*10) {
+			t.Errorf("I expected 0x%X, got 0x%X", bigFontOffset+7*10, c.I)
+		}
+		if c.memory[c.I] != bigFontSet[70] {
+			t.Error("Big font not loaded into memory")
+		}
+	})
+
+	t.Run("Fx75/Fx85 - save and restore flags", func(t *testing.T) {
+		c := createChip("schip", 0xF275, 0xF285)
+		c.V[0], c.V[1], c.V[2], c.V[3] = 1, 2, 3, 4
+		c.Cycle()
+		c.V[0], c.V[1], c.V[2], c.V[3] = 0, 0, 0, 0
+		c.Cycle()
+		if c.V[0] != 1 || c.V[1] != 2 || c.V[2] != 3 || c.V[3] != 0 {
+			t.Errorf("V0..V3 expected 1,2,3,0, got %v", c.V[:4])
+		}
+	})
+
+	t.Run("00FD - EXIT halts the CPU", func(t *testing.T) {
+		c := createChip("schip", 0x00FD)
+		c.Cycle()
+		_, _, halted := c.Cycle()
+		if !c.Exited() || !halted || c.PC != romOffset {
+			t.Errorf("00FD expected exited and halted at 0x%X, got exited=%t halted=%t PC=0x%X", romOffset, c.Exited(), halted, c.PC)
+		}
+	})
+
+	t.Run("XO-CHIP - drawing on both planes", func(t *testing.T) {
+		c := createChip("xochip", 0xF301, 0xD011)
+		c.I = 0x300
+		c.memory[0x300] = 0x80 // Plane 1: leftmost pixel
+		c.memory[0x301] = 0xC0 // Plane 2: two leftmost pixels
+		c.Cycle()
+		c.Cycle()
+		if c.gfx[0] != 3 || c.gfx[1] != 2 {
+			t.Errorf("Pixels expected 3 and 2, got %d and %d", c.gfx[0], c.gfx[1])
+		}
+	})
+
+	t.Run("XO-CHIP - CLS only clears the selected plane", func(t *testing.T) {
+		c := createChip("xochip", 0xF201, 0x00E0)
+		c.gfx[0] = 3
+		c.Cycle()
+		c.Cycle()
+		if c.gfx[0] != 1 {
+			t.Errorf("Pixel expected 1 (plane 1 kept), got %d", c.gfx[0])
+		}
+	})
+
+	t.Run("XO-CHIP - F000 NNNN and skipping over it", func(t *testing.T) {
+		c := createChip("xochip", 0x3000, 0xF000, 0x1234, 0xF000, 0xABCD)
+		c.Cycle() // V0 == 0, so the 4-byte F000 is skipped
+		if c.PC != romOffset+6 {
+			t.Fatalf("PC after skip expected 0x%X, got 0x%X", romOffset+6, c.PC)
+		}
+		c.Cycle()
+		if c.I != 0xABCD || c.PC != romOffset+10 {
+			t.Errorf("F000 expected I=0xABCD PC=0x%X, got I=0x%X PC=0x%X", romOffset+10, c.I, c.PC)
+		}
+	})
+
+	t.Run("XO-CHIP - 5xy2/5xy3 save and load a register range", func(t *testing.T) {
+		c := createChip("xochip", 0x5242, 0x5423)
+		c.I = 0x400
+		c.V[2], c.V[3], c.V[4] = 0x11, 0x22, 0x33
+		c.Cycle()
+		if c.memory[0x400] != 0x11 || c.memory[0x402] != 0x33 || c.I != 0x400 {
+			t.Error("5242 should store V2..V4 at I without changing I")
+		}
+		c.Cycle() // Load V4..V2 in reverse order
+		if c.V[4] != 0x11 || c.V[3] != 0x22 || c.V[2] != 0x33 {
+			t.Errorf("5423 expected V4,V3,V2 = 0x11,0x22,0x33, got 0x%X,0x%X,0x%X", c.V[4], c.V[3], c.V[2])
+		}
+	})
+
+	t.Run("XO-CHIP - audio pattern and pitch", func(t *testing.T) {
+		c := createChip("xochip", 0xF002, 0xF53A)
+		c.I = 0x300
+		c.memory[0x300] = 0xAA
+		c.memory[0x30F] = 0x55
+		c.V[5] = 112
+		c.Cycle()
+		c.Cycle()
+		pattern := c.AudioPattern()
+		if pattern[0] != 0xAA || pattern[15] != 0x55 {
+			t.Errorf("Audio pattern not loaded from I, got %v", pattern)
+		}
+		if rate := c.PlaybackRate(); rate != 8000 {
+			t.Errorf("Playback rate for pitch 112 expected 8000Hz, got %v", rate)
+		}
+	})
+
+	t.Run("XO-CHIP - ROMs larger than 4KB", func(t *testing.T) {
+		romPath := filepath.Join(t.TempDir(), "large.ch8")
+		if err := os.WriteFile(romPath, make([]byte, 8192), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := createChip("xochip").LoadROM(romPath); err != nil {
+			t.Errorf("XO-CHIP should accept an 8KB ROM: %v", err)
+		}
+		if err := createChip("schip").LoadROM(romPath); err == nil {
+			t.Error("SCHIP should reject an 8KB ROM")
+		}
+	})
+}
@@ -0,0 +1,341 @@
+package chip8
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op identifies a CHIP-8, SCHIP or XO-CHIP instruction independent of its operands.
+// The interpreter (executeOpcode) and the disassembler both dispatch on the Op returned by Decode,
+// so they always agree on what an opcode means.
+type Op int
+
+const (
+	OpUnknown Op = iota
+	OpSYS        // 0nnn: SYS addr (ignored)
+	OpCLS        // 00E0: CLS
+	OpRET        // 00EE: RET
+	OpJP         // 1nnn: JP addr
+	OpCALL       // 2nnn: CALL addr
+	OpSEByte     // 3xkk: SE Vx, byte
+	OpSNEByte    // 4xkk: SNE Vx, byte
+	OpSEReg      // 5xy0: SE Vx, Vy
+	OpLDByte     // 6xkk: LD Vx, byte
+	OpADDByte    // 7xkk: ADD Vx, byte
+	OpLDReg      // 8xy0: LD Vx, Vy
+	OpOR         // 8xy1: OR Vx, Vy
+	OpAND        // 8xy2: AND Vx, Vy
+	OpXOR        // 8xy3: XOR Vx, Vy
+	OpADDReg     // 8xy4: ADD Vx, Vy
+	OpSUB        // 8xy5: SUB Vx, Vy
+	OpSHR        // 8xy6: SHR Vx {, Vy}
+	OpSUBN       // 8xy7: SUBN Vx, Vy
+	OpSHL        // 8xyE: SHL Vx {, Vy}
+	OpSNEReg     // 9xy0: SNE Vx, Vy
+	OpLDI        // Annn: LD I, addr
+	OpJPV0       // Bnnn: JP V0, addr
+	OpRND        // Cxkk: RND Vx, byte
+	OpDRW        // Dxyn: DRW Vx, Vy, nibble
+	OpSKP        // Ex9E: SKP Vx
+	OpSKNP       // ExA1: SKNP Vx
+	OpLDVxDT     // Fx07: LD Vx, DT
+	OpLDVxK      // Fx0A: LD Vx, K
+	OpLDDTVx     // Fx15: LD DT, Vx
+	OpLDSTVx     // Fx18: LD ST, Vx
+	OpADDIVx     // Fx1E: ADD I, Vx
+	OpLDFVx      // Fx29: LD F, Vx
+	OpLDBVx      // Fx33: LD B, Vx
+	OpLDIVx      // Fx55: LD [I], Vx
+	OpLDVxI      // Fx65: LD Vx, [I]
+
+	// SCHIP (also available on XO-CHIP)
+	OpSCD    // 00CN: SCD nibble
+	OpSCR    // 00FB: SCR
+	OpSCL    // 00FC: SCL
+	OpEXIT   // 00FD: EXIT
+	OpLOW    // 00FE: LOW
+	OpHIGH   // 00FF: HIGH
+	OpLDHFVx // Fx30: LD HF, Vx
+	OpLDRVx  // Fx75: LD R, Vx
+	OpLDVxR  // Fx85: LD Vx, R
+
+	// XO-CHIP
+	OpSCU       // 00DN: SCU nibble
+	OpSaveRange // 5xy2: LD [I], Vx-Vy
+	OpLoadRange // 5xy3: LD Vx-Vy, [I]
+	OpLDILong   // F000 NNNN: LD I, long addr
+	OpPLANE     // Fn01: PLANE n
+	OpAUDIO     // F002: AUDIO
+	OpPITCH     // Fx3A: PITCH Vx
+)
+
+// Instruction is a decoded opcode with its operand fields extracted.
+// Fields that the instruction does not use are still filled from the opcode bits.
+type Instruction struct {
+	Opcode uint16
+	Op     Op
+	X      byte   // Register index in the second nibble
+	Y      byte   // Register index in the third nibble
+	N      byte   // Lowest nibble
+	KK     byte   // Lowest byte
+	NNN    uint16 // Lowest 12 bits (address)
+	Long   uint16 // 16-bit address following F000 (OpLDILong); Decode cannot see it, so the caller fills it in
+}
+
+// Decode decodes opcode for the variant the machine runs (CHIP-8, SCHIP or XO-CHIP).
+func (c *Chip8) Decode(opcode uint16) Instruction {
+	return decode(opcode, c.extended(), c.variantXOCHIP)
+}
+
+func decode(opcode uint16, extended, xochip bool) Instruction {
+	return Instruction{
+		Opcode: opcode,
+		Op:     decodeOp(opcode, extended, xochip),
+		X:      byte((opcode & 0x0F00) >> 8),
+		Y:      byte((opcode & 0x00F0) >> 4),
+		N:      byte(opcode & 0x000F),
+		KK:     byte(opcode & 0x00FF),
+		NNN:    opcode & 0x0FFF,
+	}
+}
+
+func decodeOp(opcode uint16, extended, xochip bool) Op {
+	// SCHIP / XO-CHIP instructions that do not exist in plain CHIP-8 take precedence
+	if extended {
+		switch {
+		case opcode&0xFFF0 == 0x00C0:
+			return OpSCD
+		case opcode&0xFFF0 == 0x00D0 && xochip:
+			return OpSCU
+		case opcode == 0x00FB:
+			return OpSCR
+		case opcode == 0x00FC:
+			return OpSCL
+		case opcode == 0x00FD:
+			return OpEXIT
+		case opcode == 0x00FE:
+			return OpLOW
+		case opcode == 0x00FF:
+			return OpHIGH
+		case opcode&0xF00F == 0x5002 && xochip:
+			return OpSaveRange
+		case opcode&0xF00F == 0x5003 && xochip:
+			return OpLoadRange
+		case opcode == 0xF000 && xochip:
+			return OpLDILong
+		case opcode&0xF0FF == 0xF001 && xochip:
+			return OpPLANE
+		case opcode == 0xF002 && xochip:
+			return OpAUDIO
+		case opcode&0xF0FF == 0xF03A && xochip:
+			return OpPITCH
+		case opcode&0xF0FF == 0xF030:
+			return OpLDHFVx
+		case opcode&0xF0FF == 0xF075:
+			return OpLDRVx
+		case opcode&0xF0FF == 0xF085:
+			return OpLDVxR
+		}
+	}
+
+	switch opcode & 0xF000 {
+	case 0x0000:
+		switch opcode & 0x00FF { // Only the low byte is checked for 00E0 and 00EE
+		case 0x00E0:
+			return OpCLS
+		case 0x00EE:
+			return OpRET
+		}
+		return OpSYS
+	case 0x1000:
+		return OpJP
+	case 0x2000:
+		return OpCALL
+	case 0x3000:
+		return OpSEByte
+	case 0x4000:
+		return OpSNEByte
+	case 0x5000:
+		if opcode&0x000F == 0 {
+			return OpSEReg
+		}
+	case 0x6000:
+		return OpLDByte
+	case 0x7000:
+		return OpADDByte
+	case 0x8000:
+		switch opcode & 0x000F {
+		case 0x0:
+			return OpLDReg
+		case 0x1:
+			return OpOR
+		case 0x2:
+			return OpAND
+		case 0x3:
+			return OpXOR
+		case 0x4:
+			return OpADDReg
+		case 0x5:
+			return OpSUB
+		case 0x6:
+			return OpSHR
+		case 0x7:
+			return OpSUBN
+		case 0xE:
+			return OpSHL
+		}
+	case 0x9000:
+		if opcode&0x000F == 0 {
+			return OpSNEReg
+		}
+	case 0xA000:
+		return OpLDI
+	case 0xB000:
+		return OpJPV0
+	case 0xC000:
+		return OpRND
+	case 0xD000:
+		return OpDRW
+	case 0xE000:
+		switch opcode & 0x00FF {
+		case 0x9E:
+			return OpSKP
+		case 0xA1:
+			return OpSKNP
+		case 0x18: // Ex18 and Ex1E are accepted as aliases of Fx18 and Fx1E
+			return OpLDSTVx
+		case 0x1E:
+			return OpADDIVx
+		}
+	case 0xF000:
+		switch opcode & 0x00FF {
+		case 0x07:
+			return OpLDVxDT
+		case 0x0A:
+			return OpLDVxK
+		case 0x15:
+			return OpLDDTVx
+		case 0x18:
+			return OpLDSTVx
+		case 0x1E:
+			return OpADDIVx
+		case 0x29:
+			return OpLDFVx
+		case 0x33:
+			return OpLDBVx
+		case 0x55:
+			return OpLDIVx
+		case 0x65:
+			return OpLDVxI
+		}
+	}
+	return OpUnknown
+}
+
+// opSyntax is the assembly syntax and a short description of each Op.
+// {x}, {y}, {n}, {kk}, {nnn} and {long} are replaced with the operands of the instruction.
+var opSyntax = map[Op]struct{ asm, desc string }{
+	OpUnknown:   {"DW {opcode}", "unknown opcode"},
+	OpSYS:       {"SYS {nnn}", "machine code routine (ignored)"},
+	OpCLS:       {"CLS", "clear the display"},
+	OpRET:       {"RET", "return from subroutine"},
+	OpJP:        {"JP {nnn}", "PC = {nnn}"},
+	OpCALL:      {"CALL {nnn}", "call subroutine at {nnn}"},
+	OpSEByte:    {"SE V{x}, {kk}", "skip next if V{x} == {kk}"},
+	OpSNEByte:   {"SNE V{x}, {kk}", "skip next if V{x} != {kk}"},
+	OpSEReg:     {"SE V{x}, V{y}", "skip next if V{x} == V{y}"},
+	OpLDByte:    {"LD V{x}, {kk}", "V{x} = {kk}"},
+	OpADDByte:   {"ADD V{x}, {kk}", "V{x} += {kk}"},
+	OpLDReg:     {"LD V{x}, V{y}", "V{x} = V{y}"},
+	OpOR:        {"OR V{x}, V{y}", "V{x} |= V{y}"},
+	OpAND:       {"AND V{x}, V{y}", "V{x} &= V{y}"},
+	OpXOR:       {"XOR V{x}, V{y}", "V{x} ^= V{y}"},
+	OpADDReg:    {"ADD V{x}, V{y}", "V{x} += V{y}, VF = carry"},
+	OpSUB:       {"SUB V{x}, V{y}", "V{x} -= V{y}, VF = not borrow"},
+	OpSHR:       {"SHR V{x}, V{y}", "V{x} >>= 1, VF = shifted out bit"},
+	OpSUBN:      {"SUBN V{x}, V{y}", "V{x} = V{y} - V{x}, VF = not borrow"},
+	OpSHL:       {"SHL V{x}, V{y}", "V{x} <<= 1, VF = shifted out bit"},
+	OpSNEReg:    {"SNE V{x}, V{y}", "skip next if V{x} != V{y}"},
+	OpLDI:       {"LD I, {nnn}", "I = {nnn}"},
+	OpJPV0:      {"JP V0, {nnn}", "PC = {nnn} + V0"},
+	OpRND:       {"RND V{x}, {kk}", "V{x} = random & {kk}"},
+	OpDRW:       {"DRW V{x}, V{y}, {n}", "draw {n}-row sprite at I to (V{x}, V{y}), VF = collision"},
+	OpSKP:       {"SKP V{x}", "skip next if key V{x} is pressed"},
+	OpSKNP:      {"SKNP V{x}", "skip next if key V{x} is not pressed"},
+	OpLDVxDT:    {"LD V{x}, DT", "V{x} = delay timer"},
+	OpLDVxK:     {"LD V{x}, K", "wait for a key press, V{x} = key"},
+	OpLDDTVx:    {"LD DT, V{x}", "delay timer = V{x}"},
+	OpLDSTVx:    {"LD ST, V{x}", "sound timer = V{x}"},
+	OpADDIVx:    {"ADD I, V{x}", "I += V{x}"},
+	OpLDFVx:     {"LD F, V{x}", "I = font sprite of digit V{x}"},
+	OpLDBVx:     {"LD B, V{x}", "store BCD of V{x} at I"},
+	OpLDIVx:     {"LD [I], V{x}", "store V0..V{x} at I"},
+	OpLDVxI:     {"LD V{x}, [I]", "load V0..V{x} from I"},
+	OpSCD:       {"SCD {n}", "scroll down {n} lines"},
+	OpSCR:       {"SCR", "scroll right 4 pixels"},
+	OpSCL:       {"SCL", "scroll left 4 pixels"},
+	OpEXIT:      {"EXIT", "stop the interpreter"},
+	OpLOW:       {"LOW", "switch to 64x32 mode"},
+	OpHIGH:      {"HIGH", "switch to 128x64 mode"},
+	OpLDHFVx:    {"LD HF, V{x}", "I = big font sprite of digit V{x}"},
+	OpLDRVx:     {"LD R, V{x}", "store V0..V{x} in the user flags"},
+	OpLDVxR:     {"LD V{x}, R", "load V0..V{x} from the user flags"},
+	OpSCU:       {"SCU {n}", "scroll up {n} lines"},
+	OpSaveRange: {"LD [I], V{x}-V{y}", "store V{x}..V{y} at I"},
+	OpLoadRange: {"LD V{x}-V{y}, [I]", "load V{x}..V{y} from I"},
+	OpLDILong:   {"LD I, {long}", "I = {long}"},
+	OpPLANE:     {"PLANE {x}", "select drawing planes {x}"},
+	OpAUDIO:     {"AUDIO", "load audio pattern from I"},
+	OpPITCH:     {"PITCH V{x}", "playback rate = V{x}"},
+}
+
+// Size returns the length of the instruction in bytes: 4 for F000 NNNN, 2 otherwise.
+func (in Instruction) Size() uint16 {
+	if in.Op == OpLDILong {
+		return 4
+	}
+	return 2
+}
+
+// Target returns the address a JP or CALL transfers control to.
+func (in Instruction) Target() (addr uint16, ok bool) {
+	switch in.Op {
+	case OpJP, OpCALL:
+		return in.NNN, true
+	}
+	return 0, false
+}
+
+// String returns the instruction in assembly syntax, e.g. "LD V1, 0x20".
+func (in Instruction) String() string {
+	return in.Format(nil)
+}
+
+// Format is like String, but passes the address operand of jumps and calls through label,
+// so that the disassembler can print symbolic targets. A nil label prints the address.
+func (in Instruction) Format(label func(addr uint16) string) string {
+	asm := opSyntax[in.Op].asm
+	if label != nil {
+		if addr, ok := in.Target(); ok {
+			asm = strings.ReplaceAll(asm, "{nnn}", label(addr))
+		}
+	}
+	return in.operands().Replace(asm)
+}
+
+// Description returns a short explanation of what the instruction does, e.g. "V1 = 0x20".
+func (in Instruction) Description() string {
+	return in.operands().Replace(opSyntax[in.Op].desc)
+}
+
+func (in Instruction) operands() *strings.Replacer {
+	return strings.NewReplacer(
+		"{opcode}", fmt.Sprintf("0x%04X", in.Opcode),
+		"{x}", fmt.Sprintf("%X", in.X),
+		"{y}", fmt.Sprintf("%X", in.Y),
+		"{n}", fmt.Sprintf("%d", in.N),
+		"{kk}", fmt.Sprintf("0x%02X", in.KK),
+		"{nnn}", fmt.Sprintf("0x%03X", in.NNN),
+		"{long}", fmt.Sprintf("0x%04X", in.Long),
+	)
+}
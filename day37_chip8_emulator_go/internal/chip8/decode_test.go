@@ -0,0 +1,78 @@
+package chip8
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		opcode   uint16
+		schip    bool
+		xochip   bool
+		expected Op
+		asm      string
+	}{
+		{name: "CLS", opcode: 0x00E0, expected: OpCLS, asm: "CLS"},
+		{name: "SYS", opcode: 0x0123, expected: OpSYS, asm: "SYS 0x123"},
+		{name: "LD Vx, byte", opcode: 0x6A02, expected: OpLDByte, asm: "LD VA, 0x02"},
+		{name: "DRW", opcode: 0xDAB6, expected: OpDRW, asm: "DRW VA, VB, 6"},
+		{name: "5xy1 is unknown", opcode: 0x5121, expected: OpUnknown, asm: "DW 0x5121"},
+		{name: "8xy8 is unknown", opcode: 0x8128, expected: OpUnknown, asm: "DW 0x8128"},
+		{name: "Ex18 is an alias of Fx18", opcode: 0xE318, expected: OpLDSTVx, asm: "LD ST, V3"},
+		{name: "00FF without SCHIP is SYS", opcode: 0x00FF, expected: OpSYS, asm: "SYS 0x0FF"},
+		{name: "00FF with SCHIP", opcode: 0x00FF, schip: true, expected: OpHIGH, asm: "HIGH"},
+		{name: "00C4 with SCHIP", opcode: 0x00C4, schip: true, expected: OpSCD, asm: "SCD 4"},
+		{name: "00D4 needs XO-CHIP", opcode: 0x00D4, schip: true, expected: OpSYS, asm: "SYS 0x0D4"},
+		{name: "00D4 with XO-CHIP", opcode: 0x00D4, xochip: true, expected: OpSCU, asm: "SCU 4"},
+		{name: "5xy2 with XO-CHIP", opcode: 0x5132, xochip: true, expected: OpSaveRange, asm: "LD [I], V1-V3"},
+		{name: "Fx30 with SCHIP", opcode: 0xF530, schip: true, expected: OpLDHFVx, asm: "LD HF, V5"},
+		{name: "Fn01 with XO-CHIP", opcode: 0xF201, xochip: true, expected: OpPLANE, asm: "PLANE 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewWithSeed(10, tt.schip, 1)
+			if tt.xochip {
+				c.EnableXOCHIP()
+			}
+			inst := c.Decode(tt.opcode)
+			if inst.Op != tt.expected {
+				t.Errorf("Decode(0x%04X).Op = %d, want %d", tt.opcode, inst.Op, tt.expected)
+			}
+			if got := inst.String(); got != tt.asm {
+				t.Errorf("Decode(0x%04X).String() = %q, want %q", tt.opcode, got, tt.asm)
+			}
+		})
+	}
+
+	t.Run("Every Op has a syntax entry", func(t *testing.T) {
+		for op := OpUnknown; op <= OpPITCH; op++ {
+			if _, ok := opSyntax[op]; !ok {
+				t.Errorf("Op %d has no opSyntax entry", op)
+			}
+		}
+	})
+
+	t.Run("F000 NNNN is 4 bytes with the long address", func(t *testing.T) {
+		c := NewWithSeed(10, false, 1)
+		c.EnableXOCHIP()
+		inst := c.Decode(0xF000)
+		inst.Long = 0x1234
+		if inst.Size() != 4 || inst.String() != "LD I, 0x1234" {
+			t.Errorf("Expected 4-byte LD I, 0x1234, got %d bytes %q", inst.Size(), inst.String())
+		}
+	})
+
+	t.Run("Format passes jump and call targets through label", func(t *testing.T) {
+		label := func(addr uint16) string { return fmt.Sprintf("sub_%03X", addr) }
+		c := NewWithSeed(10, false, 1)
+		if got := c.Decode(0x22D4).Format(label); got != "CALL sub_2D4" {
+			t.Errorf("Expected CALL sub_2D4, got %q", got)
+		}
+		if got := c.Decode(0xA2EA).Format(label); got != "LD I, 0x2EA" {
+			t.Errorf("Expected LD I to keep its address, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package chip8
+
+import "log"
+
+// Display helpers shared by the CHIP-8, SCHIP and XO-CHIP drawing opcodes.
+// Every pixel of gfx stores one bit per plane, so the plain CHIP-8 opcodes (which only use plane 1)
+// keep seeing pixels as 0 or 1.
+
+// clearPlanes turns off the selected planes of every pixel (CLS).
+func (c *Chip8) clearPlanes() {
+	for i := range c.gfx {
+		c.gfx[i] &^= c.planes
+	}
+}
+
+// setHires switches between 64x32 and 128x64 mode (00FE/00FF). The screen is cleared, since the pixel layout changes.
+func (c *Chip8) setHires(hires bool) {
+	c.hires = hires
+	for i := range c.gfx {
+		c.gfx[i] = 0
+	}
+}
+
+// scroll shifts the selected planes by (dx, dy) pixels; pixels scrolled in from the edges are off.
+func (c *Chip8) scroll(dx, dy int) {
+	width, height := c.Resolution()
+	var shifted [gfxSize]byte
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX, srcY := x-dx, y-dy
+			if srcX < 0 || srcX >= width || srcY < 0 || srcY >= height {
+				continue
+			}
+			shifted[y*width+x] = c.gfx[srcY*width+srcX] & c.planes
+		}
+	}
+	for i := 0; i < width*height; i++ {
+		c.gfx[i] = c.gfx[i]&^c.planes | shifted[i]
+	}
+}
+
+// drawSprite XORs a sprite read from memory at I onto the selected planes at (vx, vy), wrapping around the screen edges.
+// height 0 draws a 16x16 sprite (SCHIP Dxy0). With both planes selected, the plane 2 data follows the plane 1 data.
+// It returns whether any pixel changed and whether any pixel was turned off (collision).
+func (c *Chip8) drawSprite(vx, vy byte, height int) (pixelChanged, collision bool) {
+	width, screenHeight := c.Resolution()
+	spriteWidth := 8
+	if height == 0 {
+		spriteWidth, height = 16, 16
+	}
+	bytesPerRow := spriteWidth / 8
+
+	addr := int(c.I)
+	for plane := byte(1); plane <= 2; plane <<= 1 {
+		if c.planes&plane == 0 {
+			continue
+		}
+		for row := 0; row < height; row++ {
+			for col := 0; col < bytesPerRow; col++ {
+				// Prevent reading out of memory bounds for sprite data
+				if addr >= c.memSize() {
+					log.Printf("DRW: Attempted to read sprite data out of memory bounds at I=0x%X", c.I)
+					return pixelChanged, collision
+				}
+				spriteByte := c.memory[addr]
+				addr++
+				for bit := 0; bit < 8; bit++ {
+					if spriteByte&(0x80>>bit) == 0 {
+						continue
+					}
+					x := (int(vx) + col*8 + bit) % width
+					y := (int(vy) + row) % screenHeight
+					i := y*width + x
+					if c.gfx[i]&plane != 0 {
+						collision = true
+					}
+					c.gfx[i] ^= plane
+					pixelChanged = true
+				}
+			}
+		}
+	}
+	return pixelChanged, collision
+}
+
+// skipLength returns how many bytes a skip instruction at PC jumps over: the next instruction is 4 bytes long
+// when it is the XO-CHIP F000 NNNN (LD I, long addr).
+func (c *Chip8) skipLength() uint16 {
+	next := int(c.PC) + 2
+	if c.variantXOCHIP && next+1 < c.memSize() && c.memory[next] == 0xF0 && c.memory[next+1] == 0x00 {
+		return 4
+	}
+	return 2
+}
@@ -8,42 +8,36 @@ import (
 // It returns whether the screen needs to be redrawn and if a collision occurred (for DRW).
 // PC is managed within this function: incremented by 2 for most opcodes,
 // or set directly for jump/call opcodes.
+// Decoding is done by Decode (decode.go), which the disassembler shares.
 func (c *Chip8) executeOpcode(opcode uint16) (redraw bool, collision bool) {
-	// Extract common opcode parts for readability if needed, or extract inside cases.
-	// x := (opcode & 0x0F00) >> 8
-	// y := (opcode & 0x00F0) >> 4
-	// nnn := opcode & 0x0FFF
-	// kk := byte(opcode & 0x00FF)
-	// n := byte(opcode & 0x000F)
+	inst := c.Decode(opcode)
+	x := uint16(inst.X)
+	y := uint16(inst.Y)
+	kk := inst.KK
 
-	switch opcode & 0xF000 {
-	case 0x0000:
-		switch opcode & 0x00FF { // More specific mask for 0x00E0 and 0x00EE
-		case 0x00E0: // CLS: Clear the display.
-			for i := range c.gfx {
-				c.gfx[i] = 0
-			}
-			c.PC += 2
-			return true, false // redraw = true, collision = false
-		case 0x00EE: // RET: Return from a subroutine.
-			if c.SP == 0 {
-				log.Printf("Stack underflow on RET (00EE) at PC 0x%X! SP is 0.", c.PC) // PC might not have advanced yet here
-				c.PC += 2                                                              // Default behavior if we don't halt
-				return false, false
-			}
-			c.SP--
-			c.PC = c.stack[c.SP]
-			return false, false
-		default:
-			// SYS addr (0nnn) - Jump to machine code routine at nnn (ignored on modern interpreters)
-			log.Printf("Ignoring SYS opcode: 0x%X", opcode)
-			c.PC += 2
+	switch inst.Op {
+	case OpCLS: // CLS: Clear the display (the selected planes on XO-CHIP).
+		c.clearPlanes()
+		c.PC += 2
+		return true, false // redraw = true, collision = false
+	case OpRET: // RET: Return from a subroutine.
+		if c.SP == 0 {
+			log.Printf("Stack underflow on RET (00EE) at PC 0x%X! SP is 0.", c.PC) // PC might not have advanced yet here
+			c.PC += 2                                                              // Default behavior if we don't halt
 			return false, false
 		}
-	case 0x1000: // JP addr (1nnn): Jump to location nnn.
-		c.PC = opcode & 0x0FFF
+		c.SP--
+		c.PC = c.stack[c.SP]
 		return false, false
-	case 0x2000: // CALL addr (2nnn): Call subroutine at nnn.
+	case OpSYS:
+		// SYS addr (0nnn) - Jump to machine code routine at nnn (ignored on modern interpreters)
+		log.Printf("Ignoring SYS opcode: 0x%X", opcode)
+		c.PC += 2
+		return false, false
+	case OpJP: // JP addr (1nnn): Jump to location nnn.
+		c.PC = inst.NNN
+		return false, false
+	case OpCALL: // CALL addr (2nnn): Call subroutine at nnn.
 		if c.SP >= stackSize {
 			log.Printf("Stack overflow on CALL (2nnn) at PC 0x%X! SP is %d.", c.PC, c.SP)
 			// Behavior on stack overflow can vary.
@@ -54,310 +48,342 @@ func (c *Chip8) executeOpcode(opcode uint16) (redraw bool, collision bool) {
 			// This is not ideal. A better way is to define behavior (e.g. halt or error).
 			// Let's allow it to overwrite for now, but cap SP to prevent out of bounds write if strict.
 			// Actually, let's prevent SP from going out of bounds and log. This will cause RET to fail later.
-			log.Printf("Stack is full. CALL to 0x%X will proceed without pushing PC.", opcode&0x0FFF)
-			c.PC = opcode & 0x0FFF // Jump anyway
+			log.Printf("Stack is full. CALL to 0x%X will proceed without pushing PC.", inst.NNN)
+			c.PC = inst.NNN // Jump anyway
 			return false, false
 		}
 		c.stack[c.SP] = c.PC + 2 // Store next instruction's address (current PC + 2 since current opcode is 2 bytes)
 		c.SP++
-		c.PC = opcode & 0x0FFF // Set PC to nnn
+		c.PC = inst.NNN // Set PC to nnn
 		return false, false
-	case 0x6000: // LD Vx, byte (6xkk): Set Vx = kk.
-		x := (opcode & 0x0F00) >> 8
-		kk := byte(opcode & 0x00FF)
+	case OpLDByte: // LD Vx, byte (6xkk): Set Vx = kk.
 		c.V[x] = kk
 		c.PC += 2
 		return false, false
-	case 0x7000: // ADD Vx, byte (7xkk): Set Vx = Vx + kk.
-		x := (opcode & 0x0F00) >> 8
-		kk := byte(opcode & 0x00FF)
+	case OpADDByte: // ADD Vx, byte (7xkk): Set Vx = Vx + kk.
 		c.V[x] += kk // VF is not affected
 		c.PC += 2
 		return false, false
-	case 0xD000: // DRW Vx, Vy, nibble (Dxyn)
+	case OpDRW: // DRW Vx, Vy, nibble (Dxyn)
 		// Display n-byte sprite starting at memory location I at (Vx, Vy), set VF = collision.
-		xReg := (opcode & 0x0F00) >> 8
-		yReg := (opcode & 0x00F0) >> 4
-		n := byte(opcode & 0x000F) // Height of the sprite (number of rows)
-
-		vx := c.V[xReg] // X coordinate from Vx
-		vy := c.V[yReg] // Y coordinate from Vy
+		// Dxy0 draws a 16x16 sprite on SCHIP / XO-CHIP and nothing on plain CHIP-8.
+		n := int(inst.N) // Height of the sprite (number of rows)
 
-		c.V[0xF] = 0          // Reset collision flag VF.
-		pixelChanged := false // Track if any pixel was actually flipped for redraw status
-
-		for yLine := byte(0); yLine < n; yLine++ {
-			// Prevent reading out of memory bounds for sprite data
-			if c.I+uint16(yLine) >= memorySize {
-				log.Printf("DRW: Attempted to read sprite data out of memory bounds at I=0x%X, yLine=%d", c.I, yLine)
-				continue // Skip this line of the sprite
-			}
-			spriteByte := c.memory[c.I+uint16(yLine)]
-			screenY := (vy + yLine) // Actual Y on screen, wrap around with %
-
-			for xBit := byte(0); xBit < 8; xBit++ { // Each byte is 8 pixels wide
-				// Check if the current bit in spriteByte is set (pixel is on)
-				// (0x80 >> xBit) creates masks 10000000, 01000000, ..., 00000001
-				if (spriteByte & (0x80 >> xBit)) != 0 {
-					screenX := (vx + xBit) // Actual X on screen, wrap around with %
-
-					// Wrap around X and Y coordinates for screen
-					wrappedX := uint16(screenX % gfxWidth)
-					wrappedY := uint16(screenY % gfxHeight)
-					gfxIndex := wrappedY*gfxWidth + wrappedX
-
-					if gfxIndex < gfxSize { // Should always be true due to modulo
-						if c.gfx[gfxIndex] == 1 { // If pixel on screen is already set
-							c.V[0xF] = 1 // Collision detected
-						}
-						c.gfx[gfxIndex] ^= 1 // XOR the pixel on the screen buffer
-						if !pixelChanged {
-							pixelChanged = true // A pixel was flipped, so screen needs redraw
-						}
-					}
-				}
-			}
+		c.V[0xF] = 0 // Reset collision flag VF.
+		var pixelChanged, collision bool
+		if n > 0 || c.extended() {
+			pixelChanged, collision = c.drawSprite(c.V[x], c.V[y], n)
+		}
+		if collision {
+			c.V[0xF] = 1
 		}
 		c.PC += 2
-		return pixelChanged, c.V[0xF] == 1
+		return pixelChanged, collision
 
-	case 0x8000: // Arithmetic and Logic opcodes (8xy0 - 8xy7, 8xyE)
-		x := (opcode & 0x0F00) >> 8
-		y := (opcode & 0x00F0) >> 4
-		switch opcode & 0x000F {
-		case 0x0000: // LD Vx, Vy (8xy0) - Set Vx = Vy.
-			c.V[x] = c.V[y]
-		case 0x0001: // OR Vx, Vy (8xy1) - Set Vx = Vx OR Vy.
-			c.V[x] |= c.V[y]
-		case 0x0002: // AND Vx, Vy (8xy2) - Set Vx = Vx AND Vy.
-			c.V[x] &= c.V[y]
-		case 0x0003: // XOR Vx, Vy (8xy3) - Set Vx = Vx XOR Vy.
-			c.V[x] ^= c.V[y]
-		case 0x0004: // ADD Vx, Vy (8xy4) - Set Vx = Vx + Vy, set VF = carry.
-			// Cast to uint16 to detect overflow for carry
-			sum := uint16(c.V[x]) + uint16(c.V[y])
-			c.V[x] = byte(sum & 0xFF) // Lower 8 bits are the result
-			if sum > 0xFF {           // If sum is greater than 255, a carry occurred
-				c.V[0xF] = 1
-			} else {
-				c.V[0xF] = 0
-			}
-		case 0x0005: // SUB Vx, Vy (8xy5) - Set Vx = Vx - Vy, set VF = NOT borrow.
-			// If Vx > Vy, then VF is 1; otherwise 0.
-			borrow := byte(0)
-			if c.V[x] >= c.V[y] { // Note: NOT borrow means Vx >= Vy for VF=1
-				borrow = 1
-			}
-			c.V[x] -= c.V[y]
-			c.V[0xF] = borrow
-		case 0x0006: // SHR Vx {, Vy} (8xy6) - Set Vx = Vx SHR 1.
-			// If variantSCHIP is true, Vx = Vy SHR 1. VF = LSB of Vy.
-			// Otherwise, Vx = Vx SHR 1. VF = LSB of Vx.
-			var lsb byte
-			if c.variantSCHIP {
-				lsb = c.V[y] & 0x1
-				c.V[x] = c.V[y] >> 1
-			} else {
-				lsb = c.V[x] & 0x1
-				c.V[x] >>= 1
-			}
-			c.V[0xF] = lsb
-		case 0x0007: // SUBN Vx, Vy (8xy7) - Set Vx = Vy - Vx, set VF = NOT borrow.
-			// If Vy > Vx, then VF is 1; otherwise 0.
-			borrow := byte(0)
-			if c.V[y] >= c.V[x] { // Note: NOT borrow means Vy >= Vx for VF=1
-				borrow = 1
-			}
-			c.V[x] = c.V[y] - c.V[x]
-			c.V[0xF] = borrow
-		case 0x000E: // SHL Vx {, Vy} (8xyE) - Set Vx = Vx SHL 1.
-			// If variantSCHIP is true, Vx = Vy SHL 1. VF = MSB of Vy.
-			// Otherwise, Vx = Vx SHL 1. VF = MSB of Vx.
-			var msb byte
-			if c.variantSCHIP {
-				msb = (c.V[y] & 0x80) >> 7 // Get MSB (0x80 is 10000000b)
-				c.V[x] = c.V[y] << 1
-			} else {
-				msb = (c.V[x] & 0x80) >> 7
-				c.V[x] <<= 1
-			}
-			c.V[0xF] = msb
-		default:
-			log.Printf("Unknown 8xxx opcode: 0x%X (PC: 0x%X)", opcode, c.PC)
+	// Arithmetic and Logic opcodes (8xy0 - 8xy7, 8xyE). Most 8xxx opcodes do not affect redraw.
+	case OpLDReg: // LD Vx, Vy (8xy0) - Set Vx = Vy.
+		c.V[x] = c.V[y]
+		c.PC += 2
+		return false, false
+	case OpOR: // OR Vx, Vy (8xy1) - Set Vx = Vx OR Vy.
+		c.V[x] |= c.V[y]
+		c.PC += 2
+		return false, false
+	case OpAND: // AND Vx, Vy (8xy2) - Set Vx = Vx AND Vy.
+		c.V[x] &= c.V[y]
+		c.PC += 2
+		return false, false
+	case OpXOR: // XOR Vx, Vy (8xy3) - Set Vx = Vx XOR Vy.
+		c.V[x] ^= c.V[y]
+		c.PC += 2
+		return false, false
+	case OpADDReg: // ADD Vx, Vy (8xy4) - Set Vx = Vx + Vy, set VF = carry.
+		// Cast to uint16 to detect overflow for carry
+		sum := uint16(c.V[x]) + uint16(c.V[y])
+		c.V[x] = byte(sum & 0xFF) // Lower 8 bits are the result
+		if sum > 0xFF {           // If sum is greater than 255, a carry occurred
+			c.V[0xF] = 1
+		} else {
+			c.V[0xF] = 0
 		}
 		c.PC += 2
-		return false, false // Most 8xxx opcodes do not affect redraw
-
-	case 0x9000: // SNE Vx, Vy (9xy0) - Skip next instruction if Vx != Vy.
-		// Ensure last nibble is 0 for this opcode
-		if opcode&0x000F != 0x0000 {
-			log.Printf("Unknown 9xxx opcode (last nibble not 0): 0x%X", opcode)
-			c.PC += 2
-			return false, false
+		return false, false
+	case OpSUB: // SUB Vx, Vy (8xy5) - Set Vx = Vx - Vy, set VF = NOT borrow.
+		// If Vx > Vy, then VF is 1; otherwise 0.
+		borrow := byte(0)
+		if c.V[x] >= c.V[y] { // Note: NOT borrow means Vx >= Vy for VF=1
+			borrow = 1
 		}
-		x := (opcode & 0x0F00) >> 8
-		y := (opcode & 0x00F0) >> 4
-		if c.V[x] != c.V[y] {
-			c.PC += 2 // Skip additional 2 bytes
+		c.V[x] -= c.V[y]
+		c.V[0xF] = borrow
+		c.PC += 2
+		return false, false
+	case OpSHR: // SHR Vx {, Vy} (8xy6) - Set Vx = Vx SHR 1.
+		// If variantSCHIP is true, Vx = Vy SHR 1. VF = LSB of Vy.
+		// Otherwise, Vx = Vx SHR 1. VF = LSB of Vx.
+		var lsb byte
+		if c.variantSCHIP {
+			lsb = c.V[y] & 0x1
+			c.V[x] = c.V[y] >> 1
+		} else {
+			lsb = c.V[x] & 0x1
+			c.V[x] >>= 1
 		}
-		c.PC += 2 // Base increment
+		c.V[0xF] = lsb
+		c.PC += 2
+		return false, false
+	case OpSUBN: // SUBN Vx, Vy (8xy7) - Set Vx = Vy - Vx, set VF = NOT borrow.
+		// If Vy > Vx, then VF is 1; otherwise 0.
+		borrow := byte(0)
+		if c.V[y] >= c.V[x] { // Note: NOT borrow means Vy >= Vx for VF=1
+			borrow = 1
+		}
+		c.V[x] = c.V[y] - c.V[x]
+		c.V[0xF] = borrow
+		c.PC += 2
+		return false, false
+	case OpSHL: // SHL Vx {, Vy} (8xyE) - Set Vx = Vx SHL 1.
+		// If variantSCHIP is true, Vx = Vy SHL 1. VF = MSB of Vy.
+		// Otherwise, Vx = Vx SHL 1. VF = MSB of Vx.
+		var msb byte
+		if c.variantSCHIP {
+			msb = (c.V[y] & 0x80) >> 7 // Get MSB (0x80 is 10000000b)
+			c.V[x] = c.V[y] << 1
+		} else {
+			msb = (c.V[x] & 0x80) >> 7
+			c.V[x] <<= 1
+		}
+		c.V[0xF] = msb
+		c.PC += 2
 		return false, false
 
-	case 0xA000: // LD I, addr (Annn) - Set I = nnn.
-		c.I = opcode & 0x0FFF
+	case OpLDI: // LD I, addr (Annn) - Set I = nnn.
+		c.I = inst.NNN
 		c.PC += 2
 		return false, false
 
-	case 0xE000:
-		x := (opcode & 0x0F00) >> 8
-		switch opcode & 0x00FF {
-		case 0x009E: // SKP Vx (Ex9E) - Skip next instruction if key with the value of Vx is pressed.
-			if c.IsKeyPressed(c.V[x]) {
-				c.PC += 2 // Skip the original PC += 2 by doing it here
-			}
-			c.PC += 2
-		case 0x00A1: // SKNP Vx (ExA1) - Skip next instruction if key with the value of Vx is not pressed.
-			if !c.IsKeyPressed(c.V[x]) {
-				c.PC += 2 // Skip the original PC += 2 by doing it here
-			}
-			c.PC += 2
-		case 0x0018: // LD ST, Vx (Fx18) - Set sound timer = Vx.
-			c.ST = c.V[x]
-			c.PC += 2
-		case 0x001E: // ADD I, Vx (Fx1E)
-			c.I += uint16(c.V[x])
-			c.PC += 2
-		default:
-			log.Printf("Unknown Ex opcode: 0x%X (PC: 0x%X)", opcode, c.PC)
-			c.PC += 2
-			return false, false
+	case OpSKP: // SKP Vx (Ex9E) - Skip next instruction if key with the value of Vx is pressed.
+		if c.IsKeyPressed(c.V[x]) {
+			c.PC += c.skipLength() // Skip the next instruction
 		}
+		c.PC += 2
+		return false, false
+	case OpSKNP: // SKNP Vx (ExA1) - Skip next instruction if key with the value of Vx is not pressed.
+		if !c.IsKeyPressed(c.V[x]) {
+			c.PC += c.skipLength() // Skip the next instruction
+		}
+		c.PC += 2
 		return false, false
 
-	case 0xF000:
-		x := (opcode & 0x0F00) >> 8 // Common for many Fx opcodes
-		switch opcode & 0x00FF {
-		case 0x0007: // LD Vx, DT (Fx07)
-			c.V[x] = c.DT
-			c.PC += 2
-			return false, false // Added return
-		case 0x000A: // LD Vx, K (Fx0A)
-			c.waitingForKey = true
-			c.keyReg = byte(x)
-			// PC does NOT advance here.
-			return false, false // No redraw, Halted state determined by Cycle()
-		case 0x0015: // LD DT, Vx (Fx15)
-			c.DT = c.V[x]
-			c.PC += 2
-			return false, false // Added return
-		case 0x0018: // LD ST, Vx (Fx18)
-			c.ST = c.V[x]
-			c.PC += 2
-			return false, false // Added return
-		case 0x001E: // ADD I, Vx (Fx1E)
-			// VF not affected
-			c.I += uint16(c.V[x])
-			c.PC += 2
-			return false, false
-		case 0x0029: // LD F, Vx (Fx29) - Set I = location of sprite for digit Vx.
-			digit := c.V[x] & 0x0F
-			c.I = uint16(fontOffset + (int(digit) * 5))
-			c.PC += 2
-			return false, false
-		case 0x0033: // LD B, Vx (Fx33) - Store BCD representation of Vx.
-			if c.I+2 >= memorySize {
-				log.Printf("Memory out of bounds on LD B, Vx (Fx33) at PC 0x%X. I=0x%X", c.PC, c.I)
-			} else {
-				val := c.V[x]
-				c.memory[c.I] = val / 100
-				c.memory[c.I+1] = (val / 10) % 10
-				c.memory[c.I+2] = val % 10
-			}
-			c.PC += 2
-			return false, false
-		case 0x0055: // LD [I], Vx (Fx55) - Store V0..Vx to memory starting at I.
-			// Check bounds before copy
-			if c.I+uint16(x) >= memorySize {
-				log.Printf("Memory out of bounds on LD [I], Vx (Fx55) at PC 0x%X. I=0x%X, x=%d", c.PC, c.I, x)
-			} else {
-				// copy(dst, src)
-				copy(c.memory[c.I:c.I+uint16(x)+1], c.V[:x+1])
-				if c.variantSCHIP {
-					c.I += uint16(x) + 1
-				}
+	case OpLDVxDT: // LD Vx, DT (Fx07)
+		c.V[x] = c.DT
+		c.PC += 2
+		return false, false
+	case OpLDVxK: // LD Vx, K (Fx0A)
+		c.waitingForKey = true
+		c.keyReg = byte(x)
+		// PC does NOT advance here.
+		return false, false // No redraw, Halted state determined by Cycle()
+	case OpLDDTVx: // LD DT, Vx (Fx15)
+		c.DT = c.V[x]
+		c.PC += 2
+		return false, false
+	case OpLDSTVx: // LD ST, Vx (Fx18, also accepted as Ex18) - Set sound timer = Vx.
+		c.ST = c.V[x]
+		c.PC += 2
+		return false, false
+	case OpADDIVx: // ADD I, Vx (Fx1E, also accepted as Ex1E)
+		// VF not affected
+		c.I += uint16(c.V[x])
+		c.PC += 2
+		return false, false
+	case OpLDFVx: // LD F, Vx (Fx29) - Set I = location of sprite for digit Vx.
+		digit := c.V[x] & 0x0F
+		c.I = uint16(fontOffset + (int(digit) * 5))
+		c.PC += 2
+		return false, false
+	case OpLDBVx: // LD B, Vx (Fx33) - Store BCD representation of Vx.
+		if int(c.I)+2 >= c.memSize() {
+			log.Printf("Memory out of bounds on LD B, Vx (Fx33) at PC 0x%X. I=0x%X", c.PC, c.I)
+		} else {
+			val := c.V[x]
+			c.memory[c.I] = val / 100
+			c.memory[c.I+1] = (val / 10) % 10
+			c.memory[c.I+2] = val % 10
+		}
+		c.PC += 2
+		return false, false
+	case OpLDIVx: // LD [I], Vx (Fx55) - Store V0..Vx to memory starting at I.
+		// Check bounds before copy
+		if int(c.I)+int(x) >= c.memSize() {
+			log.Printf("Memory out of bounds on LD [I], Vx (Fx55) at PC 0x%X. I=0x%X, x=%d", c.PC, c.I, x)
+		} else {
+			// copy(dst, src)
+			copy(c.memory[c.I:c.I+uint16(x)+1], c.V[:x+1])
+			if c.variantSCHIP {
+				c.I += uint16(x) + 1
 			}
-			c.PC += 2
-			return false, false
-		case 0x0065: // LD Vx, [I] (Fx65) - Read V0..Vx from memory starting at I.
-			// Check bounds before copy
-			if c.I+uint16(x) >= memorySize {
-				log.Printf("Memory out of bounds on LD Vx, [I] (Fx65) at PC 0x%X. I=0x%X, x=%d", c.PC, c.I, x)
-			} else {
-				// copy(dst, src)
-				copy(c.V[:x+1], c.memory[c.I:c.I+uint16(x)+1])
-				if c.variantSCHIP {
-					c.I += uint16(x) + 1
-				}
+		}
+		c.PC += 2
+		return false, false
+	case OpLDVxI: // LD Vx, [I] (Fx65) - Read V0..Vx from memory starting at I.
+		// Check bounds before copy
+		if int(c.I)+int(x) >= c.memSize() {
+			log.Printf("Memory out of bounds on LD Vx, [I] (Fx65) at PC 0x%X. I=0x%X, x=%d", c.PC, c.I, x)
+		} else {
+			// copy(dst, src)
+			copy(c.V[:x+1], c.memory[c.I:c.I+uint16(x)+1])
+			if c.variantSCHIP {
+				c.I += uint16(x) + 1
 			}
-			c.PC += 2
-			return false, false
-		default:
-			log.Printf("Unknown Fx opcode: 0x%X (PC: 0x%X)", opcode, c.PC)
-			c.PC += 2
-			return false, false // Return for default case too
 		}
-		// This final return is now unreachable if all cases return, which is good.
-		// return false, false
+		c.PC += 2
+		return false, false
 
-	case 0x3000: // SE Vx, byte (3xkk) - Skip next instruction if Vx = kk.
-		x := (opcode & 0x0F00) >> 8
-		kk := byte(opcode & 0x00FF)
+	case OpSEByte: // SE Vx, byte (3xkk) - Skip next instruction if Vx = kk.
 		if c.V[x] == kk {
-			c.PC += 2 // Skip additional 2 bytes
+			c.PC += c.skipLength() // Skip the next instruction
 		}
 		c.PC += 2 // Base increment
 		return false, false
-	case 0x4000: // SNE Vx, byte (4xkk) - Skip next instruction if Vx != kk.
-		x := (opcode & 0x0F00) >> 8
-		kk := byte(opcode & 0x00FF)
+	case OpSNEByte: // SNE Vx, byte (4xkk) - Skip next instruction if Vx != kk.
 		if c.V[x] != kk {
-			c.PC += 2 // Skip additional 2 bytes
+			c.PC += c.skipLength() // Skip the next instruction
 		}
 		c.PC += 2 // Base increment
 		return false, false
-	case 0x5000: // SE Vx, Vy (5xy0) - Skip next instruction if Vx = Vy.
-		// Ensure last nibble is 0 for this opcode
-		if opcode&0x000F != 0x0000 {
-			log.Printf("Unknown 5xxx opcode (last nibble not 0): 0x%X", opcode)
-			c.PC += 2
-			return false, false
-		}
-		x := (opcode & 0x0F00) >> 8
-		y := (opcode & 0x00F0) >> 4
+	case OpSEReg: // SE Vx, Vy (5xy0) - Skip next instruction if Vx = Vy.
 		if c.V[x] == c.V[y] {
-			c.PC += 2 // Skip additional 2 bytes
+			c.PC += c.skipLength() // Skip the next instruction
+		}
+		c.PC += 2 // Base increment
+		return false, false
+	case OpSNEReg: // SNE Vx, Vy (9xy0) - Skip next instruction if Vx != Vy.
+		if c.V[x] != c.V[y] {
+			c.PC += c.skipLength() // Skip the next instruction
 		}
 		c.PC += 2 // Base increment
 		return false, false
 
-	case 0xB000: // JP V0, addr (Bnnn) - Jump to location nnn + V0.
-		// Not specified in the original plan, but a common CHIP-8 instruction.
-		// Let's add it here for completeness, or defer if strictly following the plan.
-		// Assuming we add it:
-		addr := opcode & 0x0FFF
-		c.PC = addr + uint16(c.V[0])
-		return false, false
-	case 0xC000: // RND Vx, byte (Cxkk) - Set Vx = random byte AND kk.
-		x := (opcode & 0x0F00) >> 8
-		kk := byte(opcode & 0x00FF)
+	case OpJPV0: // JP V0, addr (Bnnn) - Jump to location nnn + V0.
+		c.PC = inst.NNN + uint16(c.V[0])
+		return false, false
+	case OpRND: // RND Vx, byte (Cxkk) - Set Vx = random byte AND kk.
 		randomByte := byte(c.rng.Intn(256)) // Generates random number in [0, 255]
 		c.V[x] = randomByte & kk
 		c.PC += 2
 		return false, false
 
-	default:
+	case OpUnknown:
 		log.Printf("Unknown opcode: 0x%X (PC: 0x%X)", opcode, c.PC)
 		c.PC += 2 // For unknown opcodes, just skip and continue
 		return false, false
+
+	default:
+		// SCHIP / XO-CHIP instructions that do not exist in plain CHIP-8
+		return c.executeExtendedOpcode(inst), false
+	}
+}
+
+// executeExtendedOpcode executes the SCHIP and XO-CHIP instructions that have no meaning in plain CHIP-8.
+// Decode only returns their Ops when the variant supports them.
+func (c *Chip8) executeExtendedOpcode(inst Instruction) (redraw bool) {
+	x := uint16(inst.X)
+	y := uint16(inst.Y)
+
+	switch inst.Op {
+	case OpSCD: // SCD nibble (00CN) - Scroll the display down N lines.
+		c.scroll(0, int(inst.N))
+	case OpSCU: // SCU nibble (00DN) - Scroll the display up N lines (XO-CHIP).
+		c.scroll(0, -int(inst.N))
+	case OpSCR: // SCR (00FB) - Scroll the display right 4 pixels.
+		c.scroll(4, 0)
+	case OpSCL: // SCL (00FC) - Scroll the display left 4 pixels.
+		c.scroll(-4, 0)
+	case OpEXIT: // EXIT (00FD) - Stop the interpreter. PC does not advance.
+		c.exited = true
+		return false
+	case OpLOW: // LOW (00FE) - Switch to 64x32 mode.
+		c.setHires(false)
+	case OpHIGH: // HIGH (00FF) - Switch to 128x64 mode.
+		c.setHires(true)
+
+	case OpSaveRange: // LD [I], Vx-Vy (5xy2) - Store Vx..Vy (in either order) at I. I is unchanged.
+		for i, r := range registerRange(x, y) {
+			if addr := int(c.I) + i; addr < c.memSize() {
+				c.memory[addr] = c.V[r]
+			}
+		}
+		c.PC += 2
+		return false
+	case OpLoadRange: // LD Vx-Vy, [I] (5xy3) - Load Vx..Vy (in either order) from I. I is unchanged.
+		for i, r := range registerRange(x, y) {
+			if addr := int(c.I) + i; addr < c.memSize() {
+				c.V[r] = c.memory[addr]
+			}
+		}
+		c.PC += 2
+		return false
+
+	case OpLDILong: // LD I, long addr (F000 NNNN) - Set I to the next 16-bit word.
+		if int(c.PC)+3 >= c.memSize() {
+			log.Printf("Memory out of bounds on LD I, long addr (F000) at PC 0x%X", c.PC)
+		} else {
+			c.I = uint16(c.memory[c.PC+2])<<8 | uint16(c.memory[c.PC+3])
+		}
+		c.PC += 4
+		return false
+	case OpPLANE: // PLANE n (Fn01) - Select the planes drawn by CLS, DRW and scrolling.
+		c.planes = byte(x) & 0x3
+		c.PC += 2
+		return false
+	case OpAUDIO: // AUDIO (F002) - Load the 16-byte audio pattern from I.
+		for i := range c.audioPattern {
+			if addr := int(c.I) + i; addr < c.memSize() {
+				c.audioPattern[i] = c.memory[addr]
+			}
+		}
+		c.PC += 2
+		return false
+	case OpPITCH: // PITCH Vx (Fx3A) - Set the audio playback rate.
+		c.pitch = c.V[x]
+		c.PC += 2
+		return false
+	case OpLDHFVx: // LD HF, Vx (Fx30) - Set I = location of the 8x10 big font sprite for digit Vx.
+		c.I = uint16(bigFontOffset + int(c.V[x]&0x0F)*10)
+		c.PC += 2
+		return false
+	case OpLDRVx: // LD R, Vx (Fx75) - Store V0..Vx in the RPL user flags.
+		copy(c.flags[:x+1], c.V[:x+1])
+		c.PC += 2
+		return false
+	case OpLDVxR: // LD Vx, R (Fx85) - Read V0..Vx from the RPL user flags.
+		copy(c.V[:x+1], c.flags[:x+1])
+		c.PC += 2
+		return false
+
+	default:
+		log.Printf("Unhandled instruction %v (0x%X) at PC 0x%X", inst, inst.Opcode, c.PC)
+		c.PC += 2
+		return false
+	}
+
+	// The display opcodes above fall through to here
+	c.PC += 2
+	return true
+}
+
+// registerRange returns the register indexes from x to y inclusive, descending when x > y (XO-CHIP 5xy2/5xy3).
+func registerRange(x, y uint16) []uint16 {
+	var regs []uint16
+	if x <= y {
+		for r := x; r <= y; r++ {
+			regs = append(regs, r)
+		}
+	} else {
+		for r := x; r >= y && r <= x; r-- { // r <= x stops the loop when r wraps below 0
+			regs = append(regs, r)
+		}
 	}
+	return regs
 }
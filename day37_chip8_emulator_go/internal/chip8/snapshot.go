@@ -0,0 +1,129 @@
+package chip8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// snapshotMagic identifies a save state file.
+var snapshotMagic = [4]byte{'C', 'H', '8', 'S'}
+
+// snapshotVersion is bumped whenever snapshotV1 changes layout; Restore rejects other versions.
+const snapshotVersion uint16 = 1
+
+// ErrInvalidSnapshot is returned by Restore for data that is not a save state of this version.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// snapshotV1 is the on-disk layout of a save state, encoded big-endian with encoding/binary.
+// Key states, the random number generator and cyclesPerFrame are not part of the state.
+type snapshotV1 struct {
+	Magic   [4]byte
+	Version uint16
+
+	VariantSCHIP  bool
+	VariantXOCHIP bool
+
+	Memory [xoMemorySize]byte
+	V      [numRegisters]byte
+	I      uint16
+	PC     uint16
+	Stack  [stackSize]uint16
+	SP     uint8
+	DT     byte
+	ST     byte
+
+	WaitingForKey bool
+	KeyReg        byte
+
+	Gfx          [gfxSize]byte
+	Hires        bool
+	Exited       bool
+	Flags        [numFlags]byte
+	Planes       byte
+	AudioPattern [audioPatternSize]byte
+	Pitch        byte
+}
+
+// Snapshot serializes the machine state (RAM, registers, timers, stack and screen) into a versioned binary format
+// that Restore can load.
+func (c *Chip8) Snapshot() []byte {
+	s := snapshotV1{
+		Magic:         snapshotMagic,
+		Version:       snapshotVersion,
+		VariantSCHIP:  c.variantSCHIP,
+		VariantXOCHIP: c.variantXOCHIP,
+		Memory:        c.memory,
+		V:             c.V,
+		I:             c.I,
+		PC:            c.PC,
+		Stack:         c.stack,
+		SP:            c.SP,
+		DT:            c.DT,
+		ST:            c.ST,
+		WaitingForKey: c.waitingForKey,
+		KeyReg:        c.keyReg,
+		Gfx:           c.gfx,
+		Hires:         c.hires,
+		Exited:        c.exited,
+		Flags:         c.flags,
+		Planes:        c.planes,
+		AudioPattern:  c.audioPattern,
+		Pitch:         c.pitch,
+	}
+	var buf bytes.Buffer
+	// Writing a fixed-size struct to a bytes.Buffer cannot fail
+	_ = binary.Write(&buf, binary.BigEndian, &s)
+	return buf.Bytes()
+}
+
+// Restore replaces the machine state with a state created by Snapshot.
+// The state must come from the same variant (CHIP-8, SCHIP or XO-CHIP), since the instructions and memory size differ.
+// On error the current state is left unchanged.
+func (c *Chip8) Restore(data []byte) error {
+	var s snapshotV1
+	if len(data) != binary.Size(&s) {
+		if len(data) >= 6 && bytes.Equal(data[:4], snapshotMagic[:]) {
+			if version := binary.BigEndian.Uint16(data[4:6]); version != snapshotVersion {
+				return fmt.Errorf("%w: unsupported version %d (want %d)", ErrInvalidSnapshot, version, snapshotVersion)
+			}
+		}
+		return fmt.Errorf("%w: unexpected size %d bytes", ErrInvalidSnapshot, len(data))
+	}
+	if err := binary.Read(bytes.NewReader(data), binary.BigEndian, &s); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if s.Magic != snapshotMagic {
+		return fmt.Errorf("%w: bad magic %q", ErrInvalidSnapshot, s.Magic[:])
+	}
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d (want %d)", ErrInvalidSnapshot, s.Version, snapshotVersion)
+	}
+	if s.VariantSCHIP != c.variantSCHIP || s.VariantXOCHIP != c.variantXOCHIP {
+		return fmt.Errorf("%w: saved with schip=%t xochip=%t, running with schip=%t xochip=%t",
+			ErrInvalidSnapshot, s.VariantSCHIP, s.VariantXOCHIP, c.variantSCHIP, c.variantXOCHIP)
+	}
+	if s.SP > stackSize || s.KeyReg >= numRegisters {
+		return fmt.Errorf("%w: corrupted registers (SP=%d, key register=%d)", ErrInvalidSnapshot, s.SP, s.KeyReg)
+	}
+
+	c.memory = s.Memory
+	c.V = s.V
+	c.I = s.I
+	c.PC = s.PC
+	c.stack = s.Stack
+	c.SP = s.SP
+	c.DT = s.DT
+	c.ST = s.ST
+	c.waitingForKey = s.WaitingForKey
+	c.keyReg = s.KeyReg
+	c.gfx = s.Gfx
+	c.hires = s.Hires
+	c.exited = s.Exited
+	c.flags = s.Flags
+	c.planes = s.Planes
+	c.audioPattern = s.AudioPattern
+	c.pitch = s.Pitch
+	return nil
+}
@@ -0,0 +1,101 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		c := NewWithSeed(10, true, 1)
+		c.memory[0x300] = 0xAB
+		c.V[3] = 0x42
+		c.I = 0x345
+		c.PC = 0x456
+		c.stack[0] = 0x222
+		c.SP = 1
+		c.DT = 30
+		c.ST = 5
+		c.gfx[10] = 1
+		c.hires = true
+		c.flags[2] = 7
+		data := c.Snapshot()
+
+		restored := NewWithSeed(10, true, 2)
+		if err := restored.Restore(data); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		if restored.memory != c.memory || restored.V != c.V || restored.stack != c.stack || restored.gfx != c.gfx {
+			t.Error("Memory, registers, stack or gfx differ after Restore")
+		}
+		if restored.I != 0x345 || restored.PC != 0x456 || restored.SP != 1 || restored.DT != 30 || restored.ST != 5 {
+			t.Errorf("Registers differ after Restore: I=0x%X PC=0x%X SP=%d DT=%d ST=%d",
+				restored.I, restored.PC, restored.SP, restored.DT, restored.ST)
+		}
+		if !restored.hires || restored.flags[2] != 7 {
+			t.Error("SCHIP state differs after Restore")
+		}
+	})
+
+	t.Run("Execution continues from the restored state", func(t *testing.T) {
+		c := NewWithSeed(10, false, 1)
+		c.memory[romOffset] = 0x70 // ADD V0, 1
+		c.memory[romOffset+1] = 0x01
+		c.memory[romOffset+2] = 0x12 // JP 0x200
+		c.memory[romOffset+3] = 0x00
+		data := c.Snapshot()
+		c.Cycle()
+		c.Cycle()
+		c.Cycle()
+		if err := c.Restore(data); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		if c.V[0] != 0 || c.PC != romOffset {
+			t.Errorf("Expected V0=0 PC=0x%X after Restore, got V0=%d PC=0x%X", romOffset, c.V[0], c.PC)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		mutate func(data []byte) []byte
+		target *Chip8
+	}{
+		{
+			name:   "Bad magic",
+			mutate: func(data []byte) []byte { data[0] = 'X'; return data },
+		},
+		{
+			name:   "Unsupported version",
+			mutate: func(data []byte) []byte { data[5] = 99; return data },
+		},
+		{
+			name:   "Truncated",
+			mutate: func(data []byte) []byte { return data[:len(data)-1] },
+		},
+		{
+			name:   "Different variant",
+			mutate: func(data []byte) []byte { return data },
+			target: NewWithSeed(10, true, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewWithSeed(10, false, 1)
+			c.V[0] = 0x11
+			data := tt.mutate(c.Snapshot())
+
+			target := tt.target
+			if target == nil {
+				target = NewWithSeed(10, false, 1)
+			}
+			target.V[0] = 0x22
+			err := target.Restore(data)
+			if !errors.Is(err, ErrInvalidSnapshot) {
+				t.Fatalf("Expected ErrInvalidSnapshot, got %v", err)
+			}
+			if target.V[0] != 0x22 {
+				t.Error("State changed despite Restore failing")
+			}
+		})
+	}
+}
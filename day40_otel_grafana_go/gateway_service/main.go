@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"html/template"
 	"io"
@@ -13,13 +14,21 @@ import (
 	"time"
 
 	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/httpclient"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/lifecycle"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/middleware"
 	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/observability"
 	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/otel"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/shoppb"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -32,24 +41,60 @@ const (
 	productServiceURL   = "http://localhost:8081/products"
 	inventoryServiceURL = "http://localhost:8082/inventory"
 	orderServiceURL     = "http://localhost:8083/orders"
+
+	// Request headers turned into OTel baggage, which every downstream service receives with the trace context
+	userIDHeader = "X-User-ID"
+	tenantHeader = "X-Tenant"
+
+	productServiceGRPCAddr   = "localhost:9081"
+	inventoryServiceGRPCAddr = "localhost:9082"
+	orderServiceGRPCAddr     = "localhost:9083"
 )
 
+var protocol = flag.String("protocol", "http", "protocol used to call the backend services: http or grpc (overridable per request with ?protocol=)")
+
 var (
-	httpClient *http.Client
-	tracer     oteltrace.Tracer
+	httpClient      *http.Client
+	tracer          oteltrace.Tracer
+	productClient   shoppb.ProductServiceClient
+	inventoryClient shoppb.InventoryServiceClient
+	orderClient     shoppb.OrderServiceClient
 )
 
 func main() {
+	flag.Parse()
+	if *protocol != "http" && *protocol != "grpc" {
+		log.Fatalf("unknown protocol %q: must be http or grpc", *protocol)
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	shutdownTracer, err := otel.InitTracerProvider(ctx, serviceName, serviceVersion, environment, otelEndpoint)
+	// Structured logs (with trace_id/span_id when a span is active), optionally exported via OTLP as well.
+	// Fatal startup errors still go to stderr through log.Fatalf.
+	shutdownLogger, err := observability.InitLoggerProvider(ctx, serviceName, serviceVersion, environment)
+	if err != nil {
+		log.Fatalf("failed to initialize logger provider: %v", err)
+	}
+	logger := observability.NewLogger("gateway_service")
+	defer func() {
+		if err := shutdownLogger(context.Background()); err != nil {
+			logger.Error("Failed to shutdown logger provider", "error", err)
+		}
+	}()
+
+	sampling, err := otel.SamplingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("invalid sampling config: %v", err)
+	}
+	shutdownTracer, err := otel.InitTracerProvider(ctx, serviceName, serviceVersion, environment, otelEndpoint, sampling)
 	if err != nil {
 		log.Fatalf("failed to initialize tracer provider: %v", err)
 	}
 	defer func() {
-		if err := shutdownTracer(ctx); err != nil {
-			log.Printf("failed to shutdown tracer provider: %v", err)
+		// ctx is already cancelled here; the spans were flushed by the shutdown coordinator
+		if err := shutdownTracer(context.Background()); err != nil {
+			logger.Error("Failed to shutdown tracer provider", "error", err)
 		}
 	}()
 	tracer = otel.GetTracer(serviceName)
@@ -59,10 +104,40 @@ func main() {
 		log.Fatalf("failed to initialize meter provider: %v", err)
 	}
 
-	httpClient = httpclient.NewTraceableClient()
+	// A slow backend times out after 1s so the client can retry within the 3s budget of callService;
+	// orders are POSTed, which is never retried
+	httpClient = httpclient.NewTraceableClient(httpclient.WithPerAttemptTimeout(1 * time.Second))
+
+	// gRPC clients; otelgrpc creates client spans and injects the trace context into the gRPC metadata
+	for _, backend := range []struct {
+		addr string
+		init func(grpc.ClientConnInterface)
+	}{
+		{productServiceGRPCAddr, func(cc grpc.ClientConnInterface) { productClient = shoppb.NewProductServiceClient(cc) }},
+		{inventoryServiceGRPCAddr, func(cc grpc.ClientConnInterface) { inventoryClient = shoppb.NewInventoryServiceClient(cc) }},
+		{orderServiceGRPCAddr, func(cc grpc.ClientConnInterface) { orderClient = shoppb.NewOrderServiceClient(cc) }},
+	} {
+		conn, err := grpc.NewClient(backend.addr,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		)
+		if err != nil {
+			log.Fatalf("failed to create gRPC client for %s: %v", backend.addr, err)
+		}
+		defer conn.Close()
+		backend.init(conn)
+	}
+
+	drainDelay, err := lifecycle.DrainDelayFromEnv()
+	if err != nil {
+		log.Fatalf("invalid drain delay: %v", err)
+	}
+	coordinator := lifecycle.New(drainDelay)
+
+	red := middleware.NewRED(serviceName)
 
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", middleware.MetricsHandler()) // OpenMetrics, so exemplars are exposed
 
 	uiTmpl := template.Must(template.New("ui").Parse(uiHTML))
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -70,38 +145,76 @@ func main() {
 			http.NotFound(w, r)
 			return
 		}
-		if err := uiTmpl.Execute(w, nil); err != nil {
-			log.Printf("Error executing UI template: %v", err)
+		if err := uiTmpl.Execute(w, struct{ Protocol string }{*protocol}); err != nil {
+			logger.ErrorContext(r.Context(), "Error executing UI template", "error", err)
 			http.Error(w, "Failed to render UI", http.StatusInternalServerError)
 		}
 	})
 
 	executeOrderHandler := http.HandlerFunc(handleExecuteOrder)
-	mux.Handle("/execute-order", otelhttp.NewHandler(executeOrderHandler, "ExecuteOrder"))
+	mux.Handle("/execute-order", otelhttp.NewHandler(red.Wrap("/execute-order", executeOrderHandler), "ExecuteOrder"))
 
 	otelHandler := otelhttp.NewHandler(mux, serviceName+"-server")
 
 	srv := &http.Server{
 		Addr:    serverPort,
-		Handler: otelHandler,
+		Handler: coordinator.Wrap(withBaggageFromHeaders(otelHandler)), // /healthz and /readyz are answered before tracing
 	}
+	coordinator.AddServer("HTTP", srv.Shutdown)
 
 	go func() {
-		log.Printf("Gateway service starting on port %s", serverPort)
+		logger.Info("Gateway service starting", "port", serverPort, "protocol", *protocol)
 		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
 			log.Fatalf("ListenAndServe(): %v", err)
 		}
 	}()
 
+	coordinator.MarkReady()
+
 	<-ctx.Done()
-	log.Println("Gateway service shutting down...")
+	logger.Info("Gateway service shutting down...")
 
-	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	// Readiness flips to "draining" first, then the server gets 5s to finish in-flight requests
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), drainDelay+5*time.Second)
 	defer cancelShutdown()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server Shutdown Failed:%+v", err)
+	if err := coordinator.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Shutdown failed", "error", err)
 	}
-	log.Println("Gateway service shutdown complete.")
+	logger.Info("Gateway service shutdown complete.")
+}
+
+// withBaggageFromHeaders puts user_id and tenant from the request headers into the baggage.
+// It wraps the otelhttp handler so the gateway's own server span gets them as attributes too.
+func withBaggageFromHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var members []baggage.Member
+		for key, header := range map[string]string{"user_id": userIDHeader, "tenant": tenantHeader} {
+			value := r.Header.Get(header)
+			if value == "" {
+				continue
+			}
+			m, err := baggage.NewMemberRaw(key, value)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid %s header: %v", header, err), http.StatusBadRequest)
+				return
+			}
+			members = append(members, m)
+		}
+		if len(members) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bag := baggage.FromContext(r.Context())
+		for _, m := range members {
+			var err error
+			if bag, err = bag.SetMember(m); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid baggage: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(baggage.ContextWithBaggage(r.Context(), bag)))
+	})
 }
 
 func handleExecuteOrder(w http.ResponseWriter, r *http.Request) {
@@ -110,35 +223,49 @@ func handleExecuteOrder(w http.ResponseWriter, r *http.Request) {
 	defer span.End()
 
 	scenario := r.URL.Query().Get("scenario")
-	span.SetAttributes(attribute.String("order.scenario", scenario))
+	protocolName := r.URL.Query().Get("protocol")
+	if protocolName == "" {
+		protocolName = *protocol
+	}
+	if protocolName != "http" && protocolName != "grpc" {
+		http.Error(w, fmt.Sprintf("Unknown protocol %q", protocolName), http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(attribute.String("order.scenario", scenario), attribute.String("order.protocol", protocolName))
 	// log.Printf("Executing order with scenario: %s\n", scenario)
-	logger.InfoContext(ctx, "Executing order", "scenario", scenario)
+	logger.InfoContext(ctx, "Executing order", "scenario", scenario, "protocol", protocolName)
 
 	var results strings.Builder
-	results.WriteString(fmt.Sprintf("<h2>Order Execution (Scenario: %s)</h2>", scenario))
+	results.WriteString(fmt.Sprintf("<h2>Order Execution (Scenario: %s, Protocol: %s)</h2>", scenario, protocolName))
 
-	productResp, err := callService(ctx, "product-service-call", productServiceURL+"?scenario="+scenario)
+	productResp, err := callBackend(ctx, protocolName, "product-service-call", http.MethodGet, productServiceURL+"?scenario="+scenario, func(ctx context.Context) (proto.Message, error) {
+		return productClient.GetProduct(ctx, &shoppb.GetProductRequest{ProductId: "prod123", Scenario: scenario})
+	})
 	if err != nil {
 		// results.WriteString(fmt.Sprintf("<p>Error calling Product Service: %v</p>", err))
-		logger.ErrorContext(ctx, "Error calling Product Service", "error", err, "url", productServiceURL+"?scenario="+scenario)
+		logger.ErrorContext(ctx, "Error calling Product Service", "error", err, "protocol", protocolName)
 		results.WriteString(fmt.Sprintf("<p>Error calling Product Service: %v</p>", err))
 		http.Error(w, results.String(), http.StatusInternalServerError)
 		return
 	}
 	results.WriteString(fmt.Sprintf("<p>Product Service: %s</p>", productResp))
 
-	inventoryResp, err := callService(ctx, "inventory-service-call", inventoryServiceURL+"?scenario="+scenario)
+	inventoryResp, err := callBackend(ctx, protocolName, "inventory-service-call", http.MethodGet, inventoryServiceURL+"?scenario="+scenario, func(ctx context.Context) (proto.Message, error) {
+		return inventoryClient.GetInventory(ctx, &shoppb.GetInventoryRequest{ProductId: "prod123", Scenario: scenario})
+	})
 	if err != nil {
-		logger.ErrorContext(ctx, "Error calling Inventory Service", "error", err, "url", inventoryServiceURL+"?scenario="+scenario)
+		logger.ErrorContext(ctx, "Error calling Inventory Service", "error", err, "protocol", protocolName)
 		results.WriteString(fmt.Sprintf("<p>Error calling Inventory Service: %v</p>", err))
 		http.Error(w, results.String(), http.StatusInternalServerError)
 		return
 	}
 	results.WriteString(fmt.Sprintf("<p>Inventory Service: %s</p>", inventoryResp))
 
-	orderResp, err := callService(ctx, "order-service-call", orderServiceURL+"?scenario="+scenario)
+	orderResp, err := callBackend(ctx, protocolName, "order-service-call", http.MethodPost, orderServiceURL+"?scenario="+scenario, func(ctx context.Context) (proto.Message, error) {
+		return orderClient.CreateOrder(ctx, &shoppb.CreateOrderRequest{ProductId: "prod123", Scenario: scenario})
+	})
 	if err != nil {
-		logger.ErrorContext(ctx, "Error calling Order Service", "error", err, "url", orderServiceURL+"?scenario="+scenario)
+		logger.ErrorContext(ctx, "Error calling Order Service", "error", err, "protocol", protocolName)
 		results.WriteString(fmt.Sprintf("<p>Error calling Order Service: %v</p>", err))
 		http.Error(w, results.String(), http.StatusInternalServerError)
 		return
@@ -146,18 +273,42 @@ func handleExecuteOrder(w http.ResponseWriter, r *http.Request) {
 	results.WriteString(fmt.Sprintf("<p>Order Service: %s</p>", orderResp))
 
 	results.WriteString("<p>Order processed successfully!</p>")
-	logger.InfoContext(ctx, "Order processed successfully", "scenario", scenario)
+	logger.InfoContext(ctx, "Order processed successfully", "scenario", scenario, "protocol", protocolName)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	fmt.Fprint(w, results.String())
 }
 
-func callService(ctx context.Context, spanName, url string) (string, error) {
+// callBackend calls a backend service over the selected protocol and returns the response as JSON text.
+func callBackend(ctx context.Context, protocolName, spanName, method, url string, grpcCall func(context.Context) (proto.Message, error)) (string, error) {
+	if protocolName == "grpc" {
+		return callGRPC(ctx, grpcCall)
+	}
+	return callService(ctx, spanName, method, url)
+}
+
+// callGRPC applies the same client-side timeout as callService; otelgrpc creates the client span.
+func callGRPC(ctx context.Context, call func(context.Context) (proto.Message, error)) (string, error) {
+	requestCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	resp, err := call(requestCtx)
+	if err != nil {
+		return "", fmt.Errorf("gRPC call failed: %w", err)
+	}
+	body, err := protojson.Marshal(resp)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode gRPC response: %w", err)
+	}
+	return string(body), nil
+}
+
+func callService(ctx context.Context, spanName, method, url string) (string, error) {
 	logger := observability.NewLogger("gateway_service")
 	// Use the context directly passed from the parent handler
 	ctxCall := ctx
 
 	// The transport will use this context
-	req, err := http.NewRequestWithContext(ctxCall, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctxCall, method, url, nil)
 	if err != nil {
 		// Error handling for request creation itself
 		return "", fmt.Errorf("failed to create request to %s: %w", url, err)
@@ -233,12 +384,24 @@ const uiHTML = `
 <body>
     <div class="container">
         <h1>Gateway Service UI</h1>
+        <div class="button-group">
+            <h2>Backend Protocol:</h2>
+            <label><input type="radio" name="protocol" value="http" {{if eq .Protocol "http"}}checked{{end}}> HTTP</label>
+            <label><input type="radio" name="protocol" value="grpc" {{if eq .Protocol "grpc"}}checked{{end}}> gRPC</label>
+        </div>
+        <div class="button-group">
+            <h2>Business Context (OTel Baggage):</h2>
+            <label>User ID <input type="text" id="userId" value="user-42"></label>
+            <label>Tenant <input type="text" id="tenant" value="acme"></label>
+        </div>
         <div class="button-group">
             <h2>Test Scenarios:</h2>
             <button class="btn-normal" onclick="executeOrder('normal')">Execute Normal Order</button>
             <button class="btn-error" onclick="executeOrder('product_error')">Simulate Product Service Error</button>
             <button class="btn-timeout" onclick="executeOrder('inventory_timeout')">Simulate Inventory Timeout</button>
             <button class="btn-normal" onclick="executeOrder('long_request')">Simulate Long Processing</button>
+            <button class="btn-timeout" onclick="executeOrder('slow_query')">Simulate Slow DB Query</button>
+            <button class="btn-error" onclick="executeOrder('chaos')">Inject Random Chaos</button>
         </div>
         <div id="response">
             <p>Click a button to execute an order scenario.</p>
@@ -249,7 +412,13 @@ const uiHTML = `
             const responseDiv = document.getElementById('response');
             responseDiv.innerHTML = '<p>Processing...</p>';
             try {
-                const response = await fetch('/execute-order?scenario=' + scenario);
+                const protocol = document.querySelector('input[name="protocol"]:checked').value;
+                const headers = {};
+                const userId = document.getElementById('userId').value;
+                const tenant = document.getElementById('tenant').value;
+                if (userId) headers['X-User-ID'] = userId;
+                if (tenant) headers['X-Tenant'] = tenant;
+                const response = await fetch('/execute-order?scenario=' + scenario + '&protocol=' + protocol, { headers });
                 const data = await response.text();
                 if (!response.ok) {
                   responseDiv.innerHTML = '<h2>Error:</h2>' + data;
@@ -7,19 +7,24 @@ import (
 )
 
 // NewTraceableClient creates a new http.Client that is instrumented with OpenTelemetry.
-func NewTraceableClient() *http.Client {
-	return &http.Client{
-		Transport: otelhttp.NewTransport(http.DefaultTransport),
-	}
+// Requests are retried and guarded by a per-host circuit breaker as configured by opts (see DefaultConfig).
+func NewTraceableClient(opts ...Option) *http.Client {
+	return NewTraceableClientWithTransport(http.DefaultTransport, opts...)
 }
 
 // NewTraceableClientWithTransport creates a new http.Client with a custom underlying transport,
 // instrumented with OpenTelemetry.
-func NewTraceableClientWithTransport(transport http.RoundTripper) *http.Client {
+func NewTraceableClientWithTransport(transport http.RoundTripper, opts ...Option) *http.Client {
 	if transport == nil {
 		transport = http.DefaultTransport
 	}
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	// The resilient transport sits outside otelhttp, so every attempt gets its own client span
+	// and the retry/breaker events are recorded on the caller's span.
 	return &http.Client{
-		Transport: otelhttp.NewTransport(transport),
+		Transport: newResilientTransport(otelhttp.NewTransport(transport), cfg),
 	}
 }
@@ -0,0 +1,279 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit breaker of the host is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Config controls retries and the circuit breaker.
+type Config struct {
+	// MaxRetries is the number of retries after the first attempt. Only idempotent methods are retried.
+	MaxRetries int
+	// InitialBackoff is doubled after each retry (with jitter) up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// PerAttemptTimeout bounds every attempt but the last, so that a slow upstream leaves room for a retry (0 = no limit).
+	// The last attempt, and requests that are not retried at all, are only bounded by the request context.
+	PerAttemptTimeout time.Duration
+
+	// FailureThreshold is the number of consecutive failures that opens the breaker of a host (0 disables the breaker).
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before letting a trial request through (half-open).
+	OpenTimeout time.Duration
+}
+
+// DefaultConfig retries twice and opens the breaker after 5 consecutive failures for 10 seconds.
+func DefaultConfig() Config {
+	return Config{
+		MaxRetries:       2,
+		InitialBackoff:   100 * time.Millisecond,
+		MaxBackoff:       1 * time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      10 * time.Second,
+	}
+}
+
+// Option modifies the Config used by NewTraceableClient.
+type Option func(*Config)
+
+// WithRetries sets the number of retries and the backoff range. maxRetries 0 disables retries.
+func WithRetries(maxRetries int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(c *Config) {
+		c.MaxRetries = maxRetries
+		c.InitialBackoff = initialBackoff
+		c.MaxBackoff = maxBackoff
+	}
+}
+
+// WithPerAttemptTimeout bounds each attempt.
+func WithPerAttemptTimeout(d time.Duration) Option {
+	return func(c *Config) { c.PerAttemptTimeout = d }
+}
+
+// WithCircuitBreaker sets the breaker thresholds. failureThreshold 0 disables the breaker.
+func WithCircuitBreaker(failureThreshold int, openTimeout time.Duration) Option {
+	return func(c *Config) {
+		c.FailureThreshold = failureThreshold
+		c.OpenTimeout = openTimeout
+	}
+}
+
+// resilientTransport retries failed attempts and keeps a circuit breaker per host.
+type resilientTransport struct {
+	next http.RoundTripper
+	cfg  Config
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker // Keyed by host:port
+}
+
+func newResilientTransport(next http.RoundTripper, cfg Config) *resilientTransport {
+	return &resilientTransport{next: next, cfg: cfg, breakers: make(map[string]*circuitBreaker)}
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	span := trace.SpanFromContext(ctx)
+	breaker := t.breaker(req.URL.Host)
+
+	maxAttempts := 1
+	if isIdempotent(req) {
+		maxAttempts += t.cfg.MaxRetries
+	}
+	backoff := t.cfg.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		if !breaker.allow(ctx, span) {
+			span.AddEvent("circuit_breaker.rejected", trace.WithAttributes(attribute.String("server.address", req.URL.Host)))
+			return nil, fmt.Errorf("%s: %w", req.URL.Host, ErrCircuitOpen)
+		}
+
+		resp, err := t.attempt(req, attempt, attempt < maxAttempts)
+		failed := err != nil || isRetryableStatus(resp.StatusCode)
+		breaker.record(span, !failed)
+		if !failed || attempt >= maxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		// Drop the failed response before retrying so the connection can be reused
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		wait := jitter(backoff)
+		span.AddEvent("http.retry", trace.WithAttributes(
+			attribute.Int("http.attempt", attempt),
+			attribute.String("http.retry.reason", reason),
+			attribute.Int64("http.retry.backoff_ms", wait.Milliseconds()),
+		))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff = min(backoff*2, t.cfg.MaxBackoff)
+	}
+}
+
+// attempt sends one copy of req, bounded by PerAttemptTimeout if another attempt may follow.
+func (t *resilientTransport) attempt(req *http.Request, attempt int, bounded bool) (*http.Response, error) {
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	if !bounded || t.cfg.PerAttemptTimeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.cfg.PerAttemptTimeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The body is read after RoundTrip returns, so the attempt context lives until it is closed
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *resilientTransport) breaker(host string) *circuitBreaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &circuitBreaker{host: host, threshold: t.cfg.FailureThreshold, openTimeout: t.cfg.OpenTimeout, state: stateClosed}
+		t.breakers[host] = b
+	}
+	return b
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// jitter returns a random duration in [d/2, d) to avoid synchronized retries.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+type breakerState string
+
+const (
+	stateClosed   breakerState = "closed"
+	stateOpen     breakerState = "open"
+	stateHalfOpen breakerState = "half-open"
+)
+
+// circuitBreaker opens after threshold consecutive failures, rejects requests for openTimeout,
+// then lets a single trial request through: success closes it again, failure reopens it.
+type circuitBreaker struct {
+	host        string
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	trial    bool // A half-open trial request is in flight
+}
+
+func (b *circuitBreaker) allow(ctx context.Context, span trace.Span) bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.transition(span, stateHalfOpen)
+		b.trial = true
+		return true
+	case stateHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) record(span trace.Span, success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if success {
+		b.failures = 0
+		if b.state != stateClosed {
+			b.transition(span, stateClosed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == stateHalfOpen || (b.state != stateOpen && b.failures >= b.threshold) {
+		b.openedAt = time.Now()
+		b.transition(span, stateOpen)
+	}
+}
+
+// transition must be called with b.mu held.
+func (b *circuitBreaker) transition(span trace.Span, to breakerState) {
+	from := b.state
+	b.state = to
+	span.AddEvent("circuit_breaker.state_change", trace.WithAttributes(
+		attribute.String("server.address", b.host),
+		attribute.String("circuit_breaker.from", string(from)),
+		attribute.String("circuit_breaker.to", string(to)),
+		attribute.Int("circuit_breaker.consecutive_failures", b.failures),
+	))
+}
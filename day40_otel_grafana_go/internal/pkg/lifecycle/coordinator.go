@@ -0,0 +1,222 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// EnvDrainDelay overrides DefaultDrainDelay.
+const EnvDrainDelay = "SHUTDOWN_DRAIN_DELAY"
+
+// DefaultDrainDelay is how long /readyz reports "draining" before the servers stop accepting connections,
+// long enough for a load balancer polling /readyz to take the instance out of rotation.
+const DefaultDrainDelay = 1 * time.Second
+
+// Probe paths served by Coordinator.Wrap.
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+)
+
+// DrainDelayFromEnv reads EnvDrainDelay, falling back to DefaultDrainDelay.
+func DrainDelayFromEnv() (time.Duration, error) {
+	v := os.Getenv(EnvDrainDelay)
+	if v == "" {
+		return DefaultDrainDelay, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", EnvDrainDelay, v, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must not be negative", EnvDrainDelay, v)
+	}
+	return d, nil
+}
+
+type namedFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Coordinator reports the health and readiness of a service and shuts it down in a fixed order:
+//
+//  1. /readyz starts returning 503 so no new traffic is routed to the instance
+//  2. after the drain delay, the servers stop accepting connections and wait for in-flight requests
+//  3. the spans of those requests are flushed to the exporter
+//  4. the hooks registered with OnShutdown run
+type Coordinator struct {
+	drainDelay time.Duration
+
+	started  atomic.Bool
+	draining atomic.Bool
+
+	mu       sync.Mutex
+	checks   []namedFunc
+	servers  []namedFunc
+	hooks    []namedFunc
+	shutdown sync.Once
+	err      error
+}
+
+// New creates a Coordinator that is not ready until MarkReady is called.
+func New(drainDelay time.Duration) *Coordinator {
+	return &Coordinator{drainDelay: drainDelay}
+}
+
+// AddReadinessCheck adds a dependency check to /readyz. The instance is not ready while check returns an error.
+func (c *Coordinator) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedFunc{name, check})
+}
+
+// AddServer registers a server to stop in step 2 of Shutdown, e.g. (*http.Server).Shutdown or GRPCStop.
+// Servers are stopped in the order they were added.
+func (c *Coordinator) AddServer(name string, stop func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.servers = append(c.servers, namedFunc{name, stop})
+}
+
+// OnShutdown registers cleanup that runs after the servers are stopped and the spans are flushed.
+// Hooks run in the order they were registered.
+func (c *Coordinator) OnShutdown(name string, hook func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, namedFunc{name, hook})
+}
+
+// MarkReady makes /readyz succeed once the servers are started.
+func (c *Coordinator) MarkReady() {
+	c.started.Store(true)
+}
+
+// Ready reports whether the instance should receive traffic, and why not.
+func (c *Coordinator) Ready(ctx context.Context) (bool, string) {
+	switch {
+	case c.draining.Load():
+		return false, "draining"
+	case !c.started.Load():
+		return false, "starting"
+	}
+	c.mu.Lock()
+	checks := append([]namedFunc(nil), c.checks...)
+	c.mu.Unlock()
+	for _, check := range checks {
+		if err := check.fn(ctx); err != nil {
+			return false, fmt.Sprintf("%s: %v", check.name, err)
+		}
+	}
+	return true, "ok"
+}
+
+// Wrap serves /healthz and /readyz in front of next. The probes bypass next, so they are neither traced nor
+// counted in the RED metrics.
+func (c *Coordinator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case HealthzPath:
+			// The process is alive as long as it answers, including while draining
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintln(w, "ok")
+		case ReadyzPath:
+			ready, reason := c.Ready(r.Context())
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if !ready {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			fmt.Fprintln(w, reason)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Shutdown runs the shutdown sequence described on Coordinator and returns the errors of every step.
+// ctx bounds the whole sequence including the drain delay, so its deadline should leave time for the servers
+// to finish. Calling Shutdown more than once returns the result of the first call.
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	c.shutdown.Do(func() {
+		c.err = c.runShutdown(ctx)
+	})
+	return c.err
+}
+
+func (c *Coordinator) runShutdown(ctx context.Context) error {
+	c.draining.Store(true)
+
+	timer := time.NewTimer(c.drainDelay)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+
+	c.mu.Lock()
+	servers := append([]namedFunc(nil), c.servers...)
+	hooks := append([]namedFunc(nil), c.hooks...)
+	c.mu.Unlock()
+
+	var errs []error
+	for _, s := range servers {
+		if err := s.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s server: %w", s.name, err))
+		}
+	}
+	if err := flushSpans(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to flush spans: %w", err))
+	}
+	for _, h := range hooks {
+		if err := h.fn(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushSpans exports the spans still buffered in the global tracer provider, i.e. those of the requests that
+// were in flight when the shutdown started.
+func flushSpans(ctx context.Context) error {
+	tp, ok := otel.GetTracerProvider().(interface {
+		ForceFlush(ctx context.Context) error
+	})
+	if !ok {
+		return nil // Tracing is not set up (no-op provider)
+	}
+	return tp.ForceFlush(ctx)
+}
+
+// GracefulStopper is implemented by *grpc.Server.
+type GracefulStopper interface {
+	GracefulStop()
+	Stop()
+}
+
+// GRPCStop adapts a gRPC server to AddServer: it waits for in-flight RPCs like GracefulStop, but cancels them
+// with Stop when ctx expires.
+func GRPCStop(s GracefulStopper) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			s.Stop()
+			<-done
+			return ctx.Err()
+		}
+	}
+}
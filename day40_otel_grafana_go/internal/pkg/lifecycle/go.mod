@@ -0,0 +1,13 @@
+module github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/lifecycle
+
+go 1.24.2
+
+require go.opentelemetry.io/otel v1.35.0
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.35.0 // indirect
+	go.opentelemetry.io/otel/trace v1.35.0 // indirect
+)
@@ -0,0 +1,83 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/messaging"
+
+// OrderCreatedSubject is the subject order-service publishes new orders to.
+const OrderCreatedSubject = "orders.created"
+
+// headerCarrier adapts nats.Header to propagation.TextMapCarrier.
+// nats.Header has the same shape as http.Header, so propagation.HeaderCarrier does the work.
+func headerCarrier(h nats.Header) propagation.HeaderCarrier {
+	return propagation.HeaderCarrier(h)
+}
+
+// Publish sends data to subject inside a PRODUCER span and injects the trace context into the message headers,
+// so the consumer's span joins the same trace.
+func Publish(ctx context.Context, nc *nats.Conn, subject string, data []byte) error {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, subject+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem("nats"),
+			semconv.MessagingDestinationName(subject),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingMessagePayloadSizeBytes(len(data)),
+		),
+	)
+	defer span.End()
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(msg.Header))
+
+	if err := nc.PublishMsg(msg); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to publish to %s: %w", subject, err)
+	}
+	return nil
+}
+
+// Handler processes a received message. ctx carries the CONSUMER span of the message.
+type Handler func(ctx context.Context, msg *nats.Msg) error
+
+// Subscribe receives messages on subject and calls handler for each of them inside a CONSUMER span whose parent
+// is the producer span extracted from the message headers.
+func Subscribe(nc *nats.Conn, subject string, handler Handler) (*nats.Subscription, error) {
+	tracer := otel.Tracer(instrumentationName)
+	sub, err := nc.Subscribe(subject, func(msg *nats.Msg) {
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), headerCarrier(msg.Header))
+		ctx, span := tracer.Start(ctx, msg.Subject+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				semconv.MessagingSystem("nats"),
+				semconv.MessagingDestinationName(msg.Subject),
+				semconv.MessagingOperationProcess,
+				semconv.MessagingMessagePayloadSizeBytes(len(msg.Data)),
+				attribute.String("messaging.nats.subscription", subject),
+			),
+		)
+		defer span.End()
+
+		if err := handler(ctx, msg); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	return sub, nil
+}
@@ -0,0 +1,20 @@
+module github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/middleware
+
+go 1.24.2
+
+require (
+	github.com/prometheus/client_golang v1.22.0
+	go.opentelemetry.io/otel/trace v1.35.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.62.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	go.opentelemetry.io/otel v1.35.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	google.golang.org/protobuf v1.36.5 // indirect
+)
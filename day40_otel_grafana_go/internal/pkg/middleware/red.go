@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exemplarTraceIDLabel matches exemplarTraceIdDestinations in grafana/provisioning/datasources/datasources.yml.
+const exemplarTraceIDLabel = "traceID"
+
+// RED records Rate, Errors and Duration metrics per route.
+// Duration observations and error increments carry the trace ID of the request as an exemplar,
+// so a Grafana panel can jump from a latency bucket to the trace in Tempo.
+type RED struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewRED creates the RED metrics of serviceName and registers them with the default Prometheus registry.
+func NewRED(serviceName string) *RED {
+	constLabels := prometheus.Labels{"service": serviceName}
+	m := &RED{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "http_requests_total",
+			Help:        "Number of HTTP requests handled, by route, method and status code.",
+			ConstLabels: constLabels,
+		}, []string{"route", "method", "code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "http_request_errors_total",
+			Help:        "Number of HTTP requests that ended with a 5xx status code.",
+			ConstLabels: constLabels,
+		}, []string{"route", "method"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "http_request_duration_seconds",
+			Help:        "Duration of HTTP requests, by route and method.",
+			ConstLabels: constLabels,
+			Buckets:     []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		}, []string{"route", "method"}),
+	}
+	prometheus.MustRegister(m.requests, m.errors, m.duration)
+	return m
+}
+
+// Wrap records the metrics of next under route.
+// It must run inside the otelhttp handler so that the request context already carries the span.
+func (m *RED) Wrap(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start).Seconds()
+
+		exemplar := traceExemplar(r)
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		if rec.status >= 500 {
+			addWithExemplar(m.errors.WithLabelValues(route, r.Method), exemplar)
+		}
+		observeWithExemplar(m.duration.WithLabelValues(route, r.Method), elapsed, exemplar)
+	})
+}
+
+// MetricsHandler serves the default registry in the OpenMetrics format when the scraper asks for it,
+// which is the only format that carries exemplars.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// traceExemplar returns the exemplar labels of a sampled request, or nil if the trace is not recorded.
+func traceExemplar(r *http.Request) prometheus.Labels {
+	sc := trace.SpanContextFromContext(r.Context())
+	if !sc.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{exemplarTraceIDLabel: sc.TraceID().String()}
+}
+
+func observeWithExemplar(o prometheus.Observer, v float64, exemplar prometheus.Labels) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(v, exemplar)
+		return
+	}
+	o.Observe(v)
+}
+
+func addWithExemplar(c prometheus.Counter, exemplar prometheus.Labels) {
+	if ea, ok := c.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		ea.AddWithExemplar(1, exemplar)
+		return
+	}
+	c.Inc()
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,111 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// OTLP ログエクスポートを制御する環境変数です。
+const (
+	EnvLogsExporter     = "OTEL_LOGS_EXPORTER" // "otlp" で OTLP エクスポートを有効化
+	EnvOTLPLogsEndpoint = "OTLP_LOGS_ENDPOINT" // host:port (デフォルトは Collector の localhost:4319)
+
+	defaultOTLPLogsEndpoint = "localhost:4319"
+)
+
+// InitLoggerProvider は OTEL_LOGS_EXPORTER=otlp の場合に OTLP ログエクスポーターを設定します。
+// 設定後は NewLogger で作ったロガーの出力がファイルに加えて OTLP でも送信されます (trace_id/span_id はログレコードに紐付きます)。
+// 無効な場合は何もしない shutdown 関数を返します。
+func InitLoggerProvider(ctx context.Context, serviceName, serviceVersion, environment string) (func(context.Context) error, error) {
+	if os.Getenv(EnvLogsExporter) != "otlp" {
+		return func(context.Context) error { return nil }, nil
+	}
+	endpoint := defaultOTLPLogsEndpoint
+	if v := os.Getenv(EnvOTLPLogsEndpoint); v != "" {
+		endpoint = v
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceVersionKey.String(serviceVersion),
+			semconv.DeploymentEnvironmentKey.String(environment),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	exporter, err := otlploggrpc.New(ctx, otlploggrpc.WithEndpoint(endpoint), otlploggrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP log exporter: %w", err)
+	}
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+	)
+	global.SetLoggerProvider(lp)
+
+	return func(ctx context.Context) error {
+		if err := lp.Shutdown(ctx); err != nil {
+			return fmt.Errorf("failed to shutdown LoggerProvider: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// newOTLPHandler はグローバルな LoggerProvider に書き込む slog ハンドラを返します。
+// InitLoggerProvider が呼ばれていない間は何も送信しません。
+func newOTLPHandler(serviceName string) slog.Handler {
+	return otelslog.NewHandler(serviceName)
+}
+
+// fanoutHandler は同じレコードを複数のハンドラに書き込みます。
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, handler := range h {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"sync"
 
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -28,13 +30,39 @@ func (h *OtelSlogHandler) Handle(ctx context.Context, r slog.Record) error {
 			slog.String("span_id", span.SpanContext().SpanID().String()),
 		)
 	}
+	// ゲートウェイが設定したバゲージ (user_id, tenant など) をログにも付与
+	for _, m := range baggage.FromContext(ctx).Members() {
+		r.AddAttrs(slog.String(m.Key(), m.Value()))
+	}
 	return h.Handler.Handle(ctx, r)
 }
 
-// NewLogger は、OtelSlogHandler を含む新しい slog.Logger を返します。
+// WithAttrs と WithGroup は埋め込みハンドラの結果を再度ラップし、logger.With(...) で作ったロガーでも trace_id などが付与されるようにします。
+func (h *OtelSlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &OtelSlogHandler{Handler: h.Handler.WithAttrs(attrs), serviceName: h.serviceName}
+}
+
+func (h *OtelSlogHandler) WithGroup(name string) slog.Handler {
+	return &OtelSlogHandler{Handler: h.Handler.WithGroup(name), serviceName: h.serviceName}
+}
+
+var (
+	loggersMu sync.Mutex
+	loggers   = make(map[string]*slog.Logger) // サービス名ごとにログファイルを1度だけ開く
+)
+
+// NewLogger は、OtelSlogHandler を含む slog.Logger を返します。
 // サービス名を元にファイルにログをJSON形式で書き出します。
 // ファイルオープンに失敗した場合は標準出力にフォールバックします。
+// InitLoggerProvider で OTLP エクスポートが有効な場合は、同じログを OTLP でも送信します。
+// 同じサービス名では同じロガーを返します。
 func NewLogger(serviceName string) *slog.Logger {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+	if logger, ok := loggers[serviceName]; ok {
+		return logger
+	}
+
 	logFilePath := fmt.Sprintf("/tmp/go_app_%s.log", serviceName)
 	logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
@@ -49,5 +77,7 @@ func NewLogger(serviceName string) *slog.Logger {
 	})
 	// NewOtelSlogHandler に serviceName を渡す
 	otelHandler := NewOtelSlogHandler(jsonHandler, serviceName)
-	return slog.New(otelHandler)
+	logger := slog.New(fanoutHandler{otelHandler, newOTLPHandler(serviceName)})
+	loggers[serviceName] = logger
+	return logger
 }
@@ -0,0 +1,23 @@
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// baggageSpanProcessor copies the baggage members of the parent context (e.g. user_id, tenant set by the gateway)
+// onto every span as attributes, so business context is searchable in Tempo without instrumenting each handler.
+type baggageSpanProcessor struct{}
+
+func (baggageSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, m := range baggage.FromContext(parent).Members() {
+		s.SetAttributes(attribute.String(m.Key(), m.Value()))
+	}
+}
+
+func (baggageSpanProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (baggageSpanProcessor) Shutdown(context.Context) error   { return nil }
+func (baggageSpanProcessor) ForceFlush(context.Context) error { return nil }
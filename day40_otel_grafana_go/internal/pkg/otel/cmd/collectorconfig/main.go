@@ -0,0 +1,36 @@
+// Command collectorconfig generates the OpenTelemetry Collector configuration for tail sampling
+// from the same environment variables the services read (TAIL_SAMPLING_LATENCY_THRESHOLD, TAIL_SAMPLING_BASELINE_RATIO).
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/otel"
+)
+
+func main() {
+	output := flag.String("o", "otel-collector.yaml", "output file")
+	flag.Parse()
+
+	cfg, err := otel.SamplingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("invalid sampling config: %v", err)
+	}
+	if cfg.TailLatencyThreshold == 0 {
+		cfg.TailLatencyThreshold = 500 * time.Millisecond
+		log.Printf("%s is not set, using %s", otel.EnvTailSamplingLatency, cfg.TailLatencyThreshold)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("failed to create %s: %v", *output, err)
+	}
+	defer f.Close()
+	if err := otel.WriteCollectorConfig(f, cfg); err != nil {
+		log.Fatalf("failed to write collector config: %v", err)
+	}
+	log.Printf("wrote %s (latency threshold %s, baseline ratio %v)", *output, cfg.TailLatencyThreshold, cfg.TailBaselineRatio)
+}
@@ -0,0 +1,72 @@
+package otel
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// collectorConfigTemplate is an OpenTelemetry Collector pipeline that receives OTLP from the services,
+// applies tail sampling and forwards the kept traces to Tempo. Logs exported via OTLP
+// (OTEL_LOGS_EXPORTER=otlp) are forwarded to Loki.
+var collectorConfigTemplate = template.Must(template.New("collector").Parse(`# Generated by internal/pkg/otel/cmd/collectorconfig. DO NOT EDIT; run "make collector-config" instead.
+receivers:
+  otlp:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+
+processors:
+  tail_sampling:
+    decision_wait: 10s
+    policies:
+      # Keep every trace that contains an error
+      - name: errors
+        type: status_code
+        status_code:
+          status_codes: [ERROR]
+      # Keep every trace slower than the threshold
+      - name: slow-traces
+        type: latency
+        latency:
+          threshold_ms: {{.ThresholdMillis}}
+      # Keep a sample of the rest
+      - name: baseline
+        type: probabilistic
+        probabilistic:
+          sampling_percentage: {{.BaselinePercent}}
+  batch: {}
+
+exporters:
+  otlp/tempo:
+    endpoint: tempo:4317
+    tls:
+      insecure: true
+  loki:
+    endpoint: http://loki:3100/loki/api/v1/push
+
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      processors: [tail_sampling, batch]
+      exporters: [otlp/tempo]
+    logs:
+      receivers: [otlp]
+      processors: [batch]
+      exporters: [loki]
+`))
+
+// WriteCollectorConfig writes the Collector configuration implementing the tail sampling part of c.
+func WriteCollectorConfig(w io.Writer, c SamplingConfig) error {
+	if c.TailLatencyThreshold <= 0 {
+		return fmt.Errorf("tail sampling latency threshold is not set (%s)", EnvTailSamplingLatency)
+	}
+	return collectorConfigTemplate.Execute(w, struct {
+		ThresholdMillis int64
+		BaselinePercent float64
+	}{
+		ThresholdMillis: c.TailLatencyThreshold.Milliseconds(),
+		BaselinePercent: c.TailBaselineRatio * 100,
+	})
+}
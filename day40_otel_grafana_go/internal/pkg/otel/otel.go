@@ -3,6 +3,7 @@ package otel
 import (
 	"context"
 	"fmt"
+	"os"
 
 	// "time"
 
@@ -26,8 +27,21 @@ var (
 	globalTracerProvider *sdktrace.TracerProvider
 )
 
+// EnvOTLPEndpoint overrides the otlpEndpoint (host:port) passed to InitTracerProvider,
+// e.g. to send spans through the tail sampling collector instead of straight to Tempo.
+const EnvOTLPEndpoint = "OTLP_ENDPOINT"
+
 // InitTracerProvider initializes an OTLP exporter, and configures the corresponding trace provider.
-func InitTracerProvider(ctx context.Context, serviceName, serviceVersion, environment, otlpEndpoint string) (func(context.Context) error, error) {
+// sampling selects the head sampler (see SamplingConfigFromEnv).
+func InitTracerProvider(ctx context.Context, serviceName, serviceVersion, environment, otlpEndpoint string, sampling SamplingConfig) (func(context.Context) error, error) {
+	sampler, err := sampling.headSampler()
+	if err != nil {
+		return nil, fmt.Errorf("invalid sampling config: %w", err)
+	}
+	if v := os.Getenv(EnvOTLPEndpoint); v != "" {
+		otlpEndpoint = v
+	}
+
 	res, err := resource.New(ctx,
 		resource.WithAttributes(
 			semconv.ServiceNameKey.String(serviceName),
@@ -53,8 +67,9 @@ func InitTracerProvider(ctx context.Context, serviceName, serviceVersion, enviro
 
 	bsp := sdktrace.NewBatchSpanProcessor(traceExporter)
 	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(sampler),
 		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(baggageSpanProcessor{}),
 		sdktrace.WithSpanProcessor(bsp),
 	)
 	otel.SetTracerProvider(tp)
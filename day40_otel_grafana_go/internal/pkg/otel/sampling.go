@@ -0,0 +1,114 @@
+package otel
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Environment variables read by SamplingConfigFromEnv.
+// The first two follow the OpenTelemetry SDK specification; the tail sampling ones are only used to generate
+// the collector configuration (see cmd/collectorconfig).
+const (
+	EnvTracesSampler            = "OTEL_TRACES_SAMPLER"
+	EnvTracesSamplerArg         = "OTEL_TRACES_SAMPLER_ARG"
+	EnvTailSamplingLatency      = "TAIL_SAMPLING_LATENCY_THRESHOLD"
+	EnvTailSamplingBaselineRate = "TAIL_SAMPLING_BASELINE_RATIO"
+)
+
+// Head sampler names, as defined for OTEL_TRACES_SAMPLER.
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// SamplingConfig selects how traces are sampled.
+// Sampler and Ratio configure head sampling in the SDK. TailLatencyThreshold and TailBaselineRatio configure
+// tail sampling in the OpenTelemetry Collector, which needs the SDK to send every span (always_on).
+type SamplingConfig struct {
+	Sampler string
+	Ratio   float64
+
+	TailLatencyThreshold time.Duration // Keep every trace slower than this (0 disables tail sampling)
+	TailBaselineRatio    float64       // Fraction of the remaining (fast, successful) traces to keep
+}
+
+// DefaultSamplingConfig samples every trace, which is what the demo did before sampling became configurable.
+func DefaultSamplingConfig() SamplingConfig {
+	return SamplingConfig{Sampler: SamplerAlwaysOn, Ratio: 1, TailBaselineRatio: 0.1}
+}
+
+// SamplingConfigFromEnv reads the sampling configuration from the environment, falling back to DefaultSamplingConfig.
+func SamplingConfigFromEnv() (SamplingConfig, error) {
+	cfg := DefaultSamplingConfig()
+	if v := os.Getenv(EnvTracesSampler); v != "" {
+		cfg.Sampler = v
+	}
+	if v := os.Getenv(EnvTracesSamplerArg); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s %q: %w", EnvTracesSamplerArg, v, err)
+		}
+		cfg.Ratio = ratio
+	}
+	if v := os.Getenv(EnvTailSamplingLatency); v != "" {
+		threshold, err := time.ParseDuration(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s %q: %w", EnvTailSamplingLatency, v, err)
+		}
+		cfg.TailLatencyThreshold = threshold
+	}
+	if v := os.Getenv(EnvTailSamplingBaselineRate); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s %q: %w", EnvTailSamplingBaselineRate, v, err)
+		}
+		cfg.TailBaselineRatio = ratio
+	}
+	return cfg, cfg.Validate()
+}
+
+// Validate checks the sampler name and ratios.
+func (c SamplingConfig) Validate() error {
+	if _, err := c.headSampler(); err != nil {
+		return err
+	}
+	if c.TailLatencyThreshold < 0 {
+		return fmt.Errorf("tail sampling latency threshold must not be negative")
+	}
+	if c.TailBaselineRatio < 0 || c.TailBaselineRatio > 1 {
+		return fmt.Errorf("tail sampling baseline ratio must be between 0 and 1, got %v", c.TailBaselineRatio)
+	}
+	return nil
+}
+
+// headSampler builds the SDK sampler. Parent-based samplers follow the sampling decision of the caller,
+// so a trace is either recorded by every service or by none of them.
+func (c SamplingConfig) headSampler() (sdktrace.Sampler, error) {
+	if c.Ratio < 0 || c.Ratio > 1 {
+		return nil, fmt.Errorf("sampler ratio must be between 0 and 1, got %v", c.Ratio)
+	}
+	switch c.Sampler {
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample(), nil
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample(), nil
+	case SamplerTraceIDRatio:
+		return sdktrace.TraceIDRatioBased(c.Ratio), nil
+	case SamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case SamplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case SamplerParentBasedTraceIDRatio:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.Ratio)), nil
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", c.Sampler)
+	}
+}
@@ -0,0 +1,64 @@
+package scenario
+
+import (
+	"io"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AdminHandler serves the runtime configuration of the engine under prefix (e.g. "/admin/scenarios"):
+//
+//	GET    prefix         all rules of this service as YAML
+//	PUT    prefix/{name}  add or replace a rule; the body is YAML (or JSON, which YAML accepts)
+//	DELETE prefix/{name}  remove a rule
+//
+// Changes are kept in memory only; restarting the service reloads the YAML file.
+func (e *Engine) AdminHandler(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix, e.handleListRules)
+	mux.HandleFunc("PUT "+prefix+"/{name}", e.handlePutRule)
+	mux.HandleFunc("DELETE "+prefix+"/{name}", e.handleDeleteRule)
+	return mux
+}
+
+func (e *Engine) handleListRules(w http.ResponseWriter, r *http.Request) {
+	writeYAML(w, http.StatusOK, e.Rules())
+}
+
+func (e *Engine) handlePutRule(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	var rule Rule
+	if err := yaml.Unmarshal(body, &rule); err != nil {
+		http.Error(w, "Invalid rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := e.SetRule(r.PathValue("name"), rule); err != nil {
+		http.Error(w, "Invalid rule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeYAML(w, http.StatusOK, rule)
+}
+
+func (e *Engine) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	if !e.DeleteRule(r.PathValue("name")) {
+		http.Error(w, "Scenario not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeYAML(w http.ResponseWriter, status int, v any) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(status)
+	w.Write(data)
+}
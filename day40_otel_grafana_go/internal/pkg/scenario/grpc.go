@@ -0,0 +1,36 @@
+package scenario
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCStatus converts the injected HTTP status to the closest gRPC code, so gRPC servers can return the error as is.
+func (e *InjectedError) GRPCStatus() *status.Status {
+	code := codes.Internal
+	switch {
+	case e.Status == http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case e.Status == http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	case e.Status == http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case e.Status == http.StatusNotFound:
+		code = codes.NotFound
+	case e.Status >= 400 && e.Status < 500:
+		code = codes.InvalidArgument
+	}
+	return status.New(code, e.Message)
+}
+
+// GRPCError converts an error returned by Outcome.Apply to a gRPC status error.
+func GRPCError(err error) error {
+	var injected *InjectedError
+	if errors.As(err, &injected) {
+		return injected.GRPCStatus().Err()
+	}
+	return status.FromContextError(err).Err()
+}
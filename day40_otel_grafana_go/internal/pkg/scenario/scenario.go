@@ -0,0 +1,231 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultScenario is applied to every request, whether or not it names a scenario.
+// Rules registered under it inject background chaos into normal traffic.
+const DefaultScenario = "default"
+
+// Rule describes the faults injected into one service for one scenario.
+type Rule struct {
+	// Latency is added with probability LatencyProbability, plus a random extra of up to LatencyJitter.
+	Latency            time.Duration `yaml:"latency,omitempty"`
+	LatencyJitter      time.Duration `yaml:"latency_jitter,omitempty"`
+	LatencyProbability float64       `yaml:"latency_probability,omitempty"`
+
+	// ErrorRate is the probability of failing the request with ErrorStatus (500 if unset).
+	ErrorRate    float64 `yaml:"error_rate,omitempty"`
+	ErrorStatus  int     `yaml:"error_status,omitempty"`
+	ErrorMessage string  `yaml:"error_message,omitempty"`
+
+	// PayloadBytes pads the response body by roughly this many bytes.
+	PayloadBytes int `yaml:"payload_bytes,omitempty"`
+
+	// Flags switch on service-specific behaviour (e.g. "slow_query" in order-service).
+	Flags []string `yaml:"flags,omitempty"`
+}
+
+// Validate reports whether the rule's knobs are within range.
+func (r Rule) Validate() error {
+	if r.Latency < 0 || r.LatencyJitter < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if r.LatencyProbability < 0 || r.LatencyProbability > 1 {
+		return fmt.Errorf("latency_probability must be between 0 and 1, got %v", r.LatencyProbability)
+	}
+	if r.ErrorRate < 0 || r.ErrorRate > 1 {
+		return fmt.Errorf("error_rate must be between 0 and 1, got %v", r.ErrorRate)
+	}
+	if r.ErrorStatus != 0 && (r.ErrorStatus < 400 || r.ErrorStatus > 599) {
+		return fmt.Errorf("error_status must be a 4xx or 5xx code, got %d", r.ErrorStatus)
+	}
+	if r.PayloadBytes < 0 {
+		return fmt.Errorf("payload_bytes must not be negative")
+	}
+	return nil
+}
+
+// Config is the layout of the scenario YAML file: scenario name -> service name -> rule.
+type Config map[string]map[string]Rule
+
+// LoadConfig reads a scenario YAML file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario config %s: %w", path, err)
+	}
+	for name, services := range cfg {
+		for service, rule := range services {
+			if err := rule.Validate(); err != nil {
+				return nil, fmt.Errorf("scenario %q for %s: %w", name, service, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// InjectedError is returned by Outcome.Apply when the scenario fails the request.
+type InjectedError struct {
+	Scenario string
+	Status   int
+	Message  string
+}
+
+func (e *InjectedError) Error() string {
+	return fmt.Sprintf("scenario %s injected error: %d %s", e.Scenario, e.Status, e.Message)
+}
+
+// Outcome is the fault decision for a single request.
+type Outcome struct {
+	Scenario     string
+	Delay        time.Duration
+	Err          *InjectedError
+	PayloadBytes int
+	Flags        []string
+}
+
+// HasFlag reports whether a matching rule set flag.
+func (o Outcome) HasFlag(flag string) bool {
+	return slices.Contains(o.Flags, flag)
+}
+
+// Padding returns filler to embed in the response so it grows by PayloadBytes.
+func (o Outcome) Padding() string {
+	return strings.Repeat("x", o.PayloadBytes)
+}
+
+// Apply records the decision on the current span, waits for the injected delay and returns the injected error, if any.
+// It returns ctx.Err() if the request is cancelled while waiting.
+func (o Outcome) Apply(ctx context.Context) error {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("scenario.name", o.Scenario))
+
+	if o.Delay > 0 {
+		span.AddEvent("scenario.latency_injected", trace.WithAttributes(attribute.Int64("scenario.delay_ms", o.Delay.Milliseconds())))
+		timer := time.NewTimer(o.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if o.Err != nil {
+		span.AddEvent("scenario.error_injected", trace.WithAttributes(attribute.Int("scenario.error_status", o.Err.Status)))
+		return o.Err
+	}
+	if o.PayloadBytes > 0 {
+		span.SetAttributes(attribute.Int("scenario.payload_bytes", o.PayloadBytes))
+	}
+	return nil
+}
+
+// Engine holds the rules of one service and decides the faults for each request.
+// Rules can be replaced at runtime through AdminHandler.
+type Engine struct {
+	service string
+
+	mu    sync.RWMutex
+	rules map[string]Rule // Scenario name -> rule for this service
+}
+
+// NewEngine creates an engine for service using its rules in cfg.
+func NewEngine(service string, cfg Config) *Engine {
+	e := &Engine{service: service, rules: make(map[string]Rule)}
+	for name, services := range cfg {
+		if rule, ok := services[service]; ok {
+			e.rules[name] = rule
+		}
+	}
+	return e
+}
+
+// Rules returns a copy of the current rules keyed by scenario name.
+func (e *Engine) Rules() map[string]Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	rules := make(map[string]Rule, len(e.rules))
+	for name, rule := range e.rules {
+		rules[name] = rule
+	}
+	return rules
+}
+
+// SetRule adds or replaces the rule of a scenario.
+func (e *Engine) SetRule(name string, rule Rule) error {
+	if name == "" {
+		return fmt.Errorf("scenario name is required")
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules[name] = rule
+	return nil
+}
+
+// DeleteRule removes the rule of a scenario. It reports whether the rule existed.
+func (e *Engine) DeleteRule(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.rules[name]
+	delete(e.rules, name)
+	return ok
+}
+
+// Decide rolls the dice for a request that asked for scenario.
+// The default rule and the named rule both apply: delays add up, and the first injected error wins.
+func (e *Engine) Decide(scenario string) Outcome {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	outcome := Outcome{Scenario: scenario}
+	names := []string{DefaultScenario}
+	if scenario != "" && scenario != DefaultScenario {
+		names = append(names, scenario)
+	}
+	for _, name := range names {
+		rule, ok := e.rules[name]
+		if !ok {
+			continue
+		}
+		if rule.Latency+rule.LatencyJitter > 0 && rand.Float64() < rule.LatencyProbability {
+			outcome.Delay += rule.Latency
+			if rule.LatencyJitter > 0 {
+				outcome.Delay += rand.N(rule.LatencyJitter)
+			}
+		}
+		if outcome.Err == nil && rand.Float64() < rule.ErrorRate {
+			status := rule.ErrorStatus
+			if status == 0 {
+				status = 500
+			}
+			message := rule.ErrorMessage
+			if message == "" {
+				message = fmt.Sprintf("Simulated %s error", e.service)
+			}
+			outcome.Err = &InjectedError{Scenario: name, Status: status, Message: message}
+		}
+		outcome.PayloadBytes = max(outcome.PayloadBytes, rule.PayloadBytes)
+		outcome.Flags = append(outcome.Flags, rule.Flags...)
+	}
+	return outcome
+}
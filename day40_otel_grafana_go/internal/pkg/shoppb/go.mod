@@ -0,0 +1,15 @@
+module github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/shoppb
+
+go 1.24.2
+
+require (
+	google.golang.org/grpc v1.72.0
+	google.golang.org/protobuf v1.36.5
+)
+
+require (
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+)
@@ -3,17 +3,24 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/lifecycle"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/middleware"
 	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/observability"
 	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/otel"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/scenario"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/shoppb"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 )
 
 const (
@@ -22,26 +29,37 @@ const (
 	environment    = "development"
 	otelEndpoint   = "localhost:4317"
 	serverPort     = ":8082"
+	grpcPort       = ":9082"
+	scenarioConfig = "../scenarios.yaml" // Services are started from their own directory
 )
 
-var tracer oteltrace.Tracer
+var (
+	tracer    oteltrace.Tracer
+	scenarios *scenario.Engine
+)
 
 type Inventory struct {
 	ProductID string `json:"productId"`
 	Stock     int    `json:"stock"`
 	Location  string `json:"location"`
+	Padding   string `json:"padding,omitempty"` // Filled by the payload_bytes scenario knob
 }
 
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	shutdownTracer, err := otel.InitTracerProvider(ctx, serviceName, serviceVersion, environment, otelEndpoint)
+	sampling, err := otel.SamplingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("invalid sampling config: %v", err)
+	}
+	shutdownTracer, err := otel.InitTracerProvider(ctx, serviceName, serviceVersion, environment, otelEndpoint, sampling)
 	if err != nil {
 		log.Fatalf("failed to initialize tracer provider: %v", err)
 	}
 	defer func() {
-		if err := shutdownTracer(ctx); err != nil {
+		// ctx is already cancelled here; the spans were flushed by the shutdown coordinator
+		if err := shutdownTracer(context.Background()); err != nil {
 			log.Printf("failed to shutdown tracer provider: %v", err)
 		}
 	}()
@@ -52,18 +70,37 @@ func main() {
 		log.Fatalf("failed to initialize meter provider: %v", err)
 	}
 
+	cfg, err := scenario.LoadConfig(scenarioConfig)
+	if err != nil {
+		log.Fatalf("failed to load scenarios: %v", err)
+	}
+	scenarios = scenario.NewEngine(serviceName, cfg)
+
+	drainDelay, err := lifecycle.DrainDelayFromEnv()
+	if err != nil {
+		log.Fatalf("invalid drain delay: %v", err)
+	}
+	coordinator := lifecycle.New(drainDelay)
+
+	red := middleware.NewRED(serviceName)
+
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", middleware.MetricsHandler()) // OpenMetrics, so exemplars are exposed
+
+	adminHandler := scenarios.AdminHandler("/admin/scenarios")
+	mux.Handle("/admin/scenarios", adminHandler)
+	mux.Handle("/admin/scenarios/", adminHandler)
 
 	inventoryHandler := http.HandlerFunc(handleGetInventory)
-	mux.Handle("/inventory", otelhttp.NewHandler(inventoryHandler, "GetInventory"))
+	mux.Handle("/inventory", otelhttp.NewHandler(red.Wrap("/inventory", inventoryHandler), "GetInventory"))
 
 	otelHandler := otelhttp.NewHandler(mux, serviceName+"-server")
 
 	srv := &http.Server{
 		Addr:    serverPort,
-		Handler: otelHandler,
+		Handler: coordinator.Wrap(otelHandler), // /healthz and /readyz are answered before tracing
 	}
+	coordinator.AddServer("HTTP", srv.Shutdown)
 
 	go func() {
 		log.Printf("%s starting on port %s", serviceName, serverPort)
@@ -72,13 +109,31 @@ func main() {
 		}
 	}()
 
+	// gRPC variant of the same API; otelgrpc extracts the trace context from the gRPC metadata
+	grpcSrv := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	shoppb.RegisterInventoryServiceServer(grpcSrv, inventoryServer{})
+	lis, err := net.Listen("tcp", grpcPort)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", grpcPort, err)
+	}
+	go func() {
+		log.Printf("%s gRPC server starting on port %s", serviceName, grpcPort)
+		if err := grpcSrv.Serve(lis); err != nil {
+			log.Fatalf("gRPC Serve(): %v", err)
+		}
+	}()
+	coordinator.AddServer("gRPC", lifecycle.GRPCStop(grpcSrv))
+
+	coordinator.MarkReady()
+
 	<-ctx.Done()
 	log.Printf("%s shutting down...", serviceName)
 
-	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	// Readiness flips to "draining" first, then the servers get 5s to finish in-flight requests
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), drainDelay+5*time.Second)
 	defer cancelShutdown()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server Shutdown Failed:%+v", err)
+	if err := coordinator.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown failed: %v", err)
 	}
 	log.Printf("%s shutdown complete.", serviceName)
 }
@@ -93,36 +148,69 @@ func handleGetInventory(w http.ResponseWriter, r *http.Request) {
 	}
 	logger.DebugContext(r.Context(), "Received request", "headers", headersMap)
 
-	_, span := tracer.Start(r.Context(), "handleGetInventoryInternal")
+	inventory, err := getInventory(r.Context(), r.URL.Query().Get("scenario"))
+	if err != nil {
+		var injected *scenario.InjectedError
+		if errors.As(err, &injected) {
+			http.Error(w, injected.Message, injected.Status)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(inventory); err != nil {
+		logger.ErrorContext(r.Context(), "Error encoding inventory to JSON", "error", err, "service_name", serviceName)
+		if r.Context().Err() == nil { // Avoid duplicate error if context was already cancelled
+			http.Error(w, "Failed to encode inventory", http.StatusInternalServerError)
+		}
+	}
+}
+
+// getInventory is shared by the HTTP and gRPC handlers.
+// It returns a *scenario.InjectedError when the scenario fails the request, or the context error if it was cancelled.
+func getInventory(ctx context.Context, scenarioName string) (Inventory, error) {
+	logger := observability.NewLogger("inventory_service")
+	ctx, span := tracer.Start(ctx, "handleGetInventoryInternal")
 	defer span.End()
 
-	scenario := r.URL.Query().Get("scenario")
-	logger.InfoContext(r.Context(), "Processing request", "service_name", serviceName, "scenario", scenario)
+	logger.InfoContext(ctx, "Processing request", "service_name", serviceName, "scenario", scenarioName)
 
-	if scenario == "inventory_timeout" {
-		logger.InfoContext(r.Context(), "Simulating inventory timeout", "service_name", serviceName, "duration", "4s")
-		time.Sleep(4 * time.Second)
-	} else if scenario == "long_request" {
-		logger.InfoContext(r.Context(), "Simulating long processing", "service_name", serviceName, "duration", "5s")
-		time.Sleep(5 * time.Second)
+	outcome := scenarios.Decide(scenarioName)
+	if outcome.Delay > 0 {
+		logger.InfoContext(ctx, "Injecting latency", "service_name", serviceName, "scenario", scenarioName, "duration", outcome.Delay.String())
 	}
-
-	if r.Context().Err() != nil {
-		logger.WarnContext(r.Context(), "Context cancelled", "service_name", serviceName, "error", r.Context().Err())
-		return
+	if err := outcome.Apply(ctx); err != nil {
+		var injected *scenario.InjectedError
+		if errors.As(err, &injected) {
+			logger.WarnContext(ctx, "Injecting error", "service_name", serviceName, "scenario", injected.Scenario, "status", injected.Status)
+		} else {
+			logger.WarnContext(ctx, "Context cancelled", "service_name", serviceName, "error", err)
+		}
+		return Inventory{}, err
 	}
 
-	inventory := Inventory{
+	return Inventory{
 		ProductID: "prod123",
 		Stock:     88,
 		Location:  "Warehouse A",
-	}
+		Padding:   outcome.Padding(),
+	}, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(inventory); err != nil {
-		logger.ErrorContext(r.Context(), "Error encoding inventory to JSON", "error", err, "service_name", serviceName)
-		if r.Context().Err() == nil { // Avoid duplicate error if context was already cancelled
-			http.Error(w, "Failed to encode inventory", http.StatusInternalServerError)
-		}
+// inventoryServer is the gRPC variant of GET /inventory.
+type inventoryServer struct {
+	shoppb.UnimplementedInventoryServiceServer
+}
+
+func (inventoryServer) GetInventory(ctx context.Context, req *shoppb.GetInventoryRequest) (*shoppb.Inventory, error) {
+	inventory, err := getInventory(ctx, req.GetScenario())
+	if err != nil {
+		return nil, scenario.GRPCError(err)
 	}
+	return &shoppb.Inventory{
+		ProductId: inventory.ProductID,
+		Stock:     int32(inventory.Stock),
+		Location:  inventory.Location,
+		Padding:   inventory.Padding,
+	}, nil
 }
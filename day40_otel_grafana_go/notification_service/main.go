@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/lifecycle"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/messaging"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/middleware"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/observability"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/otel"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName    = "notification-service"
+	serviceVersion = "0.1.0"
+	environment    = "development"
+	otelEndpoint   = "localhost:4317"
+	serverPort     = ":8084"
+	natsHost       = "127.0.0.1"
+	natsPort       = 4222 // order-service publishes to the NATS server embedded here
+
+	maxNotifications = 50
+)
+
+var tracer oteltrace.Tracer
+
+// Order is the payload of an orders.created message
+type Order struct {
+	OrderID     string    `json:"orderId"`
+	Status      string    `json:"status"`
+	TotalAmount float64   `json:"totalAmount"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Notification is a (simulated) order confirmation sent to the customer
+type Notification struct {
+	OrderID string    `json:"orderId"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sentAt"`
+	TraceID string    `json:"traceId"`
+}
+
+var (
+	notifications   []Notification // Most recent last
+	notificationsMu sync.Mutex
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	sampling, err := otel.SamplingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("invalid sampling config: %v", err)
+	}
+	shutdownTracer, err := otel.InitTracerProvider(ctx, serviceName, serviceVersion, environment, otelEndpoint, sampling)
+	if err != nil {
+		log.Fatalf("failed to initialize tracer provider: %v", err)
+	}
+	defer func() {
+		// ctx is already cancelled here; the spans were flushed by the shutdown coordinator
+		if err := shutdownTracer(context.Background()); err != nil {
+			log.Printf("failed to shutdown tracer provider: %v", err)
+		}
+	}()
+	tracer = otel.GetTracer(serviceName)
+
+	_, err = otel.InitMeterProvider(ctx, serviceName, serviceVersion, environment)
+	if err != nil {
+		log.Fatalf("failed to initialize meter provider: %v", err)
+	}
+
+	// Embedded NATS server: the message queue between order-service and this service
+	natsServer, err := server.NewServer(&server.Options{Host: natsHost, Port: natsPort, NoSigs: true})
+	if err != nil {
+		log.Fatalf("failed to create NATS server: %v", err)
+	}
+	go natsServer.Start()
+	if !natsServer.ReadyForConnections(5 * time.Second) {
+		log.Fatalf("NATS server did not become ready on %s:%d", natsHost, natsPort)
+	}
+	log.Printf("Embedded NATS server listening on %s:%d", natsHost, natsPort)
+
+	ncClosed := make(chan struct{})
+	nc, err := nats.Connect(natsServer.ClientURL(), nats.InProcessServer(natsServer),
+		nats.ClosedHandler(func(*nats.Conn) { close(ncClosed) }))
+	if err != nil {
+		log.Fatalf("failed to connect to embedded NATS server: %v", err)
+	}
+	if _, err := messaging.Subscribe(nc, messaging.OrderCreatedSubject, handleOrderCreated); err != nil {
+		log.Fatalf("failed to subscribe: %v", err)
+	}
+
+	drainDelay, err := lifecycle.DrainDelayFromEnv()
+	if err != nil {
+		log.Fatalf("invalid drain delay: %v", err)
+	}
+	coordinator := lifecycle.New(drainDelay)
+
+	red := middleware.NewRED(serviceName)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", middleware.MetricsHandler()) // OpenMetrics, so exemplars are exposed
+
+	notificationsHandler := http.HandlerFunc(handleListNotifications)
+	mux.Handle("/notifications", otelhttp.NewHandler(red.Wrap("/notifications", notificationsHandler), "ListNotifications"))
+
+	otelHandler := otelhttp.NewHandler(mux, serviceName+"-server")
+
+	srv := &http.Server{
+		Addr:    serverPort,
+		Handler: coordinator.Wrap(otelHandler), // /healthz and /readyz are answered before tracing
+	}
+	coordinator.AddServer("HTTP", srv.Shutdown)
+	// NATS is an intake like the HTTP server: finish the messages already received before the spans are flushed
+	coordinator.AddServer("NATS", func(ctx context.Context) error {
+		defer natsServer.Shutdown()
+		if err := nc.Drain(); err != nil {
+			return err
+		}
+		select {
+		case <-ncClosed:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	go func() {
+		log.Printf("%s starting on port %s", serviceName, serverPort)
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("ListenAndServe(): %v", err)
+		}
+	}()
+
+	coordinator.MarkReady()
+
+	<-ctx.Done()
+	log.Printf("%s shutting down...", serviceName)
+
+	// Readiness flips to "draining" first, then the servers get 5s to finish in-flight requests
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), drainDelay+5*time.Second)
+	defer cancelShutdown()
+	if err := coordinator.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown failed: %v", err)
+	}
+	log.Printf("%s shutdown complete.", serviceName)
+}
+
+// handleOrderCreated runs inside the CONSUMER span created by messaging.Subscribe,
+// which continues the trace of the order-service request that published the message.
+func handleOrderCreated(ctx context.Context, msg *nats.Msg) error {
+	logger := observability.NewLogger("notification_service")
+
+	var order Order
+	if err := json.Unmarshal(msg.Data, &order); err != nil {
+		logger.ErrorContext(ctx, "Invalid order message", "error", err, "service_name", serviceName)
+		return fmt.Errorf("failed to decode order: %w", err)
+	}
+	logger.InfoContext(ctx, "Received order created message", "service_name", serviceName, "order_id", order.OrderID)
+
+	ctx, span := tracer.Start(ctx, "sendOrderConfirmation")
+	defer span.End()
+	span.SetAttributes(attribute.String("order.id", order.OrderID))
+
+	// Simulate talking to a mail provider
+	time.Sleep(50 * time.Millisecond)
+
+	notification := Notification{
+		OrderID: order.OrderID,
+		Message: fmt.Sprintf("Thank you! Your order %s (%.2f) has been received.", order.OrderID, order.TotalAmount),
+		SentAt:  time.Now(),
+		TraceID: span.SpanContext().TraceID().String(),
+	}
+	notificationsMu.Lock()
+	notifications = append(notifications, notification)
+	if len(notifications) > maxNotifications {
+		notifications = notifications[len(notifications)-maxNotifications:]
+	}
+	notificationsMu.Unlock()
+
+	logger.InfoContext(ctx, "Order confirmation sent", "service_name", serviceName, "order_id", order.OrderID)
+	return nil
+}
+
+func handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	logger := observability.NewLogger("notification_service")
+
+	notificationsMu.Lock()
+	list := append([]Notification(nil), notifications...)
+	notificationsMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		logger.ErrorContext(r.Context(), "Error encoding notifications to JSON", "error", err, "service_name", serviceName)
+		http.Error(w, "Failed to encode notifications", http.StatusInternalServerError)
+	}
+}
@@ -3,17 +3,28 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/lifecycle"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/messaging"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/middleware"
 	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/observability"
 	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/otel"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/scenario"
+	"github.com/lirlia/100day_challenge_backend/day40_otel_grafana_go/internal/pkg/shoppb"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -22,27 +33,42 @@ const (
 	environment    = "development"
 	otelEndpoint   = "localhost:4317"
 	serverPort     = ":8083"
+	grpcPort       = ":9083"
+	scenarioConfig = "../scenarios.yaml" // Services are started from their own directory
+	dbPath         = "orders.db"
+	natsURL        = "nats://127.0.0.1:4222" // Embedded in notification-service
 )
 
-var tracer oteltrace.Tracer
+var (
+	tracer    oteltrace.Tracer
+	store     *OrderStore
+	nc        *nats.Conn
+	scenarios *scenario.Engine
+)
 
 type Order struct {
 	OrderID     string    `json:"orderId"`
 	Status      string    `json:"status"`
 	TotalAmount float64   `json:"totalAmount"`
 	CreatedAt   time.Time `json:"createdAt"`
+	Padding     string    `json:"padding,omitempty"` // Filled by the payload_bytes scenario knob
 }
 
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	shutdownTracer, err := otel.InitTracerProvider(ctx, serviceName, serviceVersion, environment, otelEndpoint)
+	sampling, err := otel.SamplingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("invalid sampling config: %v", err)
+	}
+	shutdownTracer, err := otel.InitTracerProvider(ctx, serviceName, serviceVersion, environment, otelEndpoint, sampling)
 	if err != nil {
 		log.Fatalf("failed to initialize tracer provider: %v", err)
 	}
 	defer func() {
-		if err := shutdownTracer(ctx); err != nil {
+		// ctx is already cancelled here; the spans were flushed by the shutdown coordinator
+		if err := shutdownTracer(context.Background()); err != nil {
 			log.Printf("failed to shutdown tracer provider: %v", err)
 		}
 	}()
@@ -53,18 +79,58 @@ func main() {
 		log.Fatalf("failed to initialize meter provider: %v", err)
 	}
 
+	// The store registers its connection pool metrics, so it is opened after the meter provider
+	store, err = OpenOrderStore(ctx, dbPath)
+	if err != nil {
+		log.Fatalf("failed to open order store: %v", err)
+	}
+	defer store.Close()
+
+	// notification-service may start after us, so keep retrying in the background instead of failing
+	nc, err = nats.Connect(natsURL, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		log.Fatalf("failed to connect to NATS: %v", err)
+	}
+	defer nc.Close()
+
+	cfg, err := scenario.LoadConfig(scenarioConfig)
+	if err != nil {
+		log.Fatalf("failed to load scenarios: %v", err)
+	}
+	scenarios = scenario.NewEngine(serviceName, cfg)
+
+	drainDelay, err := lifecycle.DrainDelayFromEnv()
+	if err != nil {
+		log.Fatalf("invalid drain delay: %v", err)
+	}
+	coordinator := lifecycle.New(drainDelay)
+	// Orders are still stored without NATS, but their confirmations would be lost
+	coordinator.AddReadinessCheck("nats", func(context.Context) error {
+		if !nc.IsConnected() {
+			return fmt.Errorf("not connected (%s)", nc.Status())
+		}
+		return nil
+	})
+
+	red := middleware.NewRED(serviceName)
+
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", middleware.MetricsHandler()) // OpenMetrics, so exemplars are exposed
+
+	adminHandler := scenarios.AdminHandler("/admin/scenarios")
+	mux.Handle("/admin/scenarios", adminHandler)
+	mux.Handle("/admin/scenarios/", adminHandler)
 
 	ordersHandler := http.HandlerFunc(handleCreateOrder)
-	mux.Handle("/orders", otelhttp.NewHandler(ordersHandler, "CreateOrder")) // Wrap with Otel
+	mux.Handle("/orders", otelhttp.NewHandler(red.Wrap("/orders", ordersHandler), "CreateOrder")) // Wrap with Otel
 
 	otelHandler := otelhttp.NewHandler(mux, serviceName+"-server")
 
 	srv := &http.Server{
 		Addr:    serverPort,
-		Handler: otelHandler,
+		Handler: coordinator.Wrap(otelHandler), // /healthz and /readyz are answered before tracing
 	}
+	coordinator.AddServer("HTTP", srv.Shutdown)
 
 	go func() {
 		log.Printf("%s starting on port %s", serviceName, serverPort)
@@ -73,13 +139,31 @@ func main() {
 		}
 	}()
 
+	// gRPC variant of the same API; otelgrpc extracts the trace context from the gRPC metadata
+	grpcSrv := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	shoppb.RegisterOrderServiceServer(grpcSrv, orderServer{})
+	lis, err := net.Listen("tcp", grpcPort)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", grpcPort, err)
+	}
+	go func() {
+		log.Printf("%s gRPC server starting on port %s", serviceName, grpcPort)
+		if err := grpcSrv.Serve(lis); err != nil {
+			log.Fatalf("gRPC Serve(): %v", err)
+		}
+	}()
+	coordinator.AddServer("gRPC", lifecycle.GRPCStop(grpcSrv))
+
+	coordinator.MarkReady()
+
 	<-ctx.Done()
 	log.Printf("%s shutting down...", serviceName)
 
-	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	// Readiness flips to "draining" first, then the servers get 5s to finish in-flight requests
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), drainDelay+5*time.Second)
 	defer cancelShutdown()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server Shutdown Failed:%+v", err)
+	if err := coordinator.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown failed: %v", err)
 	}
 	log.Printf("%s shutdown complete.", serviceName)
 }
@@ -94,30 +178,18 @@ func handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 	logger.DebugContext(r.Context(), "Received request", "headers", headersMap)
 
-	_, span := tracer.Start(r.Context(), "handleCreateOrderInternal")
-	defer span.End()
-
-	scenario := r.URL.Query().Get("scenario")
-	logger.InfoContext(r.Context(), "Processing request", "service_name", serviceName, "scenario", scenario)
-
-	// Handle scenarios if needed, e.g., simulate DB delay or error
-	if scenario == "long_request" {
-		logger.InfoContext(r.Context(), "Simulating long processing", "service_name", serviceName, "duration", "1s")
-		time.Sleep(1 * time.Second)
-	}
-
-	if r.Context().Err() != nil {
-		logger.WarnContext(r.Context(), "Context cancelled", "service_name", serviceName, "error", r.Context().Err())
+	order, err := createOrder(r.Context(), r.URL.Query().Get("scenario"))
+	if err != nil {
+		var injected *scenario.InjectedError
+		switch {
+		case errors.As(err, &injected):
+			http.Error(w, injected.Message, injected.Status)
+		case r.Context().Err() == nil:
+			http.Error(w, "Failed to create order", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	order := Order{
-		OrderID:     "ord951",
-		Status:      "CREATED",
-		TotalAmount: 19.99, // Assuming it matches the product price for simplicity
-		CreatedAt:   time.Now(),
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(order); err != nil {
 		logger.ErrorContext(r.Context(), "Error encoding order to JSON", "error", err, "service_name", serviceName)
@@ -126,3 +198,69 @@ func handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// createOrder is shared by the HTTP and gRPC handlers.
+// It returns a *scenario.InjectedError when the scenario fails the request, or the context error if it was cancelled.
+func createOrder(ctx context.Context, scenarioName string) (Order, error) {
+	logger := observability.NewLogger("order_service")
+	ctx, span := tracer.Start(ctx, "handleCreateOrderInternal")
+	defer span.End()
+
+	logger.InfoContext(ctx, "Processing request", "service_name", serviceName, "scenario", scenarioName)
+
+	outcome := scenarios.Decide(scenarioName)
+	if outcome.Delay > 0 {
+		logger.InfoContext(ctx, "Injecting latency", "service_name", serviceName, "scenario", scenarioName, "duration", outcome.Delay.String())
+	}
+	if err := outcome.Apply(ctx); err != nil {
+		var injected *scenario.InjectedError
+		if errors.As(err, &injected) {
+			logger.WarnContext(ctx, "Injecting error", "service_name", serviceName, "scenario", injected.Scenario, "status", injected.Status)
+		} else {
+			logger.WarnContext(ctx, "Context cancelled", "service_name", serviceName, "error", err)
+		}
+		return Order{}, err
+	}
+
+	if outcome.HasFlag("slow_query") {
+		logger.InfoContext(ctx, "Simulating slow DB query", "service_name", serviceName)
+		if err := store.SimulateSlowQuery(ctx); err != nil {
+			logger.ErrorContext(ctx, "Slow query failed", "error", err, "service_name", serviceName)
+			return Order{}, err
+		}
+	}
+
+	order, err := store.CreateOrder(ctx, "prod123", 19.99) // Assuming it matches the product price for simplicity
+	if err != nil {
+		logger.ErrorContext(ctx, "Error creating order", "error", err, "service_name", serviceName)
+		return Order{}, err
+	}
+	order.Padding = outcome.Padding()
+
+	// Notify asynchronously; the order is already stored, so a publish failure does not fail the request
+	if data, err := json.Marshal(order); err != nil {
+		logger.ErrorContext(ctx, "Error encoding order event", "error", err, "service_name", serviceName)
+	} else if err := messaging.Publish(ctx, nc, messaging.OrderCreatedSubject, data); err != nil {
+		logger.WarnContext(ctx, "Failed to publish order created event", "error", err, "service_name", serviceName, "order_id", order.OrderID)
+	}
+	return order, nil
+}
+
+// orderServer is the gRPC variant of POST /orders.
+type orderServer struct {
+	shoppb.UnimplementedOrderServiceServer
+}
+
+func (orderServer) CreateOrder(ctx context.Context, req *shoppb.CreateOrderRequest) (*shoppb.Order, error) {
+	order, err := createOrder(ctx, req.GetScenario())
+	if err != nil {
+		return nil, scenario.GRPCError(err)
+	}
+	return &shoppb.Order{
+		OrderId:     order.OrderID,
+		Status:      order.Status,
+		TotalAmount: order.TotalAmount,
+		CreatedAt:   timestamppb.New(order.CreatedAt),
+		Padding:     order.Padding,
+	}, nil
+}
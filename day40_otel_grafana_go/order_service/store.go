@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	_ "github.com/mattn/go-sqlite3" // SQLiteドライバー
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS orders (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	product_id   TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	total_amount REAL NOT NULL,
+	created_at   TIMESTAMP NOT NULL
+);
+`
+
+// slowQuery は重いクエリを模擬するための再帰CTEです。インデックスの効かない大きなスキャンの代わりに使います。
+const slowQuery = `
+WITH RECURSIVE counter(n) AS (
+	SELECT 1
+	UNION ALL
+	SELECT n + 1 FROM counter WHERE n < ?
+)
+SELECT count(*) FROM counter`
+
+// slowQueryIterations は slow_query シナリオで数える行数です (1秒前後かかる程度)。
+const slowQueryIterations = 7500000
+
+// OrderStore は注文を SQLite に保存します。
+// database/sql を otelsql でラップしているため、すべてのクエリが DB スパン (クエリ文字列付き) としてトレースに現れます。
+type OrderStore struct {
+	db *sql.DB
+}
+
+// OpenOrderStore は SQLite ファイルを開き、スキーマを適用します。
+func OpenOrderStore(ctx context.Context, path string) (*OrderStore, error) {
+	db, err := otelsql.Open("sqlite3", path,
+		otelsql.WithAttributes(semconv.DBSystemSqlite),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true, // コネクションプールの内部処理はノイズになるので除外
+			OmitConnPrepare:      true,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1) // SQLite は書き込みが1接続のみ
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply schema: %w", err)
+	}
+
+	// コネクションプールの統計 (db.sql.connection.*) をメトリクスとして公開
+	if err := otelsql.RegisterDBStatsMetrics(db, otelsql.WithAttributes(semconv.DBSystemSqlite)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to register DB stats metrics: %w", err)
+	}
+	return &OrderStore{db: db}, nil
+}
+
+// Close はデータベース接続を閉じます。
+func (s *OrderStore) Close() error {
+	return s.db.Close()
+}
+
+// CreateOrder は注文を登録し、採番された注文を返します。
+func (s *OrderStore) CreateOrder(ctx context.Context, productID string, totalAmount float64) (Order, error) {
+	order := Order{
+		Status:      "CREATED",
+		TotalAmount: totalAmount,
+		CreatedAt:   time.Now().UTC(),
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO orders (product_id, status, total_amount, created_at) VALUES (?, ?, ?, ?)`,
+		productID, order.Status, order.TotalAmount, order.CreatedAt)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to insert order: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to get order ID: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err == nil {
+		oteltrace.SpanFromContext(ctx).SetAttributes(attribute.Int64("db.rows_affected", affected))
+	}
+	order.OrderID = fmt.Sprintf("ord%d", id)
+	return order, nil
+}
+
+// SimulateSlowQuery は時間のかかるクエリを実行します。数えた行数をスパン属性に記録します。
+func (s *OrderStore) SimulateSlowQuery(ctx context.Context) error {
+	var rows int
+	if err := s.db.QueryRowContext(ctx, slowQuery, slowQueryIterations).Scan(&rows); err != nil {
+		return fmt.Errorf("slow query failed: %w", err)
+	}
+	oteltrace.SpanFromContext(ctx).SetAttributes(attribute.Int("db.slow_query.rows", rows))
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// chaosCmd はネットワーク分断やノード停止による障害注入コマンドの親コマンドです。
+var chaosCmd = &cobra.Command{
+	Use:   "chaos",
+	Short: "Inject faults (network partitions, node crashes) into the local cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient := newClusterAPIClient()
+		resp, err := apiClient.ChaosStatus()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching chaos status: %v\n", err)
+			os.Exit(1)
+		}
+		printChaosStatus(resp)
+	},
+}
+
+var chaosPartitionCmd = &cobra.Command{
+	Use:   "partition <group> / <group> [/ <group>...]",
+	Short: "Drop Raft traffic between groups of nodes (e.g. node0,node1 / node2)",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		groups, err := parsePartitionGroups(args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending Partition request to %s (groups: %v)...", targetNodeAddr, groups)
+		resp, err := apiClient.Partition(groups)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error partitioning network: %v\n", err)
+			os.Exit(1)
+		}
+		printChaosStatus(resp)
+	},
+}
+
+var chaosHealCmd = &cobra.Command{
+	Use:   "heal",
+	Short: "Remove all network partitions",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending Heal request to %s...", targetNodeAddr)
+		resp, err := apiClient.Heal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error healing network: %v\n", err)
+			os.Exit(1)
+		}
+		printChaosStatus(resp)
+	},
+}
+
+var chaosKillCmd = &cobra.Command{
+	Use:   "kill <node-id>",
+	Short: "Stop a node without removing it from the cluster (its data is kept)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending KillNode request to %s for node %s...", targetNodeAddr, args[0])
+		resp, err := apiClient.KillNode(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error killing node: %v\n", err)
+			os.Exit(1)
+		}
+		printChaosStatus(resp)
+	},
+}
+
+var chaosRestartCmd = &cobra.Command{
+	Use:   "restart <node-id>",
+	Short: "Restart a killed node with its previous address and data",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending RestartNode request to %s for node %s...", targetNodeAddr, args[0])
+		resp, err := apiClient.RestartNode(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error restarting node: %v\n", err)
+			os.Exit(1)
+		}
+		printChaosStatus(resp)
+	},
+}
+
+// parsePartitionGroups は "node0,node1 / node2" 形式の引数をグループのリストに変換します。
+// グループは "/" で、グループ内のノードは "," で区切ります。引数は空白で分割されていても構いません。
+func parsePartitionGroups(args []string) ([][]string, error) {
+	var groups [][]string
+	for _, part := range strings.Split(strings.Join(args, " "), "/") {
+		group := strings.FieldsFunc(part, func(r rune) bool { return r == ',' || r == ' ' })
+		if len(group) == 0 {
+			return nil, fmt.Errorf("empty group in partition spec %q", strings.Join(args, " "))
+		}
+		groups = append(groups, group)
+	}
+	if len(groups) < 2 {
+		return nil, fmt.Errorf("partition spec %q needs at least 2 groups separated by '/'", strings.Join(args, " "))
+	}
+	return groups, nil
+}
+
+// printChaosStatus は障害注入の状態を表示します。
+func printChaosStatus(resp *client.ChaosResponse) {
+	fmt.Println(resp.Message)
+	if len(resp.Status.Partitions) == 0 {
+		fmt.Println("Partitions: none")
+	} else {
+		groups := make([]string, len(resp.Status.Partitions))
+		for i, group := range resp.Status.Partitions {
+			groups[i] = strings.Join(group, ",")
+		}
+		fmt.Printf("Partitions: %s\n", strings.Join(groups, " / "))
+	}
+	if len(resp.Status.KilledNodes) == 0 {
+		fmt.Println("Killed nodes: none")
+	} else {
+		fmt.Printf("Killed nodes: %s\n", strings.Join(resp.Status.KilledNodes, ", "))
+	}
+}
+
+func init() {
+	chaosCmd.AddCommand(chaosPartitionCmd)
+	chaosCmd.AddCommand(chaosHealCmd)
+	chaosCmd.AddCommand(chaosKillCmd)
+	chaosCmd.AddCommand(chaosRestartCmd)
+}
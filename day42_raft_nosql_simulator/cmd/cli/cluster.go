@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var clusterNodeID string
+
+// clusterCmd はクラスタメンバーシップを操作するコマンドの親コマンドです。
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Show or change the Raft cluster membership",
+}
+
+var clusterShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the current Raft cluster configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient := newClusterAPIClient()
+		log.Printf("Fetching cluster configuration from %s...", targetNodeAddr)
+		resp, err := apiClient.ClusterConfiguration()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching cluster configuration: %v\n", err)
+			os.Exit(1)
+		}
+		printClusterConfiguration(resp)
+	},
+}
+
+var clusterAddNodeCmd = &cobra.Command{
+	Use:   "add-node",
+	Short: "Start a new local node and add it to the cluster as a voter",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending AddNode request to %s (node-id: %q)...", targetNodeAddr, clusterNodeID)
+		resp, err := apiClient.AddNode(clusterNodeID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding node: %v\n", err)
+			os.Exit(1)
+		}
+		printClusterConfiguration(resp)
+	},
+}
+
+var clusterRemoveNodeCmd = &cobra.Command{
+	Use:   "remove-node",
+	Short: "Remove a node from the cluster and stop it",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending RemoveNode request to %s for node %s...", targetNodeAddr, clusterNodeID)
+		resp, err := apiClient.RemoveNode(clusterNodeID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error removing node: %v\n", err)
+			os.Exit(1)
+		}
+		printClusterConfiguration(resp)
+	},
+}
+
+func newClusterAPIClient() *client.APIClient {
+	if targetNodeAddr == "" {
+		fmt.Fprintln(os.Stderr, "Error: --target-addr must be specified")
+		os.Exit(1)
+	}
+	return client.NewAPIClient(targetNodeAddr)
+}
+
+// printClusterConfiguration はクラスタ構成を表形式で表示します。
+func printClusterConfiguration(resp *client.ClusterResponse) {
+	fmt.Println(resp.Message)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tRAFT ADDR\tHTTP API ADDR\tSUFFRAGE\tLEADER")
+	for _, s := range resp.Servers {
+		leader := ""
+		if s.IsLeader {
+			leader = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.ID, s.RaftAddr, s.HttpApiAddr, s.Suffrage, leader)
+	}
+	w.Flush()
+}
+
+func init() {
+	clusterAddNodeCmd.Flags().StringVar(&clusterNodeID, "node-id", "", "ID of the node to add (optional, auto-assigned as node<N> if empty)")
+
+	clusterRemoveNodeCmd.Flags().StringVar(&clusterNodeID, "node-id", "", "ID of the node to remove (required)")
+	clusterRemoveNodeCmd.MarkFlagRequired("node-id")
+
+	clusterCmd.AddCommand(clusterShowCmd)
+	clusterCmd.AddCommand(clusterAddNodeCmd)
+	clusterCmd.AddCommand(clusterRemoveNodeCmd)
+}
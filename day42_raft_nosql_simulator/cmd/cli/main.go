@@ -2,25 +2,23 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
 	"time"
 
-	"github.com/hashicorp/raft"
-
-	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/raft_node"
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/cluster"
 	// "github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/store"
 )
 
 var (
-	numNodes    int    = 3
+	numNodes    int    = 3 // 起動時のノード数
 	basePort    int    = 8000
 	dataDirBase string = "./data"
-	nodes       []*raft_node.Node
+	manager     *cluster.Manager
+
+	restoreSnapshotPath string // 指定された場合、node0 をこのスナップショットから復元して起動する
 )
 
 // SetDataDirBase はサーバーのデータディレクトリのベースパスを設定します。
@@ -33,82 +31,49 @@ func SetDataDirBase(newBase string) {
 }
 
 // runServer はRaftクラスタサーバーを起動・管理します。
+// node0 で単一ノードのクラスタをブートストラップした後、残りのノードを cluster.Manager 経由で追加します。
+// 起動後も `cluster add-node` / `cluster remove-node` (または /cluster/add-node, /cluster/remove-node) でノードを増減できます。
 func runServer() {
 	log.Println("Starting Raft cluster server...")
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	nodes = make([]*raft_node.Node, numNodes)
-
-	for i := 0; i < numNodes; i++ {
-		nodeID := fmt.Sprintf("node%d", i)
-		rpcAddr := fmt.Sprintf("127.0.0.1:%d", basePort+i)
-		httpApiPortOffset := 100 // Raftポートとのオフセット
-		httpApiAddr := fmt.Sprintf("127.0.0.1:%d", basePort+i+httpApiPortOffset)
-		nodeDataDir := filepath.Join(dataDirBase, nodeID)
-
-		if err := os.MkdirAll(filepath.Join(nodeDataDir, "snapshots"), 0755); err != nil {
-			log.Fatalf("Failed to create snapshot directory for node %s: %v", nodeID, err)
-		}
+	if numNodes < 1 {
+		log.Fatalf("Number of initial nodes must be at least 1, got %d", numNodes)
+	}
 
-		cfg := raft_node.Config{
-			NodeID:           raft.ServerID(nodeID),
-			Addr:             raft.ServerAddress(rpcAddr),
-			HttpApiAddr:      httpApiAddr, // HttpApiAddrを設定
-			DataDir:          nodeDataDir,
-			BootstrapCluster: i == 0,
-		}
+	httpApiPortOffset := 100 // Raftポートとのオフセット
+	manager = cluster.NewManager(dataDirBase, basePort, httpApiPortOffset)
 
-		transport, err := raft.NewTCPTransport(string(cfg.Addr), nil, 2, 5*time.Second, os.Stderr)
-		if err != nil {
-			log.Fatalf("Failed to create transport for node %s: %v", nodeID, err)
+	if restoreSnapshotPath != "" {
+		log.Printf("Restoring node0 from snapshot %s and waiting for leader election...", restoreSnapshotPath)
+		if err := manager.BootstrapFromSnapshot("node0", restoreSnapshotPath); err != nil {
+			log.Fatalf("Failed to bootstrap cluster from snapshot: %v", err)
 		}
-
-		n, err := raft_node.NewNode(cfg, transport)
-		if err != nil {
-			log.Fatalf("Failed to create node %s: %v", nodeID, err)
+	} else {
+		log.Println("Bootstrapping cluster and waiting for leader election...")
+		if err := manager.Bootstrap("node0"); err != nil {
+			log.Fatalf("Failed to bootstrap cluster: %v", err)
 		}
-		nodes[i] = n
-		log.Printf("Node %s created. Data dir: %s, Addr: %s, Bootstrap: %t", nodeID, nodeDataDir, rpcAddr, cfg.BootstrapCluster)
 	}
 
-	log.Println("Waiting for leader election...")
-	var leaderNode *raft_node.Node
-
-	if numNodes > 0 {
-		_, err := nodes[0].WaitForLeader(15 * time.Second)
+	for i := 1; i < numNodes; i++ {
+		nodeID, _, err := manager.AddNode("")
 		if err != nil {
-			log.Fatalf("Failed to find leader after 15 seconds: %v", err)
-		}
-	}
-
-	for _, node := range nodes {
-		if node.IsLeader() {
-			leaderNode = node
-			break
+			log.Printf("Warning: failed to add initial node %d to cluster: %v", i, err)
+			continue
 		}
+		log.Printf("Node %s added to cluster as voter.", nodeID)
 	}
 
-	if leaderNode == nil {
-		log.Fatalf("No leader elected after 15 seconds")
+	leaderNode, err := manager.Leader()
+	if err != nil {
+		log.Fatalf("No leader elected: %v", err)
 	}
 	log.Printf("Leader elected: Node ID=%s, Address=%s", leaderNode.NodeID(), leaderNode.RaftAddr())
 
-	for _, node := range nodes {
-		if node.NodeID() == leaderNode.NodeID() {
-			continue
-		}
-		log.Printf("Attempting to add voter %s (%s) to leader %s", node.NodeID(), node.RaftAddr(), leaderNode.NodeID())
-		err := leaderNode.AddVoter(node.GetConfig().NodeID, node.GetConfig().Addr, 0, 0)
-		if err != nil {
-			log.Printf("Warning: failed to add voter %s to cluster: %v", node.NodeID(), err)
-		} else {
-			log.Printf("Node %s added to cluster as voter.", node.NodeID())
-		}
-	}
-
-	for i, node := range nodes {
+	for i, node := range manager.Nodes() {
 		time.Sleep(1 * time.Second)
 		status := node.Stats()
 		leaderID, leaderAddr := node.LeaderWithID()
@@ -128,20 +93,7 @@ func runServer() {
 		log.Printf("Received signal: %v. Shutting down...", s)
 	}
 
-	for i := len(nodes) - 1; i >= 0; i-- {
-		n := nodes[i]
-		log.Printf("Shutting down node %s...", n.NodeID())
-		if err := n.Shutdown(); err != nil {
-			log.Printf("Error shutting down node %s: %v", n.NodeID(), err)
-		} else {
-			log.Printf("Node %s shutdown complete.", n.NodeID())
-		}
-		if transportToClose, ok := n.Transport().(interface{ Close() error }); ok {
-			if err := transportToClose.Close(); err != nil {
-				log.Printf("Error closing transport for node %s: %v", n.NodeID(), err)
-			}
-		}
-	}
+	manager.Shutdown()
 	log.Println("All nodes shut down. Exiting.")
 }
 
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var queryIndex string
+
+// queryCmd はKV APIのセカンダリインデックスを引くコマンドです。
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Find KV API keys whose indexed JSON field equals a value (e.g. --index city=Tokyo)",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		field, value, ok := strings.Cut(queryIndex, "=")
+		if !ok || field == "" {
+			fmt.Fprintf(os.Stderr, "Error: --index must be in the form field=value, got %q\n", queryIndex)
+			os.Exit(1)
+		}
+
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending index query %s=%s to %s...", field, value, targetNodeAddr)
+		resp, err := apiClient.QueryKVIndex(field, value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying index: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Found %d key(s) with %s=%s (raft index %d)\n", len(resp.Keys), resp.Field, resp.Value, resp.Index)
+		for _, key := range resp.Keys {
+			fmt.Println(key)
+		}
+	},
+}
+
+func init() {
+	queryCmd.Flags().StringVar(&queryIndex, "index", "", "Index condition in the form field=value (required)")
+	queryCmd.MarkFlagRequired("index")
+}
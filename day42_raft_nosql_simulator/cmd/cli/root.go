@@ -62,6 +62,7 @@ func init() {
 
 	// serverCmd にローカルフラグを追加
 	serverCmd.Flags().StringVar(&dataDirRoot, "data-dir-root", "./data", "Root directory for server data storage.")
+	serverCmd.Flags().IntVar(&numNodes, "nodes", 3, "Number of nodes to start the cluster with. More can be added later with 'cluster add-node'.")
 
 	rootCmd.AddCommand(serverCmd)
 
@@ -75,9 +76,27 @@ func init() {
 	rootCmd.AddCommand(deleteItemCmd)
 	rootCmd.AddCommand(queryItemsCmd)
 
+	// query.go のコマンドを追加
+	rootCmd.AddCommand(queryCmd)
+
+	// txn.go のコマンドを追加
+	rootCmd.AddCommand(txnCmd)
+
+	// watch.go のコマンドを追加
+	rootCmd.AddCommand(watchCmd)
+
 	// status.go のコマンドを追加
 	rootCmd.AddCommand(statusCmd)
 
+	// cluster.go のコマンドを追加
+	rootCmd.AddCommand(clusterCmd)
+
+	// snapshot.go のコマンドを追加
+	rootCmd.AddCommand(snapshotCmd)
+
+	// chaos.go のコマンドを追加
+	rootCmd.AddCommand(chaosCmd)
+
 	// ここに他のコマンド (table, itemなど) を追加していく
 	// rootCmd.AddCommand(tableCmd)
 	// rootCmd.AddCommand(itemCmd)
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// txnCmd は標準入力から読んだKV APIの操作を1つのバッチとして不可分に書き込むコマンドです。
+var txnCmd = &cobra.Command{
+	Use:   "txn",
+	Short: "Atomically apply KV operations read from stdin (one JSON operation per line)",
+	Long: `Reads KV operations from stdin, one JSON object per line, and applies them as a single
+atomic batch: either all operations are committed or none are.
+
+  {"op":"put","key":"users/1","value":{"name":"alice","city":"Tokyo"},"index":["city"]}
+  {"op":"put","key":"config/mode","value":"maintenance"}
+  {"op":"delete","key":"users/2"}
+
+Blank lines and lines starting with '#' are ignored.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ops, err := parseTxnOperations(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading operations: %v\n", err)
+			os.Exit(1)
+		}
+
+		apiClient := newClusterAPIClient()
+		log.Printf("Sending BatchWrite request with %d operation(s) to %s...", len(ops), targetNodeAddr)
+		resp, err := apiClient.BatchWrite(ops)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying batch (no operation was applied): %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s (raft index %d)\n", resp.Message, resp.Version)
+	},
+}
+
+// parseTxnOperations は1行に1つのJSONオブジェクトとして書かれた操作を読み込みます。
+func parseTxnOperations(r io.Reader) ([]client.BatchOperation, error) {
+	var ops []client.BatchOperation
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var op client.BatchOperation
+		if err := json.Unmarshal([]byte(line), &op); err != nil {
+			return nil, fmt.Errorf("line %d: invalid operation: %w", lineNo, err)
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no operations given on stdin")
+	}
+	return ops, nil
+}
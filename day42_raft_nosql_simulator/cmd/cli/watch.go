@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// errWatchLagged はサーバーが変更を配信し切れずにストリームを打ち切ったことを表します。
+var errWatchLagged = errors.New("watch fell behind and was closed by the server; re-read the keys and watch again")
+
+// watchCmd はKV APIのキーの変更を接続先ノードから受け取り続けるコマンドです。
+var watchCmd = &cobra.Command{
+	Use:   "watch [key-prefix]",
+	Short: "Stream committed changes to KV API keys with the given prefix from the target node",
+	Long: `Connects to GET /kv-watch on the target node and prints each committed change to KV API keys
+starting with the prefix (all keys if omitted), together with the Raft index that applied it.
+Changes are delivered as the target node applies them, so a follower may lag slightly behind the leader.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+
+		apiClient := newClusterAPIClient()
+		log.Printf("Watching keys with prefix %q on %s...", prefix, targetNodeAddr)
+		err := apiClient.WatchKV(prefix, printWatchEvent)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error watching keys: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "Watch stream closed by the server")
+	},
+}
+
+// printWatchEvent は受け取ったイベントを1行で表示します。
+func printWatchEvent(event client.KVWatchEvent) error {
+	switch event.Op {
+	case "ready":
+		fmt.Printf("Watching prefix %q on %s from raft index %d\n", event.Prefix, event.NodeID, event.Index)
+	case "put":
+		fmt.Printf("[%d] put %s = %s\n", event.Index, event.Key, event.Value)
+	case "delete":
+		fmt.Printf("[%d] delete %s\n", event.Index, event.Key)
+	case "reset":
+		fmt.Println("[-] reset: the node was restored from a snapshot; re-read the keys")
+	case "lagged":
+		return errWatchLagged
+	default:
+		fmt.Printf("[%d] %s %s\n", event.Index, event.Op, event.Key)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package chaos
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/raft"
+)
+
+// Network は同一プロセス内のノード間のRaft通信について、ネットワーク分断の状態を管理します。
+// 各ノードのトランスポートは Wrap で包まれ、送信前に Blocked で遮断されているかを確認します。
+// 送信側で遮断するため、分断は常に双方向です。
+type Network struct {
+	mu     sync.RWMutex
+	groups map[raft.ServerID]int // ノードID -> パーティショングループ番号。空なら分断なし
+}
+
+// NewNetwork は分断のない Network を作成します。
+func NewNetwork() *Network {
+	return &Network{groups: make(map[raft.ServerID]int)}
+}
+
+// Partition はノードをグループに分け、異なるグループのノード間の通信を遮断します。
+// どのグループにも含まれないノードの通信は遮断されません。既存の分断は置き換えられます。
+func (n *Network) Partition(groups [][]raft.ServerID) error {
+	if len(groups) < 2 {
+		return fmt.Errorf("partition needs at least 2 groups, got %d", len(groups))
+	}
+	assigned := make(map[raft.ServerID]int)
+	for i, group := range groups {
+		if len(group) == 0 {
+			return fmt.Errorf("partition group %d is empty", i+1)
+		}
+		for _, id := range group {
+			if prev, ok := assigned[id]; ok {
+				return fmt.Errorf("node %s appears in both group %d and group %d", id, prev+1, i+1)
+			}
+			assigned[id] = i
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.groups = assigned
+	return nil
+}
+
+// Heal はすべての分断を解消します。
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.groups = make(map[raft.ServerID]int)
+}
+
+// Blocked は from から to への通信が遮断されているかを返します。
+func (n *Network) Blocked(from, to raft.ServerID) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	fromGroup, fromOK := n.groups[from]
+	toGroup, toOK := n.groups[to]
+	return fromOK && toOK && fromGroup != toGroup
+}
+
+// Groups は現在のパーティショングループを返します。分断がない場合は nil を返します。
+func (n *Network) Groups() [][]raft.ServerID {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if len(n.groups) == 0 {
+		return nil
+	}
+
+	numGroups := 0
+	for _, g := range n.groups {
+		if g+1 > numGroups {
+			numGroups = g + 1
+		}
+	}
+	groups := make([][]raft.ServerID, numGroups)
+	for id, g := range n.groups {
+		groups[g] = append(groups[g], id)
+	}
+	for _, group := range groups {
+		sort.Slice(group, func(i, j int) bool { return group[i] < group[j] })
+	}
+	return groups
+}
+
+// Wrap は localID のノードのトランスポートを、このネットワークの分断状態に従うトランスポートで包みます。
+func (n *Network) Wrap(localID raft.ServerID, trans raft.Transport) *Transport {
+	return &Transport{Transport: trans, localID: localID, network: n}
+}
@@ -0,0 +1,69 @@
+package chaos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_PartitionAndHeal(t *testing.T) {
+	n := NewNetwork()
+	require.False(t, n.Blocked("node0", "node1"), "No traffic should be blocked initially")
+	require.Nil(t, n.Groups())
+
+	require.NoError(t, n.Partition([][]raft.ServerID{{"node1", "node0"}, {"node2"}}))
+	require.False(t, n.Blocked("node0", "node1"), "Nodes in the same group can talk")
+	require.True(t, n.Blocked("node0", "node2"))
+	require.True(t, n.Blocked("node2", "node1"), "Partitions are symmetric")
+	require.False(t, n.Blocked("node3", "node2"), "Nodes outside any group are not affected")
+	require.Equal(t, [][]raft.ServerID{{"node0", "node1"}, {"node2"}}, n.Groups())
+
+	t.Run("Invalid partitions are rejected and keep the current state", func(t *testing.T) {
+		require.Error(t, n.Partition([][]raft.ServerID{{"node0", "node1"}}), "A single group is not a partition")
+		require.Error(t, n.Partition([][]raft.ServerID{{"node0"}, {}}), "Empty groups are rejected")
+		require.Error(t, n.Partition([][]raft.ServerID{{"node0"}, {"node0", "node1"}}), "A node cannot be in two groups")
+		require.True(t, n.Blocked("node0", "node2"))
+	})
+
+	n.Heal()
+	require.False(t, n.Blocked("node0", "node2"))
+	require.Nil(t, n.Groups())
+}
+
+func TestTransport_DropsTrafficAcrossPartition(t *testing.T) {
+	n := NewNetwork()
+
+	addr0, inmem0 := raft.NewInmemTransport("")
+	addr1, inmem1 := raft.NewInmemTransport("")
+	inmem0.Connect(addr1, inmem1)
+	inmem1.Connect(addr0, inmem0)
+	trans0 := n.Wrap("node0", inmem0)
+	defer trans0.Close()
+	defer inmem1.Close()
+
+	// node1 側で RPC に応答する
+	go func() {
+		for rpc := range inmem1.Consumer() {
+			rpc.Respond(&raft.RequestVoteResponse{Granted: true}, nil)
+		}
+	}()
+
+	var resp raft.RequestVoteResponse
+	require.NoError(t, trans0.RequestVote("node1", addr1, &raft.RequestVoteRequest{}, &resp))
+	require.True(t, resp.Granted)
+
+	require.NoError(t, n.Partition([][]raft.ServerID{{"node0"}, {"node1"}}))
+	err := trans0.RequestVote("node1", addr1, &raft.RequestVoteRequest{}, &raft.RequestVoteResponse{})
+	require.True(t, errors.Is(err, ErrPartitioned), "RequestVote should be dropped, got %v", err)
+	err = trans0.AppendEntries("node1", addr1, &raft.AppendEntriesRequest{}, &raft.AppendEntriesResponse{})
+	require.True(t, errors.Is(err, ErrPartitioned), "AppendEntries should be dropped, got %v", err)
+	_, err = trans0.AppendEntriesPipeline("node1", addr1)
+	require.True(t, errors.Is(err, ErrPartitioned), "Pipeline should not be created, got %v", err)
+
+	n.Heal()
+	resp = raft.RequestVoteResponse{}
+	require.NoError(t, trans0.RequestVote("node1", addr1, &raft.RequestVoteRequest{}, &resp))
+	require.True(t, resp.Granted)
+}
@@ -0,0 +1,112 @@
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/raft"
+)
+
+// ErrPartitioned は分断により送信先への通信が遮断された場合に返されるエラーです。
+var ErrPartitioned = errors.New("traffic dropped by network partition")
+
+// Transport は raft.Transport を包み、Network で遮断されている相手へのRPCを送信せずに失敗させます。
+// 遮断中のRPCは即座にエラーになるため、タイムアウトを待たずに選挙やレプリケーションの失敗を再現できます。
+type Transport struct {
+	raft.Transport
+	localID raft.ServerID
+	network *Network
+}
+
+// Compile-time check to ensure Transport keeps the optional interfaces of raft.NetworkTransport.
+var (
+	_ raft.WithClose   = (*Transport)(nil)
+	_ raft.WithPreVote = (*Transport)(nil)
+)
+
+func (t *Transport) check(id raft.ServerID) error {
+	if t.network.Blocked(t.localID, id) {
+		return fmt.Errorf("%s -> %s: %w", t.localID, id, ErrPartitioned)
+	}
+	return nil
+}
+
+// AppendEntriesPipeline は遮断されていなければパイプラインを作成します。
+// 作成済みのパイプラインも、後から分断された場合は送信時に失敗します。
+func (t *Transport) AppendEntriesPipeline(id raft.ServerID, target raft.ServerAddress) (raft.AppendPipeline, error) {
+	if err := t.check(id); err != nil {
+		return nil, err
+	}
+	pipeline, err := t.Transport.AppendEntriesPipeline(id, target)
+	if err != nil {
+		return nil, err
+	}
+	return &appendPipeline{AppendPipeline: pipeline, transport: t, id: id}, nil
+}
+
+// AppendEntries は遮断されていなければ AppendEntries RPC を送信します。
+func (t *Transport) AppendEntries(id raft.ServerID, target raft.ServerAddress, args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) error {
+	if err := t.check(id); err != nil {
+		return err
+	}
+	return t.Transport.AppendEntries(id, target, args, resp)
+}
+
+// RequestVote は遮断されていなければ RequestVote RPC を送信します。
+func (t *Transport) RequestVote(id raft.ServerID, target raft.ServerAddress, args *raft.RequestVoteRequest, resp *raft.RequestVoteResponse) error {
+	if err := t.check(id); err != nil {
+		return err
+	}
+	return t.Transport.RequestVote(id, target, args, resp)
+}
+
+// RequestPreVote は遮断されていなければ RequestPreVote RPC を送信します。
+func (t *Transport) RequestPreVote(id raft.ServerID, target raft.ServerAddress, args *raft.RequestPreVoteRequest, resp *raft.RequestPreVoteResponse) error {
+	if err := t.check(id); err != nil {
+		return err
+	}
+	preVoteTrans, ok := t.Transport.(raft.WithPreVote)
+	if !ok {
+		return fmt.Errorf("underlying transport does not support pre-vote")
+	}
+	return preVoteTrans.RequestPreVote(id, target, args, resp)
+}
+
+// InstallSnapshot は遮断されていなければスナップショットを送信します。
+func (t *Transport) InstallSnapshot(id raft.ServerID, target raft.ServerAddress, args *raft.InstallSnapshotRequest, resp *raft.InstallSnapshotResponse, data io.Reader) error {
+	if err := t.check(id); err != nil {
+		return err
+	}
+	return t.Transport.InstallSnapshot(id, target, args, resp, data)
+}
+
+// TimeoutNow は遮断されていなければ TimeoutNow RPC を送信します。
+func (t *Transport) TimeoutNow(id raft.ServerID, target raft.ServerAddress, args *raft.TimeoutNowRequest, resp *raft.TimeoutNowResponse) error {
+	if err := t.check(id); err != nil {
+		return err
+	}
+	return t.Transport.TimeoutNow(id, target, args, resp)
+}
+
+// Close は包んでいるトランスポートを閉じます。
+func (t *Transport) Close() error {
+	if closer, ok := t.Transport.(raft.WithClose); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// appendPipeline はパイプライン経由の AppendEntries にも分断を適用します。
+type appendPipeline struct {
+	raft.AppendPipeline
+	transport *Transport
+	id        raft.ServerID
+}
+
+func (p *appendPipeline) AppendEntries(args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) (raft.AppendFuture, error) {
+	if err := p.transport.check(p.id); err != nil {
+		return nil, err
+	}
+	return p.AppendPipeline.AppendEntries(args, resp)
+}
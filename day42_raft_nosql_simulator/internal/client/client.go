@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -122,6 +124,93 @@ type APISuccessResponse struct {
 	Tables      []string                 `json:"tables,omitempty"`       // For ListTables
 }
 
+// ClusterNodeRequest はクラスタへのノード追加・削除APIへのリクエストボディです。
+type ClusterNodeRequest struct {
+	NodeID string `json:"node_id,omitempty"`
+}
+
+// ClusterMember はクラスタ構成に含まれる1ノードの情報です。
+type ClusterMember struct {
+	ID          string `json:"id"`
+	RaftAddr    string `json:"raft_addr"`
+	HttpApiAddr string `json:"http_api_addr,omitempty"`
+	Suffrage    string `json:"suffrage"`
+	IsLeader    bool   `json:"is_leader"`
+}
+
+// SnapshotInfo はノードのローカルに保存されたRaftスナップショットの情報です。
+type SnapshotInfo struct {
+	ID    string `json:"id"`
+	Index uint64 `json:"index"`
+	Term  uint64 `json:"term"`
+	Size  int64  `json:"size"`
+	Path  string `json:"path"`
+}
+
+// SnapshotResponse はスナップショットAPIのレスポンスです。
+type SnapshotResponse struct {
+	Message   string         `json:"message"`
+	NodeID    string         `json:"node_id"`
+	Snapshots []SnapshotInfo `json:"snapshots"`
+}
+
+// ClusterResponse はクラスタ構成APIのレスポンスです。
+type ClusterResponse struct {
+	Message string          `json:"message"`
+	NodeID  string          `json:"node_id,omitempty"`
+	Servers []ClusterMember `json:"servers"`
+}
+
+// BatchOperation はバッチ書き込みの1操作です。op は "put" または "delete" です。
+// value にJSON文字列を指定するとその文字列が、オブジェクトなどを指定するとそのJSONテキストが値として保存されます。
+type BatchOperation struct {
+	Op    string          `json:"op"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Index []string        `json:"index,omitempty"`
+}
+
+// BatchWriteRequest はバッチ書き込みAPIへのリクエストボディです。
+type BatchWriteRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// KVIndexQueryResponse はKV APIのセカンダリインデックスのクエリ結果です。
+type KVIndexQueryResponse struct {
+	Field string   `json:"field"`
+	Value string   `json:"value"`
+	Keys  []string `json:"keys"`
+	Index uint64   `json:"index"`
+}
+
+// ChaosPartitionRequest はネットワーク分断APIへのリクエストボディです。
+type ChaosPartitionRequest struct {
+	Groups [][]string `json:"groups"`
+}
+
+// ChaosStatus は障害注入の現在の状態です。
+type ChaosStatus struct {
+	Partitions  [][]string `json:"partitions,omitempty"`
+	KilledNodes []string   `json:"killed_nodes,omitempty"`
+}
+
+// KVWatchEvent は GET /kv-watch で配信されるイベントです。
+// Op は ready (開始), put, delete, reset (スナップショットからの復元), lagged (取りこぼしによる打ち切り) のいずれかです。
+type KVWatchEvent struct {
+	Op     string `json:"op"`
+	Key    string `json:"key,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Index  uint64 `json:"index"`
+	NodeID string `json:"node_id,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// ChaosResponse は障害注入APIのレスポンスです。
+type ChaosResponse struct {
+	Message string      `json:"message"`
+	Status  ChaosStatus `json:"status"`
+}
+
 // APIErrorResponse はエラー時のAPIレスポンスです。
 type APIErrorResponse struct {
 	Error       string `json:"error"`
@@ -288,6 +377,181 @@ func (c *APIClient) QueryItems(tableName, partitionKey, sortKeyPrefix string) (*
 	return &apiResp, nil
 }
 
+// ClusterConfiguration はターゲットノードから見た現在のクラスタ構成を取得します。
+func (c *APIClient) ClusterConfiguration() (*ClusterResponse, error) {
+	var apiResp ClusterResponse
+	if err := c.makeRequest(http.MethodGet, "/cluster", nil, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// AddNode は新しいノードを起動してクラスタに追加するようリクエストします。nodeID が空の場合はサーバー側で採番されます。
+func (c *APIClient) AddNode(nodeID string) (*ClusterResponse, error) {
+	var apiResp ClusterResponse
+	if err := c.makeRequest(http.MethodPost, "/cluster/add-node", ClusterNodeRequest{NodeID: nodeID}, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// RemoveNode は指定ノードをクラスタから削除して停止するようリクエストします。
+func (c *APIClient) RemoveNode(nodeID string) (*ClusterResponse, error) {
+	if nodeID == "" {
+		return nil, errors.New("node ID cannot be empty for RemoveNode")
+	}
+	var apiResp ClusterResponse
+	if err := c.makeRequest(http.MethodPost, "/cluster/remove-node", ClusterNodeRequest{NodeID: nodeID}, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// TakeSnapshot はターゲットノードにRaftスナップショットを作成させます。
+func (c *APIClient) TakeSnapshot() (*SnapshotResponse, error) {
+	var apiResp SnapshotResponse
+	if err := c.makeRequest(http.MethodPost, "/snapshot", nil, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// ListSnapshots はターゲットノードに保存されているスナップショットの一覧を取得します。
+func (c *APIClient) ListSnapshots() (*SnapshotResponse, error) {
+	var apiResp SnapshotResponse
+	if err := c.makeRequest(http.MethodGet, "/snapshots", nil, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// BatchWrite は複数キーへの書き込みを1つのRaftログとして不可分に適用するようリクエストします。
+// レスポンスの Version には適用されたRaftログのインデックスが入ります。
+func (c *APIClient) BatchWrite(ops []BatchOperation) (*APISuccessResponse, error) {
+	if len(ops) == 0 {
+		return nil, errors.New("batch must contain at least one operation")
+	}
+	var apiResp APISuccessResponse
+	if err := c.makeRequest(http.MethodPost, "/kv/batch", BatchWriteRequest{Operations: ops}, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// QueryKVIndex はセカンダリインデックスで field の値が value であるKV APIのキーを取得します。
+// フォロワーに送った場合もリーダーへ転送され、コミット済みの書き込みをすべて反映した結果が返ります。
+func (c *APIClient) QueryKVIndex(field, value string) (*KVIndexQueryResponse, error) {
+	if field == "" {
+		return nil, errors.New("index field cannot be empty")
+	}
+	query := url.Values{"field": {field}, "value": {value}}
+	var apiResp KVIndexQueryResponse
+	if err := c.makeRequest(http.MethodGet, "/kv-index?"+query.Encode(), nil, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// WatchKV は接続先ノードの GET /kv-watch に接続し、prefix で始まるキーの変更を受け取るたびに handle を呼び出します。
+// handle がエラーを返すかサーバーがストリームを閉じるまで戻りません。サーバーが閉じた場合は nil を返します。
+func (c *APIClient) WatchKV(prefix string, handle func(KVWatchEvent) error) error {
+	query := url.Values{"prefix": {prefix}}
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/kv-watch?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// ストリームは長時間続くため、タイムアウト付きの c.httpClient は使わない
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return c.handleErrorResponse(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "": // 空行でイベントが確定する
+			if data.Len() == 0 {
+				continue
+			}
+			var event KVWatchEvent
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return fmt.Errorf("failed to parse watch event: %w. Data: %s", err, data.String())
+			}
+			data.Reset()
+			if err := handle(event); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		default: // event: / id: はデータと重複するため読み飛ばし、":" で始まる行はコメント
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("watch stream from %s failed: %w", c.baseURL, err)
+	}
+	return nil
+}
+
+// ChaosStatus は現在のネットワーク分断と停止中のノードを取得します。
+func (c *APIClient) ChaosStatus() (*ChaosResponse, error) {
+	var apiResp ChaosResponse
+	if err := c.makeRequest(http.MethodGet, "/chaos", nil, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// Partition はノードをグループに分け、異なるグループ間のRaft通信を遮断するようリクエストします。
+func (c *APIClient) Partition(groups [][]string) (*ChaosResponse, error) {
+	var apiResp ChaosResponse
+	if err := c.makeRequest(http.MethodPost, "/chaos/partition", ChaosPartitionRequest{Groups: groups}, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// Heal はすべてのネットワーク分断を解消するようリクエストします。
+func (c *APIClient) Heal() (*ChaosResponse, error) {
+	var apiResp ChaosResponse
+	if err := c.makeRequest(http.MethodPost, "/chaos/heal", nil, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// KillNode は指定ノードをクラスタ構成に残したまま停止するようリクエストします。
+func (c *APIClient) KillNode(nodeID string) (*ChaosResponse, error) {
+	if nodeID == "" {
+		return nil, errors.New("node ID cannot be empty for KillNode")
+	}
+	var apiResp ChaosResponse
+	if err := c.makeRequest(http.MethodPost, "/chaos/kill", ClusterNodeRequest{NodeID: nodeID}, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
+// RestartNode は KillNode で停止したノードを再起動するようリクエストします。
+func (c *APIClient) RestartNode(nodeID string) (*ChaosResponse, error) {
+	if nodeID == "" {
+		return nil, errors.New("node ID cannot be empty for RestartNode")
+	}
+	var apiResp ChaosResponse
+	if err := c.makeRequest(http.MethodPost, "/chaos/restart", ClusterNodeRequest{NodeID: nodeID}, &apiResp); err != nil {
+		return nil, err
+	}
+	return &apiResp, nil
+}
+
 func (c *APIClient) makeRequest(method, path string, body interface{}, responseDest interface{}) error {
 	return c.makeRequestRecursive(method, path, body, responseDest, 0)
 }
@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/server"
+)
+
+// Compile-time check to ensure Manager implements server.ChaosController.
+var _ server.ChaosController = (*Manager)(nil)
+
+// Partition はノードをグループに分け、異なるグループのノード間のRaft通信を遮断します。
+// 既存の分断は置き換えられます。どのグループにも含まれないノードは全ノードと通信できます。
+// 遮断するのはRaftのRPCのみで、HTTP APIによるリーダーへの転送は遮断しません。
+func (m *Manager) Partition(groups [][]string) (server.ChaosStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	serverGroups := make([][]raft.ServerID, 0, len(groups))
+	for _, group := range groups {
+		ids := make([]raft.ServerID, 0, len(group))
+		for _, nodeID := range group {
+			id := raft.ServerID(nodeID)
+			if _, ok := m.nodes[id]; !ok && !m.isKilledLocked(id) {
+				return server.ChaosStatus{}, fmt.Errorf("node %s is not managed by this cluster", nodeID)
+			}
+			ids = append(ids, id)
+		}
+		serverGroups = append(serverGroups, ids)
+	}
+	if err := m.network.Partition(serverGroups); err != nil {
+		return server.ChaosStatus{}, err
+	}
+	log.Printf("[INFO] [Cluster] Network partitioned: %v", groups)
+	return m.chaosStatusLocked(), nil
+}
+
+// Heal はすべてのネットワーク分断を解消します。
+func (m *Manager) Heal() server.ChaosStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.network.Heal()
+	log.Printf("[INFO] [Cluster] Network partitions healed")
+	return m.chaosStatusLocked()
+}
+
+// KillNode はノードをクラスタ構成に残したまま停止します。データディレクトリは保持され、RestartNode で再起動できます。
+// このリクエスト自体を停止対象ノードのHTTP APIが処理している可能性があるため、停止は非同期で行います。
+func (m *Manager) KillNode(nodeID string) (server.ChaosStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := raft.ServerID(nodeID)
+	mn, ok := m.nodes[id]
+	if !ok {
+		if m.isKilledLocked(id) {
+			return server.ChaosStatus{}, fmt.Errorf("node %s is already killed", nodeID)
+		}
+		return server.ChaosStatus{}, fmt.Errorf("node %s is not managed by this cluster", nodeID)
+	}
+	if len(m.nodes) == 1 {
+		return server.ChaosStatus{}, fmt.Errorf("cannot kill the last running node %s", nodeID)
+	}
+
+	log.Printf("[INFO] [Cluster] Killing node %s (leader: %t)", id, mn.node.IsLeader())
+	m.killed[id] = mn.slot
+	m.stopNodeLocked(id, true, false)
+	return m.chaosStatusLocked(), nil
+}
+
+// RestartNode は KillNode で停止したノードを同じスロット (アドレス) とデータディレクトリで再起動します。
+// ノードは保存済みのRaftログとスナップショットから復帰し、リーダーから不足分を受け取って追いつきます。
+func (m *Manager) RestartNode(nodeID string) (server.ChaosStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := raft.ServerID(nodeID)
+	slot, ok := m.killed[id]
+	if !ok {
+		if _, running := m.nodes[id]; running {
+			return server.ChaosStatus{}, fmt.Errorf("node %s is already running", nodeID)
+		}
+		return server.ChaosStatus{}, fmt.Errorf("node %s is not killed", nodeID)
+	}
+	if m.isStoppingLocked(id) {
+		return server.ChaosStatus{}, fmt.Errorf("node %s is still shutting down, retry later", nodeID)
+	}
+
+	if _, err := m.startNodeLocked(id, slot, false, ""); err != nil {
+		return server.ChaosStatus{}, err
+	}
+	delete(m.killed, id)
+	log.Printf("[INFO] [Cluster] Node %s restarted", id)
+	return m.chaosStatusLocked(), nil
+}
+
+// ChaosStatus は現在のネットワーク分断と停止中のノードを返します。
+func (m *Manager) ChaosStatus() server.ChaosStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.chaosStatusLocked()
+}
+
+func (m *Manager) chaosStatusLocked() server.ChaosStatus {
+	var status server.ChaosStatus
+	for _, group := range m.network.Groups() {
+		ids := make([]string, len(group))
+		for i, id := range group {
+			ids[i] = string(id)
+		}
+		status.Partitions = append(status.Partitions, ids)
+	}
+	for id := range m.killed {
+		status.KilledNodes = append(status.KilledNodes, string(id))
+	}
+	sort.Strings(status.KilledNodes)
+	return status
+}
@@ -0,0 +1,393 @@
+package cluster
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/chaos"
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/raft_node"
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/server"
+)
+
+const (
+	membershipChangeTimeout = 10 * time.Second // AddVoter/RemoveServer のタイムアウト
+	leaderWaitTimeout       = 15 * time.Second // リーダー選出待ちのタイムアウト
+)
+
+// Manager は同一プロセス内で動作するRaftノード群を所有し、
+// ノードの起動・停止とRaftクラスタ構成の変更 (AddVoter/RemoveServer) をまとめて行います。
+// 各ノードのHTTP APIに server.ClusterManager として登録され、/cluster/add-node などから呼び出されます。
+// また server.ChaosController として、ネットワーク分断やノードの停止・再起動による障害注入も行います。
+//
+// ポートはスロット番号から決まります: Raft = basePort+slot, HTTP API = basePort+slot+httpApiPortOffset。
+type Manager struct {
+	mu                sync.Mutex
+	host              string
+	dataDirBase       string
+	basePort          int
+	httpApiPortOffset int
+	nodes             map[raft.ServerID]*managedNode
+	stopping          map[raft.ServerID]int // 停止処理中のノードID -> スロット (停止完了までIDとポートを再利用しない)
+	killed            map[raft.ServerID]int // KillNode で停止したノードID -> スロット (クラスタ構成には残っている)
+	stopWg            sync.WaitGroup
+	network           *chaos.Network // 全ノードのトランスポートが共有するネットワーク分断の状態
+}
+
+type managedNode struct {
+	node *raft_node.Node
+	slot int
+}
+
+// NewManager は新しい Manager を作成します。ノードはまだ起動しません。
+func NewManager(dataDirBase string, basePort, httpApiPortOffset int) *Manager {
+	return &Manager{
+		host:              "127.0.0.1",
+		dataDirBase:       dataDirBase,
+		basePort:          basePort,
+		httpApiPortOffset: httpApiPortOffset,
+		nodes:             make(map[raft.ServerID]*managedNode),
+		stopping:          make(map[raft.ServerID]int),
+		killed:            make(map[raft.ServerID]int),
+		network:           chaos.NewNetwork(),
+	}
+}
+
+// Bootstrap は nodeID のノードを起動して単一ノードのクラスタをブートストラップし、リーダーになるまで待ちます。
+func (m *Manager) Bootstrap(nodeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.nodes) > 0 {
+		return fmt.Errorf("cluster is already bootstrapped")
+	}
+	if _, err := m.startNodeLocked(raft.ServerID(nodeID), 0, true, ""); err != nil {
+		return err
+	}
+	_, err := m.waitForLeaderLocked(leaderWaitTimeout)
+	return err
+}
+
+// BootstrapFromSnapshot は nodeID のノードをスナップショットから復元し、そのノードだけのクラスタとして起動します。
+// スナップショットの元のクラスタ構成は破棄されます。以降 AddNode したノードはリーダーからスナップショットを受け取って同期します。
+func (m *Manager) BootstrapFromSnapshot(nodeID, snapshotPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.nodes) > 0 {
+		return fmt.Errorf("cluster is already bootstrapped")
+	}
+	if _, err := m.startNodeLocked(raft.ServerID(nodeID), 0, false, snapshotPath); err != nil {
+		return err
+	}
+	_, err := m.waitForLeaderLocked(leaderWaitTimeout)
+	return err
+}
+
+// AddNode はノードを起動し、リーダーに AddVoter させて投票メンバーとして追加します。
+// nodeID が空の場合は "node<スロット番号>" を自動採番します。追加後のクラスタ構成を返します。
+func (m *Manager) AddNode(nodeID string) (string, []server.ClusterMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	leader, err := m.waitForLeaderLocked(leaderWaitTimeout)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id, slot, err := m.allocateLocked(nodeID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	n, err := m.startNodeLocked(id, slot, false, "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	log.Printf("[INFO] [Cluster] Adding voter %s (%s) via leader %s", id, n.RaftAddr(), leader.NodeID())
+	if err := leader.AddVoter(id, n.RaftAddr(), 0, membershipChangeTimeout); err != nil {
+		m.stopNodeLocked(id, false, false)
+		return "", nil, fmt.Errorf("failed to add voter %s via leader %s: %w", id, leader.NodeID(), err)
+	}
+
+	// 既存ノードが新ノードへ書き込みを転送できるよう、HTTP APIアドレスを共有する
+	for otherID, other := range m.nodes {
+		if otherID != id {
+			other.node.SetPeerHttpApiAddr(id, n.GetConfig().HttpApiAddr)
+		}
+	}
+
+	servers, err := leader.ClusterConfiguration()
+	return string(id), servers, err
+}
+
+// RemoveNode はリーダーに RemoveServer させてノードをクラスタから削除し、そのノードを停止します。
+// 削除したノードのデータディレクトリも削除するため、同じIDで再追加すると空の状態から同期されます。
+// 削除対象がリーダーだった場合は、残りのノードで新しいリーダーが選出されるまで待ちます。
+// KillNode で停止中のノードも削除できます。
+func (m *Manager) RemoveNode(nodeID string) ([]server.ClusterMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := raft.ServerID(nodeID)
+	_, running := m.nodes[id]
+	_, killed := m.killed[id]
+	if !running && !killed {
+		return nil, fmt.Errorf("node %s is not managed by this cluster", nodeID)
+	}
+	if running && len(m.nodes) == 1 {
+		return nil, fmt.Errorf("cannot remove the last node %s", nodeID)
+	}
+	if killed && m.isStoppingLocked(id) {
+		return nil, fmt.Errorf("node %s is still shutting down, retry later", nodeID)
+	}
+
+	leader, err := m.waitForLeaderLocked(leaderWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] [Cluster] Removing server %s via leader %s", id, leader.NodeID())
+	if err := leader.RemoveServer(id, 0, membershipChangeTimeout); err != nil {
+		return nil, fmt.Errorf("failed to remove server %s via leader %s: %w", id, leader.NodeID(), err)
+	}
+
+	if killed {
+		delete(m.killed, id)
+		if err := os.RemoveAll(m.nodeDataDir(id)); err != nil {
+			log.Printf("[WARN] [Cluster] Failed to remove data dir of node %s: %v", id, err)
+		}
+	} else {
+		// このリクエスト自体を削除対象ノードのHTTP APIが処理している可能性があるため、停止は非同期で行う
+		m.stopNodeLocked(id, true, true)
+	}
+
+	newLeader, err := m.waitForLeaderLocked(leaderWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newLeader.ClusterConfiguration()
+}
+
+// Nodes は管理中のノードをスロット順に返します。
+func (m *Manager) Nodes() []*raft_node.Node {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	managed := make([]*managedNode, 0, len(m.nodes))
+	for _, mn := range m.nodes {
+		managed = append(managed, mn)
+	}
+	sort.Slice(managed, func(i, j int) bool { return managed[i].slot < managed[j].slot })
+
+	nodes := make([]*raft_node.Node, len(managed))
+	for i, mn := range managed {
+		nodes[i] = mn.node
+	}
+	return nodes
+}
+
+// Leader は管理中のノードのうち、現在リーダーであるノードを返します。リーダーが選出されるまで待ちます。
+func (m *Manager) Leader() (*raft_node.Node, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.waitForLeaderLocked(leaderWaitTimeout)
+}
+
+// Shutdown は管理中のすべてのノードを停止します。非同期で停止中のノードの完了も待ちます。
+func (m *Manager) Shutdown() {
+	nodes := m.Nodes()
+
+	m.mu.Lock()
+	for i := len(nodes) - 1; i >= 0; i-- {
+		m.stopNodeLocked(nodes[i].GetConfig().NodeID, false, false)
+	}
+	m.mu.Unlock()
+
+	m.stopWg.Wait()
+}
+
+// allocateLocked はノードIDとスロットを決定します。
+// 停止中のノードのスロットは、再起動や削除に備えて使用中として扱います。
+func (m *Manager) allocateLocked(nodeID string) (raft.ServerID, int, error) {
+	used := make(map[int]bool, len(m.nodes)+len(m.stopping)+len(m.killed))
+	for _, mn := range m.nodes {
+		used[mn.slot] = true
+	}
+	for _, slot := range m.stopping {
+		used[slot] = true
+	}
+	for _, slot := range m.killed {
+		used[slot] = true
+	}
+
+	if nodeID == "" {
+		for slot := 0; ; slot++ {
+			id := raft.ServerID(fmt.Sprintf("node%d", slot))
+			if !used[slot] && m.nodes[id] == nil && !m.isStoppingLocked(id) && !m.isKilledLocked(id) {
+				return id, slot, nil
+			}
+		}
+	}
+
+	id := raft.ServerID(nodeID)
+	if _, ok := m.nodes[id]; ok {
+		return "", 0, fmt.Errorf("node %s already exists", nodeID)
+	}
+	if m.isKilledLocked(id) {
+		return "", 0, fmt.Errorf("node %s is killed, use restart instead", nodeID)
+	}
+	if m.isStoppingLocked(id) {
+		return "", 0, fmt.Errorf("node %s is still shutting down, retry later", nodeID)
+	}
+	slot := 0
+	for used[slot] {
+		slot++
+	}
+	return id, slot, nil
+}
+
+func (m *Manager) isStoppingLocked(id raft.ServerID) bool {
+	_, ok := m.stopping[id]
+	return ok
+}
+
+func (m *Manager) isKilledLocked(id raft.ServerID) bool {
+	_, ok := m.killed[id]
+	return ok
+}
+
+func (m *Manager) nodeDataDir(id raft.ServerID) string {
+	return filepath.Join(m.dataDirBase, string(id))
+}
+
+func (m *Manager) httpApiAddr(slot int) string {
+	return fmt.Sprintf("%s:%d", m.host, m.basePort+slot+m.httpApiPortOffset)
+}
+
+// startNodeLocked はスロットに対応するポートでノードを起動し、管理対象に加えます。
+// snapshotPath が指定された場合は、起動前にデータディレクトリをそのスナップショットから復元します。
+// トランスポートは m.network で包まれ、Partition による分断の対象になります。
+func (m *Manager) startNodeLocked(id raft.ServerID, slot int, bootstrap bool, snapshotPath string) (*raft_node.Node, error) {
+	rpcAddr := fmt.Sprintf("%s:%d", m.host, m.basePort+slot)
+	httpApiAddr := m.httpApiAddr(slot)
+	nodeDataDir := m.nodeDataDir(id)
+
+	if err := os.MkdirAll(filepath.Join(nodeDataDir, "snapshots"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory for node %s: %w", id, err)
+	}
+
+	peerHttpApiAddrs := make(map[raft.ServerID]string, len(m.nodes)+len(m.killed))
+	for otherID, other := range m.nodes {
+		peerHttpApiAddrs[otherID] = other.node.GetConfig().HttpApiAddr
+	}
+	for otherID, otherSlot := range m.killed {
+		if otherID != id {
+			peerHttpApiAddrs[otherID] = m.httpApiAddr(otherSlot)
+		}
+	}
+
+	cfg := raft_node.Config{
+		NodeID:           id,
+		Addr:             raft.ServerAddress(rpcAddr),
+		HttpApiAddr:      httpApiAddr,
+		DataDir:          nodeDataDir,
+		BootstrapCluster: bootstrap,
+		PeerHttpApiAddrs: peerHttpApiAddrs,
+	}
+
+	tcpTransport, err := raft.NewTCPTransport(rpcAddr, nil, 2, 5*time.Second, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport for node %s: %w", id, err)
+	}
+	transport := m.network.Wrap(id, tcpTransport)
+
+	if snapshotPath != "" {
+		if _, err := raft_node.SeedDataDirFromSnapshot(cfg, transport, snapshotPath); err != nil {
+			transport.Close()
+			return nil, fmt.Errorf("failed to restore node %s from snapshot: %w", id, err)
+		}
+	}
+
+	n, err := raft_node.NewNode(cfg, transport)
+	if err != nil {
+		transport.Close()
+		return nil, fmt.Errorf("failed to create node %s: %w", id, err)
+	}
+	n.SetClusterManager(m)
+	m.nodes[id] = &managedNode{node: n, slot: slot}
+	log.Printf("[INFO] [Cluster] Node %s started. Data dir: %s, Addr: %s, HTTP API: %s, Bootstrap: %t", id, nodeDataDir, rpcAddr, httpApiAddr, bootstrap)
+	return n, nil
+}
+
+// stopNodeLocked はノードを管理対象から外して停止します。
+// async が true の場合は停止をバックグラウンドで行い、完了するまでノードIDとスロットを stopping に登録します。
+// removeData が true の場合は停止後にデータディレクトリを削除します。
+func (m *Manager) stopNodeLocked(id raft.ServerID, async, removeData bool) {
+	mn, ok := m.nodes[id]
+	if !ok {
+		return
+	}
+	delete(m.nodes, id)
+
+	stop := func() {
+		shutdownNode(mn.node)
+		if !removeData {
+			log.Printf("[INFO] [Cluster] Node %s stopped.", id)
+			return
+		}
+		if err := os.RemoveAll(mn.node.GetConfig().DataDir); err != nil {
+			log.Printf("[WARN] [Cluster] Failed to remove data dir of node %s: %v", id, err)
+		}
+		log.Printf("[INFO] [Cluster] Node %s stopped and its data removed.", id)
+	}
+	if !async {
+		stop()
+		return
+	}
+
+	m.stopping[id] = mn.slot
+	m.stopWg.Add(1)
+	go func() {
+		defer m.stopWg.Done()
+		stop()
+		m.mu.Lock()
+		delete(m.stopping, id)
+		m.mu.Unlock()
+	}()
+}
+
+// waitForLeaderLocked は管理中のノードのいずれかがリーダーになるまで待ちます。
+func (m *Manager) waitForLeaderLocked(timeout time.Duration) (*raft_node.Node, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		for _, mn := range m.nodes {
+			if mn.node.IsLeader() {
+				return mn.node, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no leader elected among %d node(s) within %s", len(m.nodes), timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// shutdownNode はノードとそのトランスポートを停止します。
+func shutdownNode(n *raft_node.Node) {
+	log.Printf("[INFO] [Cluster] Shutting down node %s...", n.NodeID())
+	if err := n.Shutdown(); err != nil {
+		log.Printf("[ERROR] [Cluster] Error shutting down node %s: %v", n.NodeID(), err)
+	}
+	if transportToClose, ok := n.Transport().(interface{ Close() error }); ok {
+		if err := transportToClose.Close(); err != nil {
+			log.Printf("[ERROR] [Cluster] Error closing transport for node %s: %v", n.NodeID(), err)
+		}
+	}
+}
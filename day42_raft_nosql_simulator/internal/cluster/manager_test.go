@@ -0,0 +1,296 @@
+package cluster_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/cluster"
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/raft_node"
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/server"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	clusterTestBasePort          = 9400 // integration_test.go や main.go とは異なるポートを使用
+	clusterTestHttpApiPortOffset = 100
+)
+
+func postClusterChange(t *testing.T, httpApiAddr, path, nodeID string) (int, server.ClusterResponse) {
+	t.Helper()
+	body, err := json.Marshal(server.ClusterNodeRequest{NodeID: nodeID})
+	require.NoError(t, err)
+	resp, err := http.Post(fmt.Sprintf("http://%s%s", httpApiAddr, path), "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var clusterResp server.ClusterResponse
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&clusterResp))
+	}
+	return resp.StatusCode, clusterResp
+}
+
+func voterIDs(servers []server.ClusterMember) []string {
+	ids := make([]string, 0, len(servers))
+	for _, s := range servers {
+		if s.Suffrage == "Voter" {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids
+}
+
+func TestManager_AddAndRemoveNodes(t *testing.T) {
+	m := cluster.NewManager(t.TempDir(), clusterTestBasePort, clusterTestHttpApiPortOffset)
+	defer m.Shutdown()
+
+	require.NoError(t, m.Bootstrap("node0"))
+
+	t.Run("AddNode assigns IDs and ports", func(t *testing.T) {
+		id, servers, err := m.AddNode("")
+		require.NoError(t, err)
+		require.Equal(t, "node1", id)
+		require.ElementsMatch(t, []string{"node0", "node1"}, voterIDs(servers))
+
+		id, servers, err = m.AddNode("")
+		require.NoError(t, err)
+		require.Equal(t, "node2", id)
+		require.ElementsMatch(t, []string{"node0", "node1", "node2"}, voterIDs(servers))
+		for _, s := range servers {
+			require.NotEmpty(t, s.HttpApiAddr, "HTTP API address of %s should be known", s.ID)
+		}
+
+		_, _, err = m.AddNode("node1")
+		require.Error(t, err, "Adding a duplicate node ID should fail")
+	})
+
+	t.Run("Remove leader through its own HTTP API", func(t *testing.T) {
+		leader, err := m.Leader()
+		require.NoError(t, err)
+
+		status, resp := postClusterChange(t, leader.GetConfig().HttpApiAddr, "/cluster/remove-node", leader.NodeID())
+		require.Equal(t, http.StatusOK, status, "Node serving the request should still respond after being removed")
+		require.Len(t, voterIDs(resp.Servers), 2)
+		require.NotContains(t, voterIDs(resp.Servers), leader.NodeID())
+
+		newLeader, err := m.Leader()
+		require.NoError(t, err)
+		require.NotEqual(t, leader.NodeID(), newLeader.NodeID())
+		require.Len(t, m.Nodes(), 2)
+	})
+
+	t.Run("Removed node can be re-added via HTTP", func(t *testing.T) {
+		nodes := m.Nodes()
+		removed := map[string]bool{"node0": true, "node1": true, "node2": true}
+		for _, n := range nodes {
+			delete(removed, n.NodeID())
+		}
+		require.Len(t, removed, 1)
+		var removedID string
+		for id := range removed {
+			removedID = id
+		}
+
+		// 停止処理が終わるまでは同じIDで追加できない
+		var resp server.ClusterResponse
+		require.Eventually(t, func() bool {
+			var status int
+			status, resp = postClusterChange(t, nodes[0].GetConfig().HttpApiAddr, "/cluster/add-node", removedID)
+			return status == http.StatusOK
+		}, 10*time.Second, 500*time.Millisecond, "Removed node should be re-addable")
+		require.Equal(t, removedID, resp.NodeID)
+		require.ElementsMatch(t, []string{"node0", "node1", "node2"}, voterIDs(resp.Servers))
+	})
+
+	t.Run("Cannot remove unknown node", func(t *testing.T) {
+		_, err := m.RemoveNode("nodeX")
+		require.Error(t, err)
+	})
+}
+
+func TestManager_BootstrapFromSnapshot(t *testing.T) {
+	const (
+		sourceBasePort   = 9600
+		restoredBasePort = 9700
+		tableName        = "Music"
+	)
+
+	// 元のクラスタにデータを書き込み、リーダーでスナップショットを作成する
+	source := cluster.NewManager(t.TempDir(), sourceBasePort, clusterTestHttpApiPortOffset)
+	require.NoError(t, source.Bootstrap("node0"))
+	_, _, err := source.AddNode("")
+	require.NoError(t, err)
+
+	leader, err := source.Leader()
+	require.NoError(t, err)
+	_, err = leader.ProposeCreateTable(tableName, "Artist", "", 5*time.Second)
+	require.NoError(t, err)
+	_, err = leader.ProposePutItem(tableName, map[string]interface{}{"Artist": "Journey", "Year": 1981}, 5*time.Second)
+	require.NoError(t, err)
+	_, err = leader.ProposePutKV("config/mode", []byte("restored"), nil, 5*time.Second)
+	require.NoError(t, err)
+
+	snapshot, err := leader.TakeSnapshot()
+	if errors.Is(err, server.ErrNothingNewToSnapshot) {
+		// SnapshotThreshold により自動で作成済みの場合は最新のものを使う
+		snapshots, listErr := leader.ListSnapshots()
+		require.NoError(t, listErr)
+		require.NotEmpty(t, snapshots)
+		snapshot = snapshots[0]
+	} else {
+		require.NoError(t, err)
+	}
+	snapshots, err := leader.ListSnapshots()
+	require.NoError(t, err)
+	require.Equal(t, snapshot.ID, snapshots[0].ID, "Latest snapshot should be listed first")
+	source.Shutdown()
+
+	// スナップショットから新しいクラスタを起動する
+	restoredDir := t.TempDir()
+	restored := cluster.NewManager(restoredDir, restoredBasePort, clusterTestHttpApiPortOffset)
+	defer restored.Shutdown()
+	require.NoError(t, restored.BootstrapFromSnapshot("node0", snapshot.Path))
+	_, servers, err := restored.AddNode("")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"node0", "node1"}, voterIDs(servers), "Original cluster configuration should be replaced")
+	raftAddrs := make([]string, 0, len(servers))
+	for _, s := range servers {
+		raftAddrs = append(raftAddrs, s.RaftAddr)
+	}
+	require.ElementsMatch(t, []string{fmt.Sprintf("127.0.0.1:%d", restoredBasePort), fmt.Sprintf("127.0.0.1:%d", restoredBasePort+1)}, raftAddrs, "Restored cluster should only use its own ports")
+
+	for _, n := range restored.Nodes() {
+		n := n
+		require.Eventually(t, func() bool {
+			if _, ok := n.GetTableMetadata(tableName); !ok {
+				return false
+			}
+			if _, _, err := n.GetItemFromLocalStore(tableName, "Journey"); err != nil {
+				return false
+			}
+			value, _, err := n.GetKVFromLocalStore("config/mode")
+			return err == nil && string(value) == "restored"
+		}, 10*time.Second, 200*time.Millisecond, "Node %s should have the data from the snapshot", n.NodeID())
+	}
+
+	// 既存のRaft状態があるデータディレクトリには復元できない
+	again := cluster.NewManager(restoredDir, restoredBasePort+10, clusterTestHttpApiPortOffset)
+	defer again.Shutdown()
+	require.Error(t, again.BootstrapFromSnapshot("node0", snapshot.Path))
+}
+
+func TestManager_ChaosPartitionAndKill(t *testing.T) {
+	const chaosBasePort = 9800
+
+	m := cluster.NewManager(t.TempDir(), chaosBasePort, clusterTestHttpApiPortOffset)
+	defer m.Shutdown()
+
+	require.NoError(t, m.Bootstrap("node0"))
+	for i := 0; i < 2; i++ {
+		_, _, err := m.AddNode("")
+		require.NoError(t, err)
+	}
+
+	oldLeader, err := m.Leader()
+	require.NoError(t, err)
+	_, err = oldLeader.ProposePutKV("chaos/key", []byte("v1"), nil, 5*time.Second)
+	require.NoError(t, err)
+
+	var majority []string
+	for _, n := range m.Nodes() {
+		if n.NodeID() != oldLeader.NodeID() {
+			majority = append(majority, n.NodeID())
+		}
+	}
+
+	t.Run("Isolated leader is replaced by the majority", func(t *testing.T) {
+		// 分断はHTTP API経由でも操作できる
+		body, err := json.Marshal(server.ChaosPartitionRequest{Groups: [][]string{{oldLeader.NodeID()}, majority}})
+		require.NoError(t, err)
+		resp, err := http.Post(fmt.Sprintf("http://%s/chaos/partition", oldLeader.GetConfig().HttpApiAddr), "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Len(t, m.ChaosStatus().Partitions, 2)
+
+		var newLeader *raft_node.Node
+		require.Eventually(t, func() bool {
+			for _, n := range m.Nodes() {
+				if n.NodeID() != oldLeader.NodeID() && n.IsLeader() {
+					newLeader = n
+					return true
+				}
+			}
+			return false
+		}, 15*time.Second, 100*time.Millisecond, "Majority side should elect a new leader")
+		require.Eventually(t, func() bool { return !oldLeader.IsLeader() }, 5*time.Second, 100*time.Millisecond, "Isolated leader should step down")
+
+		_, err = newLeader.ProposePutKV("chaos/key", []byte("v2"), nil, 5*time.Second)
+		require.NoError(t, err)
+		_, err = oldLeader.ProposePutKV("chaos/key", []byte("lost"), nil, time.Second)
+		require.Error(t, err, "Minority side must not accept writes")
+		value, _, err := oldLeader.GetKVFromLocalStore("chaos/key")
+		require.NoError(t, err)
+		require.Equal(t, "v1", string(value), "Isolated node should not see writes made during the partition")
+	})
+
+	t.Run("Heal lets the old leader catch up", func(t *testing.T) {
+		require.Empty(t, m.Heal().Partitions)
+		require.Eventually(t, func() bool {
+			value, _, err := oldLeader.GetKVFromLocalStore("chaos/key")
+			return err == nil && string(value) == "v2"
+		}, 15*time.Second, 200*time.Millisecond, "Old leader should replicate the new value after healing")
+	})
+
+	t.Run("Killed node keeps its membership and catches up on restart", func(t *testing.T) {
+		leader, err := m.Leader()
+		require.NoError(t, err)
+		var victim string
+		for _, n := range m.Nodes() {
+			if n.NodeID() != leader.NodeID() {
+				victim = n.NodeID()
+				break
+			}
+		}
+
+		status, err := m.KillNode(victim)
+		require.NoError(t, err)
+		require.Equal(t, []string{victim}, status.KilledNodes)
+		require.Len(t, m.Nodes(), 2)
+		_, err = m.KillNode(victim)
+		require.Error(t, err, "Killing a killed node should fail")
+		_, _, err = m.AddNode(victim)
+		require.Error(t, err, "Killed node ID should not be reusable by AddNode")
+
+		servers, err := leader.ClusterConfiguration()
+		require.NoError(t, err)
+		require.Contains(t, voterIDs(servers), victim, "Killed node should stay in the configuration")
+
+		_, err = leader.ProposePutKV("chaos/key", []byte("v3"), nil, 5*time.Second)
+		require.NoError(t, err, "Two of three voters are still a quorum")
+
+		require.Eventually(t, func() bool {
+			_, err := m.RestartNode(victim)
+			return err == nil
+		}, 10*time.Second, 200*time.Millisecond, "Killed node should be restartable once stopped")
+		require.Empty(t, m.ChaosStatus().KilledNodes)
+
+		var restarted *raft_node.Node
+		for _, n := range m.Nodes() {
+			if n.NodeID() == victim {
+				restarted = n
+			}
+		}
+		require.NotNil(t, restarted)
+		require.Eventually(t, func() bool {
+			value, _, err := restarted.GetKVFromLocalStore("chaos/key")
+			return err == nil && string(value) == "v3"
+		}, 15*time.Second, 200*time.Millisecond, "Restarted node should catch up with the leader")
+	})
+}
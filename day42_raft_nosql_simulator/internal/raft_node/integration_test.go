@@ -3,13 +3,19 @@ package raft_node_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/client"
 	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/raft_node"
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/server"
 
 	"github.com/hashicorp/raft"
 	"github.com/stretchr/testify/require"
@@ -32,6 +38,10 @@ func setupIntegrationTestCluster(t *testing.T) ([]*raft_node.Node, []raft.Transp
 
 	nodes := make([]*raft_node.Node, integrationTestNumNodes)
 	transports := make([]raft.Transport, integrationTestNumNodes)
+	peerHttpApiAddrs := make(map[raft.ServerID]string, integrationTestNumNodes)
+	for i := 0; i < integrationTestNumNodes; i++ {
+		peerHttpApiAddrs[raft.ServerID(fmt.Sprintf("intNode%d", i))] = fmt.Sprintf("127.0.0.1:%d", integrationTestBasePort+i+100)
+	}
 
 	for i := 0; i < integrationTestNumNodes; i++ {
 		nodeIDStr := fmt.Sprintf("intNode%d", i)
@@ -47,9 +57,10 @@ func setupIntegrationTestCluster(t *testing.T) ([]*raft_node.Node, []raft.Transp
 		cfg := raft_node.Config{
 			NodeID:           nodeID,
 			Addr:             raftAddr,
-			HttpApiAddr:      fmt.Sprintf("127.0.0.1:%d", integrationTestBasePort+i+100), // Add offset for HTTP API port
+			HttpApiAddr:      peerHttpApiAddrs[nodeID], // Raftポート + 100
 			DataDir:          nodeDataDir,
 			BootstrapCluster: i == 0, // 最初のノードのみクラスタをブートストラップ
+			PeerHttpApiAddrs: peerHttpApiAddrs,
 		}
 
 		n, err := raft_node.NewNode(cfg, transport)
@@ -260,3 +271,172 @@ func TestIntegration_ItemOperations(t *testing.T) {
 		require.NoError(t, getErrItem2, "Item2 should still exist after deleting item1")
 	})
 }
+
+func TestIntegration_KVAPIForwarding(t *testing.T) {
+	nodes, _, _, cleanup := setupIntegrationTestCluster(t)
+	defer cleanup()
+
+	leader := getLeaderNode(t, nodes)
+	require.NotNil(t, leader, "Leader must exist for KV API test")
+	var follower *raft_node.Node
+	for _, n := range nodes {
+		if !n.IsLeader() {
+			follower = n
+			break
+		}
+	}
+	require.NotNil(t, follower, "Follower must exist for KV API test")
+
+	doRequest := func(t *testing.T, method string, node *raft_node.Node, key string, body string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, fmt.Sprintf("http://%s/kv/%s", node.GetConfig().HttpApiAddr, key), strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(respBody)
+	}
+
+	t.Run("PUT to follower is forwarded to leader", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodPut, follower, "users/1", `{"name":"alice"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "PUT via follower should succeed: %s", body)
+		require.Equal(t, leader.NodeID(), resp.Header.Get(server.HeaderLeaderID))
+		require.Equal(t, leader.GetConfig().HttpApiAddr, resp.Header.Get(server.HeaderLeaderAddr))
+		require.NotEmpty(t, resp.Header.Get(server.HeaderRaftIndex))
+
+		// リーダーでは転送されたPUTの完了時点で値が読める
+		resp, body = doRequest(t, http.MethodGet, leader, "users/1", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, `{"name":"alice"}`, body)
+
+		// フォロワーには結果整合性で伝播する
+		require.Eventually(t, func() bool {
+			value, _, err := follower.GetKVFromLocalStore("users/1")
+			return err == nil && string(value) == `{"name":"alice"}`
+		}, integrationTestWaitDelay, 100*time.Millisecond, "Value should be replicated to the follower")
+	})
+
+	t.Run("DELETE to follower is forwarded to leader", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodDelete, follower, "users/1", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, "DELETE via follower should succeed: %s", body)
+		require.Equal(t, leader.NodeID(), resp.Header.Get(server.HeaderLeaderID))
+
+		resp, _ = doRequest(t, http.MethodGet, leader, "users/1", "")
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Index query via follower sees committed writes", func(t *testing.T) {
+		resp, body := doRequest(t, http.MethodPut, follower, "users/10?index=city,age", `{"city":"Tokyo","age":30}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, "Indexed PUT via follower should succeed: %s", body)
+		resp, body = doRequest(t, http.MethodPut, leader, "users/11?index=city", `{"city":"Tokyo"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+		// 直後にフォロワーへ問い合わせても、リーダーで読むため両方のキーが返る
+		queryResp, err := http.Get(fmt.Sprintf("http://%s/kv-index?field=city&value=Tokyo", follower.GetConfig().HttpApiAddr))
+		require.NoError(t, err)
+		defer queryResp.Body.Close()
+		require.Equal(t, http.StatusOK, queryResp.StatusCode)
+		require.Equal(t, leader.NodeID(), queryResp.Header.Get(server.HeaderLeaderID))
+		var result server.KVIndexQueryResponse
+		require.NoError(t, json.NewDecoder(queryResp.Body).Decode(&result))
+		require.Equal(t, []string{"users/10", "users/11"}, result.Keys)
+		require.NotZero(t, result.Index)
+
+		resp, _ = doRequest(t, http.MethodPut, leader, "users/12?index=city", "not json")
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, "Indexed values must be JSON objects")
+	})
+
+	t.Run("Batch via follower is applied atomically", func(t *testing.T) {
+		postBatch := func(t *testing.T, body string) *http.Response {
+			t.Helper()
+			resp, err := http.Post(fmt.Sprintf("http://%s/kv/batch", follower.GetConfig().HttpApiAddr), "application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+
+		resp := postBatch(t, `{"operations":[
+			{"op":"put","key":"orders/1","value":{"status":"paid"},"index":["status"]},
+			{"op":"put","key":"stock/apple","value":"9"},
+			{"op":"delete","key":"users/10"}
+		]}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, leader.NodeID(), resp.Header.Get(server.HeaderLeaderID))
+		batchIndex, err := strconv.ParseUint(resp.Header.Get(server.HeaderRaftIndex), 10, 64)
+		require.NoError(t, err)
+
+		value, index, err := leader.GetKVFromLocalStore("stock/apple")
+		require.NoError(t, err)
+		require.Equal(t, "9", string(value), "JSON string values are stored as plain strings")
+		require.Equal(t, batchIndex, index)
+		value, _, err = leader.GetKVFromLocalStore("orders/1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"status":"paid"}`, string(value))
+		_, _, err = leader.GetKVFromLocalStore("users/10")
+		require.Error(t, err)
+
+		// 1つでも不正な操作があればどの操作も適用されない
+		resp = postBatch(t, `{"operations":[{"op":"put","key":"stock/apple","value":"0"},{"op":"rename","key":"x"}]}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		value, _, err = leader.GetKVFromLocalStore("stock/apple")
+		require.NoError(t, err)
+		require.Equal(t, "9", string(value))
+	})
+
+	t.Run("Watch on follower streams committed changes for the prefix", func(t *testing.T) {
+		events := make(chan client.KVWatchEvent, 16)
+		go func() {
+			_ = client.NewAPIClient(follower.GetConfig().HttpApiAddr).WatchKV("watch/", func(event client.KVWatchEvent) error {
+				select {
+				case events <- event:
+				default:
+				}
+				return nil
+			})
+		}()
+		next := func(t *testing.T) client.KVWatchEvent {
+			t.Helper()
+			select {
+			case event := <-events:
+				return event
+			case <-time.After(integrationTestWaitDelay):
+				t.Fatal("Timed out waiting for watch event")
+				return client.KVWatchEvent{}
+			}
+		}
+
+		ready := next(t)
+		require.Equal(t, server.KVWatchEventReady, ready.Op)
+		require.Equal(t, follower.NodeID(), ready.NodeID)
+
+		resp, body := doRequest(t, http.MethodPut, leader, "other/1", "ignored")
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+		resp, body = doRequest(t, http.MethodPut, leader, "watch/a", "1")
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+		putIndex, err := strconv.ParseUint(resp.Header.Get(server.HeaderRaftIndex), 10, 64)
+		require.NoError(t, err)
+		resp, body = doRequest(t, http.MethodDelete, leader, "watch/a", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+
+		put := next(t)
+		require.Equal(t, client.KVWatchEvent{Op: "put", Key: "watch/a", Value: "1", Index: putIndex}, put)
+		require.Greater(t, put.Index, ready.Index)
+		del := next(t)
+		require.Equal(t, "delete", del.Op)
+		require.Equal(t, "watch/a", del.Key)
+		require.Greater(t, del.Index, put.Index)
+	})
+
+	t.Run("Forwarded request reaching a follower is rejected", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("http://%s/kv/loop", follower.GetConfig().HttpApiAddr), strings.NewReader("v"))
+		require.NoError(t, err)
+		req.Header.Set(server.HeaderForwardedBy, "otherNode")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Equal(t, leader.NodeID(), resp.Header.Get(server.HeaderLeaderID))
+	})
+}
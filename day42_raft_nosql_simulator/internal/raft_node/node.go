@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/server"
@@ -30,13 +31,15 @@ const (
 // IsLeader: このノードが初期状態でリーダーとして起動するかどうか（通常はfalseで、リーダー選出に任せる）。
 // BootstrapCluster: 新しいクラスタをブートストラップするかどうか。最初のノードのみtrueに設定。
 // JoinAddr: Joinするクラスタのアドレス
+// PeerHttpApiAddrs: 各ノードのHTTP APIアドレス。フォロワーが書き込みをリーダーへ転送する際に使用する。
 type Config struct {
 	NodeID           raft.ServerID
 	Addr             raft.ServerAddress // Raft通信用のアドレス
 	HttpApiAddr      string             // HTTP APIサーバー用のアドレス (例: "127.0.0.1:8080")
 	DataDir          string
 	BootstrapCluster bool
-	JoinAddr         string // 追加: Joinするクラスタのアドレス
+	JoinAddr         string                   // 追加: Joinするクラスタのアドレス
+	PeerHttpApiAddrs map[raft.ServerID]string // ノードID -> HTTP APIアドレス
 }
 
 // Node はRaftクラスタの単一ノードを表します。
@@ -59,6 +62,9 @@ type Node struct {
 	snapshotStore raft.SnapshotStore
 	httpApiServer *server.APIServer // HTTP APIサーバーの参照
 	raftConfig    *raft.Config      // raftConfig を追加
+
+	peerMu           sync.RWMutex
+	peerHttpApiAddrs map[raft.ServerID]string // リーダーへのフォワーディング用 (ノードID -> HTTP APIアドレス)
 }
 
 // GetConfig はノードの設定を返します。
@@ -123,13 +129,18 @@ func NewNode(cfg Config, transport raft.Transport) (*Node, error) {
 	}
 
 	node := &Node{
-		fsm:           fsm,
-		kvStore:       kvStore,
-		config:        cfg,
-		transport:     transport,
-		boltStore:     boltDBStore, // 修正: logStore, stableStore の代わりに boltStore
-		snapshotStore: snapshotStore,
+		fsm:              fsm,
+		kvStore:          kvStore,
+		config:           cfg,
+		transport:        transport,
+		boltStore:        boltDBStore, // 修正: logStore, stableStore の代わりに boltStore
+		snapshotStore:    snapshotStore,
+		peerHttpApiAddrs: make(map[raft.ServerID]string),
+	}
+	for id, addr := range cfg.PeerHttpApiAddrs {
+		node.peerHttpApiAddrs[id] = addr
 	}
+	node.peerHttpApiAddrs[cfg.NodeID] = cfg.HttpApiAddr
 
 	// HTTP APIサーバーの初期化と起動
 	// nodeが完全に初期化されてから APIServer を作成するために、nodeのポインタを渡す
@@ -143,14 +154,7 @@ func NewNode(cfg Config, transport raft.Transport) (*Node, error) {
 	}
 
 	// Raftインスタンスの作成
-	raftCfg := raft.DefaultConfig()
-	raftCfg.LocalID = cfg.NodeID
-	raftCfg.HeartbeatTimeout = 1000 * time.Millisecond
-	raftCfg.ElectionTimeout = 1000 * time.Millisecond
-	raftCfg.LeaderLeaseTimeout = 500 * time.Millisecond
-	raftCfg.CommitTimeout = 50 * time.Millisecond
-	raftCfg.SnapshotInterval = 20 * time.Second // スナップショットの間隔
-	raftCfg.SnapshotThreshold = 5               // この数のコミット後にスナップショット
+	raftCfg := newRaftConfig(cfg.NodeID)
 
 	// ログ関連の設定
 	// raftCfg.Logger = hclog.New(&hclog.LoggerOptions{
@@ -200,6 +204,19 @@ func NewNode(cfg Config, transport raft.Transport) (*Node, error) {
 	return node, nil
 }
 
+// newRaftConfig はノードで使用するRaftの設定を返します。
+func newRaftConfig(nodeID raft.ServerID) *raft.Config {
+	raftCfg := raft.DefaultConfig()
+	raftCfg.LocalID = nodeID
+	raftCfg.HeartbeatTimeout = 1000 * time.Millisecond
+	raftCfg.ElectionTimeout = 1000 * time.Millisecond
+	raftCfg.LeaderLeaseTimeout = 500 * time.Millisecond
+	raftCfg.CommitTimeout = 50 * time.Millisecond
+	raftCfg.SnapshotInterval = 20 * time.Second // スナップショットの間隔
+	raftCfg.SnapshotThreshold = 5               // この数のコミット後にスナップショット
+	return raftCfg
+}
+
 // Start はノードを起動します。(現在はNewNodeでRaftが起動処理を開始)
 func (n *Node) Start() error {
 	fmt.Printf("Node %s starting with Raft instance. Transport addr: %s\n", n.config.NodeID, n.transport.LocalAddr())
@@ -573,3 +590,129 @@ func (n *Node) LeaderID() string {
 	_, id := n.RaftLeaderWithID()
 	return string(id)
 }
+
+// SetPeerHttpApiAddr は他ノードのHTTP APIアドレスを登録します。
+// 登録されたアドレスは、そのノードがリーダーになった際のフォワーディング先として使われます。
+func (n *Node) SetPeerHttpApiAddr(id raft.ServerID, httpApiAddr string) {
+	n.peerMu.Lock()
+	defer n.peerMu.Unlock()
+	n.peerHttpApiAddrs[id] = httpApiAddr
+}
+
+// LeaderHttpApiAddr は現在のリーダーのHTTP APIアドレスとIDを返します。
+// リーダーが不明な場合、またはリーダーのHTTP APIアドレスが登録されていない場合はエラーを返します。
+func (n *Node) LeaderHttpApiAddr() (string, string, error) {
+	_, leaderID := n.RaftLeaderWithID()
+	if leaderID == "" {
+		return "", "", fmt.Errorf("no leader is currently known to node %s", n.config.NodeID)
+	}
+	n.peerMu.RLock()
+	httpApiAddr, ok := n.peerHttpApiAddrs[leaderID]
+	n.peerMu.RUnlock()
+	if !ok {
+		return "", string(leaderID), fmt.Errorf("HTTP API address of leader %s is unknown to node %s", leaderID, n.config.NodeID)
+	}
+	return httpApiAddr, string(leaderID), nil
+}
+
+// ProposePutKV はKV APIのキー書き込みコマンドをRaftクラスタに提案し、適用されたRaftログのインデックスを返します。
+// indexFields を指定すると、値 (JSONオブジェクト) のそれらのフィールドがセカンダリインデックスに登録されます。
+func (n *Node) ProposePutKV(key string, value []byte, indexFields []string, timeout time.Duration) (uint64, error) {
+	return n.proposeKVCommand(store.PutKVCommandType, store.PutKVCommandPayload{Key: key, Value: value, IndexFields: indexFields}, timeout)
+}
+
+// ProposeDeleteKV はKV APIのキー削除コマンドをRaftクラスタに提案し、適用されたRaftログのインデックスを返します。
+func (n *Node) ProposeDeleteKV(key string, timeout time.Duration) (uint64, error) {
+	return n.proposeKVCommand(store.DeleteKVCommandType, store.DeleteKVCommandPayload{Key: key}, timeout)
+}
+
+// ProposeBatchWrite はKV APIの複数キーへの書き込みを1つのRaftログとして提案し、適用されたRaftログのインデックスを返します。
+// FSMは全操作を不可分に適用するため、いずれかの操作が失敗した場合はどのキーも変更されません。
+func (n *Node) ProposeBatchWrite(ops []store.KVBatchOperation, timeout time.Duration) (uint64, error) {
+	return n.proposeKVCommand(store.BatchWriteCommandType, store.BatchWriteCommandPayload{Operations: ops}, timeout)
+}
+
+// proposeKVCommand はKV APIのコマンドを適用し、FSMが失敗を返した場合はエラーに変換します。
+func (n *Node) proposeKVCommand(cmdType store.CommandType, payload interface{}, timeout time.Duration) (uint64, error) {
+	if !n.IsLeader() {
+		leaderID, leaderAddr := n.LeaderWithID()
+		return 0, fmt.Errorf("not a leader, current leader is %s (%s)", leaderID, leaderAddr)
+	}
+	cmdBytes, err := store.EncodeCommand(cmdType, payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode %s command: %w", cmdType, err)
+	}
+	future := n.Apply(cmdBytes, timeout)
+	if err := future.Error(); err != nil {
+		return 0, fmt.Errorf("failed to apply %s command: %w", cmdType, err)
+	}
+	resp, ok := future.Response().(store.CommandResponse)
+	if !ok {
+		return 0, fmt.Errorf("unexpected fsm response for %s: %T", cmdType, future.Response())
+	}
+	if !resp.Success {
+		return 0, fmt.Errorf("fsm apply error for %s: %s", cmdType, resp.Error)
+	}
+	return future.Index(), nil
+}
+
+// GetKVFromLocalStore はローカルのKVStoreからKV APIのキーの値を取得します (結果整合性)。
+func (n *Node) GetKVFromLocalStore(key string) ([]byte, uint64, error) {
+	return n.kvStore.GetKV(key)
+}
+
+// QueryKVIndex はセカンダリインデックスを引き、field の値が value であるKV APIのキーと、読み取り時点の適用済みインデックスを返します。
+// リーダーでBarrierを発行し、それまでにコミットされた書き込みをすべてFSMに適用してから読むため、結果は強い整合性を持ちます。
+func (n *Node) QueryKVIndex(field, value string, timeout time.Duration) ([]string, uint64, error) {
+	if !n.IsLeader() {
+		leaderID, leaderAddr := n.LeaderWithID()
+		return nil, 0, fmt.Errorf("not a leader, current leader is %s (%s)", leaderID, leaderAddr)
+	}
+	if err := n.raft.Barrier(timeout).Error(); err != nil {
+		return nil, 0, fmt.Errorf("failed to wait for committed writes to be applied: %w", err)
+	}
+	keys, err := n.kvStore.QueryKVIndex(field, value)
+	if err != nil {
+		return nil, 0, err
+	}
+	return keys, n.raft.AppliedIndex(), nil
+}
+
+// WatchKV は prefix で始まるKV APIのキーについて、このノードが以降に適用した変更を受け取るウォッチャーを登録し、
+// 登録時点でFSMに適用済みのRaftログのインデックスを返します。返されるインデックス以下の変更が配信されることもあります。
+func (n *Node) WatchKV(prefix string) (*store.KVWatcher, uint64) {
+	watcher := n.fsm.WatchKV(prefix)
+	return watcher, n.raft.AppliedIndex()
+}
+
+// SetClusterManager はメンバーシップ変更API (/cluster/add-node, /cluster/remove-node) が使用するマネージャーを登録します。
+func (n *Node) SetClusterManager(m server.ClusterManager) {
+	if n.httpApiServer != nil {
+		n.httpApiServer.SetClusterManager(m)
+	}
+}
+
+// ClusterConfiguration はこのノードが認識している最新のRaftクラスタ構成を返します。
+// HTTP APIアドレスは登録済みのもののみ設定されます。
+func (n *Node) ClusterConfiguration() ([]server.ClusterMember, error) {
+	configFuture := n.raft.GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return nil, fmt.Errorf("failed to get raft configuration: %w", err)
+	}
+	_, leaderID := n.RaftLeaderWithID()
+
+	n.peerMu.RLock()
+	defer n.peerMu.RUnlock()
+	servers := configFuture.Configuration().Servers
+	members := make([]server.ClusterMember, 0, len(servers))
+	for _, srv := range servers {
+		members = append(members, server.ClusterMember{
+			ID:          string(srv.ID),
+			RaftAddr:    string(srv.Address),
+			HttpApiAddr: n.peerHttpApiAddrs[srv.ID],
+			Suffrage:    srv.Suffrage.String(),
+			IsLeader:    srv.ID == leaderID,
+		})
+	}
+	return members, nil
+}
@@ -0,0 +1,170 @@
+package raft_node
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/server"
+	"github.com/lirlia/100day_challenge_backend/day42_raft_nosql_simulator/internal/store"
+
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+
+	"github.com/hashicorp/raft"
+)
+
+const (
+	snapshotDirName      = "snapshots" // raft.FileSnapshotStore が DataDir 配下に作成するディレクトリ
+	snapshotMetaFileName = "meta.json"
+	snapshotStateFile    = "state.bin"
+)
+
+// TakeSnapshot はこのノードのRaftスナップショットを作成し、その情報を返します。
+// 前回のスナップショット以降に適用されたログがない場合は server.ErrNothingNewToSnapshot を返します。
+func (n *Node) TakeSnapshot() (server.SnapshotInfo, error) {
+	future := n.raft.Snapshot()
+	if err := future.Error(); err != nil {
+		if errors.Is(err, raft.ErrNothingNewToSnapshot) {
+			return server.SnapshotInfo{}, fmt.Errorf("%w on node %s", server.ErrNothingNewToSnapshot, n.config.NodeID)
+		}
+		return server.SnapshotInfo{}, fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	meta, rc, err := future.Open()
+	if err != nil {
+		return server.SnapshotInfo{}, fmt.Errorf("failed to open created snapshot: %w", err)
+	}
+	rc.Close()
+	log.Printf("[INFO] [RaftNode] [%s] TakeSnapshot: Created snapshot %s (index=%d, term=%d, size=%d)", n.config.NodeID, meta.ID, meta.Index, meta.Term, meta.Size)
+	return n.snapshotInfo(meta), nil
+}
+
+// ListSnapshots はこのノードのスナップショットストアにあるスナップショットを新しい順に返します。
+func (n *Node) ListSnapshots() ([]server.SnapshotInfo, error) {
+	metas, err := n.snapshotStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	snapshots := make([]server.SnapshotInfo, 0, len(metas))
+	for _, meta := range metas {
+		snapshots = append(snapshots, n.snapshotInfo(meta))
+	}
+	return snapshots, nil
+}
+
+func (n *Node) snapshotInfo(meta *raft.SnapshotMeta) server.SnapshotInfo {
+	path := filepath.Join(n.config.DataDir, snapshotDirName, meta.ID)
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	return server.SnapshotInfo{
+		ID:    meta.ID,
+		Index: meta.Index,
+		Term:  meta.Term,
+		Size:  meta.Size,
+		Path:  path,
+	}
+}
+
+// snapshotFileMeta は raft.FileSnapshotStore が meta.json に書き出す内容です。
+type snapshotFileMeta struct {
+	raft.SnapshotMeta
+	CRC []byte
+}
+
+// SeedDataDirFromSnapshot はスナップショットディレクトリ (meta.json と state.bin を含む) を cfg.DataDir に取り込み、
+// cfg.NodeID だけを投票メンバーとするクラスタ構成で復元します。
+// この後 BootstrapCluster=false で NewNode すると、ノードはスナップショットの状態から単一ノードのクラスタとして起動します。
+// cfg.DataDir に既にRaftの状態がある場合はエラーを返します。
+func SeedDataDirFromSnapshot(cfg Config, transport raft.Transport, snapshotPath string) (*raft.SnapshotMeta, error) {
+	snapshotDir := snapshotPath
+	if base := filepath.Base(snapshotPath); base == snapshotMetaFileName || base == snapshotStateFile {
+		snapshotDir = filepath.Dir(snapshotPath)
+	}
+
+	metaBytes, err := os.ReadFile(filepath.Join(snapshotDir, snapshotMetaFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot meta in %s: %w", snapshotDir, err)
+	}
+	var fileMeta snapshotFileMeta
+	if err := json.Unmarshal(metaBytes, &fileMeta); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot meta in %s: %w", snapshotDir, err)
+	}
+	meta := fileMeta.SnapshotMeta
+
+	state, err := os.Open(filepath.Join(snapshotDir, snapshotStateFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot state in %s: %w", snapshotDir, err)
+	}
+	defer state.Close()
+
+	// 稼働中のノードのデータディレクトリを指定した場合に BoltDB のファイルロック待ちで固まらないよう、開く前に確認する
+	boltDBPath := filepath.Join(cfg.DataDir, "raft.db")
+	if _, err := os.Stat(boltDBPath); err == nil {
+		return nil, fmt.Errorf("data dir %s already has raft state, use an empty data dir to restore", cfg.DataDir)
+	}
+	boltDBStore, err := raftboltdb.NewBoltStore(boltDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bolt store in %s: %w", cfg.DataDir, err)
+	}
+	defer boltDBStore.Close()
+
+	snapshotStore, err := raft.NewFileSnapshotStore(cfg.DataDir, retainSnapshotCount, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot store at %s: %w", cfg.DataDir, err)
+	}
+
+	hasState, err := raft.HasExistingState(boltDBStore, boltDBStore, snapshotStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing state in %s: %w", cfg.DataDir, err)
+	}
+	if hasState {
+		return nil, fmt.Errorf("data dir %s already has raft state, use an empty data dir to restore", cfg.DataDir)
+	}
+
+	// スナップショットをそのままこのノードのスナップショットストアへコピーする (CRCを検証しながら)
+	sink, err := snapshotStore.Create(meta.Version, meta.Index, meta.Term, meta.Configuration, meta.ConfigurationIndex, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot sink: %w", err)
+	}
+	hash := crc64.New(crc64.MakeTable(crc64.ECMA))
+	if _, err := io.Copy(sink, io.TeeReader(state, hash)); err != nil {
+		sink.Cancel()
+		return nil, fmt.Errorf("failed to copy snapshot state: %w", err)
+	}
+	if len(fileMeta.CRC) > 0 && !bytes.Equal(hash.Sum(nil), fileMeta.CRC) {
+		sink.Cancel()
+		return nil, fmt.Errorf("snapshot %s is corrupt: CRC mismatch", meta.ID)
+	}
+	if err := sink.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close snapshot sink: %w", err)
+	}
+
+	// 元のクラスタ構成を破棄し、このノードのみの構成で復元する。
+	// RecoverCluster はスナップショットをFSMにRestoreし、新しい構成でスナップショットを取り直す。
+	kvStore, err := store.NewKVStore(cfg.DataDir, string(cfg.NodeID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KVStore: %w", err)
+	}
+	fsm := store.NewFSM(kvStore, cfg.NodeID, log.Default())
+	configuration := raft.Configuration{
+		Servers: []raft.Server{
+			{
+				Suffrage: raft.Voter,
+				ID:       cfg.NodeID,
+				Address:  cfg.Addr,
+			},
+		},
+	}
+	if err := raft.RecoverCluster(newRaftConfig(cfg.NodeID), fsm, boltDBStore, boltDBStore, snapshotStore, transport, configuration); err != nil {
+		return nil, fmt.Errorf("failed to recover cluster from snapshot %s: %w", meta.ID, err)
+	}
+
+	log.Printf("[INFO] [RaftNode] [%s] SeedDataDirFromSnapshot: Seeded %s from snapshot %s (index=%d, term=%d)", cfg.NodeID, cfg.DataDir, meta.ID, meta.Index, meta.Term)
+	return &meta, nil
+}
@@ -1,11 +1,18 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	// raft.ServerID と raft.ServerAddress のため
@@ -29,21 +36,110 @@ type RaftNodeProxy interface {
 	ListTablesFromFSM() []string
 	GetClusterStatus() (map[string]interface{}, error)
 	LeaderWithID() (raftAddress string, raftID string) // http_api.go での raft.ServerAddress, raft.ServerID の直接参照を避けるため文字列で返す
+	LeaderHttpApiAddr() (httpApiAddr string, leaderID string, err error)
+	ProposePutKV(key string, value []byte, indexFields []string, timeout time.Duration) (uint64, error)
+	ProposeDeleteKV(key string, timeout time.Duration) (uint64, error)
+	ProposeBatchWrite(ops []store.KVBatchOperation, timeout time.Duration) (uint64, error)
+	WatchKV(prefix string) (watcher *store.KVWatcher, appliedIndex uint64)
+	GetKVFromLocalStore(key string) ([]byte, uint64, error)
+	QueryKVIndex(field, value string, timeout time.Duration) (keys []string, appliedIndex uint64, err error)
+	ClusterConfiguration() ([]ClusterMember, error)
+	TakeSnapshot() (SnapshotInfo, error)
+	ListSnapshots() ([]SnapshotInfo, error)
 }
 
+// ErrNothingNewToSnapshot は前回のスナップショット以降に適用されたログがなく、スナップショットを作成できない場合のエラーです。
+var ErrNothingNewToSnapshot = errors.New("nothing new to snapshot")
+
+// SnapshotInfo はノードのローカルに保存されたRaftスナップショットの情報です。
+type SnapshotInfo struct {
+	ID    string `json:"id"`
+	Index uint64 `json:"index"`
+	Term  uint64 `json:"term"`
+	Size  int64  `json:"size"`
+	Path  string `json:"path"` // meta.json と state.bin を含むディレクトリ。`snapshot restore --snapshot` に指定できる
+}
+
+// ClusterMember はRaftクラスタ構成に含まれる1ノードの情報です。
+type ClusterMember struct {
+	ID          string `json:"id"`
+	RaftAddr    string `json:"raft_addr"`
+	HttpApiAddr string `json:"http_api_addr,omitempty"`
+	Suffrage    string `json:"suffrage"` // Voter / Nonvoter / Staging
+	IsLeader    bool   `json:"is_leader"`
+}
+
+// ClusterManager はノードプロセスの起動・停止を伴うクラスタメンバーシップ変更を担当します。
+// ノード自体はプロセスを生成できないため、ノードを所有する側 (cmd/cli の server) が実装して APIServer に登録します。
+type ClusterManager interface {
+	// AddNode はノードを起動し、リーダー経由で投票メンバーとして追加します。nodeID が空の場合は自動採番します。
+	AddNode(nodeID string) (addedNodeID string, servers []ClusterMember, err error)
+	// RemoveNode はリーダー経由でノードをクラスタから削除し、そのノードを停止します。
+	RemoveNode(nodeID string) ([]ClusterMember, error)
+}
+
+// ChaosStatus は障害注入の現在の状態です。
+type ChaosStatus struct {
+	Partitions  [][]string `json:"partitions,omitempty"`   // 互いに通信できないノードのグループ。空なら分断なし
+	KilledNodes []string   `json:"killed_nodes,omitempty"` // 停止中 (クラスタには残っている) のノード
+}
+
+// ChaosController はネットワーク分断やノードの停止・再起動による障害注入を担当します。
+// ClusterManager がこのインターフェースも実装している場合に /chaos/* が有効になります。
+type ChaosController interface {
+	// Partition はノードをグループに分け、異なるグループ間のRaft通信を遮断します。
+	Partition(groups [][]string) (ChaosStatus, error)
+	// Heal はすべてのネットワーク分断を解消します。
+	Heal() ChaosStatus
+	// KillNode はノードをクラスタ構成に残したまま停止します。データディレクトリは保持されます。
+	KillNode(nodeID string) (ChaosStatus, error)
+	// RestartNode は KillNode で停止したノードを同じアドレスとデータで再起動します。
+	RestartNode(nodeID string) (ChaosStatus, error)
+	// ChaosStatus は現在の障害注入の状態を返します。
+	ChaosStatus() ChaosStatus
+}
+
+// KV API のレスポンスヘッダ
+const (
+	// HeaderLeaderID はリクエスト処理時点のリーダーのノードIDです。
+	HeaderLeaderID = "X-Raft-Leader-Id"
+	// HeaderLeaderAddr はリクエスト処理時点のリーダーのHTTP APIアドレスです。クライアントは以降の書き込みをここへ直接送れます。
+	HeaderLeaderAddr = "X-Raft-Leader-Addr"
+	// HeaderRaftIndex はキーを最後に更新した (または今回の書き込みが適用された) Raftログのインデックスです。
+	HeaderRaftIndex = "X-Raft-Index"
+	// HeaderForwardedBy はフォロワーがリーダーへ転送したリクエストに付与する、転送元のノードIDです。
+	HeaderForwardedBy = "X-Raft-Forwarded-By"
+)
+
+// maxKVValueSize は KV API で受け付ける値の最大サイズです。
+const maxKVValueSize = 1 << 20
+
+// maxKVBatchSize は POST /kv/batch で受け付けるリクエストボディの最大サイズです。
+const maxKVBatchSize = 4 << 20
+
+// kvWatchHeartbeatInterval は GET /kv-watch で変更がない間に接続維持のコメントを送る間隔です。
+const kvWatchHeartbeatInterval = 15 * time.Second
+
 // APIServer は Raft ノードへの HTTP API を提供します。
 // この構造体は main 関数で初期化され、HTTPリクエストを処理します。
 type APIServer struct {
 	httpServer *http.Server
 	nodeProxy  RaftNodeProxy // Raftノードの操作用プロキシ
 	addr       string
+
+	clusterMu      sync.RWMutex
+	clusterManager ClusterManager // nil の場合、メンバーシップ変更APIは 501 を返す
+
+	shutdownCh   chan struct{} // Shutdown で閉じられ、/kv-watch のストリームを終了させる
+	shutdownOnce sync.Once
 }
 
 // NewAPIServer は新しいAPIServerインスタンスを作成します。
 func NewAPIServer(addr string, nodeProxy RaftNodeProxy) *APIServer {
 	srv := &APIServer{
-		nodeProxy: nodeProxy,
-		addr:      addr,
+		nodeProxy:  nodeProxy,
+		addr:       addr,
+		shutdownCh: make(chan struct{}),
 	}
 
 	mux := http.NewServeMux()
@@ -55,6 +151,20 @@ func NewAPIServer(addr string, nodeProxy RaftNodeProxy) *APIServer {
 	mux.HandleFunc("/delete-item", srv.handleDeleteItem)
 	mux.HandleFunc("/query-items", srv.handleQueryItems)
 	mux.HandleFunc("/status", srv.handleStatus)
+	mux.HandleFunc("/kv/", srv.handleKV)
+	mux.HandleFunc("/kv/batch", srv.handleKVBatch)
+	mux.HandleFunc("/kv-index", srv.handleKVIndexQuery)
+	mux.HandleFunc("/kv-watch", srv.handleKVWatch)
+	mux.HandleFunc("/snapshot", srv.handleTakeSnapshot)
+	mux.HandleFunc("/snapshots", srv.handleListSnapshots)
+	mux.HandleFunc("/cluster", srv.handleClusterConfiguration)
+	mux.HandleFunc("/cluster/add-node", srv.handleClusterAddNode)
+	mux.HandleFunc("/cluster/remove-node", srv.handleClusterRemoveNode)
+	mux.HandleFunc("/chaos", srv.handleChaosStatus)
+	mux.HandleFunc("/chaos/partition", srv.handleChaosPartition)
+	mux.HandleFunc("/chaos/heal", srv.handleChaosHeal)
+	mux.HandleFunc("/chaos/kill", srv.handleChaosKill)
+	mux.HandleFunc("/chaos/restart", srv.handleChaosRestart)
 
 	srv.httpServer = &http.Server{
 		Addr:    addr,
@@ -74,11 +184,26 @@ func (s *APIServer) Start() error {
 	return nil
 }
 
+// SetClusterManager はメンバーシップ変更APIが使用する ClusterManager を登録します。
+func (s *APIServer) SetClusterManager(m ClusterManager) {
+	s.clusterMu.Lock()
+	defer s.clusterMu.Unlock()
+	s.clusterManager = m
+}
+
 // Shutdown はHTTP APIサーバーをシャットダウンします。
 func (s *APIServer) Shutdown(timeout time.Duration) error {
 	log.Printf("[INFO] [APIServer] [%s] HTTP API server shutting down...", s.nodeProxy.NodeID())
-	// TODO: context.WithTimeout を使用して適切にシャットダウンする
-	return s.httpServer.Close()
+	// /kv-watch のストリームは自分からは終了しないため、先に閉じてから処理中のリクエストを待つ
+	s.shutdownOnce.Do(func() { close(s.shutdownCh) })
+	// 処理中のリクエスト (自ノードを削除する /cluster/remove-node など) のレスポンスを返し終えるまで待つ
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		log.Printf("[WARN] [APIServer] [%s] Graceful shutdown failed, closing: %v", s.nodeProxy.NodeID(), err)
+		return s.httpServer.Close()
+	}
+	return nil
 }
 
 // --- Request/Response Structs (client.go と共通化も検討) ---
@@ -116,6 +241,74 @@ type QueryItemsRequest struct {
 	SortKeyPrefix string `json:"sort_key_prefix,omitempty"`
 }
 
+type ClusterNodeRequest struct {
+	NodeID string `json:"node_id"`
+}
+
+// BatchWriteRequest は POST /kv/batch のリクエストボディです。
+type BatchWriteRequest struct {
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation はバッチ書き込みの1操作です。
+// value にJSON文字列を指定した場合はその文字列が、それ以外のJSON (オブジェクトなど) を指定した場合はそのJSONテキストが値として保存されます。
+type BatchOperation struct {
+	Op    string          `json:"op"` // "put" または "delete"
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Index []string        `json:"index,omitempty"` // put の値 (JSONオブジェクト) でインデックスを張るフィールド
+}
+
+type ChaosPartitionRequest struct {
+	Groups [][]string `json:"groups"`
+}
+
+// SnapshotResponse はスナップショットAPIのレスポンスです。
+type SnapshotResponse struct {
+	Message   string         `json:"message"`
+	NodeID    string         `json:"node_id"`
+	Snapshots []SnapshotInfo `json:"snapshots"`
+}
+
+// ClusterResponse はクラスタ構成APIのレスポンスです。
+type ClusterResponse struct {
+	Message string          `json:"message"`
+	NodeID  string          `json:"node_id,omitempty"` // 追加・削除されたノードのID
+	Servers []ClusterMember `json:"servers"`
+}
+
+// KVIndexQueryResponse はKV APIのセカンダリインデックスのクエリ結果です。
+type KVIndexQueryResponse struct {
+	Field string   `json:"field"`
+	Value string   `json:"value"`
+	Keys  []string `json:"keys"`
+	Index uint64   `json:"index"` // 読み取り時点でFSMに適用済みのRaftログのインデックス
+}
+
+// GET /kv-watch で配信するイベントの種類です。put / delete / reset は store.KVChangeOp と同じ値です。
+const (
+	// KVWatchEventReady はストリーム開始時に一度だけ送られ、Index にウォッチ開始時点の適用済みインデックスが入ります。
+	KVWatchEventReady = "ready"
+	// KVWatchEventLagged はクライアントが変更を受け取り切れず、ストリームが打ち切られたことを表します。
+	KVWatchEventLagged = "lagged"
+)
+
+// KVWatchEvent は GET /kv-watch が Server-Sent Events の data として送るイベントです。
+type KVWatchEvent struct {
+	Op     string `json:"op"`
+	Key    string `json:"key,omitempty"`
+	Value  string `json:"value,omitempty"` // put の場合のみ
+	Index  uint64 `json:"index"`           // 変更を適用したRaftログのインデックス
+	NodeID string `json:"node_id,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// ChaosResponse は障害注入APIのレスポンスです。
+type ChaosResponse struct {
+	Message string      `json:"message"`
+	Status  ChaosStatus `json:"status"`
+}
+
 // APIErrorResponse はエラー時のAPIレスポンスです。
 type APIErrorResponse struct {
 	Error   string `json:"error"`
@@ -376,6 +569,590 @@ func (s *APIServer) handleDeleteTableREST(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// handleKV は PUT/GET/DELETE /kv/{key} を処理します。
+// GET はローカルリード (結果整合性)。PUT/DELETE はフォロワーで受けた場合、リーダーへ透過的に転送します。
+// PUT の ?index=field1,field2 で、値 (JSONオブジェクト) のフィールドにセカンダリインデックスを張れます。
+func (s *APIServer) handleKV(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/kv/")
+	if err := store.ValidateKVKey(key); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid key", err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.setLeaderHeaders(w)
+		value, index, err := s.nodeProxy.GetKVFromLocalStore(key)
+		if errors.Is(err, store.ErrKeyNotFound) {
+			s.respondWithError(w, http.StatusNotFound, fmt.Sprintf("Key %s not found", key), "")
+			return
+		}
+		if err != nil {
+			s.respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get key: %s", err.Error()), "")
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set(HeaderRaftIndex, strconv.FormatUint(index, 10))
+		w.WriteHeader(http.StatusOK)
+		w.Write(value)
+
+	case http.MethodPut, http.MethodDelete:
+		if !s.nodeProxy.IsLeader() {
+			s.forwardToLeader(w, r)
+			return
+		}
+		s.setLeaderHeaders(w)
+
+		var index uint64
+		var err error
+		if r.Method == http.MethodPut {
+			value, readErr := io.ReadAll(http.MaxBytesReader(w, r.Body, maxKVValueSize))
+			if readErr != nil {
+				s.respondWithError(w, http.StatusBadRequest, "Failed to read request body", readErr.Error())
+				return
+			}
+			indexFields, parseErr := parseIndexFields(r.URL.Query().Get("index"), value)
+			if parseErr != nil {
+				s.respondWithError(w, http.StatusBadRequest, "Invalid index fields", parseErr.Error())
+				return
+			}
+			index, err = s.nodeProxy.ProposePutKV(key, value, indexFields, 10*time.Second)
+		} else {
+			index, err = s.nodeProxy.ProposeDeleteKV(key, 10*time.Second)
+		}
+		if err != nil {
+			s.respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to propose %s for key %s", r.Method, key), err.Error())
+			return
+		}
+		w.Header().Set(HeaderRaftIndex, strconv.FormatUint(index, 10))
+		s.respondWithJSON(w, http.StatusOK, APISuccessResponse{
+			Message: fmt.Sprintf("%s for key %s committed", r.Method, key),
+			Version: int64(index),
+		})
+
+	default:
+		http.Error(w, "Only GET, PUT and DELETE methods are allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// parseIndexFields は ?index= のカンマ区切りのフィールド名を検証します。
+// インデックスを張る場合、値はJSONオブジェクトでなければなりません。
+func parseIndexFields(param string, value []byte) ([]string, error) {
+	if strings.TrimSpace(param) == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(param, ",") {
+		fields = append(fields, strings.TrimSpace(field))
+	}
+	if err := validateIndexedValue(fields, value); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// validateIndexedValue はインデックス対象フィールドと、インデックスを張る値がJSONオブジェクトであることを検証します。
+func validateIndexedValue(fields []string, value []byte) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := store.ValidateIndexFields(fields); err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return fmt.Errorf("value must be a JSON object to be indexed: %v", err)
+	}
+	return nil
+}
+
+// handleKVBatch は POST /kv/batch で複数キーへの書き込みを1つのRaftログとして不可分に適用します。
+// いずれかの操作が不正な場合や適用に失敗した場合、どのキーも変更されません。
+// POST 以外のメソッドは "batch" という名前のキーへの通常のKV APIとして扱います。
+func (s *APIServer) handleKVBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.handleKV(w, r)
+		return
+	}
+	if !s.nodeProxy.IsLeader() {
+		s.forwardToLeader(w, r)
+		return
+	}
+	s.setLeaderHeaders(w)
+
+	var req BatchWriteRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxKVBatchSize)).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid request payload", err.Error())
+		return
+	}
+	ops, err := toKVBatchOperations(req.Operations)
+	if err == nil {
+		err = store.ValidateKVBatch(ops)
+	}
+	if err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid batch", err.Error())
+		return
+	}
+
+	index, err := s.nodeProxy.ProposeBatchWrite(ops, 10*time.Second)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to apply batch of %d operation(s)", len(ops)), err.Error())
+		return
+	}
+	w.Header().Set(HeaderRaftIndex, strconv.FormatUint(index, 10))
+	s.respondWithJSON(w, http.StatusOK, APISuccessResponse{
+		Message: fmt.Sprintf("Batch of %d operation(s) committed", len(ops)),
+		Version: int64(index),
+	})
+}
+
+// toKVBatchOperations はリクエストの操作をFSMに渡す形式に変換します。
+func toKVBatchOperations(reqOps []BatchOperation) ([]store.KVBatchOperation, error) {
+	ops := make([]store.KVBatchOperation, 0, len(reqOps))
+	for i, reqOp := range reqOps {
+		op := store.KVBatchOperation{
+			Op:          store.KVBatchOp(reqOp.Op),
+			Key:         reqOp.Key,
+			IndexFields: reqOp.Index,
+		}
+		if op.Op == store.KVBatchPut {
+			if len(reqOp.Value) == 0 {
+				return nil, fmt.Errorf("operation %d (key %s): put requires a value", i, reqOp.Key)
+			}
+			var str string
+			if err := json.Unmarshal(reqOp.Value, &str); err == nil {
+				op.Value = []byte(str)
+			} else {
+				op.Value = []byte(reqOp.Value)
+			}
+			if err := validateIndexedValue(op.IndexFields, op.Value); err != nil {
+				return nil, fmt.Errorf("operation %d (key %s): %w", i, reqOp.Key, err)
+			}
+		} else if len(reqOp.Value) > 0 {
+			return nil, fmt.Errorf("operation %d (key %s): only put can have a value", i, reqOp.Key)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// handleKVIndexQuery は GET /kv-index?field=...&value=... でセカンダリインデックスを引き、一致するキーを返します。
+// 読み取りはリーダーで行い (フォロワーで受けた場合は転送)、それまでにコミットされた書き込みをすべて反映した結果を返します。
+func (s *APIServer) handleKVIndexQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	field := r.URL.Query().Get("field")
+	value := r.URL.Query().Get("value")
+	if err := store.ValidateIndexField(field); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid index field", err.Error())
+		return
+	}
+	if !s.nodeProxy.IsLeader() {
+		s.forwardToLeader(w, r)
+		return
+	}
+	s.setLeaderHeaders(w)
+
+	keys, index, err := s.nodeProxy.QueryKVIndex(field, value, 10*time.Second)
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to query index %s=%s", field, value), err.Error())
+		return
+	}
+	w.Header().Set(HeaderRaftIndex, strconv.FormatUint(index, 10))
+	s.respondWithJSON(w, http.StatusOK, KVIndexQueryResponse{
+		Field: field,
+		Value: value,
+		Keys:  keys,
+		Index: index,
+	})
+}
+
+// handleKVWatch は GET /kv-watch?prefix=... で、prefix で始まるKV APIのキーの変更を Server-Sent Events で配信します。
+// リーダーへは転送せず、受け付けたノードのFSMがコミット済みのログを適用した順に配信します。
+// 最初に ready イベントでウォッチ開始時点の適用済みインデックスを送るため、クライアントは値を読んだ後、それより大きいインデックスの変更だけを反映すればよいです。
+func (s *APIServer) handleKVWatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.respondWithError(w, http.StatusInternalServerError, "Streaming is not supported", "")
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	nodeID := s.nodeProxy.NodeID()
+
+	watcher, appliedIndex := s.nodeProxy.WatchKV(prefix)
+	defer watcher.Close()
+	log.Printf("[INFO] [APIServer] [%s] Watching kv prefix '%s' from index %d for %s", nodeID, prefix, appliedIndex, r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set(HeaderRaftIndex, strconv.FormatUint(appliedIndex, 10))
+	w.WriteHeader(http.StatusOK)
+	if err := writeKVWatchEvent(w, KVWatchEvent{Op: KVWatchEventReady, Index: appliedIndex, NodeID: nodeID, Prefix: prefix}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(kvWatchHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case change, ok := <-watcher.Changes():
+			if !ok {
+				if watcher.Lagged() {
+					log.Printf("[WARN] [APIServer] [%s] Watch on kv prefix '%s' for %s fell behind, closing stream", nodeID, prefix, r.RemoteAddr)
+					_ = writeKVWatchEvent(w, KVWatchEvent{Op: KVWatchEventLagged, NodeID: nodeID, Prefix: prefix})
+					flusher.Flush()
+				}
+				return
+			}
+			err = writeKVWatchEvent(w, KVWatchEvent{Op: string(change.Op), Key: change.Key, Value: string(change.Value), Index: change.Index})
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		case <-s.shutdownCh:
+			return
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeKVWatchEvent はイベントを1つの Server-Sent Event として書き込みます。変更イベントの id にはRaftログのインデックスを使います。
+func writeKVWatchEvent(w io.Writer, event KVWatchEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.Index > 0 && event.Op != KVWatchEventReady {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.Index); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Op, data)
+	return err
+}
+
+// forwardToLeader はリクエストをリーダーのHTTP APIへそのまま転送し、リーダーのレスポンスを返します。
+// 転送済みのリクエストを再度転送することはしません (リーダー交代中のループ防止)。
+func (s *APIServer) forwardToLeader(w http.ResponseWriter, r *http.Request) {
+	if forwardedBy := r.Header.Get(HeaderForwardedBy); forwardedBy != "" {
+		errMsg := fmt.Sprintf("Request forwarded by %s but this node is not the leader", forwardedBy)
+		log.Printf("[WARN] [APIServer] [%s] forwardToLeader: %s", s.nodeProxy.NodeID(), errMsg)
+		s.setLeaderHeaders(w)
+		s.respondWithError(w, http.StatusServiceUnavailable, errMsg, "Leadership changed while forwarding. Please retry.")
+		return
+	}
+
+	leaderHttpAddr, leaderID, err := s.nodeProxy.LeaderHttpApiAddr()
+	if err != nil {
+		log.Printf("[WARN] [APIServer] [%s] forwardToLeader: %v", s.nodeProxy.NodeID(), err)
+		s.setLeaderHeaders(w)
+		s.respondWithError(w, http.StatusServiceUnavailable, "Leader is not available", err.Error())
+		return
+	}
+
+	log.Printf("[INFO] [APIServer] [%s] Forwarding %s %s to leader %s (%s)", s.nodeProxy.NodeID(), r.Method, r.URL.Path, leaderID, leaderHttpAddr)
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: leaderHttpAddr})
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Header.Set(HeaderForwardedBy, s.nodeProxy.NodeID())
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("[ERROR] [APIServer] [%s] Forwarding to leader %s (%s) failed: %v", s.nodeProxy.NodeID(), leaderID, leaderHttpAddr, err)
+		s.respondWithError(w, http.StatusBadGateway, fmt.Sprintf("Failed to forward request to leader %s", leaderID), err.Error())
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// setLeaderHeaders は現在のリーダーのIDとHTTP APIアドレスをレスポンスヘッダに設定します。
+func (s *APIServer) setLeaderHeaders(w http.ResponseWriter) {
+	leaderHttpAddr, leaderID, err := s.nodeProxy.LeaderHttpApiAddr()
+	if leaderID != "" {
+		w.Header().Set(HeaderLeaderID, leaderID)
+	}
+	if err == nil {
+		w.Header().Set(HeaderLeaderAddr, leaderHttpAddr)
+	}
+}
+
+// handleTakeSnapshot は POST /snapshot でこのノードのRaftスナップショットを作成します。
+// スナップショットはノードごとにローカルに作成されます。
+func (s *APIServer) handleTakeSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshot, err := s.nodeProxy.TakeSnapshot()
+	if errors.Is(err, ErrNothingNewToSnapshot) {
+		s.respondWithError(w, http.StatusConflict, "Nothing new to snapshot", err.Error())
+		return
+	}
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "Failed to take snapshot", err.Error())
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, SnapshotResponse{
+		Message:   fmt.Sprintf("Snapshot %s created at index %d", snapshot.ID, snapshot.Index),
+		NodeID:    s.nodeProxy.NodeID(),
+		Snapshots: []SnapshotInfo{snapshot},
+	})
+}
+
+// handleListSnapshots は GET /snapshots でこのノードに保存されているスナップショットを新しい順に返します。
+func (s *APIServer) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	snapshots, err := s.nodeProxy.ListSnapshots()
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "Failed to list snapshots", err.Error())
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, SnapshotResponse{
+		Message:   fmt.Sprintf("%d snapshot(s) found", len(snapshots)),
+		NodeID:    s.nodeProxy.NodeID(),
+		Snapshots: snapshots,
+	})
+}
+
+// handleClusterConfiguration は GET /cluster でこのノードから見た現在のクラスタ構成を返します。
+func (s *APIServer) handleClusterConfiguration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	servers, err := s.nodeProxy.ClusterConfiguration()
+	if err != nil {
+		s.respondWithError(w, http.StatusInternalServerError, "Failed to get cluster configuration", err.Error())
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, ClusterResponse{
+		Message: fmt.Sprintf("Cluster configuration has %d server(s)", len(servers)),
+		Servers: servers,
+	})
+}
+
+// handleClusterAddNode は POST /cluster/add-node でノードを起動してクラスタに追加します。
+// node_id を省略した場合はマネージャーが自動採番します。
+func (s *APIServer) handleClusterAddNode(w http.ResponseWriter, r *http.Request) {
+	manager, req, ok := s.prepareClusterChange(w, r)
+	if !ok {
+		return
+	}
+
+	nodeID, servers, err := manager.AddNode(req.NodeID)
+	if err != nil {
+		log.Printf("[WARN] [APIServer] [%s] handleClusterAddNode: failed to add node %q: %v", s.nodeProxy.NodeID(), req.NodeID, err)
+		s.respondWithError(w, http.StatusInternalServerError, "Failed to add node", err.Error())
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, ClusterResponse{
+		Message: fmt.Sprintf("Node %s added to cluster", nodeID),
+		NodeID:  nodeID,
+		Servers: servers,
+	})
+}
+
+// handleClusterRemoveNode は POST /cluster/remove-node でノードをクラスタから削除して停止します。
+func (s *APIServer) handleClusterRemoveNode(w http.ResponseWriter, r *http.Request) {
+	manager, req, ok := s.prepareClusterChange(w, r)
+	if !ok {
+		return
+	}
+	if req.NodeID == "" {
+		s.respondWithError(w, http.StatusBadRequest, "node_id is required", "")
+		return
+	}
+
+	servers, err := manager.RemoveNode(req.NodeID)
+	if err != nil {
+		log.Printf("[WARN] [APIServer] [%s] handleClusterRemoveNode: failed to remove node %q: %v", s.nodeProxy.NodeID(), req.NodeID, err)
+		s.respondWithError(w, http.StatusInternalServerError, "Failed to remove node", err.Error())
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, ClusterResponse{
+		Message: fmt.Sprintf("Node %s removed from cluster", req.NodeID),
+		NodeID:  req.NodeID,
+		Servers: servers,
+	})
+}
+
+// prepareClusterChange はメンバーシップ変更APIの共通チェックとリクエストのデコードを行います。
+// リーダーの特定とAddVoter/RemoveServerの呼び出しは ClusterManager が行うため、どのノードで受けても構いません。
+func (s *APIServer) prepareClusterChange(w http.ResponseWriter, r *http.Request) (ClusterManager, ClusterNodeRequest, bool) {
+	var req ClusterNodeRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return nil, req, false
+	}
+
+	s.clusterMu.RLock()
+	manager := s.clusterManager
+	s.clusterMu.RUnlock()
+	if manager == nil {
+		s.respondWithError(w, http.StatusNotImplemented, "Cluster membership changes are not supported by this node", "No cluster manager is registered.")
+		return nil, req, false
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.respondWithError(w, http.StatusBadRequest, "Invalid request payload", err.Error())
+			return nil, req, false
+		}
+	}
+	return manager, req, true
+}
+
+// handleChaosStatus は GET /chaos で現在の障害注入の状態を返します。
+func (s *APIServer) handleChaosStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	controller, ok := s.chaosController(w)
+	if !ok {
+		return
+	}
+	status := controller.ChaosStatus()
+	s.respondWithJSON(w, http.StatusOK, ChaosResponse{
+		Message: fmt.Sprintf("%d partition group(s), %d killed node(s)", len(status.Partitions), len(status.KilledNodes)),
+		Status:  status,
+	})
+}
+
+// handleChaosPartition は POST /chaos/partition でノード間のRaft通信をグループ単位で遮断します。
+func (s *APIServer) handleChaosPartition(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	controller, ok := s.chaosController(w)
+	if !ok {
+		return
+	}
+	var req ChaosPartitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid request payload", err.Error())
+		return
+	}
+
+	status, err := controller.Partition(req.Groups)
+	if err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Failed to partition network", err.Error())
+		return
+	}
+	log.Printf("[INFO] [APIServer] [%s] handleChaosPartition: network partitioned into %v", s.nodeProxy.NodeID(), status.Partitions)
+	s.respondWithJSON(w, http.StatusOK, ChaosResponse{
+		Message: fmt.Sprintf("Network partitioned into %d group(s)", len(status.Partitions)),
+		Status:  status,
+	})
+}
+
+// handleChaosHeal は POST /chaos/heal ですべてのネットワーク分断を解消します。
+func (s *APIServer) handleChaosHeal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	controller, ok := s.chaosController(w)
+	if !ok {
+		return
+	}
+	status := controller.Heal()
+	log.Printf("[INFO] [APIServer] [%s] handleChaosHeal: network partitions healed", s.nodeProxy.NodeID())
+	s.respondWithJSON(w, http.StatusOK, ChaosResponse{
+		Message: "Network partitions healed",
+		Status:  status,
+	})
+}
+
+// handleChaosKill は POST /chaos/kill でノードをクラスタ構成に残したまま停止します。
+func (s *APIServer) handleChaosKill(w http.ResponseWriter, r *http.Request) {
+	controller, req, ok := s.prepareChaosNodeChange(w, r)
+	if !ok {
+		return
+	}
+	status, err := controller.KillNode(req.NodeID)
+	if err != nil {
+		log.Printf("[WARN] [APIServer] [%s] handleChaosKill: failed to kill node %q: %v", s.nodeProxy.NodeID(), req.NodeID, err)
+		s.respondWithError(w, http.StatusBadRequest, "Failed to kill node", err.Error())
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, ChaosResponse{
+		Message: fmt.Sprintf("Node %s killed", req.NodeID),
+		Status:  status,
+	})
+}
+
+// handleChaosRestart は POST /chaos/restart で停止中のノードを再起動します。
+func (s *APIServer) handleChaosRestart(w http.ResponseWriter, r *http.Request) {
+	controller, req, ok := s.prepareChaosNodeChange(w, r)
+	if !ok {
+		return
+	}
+	status, err := controller.RestartNode(req.NodeID)
+	if err != nil {
+		log.Printf("[WARN] [APIServer] [%s] handleChaosRestart: failed to restart node %q: %v", s.nodeProxy.NodeID(), req.NodeID, err)
+		s.respondWithError(w, http.StatusBadRequest, "Failed to restart node", err.Error())
+		return
+	}
+	s.respondWithJSON(w, http.StatusOK, ChaosResponse{
+		Message: fmt.Sprintf("Node %s restarted", req.NodeID),
+		Status:  status,
+	})
+}
+
+// prepareChaosNodeChange は kill/restart の共通チェックとリクエストのデコードを行います。
+func (s *APIServer) prepareChaosNodeChange(w http.ResponseWriter, r *http.Request) (ChaosController, ClusterNodeRequest, bool) {
+	var req ClusterNodeRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return nil, req, false
+	}
+	controller, ok := s.chaosController(w)
+	if !ok {
+		return nil, req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondWithError(w, http.StatusBadRequest, "Invalid request payload", err.Error())
+		return nil, req, false
+	}
+	if req.NodeID == "" {
+		s.respondWithError(w, http.StatusBadRequest, "node_id is required", "")
+		return nil, req, false
+	}
+	return controller, req, true
+}
+
+// chaosController は登録済みの ClusterManager が ChaosController を実装していればそれを返します。
+func (s *APIServer) chaosController(w http.ResponseWriter) (ChaosController, bool) {
+	s.clusterMu.RLock()
+	manager := s.clusterManager
+	s.clusterMu.RUnlock()
+	controller, ok := manager.(ChaosController)
+	if !ok {
+		s.respondWithError(w, http.StatusNotImplemented, "Fault injection is not supported by this node", "No chaos controller is registered.")
+		return nil, false
+	}
+	return controller, true
+}
+
 // --- Helper functions for responding ---
 
 func (s *APIServer) respondWithError(w http.ResponseWriter, code int, errorType string, message string) {
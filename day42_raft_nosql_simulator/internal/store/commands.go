@@ -15,6 +15,9 @@ const (
 	PutItemCommandType     CommandType = "PutItem"
 	DeleteItemCommandType  CommandType = "DeleteItem"
 	QueryItemsCommandType  CommandType = "QueryItems"
+	PutKVCommandType       CommandType = "PutKV"
+	DeleteKVCommandType    CommandType = "DeleteKV"
+	BatchWriteCommandType  CommandType = "BatchWrite"
 )
 
 // Command はFSMに適用される操作の汎用ラッパーです。
@@ -56,6 +59,23 @@ type QueryItemsCommandPayload struct {
 	Filter    map[string]interface{} `json:"filter"` // フィルタ条件（省略可）
 }
 
+// PutKVCommandPayload はKV APIのキー書き込みコマンドのペイロードです。
+type PutKVCommandPayload struct {
+	Key         string   `json:"key"`
+	Value       []byte   `json:"value"`                  // 任意のバイト列 (JSONではbase64)
+	IndexFields []string `json:"index_fields,omitempty"` // セカンダリインデックスを張るフィールド (値はJSONオブジェクト)
+}
+
+// DeleteKVCommandPayload はKV APIのキー削除コマンドのペイロードです。
+type DeleteKVCommandPayload struct {
+	Key string `json:"key"`
+}
+
+// BatchWriteCommandPayload はKV APIの複数キーへの書き込みを不可分に適用するコマンドのペイロードです。
+type BatchWriteCommandPayload struct {
+	Operations []KVBatchOperation `json:"operations"`
+}
+
 // EncodeCommand は指定されたコマンドタイプとペイロードからコマンドを生成し、JSONバイト列にエンコードします。
 func EncodeCommand(cmdType CommandType, payload interface{}) ([]byte, error) {
 	payloadBytes, err := json.Marshal(payload)
@@ -109,6 +129,33 @@ func DecodeDeleteItemCommand(payload json.RawMessage) (*DeleteItemCommandPayload
 	return &cmdPayload, nil
 }
 
+// DecodePutKVCommand はコマンドペイロードからPutKVCommandPayloadをデコードします。
+func DecodePutKVCommand(payload json.RawMessage) (*PutKVCommandPayload, error) {
+	var cmdPayload PutKVCommandPayload
+	if err := json.Unmarshal(payload, &cmdPayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal PutKVCommandPayload: %w", err)
+	}
+	return &cmdPayload, nil
+}
+
+// DecodeDeleteKVCommand はコマンドペイロードからDeleteKVCommandPayloadをデコードします。
+func DecodeDeleteKVCommand(payload json.RawMessage) (*DeleteKVCommandPayload, error) {
+	var cmdPayload DeleteKVCommandPayload
+	if err := json.Unmarshal(payload, &cmdPayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DeleteKVCommandPayload: %w", err)
+	}
+	return &cmdPayload, nil
+}
+
+// DecodeBatchWriteCommand はコマンドペイロードからBatchWriteCommandPayloadをデコードします。
+func DecodeBatchWriteCommand(payload json.RawMessage) (*BatchWriteCommandPayload, error) {
+	var cmdPayload BatchWriteCommandPayload
+	if err := json.Unmarshal(payload, &cmdPayload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal BatchWriteCommandPayload: %w", err)
+	}
+	return &cmdPayload, nil
+}
+
 // NewPutItemCommandPayload creates a new PutItemCommandPayload with the current timestamp.
 func NewPutItemCommandPayload(tableName string, itemData map[string]interface{}) (*PutItemCommandPayload, error) {
 	itemBytes, err := json.Marshal(itemData)
@@ -28,6 +28,7 @@ type FSM struct {
 	localNodeID raft.ServerID
 	tables      map[string]TableMetadata // テーブル名とメタデータのマップ
 	logger      *log.Logger
+	watchHub    *KVWatchHub // 適用したKV APIの変更の配信先
 }
 
 // NewFSM は新しいFSMインスタンスを作成します。
@@ -41,9 +42,15 @@ func NewFSM(kvStore *KVStore, localNodeID raft.ServerID, logger *log.Logger) *FS
 		localNodeID: localNodeID,
 		tables:      make(map[string]TableMetadata),
 		logger:      logger,
+		watchHub:    NewKVWatchHub(),
 	}
 }
 
+// WatchKV は prefix で始まるKV APIのキーについて、このノードのFSMが以降に適用した変更を受け取るウォッチャーを登録します。
+func (f *FSM) WatchKV(prefix string) *KVWatcher {
+	return f.watchHub.Watch(prefix)
+}
+
 // Apply はFSMにコマンドを適用します。
 func (f *FSM) Apply(logEntry *raft.Log) interface{} {
 	f.logger.Printf("[DEBUG] FSM.Apply: Received log entry: type=%d, index=%d, term=%d", logEntry.Type, logEntry.Index, logEntry.Term)
@@ -75,6 +82,10 @@ func (f *FSM) Apply(logEntry *raft.Log) interface{} {
 		}
 		f.logger.Printf("[INFO] FSM.Apply: Creating table '%s' with PK '%s', SK '%s'", payload.TableName, payload.PartitionKeyName, payload.SortKeyName)
 
+		if payload.TableName == KVKeyspaceName || payload.TableName == KVIndexKeyspaceName {
+			f.logger.Printf("[WARN] FSM.Apply(CreateTable): Table name '%s' is reserved for the KV API", payload.TableName)
+			return CommandResponse{Success: false, Error: fmt.Sprintf("table name %s is reserved", payload.TableName)}
+		}
 		if _, exists := f.tables[payload.TableName]; exists {
 			f.logger.Printf("[WARN] FSM.Apply(CreateTable): Table '%s' already exists in FSM metadata", payload.TableName)
 			return CommandResponse{Success: false, Error: fmt.Sprintf("table %s already exists", payload.TableName)}
@@ -331,6 +342,48 @@ func (f *FSM) Apply(logEntry *raft.Log) interface{} {
 		}
 		return CommandResponse{Success: true, TableName: payload.TableName, Data: items}
 
+	case PutKVCommandType:
+		payload, err := DecodePutKVCommand(cmd.Payload)
+		if err != nil {
+			f.logger.Printf("[ERROR] FSM.Apply(PutKV): Failed to unmarshal payload: %v", err)
+			return CommandResponse{Success: false, Error: err.Error()}
+		}
+		if err := f.kvStore.PutKV(payload.Key, payload.Value, logEntry.Index, payload.IndexFields); err != nil {
+			f.logger.Printf("[ERROR] FSM.Apply(PutKV): kvStore.PutKV failed for key '%s': %v", payload.Key, err)
+			return CommandResponse{Success: false, ItemKey: payload.Key, Error: fmt.Sprintf("kvStore.PutKV failed: %v", err)}
+		}
+		f.logger.Printf("[INFO] FSM.Apply(PutKV): Successfully put key '%s' at index %d", payload.Key, logEntry.Index)
+		f.watchHub.Publish([]KVChange{{Op: KVChangePut, Key: payload.Key, Value: payload.Value, Index: logEntry.Index}})
+		return CommandResponse{Success: true, ItemKey: payload.Key, Message: "Key put successfully", Data: logEntry.Index}
+
+	case DeleteKVCommandType:
+		payload, err := DecodeDeleteKVCommand(cmd.Payload)
+		if err != nil {
+			f.logger.Printf("[ERROR] FSM.Apply(DeleteKV): Failed to unmarshal payload: %v", err)
+			return CommandResponse{Success: false, Error: err.Error()}
+		}
+		if err := f.kvStore.DeleteKV(payload.Key); err != nil {
+			f.logger.Printf("[ERROR] FSM.Apply(DeleteKV): kvStore.DeleteKV failed for key '%s': %v", payload.Key, err)
+			return CommandResponse{Success: false, ItemKey: payload.Key, Error: fmt.Sprintf("kvStore.DeleteKV failed: %v", err)}
+		}
+		f.logger.Printf("[INFO] FSM.Apply(DeleteKV): Successfully deleted key '%s' at index %d", payload.Key, logEntry.Index)
+		f.watchHub.Publish([]KVChange{{Op: KVChangeDelete, Key: payload.Key, Index: logEntry.Index}})
+		return CommandResponse{Success: true, ItemKey: payload.Key, Message: "Key deleted successfully", Data: logEntry.Index}
+
+	case BatchWriteCommandType:
+		payload, err := DecodeBatchWriteCommand(cmd.Payload)
+		if err != nil {
+			f.logger.Printf("[ERROR] FSM.Apply(BatchWrite): Failed to unmarshal payload: %v", err)
+			return CommandResponse{Success: false, Error: fmt.Sprintf("failed to unmarshal BatchWrite payload: %v", err)}
+		}
+		if err := f.kvStore.ApplyKVBatch(payload.Operations, logEntry.Index); err != nil {
+			f.logger.Printf("[ERROR] FSM.Apply(BatchWrite): kvStore.ApplyKVBatch failed for %d operation(s): %v", len(payload.Operations), err)
+			return CommandResponse{Success: false, Error: fmt.Sprintf("kvStore.ApplyKVBatch failed: %v", err)}
+		}
+		f.logger.Printf("[INFO] FSM.Apply(BatchWrite): Successfully applied %d operation(s) at index %d", len(payload.Operations), logEntry.Index)
+		f.watchHub.Publish(batchChanges(payload.Operations, logEntry.Index))
+		return CommandResponse{Success: true, Message: fmt.Sprintf("Batch of %d operation(s) applied", len(payload.Operations)), Data: logEntry.Index}
+
 	default:
 		f.logger.Printf("[ERROR] FSM.Apply: Unknown command type: %s", cmd.Type)
 		return CommandResponse{Success: false, Error: fmt.Sprintf("unknown command type: %s", cmd.Type)}
@@ -359,17 +412,46 @@ func (f *FSM) ListTables() []string {
 	return tableNames
 }
 
+// snapshotFormatVersion はスナップショットのフォーマットバージョンです。
+// バージョン0 (フィールドなし) はテーブルメタデータのマップのみを保存していた旧フォーマットです。
+const snapshotFormatVersion = 1
+
+// fsmSnapshotData はスナップショットに保存されるFSMの状態です。
+// テーブルメタデータに加えてアイテムとKV APIのキーも含むため、スナップショットだけで新しいノードを復元できます。
+type fsmSnapshotData struct {
+	Version int                              `json:"version"`
+	Tables  map[string]TableMetadata         `json:"tables"`
+	Items   map[string]map[string]StoredItem `json:"items"` // テーブル名 -> 生のアイテムキー -> アイテム
+	KV      map[string]StoredKV              `json:"kv"`
+}
+
 // Snapshot は現在のFSMの状態のスナップショットを返します。
+// Persist はApplyと並行して呼ばれるため、KVStoreの内容はここで読み込んでおきます。
 func (f *FSM) Snapshot() (raft.FSMSnapshot, error) {
 	f.logger.Printf("[INFO] [FSM] [%s] Snapshot: Creating FSM snapshot with current table metadata (%d tables)", f.localNodeID, len(f.tables))
-	tablesCopy := make(map[string]TableMetadata, len(f.tables))
+	data := fsmSnapshotData{
+		Version: snapshotFormatVersion,
+		Tables:  make(map[string]TableMetadata, len(f.tables)),
+		Items:   make(map[string]map[string]StoredItem, len(f.tables)),
+	}
 	for k, v := range f.tables { // f.tables を直接渡すとレースコンディションの可能性があるためコピー
-		tablesCopy[k] = v
+		data.Tables[k] = v
+		items, err := f.kvStore.DumpTable(k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump table %s for snapshot: %w", k, err)
+		}
+		data.Items[k] = items
+	}
+	kvs, err := f.kvStore.DumpKV()
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump kv keys for snapshot: %w", err)
 	}
-	return &fsmSnapshot{tables: tablesCopy, localNodeID: string(f.localNodeID)}, nil
+	data.KV = kvs
+	return &fsmSnapshot{data: data, localNodeID: string(f.localNodeID)}, nil
 }
 
 // Restore はスナップショットからFSMの状態を復元します。
+// 現在のテーブルとKV APIのデータは破棄され、スナップショットの内容で置き換えられます。
 func (f *FSM) Restore(rc io.ReadCloser) error {
 	f.logger.Printf("[INFO] [FSM] [%s] Restore: Restoring FSM from snapshot", f.localNodeID)
 	defer func() {
@@ -378,14 +460,33 @@ func (f *FSM) Restore(rc io.ReadCloser) error {
 		}
 	}()
 
-	var tablesSnapshot map[string]TableMetadata
-	dec := json.NewDecoder(rc)
-	if err := dec.Decode(&tablesSnapshot); err != nil {
+	raw, err := io.ReadAll(rc)
+	if err != nil {
+		f.logger.Printf("[ERROR] [FSM] [%s] Restore: Failed to read snapshot data: %v", f.localNodeID, err)
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	data, err := decodeSnapshotData(raw)
+	if err != nil {
 		f.logger.Printf("[ERROR] [FSM] [%s] Restore: Failed to decode snapshot data: %v", f.localNodeID, err)
 		return fmt.Errorf("failed to decode snapshot: %w", err)
 	}
-	f.tables = tablesSnapshot // 新しいマップで上書き
-	f.logger.Printf("[INFO] [FSM] [%s] Restore: Successfully restored %d tables from snapshot. Ensuring KVStore directories.", f.localNodeID, len(f.tables))
+
+	// 既存のデータを削除してからスナップショットの内容を書き戻す
+	for tableName := range f.tables {
+		if err := f.kvStore.RemoveTableDir(tableName); err != nil {
+			return fmt.Errorf("failed to remove existing table %s before restore: %w", tableName, err)
+		}
+	}
+	if err := f.kvStore.RemoveTableDir(KVKeyspaceName); err != nil {
+		return fmt.Errorf("failed to remove existing kv keys before restore: %w", err)
+	}
+	// インデックスはスナップショットに含めず、キーごとの IndexFields から再構築する
+	if err := f.kvStore.RemoveTableDir(KVIndexKeyspaceName); err != nil {
+		return fmt.Errorf("failed to remove existing kv index before restore: %w", err)
+	}
+
+	f.tables = data.Tables // 新しいマップで上書き
+	f.logger.Printf("[INFO] [FSM] [%s] Restore: Successfully restored %d tables from snapshot (format version %d). Ensuring KVStore directories.", f.localNodeID, len(f.tables), data.Version)
 
 	for tableName := range f.tables {
 		f.logger.Printf("[DEBUG] [FSM] [%s] Restore: Ensuring directory for restored table '%s'", f.localNodeID, tableName)
@@ -396,22 +497,67 @@ func (f *FSM) Restore(rc io.ReadCloser) error {
 			f.logger.Printf("[ERROR] [FSM] [%s] Restore: Failed to ensure directory for restored table '%s' in KVStore: %v", f.localNodeID, tableName, err)
 			return fmt.Errorf("failed to ensure kvstore directory for restored table %s: %w", tableName, err)
 		}
+		for itemKey, item := range data.Items[tableName] {
+			if err := f.kvStore.PutItem(tableName, itemKey, item.Data, item.Timestamp); err != nil {
+				return fmt.Errorf("failed to restore item %s in table %s: %w", itemKey, tableName, err)
+			}
+		}
+	}
+	for key, kv := range data.KV {
+		if err := f.kvStore.PutKV(key, kv.Value, kv.Index, kv.IndexFields); err != nil {
+			return fmt.Errorf("failed to restore key %s: %w", key, err)
+		}
 	}
 	f.logger.Printf("[INFO] [FSM] [%s] Restore: Completed successfully.", f.localNodeID)
+	// 復元前後の差分は配信できないため、ウォッチャーには値を読み直すよう通知する
+	f.watchHub.Publish([]KVChange{{Op: KVChangeReset}})
 	return nil
 }
 
+// batchChanges はバッチ書き込みの操作をウォッチャーへ配信する変更に変換します。
+func batchChanges(ops []KVBatchOperation, index uint64) []KVChange {
+	changes := make([]KVChange, len(ops))
+	for i, op := range ops {
+		if op.Op == KVBatchDelete {
+			changes[i] = KVChange{Op: KVChangeDelete, Key: op.Key, Index: index}
+			continue
+		}
+		changes[i] = KVChange{Op: KVChangePut, Key: op.Key, Value: op.Value, Index: index}
+	}
+	return changes
+}
+
+// decodeSnapshotData はスナップショットのバイト列をデコードします。旧フォーマット (テーブルメタデータのみ) も受け付けます。
+func decodeSnapshotData(raw []byte) (fsmSnapshotData, error) {
+	var data fsmSnapshotData
+	if err := json.Unmarshal(raw, &data); err == nil && data.Version >= 1 {
+		if data.Tables == nil {
+			data.Tables = make(map[string]TableMetadata)
+		}
+		return data, nil
+	}
+
+	var legacyTables map[string]TableMetadata
+	if err := json.Unmarshal(raw, &legacyTables); err != nil {
+		return fsmSnapshotData{}, err
+	}
+	if legacyTables == nil {
+		legacyTables = make(map[string]TableMetadata)
+	}
+	return fsmSnapshotData{Tables: legacyTables}, nil
+}
+
 // fsmSnapshot は Raft のスナップショットインターフェースを実装します。
 type fsmSnapshot struct {
-	tables      map[string]TableMetadata
+	data        fsmSnapshotData
 	localNodeID string
 }
 
 // Persist はスナップショットの内容を Raft のストレージに永続化します。
 func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
-	s.logf(log.Printf, "[INFO] fsmSnapshot.Persist started with %d tables. Sink ID: %s", len(s.tables), sink.ID())
+	s.logf(log.Printf, "[INFO] fsmSnapshot.Persist started with %d tables and %d kv keys. Sink ID: %s", len(s.data.Tables), len(s.data.KV), sink.ID())
 	err := func() error {
-		data, err := json.Marshal(s.tables)
+		data, err := json.Marshal(s.data)
 		if err != nil {
 			s.logf(log.Printf, "[ERROR] fsmSnapshot.Persist failed to marshal tables: %v", err)
 			return fmt.Errorf("failed to marshal tables for snapshot: %w", err)
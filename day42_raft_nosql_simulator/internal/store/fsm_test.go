@@ -428,15 +428,15 @@ func TestFSM_SnapshotRestore(t *testing.T) {
 	require.True(t, exists, "Restored FSM should have table metadata")
 	require.Equal(t, tableName, meta.TableName)
 
-	// FSMのスナップショット/リストアはテーブルメタデータのみ復元する仕様のため、
-	// アイテムデータの復元は行われない。以下のテストは削除またはコメントアウト。
-	// itemStoreKey := "item1"
-	// retrievedData, _, err := newFSM.kvStore.GetItem(tableName, itemStoreKey)
-	// require.NoError(t, err, "Item should exist in restored FSM. Key: %s", itemStoreKey)
-	// var retrievedMap, originalMap map[string]interface{}
-	// json.Unmarshal(retrievedData, &retrievedMap)
-	// json.Unmarshal(itemDataBytes, &originalMap)
-	// require.True(t, deepEqualWithNumberTolerance(originalMap, retrievedMap))
+	// スナップショットにはアイテムデータも含まれるため、新しいノードのKVStoreにも復元される
+	itemStoreKey := "item1"
+	retrievedData, ts, err := newFSM.kvStore.GetItem(tableName, itemStoreKey)
+	require.NoError(t, err, "Item should exist in restored FSM. Key: %s", itemStoreKey)
+	require.Equal(t, int64(12345), ts)
+	var retrievedMap, originalMap map[string]interface{}
+	require.NoError(t, json.Unmarshal(retrievedData, &retrievedMap))
+	require.NoError(t, json.Unmarshal(itemDataBytes, &originalMap))
+	require.True(t, deepEqualWithNumberTolerance(originalMap, retrievedMap))
 
 	snapshot.Release()
 }
@@ -537,3 +537,222 @@ func TestFSM_Restore_KVStoreError(t *testing.T) {
 // io.ReadCloser のためのヘルパー
 // import "io"
 // io.NopCloser(bytes.NewReader(data))
+
+func TestFSM_KVOperations(t *testing.T) {
+	fsm, kv, _ := setupFSMWithKVStore(t)
+
+	t.Run("PutKV", func(t *testing.T) {
+		cmdBytes := mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: "config/feature", Value: []byte("on")})
+		resp, ok := fsm.Apply(&raft.Log{Index: 7, Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+		require.True(t, ok)
+		require.True(t, resp.Success, "PutKV should succeed. Error: %s", resp.Error)
+		require.Equal(t, uint64(7), resp.Data)
+
+		value, index, err := kv.GetKV("config/feature")
+		require.NoError(t, err)
+		require.Equal(t, []byte("on"), value)
+		require.Equal(t, uint64(7), index)
+		require.NotContains(t, fsm.ListTables(), KVKeyspaceName, "KV keyspace must not be listed as a table")
+	})
+
+	t.Run("DeleteKV", func(t *testing.T) {
+		cmdBytes := mustEncode(t, DeleteKVCommandType, DeleteKVCommandPayload{Key: "config/feature"})
+		resp, ok := fsm.Apply(&raft.Log{Index: 8, Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+		require.True(t, ok)
+		require.True(t, resp.Success, "DeleteKV should succeed. Error: %s", resp.Error)
+
+		_, _, err := kv.GetKV("config/feature")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("PutKV_empty_key", func(t *testing.T) {
+		cmdBytes := mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: "", Value: []byte("x")})
+		resp, ok := fsm.Apply(&raft.Log{Index: 9, Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+		require.True(t, ok)
+		require.False(t, resp.Success)
+		require.Contains(t, resp.Error, "key cannot be empty")
+	})
+
+	t.Run("CreateTable_reserved_name", func(t *testing.T) {
+		for i, name := range []string{KVKeyspaceName, KVIndexKeyspaceName} {
+			cmdBytes := mustEncode(t, CreateTableCommandType, CreateTableCommandPayload{TableName: name, PartitionKeyName: "pk"})
+			resp, ok := fsm.Apply(&raft.Log{Index: uint64(10 + i), Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+			require.True(t, ok)
+			require.False(t, resp.Success)
+			require.Contains(t, resp.Error, "reserved")
+		}
+	})
+}
+
+func TestFSM_BatchWrite(t *testing.T) {
+	fsm, kv, _ := setupFSMWithKVStore(t)
+
+	t.Run("Batch is applied at the log index", func(t *testing.T) {
+		cmdBytes := mustEncode(t, BatchWriteCommandType, BatchWriteCommandPayload{Operations: []KVBatchOperation{
+			{Op: KVBatchPut, Key: "x", Value: []byte("1")},
+			{Op: KVBatchPut, Key: "y", Value: []byte("2")},
+		}})
+		resp, ok := fsm.Apply(&raft.Log{Index: 11, Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+		require.True(t, ok)
+		require.True(t, resp.Success, "BatchWrite should succeed. Error: %s", resp.Error)
+		require.Equal(t, uint64(11), resp.Data)
+
+		for _, key := range []string{"x", "y"} {
+			_, index, err := kv.GetKV(key)
+			require.NoError(t, err)
+			require.Equal(t, uint64(11), index)
+		}
+	})
+
+	t.Run("Invalid batch changes nothing", func(t *testing.T) {
+		cmdBytes := mustEncode(t, BatchWriteCommandType, BatchWriteCommandPayload{Operations: []KVBatchOperation{
+			{Op: KVBatchDelete, Key: "x"},
+			{Op: KVBatchPut, Key: ""},
+		}})
+		resp, ok := fsm.Apply(&raft.Log{Index: 12, Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+		require.True(t, ok)
+		require.False(t, resp.Success)
+		require.Contains(t, resp.Error, "key cannot be empty")
+
+		_, _, err := kv.GetKV("x")
+		require.NoError(t, err, "Delete in a rejected batch must not be applied")
+	})
+}
+
+func TestFSM_WatchKV(t *testing.T) {
+	fsm, _, _ := setupFSMWithKVStore(t)
+
+	watcher := fsm.WatchKV("users/")
+	defer watcher.Close()
+	all := fsm.WatchKV("")
+	defer all.Close()
+
+	apply := func(index uint64, cmdBytes []byte) {
+		resp, ok := fsm.Apply(&raft.Log{Index: index, Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+		require.True(t, ok)
+		require.True(t, resp.Success, "Apply should succeed. Error: %s", resp.Error)
+	}
+	apply(1, mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: "users/1", Value: []byte("alice")}))
+	apply(2, mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: "orders/1", Value: []byte("x")}))
+	apply(3, mustEncode(t, BatchWriteCommandType, BatchWriteCommandPayload{Operations: []KVBatchOperation{
+		{Op: KVBatchPut, Key: "users/2", Value: []byte("bob")},
+		{Op: KVBatchDelete, Key: "orders/1"},
+	}}))
+	apply(4, mustEncode(t, DeleteKVCommandType, DeleteKVCommandPayload{Key: "users/1"}))
+
+	// 失敗したコマンドは配信されない
+	resp := fsm.Apply(&raft.Log{Index: 5, Data: mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: ""}), Type: raft.LogCommand}).(CommandResponse)
+	require.False(t, resp.Success)
+
+	receive := func(w *KVWatcher, n int) []KVChange {
+		var changes []KVChange
+		for i := 0; i < n; i++ {
+			select {
+			case change := <-w.Changes():
+				changes = append(changes, change)
+			case <-time.After(time.Second):
+				t.Fatalf("Timed out waiting for change %d", i)
+			}
+		}
+		select {
+		case change := <-w.Changes():
+			t.Fatalf("Unexpected change: %+v", change)
+		default:
+		}
+		return changes
+	}
+	require.Equal(t, []KVChange{
+		{Op: KVChangePut, Key: "users/1", Value: []byte("alice"), Index: 1},
+		{Op: KVChangePut, Key: "users/2", Value: []byte("bob"), Index: 3},
+		{Op: KVChangeDelete, Key: "users/1", Index: 4},
+	}, receive(watcher, 3))
+	require.Len(t, receive(all, 5), 5)
+
+	t.Run("Restore notifies watchers to re-read", func(t *testing.T) {
+		snapshot, err := fsm.Snapshot()
+		require.NoError(t, err)
+		sink := &mockSnapshotSink{}
+		require.NoError(t, snapshot.Persist(sink))
+		require.NoError(t, fsm.Restore(io.NopCloser(bytes.NewReader(sink.Bytes()))))
+		require.Equal(t, []KVChange{{Op: KVChangeReset}}, receive(watcher, 1))
+	})
+
+	t.Run("Slow watcher is closed instead of blocking Apply", func(t *testing.T) {
+		slow := fsm.WatchKV("slow/")
+		for i := 0; i < kvWatchBufferSize+1; i++ {
+			apply(uint64(100+i), mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: "slow/key", Value: []byte("v")}))
+		}
+		received := 0
+		for range slow.Changes() {
+			received++
+		}
+		require.Equal(t, kvWatchBufferSize, received)
+		require.True(t, slow.Lagged())
+		slow.Close() // 閉じられた後の Close は何もしない
+	})
+}
+
+func TestFSM_KVIndexSurvivesSnapshotRestore(t *testing.T) {
+	fsm, kv, _ := setupFSMWithKVStore(t)
+
+	put := func(index uint64, key, value string, fields ...string) {
+		cmdBytes := mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: key, Value: []byte(value), IndexFields: fields})
+		resp, ok := fsm.Apply(&raft.Log{Index: index, Data: cmdBytes, Type: raft.LogCommand}).(CommandResponse)
+		require.True(t, ok)
+		require.True(t, resp.Success, "PutKV should succeed. Error: %s", resp.Error)
+	}
+	put(1, "users/1", `{"city":"Tokyo"}`, "city")
+	put(2, "users/2", `{"city":"Tokyo"}`, "city")
+
+	snap, err := fsm.Snapshot()
+	require.NoError(t, err)
+	sink := &mockSnapshotSink{id: "index"}
+	require.NoError(t, snap.Persist(sink))
+
+	// スナップショット後のインデックス変更はRestoreで破棄される
+	put(3, "users/3", `{"city":"Tokyo"}`, "city")
+	put(4, "users/1", `{"city":"Osaka"}`, "city")
+
+	require.NoError(t, fsm.Restore(io.NopCloser(bytes.NewReader(sink.Bytes()))))
+	keys, err := kv.QueryKVIndex("city", "Tokyo")
+	require.NoError(t, err)
+	require.Equal(t, []string{"users/1", "users/2"}, keys)
+	keys, err = kv.QueryKVIndex("city", "Osaka")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
+
+func TestFSM_Restore_ReplacesStateAndAcceptsLegacyFormat(t *testing.T) {
+	fsm, kv, _ := setupFSMWithKVStore(t)
+
+	fsm.Apply(&raft.Log{Index: 1, Type: raft.LogCommand, Data: mustEncode(t, CreateTableCommandType, CreateTableCommandPayload{TableName: "Keep", PartitionKeyName: "id"})})
+	fsm.Apply(&raft.Log{Index: 2, Type: raft.LogCommand, Data: mustEncode(t, PutItemCommandType, PutItemCommandPayload{TableName: "Keep", Item: json.RawMessage(`{"id":"a"}`), Timestamp: 1})})
+	fsm.Apply(&raft.Log{Index: 3, Type: raft.LogCommand, Data: mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: "k/1", Value: []byte("v1")})})
+
+	snap, err := fsm.Snapshot()
+	require.NoError(t, err)
+	sink := &mockSnapshotSink{id: "replace"}
+	require.NoError(t, snap.Persist(sink))
+
+	// スナップショット後の変更はRestoreで破棄される
+	fsm.Apply(&raft.Log{Index: 4, Type: raft.LogCommand, Data: mustEncode(t, CreateTableCommandType, CreateTableCommandPayload{TableName: "Drop", PartitionKeyName: "id"})})
+	fsm.Apply(&raft.Log{Index: 5, Type: raft.LogCommand, Data: mustEncode(t, PutKVCommandType, PutKVCommandPayload{Key: "k/2", Value: []byte("v2")})})
+
+	require.NoError(t, fsm.Restore(io.NopCloser(bytes.NewReader(sink.Bytes()))))
+	require.ElementsMatch(t, []string{"Keep"}, fsm.ListTables())
+	_, _, err = kv.GetItem("Keep", "a")
+	require.NoError(t, err)
+	value, index, err := kv.GetKV("k/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), value)
+	require.Equal(t, uint64(3), index)
+	_, _, err = kv.GetKV("k/2")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 旧フォーマット (テーブルメタデータのマップのみ)
+	legacy := []byte(`{"Legacy":{"table_name":"Legacy","partition_key_name":"pk"}}`)
+	require.NoError(t, fsm.Restore(io.NopCloser(bytes.NewReader(legacy))))
+	require.ElementsMatch(t, []string{"Legacy"}, fsm.ListTables())
+	_, _, err = kv.GetKV("k/1")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"fmt"
+	"log"
+)
+
+// KVBatchOp はバッチ書き込みに含まれる1操作の種類です。
+type KVBatchOp string
+
+const (
+	KVBatchPut    KVBatchOp = "put"
+	KVBatchDelete KVBatchOp = "delete"
+)
+
+// MaxKVBatchOperations は1つのバッチ書き込みに含められる操作の最大数です。
+const MaxKVBatchOperations = 100
+
+// KVBatchOperation はバッチ書き込みに含まれるKV APIのキーへの1操作です。
+type KVBatchOperation struct {
+	Op          KVBatchOp `json:"op"`
+	Key         string    `json:"key"`
+	Value       []byte    `json:"value,omitempty"`        // put の場合のみ (JSONではbase64)
+	IndexFields []string  `json:"index_fields,omitempty"` // put の場合のみ
+}
+
+// ValidateKVBatch はバッチ書き込みの全操作を検証します。1つでも不正な操作があればバッチ全体が拒否されます。
+func ValidateKVBatch(ops []KVBatchOperation) error {
+	if len(ops) == 0 {
+		return fmt.Errorf("batch has no operations")
+	}
+	if len(ops) > MaxKVBatchOperations {
+		return fmt.Errorf("batch has too many operations: %d (max %d)", len(ops), MaxKVBatchOperations)
+	}
+	for i, op := range ops {
+		if err := ValidateKVKey(op.Key); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+		switch op.Op {
+		case KVBatchPut:
+			if err := ValidateIndexFields(op.IndexFields); err != nil {
+				return fmt.Errorf("operation %d (key %s): %w", i, op.Key, err)
+			}
+		case KVBatchDelete:
+			if len(op.Value) > 0 || len(op.IndexFields) > 0 {
+				return fmt.Errorf("operation %d (key %s): delete cannot have a value or index fields", i, op.Key)
+			}
+		default:
+			return fmt.Errorf("operation %d (key %s): unknown op %q (expected %q or %q)", i, op.Key, op.Op, KVBatchPut, KVBatchDelete)
+		}
+	}
+	return nil
+}
+
+// ApplyKVBatch はバッチ書き込みを不可分に適用します。
+// 全操作を検証してから順に適用し、途中で失敗した場合は変更したキーを適用前の状態 (インデックスを含む) に戻してエラーを返します。
+// 適用中は kvMu を保持するため、読み取り側からバッチの途中の状態が見えることはありません。
+// 同じキーへの複数の操作は順に適用され、最後の操作の結果が残ります。
+func (s *KVStore) ApplyKVBatch(ops []KVBatchOperation, index uint64) error {
+	if err := ValidateKVBatch(ops); err != nil {
+		return err
+	}
+
+	s.kvMu.Lock()
+	defer s.kvMu.Unlock()
+
+	// ロールバック用に、各キーの適用前の状態を最初に触れる前に保存しておく
+	var backups []kvBatchBackup
+	saved := make(map[string]bool, len(ops))
+
+	for i, op := range ops {
+		if !saved[op.Key] {
+			filePath, err := s.getKVFilePath(op.Key)
+			if err != nil {
+				return err
+			}
+			previous, err := s.readStoredKV(op.Key, filePath)
+			if err != nil {
+				s.rollbackKVBatchLocked(backups)
+				return fmt.Errorf("operation %d (key %s): %w", i, op.Key, err)
+			}
+			backups = append(backups, kvBatchBackup{key: op.Key, previous: previous})
+			saved[op.Key] = true
+		}
+
+		var err error
+		if op.Op == KVBatchPut {
+			err = s.putKVLocked(op.Key, op.Value, index, op.IndexFields)
+		} else {
+			err = s.deleteKVLocked(op.Key)
+		}
+		if err != nil {
+			log.Printf("[ERROR] [KVStore] [%s] ApplyKVBatch: operation %d (%s %s) failed at index %d, rolling back %d key(s): %v", s.localNodeID, i, op.Op, op.Key, index, len(backups), err)
+			s.rollbackKVBatchLocked(backups)
+			return fmt.Errorf("operation %d (%s %s) failed, batch rolled back: %w", i, op.Op, op.Key, err)
+		}
+	}
+	return nil
+}
+
+// kvBatchBackup はバッチ適用前のキーの状態です。
+type kvBatchBackup struct {
+	key      string
+	previous *StoredKV // nil の場合、適用前にキーは存在しなかった
+}
+
+// rollbackKVBatchLocked はバッチで変更したキーを適用前の状態に逆順で戻します。
+func (s *KVStore) rollbackKVBatchLocked(backups []kvBatchBackup) {
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		var err error
+		if b.previous == nil {
+			err = s.deleteKVLocked(b.key)
+		} else {
+			err = s.putKVLocked(b.key, b.previous.Value, b.previous.Index, b.previous.IndexFields)
+		}
+		if err != nil {
+			log.Printf("[ERROR] [KVStore] [%s] rollbackKVBatch: failed to restore key '%s': %v", s.localNodeID, b.key, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// KVIndexKeyspaceName はKV APIのセカンダリインデックスを保存するディレクトリ名です。テーブル名としては予約されています。
+	KVIndexKeyspaceName = "_kv_index"
+	// maxIndexFieldLength はインデックス対象フィールド名 (ドット区切りのパス) の最大長です。
+	maxIndexFieldLength = 64
+	// maxIndexFieldsPerKey は1つのキーに宣言できるインデックス対象フィールドの最大数です。
+	maxIndexFieldsPerKey = 8
+)
+
+// kvIndexEntries は1つのフィールドのインデックスファイルの内容です (正規化した値 -> ソート済みのキー一覧)。
+type kvIndexEntries map[string][]string
+
+// ValidateIndexFields はKV APIの書き込みで宣言されたインデックス対象フィールドを検証します。
+// フィールドはJSONオブジェクトのトップレベルのフィールド名か、"address.city" のようなドット区切りのパスです。
+func ValidateIndexFields(fields []string) error {
+	if len(fields) > maxIndexFieldsPerKey {
+		return fmt.Errorf("too many index fields: %d (max %d)", len(fields), maxIndexFieldsPerKey)
+	}
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if err := ValidateIndexField(field); err != nil {
+			return err
+		}
+		if seen[field] {
+			return fmt.Errorf("index field %q is declared twice", field)
+		}
+		seen[field] = true
+	}
+	return nil
+}
+
+// ValidateIndexField はインデックス対象フィールド名として使用できるかを検証します。
+func ValidateIndexField(field string) error {
+	if field == "" {
+		return fmt.Errorf("index field cannot be empty")
+	}
+	if len(field) > maxIndexFieldLength {
+		return fmt.Errorf("index field is too long: %d bytes (max %d)", len(field), maxIndexFieldLength)
+	}
+	for _, part := range strings.Split(field, ".") {
+		if part == "" {
+			return fmt.Errorf("index field %q has an empty path segment", field)
+		}
+	}
+	return nil
+}
+
+// IndexValues は値 (JSONオブジェクト) から各インデックス対象フィールドの値を取り出し、正規化して返します。
+// 値がJSONオブジェクトでない場合や、フィールドが存在しないかスカラー値でない場合、そのフィールドはインデックスされません。
+func IndexValues(value []byte, fields []string) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(value, &doc); err != nil {
+		return nil
+	}
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if v, ok := lookupField(doc, field); ok {
+			if normalized, ok := normalizeIndexValue(v); ok {
+				values[field] = normalized
+			}
+		}
+	}
+	return values
+}
+
+// lookupField はドット区切りのパスでJSONオブジェクトのフィールドを参照します。
+func lookupField(doc map[string]interface{}, field string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(field, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// normalizeIndexValue はスカラー値をクエリで比較する文字列に変換します。数値は 30 と 30.0 を同じ値として扱います。
+func normalizeIndexValue(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default: // null, オブジェクト, 配列はインデックスしない
+		return "", false
+	}
+}
+
+// QueryKVIndex は field の値が value であるKV APIのキーをソートして返します。
+// value は文字列としてそのまま比較されます (数値フィールドは "30"、真偽値は "true" のように指定します)。
+func (s *KVStore) QueryKVIndex(field, value string) ([]string, error) {
+	if err := ValidateIndexField(field); err != nil {
+		return nil, err
+	}
+	s.kvMu.RLock()
+	defer s.kvMu.RUnlock()
+
+	entries, err := s.readIndexFile(field)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(entries[value]))
+	copy(keys, entries[value])
+	return keys, nil
+}
+
+// updateKVIndex はキーの値の変更に合わせてインデックスを更新します。
+// oldValues / newValues は IndexValues の結果で、削除の場合 newValues は nil です。呼び出し側で kvMu を保持している必要があります。
+func (s *KVStore) updateKVIndex(key string, oldValues, newValues map[string]string) error {
+	fields := make(map[string]bool, len(oldValues)+len(newValues))
+	for field := range oldValues {
+		fields[field] = true
+	}
+	for field := range newValues {
+		fields[field] = true
+	}
+
+	for field := range fields {
+		oldValue, hadOld := oldValues[field]
+		newValue, hasNew := newValues[field]
+		if hadOld && hasNew && oldValue == newValue {
+			continue
+		}
+
+		entries, err := s.readIndexFile(field)
+		if err != nil {
+			return err
+		}
+		if hadOld {
+			entries[oldValue] = removeSortedKey(entries[oldValue], key)
+			if len(entries[oldValue]) == 0 {
+				delete(entries, oldValue)
+			}
+		}
+		if hasNew {
+			entries[newValue] = insertSortedKey(entries[newValue], key)
+		}
+		if err := s.writeIndexFile(field, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *KVStore) indexFilePath(field string) string {
+	return filepath.Join(s.tablePath(KVIndexKeyspaceName), url.PathEscape(field)+".json")
+}
+
+// readIndexFile はフィールドのインデックスファイルを読み込みます。ファイルがない場合は空のエントリを返します。
+func (s *KVStore) readIndexFile(field string) (kvIndexEntries, error) {
+	data, err := os.ReadFile(s.indexFilePath(field))
+	if os.IsNotExist(err) {
+		return make(kvIndexEntries), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index file for field %s: %w", field, err)
+	}
+	entries := make(kvIndexEntries)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal index file for field %s: %w", field, err)
+	}
+	return entries, nil
+}
+
+// writeIndexFile はフィールドのインデックスファイルを書き込みます。エントリが空になった場合はファイルを削除します。
+func (s *KVStore) writeIndexFile(field string, entries kvIndexEntries) error {
+	path := s.indexFilePath(field)
+	if len(entries) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove index file for field %s: %w", field, err)
+		}
+		return nil
+	}
+	if err := s.EnsureTableDir(KVIndexKeyspaceName); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index for field %s: %w", field, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Printf("[ERROR] [KVStore] [%s] writeIndexFile: FAILED to write file '%s': %v", s.localNodeID, path, err)
+		return fmt.Errorf("failed to write index file for field %s: %w", field, err)
+	}
+	return nil
+}
+
+func insertSortedKey(keys []string, key string) []string {
+	i := sort.SearchStrings(keys, key)
+	if i < len(keys) && keys[i] == key {
+		return keys
+	}
+	keys = append(keys, "")
+	copy(keys[i+1:], keys[i:])
+	keys[i] = key
+	return keys
+}
+
+func removeSortedKey(keys []string, key string) []string {
+	i := sort.SearchStrings(keys, key)
+	if i == len(keys) || keys[i] != key {
+		return keys
+	}
+	return append(keys[:i], keys[i+1:]...)
+}
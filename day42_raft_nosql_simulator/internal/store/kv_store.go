@@ -9,16 +9,28 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // ErrItemNotFound はアイテムが見つからない場合に返されるエラーです。
 var ErrItemNotFound = errors.New("item not found")
 
+// ErrKeyNotFound はKV APIのキーが見つからない場合に返されるエラーです。
+var ErrKeyNotFound = errors.New("key not found")
+
+const (
+	// KVKeyspaceName はKV APIのキーを保存するディレクトリ名です。テーブル名としては予約されています。
+	KVKeyspaceName = "_kv"
+	// maxKVKeyLength はKV APIのキーの最大長 (バイト) です。エスケープ後もファイル名の上限に収まるようにします。
+	maxKVKeyLength = 80
+)
+
 // KVStore はローカルファイルシステム上でキーバリューストアを管理します。
 // 各テーブルはベースディレクトリ内のサブディレクトリとして表現されます。
 type KVStore struct {
 	baseDir     string
-	localNodeID string // デバッグログ用
+	localNodeID string       // デバッグログ用
+	kvMu        sync.RWMutex // KV APIのキーとセカンダリインデックスのファイルの読み書きを保護 (バッチ書き込みを不可分にする)
 }
 
 // NewKVStore は新しいKVStoreインスタンスを作成します。
@@ -358,5 +370,202 @@ func (s *KVStore) QueryItems(tableName string, partitionKey string, sortKeyPrefi
 	return items, nil
 }
 
-// TODO: PutItem, GetItem, DeleteItem, QueryItems などのメソッドを実装
-// このTODOコメントはもう不要なので削除します。
+// StoredKV はKV APIのキーごとのファイルに保存される構造です。
+// Index はそのキーを最後に更新したRaftログのインデックスです。
+// IndexFields は書き込み時に宣言されたセカンダリインデックスの対象フィールドです。
+type StoredKV struct {
+	Index       uint64   `json:"index"`
+	Value       []byte   `json:"value"`
+	IndexFields []string `json:"index_fields,omitempty"`
+}
+
+// ValidateKVKey はKV APIのキーとして使用できるかを検証します。
+// テーブルのアイテムキーと異なり、キーには任意の文字 ('/' を含む) を使用できます。
+func ValidateKVKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+	if len(key) > maxKVKeyLength {
+		return fmt.Errorf("key is too long: %d bytes (max %d)", len(key), maxKVKeyLength)
+	}
+	return nil
+}
+
+// getKVFilePath はKV APIのキーに対するファイルのフルパスを生成します。
+// ファイル名にはキーをURLパスエスケープしたものを使います ('/' もエスケープされる)。
+func (s *KVStore) getKVFilePath(key string) (string, error) {
+	if err := ValidateKVKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.tablePath(KVKeyspaceName), url.PathEscape(key)+".json"), nil
+}
+
+// PutKV はKV APIのキーに値を保存します。
+// 書き込みはRaftログの順に適用されるため、LWWのチェックは行わず常に上書きします。
+// indexFields が指定された場合、値 (JSONオブジェクト) のそれらのフィールドをセカンダリインデックスに登録します。
+// 前回の書き込みで登録したインデックスエントリは、今回宣言されていないフィールドも含めて置き換えられます。
+func (s *KVStore) PutKV(key string, value []byte, index uint64, indexFields []string) error {
+	s.kvMu.Lock()
+	defer s.kvMu.Unlock()
+	return s.putKVLocked(key, value, index, indexFields)
+}
+
+func (s *KVStore) putKVLocked(key string, value []byte, index uint64, indexFields []string) error {
+	log.Printf("[INFO] [KVStore] [%s] PutKV: CALLED for key='%s', index=%d, value_size=%d, index_fields=%v", s.localNodeID, key, index, len(value), indexFields)
+	filePath, err := s.getKVFilePath(key)
+	if err != nil {
+		return err
+	}
+	if err := ValidateIndexFields(indexFields); err != nil {
+		return err
+	}
+	previous, err := s.readStoredKV(key, filePath)
+	if err != nil {
+		return err
+	}
+	if err := s.EnsureTableDir(KVKeyspaceName); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(StoredKV{Index: index, Value: value, IndexFields: indexFields}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal key %s for storage: %w", key, err)
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		log.Printf("[ERROR] [KVStore] [%s] PutKV: FAILED to write file '%s': %v", s.localNodeID, filePath, err)
+		return fmt.Errorf("failed to write key %s to file: %w", key, err)
+	}
+
+	var oldValues map[string]string
+	if previous != nil {
+		oldValues = IndexValues(previous.Value, previous.IndexFields)
+	}
+	if err := s.updateKVIndex(key, oldValues, IndexValues(value, indexFields)); err != nil {
+		return fmt.Errorf("failed to update index for key %s: %w", key, err)
+	}
+	return nil
+}
+
+// readStoredKV はキーのファイルを読み込みます。キーが存在しない場合は nil を返します。
+func (s *KVStore) readStoredKV(key, filePath string) (*StoredKV, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", key, err)
+	}
+	var stored StoredKV
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal key file %s: %w", key, err)
+	}
+	return &stored, nil
+}
+
+// GetKV はKV APIのキーの値と、そのキーを最後に更新したRaftログのインデックスを返します。
+// キーが存在しない場合は ErrKeyNotFound を返します。
+func (s *KVStore) GetKV(key string) ([]byte, uint64, error) {
+	s.kvMu.RLock()
+	defer s.kvMu.RUnlock()
+	filePath, err := s.getKVFilePath(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	stored, err := s.readStoredKV(key, filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	if stored == nil {
+		return nil, 0, ErrKeyNotFound
+	}
+	return stored.Value, stored.Index, nil
+}
+
+// DeleteKV はKV APIのキーとそのインデックスエントリを削除します。存在しないキーの削除は成功として扱います。
+func (s *KVStore) DeleteKV(key string) error {
+	s.kvMu.Lock()
+	defer s.kvMu.Unlock()
+	return s.deleteKVLocked(key)
+}
+
+func (s *KVStore) deleteKVLocked(key string) error {
+	log.Printf("[INFO] [KVStore] [%s] DeleteKV: CALLED for key='%s'", s.localNodeID, key)
+	filePath, err := s.getKVFilePath(key)
+	if err != nil {
+		return err
+	}
+	previous, err := s.readStoredKV(key, filePath)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return nil
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("[ERROR] [KVStore] [%s] DeleteKV: FAILED to remove file '%s': %v", s.localNodeID, filePath, err)
+		return fmt.Errorf("failed to remove key file %s: %w", key, err)
+	}
+	if err := s.updateKVIndex(key, IndexValues(previous.Value, previous.IndexFields), nil); err != nil {
+		return fmt.Errorf("failed to update index for key %s: %w", key, err)
+	}
+	return nil
+}
+
+// DumpTable はテーブル内の全アイテムを読み込み、生のアイテムキー -> StoredItem のマップで返します。
+// スナップショット作成時に使用します。テーブルディレクトリが存在しない場合は空のマップを返します。
+func (s *KVStore) DumpTable(tableName string) (map[string]StoredItem, error) {
+	items := make(map[string]StoredItem)
+	err := s.walkJSONFiles(s.tablePath(tableName), func(rawKey string, data []byte) error {
+		var stored StoredItem
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return fmt.Errorf("failed to unmarshal item %s in table %s: %w", rawKey, tableName, err)
+		}
+		items[rawKey] = stored
+		return nil
+	})
+	return items, err
+}
+
+// DumpKV はKV APIの全キーを読み込み、キー -> StoredKV のマップで返します。
+func (s *KVStore) DumpKV() (map[string]StoredKV, error) {
+	s.kvMu.RLock()
+	defer s.kvMu.RUnlock()
+	kvs := make(map[string]StoredKV)
+	err := s.walkJSONFiles(s.tablePath(KVKeyspaceName), func(key string, data []byte) error {
+		var stored StoredKV
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return fmt.Errorf("failed to unmarshal key %s: %w", key, err)
+		}
+		kvs[key] = stored
+		return nil
+	})
+	return kvs, err
+}
+
+// walkJSONFiles はディレクトリ内の *.json ファイルを、ファイル名をアンエスケープしたキーとともに fn に渡します。
+func (s *KVStore) walkJSONFiles(dir string, fn func(key string, data []byte) error) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			log.Printf("[WARN] [KVStore] [%s] walkJSONFiles: failed to unescape file name '%s', skipping: %v", s.localNodeID, entry.Name(), err)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", entry.Name(), err)
+		}
+		if err := fn(key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
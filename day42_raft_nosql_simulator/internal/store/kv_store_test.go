@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -325,3 +326,153 @@ func TestKVStore_QueryItems(t *testing.T) {
 		require.Contains(t, err.Error(), "does not exist")
 	})
 }
+
+func TestKVStore_KVOperations(t *testing.T) {
+	kv, err := NewKVStore(t.TempDir(), "test-kv-node")
+	require.NoError(t, err)
+
+	t.Run("Get non-existent key", func(t *testing.T) {
+		_, _, err := kv.GetKV("missing")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("Put and overwrite", func(t *testing.T) {
+		require.NoError(t, kv.PutKV("a/b c", []byte("v1"), 3, nil))
+		require.NoError(t, kv.PutKV("a/b c", []byte("v2"), 5, nil))
+		value, index, err := kv.GetKV("a/b c")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), value)
+		require.Equal(t, uint64(5), index)
+	})
+
+	t.Run("Delete is idempotent", func(t *testing.T) {
+		require.NoError(t, kv.DeleteKV("a/b c"))
+		require.NoError(t, kv.DeleteKV("a/b c"))
+		_, _, err := kv.GetKV("a/b c")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("Invalid keys", func(t *testing.T) {
+		require.Error(t, kv.PutKV("", []byte("v"), 1, nil))
+		require.Error(t, kv.PutKV(strings.Repeat("k", maxKVKeyLength+1), []byte("v"), 1, nil))
+	})
+}
+
+func TestKVStore_KVIndex(t *testing.T) {
+	kv, err := NewKVStore(t.TempDir(), "test-kv-index-node")
+	require.NoError(t, err)
+
+	queryKeys := func(t *testing.T, field, value string) []string {
+		t.Helper()
+		keys, err := kv.QueryKVIndex(field, value)
+		require.NoError(t, err)
+		return keys
+	}
+
+	require.NoError(t, kv.PutKV("users/2", []byte(`{"city":"Tokyo","age":30,"address":{"zip":"100"}}`), 1, []string{"city", "age", "address.zip"}))
+	require.NoError(t, kv.PutKV("users/1", []byte(`{"city":"Tokyo","age":25.0}`), 2, []string{"city", "age"}))
+	require.NoError(t, kv.PutKV("users/3", []byte(`{"city":"Tokyo"}`), 3, nil))
+
+	t.Run("Query returns sorted keys of indexed writes only", func(t *testing.T) {
+		require.Equal(t, []string{"users/1", "users/2"}, queryKeys(t, "city", "Tokyo"))
+		require.Equal(t, []string{"users/2"}, queryKeys(t, "age", "30"))
+		require.Equal(t, []string{"users/1"}, queryKeys(t, "age", "25"), "Numbers are normalized")
+		require.Equal(t, []string{"users/2"}, queryKeys(t, "address.zip", "100"), "Nested fields can be indexed")
+		require.Empty(t, queryKeys(t, "city", "Osaka"))
+		require.Empty(t, queryKeys(t, "unknown", "x"))
+	})
+
+	t.Run("Overwrite moves and drops index entries", func(t *testing.T) {
+		require.NoError(t, kv.PutKV("users/2", []byte(`{"city":"Osaka","age":30}`), 4, []string{"city"}))
+		require.Equal(t, []string{"users/1"}, queryKeys(t, "city", "Tokyo"))
+		require.Equal(t, []string{"users/2"}, queryKeys(t, "city", "Osaka"))
+		require.Empty(t, queryKeys(t, "age", "30"), "Fields no longer declared are removed from the index")
+		require.Empty(t, queryKeys(t, "address.zip", "100"))
+	})
+
+	t.Run("Delete removes index entries", func(t *testing.T) {
+		require.NoError(t, kv.DeleteKV("users/1"))
+		require.Empty(t, queryKeys(t, "city", "Tokyo"))
+		require.Empty(t, queryKeys(t, "age", "25"))
+	})
+
+	t.Run("Non-object values and invalid fields", func(t *testing.T) {
+		require.NoError(t, kv.PutKV("raw", []byte("not json"), 5, []string{"city"}), "Non-JSON values are stored without index entries")
+		require.Empty(t, queryKeys(t, "city", "not json"))
+		require.Error(t, kv.PutKV("bad", []byte(`{}`), 6, []string{"a..b"}))
+		require.Error(t, kv.PutKV("bad", []byte(`{}`), 6, []string{"city", "city"}))
+		_, err := kv.QueryKVIndex("", "x")
+		require.Error(t, err)
+	})
+}
+
+func TestKVStore_ApplyKVBatch(t *testing.T) {
+	baseDir := t.TempDir()
+	kv, err := NewKVStore(baseDir, "test-kv-batch-node")
+	require.NoError(t, err)
+
+	require.NoError(t, kv.PutKV("a", []byte(`{"city":"Tokyo"}`), 1, []string{"city"}))
+	require.NoError(t, kv.PutKV("b", []byte("old-b"), 2, nil))
+
+	t.Run("All operations are applied with the same index", func(t *testing.T) {
+		err := kv.ApplyKVBatch([]KVBatchOperation{
+			{Op: KVBatchPut, Key: "c", Value: []byte(`{"city":"Tokyo"}`), IndexFields: []string{"city"}},
+			{Op: KVBatchPut, Key: "d", Value: []byte("v-d")},
+			{Op: KVBatchDelete, Key: "d"},
+			{Op: KVBatchPut, Key: "e", Value: []byte("v-e")},
+		}, 3)
+		require.NoError(t, err)
+
+		_, index, err := kv.GetKV("c")
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), index)
+		_, _, err = kv.GetKV("d")
+		require.ErrorIs(t, err, ErrKeyNotFound, "Later operations on the same key win")
+		keys, err := kv.QueryKVIndex("city", "Tokyo")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "c"}, keys)
+	})
+
+	t.Run("Invalid operation rejects the whole batch", func(t *testing.T) {
+		err := kv.ApplyKVBatch([]KVBatchOperation{
+			{Op: KVBatchPut, Key: "f", Value: []byte("v-f")},
+			{Op: "upsert", Key: "g", Value: []byte("v-g")},
+		}, 4)
+		require.Error(t, err)
+		_, _, err = kv.GetKV("f")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		require.Error(t, kv.ApplyKVBatch(nil, 4), "Empty batch is rejected")
+		require.Error(t, kv.ApplyKVBatch([]KVBatchOperation{{Op: KVBatchDelete, Key: "a", Value: []byte("x")}}, 4))
+	})
+
+	t.Run("Failure in the middle rolls back earlier operations", func(t *testing.T) {
+		// キーのファイルパスにディレクトリを置いて書き込みを失敗させる
+		require.NoError(t, os.MkdirAll(filepath.Join(baseDir, KVKeyspaceName, "blocked.json"), 0755))
+
+		err := kv.ApplyKVBatch([]KVBatchOperation{
+			{Op: KVBatchPut, Key: "a", Value: []byte(`{"city":"Osaka"}`), IndexFields: []string{"city"}},
+			{Op: KVBatchDelete, Key: "b"},
+			{Op: KVBatchPut, Key: "h", Value: []byte("v-h")},
+			{Op: KVBatchPut, Key: "blocked", Value: []byte("v")},
+		}, 5)
+		require.Error(t, err)
+
+		value, index, err := kv.GetKV("a")
+		require.NoError(t, err)
+		require.Equal(t, `{"city":"Tokyo"}`, string(value))
+		require.Equal(t, uint64(1), index, "Rolled back key keeps its original index")
+		value, _, err = kv.GetKV("b")
+		require.NoError(t, err)
+		require.Equal(t, "old-b", string(value))
+		_, _, err = kv.GetKV("h")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+
+		keys, err := kv.QueryKVIndex("city", "Tokyo")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "c"}, keys, "Index entries are rolled back too")
+		keys, err = kv.QueryKVIndex("city", "Osaka")
+		require.NoError(t, err)
+		require.Empty(t, keys)
+	})
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"strings"
+	"sync"
+)
+
+// KVChangeOp はKV APIのキーに対する変更の種類です。
+type KVChangeOp string
+
+const (
+	KVChangePut    KVChangeOp = "put"
+	KVChangeDelete KVChangeOp = "delete"
+	// KVChangeReset はスナップショットからの復元でKV APIのデータ全体が置き換えられたことを表します。Key は空です。
+	KVChangeReset KVChangeOp = "reset"
+)
+
+// kvWatchBufferSize は1つのウォッチャーが受け取らずに溜めておける変更の数です。
+const kvWatchBufferSize = 256
+
+// KVChange はFSMが適用したKV APIのキーへの1つの変更です。
+type KVChange struct {
+	Op    KVChangeOp
+	Key   string
+	Value []byte // put の場合のみ
+	Index uint64 // 変更を適用したRaftログのインデックス (reset の場合は 0)
+}
+
+// KVWatchHub はFSMが適用したKV APIの変更を、キーのプレフィックスごとのウォッチャーへ配信します。
+// 配信はFSMの Apply をブロックしません。バッファが一杯になったウォッチャーは取りこぼしが発生したとして閉じられます。
+type KVWatchHub struct {
+	mu       sync.Mutex
+	watchers map[*KVWatcher]struct{}
+}
+
+// NewKVWatchHub は新しいKVWatchHubを作成します。
+func NewKVWatchHub() *KVWatchHub {
+	return &KVWatchHub{watchers: make(map[*KVWatcher]struct{})}
+}
+
+// KVWatcher はプレフィックスに一致するキーの変更を受け取るウォッチャーです。
+type KVWatcher struct {
+	hub    *KVWatchHub
+	prefix string
+	ch     chan KVChange
+	closed bool // hub.mu で保護される
+	lagged bool // hub.mu で保護される
+}
+
+// Watch は prefix で始まるキーの変更を受け取るウォッチャーを登録します。prefix が空の場合は全キーが対象です。
+// 使い終わったら Close を呼び出す必要があります。
+func (h *KVWatchHub) Watch(prefix string) *KVWatcher {
+	w := &KVWatcher{
+		hub:    h,
+		prefix: prefix,
+		ch:     make(chan KVChange, kvWatchBufferSize),
+	}
+	h.mu.Lock()
+	h.watchers[w] = struct{}{}
+	h.mu.Unlock()
+	return w
+}
+
+// Publish は1つのRaftログで適用された変更をウォッチャーへ配信します。
+// 同じログの変更は順に配信され、ウォッチャーのバッファに入りきらない場合そのウォッチャーは閉じられます。
+func (h *KVWatchHub) Publish(changes []KVChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for w := range h.watchers {
+		for _, change := range changes {
+			if change.Op != KVChangeReset && !strings.HasPrefix(change.Key, w.prefix) {
+				continue
+			}
+			select {
+			case w.ch <- change:
+			default:
+				w.lagged = true
+				w.closeLocked()
+			}
+			if w.closed {
+				break
+			}
+		}
+	}
+}
+
+// Changes は変更を受け取るチャネルを返します。ウォッチャーが閉じられるとチャネルも閉じられます。
+func (w *KVWatcher) Changes() <-chan KVChange {
+	return w.ch
+}
+
+// Lagged は変更を受け取り切れずにウォッチャーが閉じられた場合 true を返します。
+// この場合クライアントは現在の値を読み直してから改めてウォッチする必要があります。
+func (w *KVWatcher) Lagged() bool {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+	return w.lagged
+}
+
+// Close はウォッチャーの登録を解除し、チャネルを閉じます。複数回呼び出しても安全です。
+func (w *KVWatcher) Close() {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+	w.closeLocked()
+}
+
+func (w *KVWatcher) closeLocked() {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	delete(w.hub.watchers, w)
+	close(w.ch)
+}
@@ -59,23 +59,39 @@ func (e *TopLevelExpression) sealedExpression() {}
 
 // --- Literal Values ---
 type Literal struct {
-	// Option 1: Parenthesized Expression
+	// Option 1: Parenthesized Expression, or Tuple when Rest is not empty
 	LParen  *string `  @"("`
 	SubExpr *Term   `  @@`
+	Rest    []*Term `  ( "," @@ )*`
 	RParen  *string `  @")"`
-	// Option 2: Integer Literal
+	// Option 2: Record Literal
+	Record *Record `| @@`
+	// Option 3: Integer Literal
 	IntVal *int `| @Int`
-	// Option 3: Boolean Literals
+	// Option 4: Boolean Literals
 	TrueTag  *string `| @True`
 	FalseTag *string `| @False`
-	// Option 4: Variable Identifier
+	// Option 5: Variable Identifier
 	Variable *string `| @Ident`
 }
 
+// IsTuple はリテラルが組 (a, b, ...) かどうかを返します。
+func (l *Literal) IsTuple() bool {
+	return l.LParen != nil && len(l.Rest) > 0
+}
+
+// TupleElements は組の要素を先頭から順に返します。
+func (l *Literal) TupleElements() []*Term {
+	return append([]*Term{l.SubExpr}, l.Rest...)
+}
+
 func (l *Literal) Pos() int {
 	if l.LParen != nil && l.SubExpr != nil { // Parenthesized expression
 		return l.SubExpr.Pos()
 	}
+	if l.Record != nil {
+		return l.Record.Pos()
+	}
 	if l.IntVal != nil {
 		return 0 // Placeholder
 	}
@@ -90,7 +106,14 @@ func (l *Literal) Pos() int {
 
 func (l *Literal) String() string {
 	if l.LParen != nil && l.SubExpr != nil && l.RParen != nil {
-		return fmt.Sprintf("(%s)", l.SubExpr.String())
+		elems := make([]string, 0, len(l.Rest)+1)
+		for _, elem := range l.TupleElements() {
+			elems = append(elems, elem.String())
+		}
+		return fmt.Sprintf("(%s)", strings.Join(elems, ", "))
+	}
+	if l.Record != nil {
+		return l.Record.String()
 	}
 	if l.IntVal != nil {
 		return fmt.Sprintf("%d", *l.IntVal)
@@ -109,6 +132,57 @@ func (l *Literal) String() string {
 
 func (l *Literal) sealedExpression() {}
 
+// Record はレコードリテラル {x = 1, y = true} を表します。
+type Record struct {
+	LBrace string         `@"{"`
+	Fields []*RecordField `@@ ( "," @@ )*`
+	RBrace string         `@"}"`
+}
+
+func (r *Record) Pos() int {
+	return 0
+}
+func (r *Record) String() string {
+	fields := make([]string, len(r.Fields))
+	for i, field := range r.Fields {
+		fields[i] = field.String()
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+func (r *Record) sealedExpression() {}
+
+// RecordField はレコードリテラルの1つのフィールド name = expr です。
+type RecordField struct {
+	Name  string `@Ident`
+	Eq    string `@Assign`
+	Value *Term  `@@`
+}
+
+func (f *RecordField) String() string {
+	valueStr := "<nil_value>"
+	if f.Value != nil {
+		valueStr = f.Value.String()
+	}
+	return fmt.Sprintf("%s = %s", f.Name, valueStr)
+}
+
+// Projection はレコードのフィールド参照 .x、または組の要素参照 .1 (1始まり) を表します。
+type Projection struct {
+	Dot   string  `@Dot`
+	Field *string `( @Ident`
+	Index *int    `| @Int )`
+}
+
+func (p *Projection) String() string {
+	if p.Field != nil {
+		return "." + *p.Field
+	}
+	if p.Index != nil {
+		return fmt.Sprintf(".%d", *p.Index)
+	}
+	return ".<invalid_projection>"
+}
+
 // --- Basic Terms ---
 // Factor は基本要素、または関数適用を表します。
 // f a b  のような形式をパースします (f が BaseFactor、a と b が引数としての BaseFactor)。
@@ -148,9 +222,10 @@ func (f *Factor) String() string {
 func (f *Factor) sealedExpression() {}
 
 type BaseFactor struct {
-	Literal *Literal `  @@`
-	Lambda  *Lambda  `| @@`
-	If      *If      `| @@`
+	Literal     *Literal      `  ( @@`
+	Projections []*Projection `    @@* )` // r.x や p.1.2 のように続くフィールド参照
+	Lambda      *Lambda       `| @@`
+	If          *If           `| @@`
 	// Parenthesized expressions are handled by Literal.SubExpr
 }
 
@@ -168,7 +243,11 @@ func (bf *BaseFactor) Pos() int {
 }
 func (bf *BaseFactor) String() string {
 	if bf.Literal != nil {
-		return bf.Literal.String()
+		res := bf.Literal.String()
+		for _, proj := range bf.Projections {
+			res += proj.String()
+		}
+		return res
 	}
 	if bf.Lambda != nil {
 		return bf.Lambda.String()
@@ -327,12 +406,14 @@ func (bt *BoolTerm) sealedExpression() {}
 
 // --- Compound Expressions ---
 type Let struct {
-	LetKw    string              `@LetKw`  // "let"
-	VarName  string              `@Ident`  // Variable name
-	Eq       string              `@Assign` // "=" from lexer
-	BindExpr *Term               `@@`      // Expression to bind
-	InKw     string              `@InKw`   // "in"
-	BodyExpr *TopLevelExpression `@@`      // Body expression
+	LetKw    string              `@LetKw`        // "let"
+	Rec      bool                `@RecKw?`       // "rec" があれば再帰束縛 (VarName を BindExpr 内で参照できる)
+	VarName  string              `@Ident`        // Variable name
+	VarType  *TypeExpr           `( Colon @@ )?` // 省略可能な型注釈 "let y : Bool = ..."
+	Eq       string              `@Assign`       // "=" from lexer
+	BindExpr *Term               `@@`            // Expression to bind
+	InKw     string              `@InKw`         // "in"
+	BodyExpr *TopLevelExpression `@@`            // Body expression
 }
 
 func (l *Let) Pos() int {
@@ -355,7 +436,14 @@ func (l *Let) String() string {
 	if l.BodyExpr != nil {
 		bodyStr = l.BodyExpr.String()
 	}
-	return fmt.Sprintf("let %s = %s in %s", varNameStr, bindStr, bodyStr)
+	letStr := "let"
+	if l.Rec {
+		letStr = "let rec"
+	}
+	if l.VarType != nil {
+		varNameStr += " : " + l.VarType.String()
+	}
+	return fmt.Sprintf("%s %s = %s in %s", letStr, varNameStr, bindStr, bodyStr)
 }
 func (l *Let) sealedExpression() {}
 
@@ -392,10 +480,11 @@ func (i *If) String() string {
 func (i *If) sealedExpression() {}
 
 type Lambda struct {
-	FnKw     string `@FnKw`  // "fn"
-	Param    string `@Ident` // Parameter name
-	Arrow    string `@Arrow` // "=>" from lexer
-	BodyExpr *Term  `@@`     // Body expression
+	FnKw      string    `@FnKw`                       // "fn"
+	Param     string    `( @Ident | "(" @Ident Colon` // Parameter name ("fn x" または型注釈付きの "fn (x : Int)")
+	ParamType *TypeExpr `  @@ ")" )`                  // 省略可能な引数の型注釈
+	Arrow     string    `@Arrow`                      // "=>" from lexer
+	BodyExpr  *Term     `@@`                          // Body expression
 }
 
 func (l *Lambda) Pos() int {
@@ -407,6 +496,9 @@ func (l *Lambda) String() string {
 	if l.Param != "" {
 		paramStr = l.Param
 	}
+	if l.ParamType != nil {
+		paramStr = fmt.Sprintf("(%s : %s)", paramStr, l.ParamType.String())
+	}
 	bodyStr := "<nil_body>"
 	if l.BodyExpr != nil {
 		bodyStr = l.BodyExpr.String()
@@ -414,3 +506,71 @@ func (l *Lambda) String() string {
 	return fmt.Sprintf("fn %s => %s", paramStr, bodyStr)
 }
 func (l *Lambda) sealedExpression() {}
+
+// --- Type Annotations ---
+// TypeExpr は型注釈 (例: Int, Int -> Bool, Int * Bool, {x : Int}) を表します。
+// -> は右結合で、* (組) は -> より強く結合します。
+type TypeExpr struct {
+	Param  *TupleTypeExpr `@@`
+	Return *TypeExpr      `( TypeArrow @@ )?`
+}
+
+func (t *TypeExpr) String() string {
+	if t.Param == nil {
+		return "<nil_type>"
+	}
+	if t.Return != nil {
+		return t.Param.String() + " -> " + t.Return.String()
+	}
+	return t.Param.String()
+}
+
+// TupleTypeExpr は組の型注釈 Int * Bool です。要素が1つの場合は組ではなくその要素の型を表します。
+type TupleTypeExpr struct {
+	Elements []*AtomTypeExpr `@@ ( Multiply @@ )*`
+}
+
+func (t *TupleTypeExpr) String() string {
+	elems := make([]string, len(t.Elements))
+	for i, elem := range t.Elements {
+		elems[i] = elem.String()
+	}
+	return strings.Join(elems, " * ")
+}
+
+// AtomTypeExpr は型名 (Int, Bool)、括弧で囲まれた型注釈、またはレコードの型注釈 {x : Int, y : Bool} です。
+type AtomTypeExpr struct {
+	Name   *string            `  @Ident`
+	Paren  *TypeExpr          `| "(" @@ ")"`
+	Record []*RecordTypeField `| "{" @@ ( "," @@ )* "}"`
+}
+
+func (t *AtomTypeExpr) String() string {
+	switch {
+	case t.Name != nil:
+		return *t.Name
+	case t.Paren != nil:
+		return "(" + t.Paren.String() + ")"
+	case t.Record != nil:
+		fields := make([]string, len(t.Record))
+		for i, field := range t.Record {
+			fields[i] = field.String()
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return "<invalid_type>"
+}
+
+// RecordTypeField はレコードの型注釈の1つのフィールド name : Type です。
+type RecordTypeField struct {
+	Name string    `@Ident`
+	Type *TypeExpr `Colon @@`
+}
+
+func (f *RecordTypeField) String() string {
+	typeStr := "<nil_type>"
+	if f.Type != nil {
+		typeStr = f.Type.String()
+	}
+	return fmt.Sprintf("%s : %s", f.Name, typeStr)
+}
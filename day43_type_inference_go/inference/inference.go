@@ -135,9 +135,45 @@ func inferExpr(env TypeEnvironment, expr ast.Expression, sub unification.Substit
 			}
 			return freshType, sub, nil
 		}
+		if e.IsTuple() { // (e1, e2, ...)
+			elements := e.TupleElements()
+			elemTypes := make([]types.Type, len(elements))
+			sCurrent := sub
+			for i, elem := range elements {
+				elemType, sElem, err := inferExpr(env.Apply(sCurrent), elem, sCurrent)
+				if err != nil {
+					return nil, sElem, fmt.Errorf("type inference failed for tuple element %d: %w", i+1, err)
+				}
+				sCurrent = sCurrent.Compose(sElem)
+				elemTypes[i] = elemType
+			}
+			for i := range elemTypes { // 後の要素の推論で確定した型変数を反映する
+				elemTypes[i] = unification.Apply(sCurrent, elemTypes[i])
+			}
+			return types.TTuple{Elements: elemTypes}, sCurrent, nil
+		}
 		if e.SubExpr != nil { // Parenthesized expression
 			return inferExpr(env, e.SubExpr, sub)
 		}
+		if e.Record != nil { // {x = e1, y = e2, ...}
+			fields := make(map[string]types.Type, len(e.Record.Fields))
+			sCurrent := sub
+			for _, field := range e.Record.Fields {
+				if _, dup := fields[field.Name]; dup {
+					return nil, sCurrent, fmt.Errorf("duplicate field '%s' in record %s", field.Name, e.Record.String())
+				}
+				fieldType, sField, err := inferExpr(env.Apply(sCurrent), field.Value, sCurrent)
+				if err != nil {
+					return nil, sField, fmt.Errorf("type inference failed for record field '%s': %w", field.Name, err)
+				}
+				sCurrent = sCurrent.Compose(sField)
+				fields[field.Name] = fieldType
+			}
+			for name, fieldType := range fields {
+				fields[name] = unification.Apply(sCurrent, fieldType)
+			}
+			return types.TRecord{Fields: fields}, sCurrent, nil
+		}
 		return nil, sub, fmt.Errorf("unknown literal structure: %s", expr.String())
 
 	case *ast.Factor: // 関数適用 (f arg1 arg2 ...)
@@ -341,15 +377,61 @@ func inferExpr(env TypeEnvironment, expr ast.Expression, sub unification.Substit
 
 	case *ast.Let:
 		// VarName: string, BindExpr: Term, BodyExpr: TopLevelExpression
-		// For non-recursive let:
 		// 1. Infer type of BindExpr in current env
-		bindExprType, sBind, err := inferExpr(env, e.BindExpr, sub)
+		//    For let rec, VarName is bound to a fresh (monomorphic) type variable while inferring BindExpr,
+		//    and that variable is unified with the inferred type afterwards.
+		//    With a type annotation, VarName is bound to the annotated type instead (let rec), and a bare lambda
+		//    is checked against it so that its parameter gets the annotated argument type.
+		var annotatedType types.Type
+		if e.VarType != nil {
+			var errAnnot error
+			annotatedType, errAnnot = typeFromAnnotation(e.VarType)
+			if errAnnot != nil {
+				return nil, sub, fmt.Errorf("invalid type annotation for '%s': %w", e.VarName, errAnnot)
+			}
+		}
+		bindEnv := env
+		var recVar types.Type
+		if e.Rec {
+			recVar = types.NewTypeVar()
+			if annotatedType != nil {
+				recVar = annotatedType
+			}
+			bindEnv = env.Extend(e.VarName, types.TScheme{BodyType: recVar})
+		}
+		var bindExprType types.Type
+		var sBind unification.Substitution
+		var err error
+		if fnType, ok := annotatedType.(types.TFunc); ok && bareLambda(e.BindExpr) != nil {
+			bindExprType, sBind, err = inferLambda(bindEnv, bareLambda(e.BindExpr), sub, fnType.ArgType)
+		} else {
+			bindExprType, sBind, err = inferExpr(bindEnv, e.BindExpr, sub)
+		}
 		if err != nil {
 			return nil, sBind, fmt.Errorf("type inference failed for let binding of '%s': %w", e.VarName, err)
 		}
 		sCurrent := sBind
 		bindExprType = unification.Apply(sCurrent, bindExprType) // Apply substitutions to the binding's type
 
+		if e.Rec {
+			recUseType := unification.Apply(sCurrent, recVar) // How VarName was used inside its own definition
+			sUnifyRec, errUnifyRec := unification.Unify(recUseType, bindExprType)
+			if errUnifyRec != nil {
+				return nil, sCurrent, fmt.Errorf("recursive binding '%s' is used as %s in its definition but defined as %s: %w", e.VarName, recUseType, bindExprType, errUnifyRec)
+			}
+			sCurrent = sCurrent.Compose(sUnifyRec)
+			bindExprType = unification.Apply(sCurrent, bindExprType)
+		}
+
+		if annotatedType != nil {
+			sUnifyAnnot, errUnifyAnnot := unification.Unify(annotatedType, bindExprType)
+			if errUnifyAnnot != nil {
+				return nil, sCurrent, fmt.Errorf("'%s' is annotated as %s but its value has type %s: %w", e.VarName, annotatedType, bindExprType, errUnifyAnnot)
+			}
+			sCurrent = sCurrent.Compose(sUnifyAnnot)
+			bindExprType = unification.Apply(sCurrent, bindExprType)
+		}
+
 		// 2. Generalize bindExprType with respect to (env + sCurrent)
 		//    The environment used for generalization should have sCurrent applied.
 		generalizedScheme := GeneralizeType(env.Apply(sCurrent), bindExprType)
@@ -368,26 +450,8 @@ func inferExpr(env TypeEnvironment, expr ast.Expression, sub unification.Substit
 
 		return unification.Apply(sCurrent, bodyType), sCurrent, nil
 
-	case *ast.Lambda: // Param: string, BodyExpr: Term
-		paramType := types.NewTypeVar()
-
-		// Extend environment with param: paramType (not a scheme, so QuantifiedVars is empty)
-		// For `let id = fn x => x`, `id` becomes polymorphic. `x` itself is monomorphic within `fn x => x`.
-		paramScheme := types.TScheme{BodyType: paramType} // No quantified vars for lambda param initially
-		extendedEnv := env.Extend(e.Param, paramScheme)
-
-		// Infer body type in extended environment. Start with the current substitution 'sub'.
-		bodyType, sBody, err := inferExpr(extendedEnv, e.BodyExpr, sub)
-		if err != nil {
-			return nil, sBody, fmt.Errorf("type inference failed for lambda body: %w", err)
-		}
-		sCurrent := sBody // Substitutions from body inference
-
-		// Apply the substitutions from body inference to the parameter's type variable
-		finalParamType := unification.Apply(sCurrent, paramType)
-		finalBodyType := unification.Apply(sCurrent, bodyType)
-
-		return types.TFunc{ArgType: finalParamType, ReturnType: finalBodyType}, sCurrent, nil
+	case *ast.Lambda: // Param: string, ParamType: *TypeExpr (optional), BodyExpr: Term
+		return inferLambda(env, e, sub, nil)
 
 	case *ast.Program:
 		if e.Expression != nil {
@@ -407,7 +471,18 @@ func inferExpr(env TypeEnvironment, expr ast.Expression, sub unification.Substit
 
 	case *ast.BaseFactor: // Literal, Lambda, If (or parenthesized via Literal.SubExpr)
 		if e.Literal != nil {
-			return inferExpr(env, e.Literal, sub)
+			litType, sLit, err := inferExpr(env, e.Literal, sub)
+			if err != nil {
+				return nil, sLit, err
+			}
+			currentType := unification.Apply(sLit, litType)
+			for _, proj := range e.Projections {
+				currentType, err = inferProjection(currentType, proj)
+				if err != nil {
+					return nil, sLit, err
+				}
+			}
+			return currentType, sLit, nil
 		}
 		if e.Lambda != nil {
 			return inferExpr(env, e.Lambda, sub)
@@ -429,3 +504,154 @@ func inferExpr(env TypeEnvironment, expr ast.Expression, sub unification.Substit
 		return nil, sub, fmt.Errorf("unhandled AST node type in inference: %T (%s)", expr, expr.String())
 	}
 }
+
+// inferProjection はレコードのフィールド参照 r.x または組の要素参照 p.1 の結果の型を求めます。
+// 行多相は扱わないため、参照する時点でレコードや組の型が確定している必要があります。
+func inferProjection(targetType types.Type, proj *ast.Projection) (types.Type, error) {
+	switch t := targetType.(type) {
+	case types.TRecord:
+		if proj.Field == nil {
+			return nil, fmt.Errorf("cannot access element %s of record type %s: use a field name", proj.String(), t)
+		}
+		fieldType, ok := t.Fields[*proj.Field]
+		if !ok {
+			return nil, fmt.Errorf("record type %s has no field '%s'", t, *proj.Field)
+		}
+		return fieldType, nil
+	case types.TTuple:
+		if proj.Index == nil {
+			return nil, fmt.Errorf("cannot access field %s of tuple type %s: use an element number such as .1", proj.String(), t)
+		}
+		if *proj.Index < 1 || *proj.Index > len(t.Elements) {
+			return nil, fmt.Errorf("tuple type %s has no element %d (elements are numbered 1 to %d)", t, *proj.Index, len(t.Elements))
+		}
+		return t.Elements[*proj.Index-1], nil
+	case types.TVar:
+		return nil, fmt.Errorf("cannot infer the type accessed by %s: the type %s is not known to be a record or tuple at this point", proj.String(), t)
+	default:
+		return nil, fmt.Errorf("cannot access %s of non-record, non-tuple type %s", proj.String(), t)
+	}
+}
+
+// inferLambda はラムダ式の型を推論します。
+// 引数に型注釈があればその型を、なければ paramHint (let の型注釈から得た引数の型、nil 可) を引数の型として本体を推論します。
+// どちらもなければ引数の型は新しい型変数です。
+func inferLambda(env TypeEnvironment, e *ast.Lambda, sub unification.Substitution, paramHint types.Type) (types.Type, unification.Substitution, error) {
+	var paramType types.Type = types.NewTypeVar()
+	if paramHint != nil {
+		paramType = paramHint
+	}
+	if e.ParamType != nil {
+		annotatedType, err := typeFromAnnotation(e.ParamType)
+		if err != nil {
+			return nil, sub, fmt.Errorf("invalid type annotation for parameter '%s': %w", e.Param, err)
+		}
+		if paramHint != nil {
+			// 型注釈は型変数を含まないため、一致するかどうかだけを確認すればよい
+			if _, err := unification.Unify(annotatedType, paramHint); err != nil {
+				return nil, sub, fmt.Errorf("parameter '%s' is annotated as %s but expected to be %s: %w", e.Param, annotatedType, paramHint, err)
+			}
+		}
+		paramType = annotatedType
+	}
+
+	// Extend environment with param: paramType (not a scheme, so QuantifiedVars is empty)
+	// For `let id = fn x => x`, `id` becomes polymorphic. `x` itself is monomorphic within `fn x => x`.
+	paramScheme := types.TScheme{BodyType: paramType} // No quantified vars for lambda param initially
+	extendedEnv := env.Extend(e.Param, paramScheme)
+
+	// Infer body type in extended environment. Start with the current substitution 'sub'.
+	bodyType, sBody, err := inferExpr(extendedEnv, e.BodyExpr, sub)
+	if err != nil {
+		if _, isVar := paramType.(types.TVar); !isVar {
+			return nil, sBody, fmt.Errorf("type inference failed for lambda body (parameter '%s' has type %s): %w", e.Param, paramType, err)
+		}
+		return nil, sBody, fmt.Errorf("type inference failed for lambda body: %w", err)
+	}
+	sCurrent := sBody // Substitutions from body inference
+
+	// Apply the substitutions from body inference to the parameter's type
+	finalParamType := unification.Apply(sCurrent, paramType)
+	finalBodyType := unification.Apply(sCurrent, bodyType)
+
+	return types.TFunc{ArgType: finalParamType, ReturnType: finalBodyType}, sCurrent, nil
+}
+
+// bareLambda は式が (括弧や演算を伴わない) ラムダ式そのものであればそれを返し、そうでなければ nil を返します。
+func bareLambda(term *ast.Term) *ast.Lambda {
+	if term == nil || len(term.Right) > 0 || term.Left == nil || len(term.Left.Right) > 0 {
+		return nil
+	}
+	mul := term.Left.Left
+	if mul == nil || len(mul.Right) > 0 || mul.Left == nil || len(mul.Left.Right) > 0 {
+		return nil
+	}
+	boolTerm := mul.Left.Left
+	if boolTerm == nil || boolTerm.Factor == nil || len(boolTerm.Factor.Args) > 0 || boolTerm.Factor.Function == nil {
+		return nil
+	}
+	return boolTerm.Factor.Function.Lambda
+}
+
+// typeFromAnnotation は型注釈を型に変換します。型名は Int と Bool (小文字の int, bool も可) です。
+func typeFromAnnotation(te *ast.TypeExpr) (types.Type, error) {
+	if te == nil || te.Param == nil {
+		return nil, fmt.Errorf("empty type annotation")
+	}
+	paramType, err := tupleTypeFromAnnotation(te.Param)
+	if err != nil {
+		return nil, err
+	}
+	if te.Return == nil {
+		return paramType, nil
+	}
+	returnType, err := typeFromAnnotation(te.Return)
+	if err != nil {
+		return nil, err
+	}
+	return types.TFunc{ArgType: paramType, ReturnType: returnType}, nil
+}
+
+func tupleTypeFromAnnotation(te *ast.TupleTypeExpr) (types.Type, error) {
+	elements := make([]types.Type, len(te.Elements))
+	for i, elem := range te.Elements {
+		elemType, err := atomTypeFromAnnotation(elem)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = elemType
+	}
+	if len(elements) == 1 {
+		return elements[0], nil
+	}
+	return types.TTuple{Elements: elements}, nil
+}
+
+func atomTypeFromAnnotation(te *ast.AtomTypeExpr) (types.Type, error) {
+	switch {
+	case te.Name != nil:
+		switch *te.Name {
+		case "Int", "int":
+			return types.TInt{}, nil
+		case "Bool", "bool":
+			return types.TBool{}, nil
+		}
+		return nil, fmt.Errorf("unknown type name '%s' (expected Int or Bool)", *te.Name)
+	case te.Paren != nil:
+		return typeFromAnnotation(te.Paren)
+	case te.Record != nil:
+		fields := make(map[string]types.Type, len(te.Record))
+		for _, field := range te.Record {
+			if _, dup := fields[field.Name]; dup {
+				return nil, fmt.Errorf("duplicate field '%s' in record type %s", field.Name, te.String())
+			}
+			fieldType, err := typeFromAnnotation(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			fields[field.Name] = fieldType
+		}
+		return types.TRecord{Fields: fields}, nil
+	}
+	return nil, fmt.Errorf("invalid type annotation: %s", te.String())
+}
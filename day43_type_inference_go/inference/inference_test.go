@@ -2,6 +2,7 @@ package inference
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/participle/v2"
@@ -423,6 +424,171 @@ func TestInferLambdaAndApp(t *testing.T) {
 	}
 }
 
+func TestInferLetRec(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          string
+		expectedStr    string
+		wantErr        bool
+		checkStructure func(t *testing.T, ty types.Type)
+	}{
+		{name: "factorial", input: "let rec fact = fn n => if n == 0 then 1 else n * fact (n - 1) in fact 5", expectedStr: "int"},
+		{name: "fibonacci", input: "let rec fib = fn n => if n < 2 then n else fib (n - 1) + fib (n - 2) in fib 10", expectedStr: "int"},
+		{name: "recursive function itself", input: "let rec fact = fn n => if n == 0 then 1 else n * fact (n - 1) in fact", expectedStr: "int -> int"},
+		{name: "curried recursion", input: "let rec pow = fn b => fn e => if e == 0 then 1 else b * pow b (e - 1) in pow 2 10", expectedStr: "int"},
+		{name: "generalized after definition", input: "let rec f = fn x => x in (f 1, f true)", expectedStr: "int * bool"},
+		{name: "non-terminating loop is polymorphic", input: "let rec loop = fn x => loop x in loop", checkStructure: func(t *testing.T, ty types.Type) {
+			t.Helper()
+			fnTy, ok := ty.(types.TFunc)
+			if !ok {
+				t.Fatalf("expected TFunc, got %s", ty)
+			}
+			argVar, okArg := fnTy.ArgType.(types.TVar)
+			retVar, okRet := fnTy.ReturnType.(types.TVar)
+			if !okArg || !okRet || argVar.Name == retVar.Name {
+				t.Errorf("expected a -> b with distinct type variables, got %s", ty)
+			}
+		}},
+		{name: "without rec the name is unbound", input: "let fact = fn n => if n == 0 then 1 else n * fact (n - 1) in fact 5", wantErr: true},
+		{name: "recursive use conflicts with definition", input: "let rec f = fn n => if f then 1 else 2 in f", wantErr: true},
+		{name: "infinite type", input: "let rec f = fn x => f in f", wantErr: true},
+		{name: "recursive call with wrong argument", input: "let rec f = fn n => if n == 0 then 0 else f true in f 1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			types.ResetTypeVarCounter()
+			expr, err := parseAndGetMainExpression(tt.input)
+			if err != nil {
+				t.Fatalf("Parse error for input '%s': %v", tt.input, err)
+			}
+
+			finalType, finalSub, err := Infer(BaseTypeEnv(), expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Infer() for '%s': error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				t.Logf("Input: '%s', Error: %v", tt.input, err)
+				return
+			}
+			resultType := unification.Apply(finalSub, finalType)
+			if tt.checkStructure != nil {
+				tt.checkStructure(t, resultType)
+			} else if resultType.String() != tt.expectedStr {
+				t.Errorf("Infer() for '%s': gotType = %s, want %s", tt.input, resultType, tt.expectedStr)
+			}
+		})
+	}
+}
+
+func TestInferTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectedStr string
+		wantErr     string // 空でなければエラーメッセージに含まれるべき文字列
+	}{
+		{name: "annotated parameter fixes identity", input: "fn (x : Int) => x", expectedStr: "int -> int"},
+		{name: "annotated parameter of higher-order function", input: "fn (f : Int -> Bool) => fn x => f x", expectedStr: "(int -> bool) -> (int -> bool)"},
+		{name: "annotated let", input: "let y : Bool = true in y", expectedStr: "bool"},
+		{name: "lowercase type names", input: "let y : int = 1 in y", expectedStr: "int"},
+		{name: "annotated let instantiates polymorphic value", input: "let f : Int -> Int = fn x => x in f", expectedStr: "int -> int"},
+		{name: "annotated tuple parameter enables projection", input: "fn (p : Int * Bool) => p.2", expectedStr: "(int * bool) -> bool"},
+		{name: "annotated record parameter enables projection", input: "fn (r : {x : Int, y : Int}) => r.x + r.y", expectedStr: "{x : int, y : int} -> int"},
+		{name: "let annotation checks lambda parameter", input: "let fst : Int * Int -> Int = fn p => p.1 in fst (1, 2)", expectedStr: "int"},
+		{name: "annotated let rec", input: "let rec sum : Int * Int -> Int = fn p => if p.1 == 0 then p.2 else sum (p.1 - 1, p.2 + p.1) in sum (3, 0)", expectedStr: "int"},
+		{name: "let annotation conflicts with value", input: "let y : Bool = 1 in y", wantErr: "'y' is annotated as bool but its value has type int"},
+		{name: "let annotation conflicts with function type", input: "let f : Int -> Bool = fn x => x + 1 in f", wantErr: "'f' is annotated as int -> bool but its value has type int -> int"},
+		{name: "annotated parameter used with another type", input: "fn (x : Bool) => x + 1", wantErr: "parameter 'x' has type bool"},
+		{name: "parameter annotation conflicts with let annotation", input: "let f : Int -> Int = fn (x : Bool) => 1 in f", wantErr: "parameter 'x' is annotated as bool but expected to be int"},
+		{name: "unknown type name", input: "fn (x : String) => x", wantErr: "unknown type name 'String'"},
+		{name: "duplicate field in record type", input: "let r : {x : Int, x : Bool} = {x = 1} in r", wantErr: "duplicate field 'x'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			types.ResetTypeVarCounter()
+			expr, err := parseAndGetMainExpression(tt.input)
+			if err != nil {
+				t.Fatalf("Parse error for input '%s': %v", tt.input, err)
+			}
+
+			finalType, finalSub, err := Infer(BaseTypeEnv(), expr)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Infer() for '%s': expected error containing %q, got type %s", tt.input, tt.wantErr, unification.Apply(finalSub, finalType))
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Infer() for '%s': error = %v, want it to contain %q", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Infer() for '%s': unexpected error: %v", tt.input, err)
+			}
+			resultType := unification.Apply(finalSub, finalType)
+			if resultType.String() != tt.expectedStr {
+				t.Errorf("Infer() for '%s': gotType = %s, want %s", tt.input, resultType, tt.expectedStr)
+			}
+		})
+	}
+}
+
+func TestInferTuplesAndRecords(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectedStr string
+		wantErr     bool
+	}{
+		{name: "pair", input: "(1, true)", expectedStr: "int * bool"},
+		{name: "triple with expressions", input: "(1 + 2, 3 > 2, fn x => x + 1)", expectedStr: "int * bool * (int -> int)"},
+		{name: "nested tuple", input: "(1, (true, 2))", expectedStr: "int * (bool * int)"},
+		{name: "tuple projection", input: "let p = (1, true) in if p.2 then p.1 else 0", expectedStr: "int"},
+		{name: "nested tuple projection", input: "(1, (true, 2)).2.1", expectedStr: "bool"},
+		{name: "tuple of polymorphic uses", input: "let id = fn x => x in (id 1, id true)", expectedStr: "int * bool"},
+		{name: "function returning tuple", input: "let f = fn x => (x, x + 1) in f 3", expectedStr: "int * int"},
+		{name: "record", input: "{y = true, x = 1}", expectedStr: "{x : int, y : bool}"},
+		{name: "record projection", input: "let r = {x = 1, y = true} in if r.y then r.x else 0", expectedStr: "int"},
+		{name: "record with tuple field", input: "{pos = (1, 2), name = true}.pos.2", expectedStr: "int"},
+		{name: "record passed to function", input: "(fn r => r) {a = 1, b = (true, 2)}", expectedStr: "{a : int, b : bool * int}"},
+		{name: "tuple unified through if", input: "fn p => if true then p else (1, true)", expectedStr: "(int * bool) -> (int * bool)"},
+		{name: "tuple element out of range", input: "(1, 2).3", wantErr: true},
+		{name: "tuple element zero", input: "(1, 2).0", wantErr: true},
+		{name: "missing record field", input: "{x = 1}.y", wantErr: true},
+		{name: "field access on tuple", input: "(1, 2).x", wantErr: true},
+		{name: "element access on record", input: "{x = 1}.1", wantErr: true},
+		{name: "projection on int", input: "1.x", wantErr: true},
+		{name: "projection on unknown type", input: "fn r => r.x", wantErr: true},
+		{name: "duplicate record field", input: "{x = 1, x = 2}", wantErr: true},
+		{name: "tuple size mismatch", input: "if true then (1, 2) else (1, 2, 3)", wantErr: true},
+		{name: "record field mismatch", input: "if true then {a = 1} else {b = 1}", wantErr: true},
+		{name: "tuple element type mismatch", input: "(1, true).2 + 1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			types.ResetTypeVarCounter()
+			expr, err := parseAndGetMainExpression(tt.input)
+			if err != nil {
+				t.Fatalf("Parse error for input '%s': %v", tt.input, err)
+			}
+
+			finalType, finalSub, err := Infer(BaseTypeEnv(), expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Infer() for '%s': error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				t.Logf("Input: '%s', Error: %v", tt.input, err)
+				return
+			}
+			if actualStr := unification.Apply(finalSub, finalType).String(); actualStr != tt.expectedStr {
+				t.Errorf("Infer() for '%s': gotType = %s, want %s", tt.input, actualStr, tt.expectedStr)
+			}
+		})
+	}
+}
+
 func checkPolyId(t *testing.T, ty types.Type) {
 	t.Helper()
 	fnTy, ok := ty.(types.TFunc)
@@ -57,10 +57,30 @@ var sampleCodes = []SampleCode{
 	{Name: "適用 (id)", Code: "(fn x => x) 123", Category: "関数"},
 	{Name: "適用 (カリー化)", Code: "let add = fn x => fn y => x + y in add 5 3", Category: "関数"},
 
+	{Name: "階乗 (let rec)", Code: "let rec fact = fn n => if n == 0 then 1 else n * fact (n - 1) in fact 5", Category: "再帰"},
+	{Name: "フィボナッチ (let rec)", Code: "let rec fib = fn n => if n < 2 then n else fib (n - 1) + fib (n - 2) in fib 10", Category: "再帰"},
+	{Name: "累乗 (カリー化)", Code: "let rec pow = fn b => fn e => if e == 0 then 1 else b * pow b (e - 1) in pow", Category: "再帰"},
+
+	{Name: "組 (ペア)", Code: "(1, true)", Category: "組・レコード"},
+	{Name: "組の要素参照", Code: "let p = (10, false) in if p.2 then 0 else p.1", Category: "組・レコード"},
+	{Name: "組を返す関数", Code: "let dup = fn x => (x, x) in dup true", Category: "組・レコード"},
+	{Name: "レコード", Code: "{name = 1, active = true}", Category: "組・レコード"},
+	{Name: "フィールド参照", Code: "let pt = {x = 3, y = 4} in pt.x * pt.x + pt.y * pt.y", Category: "組・レコード"},
+	{Name: "入れ子", Code: "{pos = (1, 2), visible = true}.pos", Category: "組・レコード"},
+
+	{Name: "引数の型注釈", Code: "fn (x : Int) => x", Category: "型注釈"},
+	{Name: "let の型注釈", Code: "let y : Bool = true in y", Category: "型注釈"},
+	{Name: "注釈で組を参照", Code: "fn (p : Int * Bool) => if p.2 then p.1 else 0", Category: "型注釈"},
+	{Name: "注釈付き再帰", Code: "let rec sum : Int * Int -> Int = fn p => if p.1 == 0 then p.2 else sum (p.1 - 1, p.2 + p.1) in sum (10, 0)", Category: "型注釈"},
+
 	{Name: "エラー (算術)", Code: "1 + true", Category: "型エラー例"},
 	{Name: "エラー (if条件)", Code: "if 1 then 10 else 20", Category: "型エラー例"},
 	{Name: "エラー (if分岐)", Code: "if true then 10 else false", Category: "型エラー例"},
 	{Name: "エラー (適用)", Code: "(fn x => x + 1) true", Category: "型エラー例"},
+	{Name: "エラー (rec なし)", Code: "let fact = fn n => if n == 0 then 1 else n * fact (n - 1) in fact 5", Category: "型エラー例"},
+	{Name: "エラー (組の要素)", Code: "(1, 2).3", Category: "型エラー例"},
+	{Name: "エラー (フィールド)", Code: "{x = 1}.y", Category: "型エラー例"},
+	{Name: "エラー (型注釈)", Code: "let y : Bool = 1 in y", Category: "型エラー例"},
 }
 
 func main() {
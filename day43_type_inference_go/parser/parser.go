@@ -8,15 +8,16 @@ import (
 
 var MiniLangLexer = lexer.MustStateful(lexer.Rules{
 	"Root": {
-		// Keywords
-		{Name: "LetKw", Pattern: `let`},
-		{Name: "InKw", Pattern: `in`},
-		{Name: "IfKw", Pattern: `if`},
-		{Name: "ThenKw", Pattern: `then`},
-		{Name: "ElseKw", Pattern: `else`},
-		{Name: "FnKw", Pattern: `fn`},
-		{Name: "True", Pattern: `true`},
-		{Name: "False", Pattern: `false`},
+		// Keywords (\b で終端し、record や index のようにキーワードで始まる識別子を分割しない)
+		{Name: "LetKw", Pattern: `let\b`},
+		{Name: "RecKw", Pattern: `rec\b`},
+		{Name: "InKw", Pattern: `in\b`},
+		{Name: "IfKw", Pattern: `if\b`},
+		{Name: "ThenKw", Pattern: `then\b`},
+		{Name: "ElseKw", Pattern: `else\b`},
+		{Name: "FnKw", Pattern: `fn\b`},
+		{Name: "True", Pattern: `true\b`},
+		{Name: "False", Pattern: `false\b`},
 
 		// Identifiers and Literals
 		{Name: "Ident", Pattern: `[a-zA-Z_][a-zA-Z0-9_]*`},
@@ -24,6 +25,7 @@ var MiniLangLexer = lexer.MustStateful(lexer.Rules{
 
 		// Named tokens / Operators / Symbols - all raw strings, regex escapes as needed
 		{Name: "Arrow", Pattern: `=>`},
+		{Name: "TypeArrow", Pattern: `->`}, // 型注釈の関数型 (Minus より先に置く)
 		{Name: "Eq", Pattern: `==`},
 		{Name: "Assign", Pattern: `=`},
 		{Name: "LogicalAnd", Pattern: `&&`},
@@ -36,6 +38,11 @@ var MiniLangLexer = lexer.MustStateful(lexer.Rules{
 		{Name: "LessThan", Pattern: `<`},
 		{Name: "LParen", Pattern: `\(`}, // Raw string: \( for literal (
 		{Name: "RParen", Pattern: `\)`}, // Raw string: \) for literal )
+		{Name: "LBrace", Pattern: `\{`},
+		{Name: "RBrace", Pattern: `\}`},
+		{Name: "Comma", Pattern: `,`},
+		{Name: "Dot", Pattern: `\.`},
+		{Name: "Colon", Pattern: `:`},
 
 		// Tokens to be discarded
 		{Name: "Comment", Pattern: `#[^\n]*`}, // Raw string: [^\n]* for not newline
@@ -112,6 +112,54 @@ func TestParse_CompoundExpressions(t *testing.T) {
 			expected: "add(3)(4)",
 			hasError: false,
 		},
+		{
+			name:     "Let Rec Expression",
+			code:     "let rec fact = fn n => if n == 0 then 1 else n * fact (n - 1) in fact 5",
+			expected: "let rec fact = fn n => if n == 0 then 1 else n * fact(n - 1) in fact 5",
+			hasError: false,
+		},
+		{
+			name:     "Identifiers Starting With Keywords",
+			code:     "let record = index in iffy fnord lettuce",
+			expected: "let record = index in iffy fnord lettuce",
+			hasError: false,
+		},
+		{
+			name:     "Tuple",
+			code:     "(1, true, x + 1)",
+			expected: "(1, true, x + 1)",
+			hasError: false,
+		},
+		{
+			name:     "Record",
+			code:     "{x = 1, y = fn a => a}",
+			expected: "{x = 1, y = fn a => a}",
+			hasError: false,
+		},
+		{
+			name:     "Projection",
+			code:     "f r.x (1, (2, 3)).2.1",
+			expected: "f r.x(1, (2, 3)).2.1",
+			hasError: false,
+		},
+		{
+			name:     "Annotated Lambda",
+			code:     "fn (x : Int) => x + 1",
+			expected: "fn (x : Int) => x + 1",
+			hasError: false,
+		},
+		{
+			name:     "Annotated Let",
+			code:     "let y : Bool = true in y",
+			expected: "let y : Bool = true in y",
+			hasError: false,
+		},
+		{
+			name:     "Function Tuple And Record Type Annotations",
+			code:     "let rec f : (Int -> Bool) -> Int * {a : Int,b : Bool} -> Int = fn g => fn p => p.1 in f",
+			expected: "let rec f : (Int -> Bool) -> Int * {a : Int, b : Bool} -> Int = fn g => fn p => p.1 in f",
+			hasError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -171,6 +219,16 @@ func TestParse_Errors(t *testing.T) {
 			code:             "if true then 1",
 			wantErrSubstring: "expected <elsekw>",
 		},
+		{
+			name:             "Unclosed_Record",
+			code:             "{x = 1, y = 2",
+			wantErrSubstring: "expected \"}\"",
+		},
+		{
+			name:             "Annotated_Param_Without_Colon",
+			code:             "fn (x Int) => x",
+			wantErrSubstring: "expected <colon>",
+		},
 		{
 			name:             "Invalid_token",
 			code:             "1 $ 2",
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -61,12 +62,14 @@ type TFunc struct {
 
 func (t TFunc) String() string {
 	argStr := t.ArgType.String()
-	if _, ok := t.ArgType.(TFunc); ok { // 引数型が関数型なら括弧で囲む
+	switch t.ArgType.(type) {
+	case TFunc, TTuple: // 引数型が関数型や組なら括弧で囲む
 		argStr = "(" + argStr + ")"
 	}
 
 	retStr := t.ReturnType.String()
-	if _, ok := t.ReturnType.(TFunc); ok { // 戻り値型が関数型なら括弧で囲む (テストケースの期待に合わせる)
+	switch t.ReturnType.(type) {
+	case TFunc, TTuple: // 戻り値型が関数型や組なら括弧で囲む (テストケースの期待に合わせる)
 		retStr = "(" + retStr + ")"
 	}
 	return argStr + " -> " + retStr
@@ -76,6 +79,64 @@ func (t TFunc) FreeTypeVars() TVarSet {
 	return t.ArgType.FreeTypeVars().Union(t.ReturnType.FreeTypeVars())
 }
 
+// TTuple は組 (直積) 型 (例: int * bool) を表します。要素は2つ以上です。
+type TTuple struct {
+	Elements []Type
+}
+
+func (t TTuple) String() string {
+	parts := make([]string, len(t.Elements))
+	for i, elem := range t.Elements {
+		parts[i] = elem.String()
+		switch elem.(type) {
+		case TFunc, TTuple: // 関数型や入れ子の組は括弧で囲む
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " * ")
+}
+func (t TTuple) sealedType() {}
+func (t TTuple) FreeTypeVars() TVarSet {
+	set := NewTVarSet()
+	for _, elem := range t.Elements {
+		set = set.Union(elem.FreeTypeVars())
+	}
+	return set
+}
+
+// TRecord はレコード型 (例: {x : int, y : bool}) を表します。
+// フィールドの順序は型に影響せず、文字列表現ではフィールド名の順に並べます。
+type TRecord struct {
+	Fields map[string]Type
+}
+
+// FieldNames はフィールド名をソートして返します。
+func (t TRecord) FieldNames() []string {
+	names := make([]string, 0, len(t.Fields))
+	for name := range t.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t TRecord) String() string {
+	names := t.FieldNames()
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + " : " + t.Fields[name].String()
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+func (t TRecord) sealedType() {}
+func (t TRecord) FreeTypeVars() TVarSet {
+	set := NewTVarSet()
+	for _, fieldType := range t.Fields {
+		set = set.Union(fieldType.FreeTypeVars())
+	}
+	return set
+}
+
 // TScheme は型スキーム (例: forall a. a -> a) を表します。
 // let多相を実現するために使われます。
 type TScheme struct {
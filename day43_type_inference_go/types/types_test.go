@@ -22,6 +22,12 @@ func TestTypeString(t *testing.T) {
 		{"TFunc (int -> bool) -> int", TFunc{TFunc{TInt{}, TBool{}}, TInt{}}, "(int -> bool) -> int"},
 		{"TFunc t0 -> t1", TFunc{tv0, tv1}, "t0 -> t1"},
 		{"TFunc (t0 -> t1) -> bool", TFunc{TFunc{tv0, tv1}, TBool{}}, "(t0 -> t1) -> bool"},
+		{"TTuple int * bool", TTuple{[]Type{TInt{}, TBool{}}}, "int * bool"},
+		{"TTuple nested", TTuple{[]Type{TInt{}, TTuple{[]Type{TBool{}, tv0}}}}, "int * (bool * t0)"},
+		{"TTuple with func element", TTuple{[]Type{TFunc{TInt{}, TInt{}}, TBool{}}}, "(int -> int) * bool"},
+		{"TFunc (int * bool) -> int", TFunc{TTuple{[]Type{TInt{}, TBool{}}}, TInt{}}, "(int * bool) -> int"},
+		{"TRecord sorted by field name", TRecord{map[string]Type{"y": TBool{}, "x": TInt{}}}, "{x : int, y : bool}"},
+		{"TRecord with tuple field", TRecord{map[string]Type{"p": TTuple{[]Type{TInt{}, tv1}}}}, "{p : int * t1}"},
 	}
 
 	for _, tt := range tests {
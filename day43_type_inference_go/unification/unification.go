@@ -2,6 +2,7 @@ package unification
 
 import (
 	"fmt" // エラーメッセージ用
+	"strings"
 
 	"github.com/lirlia/100day_challenge_backend/day43_type_inference_go/types"
 )
@@ -66,6 +67,18 @@ func applyRecursive(sub Substitution, t types.Type, resolving map[string]struct{
 			ArgType:    applyRecursive(sub, tt.ArgType, resolving),
 			ReturnType: applyRecursive(sub, tt.ReturnType, resolving),
 		}
+	case types.TTuple:
+		elems := make([]types.Type, len(tt.Elements))
+		for i, elem := range tt.Elements {
+			elems[i] = applyRecursive(sub, elem, resolving)
+		}
+		return types.TTuple{Elements: elems}
+	case types.TRecord:
+		fields := make(map[string]types.Type, len(tt.Fields))
+		for name, fieldType := range tt.Fields {
+			fields[name] = applyRecursive(sub, fieldType, resolving)
+		}
+		return types.TRecord{Fields: fields}
 	default:
 		// TScheme はここでは扱わない (型スキームへの代入は別の処理が必要な場合がある)
 		// または、TScheme の自由変数にのみ適用するなどのルールが必要
@@ -89,6 +102,14 @@ func collectFreeTypeVars(t types.Type, vars map[string]struct{}) {
 	case types.TFunc:
 		collectFreeTypeVars(tt.ArgType, vars)
 		collectFreeTypeVars(tt.ReturnType, vars)
+	case types.TTuple:
+		for _, elem := range tt.Elements {
+			collectFreeTypeVars(elem, vars)
+		}
+	case types.TRecord:
+		for _, fieldType := range tt.Fields {
+			collectFreeTypeVars(fieldType, vars)
+		}
 	default:
 		panic(fmt.Sprintf("FreeTypeVars: unhandled type %T", t))
 	}
@@ -131,6 +152,27 @@ func Unify(t1, t2 types.Type) (Substitution, error) {
 			}
 			return sub2.Compose(sub1), nil
 		}
+	case types.TTuple:
+		if t2Tuple, ok := t2.(types.TTuple); ok {
+			if len(t1.Elements) != len(t2Tuple.Elements) {
+				return nil, fmt.Errorf("type mismatch: cannot unify %s with %s (tuples have %d and %d elements)", t1.String(), t2.String(), len(t1.Elements), len(t2Tuple.Elements))
+			}
+			return unifyPairwise(t1.Elements, t2Tuple.Elements, func(i int) string { return fmt.Sprintf("tuple element %d", i+1) })
+		}
+	case types.TRecord:
+		if t2Record, ok := t2.(types.TRecord); ok {
+			names := t1.FieldNames()
+			if strings.Join(names, ",") != strings.Join(t2Record.FieldNames(), ",") {
+				return nil, fmt.Errorf("type mismatch: cannot unify %s with %s (records have different fields)", t1.String(), t2.String())
+			}
+			elems1 := make([]types.Type, len(names))
+			elems2 := make([]types.Type, len(names))
+			for i, name := range names {
+				elems1[i] = t1.Fields[name]
+				elems2[i] = t2Record.Fields[name]
+			}
+			return unifyPairwise(elems1, elems2, func(i int) string { return "field " + names[i] })
+		}
 	}
 
 	// t1 が上記ケースで処理されなかった場合、t2 が型変数かどうかをチェック
@@ -141,6 +183,21 @@ func Unify(t1, t2 types.Type) (Substitution, error) {
 	return nil, fmt.Errorf("type mismatch: cannot unify %s with %s", t1.String(), t2.String())
 }
 
+// unifyPairwise は組の要素やレコードのフィールドのように対応する型同士を順に単一化し、代入を合成します。
+// describe はエラーメッセージに使う i 番目の要素の説明を返します。
+func unifyPairwise(ts1, ts2 []types.Type, describe func(i int) string) (Substitution, error) {
+	sub := EmptySubstitution()
+	for i := range ts1 {
+		elem1, elem2 := Apply(sub, ts1[i]), Apply(sub, ts2[i])
+		s, err := Unify(elem1, elem2)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unify %s (%s vs %s): %v", describe(i), elem1.String(), elem2.String(), err)
+		}
+		sub = s.Compose(sub)
+	}
+	return sub, nil
+}
+
 // unifyVar は型変数 v と型 t を単一化します。
 func unifyVar(v types.TVar, t types.Type) (Substitution, error) {
 	// v と t が同じ型変数なら、何もする必要はない (例: t0 と t0)
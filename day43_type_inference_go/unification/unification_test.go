@@ -114,3 +114,88 @@ func TestCompose(t *testing.T) {
 		})
 	}
 }
+
+func TestUnifyTuplesAndRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		t1       types.Type
+		t2       types.Type
+		expected Substitution
+		wantErr  bool
+	}{
+		{
+			"Tuple elements are unified pairwise",
+			types.TTuple{Elements: []types.Type{t0, tbool}},
+			types.TTuple{Elements: []types.Type{tint, t1}},
+			Substitution{"t0": tint, "t1": tbool},
+			false,
+		},
+		{
+			"Tuple element substitution is applied to later elements",
+			types.TTuple{Elements: []types.Type{t0, t0}},
+			types.TTuple{Elements: []types.Type{tint, t1}},
+			Substitution{"t0": tint, "t1": tint},
+			false,
+		},
+		{
+			"Tuples of different sizes",
+			types.TTuple{Elements: []types.Type{tint, tint}},
+			types.TTuple{Elements: []types.Type{tint, tint, tint}},
+			nil,
+			true,
+		},
+		{
+			"Tuple element mismatch",
+			types.TTuple{Elements: []types.Type{tint, tint}},
+			types.TTuple{Elements: []types.Type{tint, tbool}},
+			nil,
+			true,
+		},
+		{
+			"Record fields are unified by name",
+			types.TRecord{Fields: map[string]types.Type{"x": t0, "y": tbool}},
+			types.TRecord{Fields: map[string]types.Type{"y": t1, "x": tint}},
+			Substitution{"t0": tint, "t1": tbool},
+			false,
+		},
+		{
+			"Records with different fields",
+			types.TRecord{Fields: map[string]types.Type{"x": tint}},
+			types.TRecord{Fields: map[string]types.Type{"y": tint}},
+			nil,
+			true,
+		},
+		{
+			"Tuple vs record",
+			types.TTuple{Elements: []types.Type{tint, tint}},
+			types.TRecord{Fields: map[string]types.Type{"x": tint}},
+			nil,
+			true,
+		},
+		{
+			"Type variable vs tuple containing it (occurs check)",
+			t0,
+			types.TTuple{Elements: []types.Type{t0, tint}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unify(tt.t1, tt.t2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unify(%s, %s) error = %v, wantErr %v", tt.t1, tt.t2, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Unify(%s, %s) = %v, want %v", tt.t1, tt.t2, got, tt.expected)
+			}
+			if Apply(got, tt.t1).String() != Apply(got, tt.t2).String() {
+				t.Errorf("Unify(%s, %s): unifier does not make the types equal: %s vs %s", tt.t1, tt.t2, Apply(got, tt.t1), Apply(got, tt.t2))
+			}
+		})
+	}
+}
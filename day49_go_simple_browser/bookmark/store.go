@@ -0,0 +1,102 @@
+package bookmark
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // SQLiteドライバー
+)
+
+// schema はブックマークのテーブル定義です。同じURLは1件だけ保存します。
+const schema = `
+CREATE TABLE IF NOT EXISTS bookmarks (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    title      TEXT NOT NULL,
+    url        TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL
+);`
+
+// Bookmark は保存されたページです。
+type Bookmark struct {
+	ID        int64
+	Title     string
+	URL       string
+	CreatedAt time.Time
+}
+
+// Store は SQLite ファイルに保存されたブックマークを操作します。
+type Store struct {
+	db *sql.DB
+}
+
+// Open は path の SQLite データベースを開き、テーブルがなければ作成します。
+func Open(path string) (*Store, error) {
+	// データベースファイルが置かれるディレクトリが存在しない場合は作成
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bookmark directory for %s: %w", path, err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bookmark database %s: %w", path, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to bookmark database %s: %w", path, err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply bookmark schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close はデータベースを閉じます。
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add はページをブックマークに追加します。登録済みのURLの場合はタイトルだけ更新します。
+func (s *Store) Add(title, url string) error {
+	_, err := s.db.Exec(`
+        INSERT INTO bookmarks (title, url, created_at) VALUES (?, ?, ?)
+        ON CONFLICT(url) DO UPDATE SET title = excluded.title`,
+		title, url, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add bookmark %s: %w", url, err)
+	}
+	return nil
+}
+
+// List はブックマークを登録順に返します。
+func (s *Store) List() ([]Bookmark, error) {
+	rows, err := s.db.Query(`SELECT id, title, url, created_at FROM bookmarks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	var bookmarks []Bookmark
+	for rows.Next() {
+		var b Bookmark
+		if err := rows.Scan(&b.ID, &b.Title, &b.URL, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan bookmark: %w", err)
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list bookmarks: %w", err)
+	}
+	return bookmarks, nil
+}
+
+// Delete は指定されたIDのブックマークを削除します。
+func (s *Store) Delete(id int64) error {
+	if _, err := s.db.Exec(`DELETE FROM bookmarks WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete bookmark %d: %w", id, err)
+	}
+	return nil
+}
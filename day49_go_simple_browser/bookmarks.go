@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/bookmark"
+)
+
+// defaultBookmarkPath はブックマークを保存する SQLite ファイルの既定のパスです (ユーザー設定ディレクトリ配下)。
+func defaultBookmarkPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bookmarks.db"
+	}
+	return filepath.Join(dir, "day49_go_simple_browser", "bookmarks.db")
+}
+
+// updateBookmarkMenu はメインメニューの「ブックマーク」を作り直し、保存済みのページを一覧表示します。
+func (b *Browser) updateBookmarkMenu() {
+	addItem := fyne.NewMenuItem("⭐ このページを追加", b.addBookmark)
+	manageItem := fyne.NewMenuItem("ブックマークを管理...", b.showBookmarkManager)
+	items := []*fyne.MenuItem{addItem, manageItem, fyne.NewMenuItemSeparator()}
+
+	if b.bookmarks == nil {
+		addItem.Disabled = true
+		manageItem.Disabled = true
+		items = append(items, &fyne.MenuItem{Label: "ブックマークを利用できません", Disabled: true})
+	} else {
+		bookmarks, err := b.bookmarks.List()
+		if err != nil {
+			log.Printf("ブックマーク読み込みエラー: %v", err)
+		}
+		for _, bm := range bookmarks {
+			items = append(items, fyne.NewMenuItem(bm.Title, func() { b.Navigate(bm.URL) }))
+		}
+		if len(bookmarks) == 0 {
+			items = append(items, &fyne.MenuItem{Label: "(ブックマークはありません)", Disabled: true})
+		}
+	}
+
+	b.window.SetMainMenu(fyne.NewMainMenu(fyne.NewMenu("ブックマーク", items...)))
+}
+
+// addBookmark は表示中のページをブックマークに追加します。
+func (b *Browser) addBookmark() {
+	if b.bookmarks == nil {
+		return
+	}
+	if b.historyIndex < 0 {
+		b.setStatus("❌ ブックマークするページがありません")
+		return
+	}
+	entry := b.history[b.historyIndex]
+	if entry.form != nil {
+		// POST の結果は URL だけでは再現できないため登録しない
+		b.setStatus("❌ フォームを送信したページはブックマークできません")
+		return
+	}
+
+	title := b.pageTitle
+	if title == "" {
+		title = entry.url
+	}
+	if err := b.bookmarks.Add(title, entry.url); err != nil {
+		b.setStatus(fmt.Sprintf("❌ エラー: %v", err))
+		return
+	}
+	b.updateBookmarkMenu()
+	b.setStatus("⭐ ブックマークに追加しました: " + title)
+}
+
+// showBookmarkManager はブックマークの一覧を表示し、選択したページへの移動と削除を行うダイアログを開きます。
+func (b *Browser) showBookmarkManager() {
+	if b.bookmarks == nil {
+		return
+	}
+
+	var bookmarks []bookmark.Bookmark
+	reloadBookmarks := func() {
+		var err error
+		if bookmarks, err = b.bookmarks.List(); err != nil {
+			log.Printf("ブックマーク読み込みエラー: %v", err)
+		}
+	}
+	reloadBookmarks()
+
+	var d dialog.Dialog
+	var list *widget.List
+	list = widget.NewList(
+		func() int { return len(bookmarks) },
+		func() fyne.CanvasObject {
+			deleteButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			return container.NewBorder(nil, nil, nil, deleteButton, widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			bm := bookmarks[id]
+			row := obj.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s (%s)", bm.Title, bm.URL))
+			row.Objects[1].(*widget.Button).OnTapped = func() {
+				if err := b.bookmarks.Delete(bm.ID); err != nil {
+					b.setStatus(fmt.Sprintf("❌ エラー: %v", err))
+					return
+				}
+				reloadBookmarks()
+				list.UnselectAll()
+				list.Refresh()
+				b.updateBookmarkMenu()
+			}
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		d.Hide()
+		b.Navigate(bookmarks[id].URL)
+	}
+
+	d = dialog.NewCustom("ブックマーク", "閉じる", list, b.window)
+	d.Resize(fyne.NewSize(700, 400))
+	d.Show()
+}
@@ -2,7 +2,10 @@ module github.com/lirlia/100day_challenge_backend/day49_go_simple_browser
 
 go 1.24.2
 
-require fyne.io/fyne/v2 v2.6.1
+require (
+	fyne.io/fyne/v2 v2.6.1
+	github.com/mattn/go-sqlite3 v1.14.28
+)
 
 require (
 	fyne.io/systray v1.11.0 // indirect
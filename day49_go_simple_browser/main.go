@@ -1,15 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	neturl "net/url"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"golang.org/x/net/html"
 
+	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/bookmark"
 	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/network"
 	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/parser"
 	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/renderer"
@@ -21,9 +26,28 @@ type Browser struct {
 	statusLabel      *widget.Label
 	contentContainer *fyne.Container
 	scrollContainer  *container.Scroll
+	backButton       *widget.Button
+	forwardButton    *widget.Button
+	reloadButton     *widget.Button
+
+	// 閲覧履歴。historyIndex は現在表示しているページの位置 (未訪問の場合は -1)
+	history      []historyEntry
+	historyIndex int
+
+	pageTitle string          // 表示中のページの <title>
+	bookmarks *bookmark.Store // ブックマーク (データベースを開けなかった場合は nil)
+}
+
+// historyEntry は履歴の1ページです。form が nil でない場合は url に POST で送信したページです。
+type historyEntry struct {
+	url  string
+	form neturl.Values
 }
 
 func main() {
+	bookmarkPath := flag.String("bookmarks", defaultBookmarkPath(), "ブックマークを保存する SQLite ファイルのパス")
+	flag.Parse()
+
 	// Fyneアプリケーション作成
 	myApp := app.New()
 	myWindow := myApp.NewWindow("Day49 - Go Mini Browser")
@@ -31,7 +55,17 @@ func main() {
 
 	// ブラウザ構造体初期化
 	browser := &Browser{
-		window: myWindow,
+		window:       myWindow,
+		historyIndex: -1,
+	}
+
+	// ブックマークを読み込む (開けない場合もブラウザとしては利用できる)
+	store, err := bookmark.Open(*bookmarkPath)
+	if err != nil {
+		log.Printf("ブックマークを利用できません: %v", err)
+	} else {
+		defer store.Close()
+		browser.bookmarks = store
 	}
 
 	// UI作成
@@ -50,11 +84,18 @@ func (b *Browser) createUI() {
 	// 読み込みボタン
 	loadButton := widget.NewButton("📖 読み込み", b.loadURL)
 
+	// 戻る・進む・再読み込みボタン
+	b.backButton = widget.NewButtonWithIcon("", theme.NavigateBackIcon(), b.goBack)
+	b.forwardButton = widget.NewButtonWithIcon("", theme.NavigateNextIcon(), b.goForward)
+	b.reloadButton = widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), b.reload)
+	b.updateNavigationButtons()
+
 	// ステータスラベル
 	b.statusLabel = widget.NewLabel("準備完了")
 
 	// トップバー作成
-	topBar := container.NewBorder(nil, nil, nil, loadButton, b.urlEntry)
+	navButtons := container.NewHBox(b.backButton, b.forwardButton, b.reloadButton)
+	topBar := container.NewBorder(nil, nil, navButtons, loadButton, b.urlEntry)
 
 	// コンテンツエリア
 	b.contentContainer = container.NewVBox()
@@ -77,6 +118,7 @@ func (b *Browser) createUI() {
 	)
 
 	b.window.SetContent(mainLayout)
+	b.updateBookmarkMenu()
 
 	// Enterキーでも読み込みを実行
 	b.urlEntry.OnSubmitted = func(string) {
@@ -84,18 +126,121 @@ func (b *Browser) createUI() {
 	}
 }
 
+// loadURL はURL入力欄のページへ遷移します。
 func (b *Browser) loadURL() {
-	url := b.urlEntry.Text
+	url := strings.TrimSpace(b.urlEntry.Text)
 	if url == "" {
 		b.setStatus("❌ URLが入力されていません")
 		return
 	}
+	b.Navigate(url)
+}
+
+// Navigate は url を履歴に追加して読み込みます。リンクのタップ時にも renderer から呼ばれます。
+func (b *Browser) Navigate(url string) {
+	b.visit(historyEntry{url: url})
+}
+
+// SubmitForm はフォームを送信し、レスポンスを新しいページとして表示します。renderer から呼ばれます。
+// GET の場合は値をクエリ文字列にしたURLへ遷移し、POST の場合は値をボディとして送信します。
+func (b *Browser) SubmitForm(method, action string, form neturl.Values) {
+	if method == "POST" {
+		b.visit(historyEntry{url: action, form: form})
+		return
+	}
+
+	target, err := network.FormGetURL(action, form)
+	if err != nil {
+		b.setStatus(fmt.Sprintf("❌ エラー: %v", err))
+		return
+	}
+	b.Navigate(target)
+}
+
+// visit は entry を履歴に追加して読み込みます。
+func (b *Browser) visit(entry historyEntry) {
+	lowerURL := strings.ToLower(entry.url)
+	if !strings.HasPrefix(lowerURL, "http://") && !strings.HasPrefix(lowerURL, "https://") {
+		b.setStatus(fmt.Sprintf("❌ 未対応のURLです: %s", entry.url))
+		return
+	}
+
+	// 戻った後に別のページへ移動した場合は、進む側の履歴を破棄する
+	b.history = append(b.history[:b.historyIndex+1], entry)
+	b.historyIndex = len(b.history) - 1
+	b.loadPage(entry)
+}
+
+// goBack は履歴を1つ戻ります。
+func (b *Browser) goBack() {
+	if b.historyIndex <= 0 {
+		return
+	}
+	b.historyIndex--
+	b.loadPage(b.history[b.historyIndex])
+}
+
+// goForward は履歴を1つ進みます。
+func (b *Browser) goForward() {
+	if b.historyIndex >= len(b.history)-1 {
+		return
+	}
+	b.historyIndex++
+	b.loadPage(b.history[b.historyIndex])
+}
+
+// reload は現在のページを再読み込みします。
+func (b *Browser) reload() {
+	if b.historyIndex < 0 {
+		return
+	}
+	b.loadPage(b.history[b.historyIndex])
+}
+
+// updateNavigationButtons は履歴の位置に合わせて戻る・進む・再読み込みボタンの有効/無効を切り替えます。
+func (b *Browser) updateNavigationButtons() {
+	setEnabled := func(button *widget.Button, enabled bool) {
+		if enabled {
+			button.Enable()
+		} else {
+			button.Disable()
+		}
+	}
+	setEnabled(b.backButton, b.historyIndex > 0)
+	setEnabled(b.forwardButton, b.historyIndex < len(b.history)-1)
+	setEnabled(b.reloadButton, b.historyIndex >= 0)
+}
+
+// loadPage は履歴を変更せずに entry のページを読み込んで表示します。
+// POST の再読み込みや戻る・進むでは、フォームの値を再送信します。
+func (b *Browser) loadPage(entry historyEntry) {
+	url := entry.url
+	b.urlEntry.SetText(url)
+	b.updateNavigationButtons()
 
 	b.setStatus("🔄 読み込み中...")
 	b.clearContent()
+	b.scrollContainer.ScrollToTop()
+	b.pageTitle = ""
 
 	// HTTPリクエスト実行
-	htmlContent, err := network.FetchURL(url)
+	var htmlContent string
+	var err error
+	if entry.form != nil {
+		var page *network.Page
+		page, err = network.PostForm(url, entry.form)
+		if err == nil {
+			htmlContent = page.Body
+			if page.URL != url {
+				// POST 後にリダイレクトされた場合は、リダイレクト先を GET で取得したページとして履歴を置き換える
+				url = page.URL
+				b.history[b.historyIndex] = historyEntry{url: url}
+				b.urlEntry.SetText(url)
+			}
+		}
+	} else {
+		htmlContent, err = network.FetchURL(url)
+	}
 	if err != nil {
 		b.setStatus(fmt.Sprintf("❌ エラー: %v", err))
 		b.showError(fmt.Sprintf("URL読み込みエラー:\n%v", err))
@@ -110,11 +255,13 @@ func (b *Browser) loadURL() {
 		return
 	}
 
-	// タイトル取得と表示
-	title := parser.GetTextContent(parser.FindElement(doc, "title"))
-	if title != "" {
-		b.window.SetTitle(fmt.Sprintf("Go Mini Browser - %s", title))
-		// b.addContent(widget.NewCard("🌐 ページタイトル", "", widget.NewLabel(title)))
+	// タイトル取得と表示 (フォームの送信結果など <title> がないページもある)
+	if titleNode := parser.FindElement(doc, "title"); titleNode != nil {
+		if title := strings.TrimSpace(parser.GetTextContent(titleNode)); title != "" {
+			b.pageTitle = title
+			b.window.SetTitle(fmt.Sprintf("Go Mini Browser - %s", title))
+			// b.addContent(widget.NewCard("🌐 ページタイトル", "", widget.NewLabel(title)))
+		}
 	}
 
 	// フレームセット構造をチェック
@@ -221,7 +368,7 @@ func (b *Browser) getFrameContent(baseURL string, frame *html.Node) []fyne.Canva
 
 	var widgets []fyne.CanvasObject
 	// HTMLをウィジェットに変換 (baseURLとしてframeURLを渡す)
-	frameWidgets := renderer.RenderHTML(frameDoc, frameURL)
+	frameWidgets := renderer.RenderHTML(frameDoc, frameURL, b)
 	widgets = append(widgets, frameWidgets...)
 
 	return widgets
@@ -272,7 +419,7 @@ func (b *Browser) loadFrameContent(frameURL string) {
 
 func (b *Browser) renderMainContent(doc *html.Node, baseURL string) {
 	// HTMLをFyneウィジェットに変換
-	widgets := renderer.RenderHTML(doc, baseURL)
+	widgets := renderer.RenderHTML(doc, baseURL, b)
 
 	if len(widgets) == 0 {
 		b.addContent(widget.NewLabel("⚠️ 表示可能なコンテンツが見つかりませんでした"))
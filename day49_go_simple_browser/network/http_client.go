@@ -11,26 +11,65 @@ import (
 	"golang.org/x/text/encoding/japanese"
 )
 
+// Page は取得したページです。URL はリダイレクト後の最終的なURLで、相対URLの解決に使います。
+type Page struct {
+	URL  string
+	Body string
+}
+
 // FetchURL は指定されたURLからコンテンツを取得し、UTF-8文字列として返します。
 // 文字コードがShift_JISの場合はUTF-8に変換します。
 func FetchURL(rawURL string) (string, error) {
-	client := &http.Client{}
-
 	req, err := http.NewRequest("GET", rawURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
+
+	page, err := fetchPage(req)
+	if err != nil {
+		return "", err
+	}
+	return page.Body, nil
+}
+
+// PostForm はフォームの値を application/x-www-form-urlencoded で rawURL に POST し、レスポンスのページを返します。
+// リダイレクト (303 など) された場合、Page.URL はリダイレクト先のURLになります。
+func PostForm(rawURL string, form url.Values) (*Page, error) {
+	req, err := http.NewRequest("POST", rawURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return fetchPage(req)
+}
+
+// FormGetURL は method="get" のフォーム送信先URLを返します。action のクエリ文字列はフォームの値で置き換えます。
+func FormGetURL(action string, form url.Values) (string, error) {
+	u, err := url.Parse(action)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse form action %s: %w", action, err)
+	}
+	u.RawQuery = form.Encode()
+	return u.String(), nil
+}
+
+// fetchPage はリクエストを実行し、レスポンスボディをUTF-8に変換したページを返します。
+func fetchPage(req *http.Request) (*Page, error) {
+	client := &http.Client{}
+	rawURL := req.URL.String()
+
 	// 標準的なブラウザのUser-Agentを設定（サイトによってはUAで挙動が変わるため）
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL %s: %w", rawURL, err)
+		return nil, fmt.Errorf("failed to fetch URL %s: %w", rawURL, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch URL %s: status code %d", rawURL, resp.StatusCode)
+		return nil, fmt.Errorf("failed to fetch URL %s: status code %d", rawURL, resp.StatusCode)
 	}
 
 	// Content-Typeからcharsetを取得
@@ -47,7 +86,7 @@ func FetchURL(rawURL string) (string, error) {
 	// まずレスポンスボディを全てバイトスライスとして読み込み
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body from %s: %w", rawURL, err)
+		return nil, fmt.Errorf("failed to read response body from %s: %w", rawURL, err)
 	}
 
 	// HTTPヘッダーでcharsetが取得できない場合、HTMLの <meta> タグから検出を試みる
@@ -60,7 +99,7 @@ func FetchURL(rawURL string) (string, error) {
 		decoder := japanese.ShiftJIS.NewDecoder()
 		utf8Bytes, err := decoder.Bytes(bodyBytes)
 		if err != nil {
-			return "", fmt.Errorf("failed to convert Shift_JIS to UTF-8 for %s: %w", rawURL, err)
+			return nil, fmt.Errorf("failed to convert Shift_JIS to UTF-8 for %s: %w", rawURL, err)
 		}
 		bodyBytes = utf8Bytes
 	} else if charset != "" && charset != "utf-8" && charset != "utf8" {
@@ -68,7 +107,7 @@ func FetchURL(rawURL string) (string, error) {
 		fmt.Printf("Warning: Unsupported charset '%s' for URL %s. Attempting to read as is.\n", charset, rawURL)
 	}
 
-	return string(bodyBytes), nil
+	return &Page{URL: resp.Request.URL.String(), Body: string(bodyBytes)}, nil
 }
 
 // FetchImage は指定されたURLから画像データを取得します。
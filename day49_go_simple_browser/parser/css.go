@@ -0,0 +1,503 @@
+package parser
+
+import (
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultFontSize は font-size が指定されていない場合の文字サイズ (px) です。Fyne のデフォルトテーマに合わせています。
+const DefaultFontSize float32 = 14
+
+// Style は要素に適用される CSS のサブセット (color, background, font-size, bold/italic, text-align) です。
+type Style struct {
+	Color      color.Color // nil の場合はテーマの文字色
+	Background color.Color // nil の場合は背景なし (継承されない)
+	FontSize   float32     // px。0 の場合はテーマの文字サイズ
+	Bold       bool
+	Italic     bool
+	TextAlign  string // "", "left", "center", "right"
+}
+
+// StyleSheet は <style> ブロックから読み込んだルールの集合です。
+// セレクタは tag, .class, #id, * とその組み合わせ (例: p.note) のみ対応し、子孫・子セレクタを含むルールは無視します。
+type StyleSheet struct {
+	rules []cssRule
+}
+
+type cssRule struct {
+	selector     compoundSelector
+	declarations map[string]string
+	specificity  int
+	order        int // 同じ詳細度のルールは後に書かれたものが優先
+}
+
+type compoundSelector struct {
+	tag     string // "" は任意の要素
+	id      string
+	classes []string
+}
+
+// CollectStyleSheet はドキュメント内のすべての <style> 要素を読み込みます。
+func CollectStyleSheet(doc *html.Node) *StyleSheet {
+	var css strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "style" {
+			css.WriteString(GetTextContent(node))
+			css.WriteString("\n")
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	if doc != nil {
+		walk(doc)
+	}
+	return ParseStyleSheet(css.String())
+}
+
+// ParseStyleSheet は CSS 文字列をパースします。対応していないセレクタや @ ルールは読み飛ばします。
+func ParseStyleSheet(css string) *StyleSheet {
+	sheet := &StyleSheet{}
+	css = stripCSSComments(css)
+	for {
+		open := strings.Index(css, "{")
+		if open == -1 {
+			break
+		}
+		closeIdx := strings.Index(css[open:], "}")
+		if closeIdx == -1 {
+			break
+		}
+		closeIdx += open
+		selectors := strings.TrimSpace(css[:open])
+		body := css[open+1 : closeIdx]
+		css = css[closeIdx+1:]
+
+		if strings.HasPrefix(selectors, "@") {
+			// @media などのブロックはネストするため、対応する閉じ括弧まで読み飛ばす
+			if strings.Contains(body, "{") {
+				css = skipNestedBlock(css)
+			}
+			continue
+		}
+
+		declarations := ParseInlineStyle(body)
+		for _, s := range strings.Split(selectors, ",") {
+			selector, specificity, ok := parseSelector(strings.TrimSpace(s))
+			if !ok {
+				continue
+			}
+			sheet.rules = append(sheet.rules, cssRule{
+				selector:     selector,
+				declarations: declarations,
+				specificity:  specificity,
+				order:        len(sheet.rules),
+			})
+		}
+	}
+	return sheet
+}
+
+// ParseInlineStyle は style 属性 (例: "color: red; font-size: 16px") をプロパティ名と値のマップに変換します。
+func ParseInlineStyle(style string) map[string]string {
+	declarations := make(map[string]string)
+	for _, decl := range strings.Split(style, ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		if name != "" && value != "" {
+			declarations[name] = value
+		}
+	}
+	return declarations
+}
+
+// ComputeStyle は要素の最終的なスタイルを計算します。
+// 親要素から継承したスタイルに、要素のデフォルトスタイル、<font> などの属性、<style> のルール、style 属性の順で上書きします。
+func (s *StyleSheet) ComputeStyle(node *html.Node) Style {
+	if node == nil {
+		return Style{}
+	}
+	if node.Type != html.ElementNode {
+		return s.ComputeStyle(node.Parent)
+	}
+
+	parent := s.ComputeStyle(node.Parent)
+	style := Style{
+		Color:     parent.Color,
+		FontSize:  parent.FontSize,
+		Bold:      parent.Bold,
+		Italic:    parent.Italic,
+		TextAlign: parent.TextAlign,
+	}
+	parentSize := parent.FontSize
+	if parentSize == 0 {
+		parentSize = DefaultFontSize
+	}
+
+	applyDefaultStyle(&style, node, parentSize)
+
+	if s != nil {
+		var matched []cssRule
+		for _, rule := range s.rules {
+			if rule.selector.matches(node) {
+				matched = append(matched, rule)
+			}
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			if matched[i].specificity != matched[j].specificity {
+				return matched[i].specificity < matched[j].specificity
+			}
+			return matched[i].order < matched[j].order
+		})
+		for _, rule := range matched {
+			applyDeclarations(&style, rule.declarations, parentSize)
+		}
+	}
+
+	if inline := GetAttribute(node, "style"); inline != "" {
+		applyDeclarations(&style, ParseInlineStyle(inline), parentSize)
+	}
+	return style
+}
+
+// headingSizes は見出しのデフォルトの文字サイズ (px) です。
+var headingSizes = map[string]float32{"h1": 24, "h2": 20, "h3": 18, "h4": 16, "h5": 14, "h6": 12}
+
+// fontTagSizes は <font size="1"〜"7"> に対応する文字サイズ (px) です。
+var fontTagSizes = [...]float32{10, 10, 12, 14, 16, 18, 24, 24}
+
+// applyDefaultStyle はブラウザ標準のスタイルと、<font color/size> や align 属性を適用します。
+func applyDefaultStyle(style *Style, node *html.Node, parentSize float32) {
+	switch node.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		style.Bold = true
+		style.FontSize = headingSizes[node.Data]
+	case "b", "strong", "th":
+		style.Bold = true
+	case "i", "em", "cite", "var":
+		style.Italic = true
+	case "small":
+		style.FontSize = parentSize * 0.85
+	case "big":
+		style.FontSize = parentSize * 1.2
+	case "center":
+		style.TextAlign = "center"
+	case "font":
+		if c := ParseColor(GetAttribute(node, "color")); c != nil {
+			style.Color = c
+		}
+		if size, err := strconv.Atoi(strings.TrimSpace(GetAttribute(node, "size"))); err == nil {
+			size = min(max(size, 0), len(fontTagSizes)-1)
+			style.FontSize = fontTagSizes[size]
+		}
+	}
+	if align := strings.ToLower(GetAttribute(node, "align")); align == "left" || align == "center" || align == "right" {
+		style.TextAlign = align
+	}
+	if c := ParseColor(GetAttribute(node, "bgcolor")); c != nil {
+		style.Background = c
+	}
+}
+
+// applyDeclarations は対応しているプロパティを style に反映します。未対応のプロパティや不正な値は無視します。
+func applyDeclarations(style *Style, declarations map[string]string, parentSize float32) {
+	for name, value := range declarations {
+		value = strings.ToLower(value)
+		switch name {
+		case "color":
+			if c := ParseColor(value); c != nil {
+				style.Color = c
+			}
+		case "background-color":
+			if c := ParseColor(value); c != nil {
+				style.Background = c
+			}
+		case "background":
+			// ショートハンドの中から色として解釈できる値を探す
+			for _, part := range splitCSSValue(value) {
+				if c := ParseColor(part); c != nil {
+					style.Background = c
+					break
+				}
+			}
+		case "font-size":
+			if size := parseFontSize(value, parentSize); size > 0 {
+				style.FontSize = size
+			}
+		case "font-weight":
+			switch value {
+			case "bold", "bolder":
+				style.Bold = true
+			case "normal", "lighter":
+				style.Bold = false
+			default:
+				if weight, err := strconv.Atoi(value); err == nil {
+					style.Bold = weight >= 600
+				}
+			}
+		case "font-style":
+			switch value {
+			case "italic", "oblique":
+				style.Italic = true
+			case "normal":
+				style.Italic = false
+			}
+		case "text-align":
+			switch value {
+			case "left", "start":
+				style.TextAlign = "left"
+			case "center":
+				style.TextAlign = "center"
+			case "right", "end":
+				style.TextAlign = "right"
+			}
+		}
+	}
+}
+
+// fontSizeKeywords は font-size のキーワードに対応する文字サイズ (px) です。
+var fontSizeKeywords = map[string]float32{
+	"xx-small": 9, "x-small": 10, "small": 13, "medium": 16, "large": 18, "x-large": 24, "xx-large": 32,
+}
+
+// parseFontSize は font-size の値 (px, pt, em, rem, %, キーワード) を px に変換します。解釈できない場合は 0 を返します。
+func parseFontSize(value string, parentSize float32) float32 {
+	if size, ok := fontSizeKeywords[value]; ok {
+		return size
+	}
+	switch value {
+	case "smaller":
+		return parentSize * 0.85
+	case "larger":
+		return parentSize * 1.2
+	}
+
+	units := []struct {
+		suffix string
+		scale  float32
+	}{
+		{"px", 1},
+		{"pt", 4.0 / 3.0},
+		{"rem", DefaultFontSize},
+		{"em", parentSize},
+		{"%", parentSize / 100},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, u.suffix)), 32)
+			if err != nil || n <= 0 {
+				return 0
+			}
+			return float32(n) * u.scale
+		}
+	}
+	return 0
+}
+
+// parseSelector は単純なセレクタ (tag, .class, #id, * とその組み合わせ) をパースし、詳細度とともに返します。
+func parseSelector(s string) (compoundSelector, int, bool) {
+	var sel compoundSelector
+	if s == "" || strings.ContainsAny(s, " >+~[:") {
+		return sel, 0, false
+	}
+
+	specificity := 0
+	rest := strings.ToLower(s)
+	// 先頭のタグ名
+	end := strings.IndexAny(rest, ".#")
+	if end == -1 {
+		end = len(rest)
+	}
+	if tag := rest[:end]; tag != "" && tag != "*" {
+		sel.tag = tag
+		specificity++
+	}
+	rest = rest[end:]
+
+	for rest != "" {
+		kind := rest[0]
+		rest = rest[1:]
+		end := strings.IndexAny(rest, ".#")
+		if end == -1 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		rest = rest[end:]
+		if name == "" {
+			return sel, 0, false
+		}
+		if kind == '#' {
+			sel.id = name
+			specificity += 100
+		} else {
+			sel.classes = append(sel.classes, name)
+			specificity += 10
+		}
+	}
+	return sel, specificity, true
+}
+
+func (sel compoundSelector) matches(node *html.Node) bool {
+	if sel.tag != "" && sel.tag != node.Data {
+		return false
+	}
+	if sel.id != "" && strings.ToLower(GetAttribute(node, "id")) != sel.id {
+		return false
+	}
+	if len(sel.classes) > 0 {
+		classes := strings.Fields(strings.ToLower(GetAttribute(node, "class")))
+		for _, want := range sel.classes {
+			found := false
+			for _, class := range classes {
+				if class == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stripCSSComments は /* ... */ コメントを取り除きます。
+func stripCSSComments(css string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(css, "/*")
+		if start == -1 {
+			b.WriteString(css)
+			return b.String()
+		}
+		b.WriteString(css[:start])
+		end := strings.Index(css[start+2:], "*/")
+		if end == -1 {
+			return b.String()
+		}
+		css = css[start+2+end+2:]
+	}
+}
+
+// skipNestedBlock は @media などのネストしたブロックの残りを読み飛ばします。
+// 呼び出し時点で最初の内側ブロックは読み終わっているため、深さ 1 から数え始めます。
+func skipNestedBlock(css string) string {
+	depth := 1
+	for i, r := range css {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return css[i+1:]
+			}
+		}
+	}
+	return ""
+}
+
+// splitCSSValue は rgb(...) の中のスペースを区切りとみなさずに値をスペースで分割します。
+func splitCSSValue(value string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range value {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ' ':
+			if depth == 0 {
+				if part := value[start:i]; part != "" {
+					parts = append(parts, part)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if part := value[start:]; part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// ParseColor は色文字列 (名前付きカラー、#RGB、#RRGGBB、rgb()) を color.Color に変換します。解釈できない場合は nil を返します。
+func ParseColor(colorStr string) color.Color {
+	colorStr = strings.ToLower(strings.TrimSpace(colorStr))
+
+	// 名前付きカラー
+	switch colorStr {
+	case "red":
+		return color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	case "green":
+		return color.RGBA{R: 0, G: 128, B: 0, A: 255}
+	case "blue":
+		return color.RGBA{R: 0, G: 0, B: 255, A: 255}
+	case "black":
+		return color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	case "white":
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	case "yellow":
+		return color.RGBA{R: 255, G: 255, B: 0, A: 255}
+	case "purple", "magenta":
+		return color.RGBA{R: 128, G: 0, B: 128, A: 255}
+	case "cyan":
+		return color.RGBA{R: 0, G: 255, B: 255, A: 255}
+	case "orange":
+		return color.RGBA{R: 255, G: 165, B: 0, A: 255}
+	case "gray", "grey":
+		return color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	case "navy":
+		return color.RGBA{R: 0, G: 0, B: 128, A: 255}
+	case "lime":
+		return color.RGBA{R: 0, G: 255, B: 0, A: 255}
+	}
+
+	// #RGB や #RRGGBB 形式の16進数カラー
+	if strings.HasPrefix(colorStr, "#") && len(colorStr) >= 4 {
+		hex := colorStr[1:]
+		if len(hex) == 3 {
+			// #RGB -> #RRGGBB に展開
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) == 6 {
+			if r, err := strconv.ParseUint(hex[0:2], 16, 8); err == nil {
+				if g, err := strconv.ParseUint(hex[2:4], 16, 8); err == nil {
+					if b, err := strconv.ParseUint(hex[4:6], 16, 8); err == nil {
+						return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}
+					}
+				}
+			}
+		}
+	}
+
+	// rgb(r, g, b) 形式
+	if strings.HasPrefix(colorStr, "rgb(") && strings.HasSuffix(colorStr, ")") {
+		parts := strings.Split(colorStr[len("rgb("):len(colorStr)-1], ",")
+		if len(parts) == 3 {
+			var rgb [3]uint8
+			for i, p := range parts {
+				v, err := strconv.Atoi(strings.TrimSpace(p))
+				if err != nil || v < 0 || v > 255 {
+					return nil
+				}
+				rgb[i] = uint8(v)
+			}
+			return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}
+		}
+	}
+
+	return nil // パース失敗
+}
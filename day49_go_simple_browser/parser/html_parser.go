@@ -52,6 +52,16 @@ func GetAttribute(node *html.Node, attrName string) string {
 	return ""
 }
 
+// HasAttribute は指定された属性が存在するかを返します (checked, selected, disabled などの真偽属性用)。
+func HasAttribute(node *html.Node, attrName string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == attrName {
+			return true
+		}
+	}
+	return false
+}
+
 // DebugPrintNode はノードツリーをデバッグ用に出力します（開発用）。
 func DebugPrintNode(node *html.Node, depth int) {
 	indent := strings.Repeat("  ", depth)
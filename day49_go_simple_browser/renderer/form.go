@@ -0,0 +1,328 @@
+package renderer
+
+import (
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/net/html"
+
+	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/network"
+	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/parser"
+)
+
+// formField はフォームの1つの入力欄です。
+type formField struct {
+	name  string
+	value func() (string, bool) // 送信する値と、送信対象かどうか (未チェックのチェックボックスなどは false)
+	reset func()                // 初期値に戻す (hidden など不要な場合は nil)
+}
+
+// formState は <form> 要素ごとに入力欄をまとめたものです。
+type formState struct {
+	node   *html.Node
+	fields []formField
+	radios map[string]bool // 描画済みのラジオボタングループの name
+}
+
+// add は入力欄をフォームに登録します。フォームの外にある入力欄 (f が nil) は送信対象になりません。
+func (f *formState) add(field formField) {
+	if f == nil || field.name == "" {
+		return
+	}
+	f.fields = append(f.fields, field)
+}
+
+// reset はすべての入力欄を初期値に戻します。
+func (f *formState) reset() {
+	if f == nil {
+		return
+	}
+	for _, field := range f.fields {
+		if field.reset != nil {
+			field.reset()
+		}
+	}
+}
+
+// formFor は node を含む <form> の状態を返します。フォームの外にある場合は nil を返します。
+func (ctx *renderContext) formFor(node *html.Node) *formState {
+	for n := node.Parent; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode || strings.ToLower(n.Data) != "form" {
+			continue
+		}
+		if ctx.forms == nil {
+			ctx.forms = make(map[*html.Node]*formState)
+		}
+		form, ok := ctx.forms[n]
+		if !ok {
+			form = &formState{node: n, radios: make(map[string]bool)}
+			ctx.forms[n] = form
+		}
+		return form
+	}
+	return nil
+}
+
+// submitForm はフォームの値を集め、action を ctx.baseURL で解決して Navigator に送信させます。
+// submitName / submitValue は押された送信ボタンの name と value です (name がない場合は送信しません)。
+func (ctx *renderContext) submitForm(form *formState, submitName, submitValue string) {
+	if form == nil || ctx.navigator == nil {
+		return
+	}
+
+	values := url.Values{}
+	for _, field := range form.fields {
+		if value, ok := field.value(); ok {
+			values.Add(field.name, value)
+		}
+	}
+	if submitName != "" {
+		values.Add(submitName, submitValue)
+	}
+
+	// action が空の場合は現在のページに送信する
+	action, err := network.ResolveURL(ctx.baseURL, strings.TrimSpace(parser.GetAttribute(form.node, "action")))
+	if err != nil {
+		log.Printf("フォーム送信先URL解決エラー (%s): %v", ctx.baseURL, err)
+		return
+	}
+
+	method := "GET"
+	if strings.EqualFold(strings.TrimSpace(parser.GetAttribute(form.node, "method")), "post") {
+		method = "POST"
+	}
+	ctx.navigator.SubmitForm(method, action, values)
+}
+
+// renderInputImproved は <input> を type に応じたウィジェットとしてレンダリングします。
+func renderInputImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	form := ctx.formFor(node)
+	name := parser.GetAttribute(node, "name")
+	value := parser.GetAttribute(node, "value")
+	disabled := parser.HasAttribute(node, "disabled")
+
+	switch strings.ToLower(parser.GetAttribute(node, "type")) {
+	case "hidden":
+		form.add(formField{name: name, value: func() (string, bool) { return value, true }})
+	case "checkbox":
+		if value == "" {
+			value = "on"
+		}
+		label := value
+		if label == "on" {
+			label = ""
+		}
+		initial := parser.HasAttribute(node, "checked")
+		check := widget.NewCheck(label, nil)
+		check.SetChecked(initial)
+		if disabled {
+			check.Disable()
+		} else {
+			form.add(formField{
+				name:  name,
+				value: func() (string, bool) { return value, check.Checked },
+				reset: func() { check.SetChecked(initial) },
+			})
+		}
+		*widgets = append(*widgets, check)
+	case "radio":
+		renderRadioGroup(node, form, widgets)
+	case "submit", "image":
+		if value == "" {
+			value = "送信"
+		}
+		button := widget.NewButton(value, func() { ctx.submitForm(form, name, value) })
+		if disabled {
+			button.Disable()
+		}
+		*widgets = append(*widgets, button)
+	case "reset":
+		if value == "" {
+			value = "リセット"
+		}
+		*widgets = append(*widgets, widget.NewButton(value, form.reset))
+	case "button":
+		// JavaScript は実行しないため、押しても何もしないボタンとして表示する
+		*widgets = append(*widgets, widget.NewButton(value, nil))
+	default: // text, password, email, search など
+		var entry *widget.Entry
+		if strings.EqualFold(parser.GetAttribute(node, "type"), "password") {
+			entry = widget.NewPasswordEntry()
+		} else {
+			entry = widget.NewEntry()
+		}
+		entry.SetText(value)
+		entry.SetPlaceHolder(parser.GetAttribute(node, "placeholder"))
+		// 1行入力欄で Enter を押すとフォームを送信する (ブラウザの暗黙的な送信と同じ)
+		entry.OnSubmitted = func(string) { ctx.submitForm(form, "", "") }
+		if disabled {
+			entry.Disable()
+		} else {
+			form.add(formField{
+				name:  name,
+				value: func() (string, bool) { return entry.Text, true },
+				reset: func() { entry.SetText(value) },
+			})
+		}
+		*widgets = append(*widgets, entry)
+	}
+}
+
+// renderRadioGroup は同じ name を持つラジオボタンを1つの RadioGroup にまとめてレンダリングします。
+// グループは最初のラジオボタンの位置に表示し、2つ目以降は何も描画しません。
+func renderRadioGroup(node *html.Node, form *formState, widgets *[]fyne.CanvasObject) {
+	name := parser.GetAttribute(node, "name")
+	radios := []*html.Node{node}
+	if form != nil && name != "" {
+		if form.radios[name] {
+			return
+		}
+		form.radios[name] = true
+		radios = findRadios(form.node, name)
+	}
+
+	var options []string
+	initial := ""
+	for _, radio := range radios {
+		value := parser.GetAttribute(radio, "value")
+		if value == "" {
+			value = "on"
+		}
+		options = append(options, value)
+		if parser.HasAttribute(radio, "checked") {
+			initial = value
+		}
+	}
+
+	group := widget.NewRadioGroup(options, nil)
+	group.Horizontal = true
+	group.SetSelected(initial)
+	form.add(formField{
+		name:  name,
+		value: func() (string, bool) { return group.Selected, group.Selected != "" },
+		reset: func() { group.SetSelected(initial) },
+	})
+	*widgets = append(*widgets, group)
+}
+
+// findRadios はフォーム内の指定された name のラジオボタンを文書順に返します。
+func findRadios(node *html.Node, name string) []*html.Node {
+	var radios []*html.Node
+	if node.Type == html.ElementNode && strings.ToLower(node.Data) == "input" &&
+		strings.EqualFold(parser.GetAttribute(node, "type"), "radio") && parser.GetAttribute(node, "name") == name {
+		radios = append(radios, node)
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		radios = append(radios, findRadios(child, name)...)
+	}
+	return radios
+}
+
+// renderSelectImproved は <select> をドロップダウンとしてレンダリングします (multiple は単一選択として扱います)。
+func renderSelectImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	var labels, values []string
+	selected := 0
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch strings.ToLower(child.Data) {
+			case "optgroup":
+				collect(child)
+			case "option":
+				label := strings.TrimSpace(collapseWhitespace(parser.GetTextContent(child)))
+				value := label
+				if parser.HasAttribute(child, "value") {
+					value = parser.GetAttribute(child, "value")
+				}
+				if parser.HasAttribute(child, "selected") {
+					selected = len(values)
+				}
+				labels = append(labels, label)
+				values = append(values, value)
+			}
+		}
+	}
+	collect(node)
+
+	sel := widget.NewSelect(labels, nil)
+	if len(labels) > 0 {
+		sel.SetSelectedIndex(selected)
+	}
+	if parser.HasAttribute(node, "disabled") {
+		sel.Disable()
+	} else {
+		ctx.formFor(node).add(formField{
+			name: parser.GetAttribute(node, "name"),
+			value: func() (string, bool) {
+				if i := sel.SelectedIndex(); i >= 0 {
+					return values[i], true
+				}
+				return "", false
+			},
+			reset: func() {
+				if len(labels) > 0 {
+					sel.SetSelectedIndex(selected)
+				}
+			},
+		})
+	}
+	*widgets = append(*widgets, sel)
+}
+
+// renderTextareaImproved は <textarea> を複数行の入力欄としてレンダリングします。
+func renderTextareaImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	// ブラウザと同様に、開始タグ直後の改行は内容に含めない
+	initial := strings.TrimPrefix(strings.TrimPrefix(parser.GetTextContent(node), "\r"), "\n")
+
+	entry := widget.NewMultiLineEntry()
+	entry.SetText(initial)
+	entry.SetPlaceHolder(parser.GetAttribute(node, "placeholder"))
+	if rows, err := strconv.Atoi(parser.GetAttribute(node, "rows")); err == nil && rows > 0 {
+		entry.SetMinRowsVisible(rows)
+	}
+	if parser.HasAttribute(node, "disabled") {
+		entry.Disable()
+	} else {
+		ctx.formFor(node).add(formField{
+			name:  parser.GetAttribute(node, "name"),
+			value: func() (string, bool) { return entry.Text, true },
+			reset: func() { entry.SetText(initial) },
+		})
+	}
+	*widgets = append(*widgets, entry)
+}
+
+// renderButtonImproved は <button> をレンダリングします。type 省略時は送信ボタンです。
+func renderButtonImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	form := ctx.formFor(node)
+	label := strings.TrimSpace(collapseWhitespace(parser.GetTextContent(node)))
+	name := parser.GetAttribute(node, "name")
+	value := parser.GetAttribute(node, "value")
+
+	var onTapped func()
+	switch strings.ToLower(parser.GetAttribute(node, "type")) {
+	case "", "submit":
+		if label == "" {
+			label = "送信"
+		}
+		onTapped = func() { ctx.submitForm(form, name, value) }
+	case "reset":
+		if label == "" {
+			label = "リセット"
+		}
+		onTapped = form.reset
+	}
+
+	button := widget.NewButton(label, onTapped)
+	if parser.HasAttribute(node, "disabled") {
+		button.Disable()
+	}
+	*widgets = append(*widgets, button)
+}
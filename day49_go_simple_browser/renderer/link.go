@@ -0,0 +1,75 @@
+package renderer
+
+import (
+	"image/color"
+	"log"
+	"net/url"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/network"
+)
+
+// Navigator はリンクのタップやフォーム送信によるページ遷移を受け持ちます (main.go の Browser が実装します)。
+type Navigator interface {
+	// Navigate は絶対URLに解決済みのリンク先へ遷移します。
+	Navigate(url string)
+	// SubmitForm はフォームの値を method ("GET" / "POST") で action (絶対URL) に送信し、レスポンスを新しいページとして表示します。
+	SubmitForm(method, action string, form url.Values)
+}
+
+// linkHandler は href を ctx.baseURL で絶対URLに解決し、タップ時に遷移する関数を返します。
+// Navigator がない場合や、javascript: リンクなど遷移できない場合は nil を返します。
+func linkHandler(href string, ctx *renderContext) func() {
+	href = strings.TrimSpace(href)
+	if href == "" || ctx.navigator == nil || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return nil
+	}
+
+	target, err := network.ResolveURL(ctx.baseURL, href)
+	if err != nil {
+		log.Printf("リンクURL解決エラー (%s, %s): %v", ctx.baseURL, href, err)
+		return nil
+	}
+	return func() {
+		ctx.navigator.Navigate(target)
+	}
+}
+
+// linkText は色付きリンク用のタップ可能なテキストです。
+// widget.Hyperlink は文字色を変更できないため、canvas.Text をラップしています。
+type linkText struct {
+	widget.BaseWidget
+	text     *canvas.Text
+	onTapped func()
+}
+
+func newLinkText(text string, textColor color.Color, onTapped func()) *linkText {
+	label := canvas.NewText(text, textColor)
+	label.TextStyle = fyne.TextStyle{Bold: true}
+	label.TextSize = 14
+
+	l := &linkText{text: label, onTapped: onTapped}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+func (l *linkText) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(l.text)
+}
+
+// Tapped はリンク先へ遷移します。
+func (l *linkText) Tapped(*fyne.PointEvent) {
+	if l.onTapped != nil {
+		l.onTapped()
+	}
+}
+
+// Cursor はマウスオーバー時にポインタカーソルを表示します。
+func (l *linkText) Cursor() desktop.Cursor {
+	return desktop.PointerCursor
+}
@@ -9,7 +9,6 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"log"
-	"strconv"
 	"strings"
 
 	"fyne.io/fyne/v2"
@@ -23,18 +22,21 @@ import (
 )
 
 // RenderHTML は DOMツリーを受け取り、Fyneウィジェットのスライスに変換します。
-func RenderHTML(root *html.Node, baseURL string) []fyne.CanvasObject {
+// <style> ブロックと style 属性の CSS (color, background, font-size, font-weight, font-style, text-align) を反映します。
+// リンクのタップやフォームの送信は、baseURL で解決したURLで nav に渡します (nav が nil の場合は遷移しません)。
+func RenderHTML(root *html.Node, baseURL string, nav Navigator) []fyne.CanvasObject {
 	var widgets []fyne.CanvasObject
 	bodyNode := parser.FindElement(root, "body")
 	if bodyNode == nil {
 		bodyNode = root
 	}
-	renderNodeImproved(bodyNode, &widgets, baseURL)
+	ctx := &renderContext{baseURL: baseURL, styles: parser.CollectStyleSheet(root), navigator: nav}
+	renderNodeImproved(bodyNode, &widgets, ctx)
 	return widgets
 }
 
 // renderNodeImproved は改良されたレンダリング関数です。
-func renderNodeImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL string) {
+func renderNodeImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
 	if node == nil {
 		return
 	}
@@ -42,58 +44,67 @@ func renderNodeImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL s
 	switch node.Type {
 	case html.ElementNode:
 		// まず要素自体をレンダリングしようと試みる (要素タイプに応じてウィジェットが追加される)
-		renderElementImproved(node, widgets, baseURL)
+		renderElementImproved(node, widgets, ctx)
 		// その後、すべての子要素に対して再帰的に処理
 		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			renderNodeImproved(child, widgets, baseURL)
+			renderNodeImproved(child, widgets, ctx)
 		}
 	case html.TextNode:
-		// テキストノードは、親要素のレンダリング時に extractTextContent を介して処理されるか、
+		// テキストノードは、親要素のレンダリング時に textSegments を介して処理されるか、
 		// または親がコンテナ的な要素で直接テキストを描画しない場合にここで描画される。
-		// ここでは、孤立したテキストノード（例えば、<body>直下など）を処理する。
-		if node.Parent != nil && (node.Parent.Type == html.DocumentNode || node.Parent.Data == "body" || node.Parent.Data == "html") {
-			trimmedData := strings.TrimSpace(node.Data)
+		// ここでは、孤立したテキストノード（例えば、<body>直下や、<form>直下の入力欄のラベルなど）を処理する。
+		if node.Parent != nil && (node.Parent.Type == html.DocumentNode || node.Parent.Data == "body" || node.Parent.Data == "html" || node.Parent.Data == "form") {
+			trimmedData := strings.TrimSpace(collapseWhitespace(node.Data))
 			if trimmedData != "" {
-				*widgets = append(*widgets, widget.NewLabel(trimmedData))
+				style := ctx.styles.ComputeStyle(node.Parent)
+				*widgets = append(*widgets, newStyledText([]widget.RichTextSegment{styledSegment(trimmedData, style, style.TextAlign)}, parser.Style{}))
 			}
 		}
 	case html.DocumentNode:
 		for child := node.FirstChild; child != nil; child = child.NextSibling {
-			renderNodeImproved(child, widgets, baseURL)
+			renderNodeImproved(child, widgets, ctx)
 		}
 	}
 }
 
 // renderElementImproved は改良されたHTML要素レンダリング関数です。
-func renderElementImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL string) {
+func renderElementImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
 	// 各要素ハンドラは、自身のテキスト表示や特殊なレイアウトを担当。
 	// 子要素の一般的な再帰処理は呼び出し元の renderNodeImproved が行う。
 	switch strings.ToLower(node.Data) {
 	case "h1", "h2", "h3", "h4", "h5", "h6":
-		renderHeadingImproved(node, widgets)
+		renderHeadingImproved(node, widgets, ctx)
 	case "p":
-		renderParagraphImproved(node, widgets)
+		renderParagraphImproved(node, widgets, ctx)
 	case "br":
 		*widgets = append(*widgets, widget.NewLabel(""))
 	case "a":
-		renderLinkImproved(node, widgets)
+		renderLinkImproved(node, widgets, ctx)
 	case "font":
-		renderFontImproved(node, widgets)
+		renderFontImproved(node, widgets, ctx)
 	case "center":
-		renderCenterImproved(node, widgets)
+		renderCenterImproved(node, widgets, ctx)
 	case "table":
-		renderTableImproved(node, widgets, baseURL) // テーブルは自身の子(tr)の処理を含む
+		renderTableImproved(node, widgets, ctx) // テーブルは自身の子(tr)の処理を含む
 	case "img":
-		renderImageImproved(node, widgets, baseURL)
+		renderImageImproved(node, widgets, ctx)
 	case "frameset":
 		renderFramesetImproved(node, widgets) // フレームセットは自身の子(frame)の処理を含む
 	case "frame":
 		// frameタグ自体は表示せず、内容はmain.goで読み込まれるのでここでは何もしない
+	case "input":
+		renderInputImproved(node, widgets, ctx)
+	case "select":
+		renderSelectImproved(node, widgets, ctx)
+	case "textarea":
+		renderTextareaImproved(node, widgets, ctx)
+	case "button":
+		renderButtonImproved(node, widgets, ctx)
 	case "ul", "ol":
-		renderListImproved(node, widgets, baseURL) // リストは自身の子(li)の処理を含む
+		renderListImproved(node, widgets, ctx) // リストは自身の子(li)の処理を含む
 	case "li":
-		renderListItemImproved(node, widgets)
-	case "body", "html", "head", "div", "span":
+		renderListItemImproved(node, widgets, ctx)
+	case "body", "html", "head", "div", "span", "form":
 		// これらのコンテナ要素は特別なウィジェットを生成しない。
 		// 子要素の処理は呼び出し元のrenderNodeImprovedに任せる。
 		break
@@ -124,113 +135,55 @@ func extractTextContent(node *html.Node) string {
 }
 
 // renderHeadingImproved は改良された見出しレンダリング関数です。
-func renderHeadingImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
-	text := extractTextContent(node)
-	if text == "" {
+// 見出しの大きさと太字は parser のデフォルトスタイルで決まり、CSS で上書きできます。
+func renderHeadingImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	text := renderStyledText(node, ctx)
+	if text == nil {
 		return
 	}
 
 	// 前に空行を追加（見出しの前のスペース）
 	*widgets = append(*widgets, widget.NewLabel(""))
-
-	label := widget.NewLabel(text)
-	label.TextStyle = fyne.TextStyle{Bold: true}
-
-	// ヘッダーレベルに応じてサイズ調整（Fyneでは制限があるので、大きなフォントサイズはRichTextを使用）
-	switch node.Data {
-	case "h1":
-		richText := widget.NewRichTextFromMarkdown("# " + text)
-		*widgets = append(*widgets, richText)
-		return
-	case "h2":
-		richText := widget.NewRichTextFromMarkdown("## " + text)
-		*widgets = append(*widgets, richText)
-		return
-	default:
-		*widgets = append(*widgets, label)
-	}
-
+	*widgets = append(*widgets, text)
 	// 見出し後に空行
 	*widgets = append(*widgets, widget.NewLabel(""))
 }
 
 // renderParagraphImproved は改良された段落レンダリング関数です。
-func renderParagraphImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
-	text := extractTextContent(node)
-	if text == "" {
+func renderParagraphImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	text := renderStyledText(node, ctx)
+	if text == nil {
 		return
 	}
 
-	*widgets = append(*widgets, widget.NewLabel(text))
+	*widgets = append(*widgets, text)
 	*widgets = append(*widgets, widget.NewLabel("")) // 段落後の空行
 }
 
 // renderFontImproved は改良された<font>要素レンダリング関数です。
-func renderFontImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
-	text := extractTextContent(node)
-	if text == "" {
-		return
-	}
-
-	// color属性をチェック
-	colorAttr := parser.GetAttribute(node, "color")
-	if colorAttr != "" {
-		if c := parseColor(colorAttr); c != nil {
-			richText := canvas.NewText(text, c)
-			richText.TextSize = 14 // デフォルトサイズ
-
-			// size属性をチェック
-			sizeAttr := parser.GetAttribute(node, "size")
-			if sizeAttr != "" {
-				if size, err := strconv.Atoi(sizeAttr); err == nil {
-					// HTML font size を実際のサイズに変換
-					switch {
-					case size <= 1:
-						richText.TextSize = 10
-					case size == 2:
-						richText.TextSize = 12
-					case size == 3:
-						richText.TextSize = 14
-					case size == 4:
-						richText.TextSize = 16
-					case size == 5:
-						richText.TextSize = 18
-					case size >= 6:
-						richText.TextSize = 24
-					}
-				}
-			}
-
-			*widgets = append(*widgets, richText)
-			return
-		}
+// color / size 属性は parser のデフォルトスタイルとして扱われるため、CSS と同じ経路で反映されます。
+func renderFontImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	if text := renderStyledText(node, ctx); text != nil {
+		*widgets = append(*widgets, text)
 	}
-
-	// 色が指定されていない場合は通常のラベル
-	label := widget.NewLabel(text)
-	*widgets = append(*widgets, label)
 }
 
 // renderCenterImproved は改良されたセンター要素レンダリング関数です。
-func renderCenterImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
-	text := extractTextContent(node)
-	if text == "" {
-		return
+// <center> は text-align: center として扱われます。
+func renderCenterImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	if text := renderStyledText(node, ctx); text != nil {
+		*widgets = append(*widgets, text)
 	}
-
-	label := widget.NewLabel(text)
-	centeredContainer := container.NewCenter(label)
-	*widgets = append(*widgets, centeredContainer)
 }
 
 // renderTableImproved は改良されたテーブルレンダリング関数です。
-func renderTableImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL string) {
+func renderTableImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
 	*widgets = append(*widgets, widget.NewLabel(""))
 
 	// テーブル内の各行を処理
 	for child := node.FirstChild; child != nil; child = child.NextSibling {
 		if child.Type == html.ElementNode && strings.ToLower(child.Data) == "tr" {
-			renderTableRowImproved(child, widgets, baseURL)
+			renderTableRowImproved(child, widgets, ctx)
 		}
 	}
 
@@ -238,13 +191,13 @@ func renderTableImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL
 }
 
 // renderTableRowImproved は改良されたテーブル行レンダリング関数です。
-func renderTableRowImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL string) {
+func renderTableRowImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
 	var rowWidgets []fyne.CanvasObject
 
 	// 行内のセルを処理
 	for child := node.FirstChild; child != nil; child = child.NextSibling {
 		if child.Type == html.ElementNode && (strings.ToLower(child.Data) == "td" || strings.ToLower(child.Data) == "th") {
-			cellWidget := renderTableCellImproved(child, baseURL)
+			cellWidget := renderTableCellImproved(child, ctx)
 			if cellWidget != nil {
 				rowWidgets = append(rowWidgets, cellWidget)
 			}
@@ -259,64 +212,30 @@ func renderTableRowImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseU
 }
 
 // renderTableCellImproved は改良されたテーブルセルレンダリング関数です。
-func renderTableCellImproved(node *html.Node, baseURL string) fyne.CanvasObject {
+func renderTableCellImproved(node *html.Node, ctx *renderContext) fyne.CanvasObject {
 	// セル内のコンテンツを詳細に処理
-	return renderCellContent(node, baseURL)
+	return renderCellContent(node, ctx)
 }
 
 // renderCellContent はセル内容を詳細にレンダリングします
-func renderCellContent(node *html.Node, baseURL string) fyne.CanvasObject {
-	// セル内にfontタグがある場合の特別処理
-	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		if child.Type == html.ElementNode && strings.ToLower(child.Data) == "font" {
-			text := extractTextContent(child)
-			if text != "" {
-				colorAttr := parser.GetAttribute(child, "color")
-				sizeAttr := parser.GetAttribute(child, "size")
-
-				if colorAttr != "" {
-					if c := parseColor(colorAttr); c != nil {
-						richText := canvas.NewText(text, c)
-						richText.TextSize = 14
-
-						// サイズ属性の処理
-						if sizeAttr != "" {
-							if size, err := strconv.Atoi(sizeAttr); err == nil {
-								switch {
-								case size <= 1:
-									richText.TextSize = 10
-								case size == 2:
-									richText.TextSize = 12
-								case size == 3:
-									richText.TextSize = 14
-								case size == 4:
-									richText.TextSize = 16
-								case size == 5:
-									richText.TextSize = 18
-								case size >= 6:
-									richText.TextSize = 24
-								}
-							}
-						}
-
-						// セルに適切なパディングを追加
-						return container.NewHBox(
-							widget.NewLabel("  "), // 左パディング
-							richText,
-							widget.NewLabel("  "), // 右パディング
-						)
-					}
+func renderCellContent(node *html.Node, ctx *renderContext) fyne.CanvasObject {
+	// セル自身のテキストがなく、fontタグがある場合はfontタグのテキストとスタイルを使う
+	content := renderStyledText(node, ctx)
+	if content == nil {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && strings.ToLower(child.Data) == "font" {
+				if content = renderStyledText(child, ctx); content != nil {
+					break
 				}
 			}
 		}
 	}
 
-	// 通常のテキスト処理
-	text := extractTextContent(node)
-	if text != "" {
+	if content != nil {
+		// セルに適切なパディングを追加
 		return container.NewHBox(
 			widget.NewLabel("  "), // 左パディング
-			widget.NewLabel(text),
+			content,
 			widget.NewLabel("  "), // 右パディング
 		)
 	}
@@ -325,13 +244,13 @@ func renderCellContent(node *html.Node, baseURL string) fyne.CanvasObject {
 }
 
 // renderListImproved はリストレンダリング関数です。
-func renderListImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL string) {
+func renderListImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
 	*widgets = append(*widgets, widget.NewLabel(""))
 
 	// リスト項目を処理
 	for child := node.FirstChild; child != nil; child = child.NextSibling {
 		if child.Type == html.ElementNode && strings.ToLower(child.Data) == "li" {
-			renderListItemImproved(child, widgets)
+			renderListItemImproved(child, widgets, ctx)
 		}
 	}
 
@@ -339,11 +258,11 @@ func renderListImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL s
 }
 
 // renderListItemImproved はリスト項目レンダリング関数です。
-func renderListItemImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
+func renderListItemImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
 	// li要素内の子要素を直接レンダリング
 	var itemWidgets []fyne.CanvasObject
 	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		renderNodeImproved(child, &itemWidgets, "") // baseURLはここでは不要
+		renderNodeImproved(child, &itemWidgets, ctx)
 	}
 
 	if len(itemWidgets) > 0 {
@@ -364,9 +283,8 @@ func renderListItemImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
 			*widgets = append(*widgets, itemWidgets...)
 		}
 	} else {
-		text := extractTextContent(node)
-		if text != "" {
-			*widgets = append(*widgets, widget.NewLabel("● "+text))
+		if text := renderStyledText(node, ctx); text != nil {
+			*widgets = append(*widgets, container.NewHBox(widget.NewLabel("● "), text))
 		}
 	}
 }
@@ -393,7 +311,7 @@ func findFirstLink(node *html.Node) *html.Node {
 }
 
 // renderLinkImproved は改良されたリンクレンダリング関数です。
-func renderLinkImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
+func renderLinkImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
 	href := parser.GetAttribute(node, "href")
 	text := extractTextContent(node)
 	if text == "" {
@@ -416,7 +334,7 @@ func renderLinkImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
 	// まず<a>タグ自身のcolor属性をチェック
 	colorAttrA := parser.GetAttribute(node, "color")
 	if colorAttrA != "" {
-		if c := parseColor(colorAttrA); c != nil {
+		if c := parser.ParseColor(colorAttrA); c != nil {
 			textColor = c
 		}
 	}
@@ -426,24 +344,24 @@ func renderLinkImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
 	if fontNode != nil {
 		colorAttrFont := parser.GetAttribute(fontNode, "color")
 		if colorAttrFont != "" {
-			if c := parseColor(colorAttrFont); c != nil {
+			if c := parser.ParseColor(colorAttrFont); c != nil {
 				textColor = c // fontタグの色で上書き
 			}
 		}
 	}
 
 	if href != "" { // リンクありの場合
+		onTapped := linkHandler(href, ctx)
 		if textColor != nil {
-			// 色付き、太字のテキスト (リンク機能なし)
-			coloredBoldLabel := canvas.NewText(text, textColor)
-			coloredBoldLabel.TextStyle = fyne.TextStyle{Bold: true}
-			coloredBoldLabel.TextSize = 14
-			*widgets = append(*widgets, coloredBoldLabel)
+			// 色付き、太字のテキスト (タップで遷移)
+			*widgets = append(*widgets, newLinkText(text, textColor, onTapped))
 		} else {
 			// 色なし、太字のハイパーリンク (FyneのHyperlinkはスタイル変更不可)
 			// 表示テキストの太字化もHyperlinkではできないため、通常のLabelで代用も検討したが、リンク機能がなくなる。
 			// ここではFyne標準のHyperlinkとし、スタイルは諦める。
+			// URLを開く代わりに OnTapped でブラウザ内の遷移を行う。
 			link := widget.NewHyperlink(text, nil)
+			link.OnTapped = onTapped
 			// link.TextStyle = fyne.TextStyle{Bold: true} // これは効果がない
 			*widgets = append(*widgets, link)
 		}
@@ -464,7 +382,8 @@ func renderLinkImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
 }
 
 // renderImageImproved は改良された画像レンダリング関数です。
-func renderImageImproved(node *html.Node, widgets *[]fyne.CanvasObject, baseURL string) {
+func renderImageImproved(node *html.Node, widgets *[]fyne.CanvasObject, ctx *renderContext) {
+	baseURL := ctx.baseURL
 	src := parser.GetAttribute(node, "src")
 	alt := parser.GetAttribute(node, "alt")
 
@@ -528,59 +447,6 @@ func renderFramesetImproved(node *html.Node, widgets *[]fyne.CanvasObject) {
 	*widgets = append(*widgets, widget.NewLabel(""))
 }
 
-// parseColor は色文字列をcolor.Colorに変換します。
-func parseColor(colorStr string) color.Color {
-	colorStr = strings.ToLower(strings.TrimSpace(colorStr))
-
-	// 名前付きカラー
-	switch colorStr {
-	case "red":
-		return color.RGBA{R: 255, G: 0, B: 0, A: 255}
-	case "green":
-		return color.RGBA{R: 0, G: 128, B: 0, A: 255}
-	case "blue":
-		return color.RGBA{R: 0, G: 0, B: 255, A: 255}
-	case "black":
-		return color.RGBA{R: 0, G: 0, B: 0, A: 255}
-	case "white":
-		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
-	case "yellow":
-		return color.RGBA{R: 255, G: 255, B: 0, A: 255}
-	case "purple", "magenta":
-		return color.RGBA{R: 128, G: 0, B: 128, A: 255}
-	case "cyan":
-		return color.RGBA{R: 0, G: 255, B: 255, A: 255}
-	case "orange":
-		return color.RGBA{R: 255, G: 165, B: 0, A: 255}
-	case "gray", "grey":
-		return color.RGBA{R: 128, G: 128, B: 128, A: 255}
-	case "navy":
-		return color.RGBA{R: 0, G: 0, B: 128, A: 255}
-	case "lime":
-		return color.RGBA{R: 0, G: 255, B: 0, A: 255}
-	}
-
-	// #RGB や #RRGGBB 形式の16進数カラー
-	if strings.HasPrefix(colorStr, "#") && len(colorStr) >= 4 {
-		hex := colorStr[1:]
-		if len(hex) == 3 {
-			// #RGB -> #RRGGBB に展開
-			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
-		}
-		if len(hex) == 6 {
-			if r, err := strconv.ParseUint(hex[0:2], 16, 8); err == nil {
-				if g, err := strconv.ParseUint(hex[2:4], 16, 8); err == nil {
-					if b, err := strconv.ParseUint(hex[4:6], 16, 8); err == nil {
-						return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}
-					}
-				}
-			}
-		}
-	}
-
-	return nil // パース失敗
-}
-
 // renderFrameImproved は改良されたフレームレンダリング関数です。
 // この関数は main.go 側でフレーム内容を直接処理するため、レンダラ側では不要になりました。
 /*
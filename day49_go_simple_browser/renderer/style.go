@@ -0,0 +1,187 @@
+package renderer
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/net/html"
+
+	"github.com/lirlia/100day_challenge_backend/day49_go_simple_browser/parser"
+)
+
+// RichText の TextSegment はテーマの色名・サイズ名しか指定できないため、
+// CSS の任意の色やサイズを名前に埋め込み、cssTheme で実際の値に戻します。
+const (
+	cssColorPrefix = "css-color-" // 例: css-color-ff0000ff
+	cssSizePrefix  = "css-size-"  // 例: css-size-18
+)
+
+// inlineTags は段落などのテキストに含めるインライン要素です。
+// a, font, img は専用のウィジェットとして個別にレンダリングされるため含めません。
+var inlineTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "span": true, "small": true, "big": true,
+	"code": true, "tt": true, "cite": true, "var": true, "abbr": true, "mark": true, "s": true, "sub": true, "sup": true,
+}
+
+// renderContext はレンダリング中に共有する情報です。
+type renderContext struct {
+	baseURL   string
+	styles    *parser.StyleSheet
+	navigator Navigator
+	forms     map[*html.Node]*formState // <form> 要素ごとの入力欄 (formFor で作成)
+}
+
+// cssTheme は css-color-* / css-size-* の名前を解決し、それ以外は現在のアプリのテーマに委譲します。
+type cssTheme struct{}
+
+func (cssTheme) base() fyne.Theme {
+	if app := fyne.CurrentApp(); app != nil {
+		return app.Settings().Theme()
+	}
+	return theme.DefaultTheme()
+}
+
+func (t cssTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if hex, ok := strings.CutPrefix(string(name), cssColorPrefix); ok {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil && len(hex) == 8 {
+			return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}
+		}
+	}
+	return t.base().Color(name, variant)
+}
+
+func (t cssTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return t.base().Font(style)
+}
+
+func (t cssTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return t.base().Icon(name)
+}
+
+func (t cssTheme) Size(name fyne.ThemeSizeName) float32 {
+	if px, ok := strings.CutPrefix(string(name), cssSizePrefix); ok {
+		if size, err := strconv.ParseFloat(px, 32); err == nil {
+			return float32(size)
+		}
+	}
+	return t.base().Size(name)
+}
+
+func cssColorName(c color.Color) fyne.ThemeColorName {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return fyne.ThemeColorName(fmt.Sprintf("%s%02x%02x%02x%02x", cssColorPrefix, rgba.R, rgba.G, rgba.B, rgba.A))
+}
+
+func cssSizeName(size float32) fyne.ThemeSizeName {
+	return fyne.ThemeSizeName(cssSizePrefix + strconv.FormatFloat(float64(size), 'f', -1, 32))
+}
+
+// styledSegment は style を反映したテキストセグメントを作成します。
+// align はブロック要素 (段落など) の text-align で、セグメント単位ではなくブロック全体に揃えます。
+func styledSegment(text string, style parser.Style, align string) *widget.TextSegment {
+	segStyle := widget.RichTextStyleInline
+	segStyle.TextStyle = fyne.TextStyle{Bold: style.Bold, Italic: style.Italic}
+	if style.Color != nil {
+		segStyle.ColorName = cssColorName(style.Color)
+	}
+	if style.FontSize > 0 {
+		segStyle.SizeName = cssSizeName(style.FontSize)
+	}
+	switch align {
+	case "center":
+		segStyle.Alignment = fyne.TextAlignCenter
+	case "right":
+		segStyle.Alignment = fyne.TextAlignTrailing
+	}
+	return &widget.TextSegment{Text: text, Style: segStyle}
+}
+
+// textSegments はノード直下のテキストと、インライン要素 (b, span など) の中のテキストを
+// それぞれのスタイルを反映したセグメントに変換します。連続する空白は1つにまとめます。
+func textSegments(node *html.Node, ctx *renderContext) []widget.RichTextSegment {
+	align := ctx.styles.ComputeStyle(node).TextAlign
+	var segments []widget.RichTextSegment
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		style := ctx.styles.ComputeStyle(n)
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type == html.TextNode:
+				if text := collapseWhitespace(child.Data); text != "" {
+					segments = append(segments, styledSegment(text, style, align))
+				}
+			case child.Type == html.ElementNode && inlineTags[strings.ToLower(child.Data)]:
+				collect(child)
+			}
+		}
+	}
+	collect(node)
+	return trimSegments(segments)
+}
+
+// newStyledText は RichText を作成し、CSS の色・サイズを解決するテーマと背景色を適用します。
+// セグメントが空の場合は nil を返します。
+func newStyledText(segments []widget.RichTextSegment, block parser.Style) fyne.CanvasObject {
+	if len(segments) == 0 {
+		return nil
+	}
+	richText := widget.NewRichText(segments...)
+	var obj fyne.CanvasObject = container.NewThemeOverride(richText, cssTheme{})
+	if block.Background != nil {
+		obj = container.NewStack(canvas.NewRectangle(block.Background), obj)
+	}
+	return obj
+}
+
+// renderStyledText は要素のテキストを CSS を反映した RichText として返します。テキストがない場合は nil を返します。
+func renderStyledText(node *html.Node, ctx *renderContext) fyne.CanvasObject {
+	return newStyledText(textSegments(node, ctx), ctx.styles.ComputeStyle(node))
+}
+
+// collapseWhitespace は HTML の空白の扱いに合わせて、連続する空白文字を1つのスペースにまとめます。
+// 前後の空白は単語の区切りとして1つだけ残します。
+func collapseWhitespace(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			return " "
+		}
+		return ""
+	}
+	text := strings.Join(fields, " ")
+	if strings.TrimLeft(s, " \t\r\n\f") != s {
+		text = " " + text
+	}
+	if strings.TrimRight(s, " \t\r\n\f") != s {
+		text += " "
+	}
+	return text
+}
+
+// trimSegments はブロック先頭・末尾の空白と、空白だけのセグメントを取り除きます。
+func trimSegments(segments []widget.RichTextSegment) []widget.RichTextSegment {
+	for len(segments) > 0 {
+		first := segments[0].(*widget.TextSegment)
+		first.Text = strings.TrimLeft(first.Text, " ")
+		if first.Text != "" {
+			break
+		}
+		segments = segments[1:]
+	}
+	for len(segments) > 0 {
+		last := segments[len(segments)-1].(*widget.TextSegment)
+		last.Text = strings.TrimRight(last.Text, " ")
+		if last.Text != "" {
+			break
+		}
+		segments = segments[:len(segments)-1]
+	}
+	return segments
+}
@@ -1,15 +1,20 @@
 package config
 
 import (
+	"net"
 	"os"
+	"path"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config はアプリケーション全体の設定を保持します。
 type Config struct {
-	Proxy   ProxyConfig `yaml:"proxy"`
-	Cache   CacheConfig `yaml:"cache,omitempty"`
+	Proxy     ProxyConfig     `yaml:"proxy"`
+	Cache     CacheConfig     `yaml:"cache,omitempty"`
+	Admin     AdminConfig     `yaml:"admin,omitempty"`
+	WebSocket WebSocketConfig `yaml:"websocket,omitempty"`
 	// Logging LoggingConfig `yaml:"logging"`
 	// ACL     ACLConfig     `yaml:"acl"`
 }
@@ -28,6 +33,86 @@ type CacheConfig struct {
 	SQLitePath        string `yaml:"sqlite_path,omitempty"`
 	DefaultTTLSeconds int    `yaml:"default_ttl_seconds,omitempty"`
 	MaxSizeMB         int    `yaml:"max_size_mb,omitempty"`
+	// AllowedHosts はキャッシュ対象とするホストのパターンです。空の場合はすべてのホストが対象です。
+	AllowedHosts []string    `yaml:"allowed_hosts,omitempty"`
+	Rules        []CacheRule `yaml:"rules,omitempty"`
+}
+
+// CacheRule はホストごとのキャッシュ設定です。上から順に評価され、最初にマッチしたルールが使われます。
+type CacheRule struct {
+	// Host はホスト名のパターンです (path.Match 形式。例: "*.example.com")。ポートは含めません。
+	Host string `yaml:"host" json:"host"`
+	// TTLSeconds はオリジンが有効期限を指定しない場合の TTL です (0 の場合は default_ttl_seconds)。
+	TTLSeconds int `yaml:"ttl_seconds,omitempty" json:"ttl_seconds,omitempty"`
+	// IgnoreOriginTTL が true の場合、Cache-Control / Expires を無視して TTLSeconds を使います。
+	IgnoreOriginTTL bool `yaml:"ignore_origin_ttl,omitempty" json:"ignore_origin_ttl,omitempty"`
+	// Disabled が true の場合、このホストのレスポンスはキャッシュしません。
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty"`
+}
+
+// AdminConfig はキャッシュ管理 API の設定です。
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host,omitempty"`
+	Port    int    `yaml:"port,omitempty"`
+}
+
+// WebSocketConfig は WebSocket の中継の設定です。
+type WebSocketConfig struct {
+	// FrameLog が true の場合、中継する WebSocket フレームをログに出力します。
+	FrameLog bool `yaml:"frame_log,omitempty"`
+	// FrameLogPayloadBytes はログに出力するテキストフレームのペイロードの最大バイト数です。
+	FrameLogPayloadBytes int `yaml:"frame_log_payload_bytes,omitempty"`
+}
+
+// RuleFor は host にマッチする最初のルールを返します。マッチしない場合は nil を返します。
+func (c *CacheConfig) RuleFor(host string) *CacheRule {
+	host = hostname(host)
+	for i := range c.Rules {
+		if MatchHost(c.Rules[i].Host, host) {
+			return &c.Rules[i]
+		}
+	}
+	return nil
+}
+
+// IsHostCacheable は host のレスポンスをキャッシュしてよいかを allowed_hosts と rules から判断します。
+func (c *CacheConfig) IsHostCacheable(host string) bool {
+	host = hostname(host)
+	if rule := c.RuleFor(host); rule != nil && rule.Disabled {
+		return false
+	}
+	if len(c.AllowedHosts) == 0 {
+		return true
+	}
+	for _, pattern := range c.AllowedHosts {
+		if MatchHost(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// TTLFor は host のデフォルト TTL (秒) を返します。
+func (c *CacheConfig) TTLFor(host string) int {
+	if rule := c.RuleFor(host); rule != nil && rule.TTLSeconds > 0 {
+		return rule.TTLSeconds
+	}
+	return c.DefaultTTLSeconds
+}
+
+// MatchHost はホスト名がパターンにマッチするかを判定します (大文字小文字は区別しない)。
+func MatchHost(pattern, host string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(hostname(host)))
+	return err == nil && matched
+}
+
+// hostname は "example.com:443" のような文字列からポートを取り除きます。
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
 }
 
 /* // 今後の機能のためにコメントアウト
@@ -79,5 +164,18 @@ func LoadConfig(path string) (*Config, error) {
 		}
 	}
 
+	if cfg.WebSocket.FrameLog && cfg.WebSocket.FrameLogPayloadBytes == 0 {
+		cfg.WebSocket.FrameLogPayloadBytes = 64
+	}
+
+	if cfg.Admin.Enabled {
+		if cfg.Admin.Host == "" {
+			cfg.Admin.Host = "127.0.0.1" // 管理 API はデフォルトでローカルからのみ受け付ける
+		}
+		if cfg.Admin.Port == 0 {
+			cfg.Admin.Port = 8081
+		}
+	}
+
 	return &cfg, nil
 }
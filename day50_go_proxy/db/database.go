@@ -282,6 +282,107 @@ func DeleteCacheByID(id int64) error {
 	return nil
 }
 
+// CacheEntrySummary はキャッシュ管理 API 用のキャッシュアイテムの概要です (レスポンスボディは含みません)。
+type CacheEntrySummary struct {
+	ID             int64
+	RequestKey     string
+	Method         string
+	RequestURL     string
+	StatusCode     int
+	BodySize       int64
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	LastAccessedAt time.Time
+}
+
+// ListCacheEntries はすべてのキャッシュアイテムの概要を最終アクセス日時の新しい順に取得します。
+func ListCacheEntries() ([]CacheEntrySummary, error) {
+	query := `
+        SELECT id, request_key, method, request_url, status_code, length(response_body),
+               created_at, expires_at, last_accessed_at
+        FROM http_cache
+        ORDER BY last_accessed_at DESC;
+    `
+	rows, err := DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cache entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []CacheEntrySummary
+	for rows.Next() {
+		var e CacheEntrySummary
+		var bodySize sql.NullInt64
+		if err := rows.Scan(
+			&e.ID,
+			&e.RequestKey,
+			&e.Method,
+			&e.RequestURL,
+			&e.StatusCode,
+			&bodySize,
+			&e.CreatedAt,
+			&e.ExpiresAt,
+			&e.LastAccessedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan cache entry: %w", err)
+		}
+		e.BodySize = bodySize.Int64
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate cache entries: %w", err)
+	}
+	return entries, nil
+}
+
+// DeleteCacheByURL は指定された URL (またはリクエストキー) のキャッシュアイテムを削除し、削除件数を返します。
+func DeleteCacheByURL(requestURL, requestKey string) (int64, error) {
+	res, err := DB.Exec(`DELETE FROM http_cache WHERE request_url = ? OR request_key = ?;`, requestURL, requestKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cache items for %s: %w", requestURL, err)
+	}
+	return res.RowsAffected()
+}
+
+// DeleteCacheByIDs は複数のキャッシュアイテムを ID で削除し、削除件数を返します。
+func DeleteCacheByIDs(ids []int64) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`DELETE FROM http_cache WHERE id = ?;`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement for deleting cache items: %w", err)
+	}
+	defer stmt.Close()
+
+	var deleted int64
+	for _, id := range ids {
+		res, err := stmt.Exec(id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete cache item by ID %d: %w", id, err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit cache deletion: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteAllCache はすべてのキャッシュアイテムを削除し、削除件数を返します。
+func DeleteAllCache() (int64, error) {
+	res, err := DB.Exec(`DELETE FROM http_cache;`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete all cache items: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 // Helper functions for sql.NullString
 func ToNullString(s string) sql.NullString {
 	if s == "" {
@@ -46,9 +46,9 @@ func main() {
 		Addr: fmt.Sprintf("%s:%d", cfg.Proxy.Host, cfg.Proxy.Port),
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if r.Method == http.MethodConnect {
-				proxy.HandleHTTPS(w, r, certManager, &cfg.Cache)
+				proxy.HandleHTTPS(w, r, certManager, &cfg.Cache, &cfg.WebSocket)
 			} else {
-				proxy.HandleHTTP(w, r, &cfg.Cache)
+				proxy.HandleHTTP(w, r, &cfg.Cache, &cfg.WebSocket)
 			}
 		}),
 	}
@@ -61,6 +61,25 @@ func main() {
 		}
 	}()
 
+	// キャッシュ管理 API (プロキシとは別のポートで待ち受ける)
+	var adminServer *http.Server
+	if cfg.Admin.Enabled {
+		if !cfg.Cache.Enabled {
+			log.Println("キャッシュが無効なため、キャッシュ管理 API は起動しません")
+		} else {
+			adminServer = &http.Server{
+				Addr:    fmt.Sprintf("%s:%d", cfg.Admin.Host, cfg.Admin.Port),
+				Handler: proxy.NewAdminHandler(&cfg.Cache),
+			}
+			go func() {
+				log.Printf("キャッシュ管理 API を %s で起動します", adminServer.Addr)
+				if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Fatalf("%s でリッスンできませんでした: %v\n", adminServer.Addr, err)
+				}
+			}()
+		}
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -69,6 +88,11 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Printf("キャッシュ管理 API のシャットダウンに失敗しました: %+v", err)
+		}
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatalf("サーバーシャットダウンに失敗しました: %+v", err)
 	}
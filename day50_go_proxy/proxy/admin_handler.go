@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day50_go_proxy/config"
+	"github.com/lirlia/100day_challenge_backend/day50_go_proxy/db"
+)
+
+// cacheEntryJSON は管理 API で返すキャッシュアイテムです。
+type cacheEntryJSON struct {
+	ID             int64     `json:"id"`
+	RequestKey     string    `json:"request_key"`
+	Method         string    `json:"method"`
+	URL            string    `json:"url"`
+	Host           string    `json:"host"`
+	StatusCode     int       `json:"status_code"`
+	BodySize       int64     `json:"body_size"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+	Fresh          bool      `json:"fresh"`
+}
+
+// NewAdminHandler はキャッシュ管理 API のハンドラを返します。
+//
+//	GET    /cache[?domain=example.com]  キャッシュ一覧 (domain はサブドメインも含む。"*" を含む場合はパターン)
+//	DELETE /cache?url=<URL>             URL 単位で削除
+//	DELETE /cache?domain=example.com    ドメイン単位で削除
+//	DELETE /cache?all=true              すべて削除
+//	POST   /cache/prune                 期限切れの削除と max_size_mb を超えた分の LRU 削除
+//	GET    /rules                       ホストごとのキャッシュ設定
+func NewAdminHandler(cacheCfg *config.CacheConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /cache", func(w http.ResponseWriter, r *http.Request) {
+		entries, err := filteredCacheEntries(r.URL.Query().Get("domain"))
+		if err != nil {
+			log.Printf("[Admin] Error listing cache entries: %v", err)
+			writeAdminError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		now := time.Now()
+		items := make([]cacheEntryJSON, 0, len(entries))
+		for _, e := range entries {
+			items = append(items, cacheEntryJSON{
+				ID:             e.ID,
+				RequestKey:     e.RequestKey,
+				Method:         e.Method,
+				URL:            e.RequestURL,
+				Host:           entryHost(e.RequestURL),
+				StatusCode:     e.StatusCode,
+				BodySize:       e.BodySize,
+				CreatedAt:      e.CreatedAt,
+				ExpiresAt:      e.ExpiresAt,
+				LastAccessedAt: e.LastAccessedAt,
+				Fresh:          e.ExpiresAt.After(now),
+			})
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]any{"entries": items, "total": len(items)})
+	})
+
+	mux.HandleFunc("DELETE /cache", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var deleted int64
+		var err error
+
+		switch {
+		case q.Get("url") != "":
+			rawURL := q.Get("url")
+			deleted, err = db.DeleteCacheByURL(rawURL, purgeKeyForURL(rawURL))
+		case q.Get("domain") != "":
+			var entries []db.CacheEntrySummary
+			entries, err = filteredCacheEntries(q.Get("domain"))
+			if err == nil && len(entries) > 0 {
+				ids := make([]int64, 0, len(entries))
+				for _, e := range entries {
+					ids = append(ids, e.ID)
+				}
+				deleted, err = db.DeleteCacheByIDs(ids)
+			}
+		case q.Get("all") == "true":
+			deleted, err = db.DeleteAllCache()
+		default:
+			writeAdminError(w, http.StatusBadRequest, "one of url, domain or all=true is required")
+			return
+		}
+
+		if err != nil {
+			log.Printf("[Admin] Error purging cache (%s): %v", r.URL.RawQuery, err)
+			writeAdminError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		log.Printf("[Admin] Purged %d cache items (%s)", deleted, r.URL.RawQuery)
+		writeAdminJSON(w, http.StatusOK, map[string]any{"deleted": deleted})
+	})
+
+	mux.HandleFunc("POST /cache/prune", func(w http.ResponseWriter, r *http.Request) {
+		if err := db.PruneCache(int64(cacheCfg.MaxSizeMB)*1024*1024, cacheCfg.DefaultTTLSeconds); err != nil {
+			log.Printf("[Admin] Error pruning cache: %v", err)
+			writeAdminError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, map[string]any{"pruned": true})
+	})
+
+	mux.HandleFunc("GET /rules", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, map[string]any{
+			"default_ttl_seconds": cacheCfg.DefaultTTLSeconds,
+			"allowed_hosts":       cacheCfg.AllowedHosts,
+			"rules":               cacheCfg.Rules,
+		})
+	})
+
+	return mux
+}
+
+// filteredCacheEntries はドメインに一致するキャッシュアイテムを返します。domain が空の場合はすべて返します。
+func filteredCacheEntries(domain string) ([]db.CacheEntrySummary, error) {
+	entries, err := db.ListCacheEntries()
+	if err != nil {
+		return nil, err
+	}
+	if domain == "" {
+		return entries, nil
+	}
+
+	filtered := entries[:0]
+	for _, e := range entries {
+		if matchDomain(domain, entryHost(e.RequestURL)) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered, nil
+}
+
+// matchDomain は host が domain またはそのサブドメインかを判定します。domain に "*" を含む場合はパターンとして扱います。
+func matchDomain(domain, host string) bool {
+	if strings.Contains(domain, "*") {
+		return config.MatchHost(domain, host)
+	}
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// entryHost はキャッシュされた URL からポートを除いたホスト名を取り出します。
+func entryHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// purgeKeyForURL は URL を GET リクエストとしたときのキャッシュキーを返します (クエリの順序を正規化するため)。
+func purgeKeyForURL(rawURL string) string {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return ""
+	}
+	return GenerateRequestKey(req)
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("[Admin] Error writing response: %v", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminJSON(w, status, map[string]string{"error": message})
+}
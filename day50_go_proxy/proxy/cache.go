@@ -73,7 +73,7 @@ func StoreResponseInCache(requestKey string, resp *http.Response, req *http.Requ
 		ResponseHeaders: string(headersJSON),
 		ResponseBody:    fullRespBytes, // レスポンスヘッダも含んだバイト列を保存
 		CreatedAt:       now,
-		ExpiresAt:       CalculateExpirationTime(resp.Header, now, cacheCfg),
+		ExpiresAt:       CalculateExpirationTime(resp.Header, now, cacheCfg, req.URL.Host),
 		ETag:            db.ToNullString(resp.Header.Get("ETag")),
 		LastModified:    db.ToNullString(resp.Header.Get("Last-Modified")),
 		LastAccessedAt:  now,
@@ -153,6 +153,12 @@ func CanCacheResponse(resp *http.Response, req *http.Request, cacheCfg *config.C
 		return false
 	}
 
+	// allowed_hosts / rules でキャッシュ対象外のホスト
+	if !cacheCfg.IsHostCacheable(req.URL.Host) {
+		log.Printf("[CanCache] Host %s is not cacheable by config for %s", req.URL.Host, req.URL.String())
+		return false
+	}
+
 	// Cache-Control ヘッダーの確認
 	ccHeader := resp.Header.Get("Cache-Control")
 	if ccHeader != "" {
@@ -190,7 +196,15 @@ func CanCacheResponse(resp *http.Response, req *http.Request, cacheCfg *config.C
 }
 
 // CalculateExpirationTime はレスポンスヘッダーと設定からキャッシュの有効期限を計算します。
-func CalculateExpirationTime(headers http.Header, now time.Time, cacheCfg *config.CacheConfig) time.Time {
+// host にマッチするルールがある場合は、そのルールの TTL をデフォルト TTL として使います。
+func CalculateExpirationTime(headers http.Header, now time.Time, cacheCfg *config.CacheConfig, host string) time.Time {
+	defaultExpiration := now.Add(time.Duration(cacheCfg.TTLFor(host)) * time.Second)
+
+	// ルールで TTL が固定されている場合はオリジンの指定を無視
+	if rule := cacheCfg.RuleFor(host); rule != nil && rule.IgnoreOriginTTL {
+		return defaultExpiration
+	}
+
 	// Cache-Control: max-age=seconds
 	ccHeader := headers.Get("Cache-Control")
 	if ccHeader != "" {
@@ -213,7 +227,7 @@ func CalculateExpirationTime(headers http.Header, now time.Time, cacheCfg *confi
 	}
 
 	// デフォルトTTL
-	return now.Add(time.Duration(cacheCfg.DefaultTTLSeconds) * time.Second)
+	return defaultExpiration
 }
 
 // IsCacheFresh はキャッシュされたアイテムがまだ新鮮かどうかを判断します。
@@ -13,9 +13,19 @@ import (
 )
 
 // HandleHTTP は通常のHTTPリクエストを処理します。
-func HandleHTTP(w http.ResponseWriter, r *http.Request, cacheCfg *config.CacheConfig) {
+func HandleHTTP(w http.ResponseWriter, r *http.Request, cacheCfg *config.CacheConfig, wsCfg *config.WebSocketConfig) {
 	log.Printf("[HTTP] Received request for: %s %s%s from %s", r.Method, r.Host, r.URL.Path, r.RemoteAddr)
 
+	// ws:// の WebSocket はキャッシュせずにオリジンとの間でトンネリングする
+	if IsWebSocketUpgrade(r) {
+		host := r.URL.Host
+		if host == "" {
+			host = r.Host
+		}
+		TunnelWebSocket(w, r, "http", host, wsCfg)
+		return
+	}
+
 	requestKey := GenerateRequestKey(r)
 
 	if cacheCfg.Enabled && r.Method == http.MethodGet { // GETリクエストのみキャッシュ対象
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,16 +12,35 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"strings"
-//	"sync" // 未使用のためコメントアウト
+	"sync"
 	"time"
 
 	"github.com/lirlia/100day_challenge_backend/day50_go_proxy/config"
 )
 
+// originTransport はオリジンサーバーと通信するためのトランスポートです。
+// 全ての CONNECT セッションで共有し、オリジンとのコネクションを再利用します。
+// DialContext を指定すると HTTP/2 が自動では有効にならないため、ForceAttemptHTTP2 で h2 をネゴシエートします。
+var originTransport = &http.Transport{
+	Proxy: nil, // このプロキシ自身を経由しない
+	DialContext: (&net.Dialer{
+		Timeout:   15 * time.Second, // TCP接続のタイムアウト
+		KeepAlive: 30 * time.Second, // TCPキープアライブ
+	}).DialContext,
+	ForceAttemptHTTP2:   true,
+	TLSHandshakeTimeout: 10 * time.Second, // オリジンサーバーとのTLSハンドシェイクのタイムアウト
+	IdleConnTimeout:     90 * time.Second,
+	MaxIdleConns:        100,
+	// TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // テスト用にオリジンサーバーの証明書検証をスキップする場合 (本番非推奨)
+}
+
+// mitmIdleTimeout は復号したコネクションでクライアントの次のリクエストを待つ時間です。
+const mitmIdleTimeout = 90 * time.Second
+
 // HandleHTTPS はクライアントからの CONNECT リクエストを処理し、指定されたホストとの間で
 // Man-in-the-Middle (MITM) プロキシとして動作します。
 // この関数は、クライアントが特定のホストとの新しいHTTPSセッションを開始しようとするたびに呼び出されます。
-func HandleHTTPS(w http.ResponseWriter, r *http.Request, certManager *CertManager, cacheConfig *config.CacheConfig) {
+func HandleHTTPS(w http.ResponseWriter, r *http.Request, certManager *CertManager, cacheConfig *config.CacheConfig, wsConfig *config.WebSocketConfig) {
 	// r.Host には、クライアントが接続しようとしているターゲットホスト名（例: "example.com:443"）が含まれます。
 	// r.RemoteAddr はクライアントのIPアドレスとポートです。
 	log.Printf("[HTTPS-MITM] Received CONNECT request for: %s from %s", r.Host, r.RemoteAddr)
@@ -61,7 +81,8 @@ func HandleHTTPS(w http.ResponseWriter, r *http.Request, certManager *CertManage
 	// 動的にサーバー証明書を生成（またはキャッシュから取得）し、CA証明書で署名します。
 	tlsConfig := &tls.Config{
 		GetCertificate: certManager.GetCertificate, // ホスト名に基づいて証明書を動的に提供
-		NextProtos:     []string{"http/1.1"},      // ALPN (Application-Layer Protocol Negotiation) で HTTP/1.1 を使用することを示す
+		// ALPN (Application-Layer Protocol Negotiation) で HTTP/2 と HTTP/1.1 を提示し、クライアントに選ばせる
+		NextProtos: []string{"h2", "http/1.1"},
 	}
 
 	// ハイジャックした生のTCPコネクションを、TLSサーバーサイドのコネクションとしてラップします。
@@ -79,181 +100,283 @@ func HandleHTTPS(w http.ResponseWriter, r *http.Request, certManager *CertManage
 		}
 		return
 	}
-	log.Printf("[HTTPS-MITM] TLS handshake with client successful for %s", r.Host)
-
-	// STEP 3: 暗号化されたTLSセッション上でのHTTPリクエストの読み取り
-	// クライアントは、プロキシとのTLSセッションが確立された後（クライアントはオリジンサーバーと通信しているつもり）、
-	// 本来のHTTPリクエスト（GET, POSTなど）を送信します。
-	// このリクエストはTLSで暗号化されているため、tlsClientConnから読み取る必要があります。
-	clientReqReader := bufio.NewReader(tlsClientConn) // TLSコネクションから読み取るためのリーダー
-	clientHTTPReq, err := http.ReadRequest(clientReqReader) // TLSペイロードからHTTPリクエストをパース
-	if err != nil {
-		if err == io.EOF || err == io.ErrUnexpectedEOF { // クライアントがリクエスト送信前に接続を閉じた場合
-			log.Printf("[HTTPS-MITM] Client %s closed connection before sending request for %s: %v", r.RemoteAddr, r.Host, err)
-		} else {
-			log.Printf("[HTTPS-MITM] Error reading request from client %s for %s: %v", r.RemoteAddr, r.Host, err)
-		}
-		return
+	log.Printf("[HTTPS-MITM] TLS handshake with client successful for %s (protocol: %s)", r.Host, negotiatedProtocol(tlsClientConn))
+
+	// STEP 3: 暗号化されたTLSセッション上でのHTTPリクエストの処理
+	// 復号したコネクションを、このコネクション専用の http.Server で処理します。
+	// http.Server は ALPN で h2 がネゴシエートされていれば HTTP/2 として、そうでなければ HTTP/1.1 として
+	// リクエストを読み取り、Keep-Alive で同じコネクション上の連続したリクエストも処理します。
+	listener := newSingleConnListener(tlsClientConn)
+	mitmServer := &http.Server{
+		Handler: &mitmHandler{
+			connectHost: r.Host,
+			remoteAddr:  r.RemoteAddr,
+			cacheConfig: cacheConfig,
+			wsConfig:    wsConfig,
+			onHijackEnd: listener.finish,
+		},
+		IdleTimeout: mitmIdleTimeout,
+		ErrorLog:    log.Default(),
+		// コネクションが閉じられたら Serve を終了させる (ハイジャックされた場合は onHijackEnd で終了させる)
+		ConnState: func(_ net.Conn, state http.ConnState) {
+			if state == http.StateClosed {
+				listener.finish()
+			}
+		},
+	}
+	if err := mitmServer.Serve(listener); err != nil && err != errListenerDone {
+		log.Printf("[HTTPS-MITM] Error serving decrypted connection for %s: %v", r.Host, err)
 	}
-	// リクエストボディは必ずクローズします。後続の処理で必要に応じて再利用されることがあります。
-	// 特に RoundTrip に渡す場合などは、クローズされていないと問題が起きることがあります。
-	defer clientHTTPReq.Body.Close()
 
-	log.Printf("[HTTPS-MITM] Received from client (%s): %s %s%s",
-		r.RemoteAddr, clientHTTPReq.Method, clientHTTPReq.Host, clientHTTPReq.URL.String())
+	log.Printf("[HTTPS-MITM] Connection closed for %s", r.Host)
+}
+
+func negotiatedProtocol(conn *tls.Conn) string {
+	if proto := conn.ConnectionState().NegotiatedProtocol; proto != "" {
+		return proto
+	}
+	return "http/1.1"
+}
+
+// mitmHandler は CONNECT で確立したトンネル上の復号済みリクエストを処理します。
+type mitmHandler struct {
+	connectHost string // CONNECT リクエストで指定されたホスト (例: "example.com:443")
+	remoteAddr  string // クライアントのアドレス
+	cacheConfig *config.CacheConfig
+	wsConfig    *config.WebSocketConfig
+	onHijackEnd func() // WebSocket でハイジャックしたコネクションの中継が終わったときに呼ぶ
+}
+
+func (h *mitmHandler) ServeHTTP(w http.ResponseWriter, clientHTTPReq *http.Request) {
+	log.Printf("[HTTPS-MITM] Received from client (%s, %s): %s %s%s",
+		h.remoteAddr, clientHTTPReq.Proto, clientHTTPReq.Method, clientHTTPReq.Host, clientHTTPReq.URL.String())
 
 	// STEP 4: オリジンサーバーへのリクエスト準備
 	// クライアントから読み取ったHTTPリクエストを、オリジンサーバーへ転送する準備をします。
 	// clientHTTPReq.URL は通常、パスのみ (例: "/path/to/resource") になっています。
 	// これにスキーム ("https") とホスト名 (CONNECTリクエストで指定されたもの) を付加して完全なURLを再構築します。
-	originHost := clientHTTPReq.Host // HTTP/1.1ではHostヘッダが優先される
+	originHost := clientHTTPReq.Host // HTTP/1.1ではHostヘッダ、HTTP/2では :authority が優先される
 	if originHost == "" {
-		originHost = r.Host // CONNECTリクエスト時のターゲットホスト (r.URL.Host ではない)
+		originHost = h.connectHost // CONNECTリクエスト時のターゲットホスト (r.URL.Host ではない)
 	}
 
-	clientHTTPReq.URL.Scheme = "https"
-	clientHTTPReq.URL.Host = originHost
+	// WebSocket のアップグレード要求は、ヘッダーを加工せずにオリジンとの間でトンネリングする
+	if IsWebSocketUpgrade(clientHTTPReq) {
+		if hijacked := TunnelWebSocket(w, clientHTTPReq, "https", originHost, h.wsConfig); hijacked {
+			h.onHijackEnd()
+		}
+		return
+	}
+
+	outReq := clientHTTPReq.Clone(clientHTTPReq.Context())
+	outReq.RequestURI = "" // クライアントリクエストとして送信するため空にする
+	outReq.URL.Scheme = "https"
+	outReq.URL.Host = originHost
+	outReq.Host = originHost
 
 	// プロキシとして動作するために不要なホップバイホップヘッダーを削除します。
-	RemoveHopByHopHeaders(clientHTTPReq.Header)
+	RemoveHopByHopHeaders(outReq.Header)
 	// X-Forwarded-For ヘッダーを追加または更新して、クライアントのIPアドレスをオリジンサーバーに伝えます。
-	UpdateXForwardedForHeader(clientHTTPReq, r.RemoteAddr)
+	UpdateXForwardedForHeader(outReq, h.remoteAddr)
 
 	// STEP 5: キャッシュ処理 (キャッシュが有効な場合)
-	cacheKey := GenerateRequestKey(clientHTTPReq) // リクエストに基づいてキャッシュキーを生成
-	log.Printf("[HTTPS-MITM Cache] Cache key for %s %s: %s", clientHTTPReq.Method, clientHTTPReq.URL.String(), cacheKey)
-
-	if cacheConfig.Enabled {
-		cachedItem, found, err := RetrieveResponseFromCache(cacheKey, clientHTTPReq, cacheConfig)
-		if err != nil {
-			log.Printf("[HTTPS-MITM Cache] Error retrieving from cache for %s: %v", cacheKey, err)
-		} else if found {
-			if IsCacheFresh(cachedItem, clientHTTPReq, cacheConfig) { // キャッシュが新鮮か確認
-				log.Printf("[HTTPS-MITM Cache] HIT and FRESH for %s. Serving from cache.", cacheKey)
-				// キャッシュされたレスポンスボディを http.Response オブジェクトにパース
-				cachedResp, readErr := http.ReadResponse(bufio.NewReader(bytes.NewReader(cachedItem.ResponseBody)), clientHTTPReq)
-				if readErr != nil {
-					log.Printf("[HTTPS-MITM Cache] Error reading cached response for %s: %v", cacheKey, readErr)
-				} else {
-					defer cachedResp.Body.Close()
-					RemoveHopByHopHeaders(cachedResp.Header) // ホップバイホップヘッダーを削除
-					cachedResp.Header.Set("X-Proxy-Cache", "HIT") // キャッシュヒットを示すヘッダーを追加
-					cachedResp.Header.Set("X-Cache-Key", cacheKey)
-					if !cachedItem.ExpiresAt.IsZero() {
-						cachedResp.Header.Set("Expires", cachedItem.ExpiresAt.Format(time.RFC1123))
-					}
-
-					// キャッシュされたレスポンスをクライアントに送信 (TLSコネクション経由)
-					err = cachedResp.Write(tlsClientConn)
-					if err != nil {
-						log.Printf("[HTTPS-MITM Cache] Error writing cached response to client for %s: %v", cacheKey, err)
-					}
-					return // キャッシュから提供したので、このリクエスト処理はここで終了
-				}
-			} else {
-				log.Printf("[HTTPS-MITM Cache] STALE for key: %s. Fetching from origin.", cacheKey)
-				// TODO: キャッシュが古いが存在する場合、条件付きGET (If-None-Match, If-Modified-Since) を
-				//       オリジンへのリクエストに付加する実装が考えられる。
-			}
+	cacheKey := GenerateRequestKey(outReq) // リクエストに基づいてキャッシュキーを生成
+	log.Printf("[HTTPS-MITM Cache] Cache key for %s %s: %s", outReq.Method, outReq.URL.String(), cacheKey)
+
+	if h.cacheConfig.Enabled {
+		if h.serveFromCache(w, outReq, cacheKey) {
+			return // キャッシュから提供したので、このリクエスト処理はここで終了
 		}
 	} else {
 		log.Printf("[HTTPS-MITM Cache] Cache disabled.")
 	}
 
 	// STEP 6: オリジンサーバーへのリクエスト転送 (キャッシュミスまたはキャッシュ無効の場合)
-	log.Printf("[HTTPS-MITM Origin] Requesting from origin: %s %s", clientHTTPReq.Method, clientHTTPReq.URL.String())
-
-	// オリジンサーバーと通信するためのトランスポートを設定。
-	// 通常は http.DefaultTransport を使用できますが、ここではタイムアウトなどを明示的に設定しています。
-	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   15 * time.Second, // TCP接続のタイムアウト
-			KeepAlive: 30 * time.Second, // TCPキープアライブ
-		}).DialContext,
-		TLSHandshakeTimeout: 10 * time.Second, // オリジンサーバーとのTLSハンドシェイクのタイムアウト
-		// TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // テスト用にオリジンサーバーの証明書検証をスキップする場合 (本番非推奨)
-	}
+	log.Printf("[HTTPS-MITM Origin] Requesting from origin: %s %s", outReq.Method, outReq.URL.String())
 
-	// clientHTTPReq をオリジンサーバーに送信し、レスポンスを取得します。
-	// RoundTrip はリクエストを送信し、レスポンスを受信するまでブロックします。
-	originResp, err := transport.RoundTrip(clientHTTPReq)
+	// outReq をオリジンサーバーに送信し、レスポンスを取得します。
+	// オリジンが h2 に対応していれば HTTP/2 で通信します (クライアント側のプロトコルとは独立)。
+	originResp, err := originTransport.RoundTrip(outReq)
 	if err != nil {
-		log.Printf("[HTTPS-MITM Origin] Error forwarding request to origin %s: %v", clientHTTPReq.URL.Host, err)
+		log.Printf("[HTTPS-MITM Origin] Error forwarding request to origin %s: %v", outReq.URL.Host, err)
 		// オリジンへの接続に失敗した場合、クライアントにエラーレスポンス (例: 502 Bad Gateway) を返します。
-		errResp := &http.Response{
-			StatusCode: http.StatusBadGateway,
-			ProtoMajor: 1, ProtoMinor: 1,
-			Header:     make(http.Header),
-			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("Proxy error: could not connect to origin server %s. Error: %v", clientHTTPReq.URL.Host, err))),
-			Request:    clientHTTPReq,
-		}
-		errResp.Header.Set("Content-Type", "text/plain; charset=utf--8")
-		errResp.Header.Set("X-Proxy-Error", "Origin connection failed")
-		writeErr := errResp.Write(tlsClientConn) // エラーレスポンスをクライアントに送信
-		if writeErr != nil {
-			log.Printf("[HTTPS-MITM Origin] Error writing error response to client: %v", writeErr)
-		}
+		w.Header().Set("X-Proxy-Error", "Origin connection failed")
+		http.Error(w, fmt.Sprintf("Proxy error: could not connect to origin server %s. Error: %v", outReq.URL.Host, err), http.StatusBadGateway)
 		return
 	}
 	defer originResp.Body.Close() // オリジンからのレスポンスボディをクローズ
 
-	log.Printf("[HTTPS-MITM Origin] Received response from origin %s: %s", clientHTTPReq.URL.Host, originResp.Status)
+	log.Printf("[HTTPS-MITM Origin] Received response from origin %s (%s): %s", outReq.URL.Host, originResp.Proto, originResp.Status)
 
 	// STEP 7: オリジンからのレスポンスのキャッシュ保存 (キャッシュが有効かつキャッシュ可能な場合)
 	// レスポンスヘッダーにキャッシュミスを示す情報を追加 (デバッグ用)
 	originResp.Header.Set("X-Proxy-Cache", "MISS")
 	originResp.Header.Set("X-Cache-Key", cacheKey)
 
-	if cacheConfig.Enabled && CanCacheResponse(originResp, clientHTTPReq, cacheConfig) {
-		log.Printf("[HTTPS-MITM Cache] Caching response for %s", cacheKey)
-		// レスポンス全体 (ヘッダーとボディ) をバイト列としてダンプします。
-		// これは、後でキャッシュから読み出す際に http.ReadResponse でパースするためです。
-		respBytes, dumpErr := httputil.DumpResponse(originResp, true)
-		if dumpErr != nil {
-			log.Printf("[HTTPS-MITM Cache] Error dumping response for caching %s: %v", cacheKey, dumpErr)
-		} else {
-			// キャッシュ保存処理は時間がかかる可能性があるため、非同期 (goroutine) で実行することも検討できます。
-			// ただし、現在の実装では同期的に行っています。
-			// DumpResponse でBodyが消費されるため、キャッシュ保存用にレスポンスを複製する
-			clonedResp, readErr := http.ReadResponse(bufio.NewReader(bytes.NewReader(respBytes)), clientHTTPReq)
-			if readErr != nil {
-				log.Printf("[HTTPS-MITM Cache] Error re-reading dumped response for %s: %v", cacheKey, readErr)
-			} else {
-				// StoreResponseInCache はレスポンスボディを読み取るため、goroutine で実行する場合は
-				// clonedResp の Body を適切に扱う（例：再度複製するか、goroutine 内で閉じる）必要がある。
-				// ここでは、StoreResponseInCache が同期的に実行され、その中で Body が閉じられることを期待。
-				// -> StoreResponseInCache に渡す前に clonedResp.Body.Close() を defer で呼ぶべきではない。
-				//    StoreResponseInCache の中で処理される。
-				//    ただし、StoreResponseInCache が goroutine で実行される場合、この clonedResp は goroutine に所有権が移る。
-				//    現在のコードでは、goroutine 内で clonedResp.Body.Close() を呼んでいる。
-				go func(key string, respToCache *http.Response, reqForCache *http.Request, cfg *config.CacheConfig, fullRespBytesToStore []byte) {
-					defer respToCache.Body.Close() // この goroutine 専用のレスポンスボディを閉じる
-					storeErr := StoreResponseInCache(key, respToCache, reqForCache, cfg, fullRespBytesToStore)
-					if storeErr != nil {
-						log.Printf("[HTTPS-MITM Cache] Error storing response for %s in cache: %v", key, storeErr)
-					} else {
-						log.Printf("[HTTPS-MITM Cache] Successfully stored response for %s in cache.", key)
-					}
-				}(cacheKey, clonedResp, clientHTTPReq, cacheConfig, respBytes)
-			}
-		}
+	if h.cacheConfig.Enabled && CanCacheResponse(originResp, outReq, h.cacheConfig) {
+		h.storeInCache(originResp, outReq, cacheKey)
 	}
 
 	// STEP 8: オリジンからのレスポンスをクライアントに送信
-	// ホップバイホップヘッダーは、オリジンからのレスポンスに含まれている場合、クライアントに送る前に削除するのが一般的。
-	// RemoveHopByHopHeaders(originResp.Header) // ここで再度呼ぶか、キャッシュ処理の前に一度だけ呼ぶか検討。
-	// 現在は、キャッシュヒット時とオリジンからのレスポンス時で個別に RemoveHopByHopHeaders を呼んでいる。
-	// オリジンからのレスポンスをそのままクライアントに書き戻します。
-	err = originResp.Write(tlsClientConn) // originResp の内容は変更されていないはず (ヘッダーは追加したが)
+	// ホップバイホップヘッダーは HTTP/2 では禁止されているため、クライアントに送る前に削除します。
+	RemoveHopByHopHeaders(originResp.Header)
+	CopyHeaders(w.Header(), originResp.Header)
+	w.WriteHeader(originResp.StatusCode)
+
+	written, err := copyAndFlush(w, originResp.Body)
 	if err != nil {
 		log.Printf("[HTTPS-MITM Origin] Error writing origin response to client for %s: %v", cacheKey, err)
 	}
 
 	// このリクエスト処理サイクルの完了
-	log.Printf("[HTTPS-MITM] Completed request for %s %s%s", clientHTTPReq.Method, clientHTTPReq.Host, clientHTTPReq.URL.String())
+	log.Printf("[HTTPS-MITM] Completed request for %s %s%s (%d bytes)", outReq.Method, outReq.Host, outReq.URL.Path, written)
+}
 
-	// 注意: 現状の実装では、1つのCONNECTセッションに対して1つのHTTPリクエストを処理して終了します。
-	// クライアントがHTTP Keep-Aliveを期待して同じTLSセッション上で連続してリクエストを送信する場合に対応するには、
-	// STEP 3 (HTTPリクエストの読み取り) から STEP 8 (レスポンスの送信) までをループ処理にする必要があります。
-	// ループは、クライアントが接続を閉じるか、タイムアウトするか、エラーが発生するまで継続します。
+// serveFromCache は新鮮なキャッシュがあればクライアントに返し、true を返します。
+func (h *mitmHandler) serveFromCache(w http.ResponseWriter, req *http.Request, cacheKey string) bool {
+	cachedItem, found, err := RetrieveResponseFromCache(cacheKey, req, h.cacheConfig)
+	if err != nil {
+		log.Printf("[HTTPS-MITM Cache] Error retrieving from cache for %s: %v", cacheKey, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	if !IsCacheFresh(cachedItem, req, h.cacheConfig) { // キャッシュが新鮮か確認
+		log.Printf("[HTTPS-MITM Cache] STALE for key: %s. Fetching from origin.", cacheKey)
+		// TODO: キャッシュが古いが存在する場合、条件付きGET (If-None-Match, If-Modified-Since) を
+		//       オリジンへのリクエストに付加する実装が考えられる。
+		return false
+	}
+
+	log.Printf("[HTTPS-MITM Cache] HIT and FRESH for %s. Serving from cache.", cacheKey)
+	// キャッシュされたレスポンスボディを http.Response オブジェクトにパース
+	cachedResp, readErr := http.ReadResponse(bufio.NewReader(bytes.NewReader(cachedItem.ResponseBody)), req)
+	if readErr != nil {
+		log.Printf("[HTTPS-MITM Cache] Error reading cached response for %s: %v", cacheKey, readErr)
+		return false
+	}
+	defer cachedResp.Body.Close()
+
+	RemoveHopByHopHeaders(cachedResp.Header) // ホップバイホップヘッダーを削除
+	CopyHeaders(w.Header(), cachedResp.Header)
+	w.Header().Set("X-Proxy-Cache", "HIT") // キャッシュヒットを示すヘッダーを追加
+	w.Header().Set("X-Cache-Key", cacheKey)
+	if !cachedItem.ExpiresAt.IsZero() {
+		w.Header().Set("Expires", cachedItem.ExpiresAt.Format(time.RFC1123))
+	}
+	w.WriteHeader(cachedResp.StatusCode)
+
+	if _, err := io.Copy(w, cachedResp.Body); err != nil {
+		log.Printf("[HTTPS-MITM Cache] Error writing cached response to client for %s: %v", cacheKey, err)
+	}
+	return true
+}
+
+// storeInCache はレスポンスをキャッシュに保存します。ボディを読み取るため、originResp.Body は読み取り済みの内容に差し替えます。
+func (h *mitmHandler) storeInCache(originResp *http.Response, req *http.Request, cacheKey string) {
+	log.Printf("[HTTPS-MITM Cache] Caching response for %s", cacheKey)
+
+	// オリジンと HTTP/2 で通信した場合も、キャッシュには HTTP/1.1 のレスポンスとして保存する
+	// (キャッシュから http.ReadResponse でパースして返すため)
+	originResp.Proto, originResp.ProtoMajor, originResp.ProtoMinor = "HTTP/1.1", 1, 1
+
+	// レスポンス全体 (ヘッダーとボディ) をバイト列としてダンプします。
+	// これは、後でキャッシュから読み出す際に http.ReadResponse でパースするためです。
+	// DumpResponse はボディを読み取った後、同じ内容を読めるように originResp.Body を差し替えます。
+	respBytes, dumpErr := httputil.DumpResponse(originResp, true)
+	if dumpErr != nil {
+		log.Printf("[HTTPS-MITM Cache] Error dumping response for caching %s: %v", cacheKey, dumpErr)
+		return
+	}
+
+	// DumpResponse でBodyが消費されるため、キャッシュ保存用にレスポンスを複製する
+	clonedResp, readErr := http.ReadResponse(bufio.NewReader(bytes.NewReader(respBytes)), req)
+	if readErr != nil {
+		log.Printf("[HTTPS-MITM Cache] Error re-reading dumped response for %s: %v", cacheKey, readErr)
+		return
+	}
+
+	// キャッシュ保存処理は時間がかかる可能性があるため、非同期 (goroutine) で実行します。
+	go func(key string, respToCache *http.Response, reqForCache *http.Request, cfg *config.CacheConfig, fullRespBytesToStore []byte) {
+		defer respToCache.Body.Close() // この goroutine 専用のレスポンスボディを閉じる
+		storeErr := StoreResponseInCache(key, respToCache, reqForCache, cfg, fullRespBytesToStore)
+		if storeErr != nil {
+			log.Printf("[HTTPS-MITM Cache] Error storing response for %s in cache: %v", key, storeErr)
+		} else {
+			log.Printf("[HTTPS-MITM Cache] Successfully stored response for %s in cache.", key)
+		}
+	}(cacheKey, clonedResp, req, h.cacheConfig, respBytes)
+}
+
+// copyAndFlush はレスポンスボディをクライアントに書き込み、書き込むたびにフラッシュします。
+// Server-Sent Events のようなストリーミングレスポンスを遅延なく中継するためです。
+func copyAndFlush(w http.ResponseWriter, body io.Reader) (int64, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return io.Copy(w, body)
+	}
+
+	var written int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			m, writeErr := w.Write(buf[:n])
+			written += int64(m)
+			if writeErr != nil {
+				return written, writeErr
+			}
+			flusher.Flush()
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+// errListenerDone は singleConnListener のコネクションの処理が終わったことを示します。
+var errListenerDone = errors.New("single connection listener is done")
+
+// singleConnListener は 1 つのコネクションだけを返す net.Listener です。
+// 復号済みのコネクションを http.Server.Serve に渡すために使い、finish が呼ばれると Accept がエラーを返して Serve が終了します。
+// http.Server が ALPN を見て HTTP/2 を処理できるよう、*tls.Conn はラップせずにそのまま返します。
+type singleConnListener struct {
+	conn       net.Conn
+	accepted   bool
+	mu         sync.Mutex
+	done       chan struct{}
+	finishOnce sync.Once
+}
+
+func newSingleConnListener(conn net.Conn) *singleConnListener {
+	return &singleConnListener{conn: conn, done: make(chan struct{})}
 }
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if !l.accepted {
+		l.accepted = true
+		l.mu.Unlock()
+		return l.conn, nil
+	}
+	l.mu.Unlock()
+
+	<-l.done
+	return nil, errListenerDone
+}
+
+// finish はコネクションの処理が終わったことを通知します。
+func (l *singleConnListener) finish() {
+	l.finishOnce.Do(func() { close(l.done) })
+}
+
+func (l *singleConnListener) Close() error {
+	l.finish()
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr { return l.conn.LocalAddr() }
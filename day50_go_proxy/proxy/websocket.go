@@ -0,0 +1,268 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lirlia/100day_challenge_backend/day50_go_proxy/config"
+)
+
+// IsWebSocketUpgrade はリクエストが WebSocket へのアップグレード要求かどうかを判定します。
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TunnelWebSocket は WebSocket のアップグレード要求をオリジンへ転送し、101 Switching Protocols が返った場合は
+// クライアントとオリジンの間でフレームをそのまま中継します。scheme が "https" の場合はオリジンと TLS で接続します。
+// WebSocket はキャッシュの対象外です。クライアントのコネクションをハイジャックした場合は true を返します。
+func TunnelWebSocket(w http.ResponseWriter, r *http.Request, scheme, host string, wsCfg *config.WebSocketConfig) bool {
+	log.Printf("[WebSocket] Upgrade request for %s://%s%s from %s", scheme, host, r.URL.RequestURI(), r.RemoteAddr)
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		// HTTP/2 のストリームはハイジャックできない (RFC 8441 の拡張 CONNECT は未対応)
+		log.Printf("[WebSocket] Hijacking not supported for %s (proto: %s)", host, r.Proto)
+		http.Error(w, "WebSocket is not supported over this connection", http.StatusNotImplemented)
+		return false
+	}
+
+	originConn, err := dialOrigin(scheme, host)
+	if err != nil {
+		log.Printf("[WebSocket] Error connecting to origin %s: %v", host, err)
+		w.Header().Set("X-Proxy-Error", "Origin connection failed")
+		http.Error(w, fmt.Sprintf("Proxy error: could not connect to origin server %s", host), http.StatusBadGateway)
+		return false
+	}
+	defer originConn.Close()
+
+	// Connection / Upgrade / Sec-WebSocket-* ヘッダーはアップグレードに必要なので残したまま転送する
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	outReq.Host = r.Host
+	if outReq.Host == "" {
+		outReq.Host = host
+	}
+	outReq.Header.Del("Proxy-Connection")
+	outReq.Header.Del("Proxy-Authorization")
+	UpdateXForwardedForHeader(outReq, r.RemoteAddr)
+	if err := outReq.Write(originConn); err != nil {
+		log.Printf("[WebSocket] Error writing upgrade request to origin %s: %v", host, err)
+		http.Error(w, "Error forwarding request to origin server", http.StatusBadGateway)
+		return false
+	}
+
+	originReader := bufio.NewReader(originConn)
+	originResp, err := http.ReadResponse(originReader, outReq)
+	if err != nil {
+		log.Printf("[WebSocket] Error reading upgrade response from origin %s: %v", host, err)
+		http.Error(w, "Error reading response from origin server", http.StatusBadGateway)
+		return false
+	}
+	defer originResp.Body.Close()
+
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		log.Printf("[WebSocket] Hijack error for %s: %v", host, err)
+		return false
+	}
+	defer clientConn.Close()
+
+	// 101 以外 (認証エラーなど) はそのままクライアントに返して終了
+	if err := originResp.Write(clientConn); err != nil {
+		log.Printf("[WebSocket] Error writing upgrade response to client for %s: %v", host, err)
+		return true
+	}
+	if originResp.StatusCode != http.StatusSwitchingProtocols {
+		log.Printf("[WebSocket] Origin %s refused upgrade: %s", host, originResp.Status)
+		return true
+	}
+	log.Printf("[WebSocket] Tunnel established for %s%s", host, r.URL.Path)
+
+	// バッファに読み込み済みのデータも中継するため、bufio.Reader から読み出す
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		relayWebSocket(originConn, clientBuf.Reader, "client->origin", host, wsCfg)
+		closeWrite(originConn)
+	}()
+	go func() {
+		defer wg.Done()
+		relayWebSocket(clientConn, originReader, "origin->client", host, wsCfg)
+		closeWrite(clientConn)
+	}()
+	wg.Wait()
+
+	log.Printf("[WebSocket] Tunnel closed for %s%s", host, r.URL.Path)
+	return true
+}
+
+// dialOrigin はオリジンサーバーに接続します。WebSocket は HTTP/1.1 でアップグレードするため ALPN は http/1.1 のみです。
+func dialOrigin(scheme, host string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 15 * time.Second, KeepAlive: 30 * time.Second}
+	if scheme != "https" {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "80")
+		}
+		return dialer.Dial("tcp", host)
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "443")
+	}
+	serverName, _, _ := net.SplitHostPort(host)
+	return tls.DialWithDialer(dialer, "tcp", host, &tls.Config{
+		ServerName: serverName,
+		NextProtos: []string{"http/1.1"},
+	})
+}
+
+// closeWrite は片方向の中継が終わったことを相手に伝えます (半クローズできない場合は何もしない)。
+func closeWrite(conn net.Conn) {
+	switch c := conn.(type) {
+	case *tls.Conn:
+		c.CloseWrite()
+	case *net.TCPConn:
+		c.CloseWrite()
+	}
+}
+
+// relayWebSocket は src から dst へデータを中継します。フレームログが有効な場合はフレームを解析してログに出力します。
+func relayWebSocket(dst io.Writer, src io.Reader, direction, host string, wsCfg *config.WebSocketConfig) {
+	if wsCfg == nil || !wsCfg.FrameLog {
+		if _, err := io.Copy(dst, src); err != nil && !isClosedConnError(err) {
+			log.Printf("[WebSocket] Relay error (%s) for %s: %v", direction, host, err)
+		}
+		return
+	}
+
+	// 読み込んだバイトはそのまま dst に書き込み、フレームの境界ごとに解析する
+	tee := io.TeeReader(src, dst)
+	for {
+		frame, err := readFrame(tee, wsCfg.FrameLogPayloadBytes)
+		if err != nil {
+			if err != io.EOF && !isClosedConnError(err) {
+				log.Printf("[WebSocket] Relay error (%s) for %s: %v", direction, host, err)
+			}
+			return
+		}
+		log.Printf("[WebSocket Frame] %s %s %s", host, direction, frame)
+	}
+}
+
+// wsFrame はログ出力用の WebSocket フレームの情報です。
+type wsFrame struct {
+	fin     bool
+	opcode  byte
+	masked  bool
+	length  uint64
+	preview []byte
+}
+
+var wsOpcodeNames = map[byte]string{
+	0x0: "continuation",
+	0x1: "text",
+	0x2: "binary",
+	0x8: "close",
+	0x9: "ping",
+	0xA: "pong",
+}
+
+func (f *wsFrame) String() string {
+	name, ok := wsOpcodeNames[f.opcode]
+	if !ok {
+		name = fmt.Sprintf("0x%x", f.opcode)
+	}
+	s := fmt.Sprintf("opcode=%s fin=%t masked=%t len=%d", name, f.fin, f.masked, f.length)
+	if f.opcode == 0x1 && len(f.preview) > 0 && utf8.Valid(f.preview) {
+		s += fmt.Sprintf(" payload=%q", f.preview)
+		if uint64(len(f.preview)) < f.length {
+			s += "..."
+		}
+	}
+	return s
+}
+
+// readFrame は WebSocket フレーム (RFC 6455 5.2) を 1 つ読み込みます。ペイロードは先頭 previewBytes だけを保持します。
+func readFrame(r io.Reader, previewBytes int) (*wsFrame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+
+	f := &wsFrame{
+		fin:    head[0]&0x80 != 0,
+		opcode: head[0] & 0x0F,
+		masked: head[1]&0x80 != 0,
+		length: uint64(head[1] & 0x7F),
+	}
+
+	switch f.length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		f.length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		f.length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	var maskKey [4]byte
+	if f.masked {
+		if _, err := io.ReadFull(r, maskKey[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	n := uint64(previewBytes)
+	if n > f.length {
+		n = f.length
+	}
+	f.preview = make([]byte, n)
+	if _, err := io.ReadFull(r, f.preview); err != nil {
+		return nil, err
+	}
+	if f.masked {
+		// クライアントからのフレームはマスクされているので、ログ用に復元する
+		for i := range f.preview {
+			f.preview[i] ^= maskKey[i%4]
+		}
+	}
+
+	if _, err := io.CopyN(io.Discard, r, int64(f.length-n)); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func isClosedConnError(err error) bool {
+	return errors.Is(err, net.ErrClosed)
+}
@@ -139,6 +139,16 @@ func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// FloatLiteral は浮動小数点数リテラルを表す
+type FloatLiteral struct {
+	Token token.Token // FLOAT トークン
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 // StringLiteral represents a string literal
 type StringLiteral struct {
 	Token token.Token // the token.STRING token
@@ -245,10 +255,10 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
-// CallExpression は関数呼び出し式を表す (puts専用)
+// CallExpression は関数呼び出し式を表す
 type CallExpression struct {
-	Token     token.Token // PUTS トークン
-	Function  Expression  // Identifier
+	Token     token.Token // '(' トークン
+	Function  Expression  // Identifier または FunctionLiteral など
 	Arguments []Expression
 }
 
@@ -269,3 +279,115 @@ func (ce *CallExpression) String() string {
 
 	return out.String()
 }
+
+// FunctionLiteral は関数リテラル fn(x, y) { ... } を表す
+// Name は let で束縛された場合の名前で、関数の中から自分自身を再帰呼び出しするために使う
+type FunctionLiteral struct {
+	Token      token.Token // FUNCTION トークン
+	Parameters []*Identifier
+	Body       *BlockStatement
+	Name       string
+}
+
+func (fl *FunctionLiteral) expressionNode()      {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+	var params []string
+
+	for _, p := range fl.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(fl.TokenLiteral())
+	if fl.Name != "" {
+		out.WriteString("<" + fl.Name + ">")
+	}
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fl.Body.String())
+
+	return out.String()
+}
+
+// ArrayLiteral は配列リテラル [1, 2, 3] を表す
+type ArrayLiteral struct {
+	Token    token.Token // '[' トークン
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+	var elements []string
+
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// IndexExpression は添字アクセス式 array[index] を表す
+type IndexExpression struct {
+	Token token.Token // '[' トークン
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	return "(" + ie.Left.String() + "[" + ie.Index.String() + "])"
+}
+
+// ThrowStatement は throw 文を表す
+type ThrowStatement struct {
+	Token token.Token // THROW トークン
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// TryExpression は try { } catch (e) { } 式を表す
+// Param は省略可能 (catch { } の場合は nil)
+type TryExpression struct {
+	Token   token.Token // TRY トークン
+	Block   *BlockStatement
+	Param   *Identifier
+	Handler *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+	out.WriteString(" catch ")
+	if te.Param != nil {
+		out.WriteString("(" + te.Param.String() + ") ")
+	}
+	out.WriteString(te.Handler.String())
+
+	return out.String()
+}
@@ -169,3 +169,31 @@ func TestTokenLiteral(t *testing.T) {
 		t.Errorf("emptyProgram.TokenLiteral() wrong. got=%q", emptyProgram.TokenLiteral())
 	}
 }
+
+func TestFunctionLiteralString(t *testing.T) {
+	fl := &FunctionLiteral{
+		Token: token.Token{Type: token.FUNCTION, Literal: "fn"},
+		Parameters: []*Identifier{
+			{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+			{Token: token.Token{Type: token.IDENT, Literal: "y"}, Value: "y"},
+		},
+		Body: &BlockStatement{
+			Token: token.Token{Type: token.LBRACE, Literal: "{"},
+			Statements: []Statement{
+				&ExpressionStatement{
+					Token:      token.Token{Type: token.IDENT, Literal: "x"},
+					Expression: &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+				},
+			},
+		},
+	}
+
+	if fl.String() != "fn(x, y) x" {
+		t.Errorf("fl.String() wrong. got=%q", fl.String())
+	}
+
+	fl.Name = "first"
+	if fl.String() != "fn<first>(x, y) x" {
+		t.Errorf("fl.String() with name wrong. got=%q", fl.String())
+	}
+}
@@ -12,18 +12,23 @@ func (ins Instructions) String() string {
 	var out bytes.Buffer
 	i := 0
 	for i < len(ins) {
-		def, err := Lookup(ins[i])
-		if err != nil {
-			fmt.Fprintf(&out, "ERROR: %s\n", err)
-			continue
-		}
-		operands, read := ReadOperands(def, ins[i+1:])
-		fmt.Fprintf(&out, "%04d %s\n", i, ins.fmtInstruction(def, operands))
-		i += 1 + read
+		text, width := ins.Disassemble(i)
+		fmt.Fprintf(&out, "%04d %s\n", i, text)
+		i += width
 	}
 	return out.String()
 }
 
+// Disassemble は pos にある命令をデコードし、その表記と命令のバイト数を返す
+func (ins Instructions) Disassemble(pos int) (string, int) {
+	def, err := Lookup(ins[pos])
+	if err != nil {
+		return fmt.Sprintf("ERROR: %s", err), 1
+	}
+	operands, read := ReadOperands(def, ins[pos+1:])
+	return ins.fmtInstruction(def, operands), 1 + read
+}
+
 func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 	operandCount := len(def.OperandWidths)
 	if len(operands) != operandCount {
@@ -35,10 +40,10 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
-	// case 2: // Current definitions don't have 2 operands, but if added in future:
-	// 	return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
-	// If an opcode has operands but doesn't fit 0 or 1, it's an issue with current definitions
+	// If an opcode has operands but doesn't fit 0, 1 or 2, it's an issue with current definitions
 	// or this formatting function needs updating for more operand counts.
 	if operandCount > 0 {
 	    return fmt.Sprintf("ERROR: unhandled operandCount %d for %s (operands: %v)\n", operandCount, def.Name, operands)
@@ -97,7 +102,28 @@ const (
 	// 組み込み関数呼び出し (puts 専用)
 	OpCallBuiltin // オペランド: 引数の数 (1バイト) - putsは1引数のみ想定
 	OpCallAtoi    // オペランド: 引数の数 (1バイト) - atoi専用
-	OpReturnValue // (今回はreturn文はVMレベルでは特別扱いしないため、もし使うなら)
+	OpReturnValue // スタックトップの値を戻り値として関数から戻る
+
+	// 名前付き組み込み関数 (sqrt, pow など)
+	OpGetBuiltin // オペランド: object.Builtins 内のインデックス (1バイト)
+	OpCall       // オペランド: 引数の数 (1バイト)。スタック上の関数を呼び出す
+
+	// 配列
+	OpArray // オペランド: 要素数 (2バイト)
+	OpIndex // スタック上の [配列, 添字] から要素を取り出す
+
+	// 例外処理
+	OpSetupTry // オペランド: catch ブロックのアドレス (2バイト)。例外ハンドラを登録する
+	OpPopTry   // try ブロックを正常に抜けたときに例外ハンドラを破棄する
+	OpThrow    // スタックトップの値をエラーとして送出する
+
+	// 関数とクロージャ
+	OpGetLocal       // オペランド: ローカル変数のインデックス (1バイト)
+	OpSetLocal       // オペランド: ローカル変数のインデックス (1バイト)
+	OpClosure        // オペランド: 関数定数のインデックス (2バイト), 自由変数の数 (1バイト)
+	OpGetFree        // オペランド: 自由変数のインデックス (1バイト)
+	OpCurrentClosure // 実行中のクロージャ自身を積む (再帰呼び出し用)
+	OpReturn         // 戻り値なしで関数から戻る (null を返す)
 )
 
 type Definition struct {
@@ -128,6 +154,19 @@ var definitions = map[Opcode]*Definition{
 	OpCallBuiltin:   {"OpCallBuiltin", []int{1}},   // 1バイト (引数の数)
 	OpCallAtoi:      {"OpCallAtoi", []int{1}},      // 1バイト (引数の数)
 	OpReturnValue:   {"OpReturnValue", []int{}},
+	OpGetBuiltin:    {"OpGetBuiltin", []int{1}}, // 1バイト (組み込み関数インデックス)
+	OpCall:          {"OpCall", []int{1}},       // 1バイト (引数の数)
+	OpArray:         {"OpArray", []int{2}},      // 2バイト (要素数)
+	OpIndex:         {"OpIndex", []int{}},
+	OpSetupTry:      {"OpSetupTry", []int{2}},   // 2バイト (catch ブロックのアドレス)
+	OpPopTry:        {"OpPopTry", []int{}},
+	OpThrow:         {"OpThrow", []int{}},
+	OpGetLocal:       {"OpGetLocal", []int{1}},    // 1バイト (ローカル変数インデックス)
+	OpSetLocal:       {"OpSetLocal", []int{1}},    // 1バイト (ローカル変数インデックス)
+	OpClosure:        {"OpClosure", []int{2, 1}},  // 2バイト (関数定数インデックス), 1バイト (自由変数の数)
+	OpGetFree:        {"OpGetFree", []int{1}},     // 1バイト (自由変数インデックス)
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpReturn:         {"OpReturn", []int{}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -15,6 +15,8 @@ func TestMake(t *testing.T) {
 		{OpPop, []int{}, []byte{byte(OpPop)}},
 		{OpGetGlobal, []int{255}, []byte{byte(OpGetGlobal), 0, 255}}, // 255 is 0x00FF
 		{OpCallBuiltin, []int{1}, []byte{byte(OpCallBuiltin), 1}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
 	}
 
 	for _, tt := range tests {
@@ -42,6 +44,8 @@ func TestInstructionsString(t *testing.T) {
 		Make(OpPop),
 		Make(OpGetGlobal, 1),
 		Make(OpCallBuiltin, 1),
+		Make(OpGetLocal, 1),
+		Make(OpClosure, 65535, 255),
 	}
 
 	expected := `0000 OpAdd
@@ -50,6 +54,8 @@ func TestInstructionsString(t *testing.T) {
 0007 OpPop
 0008 OpGetGlobal 1
 0011 OpCallBuiltin 1
+0013 OpGetLocal 1
+0015 OpClosure 65535 255
 `
 
 	concatted := Instructions{}
@@ -72,6 +78,8 @@ func TestReadOperands(t *testing.T) {
 		{OpConstant, []int{65535}, 2},
 		{OpGetGlobal, []int{255}, 2},
 		{OpCallBuiltin, []int{1}, 1},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
 	}
 
 	for _, tt := range tests {
@@ -94,3 +102,30 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestDisassemble(t *testing.T) {
+	ins := Instructions{}
+	ins = append(ins, Make(OpConstant, 65534)...)
+	ins = append(ins, Make(OpCall, 2)...)
+	ins = append(ins, Make(OpPop)...)
+
+	tests := []struct {
+		pos           int
+		expectedText  string
+		expectedWidth int
+	}{
+		{0, "OpConstant 65534", 3},
+		{3, "OpCall 2", 2},
+		{5, "OpPop", 1},
+	}
+
+	for _, tt := range tests {
+		text, width := ins.Disassemble(tt.pos)
+		if text != tt.expectedText {
+			t.Errorf("wrong text at %d. want=%q, got=%q", tt.pos, tt.expectedText, text)
+		}
+		if width != tt.expectedWidth {
+			t.Errorf("wrong width at %d. want=%d, got=%d", tt.pos, tt.expectedWidth, width)
+		}
+	}
+}
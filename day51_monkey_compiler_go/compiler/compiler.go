@@ -20,24 +20,44 @@ type EmittedInstruction struct {
 	Position int
 }
 
-// Compiler はASTをバイトコードにコンパイルする
-type Compiler struct {
+// CompilationScope は関数本体ごとの命令列。関数リテラルをコンパイルする間は新しいスコープに命令を発行する
+type CompilationScope struct {
 	instructions        code.Instructions
-	constants           []object.Object
-	symbolTable         *SymbolTable
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
 }
 
+// Compiler はASTをバイトコードにコンパイルする
+type Compiler struct {
+	constants   []object.Object
+	symbolTable *SymbolTable
+
+	scopes     []CompilationScope
+	scopeIndex int
+}
+
 func New() *Compiler {
-	symbolTable := NewSymbolTable()
-	return &Compiler{
+	mainScope := CompilationScope{
 		instructions:        code.Instructions{},
-		constants:           []object.Object{},
-		symbolTable:         symbolTable,
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
 	}
+	symbolTable := NewSymbolTable()
+	DefineBuiltins(symbolTable)
+	return &Compiler{
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		scopes:      []CompilationScope{mainScope},
+		scopeIndex:  0,
+	}
+}
+
+// DefineBuiltins は object.Builtins の組み込み関数をシンボルテーブルに登録する
+// REPL のように NewWithState で状態を引き継ぐ場合は、呼び出し側で一度だけ実行する
+func DefineBuiltins(s *SymbolTable) {
+	for i, v := range object.Builtins {
+		s.DefineBuiltin(i, v.Name)
+	}
 }
 
 func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
@@ -117,9 +137,30 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
 	case *ast.StringLiteral:
 		str := &object.String{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(str))
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.IndexExpression:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -142,7 +183,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.removeLastPop()
 		}
 		jumpPos := c.emit(code.OpJump, 9999)
-		jumpNotTruthyAddress := len(c.instructions)
+		jumpNotTruthyAddress := len(c.currentInstructions())
 		c.changeOperand(jumpNotTruthyPos, jumpNotTruthyAddress)
 		if node.Alternative == nil {
 			c.emit(code.OpNull)
@@ -155,8 +196,35 @@ func (c *Compiler) Compile(node ast.Node) error {
 				c.removeLastPop()
 			}
 		}
-		jumpAddress := len(c.instructions)
+		jumpAddress := len(c.currentInstructions())
 		c.changeOperand(jumpPos, jumpAddress)
+	case *ast.TryExpression:
+		setupTryPos := c.emit(code.OpSetupTry, 9999)
+		err := c.compileBlockValue(node.Block)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpPopTry)
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		// 例外発生時、VM はエラー値をスタックに積んでここへジャンプする
+		c.changeOperand(setupTryPos, len(c.currentInstructions()))
+		if node.Param != nil {
+			c.storeSymbol(c.symbolTable.Define(node.Param.Value))
+		} else {
+			c.emit(code.OpPop)
+		}
+		err = c.compileBlockValue(node.Handler)
+		if err != nil {
+			return err
+		}
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+	case *ast.ThrowStatement:
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
 	case *ast.BlockStatement:
 		for _, s := range node.Statements {
 			err := c.Compile(s)
@@ -169,14 +237,60 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		symbol := c.symbolTable.Define(node.Name.Value)
-		c.emit(code.OpSetGlobal, symbol.Index)
+		c.storeSymbol(c.symbolTable.Define(node.Name.Value))
+	case *ast.ReturnStatement:
+		if c.scopeIndex == 0 {
+			return fmt.Errorf("return statement outside of function")
+		}
+		err := c.Compile(node.ReturnValue)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+	case *ast.FunctionLiteral:
+		c.enterScope()
+
+		if node.Name != "" {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+		for _, p := range node.Parameters {
+			c.symbolTable.Define(p.Value)
+		}
+
+		err := c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		// 本体の最後の式の値を暗黙の戻り値とする。値を持たない本体は null を返す
+		if c.lastInstructionIs(code.OpPop) {
+			c.replaceLastPopWithReturn()
+		}
+		if !c.lastInstructionIs(code.OpReturnValue) {
+			c.emit(code.OpReturn)
+		}
+
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.numDefinitions
+		instructions := c.leaveScope()
+
+		// 捕捉する変数を外側のスコープで積み、OpClosure でクロージャに閉じ込める
+		for _, s := range freeSymbols {
+			c.loadSymbol(s)
+		}
+
+		compiledFn := &object.CompiledFunction{
+			Instructions:  instructions,
+			NumLocals:     numLocals,
+			NumParameters: len(node.Parameters),
+		}
+		c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
 			return fmt.Errorf("undefined variable %s", node.Value)
 		}
-		c.emit(code.OpGetGlobal, symbol.Index)
+		c.loadSymbol(symbol)
 	case *ast.CallExpression:
 		if node.Function.TokenLiteral() == "puts" {
 			for _, arg := range node.Arguments {
@@ -201,19 +315,92 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 			c.emit(code.OpCallAtoi, 1)
 		} else {
-			return fmt.Errorf("unsupported function call: %s", node.Function.TokenLiteral())
+			err := c.Compile(node.Function)
+			if err != nil {
+				return err
+			}
+			for _, arg := range node.Arguments {
+				err := c.Compile(arg)
+				if err != nil {
+					return err
+				}
+			}
+			c.emit(code.OpCall, len(node.Arguments))
 		}
 	}
 	return nil
 }
 
+// compileBlockValue はブロックを式としてコンパイルし、最後の式の値をスタックに残す
+// 値を持たないブロック (空のブロックや let で終わるブロック) は null になる
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	start := len(c.currentInstructions())
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+	if len(c.currentInstructions()) > start && c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+	return nil
+}
+
+// loadSymbol はシンボルのスコープに応じて値をスタックに積む命令を発行する
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	default:
+		c.emit(code.OpGetGlobal, s.Index)
+	}
+}
+
+// storeSymbol はスタックトップの値を let などで定義したシンボルに格納する命令を発行する
+func (c *Compiler) storeSymbol(s Symbol) {
+	if s.Scope == LocalScope {
+		c.emit(code.OpSetLocal, s.Index)
+	} else {
+		c.emit(code.OpSetGlobal, s.Index)
+	}
+}
+
 func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
-		Instructions: c.instructions,
+		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
 	}
 }
 
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// enterScope は関数本体をコンパイルするための命令列とシンボルテーブルを用意する
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, CompilationScope{})
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// leaveScope は関数本体のスコープを抜け、その命令列を返す
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
 func (c *Compiler) addConstant(obj object.Object) int {
 	c.constants = append(c.constants, obj)
 	return len(c.constants) - 1
@@ -229,41 +416,50 @@ func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 }
 
 func (c *Compiler) addInstruction(ins []byte) int {
-	posNewInstruction := len(c.instructions)
-	c.instructions = append(c.instructions, ins...)
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
 	return posNewInstruction
 }
 
 func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
-	previous := c.lastInstruction
+	previous := c.scopes[c.scopeIndex].lastInstruction
 	last := EmittedInstruction{Opcode: op, Position: pos}
 
-	c.previousInstruction = previous
-	c.lastInstruction = last
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
 }
 
 func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
-	if len(c.instructions) == 0 {
+	if len(c.currentInstructions()) == 0 {
 		return false
 	}
-	return c.lastInstruction.Opcode == op
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
 }
 
 func (c *Compiler) removeLastPop() {
 	if c.lastInstructionIs(code.OpPop) {
-		c.instructions = c.instructions[:c.lastInstruction.Position]
-		c.lastInstruction = c.previousInstruction
+		scope := &c.scopes[c.scopeIndex]
+		scope.instructions = scope.instructions[:scope.lastInstruction.Position]
+		scope.lastInstruction = scope.previousInstruction
 	}
 }
 
+// replaceLastPopWithReturn は関数本体の最後の OpPop を OpReturnValue に置き換える
+func (c *Compiler) replaceLastPopWithReturn() {
+	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
+	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
+	c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+}
+
 func (c *Compiler) changeOperand(opPos int, operand int) {
-	op := code.Opcode(c.instructions[opPos])
+	op := code.Opcode(c.currentInstructions()[opPos])
 	newInstruction := code.Make(op, operand)
 	c.replaceInstruction(opPos, newInstruction)
 }
 
 func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
 	for i := 0; i < len(newInstruction); i++ {
-		c.instructions[pos+i] = newInstruction[i]
+		ins[pos+i] = newInstruction[i]
 	}
 }
@@ -0,0 +1,189 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/code"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/object"
+)
+
+// バイトコードファイル (.mkc) のフォーマット
+//
+//	magic       [4]byte  "MKC\x00"
+//	version     uint16
+//	constants   uint32 個数 + 各定数 (タグ 1 byte + 値)
+//	instructions uint32 長さ + 命令列
+//
+// 数値はすべてビッグエンディアン (code.Make と同じ) で書き込む
+// バージョン 2 で関数定数を追加した。関数定数を含まないバージョン 1 のファイルもそのまま読み込める
+const BytecodeVersion uint16 = 2
+
+const minBytecodeVersion uint16 = 1
+
+var bytecodeMagic = []byte{'M', 'K', 'C', 0}
+
+// 定数プールに格納される定数の種類
+const (
+	constInteger byte = iota + 1
+	constFloat
+	constString
+	constFunction // ローカル変数の数 uint16 + 引数の数 uint8 + 命令列 (uint32 長さ + 命令列)
+)
+
+// IsBytecode はデータがバイトコードファイルの形式かどうかをマジックナンバーで判定する
+func IsBytecode(data []byte) bool {
+	return bytes.HasPrefix(data, bytecodeMagic)
+}
+
+// WriteTo はバイトコードをバイナリ形式で書き込む
+func (b *Bytecode) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	buf.Write(bytecodeMagic)
+	binary.Write(&buf, binary.BigEndian, BytecodeVersion)
+
+	binary.Write(&buf, binary.BigEndian, uint32(len(b.Constants)))
+	for i, c := range b.Constants {
+		switch c := c.(type) {
+		case *object.Integer:
+			buf.WriteByte(constInteger)
+			binary.Write(&buf, binary.BigEndian, c.Value)
+		case *object.Float:
+			buf.WriteByte(constFloat)
+			binary.Write(&buf, binary.BigEndian, math.Float64bits(c.Value))
+		case *object.String:
+			buf.WriteByte(constString)
+			binary.Write(&buf, binary.BigEndian, uint32(len(c.Value)))
+			buf.WriteString(c.Value)
+		case *object.CompiledFunction:
+			buf.WriteByte(constFunction)
+			binary.Write(&buf, binary.BigEndian, uint16(c.NumLocals))
+			buf.WriteByte(byte(c.NumParameters))
+			binary.Write(&buf, binary.BigEndian, uint32(len(c.Instructions)))
+			buf.Write(c.Instructions)
+		default:
+			return 0, fmt.Errorf("constant %d: cannot serialize %s", i, c.Type())
+		}
+	}
+
+	binary.Write(&buf, binary.BigEndian, uint32(len(b.Instructions)))
+	buf.Write(b.Instructions)
+
+	return buf.WriteTo(w)
+}
+
+// ReadBytecode は WriteTo で書き込まれたバイトコードを読み込む
+func ReadBytecode(r io.Reader) (*Bytecode, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(bytecodeMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic, bytecodeMagic) {
+		return nil, fmt.Errorf("not a monkey bytecode file")
+	}
+
+	var version uint16
+	if err := binary.Read(br, binary.BigEndian, &version); err != nil {
+		return nil, fmt.Errorf("failed to read version: %w", err)
+	}
+	if version < minBytecodeVersion || version > BytecodeVersion {
+		return nil, fmt.Errorf("unsupported bytecode version %d (expected %d..%d)", version, minBytecodeVersion, BytecodeVersion)
+	}
+
+	var numConstants uint32
+	if err := binary.Read(br, binary.BigEndian, &numConstants); err != nil {
+		return nil, fmt.Errorf("failed to read constants: %w", err)
+	}
+
+	// 個数や長さはファイルの値をそのまま信用せず、事前に確保しない (壊れたファイルで巨大な確保をしないため)
+	var constants []object.Object
+	for i := uint32(0); i < numConstants; i++ {
+		c, err := readConstant(br)
+		if err != nil {
+			return nil, fmt.Errorf("constant %d: %w", i, err)
+		}
+		constants = append(constants, c)
+	}
+
+	var numInstructions uint32
+	if err := binary.Read(br, binary.BigEndian, &numInstructions); err != nil {
+		return nil, fmt.Errorf("failed to read instructions: %w", err)
+	}
+	instructions, err := readBytes(br, numInstructions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instructions: %w", err)
+	}
+
+	return &Bytecode{
+		Instructions: code.Instructions(instructions),
+		Constants:    constants,
+	}, nil
+}
+
+func readConstant(r io.Reader) (object.Object, error) {
+	var tag [1]byte
+	if _, err := io.ReadFull(r, tag[:]); err != nil {
+		return nil, err
+	}
+
+	switch tag[0] {
+	case constInteger:
+		var v int64
+		if err := binary.Read(r, binary.BigEndian, &v); err != nil {
+			return nil, err
+		}
+		return &object.Integer{Value: v}, nil
+	case constFloat:
+		var bits uint64
+		if err := binary.Read(r, binary.BigEndian, &bits); err != nil {
+			return nil, err
+		}
+		return &object.Float{Value: math.Float64frombits(bits)}, nil
+	case constString:
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		s, err := readBytes(r, n)
+		if err != nil {
+			return nil, err
+		}
+		return &object.String{Value: string(s)}, nil
+	case constFunction:
+		var header struct {
+			NumLocals     uint16
+			NumParameters uint8
+			Length        uint32
+		}
+		if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+			return nil, err
+		}
+		instructions, err := readBytes(r, header.Length)
+		if err != nil {
+			return nil, err
+		}
+		return &object.CompiledFunction{
+			Instructions:  code.Instructions(instructions),
+			NumLocals:     int(header.NumLocals),
+			NumParameters: int(header.NumParameters),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown constant tag %d", tag[0])
+	}
+}
+
+// readBytes は n バイトを読み込む。実際に読めた分だけバッファを伸ばすため、n が壊れていても読める量以上は確保しない
+func readBytes(r io.Reader, n uint32) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
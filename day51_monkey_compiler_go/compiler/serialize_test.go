@@ -0,0 +1,166 @@
+package compiler
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/code"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/object"
+)
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	input := `let a = 1; let b = 2.5; let s = "hello, monkey"; if (a < 2) { puts(s) } else { puts(sqrt(b)) }`
+
+	comp := New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := comp.Bytecode()
+
+	var buf bytes.Buffer
+	if _, err := original.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %s", err)
+	}
+	if !IsBytecode(buf.Bytes()) {
+		t.Fatalf("serialized data is not recognized as bytecode")
+	}
+
+	loaded, err := ReadBytecode(&buf)
+	if err != nil {
+		t.Fatalf("ReadBytecode failed: %s", err)
+	}
+
+	if err := testInstructions([]code.Instructions{original.Instructions}, loaded.Instructions); err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+
+	expectedConstants := []interface{}{1, 2.5, "hello, monkey", 2}
+	if err := testConstants(t, expectedConstants, loaded.Constants); err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+}
+
+func TestBytecodeRoundTripFunctions(t *testing.T) {
+	input := `let add = fn(a) { fn(b) { a + b } }; sort([3, 1], fn(x, y) { x > y })`
+
+	comp := New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	original := comp.Bytecode()
+
+	var buf bytes.Buffer
+	if _, err := original.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %s", err)
+	}
+	loaded, err := ReadBytecode(&buf)
+	if err != nil {
+		t.Fatalf("ReadBytecode failed: %s", err)
+	}
+
+	if len(loaded.Constants) != len(original.Constants) {
+		t.Fatalf("wrong number of constants. got=%d, want=%d", len(loaded.Constants), len(original.Constants))
+	}
+	for i, c := range original.Constants {
+		fn, ok := c.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		got, ok := loaded.Constants[i].(*object.CompiledFunction)
+		if !ok {
+			t.Fatalf("constant %d - object is not CompiledFunction. got=%T", i, loaded.Constants[i])
+		}
+		if got.NumLocals != fn.NumLocals || got.NumParameters != fn.NumParameters {
+			t.Errorf("constant %d - wrong locals/parameters. got=%d/%d, want=%d/%d",
+				i, got.NumLocals, got.NumParameters, fn.NumLocals, fn.NumParameters)
+		}
+		if err := testInstructions([]code.Instructions{fn.Instructions}, got.Instructions); err != nil {
+			t.Errorf("constant %d - testInstructions failed: %s", i, err)
+		}
+	}
+}
+
+func TestReadBytecodeVersion1(t *testing.T) {
+	comp := New()
+	if err := comp.Compile(parse(`let a = 1; a + 2`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := comp.Bytecode().WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %s", err)
+	}
+	// 関数定数を含まないファイルはバージョン 1 と同じ形式になる
+	data := buf.Bytes()
+	data[5] = 1
+
+	loaded, err := ReadBytecode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadBytecode failed for version 1: %s", err)
+	}
+	if err := testConstants(t, []interface{}{1, 2}, loaded.Constants); err != nil {
+		t.Fatalf("testConstants failed: %s", err)
+	}
+}
+
+func TestReadBytecodeErrors(t *testing.T) {
+	valid := &Bytecode{
+		Instructions: code.Make(code.OpConstant, 0),
+		Constants:    []object.Object{&object.Integer{Value: 1}},
+	}
+	var buf bytes.Buffer
+	if _, err := valid.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %s", err)
+	}
+	data := buf.Bytes()
+
+	wrongVersion := append([]byte{}, data...)
+	wrongVersion[5] = 99
+
+	// ヘッダーの後に個数・長さとして最大値だけがある壊れたファイル
+	header := data[:len(bytecodeMagic)+2]
+	hugeConstants := append(append([]byte{}, header...), 0xff, 0xff, 0xff, 0xff)
+	hugeInstructions := append(append([]byte{}, header...), 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff)
+	hugeString := append(append([]byte{}, header...), 0, 0, 0, 1, constString, 0xff, 0xff, 0xff, 0xff)
+	hugeFunction := append(append([]byte{}, header...), 0, 0, 0, 1, constFunction, 0, 0, 0, 0xff, 0xff, 0xff, 0xff)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not bytecode", []byte("let a = 1;")},
+		{"wrong version", wrongVersion},
+		{"truncated", data[:len(data)-1]},
+		{"huge constant count", hugeConstants},
+		{"huge instructions length", hugeInstructions},
+		{"huge string length", hugeString},
+		{"huge function length", hugeFunction},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadBytecode(bytes.NewReader(tt.data)); err == nil {
+			t.Errorf("%s: expected error, got nil", tt.name)
+		}
+	}
+
+	// 壊れた個数・長さから事前に確保しないことを確認する (確保すれば 4 GiB 近くになる)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for _, data := range [][]byte{hugeConstants, hugeInstructions, hugeString, hugeFunction} {
+		ReadBytecode(bytes.NewReader(data))
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("ReadBytecode allocated %d bytes for truncated files, expected no allocation based on the header", allocated)
+	}
+}
+
+func TestWriteUnsupportedConstant(t *testing.T) {
+	bytecode := &Bytecode{
+		Constants: []object.Object{&object.Boolean{Value: true}},
+	}
+	if _, err := bytecode.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Errorf("expected error for unsupported constant, got nil")
+	}
+}
@@ -1,12 +1,16 @@
 package compiler
 
+import "sort"
+
 // SymbolScope はシンボルのスコープを表す
 type SymbolScope string
 
 const (
-	GlobalScope SymbolScope = "GLOBAL"
-	// LocalScope  SymbolScope = "LOCAL" // 簡略版では未使用
-	// BuiltinScope SymbolScope = "BUILTIN" // 簡略版では未使用
+	GlobalScope   SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	BuiltinScope  SymbolScope = "BUILTIN"
+	FreeScope     SymbolScope = "FREE"     // 外側の関数のローカル変数をクロージャが捕捉したもの
+	FunctionScope SymbolScope = "FUNCTION" // 実行中の関数自身 (let で束縛した関数の再帰呼び出し用)
 )
 
 // Symbol はシンボルテーブル内の各エントリを表す
@@ -17,11 +21,15 @@ type Symbol struct {
 }
 
 // SymbolTable はシンボルとその情報を格納する
-// 簡略版ではグローバルスコープのみを扱う
+// 関数の本体は外側のテーブルを Outer に持つテーブルで管理し、トップレベルのテーブルがグローバルスコープになる
 type SymbolTable struct {
+	Outer *SymbolTable
+
 	store          map[string]Symbol
 	numDefinitions int
-	// outer *SymbolTable // 簡略版では未使用 (ローカルスコープ用)
+
+	// FreeSymbols はこのスコープの関数が捕捉する外側のシンボル (元のスコープのまま)
+	FreeSymbols []Symbol
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -29,17 +37,86 @@ func NewSymbolTable() *SymbolTable {
 	return &SymbolTable{store: s}
 }
 
+// NewEnclosedSymbolTable は関数本体用に outer の内側のシンボルテーブルを作成する
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
 // Define は新しいシンボルを定義する
-// 簡略版では常にグローバルスコープとして定義する
+// トップレベルのテーブルではグローバル、関数本体のテーブルではローカルのシンボルになる
 func (s *SymbolTable) Define(name string) Symbol {
 	symbol := Symbol{Name: name, Index: s.numDefinitions, Scope: GlobalScope}
+	if s.Outer != nil {
+		symbol.Scope = LocalScope
+	}
 	s.store[name] = symbol
 	s.numDefinitions++
 	return symbol
 }
 
+// DefineBuiltin は組み込み関数を BuiltinScope のシンボルとして定義する
+// index は object.Builtins 内の位置で、グローバル変数のインデックスは消費しない
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineFunctionName は関数自身の名前を FunctionScope のシンボルとして定義する
+// ローカル変数のインデックスは消費しない
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// defineFree は外側の関数のシンボルを自由変数として登録する
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.store[original.Name] = symbol
+	return symbol
+}
+
 // Resolve は指定された名前のシンボルを解決する
+// 外側の関数のローカル変数 (または自由変数) であれば、このスコープの自由変数として登録して返す
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := s.store[name]
-	return obj, ok
+	if ok || s.Outer == nil {
+		return obj, ok
+	}
+
+	obj, ok = s.Outer.Resolve(name)
+	if !ok {
+		return obj, ok
+	}
+	if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+		return obj, ok
+	}
+	return s.defineFree(obj), true
+}
+
+// GlobalSymbols はグローバル変数のシンボルをインデックス順に返す
+func (s *SymbolTable) GlobalSymbols() []Symbol {
+	var symbols []Symbol
+	for _, symbol := range s.store {
+		if symbol.Scope == GlobalScope {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Index < symbols[j].Index })
+	return symbols
+}
+
+// Clone はシンボルテーブルのコピーを返す
+// REPL で実行せずにコンパイルだけ行う場合に、元のテーブルを変更しないために使用する
+func (s *SymbolTable) Clone() *SymbolTable {
+	store := make(map[string]Symbol, len(s.store))
+	for name, symbol := range s.store {
+		store[name] = symbol
+	}
+	return &SymbolTable{Outer: s.Outer, store: store, numDefinitions: s.numDefinitions}
 }
@@ -49,3 +49,128 @@ func TestResolveGlobal(t *testing.T) {
 		t.Errorf("name 'nonExistent' resolved, but should not have")
 	}
 }
+
+func TestCloneAndGlobalSymbols(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "sqrt")
+	global.Define("a")
+	global.Define("b")
+
+	clone := global.Clone()
+	c := clone.Define("c")
+	if c.Index != 2 {
+		t.Errorf("clone should continue numbering. got=%d, want=2", c.Index)
+	}
+	if _, ok := global.Resolve("c"); ok {
+		t.Errorf("defining on clone must not modify the original table")
+	}
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "b", Scope: GlobalScope, Index: 1},
+		{Name: "c", Scope: GlobalScope, Index: 2},
+	}
+	symbols := clone.GlobalSymbols()
+	if len(symbols) != len(expected) {
+		t.Fatalf("wrong number of global symbols. got=%d, want=%d", len(symbols), len(expected))
+	}
+	for i, sym := range expected {
+		if symbols[i] != sym {
+			t.Errorf("expected %+v, got=%+v", sym, symbols[i])
+		}
+	}
+}
+
+func TestResolveLocal(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineBuiltin(0, "sqrt")
+	global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("c")
+
+	expected := []Symbol{
+		{Name: "sqrt", Scope: BuiltinScope, Index: 0},
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "c", Scope: LocalScope, Index: 0},
+	}
+
+	for _, sym := range expected {
+		result, ok := local.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+	if len(local.FreeSymbols) != 0 {
+		t.Errorf("globals and builtins must not be free symbols. got=%+v", local.FreeSymbols)
+	}
+}
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+
+	expected := []Symbol{
+		{Name: "a", Scope: GlobalScope, Index: 0},
+		{Name: "c", Scope: FreeScope, Index: 0},
+		{Name: "e", Scope: LocalScope, Index: 0},
+	}
+	for _, sym := range expected {
+		result, ok := secondLocal.Resolve(sym.Name)
+		if !ok {
+			t.Errorf("name %s not resolvable", sym.Name)
+			continue
+		}
+		if result != sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+		}
+	}
+
+	expectedFree := []Symbol{{Name: "c", Scope: LocalScope, Index: 0}}
+	if len(secondLocal.FreeSymbols) != len(expectedFree) || secondLocal.FreeSymbols[0] != expectedFree[0] {
+		t.Errorf("wrong free symbols. got=%+v, want=%+v", secondLocal.FreeSymbols, expectedFree)
+	}
+
+	if _, ok := secondLocal.Resolve("x"); ok {
+		t.Errorf("name 'x' resolved, but should not have")
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+
+	expected := Symbol{Name: "a", Scope: FunctionScope, Index: 0}
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestShadowingFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	global.Define("a")
+
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 0}
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
@@ -1,6 +1,10 @@
 package lexer
 
-import "github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/token"
+import (
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/token"
+)
 
 type Lexer struct {
 	input        string
@@ -84,6 +88,10 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 	// String literals
@@ -100,8 +108,11 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok // readIdentifier() calls readChar(), so early return here
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
 			tok.Literal = l.readNumber()
+			tok.Type = token.INT
+			if strings.Contains(tok.Literal, ".") {
+				tok.Type = token.FLOAT
+			}
 			return tok // readNumber() calls readChar(), so early return here
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -126,11 +137,19 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
+// readNumber は整数または小数 (例: 3.14) を読み取る
 func (l *Lexer) readNumber() string {
 	position := l.position
 	for isDigit(l.ch) {
 		l.readChar()
 	}
+	// 小数点の後に数字が続く場合のみ小数として扱う
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
 	return l.input[position:l.position]
 }
 
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
+	"strings"
 
 	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/compiler"
 	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/lexer"
@@ -14,26 +17,86 @@ import (
 )
 
 func main() {
-	if len(os.Args) > 2 {
-		fmt.Fprintln(os.Stderr, "Usage: ./day51_monkey_compiler_go [script_file]")
-		os.Exit(1)
+	args := os.Args[1:]
+
+	switch {
+	case len(args) == 0: // 引数がない場合はREPLを起動
+		startRepl()
+	case args[0] == "build":
+		buildFile(args[1:])
+	case args[0] == "run":
+		if len(args) != 2 {
+			usage()
+		}
+		executeFile(args[1])
+	case len(args) == 1: // ファイルパスが引数として与えられた場合
+		executeFile(args[0])
+	default:
+		usage()
 	}
+}
 
-	if len(os.Args) == 2 { // ファイルパスが引数として与えられた場合
-		filePath := os.Args[1]
-		executeFile(filePath)
-	} else { // 引数がない場合はREPLを起動
-		currentUser, err := user.Current()
-		if err != nil {
-			// REPL起動時のユーザー名取得エラーは致命的ではないかもしれない
-			// panic(err) // またはエラーメッセージ表示してデフォルト名を使うなど
-			fmt.Fprintf(os.Stderr, "Warning: could not get current user: %v\n", err)
-			fmt.Println("Hello! This is the Monkey programming language with Compiler and VM! (REPL mode)")
-		} else {
-			fmt.Printf("Hello %s! This is the Monkey programming language with Compiler and VM! (REPL mode)\n", currentUser.Username)
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  monkey                               REPL を起動")
+	fmt.Fprintln(os.Stderr, "  monkey [run] <script_file|bytecode>  スクリプトまたはバイトコードを実行")
+	fmt.Fprintln(os.Stderr, "  monkey build <script_file> [-o out]  バイトコード (.mkc) にコンパイル")
+	os.Exit(1)
+}
+
+func startRepl() {
+	currentUser, err := user.Current()
+	if err != nil {
+		// REPL起動時のユーザー名取得エラーは致命的ではないかもしれない
+		// panic(err) // またはエラーメッセージ表示してデフォルト名を使うなど
+		fmt.Fprintf(os.Stderr, "Warning: could not get current user: %v\n", err)
+		fmt.Println("Hello! This is the Monkey programming language with Compiler and VM! (REPL mode)")
+	} else {
+		fmt.Printf("Hello %s! This is the Monkey programming language with Compiler and VM! (REPL mode)\n", currentUser.Username)
+	}
+	fmt.Printf("Feel free to type in commands\n")
+	repl.Start(os.Stdin, os.Stdout)
+}
+
+// buildFile はスクリプトをコンパイルし、バイトコードをファイルに書き出す
+func buildFile(args []string) {
+	var srcPath, outPath string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-o" && i+1 < len(args):
+			outPath = args[i+1]
+			i++
+		case srcPath == "":
+			srcPath = args[i]
+		default:
+			usage()
 		}
-		fmt.Printf("Feel free to type in commands\n")
-		repl.Start(os.Stdin, os.Stdout)
+	}
+	if srcPath == "" {
+		usage()
+	}
+	if outPath == "" {
+		outPath = strings.TrimSuffix(srcPath, filepath.Ext(srcPath)) + ".mkc"
+	}
+
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file %s: %s\n", srcPath, err)
+		os.Exit(1)
+	}
+
+	bytecode := compileSource(string(data))
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating file %s: %s\n", outPath, err)
+		os.Exit(1)
+	}
+	defer out.Close()
+
+	if _, err := bytecode.WriteTo(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Writing bytecode failed:\n %s\n", err)
+		os.Exit(1)
 	}
 }
 
@@ -47,7 +110,36 @@ func executeFile(filePath string) {
 	// fmt.Fprintf(os.Stderr, "[DEBUG] Successfully read file. Data length: %d\n", len(data))
 	// fmt.Fprintf(os.Stderr, "[DEBUG] File content: %s\n", string(data)) // 内容が長い可能性があるので注意
 
-	l := lexer.New(string(data))
+	var bytecode *compiler.Bytecode
+	if compiler.IsBytecode(data) {
+		// コンパイル済みのバイトコードは字句解析・構文解析・コンパイルを省略する
+		bytecode, err = compiler.ReadBytecode(bytes.NewReader(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Loading bytecode failed:\n %s\n", err)
+			os.Exit(1)
+		}
+	} else {
+		bytecode = compileSource(string(data))
+	}
+
+	// ファイル実行時はグローバル変数のストアも新規作成
+	globals := make([]object.Object, vm.GlobalsSize)
+	machine := vm.NewWithGlobalsStore(bytecode, globals)
+
+	err = machine.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Executing bytecode failed:\n %s\n", err)
+		os.Exit(1)
+	}
+
+	// ファイル実行の場合、明示的な puts による出力以外は行わない。
+	// スクリプトの最後の式の値は表示しない。
+	// エラーがなければ正常終了とする。
+}
+
+// compileSource はソースコードをバイトコードにコンパイルする。エラー時は終了する
+func compileSource(input string) *compiler.Bytecode {
+	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 
@@ -59,25 +151,13 @@ func executeFile(filePath string) {
 		os.Exit(1)
 	}
 
-	// ファイル実行時は状態を共有しない新しいコンパイラとVMインスタンスを使用
+	// ファイル実行時は状態を共有しない新しいコンパイラを使用
 	comp := compiler.New()
-	err = comp.Compile(program)
+	err := comp.Compile(program)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Compilation failed:\n %s\n", err)
 		os.Exit(1)
 	}
 
-	// ファイル実行時はグローバル変数のストアも新規作成
-	globals := make([]object.Object, vm.GlobalsSize)
-	machine := vm.NewWithGlobalsStore(comp.Bytecode(), globals)
-
-	err = machine.Run()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Executing bytecode failed:\n %s\n", err)
-		os.Exit(1)
-	}
-
-	// ファイル実行の場合、明示的な puts による出力以外は行わない。
-	// スクリプトの最後の式の値は表示しない。
-	// エラーがなければ正常終了とする。
+	return comp.Bytecode()
 }
@@ -0,0 +1,536 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Builtins は名前付きの組み込み関数の一覧
+// インデックスは OpGetBuiltin のオペランドとして使われるため、順序を変更しないこと
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{"sqrt", &Builtin{Fn: builtinSqrt}},
+	{"pow", &Builtin{Fn: builtinPow}},
+	{"abs", &Builtin{Fn: builtinAbs}},
+	{"floor", &Builtin{Fn: builtinFloor}},
+	{"ceil", &Builtin{Fn: builtinCeil}},
+	{"int", &Builtin{Fn: builtinInt}},
+	{"float", &Builtin{Fn: builtinFloat}},
+	{"len", &Builtin{Fn: builtinLen}},
+	{"split", &Builtin{Fn: builtinSplit}},
+	{"join", &Builtin{Fn: builtinJoin}},
+	{"replace", &Builtin{Fn: builtinReplace}},
+	{"contains", &Builtin{Fn: builtinContains}},
+	{"toUpper", &Builtin{Fn: builtinToUpper}},
+	{"toLower", &Builtin{Fn: builtinToLower}},
+	{"map", &Builtin{Fn: builtinMap}},
+	{"filter", &Builtin{Fn: builtinFilter}},
+	{"reduce", &Builtin{Fn: builtinReduce}},
+	{"sort", &Builtin{Fn: builtinSort}},
+	{"error", &Builtin{Fn: builtinError}},
+	{"errorKind", &Builtin{Fn: builtinErrorKind}},
+	{"errorMessage", &Builtin{Fn: builtinErrorMessage}},
+}
+
+// GetBuiltinByName は名前から組み込み関数を取得する
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+// ToFloat は数値オブジェクト (Integer / Float) を float64 に変換する
+func ToFloat(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
+// IsTruthy はMonkey言語の条件評価における真偽を決定します。
+// - false, null は偽 (falsey)
+// - 整数 0 と 0.0 は偽 (falsey)
+// - それ以外は全て真 (truthy)
+func IsTruthy(obj Object) bool {
+	switch val := obj.(type) {
+	case *Boolean:
+		return val.Value // Trueならtrue, Falseならfalse
+	case *Null:
+		return false
+	case *Integer:
+		return val.Value != 0 // 0ならfalse, それ以外ならtrue
+	case *Float:
+		return val.Value != 0
+	default:
+		// その他の型（エラーオブジェクトなど、もしあれば）は真として扱う
+		return true
+	}
+}
+
+func newError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
+
+func newTypeError(format string, a ...interface{}) *Error {
+	return &Error{Kind: ErrorKindType, Message: fmt.Sprintf(format, a...)}
+}
+
+func newArgumentError(format string, a ...interface{}) *Error {
+	return &Error{Kind: ErrorKindArgument, Message: fmt.Sprintf(format, a...)}
+}
+
+// numericArg は単一の数値引数を検証して float64 で返す
+func numericArg(name string, args []Object) (float64, *Error) {
+	if len(args) != 1 {
+		return 0, newArgumentError("wrong number of arguments to `%s`. got=%d, want=1", name, len(args))
+	}
+	value, ok := ToFloat(args[0])
+	if !ok {
+		return 0, newTypeError("argument to `%s` must be INTEGER or FLOAT, got %s", name, args[0].Type())
+	}
+	return value, nil
+}
+
+func builtinSqrt(args ...Object) Object {
+	value, err := numericArg("sqrt", args)
+	if err != nil {
+		return err
+	}
+	if value < 0 {
+		return newError("argument to `sqrt` must not be negative, got %s", args[0].Inspect())
+	}
+	return &Float{Value: math.Sqrt(value)}
+}
+
+// builtinPow は整数同士 (指数が0以上) の場合は整数、それ以外は浮動小数点数を返す
+func builtinPow(args ...Object) Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments to `pow`. got=%d, want=2", len(args))
+	}
+
+	base, baseIsInt := args[0].(*Integer)
+	exp, expIsInt := args[1].(*Integer)
+	if baseIsInt && expIsInt && exp.Value >= 0 {
+		result := int64(1)
+		for i := int64(0); i < exp.Value; i++ {
+			result *= base.Value
+		}
+		return &Integer{Value: result}
+	}
+
+	x, ok := ToFloat(args[0])
+	if !ok {
+		return newTypeError("first argument to `pow` must be INTEGER or FLOAT, got %s", args[0].Type())
+	}
+	y, ok := ToFloat(args[1])
+	if !ok {
+		return newTypeError("second argument to `pow` must be INTEGER or FLOAT, got %s", args[1].Type())
+	}
+	return &Float{Value: math.Pow(x, y)}
+}
+
+func builtinAbs(args ...Object) Object {
+	if _, err := numericArg("abs", args); err != nil {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *Integer:
+		if arg.Value < 0 {
+			return &Integer{Value: -arg.Value}
+		}
+		return arg
+	default:
+		return &Float{Value: math.Abs(arg.(*Float).Value)}
+	}
+}
+
+func builtinFloor(args ...Object) Object {
+	value, err := numericArg("floor", args)
+	if err != nil {
+		return err
+	}
+	return &Integer{Value: int64(math.Floor(value))}
+}
+
+func builtinCeil(args ...Object) Object {
+	value, err := numericArg("ceil", args)
+	if err != nil {
+		return err
+	}
+	return &Integer{Value: int64(math.Ceil(value))}
+}
+
+// builtinInt は小数部を切り捨てて整数に変換する
+func builtinInt(args ...Object) Object {
+	value, err := numericArg("int", args)
+	if err != nil {
+		return err
+	}
+	return &Integer{Value: int64(value)}
+}
+
+func builtinFloat(args ...Object) Object {
+	value, err := numericArg("float", args)
+	if err != nil {
+		return err
+	}
+	return &Float{Value: value}
+}
+
+func nativeBool(b bool) *Boolean {
+	return &Boolean{Value: b}
+}
+
+// stringArgs は引数がすべて文字列であることを検証して値を返す
+func stringArgs(name string, args []Object, want int) ([]string, *Error) {
+	if len(args) != want {
+		return nil, newArgumentError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)
+	}
+	values := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*String)
+		if !ok {
+			return nil, newTypeError("argument %d to `%s` must be STRING, got %s", i+1, name, arg.Type())
+		}
+		values[i] = str.Value
+	}
+	return values, nil
+}
+
+// arrayArg は最初の引数が配列であることを検証する
+func arrayArg(name string, args []Object, min, max int) (*Array, *Error) {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return nil, newArgumentError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), min)
+		}
+		return nil, newArgumentError("wrong number of arguments to `%s`. got=%d, want=%d..%d", name, len(args), min, max)
+	}
+	array, ok := args[0].(*Array)
+	if !ok {
+		return nil, newTypeError("first argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	return array, nil
+}
+
+// callable は関数として呼び出せるオブジェクトを Go の関数に変換する
+// クロージャは VM が実行用の組み込み関数に包んで渡すため、ここでは組み込み関数のみ受け付ける
+func callable(name string, obj Object) (BuiltinFunction, *Error) {
+	builtin, ok := obj.(*Builtin)
+	if !ok {
+		return nil, newTypeError("function argument to `%s` must be callable, got %s", name, obj.Type())
+	}
+	return builtin.Fn, nil
+}
+
+func isError(obj Object) bool {
+	return obj != nil && obj.Type() == ERROR_OBJ
+}
+
+func builtinLen(args ...Object) Object {
+	if len(args) != 1 {
+		return newArgumentError("wrong number of arguments to `len`. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *String:
+		return &Integer{Value: int64(len([]rune(arg.Value)))}
+	case *Array:
+		return &Integer{Value: int64(len(arg.Elements))}
+	default:
+		return newTypeError("argument to `len` not supported, got %s", args[0].Type())
+	}
+}
+
+func builtinSplit(args ...Object) Object {
+	values, err := stringArgs("split", args, 2)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(values[0], values[1])
+	elements := make([]Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &String{Value: part}
+	}
+	return &Array{Elements: elements}
+}
+
+// builtinJoin は要素を Inspect で文字列化して連結する
+func builtinJoin(args ...Object) Object {
+	array, err := arrayArg("join", args, 2, 2)
+	if err != nil {
+		return err
+	}
+	sep, ok := args[1].(*String)
+	if !ok {
+		return newTypeError("second argument to `join` must be STRING, got %s", args[1].Type())
+	}
+
+	parts := make([]string, len(array.Elements))
+	for i, el := range array.Elements {
+		parts[i] = el.Inspect()
+	}
+	return &String{Value: strings.Join(parts, sep.Value)}
+}
+
+func builtinReplace(args ...Object) Object {
+	values, err := stringArgs("replace", args, 3)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.ReplaceAll(values[0], values[1], values[2])}
+}
+
+// builtinContains は文字列の部分一致、または配列に値が含まれるかを判定する
+func builtinContains(args ...Object) Object {
+	if len(args) != 2 {
+		return newArgumentError("wrong number of arguments to `contains`. got=%d, want=2", len(args))
+	}
+
+	switch container := args[0].(type) {
+	case *String:
+		sub, ok := args[1].(*String)
+		if !ok {
+			return newTypeError("second argument to `contains` must be STRING, got %s", args[1].Type())
+		}
+		return nativeBool(strings.Contains(container.Value, sub.Value))
+	case *Array:
+		for _, el := range container.Elements {
+			if equals(el, args[1]) {
+				return nativeBool(true)
+			}
+		}
+		return nativeBool(false)
+	default:
+		return newTypeError("first argument to `contains` must be STRING or ARRAY, got %s", args[0].Type())
+	}
+}
+
+// equals は値として等しいかどうかを判定する (整数と浮動小数点数は数値として比較)
+func equals(a, b Object) bool {
+	if x, ok := ToFloat(a); ok {
+		y, ok := ToFloat(b)
+		return ok && x == y
+	}
+
+	switch a := a.(type) {
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	default:
+		return a == b
+	}
+}
+
+func builtinToUpper(args ...Object) Object {
+	values, err := stringArgs("toUpper", args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.ToUpper(values[0])}
+}
+
+func builtinToLower(args ...Object) Object {
+	values, err := stringArgs("toLower", args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.ToLower(values[0])}
+}
+
+// builtinMap は map(array, fn) で各要素に fn を適用した新しい配列を返す
+func builtinMap(args ...Object) Object {
+	array, err := arrayArg("map", args, 2, 2)
+	if err != nil {
+		return err
+	}
+	fn, err := callable("map", args[1])
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Object, len(array.Elements))
+	for i, el := range array.Elements {
+		result := fn(el)
+		if isError(result) {
+			return result
+		}
+		elements[i] = result
+	}
+	return &Array{Elements: elements}
+}
+
+// builtinFilter は filter(array, fn) で fn の結果が真となる要素だけを残す
+func builtinFilter(args ...Object) Object {
+	array, err := arrayArg("filter", args, 2, 2)
+	if err != nil {
+		return err
+	}
+	fn, err := callable("filter", args[1])
+	if err != nil {
+		return err
+	}
+
+	elements := []Object{}
+	for _, el := range array.Elements {
+		result := fn(el)
+		if isError(result) {
+			return result
+		}
+		if IsTruthy(result) {
+			elements = append(elements, el)
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+// builtinReduce は reduce(array, fn[, initial]) で fn(acc, element) を順に適用する
+// initial を省略した場合は最初の要素を初期値とする
+func builtinReduce(args ...Object) Object {
+	array, err := arrayArg("reduce", args, 2, 3)
+	if err != nil {
+		return err
+	}
+	fn, err := callable("reduce", args[1])
+	if err != nil {
+		return err
+	}
+
+	elements := array.Elements
+	var acc Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(elements) == 0 {
+			return newError("`reduce` of empty array with no initial value")
+		}
+		acc, elements = elements[0], elements[1:]
+	}
+
+	for _, el := range elements {
+		acc = fn(acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// builtinSort は sort(array[, less]) で整列した新しい配列を返す
+// less を省略した場合は数値または文字列の昇順で整列する
+func builtinSort(args ...Object) Object {
+	array, err := arrayArg("sort", args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	less := naturalLess
+	if len(args) == 2 {
+		fn, err := callable("sort", args[1])
+		if err != nil {
+			return err
+		}
+		less = func(a, b Object) (bool, *Error) {
+			result := fn(a, b)
+			if e, ok := result.(*Error); ok {
+				return false, e
+			}
+			return IsTruthy(result), nil
+		}
+	}
+
+	elements := make([]Object, len(array.Elements))
+	copy(elements, array.Elements)
+
+	var sortErr *Error
+	sort.SliceStable(elements, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		result, err := less(elements[i], elements[j])
+		if err != nil {
+			sortErr = err
+		}
+		return result
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+	return &Array{Elements: elements}
+}
+
+// naturalLess は数値同士または文字列同士を比較する
+func naturalLess(a, b Object) (bool, *Error) {
+	if x, ok := ToFloat(a); ok {
+		if y, ok := ToFloat(b); ok {
+			return x < y, nil
+		}
+	}
+	if x, ok := a.(*String); ok {
+		if y, ok := b.(*String); ok {
+			return x.Value < y.Value, nil
+		}
+	}
+	return false, newTypeError("`sort` cannot compare %s and %s without a comparator", a.Type(), b.Type())
+}
+
+// builtinError は error(message[, kind]) でエラー値を生成する
+func builtinError(args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newArgumentError("wrong number of arguments to `error`. got=%d, want=1..2", len(args))
+	}
+	values, err := stringArgs("error", args, len(args))
+	if err != nil {
+		return err
+	}
+
+	kind := ErrorKindError
+	if len(values) == 2 {
+		kind = values[1]
+	}
+	return &ErrorValue{Kind: kind, Message: values[0]}
+}
+
+// errorValueArg は単一のエラー値引数を検証する
+func errorValueArg(name string, args []Object) (*ErrorValue, *Error) {
+	if len(args) != 1 {
+		return nil, newArgumentError("wrong number of arguments to `%s`. got=%d, want=1", name, len(args))
+	}
+	value, ok := args[0].(*ErrorValue)
+	if !ok {
+		return nil, newTypeError("argument to `%s` must be ERROR_VALUE, got %s", name, args[0].Type())
+	}
+	return value, nil
+}
+
+func builtinErrorKind(args ...Object) Object {
+	value, err := errorValueArg("errorKind", args)
+	if err != nil {
+		return err
+	}
+	return &String{Value: value.Kind}
+}
+
+func builtinErrorMessage(args ...Object) Object {
+	value, err := errorValueArg("errorMessage", args)
+	if err != nil {
+		return err
+	}
+	return &String{Value: value.Message}
+}
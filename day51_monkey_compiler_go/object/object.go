@@ -1,16 +1,29 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/code"
+)
 
 type ObjectType string
 
 const (
 	INTEGER_OBJ     = "INTEGER"
+	FLOAT_OBJ       = "FLOAT"
 	BOOLEAN_OBJ     = "BOOLEAN"
 	NULL_OBJ        = "NULL"
 	BUILTIN_OBJ     = "BUILTIN"
 	STRING_OBJ      = "STRING"
+	ARRAY_OBJ       = "ARRAY"
+	ERROR_VALUE_OBJ = "ERROR_VALUE"
 	ERROR_OBJ       = "ERROR"
+
+	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ           = "CLOSURE"
 )
 
 type Object interface {
@@ -26,6 +39,21 @@ type Integer struct {
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+// Float は浮動小数点数オブジェクト
+type Float struct {
+	Value float64
+}
+
+// Inspect は整数値の場合も小数点付きで表示する (例: 2.0)
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEnN") { // NaN, +Inf は除外
+		s += ".0"
+	}
+	return s
+}
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+
 // Boolean は真偽値オブジェクト
 type Boolean struct {
 	Value bool
@@ -42,14 +70,70 @@ type Null struct{}
 func (n *Null) Inspect() string  { return "null" }
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 
+// エラーの種類 (Error.Kind / ErrorValue.Kind)
+const (
+	ErrorKindError        = "Error" // error() で種類を省略した場合
+	ErrorKindRuntime      = "RuntimeError"
+	ErrorKindType         = "TypeError"
+	ErrorKindIndex        = "IndexError"
+	ErrorKindArgument     = "ArgumentError"
+	ErrorKindZeroDivision = "ZeroDivisionError"
+)
+
 // Error はエラーオブジェクト
+// 組み込み関数がこれを返すと VM の実行時エラーとして扱われる
 type Error struct {
+	Kind    string // 空の場合は ErrorKindRuntime
 	Message string
 }
 
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 
+// ErrorValue は Monkey のコードから扱えるエラー値
+// error() で生成して throw で送出するほか、catch で捕捉した実行時エラーもこの値になる
+type ErrorValue struct {
+	Kind    string
+	Message string
+}
+
+func (e *ErrorValue) Inspect() string  { return e.Kind + ": " + e.Message }
+func (e *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+
+// BuiltinFunction は組み込み関数の実装
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin は組み込み関数オブジェクト
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Inspect() string  { return "builtin function" }
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+
+// CompiledFunction はコンパイル済みの関数リテラル。定数プールに格納される
+type CompiledFunction struct {
+	Instructions  code.Instructions
+	NumLocals     int // 引数を含むローカル変数の数
+	NumParameters int
+}
+
+func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
+func (cf *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", cf)
+}
+
+// Closure は実行時の関数値。CompiledFunction と捕捉した自由変数の値を持つ
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+func (c *Closure) Type() ObjectType { return CLOSURE_OBJ }
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
 // Bytecode はコンパイル結果を表す
 type Bytecode struct {
 	Instructions Instructions
@@ -75,3 +159,28 @@ type String struct {
 
 func (s *String) Inspect() string  { return s.Value }
 func (s *String) Type() ObjectType { return STRING_OBJ }
+
+// Array は配列オブジェクト
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		if str, ok := e.(*String); ok {
+			elements = append(elements, strconv.Quote(str.Value))
+			continue
+		}
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
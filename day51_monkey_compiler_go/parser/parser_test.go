@@ -131,6 +131,37 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "3.25;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d",
+			len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != 3.25 {
+		t.Errorf("literal.Value not %f. got=%f", 3.25, literal.Value)
+	}
+	if literal.TokenLiteral() != "3.25" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "3.25",
+			literal.TokenLiteral())
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input        string
@@ -271,6 +302,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"a * [1, 2][b * c] * d",
+			"((a * ([1, 2][(b * c)])) * d)",
+		},
+		{
+			"len([a * b])[0]",
+			"(len([(a * b)])[0])",
+		},
 		{
 			"!-a",
 			"(!(-a))",
@@ -659,6 +698,237 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("len(array.Elements) not 3. got=%d", len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingEmptyArrayLiteral(t *testing.T) {
+	l := lexer.New("[]")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	input := `try { x } catch (err) { y }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.TryExpression. got=%T", stmt.Expression)
+	}
+
+	if len(exp.Block.Statements) != 1 {
+		t.Fatalf("try block is not 1 statement. got=%d", len(exp.Block.Statements))
+	}
+	block := exp.Block.Statements[0].(*ast.ExpressionStatement)
+	if !testIdentifier(t, block.Expression, "x") {
+		return
+	}
+
+	if exp.Param == nil || exp.Param.Value != "err" {
+		t.Fatalf("catch param is not 'err'. got=%v", exp.Param)
+	}
+
+	if len(exp.Handler.Statements) != 1 {
+		t.Fatalf("catch block is not 1 statement. got=%d", len(exp.Handler.Statements))
+	}
+	handler := exp.Handler.Statements[0].(*ast.ExpressionStatement)
+	testIdentifier(t, handler.Expression, "y")
+}
+
+func TestTryExpressionWithoutParam(t *testing.T) {
+	l := lexer.New(`try { x } catch { }`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.TryExpression. got=%T", stmt.Expression)
+	}
+	if exp.Param != nil {
+		t.Errorf("catch param should be nil. got=%v", exp.Param)
+	}
+	if len(exp.Handler.Statements) != 0 {
+		t.Errorf("catch block should be empty. got=%d", len(exp.Handler.Statements))
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	l := lexer.New(`throw error("x");`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+	if stmt.String() != `throw error("x");` {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	l := lexer.New(`fn(x, y) { x + y; }`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(function.Parameters))
+	}
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statement. got=%d", len(function.Body.Statements))
+	}
+	body := function.Body.Statements[0].(*ast.ExpressionStatement)
+	testInfixExpression(t, body.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{input: "fn() {};", expectedParams: []string{}},
+		{input: "fn(x) {};", expectedParams: []string{"x"}},
+		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Errorf("length parameters wrong. want %d, got=%d", len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+	}
+}
+
+func TestFunctionLiteralWithName(t *testing.T) {
+	l := lexer.New(`let myFunction = fn() { };`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not *ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+	if function.Name != "myFunction" {
+		t.Errorf("function literal name wrong. want 'myFunction', got=%q", function.Name)
+	}
+}
+
+func TestReturnStatement(t *testing.T) {
+	l := lexer.New(`fn(x) { return x * 2; }`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	function := stmt.Expression.(*ast.FunctionLiteral)
+	returnStmt, ok := function.Body.Statements[0].(*ast.ReturnStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ReturnStatement. got=%T", function.Body.Statements[0])
+	}
+	testInfixExpression(t, returnStmt.ReturnValue, "x", "*", 2)
+}
+
+func TestCallExpressionWithFunctionLiteral(t *testing.T) {
+	l := lexer.New(`sort(a, fn(x, y) { x > y })`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	if stmt.String() != "sort(a, fn(x, y) (x > y))" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -0,0 +1,356 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/code"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/compiler"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/lexer"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/object"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/parser"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/vm"
+)
+
+const debugHelp = `Commands:
+  :disasm <expr>    コンパイル結果の命令列を表示 (実行はしない)
+  :stack            VM のスタックを表示 (上がスタックトップ)
+  :globals          グローバル変数の一覧を表示
+  :break [<const>]  定数を読み込む OpConstant でブレークする (引数なしで一覧表示)
+  :clear [<const>]  ブレークポイントを削除 (引数なしで全削除)
+  :step [<expr>]    式をステップ実行で開始 / 停止中の命令を1つ実行
+  :continue         次のブレークポイントまで実行
+  :abort            停止中の実行を中断
+  :help             このヘルプを表示
+`
+
+// session は REPL セッション全体で共有される状態
+type session struct {
+	out         io.Writer
+	constants   []object.Object
+	symbolTable *compiler.SymbolTable
+	globals     []object.Object
+
+	// breakpoints は OpConstant で読み込まれたときに停止する定数 (Inspect 表記)
+	breakpoints map[string]bool
+	// paused はブレークポイントまたはステップ実行で停止中の VM
+	paused *vm.VM
+	// last は最後に実行した VM (:stack で参照する)
+	last *vm.VM
+}
+
+func newSession(out io.Writer) *session {
+	symbolTable := compiler.NewSymbolTable()
+	compiler.DefineBuiltins(symbolTable)
+
+	return &session{
+		out:         out,
+		constants:   []object.Object{},
+		symbolTable: symbolTable,
+		globals:     make([]object.Object, vm.GlobalsSize),
+		breakpoints: map[string]bool{},
+	}
+}
+
+// compile は入力をコンパイルする。persist が false の場合はセッションの状態を変更しない
+func (s *session) compile(line string, persist bool) (*compiler.Bytecode, bool) {
+	l := lexer.New(line)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil, false
+	}
+
+	symbolTable := s.symbolTable
+	constants := s.constants
+	if !persist {
+		symbolTable = symbolTable.Clone()
+		constants = append([]object.Object{}, constants...)
+	}
+
+	// REPLでは、前回の状態を引き継ぐコンパイラを使用
+	comp := compiler.NewWithState(symbolTable, constants)
+	err := comp.Compile(program)
+	if err != nil {
+		fmt.Fprintf(s.out, "Woops! Compilation failed:\n %s\n", err)
+		return nil, false
+	}
+
+	if persist {
+		// コンパイル結果（特に定数プール）を次のイテレーションのために更新
+		s.constants = comp.Bytecode().Constants
+	}
+	return comp.Bytecode(), true
+}
+
+// eval は通常の入力をコンパイルして実行する。ブレークポイントに到達した場合は停止する
+func (s *session) eval(line string) {
+	if s.paused != nil {
+		fmt.Fprintln(s.out, "execution is paused. use :step, :continue or :abort")
+		return
+	}
+
+	bytecode, ok := s.compile(line, true)
+	if !ok {
+		return
+	}
+
+	// REPLでは、前回のグローバル変数の状態を引き継ぐVMを使用
+	machine := vm.NewWithGlobalsStore(bytecode, s.globals)
+	s.last = machine
+	s.resume(machine, false)
+}
+
+// resume はブレークポイントに到達するか実行が終わるまで VM を実行する
+// skipFirst が true の場合、現在の命令ではブレークしない (停止位置からの再開用)
+func (s *session) resume(machine *vm.VM, skipFirst bool) {
+	for !machine.Done() {
+		if !skipFirst && s.atBreakpoint(machine) {
+			s.paused = machine
+			fmt.Fprintf(s.out, "breakpoint hit\n")
+			s.printNext(machine)
+			return
+		}
+		skipFirst = false
+
+		if err := machine.Step(); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+	s.finish(machine)
+}
+
+// atBreakpoint は次の命令がブレークポイントに設定された定数を読み込むかどうかを返す
+func (s *session) atBreakpoint(machine *vm.VM) bool {
+	if len(s.breakpoints) == 0 {
+		return false
+	}
+
+	ins := machine.Instructions()
+	ip := machine.IP()
+	if code.Opcode(ins[ip]) != code.OpConstant {
+		return false
+	}
+
+	index := int(code.ReadUint16(ins[ip+1:]))
+	return index < len(s.constants) && s.breakpoints[s.constants[index].Inspect()]
+}
+
+func (s *session) finish(machine *vm.VM) {
+	s.paused = nil
+	printResult(s.out, machine)
+}
+
+func (s *session) fail(err error) {
+	s.paused = nil
+	fmt.Fprintf(s.out, "Woops! Executing bytecode failed:\n %s\n", err)
+}
+
+// command は ":" で始まる REPL コマンドを処理する
+func (s *session) command(line string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":help":
+		io.WriteString(s.out, debugHelp)
+	case ":disasm":
+		s.disasm(arg)
+	case ":stack":
+		s.printStack()
+	case ":globals":
+		s.printGlobals()
+	case ":break":
+		s.setBreakpoint(arg)
+	case ":clear":
+		s.clearBreakpoint(arg)
+	case ":step":
+		s.step(arg)
+	case ":continue":
+		if s.paused == nil {
+			fmt.Fprintln(s.out, "not paused")
+			return
+		}
+		s.resume(s.paused, true)
+	case ":abort":
+		if s.paused == nil {
+			fmt.Fprintln(s.out, "not paused")
+			return
+		}
+		s.paused = nil
+		fmt.Fprintln(s.out, "aborted")
+	default:
+		fmt.Fprintf(s.out, "unknown command %s (type :help)\n", name)
+	}
+}
+
+func (s *session) disasm(input string) {
+	if input == "" {
+		fmt.Fprintln(s.out, "usage: :disasm <expr>")
+		return
+	}
+
+	bytecode, ok := s.compile(input, false)
+	if !ok {
+		return
+	}
+
+	ins := bytecode.Instructions
+	for ip := 0; ip < len(ins); {
+		text, width := s.describe(ins, ip, bytecode.Constants)
+		fmt.Fprintf(s.out, "%04d %s\n", ip, text)
+		ip += width
+	}
+}
+
+// describe は命令をデコードし、定数や組み込み関数の場合は参照先を注記する
+func (s *session) describe(ins code.Instructions, ip int, constants []object.Object) (string, int) {
+	text, width := ins.Disassemble(ip)
+
+	switch code.Opcode(ins[ip]) {
+	case code.OpConstant:
+		index := int(code.ReadUint16(ins[ip+1:]))
+		if index < len(constants) {
+			text += fmt.Sprintf("\t; %s", inspectLiteral(constants[index]))
+		}
+	case code.OpGetBuiltin:
+		index := int(code.ReadUint8(ins[ip+1:]))
+		if index < len(object.Builtins) {
+			text += fmt.Sprintf("\t; %s", object.Builtins[index].Name)
+		}
+	}
+	return text, width
+}
+
+func (s *session) printStack() {
+	machine := s.paused
+	if machine == nil {
+		machine = s.last
+	}
+	if machine == nil || len(machine.Stack()) == 0 {
+		fmt.Fprintln(s.out, "(empty)")
+		return
+	}
+
+	stack := machine.Stack()
+	for i := len(stack) - 1; i >= 0; i-- {
+		fmt.Fprintf(s.out, "%4d %s\n", i, inspectLiteral(stack[i]))
+	}
+}
+
+func (s *session) printGlobals() {
+	symbols := s.symbolTable.GlobalSymbols()
+	if len(symbols) == 0 {
+		fmt.Fprintln(s.out, "(none)")
+		return
+	}
+
+	for _, symbol := range symbols {
+		value := "<unset>"
+		if obj := s.globals[symbol.Index]; obj != nil {
+			value = inspectLiteral(obj)
+		}
+		fmt.Fprintf(s.out, "%4d %s = %s\n", symbol.Index, symbol.Name, value)
+	}
+}
+
+func (s *session) setBreakpoint(arg string) {
+	if arg == "" {
+		if len(s.breakpoints) == 0 {
+			fmt.Fprintln(s.out, "no breakpoints")
+			return
+		}
+		var keys []string
+		for k := range s.breakpoints {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(s.out, "break on constant %s\n", k)
+		}
+		return
+	}
+
+	s.breakpoints[unquote(arg)] = true
+	fmt.Fprintf(s.out, "break on constant %s\n", arg)
+}
+
+func (s *session) clearBreakpoint(arg string) {
+	if arg == "" {
+		s.breakpoints = map[string]bool{}
+		fmt.Fprintln(s.out, "all breakpoints cleared")
+		return
+	}
+	delete(s.breakpoints, unquote(arg))
+	fmt.Fprintf(s.out, "breakpoint %s cleared\n", arg)
+}
+
+// step は引数があれば新しい実行をステップ実行で開始し、なければ停止中の VM を1命令進める
+func (s *session) step(input string) {
+	if input != "" {
+		if s.paused != nil {
+			fmt.Fprintln(s.out, "execution is paused. use :continue or :abort first")
+			return
+		}
+		bytecode, ok := s.compile(input, true)
+		if !ok {
+			return
+		}
+		machine := vm.NewWithGlobalsStore(bytecode, s.globals)
+		s.last = machine
+		if machine.Done() {
+			s.finish(machine)
+			return
+		}
+		s.paused = machine
+		s.printNext(machine)
+		return
+	}
+
+	machine := s.paused
+	if machine == nil {
+		fmt.Fprintln(s.out, "not paused. usage: :step <expr>")
+		return
+	}
+
+	text, _ := s.describe(machine.Instructions(), machine.IP(), s.constants)
+	ip := machine.IP()
+	if err := machine.Step(); err != nil {
+		s.fail(err)
+		return
+	}
+	fmt.Fprintf(s.out, "  %04d %s\n", ip, text)
+
+	if machine.Done() {
+		s.finish(machine)
+		return
+	}
+	s.printNext(machine)
+}
+
+// printNext は次に実行される命令を表示する
+func (s *session) printNext(machine *vm.VM) {
+	text, _ := s.describe(machine.Instructions(), machine.IP(), s.constants)
+	fmt.Fprintf(s.out, "=> %04d %s\n", machine.IP(), text)
+}
+
+// inspectLiteral は文字列を引用符付きで表示し、数値や真偽値と区別できるようにする
+func inspectLiteral(obj object.Object) string {
+	if str, ok := obj.(*object.String); ok {
+		return strconv.Quote(str.Value)
+	}
+	return obj.Inspect()
+}
+
+func unquote(s string) string {
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
+	return s
+}
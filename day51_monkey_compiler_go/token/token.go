@@ -14,6 +14,7 @@ const (
 	// 識別子 + リテラル
 	IDENT = "IDENT" // add, foobar, x, y, ...
 	INT   = "INT"   // 1343456
+	FLOAT = "FLOAT" // 3.14
 
 	// 演算子
 	ASSIGN   = "="
@@ -38,30 +39,38 @@ const (
 	RPAREN = ")"
 	LBRACE = "{"
 	RBRACE = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	STRING = "STRING" // Added for string literals
 
 	// キーワード
-	// FUNCTION = "FUNCTION" // 削除
+	FUNCTION = "FUNCTION"
 	LET = "LET"
 	TRUE    = "TRUE"
 	FALSE   = "FALSE"
 	IF      = "IF"
 	ELSE    = "ELSE"
-	// RETURN   = "RETURN" // 削除
+	RETURN   = "RETURN"
 	NULL = "NULL"
+	TRY   = "TRY"
+	CATCH = "CATCH"
+	THROW = "THROW"
 	// PUTS     = "PUTS" // 削除
 )
 
 var keywords = map[string]TokenType{
-	// "fn":     FUNCTION, // 削除
+	"fn":     FUNCTION,
 	"let":   LET,
 	"true":  TRUE,
 	"false": FALSE,
 	"if":    IF,
 	"else":  ELSE,
-	// "return": RETURN, // 削除
+	"return": RETURN,
 	"null":  NULL,
+	"try":   TRY,
+	"catch": CATCH,
+	"throw": THROW,
 	// "puts":   PUTS, // 削除
 }
 
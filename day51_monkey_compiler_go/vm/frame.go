@@ -0,0 +1,21 @@
+package vm
+
+import (
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/code"
+	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/object"
+)
+
+// Frame は関数呼び出し1回分の実行状態
+type Frame struct {
+	cl          *object.Closure
+	ip          int // 次に実行する命令の位置
+	basePointer int // ローカル変数の先頭。呼び出し前のスタックポインタ
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{cl: cl, ip: 0, basePointer: basePointer}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
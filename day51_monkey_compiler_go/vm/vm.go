@@ -15,7 +15,7 @@ import (
 const (
 	StackSize   = 2048
 	GlobalsSize = 65536
-	// MaxFrames   = 1024 // フレーム管理は行わないため不要
+	MaxFrames   = 1024
 )
 
 var (
@@ -24,30 +24,83 @@ var (
 	Null  = &object.Null{}
 )
 
+// handler は OpSetupTry で登録される例外ハンドラ
+type handler struct {
+	catchIP     int // catch ブロックの開始位置
+	sp          int // try 開始時のスタックポインタ (例外発生時に巻き戻す)
+	framesIndex int // try を実行したフレームの深さ (例外発生時にこのフレームまで戻る)
+}
+
+// RuntimeError は種類 (Kind) 付きの実行時エラー
+// try/catch で捕捉されると Value が catch の変数に束縛される
+type RuntimeError struct {
+	Value *object.ErrorValue
+}
+
+func (e *RuntimeError) Error() string { return e.Value.Inspect() }
+
+func newRuntimeError(kind, format string, a ...interface{}) *RuntimeError {
+	return &RuntimeError{Value: &object.ErrorValue{Kind: kind, Message: fmt.Sprintf(format, a...)}}
+}
+
 type VM struct {
-	constants   []object.Object
-	instructions code.Instructions
+	constants []object.Object
 
 	stack []object.Object
 	sp    int // スタックポインタ: 常にスタックの次の空きスロットを指す。スタックトップは stack[sp-1]
 
 	globals []object.Object
 
+	frames      []*Frame
+	framesIndex int // 実行中のフレームの数。frames[framesIndex-1] が実行中のフレーム
+
+	handlers []handler // try ブロックの例外ハンドラ (内側が末尾)
+
 	// REPLなどで最後のポップされた要素を検査するために使用
 	lastPoppedStackElem object.Object
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
+	// トップレベルのプログラムも引数なしの関数として main フレームで実行する
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFrame := NewFrame(&object.Closure{Fn: mainFn}, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
 	return &VM{
-		constants:    bytecode.Constants,
-		instructions: bytecode.Instructions,
+		constants: bytecode.Constants,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		globals: make([]object.Object, GlobalsSize),
+
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
 
-		stack:        make([]object.Object, StackSize),
-		sp:           0,
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("stack overflow: too many nested function calls (max %d)", MaxFrames)
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
 
-		globals:      make([]object.Object, GlobalsSize),
-		// フレーム関連の初期化は削除
+// popFrame は実行中のフレームを破棄する。そのフレーム内で登録された例外ハンドラも破棄する
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].framesIndex > vm.framesIndex {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
 	}
+	return vm.frames[vm.framesIndex]
 }
 
 func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
@@ -83,183 +136,514 @@ func (vm *VM) pop() object.Object {
 }
 
 func (vm *VM) Run() error {
-	var ip int
-	var ins code.Instructions
-	var op code.Opcode
-
-	// フレーム管理がなくなったため、vm.instructions を直接参照
-	for ip < len(vm.instructions) {
-		op = code.Opcode(vm.instructions[ip])
-		ins = vm.instructions
-
-		switch op {
-		case code.OpConstant:
-			constIndex := code.ReadUint16(ins[ip+1:])
-			ip += 2
-			err := vm.push(vm.constants[constIndex])
-			if err != nil {
-				return err
-			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
-			err := vm.executeBinaryOperation(op)
-			if err != nil {
-				return err
-			}
-		case code.OpTrue:
-			err := vm.push(True)
-			if err != nil {
-				return err
-			}
-		case code.OpFalse:
-			err := vm.push(False)
-			if err != nil {
-				return err
-			}
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
-			err := vm.executeComparison(op)
-			if err != nil {
-				return err
-			}
-		case code.OpBang:
-			err := vm.executeBangOperator()
-			if err != nil {
-				return err
-			}
-		case code.OpMinus:
-			err := vm.executeMinusOperator()
+	for !vm.Done() {
+		if err := vm.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Done はトップレベルの命令をすべて実行し終えたかどうかを返す
+func (vm *VM) Done() bool {
+	frame := vm.currentFrame()
+	return vm.framesIndex == 1 && frame.ip >= len(frame.Instructions())
+}
+
+// IP は実行中のフレームで次に実行する命令の位置を返す
+func (vm *VM) IP() int {
+	return vm.currentFrame().ip
+}
+
+// Instructions は実行中のフレームの命令列を返す
+func (vm *VM) Instructions() code.Instructions {
+	return vm.currentFrame().Instructions()
+}
+
+// Stack は現在スタックに積まれている要素を底から順に返す
+func (vm *VM) Stack() []object.Object {
+	return vm.stack[:vm.sp]
+}
+
+// Step は命令を1つだけ実行する。デバッガのステップ実行で使用する
+// 実行時エラーは、例外ハンドラが登録されていれば catch ブロックへの分岐に変換される
+func (vm *VM) Step() error {
+	return vm.step(0)
+}
+
+// step は命令を1つ実行し、minHandlers 個より後に登録された例外ハンドラでのみエラーを捕捉する
+// 組み込み関数から呼び出したクロージャの実行中に、呼び出し元の try で捕捉しないようにするため
+func (vm *VM) step(minHandlers int) error {
+	err := vm.execute()
+	if err == nil || len(vm.handlers) <= minHandlers {
+		return err
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.framesIndex = h.framesIndex
+	vm.sp = h.sp
+	vm.currentFrame().ip = h.catchIP
+	return vm.push(errorValue(err))
+}
+
+// errorValue は実行時エラーを catch で受け取るエラー値に変換する
+func errorValue(err error) *object.ErrorValue {
+	if rtErr, ok := err.(*RuntimeError); ok {
+		return rtErr.Value
+	}
+	return &object.ErrorValue{Kind: object.ErrorKindRuntime, Message: err.Error()}
+}
+
+// execute は実行中のフレームの ip の位置の命令を1つ実行する
+func (vm *VM) execute() error {
+	frame := vm.currentFrame()
+	ip := frame.ip
+	ins := frame.Instructions()
+	op := code.Opcode(ins[ip])
+
+	switch op {
+	case code.OpConstant:
+		constIndex := code.ReadUint16(ins[ip+1:])
+		ip += 2
+		err := vm.push(vm.constants[constIndex])
+		if err != nil {
+			return err
+		}
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		err := vm.executeBinaryOperation(op)
+		if err != nil {
+			return err
+		}
+	case code.OpTrue:
+		err := vm.push(True)
+		if err != nil {
+			return err
+		}
+	case code.OpFalse:
+		err := vm.push(False)
+		if err != nil {
+			return err
+		}
+	case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
+		err := vm.executeComparison(op)
+		if err != nil {
+			return err
+		}
+	case code.OpBang:
+		err := vm.executeBangOperator()
+		if err != nil {
+			return err
+		}
+	case code.OpMinus:
+		err := vm.executeMinusOperator()
+		if err != nil {
+			return err
+		}
+	case code.OpPop:
+		popped := vm.pop()
+		vm.lastPoppedStackElem = popped
+	case code.OpJump:
+		jumpPos := int(code.ReadUint16(ins[ip+1:]))
+		ip = jumpPos - 1
+	case code.OpJumpNotTruthy:
+		jumpPos := int(code.ReadUint16(ins[ip+1:]))
+		ip += 2
+		condition := vm.pop()
+		if !vm.isTruthy(condition) {
+			ip = jumpPos - 1
+		}
+	case code.OpNull:
+		err := vm.push(Null)
+		if err != nil {
+			return err
+		}
+	case code.OpSetGlobal:
+		globalIndex := code.ReadUint16(ins[ip+1:])
+		ip += 2
+		vm.globals[globalIndex] = vm.pop()
+	case code.OpGetGlobal:
+		globalIndex := code.ReadUint16(ins[ip+1:])
+		ip += 2
+		val := vm.globals[globalIndex]
+		if val == nil {
+			err := vm.push(Null)
 			if err != nil {
 				return err
 			}
-		case code.OpPop:
-			popped := vm.pop()
-			vm.lastPoppedStackElem = popped
-		case code.OpJump:
-			jumpPos := int(code.ReadUint16(ins[ip+1:]))
-			ip = jumpPos - 1
-		case code.OpJumpNotTruthy:
-			jumpPos := int(code.ReadUint16(ins[ip+1:]))
-			ip += 2
-			condition := vm.pop()
-			if !vm.isTruthy(condition) {
-				ip = jumpPos - 1
-			}
-		case code.OpNull:
-			err := vm.push(Null)
+		} else {
+			err := vm.push(val)
 			if err != nil {
 				return err
 			}
-		case code.OpSetGlobal:
-			globalIndex := code.ReadUint16(ins[ip+1:])
-			ip += 2
-			vm.globals[globalIndex] = vm.pop()
-		case code.OpGetGlobal:
-			globalIndex := code.ReadUint16(ins[ip+1:])
-			ip += 2
-			val := vm.globals[globalIndex]
-			if val == nil {
-				err := vm.push(Null)
+		}
+	case code.OpCallBuiltin:
+		numArgs := int(code.ReadUint8(ins[ip+1:]))
+		ip += 1
+
+		if vm.sp < numArgs {
+			return fmt.Errorf("not enough arguments on stack: expected %d, got %d", numArgs, vm.sp)
+		}
+
+		if numArgs == 0 {
+			// input() 関数の場合（引数なし）
+			fmt.Print("Input: ")
+			scanner := bufio.NewScanner(os.Stdin)
+			if scanner.Scan() {
+				input := strings.TrimSpace(scanner.Text())
+				err := vm.push(&object.String{Value: input})
 				if err != nil {
 					return err
 				}
 			} else {
-				err := vm.push(val)
+				// 入力エラーまたはEOF
+				err := vm.push(&object.String{Value: ""})
 				if err != nil {
 					return err
 				}
 			}
-		case code.OpCallBuiltin:
-			numArgs := int(code.ReadUint8(vm.instructions[ip+1:]))
-			ip += 1
+		} else {
+			// puts() 関数の場合
+			args := vm.stack[vm.sp-numArgs : vm.sp]
+			vm.sp = vm.sp - numArgs
+
+			// puts の実装
+			for _, arg := range args {
+				switch obj := arg.(type) {
+				case *object.Integer:
+					fmt.Println(obj.Value)
+				case *object.Boolean:
+					fmt.Println(obj.Value)
+				case *object.String:
+					fmt.Println(obj.Value)
+				case *object.Null:
+					fmt.Println("null")
+				default:
+					fmt.Printf("%s\n", obj.Inspect())
+				}
+			}
 
-			if vm.sp < numArgs {
-				return fmt.Errorf("not enough arguments on stack: expected %d, got %d", numArgs, vm.sp)
+			err := vm.push(Null)
+			if err != nil {
+				return err
 			}
+		}
+
+	case code.OpCallAtoi:
+		numArgs := int(code.ReadUint8(ins[ip+1:]))
+		ip += 1
+
+		if vm.sp < numArgs {
+			return fmt.Errorf("not enough arguments on stack: expected %d, got %d", numArgs, vm.sp)
+		}
 
-			if numArgs == 0 {
-				// input() 関数の場合（引数なし）
-				fmt.Print("Input: ")
-				scanner := bufio.NewScanner(os.Stdin)
-				if scanner.Scan() {
-					input := strings.TrimSpace(scanner.Text())
-					err := vm.push(&object.String{Value: input})
-					if err != nil {
-						return err
-					}
-				} else {
-					// 入力エラーまたはEOF
-					err := vm.push(&object.String{Value: ""})
-					if err != nil {
-						return err
-					}
+		if numArgs != 1 {
+			return newRuntimeError(object.ErrorKindArgument, "atoi expects exactly 1 argument, got %d", numArgs)
+		}
+
+		arg := vm.stack[vm.sp-1]
+		vm.sp--
+
+		// atoi() の処理: 文字列を整数に変換
+		if strObj, ok := arg.(*object.String); ok {
+			if intVal, err := strconv.ParseInt(strObj.Value, 10, 64); err == nil {
+				err := vm.push(&object.Integer{Value: intVal})
+				if err != nil {
+					return err
 				}
 			} else {
-				// puts() 関数の場合
-				args := vm.stack[vm.sp-numArgs : vm.sp]
-				vm.sp = vm.sp - numArgs
-
-				// puts の実装
-				for _, arg := range args {
-					switch obj := arg.(type) {
-					case *object.Integer:
-						fmt.Println(obj.Value)
-					case *object.Boolean:
-						fmt.Println(obj.Value)
-					case *object.String:
-						fmt.Println(obj.Value)
-					case *object.Null:
-						fmt.Println("null")
-					default:
-						fmt.Printf("%s\n", obj.Inspect())
-					}
-				}
-
-				err := vm.push(Null)
+				// 変換失敗時は 0 を返す
+				err := vm.push(&object.Integer{Value: 0})
 				if err != nil {
 					return err
 				}
 			}
+		} else {
+			return fmt.Errorf("atoi expects string argument, got %T", arg)
+		}
 
-		case code.OpCallAtoi:
-			numArgs := int(code.ReadUint8(vm.instructions[ip+1:]))
-			ip += 1
+	case code.OpGetBuiltin:
+		builtinIndex := code.ReadUint8(ins[ip+1:])
+		ip += 1
+		if int(builtinIndex) >= len(object.Builtins) {
+			return fmt.Errorf("unknown builtin index %d", builtinIndex)
+		}
+		err := vm.push(object.Builtins[builtinIndex].Builtin)
+		if err != nil {
+			return err
+		}
 
-			if vm.sp < numArgs {
-				return fmt.Errorf("not enough arguments on stack: expected %d, got %d", numArgs, vm.sp)
-			}
+	case code.OpCall:
+		numArgs := int(code.ReadUint8(ins[ip+1:]))
+		ip += 1
+		err := vm.callFunction(numArgs)
+		if err != nil {
+			return err
+		}
 
-			if numArgs != 1 {
-				return fmt.Errorf("atoi expects exactly 1 argument, got %d", numArgs)
-			}
+	case code.OpArray:
+		numElements := int(code.ReadUint16(ins[ip+1:]))
+		ip += 2
 
-			arg := vm.stack[vm.sp-1]
-			vm.sp--
-
-			// atoi() の処理: 文字列を整数に変換
-			if strObj, ok := arg.(*object.String); ok {
-				if intVal, err := strconv.ParseInt(strObj.Value, 10, 64); err == nil {
-					err := vm.push(&object.Integer{Value: intVal})
-					if err != nil {
-						return err
-					}
-				} else {
-					// 変換失敗時は 0 を返す
-					err := vm.push(&object.Integer{Value: 0})
-					if err != nil {
-						return err
-					}
-				}
-			} else {
-				return fmt.Errorf("atoi expects string argument, got %T", arg)
-			}
+		elements := make([]object.Object, numElements)
+		copy(elements, vm.stack[vm.sp-numElements:vm.sp])
+		vm.sp = vm.sp - numElements
+
+		err := vm.push(&object.Array{Elements: elements})
+		if err != nil {
+			return err
+		}
+
+	case code.OpIndex:
+		index := vm.pop()
+		left := vm.pop()
+
+		err := vm.executeIndexExpression(left, index)
+		if err != nil {
+			return err
+		}
+
+	case code.OpSetupTry:
+		catchIP := int(code.ReadUint16(ins[ip+1:]))
+		ip += 2
+		vm.handlers = append(vm.handlers, handler{catchIP: catchIP, sp: vm.sp, framesIndex: vm.framesIndex})
+
+	case code.OpPopTry:
+		if len(vm.handlers) == 0 {
+			return fmt.Errorf("no exception handler to pop")
+		}
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	case code.OpThrow:
+		return vm.throw(vm.pop())
+
+	case code.OpGetLocal:
+		localIndex := int(code.ReadUint8(ins[ip+1:]))
+		ip += 1
+		val := vm.stack[frame.basePointer+localIndex]
+		if val == nil {
+			val = Null
+		}
+		err := vm.push(val)
+		if err != nil {
+			return err
+		}
+
+	case code.OpSetLocal:
+		localIndex := int(code.ReadUint8(ins[ip+1:]))
+		ip += 1
+		vm.stack[frame.basePointer+localIndex] = vm.pop()
+
+	case code.OpClosure:
+		constIndex := int(code.ReadUint16(ins[ip+1:]))
+		numFree := int(code.ReadUint8(ins[ip+3:]))
+		ip += 3
+		err := vm.pushClosure(constIndex, numFree)
+		if err != nil {
+			return err
+		}
 
-		default:
-			return fmt.Errorf("unknown opcode %d (%s)", op, op.String())
+	case code.OpGetFree:
+		freeIndex := int(code.ReadUint8(ins[ip+1:]))
+		ip += 1
+		err := vm.push(frame.cl.Free[freeIndex])
+		if err != nil {
+			return err
 		}
-		ip++
+
+	case code.OpCurrentClosure:
+		err := vm.push(frame.cl)
+		if err != nil {
+			return err
+		}
+
+	case code.OpReturnValue:
+		returnValue := vm.pop()
+		callee := vm.popFrame()
+		vm.sp = callee.basePointer - 1 // 呼び出された関数自身もスタックから取り除く
+		return vm.push(returnValue)
+
+	case code.OpReturn:
+		callee := vm.popFrame()
+		vm.sp = callee.basePointer - 1
+		return vm.push(Null)
+
+	default:
+		return fmt.Errorf("unknown opcode %d (%s)", op, op.String())
+	}
+	frame.ip = ip + 1
+	return nil
+}
+
+// pushClosure は定数プールの関数と、スタックに積まれた numFree 個の自由変数からクロージャを作る
+func (vm *VM) pushClosure(constIndex, numFree int) error {
+	fn, ok := vm.constants[constIndex].(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %+v", vm.constants[constIndex])
+	}
+
+	free := make([]object.Object, numFree)
+	copy(free, vm.stack[vm.sp-numFree:vm.sp])
+	vm.sp = vm.sp - numFree
+
+	return vm.push(&object.Closure{Fn: fn, Free: free})
+}
+
+// callFunction はスタック上の関数 (クロージャまたは組み込み関数) を numArgs 個の引数で呼び出す
+func (vm *VM) callFunction(numArgs int) error {
+	if vm.sp < numArgs+1 {
+		return fmt.Errorf("not enough arguments on stack: expected %d, got %d", numArgs+1, vm.sp)
+	}
+
+	switch callee := vm.stack[vm.sp-1-numArgs].(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return newRuntimeError(object.ErrorKindType, "calling non-function: %s", callee.Type())
 	}
+}
+
+// callClosure は新しいフレームを積んでクロージャの実行を開始する
+// 呼び出し元の ip は execute の最後で OpCall の次に進むため、戻ったときはそこから再開する
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return newRuntimeError(object.ErrorKindArgument, "wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+
+	basePointer := vm.sp - numArgs
+	if basePointer+cl.Fn.NumLocals >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+	if err := vm.pushFrame(NewFrame(cl, basePointer)); err != nil {
+		return err
+	}
+
+	// 引数以外のローカル変数は未代入 (null) にしておく
+	for i := basePointer + numArgs; i < basePointer+cl.Fn.NumLocals; i++ {
+		vm.stack[i] = nil
+	}
+	vm.sp = basePointer + cl.Fn.NumLocals
 	return nil
 }
 
+// callBuiltin は組み込み関数を呼び出し、結果をスタックに積む
+// 引数のクロージャは、組み込み関数 (map や sort など) からその場で実行できるように組み込み関数に包んで渡す
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := make([]object.Object, numArgs)
+	for i, arg := range vm.stack[vm.sp-numArgs : vm.sp] {
+		if cl, ok := arg.(*object.Closure); ok {
+			args[i] = vm.closureAsBuiltin(cl)
+		} else {
+			args[i] = arg
+		}
+	}
+
+	result := builtin.Fn(args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	if errObj, ok := result.(*object.Error); ok {
+		kind := errObj.Kind
+		if kind == "" {
+			kind = object.ErrorKindRuntime
+		}
+		return newRuntimeError(kind, "%s", errObj.Message)
+	}
+	if result == nil {
+		return vm.push(Null)
+	}
+	if b, ok := result.(*object.Boolean); ok {
+		// 比較演算 (ポインタ比較) が成り立つように True/False のシングルトンに揃える
+		return vm.push(nativeBoolToBooleanObject(b.Value))
+	}
+	return vm.push(result)
+}
+
+// closureAsBuiltin はクロージャをこの VM 上で同期的に実行する組み込み関数に変換する
+// クロージャ内の捕捉されなかったエラーは object.Error として組み込み関数に返す
+func (vm *VM) closureAsBuiltin(cl *object.Closure) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		sp, framesIndex, numHandlers := vm.sp, vm.framesIndex, len(vm.handlers)
+
+		err := vm.runClosure(cl, args, framesIndex, numHandlers)
+		if err != nil {
+			vm.sp, vm.framesIndex, vm.handlers = sp, framesIndex, vm.handlers[:numHandlers]
+			value := errorValue(err)
+			return &object.Error{Kind: value.Kind, Message: value.Message}
+		}
+
+		// 戻り値はクロージャを積んだ位置に置かれている
+		result := vm.stack[vm.sp-1]
+		vm.sp--
+		return result
+	}}
+}
+
+// runClosure はクロージャと引数を積んで呼び出し、framesIndex のフレームに戻るまで実行する
+func (vm *VM) runClosure(cl *object.Closure, args []object.Object, framesIndex, numHandlers int) error {
+	if err := vm.push(cl); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := vm.push(arg); err != nil {
+			return err
+		}
+	}
+	if err := vm.callClosure(cl, len(args)); err != nil {
+		return err
+	}
+	for vm.framesIndex > framesIndex {
+		if err := vm.step(numHandlers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// throw は値をエラーとして送出する。エラー値以外は Error 種別のエラーに包む
+func (vm *VM) throw(value object.Object) error {
+	switch value := value.(type) {
+	case *object.ErrorValue:
+		return &RuntimeError{Value: value}
+	case *object.String:
+		return newRuntimeError(object.ErrorKindError, "%s", value.Value)
+	default:
+		return newRuntimeError(object.ErrorKindError, "%s", value.Inspect())
+	}
+}
+
+// executeIndexExpression は配列または文字列の添字アクセスを実行する
+// 範囲外の添字はエラーとする
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	i, ok := index.(*object.Integer)
+	if !ok {
+		return newRuntimeError(object.ErrorKindType, "index must be INTEGER, got %s", index.Type())
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return newRuntimeError(object.ErrorKindIndex, "index out of range: %d (length %d)", i.Value, len(left.Elements))
+		}
+		return vm.push(left.Elements[i.Value])
+	case *object.String:
+		runes := []rune(left.Value)
+		if i.Value < 0 || i.Value >= int64(len(runes)) {
+			return newRuntimeError(object.ErrorKindIndex, "index out of range: %d (length %d)", i.Value, len(runes))
+		}
+		return vm.push(&object.String{Value: string(runes[i.Value])})
+	default:
+		return newRuntimeError(object.ErrorKindType, "index operator not supported: %s", left.Type())
+	}
+}
+
+// isNumber は Integer または Float かどうかを判定する
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
 func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	right := vm.pop()
 	left := vm.pop()
@@ -270,6 +654,9 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	switch {
 	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
 		return vm.executeBinaryIntegerOperation(op, left, right)
+	case isNumber(left) && isNumber(right):
+		// 整数と浮動小数点数の混在は浮動小数点数として演算する
+		return vm.executeBinaryFloatOperation(op, left, right)
 	case leftType == object.STRING_OBJ && rightType == object.STRING_OBJ:
 		return vm.executeBinaryStringOperation(op, left, right)
 	default:
@@ -291,7 +678,7 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 		result = leftValue * rightValue
 	case code.OpDiv:
 		if rightValue == 0 {
-			return fmt.Errorf("division by zero")
+			return newRuntimeError(object.ErrorKindZeroDivision, "division by zero")
 		}
 		result = leftValue / rightValue
 	default:
@@ -300,6 +687,29 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 	return vm.push(&object.Integer{Value: result})
 }
 
+func (vm *VM) executeBinaryFloatOperation(op code.Opcode, left, right object.Object) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+	var result float64
+
+	switch op {
+	case code.OpAdd:
+		result = leftValue + rightValue
+	case code.OpSub:
+		result = leftValue - rightValue
+	case code.OpMul:
+		result = leftValue * rightValue
+	case code.OpDiv:
+		if rightValue == 0 {
+			return newRuntimeError(object.ErrorKindZeroDivision, "division by zero")
+		}
+		result = leftValue / rightValue
+	default:
+		return fmt.Errorf("unknown float operator: %d", op)
+	}
+	return vm.push(&object.Float{Value: result})
+}
+
 func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
 	if op != code.OpAdd {
 		return fmt.Errorf("unknown string operator: %d", op)
@@ -318,6 +728,9 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
 		return vm.executeIntegerComparison(op, left, right)
 	}
+	if isNumber(left) && isNumber(right) {
+		return vm.executeFloatComparison(op, left, right)
+	}
 
 	switch op {
 	case code.OpEqual:
@@ -347,6 +760,25 @@ func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object
 	}
 }
 
+// executeFloatComparison は浮動小数点数を含む数値の比較を行う (1 == 1.0 は true)
+func (vm *VM) executeFloatComparison(op code.Opcode, left, right object.Object) error {
+	leftValue, _ := object.ToFloat(left)
+	rightValue, _ := object.ToFloat(right)
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	default:
+		return fmt.Errorf("unknown float comparison operator: %d (%s)", op, op.String())
+	}
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return True
@@ -370,35 +802,32 @@ func (vm *VM) executeBangOperator() error {
 			}
 			return vm.push(False) // !non-zero-integer is false
 		}
+		if floatVal, ok := operand.(*object.Float); ok {
+			return vm.push(nativeBoolToBooleanObject(floatVal.Value == 0)) // !0.0 is true
+		}
 		return vm.push(False) // !other-types (e.g. Error) is false, consistent with isTruthy
 	}
 }
 
 // isTruthy はMonkey言語の条件評価における真偽を決定します。
 // - false, null は偽 (falsey)
-// - 整数 0 は偽 (falsey)
+// - 整数 0 と 0.0 は偽 (falsey)
 // - それ以外は全て真 (truthy)
 func (vm *VM) isTruthy(obj object.Object) bool {
-	switch val := obj.(type) {
-	case *object.Boolean:
-		return val.Value // Trueならtrue, Falseならfalse
-	case *object.Null:
-		return false
-	case *object.Integer:
-		return val.Value != 0 // 0ならfalse, それ以外ならtrue
-	default:
-		// その他の型（エラーオブジェクトなど、もしあれば）は真として扱う
-		return true
-	}
+	// 組み込み関数 (filter など) と同じ判定を使う
+	return object.IsTruthy(obj)
 }
 
 func (vm *VM) executeMinusOperator() error {
 	operand := vm.pop()
-	if operand.Type() != object.INTEGER_OBJ {
+	switch operand := operand.(type) {
+	case *object.Integer:
+		return vm.push(&object.Integer{Value: -operand.Value})
+	case *object.Float:
+		return vm.push(&object.Float{Value: -operand.Value})
+	default:
 		return fmt.Errorf("unsupported type for negation: %s", operand.Type())
 	}
-	value := operand.(*object.Integer).Value
-	return vm.push(&object.Integer{Value: -value})
 }
 
 func (vm *VM) LastPoppedStackElem() object.Object {
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/lirlia/100day_challenge_backend/day51_monkey_compiler_go/ast"
@@ -45,6 +46,39 @@ func testIntegerObject(t *testing.T, expected int64, actual object.Object) bool
 	return true
 }
 
+func testFloatObject(t *testing.T, expected float64, actual object.Object) bool {
+	t.Helper()
+	result, ok := actual.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", actual, actual)
+		return false
+	}
+	if math.Abs(result.Value-expected) > 1e-9 {
+		t.Errorf("object has wrong value. got=%f, want=%f", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testIntegerArrayObject(t *testing.T, expected []int, actual object.Object) bool {
+	t.Helper()
+	array, ok := actual.(*object.Array)
+	if !ok {
+		t.Errorf("object is not Array. got=%T (%+v)", actual, actual)
+		return false
+	}
+	if len(array.Elements) != len(expected) {
+		t.Errorf("wrong num of elements. got=%d, want=%d", len(array.Elements), len(expected))
+		return false
+	}
+	for i, el := range expected {
+		if !testIntegerObject(t, int64(el), array.Elements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func testBooleanObject(t *testing.T, expected bool, actual object.Object) bool {
 	t.Helper()
 	result, ok := actual.(*object.Boolean)
@@ -109,6 +143,8 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 			testIntegerObject(t, int64(expectedVal), stackElem)
 		case int64: // int64 も直接使えるように
 			testIntegerObject(t, expectedVal, stackElem)
+		case float64:
+			testFloatObject(t, expectedVal, stackElem)
 		case bool:
 			testBooleanObject(t, expectedVal, stackElem)
 		case string:
@@ -117,6 +153,8 @@ func runVmTests(t *testing.T, tests []vmTestCase) {
 				continue
 			}
 			testStringObject(t, expectedVal, stackElem)
+		case []int:
+			testIntegerArrayObject(t, expectedVal, stackElem)
 		case nil: // 期待値が Go の nil の場合は、Monkey の Null オブジェクトを期待
 			if stackElem != Null {
 				t.Errorf("expected Null object, got %T (%+v) for input: %s", stackElem, stackElem, tt.input)
@@ -257,3 +295,277 @@ func TestAtoiFunction(t *testing.T) {
 
 	runVmTests(t, tests)
 }
+
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"1.5 + 2.25", 3.75},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"5.0 - 7", -2.0},
+		{"2 * 1.5", 3.0},
+		{"7 / 2.0", 3.5},
+		{"7 / 2", 3}, // 整数同士の除算は整数のまま
+		{"-2.5", -2.5},
+		{"(1.5 + 0.5) * 2", 4.0},
+		{"1.0 / 0", "error"},
+		{"1 / 0.0", "error"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestFloatComparisons(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5 < 2", true},
+		{"2 < 1.5", false},
+		{"2.5 > 2.25", true},
+		{"1 == 1.0", true},
+		{"1 != 1.0", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"!0.0", true},
+		{"!1.5", false},
+		{"if (0.0) { 1 } else { 2 }", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{"sqrt(16)", 4.0},
+		{"sqrt(2.25)", 1.5},
+		{"sqrt(-1)", "error"},
+		{"pow(2, 10)", 1024},
+		{"pow(2, 0.5)", math.Sqrt2},
+		{"pow(2, -1)", 0.5},
+		{"abs(-5)", 5},
+		{"abs(-2.5)", 2.5},
+		{"floor(2.7)", 2},
+		{"ceil(2.1)", 3},
+		{"floor(-2.5)", -3},
+		{"int(3.9)", 3},
+		{"float(3)", 3.0},
+		{"let x = 9; sqrt(x) + 1", 4.0},
+		{`abs("a")`, "error"},
+		{"sqrt(1, 2)", "error"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestStep(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("1 + 2")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vmInstance := New(comp.Bytecode())
+
+	// OpConstant 0, OpConstant 1, OpAdd, OpPop を1命令ずつ実行する
+	expectedStacks := [][]int64{{1}, {1, 2}, {3}, {}}
+	expectedIPs := []int{3, 6, 7, 8}
+
+	for i, expected := range expectedStacks {
+		if vmInstance.Done() {
+			t.Fatalf("step %d: vm finished too early", i)
+		}
+		if err := vmInstance.Step(); err != nil {
+			t.Fatalf("step %d: vm error: %s", i, err)
+		}
+		if vmInstance.IP() != expectedIPs[i] {
+			t.Errorf("step %d: wrong ip. got=%d, want=%d", i, vmInstance.IP(), expectedIPs[i])
+		}
+
+		stack := vmInstance.Stack()
+		if len(stack) != len(expected) {
+			t.Fatalf("step %d: wrong stack size. got=%d, want=%d", i, len(stack), len(expected))
+		}
+		for j, v := range expected {
+			testIntegerObject(t, v, stack[j])
+		}
+	}
+
+	if !vmInstance.Done() {
+		t.Errorf("vm should be done after all instructions")
+	}
+	testIntegerObject(t, 3, vmInstance.LastPoppedStackElem())
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []vmTestCase{
+		{"[]", []int{}},
+		{"[1, 2, 3]", []int{1, 2, 3}},
+		{"[1 + 2, 3 * 4, 5 + 6]", []int{3, 12, 11}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3][1]", 2},
+		{"[[1, 1, 1]][0][0]", 1},
+		{"let a = [1, 2, 3]; a[0] + a[2]", 4},
+		{`"monkey"[1]`, "o"},
+		{"[1, 2, 3][3]", "error"},
+		{"[1, 2, 3][-1]", "error"},
+		{`[1][true]`, "error"},
+		{"1[0]", "error"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestStringAndArrayBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`len("hello")`, 5},
+		{"len([1, 2, 3])", 3},
+		{`len(1)`, "error"},
+		{`split("a,b,c", ",")[2]`, "c"},
+		{`len(split("a,b,c", ","))`, 3},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join([1, 2.5, true], ", ")`, "1, 2.5, true"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`contains("monkey", "key")`, true},
+		{`contains("monkey", "cat")`, false},
+		{`contains([1, "a", 2.0], 2)`, true},
+		{`contains([1, "a"], "b")`, false},
+		{`contains("monkey", "key") == true`, true},
+		{`toUpper("monkey")`, "MONKEY"},
+		{`toLower("MoNkEy")`, "monkey"},
+		{`toUpper(1)`, "error"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []vmTestCase{
+		{`map(["a", "b"], toUpper)[1]`, "B"},
+		{"map([-1, 2, -3], abs)", []int{1, 2, 3}},
+		{"filter([0, 1, 0, 2], int)", []int{1, 2}},
+		{"reduce([2, 3, 2], pow)", 64},
+		{"reduce([3], pow, 2)", 8},
+		{"reduce([], pow)", "error"},
+		{"reduce([], pow, 1)", 1},
+		{"sort([3, 1, 2])", []int{1, 2, 3}},
+		{`sort(["b", "c", "a"])[0]`, "a"},
+		{`sort([1, "a"])`, "error"},
+		{"map([1], 1)", "error"},
+		{"map([-4], sqrt)", "error"},
+		// 関数リテラルとクロージャを渡す
+		{"sort([3, 1, 2], fn(a, b) { a > b })", []int{3, 2, 1}},
+		{`sort(["bb", "a", "ccc"], fn(a, b) { len(a) < len(b) })[2]`, "ccc"},
+		{"map([1, 2, 3], fn(x) { x * 2 })", []int{2, 4, 6}},
+		{"let n = 10; map([1, 2], fn(x) { x + n })", []int{11, 12}},
+		{"filter([1, 2, 3, 4], fn(x) { x > 2 })", []int{3, 4}},
+		{"reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)", 16},
+		{"let adder = fn(n) { fn(x) { x + n } }; map([1, 2], adder(5))", []int{6, 7}},
+		{"map([[2, 1], [4, 3]], fn(a) { sort(a, fn(x, y) { x < y })[0] })", []int{1, 3}},
+		{"map([1], fn(x, y) { x })", "error"},
+		{"sort([2, 1], fn(a, b) { [][0] })", "error"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();", 15},
+		{"fn(a, b) { a + b }(1, 2)", 3},
+		{"let f = fn() { return 99; 100; }; f();", 99},
+		{"let f = fn(x) { if (x > 1) { return 1; } 0 }; f(2) + f(0)", 1},
+		{"let noReturn = fn() { }; noReturn();", nil},
+		{"let onlyLet = fn() { let a = 1; }; onlyLet();", nil},
+		{"let f = fn(a) { let b = a * 2; let c = b + 1; c }; f(3) + f(4)", 16},
+		{"let g = 50; let f = fn() { let g = 1; g }; f() + g", 51},
+		{"let f = fn() { if (false) { let a = 1; } a }; f()", nil},
+		{"let returnsOne = fn() { 1; }; let returnsOneReturner = fn() { returnsOne; }; returnsOneReturner()();", 1},
+		{"fn() { 1; }(1);", "error"},
+		{"fn(a, b) { a + b; }(1);", "error"},
+		{"1()", "error"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{"let newClosure = fn(a) { fn() { a; }; }; let closure = newClosure(99); closure();", 99},
+		{"let newAdder = fn(a, b) { fn(c) { a + b + c } }; let adder = newAdder(1, 2); adder(8);", 11},
+		{`
+let newAdderOuter = fn(a, b) {
+	let c = a + b;
+	fn(d) {
+		let e = d + c;
+		fn(f) { e + f; };
+	};
+};
+let newAdderInner = newAdderOuter(1, 2);
+let adder = newAdderInner(3);
+adder(8);`, 14},
+		{`
+let fibonacci = fn(x) {
+	if (x < 2) { return x; }
+	fibonacci(x - 1) + fibonacci(x - 2);
+};
+fibonacci(15);`, 610},
+		{`
+let wrapper = fn() {
+	let countDown = fn(x) { if (x == 0) { return 0; } countDown(x - 1); };
+	countDown(3);
+};
+wrapper();`, 0},
+		{"let loop = fn(n) { loop(n + 1) }; loop(0)", "error"},
+	}
+	runVmTests(t, tests)
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []vmTestCase{
+		{"try { 1 + 1 } catch (e) { 0 }", 2},
+		{"try { [1][5] } catch (e) { 0 }", 0},
+		{"try { [1][5] } catch (e) { errorKind(e) }", "IndexError"},
+		{"try { [1][5] } catch (e) { errorMessage(e) }", "index out of range: 5 (length 1)"},
+		{"try { 1 / 0 } catch (e) { errorKind(e) }", "ZeroDivisionError"},
+		{"try { sqrt(1, 2) } catch (e) { errorKind(e) }", "ArgumentError"},
+		{`try { toUpper(1) } catch (e) { errorKind(e) }`, "TypeError"},
+		{`try { -"a" } catch (e) { errorKind(e) }`, "RuntimeError"},
+		{`try { throw "boom"; } catch (e) { errorMessage(e) }`, "boom"},
+		{`try { throw error("bad", "CustomError"); } catch (e) { errorKind(e) }`, "CustomError"},
+		{`try { throw 42; } catch (e) { errorMessage(e) }`, "42"},
+		{"try { 1 } catch { 2 }", 1},
+		{"try { throw 1; } catch { 2 }", 2},
+		{"try { let a = 1; } catch { 2 }", nil},
+		// スタックは try 開始時点まで巻き戻される
+		{"1 + try { 2 * [][0] } catch { 10 }", 11},
+		// ネストした try と再送出
+		{`try { try { throw "a"; } catch (e) { throw errorMessage(e) + "b"; } } catch (e) { errorMessage(e) }`, "ab"},
+		// catch の外の実行時エラーは捕捉されない
+		{"try { 1 } catch { 2 }; [][0]", "error"},
+		{`throw "uncaught";`, "error"},
+		// 関数の中で発生したエラーを呼び出し元で捕捉する
+		{"let f = fn() { [][0] }; try { f() } catch (e) { errorKind(e) }", "IndexError"},
+		{"1 + try { fn() { [][0] }() } catch { 10 }", 11},
+		{"let f = fn(x) { try { 10 / x } catch (e) { -1 } }; f(0) + f(5)", 1},
+		// try の中で return した場合、そのハンドラは関数から戻るときに破棄される
+		{"let f = fn() { try { return 1; } catch { 2 } }; f(); [][0]", "error"},
+		// 組み込み関数から呼び出したクロージャのエラー
+		{"try { map([1], fn(x) { [][0] }) } catch (e) { errorKind(e) }", "IndexError"},
+		{`try { sort([1, 2], fn(a, b) { throw error("no", "CustomError"); }) } catch (e) { errorKind(e) }`, "CustomError"},
+		{"map([1, 0], fn(x) { try { 1 / x } catch { -1 } })", []int{1, -1}},
+		{"try { map([1], fn(x) { [][0] }) } catch { 0 }; map([1], fn(x) { x + 1 })", []int{2}},
+	}
+	runVmTests(t, tests)
+}
+
+func TestUncaughtErrorKind(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`throw error("bad", "CustomError");`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err := New(comp.Bytecode()).Run()
+	rtErr, ok := err.(*RuntimeError)
+	if !ok {
+		t.Fatalf("error is not *RuntimeError. got=%T (%v)", err, err)
+	}
+	if rtErr.Value.Kind != "CustomError" || rtErr.Value.Message != "bad" {
+		t.Errorf("wrong error value. got=%s", rtErr.Value.Inspect())
+	}
+	if err.Error() != "CustomError: bad" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 
@@ -53,8 +54,18 @@ func createTables() error {
 			redirect_uris TEXT NOT NULL,  -- JSON配列
 			scopes TEXT NOT NULL,         -- JSON配列
 			grant_types TEXT NOT NULL,    -- JSON配列
+			access_token_ttl INTEGER NOT NULL DEFAULT 3600,        -- 秒
+			refresh_token_ttl INTEGER NOT NULL DEFAULT 2592000,    -- 秒（30日）
+			rotate_refresh_token BOOLEAN NOT NULL DEFAULT true,
+			require_pkce BOOLEAN NOT NULL DEFAULT false,
+			access_token_format TEXT NOT NULL DEFAULT 'jwt', -- jwt / opaque
+			token_endpoint_auth_method TEXT NOT NULL DEFAULT 'client_secret_basic', -- noneはパブリッククライアント（client_secretは空）
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			created_by TEXT NOT NULL DEFAULT '',  -- 作成した管理者
+			updated_by TEXT NOT NULL DEFAULT '',  -- 最後に更新した管理者
+			deleted_at DATETIME,                  -- 論理削除（無効化）
+			deleted_by TEXT NOT NULL DEFAULT ''
 		)`,
 
 		// ユーザー
@@ -65,7 +76,11 @@ func createTables() error {
 			name TEXT NOT NULL,
 			profile TEXT,                 -- JSON（プロフィール情報）
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			created_by TEXT NOT NULL DEFAULT '',  -- 作成した管理者
+			updated_by TEXT NOT NULL DEFAULT '',  -- 最後に更新した管理者
+			deleted_at DATETIME,                  -- 論理削除（無効化）
+			deleted_by TEXT NOT NULL DEFAULT ''
 		)`,
 
 		// 認可コード
@@ -98,10 +113,13 @@ func createTables() error {
 			FOREIGN KEY (user_id) REFERENCES users(id)
 		)`,
 
-		// アクセストークンの記録（オプション）
+		// 発行したアクセストークンの記録（イントロスペクション・失効に使用）
 		`CREATE TABLE IF NOT EXISTS access_tokens (
 			id TEXT PRIMARY KEY,
 			token_hash TEXT UNIQUE NOT NULL,  -- SHA256ハッシュ
+			jti TEXT,                         -- JWT ID
+			status TEXT NOT NULL DEFAULT 'active', -- active / revoked
+			format TEXT NOT NULL DEFAULT 'jwt',    -- jwt / opaque
 			client_id TEXT NOT NULL,
 			user_id TEXT,                     -- Client Credentialsの場合はNULL
 			scopes TEXT NOT NULL,             -- JSON配列
@@ -111,6 +129,49 @@ func createTables() error {
 			FOREIGN KEY (user_id) REFERENCES users(id)
 		)`,
 
+		// スコープ定義
+		`CREATE TABLE IF NOT EXISTS scopes (
+			name TEXT PRIMARY KEY,
+			description TEXT NOT NULL,    -- 同意画面に表示する説明
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// 標準スコープ
+		`INSERT OR IGNORE INTO scopes (name, description) VALUES
+			('openid', 'Verify your identity'),
+			('profile', 'Access your basic profile information'),
+			('email', 'Access your email address'),
+			('read', 'Read access to your data'),
+			('write', 'Write access to your data')`,
+
+		// ユーザーがクライアントに許可したスコープ
+		`CREATE TABLE IF NOT EXISTS user_consents (
+			user_id TEXT NOT NULL,
+			client_id TEXT NOT NULL,
+			scopes TEXT NOT NULL,         -- JSON配列
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, client_id),
+			FOREIGN KEY (client_id) REFERENCES oauth_clients(id),
+			FOREIGN KEY (user_id) REFERENCES users(id)
+		)`,
+
+		// スコープとIDトークン・UserInfoのクレームの対応
+		`CREATE TABLE IF NOT EXISTS scope_claims (
+			id TEXT PRIMARY KEY,
+			scope TEXT NOT NULL,
+			claim TEXT NOT NULL,          -- クレーム名
+			source TEXT NOT NULL,         -- 値の取得元（email / name / profile.<key>）
+			client_id TEXT NOT NULL DEFAULT '', -- クライアント別の設定（空は全クライアント共通）
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (scope, claim, client_id)
+		)`,
+
+		// 標準クレーム
+		`INSERT OR IGNORE INTO scope_claims (id, scope, claim, source) VALUES
+			('default-profile-name', 'profile', 'name', 'name'),
+			('default-email-email', 'email', 'email', 'email')`,
+
 		// RSA鍵ペア保存
 		`CREATE TABLE IF NOT EXISTS key_pairs (
 			id TEXT PRIMARY KEY,
@@ -119,7 +180,8 @@ func createTables() error {
 			kid TEXT UNIQUE NOT NULL,     -- Key ID
 			algorithm TEXT NOT NULL,      -- RS256など
 			is_active BOOLEAN DEFAULT true,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			retired_at DATETIME           -- ローテーションで署名鍵でなくなった日時
 		)`,
 	}
 
@@ -129,5 +191,96 @@ func createTables() error {
 		}
 	}
 
+	return migrateTables()
+}
+
+// migrateTables adds columns introduced after the initial schema to existing databases
+func migrateTables() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		// クライアントごとのトークン設定
+		{"oauth_clients", "access_token_ttl", "INTEGER NOT NULL DEFAULT 3600"},
+		{"oauth_clients", "refresh_token_ttl", "INTEGER NOT NULL DEFAULT 2592000"},
+		{"oauth_clients", "rotate_refresh_token", "BOOLEAN NOT NULL DEFAULT true"},
+		// PKCEの必須化
+		{"oauth_clients", "require_pkce", "BOOLEAN NOT NULL DEFAULT false"},
+		// トークンのイントロスペクション・失効
+		{"access_tokens", "jti", "TEXT"},
+		{"access_tokens", "status", "TEXT NOT NULL DEFAULT 'active'"},
+		// 署名鍵のローテーション
+		{"key_pairs", "retired_at", "DATETIME"},
+		// 管理APIの監査項目・論理削除
+		{"oauth_clients", "created_by", "TEXT NOT NULL DEFAULT ''"},
+		{"oauth_clients", "updated_by", "TEXT NOT NULL DEFAULT ''"},
+		{"oauth_clients", "deleted_at", "DATETIME"},
+		{"oauth_clients", "deleted_by", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "created_by", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "updated_by", "TEXT NOT NULL DEFAULT ''"},
+		{"users", "deleted_at", "DATETIME"},
+		{"users", "deleted_by", "TEXT NOT NULL DEFAULT ''"},
+		// 不透明なアクセストークン
+		{"oauth_clients", "access_token_format", "TEXT NOT NULL DEFAULT 'jwt'"},
+		{"access_tokens", "format", "TEXT NOT NULL DEFAULT 'jwt'"},
+		// パブリッククライアント
+		{"oauth_clients", "token_endpoint_auth_method", "TEXT NOT NULL DEFAULT 'client_secret_basic'"},
+	}
+
+	for _, c := range columns {
+		exists, err := columnExists(c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		log.Printf("Adding column %s.%s", c.table, c.column)
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)
+		if _, err := DB.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	indexes := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_access_tokens_jti ON access_tokens(jti)`,
+		`CREATE INDEX IF NOT EXISTS idx_access_tokens_client_user ON access_tokens(client_id, user_id)`,
+	}
+	for _, index := range indexes {
+		if _, err := DB.Exec(index); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// columnExists checks if the column exists in the table
+func columnExists(table, column string) (bool, error) {
+	rows, err := DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
@@ -4,17 +4,44 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/models"
+	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/services"
 )
 
+// adminUserHeader is the header that identifies the administrator for the audit fields
+const adminUserHeader = "X-Admin-User"
+
 // CreateClientRequest represents a request to create an OAuth2 client
 type CreateClientRequest struct {
-	Name         string   `json:"name"`
-	RedirectURIs []string `json:"redirect_uris"`
-	Scopes       []string `json:"scopes"`
-	GrantTypes   []string `json:"grant_types"`
+	Name               string   `json:"name"`
+	RedirectURIs       []string `json:"redirect_uris"`
+	Scopes             []string `json:"scopes"`
+	GrantTypes         []string `json:"grant_types"`
+	AccessTokenTTL     int      `json:"access_token_ttl,omitempty"`     // 秒
+	RefreshTokenTTL    int      `json:"refresh_token_ttl,omitempty"`    // 秒
+	RotateRefreshToken *bool    `json:"rotate_refresh_token,omitempty"` // 省略時はtrue
+	RequirePKCE        *bool    `json:"require_pkce,omitempty"`         // 省略時はfalse
+	AccessTokenFormat  string   `json:"access_token_format,omitempty"`  // jwt / opaque（省略時はjwt）
+	// client_secret_basic / client_secret_post / none（省略時はclient_secret_basic）。noneはclient_secretなしのパブリッククライアント
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method,omitempty"`
+}
+
+// CreateScopeRequest represents a request to create a scope
+type CreateScopeRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateClaimMappingRequest represents a request to map a scope to a claim
+type CreateClaimMappingRequest struct {
+	Scope    string `json:"scope"`
+	Claim    string `json:"claim"`
+	Source   string `json:"source"`              // email / name / profile.<key>
+	ClientID string `json:"client_id,omitempty"` // 指定した場合はそのクライアントだけ共通設定を上書きする
 }
 
 // CreateUserRequest represents a request to create a user
@@ -41,13 +68,24 @@ func ClientsHandler(w http.ResponseWriter, r *http.Request) {
 func ClientHandler(w http.ResponseWriter, r *http.Request) {
 	// URLからclient_idを抽出（簡易実装）
 	path := strings.TrimPrefix(r.URL.Path, "/api/clients/")
-	clientID := strings.Split(path, "/")[0]
+	segments := strings.Split(path, "/")
+	clientID := segments[0]
 
 	if clientID == "" {
 		http.Error(w, "Client ID required", http.StatusBadRequest)
 		return
 	}
 
+	// POST /api/clients/{id}/restore
+	if len(segments) == 2 && segments[1] == "restore" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleRestoreClient(w, r, clientID)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		handleGetClient(w, r, clientID)
@@ -60,6 +98,62 @@ func ClientHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ScopesHandler handles operations for scopes
+func ScopesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleGetScopes(w, r)
+	case http.MethodPost:
+		handleCreateScope(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ScopeHandler handles operations for a specific scope
+func ScopeHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/scopes/")
+	if name == "" {
+		http.Error(w, "Scope name required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		handleDeleteScope(w, r, name)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ClaimsHandler handles operations for scope-to-claim mappings
+func ClaimsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleGetClaimMappings(w, r)
+	case http.MethodPost:
+		handleCreateClaimMapping(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ClaimHandler handles operations for a specific scope-to-claim mapping
+func ClaimHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/claims/")
+	if id == "" {
+		http.Error(w, "Claim mapping ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		handleDeleteClaimMapping(w, r, id)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 // UsersHandler handles CRUD operations for users
 func UsersHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -76,13 +170,24 @@ func UsersHandler(w http.ResponseWriter, r *http.Request) {
 func UserHandler(w http.ResponseWriter, r *http.Request) {
 	// URLからuser_idを抽出（簡易実装）
 	path := strings.TrimPrefix(r.URL.Path, "/api/users/")
-	userID := strings.Split(path, "/")[0]
+	segments := strings.Split(path, "/")
+	userID := segments[0]
 
 	if userID == "" {
 		http.Error(w, "User ID required", http.StatusBadRequest)
 		return
 	}
 
+	// POST /api/users/{id}/restore
+	if len(segments) == 2 && segments[1] == "restore" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleRestoreUser(w, r, userID)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		handleGetUser(w, r, userID)
@@ -95,9 +200,34 @@ func UserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleGetClients retrieves all OAuth2 clients
+// KeysHandler lists the signing keys published in the JWKS
+func KeysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleGetKeys(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// KeyRotateHandler rotates the signing key immediately
+func KeyRotateHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		handleRotateKeys(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleGetClients retrieves OAuth2 clients with pagination and filtering
 func handleGetClients(w http.ResponseWriter, r *http.Request) {
-	clients, err := models.GetAllClients()
+	opts, ok := parseListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	clients, total, err := models.ListClients(opts)
 	if err != nil {
 		log.Printf("Failed to get clients: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -106,10 +236,11 @@ func handleGetClients(w http.ResponseWriter, r *http.Request) {
 
 	// client_secretを隠す
 	for _, client := range clients {
-		client.ClientSecret = "***"
+		maskClientSecret(client)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	json.NewEncoder(w).Encode(clients)
 }
 
@@ -126,20 +257,68 @@ func handleCreateClient(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Name is required", http.StatusBadRequest)
 		return
 	}
-	if len(req.RedirectURIs) == 0 {
+	// デフォルト値設定
+	if len(req.GrantTypes) == 0 {
+		req.GrantTypes = []string{"authorization_code", "refresh_token"}
+	}
+	if req.TokenEndpointAuthMethod == "" {
+		req.TokenEndpointAuthMethod = models.AuthMethodClientSecretBasic
+	}
+	if !models.ValidateTokenEndpointAuthMethod(req.TokenEndpointAuthMethod) {
+		http.Error(w, "token_endpoint_auth_method must be client_secret_basic, client_secret_post or none", http.StatusBadRequest)
+		return
+	}
+	// パブリッククライアントはclient_secretを持たないため、client_credentialsは使えずPKCEは常に必須
+	if req.TokenEndpointAuthMethod == models.AuthMethodNone {
+		if slices.Contains(req.GrantTypes, "client_credentials") {
+			http.Error(w, "Public clients cannot use the client_credentials grant", http.StatusBadRequest)
+			return
+		}
+		if req.RequirePKCE != nil && !*req.RequirePKCE {
+			http.Error(w, "PKCE cannot be disabled for public clients", http.StatusBadRequest)
+			return
+		}
+	}
+	// client_credentialsのみのクライアント（マシン間通信）はリダイレクトURI不要
+	if len(req.RedirectURIs) == 0 && slices.Contains(req.GrantTypes, "authorization_code") {
 		http.Error(w, "At least one redirect URI is required", http.StatusBadRequest)
 		return
 	}
+	if req.AccessTokenTTL < 0 || req.RefreshTokenTTL < 0 {
+		http.Error(w, "Token TTL must not be negative", http.StatusBadRequest)
+		return
+	}
 
-	// デフォルト値設定
 	if len(req.Scopes) == 0 {
 		req.Scopes = []string{"openid", "profile", "email"}
 	}
-	if len(req.GrantTypes) == 0 {
-		req.GrantTypes = []string{"authorization_code", "refresh_token"}
+	if !validateScopes(w, req.Scopes) {
+		return
+	}
+	if req.RedirectURIs == nil {
+		req.RedirectURIs = []string{}
+	}
+	if req.AccessTokenTTL == 0 {
+		req.AccessTokenTTL = models.DefaultAccessTokenTTL
+	}
+	if req.RefreshTokenTTL == 0 {
+		req.RefreshTokenTTL = models.DefaultRefreshTokenTTL
+	}
+	rotateRefreshToken := true
+	if req.RotateRefreshToken != nil {
+		rotateRefreshToken = *req.RotateRefreshToken
+	}
+	requirePKCE := req.RequirePKCE != nil && *req.RequirePKCE
+	if req.AccessTokenFormat == "" {
+		req.AccessTokenFormat = models.TokenFormatJWT
+	}
+	if !models.ValidateAccessTokenFormat(req.AccessTokenFormat) {
+		http.Error(w, "access_token_format must be jwt or opaque", http.StatusBadRequest)
+		return
 	}
 
-	client, err := models.CreateClient(req.Name, req.RedirectURIs, req.Scopes, req.GrantTypes)
+	client, err := models.CreateClient(req.Name, req.RedirectURIs, req.Scopes, req.GrantTypes,
+		req.AccessTokenTTL, req.RefreshTokenTTL, rotateRefreshToken, requirePKCE, req.AccessTokenFormat, req.TokenEndpointAuthMethod, auditActor(r))
 	if err != nil {
 		log.Printf("Failed to create client: %v", err)
 		http.Error(w, "Failed to create client", http.StatusInternalServerError)
@@ -151,16 +330,16 @@ func handleCreateClient(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(client)
 }
 
-// handleGetClient retrieves a specific OAuth2 client
+// handleGetClient retrieves a specific OAuth2 client (including deactivated clients)
 func handleGetClient(w http.ResponseWriter, r *http.Request, clientID string) {
-	client, err := models.GetClientByID(clientID)
+	client, err := models.GetClientByIDIncludingDeleted(clientID)
 	if err != nil {
 		http.Error(w, "Client not found", http.StatusNotFound)
 		return
 	}
 
 	// client_secretを隠す
-	client.ClientSecret = "***"
+	maskClientSecret(client)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(client)
@@ -188,11 +367,59 @@ func handleUpdateClient(w http.ResponseWriter, r *http.Request, clientID string)
 		client.RedirectURIs = req.RedirectURIs
 	}
 	if len(req.Scopes) > 0 {
+		if !validateScopes(w, req.Scopes) {
+			return
+		}
 		client.Scopes = req.Scopes
 	}
 	if len(req.GrantTypes) > 0 {
+		if client.IsPublic() && slices.Contains(req.GrantTypes, "client_credentials") {
+			http.Error(w, "Public clients cannot use the client_credentials grant", http.StatusBadRequest)
+			return
+		}
 		client.GrantTypes = req.GrantTypes
 	}
+	if req.AccessTokenTTL < 0 || req.RefreshTokenTTL < 0 {
+		http.Error(w, "Token TTL must not be negative", http.StatusBadRequest)
+		return
+	}
+	if req.AccessTokenTTL > 0 {
+		client.AccessTokenTTL = req.AccessTokenTTL
+	}
+	if req.RefreshTokenTTL > 0 {
+		client.RefreshTokenTTL = req.RefreshTokenTTL
+	}
+	if req.RotateRefreshToken != nil {
+		client.RotateRefreshToken = *req.RotateRefreshToken
+	}
+	if req.RequirePKCE != nil {
+		if client.IsPublic() && !*req.RequirePKCE {
+			http.Error(w, "PKCE cannot be disabled for public clients", http.StatusBadRequest)
+			return
+		}
+		client.RequirePKCE = *req.RequirePKCE
+	}
+	if req.TokenEndpointAuthMethod != "" {
+		if !models.ValidateTokenEndpointAuthMethod(req.TokenEndpointAuthMethod) {
+			http.Error(w, "token_endpoint_auth_method must be client_secret_basic, client_secret_post or none", http.StatusBadRequest)
+			return
+		}
+		// client_secretの発行・破棄が伴うため、パブリッククライアントとの切り替えは作り直してもらう
+		if (req.TokenEndpointAuthMethod == models.AuthMethodNone) != client.IsPublic() {
+			http.Error(w, "token_endpoint_auth_method cannot be changed between public and confidential clients", http.StatusBadRequest)
+			return
+		}
+		client.TokenEndpointAuthMethod = req.TokenEndpointAuthMethod
+	}
+	if req.AccessTokenFormat != "" {
+		// 変更前に発行したトークンは有効期限まで元の形式のまま使える
+		if !models.ValidateAccessTokenFormat(req.AccessTokenFormat) {
+			http.Error(w, "access_token_format must be jwt or opaque", http.StatusBadRequest)
+			return
+		}
+		client.AccessTokenFormat = req.AccessTokenFormat
+	}
+	client.UpdatedBy = auditActor(r)
 
 	if err := client.Update(); err != nil {
 		log.Printf("Failed to update client: %v", err)
@@ -201,26 +428,219 @@ func handleUpdateClient(w http.ResponseWriter, r *http.Request, clientID string)
 	}
 
 	// client_secretを隠す
-	client.ClientSecret = "***"
+	maskClientSecret(client)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(client)
 }
 
-// handleDeleteClient deletes a specific OAuth2 client
+// maskClientSecret hides the client_secret in API responses. パブリッククライアントはclient_secretを持たないので空のまま返す
+func maskClientSecret(client *models.OAuthClient) {
+	if client.ClientSecret != "" {
+		client.ClientSecret = "***"
+	}
+}
+
+// handleDeleteClient deactivates (soft-deletes) a specific OAuth2 client and revokes its tokens
 func handleDeleteClient(w http.ResponseWriter, r *http.Request, clientID string) {
-	if err := models.DeleteClient(clientID); err != nil {
+	deleted, err := models.DeactivateClient(clientID, auditActor(r))
+	if err != nil {
 		log.Printf("Failed to delete client: %v", err)
 		http.Error(w, "Failed to delete client", http.StatusInternalServerError)
 		return
 	}
+	if !deleted {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// handleGetUsers retrieves all users
+// handleRestoreClient reactivates a deactivated OAuth2 client
+func handleRestoreClient(w http.ResponseWriter, r *http.Request, clientID string) {
+	restored, err := models.RestoreClient(clientID, auditActor(r))
+	if err != nil {
+		log.Printf("Failed to restore client: %v", err)
+		http.Error(w, "Failed to restore client", http.StatusInternalServerError)
+		return
+	}
+	if !restored {
+		http.Error(w, "Deleted client not found", http.StatusNotFound)
+		return
+	}
+
+	handleGetClient(w, r, clientID)
+}
+
+// validateScopes checks that all scopes are registered. It writes an error response and returns false otherwise
+func validateScopes(w http.ResponseWriter, scopes []string) bool {
+	unknown, err := models.FindUnknownScopes(scopes)
+	if err != nil {
+		log.Printf("Failed to validate scopes: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if len(unknown) > 0 {
+		http.Error(w, "Unknown scopes: "+strings.Join(unknown, ", "), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// handleGetScopes retrieves all scopes
+func handleGetScopes(w http.ResponseWriter, r *http.Request) {
+	scopes, err := models.GetAllScopes()
+	if err != nil {
+		log.Printf("Failed to get scopes: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scopes)
+}
+
+// handleCreateScope creates a new scope
+func handleCreateScope(w http.ResponseWriter, r *http.Request) {
+	var req CreateScopeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// バリデーション（スコープはスペース区切りで送られるため空白を含められない）
+	if req.Name == "" || strings.ContainsAny(req.Name, " \t\n\"\\") {
+		http.Error(w, "Invalid scope name", http.StatusBadRequest)
+		return
+	}
+	if req.Description == "" {
+		http.Error(w, "Description is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := models.GetScopeByName(req.Name); err == nil {
+		http.Error(w, "Scope already exists", http.StatusConflict)
+		return
+	}
+
+	scope, err := models.CreateScope(req.Name, req.Description)
+	if err != nil {
+		log.Printf("Failed to create scope: %v", err)
+		http.Error(w, "Failed to create scope", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scope)
+}
+
+// handleDeleteScope deletes a specific scope
+func handleDeleteScope(w http.ResponseWriter, r *http.Request, name string) {
+	if err := models.DeleteScope(name); err != nil {
+		log.Printf("Failed to delete scope: %v", err)
+		http.Error(w, "Failed to delete scope", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetClaimMappings retrieves all scope-to-claim mappings
+func handleGetClaimMappings(w http.ResponseWriter, r *http.Request) {
+	mappings, err := models.GetAllClaimMappings()
+	if err != nil {
+		log.Printf("Failed to get claim mappings: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mappings)
+}
+
+// handleCreateClaimMapping maps a scope to a claim
+func handleCreateClaimMapping(w http.ResponseWriter, r *http.Request) {
+	var req CreateClaimMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// バリデーション
+	if _, err := models.GetScopeByName(req.Scope); err != nil {
+		http.Error(w, "Unknown scope: "+req.Scope, http.StatusBadRequest)
+		return
+	}
+	if req.Claim == "" || strings.ContainsAny(req.Claim, " \t\n\"\\") {
+		http.Error(w, "Invalid claim name", http.StatusBadRequest)
+		return
+	}
+	if slices.Contains(models.ReservedClaims, req.Claim) {
+		http.Error(w, "Reserved claim: "+req.Claim, http.StatusBadRequest)
+		return
+	}
+	if err := models.ValidateClaimSource(req.Source); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.ClientID != "" {
+		if _, err := models.GetClientByID(req.ClientID); err != nil {
+			http.Error(w, "Client not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	mappings, err := models.GetAllClaimMappings()
+	if err != nil {
+		log.Printf("Failed to get claim mappings: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	for _, mapping := range mappings {
+		if mapping.Scope == req.Scope && mapping.Claim == req.Claim && mapping.ClientID == req.ClientID {
+			http.Error(w, "Claim mapping already exists", http.StatusConflict)
+			return
+		}
+	}
+
+	mapping, err := models.CreateClaimMapping(req.Scope, req.Claim, req.Source, req.ClientID)
+	if err != nil {
+		log.Printf("Failed to create claim mapping: %v", err)
+		http.Error(w, "Failed to create claim mapping", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mapping)
+}
+
+// handleDeleteClaimMapping deletes a specific scope-to-claim mapping
+func handleDeleteClaimMapping(w http.ResponseWriter, r *http.Request, id string) {
+	deleted, err := models.DeleteClaimMapping(id)
+	if err != nil {
+		log.Printf("Failed to delete claim mapping: %v", err)
+		http.Error(w, "Failed to delete claim mapping", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Claim mapping not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetUsers retrieves users with pagination and filtering
 func handleGetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := models.GetAllUsers()
+	opts, ok := parseListOptions(w, r)
+	if !ok {
+		return
+	}
+
+	users, total, err := models.ListUsers(opts)
 	if err != nil {
 		log.Printf("Failed to get users: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -228,6 +648,7 @@ func handleGetUsers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 	json.NewEncoder(w).Encode(users)
 }
 
@@ -253,7 +674,7 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := models.CreateUser(req.Email, req.Password, req.Name, req.Profile)
+	user, err := models.CreateUser(req.Email, req.Password, req.Name, req.Profile, auditActor(r))
 	if err != nil {
 		log.Printf("Failed to create user: %v", err)
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
@@ -265,9 +686,9 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-// handleGetUser retrieves a specific user
+// handleGetUser retrieves a specific user (including deactivated users)
 func handleGetUser(w http.ResponseWriter, r *http.Request, userID string) {
-	user, err := models.GetUserByID(userID)
+	user, err := models.GetUserByIDIncludingDeleted(userID)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -298,6 +719,7 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request, userID string) {
 	if req.Profile != nil {
 		user.Profile = req.Profile
 	}
+	user.UpdatedBy = auditActor(r)
 
 	if err := user.Update(); err != nil {
 		log.Printf("Failed to update user: %v", err)
@@ -309,13 +731,94 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request, userID string) {
 	json.NewEncoder(w).Encode(user)
 }
 
-// handleDeleteUser deletes a specific user
+// handleDeleteUser deactivates (soft-deletes) a specific user and revokes the tokens issued on behalf of the user
 func handleDeleteUser(w http.ResponseWriter, r *http.Request, userID string) {
-	if err := models.DeleteUser(userID); err != nil {
+	deleted, err := models.DeactivateUser(userID, auditActor(r))
+	if err != nil {
 		log.Printf("Failed to delete user: %v", err)
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
+	if !deleted {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// handleRestoreUser reactivates a deactivated user
+func handleRestoreUser(w http.ResponseWriter, r *http.Request, userID string) {
+	restored, err := models.RestoreUser(userID, auditActor(r))
+	if err != nil {
+		log.Printf("Failed to restore user: %v", err)
+		http.Error(w, "Failed to restore user", http.StatusInternalServerError)
+		return
+	}
+	if !restored {
+		http.Error(w, "Deleted user not found", http.StatusNotFound)
+		return
+	}
+
+	handleGetUser(w, r, userID)
+}
+
+// parseListOptions parses limit, offset, q and include_deleted query parameters.
+// It writes an error response and returns false if they are invalid
+func parseListOptions(w http.ResponseWriter, r *http.Request) (models.ListOptions, bool) {
+	query := r.URL.Query()
+	opts := models.ListOptions{
+		Limit:          models.DefaultListLimit,
+		Query:          strings.TrimSpace(query.Get("q")),
+		IncludeDeleted: query.Get("include_deleted") == "true",
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > models.MaxListLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(models.MaxListLimit), http.StatusBadRequest)
+			return opts, false
+		}
+		opts.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			http.Error(w, "offset must not be negative", http.StatusBadRequest)
+			return opts, false
+		}
+		opts.Offset = offset
+	}
+
+	return opts, true
+}
+
+// auditActor returns the administrator recorded in the audit fields
+func auditActor(r *http.Request) string {
+	// 管理APIに認証はないため、リクエストヘッダーの値をそのまま記録する
+	if actor := strings.TrimSpace(r.Header.Get(adminUserHeader)); actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+// handleGetKeys retrieves the active key and the retired keys within the grace period
+func handleGetKeys(w http.ResponseWriter, r *http.Request) {
+	// 秘密鍵はJSONに含めない（KeyPair.PrivateKey は json:"-"）
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.GetPublishedKeyPairs())
+}
+
+// handleRotateKeys generates a new signing key and retires the current one
+func handleRotateKeys(w http.ResponseWriter, r *http.Request) {
+	keyPair, err := services.RotateKeys()
+	if err != nil {
+		log.Printf("Failed to rotate keys: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(keyPair)
+}
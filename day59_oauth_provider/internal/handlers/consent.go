@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/models"
+	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/services"
+)
+
+// authorizePageStyle is the shared style of the login and consent pages
+const authorizePageStyle = `
+        body { font-family: Arial, sans-serif; max-width: 600px; margin: 50px auto; padding: 20px; }
+        .auth-form { background: #f5f5f5; padding: 20px; border-radius: 8px; }
+        .client-info { background: #e3f2fd; padding: 15px; border-radius: 5px; margin-bottom: 20px; }
+        .scopes { margin: 15px 0; }
+        .scope-item { margin: 8px 0; }
+        .scope-item small { color: #616161; margin-left: 24px; display: block; }
+        button { background: #1976d2; color: white; padding: 10px 20px; border: none; border-radius: 4px; cursor: pointer; }
+        button:hover { background: #1565c0; }
+        .cancel { background: #757575; margin-left: 10px; }
+        .cancel:hover { background: #616161; }`
+
+// authorizeHiddenFields carries the authorization request parameters between the pages
+const authorizeHiddenFields = `
+            <input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+            <input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+            <input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+            <input type="hidden" name="scope" value="{{.Request.Scope}}">
+            <input type="hidden" name="state" value="{{.Request.State}}">
+            <input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+            <input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+            <input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">`
+
+var loginPageTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>OAuth2 Sign In</title>
+    <style>` + authorizePageStyle + `
+    </style>
+</head>
+<body>
+    <div class="auth-form">
+        <h2>Sign in</h2>
+        <div class="client-info">
+            <h3>Application: {{.Client.Name}}</h3>
+            <p><strong>Client ID:</strong> {{.Client.ID}}</p>
+            <p><strong>Redirect URI:</strong> {{.Request.RedirectURI}}</p>
+        </div>
+
+        <form method="get" action="/authorize">` + authorizeHiddenFields + `
+
+            <label for="user_id">User ID (for demo):</label>
+            <input type="text" name="user_id" id="user_id" placeholder="Enter user ID" required>
+
+            <br><br>
+            <button type="submit">Sign in</button>
+            <button type="button" class="cancel" onclick="window.history.back()">Cancel</button>
+        </form>
+    </div>
+</body>
+</html>`))
+
+var consentPageTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>OAuth2 Authorization</title>
+    <style>` + authorizePageStyle + `
+    </style>
+</head>
+<body>
+    <div class="auth-form">
+        <h2>OAuth2 Authorization Request</h2>
+        <div class="client-info">
+            <h3>Application: {{.Client.Name}}</h3>
+            <p><strong>Client ID:</strong> {{.Client.ID}}</p>
+            <p><strong>Redirect URI:</strong> {{.Request.RedirectURI}}</p>
+        </div>
+
+        <p>Signed in as <strong>{{.User.Name}}</strong> ({{.User.Email}})</p>
+
+        <form method="post" action="/authorize">` + authorizeHiddenFields + `
+            <input type="hidden" name="user_id" value="{{.User.ID}}">
+
+            <div class="scopes">
+                <h4>Requested Permissions:</h4>
+                {{range .Scopes}}
+                <div class="scope-item">
+                    {{if .Required}}
+                    <input type="checkbox" id="scope-{{.Name}}" checked disabled>
+                    <input type="hidden" name="granted_scope" value="{{.Name}}">
+                    {{else}}
+                    <input type="checkbox" name="granted_scope" value="{{.Name}}" id="scope-{{.Name}}" checked>
+                    {{end}}
+                    <label for="scope-{{.Name}}"><strong>{{.Name}}</strong></label>
+                    <small>{{.Description}}</small>
+                </div>
+                {{else}}
+                <div class="scope-item">No specific permissions requested</div>
+                {{end}}
+            </div>
+
+            <p>Do you authorize this application to access your account?</p>
+
+            <button type="submit" name="consent" value="approve">Approve</button>
+            <button type="submit" name="consent" value="deny" class="cancel">Deny</button>
+        </form>
+    </div>
+</body>
+</html>`))
+
+// consentScope is a scope displayed on the consent page
+type consentScope struct {
+	Name        string
+	Description string
+	Required    bool // openidはIDトークンの発行に必要なため外せない
+}
+
+// renderLoginPage renders the (demo) login page
+func renderLoginPage(w http.ResponseWriter, client *models.OAuthClient, req *services.AuthorizeRequest) {
+	data := struct {
+		Client  *models.OAuthClient
+		Request *services.AuthorizeRequest
+	}{client, req}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := loginPageTemplate.Execute(w, data); err != nil {
+		log.Printf("Failed to render login page: %v", err)
+	}
+}
+
+// renderConsentPage renders the consent page listing the requested scopes
+func renderConsentPage(w http.ResponseWriter, client *models.OAuthClient, req *services.AuthorizeRequest, user *models.User) {
+	scopes := make([]consentScope, 0, len(req.Scopes))
+	for _, name := range req.Scopes {
+		description := "Access to " + name
+		if scope, err := models.GetScopeByName(name); err == nil {
+			description = scope.Description
+		}
+		scopes = append(scopes, consentScope{
+			Name:        name,
+			Description: description,
+			Required:    name == "openid",
+		})
+	}
+
+	data := struct {
+		Client  *models.OAuthClient
+		Request *services.AuthorizeRequest
+		User    *models.User
+		Scopes  []consentScope
+	}{client, req, user, scopes}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := consentPageTemplate.Execute(w, data); err != nil {
+		log.Printf("Failed to render consent page: %v", err)
+	}
+}
+
+// grantedScopes returns the requested scopes the user approved, keeping the requested order
+func grantedScopes(requested, approved []string) []string {
+	granted := []string{}
+	for _, scope := range requested {
+		if scope == "openid" || containsScope(approved, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted
+}
+
+// saveConsent adds the granted scopes to the consent of the user for the client
+func saveConsent(userID, clientID string, scopes []string) error {
+	merged := scopes
+	consent, err := models.GetConsent(userID, clientID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if consent != nil {
+		merged = append([]string{}, consent.Scopes...)
+		for _, scope := range scopes {
+			if !consent.HasScope(scope) {
+				merged = append(merged, scope)
+			}
+		}
+	}
+
+	return models.SaveConsent(userID, clientID, merged)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,22 +14,27 @@ import (
 )
 
 // AuthorizeHandler handles the OAuth2 authorization endpoint
+// GETでログイン画面・同意画面を表示し、同意画面からのPOSTで認可コードを発行する
 func AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// パラメータ解析
+	// パラメータ解析（GETはクエリ、POSTは同意画面のフォームから取得）
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
 	req := &services.AuthorizeRequest{
-		ClientID:            r.URL.Query().Get("client_id"),
-		RedirectURI:         r.URL.Query().Get("redirect_uri"),
-		ResponseType:        r.URL.Query().Get("response_type"),
-		Scope:               r.URL.Query().Get("scope"),
-		State:               r.URL.Query().Get("state"),
-		Nonce:               r.URL.Query().Get("nonce"),
-		CodeChallenge:       r.URL.Query().Get("code_challenge"),
-		CodeChallengeMethod: r.URL.Query().Get("code_challenge_method"),
+		ClientID:            r.FormValue("client_id"),
+		RedirectURI:         r.FormValue("redirect_uri"),
+		ResponseType:        r.FormValue("response_type"),
+		Scope:               r.FormValue("scope"),
+		State:               r.FormValue("state"),
+		Nonce:               r.FormValue("nonce"),
+		CodeChallenge:       r.FormValue("code_challenge"),
+		CodeChallengeMethod: r.FormValue("code_challenge_method"),
 	}
 
 	// リクエスト検証
@@ -35,9 +42,13 @@ func AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Printf("Authorization request validation failed: %v", err)
 
-		// エラーレスポンス
-		if req.RedirectURI != "" {
-			redirectURL, _ := services.BuildErrorRedirectURL(req.RedirectURI, "invalid_request", err.Error(), req.State)
+		// エラーレスポンス（クライアントとredirect_uriが確認できた場合のみリダイレクトする）
+		if client != nil {
+			errorCode := "invalid_request"
+			if errors.Is(err, services.ErrInvalidScope) {
+				errorCode = "invalid_scope"
+			}
+			redirectURL, _ := services.BuildErrorRedirectURL(req.RedirectURI, errorCode, err.Error(), req.State)
 			http.Redirect(w, r, redirectURL, http.StatusFound)
 			return
 		}
@@ -47,68 +58,10 @@ func AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 簡易的なユーザー認証（実際の実装では適切な認証フローが必要）
-	// ここでは、クエリパラメータでuser_idを受け取る簡易実装
-	userID := r.URL.Query().Get("user_id")
+	// ここでは、user_idを受け取る簡易実装
+	userID := r.FormValue("user_id")
 	if userID == "" {
-		// 認証が必要な場合のレスポンス（実際にはログイン画面にリダイレクト）
-		w.Header().Set("Content-Type", "text/html")
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>OAuth2 Authorization</title>
-    <style>
-        body { font-family: Arial, sans-serif; max-width: 600px; margin: 50px auto; padding: 20px; }
-        .auth-form { background: #f5f5f5; padding: 20px; border-radius: 8px; }
-        .client-info { background: #e3f2fd; padding: 15px; border-radius: 5px; margin-bottom: 20px; }
-        .scopes { margin: 15px 0; }
-        .scope-item { margin: 5px 0; }
-        button { background: #1976d2; color: white; padding: 10px 20px; border: none; border-radius: 4px; cursor: pointer; }
-        button:hover { background: #1565c0; }
-        .cancel { background: #757575; margin-left: 10px; }
-        .cancel:hover { background: #616161; }
-    </style>
-</head>
-<body>
-    <div class="auth-form">
-        <h2>OAuth2 Authorization Request</h2>
-        <div class="client-info">
-            <h3>Application: %s</h3>
-            <p><strong>Client ID:</strong> %s</p>
-            <p><strong>Redirect URI:</strong> %s</p>
-        </div>
-
-        <div class="scopes">
-            <h4>Requested Permissions:</h4>
-            %s
-        </div>
-
-        <p>Do you authorize this application to access your account?</p>
-
-        <form method="get" action="/authorize">
-            <input type="hidden" name="client_id" value="%s">
-            <input type="hidden" name="redirect_uri" value="%s">
-            <input type="hidden" name="response_type" value="%s">
-            <input type="hidden" name="scope" value="%s">
-            <input type="hidden" name="state" value="%s">
-            <input type="hidden" name="nonce" value="%s">
-            <input type="hidden" name="code_challenge" value="%s">
-            <input type="hidden" name="code_challenge_method" value="%s">
-
-            <label for="user_id">User ID (for demo):</label>
-            <input type="text" name="user_id" id="user_id" placeholder="Enter user ID" required>
-
-            <br><br>
-            <button type="submit">Authorize</button>
-            <button type="button" class="cancel" onclick="window.history.back()">Cancel</button>
-        </form>
-    </div>
-</body>
-</html>`,
-			client.Name, client.ID, req.RedirectURI,
-			formatScopes(req.Scopes),
-			req.ClientID, req.RedirectURI, req.ResponseType, req.Scope, req.State, req.Nonce, req.CodeChallenge, req.CodeChallengeMethod)
+		renderLoginPage(w, client, req)
 		return
 	}
 
@@ -121,6 +74,41 @@ func AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 同意の確認（承認・拒否は同意画面からのPOSTのみ受け付ける）
+	decision := ""
+	if r.Method == http.MethodPost {
+		decision = r.PostFormValue("consent")
+	}
+
+	switch decision {
+	case "deny":
+		log.Printf("Authorization denied by user %s for client %s", user.ID, client.ID)
+		redirectURL, _ := services.BuildErrorRedirectURL(req.RedirectURI, "access_denied", "The user denied the request", req.State)
+		http.Redirect(w, r, redirectURL, http.StatusFound)
+		return
+	case "approve":
+		// ユーザーがチェックを外したスコープは付与しない
+		req.Scopes = grantedScopes(req.Scopes, r.PostForm["granted_scope"])
+		if err := saveConsent(user.ID, client.ID, req.Scopes); err != nil {
+			log.Printf("Failed to save consent: %v", err)
+			redirectURL, _ := services.BuildErrorRedirectURL(req.RedirectURI, "server_error", "Failed to save consent", req.State)
+			http.Redirect(w, r, redirectURL, http.StatusFound)
+			return
+		}
+	default:
+		// 要求されたスコープをすべて許可済みなら同意画面を省略する
+		consent, err := models.GetConsent(user.ID, client.ID)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Failed to get consent: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if consent == nil || !consent.Covers(req.Scopes) {
+			renderConsentPage(w, client, req, user)
+			return
+		}
+	}
+
 	// 認可コード生成
 	authCode, err := services.CreateAuthorizationCode(req, user.ID)
 	if err != nil {
@@ -138,7 +126,7 @@ func AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Authorization successful for user %s, client %s", user.ID, client.ID)
+	log.Printf("Authorization successful for user %s, client %s (scopes: %v)", user.ID, client.ID, req.Scopes)
 	http.Redirect(w, r, redirectURL, http.StatusFound)
 }
 
@@ -183,7 +171,17 @@ func TokenHandler(w http.ResponseWriter, r *http.Request) {
 	client, err := services.ValidateTokenRequest(req)
 	if err != nil {
 		log.Printf("Token request validation failed: %v", err)
-		writeErrorResponse(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		switch {
+		case errors.Is(err, services.ErrInvalidClient):
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth2-provider"`)
+			writeErrorResponse(w, "invalid_client", err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, services.ErrUnauthorizedClient):
+			writeErrorResponse(w, "unauthorized_client", err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrUnsupportedGrantType):
+			writeErrorResponse(w, "unsupported_grant_type", err.Error(), http.StatusBadRequest)
+		default:
+			writeErrorResponse(w, "invalid_request", err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 
@@ -203,7 +201,11 @@ func TokenHandler(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		log.Printf("Token grant processing failed: %v", err)
-		writeErrorResponse(w, "invalid_grant", err.Error(), http.StatusBadRequest)
+		errorCode := "invalid_grant"
+		if errors.Is(err, services.ErrInvalidScope) {
+			errorCode = "invalid_scope"
+		}
+		writeErrorResponse(w, errorCode, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -221,6 +223,95 @@ func TokenHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Token issued successfully for client %s, grant_type %s", client.ID, req.GrantType)
 }
 
+// IntrospectHandler handles the OAuth2 token introspection endpoint (RFC 7662)
+func IntrospectHandler(w http.ResponseWriter, r *http.Request) {
+	client, token, ok := parseTokenManagementRequest(w, r)
+	if !ok {
+		return
+	}
+	// client_idだけで誰でも問い合わせられてしまうため、パブリッククライアントにはイントロスペクションを許可しない
+	if client.IsPublic() {
+		writeErrorResponse(w, "unauthorized_client", "Public clients cannot use token introspection", http.StatusBadRequest)
+		return
+	}
+
+	response := services.IntrospectToken(token, r.FormValue("token_type_hint"))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode introspection response: %v", err)
+		return
+	}
+
+	log.Printf("Token introspected by client %s (active: %t)", client.ID, response.Active)
+}
+
+// RevokeHandler handles the OAuth2 token revocation endpoint (RFC 7009)
+func RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	client, token, ok := parseTokenManagementRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := services.RevokeToken(token, r.FormValue("token_type_hint"), client); err != nil {
+		if errors.Is(err, services.ErrTokenNotOwned) {
+			writeErrorResponse(w, "unauthorized_client", err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Token revocation failed: %v", err)
+		writeErrorResponse(w, "server_error", "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	// 不明なトークンの場合も200を返す（RFC 7009 2.2）
+	w.WriteHeader(http.StatusOK)
+	log.Printf("Token revoked by client %s", client.ID)
+}
+
+// parseTokenManagementRequest authenticates the client and extracts the token for introspection and revocation.
+// It writes an error response and returns false if the request is invalid
+func parseTokenManagementRequest(w http.ResponseWriter, r *http.Request) (*models.OAuthClient, string, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, "", false
+	}
+
+	if !strings.Contains(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		writeErrorResponse(w, "invalid_request", "Content-Type must be application/x-www-form-urlencoded", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeErrorResponse(w, "invalid_request", "Failed to parse form data", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	// クライアント認証（リソースサーバーもクライアントとして登録して利用する）
+	clientID, clientSecret := getClientCredentials(r)
+	client, err := models.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		log.Printf("Client authentication failed: %v", err)
+		writeErrorResponse(w, "server_error", "Client authentication failed", http.StatusInternalServerError)
+		return nil, "", false
+	}
+	if client == nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth2-provider"`)
+		writeErrorResponse(w, "invalid_client", "Invalid client credentials", http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		writeErrorResponse(w, "invalid_request", "token is required", http.StatusBadRequest)
+		return nil, "", false
+	}
+
+	return client, token, true
+}
+
 // getClientCredentials extracts client credentials from Basic auth or form data
 func getClientCredentials(r *http.Request) (clientID, clientSecret string) {
 	// Basic認証を試行
@@ -244,33 +335,3 @@ func writeErrorResponse(w http.ResponseWriter, errorCode, description string, st
 
 	json.NewEncoder(w).Encode(errorResp)
 }
-
-// formatScopes formats scopes for HTML display
-func formatScopes(scopes []string) string {
-	if len(scopes) == 0 {
-		return "<div class='scope-item'>No specific permissions requested</div>"
-	}
-
-	var result strings.Builder
-	for _, scope := range scopes {
-		description := getScopeDescription(scope)
-		result.WriteString(fmt.Sprintf("<div class='scope-item'>• %s: %s</div>", scope, description))
-	}
-	return result.String()
-}
-
-// getScopeDescription returns a human-readable description for a scope
-func getScopeDescription(scope string) string {
-	descriptions := map[string]string{
-		"openid":  "Verify your identity",
-		"profile": "Access your basic profile information",
-		"email":   "Access your email address",
-		"read":    "Read access to your data",
-		"write":   "Write access to your data",
-	}
-
-	if desc, exists := descriptions[scope]; exists {
-		return desc
-	}
-	return "Access to " + scope
-}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/models"
 	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/services"
@@ -14,6 +15,8 @@ type OpenIDConfiguration struct {
 	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
 	TokenEndpoint                     string   `json:"token_endpoint"`
 	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
 	JwksURI                           string   `json:"jwks_uri"`
 	ScopesSupported                   []string `json:"scopes_supported"`
 	ResponseTypesSupported            []string `json:"response_types_supported"`
@@ -24,13 +27,6 @@ type OpenIDConfiguration struct {
 	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
 }
 
-// UserInfoResponse represents the userinfo endpoint response
-type UserInfoResponse struct {
-	Sub   string `json:"sub"`
-	Email string `json:"email,omitempty"`
-	Name  string `json:"name,omitempty"`
-}
-
 // DiscoveryHandler handles the OpenID Connect discovery endpoint
 func DiscoveryHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -40,19 +36,26 @@ func DiscoveryHandler(w http.ResponseWriter, r *http.Request) {
 
 	baseURL := "http://localhost:8081"
 
+	// 登録済みのスコープを公開する
+	scopes, err := models.GetAllScopes()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	scopesSupported := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scopesSupported = append(scopesSupported, scope.Name)
+	}
+
 	config := OpenIDConfiguration{
 		Issuer:                baseURL,
 		AuthorizationEndpoint: baseURL + "/authorize",
 		TokenEndpoint:         baseURL + "/token",
 		UserinfoEndpoint:      baseURL + "/userinfo",
+		IntrospectionEndpoint: baseURL + "/introspect",
+		RevocationEndpoint:    baseURL + "/revoke",
 		JwksURI:               baseURL + "/.well-known/jwks.json",
-		ScopesSupported: []string{
-			"openid",
-			"profile",
-			"email",
-			"read",
-			"write",
-		},
+		ScopesSupported:       scopesSupported,
 		ResponseTypesSupported: []string{
 			"code",
 		},
@@ -70,6 +73,7 @@ func DiscoveryHandler(w http.ResponseWriter, r *http.Request) {
 		TokenEndpointAuthMethodsSupported: []string{
 			"client_secret_post",
 			"client_secret_basic",
+			"none",
 		},
 		CodeChallengeMethodsSupported: []string{
 			"S256",
@@ -128,21 +132,16 @@ func UserInfoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// トークン検証
-	claims, err := services.ValidateAccessToken(token)
+	// トークン検証（失効済みのトークンも拒否する）
+	claims, err := services.ValidateActiveAccessToken(token)
 	if err != nil {
-		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
 		http.Error(w, "Invalid token", http.StatusUnauthorized)
 		return
 	}
 
 	// スコープ確認（openidスコープが必要）
-	scopes, err := services.GetScopesFromToken(token)
-	if err != nil {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
+	scopes := strings.Fields(claims.Scope)
 	if !containsScope(scopes, "openid") {
 		http.Error(w, "Insufficient scope", http.StatusForbidden)
 		return
@@ -155,17 +154,15 @@ func UserInfoHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// レスポンス構築
-	userInfo := UserInfoResponse{
-		Sub: user.ID,
+	// スコープとクレームの対応（クライアント別の設定を含む）に従ってレスポンスを構築
+	clientID := ""
+	if len(claims.Audience) > 0 {
+		clientID = claims.Audience[0]
 	}
-
-	// スコープに応じて情報を追加
-	if containsScope(scopes, "email") {
-		userInfo.Email = user.Email
-	}
-	if containsScope(scopes, "profile") {
-		userInfo.Name = user.Name
+	userInfo, err := user.GetUserInfoClaims(clientID, scopes)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -2,6 +2,7 @@ package models
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
@@ -123,7 +124,7 @@ func (ac *AuthorizationCode) ValidatePKCE(codeVerifier string) bool {
 	hash := sha256.Sum256([]byte(codeVerifier))
 	encodedHash := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(hash[:])
 
-	return encodedHash == ac.CodeChallenge
+	return subtle.ConstantTimeCompare([]byte(encodedHash), []byte(ac.CodeChallenge)) == 1
 }
 
 // CleanupExpiredCodes deletes expired authorization codes
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/database"
+)
+
+// profileSourcePrefix is the prefix of claim sources that read a key of the user's profile
+const profileSourcePrefix = "profile."
+
+// ReservedClaims are the claims set by the token service that cannot be mapped from a scope
+var ReservedClaims = []string{"iss", "sub", "aud", "exp", "iat", "nbf", "jti", "nonce", "scope", "azp", "auth_time"}
+
+// ClaimMapping maps a scope to a claim included in the ID token and the userinfo response
+type ClaimMapping struct {
+	ID        string    `json:"id"`
+	Scope     string    `json:"scope"`
+	Claim     string    `json:"claim"`
+	Source    string    `json:"source"`              // email / name / profile.<key>
+	ClientID  string    `json:"client_id,omitempty"` // 空の場合は全クライアント共通
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidateClaimSource checks that the source is one of email, name or profile.<key>
+func ValidateClaimSource(source string) error {
+	switch {
+	case source == "email", source == "name":
+		return nil
+	case strings.HasPrefix(source, profileSourcePrefix) && len(source) > len(profileSourcePrefix):
+		return nil
+	}
+	return fmt.Errorf("invalid claim source %q (must be email, name or profile.<key>)", source)
+}
+
+// CreateClaimMapping creates a new scope-to-claim mapping
+func CreateClaimMapping(scope, claim, source, clientID string) (*ClaimMapping, error) {
+	mapping := &ClaimMapping{
+		ID:        uuid.New().String(),
+		Scope:     scope,
+		Claim:     claim,
+		Source:    source,
+		ClientID:  clientID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := `INSERT INTO scope_claims (id, scope, claim, source, client_id, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := database.DB.Exec(query, mapping.ID, mapping.Scope, mapping.Claim, mapping.Source, mapping.ClientID, mapping.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+// GetAllClaimMappings retrieves all scope-to-claim mappings
+func GetAllClaimMappings() ([]*ClaimMapping, error) {
+	return queryClaimMappings(`SELECT id, scope, claim, source, client_id, created_at
+			  FROM scope_claims ORDER BY client_id, scope, claim`)
+}
+
+// DeleteClaimMapping deletes a scope-to-claim mapping. It returns false if the mapping does not exist
+func DeleteClaimMapping(id string) (bool, error) {
+	result, err := database.DB.Exec(`DELETE FROM scope_claims WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetClaimMappings returns the mappings applied to the client for the granted scopes.
+// クライアント別の設定があるスコープは、共通設定の代わりにクライアント別の設定を使う
+func GetClaimMappings(clientID string, scopes []string) ([]*ClaimMapping, error) {
+	mappings, err := queryClaimMappings(`SELECT id, scope, claim, source, client_id, created_at
+			  FROM scope_claims WHERE client_id = '' OR client_id = ? ORDER BY scope, claim`, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := make(map[string]bool)
+	for _, mapping := range mappings {
+		if mapping.ClientID != "" {
+			overridden[mapping.Scope] = true
+		}
+	}
+
+	var applied []*ClaimMapping
+	for _, mapping := range mappings {
+		if !slices.Contains(scopes, mapping.Scope) {
+			continue
+		}
+		if mapping.ClientID == "" && overridden[mapping.Scope] {
+			continue
+		}
+		applied = append(applied, mapping)
+	}
+
+	return applied, nil
+}
+
+// GetUserInfoClaims returns the user's claims for the ID token and the userinfo endpoint
+// according to the scope-to-claim mappings applied to the client.
+// 値のないクレーム（プロフィールにないキーなど）は含めない
+func (u *User) GetUserInfoClaims(clientID string, scopes []string) (map[string]interface{}, error) {
+	mappings, err := GetClaimMappings(clientID, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	for _, mapping := range mappings {
+		if value, ok := u.claimValue(mapping.Source); ok {
+			claims[mapping.Claim] = value
+		}
+	}
+
+	// sub は常に含める
+	claims["sub"] = u.ID
+
+	return claims, nil
+}
+
+// claimValue returns the value of the user attribute referenced by a claim source
+func (u *User) claimValue(source string) (interface{}, bool) {
+	switch source {
+	case "email":
+		return u.Email, u.Email != ""
+	case "name":
+		return u.Name, u.Name != ""
+	}
+
+	if key := strings.TrimPrefix(source, profileSourcePrefix); key != source && u.Profile != nil {
+		value, ok := u.Profile[key]
+		return value, ok && value != nil
+	}
+	return nil, false
+}
+
+// queryClaimMappings runs a query selecting the scope_claims columns
+func queryClaimMappings(query string, args ...interface{}) ([]*ClaimMapping, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mappings := []*ClaimMapping{}
+	for rows.Next() {
+		var mapping ClaimMapping
+		if err := rows.Scan(&mapping.ID, &mapping.Scope, &mapping.Claim, &mapping.Source,
+			&mapping.ClientID, &mapping.CreatedAt); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, &mapping)
+	}
+
+	return mappings, rows.Err()
+}
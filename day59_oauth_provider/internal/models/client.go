@@ -1,6 +1,7 @@
 package models
 
 import (
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"time"
@@ -9,38 +10,94 @@ import (
 	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/database"
 )
 
+const (
+	// DefaultAccessTokenTTL is the default access token lifetime in seconds
+	DefaultAccessTokenTTL = 3600 // 1時間
+	// DefaultRefreshTokenTTL is the default refresh token lifetime in seconds
+	DefaultRefreshTokenTTL = 30 * 24 * 3600 // 30日
+)
+
+// Access token formats
+const (
+	// TokenFormatJWT issues self-contained JWT access tokens
+	TokenFormatJWT = "jwt"
+	// TokenFormatOpaque issues random access tokens resolved from the access_tokens table
+	TokenFormatOpaque = "opaque"
+)
+
+// Token endpoint authentication methods (RFC 7591 2.)
+const (
+	AuthMethodClientSecretBasic = "client_secret_basic" // コンフィデンシャルクライアント（Basic認証）
+	AuthMethodClientSecretPost  = "client_secret_post"  // コンフィデンシャルクライアント（フォームのclient_secret）
+	AuthMethodNone              = "none"                // パブリッククライアント（client_secretなし、client_idのみで認証しPKCE必須）
+)
+
 // OAuthClient represents an OAuth2 client
 type OAuthClient struct {
-	ID           string    `json:"id"`
-	ClientSecret string    `json:"client_secret,omitempty"`
-	Name         string    `json:"name"`
-	RedirectURIs []string  `json:"redirect_uris"`
-	Scopes       []string  `json:"scopes"`
-	GrantTypes   []string  `json:"grant_types"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-}
-
-// CreateClient creates a new OAuth2 client
-func CreateClient(name string, redirectURIs, scopes, grantTypes []string) (*OAuthClient, error) {
+	ID                      string     `json:"id"`
+	ClientSecret            string     `json:"client_secret,omitempty"`
+	Name                    string     `json:"name"`
+	RedirectURIs            []string   `json:"redirect_uris"`
+	Scopes                  []string   `json:"scopes"`
+	GrantTypes              []string   `json:"grant_types"`
+	AccessTokenTTL          int        `json:"access_token_ttl"`           // 秒
+	RefreshTokenTTL         int        `json:"refresh_token_ttl"`          // 秒
+	RotateRefreshToken      bool       `json:"rotate_refresh_token"`       // リフレッシュ時に新しいリフレッシュトークンを発行する
+	RequirePKCE             bool       `json:"require_pkce"`               // 認可コードフローでPKCE（S256）を必須にする（パブリッククライアントは常に必須）
+	AccessTokenFormat       string     `json:"access_token_format"`        // jwt / opaque
+	TokenEndpointAuthMethod string     `json:"token_endpoint_auth_method"` // client_secret_basic / client_secret_post / none（パブリッククライアント）
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+	CreatedBy               string     `json:"created_by,omitempty"`
+	UpdatedBy               string     `json:"updated_by,omitempty"`
+	DeletedAt               *time.Time `json:"deleted_at,omitempty"` // 論理削除（無効化）された日時
+	DeletedBy               string     `json:"deleted_by,omitempty"`
+}
+
+const clientColumns = `id, client_secret, name, redirect_uris, scopes, grant_types,
+			  access_token_ttl, refresh_token_ttl, rotate_refresh_token, require_pkce, access_token_format, token_endpoint_auth_method,
+			  created_at, updated_at, created_by, updated_by, deleted_at, deleted_by`
+
+// CreateClient creates a new OAuth2 client. Public clients (AuthMethodNone) get no client_secret and always require PKCE
+func CreateClient(name string, redirectURIs, scopes, grantTypes []string, accessTokenTTL, refreshTokenTTL int, rotateRefreshToken, requirePKCE bool, accessTokenFormat, tokenEndpointAuthMethod, createdBy string) (*OAuthClient, error) {
+	clientSecret := uuid.New().String()
+	if tokenEndpointAuthMethod == AuthMethodNone {
+		clientSecret = ""
+		requirePKCE = true
+	}
+
+	now := time.Now().UTC()
 	client := &OAuthClient{
-		ID:           uuid.New().String(),
-		ClientSecret: uuid.New().String(),
-		Name:         name,
-		RedirectURIs: redirectURIs,
-		Scopes:       scopes,
-		GrantTypes:   grantTypes,
+		ID:                      uuid.New().String(),
+		ClientSecret:            clientSecret,
+		Name:                    name,
+		RedirectURIs:            redirectURIs,
+		Scopes:                  scopes,
+		GrantTypes:              grantTypes,
+		AccessTokenTTL:          accessTokenTTL,
+		RefreshTokenTTL:         refreshTokenTTL,
+		RotateRefreshToken:      rotateRefreshToken,
+		RequirePKCE:             requirePKCE,
+		AccessTokenFormat:       accessTokenFormat,
+		CreatedAt:               now,
+		TokenEndpointAuthMethod: tokenEndpointAuthMethod,
+		UpdatedAt:               now,
+		CreatedBy:               createdBy,
+		UpdatedBy:               createdBy,
 	}
 
 	redirectURIsJSON, _ := json.Marshal(redirectURIs)
 	scopesJSON, _ := json.Marshal(scopes)
 	grantTypesJSON, _ := json.Marshal(grantTypes)
 
-	query := `INSERT INTO oauth_clients (id, client_secret, name, redirect_uris, scopes, grant_types)
-			  VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO oauth_clients (id, client_secret, name, redirect_uris, scopes, grant_types, access_token_ttl, refresh_token_ttl, rotate_refresh_token, require_pkce,
+			  access_token_format, token_endpoint_auth_method, created_at, updated_at, created_by, updated_by)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := database.DB.Exec(query, client.ID, client.ClientSecret, client.Name,
-		string(redirectURIsJSON), string(scopesJSON), string(grantTypesJSON))
+		string(redirectURIsJSON), string(scopesJSON), string(grantTypesJSON),
+		client.AccessTokenTTL, client.RefreshTokenTTL, client.RotateRefreshToken, client.RequirePKCE,
+		client.AccessTokenFormat, client.TokenEndpointAuthMethod, client.CreatedAt, client.UpdatedAt, client.CreatedBy, client.UpdatedBy)
 	if err != nil {
 		return nil, err
 	}
@@ -48,18 +105,57 @@ func CreateClient(name string, redirectURIs, scopes, grantTypes []string) (*OAut
 	return client, nil
 }
 
-// GetClientByID retrieves a client by ID
+// GetClientByID retrieves an active (not deleted) client by ID
 func GetClientByID(clientID string) (*OAuthClient, error) {
-	query := `SELECT id, client_secret, name, redirect_uris, scopes, grant_types, created_at, updated_at
-			  FROM oauth_clients WHERE id = ?`
+	query := `SELECT ` + clientColumns + ` FROM oauth_clients WHERE id = ? AND deleted_at IS NULL`
+	return scanClient(database.DB.QueryRow(query, clientID))
+}
+
+// GetClientByIDIncludingDeleted retrieves a client by ID, including deleted clients (for admin purposes)
+func GetClientByIDIncludingDeleted(clientID string) (*OAuthClient, error) {
+	query := `SELECT ` + clientColumns + ` FROM oauth_clients WHERE id = ?`
+	return scanClient(database.DB.QueryRow(query, clientID))
+}
+
+// ListClients retrieves clients matching the options, newest first, and the total number of matching clients
+func ListClients(opts ListOptions) ([]*OAuthClient, int, error) {
+	where, args := listWhere(opts, "name")
 
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM oauth_clients`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + clientColumns + ` FROM oauth_clients` + where + ` ORDER BY created_at DESC, id LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, opts.Limit, opts.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	clients := []*OAuthClient{}
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, total, rows.Err()
+}
+
+// scanClient scans a row selected with clientColumns
+func scanClient(row interface{ Scan(...interface{}) error }) (*OAuthClient, error) {
 	var client OAuthClient
 	var redirectURIsJSON, scopesJSON, grantTypesJSON string
 
-	err := database.DB.QueryRow(query, clientID).Scan(
+	err := row.Scan(
 		&client.ID, &client.ClientSecret, &client.Name,
 		&redirectURIsJSON, &scopesJSON, &grantTypesJSON,
-		&client.CreatedAt, &client.UpdatedAt,
+		&client.AccessTokenTTL, &client.RefreshTokenTTL, &client.RotateRefreshToken, &client.RequirePKCE,
+		&client.AccessTokenFormat, &client.TokenEndpointAuthMethod, &client.CreatedAt, &client.UpdatedAt,
+		&client.CreatedBy, &client.UpdatedBy, &client.DeletedAt, &client.DeletedBy,
 	)
 	if err != nil {
 		return nil, err
@@ -72,59 +168,65 @@ func GetClientByID(clientID string) (*OAuthClient, error) {
 	return &client, nil
 }
 
-// GetAllClients retrieves all clients
-func GetAllClients() ([]*OAuthClient, error) {
-	query := `SELECT id, client_secret, name, redirect_uris, scopes, grant_types, created_at, updated_at
-			  FROM oauth_clients ORDER BY created_at DESC`
-
-	rows, err := database.DB.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var clients []*OAuthClient
-	for rows.Next() {
-		var client OAuthClient
-		var redirectURIsJSON, scopesJSON, grantTypesJSON string
-
-		err := rows.Scan(
-			&client.ID, &client.ClientSecret, &client.Name,
-			&redirectURIsJSON, &scopesJSON, &grantTypesJSON,
-			&client.CreatedAt, &client.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		_ = json.Unmarshal([]byte(redirectURIsJSON), &client.RedirectURIs)
-		_ = json.Unmarshal([]byte(scopesJSON), &client.Scopes)
-		_ = json.Unmarshal([]byte(grantTypesJSON), &client.GrantTypes)
-
-		clients = append(clients, &client)
-	}
-
-	return clients, nil
-}
-
-// UpdateClient updates an existing client
+// Update updates an existing client. UpdatedBy must be set by the caller.
+// client_secretは更新しないため、パブリッククライアントとコンフィデンシャルクライアントを切り替えないこと
 func (c *OAuthClient) Update() error {
 	redirectURIsJSON, _ := json.Marshal(c.RedirectURIs)
 	scopesJSON, _ := json.Marshal(c.Scopes)
 	grantTypesJSON, _ := json.Marshal(c.GrantTypes)
 
-	query := `UPDATE oauth_clients SET name = ?, redirect_uris = ?, scopes = ?, grant_types = ?, updated_at = CURRENT_TIMESTAMP
+	c.UpdatedAt = time.Now().UTC()
+	query := `UPDATE oauth_clients SET name = ?, redirect_uris = ?, scopes = ?, grant_types = ?,
+			  access_token_ttl = ?, refresh_token_ttl = ?, rotate_refresh_token = ?, require_pkce = ?, access_token_format = ?,
+			  token_endpoint_auth_method = ?, updated_at = ?, updated_by = ?
 			  WHERE id = ?`
 
-	_, err := database.DB.Exec(query, c.Name, string(redirectURIsJSON), string(scopesJSON), string(grantTypesJSON), c.ID)
+	_, err := database.DB.Exec(query, c.Name, string(redirectURIsJSON), string(scopesJSON), string(grantTypesJSON),
+		c.AccessTokenTTL, c.RefreshTokenTTL, c.RotateRefreshToken, c.RequirePKCE, c.AccessTokenFormat,
+		c.TokenEndpointAuthMethod, c.UpdatedAt, c.UpdatedBy, c.ID)
 	return err
 }
 
-// DeleteClient deletes a client
-func DeleteClient(clientID string) error {
-	query := `DELETE FROM oauth_clients WHERE id = ?`
-	_, err := database.DB.Exec(query, clientID)
-	return err
+// DeactivateClient soft-deletes a client. The client can no longer authenticate or start authorization,
+// and the tokens issued to it are revoked. It returns false if the client does not exist or is already deactivated
+func DeactivateClient(clientID, deletedBy string) (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE oauth_clients SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`,
+		time.Now().UTC(), deletedBy, clientID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	// 発行済みトークンを失効させる
+	if _, err := tx.Exec(`UPDATE access_tokens SET status = ? WHERE client_id = ? AND status = ?`,
+		TokenStatusRevoked, clientID, TokenStatusActive); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM refresh_tokens WHERE client_id = ?`, clientID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// RestoreClient reactivates a deactivated client. It returns false if the client is not deactivated
+func RestoreClient(clientID, updatedBy string) (bool, error) {
+	query := `UPDATE oauth_clients SET deleted_at = NULL, deleted_by = '', updated_at = ?, updated_by = ?
+			  WHERE id = ? AND deleted_at IS NOT NULL`
+	result, err := database.DB.Exec(query, time.Now().UTC(), updatedBy, clientID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
 }
 
 // ValidateRedirectURI checks if the redirect URI is valid for this client
@@ -157,7 +259,50 @@ func (c *OAuthClient) HasGrantType(grantType string) bool {
 	return false
 }
 
-// AuthenticateClient authenticates a client using client_id and client_secret
+// AccessTokenLifetime returns the access token lifetime for this client
+func (c *OAuthClient) AccessTokenLifetime() time.Duration {
+	if c.AccessTokenTTL <= 0 {
+		return DefaultAccessTokenTTL * time.Second
+	}
+	return time.Duration(c.AccessTokenTTL) * time.Second
+}
+
+// IssuesOpaqueAccessTokens reports whether the client receives opaque access tokens instead of JWTs
+func (c *OAuthClient) IssuesOpaqueAccessTokens() bool {
+	return c.AccessTokenFormat == TokenFormatOpaque
+}
+
+// ValidateAccessTokenFormat checks that the format is jwt or opaque
+func ValidateAccessTokenFormat(format string) bool {
+	return format == TokenFormatJWT || format == TokenFormatOpaque
+}
+
+// ValidateTokenEndpointAuthMethod checks that the method is client_secret_basic, client_secret_post or none
+func ValidateTokenEndpointAuthMethod(method string) bool {
+	return method == AuthMethodClientSecretBasic || method == AuthMethodClientSecretPost || method == AuthMethodNone
+}
+
+// IsPublic reports whether the client is a public client without a client_secret
+func (c *OAuthClient) IsPublic() bool {
+	return c.TokenEndpointAuthMethod == AuthMethodNone
+}
+
+// PKCERequired reports whether authorization requests from this client must use PKCE.
+// パブリッククライアントは認可コードの横取りを防ぐ手段がPKCEしかないため、設定にかかわらず必須にする
+func (c *OAuthClient) PKCERequired() bool {
+	return c.RequirePKCE || c.IsPublic()
+}
+
+// RefreshTokenLifetime returns the refresh token lifetime for this client
+func (c *OAuthClient) RefreshTokenLifetime() time.Duration {
+	if c.RefreshTokenTTL <= 0 {
+		return DefaultRefreshTokenTTL * time.Second
+	}
+	return time.Duration(c.RefreshTokenTTL) * time.Second
+}
+
+// AuthenticateClient authenticates a client using client_id and client_secret.
+// パブリッククライアントはclient_idのみで認証し、client_secretが送られた場合は失敗とする
 func AuthenticateClient(clientID, clientSecret string) (*OAuthClient, error) {
 	client, err := GetClientByID(clientID)
 	if err != nil {
@@ -167,7 +312,14 @@ func AuthenticateClient(clientID, clientSecret string) (*OAuthClient, error) {
 		return nil, err
 	}
 
-	if client.ClientSecret != clientSecret {
+	if client.IsPublic() {
+		if clientSecret != "" {
+			return nil, nil // 認証失敗
+		}
+		return client, nil
+	}
+
+	if clientSecret == "" || subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(clientSecret)) != 1 {
 		return nil, nil // 認証失敗
 	}
 
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/database"
+)
+
+// Consent represents the scopes a user has granted to a client
+type Consent struct {
+	UserID    string    `json:"user_id"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveConsent creates or replaces the consent of a user for a client
+func SaveConsent(userID, clientID string, scopes []string) error {
+	scopesJSON, _ := json.Marshal(scopes)
+
+	query := `INSERT INTO user_consents (user_id, client_id, scopes) VALUES (?, ?, ?)
+			  ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = excluded.scopes, updated_at = CURRENT_TIMESTAMP`
+
+	_, err := database.DB.Exec(query, userID, clientID, string(scopesJSON))
+	return err
+}
+
+// GetConsent retrieves the consent of a user for a client
+func GetConsent(userID, clientID string) (*Consent, error) {
+	query := `SELECT user_id, client_id, scopes, created_at, updated_at
+			  FROM user_consents WHERE user_id = ? AND client_id = ?`
+
+	var consent Consent
+	var scopesJSON string
+
+	err := database.DB.QueryRow(query, userID, clientID).Scan(
+		&consent.UserID, &consent.ClientID, &scopesJSON, &consent.CreatedAt, &consent.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = json.Unmarshal([]byte(scopesJSON), &consent.Scopes)
+
+	return &consent, nil
+}
+
+// Covers checks if all the given scopes have been granted
+func (c *Consent) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !c.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasScope checks if the scope has been granted
+func (c *Consent) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
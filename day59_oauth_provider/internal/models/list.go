@@ -0,0 +1,48 @@
+package models
+
+import "strings"
+
+const (
+	// DefaultListLimit is the default page size of the admin list APIs
+	DefaultListLimit = 50
+	// MaxListLimit is the maximum page size of the admin list APIs
+	MaxListLimit = 100
+)
+
+// ListOptions represents pagination and filtering options for the admin list APIs
+type ListOptions struct {
+	Limit          int
+	Offset         int
+	Query          string // 部分一致検索（ユーザーはemail/name、クライアントはname）
+	IncludeDeleted bool   // 論理削除（無効化）済みのレコードも含める
+}
+
+// likePattern returns a LIKE pattern for a partial match, escaping the wildcards in the query.
+// ESCAPE '\' と組み合わせて使う
+func likePattern(query string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(query) + "%"
+}
+
+// listWhere builds the WHERE clause for a list query
+func listWhere(opts ListOptions, searchColumns ...string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if !opts.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if opts.Query != "" {
+		var search []string
+		for _, column := range searchColumns {
+			search = append(search, column+` LIKE ? ESCAPE '\'`)
+			args = append(args, likePattern(opts.Query))
+		}
+		conditions = append(conditions, "("+strings.Join(search, " OR ")+")")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
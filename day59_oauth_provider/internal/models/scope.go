@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/database"
+)
+
+// Scope represents an OAuth2 scope
+type Scope struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateScope creates a new scope
+func CreateScope(name, description string) (*Scope, error) {
+	scope := &Scope{
+		Name:        name,
+		Description: description,
+	}
+
+	query := `INSERT INTO scopes (name, description) VALUES (?, ?)`
+	_, err := database.DB.Exec(query, scope.Name, scope.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	return scope, nil
+}
+
+// GetScopeByName retrieves a scope by name
+func GetScopeByName(name string) (*Scope, error) {
+	query := `SELECT name, description, created_at FROM scopes WHERE name = ?`
+
+	var scope Scope
+	err := database.DB.QueryRow(query, name).Scan(&scope.Name, &scope.Description, &scope.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scope, nil
+}
+
+// GetAllScopes retrieves all scopes
+func GetAllScopes() ([]*Scope, error) {
+	query := `SELECT name, description, created_at FROM scopes ORDER BY created_at, name`
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scopes []*Scope
+	for rows.Next() {
+		var scope Scope
+		if err := rows.Scan(&scope.Name, &scope.Description, &scope.CreatedAt); err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, &scope)
+	}
+
+	return scopes, rows.Err()
+}
+
+// DeleteScope deletes a scope and its claim mappings
+func DeleteScope(name string) error {
+	query := `DELETE FROM scopes WHERE name = ?`
+	if _, err := database.DB.Exec(query, name); err != nil {
+		return err
+	}
+
+	_, err := database.DB.Exec(`DELETE FROM scope_claims WHERE scope = ?`, name)
+	return err
+}
+
+// FindUnknownScopes returns the scope names that are not registered
+func FindUnknownScopes(names []string) ([]string, error) {
+	scopes, err := GetAllScopes()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		known[scope.Name] = true
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	return unknown, nil
+}
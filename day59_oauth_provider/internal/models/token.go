@@ -22,10 +22,19 @@ type RefreshToken struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Access token status
+const (
+	TokenStatusActive  = "active"
+	TokenStatusRevoked = "revoked"
+)
+
 // AccessToken represents an access token record
 type AccessToken struct {
 	ID        string    `json:"id"`
 	TokenHash string    `json:"token_hash"`
+	JTI       string    `json:"jti,omitempty"`
+	Status    string    `json:"status"`
+	Format    string    `json:"format"` // jwt / opaque
 	ClientID  string    `json:"client_id"`
 	UserID    *string   `json:"user_id,omitempty"` // Client Credentialsの場合はnull
 	Scopes    []string  `json:"scopes"`
@@ -34,28 +43,71 @@ type AccessToken struct {
 }
 
 // CreateRefreshToken creates a new refresh token
-func CreateRefreshToken(clientID, userID string, scopes []string) (*RefreshToken, error) {
-	token := &RefreshToken{
+func CreateRefreshToken(clientID, userID string, scopes []string, ttl time.Duration) (*RefreshToken, error) {
+	token := newRefreshToken(clientID, userID, scopes, ttl)
+	if err := insertRefreshToken(database.DB, token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken consumes the refresh token and issues a new one with the same client, user and scopes
+func RotateRefreshToken(old *RefreshToken, ttl time.Duration) (*RefreshToken, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 同じリフレッシュトークンが同時に使われた場合は、先に削除した方だけを有効にする
+	result, err := tx.Exec(`DELETE FROM refresh_tokens WHERE token = ?`, old.Token)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	token := newRefreshToken(old.ClientID, old.UserID, old.Scopes, ttl)
+	if err := insertRefreshToken(tx, token); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func newRefreshToken(clientID, userID string, scopes []string, ttl time.Duration) *RefreshToken {
+	return &RefreshToken{
 		ID:        uuid.New().String(),
 		Token:     uuid.New().String(),
 		ClientID:  clientID,
 		UserID:    userID,
 		Scopes:    scopes,
-		ExpiresAt: time.Now().Add(30 * 24 * time.Hour), // 30日間有効
+		ExpiresAt: time.Now().Add(ttl),
 	}
+}
 
-	scopesJSON, _ := json.Marshal(scopes)
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertRefreshToken(db execer, token *RefreshToken) error {
+	scopesJSON, _ := json.Marshal(token.Scopes)
 
 	query := `INSERT INTO refresh_tokens (id, token, client_id, user_id, scopes, expires_at)
 			  VALUES (?, ?, ?, ?, ?, ?)`
 
-	_, err := database.DB.Exec(query, token.ID, token.Token, token.ClientID, token.UserID,
+	_, err := db.Exec(query, token.ID, token.Token, token.ClientID, token.UserID,
 		string(scopesJSON), token.ExpiresAt)
-	if err != nil {
-		return nil, err
-	}
-
-	return token, nil
+	return err
 }
 
 // GetRefreshToken retrieves a refresh token
@@ -91,8 +143,9 @@ func (rt *RefreshToken) IsExpired() bool {
 	return time.Now().After(rt.ExpiresAt)
 }
 
-// CreateAccessTokenRecord creates a record of an access token (for tracking)
-func CreateAccessTokenRecord(token, clientID string, userID *string, scopes []string) (*AccessToken, error) {
+// CreateAccessTokenRecord creates a record of an access token (for tracking).
+// 不透明なアクセストークンはこの記録だけで検証されるため、メタデータをすべて保存する
+func CreateAccessTokenRecord(jti, token, format, clientID string, userID *string, scopes []string, ttl time.Duration) (*AccessToken, error) {
 	// トークンをハッシュ化して保存
 	hash := sha256.Sum256([]byte(token))
 	tokenHash := hex.EncodeToString(hash[:])
@@ -100,19 +153,22 @@ func CreateAccessTokenRecord(token, clientID string, userID *string, scopes []st
 	accessToken := &AccessToken{
 		ID:        uuid.New().String(),
 		TokenHash: tokenHash,
+		JTI:       jti,
+		Status:    TokenStatusActive,
+		Format:    format,
 		ClientID:  clientID,
 		UserID:    userID,
 		Scopes:    scopes,
-		ExpiresAt: time.Now().Add(1 * time.Hour), // 1時間有効
+		ExpiresAt: time.Now().Add(ttl),
 	}
 
 	scopesJSON, _ := json.Marshal(scopes)
 
-	query := `INSERT INTO access_tokens (id, token_hash, client_id, user_id, scopes, expires_at)
-			  VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO access_tokens (id, token_hash, jti, status, format, client_id, user_id, scopes, expires_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := database.DB.Exec(query, accessToken.ID, accessToken.TokenHash, accessToken.ClientID,
-		accessToken.UserID, string(scopesJSON), accessToken.ExpiresAt)
+	_, err := database.DB.Exec(query, accessToken.ID, accessToken.TokenHash, accessToken.JTI, accessToken.Status,
+		accessToken.Format, accessToken.ClientID, accessToken.UserID, string(scopesJSON), accessToken.ExpiresAt)
 	if err != nil {
 		return nil, err
 	}
@@ -125,21 +181,24 @@ func GetAccessTokenRecord(token string) (*AccessToken, error) {
 	hash := sha256.Sum256([]byte(token))
 	tokenHash := hex.EncodeToString(hash[:])
 
-	query := `SELECT id, token_hash, client_id, user_id, scopes, expires_at, created_at
+	query := `SELECT id, token_hash, jti, status, format, client_id, user_id, scopes, expires_at, created_at
 			  FROM access_tokens WHERE token_hash = ?`
 
 	var accessToken AccessToken
 	var scopesJSON string
-	var userID sql.NullString
+	var jti, userID sql.NullString
 
 	err := database.DB.QueryRow(query, tokenHash).Scan(
-		&accessToken.ID, &accessToken.TokenHash, &accessToken.ClientID, &userID,
+		&accessToken.ID, &accessToken.TokenHash, &jti, &accessToken.Status, &accessToken.Format, &accessToken.ClientID, &userID,
 		&scopesJSON, &accessToken.ExpiresAt, &accessToken.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if jti.Valid {
+		accessToken.JTI = jti.String
+	}
 	if userID.Valid {
 		accessToken.UserID = &userID.String
 	}
@@ -154,13 +213,26 @@ func (at *AccessToken) IsExpired() bool {
 	return time.Now().After(at.ExpiresAt)
 }
 
+// IsActive checks if the access token is neither revoked nor expired
+func (at *AccessToken) IsActive() bool {
+	return at.Status == TokenStatusActive && !at.IsExpired()
+}
+
 // RevokeAccessToken revokes an access token
 func RevokeAccessToken(token string) error {
 	hash := sha256.Sum256([]byte(token))
 	tokenHash := hex.EncodeToString(hash[:])
 
-	query := `DELETE FROM access_tokens WHERE token_hash = ?`
-	_, err := database.DB.Exec(query, tokenHash)
+	// イントロスペクションで失効済みと判定できるよう、削除せずにステータスを更新する
+	query := `UPDATE access_tokens SET status = ? WHERE token_hash = ?`
+	_, err := database.DB.Exec(query, TokenStatusRevoked, tokenHash)
+	return err
+}
+
+// RevokeAccessTokensForUser revokes all access tokens issued to the client on behalf of the user
+func RevokeAccessTokensForUser(clientID, userID string) error {
+	query := `UPDATE access_tokens SET status = ? WHERE client_id = ? AND user_id = ? AND status = ?`
+	_, err := database.DB.Exec(query, TokenStatusRevoked, clientID, userID, TokenStatusActive)
 	return err
 }
 
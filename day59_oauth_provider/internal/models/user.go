@@ -19,22 +19,34 @@ type User struct {
 	Profile      map[string]interface{} `json:"profile,omitempty"`
 	CreatedAt    time.Time              `json:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at"`
+	CreatedBy    string                 `json:"created_by,omitempty"`
+	UpdatedBy    string                 `json:"updated_by,omitempty"`
+	DeletedAt    *time.Time             `json:"deleted_at,omitempty"` // 論理削除（無効化）された日時
+	DeletedBy    string                 `json:"deleted_by,omitempty"`
 }
 
+const userColumns = `id, email, password_hash, name, profile, created_at, updated_at,
+			  created_by, updated_by, deleted_at, deleted_by`
+
 // CreateUser creates a new user
-func CreateUser(email, password, name string, profile map[string]interface{}) (*User, error) {
+func CreateUser(email, password, name string, profile map[string]interface{}, createdBy string) (*User, error) {
 	// パスワードをハッシュ化
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now().UTC()
 	user := &User{
 		ID:           uuid.New().String(),
 		Email:        email,
 		PasswordHash: string(passwordHash),
 		Name:         name,
 		Profile:      profile,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		CreatedBy:    createdBy,
+		UpdatedBy:    createdBy,
 	}
 
 	var profileJSON []byte
@@ -42,8 +54,10 @@ func CreateUser(email, password, name string, profile map[string]interface{}) (*
 		profileJSON, _ = json.Marshal(profile)
 	}
 
-	query := `INSERT INTO users (id, email, password_hash, name, profile) VALUES (?, ?, ?, ?, ?)`
-	_, err = database.DB.Exec(query, user.ID, user.Email, user.PasswordHash, user.Name, string(profileJSON))
+	query := `INSERT INTO users (id, email, password_hash, name, profile, created_at, updated_at, created_by, updated_by)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = database.DB.Exec(query, user.ID, user.Email, user.PasswordHash, user.Name, string(profileJSON),
+		user.CreatedAt, user.UpdatedAt, user.CreatedBy, user.UpdatedBy)
 	if err != nil {
 		return nil, err
 	}
@@ -51,38 +65,61 @@ func CreateUser(email, password, name string, profile map[string]interface{}) (*
 	return user, nil
 }
 
-// GetUserByID retrieves a user by ID
+// GetUserByID retrieves an active (not deleted) user by ID
 func GetUserByID(userID string) (*User, error) {
-	query := `SELECT id, email, password_hash, name, profile, created_at, updated_at FROM users WHERE id = ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND deleted_at IS NULL`
+	return scanUser(database.DB.QueryRow(query, userID))
+}
 
-	var user User
-	var profileJSON sql.NullString
+// GetUserByIDIncludingDeleted retrieves a user by ID, including deleted users (for admin purposes)
+func GetUserByIDIncludingDeleted(userID string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
+	return scanUser(database.DB.QueryRow(query, userID))
+}
 
-	err := database.DB.QueryRow(query, userID).Scan(
-		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &profileJSON,
-		&user.CreatedAt, &user.UpdatedAt,
-	)
+// GetUserByEmail retrieves an active (not deleted) user by email
+func GetUserByEmail(email string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ? AND deleted_at IS NULL`
+	return scanUser(database.DB.QueryRow(query, email))
+}
+
+// ListUsers retrieves users matching the options, newest first, and the total number of matching users
+func ListUsers(opts ListOptions) ([]*User, int, error) {
+	where, args := listWhere(opts, "email", "name")
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY created_at DESC, id LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, opts.Limit, opts.Offset)...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
+	defer rows.Close()
 
-	if profileJSON.Valid && profileJSON.String != "" {
-		_ = json.Unmarshal([]byte(profileJSON.String), &user.Profile)
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
 	}
 
-	return &user, nil
+	return users, total, rows.Err()
 }
 
-// GetUserByEmail retrieves a user by email
-func GetUserByEmail(email string) (*User, error) {
-	query := `SELECT id, email, password_hash, name, profile, created_at, updated_at FROM users WHERE email = ?`
-
+// scanUser scans a row selected with userColumns
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
 	var user User
 	var profileJSON sql.NullString
 
-	err := database.DB.QueryRow(query, email).Scan(
+	err := row.Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.Name, &profileJSON,
 		&user.CreatedAt, &user.UpdatedAt,
+		&user.CreatedBy, &user.UpdatedBy, &user.DeletedAt, &user.DeletedBy,
 	)
 	if err != nil {
 		return nil, err
@@ -95,45 +132,12 @@ func GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-// GetAllUsers retrieves all users
-func GetAllUsers() ([]*User, error) {
-	query := `SELECT id, email, password_hash, name, profile, created_at, updated_at FROM users ORDER BY created_at DESC`
-
-	rows, err := database.DB.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var users []*User
-	for rows.Next() {
-		var user User
-		var profileJSON sql.NullString
-
-		err := rows.Scan(
-			&user.ID, &user.Email, &user.PasswordHash, &user.Name, &profileJSON,
-			&user.CreatedAt, &user.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		if profileJSON.Valid && profileJSON.String != "" {
-			_ = json.Unmarshal([]byte(profileJSON.String), &user.Profile)
-		}
-
-		users = append(users, &user)
-	}
-
-	return users, nil
-}
-
 // AuthenticateUser authenticates a user with email and password
 func AuthenticateUser(email, password string) (*User, error) {
 	user, err := GetUserByEmail(email)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, nil // ユーザーが見つからない
+			return nil, nil // ユーザーが見つからない（無効化されたユーザーを含む）
 		}
 		return nil, err
 	}
@@ -147,46 +151,57 @@ func AuthenticateUser(email, password string) (*User, error) {
 	return user, nil
 }
 
-// UpdateUser updates an existing user
+// Update updates an existing user. UpdatedBy must be set by the caller
 func (u *User) Update() error {
 	var profileJSON []byte
 	if u.Profile != nil {
 		profileJSON, _ = json.Marshal(u.Profile)
 	}
 
-	query := `UPDATE users SET name = ?, profile = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := database.DB.Exec(query, u.Name, string(profileJSON), u.ID)
+	u.UpdatedAt = time.Now().UTC()
+	query := `UPDATE users SET name = ?, profile = ?, updated_at = ?, updated_by = ? WHERE id = ?`
+	_, err := database.DB.Exec(query, u.Name, string(profileJSON), u.UpdatedAt, u.UpdatedBy, u.ID)
 	return err
 }
 
-// DeleteUser deletes a user
-func DeleteUser(userID string) error {
-	query := `DELETE FROM users WHERE id = ?`
-	_, err := database.DB.Exec(query, userID)
-	return err
-}
+// DeactivateUser soft-deletes a user. The user can no longer log in, and the tokens issued on behalf of
+// the user are revoked. It returns false if the user does not exist or is already deactivated
+func DeactivateUser(userID, deletedBy string) (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
 
-// GetUserInfoClaims returns user info claims for JWT/userinfo endpoint
-func (u *User) GetUserInfoClaims(scopes []string) map[string]interface{} {
-	claims := make(map[string]interface{})
-
-	// sub は常に含める
-	claims["sub"] = u.ID
-
-	// scope に応じて情報を追加
-	for _, scope := range scopes {
-		switch scope {
-		case "profile":
-			claims["name"] = u.Name
-			if u.Profile != nil {
-				for key, value := range u.Profile {
-					claims[key] = value
-				}
-			}
-		case "email":
-			claims["email"] = u.Email
-		}
+	result, err := tx.Exec(`UPDATE users SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`,
+		time.Now().UTC(), deletedBy, userID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
 	}
 
-	return claims
+	// 発行済みトークンを失効させる
+	if _, err := tx.Exec(`UPDATE access_tokens SET status = ? WHERE user_id = ? AND status = ?`,
+		TokenStatusRevoked, userID, TokenStatusActive); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM refresh_tokens WHERE user_id = ?`, userID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// RestoreUser reactivates a deactivated user. It returns false if the user is not deactivated
+func RestoreUser(userID, updatedBy string) (bool, error) {
+	query := `UPDATE users SET deleted_at = NULL, deleted_by = '', updated_at = ?, updated_by = ?
+			  WHERE id = ? AND deleted_at IS NOT NULL`
+	result, err := database.DB.Exec(query, time.Now().UTC(), updatedBy, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
 }
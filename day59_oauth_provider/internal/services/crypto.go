@@ -4,29 +4,57 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
-	"database/sql"
 	"encoding/base64"
 	"encoding/pem"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/database"
 )
 
+const (
+	// KeyRotationInterval is how long a signing key is used before it is rotated
+	KeyRotationInterval = 30 * 24 * time.Hour // 30日
+	// KeyGracePeriod is how long a retired key stays in the JWKS so that tokens signed with it can still be verified.
+	// 発行済みトークンの有効期限より長くする（IDトークンは1時間、アクセストークンはクライアント設定で既定1時間）
+	KeyGracePeriod = 7 * 24 * time.Hour // 7日
+	// keyRotationCheckInterval is how often the background job checks whether rotation is due
+	keyRotationCheckInterval = 1 * time.Hour
+)
+
 // KeyPair represents an RSA key pair
 type KeyPair struct {
-	ID         string `json:"id"`
-	PrivateKey string `json:"private_key"`
-	PublicKey  string `json:"public_key"`
-	Kid        string `json:"kid"` // Key ID
-	Algorithm  string `json:"algorithm"`
-	IsActive   bool   `json:"is_active"`
+	ID         string     `json:"id"`
+	PrivateKey string     `json:"-"`
+	PublicKey  string     `json:"public_key"`
+	Kid        string     `json:"kid"` // Key ID
+	Algorithm  string     `json:"algorithm"`
+	IsActive   bool       `json:"is_active"` // 署名に使う鍵（常に1つ）
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"` // ローテーションで署名に使われなくなった日時
+
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+// ExpiresAt returns when a retired key is removed from the JWKS (nil for the active key)
+func (k *KeyPair) ExpiresAt() *time.Time {
+	if k.RetiredAt == nil {
+		return nil
+	}
+	t := k.RetiredAt.Add(KeyGracePeriod)
+	return &t
 }
 
 // JWK represents a JSON Web Key
 type JWK struct {
 	Kty string `json:"kty"`
 	Use string `json:"use"`
+	Alg string `json:"alg"`
 	Kid string `json:"kid"`
 	N   string `json:"n"`
 	E   string `json:"e"`
@@ -37,42 +65,89 @@ type JWKS struct {
 	Keys []JWK `json:"keys"`
 }
 
-var (
-	currentKeyPair *KeyPair
-	rsaPrivateKey  *rsa.PrivateKey
-	rsaPublicKey   *rsa.PublicKey
-)
+// keyStore holds the signing key and the keys published for verification
+var keyStore struct {
+	sync.RWMutex
+	current *KeyPair
+	keys    []*KeyPair // current を先頭に、猶予期間中の旧鍵を新しい順に並べる
+}
 
-// InitializeKeys initializes RSA key pairs for JWT signing
+// InitializeKeys loads the signing key and the retired keys still within the grace period.
+// 署名鍵がない場合は生成し、ローテーション期限を過ぎている場合はローテーションする
 func InitializeKeys() error {
-	// 既存のアクティブな鍵を確認
-	keyPair, err := getActiveKeyPair()
-	if err != nil && err != sql.ErrNoRows {
+	if err := rotateKeysIfDue(); err != nil {
 		return err
 	}
 
-	if keyPair == nil {
-		// 鍵が存在しない場合は新しく生成
-		log.Println("Generating new RSA key pair...")
-		keyPair, err = generateAndSaveKeyPair()
-		if err != nil {
-			return err
+	current := GetCurrentKeyPair()
+	log.Printf("RSA key pair loaded successfully (Kid: %s, published keys: %d)", current.Kid, len(GetPublishedKeyPairs()))
+	return nil
+}
+
+// StartKeyRotation starts a background job that rotates the signing key every KeyRotationInterval
+// and drops retired keys from the JWKS after KeyGracePeriod
+func StartKeyRotation() {
+	go func() {
+		ticker := time.NewTicker(keyRotationCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := rotateKeysIfDue(); err != nil {
+				log.Printf("Scheduled key rotation failed: %v", err)
+			}
 		}
-	}
+	}()
+}
 
-	// メモリ上でRSA鍵を読み込み
-	err = loadKeyPairIntoMemory(keyPair)
+// rotateKeysIfDue rotates the signing key if it is missing or older than KeyRotationInterval, and reloads the key store
+func rotateKeysIfDue() error {
+	active, err := getActiveKeyPair()
 	if err != nil {
 		return err
 	}
+	if active == nil || time.Since(active.CreatedAt) >= KeyRotationInterval {
+		_, err := RotateKeys()
+		return err
+	}
+	return loadKeys()
+}
+
+// RotateKeys generates a new signing key and retires the current one.
+// 旧鍵は KeyGracePeriod の間JWKSに残るため、それまでに発行されたトークンも検証できる
+func RotateKeys() (*KeyPair, error) {
+	keyPair, err := generateKeyPair()
+	if err != nil {
+		return nil, err
+	}
 
-	currentKeyPair = keyPair
-	log.Printf("RSA key pair loaded successfully (Kid: %s)", keyPair.Kid)
-	return nil
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`UPDATE key_pairs SET is_active = false, retired_at = ? WHERE is_active = true`, now); err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO key_pairs (id, private_key, public_key, kid, algorithm, is_active, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(query, keyPair.ID, keyPair.PrivateKey, keyPair.PublicKey,
+		keyPair.Kid, keyPair.Algorithm, true, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Rotated signing key (new Kid: %s)", keyPair.Kid)
+	if err := loadKeys(); err != nil {
+		return nil, err
+	}
+	return GetCurrentKeyPair(), nil
 }
 
-// generateAndSaveKeyPair generates a new RSA key pair and saves it to database
-func generateAndSaveKeyPair() (*KeyPair, error) {
+// generateKeyPair generates a new RSA key pair
+func generateKeyPair() (*KeyPair, error) {
 	// RSA鍵ペア生成（2048bit）
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
@@ -97,51 +172,97 @@ func generateAndSaveKeyPair() (*KeyPair, error) {
 	}
 	publicKeyBytes := pem.EncodeToMemory(publicKeyPEM)
 
-	// データベースに保存
-	keyPair := &KeyPair{
+	return &KeyPair{
 		ID:         uuid.New().String(),
 		PrivateKey: string(privateKeyBytes),
 		PublicKey:  string(publicKeyBytes),
 		Kid:        uuid.New().String(),
 		Algorithm:  "RS256",
 		IsActive:   true,
+	}, nil
+}
+
+// getActiveKeyPair retrieves the active key pair from database, or nil if there is none
+func getActiveKeyPair() (*KeyPair, error) {
+	keyPairs, err := getKeyPairs(`WHERE is_active = true`)
+	if err != nil || len(keyPairs) == 0 {
+		return nil, err
 	}
+	return keyPairs[0], nil
+}
 
-	query := `INSERT INTO key_pairs (id, private_key, public_key, kid, algorithm, is_active)
-			  VALUES (?, ?, ?, ?, ?, ?)`
+// getKeyPairs retrieves key pairs from database, newest first
+func getKeyPairs(where string) ([]*KeyPair, error) {
+	query := `SELECT id, private_key, public_key, kid, algorithm, is_active, created_at, retired_at
+			  FROM key_pairs ` + where + ` ORDER BY created_at DESC`
 
-	_, err = database.DB.Exec(query, keyPair.ID, keyPair.PrivateKey, keyPair.PublicKey,
-		keyPair.Kid, keyPair.Algorithm, keyPair.IsActive)
+	rows, err := database.DB.Query(query)
 	if err != nil {
 		return nil, err
 	}
-
-	return keyPair, nil
+	defer rows.Close()
+
+	var keyPairs []*KeyPair
+	for rows.Next() {
+		var keyPair KeyPair
+		if err := rows.Scan(
+			&keyPair.ID, &keyPair.PrivateKey, &keyPair.PublicKey,
+			&keyPair.Kid, &keyPair.Algorithm, &keyPair.IsActive,
+			&keyPair.CreatedAt, &keyPair.RetiredAt,
+		); err != nil {
+			return nil, err
+		}
+		keyPairs = append(keyPairs, &keyPair)
+	}
+	return keyPairs, rows.Err()
 }
 
-// getActiveKeyPair retrieves the active key pair from database
-func getActiveKeyPair() (*KeyPair, error) {
-	query := `SELECT id, private_key, public_key, kid, algorithm, is_active
-			  FROM key_pairs WHERE is_active = true LIMIT 1`
-
-	var keyPair KeyPair
-	err := database.DB.QueryRow(query).Scan(
-		&keyPair.ID, &keyPair.PrivateKey, &keyPair.PublicKey,
-		&keyPair.Kid, &keyPair.Algorithm, &keyPair.IsActive,
-	)
+// loadKeys loads the active key and the retired keys within the grace period into memory,
+// and deletes retired keys whose grace period has passed
+func loadKeys() error {
+	keyPairs, err := getKeyPairs(`WHERE is_active = true OR retired_at IS NOT NULL`)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	var current *KeyPair
+	var published []*KeyPair
+	for _, keyPair := range keyPairs {
+		if expiresAt := keyPair.ExpiresAt(); expiresAt != nil && time.Now().After(*expiresAt) {
+			// 猶予期間を過ぎた旧鍵は削除する（この鍵で署名されたトークンは検証できなくなる）
+			if _, err := database.DB.Exec(`DELETE FROM key_pairs WHERE id = ?`, keyPair.ID); err != nil {
+				return err
+			}
+			log.Printf("Removed retired signing key (Kid: %s)", keyPair.Kid)
+			continue
+		}
+
+		if err := parseKeyPair(keyPair); err != nil {
+			return fmt.Errorf("failed to load key %s: %w", keyPair.Kid, err)
+		}
+		if keyPair.IsActive && current == nil {
+			current = keyPair
+			continue
+		}
+		published = append(published, keyPair)
+	}
+	if current == nil {
+		return fmt.Errorf("no active signing key")
 	}
 
-	return &keyPair, nil
+	keyStore.Lock()
+	defer keyStore.Unlock()
+	keyStore.current = current
+	keyStore.keys = append([]*KeyPair{current}, published...)
+	return nil
 }
 
-// loadKeyPairIntoMemory loads the key pair into memory for signing/verification
-func loadKeyPairIntoMemory(keyPair *KeyPair) error {
+// parseKeyPair parses the PEM encoded keys for signing/verification
+func parseKeyPair(keyPair *KeyPair) error {
 	// 秘密鍵を読み込み
 	privateKeyBlock, _ := pem.Decode([]byte(keyPair.PrivateKey))
 	if privateKeyBlock == nil {
-		return nil
+		return fmt.Errorf("invalid private key PEM")
 	}
 
 	privateKey, err := x509.ParsePKCS1PrivateKey(privateKeyBlock.Bytes)
@@ -152,7 +273,7 @@ func loadKeyPairIntoMemory(keyPair *KeyPair) error {
 	// 公開鍵を読み込み
 	publicKeyBlock, _ := pem.Decode([]byte(keyPair.PublicKey))
 	if publicKeyBlock == nil {
-		return nil
+		return fmt.Errorf("invalid public key PEM")
 	}
 
 	publicKeyInterface, err := x509.ParsePKIXPublicKey(publicKeyBlock.Bytes)
@@ -162,61 +283,105 @@ func loadKeyPairIntoMemory(keyPair *KeyPair) error {
 
 	publicKey, ok := publicKeyInterface.(*rsa.PublicKey)
 	if !ok {
-		return nil
+		return fmt.Errorf("public key is not an RSA key")
 	}
 
-	rsaPrivateKey = privateKey
-	rsaPublicKey = publicKey
-
+	keyPair.privateKey = privateKey
+	keyPair.publicKey = publicKey
 	return nil
 }
 
 // GetCurrentKeyPair returns the current active key pair
 func GetCurrentKeyPair() *KeyPair {
-	return currentKeyPair
+	keyStore.RLock()
+	defer keyStore.RUnlock()
+	return keyStore.current
+}
+
+// GetPublishedKeyPairs returns the key pairs published in the JWKS (the active key first)
+func GetPublishedKeyPairs() []*KeyPair {
+	keyStore.RLock()
+	defer keyStore.RUnlock()
+	return append([]*KeyPair(nil), keyStore.keys...)
 }
 
 // GetPrivateKey returns the current RSA private key
 func GetPrivateKey() *rsa.PrivateKey {
-	return rsaPrivateKey
+	if current := GetCurrentKeyPair(); current != nil {
+		return current.privateKey
+	}
+	return nil
 }
 
 // GetPublicKey returns the current RSA public key
 func GetPublicKey() *rsa.PublicKey {
-	return rsaPublicKey
+	if current := GetCurrentKeyPair(); current != nil {
+		return current.publicKey
+	}
+	return nil
+}
+
+// signToken signs the claims with the current key and sets its kid in the header
+func signToken(claims jwt.Claims) (string, error) {
+	current := GetCurrentKeyPair()
+	if current == nil {
+		return "", fmt.Errorf("private key not initialized")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = current.Kid
+	return token.SignedString(current.privateKey)
+}
+
+// verificationKey is a jwt.Keyfunc that returns the published public key matching the token's kid
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	// 署名方法の確認
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	for _, keyPair := range GetPublishedKeyPairs() {
+		if keyPair.Kid == kid {
+			return keyPair.publicKey, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown kid: %q", kid)
 }
 
 // GetJWKS returns the JSON Web Key Set for the /.well-known/jwks.json endpoint
 func GetJWKS() (*JWKS, error) {
-	if currentKeyPair == nil {
+	keyPairs := GetPublishedKeyPairs()
+	if len(keyPairs) == 0 {
 		return nil, nil
 	}
 
+	jwks := &JWKS{Keys: []JWK{}}
+	for _, keyPair := range keyPairs {
+		jwks.Keys = append(jwks.Keys, publicKeyToJWK(keyPair.Kid, keyPair.publicKey))
+	}
+	return jwks, nil
+}
+
+// publicKeyToJWK converts an RSA public key to a JWK
+func publicKeyToJWK(kid string, publicKey *rsa.PublicKey) JWK {
 	// RSA公開鍵からJWKを生成
-	n := rsaPublicKey.N.Bytes()
+	n := publicKey.N.Bytes()
 	e := make([]byte, 4)
-	e[0] = byte(rsaPublicKey.E >> 24)
-	e[1] = byte(rsaPublicKey.E >> 16)
-	e[2] = byte(rsaPublicKey.E >> 8)
-	e[3] = byte(rsaPublicKey.E)
+	e[0] = byte(publicKey.E >> 24)
+	e[1] = byte(publicKey.E >> 16)
+	e[2] = byte(publicKey.E >> 8)
+	e[3] = byte(publicKey.E)
 
 	// Base64 URL エンコード（パディングなし）
-	nBase64 := base64URLEncode(n)
-	eBase64 := base64URLEncode(e)
-
-	jwk := JWK{
+	return JWK{
 		Kty: "RSA",
 		Use: "sig",
-		Kid: currentKeyPair.Kid,
-		N:   nBase64,
-		E:   eBase64,
-	}
-
-	jwks := &JWKS{
-		Keys: []JWK{jwk},
+		Alg: "RS256",
+		Kid: kid,
+		N:   base64URLEncode(n),
+		E:   base64URLEncode(e),
 	}
-
-	return jwks, nil
 }
 
 // base64URLEncode encodes bytes to base64 URL encoding without padding
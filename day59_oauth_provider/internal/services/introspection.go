@@ -0,0 +1,214 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/models"
+)
+
+// ErrTokenNotOwned is returned when a client tries to revoke a token issued to another client
+var ErrTokenNotOwned = errors.New("token was not issued to this client")
+
+// IntrospectionResponse represents an OAuth2 token introspection response (RFC 7662)
+type IntrospectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       []string `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+}
+
+// ValidateActiveAccessToken validates an access token and checks that it has been issued by this provider and not revoked.
+// Opaque access tokens are resolved from the stored token metadata
+func ValidateActiveAccessToken(tokenString string) (*AccessTokenClaims, error) {
+	record, err := models.GetAccessTokenRecord(tokenString)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("unknown token")
+		}
+		return nil, err
+	}
+	if !record.IsActive() {
+		return nil, fmt.Errorf("token is not active")
+	}
+
+	if record.Format == models.TokenFormatOpaque {
+		return opaqueAccessTokenClaims(record), nil
+	}
+	return ValidateAccessToken(tokenString)
+}
+
+// opaqueAccessTokenClaims builds the claims of an opaque access token from its record,
+// in the same shape as the claims of a JWT access token
+func opaqueAccessTokenClaims(record *models.AccessToken) *AccessTokenClaims {
+	// Client Credentialsの場合はclient_idがsubject
+	subject := record.ClientID
+	if record.UserID != nil {
+		subject = *record.UserID
+	}
+
+	return &AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   subject,
+			Audience:  []string{record.ClientID},
+			ExpiresAt: jwt.NewNumericDate(record.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(record.CreatedAt),
+			NotBefore: jwt.NewNumericDate(record.CreatedAt),
+			ID:        record.JTI,
+		},
+		Scope: strings.Join(record.Scopes, " "),
+	}
+}
+
+// IntrospectToken returns the state of a token. Unknown, expired and revoked tokens are reported as inactive
+func IntrospectToken(token, tokenTypeHint string) *IntrospectionResponse {
+	// token_type_hintはあくまでヒントなので、見つからなければもう一方の種類としても調べる
+	lookups := []func(string) *IntrospectionResponse{introspectAccessToken, introspectRefreshToken}
+	if tokenTypeHint == "refresh_token" {
+		lookups = []func(string) *IntrospectionResponse{introspectRefreshToken, introspectAccessToken}
+	}
+
+	for _, lookup := range lookups {
+		if resp := lookup(token); resp != nil {
+			return resp
+		}
+	}
+
+	return &IntrospectionResponse{Active: false}
+}
+
+// introspectAccessToken returns the introspection response for an active access token, or nil
+func introspectAccessToken(token string) *IntrospectionResponse {
+	claims, err := ValidateActiveAccessToken(token)
+	if err != nil {
+		return nil
+	}
+
+	resp := &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}
+	if len(claims.Audience) > 0 {
+		resp.ClientID = claims.Audience[0]
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		resp.Nbf = claims.NotBefore.Unix()
+	}
+
+	// Client Credentialsの場合はsubがclient_idなのでユーザーは存在しない
+	if claims.Subject != resp.ClientID {
+		if user, err := models.GetUserByID(claims.Subject); err == nil {
+			resp.Username = user.Email
+		}
+	}
+
+	return resp
+}
+
+// introspectRefreshToken returns the introspection response for an active refresh token, or nil
+func introspectRefreshToken(token string) *IntrospectionResponse {
+	refreshToken, err := models.GetRefreshToken(token)
+	if err != nil || refreshToken.IsExpired() {
+		return nil
+	}
+
+	resp := &IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(refreshToken.Scopes, " "),
+		ClientID:  refreshToken.ClientID,
+		TokenType: "refresh_token",
+		Exp:       refreshToken.ExpiresAt.Unix(),
+		Iat:       refreshToken.CreatedAt.Unix(),
+		Sub:       refreshToken.UserID,
+		Iss:       Issuer,
+	}
+	if user, err := models.GetUserByID(refreshToken.UserID); err == nil {
+		resp.Username = user.Email
+	}
+
+	return resp
+}
+
+// RevokeToken revokes an access token or a refresh token issued to the client (RFC 7009).
+// Unknown tokens are ignored
+func RevokeToken(token, tokenTypeHint string, client *models.OAuthClient) error {
+	lookups := []func(string, *models.OAuthClient) (bool, error){revokeAccessToken, revokeRefreshToken}
+	if tokenTypeHint == "refresh_token" {
+		lookups = []func(string, *models.OAuthClient) (bool, error){revokeRefreshToken, revokeAccessToken}
+	}
+
+	for _, lookup := range lookups {
+		found, err := lookup(token, client)
+		if err != nil {
+			return err
+		}
+		if found {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// revokeAccessToken revokes the access token if it exists
+func revokeAccessToken(token string, client *models.OAuthClient) (bool, error) {
+	record, err := models.GetAccessTokenRecord(token)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if record.ClientID != client.ID {
+		return true, ErrTokenNotOwned
+	}
+
+	return true, models.RevokeAccessToken(token)
+}
+
+// revokeRefreshToken revokes the refresh token and the access tokens issued to the same user if it exists
+func revokeRefreshToken(token string, client *models.OAuthClient) (bool, error) {
+	refreshToken, err := models.GetRefreshToken(token)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if refreshToken.ClientID != client.ID {
+		return true, ErrTokenNotOwned
+	}
+
+	if err := models.DeleteRefreshToken(token); err != nil {
+		return true, err
+	}
+
+	// リフレッシュトークンの失効はセッションの終了とみなし、同じユーザーのアクセストークンも失効させる
+	return true, models.RevokeAccessTokensForUser(client.ID, refreshToken.UserID)
+}
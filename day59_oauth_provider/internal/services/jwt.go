@@ -19,116 +19,84 @@ type AccessTokenClaims struct {
 	Scope string `json:"scope"`
 }
 
-// IDTokenClaims represents the claims for an ID token (OpenID Connect)
+// IDTokenClaims represents the claims for an ID token (OpenID Connect).
+// スコープのマッピングで追加したクレームは含まない
 type IDTokenClaims struct {
 	jwt.RegisteredClaims
 	Email string `json:"email,omitempty"`
 	Name  string `json:"name,omitempty"`
 	Nonce string `json:"nonce,omitempty"`
+	Scope string `json:"scope,omitempty"` // ユーザーが許可したスコープ
 }
 
 // GenerateAccessToken generates a JWT access token
-func GenerateAccessToken(clientID, userID string, scopes []string) (string, error) {
-	if GetPrivateKey() == nil {
-		return "", fmt.Errorf("private key not initialized")
-	}
-
+func GenerateAccessToken(jti, clientID, userID string, scopes []string, ttl time.Duration) (string, error) {
 	now := time.Now()
 	claims := AccessTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    Issuer,
 			Subject:   userID,
 			Audience:  []string{clientID},
-			ExpiresAt: jwt.NewNumericDate(now.Add(1 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
+			ID:        jti,
 		},
 		Scope: strings.Join(scopes, " "),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	// Key IDを設定
-	if keyPair := GetCurrentKeyPair(); keyPair != nil {
-		token.Header["kid"] = keyPair.Kid
-	}
-
-	return token.SignedString(GetPrivateKey())
+	// 現在の署名鍵で署名し、kidをヘッダーに設定する
+	return signToken(claims)
 }
 
-// GenerateIDToken generates a JWT ID token for OpenID Connect
-func GenerateIDToken(clientID, userID, email, name, nonce string) (string, error) {
-	if GetPrivateKey() == nil {
-		return "", fmt.Errorf("private key not initialized")
-	}
-
+// GenerateIDToken generates a JWT ID token for OpenID Connect.
+// userClaims are the claims mapped from the granted scopes (see models.User.GetUserInfoClaims)
+func GenerateIDToken(clientID, userID, nonce string, scopes []string, userClaims map[string]interface{}) (string, error) {
 	now := time.Now()
-	claims := IDTokenClaims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    Issuer,
-			Subject:   userID,
-			Audience:  []string{clientID},
-			ExpiresAt: jwt.NewNumericDate(now.Add(1 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(now),
-			NotBefore: jwt.NewNumericDate(now),
-		},
-		Email: email,
-		Name:  name,
-		Nonce: nonce,
+	claims := jwt.MapClaims{}
+	for name, value := range userClaims {
+		claims[name] = value
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	// Key IDを設定
-	if keyPair := GetCurrentKeyPair(); keyPair != nil {
-		token.Header["kid"] = keyPair.Kid
+	// 登録済みクレームはスコープのマッピングで上書きさせない
+	claims["iss"] = Issuer
+	claims["sub"] = userID
+	claims["aud"] = []string{clientID}
+	claims["exp"] = jwt.NewNumericDate(now.Add(1 * time.Hour))
+	claims["iat"] = jwt.NewNumericDate(now)
+	claims["nbf"] = jwt.NewNumericDate(now)
+	claims["scope"] = strings.Join(scopes, " ")
+	if nonce != "" {
+		claims["nonce"] = nonce
 	}
 
-	return token.SignedString(GetPrivateKey())
+	// 現在の署名鍵で署名し、kidをヘッダーに設定する
+	return signToken(claims)
 }
 
 // GenerateClientCredentialsToken generates a JWT token for client credentials flow
-func GenerateClientCredentialsToken(clientID string, scopes []string) (string, error) {
-	if GetPrivateKey() == nil {
-		return "", fmt.Errorf("private key not initialized")
-	}
-
+func GenerateClientCredentialsToken(jti, clientID string, scopes []string, ttl time.Duration) (string, error) {
 	now := time.Now()
 	claims := AccessTokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    Issuer,
 			Subject:   clientID, // Client Credentialsの場合はclient_idがsubject
 			Audience:  []string{clientID},
-			ExpiresAt: jwt.NewNumericDate(now.Add(1 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
+			ID:        jti,
 		},
 		Scope: strings.Join(scopes, " "),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	// Key IDを設定
-	if keyPair := GetCurrentKeyPair(); keyPair != nil {
-		token.Header["kid"] = keyPair.Kid
-	}
-
-	return token.SignedString(GetPrivateKey())
+	// 現在の署名鍵で署名し、kidをヘッダーに設定する
+	return signToken(claims)
 }
 
 // ValidateAccessToken validates and parses an access token
 func ValidateAccessToken(tokenString string) (*AccessTokenClaims, error) {
-	if GetPublicKey() == nil {
-		return nil, fmt.Errorf("public key not initialized")
-	}
-
-	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 署名方法の確認
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return GetPublicKey(), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenClaims{}, verificationKey) // kidに対応する公開鍵（猶予期間中の旧鍵を含む）で検証する
 
 	if err != nil {
 		return nil, err
@@ -143,17 +111,7 @@ func ValidateAccessToken(tokenString string) (*AccessTokenClaims, error) {
 
 // ValidateIDToken validates and parses an ID token
 func ValidateIDToken(tokenString string) (*IDTokenClaims, error) {
-	if GetPublicKey() == nil {
-		return nil, fmt.Errorf("public key not initialized")
-	}
-
-	token, err := jwt.ParseWithClaims(tokenString, &IDTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 署名方法の確認
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return GetPublicKey(), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &IDTokenClaims{}, verificationKey) // kidに対応する公開鍵（猶予期間中の旧鍵を含む）で検証する
 
 	if err != nil {
 		return nil, err
@@ -1,10 +1,15 @@
 package services
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/lirlia/100day_challenge_backend/day59_oauth_provider/internal/models"
 )
 
@@ -50,7 +55,31 @@ type ErrorResponse struct {
 	ErrorURI         string `json:"error_uri,omitempty"`
 }
 
-// ValidateAuthorizeRequest validates an OAuth2 authorization request
+// Token request errors mapped to OAuth2 error codes by the handler (RFC 6749 5.2)
+var (
+	// ErrInvalidScope is returned when the requested scope is unknown or not allowed for the client
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrInvalidClient is returned when client authentication fails
+	ErrInvalidClient = errors.New("invalid client credentials")
+	// ErrUnsupportedGrantType is returned for grant types this server does not implement
+	ErrUnsupportedGrantType = errors.New("unsupported grant_type")
+	// ErrUnauthorizedClient is returned when the client is not registered for the requested grant type
+	ErrUnauthorizedClient = errors.New("client is not allowed to use this grant_type")
+)
+
+// userScopes are scopes that only make sense when a user is involved, so they are not granted to client_credentials
+var userScopes = []string{"openid", "profile", "email"}
+
+// PKCE parameter formats (RFC 7636 4.1, 4.2)
+var (
+	// code_verifierは43〜128文字のunreserved文字
+	codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+	// S256のcode_challengeはSHA256のbase64url（パディングなし）なので常に43文字
+	codeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9\-_]{43}$`)
+)
+
+// ValidateAuthorizeRequest validates an OAuth2 authorization request.
+// redirect_uriの検証後に見つかったエラーの場合はクライアントも返す（エラーをredirect_uriに返せる）
 func ValidateAuthorizeRequest(req *AuthorizeRequest) (*models.OAuthClient, error) {
 	// 必須パラメータチェック
 	if req.ClientID == "" {
@@ -76,30 +105,52 @@ func ValidateAuthorizeRequest(req *AuthorizeRequest) (*models.OAuthClient, error
 
 	// response_type検証
 	if req.ResponseType != "code" {
-		return nil, fmt.Errorf("unsupported response_type")
+		return client, fmt.Errorf("unsupported response_type")
 	}
 
 	// スコープ解析
 	if req.Scope != "" {
-		req.Scopes = strings.Split(req.Scope, " ")
-		// クライアントが要求されたスコープを持っているか確認
+		req.Scopes = strings.Fields(req.Scope)
+		// 登録済みのスコープで、かつクライアントに許可されているか確認
 		for _, scope := range req.Scopes {
+			if _, err := models.GetScopeByName(scope); err != nil {
+				return client, fmt.Errorf("%w: unknown scope %s", ErrInvalidScope, scope)
+			}
 			if !client.HasScope(scope) {
-				return nil, fmt.Errorf("invalid scope: %s", scope)
+				return client, fmt.Errorf("%w: %s is not allowed for this client", ErrInvalidScope, scope)
 			}
 		}
 	}
 
-	// PKCE検証（オプション）
-	if req.CodeChallenge != "" {
-		if req.CodeChallengeMethod != "S256" {
-			return nil, fmt.Errorf("unsupported code_challenge_method")
-		}
+	if err := validateCodeChallenge(req, client); err != nil {
+		return client, err
 	}
 
 	return client, nil
 }
 
+// validateCodeChallenge validates the PKCE parameters of an authorization request.
+// plainはcode_verifierが漏れると意味がないためS256のみ受け付ける
+func validateCodeChallenge(req *AuthorizeRequest, client *models.OAuthClient) error {
+	if req.CodeChallenge == "" {
+		if req.CodeChallengeMethod != "" {
+			return fmt.Errorf("code_challenge is required when code_challenge_method is specified")
+		}
+		if client.PKCERequired() {
+			return fmt.Errorf("code_challenge is required for this client (PKCE with S256)")
+		}
+		return nil
+	}
+
+	if req.CodeChallengeMethod != "S256" {
+		return fmt.Errorf("unsupported code_challenge_method: only S256 is allowed")
+	}
+	if !codeChallengePattern.MatchString(req.CodeChallenge) {
+		return fmt.Errorf("invalid code_challenge")
+	}
+	return nil
+}
+
 // CreateAuthorizationCode creates an authorization code for the given request
 func CreateAuthorizationCode(req *AuthorizeRequest, userID string) (*models.AuthorizationCode, error) {
 	return models.CreateAuthorizationCode(
@@ -130,12 +181,20 @@ func ValidateTokenRequest(req *TokenRequest) (*models.OAuthClient, error) {
 		return nil, fmt.Errorf("client authentication failed")
 	}
 	if client == nil {
-		return nil, fmt.Errorf("invalid client credentials")
+		return nil, ErrInvalidClient
 	}
 
 	// grant_type検証
 	if !client.HasGrantType(req.GrantType) {
-		return nil, fmt.Errorf("unsupported grant_type")
+		switch req.GrantType {
+		case "authorization_code", "refresh_token", "client_credentials":
+			return nil, fmt.Errorf("%w: %s", ErrUnauthorizedClient, req.GrantType)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedGrantType, req.GrantType)
+	}
+	// client_idだけではクライアント自身を証明できないため、パブリッククライアントにはclient_credentialsを許可しない
+	if req.GrantType == "client_credentials" && client.IsPublic() {
+		return nil, fmt.Errorf("%w: %s is not allowed for public clients", ErrUnauthorizedClient, req.GrantType)
 	}
 
 	switch req.GrantType {
@@ -153,7 +212,7 @@ func ValidateTokenRequest(req *TokenRequest) (*models.OAuthClient, error) {
 	case "client_credentials":
 		// client_credentialsは追加パラメータ不要
 	default:
-		return nil, fmt.Errorf("unsupported grant_type")
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedGrantType, req.GrantType)
 	}
 
 	return client, nil
@@ -181,14 +240,22 @@ func ProcessAuthorizationCodeGrant(req *TokenRequest, client *models.OAuthClient
 		return nil, fmt.Errorf("redirect_uri mismatch")
 	}
 
-	// PKCE検証
+	// PKCE検証（認可リクエスト後にクライアントの設定が変わった場合も必須化を適用する）
 	if authCode.HasPKCE() {
 		if req.CodeVerifier == "" {
 			return nil, fmt.Errorf("code_verifier is required")
 		}
-		if !authCode.ValidatePKCE(req.CodeVerifier) {
+		if !codeVerifierPattern.MatchString(req.CodeVerifier) || !authCode.ValidatePKCE(req.CodeVerifier) {
 			return nil, fmt.Errorf("invalid code_verifier")
 		}
+	} else {
+		if client.PKCERequired() {
+			return nil, fmt.Errorf("authorization code was issued without PKCE")
+		}
+		// code_challengeなしで発行されたコードにcode_verifierが送られた場合はダウングレード攻撃の可能性があるため拒否する
+		if req.CodeVerifier != "" {
+			return nil, fmt.Errorf("code_verifier was provided but the authorization request had no code_challenge")
+		}
 	}
 
 	// ユーザー情報取得
@@ -201,31 +268,34 @@ func ProcessAuthorizationCodeGrant(req *TokenRequest, client *models.OAuthClient
 	models.DeleteAuthorizationCode(req.Code)
 
 	// トークン生成
-	accessToken, err := GenerateAccessToken(client.ID, user.ID, authCode.Scopes)
+	accessToken, err := issueAccessToken(client, &user.ID, authCode.Scopes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token")
+		return nil, err
 	}
 
-	// リフレッシュトークン生成
-	refreshToken, err := models.CreateRefreshToken(client.ID, user.ID, authCode.Scopes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token")
+	response := &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(client.AccessTokenLifetime().Seconds()),
+		Scope:       strings.Join(authCode.Scopes, " "),
 	}
 
-	// アクセストークン記録
-	models.CreateAccessTokenRecord(accessToken, client.ID, &user.ID, authCode.Scopes)
-
-	response := &TokenResponse{
-		AccessToken:  accessToken,
-		TokenType:    "Bearer",
-		ExpiresIn:    3600, // 1時間
-		RefreshToken: refreshToken.Token,
-		Scope:        strings.Join(authCode.Scopes, " "),
+	// リフレッシュトークン生成（refresh_tokenグラントを許可されたクライアントのみ）
+	if client.HasGrantType("refresh_token") {
+		refreshToken, err := models.CreateRefreshToken(client.ID, user.ID, authCode.Scopes, client.RefreshTokenLifetime())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate refresh token")
+		}
+		response.RefreshToken = refreshToken.Token
 	}
 
-	// OpenID Connect: IDトークン生成
+	// OpenID Connect: IDトークン生成（ユーザーが許可したスコープに対応するクレームのみ含める）
 	if containsScope(authCode.Scopes, "openid") {
-		idToken, err := GenerateIDToken(client.ID, user.ID, user.Email, user.Name, authCode.Nonce)
+		userClaims, err := user.GetUserInfoClaims(client.ID, authCode.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve claims")
+		}
+		idToken, err := GenerateIDToken(client.ID, user.ID, authCode.Nonce, authCode.Scopes, userClaims)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate ID token")
 		}
@@ -262,67 +332,133 @@ func ProcessRefreshTokenGrant(req *TokenRequest, client *models.OAuthClient) (*T
 	// スコープ処理（要求されたスコープが元のスコープ以下であることを確認）
 	scopes := refreshToken.Scopes
 	if req.Scope != "" {
-		requestedScopes := strings.Split(req.Scope, " ")
+		requestedScopes := strings.Fields(req.Scope)
 		for _, scope := range requestedScopes {
 			if !containsScope(refreshToken.Scopes, scope) {
-				return nil, fmt.Errorf("invalid scope: %s", scope)
+				return nil, fmt.Errorf("%w: %s was not granted", ErrInvalidScope, scope)
 			}
 		}
 		scopes = requestedScopes
 	}
 
+	// ローテーション: 使用済みのリフレッシュトークンを無効にして新しいものを発行する
+	var newRefreshToken *models.RefreshToken
+	if client.RotateRefreshToken {
+		newRefreshToken, err = models.RotateRefreshToken(refreshToken, client.RefreshTokenLifetime())
+		if err != nil {
+			return nil, fmt.Errorf("invalid refresh token")
+		}
+	}
+
 	// 新しいアクセストークン生成
-	accessToken, err := GenerateAccessToken(client.ID, user.ID, scopes)
+	accessToken, err := issueAccessToken(client, &user.ID, scopes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token")
+		return nil, err
 	}
 
-	// アクセストークン記録
-	models.CreateAccessTokenRecord(accessToken, client.ID, &user.ID, scopes)
-
 	response := &TokenResponse{
 		AccessToken: accessToken,
 		TokenType:   "Bearer",
-		ExpiresIn:   3600, // 1時間
+		ExpiresIn:   int(client.AccessTokenLifetime().Seconds()),
 		Scope:       strings.Join(scopes, " "),
 	}
+	if newRefreshToken != nil {
+		response.RefreshToken = newRefreshToken.Token
+	}
 
 	return response, nil
 }
 
 // ProcessClientCredentialsGrant processes client credentials grant
 func ProcessClientCredentialsGrant(req *TokenRequest, client *models.OAuthClient) (*TokenResponse, error) {
-	// スコープ処理
-	scopes := []string{}
-	if req.Scope != "" {
-		requestedScopes := strings.Split(req.Scope, " ")
-		for _, scope := range requestedScopes {
-			if !client.HasScope(scope) {
-				return nil, fmt.Errorf("invalid scope: %s", scope)
-			}
-		}
-		scopes = requestedScopes
+	scopes, err := clientCredentialsScopes(req.Scope, client)
+	if err != nil {
+		return nil, err
 	}
 
-	// クライアント認証情報でトークン生成
-	accessToken, err := GenerateClientCredentialsToken(client.ID, scopes)
+	// クライアント認証情報でトークン生成（userIDはnull）
+	accessToken, err := issueAccessToken(client, nil, scopes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token")
+		return nil, err
 	}
 
-	// アクセストークン記録（userIDはnull）
-	models.CreateAccessTokenRecord(accessToken, client.ID, nil, scopes)
-
+	// ユーザーが関与しないためリフレッシュトークンは発行しない（RFC 6749 4.4.3）
 	response := &TokenResponse{
 		AccessToken: accessToken,
 		TokenType:   "Bearer",
-		ExpiresIn:   3600, // 1時間
+		ExpiresIn:   int(client.AccessTokenLifetime().Seconds()),
 		Scope:       strings.Join(scopes, " "),
 	}
 
 	return response, nil
 }
 
+// clientCredentialsScopes validates the scopes requested with client_credentials against the client registration.
+// scopeを省略した場合はクライアントに登録されたスコープ（ユーザー向けのものを除く）を付与する（RFC 6749 3.3）
+func clientCredentialsScopes(scope string, client *models.OAuthClient) ([]string, error) {
+	if scope == "" {
+		scopes := []string{}
+		for _, s := range client.Scopes {
+			if !containsScope(userScopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes, nil
+	}
+
+	scopes := strings.Fields(scope)
+	for _, s := range scopes {
+		// openid・profile・emailはユーザーの認証を伴うためマシン間通信では使えない
+		if containsScope(userScopes, s) {
+			return nil, fmt.Errorf("%w: %s is not allowed for client_credentials", ErrInvalidScope, s)
+		}
+		if _, err := models.GetScopeByName(s); err != nil {
+			return nil, fmt.Errorf("%w: unknown scope %s", ErrInvalidScope, s)
+		}
+		if !client.HasScope(s) {
+			return nil, fmt.Errorf("%w: %s is not allowed for this client", ErrInvalidScope, s)
+		}
+	}
+	return scopes, nil
+}
+
+// issueAccessToken generates an access token in the client's format and records it so that it can be introspected and revoked
+func issueAccessToken(client *models.OAuthClient, userID *string, scopes []string) (string, error) {
+	jti := uuid.New().String()
+	ttl := client.AccessTokenLifetime()
+
+	format := models.TokenFormatJWT
+	var accessToken string
+	var err error
+	if client.IssuesOpaqueAccessTokens() {
+		format = models.TokenFormatOpaque
+		accessToken, err = generateOpaqueToken()
+	} else if userID != nil {
+		accessToken, err = GenerateAccessToken(jti, client.ID, *userID, scopes, ttl)
+	} else {
+		accessToken, err = GenerateClientCredentialsToken(jti, client.ID, scopes, ttl)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate access token")
+	}
+
+	// 記録のないトークンは失効済みとして扱われるため、記録に失敗した場合はエラーにする
+	if _, err := models.CreateAccessTokenRecord(jti, accessToken, format, client.ID, userID, scopes, ttl); err != nil {
+		return "", fmt.Errorf("failed to record access token")
+	}
+
+	return accessToken, nil
+}
+
+// generateOpaqueToken generates a random access token that carries no information by itself
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // BuildAuthorizeRedirectURL builds the redirect URL for authorization response
 func BuildAuthorizeRedirectURL(redirectURI, code, state string) (string, error) {
 	u, err := url.Parse(redirectURI)
@@ -50,15 +50,31 @@ func customHandler(w http.ResponseWriter, r *http.Request) {
 		handlers.AuthorizeHandler(w, r)
 	case "/token":
 		handlers.TokenHandler(w, r)
+	case "/introspect":
+		handlers.IntrospectHandler(w, r)
+	case "/revoke":
+		handlers.RevokeHandler(w, r)
 	default:
 		if strings.HasPrefix(r.URL.Path, "/api/clients/") {
 			handlers.ClientHandler(w, r)
 		} else if r.URL.Path == "/api/clients" {
 			handlers.ClientsHandler(w, r)
+		} else if strings.HasPrefix(r.URL.Path, "/api/scopes/") {
+			handlers.ScopeHandler(w, r)
+		} else if r.URL.Path == "/api/scopes" {
+			handlers.ScopesHandler(w, r)
+		} else if strings.HasPrefix(r.URL.Path, "/api/claims/") {
+			handlers.ClaimHandler(w, r)
+		} else if r.URL.Path == "/api/claims" {
+			handlers.ClaimsHandler(w, r)
 		} else if strings.HasPrefix(r.URL.Path, "/api/users/") {
 			handlers.UserHandler(w, r)
 		} else if r.URL.Path == "/api/users" {
 			handlers.UsersHandler(w, r)
+		} else if r.URL.Path == "/api/keys/rotate" {
+			handlers.KeyRotateHandler(w, r)
+		} else if r.URL.Path == "/api/keys" {
+			handlers.KeysHandler(w, r)
 		} else {
 			http.NotFound(w, r)
 		}
@@ -78,6 +94,8 @@ func main() {
 	if err := services.InitializeKeys(); err != nil {
 		log.Fatalf("Failed to initialize keys: %v", err)
 	}
+	// 署名鍵の定期ローテーション
+	services.StartKeyRotation()
 
 	// サーバー起動
 	server := &http.Server{
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/build"
+	"github.com/spf13/cobra"
+)
+
+func buildCmd() *cobra.Command {
+	var (
+		tagName    string
+		dockerfile string
+		noCache    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "build [context]",
+		Short: "Build an image from a Dockerfile",
+		Long: `Build an image from a Dockerfile in the given build context.
+Supported instructions: FROM, COPY, RUN, ENV, CMD.
+The base image given in FROM must have been pulled beforehand.
+
+Examples:
+  container build -t myapp .
+  container build -t myapp:v1 -f docker/Dockerfile .
+  container build --no-cache -t myapp .`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if tagName == "" {
+				return fmt.Errorf("an image name must be given with -t")
+			}
+
+			contextDir, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to resolve build context: %w", err)
+			}
+
+			if dockerfile == "" {
+				dockerfile = filepath.Join(contextDir, "Dockerfile")
+			}
+
+			name, tag := parseImageName(tagName)
+			logVerbose("Building image %s:%s from %s", name, tag, dockerfile)
+
+			return buildImage(&build.Options{
+				Repository: name,
+				Tag:        tag,
+				ContextDir: contextDir,
+				Dockerfile: dockerfile,
+				NoCache:    noCache,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&tagName, "tag", "t", "", "Name and optionally a tag in the 'name:tag' format")
+	cmd.Flags().StringVarP(&dockerfile, "file", "f", "", "Path to the Dockerfile (default: <context>/Dockerfile)")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "Do not use the build cache")
+
+	return cmd
+}
+
+// buildImage runs the image build and reports the result
+func buildImage(opts *build.Options) error {
+	builder := build.NewBuilder(getDataDir())
+
+	image, err := builder.Build(opts)
+	if err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	fmt.Printf("✓ Successfully built %s (%d layers)\n", truncateDigest(strings.TrimPrefix(image.Digest, "sha256:")), len(image.Layers))
+	fmt.Printf("✓ Successfully tagged %s:%s\n", image.Repository, image.Tag)
+	return nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+func psCmd() *cobra.Command {
+	var showAll bool
+
+	cmd := &cobra.Command{
+		Use:   "ps",
+		Short: "List containers",
+		Long: `List containers. By default only running containers are shown.
+
+Examples:
+  container ps
+  container ps -a`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logVerbose("Listing containers")
+
+			return listContainers(showAll)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&showAll, "all", "a", false, "Show all containers (default shows just running)")
+
+	return cmd
+}
+
+func stopCmd() *cobra.Command {
+	var timeout int
+
+	cmd := &cobra.Command{
+		Use:   "stop [container...]",
+		Short: "Stop one or more running containers",
+		Long: `Stop running containers by sending SIGTERM, followed by SIGKILL
+if the container does not exit within the timeout.
+
+Examples:
+  container stop web
+  container stop -t 3 web db`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerRuntime := runtime.NewContainerRuntime(getDataDir())
+
+			for _, id := range args {
+				logVerbose("Stopping container %s (timeout %ds)", id, timeout)
+				container, err := containerRuntime.StopContainer(id, time.Duration(timeout)*time.Second)
+				if err != nil {
+					return err
+				}
+				fmt.Println(container.ID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&timeout, "time", "t", 10, "Seconds to wait before killing the container")
+
+	return cmd
+}
+
+func rmCmd() *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "rm [container...]",
+		Short: "Remove one or more containers",
+		Long: `Remove stopped containers including their rootfs and logs.
+
+Examples:
+  container rm web
+  container rm -f web`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerRuntime := runtime.NewContainerRuntime(getDataDir())
+
+			for _, id := range args {
+				logVerbose("Removing container %s", id)
+				if err := containerRuntime.RemoveContainer(id, force); err != nil {
+					return err
+				}
+				fmt.Println(id)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Force the removal of a running container")
+
+	return cmd
+}
+
+func logsCmd() *cobra.Command {
+	var follow bool
+
+	cmd := &cobra.Command{
+		Use:   "logs [container]",
+		Short: "Fetch the logs of a container",
+		Long: `Fetch the stdout/stderr output captured from a container.
+
+Examples:
+  container logs web
+  container logs -f web`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showLogs(args[0], follow)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+
+	return cmd
+}
+
+// shimCmd is the hidden command executed in the background for detached containers
+func shimCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "shim [container]",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerRuntime := runtime.NewContainerRuntime(getDataDir())
+
+			container, err := containerRuntime.LoadContainer(args[0])
+			if err != nil {
+				return err
+			}
+			if container == nil {
+				return fmt.Errorf("container %s not found", args[0])
+			}
+
+			return containerRuntime.StartContainer(container, false)
+		},
+	}
+}
+
+// startShim launches the shim for a container as a detached background process
+func startShim(containerID string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %w", err)
+	}
+
+	absDataDir, err := filepath.Abs(getDataDir())
+	if err != nil {
+		return fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+
+	// Runtime messages from the shim go to a separate file so that the
+	// container log only holds the container output
+	shimLog, err := os.OpenFile(filepath.Join(absDataDir, "containers", containerID, "shim.log"),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open shim log: %w", err)
+	}
+	defer shimLog.Close()
+
+	cmd := exec.Command(executable, "--data-dir", absDataDir, "shim", containerID)
+	cmd.Stdout = shimLog
+	cmd.Stderr = shimLog
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	logDebug("Started shim for %s with PID %d", containerID, cmd.Process.Pid)
+	return cmd.Process.Release()
+}
+
+// listContainers prints containers in a formatted table
+func listContainers(showAll bool) error {
+	containerRuntime := runtime.NewContainerRuntime(getDataDir())
+
+	containers, err := containerRuntime.ListContainers()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER ID\tIMAGE\tCOMMAND\tCREATED\tSTATUS\tPID\tPORTS")
+
+	for _, c := range containers {
+		if !showAll && c.Status != runtime.StatusRunning {
+			continue
+		}
+
+		pid := "-"
+		if c.Pid > 0 {
+			pid = fmt.Sprintf("%d", c.Pid)
+		}
+
+		var ports []string
+		for _, p := range c.Ports {
+			ports = append(ports, fmt.Sprintf("0.0.0.0:%d->%d/%s", p.HostPort, p.ContainerPort, p.Protocol))
+		}
+
+		fmt.Fprintf(w, "%s\t%s:%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.ID,
+			c.ImageName, c.ImageTag,
+			truncateString(fmt.Sprintf("%q", strings.Join(c.Command, " ")), 20),
+			formatRelativeTime(c.CreatedAt),
+			formatContainerStatus(c),
+			pid,
+			strings.Join(ports, ", "))
+	}
+
+	return w.Flush()
+}
+
+// formatContainerStatus formats the status column like `docker ps`
+func formatContainerStatus(c *runtime.Container) string {
+	switch c.Status {
+	case runtime.StatusRunning:
+		if c.StartedAt != nil {
+			return "Up " + strings.TrimSuffix(formatRelativeTime(*c.StartedAt), " ago")
+		}
+		return "Up"
+	case runtime.StatusExited, runtime.StatusFailed:
+		exitCode := -1
+		if c.ExitCode != nil {
+			exitCode = *c.ExitCode
+		}
+		if c.FinishedAt != nil {
+			return fmt.Sprintf("Exited (%d) %s", exitCode, formatRelativeTime(*c.FinishedAt))
+		}
+		return fmt.Sprintf("Exited (%d)", exitCode)
+	default:
+		return "Created"
+	}
+}
+
+// showLogs prints the captured output of a container, optionally following it
+func showLogs(containerID string, follow bool) error {
+	containerRuntime := runtime.NewContainerRuntime(getDataDir())
+
+	container, err := containerRuntime.LoadContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return fmt.Errorf("container %s not found", containerID)
+	}
+
+	file, err := os.Open(container.LogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Container has not produced any output yet
+		}
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	for {
+		if _, err := io.Copy(os.Stdout, file); err != nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+		if !follow {
+			return nil
+		}
+
+		// Stop following once the container is no longer running
+		latest, err := containerRuntime.LoadContainer(containerID)
+		if err != nil || latest == nil || latest.Status != runtime.StatusRunning && latest.Status != runtime.StatusCreated {
+			_, err := io.Copy(os.Stdout, file)
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
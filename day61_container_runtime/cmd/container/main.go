@@ -43,9 +43,16 @@ func main() {
 
 	// Add subcommands
 	rootCmd.AddCommand(pullCmd())
+	rootCmd.AddCommand(buildCmd())
 	rootCmd.AddCommand(listCmd())
 	rootCmd.AddCommand(runCmd())
 	rootCmd.AddCommand(inspectCmd())
+	rootCmd.AddCommand(psCmd())
+	rootCmd.AddCommand(stopCmd())
+	rootCmd.AddCommand(rmCmd())
+	rootCmd.AddCommand(logsCmd())
+	rootCmd.AddCommand(specCmd())
+	rootCmd.AddCommand(shimCmd())
 
 	// Execute root command
 	if err := rootCmd.Execute(); err != nil {
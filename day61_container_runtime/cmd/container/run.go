@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/network"
 	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/runtime"
 	"github.com/spf13/cobra"
 )
@@ -14,19 +15,28 @@ func runCmd() *cobra.Command {
 		workdir     string
 		envVars     []string
 		name        string
+		publish     []string
+		detach      bool
+		driver      string
+		ociRuntime  string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "run [image:tag] [command] [args...]",
 		Short: "Run a command in a new container",
 		Long: `Run a command in a new container created from the specified image.
+If no command is given, the image's default command (CMD) is used.
 
 Examples:
   container run busybox:latest /bin/echo "Hello World"
   container run alpine:latest /bin/sh -c "ls -la /"
   container run --name mycontainer busybox:latest /bin/pwd
-  container run -e FOO=bar -w /tmp alpine:latest /bin/env`,
-		Args: cobra.MinimumNArgs(2),
+  container run -e FOO=bar -w /tmp alpine:latest /bin/env
+  container run -p 8080:80 busybox:latest /bin/httpd -f -p 80
+  container run -d --name web busybox:latest /bin/httpd -f -p 80
+  container run myapp:latest
+  container run --runtime runc busybox:latest /bin/echo "Hello from runc"`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			imageName := args[0]
 			command := args[1:]
@@ -42,6 +52,16 @@ Examples:
 				logDebug("Command[%d]: '%s'", i, arg)
 			}
 
+			// Parse published ports
+			var ports []network.PortMapping
+			for _, spec := range publish {
+				port, err := network.ParsePortMapping(spec)
+				if err != nil {
+					return err
+				}
+				ports = append(ports, port)
+			}
+
 			// TODO: Implement actual container run logic
 			return runContainer(imgName, tag, command, &ContainerRunOptions{
 				Interactive: interactive,
@@ -49,6 +69,10 @@ Examples:
 				WorkDir:     workdir,
 				EnvVars:     envVars,
 				Name:        name,
+				Ports:       ports,
+				Detach:      detach,
+				Driver:      driver,
+				Runtime:     ociRuntime,
 			})
 		},
 	}
@@ -58,6 +82,10 @@ Examples:
 	cmd.Flags().StringVarP(&workdir, "workdir", "w", "", "Working directory inside the container")
 	cmd.Flags().StringArrayVarP(&envVars, "env", "e", []string{}, "Set environment variables")
 	cmd.Flags().StringVar(&name, "name", "", "Assign a name to the container")
+	cmd.Flags().BoolVarP(&detach, "detach", "d", false, "Run container in background and print container ID")
+	cmd.Flags().StringVar(&driver, "storage-driver", "", "Rootfs storage driver: overlay or copy (default: overlay when available)")
+	cmd.Flags().StringVar(&ociRuntime, "runtime", "native", "Runtime to execute the container with: native or runc")
+	cmd.Flags().StringArrayVarP(&publish, "publish", "p", []string{}, "Publish a container port to the host (hostPort:containerPort[/protocol])")
 
 	return cmd
 }
@@ -69,6 +97,10 @@ type ContainerRunOptions struct {
 	WorkDir     string
 	EnvVars     []string
 	Name        string
+	Ports       []network.PortMapping
+	Detach      bool
+	Driver      string
+	Runtime     string
 }
 
 // runContainer executes a container with the given options
@@ -78,10 +110,6 @@ func runContainer(imageName, tag string, command []string, opts *ContainerRunOpt
 		return fmt.Errorf("image name cannot be empty")
 	}
 
-	if len(command) == 0 {
-		return fmt.Errorf("command cannot be empty")
-	}
-
 	logDebug("Starting container from %s:%s", imageName, tag)
 
 	// Phase 2: Check if image exists locally
@@ -103,15 +131,23 @@ func runContainer(imageName, tag string, command []string, opts *ContainerRunOpt
 		Interactive: opts.Interactive,
 		TTY:         opts.TTY,
 		Name:        opts.Name,
+		Ports:       opts.Ports,
+
+		StorageDriver: opts.Driver,
+		Runtime:       opts.Runtime,
 	}
 
 	// Copy environment variables
 	copy(runOpts.Environment, opts.EnvVars)
 
-	logVerbose("Run options: WorkDir=%s, Env=%v, Name=%s", runOpts.WorkDir, runOpts.Environment, runOpts.Name)
+	logVerbose("Run options: WorkDir=%s, Env=%v, Name=%s, Ports=%v", runOpts.WorkDir, runOpts.Environment, runOpts.Name, runOpts.Ports)
 
 	// Phase 5: Execute container
 	fmt.Printf("Creating container from %s:%s...\n", imageName, tag)
+	if opts.Detach {
+		return runDetached(containerRuntime, imageName, tag, command, runOpts)
+	}
+
 	container, err := containerRuntime.RunContainer(imageName, tag, command, runOpts)
 	if err != nil {
 		return fmt.Errorf("container execution failed: %w", err)
@@ -124,3 +160,19 @@ func runContainer(imageName, tag string, command []string, opts *ContainerRunOpt
 
 	return nil
 }
+
+// runDetached creates the container and hands execution over to a background
+// shim process that waits for it and records its exit status
+func runDetached(containerRuntime *runtime.ContainerRuntime, imageName, tag string, command []string, runOpts *runtime.RunOptions) error {
+	container, err := containerRuntime.CreateContainer(imageName, tag, command, runOpts)
+	if err != nil {
+		return fmt.Errorf("container creation failed: %w", err)
+	}
+
+	if err := startShim(container.ID); err != nil {
+		return fmt.Errorf("failed to start container %s: %w", container.ID, err)
+	}
+
+	fmt.Println(container.ID)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/runtime"
+	"github.com/spf13/cobra"
+)
+
+func specCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spec [container]",
+		Short: "Show the OCI runtime spec of a container",
+		Long: `Show the OCI runtime spec (config.json) generated for a container.
+The container directory is a valid OCI bundle that can be passed to runc.
+
+Examples:
+  container spec web
+  runc run --bundle ./data/containers/web web`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerRuntime := runtime.NewContainerRuntime(getDataDir())
+
+			container, err := containerRuntime.LoadContainer(args[0])
+			if err != nil {
+				return err
+			}
+			if container == nil {
+				return fmt.Errorf("container %s not found", args[0])
+			}
+
+			data, err := os.ReadFile(containerRuntime.SpecPath(container.ID))
+			if err != nil {
+				return fmt.Errorf("failed to read runtime spec: %w", err)
+			}
+
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,360 @@
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/image"
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/registry"
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/runtime"
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/storage"
+)
+
+const layerMediaType = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+
+// Builder builds images from a Dockerfile
+type Builder struct {
+	dataDir        string
+	storageManager *storage.Manager
+	layerExtractor *image.LayerExtractor
+	runtime        *runtime.ContainerRuntime
+}
+
+// NewBuilder creates a new image builder
+func NewBuilder(dataDir string) *Builder {
+	return &Builder{
+		dataDir:        dataDir,
+		storageManager: storage.NewManager(dataDir),
+		layerExtractor: image.NewLayerExtractor(dataDir),
+		runtime:        runtime.NewContainerRuntime(dataDir),
+	}
+}
+
+// Options holds options for an image build
+type Options struct {
+	Repository string // Name of the resulting image
+	Tag        string // Tag of the resulting image
+	ContextDir string // Build context directory
+	Dockerfile string // Path to the Dockerfile
+	NoCache    bool   // Do not use the build cache
+}
+
+// cacheEntry is a build cache record stored in <dataDir>/build-cache
+type cacheEntry struct {
+	Layer     string    `json:"layer"`
+	DiffID    string    `json:"diffId"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// buildState holds the image being assembled while instructions are applied
+type buildState struct {
+	image    *storage.LocalImage
+	cacheKey string // Chain key of all instructions applied so far
+	rootfs   string // Working rootfs for RUN instructions (built lazily)
+}
+
+// Build executes the Dockerfile and stores the resulting image
+func (b *Builder) Build(opts *Options) (*storage.LocalImage, error) {
+	instructions, err := ParseDockerfile(opts.Dockerfile)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, err := os.MkdirTemp(filepath.Join(b.dataDir, "containers"), "build-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	state := &buildState{}
+
+	for i, inst := range instructions {
+		fmt.Printf("Step %d/%d : %s\n", i+1, len(instructions), inst.Raw)
+
+		if err := b.apply(state, inst, opts, workDir); err != nil {
+			return nil, fmt.Errorf("step %d (line %d): %w", i+1, inst.Line, err)
+		}
+	}
+
+	img := state.image
+	img.Repository = opts.Repository
+	img.Tag = opts.Tag
+	img.Created = time.Now().UTC().Format(time.RFC3339)
+	img.PulledAt = time.Now()
+
+	configData, err := json.Marshal(img.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal image config: %w", err)
+	}
+	configDigest := sha256.Sum256(configData)
+	img.Digest = "sha256:" + hex.EncodeToString(configDigest[:])
+	img.Manifest.Config = registry.DescriptorConfig{
+		MediaType: "application/vnd.docker.container.image.v1+json",
+		Size:      int64(len(configData)),
+		Digest:    img.Digest,
+	}
+
+	if err := b.storageManager.SaveImage(img); err != nil {
+		return nil, fmt.Errorf("failed to save image metadata: %w", err)
+	}
+
+	return img, nil
+}
+
+// apply executes a single instruction against the build state
+func (b *Builder) apply(state *buildState, inst *Instruction, opts *Options, workDir string) error {
+	switch inst.Command {
+	case "FROM":
+		return b.from(state, inst.Args[0])
+
+	case "ENV":
+		envs, _ := parseEnv(inst.Args)
+		for _, env := range envs {
+			state.image.Config.Config.Env = setEnv(state.image.Config.Config.Env, env)
+		}
+		state.cacheKey = chainKey(state.cacheKey, inst.Raw, "")
+		b.addHistory(state, inst, true)
+		return nil
+
+	case "CMD":
+		state.image.Config.Config.Cmd = inst.ShellCommand()
+		state.cacheKey = chainKey(state.cacheKey, inst.Raw, "")
+		b.addHistory(state, inst, true)
+		return nil
+
+	case "COPY":
+		sources, dest := inst.Args[:len(inst.Args)-1], inst.Args[len(inst.Args)-1]
+		contextHash, err := hashContext(opts.ContextDir, sources)
+		if err != nil {
+			return err
+		}
+		return b.layerStep(state, inst, contextHash, opts.NoCache, func() (*layerArchive, error) {
+			return copyLayer(opts.ContextDir, sources, dest)
+		})
+
+	case "RUN":
+		return b.layerStep(state, inst, "", opts.NoCache, func() (*layerArchive, error) {
+			return b.run(state, inst, workDir)
+		})
+	}
+
+	return fmt.Errorf("unsupported instruction %s", inst.Command)
+}
+
+// from initializes the build state from a locally stored base image
+func (b *Builder) from(state *buildState, ref string) error {
+	repository, tag := ref, "latest"
+	if idx := strings.LastIndex(ref, ":"); idx > 0 {
+		repository, tag = ref[:idx], ref[idx+1:]
+	}
+
+	base, err := b.storageManager.LoadImage(repository, tag)
+	if err != nil {
+		return fmt.Errorf("failed to load base image: %w", err)
+	}
+	if base == nil {
+		return fmt.Errorf("base image %s:%s not found locally, run `container pull %s:%s` first", repository, tag, repository, tag)
+	}
+
+	// Deep copy the base image so that its metadata is left untouched
+	data, err := json.Marshal(base)
+	if err != nil {
+		return fmt.Errorf("failed to copy base image: %w", err)
+	}
+	img := &storage.LocalImage{}
+	if err := json.Unmarshal(data, img); err != nil {
+		return fmt.Errorf("failed to copy base image: %w", err)
+	}
+
+	if img.Config == nil {
+		img.Config = &registry.ImageConfig{Architecture: img.Architecture, OS: img.OS}
+	}
+	if img.Config.Config == nil {
+		img.Config.Config = &registry.ContainerConfig{}
+	}
+	if img.Config.RootFS == nil {
+		img.Config.RootFS = &registry.RootFS{Type: "layers"}
+	}
+	if img.Manifest == nil {
+		img.Manifest = &registry.Manifest{SchemaVersion: 2}
+	}
+
+	state.image = img
+	state.cacheKey = chainKey("", "FROM "+base.Digest, "")
+	fmt.Printf(" ---> %s\n", truncateDigest(base.Digest))
+	return nil
+}
+
+// layerStep runs a layer-producing instruction, reusing the build cache when possible
+func (b *Builder) layerStep(state *buildState, inst *Instruction, contextHash string, noCache bool, create func() (*layerArchive, error)) error {
+	key := chainKey(state.cacheKey, inst.Raw, contextHash)
+
+	if !noCache {
+		if entry, err := b.loadCache(key); err == nil && entry != nil && b.storageManager.LayerExists(entry.Layer) {
+			fmt.Printf(" ---> Using cache\n")
+			fmt.Printf(" ---> %s\n", truncateDigest(entry.Layer))
+			state.cacheKey = key
+			state.rootfs = "" // The cached layer is not applied to the working rootfs
+			b.appendLayer(state, inst, entry.Layer, entry.DiffID, entry.Size)
+			return nil
+		}
+	}
+
+	layer, err := create()
+	if err != nil {
+		return err
+	}
+
+	if _, err := b.storageManager.SaveLayer(layer.Digest, layer.Data); err != nil {
+		return fmt.Errorf("failed to save layer: %w", err)
+	}
+
+	entry := &cacheEntry{
+		Layer:     layer.Digest,
+		DiffID:    layer.DiffID,
+		Size:      int64(len(layer.Data)),
+		CreatedAt: time.Now(),
+	}
+	if err := b.saveCache(key, entry); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	fmt.Printf(" ---> %s\n", truncateDigest(layer.Digest))
+	state.cacheKey = key
+	if inst.Command != "RUN" {
+		state.rootfs = "" // Only RUN modifies the working rootfs in place
+	}
+	b.appendLayer(state, inst, entry.Layer, entry.DiffID, entry.Size)
+	return nil
+}
+
+// run executes a RUN instruction in an intermediate container and returns
+// the filesystem changes as a new layer
+func (b *Builder) run(state *buildState, inst *Instruction, workDir string) (*layerArchive, error) {
+	// The intermediate rootfs is rebuilt from the current layers when needed
+	if state.rootfs == "" {
+		state.rootfs = filepath.Join(workDir, "rootfs")
+		if err := os.RemoveAll(state.rootfs); err != nil {
+			return nil, fmt.Errorf("failed to reset build rootfs: %w", err)
+		}
+		if err := b.layerExtractor.BuildRootFS(state.image.Repository, state.image.Tag, state.image.Layers, state.rootfs); err != nil {
+			return nil, fmt.Errorf("failed to build rootfs: %w", err)
+		}
+	}
+
+	before, err := takeSnapshot(state.rootfs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot rootfs: %w", err)
+	}
+
+	config := state.image.Config.Config
+	workingDir := config.WorkingDir
+	if workingDir == "" {
+		workingDir = "/"
+	}
+
+	if err := b.runtime.ExecBuildStep(state.rootfs, inst.ShellCommand(), config.Env, workingDir, os.Stdout); err != nil {
+		return nil, err
+	}
+
+	return diffLayer(state.rootfs, before)
+}
+
+// appendLayer adds a layer to the image being built
+func (b *Builder) appendLayer(state *buildState, inst *Instruction, digest, diffID string, size int64) {
+	img := state.image
+	img.Layers = append(img.Layers, digest)
+	img.Size += size
+	img.Config.RootFS.DiffIDs = append(img.Config.RootFS.DiffIDs, diffID)
+	img.Manifest.Layers = append(img.Manifest.Layers, registry.LayerDescriptor{
+		MediaType: layerMediaType,
+		Size:      size,
+		Digest:    digest,
+	})
+
+	b.addHistory(state, inst, false)
+}
+
+// addHistory records an instruction in the image history
+func (b *Builder) addHistory(state *buildState, inst *Instruction, emptyLayer bool) {
+	state.image.Config.History = append(state.image.Config.History, registry.HistoryEntry{
+		Created:    time.Now().UTC().Format(time.RFC3339),
+		CreatedBy:  inst.Raw,
+		Comment:    "container build",
+		EmptyLayer: emptyLayer,
+	})
+}
+
+// cachePath returns the path of a build cache entry
+func (b *Builder) cachePath(key string) string {
+	return filepath.Join(b.dataDir, "build-cache", key+".json")
+}
+
+// loadCache looks up a build cache entry; it returns nil if there is none
+func (b *Builder) loadCache(key string) (*cacheEntry, error) {
+	data, err := os.ReadFile(b.cachePath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// saveCache stores a build cache entry
+func (b *Builder) saveCache(key string, entry *cacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(b.cachePath(key)), 0755); err != nil {
+		return fmt.Errorf("failed to create build cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal build cache entry: %w", err)
+	}
+
+	if err := os.WriteFile(b.cachePath(key), data, 0644); err != nil {
+		return fmt.Errorf("failed to write build cache entry: %w", err)
+	}
+	return nil
+}
+
+// chainKey derives the cache key of an instruction from its parent key,
+// the instruction text and (for COPY) the hash of the copied context
+func chainKey(parent, instruction, contextHash string) string {
+	sum := sha256.Sum256([]byte(parent + "\n" + instruction + "\n" + contextHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// setEnv sets or replaces a KEY=VALUE entry in an environment list
+func setEnv(env []string, entry string) []string {
+	key, _, _ := strings.Cut(entry, "=")
+	for i, existing := range env {
+		if strings.HasPrefix(existing, key+"=") {
+			env[i] = entry
+			return env
+		}
+	}
+	return append(env, entry)
+}
+
+// truncateDigest shortens a digest for display
+func truncateDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
@@ -0,0 +1,189 @@
+package build
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Instruction represents a single Dockerfile instruction
+type Instruction struct {
+	Command string   // Upper-cased instruction name (FROM, COPY, ...)
+	Args    []string // Parsed arguments
+	JSON    bool     // Whether the arguments were given in exec (JSON array) form
+	Raw     string   // Original instruction text, used for the build cache key
+	Line    int      // Line number in the Dockerfile
+}
+
+// supportedInstructions lists the Dockerfile subset understood by the builder
+var supportedInstructions = map[string]bool{
+	"FROM": true,
+	"COPY": true,
+	"RUN":  true,
+	"ENV":  true,
+	"CMD":  true,
+}
+
+// ParseDockerfile reads and parses a Dockerfile
+func ParseDockerfile(path string) ([]*Instruction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Dockerfile: %w", err)
+	}
+	defer file.Close()
+
+	var instructions []*Instruction
+	var current strings.Builder
+	startLine := 0
+	lineNo := 0
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip comments and blank lines (also inside continuations)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if current.Len() == 0 {
+			startLine = lineNo
+		}
+
+		// Handle line continuations
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\"))
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(line)
+
+		inst, err := parseInstruction(current.String(), startLine)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, inst)
+		current.Reset()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Dockerfile: %w", err)
+	}
+
+	if current.Len() > 0 {
+		inst, err := parseInstruction(current.String(), startLine)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, inst)
+	}
+
+	if len(instructions) == 0 {
+		return nil, fmt.Errorf("Dockerfile has no instructions")
+	}
+	if instructions[0].Command != "FROM" {
+		return nil, fmt.Errorf("line %d: Dockerfile must start with FROM", instructions[0].Line)
+	}
+
+	return instructions, nil
+}
+
+// parseInstruction parses a single (continuation-joined) instruction line
+func parseInstruction(text string, line int) (*Instruction, error) {
+	text = strings.TrimSpace(text)
+	fields := strings.SplitN(text, " ", 2)
+	command := strings.ToUpper(fields[0])
+
+	if !supportedInstructions[command] {
+		return nil, fmt.Errorf("line %d: unsupported instruction %s", line, command)
+	}
+
+	rest := ""
+	if len(fields) > 1 {
+		rest = strings.TrimSpace(fields[1])
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("line %d: %s requires at least one argument", line, command)
+	}
+
+	inst := &Instruction{
+		Command: command,
+		Raw:     command + " " + rest,
+		Line:    line,
+	}
+
+	// Exec form: ["executable", "param1", ...]
+	if strings.HasPrefix(rest, "[") && (command == "RUN" || command == "CMD" || command == "COPY") {
+		var args []string
+		if err := json.Unmarshal([]byte(rest), &args); err == nil {
+			inst.Args = args
+			inst.JSON = true
+			return inst, validateInstruction(inst)
+		}
+	}
+
+	switch command {
+	case "RUN", "CMD":
+		// Shell form keeps the whole command line as a single argument
+		inst.Args = []string{rest}
+	default:
+		inst.Args = strings.Fields(rest)
+	}
+
+	return inst, validateInstruction(inst)
+}
+
+// validateInstruction checks argument counts for each instruction
+func validateInstruction(inst *Instruction) error {
+	switch inst.Command {
+	case "FROM":
+		if len(inst.Args) != 1 {
+			return fmt.Errorf("line %d: FROM requires exactly one argument", inst.Line)
+		}
+	case "COPY":
+		if len(inst.Args) < 2 {
+			return fmt.Errorf("line %d: COPY requires at least two arguments", inst.Line)
+		}
+	case "ENV":
+		if _, err := parseEnv(inst.Args); err != nil {
+			return fmt.Errorf("line %d: %w", inst.Line, err)
+		}
+	}
+	return nil
+}
+
+// ShellCommand returns the command to execute for a RUN or CMD instruction
+func (i *Instruction) ShellCommand() []string {
+	if i.JSON {
+		return i.Args
+	}
+	return []string{"/bin/sh", "-c", i.Args[0]}
+}
+
+// parseEnv parses ENV arguments in either `KEY=VALUE ...` or `KEY VALUE` form
+func parseEnv(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("ENV requires at least one argument")
+	}
+
+	// Legacy form: ENV KEY some value
+	if !strings.Contains(args[0], "=") {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("ENV %s is missing a value", args[0])
+		}
+		return []string{args[0] + "=" + strings.Join(args[1:], " ")}, nil
+	}
+
+	var envs []string
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid ENV argument %q", arg)
+		}
+		envs = append(envs, key+"="+strings.Trim(value, `"'`))
+	}
+	return envs, nil
+}
@@ -0,0 +1,276 @@
+package build
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// layerArchive is a gzip-compressed layer tarball ready to be stored
+type layerArchive struct {
+	Data   []byte // gzip-compressed tar
+	Digest string // sha256 of the compressed data
+	DiffID string // sha256 of the uncompressed tar
+}
+
+// fileState captures the attributes used to detect changes in a rootfs
+type fileState struct {
+	mode    os.FileMode
+	size    int64
+	modTime time.Time
+	link    string
+}
+
+// snapshot records the state of every path in a rootfs
+type snapshot map[string]fileState
+
+// takeSnapshot walks a rootfs and records the state of all paths
+func takeSnapshot(root string) (snapshot, error) {
+	snap := snapshot{}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+
+		state := fileState{
+			mode:    info.Mode(),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			state.link, _ = os.Readlink(path)
+		}
+
+		snap[relPath] = state
+		return nil
+	})
+
+	return snap, err
+}
+
+// diffLayer creates a layer holding everything that changed in root since
+// the before snapshot; removed paths are recorded as whiteout files
+func diffLayer(root string, before snapshot) (*layerArchive, error) {
+	after, err := takeSnapshot(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot rootfs: %w", err)
+	}
+
+	var changed []string
+	for path, state := range after {
+		if prev, ok := before[path]; !ok || prev != state {
+			changed = append(changed, path)
+		}
+	}
+
+	var removed []string
+	for path := range before {
+		if _, ok := after[path]; ok {
+			continue
+		}
+		// Only the topmost removed path needs a whiteout
+		parent := filepath.Dir(path)
+		if _, parentExisted := before[parent]; parentExisted {
+			if _, parentExists := after[parent]; !parentExists {
+				continue
+			}
+		}
+		removed = append(removed, path)
+	}
+
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	return writeLayer(func(tw *tar.Writer) error {
+		for _, path := range changed {
+			if err := addPath(tw, filepath.Join(root, path), path); err != nil {
+				return err
+			}
+		}
+		for _, path := range removed {
+			whiteout := filepath.Join(filepath.Dir(path), ".wh."+filepath.Base(path))
+			if err := tw.WriteHeader(&tar.Header{
+				Name:     filepath.ToSlash(whiteout),
+				Typeflag: tar.TypeReg,
+				Mode:     0644,
+				ModTime:  time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// copyLayer creates a layer containing the given context sources placed at dest
+func copyLayer(contextDir string, sources []string, dest string) (*layerArchive, error) {
+	toDir := len(sources) > 1 || strings.HasSuffix(dest, "/")
+	dest = strings.TrimPrefix(filepath.Clean("/"+dest), "/")
+
+	return writeLayer(func(tw *tar.Writer) error {
+		for _, src := range sources {
+			srcPath, err := contextPath(contextDir, src)
+			if err != nil {
+				return err
+			}
+
+			info, err := os.Stat(srcPath)
+			if err != nil {
+				return fmt.Errorf("COPY source %s: %w", src, err)
+			}
+
+			// Directories are copied by content, files keep their name when copied into a directory
+			target := dest
+			if !info.IsDir() && (toDir || dest == "") {
+				target = filepath.Join(dest, filepath.Base(srcPath))
+			}
+
+			err = filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				relPath, err := filepath.Rel(srcPath, path)
+				if err != nil {
+					return err
+				}
+				name := filepath.Join(target, relPath)
+				if name == "." || name == "" {
+					return nil
+				}
+				return addPath(tw, path, name)
+			})
+			if err != nil {
+				return fmt.Errorf("COPY source %s: %w", src, err)
+			}
+		}
+		return nil
+	})
+}
+
+// contextPath resolves a COPY source within the build context
+func contextPath(contextDir, src string) (string, error) {
+	path := filepath.Join(contextDir, src)
+	if path != filepath.Clean(contextDir) && !strings.HasPrefix(path, filepath.Clean(contextDir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("COPY source %s is outside the build context", src)
+	}
+	return path, nil
+}
+
+// hashContext computes a content hash of COPY sources for the build cache
+func hashContext(contextDir string, sources []string) (string, error) {
+	hasher := sha256.New()
+
+	for _, src := range sources {
+		srcPath, err := contextPath(contextDir, src)
+		if err != nil {
+			return "", err
+		}
+
+		err = filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			relPath, _ := filepath.Rel(contextDir, path)
+			fmt.Fprintf(hasher, "%s %o\n", relPath, info.Mode())
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(hasher, file)
+			return err
+		})
+		if err != nil {
+			return "", fmt.Errorf("COPY source %s: %w", src, err)
+		}
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// addPath writes a single filesystem entry to the tar archive
+func addPath(tw *tar.Writer, path, name string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(name)
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// writeLayer builds a gzip-compressed tar using the given writer function
+func writeLayer(fill func(tw *tar.Writer) error) (*layerArchive, error) {
+	var compressed bytes.Buffer
+	diffHasher := sha256.New()
+
+	gzipWriter := gzip.NewWriter(&compressed)
+	tarWriter := tar.NewWriter(io.MultiWriter(gzipWriter, diffHasher))
+
+	if err := fill(tarWriter); err != nil {
+		return nil, fmt.Errorf("failed to create layer: %w", err)
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize layer tar: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress layer: %w", err)
+	}
+
+	digest := sha256.Sum256(compressed.Bytes())
+
+	return &layerArchive{
+		Data:   compressed.Bytes(),
+		Digest: "sha256:" + hex.EncodeToString(digest[:]),
+		DiffID: "sha256:" + hex.EncodeToString(diffHasher.Sum(nil)),
+	}, nil
+}
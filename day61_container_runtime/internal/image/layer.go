@@ -24,6 +24,11 @@ func NewLayerExtractor(dataDir string) *LayerExtractor {
 
 // ExtractLayer extracts a single layer to a target directory
 func (e *LayerExtractor) ExtractLayer(layerPath, targetDir string) error {
+	return e.extractLayer(layerPath, targetDir, e.applyWhiteout)
+}
+
+// extractLayer extracts a layer, handing whiteout files to the given handler
+func (e *LayerExtractor) extractLayer(layerPath, targetDir string, whiteout func(path string) error) error {
 	// fmt.Printf("  Extracting layer: %s\n", filepath.Base(layerPath))
 
 	// Open the layer file
@@ -69,6 +74,14 @@ func (e *LayerExtractor) ExtractLayer(layerPath, targetDir string) error {
 			continue
 		}
 
+		// Whiteout files mark paths deleted from lower layers
+		if base := filepath.Base(targetPath); strings.HasPrefix(base, ".wh.") {
+			if err := whiteout(targetPath); err != nil {
+				return fmt.Errorf("failed to apply whiteout %s: %w", header.Name, err)
+			}
+			continue
+		}
+
 		switch header.Typeflag {
 		case tar.TypeDir:
 			// Create directory
@@ -104,6 +117,31 @@ func (e *LayerExtractor) ExtractLayer(layerPath, targetDir string) error {
 	return nil
 }
 
+// applyWhiteout removes the path hidden by a whiteout file. An opaque
+// whiteout (.wh..wh..opq) clears the contents of its directory.
+func (e *LayerExtractor) applyWhiteout(whiteoutPath string) error {
+	dir := filepath.Dir(whiteoutPath)
+	base := filepath.Base(whiteoutPath)
+
+	if base == ".wh..wh..opq" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, entry := range entries {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return os.RemoveAll(filepath.Join(dir, strings.TrimPrefix(base, ".wh.")))
+}
+
 // extractFile extracts a regular file
 func (e *LayerExtractor) extractFile(tarReader *tar.Reader, targetPath string, header *tar.Header) error {
 	// Create parent directory if it doesn't exist
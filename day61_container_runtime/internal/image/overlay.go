@@ -0,0 +1,188 @@
+package image
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Storage drivers used to construct container root filesystems
+const (
+	// DriverOverlay mounts the extracted layers with overlayfs
+	DriverOverlay = "overlay"
+	// DriverCopy extracts all layers into a private rootfs directory
+	DriverCopy = "copy"
+)
+
+// OverlaySupported reports whether overlayfs mounts can be used on this host.
+// It requires Linux, root privileges and kernel overlay support.
+func (e *LayerExtractor) OverlaySupported() bool {
+	if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+		return false
+	}
+
+	file, err := os.Open("/proc/filesystems")
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[len(fields)-1] == "overlay" {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractedLayerPath returns the content-addressed directory holding an
+// extracted layer. The "sha256:" prefix is dropped because overlayfs uses
+// ':' to separate lower directories.
+func (e *LayerExtractor) ExtractedLayerPath(digest string) string {
+	return filepath.Join(e.dataDir, "layers", "extracted", strings.TrimPrefix(digest, "sha256:"))
+}
+
+// PrepareLayer extracts a layer once into its content-addressed directory,
+// converting whiteouts into the overlayfs representation
+func (e *LayerExtractor) PrepareLayer(digest string) (string, error) {
+	layerDir := e.ExtractedLayerPath(digest)
+	if _, err := os.Stat(layerDir); err == nil {
+		return layerDir, nil // Already extracted
+	}
+
+	layerPath := filepath.Join(e.dataDir, "layers", digest+".tar.gz")
+	if _, err := os.Stat(layerPath); err != nil {
+		return "", fmt.Errorf("layer file not found: %s: %w", layerPath, err)
+	}
+
+	// Extract to a temporary directory and rename so that a partially
+	// extracted layer is never used
+	tmpDir := layerDir + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return "", fmt.Errorf("failed to clean temporary layer directory: %w", err)
+	}
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create layer directory: %w", err)
+	}
+
+	if err := e.extractLayer(layerPath, tmpDir, e.overlayWhiteout); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to extract layer %s: %w", digest, err)
+	}
+
+	if err := os.Rename(tmpDir, layerDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to store extracted layer: %w", err)
+	}
+
+	return layerDir, nil
+}
+
+// overlayWhiteout converts an OCI whiteout file into its overlayfs form:
+// a 0/0 character device for deleted paths, or the opaque xattr on a directory
+func (e *LayerExtractor) overlayWhiteout(whiteoutPath string) error {
+	dir := filepath.Dir(whiteoutPath)
+	base := filepath.Base(whiteoutPath)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	if base == ".wh..wh..opq" {
+		return runCommand("setfattr", "-n", "trusted.overlay.opaque", "-v", "y", dir)
+	}
+
+	target := filepath.Join(dir, strings.TrimPrefix(base, ".wh."))
+	os.RemoveAll(target)
+	return runCommand("mknod", target, "c", "0", "0")
+}
+
+// MountOverlay mounts the given layers (lowest first) at rootfsPath using
+// overlayfs, with writable upper and work directories under containerDir
+func (e *LayerExtractor) MountOverlay(layerDigests []string, containerDir, rootfsPath string) error {
+	if len(layerDigests) == 0 {
+		return fmt.Errorf("overlay mount requires at least one layer")
+	}
+
+	// overlayfs lists lower directories from top to bottom
+	lowerDirs := make([]string, 0, len(layerDigests))
+	for i := len(layerDigests) - 1; i >= 0; i-- {
+		layerDir, err := e.PrepareLayer(layerDigests[i])
+		if err != nil {
+			return err
+		}
+		absDir, err := filepath.Abs(layerDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve layer directory: %w", err)
+		}
+		lowerDirs = append(lowerDirs, absDir)
+	}
+
+	absContainerDir, err := filepath.Abs(containerDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve container directory: %w", err)
+	}
+	upperDir := filepath.Join(absContainerDir, "upper")
+	workDir := filepath.Join(absContainerDir, "work")
+
+	for _, dir := range []string{upperDir, workDir, rootfsPath} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+
+	options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", strings.Join(lowerDirs, ":"), upperDir, workDir)
+	if err := runCommand("mount", "-t", "overlay", "overlay", "-o", options, rootfsPath); err != nil {
+		return fmt.Errorf("failed to mount overlay: %w", err)
+	}
+
+	return nil
+}
+
+// UnmountOverlay unmounts an overlay rootfs if it is currently mounted
+func (e *LayerExtractor) UnmountOverlay(rootfsPath string) error {
+	absPath, err := filepath.Abs(rootfsPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve rootfs path: %w", err)
+	}
+
+	if !isMounted(absPath) {
+		return nil
+	}
+
+	if err := runCommand("umount", absPath); err != nil {
+		return fmt.Errorf("failed to unmount overlay: %w", err)
+	}
+	return nil
+}
+
+// isMounted checks /proc/mounts for a mount point
+func isMounted(path string) bool {
+	data, err := os.ReadFile("/proc/mounts")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == path {
+			return true
+		}
+	}
+	return false
+}
+
+// runCommand executes a command and includes its output in the returned error
+func runCommand(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w (%s)", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,392 @@
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultBridgeName is the name of the host bridge shared by all containers
+	DefaultBridgeName = "ctr0"
+	// DefaultSubnet is the subnet assigned to the bridge network
+	DefaultSubnet = "10.88.0.0/24"
+)
+
+// PortMapping represents a published port (hostPort:containerPort[/protocol])
+type PortMapping struct {
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+// String returns the mapping in -p flag format
+func (p PortMapping) String() string {
+	return fmt.Sprintf("%d:%d/%s", p.HostPort, p.ContainerPort, p.Protocol)
+}
+
+// ParsePortMapping parses a -p flag value such as "8080:80" or "5353:53/udp"
+func ParsePortMapping(spec string) (PortMapping, error) {
+	mapping := PortMapping{Protocol: "tcp"}
+
+	ports := spec
+	if idx := strings.Index(spec, "/"); idx >= 0 {
+		ports = spec[:idx]
+		mapping.Protocol = strings.ToLower(spec[idx+1:])
+	}
+	if mapping.Protocol != "tcp" && mapping.Protocol != "udp" {
+		return mapping, fmt.Errorf("invalid protocol in port mapping %q: %s", spec, mapping.Protocol)
+	}
+
+	parts := strings.Split(ports, ":")
+	if len(parts) != 2 {
+		return mapping, fmt.Errorf("invalid port mapping %q: expected hostPort:containerPort", spec)
+	}
+
+	hostPort, err := parsePort(parts[0])
+	if err != nil {
+		return mapping, fmt.Errorf("invalid host port in %q: %w", spec, err)
+	}
+	containerPort, err := parsePort(parts[1])
+	if err != nil {
+		return mapping, fmt.Errorf("invalid container port in %q: %w", spec, err)
+	}
+
+	mapping.HostPort = hostPort
+	mapping.ContainerPort = containerPort
+	return mapping, nil
+}
+
+// parsePort parses and validates a single port number
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("not a number: %s", s)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("out of range: %d", port)
+	}
+	return port, nil
+}
+
+// Endpoint describes the network resources attached to a single container
+type Endpoint struct {
+	ContainerID string        `json:"containerId"`
+	NetNS       string        `json:"netns"`
+	HostVeth    string        `json:"hostVeth"`
+	IPAddress   string        `json:"ipAddress"`
+	Gateway     string        `json:"gateway"`
+	Ports       []PortMapping `json:"ports"`
+	// Rules holds the iptables rules added for this endpoint so that they
+	// can be removed with exactly the same arguments on teardown
+	Rules []IPTablesRule `json:"rules"`
+}
+
+// IPTablesRule is an iptables rule in a chain of a table
+type IPTablesRule struct {
+	Table string   `json:"table"`
+	Chain string   `json:"chain"`
+	Spec  []string `json:"spec"`
+}
+
+// args returns the iptables arguments that apply action (-A, -D or -C) to the rule.
+// The table must come before the action, otherwise iptables reads -t as the chain name.
+func (r IPTablesRule) args(action string) []string {
+	return append([]string{"-t", r.Table, action, r.Chain}, r.Spec...)
+}
+
+// BridgeManager sets up bridge networking for containers
+type BridgeManager struct {
+	dataDir    string
+	bridgeName string
+	subnet     *net.IPNet
+	gateway    net.IP
+}
+
+// NewBridgeManager creates a new bridge manager using the default bridge and subnet
+func NewBridgeManager(dataDir string) *BridgeManager {
+	_, subnet, _ := net.ParseCIDR(DefaultSubnet)
+	gateway := make(net.IP, len(subnet.IP.To4()))
+	copy(gateway, subnet.IP.To4())
+	gateway[3] = 1
+
+	return &BridgeManager{
+		dataDir:    dataDir,
+		bridgeName: DefaultBridgeName,
+		subnet:     subnet,
+		gateway:    gateway,
+	}
+}
+
+// Setup creates the bridge (if needed), a network namespace and veth pair for
+// the container, assigns it an IP address and installs DNAT rules for ports
+func (b *BridgeManager) Setup(containerID string, ports []PortMapping) (*Endpoint, error) {
+	if err := b.ensureBridge(); err != nil {
+		return nil, fmt.Errorf("failed to set up bridge %s: %w", b.bridgeName, err)
+	}
+
+	ip, err := b.allocateIP(containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate IP address: %w", err)
+	}
+
+	short := shortID(containerID)
+	ep := &Endpoint{
+		ContainerID: containerID,
+		NetNS:       "ctr-" + short,
+		HostVeth:    "veth" + short,
+		IPAddress:   ip.String(),
+		Gateway:     b.gateway.String(),
+		Ports:       ports,
+	}
+
+	if err := b.setupNamespace(ep, "ceth"+short); err != nil {
+		b.Teardown(ep)
+		return nil, err
+	}
+
+	for _, port := range ports {
+		for _, rule := range b.portRules(ep, port) {
+			if err := run("iptables", rule.args("-A")...); err != nil {
+				b.Teardown(ep)
+				return nil, fmt.Errorf("failed to publish port %s: %w", port, err)
+			}
+			ep.Rules = append(ep.Rules, rule)
+		}
+	}
+
+	return ep, nil
+}
+
+// Teardown removes all resources created by Setup. It is best-effort so that
+// partially created endpoints can be cleaned up as well.
+func (b *BridgeManager) Teardown(ep *Endpoint) error {
+	var errs []string
+
+	for _, rule := range ep.Rules {
+		if err := run("iptables", rule.args("-D")...); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	ep.Rules = nil
+
+	// Deleting the host side of the veth pair also removes the peer
+	if linkExists(ep.HostVeth) {
+		if err := run("ip", "link", "del", ep.HostVeth); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := run("ip", "netns", "del", ep.NetNS); err != nil && netnsExists(ep.NetNS) {
+		errs = append(errs, err.Error())
+	}
+
+	if err := b.releaseIP(ep.ContainerID); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("network teardown incomplete: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Command wraps a command so that it runs inside the endpoint's network namespace
+func (ep *Endpoint) Command(path string, args ...string) *exec.Cmd {
+	return exec.Command("ip", append([]string{"netns", "exec", ep.NetNS, path}, args...)...)
+}
+
+// ensureBridge creates and configures the host bridge if it does not exist yet
+func (b *BridgeManager) ensureBridge() error {
+	if !linkExists(b.bridgeName) {
+		ones, _ := b.subnet.Mask.Size()
+		cmds := [][]string{
+			{"ip", "link", "add", b.bridgeName, "type", "bridge"},
+			{"ip", "addr", "add", fmt.Sprintf("%s/%d", b.gateway, ones), "dev", b.bridgeName},
+			{"ip", "link", "set", b.bridgeName, "up"},
+		}
+		for _, c := range cmds {
+			if err := run(c[0], c[1:]...); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Required for forwarding to containers and for DNAT of localhost traffic
+	if err := os.WriteFile("/proc/sys/net/ipv4/ip_forward", []byte("1"), 0644); err != nil {
+		return fmt.Errorf("failed to enable ip_forward: %w", err)
+	}
+	routeLocalnet := fmt.Sprintf("/proc/sys/net/ipv4/conf/%s/route_localnet", b.bridgeName)
+	if err := os.WriteFile(routeLocalnet, []byte("1"), 0644); err != nil {
+		return fmt.Errorf("failed to enable route_localnet: %w", err)
+	}
+
+	// Outbound traffic from containers is masqueraded behind the host
+	masquerade := IPTablesRule{Table: "nat", Chain: "POSTROUTING", Spec: []string{"-s", b.subnet.String(), "!", "-o", b.bridgeName, "-j", "MASQUERADE"}}
+	return ensureRule(masquerade)
+}
+
+// setupNamespace creates the network namespace and veth pair for an endpoint
+func (b *BridgeManager) setupNamespace(ep *Endpoint, peer string) error {
+	ones, _ := b.subnet.Mask.Size()
+	cmds := [][]string{
+		{"ip", "netns", "add", ep.NetNS},
+		{"ip", "link", "add", ep.HostVeth, "type", "veth", "peer", "name", peer},
+		{"ip", "link", "set", ep.HostVeth, "master", b.bridgeName},
+		{"ip", "link", "set", ep.HostVeth, "up"},
+		{"ip", "link", "set", peer, "netns", ep.NetNS},
+		{"ip", "netns", "exec", ep.NetNS, "ip", "link", "set", peer, "name", "eth0"},
+		{"ip", "netns", "exec", ep.NetNS, "ip", "addr", "add", fmt.Sprintf("%s/%d", ep.IPAddress, ones), "dev", "eth0"},
+		{"ip", "netns", "exec", ep.NetNS, "ip", "link", "set", "eth0", "up"},
+		{"ip", "netns", "exec", ep.NetNS, "ip", "link", "set", "lo", "up"},
+		{"ip", "netns", "exec", ep.NetNS, "ip", "route", "add", "default", "via", ep.Gateway},
+	}
+	for _, c := range cmds {
+		if err := run(c[0], c[1:]...); err != nil {
+			return fmt.Errorf("failed to set up container network: %w", err)
+		}
+	}
+	return nil
+}
+
+// portRules returns the iptables rules needed to publish a port
+func (b *BridgeManager) portRules(ep *Endpoint, port PortMapping) []IPTablesRule {
+	dest := fmt.Sprintf("%s:%d", ep.IPAddress, port.ContainerPort)
+	hostPort := strconv.Itoa(port.HostPort)
+	containerPort := strconv.Itoa(port.ContainerPort)
+	comment := []string{"-m", "comment", "--comment", "container:" + ep.ContainerID}
+
+	rules := []IPTablesRule{
+		// Traffic arriving from other hosts
+		{Table: "nat", Chain: "PREROUTING", Spec: append([]string{"-p", port.Protocol, "--dport", hostPort, "-j", "DNAT", "--to-destination", dest}, comment...)},
+		// Traffic originating on the host itself (e.g. curl localhost:hostPort)
+		{Table: "nat", Chain: "OUTPUT", Spec: append([]string{"-p", port.Protocol, "-m", "addrtype", "--dst-type", "LOCAL", "--dport", hostPort, "-j", "DNAT", "--to-destination", dest}, comment...)},
+		// Localhost sources must be rewritten, otherwise replies never return
+		{Table: "nat", Chain: "POSTROUTING", Spec: append([]string{"-p", port.Protocol, "-s", "127.0.0.0/8", "-d", ep.IPAddress, "--dport", containerPort, "-j", "MASQUERADE"}, comment...)},
+		// Allow forwarded traffic even when the default FORWARD policy is DROP
+		{Table: "filter", Chain: "FORWARD", Spec: append([]string{"-p", port.Protocol, "-d", ep.IPAddress, "--dport", containerPort, "-j", "ACCEPT"}, comment...)},
+	}
+	return rules
+}
+
+// allocateIP assigns a free address from the subnet and records it in the data dir
+func (b *BridgeManager) allocateIP(containerID string) (net.IP, error) {
+	allocations, err := b.loadAllocations()
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	for _, ip := range allocations {
+		used[ip] = true
+	}
+
+	base := b.subnet.IP.To4()
+	for host := 2; host < 255; host++ {
+		candidate := net.IPv4(base[0], base[1], base[2], byte(host)).To4()
+		if used[candidate.String()] {
+			continue
+		}
+		allocations[containerID] = candidate.String()
+		if err := b.saveAllocations(allocations); err != nil {
+			return nil, err
+		}
+		return candidate, nil
+	}
+
+	return nil, fmt.Errorf("no free addresses left in %s", b.subnet)
+}
+
+// releaseIP frees the address assigned to a container
+func (b *BridgeManager) releaseIP(containerID string) error {
+	allocations, err := b.loadAllocations()
+	if err != nil {
+		return err
+	}
+	if _, ok := allocations[containerID]; !ok {
+		return nil
+	}
+	delete(allocations, containerID)
+	return b.saveAllocations(allocations)
+}
+
+// allocationsPath returns the path of the IPAM state file
+func (b *BridgeManager) allocationsPath() string {
+	return filepath.Join(b.dataDir, "network", "ipam.json")
+}
+
+// loadAllocations reads containerID -> IP allocations from disk
+func (b *BridgeManager) loadAllocations() (map[string]string, error) {
+	allocations := make(map[string]string)
+
+	data, err := os.ReadFile(b.allocationsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return allocations, nil
+		}
+		return nil, fmt.Errorf("failed to read IP allocations: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &allocations); err != nil {
+		return nil, fmt.Errorf("failed to parse IP allocations: %w", err)
+	}
+	return allocations, nil
+}
+
+// saveAllocations writes containerID -> IP allocations to disk
+func (b *BridgeManager) saveAllocations(allocations map[string]string) error {
+	path := b.allocationsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create network directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(allocations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal IP allocations: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write IP allocations: %w", err)
+	}
+	return nil
+}
+
+// ensureRule appends an iptables rule unless it already exists
+func ensureRule(rule IPTablesRule) error {
+	if err := exec.Command("iptables", rule.args("-C")...).Run(); err == nil {
+		return nil
+	}
+	return run("iptables", rule.args("-A")...)
+}
+
+// linkExists checks whether a network interface exists on the host
+func linkExists(name string) bool {
+	return exec.Command("ip", "link", "show", name).Run() == nil
+}
+
+// netnsExists checks whether a named network namespace exists
+func netnsExists(name string) bool {
+	_, err := os.Stat(filepath.Join("/var/run/netns", name))
+	return err == nil
+}
+
+// shortID derives a stable 8 character identifier usable in interface names,
+// which are limited to 15 characters by the kernel
+func shortID(containerID string) string {
+	sum := sha256.Sum256([]byte(containerID))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// run executes a command and includes its output in the returned error
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %w (%s)", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
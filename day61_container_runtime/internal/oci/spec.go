@@ -0,0 +1,193 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Version is the OCI runtime specification version the generated spec follows
+const Version = "1.0.2"
+
+// Spec is the subset of the OCI runtime specification (config.json) used by this runtime
+type Spec struct {
+	OCIVersion string  `json:"ociVersion"`
+	Process    Process `json:"process"`
+	Root       Root    `json:"root"`
+	Hostname   string  `json:"hostname,omitempty"`
+	Mounts     []Mount `json:"mounts,omitempty"`
+	Linux      *Linux  `json:"linux,omitempty"`
+}
+
+// Process describes the container process
+type Process struct {
+	Terminal        bool          `json:"terminal,omitempty"`
+	User            User          `json:"user"`
+	Args            []string      `json:"args"`
+	Env             []string      `json:"env,omitempty"`
+	Cwd             string        `json:"cwd"`
+	Capabilities    *Capabilities `json:"capabilities,omitempty"`
+	Rlimits         []Rlimit      `json:"rlimits,omitempty"`
+	NoNewPrivileges bool          `json:"noNewPrivileges,omitempty"`
+}
+
+// User specifies the user the process runs as
+type User struct {
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+}
+
+// Capabilities lists the Linux capabilities of the process
+type Capabilities struct {
+	Bounding  []string `json:"bounding,omitempty"`
+	Effective []string `json:"effective,omitempty"`
+	Permitted []string `json:"permitted,omitempty"`
+}
+
+// Rlimit is a POSIX resource limit
+type Rlimit struct {
+	Type string `json:"type"`
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
+// Root is the container root filesystem, relative to the bundle directory
+type Root struct {
+	Path     string `json:"path"`
+	Readonly bool   `json:"readonly,omitempty"`
+}
+
+// Mount is an additional filesystem mounted in the container
+type Mount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// Linux holds Linux specific configuration
+type Linux struct {
+	Namespaces    []Namespace `json:"namespaces,omitempty"`
+	MaskedPaths   []string    `json:"maskedPaths,omitempty"`
+	ReadonlyPaths []string    `json:"readonlyPaths,omitempty"`
+}
+
+// Namespace is a Linux namespace; an empty Path creates a new namespace
+type Namespace struct {
+	Type string `json:"type"`
+	Path string `json:"path,omitempty"`
+}
+
+// Options holds the container settings the spec is generated from
+type Options struct {
+	Args     []string
+	Env      []string
+	Cwd      string
+	Hostname string
+	Terminal bool
+	// NetNSPath joins an existing network namespace instead of creating one
+	NetNSPath string
+}
+
+// defaultCapabilities matches the capability set granted by Docker and runc's default spec
+var defaultCapabilities = []string{
+	"CAP_CHOWN",
+	"CAP_DAC_OVERRIDE",
+	"CAP_FSETID",
+	"CAP_FOWNER",
+	"CAP_MKNOD",
+	"CAP_NET_RAW",
+	"CAP_SETGID",
+	"CAP_SETUID",
+	"CAP_SETFCAP",
+	"CAP_SETPCAP",
+	"CAP_NET_BIND_SERVICE",
+	"CAP_SYS_CHROOT",
+	"CAP_KILL",
+	"CAP_AUDIT_WRITE",
+}
+
+// Generate builds a runtime spec for a container whose rootfs lives in the
+// "rootfs" directory of the bundle
+func Generate(opts *Options) *Spec {
+	cwd := opts.Cwd
+	if cwd == "" {
+		cwd = "/"
+	}
+
+	namespaces := []Namespace{
+		{Type: "pid"},
+		{Type: "ipc"},
+		{Type: "uts"},
+		{Type: "mount"},
+		{Type: "network", Path: opts.NetNSPath},
+	}
+
+	return &Spec{
+		OCIVersion: Version,
+		Process: Process{
+			Terminal: opts.Terminal,
+			User:     User{UID: 0, GID: 0},
+			Args:     opts.Args,
+			Env:      opts.Env,
+			Cwd:      cwd,
+			Capabilities: &Capabilities{
+				Bounding:  defaultCapabilities,
+				Effective: defaultCapabilities,
+				Permitted: defaultCapabilities,
+			},
+			Rlimits: []Rlimit{
+				{Type: "RLIMIT_NOFILE", Hard: 1024, Soft: 1024},
+			},
+			NoNewPrivileges: true,
+		},
+		Root: Root{
+			Path: "rootfs",
+		},
+		Hostname: opts.Hostname,
+		Mounts: []Mount{
+			{Destination: "/proc", Type: "proc", Source: "proc"},
+			{Destination: "/dev", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"}},
+			{Destination: "/dev/pts", Type: "devpts", Source: "devpts", Options: []string{"nosuid", "noexec", "newinstance", "ptmxmode=0666", "mode=0620", "gid=5"}},
+			{Destination: "/dev/shm", Type: "tmpfs", Source: "shm", Options: []string{"nosuid", "noexec", "nodev", "mode=1777", "size=65536k"}},
+			{Destination: "/dev/mqueue", Type: "mqueue", Source: "mqueue", Options: []string{"nosuid", "noexec", "nodev"}},
+			{Destination: "/sys", Type: "sysfs", Source: "sysfs", Options: []string{"nosuid", "noexec", "nodev", "ro"}},
+		},
+		Linux: &Linux{
+			Namespaces: namespaces,
+			MaskedPaths: []string{
+				"/proc/acpi",
+				"/proc/kcore",
+				"/proc/keys",
+				"/proc/latency_stats",
+				"/proc/timer_list",
+				"/proc/timer_stats",
+				"/proc/sched_debug",
+				"/proc/scsi",
+				"/sys/firmware",
+			},
+			ReadonlyPaths: []string{
+				"/proc/asound",
+				"/proc/bus",
+				"/proc/fs",
+				"/proc/irq",
+				"/proc/sys",
+				"/proc/sysrq-trigger",
+			},
+		},
+	}
+}
+
+// Write stores the spec as config.json in the bundle directory
+func (s *Spec) Write(bundleDir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal runtime spec: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(bundleDir, "config.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write runtime spec: %w", err)
+	}
+	return nil
+}
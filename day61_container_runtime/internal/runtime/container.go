@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/image"
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/network"
 	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/storage"
 )
 
@@ -35,19 +37,37 @@ func NewContainerRuntime(dataDir string) *ContainerRuntime {
 
 // Container represents a running container
 type Container struct {
-	ID          string
-	ImageName   string
-	ImageTag    string
-	Command     []string
-	WorkDir     string
-	Environment []string
-	RootFS      string
-	Status      ContainerStatus
-	CreatedAt   time.Time
-	StartedAt   *time.Time
-	FinishedAt  *time.Time
-	ExitCode    *int
-	Process     *os.Process
+	ID            string                `json:"id"`
+	Name          string                `json:"name,omitempty"`
+	ImageName     string                `json:"imageName"`
+	ImageTag      string                `json:"imageTag"`
+	Command       []string              `json:"command"`
+	WorkDir       string                `json:"workDir"`
+	Environment   []string              `json:"environment"`
+	RootFS        string                `json:"rootfs"`
+	StorageDriver string                `json:"storageDriver"`
+	Runtime       string                `json:"runtime"`
+	Status        ContainerStatus       `json:"status"`
+	CreatedAt     time.Time             `json:"createdAt"`
+	StartedAt     *time.Time            `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time            `json:"finishedAt,omitempty"`
+	ExitCode      *int                  `json:"exitCode,omitempty"`
+	Pid           int                   `json:"pid,omitempty"`
+	LogPath       string                `json:"logPath"`
+	Ports         []network.PortMapping `json:"ports,omitempty"`
+	Process       *os.Process           `json:"-"`
+	Network       *network.Endpoint     `json:"network,omitempty"`
+
+	// stdout receives the container output (log file, optionally tee'd to the terminal)
+	stdout io.Writer
+}
+
+// output returns the writer container output should be written to
+func (c *Container) output() io.Writer {
+	if c.stdout != nil {
+		return c.stdout
+	}
+	return os.Stdout
 }
 
 // ContainerStatus represents container status
@@ -67,12 +87,45 @@ type RunOptions struct {
 	Interactive bool
 	TTY         bool
 	Name        string
+	Ports       []network.PortMapping
+	// StorageDriver selects how the rootfs is built: "overlay", "copy" or
+	// empty to use overlay when available and copy otherwise
+	StorageDriver string
+	// Runtime selects the executor: "native" (default) or "runc"
+	Runtime string
 }
 
-// RunContainer runs a container from an image
+// RunContainer runs a container from an image in the foreground
 func (r *ContainerRuntime) RunContainer(imageName, imageTag string, command []string, opts *RunOptions) (*Container, error) {
+	container, err := r.CreateContainer(imageName, imageTag, command, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.StartContainer(container, true); err != nil {
+		return container, err
+	}
+
+	return container, nil
+}
+
+// CreateContainer prepares a container (rootfs and state) without starting it
+func (r *ContainerRuntime) CreateContainer(imageName, imageTag string, command []string, opts *RunOptions) (*Container, error) {
 	fmt.Printf("🔧 Starting container execution for %s:%s\n", imageName, imageTag)
 
+	containerRuntime := opts.Runtime
+	if containerRuntime == "" {
+		containerRuntime = RuntimeNative
+	}
+	if containerRuntime != RuntimeNative && containerRuntime != RuntimeRunc {
+		return nil, fmt.Errorf("unknown runtime %q (expected native or runc)", opts.Runtime)
+	}
+	if containerRuntime == RuntimeRunc {
+		if _, err := exec.LookPath("runc"); err != nil {
+			return nil, fmt.Errorf("runtime runc requested but runc is not installed: %w", err)
+		}
+	}
+
 	// Load image from storage
 	localImage, err := r.storageManager.LoadImage(imageName, imageTag)
 	if err != nil {
@@ -83,6 +136,16 @@ func (r *ContainerRuntime) RunContainer(imageName, imageTag string, command []st
 		return nil, fmt.Errorf("image %s:%s not found locally", imageName, imageTag)
 	}
 
+	// Fall back to the image's default command
+	if len(command) == 0 {
+		if localImage.Config != nil && localImage.Config.Config != nil {
+			command = append(append([]string{}, localImage.Config.Config.Entrypoint...), localImage.Config.Config.Cmd...)
+		}
+		if len(command) == 0 {
+			return nil, fmt.Errorf("no command specified and image %s:%s has no default command", imageName, imageTag)
+		}
+	}
+
 	// Generate container ID
 	containerID, err := generateContainerID()
 	if err != nil {
@@ -90,6 +153,9 @@ func (r *ContainerRuntime) RunContainer(imageName, imageTag string, command []st
 	}
 
 	if opts.Name != "" {
+		if existing, _ := r.LoadContainer(opts.Name); existing != nil {
+			return nil, fmt.Errorf("container name %q is already in use", opts.Name)
+		}
 		containerID = opts.Name
 	}
 
@@ -98,6 +164,7 @@ func (r *ContainerRuntime) RunContainer(imageName, imageTag string, command []st
 	// Create container
 	container := &Container{
 		ID:          containerID,
+		Name:        opts.Name,
 		ImageName:   imageName,
 		ImageTag:    imageTag,
 		Command:     command,
@@ -105,16 +172,20 @@ func (r *ContainerRuntime) RunContainer(imageName, imageTag string, command []st
 		Environment: opts.Environment,
 		Status:      StatusCreated,
 		CreatedAt:   time.Now(),
+		LogPath:     filepath.Join(r.containerDir(containerID), "container.log"),
+		Ports:       opts.Ports,
+		Runtime:     containerRuntime,
 	}
 
 	// Build rootfs
-	rootfsPath := filepath.Join(r.dataDir, "containers", containerID, "rootfs")
-	fmt.Printf("📁 Building rootfs at: %s\n", rootfsPath)
-	if err := r.layerExtractor.BuildRootFS(imageName, imageTag, localImage.Layers, rootfsPath); err != nil {
-		return nil, fmt.Errorf("failed to build rootfs: %w", err)
+	rootfsPath := filepath.Join(r.containerDir(containerID), "rootfs")
+	driver, err := r.prepareRootFS(imageName, imageTag, localImage.Layers, containerID, rootfsPath, opts.StorageDriver)
+	if err != nil {
+		return nil, err
 	}
 	container.RootFS = rootfsPath
-	fmt.Printf("✅ Rootfs built successfully\n")
+	container.StorageDriver = driver
+	fmt.Printf("✅ Rootfs built successfully (%s)\n", driver)
 
 	// Set default working directory if not specified
 	if container.WorkDir == "" {
@@ -130,14 +201,183 @@ func (r *ContainerRuntime) RunContainer(imageName, imageTag string, command []st
 		container.Environment = localImage.Config.Config.Env
 	}
 
+	// Generate the OCI runtime spec from the image config and run options
+	if err := r.writeSpec(container, opts.TTY); err != nil {
+		return nil, err
+	}
+
+	if err := r.SaveContainer(container); err != nil {
+		return nil, err
+	}
+
+	return container, nil
+}
+
+// prepareRootFS builds the container rootfs with the requested storage driver.
+// Overlay mounts the shared extracted layers; when overlayfs is unavailable
+// (or the mount fails) and no driver was requested, it falls back to copying.
+func (r *ContainerRuntime) prepareRootFS(imageName, imageTag string, layers []string, containerID, rootfsPath, driver string) (string, error) {
+	switch driver {
+	case "", image.DriverOverlay:
+		if r.layerExtractor.OverlaySupported() {
+			fmt.Printf("📁 Mounting overlay rootfs at: %s\n", rootfsPath)
+			err := r.layerExtractor.MountOverlay(layers, r.containerDir(containerID), rootfsPath)
+			if err == nil {
+				return image.DriverOverlay, nil
+			}
+			if driver == image.DriverOverlay {
+				return "", err
+			}
+			fmt.Printf("⚠️  %v, falling back to copy mode\n", err)
+		} else if driver == image.DriverOverlay {
+			return "", fmt.Errorf("overlayfs is not available on this host (requires Linux and root)")
+		}
+	case image.DriverCopy:
+	default:
+		return "", fmt.Errorf("unknown storage driver %q (expected overlay or copy)", driver)
+	}
+
+	fmt.Printf("📁 Building rootfs at: %s\n", rootfsPath)
+	if err := r.layerExtractor.BuildRootFS(imageName, imageTag, layers, rootfsPath); err != nil {
+		return "", fmt.Errorf("failed to build rootfs: %w", err)
+	}
+	return image.DriverCopy, nil
+}
+
+// StartContainer executes a created container and waits for it to finish.
+// Output is always captured to the container log file; when attach is true
+// it is also written to the terminal.
+func (r *ContainerRuntime) StartContainer(container *Container, attach bool) error {
+	logFile, err := os.OpenFile(container.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logFile.Close()
+
+	container.stdout = logFile
+	if attach {
+		container.stdout = io.MultiWriter(os.Stdout, logFile)
+	}
+
+	// Set up bridge networking for published ports
+	if len(container.Ports) > 0 {
+		if err := r.setupNetwork(container, container.Ports); err != nil {
+			container.Status = StatusFailed
+			r.SaveContainer(container)
+			return err
+		}
+		if container.Network != nil {
+			defer r.teardownNetwork(container)
+		}
+	}
+
 	// Execute container
-	fmt.Printf("⚡ Executing command: %v\n", command)
-	if err := r.executeContainer(container); err != nil {
+	fmt.Printf("⚡ Executing command: %v\n", container.Command)
+	var execErr error
+	if container.Runtime == RuntimeRunc {
+		execErr = r.executeRunc(container)
+	} else {
+		execErr = r.executeContainer(container)
+	}
+	if execErr != nil && container.ExitCode == nil {
 		container.Status = StatusFailed
-		return container, fmt.Errorf("failed to execute container: %w", err)
 	}
 
-	return container, nil
+	// Simulated commands do not report an exit status of their own
+	if container.Status == StatusRunning {
+		finishedAt := time.Now()
+		exitCode := 0
+		container.Status = StatusExited
+		container.FinishedAt = &finishedAt
+		container.ExitCode = &exitCode
+	}
+
+	// Persist final state
+	container.Pid = 0
+	if err := r.SaveContainer(container); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	if execErr != nil {
+		return fmt.Errorf("failed to execute container: %w", execErr)
+	}
+	return nil
+}
+
+// ExecBuildStep runs a command in an intermediate container rooted at rootfs,
+// as used by RUN instructions during an image build. The command is run under
+// chroot when possible (Linux as root) and simulated otherwise so that a build
+// never modifies the host filesystem.
+func (r *ContainerRuntime) ExecBuildStep(rootfs string, command, env []string, workDir string, out io.Writer) error {
+	buildID, err := generateContainerID()
+	if err != nil {
+		return fmt.Errorf("failed to generate container ID: %w", err)
+	}
+
+	container := &Container{
+		ID:          "build-" + buildID,
+		Command:     command,
+		WorkDir:     workDir,
+		Environment: env,
+		RootFS:      rootfs,
+		Status:      StatusRunning,
+		CreatedAt:   time.Now(),
+		stdout:      out,
+	}
+
+	if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+		fmt.Printf("⚠️  chroot unavailable, simulating build step\n")
+		if filepath.Base(command[0]) == "sh" || filepath.Base(command[0]) == "bash" {
+			return r.executeShellCommand(container)
+		}
+		return r.simulateCommand(container)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = env
+	cmd.Dir = workDir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.SysProcAttr = &syscall.SysProcAttr{Chroot: rootfs}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command %v failed: %w", command, err)
+	}
+	return nil
+}
+
+// setupNetwork attaches the container to the bridge network and publishes ports
+func (r *ContainerRuntime) setupNetwork(container *Container, ports []network.PortMapping) error {
+	// Network namespaces, veth and iptables are only available on Linux
+	if runtime.GOOS != "linux" {
+		fmt.Printf("⚠️  Port publishing requires Linux, ignoring %d port mapping(s)\n", len(ports))
+		return nil
+	}
+
+	fmt.Printf("🌐 Setting up bridge network (%s)...\n", network.DefaultBridgeName)
+	endpoint, err := network.NewBridgeManager(r.dataDir).Setup(container.ID, ports)
+	if err != nil {
+		return fmt.Errorf("failed to set up network: %w", err)
+	}
+	container.Network = endpoint
+
+	for _, port := range ports {
+		fmt.Printf("🔌 Publishing 0.0.0.0:%d -> %s:%d/%s\n", port.HostPort, endpoint.IPAddress, port.ContainerPort, port.Protocol)
+	}
+	return nil
+}
+
+// teardownNetwork removes the bridge network resources of the container
+func (r *ContainerRuntime) teardownNetwork(container *Container) {
+	if err := network.NewBridgeManager(r.dataDir).Teardown(container.Network); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+		return
+	}
+	// Persist the removed rules so that rm does not try to delete them again
+	if err := r.SaveContainer(container); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	fmt.Printf("🧹 Network resources for %s cleaned up\n", container.ID)
 }
 
 // executeContainer executes the container process using actual binary execution
@@ -145,6 +385,9 @@ func (r *ContainerRuntime) executeContainer(container *Container) error {
 	container.Status = StatusRunning
 	now := time.Now()
 	container.StartedAt = &now
+	if err := r.SaveContainer(container); err != nil {
+		return err
+	}
 
 	// Find the actual binary in the container rootfs
 	binaryPath, err := r.findBinaryInContainer(container, container.Command[0])
@@ -249,11 +492,12 @@ func (r *ContainerRuntime) isLinuxBinary(path string) bool {
 
 // executeShellCommand handles shell execution
 func (r *ContainerRuntime) executeShellCommand(container *Container) error {
+	w := container.output()
 	fmt.Printf("🐚 Executing shell command in container context\n")
 
 	// If command is just 'sh' or 'bash', simulate interactive shell
 	if len(container.Command) == 1 {
-		fmt.Println("/ # (simulated container shell)")
+		fmt.Fprintln(w, "/ # (simulated container shell)")
 		return nil
 	}
 
@@ -271,11 +515,12 @@ func (r *ContainerRuntime) executeShellCommand(container *Container) error {
 
 // executeBusyboxCommand handles busybox multi-call binary
 func (r *ContainerRuntime) executeBusyboxCommand(container *Container) error {
+	w := container.output()
 	fmt.Printf("📦 Executing busybox command\n")
 
 	if len(container.Command) < 2 {
 		// Just busybox without subcommand
-		fmt.Println("BusyBox v1.36.1 (simulated)")
+		fmt.Fprintln(w, "BusyBox v1.36.1 (simulated)")
 		return nil
 	}
 
@@ -323,8 +568,11 @@ func (r *ContainerRuntime) executeShellScript(container *Container, script strin
 func (r *ContainerRuntime) executeActualBinary(container *Container, binaryPath string) error {
 	fmt.Printf("🏃 Attempting direct binary execution: %s\n", binaryPath)
 
-	// Prepare the command
+	// Prepare the command, inside the container network namespace if any
 	cmd := exec.Command(binaryPath, container.Command[1:]...)
+	if container.Network != nil {
+		cmd = container.Network.Command(binaryPath, container.Command[1:]...)
+	}
 
 	// Set environment variables
 	cmd.Env = container.Environment
@@ -339,11 +587,20 @@ func (r *ContainerRuntime) executeActualBinary(container *Container, binaryPath
 
 	// Set up stdio
 	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = container.output()
+	cmd.Stderr = container.output()
 
-	// Execute the command
-	err := cmd.Run()
+	// Start the process and record its PID so that it can be stopped
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	container.Process = cmd.Process
+	container.Pid = cmd.Process.Pid
+	if err := r.SaveContainer(container); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	err := cmd.Wait()
 
 	// Update container status after execution
 	finishedAt := time.Now()
@@ -369,6 +626,7 @@ func (r *ContainerRuntime) executeActualBinary(container *Container, binaryPath
 
 // simulateCommand provides realistic command simulation
 func (r *ContainerRuntime) simulateCommand(container *Container) error {
+	w := container.output()
 	fmt.Printf("🎭 Simulating command execution: %s\n", strings.Join(container.Command, " "))
 
 	command := container.Command[0]
@@ -386,13 +644,13 @@ func (r *ContainerRuntime) simulateCommand(container *Container) error {
 	switch command {
 	case "echo":
 		if len(args) > 0 {
-			fmt.Println(strings.Join(args, " "))
+			fmt.Fprintln(w, strings.Join(args, " "))
 		} else {
-			fmt.Println()
+			fmt.Fprintln(w)
 		}
 
 	case "pwd":
-		fmt.Println(container.WorkDir)
+		fmt.Fprintln(w, container.WorkDir)
 
 	case "ls":
 		return r.simulateLs(container, args)
@@ -401,7 +659,7 @@ func (r *ContainerRuntime) simulateCommand(container *Container) error {
 		return r.simulateCat(container, args)
 
 	case "whoami":
-		fmt.Println("root")
+		fmt.Fprintln(w, "root")
 
 	case "env", "printenv":
 		r.simulateEnv(container, args)
@@ -410,7 +668,7 @@ func (r *ContainerRuntime) simulateCommand(container *Container) error {
 		return r.simulatePython(container, args)
 
 	case "uname":
-		r.simulateUname(args)
+		r.simulateUname(w, args)
 
 	case "which":
 		r.simulateWhich(container, args)
@@ -421,9 +679,9 @@ func (r *ContainerRuntime) simulateCommand(container *Container) error {
 	default:
 		// For unknown commands, try to provide helpful simulation
 		if len(args) > 0 {
-			fmt.Printf("[SIMULATED] %s %s\n", command, strings.Join(args, " "))
+			fmt.Fprintf(w, "[SIMULATED] %s %s\n", command, strings.Join(args, " "))
 		} else {
-			fmt.Printf("[SIMULATED] %s\n", command)
+			fmt.Fprintf(w, "[SIMULATED] %s\n", command)
 		}
 	}
 
@@ -432,6 +690,7 @@ func (r *ContainerRuntime) simulateCommand(container *Container) error {
 
 // simulateLs simulates ls command with different options
 func (r *ContainerRuntime) simulateLs(container *Container, args []string) error {
+	w := container.output()
 	var targetPath string = container.RootFS
 	var showAll bool = false
 	var longFormat bool = false
@@ -457,7 +716,7 @@ func (r *ContainerRuntime) simulateLs(container *Container, args []string) error
 
 	entries, err := os.ReadDir(targetPath)
 	if err != nil {
-		fmt.Printf("ls: cannot access '%s': No such file or directory\n", targetPath)
+		fmt.Fprintf(w, "ls: cannot access '%s': No such file or directory\n", targetPath)
 		return nil
 	}
 
@@ -471,22 +730,23 @@ func (r *ContainerRuntime) simulateLs(container *Container, args []string) error
 			mode := info.Mode()
 			size := info.Size()
 			modTime := info.ModTime().Format("Jan 2 15:04")
-			fmt.Printf("%s %8d %s %s\n", mode.String(), size, modTime, entry.Name())
+			fmt.Fprintf(w, "%s %8d %s %s\n", mode.String(), size, modTime, entry.Name())
 		} else {
-			fmt.Print(entry.Name() + "  ")
+			fmt.Fprint(w, entry.Name()+"  ")
 		}
 	}
 
 	if !longFormat {
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 	return nil
 }
 
 // simulateCat simulates cat command
 func (r *ContainerRuntime) simulateCat(container *Container, args []string) error {
+	w := container.output()
 	if len(args) == 0 {
-		fmt.Println("cat: missing file operand")
+		fmt.Fprintln(w, "cat: missing file operand")
 		return nil
 	}
 
@@ -500,28 +760,29 @@ func (r *ContainerRuntime) simulateCat(container *Container, args []string) erro
 
 		content, err := os.ReadFile(filePath)
 		if err != nil {
-			fmt.Printf("cat: %s: No such file or directory\n", arg)
+			fmt.Fprintf(w, "cat: %s: No such file or directory\n", arg)
 			continue
 		}
 
-		fmt.Print(string(content))
+		fmt.Fprint(w, string(content))
 	}
 	return nil
 }
 
 // simulateEnv simulates env/printenv command
 func (r *ContainerRuntime) simulateEnv(container *Container, args []string) {
+	w := container.output()
 	if len(args) == 0 {
 		// Print all environment variables
 		for _, env := range container.Environment {
-			fmt.Println(env)
+			fmt.Fprintln(w, env)
 		}
 	} else {
 		// Print specific environment variable
 		varName := args[0]
 		for _, env := range container.Environment {
 			if strings.HasPrefix(env, varName+"=") {
-				fmt.Println(strings.TrimPrefix(env, varName+"="))
+				fmt.Fprintln(w, strings.TrimPrefix(env, varName+"="))
 				return
 			}
 		}
@@ -530,9 +791,10 @@ func (r *ContainerRuntime) simulateEnv(container *Container, args []string) {
 
 // simulatePython simulates python command execution
 func (r *ContainerRuntime) simulatePython(container *Container, args []string) error {
+	w := container.output()
 	if len(args) == 0 {
-		fmt.Println("Python 3.13.4 (simulated)")
-		fmt.Println(">>> ")
+		fmt.Fprintln(w, "Python 3.13.4 (simulated)")
+		fmt.Fprintln(w, ">>> ")
 		return nil
 	}
 
@@ -540,7 +802,7 @@ func (r *ContainerRuntime) simulatePython(container *Container, args []string) e
 	if len(args) >= 2 && args[0] == "-c" {
 		code := args[1]
 		scriptArgs := args[2:] // sys.argv[1:] in the script
-		return r.executePythonCodeWithArgs(code, scriptArgs)
+		return r.executePythonCodeWithArgs(w, code, scriptArgs)
 	}
 
 	// Handle script file execution
@@ -556,15 +818,15 @@ func (r *ContainerRuntime) simulatePython(container *Container, args []string) e
 
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		fmt.Printf("python: can't open file '%s': [Errno 2] No such file or directory\n", scriptPath)
+		fmt.Fprintf(w, "python: can't open file '%s': [Errno 2] No such file or directory\n", scriptPath)
 		return nil
 	}
 
-	return r.executePythonCodeWithArgs(string(content), scriptArgs)
+	return r.executePythonCodeWithArgs(w, string(content), scriptArgs)
 }
 
 // executePythonCodeWithArgs simulates Python code execution with sys.argv
-func (r *ContainerRuntime) executePythonCodeWithArgs(code string, args []string) error {
+func (r *ContainerRuntime) executePythonCodeWithArgs(w io.Writer, code string, args []string) error {
 	code = strings.TrimSpace(code)
 
 	// Handle sys.argv related code
@@ -577,14 +839,14 @@ func (r *ContainerRuntime) executePythonCodeWithArgs(code string, args []string)
 					sum += num
 				}
 			}
-			fmt.Printf("Arguments: %v\n", args)
-			fmt.Printf("Sum: %d\n", sum)
+			fmt.Fprintf(w, "Arguments: %v\n", args)
+			fmt.Fprintf(w, "Sum: %d\n", sum)
 			return nil
 		}
 
 		// Print arguments if that's what's requested
 		if strings.Contains(code, "print('Arguments:', sys.argv[1:])") || strings.Contains(code, `print("Arguments:", sys.argv[1:])`) {
-			fmt.Printf("Arguments: %v\n", args)
+			fmt.Fprintf(w, "Arguments: %v\n", args)
 		}
 	}
 
@@ -593,14 +855,14 @@ func (r *ContainerRuntime) executePythonCodeWithArgs(code string, args []string)
 		content := strings.TrimPrefix(code, "print(")
 		content = strings.TrimSuffix(content, ")")
 		content = strings.Trim(content, "\"'")
-		fmt.Println(content)
+		fmt.Fprintln(w, content)
 		return nil
 	}
 
 	// Default Python simulation
-	fmt.Printf("[PYTHON] %s\n", code)
+	fmt.Fprintf(w, "[PYTHON] %s\n", code)
 	if len(args) > 0 {
-		fmt.Printf("[ARGS] %v\n", args)
+		fmt.Fprintf(w, "[ARGS] %v\n", args)
 	}
 
 	return nil
@@ -608,7 +870,7 @@ func (r *ContainerRuntime) executePythonCodeWithArgs(code string, args []string)
 
 // executePythonCode simulates basic Python code execution (backward compatibility)
 func (r *ContainerRuntime) executePythonCode(code string) error {
-	return r.executePythonCodeWithArgs(code, []string{})
+	return r.executePythonCodeWithArgs(os.Stdout, code, []string{})
 }
 
 // parseNumber safely parses a string to integer
@@ -619,16 +881,17 @@ func parseNumber(s string) int {
 }
 
 // simulateUname simulates uname command
-func (r *ContainerRuntime) simulateUname(args []string) {
+func (r *ContainerRuntime) simulateUname(w io.Writer, args []string) {
 	if len(args) == 0 || (len(args) == 1 && args[0] == "-s") {
-		fmt.Println("Linux")
+		fmt.Fprintln(w, "Linux")
 	} else if len(args) == 1 && args[0] == "-a" {
-		fmt.Println("Linux container 5.4.0 #1 SMP Mon Oct 1 12:00:00 UTC 2024 x86_64 GNU/Linux")
+		fmt.Fprintln(w, "Linux container 5.4.0 #1 SMP Mon Oct 1 12:00:00 UTC 2024 x86_64 GNU/Linux")
 	}
 }
 
 // simulateWhich simulates which command
 func (r *ContainerRuntime) simulateWhich(container *Container, args []string) {
+	w := container.output()
 	if len(args) == 0 {
 		return
 	}
@@ -647,7 +910,7 @@ func (r *ContainerRuntime) simulateWhich(container *Container, args []string) {
 		if _, err := os.Stat(candidatePath); err == nil {
 			// Return path relative to container root
 			relPath := strings.TrimPrefix(candidatePath, container.RootFS)
-			fmt.Println(relPath)
+			fmt.Fprintln(w, relPath)
 			return
 		}
 	}
@@ -655,8 +918,9 @@ func (r *ContainerRuntime) simulateWhich(container *Container, args []string) {
 
 // simulateHeadTail simulates head/tail commands
 func (r *ContainerRuntime) simulateHeadTail(container *Container, command string, args []string) error {
+	w := container.output()
 	if len(args) == 0 {
-		fmt.Printf("%s: missing file operand\n", command)
+		fmt.Fprintf(w, "%s: missing file operand\n", command)
 		return nil
 	}
 
@@ -670,7 +934,7 @@ func (r *ContainerRuntime) simulateHeadTail(container *Container, command string
 
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		fmt.Printf("%s: cannot open '%s' for reading: No such file or directory\n", command, filename)
+		fmt.Fprintf(w, "%s: cannot open '%s' for reading: No such file or directory\n", command, filename)
 		return nil
 	}
 
@@ -680,7 +944,7 @@ func (r *ContainerRuntime) simulateHeadTail(container *Container, command string
 			if i >= 10 { // Default head shows 10 lines
 				break
 			}
-			fmt.Println(line)
+			fmt.Fprintln(w, line)
 		}
 	} else { // tail
 		start := len(lines) - 10
@@ -688,7 +952,7 @@ func (r *ContainerRuntime) simulateHeadTail(container *Container, command string
 			start = 0
 		}
 		for i := start; i < len(lines); i++ {
-			fmt.Println(lines[i])
+			fmt.Fprintln(w, lines[i])
 		}
 	}
 
@@ -717,15 +981,18 @@ func (r *ContainerRuntime) simulateHeadTail(container *Container, command string
 	return nil
 }
 
-// CleanupContainer removes container files
+// CleanupContainer removes container files, unmounting an overlay rootfs first
 func (r *ContainerRuntime) CleanupContainer(containerID string) error {
-	containerPath := filepath.Join(r.dataDir, "containers", containerID)
-	return os.RemoveAll(containerPath)
+	rootfsPath := filepath.Join(r.containerDir(containerID), "rootfs")
+	if err := r.layerExtractor.UnmountOverlay(rootfsPath); err != nil {
+		return err
+	}
+	return os.RemoveAll(r.containerDir(containerID))
 }
 
 // ListRootFSContents lists the contents of a container's rootfs
 func (r *ContainerRuntime) ListRootFSContents(containerID string) ([]string, error) {
-	rootfsPath := filepath.Join(r.dataDir, "containers", containerID, "rootfs")
+	rootfsPath := filepath.Join(r.containerDir(containerID), "rootfs")
 	return r.layerExtractor.ListRootFSContents(rootfsPath)
 }
 
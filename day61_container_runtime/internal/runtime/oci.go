@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/oci"
+)
+
+// Runtimes that can execute a container
+const (
+	// RuntimeNative executes containers with this project's own runtime
+	RuntimeNative = "native"
+	// RuntimeRunc delegates execution of the OCI bundle to an installed runc
+	RuntimeRunc = "runc"
+)
+
+// SpecPath returns the path of a container's OCI runtime spec (config.json)
+func (r *ContainerRuntime) SpecPath(containerID string) string {
+	return filepath.Join(r.containerDir(containerID), "config.json")
+}
+
+// writeSpec generates the OCI runtime spec for a container. The container
+// directory doubles as the OCI bundle, with the rootfs in its "rootfs" directory.
+func (r *ContainerRuntime) writeSpec(container *Container, terminal bool) error {
+	opts := &oci.Options{
+		Args:     container.Command,
+		Env:      container.Environment,
+		Cwd:      container.WorkDir,
+		Hostname: shortContainerID(container.ID),
+		Terminal: terminal,
+	}
+	if container.Network != nil {
+		opts.NetNSPath = filepath.Join("/var/run/netns", container.Network.NetNS)
+	}
+
+	return oci.Generate(opts).Write(r.containerDir(container.ID))
+}
+
+// executeRunc runs the container bundle with runc and waits for it to exit
+func (r *ContainerRuntime) executeRunc(container *Container) error {
+	runcPath, err := exec.LookPath("runc")
+	if err != nil {
+		return fmt.Errorf("runc not found in PATH: %w", err)
+	}
+
+	// Regenerate the spec so that it reflects the network namespace
+	if err := r.writeSpec(container, false); err != nil {
+		return err
+	}
+
+	bundleDir, err := filepath.Abs(r.containerDir(container.ID))
+	if err != nil {
+		return fmt.Errorf("failed to resolve bundle directory: %w", err)
+	}
+
+	container.Status = StatusRunning
+	now := time.Now()
+	container.StartedAt = &now
+
+	fmt.Printf("🏃 Delegating to runc: %s run --bundle %s %s\n", runcPath, bundleDir, container.ID)
+	cmd := exec.Command(runcPath, "run", "--bundle", bundleDir, container.ID)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = container.output()
+	cmd.Stderr = container.output()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	container.Process = cmd.Process
+	container.Pid = cmd.Process.Pid
+	if err := r.SaveContainer(container); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+
+	err = cmd.Wait()
+
+	finishedAt := time.Now()
+	container.FinishedAt = &finishedAt
+	exitCode := 0
+	if err != nil {
+		exitError, ok := err.(*exec.ExitError)
+		if !ok {
+			container.Status = StatusFailed
+			return err
+		}
+		if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
+			exitCode = status.ExitStatus()
+		}
+	}
+
+	// runc reports the container's exit status as its own
+	container.ExitCode = &exitCode
+	container.Status = StatusExited
+	return nil
+}
+
+// shortContainerID returns the first 12 characters of a container ID
+func shortContainerID(containerID string) string {
+	if len(containerID) > 12 {
+		return containerID[:12]
+	}
+	return containerID
+}
@@ -0,0 +1,211 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day61_container_runtime/internal/network"
+)
+
+// containerDir returns the directory holding a container's state, logs and rootfs
+func (r *ContainerRuntime) containerDir(containerID string) string {
+	return filepath.Join(r.dataDir, "containers", containerID)
+}
+
+// statePath returns the path of a container's state file
+func (r *ContainerRuntime) statePath(containerID string) string {
+	return filepath.Join(r.containerDir(containerID), "state.json")
+}
+
+// SaveContainer persists container state to the data directory
+func (r *ContainerRuntime) SaveContainer(container *Container) error {
+	if err := os.MkdirAll(r.containerDir(container.ID), 0755); err != nil {
+		return fmt.Errorf("failed to create container directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(container, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal container state: %w", err)
+	}
+
+	// Write atomically so that concurrent readers (ps, logs) never see a partial file
+	tmpPath := r.statePath(container.ID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write container state: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.statePath(container.ID)); err != nil {
+		return fmt.Errorf("failed to write container state: %w", err)
+	}
+
+	return nil
+}
+
+// LoadContainer loads container state from the data directory.
+// It returns nil without error if the container does not exist.
+func (r *ContainerRuntime) LoadContainer(containerID string) (*Container, error) {
+	data, err := os.ReadFile(r.statePath(containerID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // Container not found
+		}
+		return nil, fmt.Errorf("failed to read container state: %w", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal(data, &container); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal container state: %w", err)
+	}
+
+	r.refreshStatus(&container)
+	return &container, nil
+}
+
+// ListContainers lists all containers, newest first
+func (r *ContainerRuntime) ListContainers() ([]*Container, error) {
+	containersDir := filepath.Join(r.dataDir, "containers")
+
+	entries, err := os.ReadDir(containersDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Container{}, nil
+		}
+		return nil, fmt.Errorf("failed to read containers directory: %w", err)
+	}
+
+	var containers []*Container
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		container, err := r.LoadContainer(entry.Name())
+		if err != nil || container == nil {
+			continue // Skip directories without readable state
+		}
+		containers = append(containers, container)
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].CreatedAt.After(containers[j].CreatedAt)
+	})
+
+	return containers, nil
+}
+
+// StopContainer sends SIGTERM to the container process and SIGKILL if it is
+// still alive after the timeout
+func (r *ContainerRuntime) StopContainer(containerID string, timeout time.Duration) (*Container, error) {
+	container, err := r.LoadContainer(containerID)
+	if err != nil {
+		return nil, err
+	}
+	if container == nil {
+		return nil, fmt.Errorf("container %s not found", containerID)
+	}
+
+	if container.Status != StatusRunning {
+		return container, nil
+	}
+
+	if container.Pid > 0 {
+		if err := syscall.Kill(container.Pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+			return nil, fmt.Errorf("failed to send SIGTERM: %w", err)
+		}
+
+		deadline := time.Now().Add(timeout)
+		for processAlive(container.Pid) && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if processAlive(container.Pid) {
+			fmt.Printf("⏱️  Container %s did not stop within %s, sending SIGKILL\n", container.ID, timeout)
+			if err := syscall.Kill(container.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+				return nil, fmt.Errorf("failed to send SIGKILL: %w", err)
+			}
+		}
+	}
+
+	// The shim records the exit code once it observes the exit; give it a
+	// moment and otherwise mark the container as exited ourselves
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		latest, err := r.LoadContainer(containerID)
+		if err == nil && latest != nil && latest.Status != StatusRunning {
+			return latest, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	r.markExited(container)
+	return container, r.SaveContainer(container)
+}
+
+// RemoveContainer deletes a container's state, logs and rootfs
+func (r *ContainerRuntime) RemoveContainer(containerID string, force bool) error {
+	container, err := r.LoadContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return fmt.Errorf("container %s not found", containerID)
+	}
+
+	if container.Status == StatusRunning {
+		if !force {
+			return fmt.Errorf("container %s is running, stop it first or use --force", containerID)
+		}
+		if _, err := r.StopContainer(containerID, 0); err != nil {
+			return err
+		}
+	}
+
+	// The shim tears the network down when the container exits, but not if it
+	// was killed first; release stale port rules and the IP here as well
+	if container.Network != nil {
+		if err := network.NewBridgeManager(r.dataDir).Teardown(container.Network); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}
+
+	return r.CleanupContainer(containerID)
+}
+
+// refreshStatus marks a running container as exited when its process is gone
+// (e.g. the shim was killed before it could record the exit)
+func (r *ContainerRuntime) refreshStatus(container *Container) {
+	if container.Status != StatusRunning || container.Pid == 0 {
+		return
+	}
+	if processAlive(container.Pid) {
+		return
+	}
+
+	r.markExited(container)
+	r.SaveContainer(container)
+}
+
+// markExited sets the exited state for a container whose exit code is unknown
+func (r *ContainerRuntime) markExited(container *Container) {
+	finishedAt := time.Now()
+	container.Status = StatusExited
+	container.FinishedAt = &finishedAt
+	container.Pid = 0
+	if container.ExitCode == nil {
+		exitCode := 137 // Killed, as reported by Docker
+		container.ExitCode = &exitCode
+	}
+}
+
+// processAlive checks whether a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
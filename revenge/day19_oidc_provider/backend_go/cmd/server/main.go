@@ -56,6 +56,9 @@ func main() {
 	// Initialize handlers (passing dependencies)
 	oidcHandler := handler.NewOIDCHandler(cfg, dbStore, sessionMgr, tokenService) // Pass tokenService
 
+	// Initialize Auditor (records requests to the audit log)
+	auditor := authMiddleware.NewAuditor(dbStore, sessionMgr)
+
 	// Initialize router
 	r := chi.NewRouter()
 
@@ -82,9 +85,12 @@ func main() {
 	r.Get("/jwks", jwks.Handler) // Serve JWKS
 
 	// OIDC/OAuth2 endpoints
-	r.Get("/authorize", oidcHandler.Authorize) // Authorization endpoint (GET)
-	r.Post("/authorize", oidcHandler.AuthorizeDecision) // Handle user decision (login/consent) from Next.js forms
-	r.Post("/token", oidcHandler.Token) // Token endpoint
+	r.With(auditor.Audit("authorize")).Get("/authorize", oidcHandler.Authorize) // Authorization endpoint (GET)
+	r.With(auditor.Audit("authorize")).Post("/authorize", oidcHandler.AuthorizeDecision) // Handle user decision (login/consent) from Next.js forms
+	r.With(auditor.Audit("token")).Post("/token", oidcHandler.Token) // Token endpoint
+	r.With(auditor.Audit("revoke")).Post("/revoke", oidcHandler.Revoke) // Token revocation endpoint (RFC 7009)
+	r.With(auditor.Audit("introspect")).Post("/introspect", oidcHandler.Introspect) // Token introspection endpoint (RFC 7662)
+	r.With(auditor.Audit("device_authorization")).Post("/device_authorization", oidcHandler.DeviceAuthorization) // Device authorization endpoint (RFC 8628)
 
 	// Protected UserInfo endpoint
 	r.Route("/userinfo", func(r chi.Router) {
@@ -95,8 +101,23 @@ func main() {
 
 	// Interaction endpoints (called by Next.js frontend via proxy)
 	r.Get("/interaction/{interactionID}/details", oidcHandler.GetInteractionDetails) // Get details for frontend
-	r.Post("/interaction/login", oidcHandler.HandleLogin)    // Handle login form submission
-	r.Post("/interaction/consent", oidcHandler.HandleConsent) // Handle consent form submission
+	r.With(auditor.Audit("login")).Post("/interaction/login", oidcHandler.HandleLogin)    // Handle login form submission
+	r.With(auditor.Audit("consent")).Post("/interaction/consent", oidcHandler.HandleConsent) // Handle consent form submission
+	r.Get("/device/{userCode}", oidcHandler.GetDeviceVerification) // Get details for the device verification page
+	r.With(auditor.Audit("device_verification")).Post("/device/verify", oidcHandler.HandleDeviceVerification) // Handle approval/denial of a device code
+	r.Get("/grants", oidcHandler.ListGrants) // List the logged-in user's grants (remembered consents)
+	r.With(auditor.Audit("grant.revoke")).Delete("/grants/{clientID}", oidcHandler.RevokeGrant) // Revoke a grant and the client's refresh tokens
+	r.With(auditor.Audit("logout")).Get("/logout", oidcHandler.EndSession) // RP-Initiated Logout (end_session_endpoint)
+	r.With(auditor.Audit("logout")).Post("/logout", oidcHandler.EndSession)
+
+	// Admin API (requires ADMIN_API_KEY)
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(authMiddleware.RequireAdminAPIKey(cfg))
+		r.Get("/users/{userID}/sessions", oidcHandler.ListUserSessions) // List a user's active sessions
+		r.With(auditor.Audit("admin.user_sessions.delete")).Delete("/users/{userID}/sessions", oidcHandler.DeleteUserSessions) // Terminate all sessions of a user
+		r.With(auditor.Audit("admin.session.delete")).Delete("/sessions/{sessionID}", oidcHandler.DeleteSession) // Terminate a single session
+		r.Get("/audit-logs", oidcHandler.ListAuditLogs) // Query the audit log (from/to/event_type/actor/client_id/outcome/limit)
+	})
 
 	// Start server
 	serverAddr := fmt.Sprintf(":%s", cfg.Port)
@@ -149,6 +170,7 @@ func seedData(s store.Storer) {
 			SecretHash:   secretAHash,
 			RedirectURIs: redirectURIsA,
 			Name:         "Test Client A",
+			PostLogoutRedirectURIs: `["http://localhost:3002/"]`,
 			CreatedAt:    time.Now(),
 			UpdatedAt:    time.Now(),
 		}
@@ -175,6 +197,7 @@ func seedData(s store.Storer) {
 			SecretHash:   secretBHash,
 			RedirectURIs: redirectURIsB,
 			Name:         "Test Client B",
+			PostLogoutRedirectURIs: `["http://localhost:3003/"]`,
 			CreatedAt:    time.Now(),
 			UpdatedAt:    time.Now(),
 		}
@@ -14,7 +14,9 @@ CREATE TABLE IF NOT EXISTS clients (
   -- JSON 配列を TEXT で保存
   name TEXT NOT NULL,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  post_logout_redirect_uris TEXT NOT NULL DEFAULT '[]'
+  -- ログアウト後のリダイレクト先 (JSON 配列)
 );
 -- Authorization Codes テーブル (OAuth 2.0 Authorization Code Flow 用)
 CREATE TABLE IF NOT EXISTS authorization_codes (
@@ -50,12 +52,18 @@ CREATE TABLE IF NOT EXISTS sessions (
 CREATE TABLE IF NOT EXISTS refresh_tokens (
   token_hash TEXT PRIMARY KEY,
   -- ハッシュ化して保存
+  family_id TEXT NOT NULL DEFAULT '',
+  -- 同じ認可から発行されたトークンの系列 (ローテーションしても変わらない)
   client_id TEXT NOT NULL,
   user_id TEXT NOT NULL,
   scopes TEXT NOT NULL,
   expires_at TIMESTAMP NOT NULL,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   last_used_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  -- ローテーション・失効で無効になった日時
+  replaced_by TEXT,
+  -- ローテーション後のトークンのハッシュ (再利用の検知に使う)
   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
   FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
 );
@@ -91,6 +99,49 @@ CREATE TABLE IF NOT EXISTS grants (
   FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE,
   UNIQUE (user_id, client_id) -- ユーザーとクライアントの組み合わせはユニーク
 );
+-- Device Codes テーブル (OAuth 2.0 Device Authorization Grant / RFC 8628 用)
+CREATE TABLE IF NOT EXISTS device_codes (
+  device_code TEXT PRIMARY KEY,
+  user_code TEXT NOT NULL UNIQUE,
+  -- ユーザーが入力するコード (ハイフンなしの大文字で保存)
+  client_id TEXT NOT NULL,
+  scopes TEXT NOT NULL,
+  -- スペース区切りのスコープ文字列
+  status TEXT NOT NULL DEFAULT 'pending',
+  -- 'pending', 'approved', 'denied'
+  user_id TEXT,
+  -- 承認したユーザー
+  poll_interval INTEGER NOT NULL,
+  -- ポーリング間隔 (秒)。slow_down のたびに 5 秒延長する
+  last_polled_at TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+);
+-- 監査ログ (追記のみ。更新・削除はトリガーで禁止する)
+CREATE TABLE IF NOT EXISTS audit_logs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_type TEXT NOT NULL,
+  -- 'authorize', 'token', 'login', 'admin.session.delete' など
+  actor TEXT NOT NULL,
+  -- 'user:<id>', 'client:<id>', 'admin', 'anonymous'
+  client_id TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  method TEXT NOT NULL,
+  path TEXT NOT NULL,
+  -- クエリ文字列は記録しない (認可コードやトークンを含むことがあるため)
+  status_code INTEGER NOT NULL,
+  outcome TEXT NOT NULL,
+  -- 'success', 'failure'
+  detail TEXT NOT NULL DEFAULT '',
+  -- grant_type やエラーコードなど
+  created_at TIMESTAMP NOT NULL
+);
+CREATE TRIGGER IF NOT EXISTS audit_logs_no_update BEFORE UPDATE ON audit_logs
+BEGIN SELECT RAISE(ABORT, 'audit_logs is append-only'); END;
+CREATE TRIGGER IF NOT EXISTS audit_logs_no_delete BEFORE DELETE ON audit_logs
+BEGIN SELECT RAISE(ABORT, 'audit_logs is append-only'); END;
 -- インデックス作成 (パフォーマンス向上のため)
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_auth_codes_user_client ON authorization_codes(user_id, client_id);
@@ -98,5 +149,8 @@ CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
 CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
 CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_client ON refresh_tokens(user_id, client_id);
 CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires ON refresh_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
 CREATE INDEX IF NOT EXISTS idx_interactions_expires ON interactions(expires_at);
-CREATE INDEX IF NOT EXISTS idx_grants_user_client ON grants(user_id, client_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_grants_user_client ON grants(user_id, client_id);
+CREATE INDEX IF NOT EXISTS idx_device_codes_expires ON device_codes(expires_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created ON audit_logs(created_at);
\ No newline at end of file
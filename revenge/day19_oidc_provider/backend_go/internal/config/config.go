@@ -6,13 +6,18 @@ import (
 )
 
 type Config struct {
-	IssuerURL      string
-	DatabasePath   string
-	Port           string
-	PrivateKeyPath string        // JWKS 用の秘密鍵ファイルパス
-	SessionSecret  string        // セッション管理用のシークレットキー
-	SessionMaxAge  time.Duration // セッションの有効期間
-	TokenTTL       time.Duration // IDトークン、アクセストークンの有効期間
+	IssuerURL              string
+	DatabasePath           string
+	Port                   string
+	PrivateKeyPath         string        // JWKS 用の秘密鍵ファイルパス
+	SessionSecret          string        // セッション管理用のシークレットキー
+	SessionMaxAge          time.Duration // セッションの有効期間
+	TokenTTL               time.Duration // IDトークン、アクセストークンの有効期間
+	RefreshTokenTTL        time.Duration // リフレッシュトークンの有効期間 (ローテーションのたびに延長される)
+	FrontendURL            string        // ログイン・同意・デバイス認証画面 (Next.js) のURL
+	DeviceCodeTTL          time.Duration // デバイスコード・ユーザーコードの有効期間
+	DeviceCodePollInterval time.Duration // デバイスコードのポーリング間隔 (最小値)
+	AdminAPIKey            string        // 管理API (/admin) の Bearer トークン。空の場合は管理APIを無効にする
 }
 
 func Load() (*Config, error) {
@@ -26,6 +31,11 @@ func Load() (*Config, error) {
 		SessionSecret:  getEnv("SESSION_SECRET", "super-secret-key-change-me"), // 本番では変更・安全に管理
 		SessionMaxAge:  24 * time.Hour,                                  // 1日
 		TokenTTL:       1 * time.Hour,                                   // 1時間
+		RefreshTokenTTL: 30 * 24 * time.Hour,                            // 30日
+		FrontendURL:    getEnv("FRONTEND_URL", "http://localhost:3001"),
+		DeviceCodeTTL:  10 * time.Minute,                                // 10分
+		DeviceCodePollInterval: 5 * time.Second,                         // RFC 8628 のデフォルト
+		AdminAPIKey:    getEnv("ADMIN_API_KEY", ""),
 	}, nil
 }
 
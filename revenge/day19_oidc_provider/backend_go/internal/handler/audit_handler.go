@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"day19_oidc_provider/backend_go/internal/store"
+)
+
+const (
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+)
+
+// auditLogResponse is an audit log entry as shown by the admin API.
+type auditLogResponse struct {
+	ID         int64     `json:"id"`
+	EventType  string    `json:"event_type"`
+	Actor      string    `json:"actor"`
+	ClientID   string    `json:"client_id,omitempty"`
+	IPAddress  string    `json:"ip_address"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	StatusCode int       `json:"status_code"`
+	Outcome    string    `json:"outcome"`
+	Detail     string    `json:"detail,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ListAuditLogs lists audit log entries, newest first (admin API).
+// Query parameters: from / to (RFC 3339, to is exclusive), event_type, actor, client_id, outcome and limit.
+func (h *OIDCHandler) ListAuditLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := store.AuditLogFilter{
+		EventType: q.Get("event_type"),
+		Actor:     q.Get("actor"),
+		ClientID:  q.Get("client_id"),
+		Outcome:   q.Get("outcome"),
+		Limit:     defaultAuditLogLimit,
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := q.Get(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeJSONError(w, "invalid_request: "+p.name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*p.dst = &t
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		writeJSONError(w, "invalid_request: from must be before to", http.StatusBadRequest)
+		return
+	}
+	if filter.Outcome != "" && filter.Outcome != store.AuditOutcomeSuccess && filter.Outcome != store.AuditOutcomeFailure {
+		writeJSONError(w, "invalid_request: outcome must be success or failure", http.StatusBadRequest)
+		return
+	}
+	if value := q.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxAuditLogLimit {
+			writeJSONError(w, "invalid_request: limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	logs, err := h.store.ListAuditLogs(filter)
+	if err != nil {
+		log.Printf("ListAuditLogs Error: %v", err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]auditLogResponse, 0, len(logs))
+	for _, l := range logs {
+		response = append(response, auditLogResponse{
+			ID:         l.ID,
+			EventType:  l.EventType,
+			Actor:      l.Actor,
+			ClientID:   l.ClientID,
+			IPAddress:  l.IPAddress,
+			Method:     l.Method,
+			Path:       l.Path,
+			StatusCode: l.StatusCode,
+			Outcome:    l.Outcome,
+			Detail:     l.Detail,
+			CreatedAt:  l.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"audit_logs": response}); err != nil {
+		log.Printf("ListAuditLogs Error: Failed to encode response: %v", err)
+	}
+}
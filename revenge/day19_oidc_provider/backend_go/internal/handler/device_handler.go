@@ -0,0 +1,377 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"day19_oidc_provider/backend_go/internal/middleware"
+	"day19_oidc_provider/backend_go/internal/service"
+	"day19_oidc_provider/backend_go/internal/store"
+)
+
+// deviceCodeGrantType is the grant_type for polling the token endpoint (RFC 8628 Section 3.4).
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// User codes use only consonants to avoid ambiguous characters and accidental words (RFC 8628 Section 6.1).
+const (
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+)
+
+// supportedScopes lists the scopes this provider understands (also advertised in the discovery document).
+var supportedScopes = []string{"openid", "email", "profile"}
+
+// DeviceAuthorization handles the device authorization request (RFC 8628 Section 3.1).
+func (h *OIDCHandler) DeviceAuthorization(w http.ResponseWriter, r *http.Request) {
+	client, err := service.AuthenticateClient(r, h.store)
+	if err != nil {
+		log.Printf("DeviceAuthorization Error: Client authentication failed: %v", err)
+		w.Header().Set("WWW-Authenticate", "Basic realm=\"Restricted\"")
+		writeJSONError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "invalid_request: Failed to parse form body", http.StatusBadRequest)
+		return
+	}
+	scopes := strings.Fields(r.PostFormValue("scope"))
+	if len(scopes) == 0 {
+		scopes = []string{"openid"}
+	}
+	for _, scope := range scopes {
+		if !containsString(supportedScopes, scope) {
+			writeJSONError(w, fmt.Sprintf("invalid_scope: Unsupported scope '%s'", scope), http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now()
+	deviceCode := &store.DeviceCode{
+		DeviceCode:   generateSecureRandomString(32),
+		ClientID:     client.ID,
+		Scopes:       strings.Join(scopes, " "),
+		Status:       store.DeviceCodeStatusPending,
+		PollInterval: int(h.cfg.DeviceCodePollInterval.Seconds()),
+		ExpiresAt:    now.Add(h.cfg.DeviceCodeTTL),
+		CreatedAt:    now,
+	}
+
+	// user_code は短いため衝突する可能性がある。UNIQUE 制約違反なら作り直す
+	for attempt := 0; ; attempt++ {
+		deviceCode.UserCode, err = generateUserCode()
+		if err == nil {
+			err = h.store.CreateDeviceCode(deviceCode)
+		}
+		if err == nil || attempt >= 2 {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("DeviceAuthorization Error: Failed to create device code for client %s: %v", client.ID, err)
+		writeJSONError(w, "server_error: Failed to create device code", http.StatusInternalServerError)
+		return
+	}
+
+	userCode := formatUserCode(deviceCode.UserCode)
+	verificationURI := h.cfg.FrontendURL + "/device"
+	response := map[string]interface{}{
+		"device_code":               deviceCode.DeviceCode,
+		"user_code":                 userCode,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": verificationURI + "?user_code=" + url.QueryEscape(userCode),
+		"expires_in":                int(h.cfg.DeviceCodeTTL.Seconds()),
+		"interval":                  deviceCode.PollInterval,
+	}
+
+	log.Printf("DeviceAuthorization Success: Issued user code %s for client %s", userCode, client.ID)
+	writeTokenResponse(w, response)
+}
+
+// deviceCodeGrant handles polling of the token endpoint with a device code (RFC 8628 Section 3.4, 3.5).
+func (h *OIDCHandler) deviceCodeGrant(w http.ResponseWriter, r *http.Request, client *store.Client) {
+	code := r.PostFormValue("device_code")
+	if code == "" {
+		writeJSONError(w, "invalid_request: Missing device_code parameter", http.StatusBadRequest)
+		return
+	}
+
+	deviceCode, err := h.store.GetDeviceCode(code)
+	if err != nil {
+		log.Printf("Token Error: Failed to get device code: %v", err)
+		writeJSONError(w, "server_error: Failed to get device code", http.StatusInternalServerError)
+		return
+	}
+	if deviceCode == nil || deviceCode.ClientID != client.ID {
+		writeJSONError(w, "invalid_grant: Device code is invalid", http.StatusBadRequest)
+		return
+	}
+
+	// ポーリング中のクライアントは error の値で分岐するため、以下のエラーはコードのみを返す
+	now := time.Now()
+	if now.After(deviceCode.ExpiresAt) {
+		_ = h.store.DeleteDeviceCode(code)
+		writeJSONError(w, "expired_token", http.StatusBadRequest)
+		return
+	}
+
+	switch deviceCode.Status {
+	case store.DeviceCodeStatusDenied:
+		_ = h.store.DeleteDeviceCode(code)
+		writeJSONError(w, "access_denied", http.StatusBadRequest)
+		return
+	case store.DeviceCodeStatusPending:
+		interval := deviceCode.PollInterval
+		tooFast := deviceCode.LastPolledAt != nil && now.Sub(*deviceCode.LastPolledAt) < time.Duration(interval)*time.Second
+		if tooFast {
+			interval += 5 // RFC 8628 Section 3.5: slow_down のたびに 5 秒延ばす
+		}
+		if err := h.store.UpdateDeviceCodePolling(code, interval, now); err != nil {
+			log.Printf("Token Warning: Failed to update device code polling: %v", err)
+		}
+		if tooFast {
+			writeJSONError(w, "slow_down", http.StatusBadRequest)
+			return
+		}
+		writeJSONError(w, "authorization_pending", http.StatusBadRequest)
+		return
+	}
+
+	// --- Approved: Consume Device Code and Issue Tokens ---
+	if err := h.store.DeleteDeviceCode(code); err != nil {
+		log.Printf("Token Warning: Failed to delete consumed device code: %v", err)
+	}
+	userID := *deviceCode.UserID
+	scopes := strings.Fields(deviceCode.Scopes)
+
+	accessToken, err := h.tokenService.GenerateAccessToken(userID, client.ID, scopes)
+	if err != nil {
+		log.Printf("Token Error: Failed to generate access token: %v", err)
+		writeJSONError(w, "server_error: Failed to generate tokens", http.StatusInternalServerError)
+		return
+	}
+	refreshToken, err := h.tokenService.IssueRefreshToken(userID, client.ID, scopes, "")
+	if err != nil {
+		log.Printf("Token Error: Failed to issue refresh token: %v", err)
+		writeJSONError(w, "server_error: Failed to generate tokens", http.StatusInternalServerError)
+		return
+	}
+
+	tokenResponse := map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.cfg.TokenTTL.Seconds()),
+		"refresh_token": refreshToken,
+		"scope":         deviceCode.Scopes,
+	}
+	if containsString(scopes, "openid") {
+		idToken, err := h.tokenService.GenerateIDToken(userID, client.ID, "", scopes)
+		if err != nil {
+			log.Printf("Token Error: Failed to generate ID token: %v", err)
+			writeJSONError(w, "server_error: Failed to generate tokens", http.StatusInternalServerError)
+			return
+		}
+		tokenResponse["id_token"] = idToken
+	}
+
+	log.Printf("Token Success: Issued tokens for device flow, user %s, client %s", userID, client.ID)
+	writeTokenResponse(w, tokenResponse)
+}
+
+// GetDeviceVerification provides details for the device verification page of the frontend.
+// If the user is not logged in, it starts a login interaction that returns to the verification page.
+func (h *OIDCHandler) GetDeviceVerification(w http.ResponseWriter, r *http.Request) {
+	userCode := normalizeUserCode(chi.URLParam(r, "userCode"))
+	deviceCode, err := h.store.GetDeviceCodeByUserCode(userCode)
+	if err != nil {
+		log.Printf("GetDeviceVerification Error: Failed to get device code: %v", err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if deviceCode == nil || time.Now().After(deviceCode.ExpiresAt) {
+		writeJSONError(w, "Invalid or expired user code", http.StatusNotFound)
+		return
+	}
+
+	client, err := h.store.GetClient(deviceCode.ClientID)
+	if err != nil {
+		log.Printf("GetDeviceVerification Error: Failed to get client %s: %v", deviceCode.ClientID, err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	details := map[string]interface{}{
+		"user_code":        formatUserCode(deviceCode.UserCode),
+		"client_id":        client.ID,
+		"client_name":      client.Name,
+		"scopes_requested": strings.Fields(deviceCode.Scopes),
+		"status":           deviceCode.Status,
+		"expires_at":       deviceCode.ExpiresAt,
+		"login_required":   false,
+	}
+
+	sessionData, err := h.sessionMgr.GetSessionFromRequest(r)
+	if err != nil {
+		log.Printf("GetDeviceVerification Error: Failed to get session: %v", err)
+		writeJSONError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	if sessionData == nil {
+		// ログイン後にデバイス認証画面へ戻れるように login の interaction を作成する
+		displayCode := formatUserCode(deviceCode.UserCode)
+		paramsJSON, err := json.Marshal(map[string]interface{}{
+			"client_id":        client.ID,
+			"client_name":      client.Name,
+			"scopes_requested": strings.Fields(deviceCode.Scopes),
+			"user_code":        displayCode,
+		})
+		if err != nil {
+			log.Printf("GetDeviceVerification Error: Failed to marshal interaction params: %v", err)
+			writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		interaction := &store.Interaction{
+			ID:        uuid.NewString(),
+			Prompt:    "login",
+			Params:    string(paramsJSON),
+			ReturnTo:  h.cfg.FrontendURL + "/device?user_code=" + url.QueryEscape(displayCode),
+			ExpiresAt: time.Now().Add(10 * time.Minute),
+			CreatedAt: time.Now(),
+		}
+		if err := h.store.CreateInteraction(interaction); err != nil {
+			log.Printf("GetDeviceVerification Error: Failed to create login interaction: %v", err)
+			writeJSONError(w, "Failed to start login flow", http.StatusInternalServerError)
+			return
+		}
+		details["login_required"] = true
+		details["login_url"] = fmt.Sprintf("%s/login?interaction_id=%s", h.cfg.FrontendURL, interaction.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(details); err != nil {
+		log.Printf("GetDeviceVerification Error: Failed to encode response: %v", err)
+	}
+}
+
+// HandleDeviceVerification processes the user's decision on the device verification page.
+func (h *OIDCHandler) HandleDeviceVerification(w http.ResponseWriter, r *http.Request) {
+	var reqBody struct {
+		UserCode string `json:"user_code"`
+		Decision string `json:"decision"` // "allow" or "deny"
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		log.Printf("HandleDeviceVerification Error: Failed to decode request body: %v", err)
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if reqBody.UserCode == "" || (reqBody.Decision != "allow" && reqBody.Decision != "deny") {
+		writeJSONError(w, "Missing required fields (user_code, decision [allow/deny])", http.StatusBadRequest)
+		return
+	}
+
+	sessionData, err := h.sessionMgr.GetSessionFromRequest(r)
+	if err != nil {
+		log.Printf("HandleDeviceVerification Error: Failed to get session: %v", err)
+		writeJSONError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	if sessionData == nil {
+		writeJSONError(w, "Login required", http.StatusUnauthorized)
+		return
+	}
+
+	userCode := normalizeUserCode(reqBody.UserCode)
+	deviceCode, err := h.store.GetDeviceCodeByUserCode(userCode)
+	if err != nil {
+		log.Printf("HandleDeviceVerification Error: Failed to get device code: %v", err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if deviceCode == nil {
+		writeJSONError(w, "Invalid or expired user code", http.StatusNotFound)
+		return
+	}
+
+	middleware.SetAuditClientID(r, deviceCode.ClientID)
+
+	status := store.DeviceCodeStatusDenied
+	if reqBody.Decision == "allow" {
+		status = store.DeviceCodeStatusApproved
+	} else {
+		middleware.SetAuditFailure(r, "access_denied")
+	}
+	updated, err := h.store.UpdateDeviceCodeStatus(userCode, status, sessionData.UserID)
+	if err != nil {
+		log.Printf("HandleDeviceVerification Error: Failed to update device code: %v", err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !updated {
+		// 期限切れ、またはすでに承認・拒否済み
+		writeJSONError(w, "Invalid or expired user code", http.StatusBadRequest)
+		return
+	}
+
+	if status == store.DeviceCodeStatusApproved {
+		grant := &store.Grant{
+			UserID:   sessionData.UserID,
+			ClientID: deviceCode.ClientID,
+			Scopes:   deviceCode.Scopes,
+		}
+		if err := h.store.CreateOrUpdateGrant(grant); err != nil {
+			log.Printf("HandleDeviceVerification Warning: Failed to store grant for user %s, client %s: %v", sessionData.UserID, deviceCode.ClientID, err)
+		}
+	}
+
+	log.Printf("HandleDeviceVerification: User %s %s device code for client %s", sessionData.UserID, status, deviceCode.ClientID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// generateUserCode generates a random user code from userCodeCharset.
+func generateUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeCharset)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		b[i] = userCodeCharset[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// formatUserCode formats a normalized user code for display (e.g. "WDJB-MJHT").
+func formatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:4] + "-" + userCode[4:]
+}
+
+// normalizeUserCode removes separators and uppercases the code the user typed.
+func normalizeUserCode(userCode string) string {
+	userCode = strings.ToUpper(userCode)
+	return strings.NewReplacer("-", "", " ", "").Replace(userCode)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// grantResponse is a grant (remembered consent) as shown to the user.
+type grantResponse struct {
+	ClientID   string     `json:"client_id"`
+	ClientName string     `json:"client_name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// ListGrants lists the clients the logged-in user has consented to.
+func (h *OIDCHandler) ListGrants(w http.ResponseWriter, r *http.Request) {
+	sessionData, err := h.sessionMgr.GetSessionFromRequest(r)
+	if err != nil {
+		log.Printf("ListGrants Error: Failed to get session: %v", err)
+		writeJSONError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	if sessionData == nil {
+		writeJSONError(w, "Login required", http.StatusUnauthorized)
+		return
+	}
+
+	grants, err := h.store.ListGrantsByUser(sessionData.UserID)
+	if err != nil {
+		log.Printf("ListGrants Error: Failed to list grants for user %s: %v", sessionData.UserID, err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]grantResponse, 0, len(grants))
+	for _, grant := range grants {
+		clientName := grant.ClientID
+		client, err := h.store.GetClient(grant.ClientID)
+		if err != nil {
+			log.Printf("ListGrants Warning: Failed to get client %s: %v", grant.ClientID, err)
+		} else {
+			clientName = client.Name
+		}
+		response = append(response, grantResponse{
+			ClientID:   grant.ClientID,
+			ClientName: clientName,
+			Scopes:     strings.Fields(grant.Scopes),
+			CreatedAt:  grant.CreatedAt,
+			ExpiresAt:  grant.ExpiresAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"grants": response}); err != nil {
+		log.Printf("ListGrants Error: Failed to encode response: %v", err)
+	}
+}
+
+// RevokeGrant revokes the logged-in user's consent to a client.
+// The refresh tokens issued to the client for the user are revoked as well, and the next
+// authorization request from the client shows the consent page again.
+// Access tokens already issued stay valid until they expire, since they are self-contained JWTs.
+func (h *OIDCHandler) RevokeGrant(w http.ResponseWriter, r *http.Request) {
+	clientID := chi.URLParam(r, "clientID")
+	if clientID == "" {
+		writeJSONError(w, "Missing client ID", http.StatusBadRequest)
+		return
+	}
+
+	sessionData, err := h.sessionMgr.GetSessionFromRequest(r)
+	if err != nil {
+		log.Printf("RevokeGrant Error: Failed to get session: %v", err)
+		writeJSONError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	if sessionData == nil {
+		writeJSONError(w, "Login required", http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := h.store.RevokeGrant(sessionData.UserID, clientID)
+	if err != nil {
+		log.Printf("RevokeGrant Error: Failed to revoke grant for user %s, client %s: %v", sessionData.UserID, clientID, err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		writeJSONError(w, "Grant not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("RevokeGrant: User %s revoked grant for client %s", sessionData.UserID, clientID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -52,12 +52,18 @@ func (h *OIDCHandler) Discovery(w http.ResponseWriter, r *http.Request) {
 		"token_endpoint":                        h.cfg.IssuerURL + "/token",
 		"userinfo_endpoint":                     h.cfg.IssuerURL + "/userinfo",
 		"jwks_uri":                              h.cfg.IssuerURL + "/jwks",
-		"scopes_supported":                      []string{"openid", "email", "profile"}, // Adjust as needed
+		"revocation_endpoint":                   h.cfg.IssuerURL + "/revoke",
+		"introspection_endpoint":                h.cfg.IssuerURL + "/introspect",
+		"device_authorization_endpoint":         h.cfg.IssuerURL + "/device_authorization",
+		"end_session_endpoint":                  h.cfg.IssuerURL + "/logout",
+		"scopes_supported":                      supportedScopes,
 		"response_types_supported":              []string{"code"},                        // Only Authorization Code Flow
-		"grant_types_supported":                 []string{"authorization_code" /*, "refresh_token" */}, // Add refresh_token later if supported
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", deviceCodeGrantType},
 		"subject_types_supported":               []string{"public"},
 		"id_token_signing_alg_values_supported": []string{"RS256"},
 		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"}, // Add others if needed
+		"revocation_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"introspection_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
 		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "email", "name"}, // Adjust as needed
 		// "service_documentation":              "<URL_TO_YOUR_DOCS>", // Optional
 		// "ui_locales_supported":               []string{"en-US", "ja-JP"}, // Optional
@@ -338,15 +344,24 @@ func (h *OIDCHandler) Token(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "invalid_request: Failed to parse form body", http.StatusBadRequest)
 		return
 	}
-	grantType := r.PostFormValue("grant_type")
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		h.authorizationCodeGrant(w, r, client)
+	case "refresh_token":
+		h.refreshTokenGrant(w, r, client)
+	case deviceCodeGrantType:
+		h.deviceCodeGrant(w, r, client)
+	default:
+		writeJSONError(w, "unsupported_grant_type: Only authorization_code, refresh_token and device_code are supported", http.StatusBadRequest)
+	}
+}
+
+// authorizationCodeGrant exchanges an authorization code for tokens.
+func (h *OIDCHandler) authorizationCodeGrant(w http.ResponseWriter, r *http.Request, client *store.Client) {
 	code := r.PostFormValue("code")
 	redirectURI := r.PostFormValue("redirect_uri")
 	codeVerifier := r.PostFormValue("code_verifier") // For PKCE
 
-	if grantType != "authorization_code" {
-		writeJSONError(w, "unsupported_grant_type: Only authorization_code is supported", http.StatusBadRequest)
-		return
-	}
 	if code == "" {
 		writeJSONError(w, "invalid_request: Missing code parameter", http.StatusBadRequest)
 		return
@@ -429,23 +444,25 @@ func (h *OIDCHandler) Token(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 新しい認可なので新しいトークン系列 (family) を開始する
+	refreshToken, err := h.tokenService.IssueRefreshToken(userID, client.ID, scopes, "")
+	if err != nil {
+		log.Printf("Token Error: Failed to issue refresh token: %v", err)
+		writeJSONError(w, "server_error: Failed to generate tokens", http.StatusInternalServerError)
+		return
+	}
+
 	// --- 7. Return Token Response ---
 	tokenResponse := map[string]interface{}{
-		"access_token": accessToken,
-		"token_type":   "Bearer",
-		"expires_in":   int(h.cfg.TokenTTL.Seconds()),
-		"id_token":     idToken,
-		// "refresh_token": refreshToken, // Add later if implemented
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.cfg.TokenTTL.Seconds()),
+		"id_token":      idToken,
+		"refresh_token": refreshToken,
 	}
 
 	log.Printf("Token Success: Issued tokens for user %s, client %s", userID, client.ID)
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Cache-Control", "no-store")
-	w.Header().Set("Pragma", "no-cache")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(tokenResponse); err != nil {
-		log.Printf("Token Error: Failed to encode token response: %v", err)
-	}
+	writeTokenResponse(w, tokenResponse)
 }
 
 // UserInfo serves user information based on the validated access token.
@@ -532,6 +549,12 @@ func (h *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "Invalid interaction type", http.StatusBadRequest)
 		return
 	}
+	var interactionParams struct {
+		ClientID string `json:"client_id"`
+	}
+	if err := json.Unmarshal([]byte(interaction.Params), &interactionParams); err == nil {
+		middleware.SetAuditClientID(r, interactionParams.ClientID)
+	}
 
 	// --- 3. Authenticate User ---
 	user, err := h.store.GetUserByEmail(reqBody.Email)
@@ -548,9 +571,10 @@ func (h *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("HandleLogin Success: User %s authenticated successfully", user.ID)
+	middleware.SetAuditActor(r, middleware.UserActor(user.ID))
 
 	// --- 4. Create Session ---
-	sessionID, err := h.sessionMgr.CreateSession(user.ID)
+	sessionID, err := h.sessionMgr.CreateSession(user.ID, r)
 	if err != nil {
 		log.Printf("HandleLogin Error: Failed to create session for user %s: %v", user.ID, err)
 		writeJSONError(w, "Login failed (session error)", http.StatusInternalServerError)
@@ -651,6 +675,7 @@ func (h *OIDCHandler) HandleConsent(w http.ResponseWriter, r *http.Request) {
 	redirectURI := getStringParam("redirect_uri")
 	state := getStringParam("state")
 	scope := getStringParam("scope") // Original requested scope string
+	middleware.SetAuditClientID(r, clientID)
 
 	// --- 4. Process Decision ---
 	consentResult := map[string]interface{}{
@@ -659,6 +684,7 @@ func (h *OIDCHandler) HandleConsent(w http.ResponseWriter, r *http.Request) {
 
 	if reqBody.Decision == "deny" {
 		log.Printf("HandleConsent: User denied consent for interaction %s", interaction.ID)
+		middleware.SetAuditFailure(r, "access_denied")
 		consentResult["consent"].(map[string]interface{})["error"] = "access_denied"
 		// Update interaction result
 		if err := h.store.UpdateInteractionResult(interaction.ID, consentResult); err != nil {
@@ -689,7 +715,7 @@ func (h *OIDCHandler) HandleConsent(w http.ResponseWriter, r *http.Request) {
 
 	// Get User ID from session
 	session, err := h.store.GetSession(*interaction.SessionID)
-	if err != nil {
+	if err != nil || session == nil {
 		log.Printf("HandleConsent Error: Could not retrieve session %s linked to interaction %s: %v", *interaction.SessionID, interaction.ID, err)
 		writeJSONError(w, "Session error after consent", http.StatusInternalServerError)
 		return
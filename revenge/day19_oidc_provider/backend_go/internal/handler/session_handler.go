@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"day19_oidc_provider/backend_go/internal/service"
+)
+
+// sessionResponse is a login session as shown by the admin API.
+type sessionResponse struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	IPAddress      string    `json:"ip_address,omitempty"`
+	UserAgent      string    `json:"user_agent,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// EndSession handles RP-Initiated Logout (OpenID Connect RP-Initiated Logout 1.0).
+// It ends the user's session and redirects to post_logout_redirect_uri if the client registered it.
+// Tokens already issued to clients are not revoked.
+func (h *OIDCHandler) EndSession(w http.ResponseWriter, r *http.Request) {
+	// GET はクエリ、POST はフォームでパラメータを受け取る
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "invalid_request: Failed to parse parameters", http.StatusBadRequest)
+		return
+	}
+	idTokenHint := r.Form.Get("id_token_hint")
+	clientID := r.Form.Get("client_id")
+	postLogoutRedirectURI := r.Form.Get("post_logout_redirect_uri")
+	state := r.Form.Get("state")
+
+	var hint *service.IDTokenClaims
+	if idTokenHint != "" {
+		claims, err := h.tokenService.ParseIDTokenHint(idTokenHint)
+		if err != nil {
+			log.Printf("EndSession Error: Invalid id_token_hint: %v", err)
+			writeJSONError(w, "invalid_request: Invalid id_token_hint", http.StatusBadRequest)
+			return
+		}
+		hint = claims
+		if clientID == "" {
+			clientID = hint.Audience[0]
+		} else if !containsString(hint.Audience, clientID) {
+			writeJSONError(w, "invalid_request: client_id does not match id_token_hint", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if postLogoutRedirectURI != "" {
+		// 登録済みの URI にのみリダイレクトする (オープンリダイレクト対策)
+		if clientID == "" {
+			writeJSONError(w, "invalid_request: id_token_hint or client_id is required with post_logout_redirect_uri", http.StatusBadRequest)
+			return
+		}
+		client, err := h.store.GetClient(clientID)
+		if err != nil {
+			log.Printf("EndSession Error: Failed to get client %s: %v", clientID, err)
+			writeJSONError(w, "invalid_request: Invalid client_id", http.StatusBadRequest)
+			return
+		}
+		if !containsString(client.ParsedPostLogoutRedirectURIs, postLogoutRedirectURI) {
+			log.Printf("EndSession Error: post_logout_redirect_uri %s is not registered for client %s", postLogoutRedirectURI, clientID)
+			writeJSONError(w, "invalid_request: Invalid post_logout_redirect_uri", http.StatusBadRequest)
+			return
+		}
+	}
+
+	sessionData, err := h.sessionMgr.GetSessionFromRequest(r)
+	if err != nil {
+		log.Printf("EndSession Error: Failed to get session: %v", err)
+		writeJSONError(w, "Session error", http.StatusInternalServerError)
+		return
+	}
+	if sessionData != nil {
+		// 別のユーザーの ID トークンでログアウトさせない
+		if hint != nil && hint.Subject != sessionData.UserID {
+			log.Printf("EndSession Error: id_token_hint subject %s does not match session user %s", hint.Subject, sessionData.UserID)
+			writeJSONError(w, "invalid_request: id_token_hint does not match the current session", http.StatusBadRequest)
+			return
+		}
+		if err := h.store.DeleteSession(sessionData.SessionID); err != nil {
+			log.Printf("EndSession Error: Failed to delete session %s: %v", sessionData.SessionID, err)
+			writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("EndSession: User %s logged out (session %s)", sessionData.UserID, sessionData.SessionID)
+	}
+	h.sessionMgr.DeleteSessionCookie(w)
+
+	if postLogoutRedirectURI != "" {
+		redirectURL, err := url.Parse(postLogoutRedirectURI)
+		if err != nil {
+			writeJSONError(w, "invalid_request: Invalid post_logout_redirect_uri", http.StatusBadRequest)
+			return
+		}
+		if state != "" {
+			q := redirectURL.Query()
+			q.Set("state", state)
+			redirectURL.RawQuery = q.Encode()
+		}
+		http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "logged_out"})
+}
+
+// ListUserSessions lists the active sessions of a user (admin API).
+func (h *OIDCHandler) ListUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	sessions, err := h.store.ListSessionsByUser(userID)
+	if err != nil {
+		log.Printf("ListUserSessions Error: Failed to list sessions for user %s: %v", userID, err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		item := sessionResponse{
+			ID:             s.ID,
+			UserID:         s.UserID,
+			CreatedAt:      s.CreatedAt,
+			LastAccessedAt: s.LastAccessedAt,
+			ExpiresAt:      s.ExpiresAt,
+		}
+		if s.IPAddress != nil {
+			item.IPAddress = *s.IPAddress
+		}
+		if s.UserAgent != nil {
+			item.UserAgent = *s.UserAgent
+		}
+		response = append(response, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"sessions": response}); err != nil {
+		log.Printf("ListUserSessions Error: Failed to encode response: %v", err)
+	}
+}
+
+// DeleteUserSessions terminates all sessions of a user (admin API).
+func (h *OIDCHandler) DeleteUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	deleted, err := h.store.DeleteSessionsByUser(userID)
+	if err != nil {
+		log.Printf("DeleteUserSessions Error: Failed to delete sessions for user %s: %v", userID, err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("DeleteUserSessions: Terminated %d sessions of user %s", deleted, userID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"deleted": deleted})
+}
+
+// DeleteSession terminates a single session (admin API).
+func (h *OIDCHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "sessionID")
+
+	s, err := h.store.GetSession(sessionID)
+	if err != nil {
+		log.Printf("DeleteSession Error: Failed to get session %s: %v", sessionID, err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if s == nil {
+		writeJSONError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.store.DeleteSession(sessionID); err != nil {
+		log.Printf("DeleteSession Error: Failed to delete session %s: %v", sessionID, err)
+		writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("DeleteSession: Terminated session %s of user %s", sessionID, s.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
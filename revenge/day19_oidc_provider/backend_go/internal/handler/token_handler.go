@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"day19_oidc_provider/backend_go/internal/service"
+	"day19_oidc_provider/backend_go/internal/store"
+)
+
+// refreshTokenGrant exchanges a refresh token for new tokens (RFC 6749 Section 6).
+// The refresh token is rotated on every use; the previous one can no longer be used.
+func (h *OIDCHandler) refreshTokenGrant(w http.ResponseWriter, r *http.Request, client *store.Client) {
+	refreshToken := r.PostFormValue("refresh_token")
+	if refreshToken == "" {
+		writeJSONError(w, "invalid_request: Missing refresh_token parameter", http.StatusBadRequest)
+		return
+	}
+
+	newRefreshToken, current, scopes, err := h.tokenService.RotateRefreshToken(refreshToken, client.ID, strings.Fields(r.PostFormValue("scope")))
+	if err != nil {
+		log.Printf("Token Error: Refresh token grant failed for client %s: %v", client.ID, err)
+		status := http.StatusBadRequest
+		if strings.HasPrefix(err.Error(), "server_error") {
+			status = http.StatusInternalServerError
+		}
+		writeJSONError(w, err.Error(), status)
+		return
+	}
+
+	userID := current.UserID
+	accessToken, err := h.tokenService.GenerateAccessToken(userID, client.ID, scopes)
+	if err != nil {
+		log.Printf("Token Error: Failed to generate access token: %v", err)
+		writeJSONError(w, "server_error: Failed to generate tokens", http.StatusInternalServerError)
+		return
+	}
+
+	tokenResponse := map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.cfg.TokenTTL.Seconds()),
+		"refresh_token": newRefreshToken,
+		"scope":         strings.Join(scopes, " "),
+	}
+
+	// OIDC Core 12.2: openid スコープがあれば ID トークンも再発行する (nonce は含めない)
+	if containsString(scopes, "openid") {
+		idToken, err := h.tokenService.GenerateIDToken(userID, client.ID, "", scopes)
+		if err != nil {
+			log.Printf("Token Error: Failed to generate ID token: %v", err)
+			writeJSONError(w, "server_error: Failed to generate tokens", http.StatusInternalServerError)
+			return
+		}
+		tokenResponse["id_token"] = idToken
+	}
+
+	log.Printf("Token Success: Refreshed tokens for user %s, client %s", userID, client.ID)
+	writeTokenResponse(w, tokenResponse)
+}
+
+// Revoke handles the token revocation request (RFC 7009).
+func (h *OIDCHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	client, err := service.AuthenticateClient(r, h.store)
+	if err != nil {
+		log.Printf("Revoke Error: Client authentication failed: %v", err)
+		w.Header().Set("WWW-Authenticate", "Basic realm=\"Restricted\"")
+		writeJSONError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "invalid_request: Failed to parse form body", http.StatusBadRequest)
+		return
+	}
+	token := r.PostFormValue("token")
+	if token == "" {
+		writeJSONError(w, "invalid_request: Missing token parameter", http.StatusBadRequest)
+		return
+	}
+
+	// token_type_hint は探索順のヒントにすぎないため、未知の値でもエラーにしない
+	if err := h.tokenService.RevokeToken(token, r.PostFormValue("token_type_hint"), client.ID); err != nil {
+		if errors.Is(err, service.ErrUnsupportedTokenType) {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Revoke Error: %v", err)
+		writeJSONError(w, "server_error: Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	// 無効なトークンや他のクライアントのトークンでも 200 を返す (RFC 7009 Section 2.2)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Introspect handles the token introspection request (RFC 7662).
+func (h *OIDCHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	client, err := service.AuthenticateClient(r, h.store)
+	if err != nil {
+		log.Printf("Introspect Error: Client authentication failed: %v", err)
+		w.Header().Set("WWW-Authenticate", "Basic realm=\"Restricted\"")
+		writeJSONError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeJSONError(w, "invalid_request: Failed to parse form body", http.StatusBadRequest)
+		return
+	}
+	token := r.PostFormValue("token")
+	if token == "" {
+		writeJSONError(w, "invalid_request: Missing token parameter", http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.tokenService.IntrospectToken(token, r.PostFormValue("token_type_hint"), client.ID)
+	if err != nil {
+		log.Printf("Introspect Error: %v", err)
+		writeJSONError(w, "server_error: Failed to introspect token", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Introspect: Client %s introspected a token (active: %t)", client.ID, response.Active)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Introspect Error: Failed to encode response: %v", err)
+	}
+}
+
+// writeTokenResponse writes a successful token response with the no-cache headers required by RFC 6749 Section 5.1.
+func writeTokenResponse(w http.ResponseWriter, tokenResponse map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(tokenResponse); err != nil {
+		log.Printf("Token Error: Failed to encode token response: %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"day19_oidc_provider/backend_go/internal/config"
+)
+
+// RequireAdminAPIKey protects the admin API with the Bearer token configured as AdminAPIKey.
+// The admin API is disabled when no key is configured.
+func RequireAdminAPIKey(cfg *config.Config) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.AdminAPIKey == "" {
+				http.Error(w, "Admin API is disabled", http.StatusForbidden)
+				return
+			}
+
+			parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" ||
+				subtle.ConstantTimeCompare([]byte(parts[1]), []byte(cfg.AdminAPIKey)) != 1 {
+				log.Printf("Admin API Error: Invalid or missing API key from %s", r.RemoteAddr)
+				w.Header().Set("WWW-Authenticate", "Bearer realm=\"Admin\"")
+				http.Error(w, "Invalid admin API key", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"day19_oidc_provider/backend_go/internal/jwks"
+)
+
+// IntrospectionResponse is the token introspection response (RFC 7662 Section 2.2).
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	TokenType string `json:"token_type,omitempty"` // "access_token" or "refresh_token"
+}
+
+// IntrospectToken reports whether the token is active (RFC 7662).
+// Any authenticated client (e.g. a resource server) may introspect access tokens,
+// but refresh tokens are only reported to the client they were issued to.
+func (s *TokenService) IntrospectToken(token, tokenTypeHint, clientID string) (*IntrospectionResponse, error) {
+	// token_type_hint は探索順を決めるだけで、見つからなければもう一方も探す
+	lookups := []func(string, string) (*IntrospectionResponse, error){s.introspectAccessToken, s.introspectRefreshToken}
+	if tokenTypeHint == "refresh_token" {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		resp, err := lookup(token, clientID)
+		if err != nil {
+			return nil, err
+		}
+		if resp != nil {
+			return resp, nil
+		}
+	}
+	return &IntrospectionResponse{Active: false}, nil
+}
+
+// introspectAccessToken returns the response for a valid access token, or nil.
+func (s *TokenService) introspectAccessToken(token, clientID string) (*IntrospectionResponse, error) {
+	claims, err := s.parseAccessToken(token)
+	if err != nil {
+		return nil, nil
+	}
+
+	resp := &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scopes,
+		ClientID:  claims.ClientID,
+		Sub:       claims.Subject,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		TokenType: "access_token",
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+	return resp, nil
+}
+
+// introspectRefreshToken returns the response for an active refresh token issued to clientID, or nil.
+func (s *TokenService) introspectRefreshToken(token, clientID string) (*IntrospectionResponse, error) {
+	refreshToken, err := s.store.GetRefreshToken(hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("server_error: %w", err)
+	}
+	if refreshToken == nil || refreshToken.RevokedAt != nil || time.Now().After(refreshToken.ExpiresAt) {
+		return nil, nil
+	}
+	if refreshToken.ClientID != clientID {
+		log.Printf("Introspect Warning: Client %s tried to introspect a refresh token issued to %s", clientID, refreshToken.ClientID)
+		return nil, nil
+	}
+
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     refreshToken.Scopes,
+		ClientID:  refreshToken.ClientID,
+		Sub:       refreshToken.UserID,
+		Exp:       refreshToken.ExpiresAt.Unix(),
+		Iat:       refreshToken.CreatedAt.Unix(),
+		Iss:       s.cfg.IssuerURL,
+		TokenType: "refresh_token",
+	}, nil
+}
+
+// parseAccessToken validates an access token signed by this server and returns its claims.
+func (s *TokenService) parseAccessToken(token string) (*AccessTokenClaims, error) {
+	claims := &AccessTokenClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return jwks.GetPublicKey(), nil
+	}, jwt.WithIssuer(s.cfg.IssuerURL), jwt.WithAudience(s.cfg.IssuerURL)) // ID トークンは aud がクライアントなので除外される
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.Valid {
+		return nil, fmt.Errorf("invalid access token")
+	}
+	return claims, nil
+}
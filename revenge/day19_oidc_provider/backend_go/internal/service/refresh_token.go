@@ -0,0 +1,161 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"day19_oidc_provider/backend_go/internal/store"
+)
+
+// ErrUnsupportedTokenType is returned by RevokeToken for tokens this server cannot revoke (RFC 7009 Section 2.2.1).
+var ErrUnsupportedTokenType = errors.New("unsupported_token_type: access tokens are self-contained JWTs and cannot be revoked")
+
+// IssueRefreshToken creates a new refresh token and stores its hash.
+// An empty familyID starts a new token family (i.e. a new authorization).
+func (s *TokenService) IssueRefreshToken(userID, clientID string, scopes []string, familyID string) (string, error) {
+	token, record, err := s.newRefreshToken(userID, clientID, scopes, familyID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.CreateRefreshToken(record); err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// RotateRefreshToken validates a refresh token presented by the client and replaces it with a new one.
+// Presenting a token that was already rotated is treated as token theft: the whole family is revoked.
+// requestedScopes may narrow down (but never extend) the scopes of the returned access token;
+// the new refresh token keeps the originally granted scopes (RFC 6749 Section 6).
+func (s *TokenService) RotateRefreshToken(token, clientID string, requestedScopes []string) (newToken string, current *store.RefreshToken, scopes []string, err error) {
+	current, err = s.store.GetRefreshToken(hashToken(token))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("server_error: %w", err)
+	}
+	if current == nil {
+		return "", nil, nil, fmt.Errorf("invalid_grant: refresh token is invalid")
+	}
+	if current.ClientID != clientID {
+		log.Printf("Refresh Token Error: Client mismatch (expected %s, got %s)", current.ClientID, clientID)
+		return "", nil, nil, fmt.Errorf("invalid_grant: refresh token was not issued to this client")
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedBy != nil {
+			// ローテーション済みのトークンが再度使われた = 漏洩の可能性があるため系列ごと失効させる
+			s.revokeFamilyOnReuse(current)
+			return "", nil, nil, fmt.Errorf("invalid_grant: refresh token reuse detected")
+		}
+		return "", nil, nil, fmt.Errorf("invalid_grant: refresh token has been revoked")
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return "", nil, nil, fmt.Errorf("invalid_grant: refresh token has expired")
+	}
+
+	scopes = strings.Fields(current.Scopes)
+	if len(requestedScopes) > 0 {
+		for _, scope := range requestedScopes {
+			if !containsScope(scopes, scope) {
+				return "", nil, nil, fmt.Errorf("invalid_scope: scope '%s' was not granted", scope)
+			}
+		}
+		scopes = requestedScopes
+	}
+
+	newToken, next, err := s.newRefreshToken(current.UserID, current.ClientID, strings.Fields(current.Scopes), current.FamilyID)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("server_error: %w", err)
+	}
+	rotated, err := s.store.RotateRefreshToken(current.TokenHash, next)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("server_error: %w", err)
+	}
+	if !rotated {
+		// 同じトークンで同時にリクエストされ、先に別のリクエストがローテーションした
+		s.revokeFamilyOnReuse(current)
+		return "", nil, nil, fmt.Errorf("invalid_grant: refresh token reuse detected")
+	}
+
+	return newToken, current, scopes, nil
+}
+
+// RevokeToken implements RFC 7009 token revocation for the authenticated client.
+// Revoking a refresh token revokes its whole family. Unknown tokens and tokens issued
+// to other clients are ignored, since the response must not reveal whether a token is valid.
+func (s *TokenService) RevokeToken(token, tokenTypeHint, clientID string) error {
+	if tokenTypeHint != "access_token" {
+		refreshToken, err := s.store.GetRefreshToken(hashToken(token))
+		if err != nil {
+			return fmt.Errorf("server_error: %w", err)
+		}
+		if refreshToken != nil {
+			if refreshToken.ClientID != clientID {
+				log.Printf("Revoke Warning: Client %s tried to revoke a token issued to %s", clientID, refreshToken.ClientID)
+				return nil
+			}
+			if err := s.store.RevokeRefreshTokenFamily(refreshToken.FamilyID); err != nil {
+				return fmt.Errorf("server_error: %w", err)
+			}
+			log.Printf("Revoke Success: Revoked refresh token family %s for client %s", refreshToken.FamilyID, clientID)
+			return nil
+		}
+	}
+
+	if _, err := s.parseAccessToken(token); err == nil {
+		return ErrUnsupportedTokenType
+	}
+	return nil
+}
+
+// newRefreshToken generates a random refresh token and the record to store for it.
+func (s *TokenService) newRefreshToken(userID, clientID string, scopes []string, familyID string) (string, *store.RefreshToken, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(b)
+
+	if familyID == "" {
+		familyID = uuid.NewString()
+	}
+	now := time.Now()
+	return token, &store.RefreshToken{
+		TokenHash: hashToken(token),
+		FamilyID:  familyID,
+		ClientID:  clientID,
+		UserID:    userID,
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: now.Add(s.cfg.RefreshTokenTTL),
+		CreatedAt: now,
+	}, nil
+}
+
+func (s *TokenService) revokeFamilyOnReuse(token *store.RefreshToken) {
+	log.Printf("Refresh Token Error: Reuse detected for family %s (user %s, client %s). Revoking the family.", token.FamilyID, token.UserID, token.ClientID)
+	if err := s.store.RevokeRefreshTokenFamily(token.FamilyID); err != nil {
+		log.Printf("Refresh Token Error: Failed to revoke family %s: %v", token.FamilyID, err)
+	}
+}
+
+// hashToken returns the hex-encoded SHA-256 hash used to store refresh tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
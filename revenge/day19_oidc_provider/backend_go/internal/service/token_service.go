@@ -39,7 +39,8 @@ type IDTokenClaims struct {
 
 // AccessTokenClaims defines the claims for the Access Token (if using JWT).
 type AccessTokenClaims struct {
-	Scopes string `json:"scp,omitempty"` // Space-separated scopes
+	Scopes   string `json:"scp,omitempty"`       // Space-separated scopes
+	ClientID string `json:"client_id,omitempty"` // Client the token was issued to (RFC 9068)
 	jwt.RegisteredClaims
 }
 
@@ -97,7 +98,8 @@ func (s *TokenService) GenerateAccessToken(userID, clientID string, scopes []str
 	expiresAt := issuedAt.Add(s.cfg.TokenTTL)
 
 	claims := AccessTokenClaims{
-		Scopes: strings.Join(scopes, " "),
+		Scopes:   strings.Join(scopes, " "),
+		ClientID: clientID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    s.cfg.IssuerURL,
 			Subject:   userID,
@@ -105,7 +107,6 @@ func (s *TokenService) GenerateAccessToken(userID, clientID string, scopes []str
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(issuedAt),
 			ID:        uuid.NewString(), // jti
-			// ClientID could also be added as a claim: "client_id": clientID
 		},
 	}
 
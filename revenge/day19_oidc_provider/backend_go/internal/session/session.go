@@ -17,6 +17,9 @@ import (
 
 const SessionCookieName = "oidc_session"
 
+// lastAccessedUpdateInterval limits how often last_accessed_at is written to the DB.
+const lastAccessedUpdateInterval = time.Minute
+
 // SessionData holds the data stored in the session.
 // Make sure all fields are registered with gob.
 type SessionData struct {
@@ -61,9 +64,12 @@ func NewManager(cfg *config.Config, store store.Storer) *Manager {
 }
 
 // CreateSession creates a new session for the user and returns the session ID.
-func (m *Manager) CreateSession(userID string) (string, error) {
+// The client IP address and User-Agent of the request are recorded to identify the session.
+func (m *Manager) CreateSession(userID string, r *http.Request) (string, error) {
 	sessionID := uuid.NewString()
 	expiresAt := time.Now().Add(m.cfg.SessionMaxAge)
+	ipAddress := r.RemoteAddr
+	userAgent := r.UserAgent()
 
 	// Store session details in the database (using store.Storer interface)
 	dbSession := &store.Session{
@@ -72,7 +78,8 @@ func (m *Manager) CreateSession(userID string) (string, error) {
 		ExpiresAt: expiresAt,
 		CreatedAt: time.Now(),
 		LastAccessedAt: time.Now(),
-		// IPAddress and UserAgent could be added here if needed
+		IPAddress:      &ipAddress,
+		UserAgent:      &userAgent,
 	}
 
 	if err := m.store.CreateSession(dbSession); err != nil {
@@ -107,13 +114,20 @@ func (m *Manager) GetSessionFromRequest(r *http.Request) (*SessionData, error) {
 		return nil, nil // Session expired
 	}
 
-	// Optional: Validate against DB session store for extra security (e.g., check if revoked)
-	// _, err = m.store.GetSession(sessionData.SessionID)
-	// if err != nil {
-	// 	 log.Printf("Session ID %s not found or invalid in DB: %v", sessionData.SessionID, err)
-	// 	 return nil, nil
-	// }
-	// TODO: Update LastAccessedAt in DB (consider performance implications)
+	// ログアウトや管理APIで削除されたセッションを無効にするため、DB のセッションも確認する
+	dbSession, err := m.store.GetSession(sessionData.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session from db: %w", err)
+	}
+	if dbSession == nil || time.Now().After(dbSession.ExpiresAt) {
+		log.Printf("Session ID %s not found or expired in DB", sessionData.SessionID)
+		return nil, nil
+	}
+	if time.Since(dbSession.LastAccessedAt) > lastAccessedUpdateInterval {
+		if err := m.store.UpdateSessionLastAccessed(dbSession.ID); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
 
 	return &sessionData, nil
 }
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migrate adds tables and columns introduced after db/schema.sql was first applied, so that
+// existing databases (e.g. prisma/dev.db) keep working without re-creating tables.
+func migrate(db *sqlx.DB) error {
+	// Same definitions as db/schema.sql
+	tables := []string{
+		`CREATE TABLE IF NOT EXISTS device_codes (
+			device_code TEXT PRIMARY KEY,
+			user_code TEXT NOT NULL UNIQUE,
+			client_id TEXT NOT NULL,
+			scopes TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			user_id TEXT,
+			poll_interval INTEGER NOT NULL,
+			last_polled_at TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (client_id) REFERENCES clients(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS audit_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_type TEXT NOT NULL,
+			actor TEXT NOT NULL,
+			client_id TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			method TEXT NOT NULL,
+			path TEXT NOT NULL,
+			status_code INTEGER NOT NULL,
+			outcome TEXT NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		)`,
+	}
+	for _, stmt := range tables {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		// Refresh token rotation
+		{"refresh_tokens", "family_id", "TEXT NOT NULL DEFAULT ''"},
+		{"refresh_tokens", "revoked_at", "TIMESTAMP"},
+		{"refresh_tokens", "replaced_by", "TEXT"},
+		// RP-Initiated Logout
+		{"clients", "post_logout_redirect_uris", "TEXT NOT NULL DEFAULT '[]'"},
+	}
+
+	for _, c := range columns {
+		exists, err := columnExists(db, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		log.Printf("Migration: adding column %s.%s", c.table, c.column)
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
+	statements := []string{
+		// Tokens issued before rotation was introduced each form their own family
+		`UPDATE refresh_tokens SET family_id = token_hash WHERE family_id = ''`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_device_codes_expires ON device_codes(expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_logs_created ON audit_logs(created_at)`,
+		// The audit log is append-only
+		`CREATE TRIGGER IF NOT EXISTS audit_logs_no_update BEFORE UPDATE ON audit_logs
+		BEGIN SELECT RAISE(ABORT, 'audit_logs is append-only'); END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_logs_no_delete BEFORE DELETE ON audit_logs
+		BEGIN SELECT RAISE(ABORT, 'audit_logs is append-only'); END`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to run migration %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// columnExists reports whether the table has the column.
+func columnExists(db *sqlx.DB, table, column string) (bool, error) {
+	var names []string
+	if err := db.Select(&names, fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table)); err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	for _, name := range names {
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, nil
+}
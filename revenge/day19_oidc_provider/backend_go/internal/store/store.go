@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,6 +29,8 @@ type Storer interface {
 	GetSession(sessionID string) (*Session, error)
 	DeleteSession(sessionID string) error
 	UpdateSessionLastAccessed(sessionID string) error
+	ListSessionsByUser(userID string) ([]*Session, error)
+	DeleteSessionsByUser(userID string) (int64, error)
 
 	// Interaction methods
 	CreateInteraction(interaction *Interaction) error
@@ -43,8 +46,26 @@ type Storer interface {
 	// Grant methods
 	GetGrant(userID, clientID string) (*Grant, error)
 	CreateOrUpdateGrant(grant *Grant) error
-
-	// Refresh Token methods (Add later if needed)
+	ListGrantsByUser(userID string) ([]*Grant, error)
+	RevokeGrant(userID, clientID string) (bool, error)
+
+	// Refresh Token methods
+	CreateRefreshToken(token *RefreshToken) error
+	GetRefreshToken(tokenHash string) (*RefreshToken, error)
+	RotateRefreshToken(oldTokenHash string, newToken *RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+
+	// Device Code methods
+	CreateDeviceCode(deviceCode *DeviceCode) error
+	GetDeviceCode(deviceCode string) (*DeviceCode, error)
+	GetDeviceCodeByUserCode(userCode string) (*DeviceCode, error)
+	UpdateDeviceCodePolling(deviceCode string, pollInterval int, polledAt time.Time) error
+	UpdateDeviceCodeStatus(userCode, status, userID string) (bool, error)
+	DeleteDeviceCode(deviceCode string) error
+
+	// Audit Log methods
+	CreateAuditLog(entry *AuditLog) error
+	ListAuditLogs(filter AuditLogFilter) ([]*AuditLog, error)
 }
 
 // DBStore implements the Storer interface using sqlx.
@@ -64,6 +85,12 @@ func NewDBStore(dataSourceName string) (*DBStore, error) {
 	db.SetMaxIdleConns(25)
 	db.SetConnMaxLifetime(5 * time.Minute)
 
+	// Add columns introduced after db/schema.sql was applied to existing databases
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	return &DBStore{DB: db},
 		nil
 }
@@ -91,9 +118,12 @@ type Client struct {
 	Name         string    `db:"name"`
 	CreatedAt    time.Time `db:"created_at"`
 	UpdatedAt    time.Time `db:"updated_at"`
+	// Allowed post_logout_redirect_uri values for RP-Initiated Logout (JSON string)
+	PostLogoutRedirectURIs string `db:"post_logout_redirect_uris"`
 
 	// Parsed redirect URIs for easier use
-	ParsedRedirectURIs []string `db:"-"`
+	ParsedRedirectURIs           []string `db:"-"`
+	ParsedPostLogoutRedirectURIs []string `db:"-"`
 }
 
 type Session struct {
@@ -130,6 +160,39 @@ type AuthorizationCode struct {
 	CreatedAt           time.Time `db:"created_at"`
 }
 
+type RefreshToken struct {
+	TokenHash  string     `db:"token_hash"` // SHA-256 of the token (the raw token is never stored)
+	FamilyID   string     `db:"family_id"`  // Shared by all tokens rotated from the same authorization
+	ClientID   string     `db:"client_id"`
+	UserID     string     `db:"user_id"`
+	Scopes     string     `db:"scopes"` // Space-separated
+	ExpiresAt  time.Time  `db:"expires_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"` // Nullable
+	RevokedAt  *time.Time `db:"revoked_at"`   // Set when rotated or revoked
+	ReplacedBy *string    `db:"replaced_by"`  // Hash of the token issued by rotation
+}
+
+// Device code statuses
+const (
+	DeviceCodeStatusPending  = "pending"
+	DeviceCodeStatusApproved = "approved"
+	DeviceCodeStatusDenied   = "denied"
+)
+
+type DeviceCode struct {
+	DeviceCode   string     `db:"device_code"`
+	UserCode     string     `db:"user_code"` // Normalized (uppercase, without hyphen)
+	ClientID     string     `db:"client_id"`
+	Scopes       string     `db:"scopes"` // Space-separated
+	Status       string     `db:"status"`
+	UserID       *string    `db:"user_id"`       // Set when approved or denied
+	PollInterval int        `db:"poll_interval"` // Seconds
+	LastPolledAt *time.Time `db:"last_polled_at"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
 type Grant struct {
 	ID        string     `db:"id"`
 	UserID    string     `db:"user_id"`
@@ -139,6 +202,38 @@ type Grant struct {
 	ExpiresAt *time.Time `db:"expires_at"` // Nullable
 }
 
+// Audit log outcomes
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditLog is an entry of the append-only audit log.
+type AuditLog struct {
+	ID         int64     `db:"id"`
+	EventType  string    `db:"event_type"` // e.g. "authorize", "token", "admin.session.delete"
+	Actor      string    `db:"actor"`      // "user:<id>", "client:<id>", "admin" or "anonymous"
+	ClientID   string    `db:"client_id"`
+	IPAddress  string    `db:"ip_address"`
+	Method     string    `db:"method"`
+	Path       string    `db:"path"` // Query string is not recorded (it may contain codes or tokens)
+	StatusCode int       `db:"status_code"`
+	Outcome    string    `db:"outcome"`
+	Detail     string    `db:"detail"` // e.g. grant type or error code
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// AuditLogFilter narrows down ListAuditLogs. Zero values mean no filter.
+type AuditLogFilter struct {
+	From      *time.Time // Inclusive
+	To        *time.Time // Exclusive
+	EventType string
+	Actor     string
+	ClientID  string
+	Outcome   string
+	Limit     int
+}
+
 // --- User Methods ---
 
 func (s *DBStore) GetUserByID(id string) (*User, error) {
@@ -191,6 +286,9 @@ func (s *DBStore) GetClient(clientID string) (*Client, error) {
 	if err := json.Unmarshal([]byte(client.RedirectURIs), &client.ParsedRedirectURIs); err != nil {
 		return nil, fmt.Errorf("failed to parse client redirect URIs: %w", err)
 	}
+	if err := json.Unmarshal([]byte(client.PostLogoutRedirectURIs), &client.ParsedPostLogoutRedirectURIs); err != nil {
+		return nil, fmt.Errorf("failed to parse client post logout redirect URIs: %w", err)
+	}
 
 	return client, nil
 }
@@ -203,8 +301,15 @@ func (s *DBStore) CreateClient(client *Client) error {
 		return fmt.Errorf("invalid redirect_uris format for client %s: must be a JSON array string", client.ID)
 	}
 
-	query := `INSERT INTO clients (id, secret_hash, redirect_uris, name, created_at, updated_at)
-              VALUES (:id, :secret_hash, :redirect_uris, :name, :created_at, :updated_at)`
+	if client.PostLogoutRedirectURIs == "" {
+		client.PostLogoutRedirectURIs = "[]"
+	}
+	if err := json.Unmarshal([]byte(client.PostLogoutRedirectURIs), &js); err != nil {
+		return fmt.Errorf("invalid post_logout_redirect_uris format for client %s: must be a JSON array string", client.ID)
+	}
+
+	query := `INSERT INTO clients (id, secret_hash, redirect_uris, name, created_at, updated_at, post_logout_redirect_uris)
+              VALUES (:id, :secret_hash, :redirect_uris, :name, :created_at, :updated_at, :post_logout_redirect_uris)`
 	_, err := s.DB.NamedExec(query, client)
 	if err != nil {
 		return fmt.Errorf("failed to create client %s: %w", client.ID, err)
@@ -224,12 +329,13 @@ func (s *DBStore) CreateSession(session *Session) error {
 	return nil
 }
 
+// GetSession returns the session, or nil if it does not exist (e.g. after logout).
 func (s *DBStore) GetSession(sessionID string) (*Session, error) {
 	session := &Session{}
 	err := s.DB.Get(session, "SELECT * FROM sessions WHERE id = ?", sessionID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("session not found")
+			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
@@ -254,6 +360,25 @@ func (s *DBStore) UpdateSessionLastAccessed(sessionID string) error {
 	return nil
 }
 
+// ListSessionsByUser returns the unexpired sessions of a user, most recently used first.
+func (s *DBStore) ListSessionsByUser(userID string) ([]*Session, error) {
+	sessions := []*Session{}
+	query := `SELECT * FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY last_accessed_at DESC`
+	if err := s.DB.Select(&sessions, query, userID, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// DeleteSessionsByUser deletes every session of a user and returns how many were deleted.
+func (s *DBStore) DeleteSessionsByUser(userID string) (int64, error) {
+	result, err := s.DB.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // --- Interaction Methods ---
 
 func (s *DBStore) CreateInteraction(interaction *Interaction) error {
@@ -365,16 +490,300 @@ func (s *DBStore) GetGrant(userID, clientID string) (*Grant, error) {
 	return grant, nil
 }
 
-// CreateOrUpdateGrant creates a new grant or updates an existing one.
+// CreateOrUpdateGrant creates a new grant or adds the granted scopes to an existing one.
+// Scopes granted earlier are kept, so consenting to a different set of scopes later does not
+// make the user consent to the earlier ones again.
 func (s *DBStore) CreateOrUpdateGrant(grant *Grant) error {
-	// Use REPLACE INTO (SQLite specific) or implement UPSERT logic
-	// For simplicity, using REPLACE INTO
-	grant.ID = uuid.NewString() // Ensure ID is set for potential insertion
-	query := `REPLACE INTO grants (id, user_id, client_id, scopes, created_at, expires_at)
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing := &Grant{}
+	err = tx.Get(existing, "SELECT * FROM grants WHERE user_id = ? AND client_id = ?", grant.UserID, grant.ClientID)
+	switch {
+	case err == sql.ErrNoRows:
+		if grant.ID == "" {
+			grant.ID = uuid.NewString()
+		}
+		if grant.CreatedAt.IsZero() {
+			grant.CreatedAt = time.Now()
+		}
+		query := `INSERT INTO grants (id, user_id, client_id, scopes, created_at, expires_at)
               VALUES (:id, :user_id, :client_id, :scopes, :created_at, :expires_at)`
-	_, err := s.DB.NamedExec(query, grant)
+		if _, err := tx.NamedExec(query, grant); err != nil {
+			return fmt.Errorf("failed to create grant: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to get grant: %w", err)
+	default:
+		// 期限切れの同意は引き継がない
+		scopes := grant.Scopes
+		if existing.ExpiresAt == nil || time.Now().Before(*existing.ExpiresAt) {
+			scopes = mergeScopes(existing.Scopes, grant.Scopes)
+		}
+		_, err := tx.Exec("UPDATE grants SET scopes = ?, expires_at = ? WHERE id = ?", scopes, grant.ExpiresAt, existing.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update grant: %w", err)
+		}
+		grant.ID = existing.ID
+		grant.Scopes = scopes
+		grant.CreatedAt = existing.CreatedAt
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit grant: %w", err)
+	}
+	return nil
+}
+
+// ListGrantsByUser returns the unexpired grants of a user, newest first.
+func (s *DBStore) ListGrantsByUser(userID string) ([]*Grant, error) {
+	grants := []*Grant{}
+	query := `SELECT * FROM grants WHERE user_id = ? AND (expires_at IS NULL OR expires_at > ?) ORDER BY created_at DESC`
+	if err := s.DB.Select(&grants, query, userID, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to list grants: %w", err)
+	}
+	return grants, nil
+}
+
+// RevokeGrant deletes the grant of a user to a client, revokes the refresh tokens issued
+// to the client for the user and deletes unused authorization codes.
+// It returns false if there was no grant.
+func (s *DBStore) RevokeGrant(userID, clientID string) (bool, error) {
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM grants WHERE user_id = ? AND client_id = ?", userID, clientID)
 	if err != nil {
-		return fmt.Errorf("failed to create or update grant: %w", err)
+		return false, fmt.Errorf("failed to delete grant: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	query := `UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND client_id = ? AND revoked_at IS NULL`
+	if _, err := tx.Exec(query, time.Now(), userID, clientID); err != nil {
+		return false, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM authorization_codes WHERE user_id = ? AND client_id = ?", userID, clientID); err != nil {
+		return false, fmt.Errorf("failed to delete authorization codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit grant revocation: %w", err)
+	}
+	return true, nil
+}
+
+// mergeScopes returns the space-separated union of two scope strings, keeping the order of a.
+func mergeScopes(a, b string) string {
+	scopes := strings.Fields(a)
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		seen[scope] = true
+	}
+	for _, scope := range strings.Fields(b) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+// --- Refresh Token Methods ---
+
+func (s *DBStore) CreateRefreshToken(token *RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (token_hash, family_id, client_id, user_id, scopes, expires_at, created_at)
+              VALUES (:token_hash, :family_id, :client_id, :user_id, :scopes, :expires_at, :created_at)`
+	_, err := s.DB.NamedExec(query, token)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
 	}
 	return nil
 }
+
+// GetRefreshToken returns the refresh token including rotated/revoked ones (needed for reuse detection).
+func (s *DBStore) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	token := &RefreshToken{}
+	err := s.DB.Get(token, "SELECT * FROM refresh_tokens WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found is not an error here
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// RotateRefreshToken marks the old token as used and stores its successor in a single transaction.
+// It returns false if the old token had already been rotated or revoked (e.g. a concurrent reuse).
+func (s *DBStore) RotateRefreshToken(oldTokenHash string, newToken *RefreshToken) (bool, error) {
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = ?, last_used_at = ?, replaced_by = ?
+              WHERE token_hash = ? AND revoked_at IS NULL`, now, now, newToken.TokenHash, oldTokenHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	query := `INSERT INTO refresh_tokens (token_hash, family_id, client_id, user_id, scopes, expires_at, created_at)
+              VALUES (:token_hash, :family_id, :client_id, :user_id, :scopes, :expires_at, :created_at)`
+	if _, err := tx.NamedExec(query, newToken); err != nil {
+		return false, fmt.Errorf("failed to create rotated refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return true, nil
+}
+
+// RevokeRefreshTokenFamily revokes every active token in the family.
+func (s *DBStore) RevokeRefreshTokenFamily(familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`
+	_, err := s.DB.Exec(query, time.Now(), familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+// --- Device Code Methods ---
+
+func (s *DBStore) CreateDeviceCode(deviceCode *DeviceCode) error {
+	query := `INSERT INTO device_codes (device_code, user_code, client_id, scopes, status, poll_interval, expires_at, created_at)
+              VALUES (:device_code, :user_code, :client_id, :scopes, :status, :poll_interval, :expires_at, :created_at)`
+	_, err := s.DB.NamedExec(query, deviceCode)
+	if err != nil {
+		return fmt.Errorf("failed to create device code: %w", err)
+	}
+	return nil
+}
+
+// GetDeviceCode returns the device code including expired ones, so the caller can answer expired_token.
+func (s *DBStore) GetDeviceCode(deviceCode string) (*DeviceCode, error) {
+	dc := &DeviceCode{}
+	err := s.DB.Get(dc, "SELECT * FROM device_codes WHERE device_code = ?", deviceCode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found is not an error here
+		}
+		return nil, fmt.Errorf("failed to get device code: %w", err)
+	}
+	return dc, nil
+}
+
+func (s *DBStore) GetDeviceCodeByUserCode(userCode string) (*DeviceCode, error) {
+	dc := &DeviceCode{}
+	err := s.DB.Get(dc, "SELECT * FROM device_codes WHERE user_code = ?", userCode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found is not an error here
+		}
+		return nil, fmt.Errorf("failed to get device code by user code: %w", err)
+	}
+	return dc, nil
+}
+
+func (s *DBStore) UpdateDeviceCodePolling(deviceCode string, pollInterval int, polledAt time.Time) error {
+	query := `UPDATE device_codes SET poll_interval = ?, last_polled_at = ? WHERE device_code = ?`
+	_, err := s.DB.Exec(query, pollInterval, polledAt, deviceCode)
+	if err != nil {
+		return fmt.Errorf("failed to update device code polling: %w", err)
+	}
+	return nil
+}
+
+// UpdateDeviceCodeStatus records the user's decision. It returns false if the code is no longer pending.
+func (s *DBStore) UpdateDeviceCodeStatus(userCode, status, userID string) (bool, error) {
+	query := `UPDATE device_codes SET status = ?, user_id = ? WHERE user_code = ? AND status = ? AND expires_at > ?`
+	result, err := s.DB.Exec(query, status, userID, userCode, DeviceCodeStatusPending, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to update device code status: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update device code status: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *DBStore) DeleteDeviceCode(deviceCode string) error {
+	query := `DELETE FROM device_codes WHERE device_code = ?`
+	_, err := s.DB.Exec(query, deviceCode)
+	if err != nil {
+		return fmt.Errorf("failed to delete device code: %w", err)
+	}
+	return nil
+}
+
+// --- Audit Log Methods ---
+
+func (s *DBStore) CreateAuditLog(entry *AuditLog) error {
+	query := `INSERT INTO audit_logs (event_type, actor, client_id, ip_address, method, path, status_code, outcome, detail, created_at)
+              VALUES (:event_type, :actor, :client_id, :ip_address, :method, :path, :status_code, :outcome, :detail, :created_at)`
+	result, err := s.DB.NamedExec(query, entry)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		entry.ID = id
+	}
+	return nil
+}
+
+// ListAuditLogs returns the audit log entries matching the filter, newest first.
+func (s *DBStore) ListAuditLogs(filter AuditLogFilter) ([]*AuditLog, error) {
+	var conditions []string
+	var args []interface{}
+	// created_at は小数秒の桁数が揃わない文字列で保存されるため、julianday で比較する
+	if filter.From != nil {
+		conditions = append(conditions, "julianday(created_at) >= julianday(?)")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "julianday(created_at) < julianday(?)")
+		args = append(args, filter.To.UTC())
+	}
+	for _, c := range []struct{ column, value string }{
+		{"event_type", filter.EventType},
+		{"actor", filter.Actor},
+		{"client_id", filter.ClientID},
+		{"outcome", filter.Outcome},
+	} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+
+	query := `SELECT * FROM audit_logs`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	logs := []*AuditLog{}
+	if err := s.DB.Select(&logs, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	return logs, nil
+}
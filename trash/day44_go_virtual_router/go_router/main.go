@@ -2,96 +2,22 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
-	"sync"
+	"strconv"
+	"strings"
 
-	"github.com/gorilla/websocket"
 	"github.com/lirlia/100day_challenge_backend/day44_go_virtual_router/go_router/router"
+	"github.com/lirlia/100day_challenge_backend/day44_go_virtual_router/go_router/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for simplicity
-	},
-}
-
 // global router manager instance
 var manager *router.RouterManager
 
-// WebSocket client management
-var (
-	clients   = make(map[*websocket.Conn]bool)
-	clientsMu sync.Mutex
-	// broadcast channel is now part of RouterManager, this can be removed or repurposed if needed
-	// broadcast = make(chan []byte) // Channel for broadcasting messages to clients
-)
-
-func handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		log.Println("Upgrade error:", err)
-		return
-	}
-	defer conn.Close()
-
-	clientsMu.Lock()
-	clients[conn] = true
-	clientsMu.Unlock()
-	log.Println("WebSocket client connected. Total clients:", len(clients))
-
-	defer func() {
-		clientsMu.Lock()
-		delete(clients, conn)
-		clientsMu.Unlock()
-		log.Println("WebSocket client disconnected. Total clients:", len(clients))
-	}()
-
-	// Send initial data or welcome message if needed
-	// conn.WriteMessage(websocket.TextMessage, []byte("Welcome to Go Virtual Router WebSocket API"))
-
-	for {
-		messageType, p, err := conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket read error: %v", err)
-			}
-			break
-		}
-		log.Printf("Received WebSocket message type %d: %s\n", messageType, p)
-
-		// Handle incoming messages from client (e.g., requests for data)
-		// For now, just echo back
-		if err := conn.WriteMessage(messageType, append([]byte("Echo: "), p...)); err != nil {
-			log.Println("WebSocket write error:", err)
-			break
-		}
-	}
-}
-
-func handleBroadcastMessages(broadcastInChan <-chan map[string]interface{}) {
-	for {
-		msgMap := <-broadcastInChan           // Receive map from RouterManager
-		msgBytes, err := json.Marshal(msgMap) // Marshal to JSON bytes
-		if err != nil {
-			log.Printf("Error marshaling broadcast message: %v. Message: %+v", err, msgMap)
-			continue
-		}
-
-		clientsMu.Lock()
-		for client := range clients {
-			if err := client.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
-				log.Printf("Broadcast error to client %s: %v", client.RemoteAddr(), err)
-				// Optionally remove unresponsive client
-				// client.Close()
-				// delete(clients, client)
-			}
-		}
-		clientsMu.Unlock()
-	}
-}
-
 type CreateRouterRequest struct {
 	ID      string `json:"id"`
 	TunName string `json:"tunName"` // e.g., "tun0"
@@ -145,6 +71,7 @@ func handleRoutersAPI(w http.ResponseWriter, r *http.Request) {
 			TunName:   createdRouter.TunDevice.GetName(),
 			IPAddress: createdRouter.TunDevice.GetIP().String(),
 			NumRoutes: len(createdRouter.GetRoutingTable()),
+			NAT:       createdRouter.GetNATStats(),
 		}
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(createdRouterInfo)
@@ -171,6 +98,37 @@ func handleSpecificRouterAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Path: /api/routers/{routerId}/routes
+	if id, ok := strings.CutSuffix(routerId, "/routes"); ok {
+		handleRouterRoutesAPI(w, r, id)
+		return
+	}
+	// Path: /api/routers/{routerId}/acl
+	if id, ok := strings.CutSuffix(routerId, "/acl"); ok {
+		handleRouterACLAPI(w, r, id)
+		return
+	}
+	// Path: /api/routers/{routerId}/nat
+	if id, ok := strings.CutSuffix(routerId, "/nat"); ok {
+		handleRouterNATAPI(w, r, id)
+		return
+	}
+
+	if id, ok := strings.CutSuffix(routerId, "/interfaces"); ok {
+		handleRouterInterfacesAPI(w, r, id)
+		return
+	}
+	// Path: /api/routers/{routerId}/dhcp/leases
+	if id, ok := strings.CutSuffix(routerId, "/dhcp/leases"); ok {
+		handleRouterDHCPLeasesAPI(w, r, id)
+		return
+	}
+	// Path: /api/routers/{routerId}/dhcp
+	if id, ok := strings.CutSuffix(routerId, "/dhcp"); ok {
+		handleRouterDHCPAPI(w, r, id)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodDelete:
 		err := manager.StopAndRemoveRouter(routerId)
@@ -209,6 +167,344 @@ func handleSpecificRouterAPI(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// StaticRouteRequest is the request body for adding or removing a static route
+type StaticRouteRequest struct {
+	Destination string `json:"destination"` // e.g., "192.168.10.0/24"
+	NextHop     string `json:"nextHop"`     // e.g., "10.0.2.1"
+	Metric      int    `json:"metric"`
+}
+
+// handleRouterRoutesAPI handles GET/POST/DELETE /api/routers/{routerId}/routes
+func handleRouterRoutesAPI(w http.ResponseWriter, r *http.Request, routerId string) {
+	switch r.Method {
+	case http.MethodGet:
+		rt, exists := manager.GetRouter(routerId)
+		if !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rt.GetRoutingTable())
+
+	case http.MethodPost:
+		var req StaticRouteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Destination == "" || req.NextHop == "" {
+			http.Error(w, "destination and nextHop are required", http.StatusBadRequest)
+			return
+		}
+		if _, exists := manager.GetRouter(routerId); !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+
+		route, err := manager.AddStaticRoute(routerId, req.Destination, req.NextHop, req.Metric)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to add static route: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("API: Static route %s via %s added to router %s", route.Network, route.NextHop, routerId)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(route)
+
+	case http.MethodDelete:
+		// The destination can be given as a query parameter (?destination=192.168.10.0/24) or in the JSON body
+		destination := r.URL.Query().Get("destination")
+		if destination == "" {
+			var req StaticRouteRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			destination = req.Destination
+		}
+		if destination == "" {
+			http.Error(w, "destination is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := manager.RemoveStaticRoute(routerId, destination); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to remove static route: %v", err), http.StatusNotFound)
+			return
+		}
+		log.Printf("API: Static route %s removed from router %s", destination, routerId)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Static route %s removed from router %s", destination, routerId)
+
+	default:
+		http.Error(w, "Method not allowed for routes", http.StatusMethodNotAllowed)
+	}
+}
+
+// AddACLRuleRequest is the request body for adding an ACL rule
+type AddACLRuleRequest struct {
+	router.ACLRule
+	Position int `json:"position"` // 1-based insert position, 0 = append
+}
+
+// handleRouterACLAPI handles GET/POST/DELETE /api/routers/{routerId}/acl
+func handleRouterACLAPI(w http.ResponseWriter, r *http.Request, routerId string) {
+	switch r.Method {
+	case http.MethodGet:
+		rt, exists := manager.GetRouter(routerId)
+		if !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rt.GetACLRules())
+
+	case http.MethodPost:
+		var req AddACLRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if _, exists := manager.GetRouter(routerId); !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		rule, err := manager.AddACLRule(routerId, req.ACLRule, req.Position)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to add ACL rule: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("API: ACL rule %d added to router %s", rule.ID, routerId)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(rule)
+
+	case http.MethodDelete:
+		// Path: /api/routers/{routerId}/acl?id={ruleId}
+		ruleId, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "id query parameter is required", http.StatusBadRequest)
+			return
+		}
+		if err := manager.RemoveACLRule(routerId, ruleId); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to remove ACL rule: %v", err), http.StatusNotFound)
+			return
+		}
+		log.Printf("API: ACL rule %d removed from router %s", ruleId, routerId)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "ACL rule %d removed from router %s", ruleId, routerId)
+
+	default:
+		http.Error(w, "Method not allowed for ACL", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRouterInterfacesAPI handles GET/POST/DELETE /api/routers/{routerId}/interfaces
+func handleRouterInterfacesAPI(w http.ResponseWriter, r *http.Request, routerId string) {
+	switch r.Method {
+	case http.MethodGet:
+		rt, exists := manager.GetRouter(routerId)
+		if !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rt.GetInterfaces())
+
+	case http.MethodPost:
+		var req router.InterfaceConfig
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if _, exists := manager.GetRouter(routerId); !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		added, err := manager.AddInterface(routerId, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to add interface: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("API: Interface %s (%s) added to router %s", added.Name, added.IPCIDR, routerId)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(added)
+
+	case http.MethodDelete:
+		// Path: /api/routers/{routerId}/interfaces?name={interfaceName}
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "name query parameter is required", http.StatusBadRequest)
+			return
+		}
+		if err := manager.RemoveInterface(routerId, name); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to remove interface: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("API: Interface %s removed from router %s", name, routerId)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "Interface %s removed from router %s", name, routerId)
+
+	default:
+		http.Error(w, "Method not allowed for interfaces", http.StatusMethodNotAllowed)
+	}
+}
+
+// NATStatusResponse is the response of GET /api/routers/{routerId}/nat
+type NATStatusResponse struct {
+	Config       router.NATConfig        `json:"config"`
+	Stats        router.NATStats         `json:"stats"`
+	Translations []router.NATTranslation `json:"translations"`
+}
+
+// handleRouterNATAPI handles GET/PUT /api/routers/{routerId}/nat
+func handleRouterNATAPI(w http.ResponseWriter, r *http.Request, routerId string) {
+	switch r.Method {
+	case http.MethodGet:
+		rt, exists := manager.GetRouter(routerId)
+		if !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(NATStatusResponse{
+			Config:       rt.GetNATConfig(),
+			Stats:        rt.GetNATStats(),
+			Translations: rt.GetNATTranslations(),
+		})
+
+	case http.MethodPut:
+		var req router.NATConfig
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if _, exists := manager.GetRouter(routerId); !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		if err := manager.SetNATConfig(routerId, req); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update NAT config: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("API: NAT config of router %s updated: %+v", routerId, req)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(req)
+
+	default:
+		http.Error(w, "Method not allowed for NAT", http.StatusMethodNotAllowed)
+	}
+}
+
+// DHCPStatusResponse is the response of GET /api/routers/{routerId}/dhcp
+type DHCPStatusResponse struct {
+	Servers []router.DHCPConfig `json:"servers"`
+	Leases  []router.DHCPLease  `json:"leases"`
+}
+
+// handleRouterDHCPAPI handles GET/PUT /api/routers/{routerId}/dhcp
+func handleRouterDHCPAPI(w http.ResponseWriter, r *http.Request, routerId string) {
+	switch r.Method {
+	case http.MethodGet:
+		rt, exists := manager.GetRouter(routerId)
+		if !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(DHCPStatusResponse{
+			Servers: rt.GetDHCPConfigs(),
+			Leases:  rt.GetDHCPLeases(),
+		})
+
+	case http.MethodPut:
+		var req router.DHCPConfig
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if _, exists := manager.GetRouter(routerId); !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		applied, err := manager.SetDHCPConfig(routerId, req)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update DHCP config: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("API: DHCP config of router %s updated: %+v", routerId, applied)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(applied)
+
+	default:
+		http.Error(w, "Method not allowed for DHCP", http.StatusMethodNotAllowed)
+	}
+}
+
+// DHCPLeaseRequest is the request body of POST /api/routers/{routerId}/dhcp/leases, sent on behalf of a simulated host
+type DHCPLeaseRequest struct {
+	Interface string `json:"interface"`
+	MAC       string `json:"mac"`
+	Hostname  string `json:"hostname,omitempty"`
+}
+
+// handleRouterDHCPLeasesAPI handles GET/POST/DELETE /api/routers/{routerId}/dhcp/leases
+func handleRouterDHCPLeasesAPI(w http.ResponseWriter, r *http.Request, routerId string) {
+	switch r.Method {
+	case http.MethodGet:
+		rt, exists := manager.GetRouter(routerId)
+		if !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rt.GetDHCPLeases())
+
+	case http.MethodPost:
+		var req DHCPLeaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Interface == "" || req.MAC == "" {
+			http.Error(w, "interface and mac are required", http.StatusBadRequest)
+			return
+		}
+		if _, exists := manager.GetRouter(routerId); !exists {
+			http.Error(w, fmt.Sprintf("Router with ID %s not found", routerId), http.StatusNotFound)
+			return
+		}
+		lease, err := manager.RequestDHCPLease(routerId, req.Interface, req.MAC, req.Hostname)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to lease an address: %v", err), http.StatusBadRequest)
+			return
+		}
+		log.Printf("API: DHCP lease %s granted to %s on router %s", lease.IP, lease.MAC, routerId)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(lease)
+
+	case http.MethodDelete:
+		// Path: /api/routers/{routerId}/dhcp/leases?interface={interfaceName}&mac={mac}
+		iface := r.URL.Query().Get("interface")
+		mac := r.URL.Query().Get("mac")
+		if iface == "" || mac == "" {
+			http.Error(w, "interface and mac query parameters are required", http.StatusBadRequest)
+			return
+		}
+		if err := manager.ReleaseDHCPLease(routerId, iface, mac); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to release lease: %v", err), http.StatusNotFound)
+			return
+		}
+		log.Printf("API: DHCP lease of %s on %s released from router %s", mac, iface, routerId)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "DHCP lease of %s on %s released from router %s", mac, iface, routerId)
+
+	default:
+		http.Error(w, "Method not allowed for DHCP leases", http.StatusMethodNotAllowed)
+	}
+}
+
 // handleConnectionsAPI handles requests for managing router connections
 type CreateConnectionRequest struct {
 	Router1ID string `json:"router1Id"`
@@ -273,6 +569,33 @@ func handleSpecificConnectionAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	switch r.Method {
+	case http.MethodGet:
+		conn, exists := manager.GetConnection(connectionId)
+		if !exists {
+			http.Error(w, fmt.Sprintf("Connection %s not found", connectionId), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conn)
+	case http.MethodPut:
+		var impairment router.LinkImpairment
+		if err := json.NewDecoder(r.Body).Decode(&impairment); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+		conn, err := manager.SetConnectionImpairment(connectionId, impairment)
+		if err != nil {
+			status := http.StatusBadRequest
+			if _, exists := manager.GetConnection(connectionId); !exists {
+				status = http.StatusNotFound
+			}
+			http.Error(w, fmt.Sprintf("Failed to update connection %s: %v", connectionId, err), status)
+			return
+		}
+		log.Printf("API: Connection %s impairment updated", connectionId)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(conn)
 	case http.MethodDelete:
 		err := manager.RemoveConnection(connectionId)
 		if err != nil {
@@ -287,12 +610,90 @@ func handleSpecificConnectionAPI(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func main() {
-	// Create the broadcast channel that RouterManager will use
-	managerBroadcastChan := make(chan map[string]interface{}, 100) // Buffered channel
-	manager = router.NewRouterManager(managerBroadcastChan)
+// PingRequest is the request body for POST /api/tools/ping
+type PingRequest struct {
+	Source      string `json:"source"`      // Router ID to send from
+	Destination string `json:"destination"` // IPv4 address or router ID
+	Count       int    `json:"count"`       // Number of echo requests (default 4)
+}
+
+// TracerouteRequest is the request body for POST /api/tools/traceroute
+type TracerouteRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	MaxHops     int    `json:"maxHops"` // Default 30
+}
+
+// handlePingAPI runs a simulated ping. Each reply is also streamed to WebSocket clients as PING_REPLY.
+func handlePingAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
-	// ★ 起動時にルータ2台を自動生成し接続
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed for ping", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Source == "" || req.Destination == "" {
+		http.Error(w, "source and destination are required", http.StatusBadRequest)
+		return
+	}
+
+	summary, err := manager.Ping(req.Source, req.Destination, req.Count)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ping failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// handleTracerouteAPI runs a simulated traceroute. Each hop is also streamed to WebSocket clients as TRACEROUTE_HOP.
+func handleTracerouteAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed for traceroute", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TracerouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Source == "" || req.Destination == "" {
+		http.Error(w, "source and destination are required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := manager.Traceroute(req.Source, req.Destination, req.MaxHops)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Traceroute failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// createDefaultTopology creates two connected routers (used when there is no saved topology)
+func createDefaultTopology() {
 	r1, err := manager.CreateAndStartRouter("router1", "utun10", "10.0.1.1/24", 1500)
 	if err != nil {
 		log.Fatal("Failed to create router1: ", err)
@@ -305,15 +706,62 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to connect router1 and router2: ", err)
 	}
+}
 
-	// Start broadcast handler for WebSockets, passing the manager's output channel
+func main() {
+	dbPath := flag.String("db", "data/topology.db", "SQLite file the topology is saved to")
+	fresh := flag.Bool("fresh", false, "Start with the default topology instead of restoring the saved one (the saved one is overwritten)")
+	flag.Parse()
+
+	// Create the broadcast channel that RouterManager will use
+	managerBroadcastChan := make(chan map[string]interface{}, 100) // Buffered channel
+	manager = router.NewRouterManager(managerBroadcastChan)
+
+	// Start broadcast handler for WebSockets, passing the manager's output channel.
+	// Started before the topology is built so that restoring many routers cannot fill the channel.
 	go handleBroadcastMessages(managerBroadcastChan)
 
+	topologyStore, err := store.Open(*dbPath)
+	if err != nil {
+		log.Fatal("Failed to open topology database: ", err)
+	}
+	defer topologyStore.Close()
+
+	// ★ 保存されたトポロジーを復元する。保存されていない (または --fresh) 場合はルータ2台を自動生成し接続
+	restored := false
+	if !*fresh {
+		topology, err := topologyStore.Load()
+		if err != nil {
+			log.Fatal("Failed to load topology: ", err)
+		}
+		if len(topology.Routers) > 0 {
+			if err := manager.RestoreTopology(topology); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			restored = true
+		}
+	}
+	if !restored {
+		createDefaultTopology()
+	}
+	// Save every change from now on (restoring must not overwrite the saved topology halfway).
+	// A restored topology is left as saved, so routers that failed to restore are kept until the next change.
+	manager.SetStore(topologyStore)
+	if !restored {
+		manager.SaveTopology()
+	}
+
 	http.HandleFunc("/ws", handleWebSocket)
 	http.HandleFunc("/api/routers", handleRoutersAPI)
 	http.HandleFunc("/api/routers/", handleSpecificRouterAPI) // Trailing slash to catch /api/routers/{id}
 	http.HandleFunc("/api/connections", handleConnectionsAPI)
 	http.HandleFunc("/api/connections/", handleSpecificConnectionAPI) // Trailing slash for /api/connections/{id}
+	http.HandleFunc("/api/tools/ping", handlePingAPI)
+	http.HandleFunc("/api/tools/traceroute", handleTracerouteAPI)
+
+	// Prometheus metrics (per-interface counters and forwarding latency, plus the Go runtime)
+	prometheus.MustRegister(&routerCollector{manager: manager})
+	http.Handle("/metrics", promhttp.Handler())
 
 	port := ":8080"
 	log.Printf("Go virtual router server starting on port %s", port)
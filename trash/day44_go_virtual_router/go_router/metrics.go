@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/lirlia/100day_challenge_backend/day44_go_virtual_router/go_router/router"
+)
+
+// Prometheus metrics
+//
+// routerCollector reads the counters of every router when /metrics is scraped, so routers and interfaces
+// that are removed simply disappear from the next scrape.
+
+var (
+	interfaceLabels = []string{"router", "interface"}
+
+	rxPacketsDesc = prometheus.NewDesc("vrouter_interface_receive_packets_total",
+		"Packets received on the interface.", interfaceLabels, nil)
+	rxBytesDesc = prometheus.NewDesc("vrouter_interface_receive_bytes_total",
+		"Bytes received on the interface.", interfaceLabels, nil)
+	txPacketsDesc = prometheus.NewDesc("vrouter_interface_transmit_packets_total",
+		"Packets sent on the interface.", interfaceLabels, nil)
+	txBytesDesc = prometheus.NewDesc("vrouter_interface_transmit_bytes_total",
+		"Bytes sent on the interface.", interfaceLabels, nil)
+	dropsDesc = prometheus.NewDesc("vrouter_interface_drops_total",
+		"Packets received on (or to be sent on) the interface that were discarded.", interfaceLabels, nil)
+	forwardingLatencyDesc = prometheus.NewDesc("vrouter_forwarding_latency_seconds",
+		"Time from receiving a packet to handing it to the next hop or the connected network.", []string{"router"}, nil)
+)
+
+// routerCollector exports the traffic counters of the RouterManager's routers.
+type routerCollector struct {
+	manager *router.RouterManager
+}
+
+func (c *routerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- rxPacketsDesc
+	ch <- rxBytesDesc
+	ch <- txPacketsDesc
+	ch <- txBytesDesc
+	ch <- dropsDesc
+	ch <- forwardingLatencyDesc
+}
+
+func (c *routerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, rm := range c.manager.GetAllMetrics() {
+		for name, counters := range rm.Interfaces {
+			ch <- prometheus.MustNewConstMetric(rxPacketsDesc, prometheus.CounterValue, float64(counters.RxPackets), rm.RouterID, name)
+			ch <- prometheus.MustNewConstMetric(rxBytesDesc, prometheus.CounterValue, float64(counters.RxBytes), rm.RouterID, name)
+			ch <- prometheus.MustNewConstMetric(txPacketsDesc, prometheus.CounterValue, float64(counters.TxPackets), rm.RouterID, name)
+			ch <- prometheus.MustNewConstMetric(txBytesDesc, prometheus.CounterValue, float64(counters.TxBytes), rm.RouterID, name)
+			ch <- prometheus.MustNewConstMetric(dropsDesc, prometheus.CounterValue, float64(counters.Drops), rm.RouterID, name)
+		}
+		latency := rm.ForwardingLatency
+		ch <- prometheus.MustNewConstHistogram(forwardingLatencyDesc, latency.Count, latency.Sum, latency.Buckets, rm.RouterID)
+	}
+}
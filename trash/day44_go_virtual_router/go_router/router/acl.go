@@ -0,0 +1,199 @@
+package router
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ACL (firewall) rules are evaluated in order for every packet the router forwards.
+// The first matching rule decides whether the packet is allowed or denied;
+// packets that match no rule are allowed.
+
+const (
+	ACLActionAllow = "allow"
+	ACLActionDeny  = "deny"
+
+	ACLHitsBroadcastInterval = 2 * time.Second // How often changed hit counters are broadcast over WebSocket
+)
+
+// ACLRule is a firewall rule. Empty fields (or 0 ports) match anything.
+type ACLRule struct {
+	ID       int    `json:"id"`
+	Action   string `json:"action"`             // "allow" or "deny"
+	Src      string `json:"src,omitempty"`      // Source CIDR
+	Dst      string `json:"dst,omitempty"`      // Destination CIDR
+	Protocol string `json:"protocol,omitempty"` // "tcp", "udp", "icmp", "any" or a protocol number
+	SrcPort  uint16 `json:"srcPort,omitempty"`  // TCP/UDP only
+	DstPort  uint16 `json:"dstPort,omitempty"`  // TCP/UDP only
+	Hits     uint64 `json:"hits"`
+
+	srcNet   *net.IPNet
+	dstNet   *net.IPNet
+	protocol int // -1 = any
+}
+
+// aclList is the ordered rule list of a router. The zero value is ready to use (no rules).
+type aclList struct {
+	mu          sync.Mutex
+	rules       []*ACLRule
+	nextID      int
+	hitsChanged bool // Set when a hit counter changed since the last takeACLHitsChanged
+}
+
+// compile validates the rule and fills in the parsed fields.
+func (rule *ACLRule) compile() error {
+	rule.Action = strings.ToLower(rule.Action)
+	if rule.Action != ACLActionAllow && rule.Action != ACLActionDeny {
+		return fmt.Errorf("invalid action %q (expected %s or %s)", rule.Action, ACLActionAllow, ACLActionDeny)
+	}
+	var err error
+	if rule.srcNet, err = parseACLNetwork(rule.Src); err != nil {
+		return err
+	}
+	if rule.dstNet, err = parseACLNetwork(rule.Dst); err != nil {
+		return err
+	}
+	if rule.srcNet != nil {
+		rule.Src = rule.srcNet.String()
+	}
+	if rule.dstNet != nil {
+		rule.Dst = rule.dstNet.String()
+	}
+
+	rule.Protocol = strings.ToLower(rule.Protocol)
+	switch rule.Protocol {
+	case "", "any":
+		rule.protocol = -1
+	case "tcp":
+		rule.protocol = TCPProtocolNumber
+	case "udp":
+		rule.protocol = UDPProtocolNumber
+	case "icmp":
+		rule.protocol = ICMPProtocolNumber
+	default:
+		n, err := strconv.Atoi(rule.Protocol)
+		if err != nil || n < 0 || n > 255 {
+			return fmt.Errorf("invalid protocol %q", rule.Protocol)
+		}
+		rule.protocol = n
+	}
+	if (rule.SrcPort != 0 || rule.DstPort != 0) && rule.protocol != TCPProtocolNumber && rule.protocol != UDPProtocolNumber {
+		return fmt.Errorf("ports can only be specified for tcp or udp rules")
+	}
+	return nil
+}
+
+func parseACLNetwork(cidr string) (*net.IPNet, error) {
+	if cidr == "" || cidr == "any" {
+		return nil, nil
+	}
+	if !strings.Contains(cidr, "/") {
+		cidr += "/32" // A single host
+	}
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	return ipNet, nil
+}
+
+// matches reports whether the packet matches the rule.
+func (rule *ACLRule) matches(hdr *IPv4Header, packet []byte) bool {
+	if rule.srcNet != nil && !rule.srcNet.Contains(hdr.SrcIP) {
+		return false
+	}
+	if rule.dstNet != nil && !rule.dstNet.Contains(hdr.DstIP) {
+		return false
+	}
+	if rule.protocol >= 0 && int(hdr.Protocol) != rule.protocol {
+		return false
+	}
+	if rule.SrcPort != 0 || rule.DstPort != 0 {
+		ihl := int(hdr.IHL) * 4
+		if len(packet) < ihl+4 {
+			return false
+		}
+		if rule.SrcPort != 0 && binary.BigEndian.Uint16(packet[ihl:ihl+2]) != rule.SrcPort {
+			return false
+		}
+		if rule.DstPort != 0 && binary.BigEndian.Uint16(packet[ihl+2:ihl+4]) != rule.DstPort {
+			return false
+		}
+	}
+	return true
+}
+
+// AddACLRule adds a rule at position (1-based); position <= 0 or past the end appends the rule.
+func (r *Router) AddACLRule(rule ACLRule, position int) (ACLRule, error) {
+	if err := rule.compile(); err != nil {
+		return ACLRule{}, err
+	}
+
+	r.acl.mu.Lock()
+	defer r.acl.mu.Unlock()
+	r.acl.nextID++
+	rule.ID = r.acl.nextID
+	rule.Hits = 0
+	newRule := &rule
+	if position <= 0 || position > len(r.acl.rules) {
+		r.acl.rules = append(r.acl.rules, newRule)
+	} else {
+		r.acl.rules = append(r.acl.rules[:position-1], append([]*ACLRule{newRule}, r.acl.rules[position-1:]...)...)
+	}
+	log.Printf("Router %s: Added ACL rule %d: %s src=%q dst=%q proto=%q srcPort=%d dstPort=%d", r.ID, rule.ID, rule.Action, rule.Src, rule.Dst, rule.Protocol, rule.SrcPort, rule.DstPort)
+	return rule, nil
+}
+
+// RemoveACLRule removes the rule with the given ID.
+func (r *Router) RemoveACLRule(id int) error {
+	r.acl.mu.Lock()
+	defer r.acl.mu.Unlock()
+	for i, rule := range r.acl.rules {
+		if rule.ID == id {
+			r.acl.rules = append(r.acl.rules[:i], r.acl.rules[i+1:]...)
+			log.Printf("Router %s: Removed ACL rule %d", r.ID, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("ACL rule %d not found on router %s", id, r.ID)
+}
+
+// GetACLRules returns a copy of the rules in evaluation order, including hit counters.
+func (r *Router) GetACLRules() []ACLRule {
+	r.acl.mu.Lock()
+	defer r.acl.mu.Unlock()
+	rules := make([]ACLRule, 0, len(r.acl.rules))
+	for _, rule := range r.acl.rules {
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+// checkACL evaluates the rules for a packet being forwarded and returns whether it is allowed.
+func (r *Router) checkACL(hdr *IPv4Header, packet []byte) bool {
+	r.acl.mu.Lock()
+	defer r.acl.mu.Unlock()
+	for _, rule := range r.acl.rules {
+		if rule.matches(hdr, packet) {
+			rule.Hits++
+			r.acl.hitsChanged = true
+			return rule.Action == ACLActionAllow
+		}
+	}
+	return true
+}
+
+// takeACLHitsChanged reports whether any hit counter changed since the last call.
+func (r *Router) takeACLHitsChanged() bool {
+	r.acl.mu.Lock()
+	defer r.acl.mu.Unlock()
+	changed := r.acl.hitsChanged
+	r.acl.hitsChanged = false
+	return changed
+}
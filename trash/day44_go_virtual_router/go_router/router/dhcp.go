@@ -0,0 +1,313 @@
+package router
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DHCP server simulation.
+// Every interface can run a DHCP server that leases addresses from the interface's subnet, with the interface
+// address as the default gateway. There are no real DHCP clients behind a TUN device, so hosts are simulated:
+// a host identified by its MAC address requests a lease through the API and the server answers as it would to a
+// DISCOVER/REQUEST exchange. A host asking again before its lease expires renews the same address.
+
+const (
+	DefaultDHCPLeaseSeconds = 3600
+	MinDHCPLeaseSeconds     = 10
+	DHCPExpiryInterval      = 5 * time.Second // How often expired leases are removed
+)
+
+// DHCPConfig is the DHCP server configuration of an interface.
+type DHCPConfig struct {
+	Interface    string `json:"interface"`
+	Enabled      bool   `json:"enabled"`
+	RangeStart   string `json:"rangeStart,omitempty"`   // First address to lease ("" = first host address of the subnet)
+	RangeEnd     string `json:"rangeEnd,omitempty"`     // Last address to lease ("" = last host address of the subnet)
+	LeaseSeconds int    `json:"leaseSeconds,omitempty"` // Lease duration (0 = DefaultDHCPLeaseSeconds)
+}
+
+// DHCPLease is an address leased to a simulated host.
+type DHCPLease struct {
+	Interface string    `json:"interface"`
+	MAC       string    `json:"mac"`
+	Hostname  string    `json:"hostname,omitempty"`
+	IP        string    `json:"ip"`
+	Network   string    `json:"network"`
+	Gateway   string    `json:"gateway"`
+	GrantedAt time.Time `json:"grantedAt"` // Last time the lease was granted or renewed
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// dhcpServer is the DHCP server of one interface.
+type dhcpServer struct {
+	config     DHCPConfig
+	network    *net.IPNet
+	gateway    net.IP
+	start, end uint32                // Address range as integers
+	leases     map[string]*DHCPLease // MAC -> lease
+}
+
+// dhcpState holds the DHCP servers of a router. The zero value is ready to use (no servers).
+type dhcpState struct {
+	mu      sync.Mutex
+	servers map[string]*dhcpServer // Interface name -> server
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
+
+// interfaceAddress returns the address and network of an interface (primary or additional).
+func (r *Router) interfaceAddress(name string) (net.IP, *net.IPNet, error) {
+	if r.TunDevice != nil && name == r.TunDevice.Name {
+		ip, network, err := net.ParseCIDR(r.config.TunIPAddress)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid address of interface %s: %w", name, err)
+		}
+		return ip, network, nil
+	}
+	r.ifaceMutex.RLock()
+	defer r.ifaceMutex.RUnlock()
+	ifc, ok := r.Interfaces[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("interface %s not found on router %s", name, r.ID)
+	}
+	return ifc.IP, ifc.Network, nil
+}
+
+// newDHCPServer validates the configuration against the interface's subnet.
+func (r *Router) newDHCPServer(config DHCPConfig) (*dhcpServer, error) {
+	gateway, network, err := r.interfaceAddress(config.Interface)
+	if err != nil {
+		return nil, err
+	}
+	if gateway.To4() == nil {
+		return nil, fmt.Errorf("DHCP is only supported on IPv4 interfaces")
+	}
+	if config.LeaseSeconds == 0 {
+		config.LeaseSeconds = DefaultDHCPLeaseSeconds
+	}
+	if config.LeaseSeconds < MinDHCPLeaseSeconds {
+		return nil, fmt.Errorf("leaseSeconds must be at least %d", MinDHCPLeaseSeconds)
+	}
+
+	// Host addresses exclude the network and broadcast addresses
+	ones, bits := network.Mask.Size()
+	if bits-ones < 2 {
+		return nil, fmt.Errorf("network %s of interface %s has no addresses to lease", network, config.Interface)
+	}
+	first := ipToUint32(network.IP) + 1
+	last := ipToUint32(network.IP) + uint32(1)<<(bits-ones) - 2
+
+	start, end := first, last
+	if config.RangeStart != "" {
+		ip := net.ParseIP(config.RangeStart)
+		if ip == nil || ip.To4() == nil || !network.Contains(ip) || ipToUint32(ip) < first || ipToUint32(ip) > last {
+			return nil, fmt.Errorf("rangeStart %s is not a host address of %s", config.RangeStart, network)
+		}
+		start = ipToUint32(ip)
+	}
+	if config.RangeEnd != "" {
+		ip := net.ParseIP(config.RangeEnd)
+		if ip == nil || ip.To4() == nil || !network.Contains(ip) || ipToUint32(ip) < first || ipToUint32(ip) > last {
+			return nil, fmt.Errorf("rangeEnd %s is not a host address of %s", config.RangeEnd, network)
+		}
+		end = ipToUint32(ip)
+	}
+	if start > end {
+		return nil, fmt.Errorf("rangeStart %s is after rangeEnd %s", uint32ToIP(start), uint32ToIP(end))
+	}
+	config.RangeStart = uint32ToIP(start).String()
+	config.RangeEnd = uint32ToIP(end).String()
+
+	return &dhcpServer{
+		config:  config,
+		network: network,
+		gateway: gateway.To4(),
+		start:   start,
+		end:     end,
+		leases:  make(map[string]*DHCPLease),
+	}, nil
+}
+
+// SetDHCPConfig enables, reconfigures or disables the DHCP server of an interface and returns the applied
+// configuration. Reconfiguring keeps the leases that are still inside the range; disabling removes all leases.
+func (r *Router) SetDHCPConfig(config DHCPConfig) (DHCPConfig, error) {
+	if !config.Enabled {
+		if _, _, err := r.interfaceAddress(config.Interface); err != nil {
+			return DHCPConfig{}, err
+		}
+		r.removeDHCPServer(config.Interface)
+		log.Printf("Router %s: DHCP server on %s disabled", r.ID, config.Interface)
+		return DHCPConfig{Interface: config.Interface}, nil
+	}
+
+	server, err := r.newDHCPServer(config)
+	if err != nil {
+		return DHCPConfig{}, err
+	}
+
+	r.dhcp.mu.Lock()
+	defer r.dhcp.mu.Unlock()
+	if r.dhcp.servers == nil {
+		r.dhcp.servers = make(map[string]*dhcpServer)
+	}
+	if old, ok := r.dhcp.servers[config.Interface]; ok {
+		for mac, lease := range old.leases {
+			if n := ipToUint32(net.ParseIP(lease.IP)); n >= server.start && n <= server.end {
+				server.leases[mac] = lease
+			}
+		}
+	}
+	r.dhcp.servers[config.Interface] = server
+	log.Printf("Router %s: DHCP server on %s enabled (range %s-%s, lease %ds)", r.ID, config.Interface, server.config.RangeStart, server.config.RangeEnd, server.config.LeaseSeconds)
+	return server.config, nil
+}
+
+// removeDHCPServer stops the DHCP server of an interface, if any, together with its leases.
+func (r *Router) removeDHCPServer(iface string) {
+	r.dhcp.mu.Lock()
+	defer r.dhcp.mu.Unlock()
+	delete(r.dhcp.servers, iface)
+}
+
+// GetDHCPConfigs returns the configuration of every enabled DHCP server, sorted by interface.
+func (r *Router) GetDHCPConfigs() []DHCPConfig {
+	r.dhcp.mu.Lock()
+	defer r.dhcp.mu.Unlock()
+	configs := make([]DHCPConfig, 0, len(r.dhcp.servers))
+	for _, server := range r.dhcp.servers {
+		configs = append(configs, server.config)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Interface < configs[j].Interface })
+	return configs
+}
+
+// GetDHCPLeases returns the active leases of all interfaces, sorted by interface and address.
+func (r *Router) GetDHCPLeases() []DHCPLease {
+	r.dhcp.mu.Lock()
+	defer r.dhcp.mu.Unlock()
+	var leases []DHCPLease
+	for _, server := range r.dhcp.servers {
+		for _, lease := range server.leases {
+			leases = append(leases, *lease)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		if leases[i].Interface != leases[j].Interface {
+			return leases[i].Interface < leases[j].Interface
+		}
+		return ipToUint32(net.ParseIP(leases[i].IP)) < ipToUint32(net.ParseIP(leases[j].IP))
+	})
+	return leases
+}
+
+// RequestDHCPLease leases an address on an interface to the host with the given MAC address.
+// If the host already has a lease, it is renewed.
+func (r *Router) RequestDHCPLease(iface, mac, hostname string, now time.Time) (DHCPLease, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return DHCPLease{}, fmt.Errorf("invalid MAC address %q: %w", mac, err)
+	}
+	mac = hw.String()
+
+	r.dhcp.mu.Lock()
+	defer r.dhcp.mu.Unlock()
+	server, ok := r.dhcp.servers[iface]
+	if !ok {
+		return DHCPLease{}, fmt.Errorf("DHCP server is not enabled on interface %s of router %s", iface, r.ID)
+	}
+	server.expire(now)
+	leaseTime := time.Duration(server.config.LeaseSeconds) * time.Second
+
+	if lease, ok := server.leases[mac]; ok {
+		lease.GrantedAt = now
+		lease.ExpiresAt = now.Add(leaseTime)
+		if hostname != "" {
+			lease.Hostname = hostname
+		}
+		log.Printf("Router %s: DHCP lease of %s renewed for %s on %s", r.ID, lease.IP, mac, iface)
+		return *lease, nil
+	}
+
+	inUse := make(map[uint32]bool, len(server.leases)+1)
+	inUse[ipToUint32(server.gateway)] = true
+	for _, lease := range server.leases {
+		inUse[ipToUint32(net.ParseIP(lease.IP))] = true
+	}
+	for n := server.start; n <= server.end; n++ {
+		if inUse[n] {
+			continue
+		}
+		lease := &DHCPLease{
+			Interface: iface,
+			MAC:       mac,
+			Hostname:  hostname,
+			IP:        uint32ToIP(n).String(),
+			Network:   server.network.String(),
+			Gateway:   server.gateway.String(),
+			GrantedAt: now,
+			ExpiresAt: now.Add(leaseTime),
+		}
+		server.leases[mac] = lease
+		log.Printf("Router %s: DHCP leased %s to %s on %s", r.ID, lease.IP, mac, iface)
+		return *lease, nil
+	}
+	return DHCPLease{}, fmt.Errorf("no free addresses in the DHCP range %s-%s of interface %s", server.config.RangeStart, server.config.RangeEnd, iface)
+}
+
+// ReleaseDHCPLease removes the lease of a host, as when the host sends a DHCPRELEASE.
+func (r *Router) ReleaseDHCPLease(iface, mac string) (DHCPLease, error) {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return DHCPLease{}, fmt.Errorf("invalid MAC address %q: %w", mac, err)
+	}
+
+	r.dhcp.mu.Lock()
+	defer r.dhcp.mu.Unlock()
+	server, ok := r.dhcp.servers[iface]
+	if !ok {
+		return DHCPLease{}, fmt.Errorf("DHCP server is not enabled on interface %s of router %s", iface, r.ID)
+	}
+	lease, ok := server.leases[hw.String()]
+	if !ok {
+		return DHCPLease{}, fmt.Errorf("no lease for %s on interface %s", hw, iface)
+	}
+	delete(server.leases, hw.String())
+	log.Printf("Router %s: DHCP lease of %s released by %s on %s", r.ID, lease.IP, lease.MAC, iface)
+	return *lease, nil
+}
+
+// expireDHCPLeases removes the leases that expired at now and returns them.
+func (r *Router) expireDHCPLeases(now time.Time) []DHCPLease {
+	r.dhcp.mu.Lock()
+	defer r.dhcp.mu.Unlock()
+	var expired []DHCPLease
+	for _, server := range r.dhcp.servers {
+		expired = append(expired, server.expire(now)...)
+	}
+	return expired
+}
+
+// expire removes the leases that expired at now and returns them. Caller must hold mu.
+func (s *dhcpServer) expire(now time.Time) []DHCPLease {
+	var expired []DHCPLease
+	for mac, lease := range s.leases {
+		if !now.Before(lease.ExpiresAt) {
+			expired = append(expired, *lease)
+			delete(s.leases, mac)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,326 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"time"
+)
+
+// Additional interfaces.
+// Besides its primary TUN device (Router.TunDevice), a router can have more TUN interfaces and 802.1Q VLAN
+// sub-interfaces (e.g. "tun0.100" with VLAN ID 100 on parent "tun0"). Every interface has its own address and
+// directly connected route. TUN devices carry bare IP packets, so a sub-interface has no device of its own:
+// packets delivered to its network are written to the parent's TUN device.
+// Connections between routers always use the primary interface.
+
+const (
+	MinVLANID = 1
+	MaxVLANID = 4094
+)
+
+// InterfaceConfig is the configuration of an additional interface.
+type InterfaceConfig struct {
+	Name   string `json:"name,omitempty"`   // Interface name; defaults to "<parent>.<vlanId>" for sub-interfaces
+	IPCIDR string `json:"ipCIDR"`           // Address in CIDR notation, e.g. "10.0.5.1/24"
+	Parent string `json:"parent,omitempty"` // Parent interface for a VLAN sub-interface ("" = new TUN interface)
+	VLANID int    `json:"vlanId,omitempty"` // 802.1Q VLAN ID (1-4094) for sub-interfaces
+}
+
+// InterfaceInfo describes an interface of a router.
+type InterfaceInfo struct {
+	Name    string `json:"name"`
+	IPCIDR  string `json:"ipCIDR"`
+	Network string `json:"network"`
+	Parent  string `json:"parent,omitempty"`
+	VLANID  int    `json:"vlanId,omitempty"`
+	Primary bool   `json:"primary"`
+}
+
+// RouterInterface is an additional interface of a router.
+type RouterInterface struct {
+	Name    string
+	IP      net.IP
+	Network *net.IPNet
+	Parent  string     // Parent interface name for VLAN sub-interfaces
+	VLANID  int        // 0 for TUN interfaces
+	device  *TUNDevice // nil for VLAN sub-interfaces
+}
+
+func (ifc *RouterInterface) info() InterfaceInfo {
+	ones, _ := ifc.Network.Mask.Size()
+	return InterfaceInfo{
+		Name:    ifc.Name,
+		IPCIDR:  fmt.Sprintf("%s/%d", ifc.IP, ones),
+		Network: ifc.Network.String(),
+		Parent:  ifc.Parent,
+		VLANID:  ifc.VLANID,
+	}
+}
+
+// connectedNetwork is a directly connected network and the interface it is reached through.
+type connectedNetwork struct {
+	Network   string
+	Interface string
+}
+
+// connectedNetworks returns the networks of the primary interface and all additional interfaces.
+func (r *Router) connectedNetworks() []connectedNetwork {
+	var networks []connectedNetwork
+	if _, ownNet, err := net.ParseCIDR(r.config.TunIPAddress); err == nil && r.TunDevice != nil {
+		networks = append(networks, connectedNetwork{Network: ownNet.String(), Interface: r.TunDevice.Name})
+	}
+	r.ifaceMutex.RLock()
+	defer r.ifaceMutex.RUnlock()
+	for _, ifc := range r.Interfaces {
+		networks = append(networks, connectedNetwork{Network: ifc.Network.String(), Interface: ifc.Name})
+	}
+	return networks
+}
+
+// isConnectedNetwork reports whether network (normalized CIDR) is directly connected to any interface.
+func (r *Router) isConnectedNetwork(network string) bool {
+	for _, connected := range r.connectedNetworks() {
+		if connected.Network == network {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsIP reports whether ip is the address of one of the router's interfaces.
+func (r *Router) ownsIP(ip net.IP) bool {
+	if r.TunDevice != nil && ip.Equal(r.TunDevice.GetIP()) {
+		return true
+	}
+	r.ifaceMutex.RLock()
+	defer r.ifaceMutex.RUnlock()
+	for _, ifc := range r.Interfaces {
+		if ip.Equal(ifc.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectedRouteEntry returns the routing table entry for a directly connected network.
+func connectedRouteEntry(connected connectedNetwork) *RoutingEntry {
+	return &RoutingEntry{
+		Network:     connected.Network,
+		NextHop:     "0.0.0.0", // Indicates directly connected
+		Interface:   connected.Interface,
+		Metric:      0,
+		LearnedFrom: "Direct",
+		LastUpdated: time.Now(),
+	}
+}
+
+// AddInterface adds a TUN interface or a VLAN sub-interface to the router and installs its connected route.
+func (r *Router) AddInterface(config InterfaceConfig) (InterfaceInfo, error) {
+	ip, ipNet, err := net.ParseCIDR(config.IPCIDR)
+	if err != nil || ip.To4() == nil {
+		return InterfaceInfo{}, fmt.Errorf("invalid IPv4 CIDR %q", config.IPCIDR)
+	}
+	ifc := &RouterInterface{Name: config.Name, IP: ip.To4(), Network: ipNet, Parent: config.Parent, VLANID: config.VLANID}
+	if config.Parent != "" {
+		if config.VLANID < MinVLANID || config.VLANID > MaxVLANID {
+			return InterfaceInfo{}, fmt.Errorf("VLAN ID must be between %d and %d (got %d)", MinVLANID, MaxVLANID, config.VLANID)
+		}
+		if ifc.Name == "" {
+			ifc.Name = fmt.Sprintf("%s.%d", config.Parent, config.VLANID)
+		}
+	} else if config.VLANID != 0 {
+		return InterfaceInfo{}, fmt.Errorf("a VLAN sub-interface needs a parent interface")
+	}
+
+	// Validate against the existing interfaces while holding the lock, so concurrent adds cannot conflict
+	r.ifaceMutex.Lock()
+	if err := r.validateNewInterface(ifc); err != nil {
+		r.ifaceMutex.Unlock()
+		return InterfaceInfo{}, err
+	}
+	if ifc.Parent == "" {
+		device, err := NewTUNDevice(ifc.Name, config.IPCIDR, DefaultMTU)
+		if err != nil {
+			r.ifaceMutex.Unlock()
+			return InterfaceInfo{}, fmt.Errorf("failed to create TUN device for interface %s: %w", ifc.Name, err)
+		}
+		ifc.device = device
+		ifc.Name = device.Name // The OS may pick the name
+		r.wg.Add(1)
+		go r.interfaceProcessingLoop(device)
+	}
+	if r.Interfaces == nil {
+		r.Interfaces = make(map[string]*RouterInterface)
+	}
+	r.Interfaces[ifc.Name] = ifc
+	r.ifaceMutex.Unlock()
+	log.Printf("Router %s: Added interface %s (%s, parent=%q, vlan=%d)", r.ID, ifc.Name, config.IPCIDR, ifc.Parent, ifc.VLANID)
+
+	r.rtMutex.Lock()
+	r.RoutingTable[ipNet.String()] = connectedRouteEntry(connectedNetwork{Network: ipNet.String(), Interface: ifc.Name})
+	r.notifyRoutingTableUpdate()
+	r.rtMutex.Unlock()
+
+	r.advertiseInterfaceChange()
+	return ifc.info(), nil
+}
+
+// validateNewInterface checks name, parent and address conflicts. Caller must hold ifaceMutex.
+func (r *Router) validateNewInterface(ifc *RouterInterface) error {
+	primaryName := ""
+	if r.TunDevice != nil {
+		primaryName = r.TunDevice.Name
+	}
+	if ifc.Name != "" && (ifc.Name == primaryName || r.Interfaces[ifc.Name] != nil) {
+		return fmt.Errorf("interface %s already exists on router %s", ifc.Name, r.ID)
+	}
+	if ifc.Parent != "" {
+		parent, exists := r.Interfaces[ifc.Parent]
+		if ifc.Parent != primaryName && (!exists || parent.Parent != "") {
+			return fmt.Errorf("parent interface %s not found on router %s (sub-interfaces cannot be nested)", ifc.Parent, r.ID)
+		}
+		for _, other := range r.Interfaces {
+			if other.Parent == ifc.Parent && other.VLANID == ifc.VLANID {
+				return fmt.Errorf("VLAN %d is already configured on %s (%s)", ifc.VLANID, ifc.Parent, other.Name)
+			}
+		}
+	}
+
+	networks := make([]*net.IPNet, 0, len(r.Interfaces)+1)
+	if _, ownNet, err := net.ParseCIDR(r.config.TunIPAddress); err == nil {
+		networks = append(networks, ownNet)
+	}
+	for _, other := range r.Interfaces {
+		networks = append(networks, other.Network)
+	}
+	for _, network := range networks {
+		if network.Contains(ifc.Network.IP) || ifc.Network.Contains(network.IP) {
+			return fmt.Errorf("%s overlaps with connected network %s", ifc.Network, network)
+		}
+	}
+	return nil
+}
+
+// RemoveInterface removes an additional interface and its connected route.
+// The primary interface and interfaces that still have sub-interfaces cannot be removed.
+func (r *Router) RemoveInterface(name string) (InterfaceInfo, error) {
+	r.ifaceMutex.Lock()
+	ifc, exists := r.Interfaces[name]
+	if !exists {
+		r.ifaceMutex.Unlock()
+		if r.TunDevice != nil && name == r.TunDevice.Name {
+			return InterfaceInfo{}, fmt.Errorf("the primary interface %s cannot be removed", name)
+		}
+		return InterfaceInfo{}, fmt.Errorf("interface %s not found on router %s", name, r.ID)
+	}
+	for _, other := range r.Interfaces {
+		if other.Parent == name {
+			r.ifaceMutex.Unlock()
+			return InterfaceInfo{}, fmt.Errorf("interface %s still has sub-interface %s", name, other.Name)
+		}
+	}
+	delete(r.Interfaces, name)
+	r.ifaceMutex.Unlock()
+
+	if ifc.device != nil {
+		if err := ifc.device.Close(); err != nil {
+			log.Printf("Router %s: Error closing TUN device of interface %s: %v", r.ID, name, err)
+		}
+	}
+	r.removeInterfaceCounters(name)
+	r.removeDHCPServer(name)
+	log.Printf("Router %s: Removed interface %s", r.ID, name)
+
+	r.rtMutex.Lock()
+	network := ifc.Network.String()
+	if entry, ok := r.RoutingTable[network]; ok && entry.LearnedFrom == "Direct" {
+		delete(r.RoutingTable, network)
+	}
+	r.notifyRoutingTableUpdate()
+	r.rtMutex.Unlock()
+
+	r.advertiseInterfaceChange()
+	return ifc.info(), nil
+}
+
+// GetInterfaces returns all interfaces of the router, primary first, then by name.
+func (r *Router) GetInterfaces() []InterfaceInfo {
+	var list []InterfaceInfo
+	if r.TunDevice != nil {
+		if _, ownNet, err := net.ParseCIDR(r.config.TunIPAddress); err == nil {
+			list = append(list, InterfaceInfo{
+				Name:    r.TunDevice.Name,
+				IPCIDR:  r.config.TunIPAddress,
+				Network: ownNet.String(),
+				Primary: true,
+			})
+		}
+	}
+
+	r.ifaceMutex.RLock()
+	others := make([]InterfaceInfo, 0, len(r.Interfaces))
+	for _, ifc := range r.Interfaces {
+		others = append(others, ifc.info())
+	}
+	r.ifaceMutex.RUnlock()
+	sort.Slice(others, func(i, j int) bool { return others[i].Name < others[j].Name })
+	return append(list, others...)
+}
+
+// advertiseInterfaceChange tells the routing protocols that the set of connected networks changed.
+func (r *Router) advertiseInterfaceChange() {
+	r.triggerLSUGeneration()
+	r.triggerRIPUpdate()
+}
+
+// interfaceDevice returns the TUN device behind an interface (the parent's device for sub-interfaces).
+func (r *Router) interfaceDevice(name string) (*TUNDevice, error) {
+	if r.TunDevice != nil && name == r.TunDevice.Name {
+		return r.TunDevice, nil
+	}
+	r.ifaceMutex.RLock()
+	defer r.ifaceMutex.RUnlock()
+	ifc, exists := r.Interfaces[name]
+	if !exists {
+		return nil, fmt.Errorf("interface %s not found on router %s", name, r.ID)
+	}
+	if ifc.Parent == "" {
+		return ifc.device, nil
+	}
+	if parent, ok := r.Interfaces[ifc.Parent]; ok {
+		return parent.device, nil
+	}
+	return r.TunDevice, nil // The parent is the primary interface
+}
+
+// interfaceProcessingLoop reads packets from an additional TUN interface and processes them.
+func (r *Router) interfaceProcessingLoop(device *TUNDevice) {
+	defer r.wg.Done()
+	log.Printf("Router %s: Packet processing loop started for TUN %s.", r.ID, device.Name)
+	for {
+		packet, ok := device.ReadPacket()
+		if !ok {
+			log.Printf("Router %s: TUN device %s closed, exiting its packet processing loop.", r.ID, device.Name)
+			return
+		}
+		if len(packet) > 0 {
+			r.countReceived(device.Name, len(packet))
+			r.processIncomingPacket(packet, device.Name)
+		}
+	}
+}
+
+// closeInterfaces closes the TUN devices of all additional interfaces.
+func (r *Router) closeInterfaces() {
+	r.ifaceMutex.Lock()
+	defer r.ifaceMutex.Unlock()
+	for name, ifc := range r.Interfaces {
+		if ifc.device != nil {
+			if err := ifc.device.Close(); err != nil {
+				log.Printf("Router [%s] error closing TUN device of interface %s: %v", r.ID, name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package router
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Link impairment emulation, similar to Linux netem/tbf.
+// Every connection can delay, drop and rate limit the packets relayed over it. The settings apply to
+// both directions, but each direction has its own transmit queue.
+
+const (
+	MaxLinkDelayMs    = 10000                  // Upper bound for DelayMs and JitterMs
+	LinkMaxQueueDelay = 500 * time.Millisecond // Packets that would wait longer in a rate limited queue are tail-dropped
+)
+
+// LinkImpairment configures how a connection degrades the packets relayed over it.
+// The zero value is an ideal link: packets are delivered immediately.
+type LinkImpairment struct {
+	DelayMs     int     `json:"delayMs"`     // One-way delay
+	JitterMs    int     `json:"jitterMs"`    // Random variation (+/-) applied to the delay
+	LossPercent float64 `json:"lossPercent"` // Probability (0-100) that a packet is lost
+	RateKbps    int     `json:"rateKbps"`    // Bandwidth limit in kbit/s (0 = unlimited)
+}
+
+// LinkStats counts the packets relayed over a connection.
+type LinkStats struct {
+	Transmitted uint64 `json:"transmitted"` // Packets sent over the link (possibly still in flight)
+	Lost        uint64 `json:"lost"`        // Packets dropped by LossPercent
+	QueueDrops  uint64 `json:"queueDrops"`  // Packets dropped because the rate limited queue was full
+}
+
+// linkState is the runtime state of a connection.
+type linkState struct {
+	mu         sync.Mutex
+	impairment LinkImpairment
+	stats      LinkStats
+	queueFree  map[string]time.Time // Sender router ID -> time its transmit queue becomes empty
+}
+
+func newLinkState() *linkState {
+	return &linkState{queueFree: make(map[string]time.Time)}
+}
+
+func (imp LinkImpairment) validate() error {
+	if imp.DelayMs < 0 || imp.DelayMs > MaxLinkDelayMs {
+		return fmt.Errorf("delayMs must be between 0 and %d", MaxLinkDelayMs)
+	}
+	if imp.JitterMs < 0 || imp.JitterMs > MaxLinkDelayMs {
+		return fmt.Errorf("jitterMs must be between 0 and %d", MaxLinkDelayMs)
+	}
+	if imp.LossPercent < 0 || imp.LossPercent > 100 {
+		return fmt.Errorf("lossPercent must be between 0 and 100")
+	}
+	if imp.RateKbps < 0 {
+		return fmt.Errorf("rateKbps must not be negative")
+	}
+	return nil
+}
+
+// impaired reports whether the link changes anything about the packets relayed over it.
+func (imp LinkImpairment) impaired() bool {
+	return imp != LinkImpairment{}
+}
+
+func (l *linkState) setImpairment(imp LinkImpairment) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.impairment = imp
+	if imp.RateKbps == 0 {
+		l.queueFree = make(map[string]time.Time)
+	}
+}
+
+func (l *linkState) snapshot() (LinkImpairment, LinkStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.impairment, l.stats
+}
+
+// transmit applies the impairment to a packet of size bytes sent by senderID at now.
+// It returns how long the packet takes to arrive, or false if the packet is dropped.
+func (l *linkState) transmit(senderID string, size int, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	imp := l.impairment
+
+	if imp.LossPercent > 0 && rand.Float64()*100 < imp.LossPercent {
+		l.stats.Lost++
+		return 0, false
+	}
+
+	var delay time.Duration
+	if imp.RateKbps > 0 {
+		// The packet is serialized after the packets already queued in this direction
+		start := now
+		if free := l.queueFree[senderID]; free.After(now) {
+			start = free
+		}
+		if start.Sub(now) > LinkMaxQueueDelay {
+			l.stats.QueueDrops++
+			return 0, false
+		}
+		txTime := time.Duration(size*8) * time.Second / time.Duration(imp.RateKbps*1000)
+		l.queueFree[senderID] = start.Add(txTime)
+		delay = l.queueFree[senderID].Sub(now)
+	}
+
+	delay += time.Duration(imp.DelayMs) * time.Millisecond
+	if imp.JitterMs > 0 {
+		delay += time.Duration(rand.Intn(2*imp.JitterMs+1)-imp.JitterMs) * time.Millisecond
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	l.stats.Transmitted++
+	return delay, true
+}
+
+// linkBetween returns the link state of the connection between two routers, or nil if they are not connected.
+func (m *RouterManager) linkBetween(router1ID, router2ID string) *linkState {
+	m.connMutex.RLock()
+	defer m.connMutex.RUnlock()
+	for id, conn := range m.connections {
+		if (conn.Router1ID == router1ID && conn.Router2ID == router2ID) || (conn.Router1ID == router2ID && conn.Router2ID == router1ID) {
+			return m.links[id]
+		}
+	}
+	return nil
+}
+
+// withLinkState fills in the impairment and counters of a connection. The caller must hold connMutex.
+func (m *RouterManager) withLinkState(conn ConnectionInfo) ConnectionInfo {
+	if link, ok := m.links[conn.ID]; ok {
+		conn.Impairment, conn.Stats = link.snapshot()
+	}
+	return conn
+}
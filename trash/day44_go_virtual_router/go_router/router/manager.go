@@ -17,17 +17,23 @@ type ConnectionInfo struct {
 	Router2ID string `json:"router2Id"`
 	// Interface1 string `json:"interface1,omitempty"` // Optional: specific interface on Router1
 	// Interface2 string `json:"interface2,omitempty"` // Optional: specific interface on Router2
-	CreatedAt time.Time `json:"createdAt"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	Impairment LinkImpairment `json:"impairment"`
+	Stats      LinkStats      `json:"stats"`
 }
 
 // RouterManager は複数の仮想ルーターを管理します。
 type RouterManager struct {
 	routers          map[string]*Router          // Key: Router ID
 	connections      map[string]ConnectionInfo   // Key: Connection ID
+	links            map[string]*linkState       // Key: Connection ID (protected by connMutex)
 	mutex            sync.RWMutex                // Protects routers map
 	connMutex        sync.RWMutex                // Protects connections map
 	BroadcastOutChan chan map[string]interface{} // Channel to send messages for WebSocket broadcast
 	routerCounter    int                         // For generating default IDs and IPs
+	packetTails      map[string]int              // Router ID -> number of clients tailing its packet log
+	tailMutex        sync.Mutex                  // Protects packetTails
+	store            storeState                  // Where the topology is saved after every change
 	// TODO: ルーター間接続の情報 (どのルーターのどのインターフェースが、どの他のルーターに接続しているか)
 	// connections map[string]string // 例: key "router1-tun0" value "router2-tun0"
 }
@@ -36,14 +42,26 @@ func NewRouterManager(broadcastChan chan map[string]interface{}) *RouterManager
 	return &RouterManager{
 		routers:          make(map[string]*Router),
 		connections:      make(map[string]ConnectionInfo),
+		links:            make(map[string]*linkState),
 		BroadcastOutChan: broadcastChan,
 		routerCounter:    0, // Initialize counter
+		packetTails:      make(map[string]int),
 		// connections: make(map[string]string),
 	}
 }
 
 // AddConnection creates a new connection between two routers
 func (m *RouterManager) AddConnection(router1ID string, router2ID string) (ConnectionInfo, error) {
+	conn, err := m.addConnection(uuid.New().String(), router1ID, router2ID, time.Now())
+	if err != nil {
+		return ConnectionInfo{}, err
+	}
+	m.SaveTopology()
+	return conn, nil
+}
+
+// addConnection creates a connection with the given ID (a restored connection keeps its ID)
+func (m *RouterManager) addConnection(connID string, router1ID string, router2ID string, createdAt time.Time) (ConnectionInfo, error) {
 	m.mutex.RLock() // Lock router map for reading
 	_, r1Exists := m.routers[router1ID]
 	_, r2Exists := m.routers[router2ID]
@@ -88,16 +106,16 @@ func (m *RouterManager) AddConnection(router1ID string, router2ID string) (Conne
 	r1.AddPeer(r2.ID, r2.TunDevice.GetIP())
 	r2.AddPeer(r1.ID, r1.TunDevice.GetIP())
 
-	connID := uuid.New().String()
 	newConn := ConnectionInfo{
 		ID:        connID,
 		Router1ID: router1ID,
 		Router2ID: router2ID,
-		CreatedAt: time.Now(),
+		CreatedAt: createdAt,
 	}
 
 	m.connMutex.Lock()
 	m.connections[connID] = newConn
+	m.links[connID] = newLinkState()
 	m.connMutex.Unlock()
 
 	log.Printf("RouterManager: Added connection %s between %s and %s", connID, router1ID, router2ID)
@@ -120,8 +138,17 @@ func (m *RouterManager) RemoveConnection(connectionID string) error {
 		return fmt.Errorf("connection with ID %s not found", connectionID)
 	}
 	delete(m.connections, connectionID)
+	delete(m.links, connectionID)
 	m.connMutex.Unlock()
 
+	// Tell both routers the peer is gone so that routes learned over this connection are withdrawn
+	if r1, ok := m.GetRouter(conn.Router1ID); ok {
+		r1.RemovePeer(conn.Router2ID)
+	}
+	if r2, ok := m.GetRouter(conn.Router2ID); ok {
+		r2.RemovePeer(conn.Router1ID)
+	}
+
 	log.Printf("RouterManager: Removed connection %s between %s and %s", conn.ID, conn.Router1ID, conn.Router2ID)
 
 	// Broadcast connection deletion event
@@ -129,6 +156,7 @@ func (m *RouterManager) RemoveConnection(connectionID string) error {
 		"event":        "CONNECTION_DELETED",
 		"connectionId": connectionID,
 	}
+	m.SaveTopology()
 	return nil
 }
 
@@ -138,11 +166,47 @@ func (m *RouterManager) GetConnections() []ConnectionInfo {
 	defer m.connMutex.RUnlock()
 	list := make([]ConnectionInfo, 0, len(m.connections))
 	for _, conn := range m.connections {
-		list = append(list, conn)
+		list = append(list, m.withLinkState(conn))
 	}
 	return list
 }
 
+// GetConnection は指定した接続を返します。
+func (m *RouterManager) GetConnection(connectionID string) (ConnectionInfo, bool) {
+	m.connMutex.RLock()
+	defer m.connMutex.RUnlock()
+	conn, exists := m.connections[connectionID]
+	if !exists {
+		return ConnectionInfo{}, false
+	}
+	return m.withLinkState(conn), true
+}
+
+// SetConnectionImpairment は接続の遅延・ジッター・パケットロス・帯域制限を設定し、CONNECTION_UPDATED イベントを通知します。
+func (m *RouterManager) SetConnectionImpairment(connectionID string, impairment LinkImpairment) (ConnectionInfo, error) {
+	if err := impairment.validate(); err != nil {
+		return ConnectionInfo{}, err
+	}
+	m.connMutex.RLock()
+	conn, exists := m.connections[connectionID]
+	link := m.links[connectionID]
+	m.connMutex.RUnlock()
+	if !exists || link == nil {
+		return ConnectionInfo{}, fmt.Errorf("connection with ID %s not found", connectionID)
+	}
+	link.setImpairment(impairment)
+	conn.Impairment, conn.Stats = link.snapshot()
+	log.Printf("RouterManager: Connection %s impairment set to delay=%dms jitter=%dms loss=%.1f%% rate=%dkbps",
+		connectionID, impairment.DelayMs, impairment.JitterMs, impairment.LossPercent, impairment.RateKbps)
+
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":      "CONNECTION_UPDATED",
+		"connection": conn,
+	}
+	m.SaveTopology()
+	return conn, nil
+}
+
 // CreateAndStartRouter は新しいルーターを作成し、設定して起動します。
 func (m *RouterManager) CreateAndStartRouter(id string, tunName string, ipCIDR string, mtu int) (*Router, error) {
 	m.mutex.Lock() // Lock for routerCounter and routers map modification
@@ -210,11 +274,22 @@ func (m *RouterManager) CreateAndStartRouter(id string, tunName string, ipCIDR s
 	}
 
 	go func(router *Router) {
+		aclTicker := time.NewTicker(ACLHitsBroadcastInterval)
+		defer aclTicker.Stop()
+		dhcpTicker := time.NewTicker(DHCPExpiryInterval)
+		defer dhcpTicker.Stop()
 		for {
 			select {
 			case <-router.shutdown:
 				log.Printf("RouterManager: Stopping routing table update listener for router %s", router.ID)
 				return
+			case <-aclTicker.C:
+				// Hit counters change on every matching packet, so they are broadcast periodically instead
+				if router.takeACLHitsChanged() {
+					m.broadcastACL(router, "ACL_HITS_UPDATED")
+				}
+			case now := <-dhcpTicker.C:
+				m.expireDHCPLeases(router, now)
 			case table, ok := <-router.RoutingTableUpdateChan:
 				if !ok {
 					log.Printf("RouterManager: RoutingTableUpdateChan closed for router %s", router.ID)
@@ -230,6 +305,7 @@ func (m *RouterManager) CreateAndStartRouter(id string, tunName string, ipCIDR s
 		}
 	}(r)
 
+	m.SaveTopology()
 	return r, nil
 }
 
@@ -256,6 +332,7 @@ func (m *RouterManager) StopAndRemoveRouter(id string) error {
 		"event":    "ROUTER_DELETED",
 		"routerId": id,
 	}
+	m.SaveTopology()
 	return nil
 }
 
@@ -267,13 +344,222 @@ func (m *RouterManager) GetRouter(id string) (*Router, bool) {
 	return r, exists
 }
 
+// AddStaticRoute は指定したルーターにスタティックルートを追加し、ROUTE_ADDED イベントを通知します。
+func (m *RouterManager) AddStaticRoute(routerID string, destination string, nextHop string, metric int) (RoutingEntry, error) {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return RoutingEntry{}, fmt.Errorf("router with ID %s not found", routerID)
+	}
+	route, err := r.AddStaticRoute(destination, nextHop, metric)
+	if err != nil {
+		return RoutingEntry{}, err
+	}
+	log.Printf("RouterManager: Added static route %s via %s to router %s", route.Network, route.NextHop, routerID)
+
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":    "ROUTE_ADDED",
+		"routerId": routerID,
+		"route":    route,
+	}
+	m.SaveTopology()
+	return route, nil
+}
+
+// RemoveStaticRoute は指定したルーターからスタティックルートを削除し、ROUTE_REMOVED イベントを通知します。
+func (m *RouterManager) RemoveStaticRoute(routerID string, destination string) error {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return fmt.Errorf("router with ID %s not found", routerID)
+	}
+	route, err := r.RemoveStaticRoute(destination)
+	if err != nil {
+		return err
+	}
+	log.Printf("RouterManager: Removed static route %s from router %s", route.Network, routerID)
+
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":    "ROUTE_REMOVED",
+		"routerId": routerID,
+		"route":    route,
+	}
+	m.SaveTopology()
+	return nil
+}
+
+// SetNATConfig は指定したルーターの NAT 設定を変更し、NAT_CONFIG_UPDATED イベントを通知します。
+func (m *RouterManager) SetNATConfig(routerID string, config NATConfig) error {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return fmt.Errorf("router with ID %s not found", routerID)
+	}
+	if err := r.SetNATConfig(config); err != nil {
+		return err
+	}
+	log.Printf("RouterManager: NAT config of router %s updated (enabled=%v, wan=%q)", routerID, config.Enabled, config.WANInterface)
+
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":    "NAT_CONFIG_UPDATED",
+		"routerId": routerID,
+		"nat":      config,
+	}
+	return nil
+}
+
+// AddACLRule は指定したルーターに ACL ルールを追加し、ACL_UPDATED イベントを通知します。
+// position は 1 始まりの挿入位置で、0 以下なら末尾に追加します。
+func (m *RouterManager) AddACLRule(routerID string, rule ACLRule, position int) (ACLRule, error) {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return ACLRule{}, fmt.Errorf("router with ID %s not found", routerID)
+	}
+	added, err := r.AddACLRule(rule, position)
+	if err != nil {
+		return ACLRule{}, err
+	}
+	m.broadcastACL(r, "ACL_UPDATED")
+	return added, nil
+}
+
+// RemoveACLRule は指定したルーターから ACL ルールを削除し、ACL_UPDATED イベントを通知します。
+func (m *RouterManager) RemoveACLRule(routerID string, ruleID int) error {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return fmt.Errorf("router with ID %s not found", routerID)
+	}
+	if err := r.RemoveACLRule(ruleID); err != nil {
+		return err
+	}
+	m.broadcastACL(r, "ACL_UPDATED")
+	return nil
+}
+
+// AddInterface は指定したルーターにインターフェース (TUN または VLAN サブインターフェース) を追加し、INTERFACE_ADDED イベントを通知します。
+func (m *RouterManager) AddInterface(routerID string, config InterfaceConfig) (InterfaceInfo, error) {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return InterfaceInfo{}, fmt.Errorf("router with ID %s not found", routerID)
+	}
+	added, err := r.AddInterface(config)
+	if err != nil {
+		return InterfaceInfo{}, err
+	}
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":     "INTERFACE_ADDED",
+		"routerId":  routerID,
+		"interface": added,
+	}
+	m.SaveTopology()
+	return added, nil
+}
+
+// RemoveInterface は指定したルーターからインターフェースを削除し、INTERFACE_REMOVED イベントを通知します。
+func (m *RouterManager) RemoveInterface(routerID string, name string) error {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return fmt.Errorf("router with ID %s not found", routerID)
+	}
+	removed, err := r.RemoveInterface(name)
+	if err != nil {
+		return err
+	}
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":     "INTERFACE_REMOVED",
+		"routerId":  routerID,
+		"interface": removed,
+	}
+	m.SaveTopology()
+	return nil
+}
+
+// SetDHCPConfig は指定したルーターのインターフェースの DHCP サーバー設定を変更し、DHCP_CONFIG_UPDATED イベントを通知します。
+func (m *RouterManager) SetDHCPConfig(routerID string, config DHCPConfig) (DHCPConfig, error) {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return DHCPConfig{}, fmt.Errorf("router with ID %s not found", routerID)
+	}
+	applied, err := r.SetDHCPConfig(config)
+	if err != nil {
+		return DHCPConfig{}, err
+	}
+	// Reconfiguring may drop leases, so the event carries the remaining leases
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":    "DHCP_CONFIG_UPDATED",
+		"routerId": routerID,
+		"dhcp":     applied,
+		"leases":   r.GetDHCPLeases(),
+	}
+	return applied, nil
+}
+
+// RequestDHCPLease は指定したルーターのインターフェースから疑似ホストにアドレスを払い出し、DHCP_LEASE_GRANTED イベントを通知します。
+func (m *RouterManager) RequestDHCPLease(routerID, iface, mac, hostname string) (DHCPLease, error) {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return DHCPLease{}, fmt.Errorf("router with ID %s not found", routerID)
+	}
+	now := time.Now()
+	// Expired leases are announced before their addresses are handed out again
+	m.expireDHCPLeases(r, now)
+	lease, err := r.RequestDHCPLease(iface, mac, hostname, now)
+	if err != nil {
+		return DHCPLease{}, err
+	}
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":    "DHCP_LEASE_GRANTED",
+		"routerId": routerID,
+		"lease":    lease,
+	}
+	return lease, nil
+}
+
+// ReleaseDHCPLease は疑似ホストのリースを解放し、DHCP_LEASE_RELEASED イベントを通知します。
+func (m *RouterManager) ReleaseDHCPLease(routerID, iface, mac string) error {
+	r, exists := m.GetRouter(routerID)
+	if !exists {
+		return fmt.Errorf("router with ID %s not found", routerID)
+	}
+	lease, err := r.ReleaseDHCPLease(iface, mac)
+	if err != nil {
+		return err
+	}
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":    "DHCP_LEASE_RELEASED",
+		"routerId": routerID,
+		"lease":    lease,
+	}
+	return nil
+}
+
+// expireDHCPLeases はルーターの期限切れリースを削除し、DHCP_LEASE_EXPIRED イベントを通知します。
+func (m *RouterManager) expireDHCPLeases(r *Router, now time.Time) {
+	for _, lease := range r.expireDHCPLeases(now) {
+		log.Printf("RouterManager: DHCP lease of %s for %s on %s/%s expired", lease.IP, lease.MAC, r.ID, lease.Interface)
+		m.BroadcastOutChan <- map[string]interface{}{
+			"event":    "DHCP_LEASE_EXPIRED",
+			"routerId": r.ID,
+			"lease":    lease,
+		}
+	}
+}
+
+// broadcastACL はルーターの ACL ルール (ヒット数を含む) を WebSocket に通知します。
+func (m *RouterManager) broadcastACL(r *Router, event string) {
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":    event,
+		"routerId": r.ID,
+		"rules":    r.GetACLRules(),
+	}
+}
+
 // GetAllRoutersInfo は管理下のすべてのルーターのリストを返します。
 // This now returns a slice of a simple struct for API safety, not direct *Router pointers.
 type RouterInfo struct {
-	ID        string `json:"id"`
-	TunName   string `json:"tunName"`
-	IPAddress string `json:"ip"`
-	NumRoutes int    `json:"numRoutes"`
+	ID         string          `json:"id"`
+	TunName    string          `json:"tunName"`
+	IPAddress  string          `json:"ip"`
+	NumRoutes  int             `json:"numRoutes"`
+	NAT        NATStats        `json:"nat"`
+	Interfaces []InterfaceInfo `json:"interfaces"`
 	// Potentially add neighbors or other brief status here
 }
 
@@ -283,10 +569,12 @@ func (m *RouterManager) GetAllRoutersInfo() []RouterInfo {
 	list := make([]RouterInfo, 0, len(m.routers))
 	for _, r := range m.routers {
 		info := RouterInfo{
-			ID:        r.ID,
-			TunName:   r.TunDevice.GetName(),
-			IPAddress: r.TunDevice.GetIP().String(),
-			NumRoutes: len(r.GetRoutingTable()), // Access routing table via method
+			ID:         r.ID,
+			TunName:    r.TunDevice.GetName(),
+			IPAddress:  r.TunDevice.GetIP().String(),
+			NumRoutes:  len(r.GetRoutingTable()), // Access routing table via method
+			NAT:        r.GetNATStats(),
+			Interfaces: r.GetInterfaces(),
 		}
 		list = append(list, info)
 	}
@@ -299,6 +587,7 @@ func (m *RouterManager) RelayPacket(sourceRouterID string, nextHopIP net.IP, pac
 	m.mutex.RLock()
 	var targetRouter *Router
 	var targetRouterID string
+	sourceRouter := m.routers[sourceRouterID]
 
 	// Find the router whose TUN device IP matches the nextHopIP
 	for id, r := range m.routers {
@@ -328,7 +617,28 @@ func (m *RouterManager) RelayPacket(sourceRouterID string, nextHopIP net.IP, pac
 		// 	return false
 		// }
 		// log.Printf("RouterManager: Packet successfully relayed to TUN %s of router %s.", targetRouter.TunDevice.GetName(), targetRouterID)
-		targetRouter.InjectPacket(packet, sourceRouterID) // Inject the packet directly
+		// Peers are connected to the primary TUN interface. Packets lost on the link still count as sent.
+		if sourceRouter != nil {
+			sourceRouter.countSent(sourceRouter.TunDevice.GetName(), len(packet))
+		}
+		link := m.linkBetween(sourceRouterID, targetRouterID)
+		if link == nil {
+			targetRouter.InjectPacket(packet, sourceRouterID) // Inject the packet directly
+			return true
+		}
+		delay, ok := link.transmit(sourceRouterID, len(packet), time.Now())
+		if !ok {
+			// Lost on the link; like a real link, the sender does not notice
+			log.Printf("RouterManager: Packet from %s to %s lost on impaired link", sourceRouterID, targetRouterID)
+			return true
+		}
+		if delay == 0 {
+			targetRouter.InjectPacket(packet, sourceRouterID)
+			return true
+		}
+		// The caller may reuse its buffer while the packet is in flight
+		delayed := append([]byte(nil), packet...)
+		time.AfterFunc(delay, func() { targetRouter.InjectPacket(delayed, sourceRouterID) })
 		return true
 	} else {
 		log.Printf("RouterManager: No router found with TUN IP %s to relay packet from %s. Packet dropped.", nextHopIP, sourceRouterID)
@@ -0,0 +1,145 @@
+package router
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ForwardingLatencyBuckets are the upper bounds (in seconds) of the forwarding latency histogram buckets.
+var ForwardingLatencyBuckets = []float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1}
+
+// InterfaceCounters counts the packets received and sent on an interface.
+type InterfaceCounters struct {
+	RxPackets uint64
+	RxBytes   uint64
+	TxPackets uint64
+	TxBytes   uint64
+	Drops     uint64 // Packets received on the interface (or to be sent on it) that were discarded
+}
+
+// LatencyHistogram is a snapshot of the forwarding latency histogram.
+type LatencyHistogram struct {
+	Buckets map[float64]uint64 // Upper bound (seconds) -> cumulative count
+	Count   uint64
+	Sum     float64 // Seconds
+}
+
+// RouterMetrics is a snapshot of the traffic counters of a router.
+type RouterMetrics struct {
+	RouterID          string
+	Interfaces        map[string]InterfaceCounters
+	ForwardingLatency LatencyHistogram // Time from receiving a packet to handing it to the next hop
+}
+
+// routerMetrics holds the traffic counters of a router. The zero value is ready to use.
+type routerMetrics struct {
+	mu            sync.Mutex
+	interfaces    map[string]*InterfaceCounters
+	latencyCounts []uint64 // Non-cumulative count per ForwardingLatencyBuckets entry, plus one for +Inf
+	latencyCount  uint64
+	latencySum    float64
+}
+
+// interfaceCounters returns the counters of an interface, creating them if needed. The caller must hold mu.
+func (rm *routerMetrics) interfaceCounters(name string) *InterfaceCounters {
+	if rm.interfaces == nil {
+		rm.interfaces = make(map[string]*InterfaceCounters)
+	}
+	c, ok := rm.interfaces[name]
+	if !ok {
+		c = &InterfaceCounters{}
+		rm.interfaces[name] = c
+	}
+	return c
+}
+
+// countReceived counts a packet received on an interface.
+func (r *Router) countReceived(iface string, size int) {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	c := r.metrics.interfaceCounters(iface)
+	c.RxPackets++
+	c.RxBytes += uint64(size)
+}
+
+// countSent counts a packet sent on an interface.
+func (r *Router) countSent(iface string, size int) {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	c := r.metrics.interfaceCounters(iface)
+	c.TxPackets++
+	c.TxBytes += uint64(size)
+}
+
+// countDrop counts a packet discarded on an interface.
+func (r *Router) countDrop(iface string) {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	r.metrics.interfaceCounters(iface).Drops++
+}
+
+// observeForwardingLatency records how long the router took to forward a packet.
+func (r *Router) observeForwardingLatency(d time.Duration) {
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(ForwardingLatencyBuckets, seconds) // First bucket whose upper bound is >= seconds
+
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	if r.metrics.latencyCounts == nil {
+		r.metrics.latencyCounts = make([]uint64, len(ForwardingLatencyBuckets)+1)
+	}
+	r.metrics.latencyCounts[i]++
+	r.metrics.latencyCount++
+	r.metrics.latencySum += seconds
+}
+
+// removeInterfaceCounters forgets the counters of a removed interface.
+func (r *Router) removeInterfaceCounters(iface string) {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	delete(r.metrics.interfaces, iface)
+}
+
+// GetMetrics returns a snapshot of the router's traffic counters.
+func (r *Router) GetMetrics() RouterMetrics {
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	snapshot := RouterMetrics{
+		RouterID:   r.ID,
+		Interfaces: make(map[string]InterfaceCounters, len(r.metrics.interfaces)),
+		ForwardingLatency: LatencyHistogram{
+			Buckets: make(map[float64]uint64, len(ForwardingLatencyBuckets)),
+			Count:   r.metrics.latencyCount,
+			Sum:     r.metrics.latencySum,
+		},
+	}
+	for name, c := range r.metrics.interfaces {
+		snapshot.Interfaces[name] = *c
+	}
+	var cumulative uint64
+	for i, bound := range ForwardingLatencyBuckets {
+		if r.metrics.latencyCounts != nil {
+			cumulative += r.metrics.latencyCounts[i]
+		}
+		snapshot.ForwardingLatency.Buckets[bound] = cumulative
+	}
+	return snapshot
+}
+
+// GetAllMetrics returns a snapshot of the traffic counters of every router, sorted by router ID.
+func (m *RouterManager) GetAllMetrics() []RouterMetrics {
+	m.mutex.RLock()
+	routers := make([]*Router, 0, len(m.routers))
+	for _, r := range m.routers {
+		routers = append(routers, r)
+	}
+	m.mutex.RUnlock()
+
+	metrics := make([]RouterMetrics, 0, len(routers))
+	for _, r := range routers {
+		metrics = append(metrics, r.GetMetrics())
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].RouterID < metrics[j].RouterID })
+	return metrics
+}
@@ -0,0 +1,387 @@
+package router
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NAT (masquerade) translates the source of packets leaving the router's LAN (its TUN network)
+// through the WAN interface to the router's own IP, and translates replies back.
+// In this simulator a router has a single TUN device and reaches other routers through connections,
+// so the WAN interface is identified by the router ID of the connected peer.
+
+const (
+	TCPProtocolNumber = 6
+	UDPProtocolNumber = 17
+
+	NATPortRangeStart = 40000
+	NATPortRangeEnd   = 60000
+	NATEntryTimeout   = 2 * time.Minute // Translations unused for this period are removed
+)
+
+// NATConfig is the NAT configuration of a router.
+type NATConfig struct {
+	Enabled      bool   `json:"enabled"`
+	WANInterface string `json:"wanInterface"` // Router ID of the peer used as WAN ("" = any peer)
+}
+
+// NATTranslation is an active NAT translation.
+type NATTranslation struct {
+	Protocol    string    `json:"protocol"`
+	InsideIP    string    `json:"insideIp"`
+	InsidePort  uint16    `json:"insidePort"` // ICMP echo identifier for ICMP
+	OutsideIP   string    `json:"outsideIp"`
+	OutsidePort uint16    `json:"outsidePort"`
+	RemoteIP    string    `json:"remoteIp"` // Last destination seen for this translation
+	Packets     uint64    `json:"packets"`
+	LastUsed    time.Time `json:"lastUsed"`
+}
+
+// NATStats are the NAT counters of a router.
+type NATStats struct {
+	Enabled            bool   `json:"enabled"`
+	WANInterface       string `json:"wanInterface"`
+	ActiveTranslations int    `json:"activeTranslations"`
+	OutboundPackets    uint64 `json:"outboundPackets"` // Packets source-translated on the way out
+	InboundPackets     uint64 `json:"inboundPackets"`  // Replies translated back to the inside host
+	DroppedPackets     uint64 `json:"droppedPackets"`  // Packets dropped because no NAT port was available
+}
+
+type natKey struct {
+	protocol byte
+	ip       string // Inside IP for outbound lookups, empty for inbound lookups
+	port     uint16
+}
+
+type natEntry struct {
+	translation NATTranslation
+	protocol    byte
+}
+
+// natTable holds the NAT configuration and translations of a router. The zero value is ready to use (NAT disabled).
+type natTable struct {
+	mu       sync.Mutex
+	config   NATConfig
+	outbound map[natKey]*natEntry // (protocol, inside IP, inside port) -> entry
+	inbound  map[natKey]*natEntry // (protocol, outside port) -> entry
+	nextPort uint16
+
+	outboundPackets uint64
+	inboundPackets  uint64
+	droppedPackets  uint64
+}
+
+// SetNATConfig enables or disables NAT. The WAN interface must be a connected peer (or empty for any peer).
+// Disabling NAT clears all translations.
+func (r *Router) SetNATConfig(config NATConfig) error {
+	if config.WANInterface != "" {
+		r.peersMutex.RLock()
+		_, connected := r.ConnectedPeers[config.WANInterface]
+		r.peersMutex.RUnlock()
+		if !connected {
+			return fmt.Errorf("WAN interface %s is not a connected peer of router %s", config.WANInterface, r.ID)
+		}
+	}
+
+	r.nat.mu.Lock()
+	defer r.nat.mu.Unlock()
+	r.nat.config = config
+	if !config.Enabled {
+		r.nat.outbound = nil
+		r.nat.inbound = nil
+	}
+	log.Printf("Router %s: NAT config updated: enabled=%v wan=%q", r.ID, config.Enabled, config.WANInterface)
+	return nil
+}
+
+// GetNATConfig returns the NAT configuration.
+func (r *Router) GetNATConfig() NATConfig {
+	r.nat.mu.Lock()
+	defer r.nat.mu.Unlock()
+	return r.nat.config
+}
+
+// GetNATTranslations returns the active translations sorted by protocol and outside port.
+func (r *Router) GetNATTranslations() []NATTranslation {
+	r.nat.mu.Lock()
+	defer r.nat.mu.Unlock()
+	r.nat.expire(time.Now())
+	list := make([]NATTranslation, 0, len(r.nat.outbound))
+	for _, entry := range r.nat.outbound {
+		list = append(list, entry.translation)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Protocol != list[j].Protocol {
+			return list[i].Protocol < list[j].Protocol
+		}
+		return list[i].OutsidePort < list[j].OutsidePort
+	})
+	return list
+}
+
+// GetNATStats returns the NAT counters.
+func (r *Router) GetNATStats() NATStats {
+	r.nat.mu.Lock()
+	defer r.nat.mu.Unlock()
+	r.nat.expire(time.Now())
+	return NATStats{
+		Enabled:            r.nat.config.Enabled,
+		WANInterface:       r.nat.config.WANInterface,
+		ActiveTranslations: len(r.nat.outbound),
+		OutboundPackets:    r.nat.outboundPackets,
+		InboundPackets:     r.nat.inboundPackets,
+		DroppedPackets:     r.nat.droppedPackets,
+	}
+}
+
+// natOutbound source-translates a packet forwarded to nextHopRouterID if it leaves the LAN through the WAN interface.
+// Returns the packet to send (the original packet if no translation applies) or nil if it must be dropped.
+func (r *Router) natOutbound(packet []byte, hdr *IPv4Header, nextHopRouterID string) []byte {
+	_, lanNet, err := net.ParseCIDR(r.config.TunIPAddress)
+	if err != nil {
+		return packet
+	}
+	outsideIP := r.TunDevice.GetIP()
+	if !lanNet.Contains(hdr.SrcIP) || hdr.SrcIP.Equal(outsideIP) {
+		return packet // Not from the LAN, or originated by the router itself
+	}
+
+	r.nat.mu.Lock()
+	defer r.nat.mu.Unlock()
+	if !r.nat.config.Enabled || (r.nat.config.WANInterface != "" && r.nat.config.WANInterface != nextHopRouterID) {
+		return packet
+	}
+
+	ihl := int(hdr.IHL) * 4
+	insidePort, ok := natSourceID(packet, ihl, hdr.Protocol)
+	if !ok {
+		return packet // Protocols without ports/identifiers are passed through untranslated
+	}
+
+	now := time.Now()
+	key := natKey{protocol: hdr.Protocol, ip: hdr.SrcIP.String(), port: insidePort}
+	entry, exists := r.nat.outbound[key]
+	if exists && now.Sub(entry.translation.LastUsed) > NATEntryTimeout {
+		r.nat.remove(entry)
+		exists = false
+	}
+	if !exists {
+		r.nat.expire(now)
+		outsidePort, ok := r.nat.allocatePort(hdr.Protocol)
+		if !ok {
+			r.nat.droppedPackets++
+			log.Printf("Router %s: NAT port range exhausted, dropping packet from %s", r.ID, hdr.SrcIP.String())
+			return nil
+		}
+		entry = &natEntry{
+			protocol: hdr.Protocol,
+			translation: NATTranslation{
+				Protocol:    protocolName(hdr.Protocol),
+				InsideIP:    hdr.SrcIP.String(),
+				InsidePort:  insidePort,
+				OutsideIP:   outsideIP.String(),
+				OutsidePort: outsidePort,
+			},
+		}
+		if r.nat.outbound == nil {
+			r.nat.outbound = make(map[natKey]*natEntry)
+			r.nat.inbound = make(map[natKey]*natEntry)
+		}
+		r.nat.outbound[key] = entry
+		r.nat.inbound[natKey{protocol: hdr.Protocol, port: outsidePort}] = entry
+		log.Printf("Router %s: NAT new translation %s %s:%d -> %s:%d", r.ID, entry.translation.Protocol, entry.translation.InsideIP, insidePort, entry.translation.OutsideIP, outsidePort)
+	}
+	entry.translation.RemoteIP = hdr.DstIP.String()
+	entry.translation.LastUsed = now
+	entry.translation.Packets++
+	r.nat.outboundPackets++
+
+	translated := make([]byte, len(packet))
+	copy(translated, packet)
+	copy(translated[12:16], outsideIP.To4())
+	natSetSourceID(translated, ihl, hdr.Protocol, entry.translation.OutsidePort)
+	natFixChecksums(translated, ihl, hdr.Protocol)
+	return translated
+}
+
+// natInbound translates a reply addressed to the router's own IP back to the inside host.
+// Returns the translated packet and true if the packet matched an active translation.
+func (r *Router) natInbound(packet []byte, hdr *IPv4Header) ([]byte, bool) {
+	if !hdr.DstIP.Equal(r.TunDevice.GetIP()) {
+		return nil, false
+	}
+
+	r.nat.mu.Lock()
+	defer r.nat.mu.Unlock()
+	if !r.nat.config.Enabled {
+		return nil, false
+	}
+
+	ihl := int(hdr.IHL) * 4
+	outsidePort, ok := natDestinationID(packet, ihl, hdr.Protocol)
+	if !ok {
+		return nil, false
+	}
+	entry, exists := r.nat.inbound[natKey{protocol: hdr.Protocol, port: outsidePort}]
+	if !exists {
+		return nil, false
+	}
+	now := time.Now()
+	if now.Sub(entry.translation.LastUsed) > NATEntryTimeout {
+		r.nat.remove(entry)
+		return nil, false
+	}
+	entry.translation.LastUsed = now
+	entry.translation.Packets++
+	r.nat.inboundPackets++
+
+	translated := make([]byte, len(packet))
+	copy(translated, packet)
+	copy(translated[16:20], net.ParseIP(entry.translation.InsideIP).To4())
+	natSetDestinationID(translated, ihl, hdr.Protocol, entry.translation.InsidePort)
+	natFixChecksums(translated, ihl, hdr.Protocol)
+	return translated, true
+}
+
+// allocatePort returns an unused outside port (or ICMP identifier) for the protocol. Caller must hold mu.
+func (t *natTable) allocatePort(protocol byte) (uint16, bool) {
+	size := NATPortRangeEnd - NATPortRangeStart + 1
+	for i := 0; i < size; i++ {
+		if t.nextPort < NATPortRangeStart || t.nextPort > NATPortRangeEnd {
+			t.nextPort = NATPortRangeStart
+		}
+		port := t.nextPort
+		t.nextPort++
+		if _, used := t.inbound[natKey{protocol: protocol, port: port}]; !used {
+			return port, true
+		}
+	}
+	return 0, false
+}
+
+// expire removes translations unused for NATEntryTimeout. Caller must hold mu.
+func (t *natTable) expire(now time.Time) {
+	for _, entry := range t.outbound {
+		if now.Sub(entry.translation.LastUsed) > NATEntryTimeout {
+			t.remove(entry)
+		}
+	}
+}
+
+// remove deletes a translation. Caller must hold mu.
+func (t *natTable) remove(entry *natEntry) {
+	delete(t.outbound, natKey{protocol: entry.protocol, ip: entry.translation.InsideIP, port: entry.translation.InsidePort})
+	delete(t.inbound, natKey{protocol: entry.protocol, port: entry.translation.OutsidePort})
+}
+
+func protocolName(protocol byte) string {
+	switch protocol {
+	case TCPProtocolNumber:
+		return "tcp"
+	case UDPProtocolNumber:
+		return "udp"
+	case ICMPProtocolNumber:
+		return "icmp"
+	default:
+		return fmt.Sprintf("proto-%d", protocol)
+	}
+}
+
+// natSourceID returns the source port (TCP/UDP) or the identifier of an ICMP echo request.
+func natSourceID(packet []byte, ihl int, protocol byte) (uint16, bool) {
+	switch protocol {
+	case TCPProtocolNumber, UDPProtocolNumber:
+		if len(packet) < ihl+4 {
+			return 0, false
+		}
+		return binary.BigEndian.Uint16(packet[ihl : ihl+2]), true
+	case ICMPProtocolNumber:
+		if len(packet) < ihl+8 || packet[ihl] != 8 { // Echo Request
+			return 0, false
+		}
+		return binary.BigEndian.Uint16(packet[ihl+4 : ihl+6]), true
+	}
+	return 0, false
+}
+
+// natDestinationID returns the destination port (TCP/UDP) or the identifier of an ICMP echo reply.
+func natDestinationID(packet []byte, ihl int, protocol byte) (uint16, bool) {
+	switch protocol {
+	case TCPProtocolNumber, UDPProtocolNumber:
+		if len(packet) < ihl+4 {
+			return 0, false
+		}
+		return binary.BigEndian.Uint16(packet[ihl+2 : ihl+4]), true
+	case ICMPProtocolNumber:
+		if len(packet) < ihl+8 || packet[ihl] != 0 { // Echo Reply
+			return 0, false
+		}
+		return binary.BigEndian.Uint16(packet[ihl+4 : ihl+6]), true
+	}
+	return 0, false
+}
+
+func natSetSourceID(packet []byte, ihl int, protocol byte, id uint16) {
+	if protocol == ICMPProtocolNumber {
+		binary.BigEndian.PutUint16(packet[ihl+4:ihl+6], id)
+		return
+	}
+	binary.BigEndian.PutUint16(packet[ihl:ihl+2], id)
+}
+
+func natSetDestinationID(packet []byte, ihl int, protocol byte, id uint16) {
+	if protocol == ICMPProtocolNumber {
+		binary.BigEndian.PutUint16(packet[ihl+4:ihl+6], id)
+		return
+	}
+	binary.BigEndian.PutUint16(packet[ihl+2:ihl+4], id)
+}
+
+// natFixChecksums recalculates the IPv4 header checksum and the TCP/UDP/ICMP checksum after rewriting.
+func natFixChecksums(packet []byte, ihl int, protocol byte) {
+	binary.BigEndian.PutUint16(packet[10:12], calculateIPv4Checksum(packet[:ihl]))
+
+	end := len(packet)
+	if totalLength := int(binary.BigEndian.Uint16(packet[2:4])); totalLength >= ihl && totalLength < end {
+		end = totalLength
+	}
+	segment := packet[ihl:end]
+	switch protocol {
+	case TCPProtocolNumber:
+		if len(segment) >= 18 {
+			segment[16], segment[17] = 0, 0
+			binary.BigEndian.PutUint16(segment[16:18], transportChecksum(packet[12:16], packet[16:20], protocol, segment))
+		}
+	case UDPProtocolNumber:
+		// A zero UDP checksum means "no checksum" and is left as is
+		if len(segment) >= 8 && (segment[6] != 0 || segment[7] != 0) {
+			segment[6], segment[7] = 0, 0
+			checksum := transportChecksum(packet[12:16], packet[16:20], protocol, segment)
+			if checksum == 0 {
+				checksum = 0xFFFF
+			}
+			binary.BigEndian.PutUint16(segment[6:8], checksum)
+		}
+	case ICMPProtocolNumber:
+		if len(segment) >= 4 {
+			segment[2], segment[3] = 0, 0
+			binary.BigEndian.PutUint16(segment[2:4], calculateICMPChecksum(segment))
+		}
+	}
+}
+
+// transportChecksum calculates the TCP/UDP checksum including the IPv4 pseudo header.
+func transportChecksum(srcIP, dstIP []byte, protocol byte, segment []byte) uint16 {
+	pseudo := make([]byte, 12, 12+len(segment))
+	copy(pseudo[0:4], srcIP)
+	copy(pseudo[4:8], dstIP)
+	pseudo[9] = protocol
+	binary.BigEndian.PutUint16(pseudo[10:12], uint16(len(segment)))
+	// calculateICMPChecksum is a plain Internet checksum over the given bytes
+	return calculateICMPChecksum(append(pseudo, segment...))
+}
@@ -0,0 +1,115 @@
+package router
+
+import (
+	"log"
+	"time"
+)
+
+// PacketLogSize is the number of recent packet log entries each router keeps.
+const PacketLogSize = 100
+
+// Packet log actions
+const (
+	PacketActionLocal     = "local"     // Destined for the router itself
+	PacketActionDelivered = "delivered" // Written to the directly connected network
+	PacketActionForwarded = "forwarded" // Relayed to the next hop router
+	PacketActionNAT       = "nat"       // Reply translated back by NAT (logged again after translation)
+	PacketActionDenied    = "denied"    // Dropped by an ACL rule
+	PacketActionNoRoute   = "no-route"  // Dropped because there is no route
+	PacketActionDropped   = "dropped"   // Dropped for another reason
+)
+
+// PacketLogEntry is a summary of a data packet processed by a router.
+// Routing protocol packets (OSPF-like and RIP) are not logged.
+type PacketLogEntry struct {
+	Time     time.Time `json:"time"`
+	RouterID string    `json:"routerId"`
+	Src      string    `json:"src"`
+	Dst      string    `json:"dst"`
+	Protocol string    `json:"protocol"`
+	Length   int       `json:"length"`
+	Action   string    `json:"action"`
+	NextHop  string    `json:"nextHop,omitempty"`
+}
+
+// packetLog is a fixed-size ring buffer of recent packet log entries. The zero value is ready to use.
+type packetLog struct {
+	entries []PacketLogEntry
+	next    int
+}
+
+// logPacket records a packet in the router's packet log and publishes it to clients tailing this router.
+func (r *Router) logPacket(hdr *IPv4Header, length int, action string, nextHop string) {
+	entry := PacketLogEntry{
+		Time:     time.Now(),
+		RouterID: r.ID,
+		Src:      hdr.SrcIP.String(),
+		Dst:      hdr.DstIP.String(),
+		Protocol: protocolName(hdr.Protocol),
+		Length:   length,
+		Action:   action,
+		NextHop:  nextHop,
+	}
+
+	r.packetLogMutex.Lock()
+	if len(r.packetLog.entries) < PacketLogSize {
+		r.packetLog.entries = append(r.packetLog.entries, entry)
+	} else {
+		r.packetLog.entries[r.packetLog.next] = entry
+	}
+	r.packetLog.next = (r.packetLog.next + 1) % PacketLogSize
+	r.packetLogMutex.Unlock()
+
+	if r.manager != nil {
+		r.manager.publishPacketLog(entry)
+	}
+}
+
+// GetPacketLog returns the recent packet log entries, oldest first.
+func (r *Router) GetPacketLog() []PacketLogEntry {
+	r.packetLogMutex.Lock()
+	defer r.packetLogMutex.Unlock()
+	entries := make([]PacketLogEntry, 0, len(r.packetLog.entries))
+	if len(r.packetLog.entries) < PacketLogSize {
+		return append(entries, r.packetLog.entries...)
+	}
+	entries = append(entries, r.packetLog.entries[r.packetLog.next:]...)
+	return append(entries, r.packetLog.entries[:r.packetLog.next]...)
+}
+
+// SetPacketTail starts (enabled=true) or stops one tail of the router's packet log.
+// PACKET_LOG events are only broadcast while at least one client is tailing the router.
+func (m *RouterManager) SetPacketTail(routerID string, enabled bool) {
+	m.tailMutex.Lock()
+	defer m.tailMutex.Unlock()
+	if enabled {
+		m.packetTails[routerID]++
+		return
+	}
+	if m.packetTails[routerID] > 0 {
+		m.packetTails[routerID]--
+	}
+	if m.packetTails[routerID] == 0 {
+		delete(m.packetTails, routerID)
+	}
+}
+
+// publishPacketLog broadcasts a PACKET_LOG event if the router is being tailed.
+// The packet path must never block on slow WebSocket clients, so the event is dropped if the channel is full.
+func (m *RouterManager) publishPacketLog(entry PacketLogEntry) {
+	m.tailMutex.Lock()
+	tailed := m.packetTails[entry.RouterID] > 0
+	m.tailMutex.Unlock()
+	if !tailed {
+		return
+	}
+	select {
+	case m.BroadcastOutChan <- map[string]interface{}{
+		"event":    "PACKET_LOG",
+		"routerId": entry.RouterID,
+		"packet":   entry,
+	}:
+	default:
+		log.Printf("RouterManager: Broadcast channel full, PACKET_LOG event for %s dropped", entry.RouterID)
+	}
+}
@@ -0,0 +1,169 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Topology persistence.
+// RouterManager saves a snapshot of the topology (routers, their extra interfaces and static routes, and the
+// connections between them) to a TopologyStore after every change, and RestoreTopology rebuilds it on boot.
+// Dynamically learned state (OSPF, RIP, NAT translations, counters) is not persisted; it is re-learned.
+
+// RouterRecord is the persisted configuration of a router.
+type RouterRecord struct {
+	ID           string
+	TunName      string
+	IPCIDR       string
+	MTU          int
+	Interfaces   []InterfaceConfig // Parents before their VLAN sub-interfaces
+	StaticRoutes []StaticRouteRecord
+}
+
+// StaticRouteRecord is a persisted static route.
+type StaticRouteRecord struct {
+	Destination string
+	NextHop     string
+	Metric      int
+}
+
+// Topology is a snapshot of everything needed to rebuild the virtual network.
+type Topology struct {
+	Routers     []RouterRecord
+	Connections []ConnectionInfo // Stats are not persisted
+}
+
+// TopologyStore loads and saves topology snapshots.
+type TopologyStore interface {
+	Load() (*Topology, error)
+	Save(topology *Topology) error
+}
+
+// storeState holds the store of a RouterManager. The zero value saves nothing.
+type storeState struct {
+	mu    sync.Mutex // Serializes saves so that snapshots are written in order
+	store TopologyStore
+}
+
+// SetStore sets the store that the topology is saved to after every change.
+// It is called after RestoreTopology so that restoring does not rewrite the saved topology halfway.
+func (m *RouterManager) SetStore(store TopologyStore) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	m.store.store = store
+}
+
+// Snapshot returns the current topology.
+func (m *RouterManager) Snapshot() *Topology {
+	topology := &Topology{}
+
+	m.mutex.RLock()
+	routers := make([]*Router, 0, len(m.routers))
+	for _, r := range m.routers {
+		routers = append(routers, r)
+	}
+	m.mutex.RUnlock()
+	sort.Slice(routers, func(i, j int) bool { return routers[i].ID < routers[j].ID })
+
+	exists := make(map[string]bool, len(routers))
+	for _, r := range routers {
+		exists[r.ID] = true
+		record := RouterRecord{
+			ID:      r.ID,
+			TunName: r.TunDevice.GetName(),
+			IPCIDR:  r.config.TunIPAddress,
+			MTU:     r.TunDevice.MTU,
+		}
+		for _, ifc := range r.GetInterfaces() {
+			if ifc.Primary {
+				continue
+			}
+			record.Interfaces = append(record.Interfaces, InterfaceConfig{Name: ifc.Name, IPCIDR: ifc.IPCIDR, Parent: ifc.Parent, VLANID: ifc.VLANID})
+		}
+		// TUN interfaces first, so that parents are restored before their sub-interfaces
+		sort.SliceStable(record.Interfaces, func(i, j int) bool {
+			return record.Interfaces[i].Parent == "" && record.Interfaces[j].Parent != ""
+		})
+		for _, route := range r.GetStaticRoutes() {
+			record.StaticRoutes = append(record.StaticRoutes, StaticRouteRecord{Destination: route.Network, NextHop: route.NextHop, Metric: route.Metric})
+		}
+		sort.Slice(record.StaticRoutes, func(i, j int) bool { return record.StaticRoutes[i].Destination < record.StaticRoutes[j].Destination })
+		topology.Routers = append(topology.Routers, record)
+	}
+
+	for _, conn := range m.GetConnections() {
+		// Connections are not removed together with their routers; they are dropped from the snapshot instead
+		if !exists[conn.Router1ID] || !exists[conn.Router2ID] {
+			continue
+		}
+		conn.Stats = LinkStats{}
+		topology.Connections = append(topology.Connections, conn)
+	}
+	sort.Slice(topology.Connections, func(i, j int) bool {
+		return topology.Connections[i].CreatedAt.Before(topology.Connections[j].CreatedAt)
+	})
+	return topology
+}
+
+// SaveTopology writes the current topology to the store, if any. Errors are logged; the change itself stays applied.
+func (m *RouterManager) SaveTopology() {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if m.store.store == nil {
+		return
+	}
+	topology := m.Snapshot()
+	if err := m.store.store.Save(topology); err != nil {
+		log.Printf("RouterManager: Failed to save topology: %v", err)
+		return
+	}
+	log.Printf("RouterManager: Saved topology (%d routers, %d connections)", len(topology.Routers), len(topology.Connections))
+}
+
+// RestoreTopology recreates the routers (including their TUN devices), interfaces, connections and static routes
+// of a saved topology. Items that cannot be restored are skipped; the errors are returned together.
+func (m *RouterManager) RestoreTopology(topology *Topology) error {
+	var errs []error
+	for _, record := range topology.Routers {
+		r, err := m.CreateAndStartRouter(record.ID, record.TunName, record.IPCIDR, record.MTU)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("router %s: %w", record.ID, err))
+			continue
+		}
+		for _, ifc := range record.Interfaces {
+			if _, err := m.AddInterface(r.ID, ifc); err != nil {
+				errs = append(errs, fmt.Errorf("interface %s of router %s: %w", ifc.Name, r.ID, err))
+			}
+		}
+	}
+
+	for _, conn := range topology.Connections {
+		restored, err := m.addConnection(conn.ID, conn.Router1ID, conn.Router2ID, conn.CreatedAt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("connection %s: %w", conn.ID, err))
+			continue
+		}
+		if conn.Impairment.impaired() {
+			if _, err := m.SetConnectionImpairment(restored.ID, conn.Impairment); err != nil {
+				errs = append(errs, fmt.Errorf("impairment of connection %s: %w", conn.ID, err))
+			}
+		}
+	}
+
+	for _, record := range topology.Routers {
+		for _, route := range record.StaticRoutes {
+			if _, err := m.AddStaticRoute(record.ID, route.Destination, route.NextHop, route.Metric); err != nil {
+				errs = append(errs, fmt.Errorf("static route %s of router %s: %w", route.Destination, record.ID, err))
+			}
+		}
+	}
+
+	log.Printf("RouterManager: Restored topology (%d routers, %d connections, %d errors)", len(topology.Routers), len(topology.Connections), len(errs))
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to restore part of the topology: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package router
+
+import (
+	"bytes"
+	"encoding/gob"
+	"log"
+	"net"
+	"time"
+)
+
+// RIP-style distance-vector routing between routers connected via RouterManager.
+// Each router periodically sends its distance vector (directly connected networks and RIP-learned routes)
+// to its connected peers. Learned routes time out when they stop being refreshed, are advertised with
+// an infinite metric (route poisoning) and are kept in hold-down before being flushed.
+
+const (
+	RIPProtocolNumber = 254 // Experimental protocol number (OSPF-like protocol uses 253)
+	RIPInfinity       = 16  // Metric meaning "unreachable"
+	RIPUpdateInterval = 10 * time.Second
+	RIPRouteTimeout   = 30 * time.Second // A route not refreshed within this period is invalidated
+	RIPHoldDownTime   = 30 * time.Second // Invalidated routes ignore worse/equal updates for this period, then are flushed
+	RIPTimerInterval  = 1 * time.Second
+
+	// RouteSourceRIP is the LearnedFrom value for routes learned via RIP.
+	RouteSourceRIP = "RIP"
+)
+
+// RIPEntry is one destination in a RIP update.
+type RIPEntry struct {
+	Network string // Destination network CIDR
+	Metric  int    // Sender's metric to the network (RIPInfinity = unreachable)
+}
+
+// RIPPacket is a RIP update (a full distance vector) sent to a connected peer.
+type RIPPacket struct {
+	RouterID string
+	Entries  []RIPEntry
+}
+
+// ripRoute is a route in the RIP database.
+type ripRoute struct {
+	Network         string
+	NextHop         string // IP of the peer the route was learned from
+	NextHopRouterID string
+	Metric          int
+	LastUpdated     time.Time
+	HoldDownUntil   time.Time // Non-zero while the route is invalid (poisoned) and in hold-down
+	PrevMetric      int       // Metric before invalidation; only better updates are accepted during hold-down
+}
+
+func (rt *ripRoute) isValid() bool {
+	return rt.Metric < RIPInfinity
+}
+
+// ripLoop sends periodic and triggered RIP updates and runs the timeout/hold-down timers.
+func (r *Router) ripLoop() {
+	defer r.wg.Done()
+	updateTicker := time.NewTicker(RIPUpdateInterval)
+	timerTicker := time.NewTicker(RIPTimerInterval)
+	defer updateTicker.Stop()
+	defer timerTicker.Stop()
+
+	log.Printf("Router %s: RIP loop started.", r.ID)
+
+	for {
+		select {
+		case <-r.shutdown:
+			log.Printf("Router %s: Shutting down RIP loop.", r.ID)
+			return
+		case <-updateTicker.C:
+			r.sendRIPUpdate()
+		case <-r.ripTrigger:
+			r.sendRIPUpdate()
+		case now := <-timerTicker.C:
+			if r.checkRIPTimers(now) {
+				r.triggerRIPUpdate()
+			}
+		}
+	}
+}
+
+// triggerRIPUpdate requests a triggered update without blocking.
+// Updates are always sent from ripLoop so that packet handlers never relay packets recursively.
+func (r *Router) triggerRIPUpdate() {
+	select {
+	case r.ripTrigger <- struct{}{}:
+	default:
+	}
+}
+
+// buildRIPUpdate returns the distance vector to advertise to the peer with the given IP.
+// Routes learned from that peer are advertised with an infinite metric (split horizon with poisoned reverse),
+// and invalidated routes are advertised with an infinite metric (route poisoning).
+func (r *Router) buildRIPUpdate(peerIP string) []RIPEntry {
+	var entries []RIPEntry
+	for _, connected := range r.connectedNetworks() {
+		entries = append(entries, RIPEntry{Network: connected.Network, Metric: 0})
+	}
+
+	r.ripMutex.RLock()
+	defer r.ripMutex.RUnlock()
+	for network, route := range r.RIPRoutes {
+		metric := route.Metric
+		if route.NextHop == peerIP {
+			metric = RIPInfinity
+		}
+		entries = append(entries, RIPEntry{Network: network, Metric: metric})
+	}
+	return entries
+}
+
+// sendRIPUpdate sends a RIP update to every connected peer via RouterManager.
+func (r *Router) sendRIPUpdate() {
+	if r.manager == nil {
+		return
+	}
+
+	r.peersMutex.RLock()
+	peers := make(map[string]net.IP, len(r.ConnectedPeers))
+	for peerID, peerIP := range r.ConnectedPeers {
+		peers[peerID] = peerIP
+	}
+	r.peersMutex.RUnlock()
+
+	for peerID, peerIP := range peers {
+		update := RIPPacket{RouterID: r.ID, Entries: r.buildRIPUpdate(peerIP.String())}
+
+		var buffer bytes.Buffer
+		if err := gob.NewEncoder(&buffer).Encode(update); err != nil {
+			log.Printf("Router %s: Error encoding RIP update: %v", r.ID, err)
+			return
+		}
+		ripData := buffer.Bytes()
+
+		ipHeader := &IPv4Header{
+			Version:  4,
+			IHL:      5,
+			TTL:      1, // RIP updates are only sent to directly connected peers
+			Protocol: RIPProtocolNumber,
+			SrcIP:    r.TunDevice.IP,
+			DstIP:    peerIP,
+		}
+		finalPacket, err := constructIPPacket(ipHeader, ripData)
+		if err != nil {
+			log.Printf("Router %s: Error constructing IP packet for RIP update to peer %s (%s): %v", r.ID, peerID, peerIP.String(), err)
+			continue
+		}
+		if !r.manager.RelayPacket(r.ID, peerIP, finalPacket) {
+			log.Printf("Router %s: Failed to relay RIP update to peer %s (%s) via RouterManager.", r.ID, peerID, peerIP.String())
+		}
+	}
+}
+
+// handleRIPPacket decodes a RIP update received from a peer and applies it.
+func (r *Router) handleRIPPacket(packetData []byte, sourceIP net.IP) {
+	var update RIPPacket
+	if err := gob.NewDecoder(bytes.NewBuffer(packetData)).Decode(&update); err != nil {
+		log.Printf("Router %s: Error decoding RIP update from %s: %v", r.ID, sourceIP.String(), err)
+		return
+	}
+	if update.RouterID == r.ID {
+		return
+	}
+	if r.processRIPUpdate(&update, sourceIP.String(), time.Now()) {
+		r.syncRIPRoutes()
+		r.triggerRIPUpdate()
+	}
+}
+
+// processRIPUpdate applies the Bellman-Ford update rule to the RIP database.
+// Returns true if any route changed (so that a triggered update should be sent).
+func (r *Router) processRIPUpdate(update *RIPPacket, fromIP string, now time.Time) bool {
+	ownNetworks := make(map[string]bool)
+	for _, connected := range r.connectedNetworks() {
+		ownNetworks[connected.Network] = true
+	}
+
+	r.ripMutex.Lock()
+	defer r.ripMutex.Unlock()
+
+	changed := false
+	for _, entry := range update.Entries {
+		_, ipNet, err := net.ParseCIDR(entry.Network)
+		if err != nil {
+			continue
+		}
+		network := ipNet.String()
+		if ownNetworks[network] {
+			continue // Directly connected networks are never learned
+		}
+		metric := entry.Metric + 1
+		if metric > RIPInfinity {
+			metric = RIPInfinity
+		}
+
+		existing, exists := r.RIPRoutes[network]
+		switch {
+		case !exists:
+			if metric >= RIPInfinity {
+				continue
+			}
+			r.RIPRoutes[network] = &ripRoute{
+				Network:         network,
+				NextHop:         fromIP,
+				NextHopRouterID: update.RouterID,
+				Metric:          metric,
+				LastUpdated:     now,
+			}
+			log.Printf("Router %s: RIP learned %s via %s (%s) metric=%d", r.ID, network, fromIP, update.RouterID, metric)
+			changed = true
+
+		case !existing.isValid():
+			// Hold-down: only accept a route that is better than the one that was lost
+			if metric < existing.PrevMetric {
+				*existing = ripRoute{
+					Network:         network,
+					NextHop:         fromIP,
+					NextHopRouterID: update.RouterID,
+					Metric:          metric,
+					LastUpdated:     now,
+				}
+				log.Printf("Router %s: RIP accepted better route %s via %s during hold-down metric=%d", r.ID, network, fromIP, metric)
+				changed = true
+			}
+
+		case existing.NextHop == fromIP:
+			existing.LastUpdated = now
+			if metric >= RIPInfinity {
+				r.invalidateRIPRoute(existing, now)
+				log.Printf("Router %s: RIP route %s poisoned by %s, entering hold-down", r.ID, network, update.RouterID)
+				changed = true
+			} else if metric != existing.Metric {
+				existing.Metric = metric
+				changed = true
+			}
+
+		case metric < existing.Metric:
+			existing.NextHop = fromIP
+			existing.NextHopRouterID = update.RouterID
+			existing.Metric = metric
+			existing.LastUpdated = now
+			log.Printf("Router %s: RIP switched %s to better route via %s metric=%d", r.ID, network, fromIP, metric)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// invalidateRIPRoute marks the route unreachable and starts hold-down. Caller must hold ripMutex.
+func (r *Router) invalidateRIPRoute(route *ripRoute, now time.Time) {
+	route.PrevMetric = route.Metric
+	route.Metric = RIPInfinity
+	route.HoldDownUntil = now.Add(RIPHoldDownTime)
+}
+
+// checkRIPTimers invalidates timed-out routes and flushes routes whose hold-down has expired.
+// Returns true if any route changed.
+func (r *Router) checkRIPTimers(now time.Time) bool {
+	r.ripMutex.Lock()
+	changed := false
+	for network, route := range r.RIPRoutes {
+		if route.isValid() {
+			if now.Sub(route.LastUpdated) > RIPRouteTimeout {
+				log.Printf("Router %s: RIP route %s via %s timed out, entering hold-down", r.ID, network, route.NextHop)
+				r.invalidateRIPRoute(route, now)
+				changed = true
+			}
+		} else if now.After(route.HoldDownUntil) {
+			log.Printf("Router %s: RIP route %s flushed after hold-down", r.ID, network)
+			delete(r.RIPRoutes, network)
+		}
+	}
+	r.ripMutex.Unlock()
+
+	if changed {
+		r.syncRIPRoutes()
+	}
+	return changed
+}
+
+// ripPeerDown immediately invalidates all routes learned from the given peer (e.g. when its connection is removed).
+func (r *Router) ripPeerDown(peerIP string) {
+	now := time.Now()
+	r.ripMutex.Lock()
+	changed := false
+	for _, route := range r.RIPRoutes {
+		if route.NextHop == peerIP && route.isValid() {
+			r.invalidateRIPRoute(route, now)
+			changed = true
+		}
+	}
+	r.ripMutex.Unlock()
+
+	if changed {
+		r.syncRIPRoutes()
+		r.triggerRIPUpdate()
+	}
+}
+
+// syncRIPRoutes updates the routing table with the current RIP database and notifies the change.
+func (r *Router) syncRIPRoutes() {
+	r.rtMutex.Lock()
+	defer r.rtMutex.Unlock()
+	for network, entry := range r.RoutingTable {
+		if entry.LearnedFrom == RouteSourceRIP {
+			delete(r.RoutingTable, network)
+		}
+	}
+	r.installRIPRoutes(r.RoutingTable)
+	r.notifyRoutingTableUpdate()
+}
+
+// installRIPRoutes adds valid RIP routes to table for networks not already reachable by a
+// directly connected, static or OSPF route. Caller must hold rtMutex.
+func (r *Router) installRIPRoutes(table map[string]*RoutingEntry) {
+	r.ripMutex.RLock()
+	defer r.ripMutex.RUnlock()
+	for network, route := range r.RIPRoutes {
+		if !route.isValid() {
+			continue
+		}
+		if _, exists := table[network]; exists {
+			continue
+		}
+		table[network] = &RoutingEntry{
+			Network:         network,
+			NextHop:         route.NextHop,
+			NextHopRouterID: route.NextHopRouterID,
+			Interface:       r.TunDevice.Name,
+			Metric:          route.Metric,
+			LearnedFrom:     RouteSourceRIP,
+			LastUpdated:     route.LastUpdated,
+		}
+	}
+}
@@ -126,6 +126,8 @@ type Router struct {
 	ID                     string
 	TunDevice              *TUNDevice
 	RoutingTable           map[string]*RoutingEntry    // Destination CIDR -> Entry
+	StaticRoutes           map[string]*RoutingEntry    // Destination CIDR -> Static route (merged into RoutingTable on every SPF run)
+	RIPRoutes              map[string]*ripRoute        // Destination CIDR -> RIP-learned route (including routes in hold-down)
 	Neighbors              map[string]*Neighbor        // Neighbor RouterID -> Neighbor Info
 	LSUDB                  map[string]*LinkStateUpdate // OriginatingRouterID -> LSU
 	shutdown               chan struct{}
@@ -139,9 +141,25 @@ type Router struct {
 	neighborMutex sync.RWMutex
 	lsudbMutex    sync.RWMutex
 	peersMutex    sync.RWMutex // Mutex for ConnectedPeers
+	ripMutex      sync.RWMutex // Mutex for RIPRoutes
 	ConnectedPeers map[string]net.IP // RouterID -> IP address of connected peer's TUN device
 
 	manager *RouterManager // Reference to the RouterManager for relaying packets
+
+	ripTrigger chan struct{} // Requests a triggered RIP update
+
+	nat natTable // NAT (masquerade) configuration and translations
+	acl aclList  // Firewall rules evaluated for forwarded packets
+
+	packetLog      packetLog  // Recent data packets processed by the router
+	packetLogMutex sync.Mutex // Mutex for packetLog
+
+	Interfaces map[string]*RouterInterface // Additional interfaces (TUN and VLAN sub-interfaces) by name
+	ifaceMutex sync.RWMutex                // Mutex for Interfaces
+
+	metrics routerMetrics // Per-interface traffic counters and forwarding latency
+
+	dhcp dhcpState // DHCP servers and leases by interface
 }
 
 // RouterConfig holds configuration for a router
@@ -169,6 +187,8 @@ func NewRouter(id string, config RouterConfig, mgr *RouterManager) (*Router, err
 		ID:                     id,
 		TunDevice:              tun, // tun is already *TUNDevice
 		RoutingTable:           make(map[string]*RoutingEntry),
+		StaticRoutes:           make(map[string]*RoutingEntry),
+		RIPRoutes:              make(map[string]*ripRoute),
 		Neighbors:              make(map[string]*Neighbor),
 		LSUDB:                  make(map[string]*LinkStateUpdate),
 		shutdown:               make(chan struct{}),
@@ -177,6 +197,8 @@ func NewRouter(id string, config RouterConfig, mgr *RouterManager) (*Router, err
 		RoutingTableUpdateChan: make(chan []RoutingEntry, 10), // Initialize channel
 		ConnectedPeers:         make(map[string]net.IP),
 		manager:                mgr, // Store the manager reference
+		ripTrigger:             make(chan struct{}, 1),
+		Interfaces:             make(map[string]*RouterInterface),
 	}
 	// Use the Name field directly, and IP.String() for IP
 	log.Printf("Router %s initialized with TUN %s (%s)", r.ID, r.TunDevice.Name, r.TunDevice.IP.String())
@@ -192,15 +214,30 @@ func (r *Router) AddPeer(peerID string, peerIP net.IP) {
 	}
 	r.ConnectedPeers[peerID] = peerIP
 	log.Printf("Router %s: Added peer %s (%s)", r.ID, peerID, peerIP.String())
+	r.triggerRIPUpdate() // Advertise our routes to the new peer right away
+}
+
+// RemovePeer removes a connected peer and invalidates the RIP routes learned from it.
+func (r *Router) RemovePeer(peerID string) {
+	r.peersMutex.Lock()
+	peerIP, exists := r.ConnectedPeers[peerID]
+	delete(r.ConnectedPeers, peerID)
+	r.peersMutex.Unlock()
+	if !exists {
+		return
+	}
+	log.Printf("Router %s: Removed peer %s (%s)", r.ID, peerID, peerIP.String())
+	r.ripPeerDown(peerIP.String())
 }
 
 // Start begins the router's packet processing and routing protocol loops.
 func (r *Router) Start() error {
 	log.Printf("Starting router %s...", r.ID)
-	r.wg.Add(3) // packetProcessingLoop, routingProtocolLoop, lsuGenerationLoop
+	r.wg.Add(4) // packetProcessingLoop, routingProtocolLoop, lsuGenerationLoop, ripLoop
 	go r.packetProcessingLoop()
 	go r.routingProtocolLoop()
 	go r.lsuGenerationLoop()
+	go r.ripLoop()
 
 	// Add directly connected route
 	r.AddDirectlyConnectedRoute()
@@ -227,6 +264,7 @@ func (r *Router) Stop() {
 			log.Printf("Router [%s] error closing TUN device: %v", r.ID, err)
 		}
 	}
+	r.closeInterfaces()
 	log.Printf("Router [%s] stopped.", r.ID)
 }
 
@@ -249,7 +287,8 @@ func (r *Router) packetProcessingLoop() {
 			}
 			if len(packet) > 0 {
 				// log.Printf("Router %s: Received packet of length %d from TUN %s", r.ID, len(packet), r.TunDevice.Name())
-				r.processIncomingPacket(packet)
+				r.countReceived(r.TunDevice.Name, len(packet))
+				r.processIncomingPacket(packet, r.TunDevice.Name)
 			}
 		}
 	}
@@ -441,6 +480,7 @@ func (r *Router) sendHelloPacket() {
 		if err != nil {
 			log.Printf("Router %s: Error sending broadcast Hello packet via TUN %s: %v", r.ID, r.TunDevice.Name, err)
 		} else {
+			r.countSent(r.TunDevice.Name, len(broadcastFinalPacket))
 			log.Printf("Router %s: Sent broadcast Hello packet via %s.", r.ID, r.TunDevice.Name)
 		}
 	}
@@ -537,7 +577,10 @@ func (r *Router) handleOSPFLogic(packetData []byte, sourceIP net.IP) {
 }
 
 // processIncomingPacket is the entry point for all packets read from the TUN device.
-func (r *Router) processIncomingPacket(fullPacket []byte) {
+// inInterface is the interface the packet was received on; drops are counted against it.
+func (r *Router) processIncomingPacket(fullPacket []byte, inInterface string) {
+	received := time.Now()
+
 	// Temporary log to see ALL packets read from TUN before parsing
 	if len(fullPacket) >= 20 { // Basic check for minimum IPv4 header size
 		srcIPRaw := net.IP(fullPacket[12:16])
@@ -551,6 +594,7 @@ func (r *Router) processIncomingPacket(fullPacket []byte) {
 	ipHeader, payload, err := parseIPPacket(fullPacket)
 	if err != nil {
 		log.Printf("Router %s: Error parsing IP packet: %v. Packet: %x", r.ID, err, fullPacket)
+		r.countDrop(inInterface)
 		return
 	}
 
@@ -562,40 +606,45 @@ func (r *Router) processIncomingPacket(fullPacket []byte) {
 		return
 	}
 
-	// Check if the packet is destined for this router's TUN interface IP
-	if ipHeader.DstIP.Equal(r.TunDevice.GetIP()) {
+	// Check if the packet is a RIP update from a connected peer
+	if ipHeader.Protocol == RIPProtocolNumber {
+		r.handleRIPPacket(payload, ipHeader.SrcIP)
+		return
+	}
+
+	// Replies to NATed connections are addressed to us; translate them back and forward to the inside host
+	if translated, ok := r.natInbound(fullPacket, ipHeader); ok {
+		r.logPacket(ipHeader, len(fullPacket), PacketActionNAT, "")
+		r.processIncomingPacket(translated, inInterface)
+		return
+	}
+
+	// Check if the packet is destined for one of this router's interface IPs
+	if r.ownsIP(ipHeader.DstIP) {
 		if ipHeader.Protocol == ICMPProtocolNumber {
 			log.Printf("Router %s: Received ICMP packet for self from %s", r.ID, ipHeader.SrcIP.String())
+			r.logPacket(ipHeader, len(fullPacket), PacketActionLocal, "")
 			r.handleICMPPacket(ipHeader, payload)
 		} else {
 			log.Printf("Router %s: Packet for self (not ICMP, proto %d) from %s. Dropping.", r.ID, ipHeader.Protocol, ipHeader.SrcIP.String())
+			r.logPacket(ipHeader, len(fullPacket), PacketActionDropped, "")
+			r.countDrop(inInterface)
 		}
 		return
 	}
 
-	// Forward the packet
-	r.rtMutex.RLock()
-	defer r.rtMutex.RUnlock()
-
-	var bestMatch *RoutingEntry = nil
-	longestPrefix := -1
-
-	for prefixStr, entry := range r.RoutingTable {
-		_, network, err := net.ParseCIDR(prefixStr)
-		if err != nil {
-			log.Printf("Router %s: Invalid CIDR in routing table: %s", r.ID, prefixStr)
-			continue
-		}
-		if network.Contains(ipHeader.DstIP) {
-			prefixLen, _ := network.Mask.Size()
-			if prefixLen > longestPrefix {
-				longestPrefix = prefixLen
-				bestMatch = entry
-			}
-		}
+	// Apply firewall rules before forwarding
+	if !r.checkACL(ipHeader, fullPacket) {
+		log.Printf("Router %s: Packet from %s to %s (proto %d) denied by ACL. Dropping.", r.ID, ipHeader.SrcIP.String(), ipHeader.DstIP.String(), ipHeader.Protocol)
+		r.logPacket(ipHeader, len(fullPacket), PacketActionDenied, "")
+		r.countDrop(inInterface)
+		return
 	}
 
-	if bestMatch != nil {
+	// Forward the packet
+	bestMatch, found := r.lookupRoute(ipHeader.DstIP)
+
+	if found {
 		if bestMatch.NextHop == "0.0.0.0" { // Directly connected
 			// This case should ideally not happen for forwarding if DstIP is not self.
 			// If it's a directly connected network, the destination is on that link.
@@ -605,45 +654,95 @@ func (r *Router) processIncomingPacket(fullPacket []byte) {
 			// This implies the other host is on the same L2 segment as our TUN.
 			// For directly connected, if DstIP is not self, it means it's for another host on the same segment.
 			// The packet is already an IP packet, just write it back to TUN.
-			log.Printf("Router %s: Dst %s is on directly connected network %s. Writing to TUN %s (Original Dst %s).", r.ID, ipHeader.DstIP.String(), bestMatch.Network, r.TunDevice.Name, ipHeader.DstIP.String())
-			_, err := r.TunDevice.WritePacket(fullPacket)
+			log.Printf("Router %s: Dst %s is on directly connected network %s. Writing to interface %s (Original Dst %s).", r.ID, ipHeader.DstIP.String(), bestMatch.Network, bestMatch.Interface, ipHeader.DstIP.String())
+			device, err := r.interfaceDevice(bestMatch.Interface)
+			if err == nil {
+				_, err = device.WritePacket(fullPacket)
+			}
 			if err != nil {
-				log.Printf("Router %s: Error writing packet to TUN %s for directly connected dst %s: %v", r.ID, r.TunDevice.Name, ipHeader.DstIP.String(), err)
+				log.Printf("Router %s: Error writing packet to interface %s for directly connected dst %s: %v", r.ID, bestMatch.Interface, ipHeader.DstIP.String(), err)
+				r.logPacket(ipHeader, len(fullPacket), PacketActionDropped, "")
+				r.countDrop(bestMatch.Interface)
+			} else {
+				r.observeForwardingLatency(time.Since(received))
+				r.countSent(bestMatch.Interface, len(fullPacket))
+				r.logPacket(ipHeader, len(fullPacket), PacketActionDelivered, "")
 			}
 		} else {
 			// Forward to next hop router via RouterManager
 			nextHopIPAddr := net.ParseIP(bestMatch.NextHop)
 			if nextHopIPAddr == nil {
 				log.Printf("Router %s: Invalid NextHop IP address '%s' in routing table for %s. Packet dropped.", r.ID, bestMatch.NextHop, ipHeader.DstIP.String())
+				r.countDrop(inInterface)
 				return
 			}
 
 			log.Printf("Router %s: Forwarding packet from %s to %s via RouterManager. NextHop IP: %s (RouterID: %s)", r.ID, ipHeader.SrcIP.String(), ipHeader.DstIP.String(), bestMatch.NextHop, bestMatch.NextHopRouterID)
 			if r.manager == nil {
 				log.Printf("Router %s: RouterManager reference is nil. Cannot relay packet.", r.ID)
+				r.countDrop(inInterface)
 				return
 			}
-			relayed := r.manager.RelayPacket(r.ID, nextHopIPAddr, fullPacket)
+			packetToSend := r.natOutbound(fullPacket, ipHeader, bestMatch.NextHopRouterID)
+			if packetToSend == nil {
+				r.logPacket(ipHeader, len(fullPacket), PacketActionDropped, bestMatch.NextHop)
+				r.countDrop(inInterface)
+				return
+			}
+			// Logged and timed before relaying because the next hop processes the packet synchronously
+			r.logPacket(ipHeader, len(fullPacket), PacketActionForwarded, bestMatch.NextHop)
+			r.observeForwardingLatency(time.Since(received))
+			relayed := r.manager.RelayPacket(r.ID, nextHopIPAddr, packetToSend)
 			if !relayed {
 				log.Printf("Router %s: Failed to relay packet via RouterManager to NextHop %s for Dst %s.", r.ID, bestMatch.NextHop, ipHeader.DstIP.String())
+				r.countDrop(bestMatch.Interface)
 			}
 		}
 	} else {
 		log.Printf("Router %s: No route to %s from %s. Packet dropped.", r.ID, ipHeader.DstIP.String(), ipHeader.SrcIP.String())
+		r.logPacket(ipHeader, len(fullPacket), PacketActionNoRoute, "")
+		r.countDrop(inInterface)
+	}
+}
+
+// lookupRoute returns a copy of the longest prefix match route for dst.
+func (r *Router) lookupRoute(dst net.IP) (RoutingEntry, bool) {
+	r.rtMutex.RLock()
+	defer r.rtMutex.RUnlock()
+
+	var bestMatch *RoutingEntry = nil
+	longestPrefix := -1
+
+	for prefixStr, entry := range r.RoutingTable {
+		_, network, err := net.ParseCIDR(prefixStr)
+		if err != nil {
+			log.Printf("Router %s: Invalid CIDR in routing table: %s", r.ID, prefixStr)
+			continue
+		}
+		if network.Contains(dst) {
+			prefixLen, _ := network.Mask.Size()
+			if prefixLen > longestPrefix {
+				longestPrefix = prefixLen
+				bestMatch = entry
+			}
+		}
 	}
+	if bestMatch == nil {
+		return RoutingEntry{}, false
+	}
+	return *bestMatch, true
 }
 
 // generateLSU creates a Link State Update packet for this router.
 // Returns *LinkStateUpdate or nil if no links.
 func (r *Router) generateLSU() *LinkStateUpdate {
 	links := []Link{}
-	// 自分のTUNネットワーク
-	_, tunNet, err := net.ParseCIDR(r.config.TunIPAddress)
-	if err == nil {
+	// 自分のインターフェースのネットワーク (TUN と VLAN サブインターフェース)
+	for _, connected := range r.connectedNetworks() {
 		links = append(links, Link{
 			NeighborRouterID: r.ID,
 			Cost:             0,
-			Network:          tunNet.String(),
+			Network:          connected.Network,
 		})
 	}
 	// 全Neighborの/32
@@ -984,20 +1083,9 @@ func (r *Router) runSPF(caller string) {
 	}
 
 	// Construct new routing table based on SPF results
-	// 1. Add directly connected route (already ensures lowest metric for local networks)
-	_, ipNetSelf, errSelf := net.ParseCIDR(r.config.TunIPAddress)
-	if errSelf == nil {
-		directNetworkCIDR := ipNetSelf.String()
-		newRoutingTable[directNetworkCIDR] = &RoutingEntry{
-			Network:     directNetworkCIDR,
-			NextHop:     "0.0.0.0",
-			Interface:   r.TunDevice.Name,
-			Metric:      0,
-			LearnedFrom: "Direct",
-			LastUpdated: time.Now(),
-		}
-	} else {
-		log.Printf("Router %s: Error parsing own TunIPAddress %s for SPF direct route: %v", r.ID, r.config.TunIPAddress, errSelf)
+	// 1. Add directly connected routes of all interfaces (already ensures lowest metric for local networks)
+	for _, connected := range r.connectedNetworks() {
+		newRoutingTable[connected.Network] = connectedRouteEntry(connected)
 	}
 
 	// dump dist, firstHopToRouter の内容
@@ -1047,6 +1135,12 @@ func (r *Router) runSPF(caller string) {
 		}
 	}
 
+	// 3. Add routes learned via RIP for networks OSPF could not reach
+	r.installRIPRoutes(newRoutingTable)
+
+	// 4. Static routes override dynamically learned routes for the same network
+	r.applyStaticRoutes(newRoutingTable)
+
 	r.RoutingTable = newRoutingTable
 	log.Printf("Router [%s] SPF run complete. Routing table updated (entries: %d).", r.ID, len(r.RoutingTable))
 	for dest, entry := range r.RoutingTable {
@@ -1055,6 +1149,12 @@ func (r *Router) runSPF(caller string) {
 	r.dumpRoutingTable() // Call dumpRoutingTable to log the new table content
 
 	// Notify about routing table update
+	r.notifyRoutingTableUpdate()
+}
+
+// notifyRoutingTableUpdate sends a copy of the routing table to RoutingTableUpdateChan without blocking.
+// Caller must hold rtMutex.
+func (r *Router) notifyRoutingTableUpdate() {
 	tableCopy := make([]RoutingEntry, 0, len(r.RoutingTable))
 	for _, entry := range r.RoutingTable {
 		tableCopy = append(tableCopy, *entry)
@@ -1196,6 +1296,7 @@ func (r *Router) handleICMPPacket(ipHdr *IPv4Header, icmpPayload []byte) {
 		if err != nil {
 			log.Printf("Router %s: Error sending ICMP Echo Reply to %s: %v", r.ID, ipHdr.SrcIP.String(), err)
 		} else {
+			r.countSent(r.TunDevice.Name, len(finalPacket))
 			log.Printf("Router %s: Sent ICMP Echo Reply to %s", r.ID, ipHdr.SrcIP.String())
 		}
 	} else {
@@ -1268,9 +1369,8 @@ func parseIPPacket(packet []byte) (*IPv4Header, []byte, error) {
 }
 
 // dumpRoutingTable prints the routing table to the log.
+// Caller must hold rtMutex (runSPF holds the write lock, so taking RLock here would deadlock).
 func (r *Router) dumpRoutingTable() {
-	r.rtMutex.RLock()
-	defer r.rtMutex.RUnlock()
 	log.Printf("Router %s: Routing Table (%d entries):", r.ID, len(r.RoutingTable))
 	if len(r.RoutingTable) == 0 {
 		log.Printf("Router %s: Routing table is EMPTY", r.ID)
@@ -1281,28 +1381,20 @@ func (r *Router) dumpRoutingTable() {
 	}
 }
 
-// AddDirectlyConnectedRoute adds the route for the TUN interface's network.
+// AddDirectlyConnectedRoute adds the routes for the networks of the router's interfaces.
 func (r *Router) AddDirectlyConnectedRoute() {
-	r.rtMutex.Lock()
-	defer r.rtMutex.Unlock()
-
-	_, ipNet, err := net.ParseCIDR(r.config.TunIPAddress)
-	if err != nil {
-		log.Printf("Router %s: Error parsing TunIPAddress %s for direct route: %v", r.ID, r.config.TunIPAddress, err)
+	connectedNetworks := r.connectedNetworks()
+	if len(connectedNetworks) == 0 {
+		log.Printf("Router %s: Error parsing TunIPAddress %s for direct route", r.ID, r.config.TunIPAddress)
 		return
 	}
 
-	networkCIDR := ipNet.String()
-	entry := &RoutingEntry{
-		Network:     networkCIDR,
-		NextHop:     "0.0.0.0", // Indicates directly connected
-		Interface:   r.TunDevice.Name,
-		Metric:      0,
-		LearnedFrom: "Direct",
-		LastUpdated: time.Now(),
+	r.rtMutex.Lock()
+	defer r.rtMutex.Unlock()
+	for _, connected := range connectedNetworks {
+		r.RoutingTable[connected.Network] = connectedRouteEntry(connected)
+		log.Printf("Router %s: Added directly connected route: %s via %s", r.ID, connected.Network, connected.Interface)
 	}
-	r.RoutingTable[networkCIDR] = entry
-	log.Printf("Router %s: Added directly connected route: %s via %s", r.ID, networkCIDR, r.TunDevice.Name)
 }
 
 // InjectPacket allows the RouterManager to inject a packet directly into this router's processing logic,
@@ -1310,7 +1402,9 @@ func (r *Router) AddDirectlyConnectedRoute() {
 func (r *Router) InjectPacket(packet []byte, fromRouterID string) {
 	// It might be useful to log that this packet was injected rather than read from TUN.
 	log.Printf("Router %s: Packet INJECTED by manager from %s (simulating arrival). Length: %d", r.ID, fromRouterID, len(packet))
-	r.processIncomingPacket(packet) // Process it as if it came from the TUN device
+	// Peers are connected to the primary TUN interface
+	r.countReceived(r.TunDevice.Name, len(packet))
+	r.processIncomingPacket(packet, r.TunDevice.Name) // Process it as if it came from the TUN device
 }
 
 // LSUDBの内容を全てdumpするユーティリティ
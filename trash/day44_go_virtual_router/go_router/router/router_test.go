@@ -1,9 +1,11 @@
 package router
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"testing"
+	"time"
 	// "github.com/stretchr/testify/assert" // testify を使う場合は go get が必要
 )
 
@@ -20,7 +22,7 @@ func TestNewRouter(t *testing.T) {
 	// and initializes fields, rather than full TUNDevice functionality.
 	// A more robust test would mock the TUNDevice interface.
 
-	r, err := NewRouter(routerID, config)
+	r, err := NewRouter(routerID, config, nil)
 
 	if err != nil {
 		// If NewTUNDevice fails, this test might not be fully indicative of NewRouter logic.
@@ -248,3 +250,881 @@ func TestProcessIncomingPacket_Forwarding(t *testing.T) {
 // 1. Run `cd day44_go_virtual_router/go_router && go get github.com/stretchr/testify`
 // 2. Uncomment the import in test files.
 // For now, standard library `testing` is used.
+
+// newTestRouter builds a Router without creating a real TUN device,
+// so that routing table logic can be tested without root privileges.
+func newTestRouter(id, ipCIDR string) *Router {
+	ip, ipNet, _ := net.ParseCIDR(ipCIDR)
+	return &Router{
+		ID:                     id,
+		TunDevice:              &TUNDevice{Name: "tun-" + id, IP: ip, Mask: ipNet.Mask},
+		RoutingTable:           make(map[string]*RoutingEntry),
+		StaticRoutes:           make(map[string]*RoutingEntry),
+		RIPRoutes:              make(map[string]*ripRoute),
+		Neighbors:              make(map[string]*Neighbor),
+		LSUDB:                  make(map[string]*LinkStateUpdate),
+		shutdown:               make(chan struct{}),
+		config:                 RouterConfig{TunInterfaceName: "tun-" + id, TunIPAddress: ipCIDR},
+		RoutingTableUpdateChan: make(chan []RoutingEntry, 10),
+		ConnectedPeers:         make(map[string]net.IP),
+		ripTrigger:             make(chan struct{}, 1),
+	}
+}
+
+func findRoute(table []RoutingEntry, network string) (RoutingEntry, bool) {
+	for _, entry := range table {
+		if entry.Network == network {
+			return entry, true
+		}
+	}
+	return RoutingEntry{}, false
+}
+
+func TestStaticRoutes(t *testing.T) {
+	r := newTestRouter("routerA", "10.0.1.1/24")
+	r.AddDirectlyConnectedRoute()
+	r.AddPeer("routerB", net.ParseIP("10.0.2.1"))
+
+	route, err := r.AddStaticRoute("192.168.10.5/24", "10.0.2.1", 5)
+	if err != nil {
+		t.Fatalf("AddStaticRoute() error = %v", err)
+	}
+	if route.Network != "192.168.10.0/24" || route.NextHop != "10.0.2.1" || route.Metric != 5 || route.LearnedFrom != RouteSourceStatic {
+		t.Errorf("AddStaticRoute() = %+v, want 192.168.10.0/24 via 10.0.2.1 metric 5 (Static)", route)
+	}
+	if route.NextHopRouterID != "routerB" {
+		t.Errorf("AddStaticRoute() NextHopRouterID = %q, want routerB", route.NextHopRouterID)
+	}
+	select {
+	case table := <-r.RoutingTableUpdateChan:
+		if _, ok := findRoute(table, "192.168.10.0/24"); !ok {
+			t.Errorf("routing table update does not contain the static route: %+v", table)
+		}
+	default:
+		t.Errorf("AddStaticRoute() did not notify RoutingTableUpdateChan")
+	}
+
+	// Static routes must survive SPF, which rebuilds the routing table from scratch
+	r.runSPF("TestStaticRoutes")
+	if _, ok := findRoute(r.GetRoutingTable(), "192.168.10.0/24"); !ok {
+		t.Errorf("static route disappeared after runSPF: %+v", r.GetRoutingTable())
+	}
+	if _, ok := findRoute(r.GetRoutingTable(), "10.0.1.0/24"); !ok {
+		t.Errorf("direct route disappeared after runSPF: %+v", r.GetRoutingTable())
+	}
+
+	invalid := []struct {
+		destination, nextHop string
+		metric               int
+	}{
+		{"not-a-cidr", "10.0.2.1", 1},
+		{"192.168.20.0/24", "bogus", 1},
+		{"192.168.20.0/24", "10.0.1.1", 1}, // own address
+		{"192.168.20.0/24", "10.0.2.1", -1},
+		{"10.0.1.0/24", "10.0.2.1", 1}, // directly connected
+	}
+	for _, tc := range invalid {
+		if _, err := r.AddStaticRoute(tc.destination, tc.nextHop, tc.metric); err == nil {
+			t.Errorf("AddStaticRoute(%q, %q, %d) succeeded, want error", tc.destination, tc.nextHop, tc.metric)
+		}
+	}
+
+	if _, err := r.RemoveStaticRoute("192.168.10.0/24"); err != nil {
+		t.Fatalf("RemoveStaticRoute() error = %v", err)
+	}
+	if _, ok := findRoute(r.GetRoutingTable(), "192.168.10.0/24"); ok {
+		t.Errorf("static route still in routing table after RemoveStaticRoute")
+	}
+	if len(r.GetStaticRoutes()) != 0 {
+		t.Errorf("GetStaticRoutes() = %+v, want empty", r.GetStaticRoutes())
+	}
+	if _, err := r.RemoveStaticRoute("192.168.10.0/24"); err == nil {
+		t.Errorf("RemoveStaticRoute() of a missing route succeeded, want error")
+	}
+}
+
+func TestRouterManagerStaticRouteEvents(t *testing.T) {
+	events := make(chan map[string]interface{}, 10)
+	m := NewRouterManager(events)
+	r := newTestRouter("routerA", "10.0.1.1/24")
+	r.manager = m
+	m.routers[r.ID] = r
+
+	if _, err := m.AddStaticRoute("routerA", "192.168.10.0/24", "10.0.2.1", 1); err != nil {
+		t.Fatalf("AddStaticRoute() error = %v", err)
+	}
+	if ev := <-events; ev["event"] != "ROUTE_ADDED" || ev["routerId"] != "routerA" {
+		t.Errorf("event = %+v, want ROUTE_ADDED for routerA", ev)
+	}
+
+	if err := m.RemoveStaticRoute("routerA", "192.168.10.0/24"); err != nil {
+		t.Fatalf("RemoveStaticRoute() error = %v", err)
+	}
+	if ev := <-events; ev["event"] != "ROUTE_REMOVED" || ev["routerId"] != "routerA" {
+		t.Errorf("event = %+v, want ROUTE_REMOVED for routerA", ev)
+	}
+
+	if _, err := m.AddStaticRoute("missing", "192.168.10.0/24", "10.0.2.1", 1); err == nil {
+		t.Errorf("AddStaticRoute() on unknown router succeeded, want error")
+	}
+}
+
+func TestRIPLearnsRoutesFromPeers(t *testing.T) {
+	m := NewRouterManager(make(chan map[string]interface{}, 100))
+	a := newTestRouter("routerA", "10.0.1.1/24")
+	b := newTestRouter("routerB", "10.0.2.1/24")
+	c := newTestRouter("routerC", "10.0.3.1/24")
+	for _, r := range []*Router{a, b, c} {
+		r.manager = m
+		m.routers[r.ID] = r
+		r.AddDirectlyConnectedRoute()
+	}
+	// Topology: A - B - C
+	a.AddPeer(b.ID, b.TunDevice.IP)
+	b.AddPeer(a.ID, a.TunDevice.IP)
+	b.AddPeer(c.ID, c.TunDevice.IP)
+	c.AddPeer(b.ID, b.TunDevice.IP)
+
+	a.sendRIPUpdate()
+	c.sendRIPUpdate()
+	b.sendRIPUpdate()
+
+	want := []struct {
+		router  *Router
+		network string
+		nextHop string
+		metric  int
+	}{
+		{b, "10.0.1.0/24", "10.0.1.1", 1},
+		{b, "10.0.3.0/24", "10.0.3.1", 1},
+		{a, "10.0.2.0/24", "10.0.2.1", 1},
+		{a, "10.0.3.0/24", "10.0.2.1", 2},
+		{c, "10.0.1.0/24", "10.0.2.1", 2},
+	}
+	for _, w := range want {
+		route, ok := findRoute(w.router.GetRoutingTable(), w.network)
+		if !ok {
+			t.Errorf("%s has no route to %s: %+v", w.router.ID, w.network, w.router.GetRoutingTable())
+			continue
+		}
+		if route.NextHop != w.nextHop || route.Metric != w.metric || route.LearnedFrom != RouteSourceRIP {
+			t.Errorf("%s route to %s = %+v, want via %s metric %d (RIP)", w.router.ID, w.network, route, w.nextHop, w.metric)
+		}
+	}
+
+	// Split horizon with poisoned reverse: B advertises A's network back to A as unreachable
+	for _, entry := range b.buildRIPUpdate("10.0.1.1") {
+		if entry.Network == "10.0.1.0/24" && entry.Metric != RIPInfinity {
+			t.Errorf("B advertises %s to A with metric %d, want %d", entry.Network, entry.Metric, RIPInfinity)
+		}
+	}
+
+	// Removing the B-C peer withdraws the routes learned from C
+	b.RemovePeer(c.ID)
+	if _, ok := findRoute(b.GetRoutingTable(), "10.0.3.0/24"); ok {
+		t.Errorf("B still has a route to 10.0.3.0/24 after removing peer C")
+	}
+}
+
+func TestRIPTimeoutPoisoningAndHoldDown(t *testing.T) {
+	r := newTestRouter("routerA", "10.0.1.1/24")
+	now := time.Now()
+	learn := func(from string, metric int, at time.Time) bool {
+		return r.processRIPUpdate(&RIPPacket{RouterID: from, Entries: []RIPEntry{{Network: "10.0.9.0/24", Metric: metric}}}, from, at)
+	}
+
+	if !learn("10.0.2.1", 1, now) {
+		t.Fatalf("processRIPUpdate() did not learn a new route")
+	}
+	if route := r.RIPRoutes["10.0.9.0/24"]; route.Metric != 2 || route.NextHop != "10.0.2.1" {
+		t.Fatalf("learned route = %+v, want metric 2 via 10.0.2.1", route)
+	}
+
+	// Own network is never learned
+	r.processRIPUpdate(&RIPPacket{RouterID: "x", Entries: []RIPEntry{{Network: "10.0.1.0/24", Metric: 0}}}, "10.0.2.1", now)
+	if _, ok := r.RIPRoutes["10.0.1.0/24"]; ok {
+		t.Errorf("processRIPUpdate() learned the directly connected network")
+	}
+
+	// Route timeout: not refreshed within RIPRouteTimeout -> invalid and in hold-down
+	if !r.checkRIPTimers(now.Add(RIPRouteTimeout + time.Second)) {
+		t.Fatalf("checkRIPTimers() did not report the timed out route")
+	}
+	if route := r.RIPRoutes["10.0.9.0/24"]; route.isValid() {
+		t.Fatalf("route still valid after timeout: %+v", route)
+	}
+	if _, ok := findRoute(r.GetRoutingTable(), "10.0.9.0/24"); ok {
+		t.Errorf("invalid route still installed in routing table")
+	}
+
+	// Hold-down: an update with an equal (or worse) metric is ignored, a better one is accepted
+	holdDown := now.Add(RIPRouteTimeout + 2*time.Second)
+	if learn("10.0.3.1", 1, holdDown) {
+		t.Errorf("update with equal metric was accepted during hold-down")
+	}
+	if !learn("10.0.3.1", 0, holdDown) {
+		t.Errorf("update with better metric was rejected during hold-down")
+	}
+	if route := r.RIPRoutes["10.0.9.0/24"]; !route.isValid() || route.NextHop != "10.0.3.1" || route.Metric != 1 {
+		t.Errorf("route after better update = %+v, want metric 1 via 10.0.3.1", route)
+	}
+
+	// Route poisoning: the next hop advertises the route as unreachable
+	if !learn("10.0.3.1", RIPInfinity, holdDown) {
+		t.Fatalf("poisoned update was not applied")
+	}
+	if route := r.RIPRoutes["10.0.9.0/24"]; route.isValid() {
+		t.Errorf("route still valid after poisoning: %+v", route)
+	}
+	for _, entry := range r.buildRIPUpdate("10.0.4.1") {
+		if entry.Network == "10.0.9.0/24" && entry.Metric != RIPInfinity {
+			t.Errorf("poisoned route advertised with metric %d, want %d", entry.Metric, RIPInfinity)
+		}
+	}
+
+	// After hold-down the route is flushed
+	r.checkRIPTimers(holdDown.Add(RIPHoldDownTime + time.Second))
+	if _, ok := r.RIPRoutes["10.0.9.0/24"]; ok {
+		t.Errorf("route not flushed after hold-down")
+	}
+}
+
+// buildUDPPacket builds an IPv4/UDP packet with valid checksums.
+func buildUDPPacket(t *testing.T, src, dst string, srcPort, dstPort uint16, payload []byte) ([]byte, *IPv4Header) {
+	t.Helper()
+	udp := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], srcPort)
+	binary.BigEndian.PutUint16(udp[2:4], dstPort)
+	binary.BigEndian.PutUint16(udp[4:6], uint16(len(udp)))
+	copy(udp[8:], payload)
+	binary.BigEndian.PutUint16(udp[6:8], transportChecksum(net.ParseIP(src).To4(), net.ParseIP(dst).To4(), UDPProtocolNumber, udp))
+
+	packet, err := constructIPPacket(&IPv4Header{Version: 4, IHL: 5, TTL: 64, Protocol: UDPProtocolNumber, SrcIP: net.ParseIP(src), DstIP: net.ParseIP(dst)}, udp)
+	if err != nil {
+		t.Fatalf("constructIPPacket() error = %v", err)
+	}
+	hdr, _, err := parseIPPacket(packet)
+	if err != nil {
+		t.Fatalf("parseIPPacket() error = %v", err)
+	}
+	return packet, hdr
+}
+
+// checkUDPChecksums verifies the IPv4 header checksum and the UDP checksum of packet.
+func checkUDPChecksums(t *testing.T, packet []byte) {
+	t.Helper()
+	header := make([]byte, 20)
+	copy(header, packet[:20])
+	want := binary.BigEndian.Uint16(header[10:12])
+	if got := calculateIPv4Checksum(header); got != want {
+		t.Errorf("IPv4 checksum = %04x, want %04x", want, got)
+	}
+	udp := make([]byte, len(packet)-20)
+	copy(udp, packet[20:])
+	want = binary.BigEndian.Uint16(udp[6:8])
+	udp[6], udp[7] = 0, 0
+	if got := transportChecksum(packet[12:16], packet[16:20], UDPProtocolNumber, udp); got != want {
+		t.Errorf("UDP checksum = %04x, want %04x", want, got)
+	}
+}
+
+func TestNATMasquerade(t *testing.T) {
+	r := newTestRouter("routerA", "10.0.1.1/24")
+	r.AddPeer("routerB", net.ParseIP("10.0.2.1"))
+	r.AddPeer("routerC", net.ParseIP("10.0.3.1"))
+
+	if err := r.SetNATConfig(NATConfig{Enabled: true, WANInterface: "unknown"}); err == nil {
+		t.Errorf("SetNATConfig() with an unknown WAN interface succeeded, want error")
+	}
+
+	packet, hdr := buildUDPPacket(t, "10.0.1.10", "10.0.2.5", 5000, 53, []byte("query"))
+	if got := r.natOutbound(packet, hdr, "routerB"); string(got) != string(packet) {
+		t.Errorf("natOutbound() translated a packet while NAT is disabled")
+	}
+
+	if err := r.SetNATConfig(NATConfig{Enabled: true, WANInterface: "routerB"}); err != nil {
+		t.Fatalf("SetNATConfig() error = %v", err)
+	}
+
+	// Traffic to a non-WAN peer is not translated
+	if got := r.natOutbound(packet, hdr, "routerC"); string(got) != string(packet) {
+		t.Errorf("natOutbound() translated a packet leaving through a non-WAN interface")
+	}
+
+	out := r.natOutbound(packet, hdr, "routerB")
+	if out == nil {
+		t.Fatalf("natOutbound() dropped the packet")
+	}
+	outHdr, _, _ := parseIPPacket(out)
+	outsidePort := binary.BigEndian.Uint16(out[20:22])
+	if !outHdr.SrcIP.Equal(net.ParseIP("10.0.1.1")) || outsidePort < NATPortRangeStart || outsidePort > NATPortRangeEnd {
+		t.Errorf("translated source = %s:%d, want 10.0.1.1:[%d-%d]", outHdr.SrcIP, outsidePort, NATPortRangeStart, NATPortRangeEnd)
+	}
+	checkUDPChecksums(t, out)
+
+	// The same inside socket reuses its translation
+	again := r.natOutbound(packet, hdr, "routerB")
+	if binary.BigEndian.Uint16(again[20:22]) != outsidePort {
+		t.Errorf("second packet got outside port %d, want %d", binary.BigEndian.Uint16(again[20:22]), outsidePort)
+	}
+
+	translations := r.GetNATTranslations()
+	if len(translations) != 1 || translations[0].InsideIP != "10.0.1.10" || translations[0].InsidePort != 5000 || translations[0].OutsidePort != outsidePort || translations[0].Protocol != "udp" {
+		t.Errorf("GetNATTranslations() = %+v", translations)
+	}
+
+	reply, replyHdr := buildUDPPacket(t, "10.0.2.5", "10.0.1.1", 53, outsidePort, []byte("answer"))
+	in, ok := r.natInbound(reply, replyHdr)
+	if !ok {
+		t.Fatalf("natInbound() did not match the reply")
+	}
+	inHdr, _, _ := parseIPPacket(in)
+	if !inHdr.DstIP.Equal(net.ParseIP("10.0.1.10")) || binary.BigEndian.Uint16(in[22:24]) != 5000 {
+		t.Errorf("translated destination = %s:%d, want 10.0.1.10:5000", inHdr.DstIP, binary.BigEndian.Uint16(in[22:24]))
+	}
+	checkUDPChecksums(t, in)
+
+	// Unknown outside port is not translated
+	stray, strayHdr := buildUDPPacket(t, "10.0.2.5", "10.0.1.1", 53, outsidePort+1, nil)
+	if _, ok := r.natInbound(stray, strayHdr); ok {
+		t.Errorf("natInbound() matched a packet without a translation")
+	}
+
+	stats := r.GetNATStats()
+	if !stats.Enabled || stats.ActiveTranslations != 1 || stats.OutboundPackets != 2 || stats.InboundPackets != 1 {
+		t.Errorf("GetNATStats() = %+v, want enabled, 1 translation, 2 outbound, 1 inbound", stats)
+	}
+
+	// Disabling NAT clears the translations
+	if err := r.SetNATConfig(NATConfig{Enabled: false}); err != nil {
+		t.Fatalf("SetNATConfig() error = %v", err)
+	}
+	if len(r.GetNATTranslations()) != 0 {
+		t.Errorf("translations remain after disabling NAT")
+	}
+}
+
+func TestACLRules(t *testing.T) {
+	r := newTestRouter("routerA", "10.0.1.1/24")
+
+	invalid := []ACLRule{
+		{Action: "drop"},
+		{Action: "deny", Src: "10.0.0.0/33"},
+		{Action: "deny", Protocol: "gre"},
+		{Action: "deny", Protocol: "icmp", DstPort: 80},
+	}
+	for _, rule := range invalid {
+		if _, err := r.AddACLRule(rule, 0); err == nil {
+			t.Errorf("AddACLRule(%+v) succeeded, want error", rule)
+		}
+	}
+
+	denyDNS, err := r.AddACLRule(ACLRule{Action: "deny", Dst: "10.0.2.5", Protocol: "udp", DstPort: 53}, 0)
+	if err != nil {
+		t.Fatalf("AddACLRule() error = %v", err)
+	}
+	if denyDNS.Dst != "10.0.2.5/32" {
+		t.Errorf("host destination normalized to %q, want 10.0.2.5/32", denyDNS.Dst)
+	}
+	denySubnet, _ := r.AddACLRule(ACLRule{Action: "deny", Src: "10.0.1.0/24", Dst: "10.0.3.0/24"}, 0)
+	// Inserted first so that it takes precedence over the subnet deny rule
+	allowHost, _ := r.AddACLRule(ACLRule{Action: "ALLOW", Src: "10.0.1.10", Dst: "10.0.3.0/24"}, 1)
+
+	rules := r.GetACLRules()
+	if len(rules) != 3 || rules[0].ID != allowHost.ID || rules[1].ID != denyDNS.ID || rules[2].ID != denySubnet.ID {
+		t.Fatalf("GetACLRules() order = %+v, want [%d %d %d]", rules, allowHost.ID, denyDNS.ID, denySubnet.ID)
+	}
+
+	cases := []struct {
+		src, dst string
+		dstPort  uint16
+		allowed  bool
+	}{
+		{"10.0.1.20", "10.0.2.5", 53, false}, // denyDNS
+		{"10.0.1.20", "10.0.2.5", 123, true}, // no rule matches
+		{"10.0.1.10", "10.0.3.7", 80, true},  // allowHost
+		{"10.0.1.20", "10.0.3.7", 80, false}, // denySubnet
+		{"10.0.9.20", "10.0.3.7", 80, true},  // source outside denySubnet
+	}
+	for _, tc := range cases {
+		packet, hdr := buildUDPPacket(t, tc.src, tc.dst, 40000, tc.dstPort, nil)
+		if got := r.checkACL(hdr, packet); got != tc.allowed {
+			t.Errorf("checkACL(%s -> %s:%d) = %v, want %v", tc.src, tc.dst, tc.dstPort, got, tc.allowed)
+		}
+	}
+
+	if !r.takeACLHitsChanged() {
+		t.Errorf("takeACLHitsChanged() = false after matching packets")
+	}
+	if r.takeACLHitsChanged() {
+		t.Errorf("takeACLHitsChanged() = true without new hits")
+	}
+	for _, rule := range r.GetACLRules() {
+		if rule.Hits != 1 {
+			t.Errorf("rule %d hits = %d, want 1", rule.ID, rule.Hits)
+		}
+	}
+
+	if err := r.RemoveACLRule(denyDNS.ID); err != nil {
+		t.Fatalf("RemoveACLRule() error = %v", err)
+	}
+	if err := r.RemoveACLRule(denyDNS.ID); err == nil {
+		t.Errorf("RemoveACLRule() of a removed rule succeeded, want error")
+	}
+	packet, hdr := buildUDPPacket(t, "10.0.1.20", "10.0.2.5", 40000, 53, nil)
+	if !r.checkACL(hdr, packet) {
+		t.Errorf("packet still denied after removing the rule")
+	}
+}
+
+func TestPingAndTraceroute(t *testing.T) {
+	m := NewRouterManager(make(chan map[string]interface{}, 100))
+	a := newTestRouter("routerA", "10.0.1.1/24")
+	b := newTestRouter("routerB", "10.0.2.1/24")
+	c := newTestRouter("routerC", "10.0.3.1/24")
+	for _, r := range []*Router{a, b, c} {
+		r.manager = m
+		m.routers[r.ID] = r
+		r.AddDirectlyConnectedRoute()
+	}
+	// A -> B -> C
+	if _, err := a.AddStaticRoute("10.0.3.0/24", "10.0.2.1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.AddStaticRoute("10.0.3.0/24", "10.0.3.1", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := m.Ping("routerA", "routerC", 2)
+	if err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if summary.Destination != "10.0.3.1" || summary.Sent != 2 || summary.Received != 2 || summary.LossPercent != 0 {
+		t.Errorf("Ping() summary = %+v, want 2/2 replies from 10.0.3.1", summary)
+	}
+	if reply := summary.Replies[0]; reply.Responder != "routerC" || reply.TTL != ProbeTTL-2 || reply.RTTMs < 4 {
+		t.Errorf("Ping() reply = %+v, want reply from routerC with TTL %d and RTT >= 4ms", reply, ProbeTTL-2)
+	}
+
+	trace, err := m.Traceroute("routerA", "10.0.3.1", 0)
+	if err != nil {
+		t.Fatalf("Traceroute() error = %v", err)
+	}
+	if !trace.Reached || len(trace.Hops) != 2 || trace.Hops[0].RouterID != "routerB" || trace.Hops[1].RouterID != "routerC" {
+		t.Errorf("Traceroute() = %+v, want hops routerB, routerC", trace)
+	}
+
+	// No route back from C towards an unknown network
+	trace, _ = m.Traceroute("routerC", "10.0.9.1", 0)
+	if trace.Reached || len(trace.Hops) != 1 || trace.Hops[0].Note != "!H" {
+		t.Errorf("Traceroute() to unreachable network = %+v, want a single !H hop", trace)
+	}
+
+	// An ACL on B blocks the probes
+	if _, err := b.AddACLRule(ACLRule{Action: ACLActionDeny, Protocol: "icmp", Dst: "10.0.3.0/24"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	summary, _ = m.Ping("routerA", "10.0.3.1", 1)
+	if summary.Received != 0 || summary.LossPercent != 100 {
+		t.Errorf("Ping() through denying ACL = %+v, want 100%% loss", summary)
+	}
+
+	if _, err := m.Ping("routerA", "nowhere", 1); err == nil {
+		t.Errorf("Ping() to an unknown destination succeeded, want error")
+	}
+}
+
+func TestPacketLogAndTail(t *testing.T) {
+	events := make(chan map[string]interface{}, 10)
+	m := NewRouterManager(events)
+	r := newTestRouter("routerA", "10.0.1.1/24")
+	r.manager = m
+	m.routers[r.ID] = r
+
+	_, hdr := buildUDPPacket(t, "10.0.1.2", "10.0.2.2", 1000, 53, []byte("q"))
+	for i := 0; i < PacketLogSize+5; i++ {
+		r.logPacket(hdr, i, PacketActionNoRoute, "")
+	}
+	entries := r.GetPacketLog()
+	if len(entries) != PacketLogSize || entries[0].Length != 5 || entries[len(entries)-1].Length != PacketLogSize+4 {
+		t.Fatalf("GetPacketLog() = %d entries from %d to %d, want %d entries from 5", len(entries), entries[0].Length, entries[len(entries)-1].Length, PacketLogSize)
+	}
+	if len(events) != 0 {
+		t.Fatalf("PACKET_LOG broadcast without a tail: %+v", <-events)
+	}
+
+	// Two tails; the events stop only when both are released
+	m.SetPacketTail("routerA", true)
+	m.SetPacketTail("routerA", true)
+	m.SetPacketTail("routerA", false)
+	r.logPacket(hdr, 1, PacketActionForwarded, "10.0.2.1")
+	ev := <-events
+	if entry, ok := ev["packet"].(PacketLogEntry); ev["event"] != "PACKET_LOG" || ev["routerId"] != "routerA" || !ok || entry.Protocol != "udp" || entry.NextHop != "10.0.2.1" {
+		t.Errorf("event = %+v, want PACKET_LOG for routerA", ev)
+	}
+	m.SetPacketTail("routerA", false)
+	r.logPacket(hdr, 1, PacketActionForwarded, "10.0.2.1")
+	if len(events) != 0 {
+		t.Errorf("PACKET_LOG broadcast after the last tail was released: %+v", <-events)
+	}
+}
+
+func TestLinkImpairment(t *testing.T) {
+	m := NewRouterManager(make(chan map[string]interface{}, 100))
+	a := newTestRouter("routerA", "10.0.1.1/24")
+	b := newTestRouter("routerB", "10.0.2.1/24")
+	for _, r := range []*Router{a, b} {
+		r.manager = m
+		m.routers[r.ID] = r
+	}
+	conn, err := m.AddConnection("routerA", "routerB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// B has no route to the destination, so every packet that arrives shows up as no-route in its packet log
+	packet, _ := buildUDPPacket(t, "10.0.1.2", "10.0.9.9", 1000, 53, make([]byte, 97)) // 125 bytes
+	relay := func() { m.RelayPacket("routerA", b.TunDevice.GetIP(), packet) }
+
+	relay()
+	if got := len(b.GetPacketLog()); got != 1 {
+		t.Fatalf("packets received over an ideal link = %d, want 1 (delivered synchronously)", got)
+	}
+
+	if _, err := m.SetConnectionImpairment(conn.ID, LinkImpairment{LossPercent: 101}); err == nil {
+		t.Errorf("SetConnectionImpairment() with lossPercent 101 succeeded, want error")
+	}
+	if _, err := m.SetConnectionImpairment("missing", LinkImpairment{}); err == nil {
+		t.Errorf("SetConnectionImpairment() on unknown connection succeeded, want error")
+	}
+
+	// 100% loss
+	if _, err := m.SetConnectionImpairment(conn.ID, LinkImpairment{LossPercent: 100}); err != nil {
+		t.Fatal(err)
+	}
+	relay()
+	if got := len(b.GetPacketLog()); got != 1 {
+		t.Errorf("packets received over a 100%% loss link = %d, want 1", got)
+	}
+
+	// Delay
+	if _, err := m.SetConnectionImpairment(conn.ID, LinkImpairment{DelayMs: 50}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	relay()
+	if got := len(b.GetPacketLog()); got != 1 {
+		t.Errorf("delayed packet arrived immediately")
+	}
+	for len(b.GetPacketLog()) < 2 && time.Since(start) < time.Second {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || len(b.GetPacketLog()) != 2 {
+		t.Errorf("delayed packet arrived after %v, want >= 50ms", elapsed)
+	}
+
+	// Rate limit: 125 bytes at 10 kbit/s take 100ms each, so the queue overflows after about 5 packets
+	if _, err := m.SetConnectionImpairment(conn.ID, LinkImpairment{RateKbps: 10}); err != nil {
+		t.Fatal(err)
+	}
+	link := m.linkBetween("routerB", "routerA")
+	now := time.Now()
+	var delays []time.Duration
+	for i := 0; i < 10; i++ {
+		if d, ok := link.transmit("routerA", len(packet), now); ok {
+			delays = append(delays, d)
+		}
+	}
+	if len(delays) != 6 || delays[0] != 100*time.Millisecond || delays[5] != 600*time.Millisecond {
+		t.Errorf("rate limited delays = %v, want 6 packets 100ms apart", delays)
+	}
+	// The other direction has its own queue
+	if d, ok := link.transmit("routerB", len(packet), now); !ok || d != 100*time.Millisecond {
+		t.Errorf("reverse direction delay = %v, %v, want 100ms", d, ok)
+	}
+
+	got, _ := m.GetConnection(conn.ID)
+	if got.Impairment.RateKbps != 10 || got.Stats.Lost != 1 || got.Stats.QueueDrops != 4 {
+		t.Errorf("GetConnection() = %+v, want rate 10kbps with 1 lost and 4 queue drops", got)
+	}
+}
+
+func TestVLANSubInterfaces(t *testing.T) {
+	r := newTestRouter("routerA", "10.0.1.1/24")
+	r.AddDirectlyConnectedRoute()
+
+	added, err := r.AddInterface(InterfaceConfig{Parent: "tun-routerA", VLANID: 100, IPCIDR: "10.0.100.1/24"})
+	if err != nil {
+		t.Fatalf("AddInterface() error = %v", err)
+	}
+	if added.Name != "tun-routerA.100" || added.Network != "10.0.100.0/24" {
+		t.Errorf("AddInterface() = %+v, want tun-routerA.100 on 10.0.100.0/24", added)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config InterfaceConfig
+	}{
+		{"overlapping network", InterfaceConfig{Parent: "tun-routerA", VLANID: 200, IPCIDR: "10.0.1.129/25"}},
+		{"duplicate VLAN", InterfaceConfig{Parent: "tun-routerA", VLANID: 100, IPCIDR: "10.0.200.1/24"}},
+		{"VLAN ID out of range", InterfaceConfig{Parent: "tun-routerA", VLANID: 4095, IPCIDR: "10.0.200.1/24"}},
+		{"unknown parent", InterfaceConfig{Parent: "eth9", VLANID: 200, IPCIDR: "10.0.200.1/24"}},
+		{"nested sub-interface", InterfaceConfig{Parent: "tun-routerA.100", VLANID: 200, IPCIDR: "10.0.200.1/24"}},
+		{"VLAN ID without parent", InterfaceConfig{VLANID: 200, IPCIDR: "10.0.200.1/24"}},
+		{"invalid address", InterfaceConfig{Parent: "tun-routerA", VLANID: 200, IPCIDR: "10.0.200.1"}},
+	} {
+		if _, err := r.AddInterface(tc.config); err == nil {
+			t.Errorf("AddInterface() with %s succeeded, want error", tc.name)
+		}
+	}
+
+	if route, ok := findRoute(r.GetRoutingTable(), "10.0.100.0/24"); !ok || route.LearnedFrom != "Direct" || route.Interface != "tun-routerA.100" {
+		t.Errorf("connected route for VLAN 100 = %+v (found %v), want Direct via tun-routerA.100", route, ok)
+	}
+	// The connected route survives SPF runs and is advertised by OSPF and RIP
+	r.runSPF("test")
+	if _, ok := findRoute(r.GetRoutingTable(), "10.0.100.0/24"); !ok {
+		t.Errorf("connected route for VLAN 100 removed by SPF")
+	}
+	lsuHasNetwork := false
+	for _, link := range r.generateLSU().Links {
+		lsuHasNetwork = lsuHasNetwork || link.Network == "10.0.100.0/24"
+	}
+	ripHasNetwork := false
+	for _, entry := range r.buildRIPUpdate("10.0.2.1") {
+		ripHasNetwork = ripHasNetwork || (entry.Network == "10.0.100.0/24" && entry.Metric == 0)
+	}
+	if !lsuHasNetwork || !ripHasNetwork {
+		t.Errorf("VLAN network advertised by OSPF = %v, RIP = %v, want both", lsuHasNetwork, ripHasNetwork)
+	}
+	if !r.ownsIP(net.ParseIP("10.0.100.1")) {
+		t.Errorf("ownsIP(10.0.100.1) = false, want true")
+	}
+	if _, err := r.AddStaticRoute("10.0.100.0/24", "10.0.2.1", 1); err == nil {
+		t.Errorf("AddStaticRoute() for a VLAN network succeeded, want error")
+	}
+
+	interfaces := r.GetInterfaces()
+	if len(interfaces) != 2 || !interfaces[0].Primary || interfaces[1].VLANID != 100 || interfaces[1].Parent != "tun-routerA" {
+		t.Errorf("GetInterfaces() = %+v, want primary and tun-routerA.100", interfaces)
+	}
+
+	if _, err := r.RemoveInterface("tun-routerA"); err == nil {
+		t.Errorf("RemoveInterface() of the primary interface succeeded, want error")
+	}
+	if _, err := r.RemoveInterface("tun-routerA.100"); err != nil {
+		t.Fatalf("RemoveInterface() error = %v", err)
+	}
+	if _, ok := findRoute(r.GetRoutingTable(), "10.0.100.0/24"); ok {
+		t.Errorf("connected route for VLAN 100 still present after removing the interface")
+	}
+}
+
+// memoryStore is a TopologyStore that keeps the last saved topology.
+type memoryStore struct {
+	saved *Topology
+	saves int
+}
+
+func (s *memoryStore) Load() (*Topology, error) { return s.saved, nil }
+
+func (s *memoryStore) Save(topology *Topology) error {
+	s.saved = topology
+	s.saves++
+	return nil
+}
+
+func TestTopologySnapshot(t *testing.T) {
+	m := NewRouterManager(make(chan map[string]interface{}, 100))
+	a := newTestRouter("routerA", "10.0.1.1/24")
+	b := newTestRouter("routerB", "10.0.2.1/24")
+	for _, r := range []*Router{a, b} {
+		r.manager = m
+		r.AddDirectlyConnectedRoute()
+		m.routers[r.ID] = r
+	}
+	store := &memoryStore{}
+	m.SetStore(store)
+	if store.saves != 0 {
+		t.Errorf("SetStore() saved %d times, want 0", store.saves)
+	}
+
+	conn, err := m.AddConnection("routerA", "routerB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SetConnectionImpairment(conn.ID, LinkImpairment{DelayMs: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.AddInterface("routerA", InterfaceConfig{Parent: "tun-routerA", VLANID: 100, IPCIDR: "10.0.100.1/24"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.AddStaticRoute("routerA", "192.168.0.0/16", "10.0.2.1", 5); err != nil {
+		t.Fatal(err)
+	}
+	if store.saves != 4 {
+		t.Errorf("topology saved %d times after 4 changes, want 4", store.saves)
+	}
+
+	saved := store.saved
+	if len(saved.Routers) != 2 || saved.Routers[0].ID != "routerA" || saved.Routers[1].ID != "routerB" {
+		t.Fatalf("saved routers = %+v, want routerA and routerB", saved.Routers)
+	}
+	ra := saved.Routers[0]
+	if ra.TunName != "tun-routerA" || ra.IPCIDR != "10.0.1.1/24" {
+		t.Errorf("saved routerA = %+v, want tun-routerA with 10.0.1.1/24", ra)
+	}
+	wantIfc := InterfaceConfig{Name: "tun-routerA.100", IPCIDR: "10.0.100.1/24", Parent: "tun-routerA", VLANID: 100}
+	if len(ra.Interfaces) != 1 || ra.Interfaces[0] != wantIfc {
+		t.Errorf("saved interfaces = %+v, want only %+v", ra.Interfaces, wantIfc)
+	}
+	wantRoute := StaticRouteRecord{Destination: "192.168.0.0/16", NextHop: "10.0.2.1", Metric: 5}
+	if len(ra.StaticRoutes) != 1 || ra.StaticRoutes[0] != wantRoute {
+		t.Errorf("saved static routes = %+v, want only %+v", ra.StaticRoutes, wantRoute)
+	}
+	if len(saved.Connections) != 1 || saved.Connections[0].ID != conn.ID || saved.Connections[0].Impairment.DelayMs != 20 {
+		t.Errorf("saved connections = %+v, want %s with 20ms delay", saved.Connections, conn.ID)
+	}
+
+	// Connections of a removed router are dropped from the snapshot
+	m.mutex.Lock()
+	delete(m.routers, "routerB")
+	m.mutex.Unlock()
+	if snapshot := m.Snapshot(); len(snapshot.Routers) != 1 || len(snapshot.Connections) != 0 {
+		t.Errorf("Snapshot() after removing routerB = %+v, want routerA only", snapshot)
+	}
+}
+
+func TestTrafficMetrics(t *testing.T) {
+	m := NewRouterManager(make(chan map[string]interface{}, 100))
+	a := newTestRouter("routerA", "10.0.1.1/24")
+	b := newTestRouter("routerB", "10.0.2.1/24")
+	for _, r := range []*Router{a, b} {
+		r.manager = m
+		r.AddDirectlyConnectedRoute()
+		m.routers[r.ID] = r
+	}
+	if _, err := m.AddConnection("routerA", "routerB"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.AddStaticRoute("10.0.9.0/24", "10.0.2.1", 1); err != nil {
+		t.Fatal(err)
+	}
+	// The control-plane packets sent while connecting are not of interest here
+	a.metrics = routerMetrics{}
+	b.metrics = routerMetrics{}
+
+	// A forwards the packet to B, which has no route to the destination and drops it
+	packet, _ := buildUDPPacket(t, "10.0.1.2", "10.0.9.9", 1000, 53, []byte("hello"))
+	a.InjectPacket(packet, "host")
+
+	want := InterfaceCounters{RxPackets: 1, RxBytes: uint64(len(packet)), TxPackets: 1, TxBytes: uint64(len(packet))}
+	if got := a.GetMetrics().Interfaces["tun-routerA"]; got != want {
+		t.Errorf("routerA tun-routerA counters = %+v, want %+v", got, want)
+	}
+	want = InterfaceCounters{RxPackets: 1, RxBytes: uint64(len(packet)), Drops: 1}
+	if got := b.GetMetrics().Interfaces["tun-routerB"]; got != want {
+		t.Errorf("routerB tun-routerB counters = %+v, want %+v", got, want)
+	}
+
+	latency := a.GetMetrics().ForwardingLatency
+	last := ForwardingLatencyBuckets[len(ForwardingLatencyBuckets)-1]
+	if latency.Count != 1 || latency.Buckets[last] != 1 {
+		t.Errorf("routerA forwarding latency = %+v, want 1 observation within %vs", latency, last)
+	}
+	if latency := b.GetMetrics().ForwardingLatency; latency.Count != 0 {
+		t.Errorf("routerB forwarding latency count = %d, want 0 (nothing forwarded)", latency.Count)
+	}
+
+	all := m.GetAllMetrics()
+	if len(all) != 2 || all[0].RouterID != "routerA" || all[1].RouterID != "routerB" {
+		t.Errorf("GetAllMetrics() = %+v, want routerA and routerB", all)
+	}
+}
+
+func TestDHCPServer(t *testing.T) {
+	events := make(chan map[string]interface{}, 10)
+	m := NewRouterManager(events)
+	r := newTestRouter("routerA", "10.0.1.1/24")
+	r.manager = m
+	m.routers[r.ID] = r
+
+	if _, err := r.RequestDHCPLease("tun-routerA", "02:00:00:00:00:01", "", time.Now()); err == nil {
+		t.Errorf("RequestDHCPLease() without a DHCP server succeeded, want error")
+	}
+	for _, tc := range []struct {
+		name   string
+		config DHCPConfig
+	}{
+		{"unknown interface", DHCPConfig{Interface: "eth9", Enabled: true}},
+		{"range outside the subnet", DHCPConfig{Interface: "tun-routerA", Enabled: true, RangeStart: "10.0.2.10"}},
+		{"broadcast address in range", DHCPConfig{Interface: "tun-routerA", Enabled: true, RangeEnd: "10.0.1.255"}},
+		{"reversed range", DHCPConfig{Interface: "tun-routerA", Enabled: true, RangeStart: "10.0.1.20", RangeEnd: "10.0.1.10"}},
+		{"short lease", DHCPConfig{Interface: "tun-routerA", Enabled: true, LeaseSeconds: 1}},
+	} {
+		if _, err := r.SetDHCPConfig(tc.config); err == nil {
+			t.Errorf("SetDHCPConfig() with %s succeeded, want error", tc.name)
+		}
+	}
+
+	// The router's own address (10.0.1.1) is never leased
+	applied, err := m.SetDHCPConfig("routerA", DHCPConfig{Interface: "tun-routerA", Enabled: true, RangeEnd: "10.0.1.3", LeaseSeconds: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.RangeStart != "10.0.1.1" || applied.RangeEnd != "10.0.1.3" {
+		t.Errorf("SetDHCPConfig() = %+v, want range 10.0.1.1-10.0.1.3", applied)
+	}
+	<-events // DHCP_CONFIG_UPDATED
+
+	first, err := m.RequestDHCPLease("routerA", "tun-routerA", "02-00-00-00-00-01", "host1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.IP != "10.0.1.2" || first.MAC != "02:00:00:00:00:01" || first.Gateway != "10.0.1.1" || first.Network != "10.0.1.0/24" {
+		t.Errorf("first lease = %+v, want 10.0.1.2 via 10.0.1.1 for 02:00:00:00:00:01", first)
+	}
+	if ev := <-events; ev["event"] != "DHCP_LEASE_GRANTED" {
+		t.Errorf("event = %v, want DHCP_LEASE_GRANTED", ev["event"])
+	}
+	second, err := m.RequestDHCPLease("routerA", "tun-routerA", "02:00:00:00:00:02", "host2")
+	if err != nil || second.IP != "10.0.1.3" {
+		t.Fatalf("second lease = %+v, %v, want 10.0.1.3", second, err)
+	}
+	<-events
+	if _, err := m.RequestDHCPLease("routerA", "tun-routerA", "02:00:00:00:00:03", "host3"); err == nil {
+		t.Errorf("RequestDHCPLease() with an exhausted pool succeeded, want error")
+	}
+
+	// Asking again renews the same address
+	later := first.GrantedAt.Add(30 * time.Second)
+	renewed, err := r.RequestDHCPLease("tun-routerA", "02:00:00:00:00:01", "", later)
+	if err != nil || renewed.IP != first.IP || !renewed.ExpiresAt.Equal(later.Add(60*time.Second)) || renewed.Hostname != "host1" {
+		t.Errorf("renewed lease = %+v, %v, want %s until %v", renewed, err, first.IP, later.Add(60*time.Second))
+	}
+
+	// host2's lease expires; host1's renewed lease does not
+	m.expireDHCPLeases(r, second.ExpiresAt)
+	if ev := <-events; ev["event"] != "DHCP_LEASE_EXPIRED" || ev["lease"].(DHCPLease).MAC != "02:00:00:00:00:02" {
+		t.Errorf("event = %v, want DHCP_LEASE_EXPIRED for 02:00:00:00:00:02", ev)
+	}
+	if leases := r.GetDHCPLeases(); len(leases) != 1 || leases[0].IP != "10.0.1.2" {
+		t.Errorf("GetDHCPLeases() after expiry = %+v, want only 10.0.1.2", leases)
+	}
+
+	if err := m.ReleaseDHCPLease("routerA", "tun-routerA", "02:00:00:00:00:01"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev["event"] != "DHCP_LEASE_RELEASED" {
+		t.Errorf("event = %v, want DHCP_LEASE_RELEASED", ev["event"])
+	}
+	if err := m.ReleaseDHCPLease("routerA", "tun-routerA", "02:00:00:00:00:01"); err == nil {
+		t.Errorf("ReleaseDHCPLease() of a released lease succeeded, want error")
+	}
+
+	if _, err := m.SetDHCPConfig("routerA", DHCPConfig{Interface: "tun-routerA"}); err != nil {
+		t.Fatal(err)
+	}
+	if configs := r.GetDHCPConfigs(); len(configs) != 0 {
+		t.Errorf("GetDHCPConfigs() after disabling = %+v, want none", configs)
+	}
+}
@@ -0,0 +1,128 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// RouteSourceStatic is the LearnedFrom value for routes configured via the API.
+const RouteSourceStatic = "Static"
+
+// AddStaticRoute installs a static route to destination (CIDR) via nextHop.
+// Static routes survive SPF runs and take precedence over OSPF routes for the same network.
+// Adding a route for an existing static destination replaces it.
+func (r *Router) AddStaticRoute(destination string, nextHop string, metric int) (RoutingEntry, error) {
+	_, dstNet, err := net.ParseCIDR(destination)
+	if err != nil {
+		return RoutingEntry{}, fmt.Errorf("invalid destination CIDR %s: %w", destination, err)
+	}
+	if dstNet.IP.To4() == nil {
+		return RoutingEntry{}, fmt.Errorf("destination %s is not an IPv4 network", destination)
+	}
+	nextHopIP := net.ParseIP(nextHop)
+	if nextHopIP == nil || nextHopIP.To4() == nil {
+		return RoutingEntry{}, fmt.Errorf("invalid next hop IPv4 address %s", nextHop)
+	}
+	if r.ownsIP(nextHopIP) {
+		return RoutingEntry{}, fmt.Errorf("next hop %s is this router's own address", nextHop)
+	}
+	if metric < 0 {
+		return RoutingEntry{}, fmt.Errorf("metric must not be negative (got %d)", metric)
+	}
+
+	network := dstNet.String()
+	if r.isConnectedNetwork(network) {
+		return RoutingEntry{}, fmt.Errorf("%s is directly connected to router %s", network, r.ID)
+	}
+
+	entry := &RoutingEntry{
+		Network:         network,
+		NextHop:         nextHopIP.String(),
+		NextHopRouterID: r.neighborIDForIP(nextHopIP.String()),
+		Interface:       r.TunDevice.Name,
+		Metric:          metric,
+		LearnedFrom:     RouteSourceStatic,
+		LastUpdated:     time.Now(),
+	}
+
+	r.rtMutex.Lock()
+	defer r.rtMutex.Unlock()
+	if r.StaticRoutes == nil {
+		r.StaticRoutes = make(map[string]*RoutingEntry)
+	}
+	r.StaticRoutes[network] = entry
+	r.RoutingTable[network] = entry
+	log.Printf("Router %s: Added static route %s via %s metric=%d", r.ID, network, entry.NextHop, metric)
+	r.notifyRoutingTableUpdate()
+	return *entry, nil
+}
+
+// RemoveStaticRoute removes the static route for destination (CIDR).
+// The network stays unreachable until the next SPF run re-learns it via OSPF (if any router advertises it).
+func (r *Router) RemoveStaticRoute(destination string) (RoutingEntry, error) {
+	_, dstNet, err := net.ParseCIDR(destination)
+	if err != nil {
+		return RoutingEntry{}, fmt.Errorf("invalid destination CIDR %s: %w", destination, err)
+	}
+	network := dstNet.String()
+
+	r.rtMutex.Lock()
+	defer r.rtMutex.Unlock()
+	entry, exists := r.StaticRoutes[network]
+	if !exists {
+		return RoutingEntry{}, fmt.Errorf("static route for %s not found on router %s", network, r.ID)
+	}
+	delete(r.StaticRoutes, network)
+	if current, ok := r.RoutingTable[network]; ok && current == entry {
+		delete(r.RoutingTable, network)
+	}
+	log.Printf("Router %s: Removed static route %s via %s", r.ID, network, entry.NextHop)
+	r.notifyRoutingTableUpdate()
+	return *entry, nil
+}
+
+// GetStaticRoutes returns a copy of the configured static routes.
+func (r *Router) GetStaticRoutes() []RoutingEntry {
+	r.rtMutex.RLock()
+	defer r.rtMutex.RUnlock()
+	routes := make([]RoutingEntry, 0, len(r.StaticRoutes))
+	for _, entry := range r.StaticRoutes {
+		routes = append(routes, *entry)
+	}
+	return routes
+}
+
+// applyStaticRoutes installs the static routes into table, overriding dynamically learned routes.
+// Directly connected networks are never overridden. Caller must hold rtMutex.
+func (r *Router) applyStaticRoutes(table map[string]*RoutingEntry) {
+	for network, entry := range r.StaticRoutes {
+		if existing, ok := table[network]; ok && existing.LearnedFrom == "Direct" {
+			continue
+		}
+		entry.NextHopRouterID = r.neighborIDForIP(entry.NextHop)
+		table[network] = entry
+	}
+}
+
+// neighborIDForIP returns the ID of the neighbor (or connected peer) with the given IP, or "" if unknown.
+func (r *Router) neighborIDForIP(ip string) string {
+	r.neighborMutex.RLock()
+	for id, neighbor := range r.Neighbors {
+		if neighbor.IPAddress == ip {
+			r.neighborMutex.RUnlock()
+			return id
+		}
+	}
+	r.neighborMutex.RUnlock()
+
+	r.peersMutex.RLock()
+	defer r.peersMutex.RUnlock()
+	for id, peerIP := range r.ConnectedPeers {
+		if peerIP.String() == ip {
+			return id
+		}
+	}
+	return ""
+}
@@ -0,0 +1,313 @@
+package router
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ping / traceroute simulation.
+// An ICMP echo request is injected at the source router and walked hop by hop through the routers'
+// routing tables and ACLs. RTTs are simulated from the number of links traversed (SimulatedLinkLatency
+// per link and direction) plus the time actually spent processing the probe.
+
+const (
+	SimulatedLinkLatency = 1 * time.Millisecond
+	DefaultPingCount     = 4
+	MaxPingCount         = 20
+	PingInterval         = 200 * time.Millisecond
+	DefaultTracerouteTTL = 30
+	ProbeTTL             = 64
+)
+
+// probeOutcome is the result of walking one probe through the virtual topology.
+type probeOutcome int
+
+const (
+	probeReached     probeOutcome = iota // The destination (or its directly connected network) was reached
+	probeTTLExceeded                     // TTL reached 0 at responder
+	probeUnreachable                     // responder has no route (or the next hop router does not exist)
+	probeFiltered                        // Denied by an ACL on responder
+)
+
+// probeResult describes how far a probe got.
+type probeResult struct {
+	outcome   probeOutcome
+	responder *Router // Router that answered (echo reply, time exceeded or unreachable)
+	hops      int     // Number of links traversed to reach responder
+}
+
+// PingReply is the result of one echo request.
+type PingReply struct {
+	Seq       int     `json:"seq"`
+	Success   bool    `json:"success"`
+	RTTMs     float64 `json:"rttMs,omitempty"`
+	TTL       int     `json:"ttl,omitempty"`   // TTL of the echo reply
+	Responder string  `json:"responder"`       // Router ID that answered
+	Error     string  `json:"error,omitempty"` // Reason when unsuccessful
+}
+
+// PingSummary is the result of a ping session.
+type PingSummary struct {
+	ID          string      `json:"id"`
+	Source      string      `json:"source"`
+	Destination string      `json:"destination"`
+	Sent        int         `json:"sent"`
+	Received    int         `json:"received"`
+	LossPercent float64     `json:"lossPercent"`
+	MinRTTMs    float64     `json:"minRttMs"`
+	AvgRTTMs    float64     `json:"avgRttMs"`
+	MaxRTTMs    float64     `json:"maxRttMs"`
+	Replies     []PingReply `json:"replies"`
+}
+
+// TracerouteHop is one hop of a traceroute.
+type TracerouteHop struct {
+	Hop      int     `json:"hop"`
+	RouterID string  `json:"routerId,omitempty"`
+	IP       string  `json:"ip,omitempty"`
+	RTTMs    float64 `json:"rttMs,omitempty"`
+	Timeout  bool    `json:"timeout"`        // No answer ("*")
+	Note     string  `json:"note,omitempty"` // e.g. "!H" (host unreachable), "!X" (administratively prohibited)
+}
+
+// TracerouteResult is the result of a traceroute session.
+type TracerouteResult struct {
+	ID          string          `json:"id"`
+	Source      string          `json:"source"`
+	Destination string          `json:"destination"`
+	Reached     bool            `json:"reached"`
+	Hops        []TracerouteHop `json:"hops"`
+}
+
+// findRouterByIP returns the router whose TUN device has the given IP.
+func (m *RouterManager) findRouterByIP(ip net.IP) *Router {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, r := range m.routers {
+		if r.TunDevice != nil && r.TunDevice.GetIP() != nil && r.TunDevice.GetIP().Equal(ip) {
+			return r
+		}
+	}
+	return nil
+}
+
+// resolveDestination accepts an IPv4 address or a router ID and returns the destination IP.
+func (m *RouterManager) resolveDestination(destination string) (net.IP, error) {
+	if ip := net.ParseIP(destination); ip != nil && ip.To4() != nil {
+		return ip.To4(), nil
+	}
+	if r, ok := m.GetRouter(destination); ok && r.TunDevice != nil {
+		return r.TunDevice.GetIP(), nil
+	}
+	return nil, fmt.Errorf("destination %s is neither an IPv4 address nor a router ID", destination)
+}
+
+// buildEchoRequest builds an ICMP echo request packet used as the probe.
+func buildEchoRequest(src, dst net.IP, ttl int, id, seq uint16) ([]byte, *IPv4Header, error) {
+	icmp := make([]byte, 8)
+	icmp[0] = 8 // Echo Request
+	binary.BigEndian.PutUint16(icmp[4:6], id)
+	binary.BigEndian.PutUint16(icmp[6:8], seq)
+	binary.BigEndian.PutUint16(icmp[2:4], calculateICMPChecksum(icmp))
+
+	hdr := &IPv4Header{Version: 4, IHL: 5, TTL: byte(ttl), Protocol: ICMPProtocolNumber, SrcIP: src, DstIP: dst}
+	packet, err := constructIPPacket(hdr, icmp)
+	return packet, hdr, err
+}
+
+// sendProbe walks an ICMP echo request from source towards dst through the virtual topology.
+func (m *RouterManager) sendProbe(source *Router, dst net.IP, ttl int, seq uint16) (probeResult, error) {
+	packet, hdr, err := buildEchoRequest(source.TunDevice.GetIP(), dst, ttl, 0xbeef, seq)
+	if err != nil {
+		return probeResult{}, err
+	}
+
+	current := source
+	for hops := 0; ; hops++ {
+		if current.ownsIP(dst) {
+			return probeResult{outcome: probeReached, responder: current, hops: hops}, nil
+		}
+		if hops > 0 {
+			// Every router that forwards the packet decrements TTL
+			hdr.TTL--
+			if hdr.TTL == 0 {
+				return probeResult{outcome: probeTTLExceeded, responder: current, hops: hops}, nil
+			}
+			// Filtering applies to transit traffic only, as in processIncomingPacket
+			if !current.checkACL(hdr, packet) {
+				return probeResult{outcome: probeFiltered, responder: current, hops: hops}, nil
+			}
+		}
+
+		route, found := current.lookupRoute(dst)
+		if !found {
+			return probeResult{outcome: probeUnreachable, responder: current, hops: hops}, nil
+		}
+		if route.NextHop == "0.0.0.0" {
+			// Delivered to a host on the directly connected network
+			return probeResult{outcome: probeReached, responder: current, hops: hops}, nil
+		}
+		next := m.findRouterByIP(net.ParseIP(route.NextHop))
+		if next == nil || next == current {
+			return probeResult{outcome: probeUnreachable, responder: current, hops: hops}, nil
+		}
+		current = next
+	}
+}
+
+// simulatedRTT returns the RTT for a probe that traversed hops links and took elapsed to process.
+func simulatedRTT(hops int, elapsed time.Duration) float64 {
+	rtt := elapsed + time.Duration(2*hops)*SimulatedLinkLatency
+	return float64(rtt.Microseconds()) / 1000
+}
+
+// Ping sends count echo requests from the source router to destination (an IP or a router ID).
+// Each reply is broadcast as a PING_REPLY event and the summary as a PING_COMPLETE event.
+func (m *RouterManager) Ping(sourceID string, destination string, count int) (PingSummary, error) {
+	source, exists := m.GetRouter(sourceID)
+	if !exists {
+		return PingSummary{}, fmt.Errorf("router with ID %s not found", sourceID)
+	}
+	dst, err := m.resolveDestination(destination)
+	if err != nil {
+		return PingSummary{}, err
+	}
+	if count <= 0 {
+		count = DefaultPingCount
+	}
+	if count > MaxPingCount {
+		count = MaxPingCount
+	}
+
+	summary := PingSummary{ID: uuid.New().String(), Source: sourceID, Destination: dst.String()}
+	log.Printf("RouterManager: Ping %s from %s (%d probes, session %s)", dst, sourceID, count, summary.ID)
+	var totalRTT float64
+	for seq := 1; seq <= count; seq++ {
+		if seq > 1 {
+			time.Sleep(PingInterval)
+		}
+		start := time.Now()
+		result, err := m.sendProbe(source, dst, ProbeTTL, uint16(seq))
+		if err != nil {
+			return PingSummary{}, err
+		}
+
+		reply := PingReply{Seq: seq, Responder: result.responder.ID}
+		switch result.outcome {
+		case probeReached:
+			reply.Success = true
+			reply.RTTMs = simulatedRTT(result.hops, time.Since(start))
+			reply.TTL = ProbeTTL - result.hops
+		case probeTTLExceeded:
+			reply.Error = fmt.Sprintf("time to live exceeded at %s", result.responder.ID)
+		case probeUnreachable:
+			reply.Error = fmt.Sprintf("destination host unreachable from %s", result.responder.ID)
+		case probeFiltered:
+			reply.Error = fmt.Sprintf("administratively prohibited by ACL on %s", result.responder.ID)
+		}
+
+		summary.Sent++
+		if reply.Success {
+			summary.Received++
+			totalRTT += reply.RTTMs
+			if summary.MinRTTMs == 0 || reply.RTTMs < summary.MinRTTMs {
+				summary.MinRTTMs = reply.RTTMs
+			}
+			if reply.RTTMs > summary.MaxRTTMs {
+				summary.MaxRTTMs = reply.RTTMs
+			}
+		}
+		summary.Replies = append(summary.Replies, reply)
+
+		m.BroadcastOutChan <- map[string]interface{}{
+			"event":       "PING_REPLY",
+			"sessionId":   summary.ID,
+			"routerId":    sourceID,
+			"destination": summary.Destination,
+			"reply":       reply,
+		}
+	}
+
+	if summary.Received > 0 {
+		summary.AvgRTTMs = totalRTT / float64(summary.Received)
+	}
+	summary.LossPercent = float64(summary.Sent-summary.Received) * 100 / float64(summary.Sent)
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":     "PING_COMPLETE",
+		"sessionId": summary.ID,
+		"routerId":  sourceID,
+		"summary":   summary,
+	}
+	return summary, nil
+}
+
+// Traceroute sends probes with increasing TTL from the source router to destination (an IP or a router ID).
+// Each hop is broadcast as a TRACEROUTE_HOP event and the result as a TRACEROUTE_COMPLETE event.
+func (m *RouterManager) Traceroute(sourceID string, destination string, maxHops int) (TracerouteResult, error) {
+	source, exists := m.GetRouter(sourceID)
+	if !exists {
+		return TracerouteResult{}, fmt.Errorf("router with ID %s not found", sourceID)
+	}
+	dst, err := m.resolveDestination(destination)
+	if err != nil {
+		return TracerouteResult{}, err
+	}
+	if maxHops <= 0 || maxHops > DefaultTracerouteTTL {
+		maxHops = DefaultTracerouteTTL
+	}
+
+	result := TracerouteResult{ID: uuid.New().String(), Source: sourceID, Destination: dst.String()}
+	log.Printf("RouterManager: Traceroute %s from %s (max %d hops, session %s)", dst, sourceID, maxHops, result.ID)
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		start := time.Now()
+		probe, err := m.sendProbe(source, dst, ttl, uint16(ttl))
+		if err != nil {
+			return TracerouteResult{}, err
+		}
+
+		hop := TracerouteHop{
+			Hop:      ttl,
+			RouterID: probe.responder.ID,
+			IP:       probe.responder.TunDevice.GetIP().String(),
+			RTTMs:    simulatedRTT(probe.hops, time.Since(start)),
+		}
+		done := false
+		switch probe.outcome {
+		case probeReached:
+			result.Reached = true
+			done = true
+		case probeUnreachable:
+			hop.Note = "!H"
+			done = true
+		case probeFiltered:
+			// The filtering router drops the probe silently
+			hop = TracerouteHop{Hop: ttl, Timeout: true, Note: "!X"}
+			done = true
+		}
+		result.Hops = append(result.Hops, hop)
+
+		m.BroadcastOutChan <- map[string]interface{}{
+			"event":       "TRACEROUTE_HOP",
+			"sessionId":   result.ID,
+			"routerId":    sourceID,
+			"destination": result.Destination,
+			"hop":         hop,
+		}
+		if done {
+			break
+		}
+	}
+
+	m.BroadcastOutChan <- map[string]interface{}{
+		"event":     "TRACEROUTE_COMPLETE",
+		"sessionId": result.ID,
+		"routerId":  sourceID,
+		"result":    result,
+	}
+	return result, nil
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3" // SQLiteドライバー
+
+	"github.com/lirlia/100day_challenge_backend/day44_go_virtual_router/go_router/router"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS routers (
+	id       TEXT PRIMARY KEY,
+	tun_name TEXT NOT NULL,
+	ip_cidr  TEXT NOT NULL,
+	mtu      INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS interfaces (
+	router_id TEXT NOT NULL REFERENCES routers(id) ON DELETE CASCADE,
+	name      TEXT NOT NULL,
+	ip_cidr   TEXT NOT NULL,
+	parent    TEXT NOT NULL DEFAULT '',
+	vlan_id   INTEGER NOT NULL DEFAULT 0,
+	position  INTEGER NOT NULL,
+	PRIMARY KEY (router_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS static_routes (
+	router_id   TEXT NOT NULL REFERENCES routers(id) ON DELETE CASCADE,
+	destination TEXT NOT NULL,
+	next_hop    TEXT NOT NULL,
+	metric      INTEGER NOT NULL,
+	PRIMARY KEY (router_id, destination)
+);
+
+CREATE TABLE IF NOT EXISTS connections (
+	id           TEXT PRIMARY KEY,
+	router1_id   TEXT NOT NULL REFERENCES routers(id) ON DELETE CASCADE,
+	router2_id   TEXT NOT NULL REFERENCES routers(id) ON DELETE CASCADE,
+	created_at   TIMESTAMP NOT NULL,
+	delay_ms     INTEGER NOT NULL DEFAULT 0,
+	jitter_ms    INTEGER NOT NULL DEFAULT 0,
+	loss_percent REAL NOT NULL DEFAULT 0,
+	rate_kbps    INTEGER NOT NULL DEFAULT 0
+);
+`
+
+// SQLiteStore は仮想ネットワークのトポロジーを SQLite ファイルに保存します (router.TopologyStore の実装)。
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// Open は SQLite ファイルを開き (存在しなければ作成し)、スキーマを適用します。
+func Open(path string) (*SQLiteStore, error) {
+	// データベースファイルが置かれるディレクトリが存在しない場合は作成
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory %s: %w", dir, err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1) // SQLite は書き込みが1接続のみ
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply schema: %w", err)
+	}
+	log.Printf("Store: Opened topology database %s", path)
+	return &SQLiteStore{db: db}, nil
+}
+
+// Close はデータベース接続を閉じます。
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Save はトポロジー全体を1トランザクションで書き換えます。
+func (s *SQLiteStore) Save(topology *router.Topology) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// routers を消すと interfaces, static_routes, connections もカスケード削除される
+	if _, err := tx.Exec(`DELETE FROM routers`); err != nil {
+		return fmt.Errorf("failed to clear routers: %w", err)
+	}
+	for _, r := range topology.Routers {
+		if _, err := tx.Exec(`INSERT INTO routers (id, tun_name, ip_cidr, mtu) VALUES (?, ?, ?, ?)`,
+			r.ID, r.TunName, r.IPCIDR, r.MTU); err != nil {
+			return fmt.Errorf("failed to save router %s: %w", r.ID, err)
+		}
+		for i, ifc := range r.Interfaces {
+			if _, err := tx.Exec(`INSERT INTO interfaces (router_id, name, ip_cidr, parent, vlan_id, position) VALUES (?, ?, ?, ?, ?, ?)`,
+				r.ID, ifc.Name, ifc.IPCIDR, ifc.Parent, ifc.VLANID, i); err != nil {
+				return fmt.Errorf("failed to save interface %s of router %s: %w", ifc.Name, r.ID, err)
+			}
+		}
+		for _, route := range r.StaticRoutes {
+			if _, err := tx.Exec(`INSERT INTO static_routes (router_id, destination, next_hop, metric) VALUES (?, ?, ?, ?)`,
+				r.ID, route.Destination, route.NextHop, route.Metric); err != nil {
+				return fmt.Errorf("failed to save static route %s of router %s: %w", route.Destination, r.ID, err)
+			}
+		}
+	}
+	for _, c := range topology.Connections {
+		if _, err := tx.Exec(`INSERT INTO connections (id, router1_id, router2_id, created_at, delay_ms, jitter_ms, loss_percent, rate_kbps) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.ID, c.Router1ID, c.Router2ID, c.CreatedAt.UTC(), c.Impairment.DelayMs, c.Impairment.JitterMs, c.Impairment.LossPercent, c.Impairment.RateKbps); err != nil {
+			return fmt.Errorf("failed to save connection %s: %w", c.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Load は保存されたトポロジーを読み込みます。何も保存されていなければ空のトポロジーを返します。
+func (s *SQLiteStore) Load() (*router.Topology, error) {
+	topology := &router.Topology{}
+	index := make(map[string]int) // router ID -> index in topology.Routers
+
+	rows, err := s.db.Query(`SELECT id, tun_name, ip_cidr, mtu FROM routers ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load routers: %w", err)
+	}
+	for rows.Next() {
+		var r router.RouterRecord
+		if err := rows.Scan(&r.ID, &r.TunName, &r.IPCIDR, &r.MTU); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan router: %w", err)
+		}
+		index[r.ID] = len(topology.Routers)
+		topology.Routers = append(topology.Routers, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load routers: %w", err)
+	}
+
+	rows, err = s.db.Query(`SELECT router_id, name, ip_cidr, parent, vlan_id FROM interfaces ORDER BY router_id, position`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+	for rows.Next() {
+		var routerID string
+		var ifc router.InterfaceConfig
+		if err := rows.Scan(&routerID, &ifc.Name, &ifc.IPCIDR, &ifc.Parent, &ifc.VLANID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan interface: %w", err)
+		}
+		r := &topology.Routers[index[routerID]]
+		r.Interfaces = append(r.Interfaces, ifc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load interfaces: %w", err)
+	}
+
+	rows, err = s.db.Query(`SELECT router_id, destination, next_hop, metric FROM static_routes ORDER BY router_id, destination`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load static routes: %w", err)
+	}
+	for rows.Next() {
+		var routerID string
+		var route router.StaticRouteRecord
+		if err := rows.Scan(&routerID, &route.Destination, &route.NextHop, &route.Metric); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan static route: %w", err)
+		}
+		r := &topology.Routers[index[routerID]]
+		r.StaticRoutes = append(r.StaticRoutes, route)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load static routes: %w", err)
+	}
+
+	rows, err = s.db.Query(`SELECT id, router1_id, router2_id, created_at, delay_ms, jitter_ms, loss_percent, rate_kbps FROM connections ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load connections: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c router.ConnectionInfo
+		if err := rows.Scan(&c.ID, &c.Router1ID, &c.Router2ID, &c.CreatedAt,
+			&c.Impairment.DelayMs, &c.Impairment.JitterMs, &c.Impairment.LossPercent, &c.Impairment.RateKbps); err != nil {
+			return nil, fmt.Errorf("failed to scan connection: %w", err)
+		}
+		topology.Connections = append(topology.Connections, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load connections: %w", err)
+	}
+	return topology, nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket command protocol
+//
+// Clients send JSON commands and receive a "response" message for each of them:
+//
+//	{"type": "subscribe", "id": "1", "routers": ["router1"], "events": ["ROUTE_ADDED"]}
+//	{"type": "unsubscribe", "id": "2"}
+//	{"type": "get_routers", "id": "3"}
+//	{"type": "get_connections", "id": "4"}
+//	{"type": "get_routing_table", "id": "5", "routerId": "router1"}
+//	{"type": "tail_packets", "id": "6", "routerId": "router1"}
+//	{"type": "untail_packets", "id": "7", "routerId": "router1"}
+//
+//	{"type": "response", "id": "5", "command": "get_routing_table", "data": [...]}
+//	{"type": "response", "id": "5", "command": "get_routing_table", "error": "..."}
+//
+// Events broadcast by RouterManager ({"event": "ROUTER_CREATED", ...}) are delivered as is, filtered by the
+// client's subscription. A new client receives all events until it subscribes. PACKET_LOG events are only
+// delivered for routers the client is tailing.
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Allow all origins for simplicity
+	},
+}
+
+// wsCommand is a command sent by a WebSocket client.
+type wsCommand struct {
+	Type     string   `json:"type"`
+	ID       string   `json:"id,omitempty"` // Echoed back in the response for correlation
+	RouterID string   `json:"routerId,omitempty"`
+	Routers  []string `json:"routers,omitempty"` // subscribe: router IDs to receive events for (empty = all)
+	Events   []string `json:"events,omitempty"`  // subscribe: event names to receive (empty = all)
+}
+
+// wsResponse is the response to a wsCommand.
+type wsResponse struct {
+	Type    string      `json:"type"` // Always "response"
+	ID      string      `json:"id,omitempty"`
+	Command string      `json:"command"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// wsClient is a connected WebSocket client and its subscription.
+type wsClient struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex // gorilla/websocket supports only one concurrent writer
+
+	mu      sync.Mutex
+	muted   bool            // Set by unsubscribe: no events until the next subscribe
+	routers map[string]bool // nil = all routers
+	events  map[string]bool // nil = all events
+	tails   map[string]bool // Routers whose packet log is tailed
+}
+
+// WebSocket client management
+var (
+	clients   = make(map[*wsClient]bool)
+	clientsMu sync.Mutex
+)
+
+func (c *wsClient) writeJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+// wants reports whether the event message matches the client's subscription.
+func (c *wsClient) wants(msg map[string]interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	event, _ := msg["event"].(string)
+	routerID, hasRouter := msg["routerId"].(string)
+
+	if event == "PACKET_LOG" {
+		return hasRouter && c.tails[routerID]
+	}
+	if c.muted {
+		return false
+	}
+	if c.events != nil && !c.events[event] {
+		return false
+	}
+	// Events that are not about a single router (e.g. connections) pass the router filter
+	if c.routers != nil && hasRouter && !c.routers[routerID] {
+		return false
+	}
+	return true
+}
+
+func toSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// handleCommand executes a command and returns the response data.
+func (c *wsClient) handleCommand(cmd wsCommand) (interface{}, error) {
+	switch cmd.Type {
+	case "subscribe":
+		c.mu.Lock()
+		c.muted = false
+		c.routers = toSet(cmd.Routers)
+		c.events = toSet(cmd.Events)
+		c.mu.Unlock()
+		return map[string]interface{}{"routers": cmd.Routers, "events": cmd.Events}, nil
+
+	case "unsubscribe":
+		c.mu.Lock()
+		c.muted = true
+		c.mu.Unlock()
+		return nil, nil
+
+	case "get_routers":
+		return manager.GetAllRoutersInfo(), nil
+
+	case "get_connections":
+		return manager.GetConnections(), nil
+
+	case "get_routing_table":
+		rt, exists := manager.GetRouter(cmd.RouterID)
+		if !exists {
+			return nil, fmt.Errorf("router with ID %s not found", cmd.RouterID)
+		}
+		return map[string]interface{}{"routerId": cmd.RouterID, "table": rt.GetRoutingTable()}, nil
+
+	case "tail_packets":
+		rt, exists := manager.GetRouter(cmd.RouterID)
+		if !exists {
+			return nil, fmt.Errorf("router with ID %s not found", cmd.RouterID)
+		}
+		c.mu.Lock()
+		if c.tails[cmd.RouterID] {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("already tailing router %s", cmd.RouterID)
+		}
+		c.tails[cmd.RouterID] = true
+		c.mu.Unlock()
+		manager.SetPacketTail(cmd.RouterID, true)
+		// Respond with the recent packets; new packets follow as PACKET_LOG events
+		return map[string]interface{}{"routerId": cmd.RouterID, "packets": rt.GetPacketLog()}, nil
+
+	case "untail_packets":
+		c.mu.Lock()
+		tailing := c.tails[cmd.RouterID]
+		delete(c.tails, cmd.RouterID)
+		c.mu.Unlock()
+		if !tailing {
+			return nil, fmt.Errorf("not tailing router %s", cmd.RouterID)
+		}
+		manager.SetPacketTail(cmd.RouterID, false)
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unknown command type %q", cmd.Type)
+	}
+}
+
+// close stops the client's packet tails.
+func (c *wsClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for routerID := range c.tails {
+		manager.SetPacketTail(routerID, false)
+	}
+	c.tails = map[string]bool{}
+}
+
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("Upgrade error:", err)
+		return
+	}
+	defer conn.Close()
+
+	client := &wsClient{conn: conn, tails: make(map[string]bool)}
+	clientsMu.Lock()
+	clients[client] = true
+	numClients := len(clients)
+	clientsMu.Unlock()
+	log.Println("WebSocket client connected. Total clients:", numClients)
+
+	defer func() {
+		clientsMu.Lock()
+		delete(clients, client)
+		numClients := len(clients)
+		clientsMu.Unlock()
+		client.close()
+		log.Println("WebSocket client disconnected. Total clients:", numClients)
+	}()
+
+	for {
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("WebSocket read error: %v", err)
+			}
+			break
+		}
+
+		var cmd wsCommand
+		resp := wsResponse{Type: "response"}
+		if err := json.Unmarshal(p, &cmd); err != nil {
+			resp.Error = fmt.Sprintf("invalid command: %v", err)
+		} else {
+			resp.ID = cmd.ID
+			resp.Command = cmd.Type
+			data, err := client.handleCommand(cmd)
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Data = data
+			}
+		}
+		if err := client.writeJSON(resp); err != nil {
+			log.Println("WebSocket write error:", err)
+			break
+		}
+	}
+}
+
+func handleBroadcastMessages(broadcastInChan <-chan map[string]interface{}) {
+	for {
+		msgMap := <-broadcastInChan           // Receive map from RouterManager
+		msgBytes, err := json.Marshal(msgMap) // Marshal to JSON bytes
+		if err != nil {
+			log.Printf("Error marshaling broadcast message: %v. Message: %+v", err, msgMap)
+			continue
+		}
+
+		clientsMu.Lock()
+		targets := make([]*wsClient, 0, len(clients))
+		for client := range clients {
+			if client.wants(msgMap) {
+				targets = append(targets, client)
+			}
+		}
+		clientsMu.Unlock()
+
+		for _, client := range targets {
+			client.writeMu.Lock()
+			err := client.conn.WriteMessage(websocket.TextMessage, msgBytes)
+			client.writeMu.Unlock()
+			if err != nil {
+				log.Printf("Broadcast error to client %s: %v", client.conn.RemoteAddr(), err)
+			}
+		}
+	}
+}
@@ -1,6 +1,6 @@
 'use client';
 
-import React, { useState, useCallback, useEffect, type FormEvent } from 'react';
+import React, { useState, useCallback, useEffect, useRef, type FormEvent } from 'react';
 import ReactFlow, {
   MiniMap,
   Controls,
@@ -22,6 +22,14 @@ interface GoRouterInfo {
   tunName: string;
   ip: string;
   numRoutes: number;
+  interfaces?: {
+    name: string;
+    ipCIDR: string;
+    network: string;
+    parent?: string;
+    vlanId?: number;
+    primary: boolean;
+  }[];
   // neighbors: any; // For now, not directly used in nodes
 }
 
@@ -47,8 +55,27 @@ interface ConnectionInfo {
   router1Id: string;
   router2Id: string;
   createdAt: string; // Assuming string from Go's time.Time JSON marshal
+  impairment?: {
+    delayMs: number;
+    jitterMs: number;
+    lossPercent: number;
+    rateKbps: number;
+  };
 }
 
+// 劣化設定のあるリンクにはラベルを表示する (例: "20±5ms 1% 512kbps")
+const impairmentLabel = (conn: ConnectionInfo): string | undefined => {
+  const imp = conn.impairment;
+  if (!imp) return undefined;
+  const parts: string[] = [];
+  if (imp.delayMs > 0 || imp.jitterMs > 0) {
+    parts.push(imp.jitterMs > 0 ? `${imp.delayMs}±${imp.jitterMs}ms` : `${imp.delayMs}ms`);
+  }
+  if (imp.lossPercent > 0) parts.push(`${imp.lossPercent}%`);
+  if (imp.rateKbps > 0) parts.push(`${imp.rateKbps}kbps`);
+  return parts.length > 0 ? parts.join(' ') : undefined;
+};
+
 // AppNode 型の定義は useNodesState のジェネリックとしては使わない
 // type AppNode = Node<CustomRouterNodeData>;
 
@@ -109,6 +136,23 @@ export default function HomePage() {
   const nodeTypes = React.useMemo(() => ({}), []);
   const edgeTypes = React.useMemo(() => ({}), []);
 
+  // Update React Flow nodes from the router list (REST API or WebSocket get_routers response)
+  const applyRouters = useCallback((data: GoRouterInfo[]) => {
+    console.log('Routers:', data); // デバッグ用
+    const newNodes: Node<CustomRouterNodeData>[] = data.map((router, index) => ({
+      id: router.id,
+      position: { x: 400, y: 300 + (index * 100) }, // 画面中央付近に縦並び
+      data: {
+        routerId: router.id,
+        label: `${router.id} (${router.tunName || 'N/A'})`,
+        ip: router.ip,
+      },
+      type: 'default',
+    }));
+    console.log('newNodes:', newNodes); // デバッグ用
+    setNodes(() => newNodes); // コールバック形式を維持
+  }, [setNodes]);
+
   // Fetch routers from API and update React Flow nodes
   const fetchAndSetRouters = useCallback(async () => {
     try {
@@ -117,26 +161,27 @@ export default function HomePage() {
         throw new Error(`Failed to fetch routers: ${response.statusText}`);
       }
       const data: GoRouterInfo[] = await response.json();
-      console.log('Routers from API:', data); // デバッグ用
-      const newNodes: Node<CustomRouterNodeData>[] = data.map((router, index) => ({
-        id: router.id,
-        position: { x: 400, y: 300 + (index * 100) }, // 画面中央付近に縦並び
-        data: {
-          routerId: router.id,
-          label: `${router.id} (${router.tunName || 'N/A'})`,
-          ip: router.ip,
-        },
-        type: 'default',
-      }));
-      console.log('newNodes:', newNodes); // デバッグ用
-      setNodes(() => newNodes); // コールバック形式を維持
+      applyRouters(data);
       setLogs((prev) => ['Fetched routers from API', ...prev].slice(-100));
     } catch (error) {
       console.error('Error fetching routers:', error);
       const errorMessage = error instanceof Error ? error.message : String(error);
       setLogs((prev) => [`ERROR fetching routers: ${errorMessage}`, ...prev].slice(-100));
     }
-  }, [setNodes]);
+  }, [applyRouters]);
+
+  // Update React Flow edges from the connection list (REST API or WebSocket get_connections response)
+  const applyConnections = useCallback((data: ConnectionInfo[]) => {
+    const newEdges: Edge[] = data.map(conn => ({
+      id: conn.id,
+      source: conn.router1Id,
+      target: conn.router2Id,
+      // type: 'custom', // Optional: if using custom edge types
+      animated: true, // Example: make edges animated
+      label: impairmentLabel(conn),
+    }));
+    setEdges(() => newEdges);
+  }, [setEdges]);
 
   const fetchAndSetConnections = useCallback(async () => {
     try {
@@ -145,41 +190,69 @@ export default function HomePage() {
         throw new Error(`Failed to fetch connections: ${response.statusText}`);
       }
       const data: ConnectionInfo[] = await response.json();
-      const newEdges: Edge[] = data.map(conn => ({
-        id: conn.id,
-        source: conn.router1Id,
-        target: conn.router2Id,
-        // type: 'custom', // Optional: if using custom edge types
-        animated: true, // Example: make edges animated
-      }));
-      setEdges(() => newEdges);
+      applyConnections(data);
       setLogs(prev => ['Fetched connections from API', ...prev].slice(-100));
     } catch (error) {
       console.error('Error fetching connections:', error);
       const errorMessage = error instanceof Error ? error.message : String(error);
       setLogs(prev => [`ERROR fetching connections: ${errorMessage}`, ...prev].slice(-100));
     }
-  }, [setEdges]);
+  }, [applyConnections]);
 
   useEffect(() => {
     fetchAndSetRouters(); // Initial fetch for routers
     fetchAndSetConnections(); // Initial fetch for connections
   }, [fetchAndSetRouters, fetchAndSetConnections]);
 
+  // WebSocket command protocol (see go_router/websocket.go)
+  const wsRef = useRef<WebSocket | null>(null);
+  const sendCommand = useCallback((type: string, params: Record<string, unknown> = {}) => {
+    const ws = wsRef.current;
+    if (!ws || ws.readyState !== WebSocket.OPEN) return false;
+    ws.send(JSON.stringify({ type, id: type, ...params }));
+    return true;
+  }, []);
+
   useEffect(() => {
     if (!wsUrl) return;
     const ws = new WebSocket(wsUrl);
-    ws.onopen = () => setLogs((prev) => ['WebSocket connected', ...prev].slice(-100));
+    wsRef.current = ws;
+    ws.onopen = () => {
+      setLogs((prev) => ['WebSocket connected', ...prev].slice(-100));
+      // Pick up anything that changed before the connection was established
+      sendCommand('get_routers');
+      sendCommand('get_connections');
+    };
     ws.onmessage = (event) => {
       const message = typeof event.data === 'string' ? event.data : JSON.stringify(event.data);
       setLogs((prev) => [`RECV: ${message}`, ...prev].slice(-100));
       try {
         const parsed = JSON.parse(message);
-        if (parsed.event === 'ROUTER_CREATED' || parsed.event === 'ROUTER_DELETED') {
+        if (parsed.type === 'response') {
+          if (parsed.error) {
+            setLogs((prev) => [`ERROR: ${parsed.command} failed: ${parsed.error}`, ...prev].slice(-100));
+          } else if (parsed.command === 'get_routers' && Array.isArray(parsed.data)) {
+            applyRouters(parsed.data as GoRouterInfo[]);
+          } else if (parsed.command === 'get_connections' && Array.isArray(parsed.data)) {
+            applyConnections(parsed.data as ConnectionInfo[]);
+          } else if (parsed.command === 'get_routing_table' && parsed.data) {
+            const { routerId, table } = parsed.data;
+            setRoutingTables((prevTables) => ({
+              ...prevTables,
+              [routerId]: (table ?? []) as RoutingEntry[],
+            }));
+            setLogs((prev) => [`Routing table fetched for ${routerId}`, ...prev].slice(-100));
+          }
+        } else if (
+          parsed.event === 'ROUTER_CREATED' ||
+          parsed.event === 'ROUTER_DELETED' ||
+          parsed.event === 'INTERFACE_ADDED' ||
+          parsed.event === 'INTERFACE_REMOVED'
+        ) {
           setLogs((prev) =>
-            [`Event received: ${parsed.event}, fetching routers...`, ...prev].slice(-100)
+            [`Event received: ${parsed.event}, requesting routers...`, ...prev].slice(-100)
           );
-          fetchAndSetRouters();
+          sendCommand('get_routers');
         } else if (parsed.event === 'ROUTING_TABLE_UPDATED') {
           const { routerId, table } = parsed;
           if (typeof routerId === 'string' && Array.isArray(table)) {
@@ -196,12 +269,13 @@ export default function HomePage() {
               ].slice(-100)
             );
           }
-        } else if (parsed.event === 'CONNECTION_CREATED') {
-          setLogs(prev => [`Event received: CONNECTION_CREATED, fetching connections...`, ...prev].slice(-100));
-          fetchAndSetConnections(); // Or add the new connection locally
-        } else if (parsed.event === 'CONNECTION_DELETED') {
-          setLogs(prev => [`Event received: CONNECTION_DELETED, fetching connections...`, ...prev].slice(-100));
-          fetchAndSetConnections(); // Or remove the connection locally
+        } else if (
+          parsed.event === 'CONNECTION_CREATED' ||
+          parsed.event === 'CONNECTION_DELETED' ||
+          parsed.event === 'CONNECTION_UPDATED'
+        ) {
+          setLogs(prev => [`Event received: ${parsed.event}, requesting connections...`, ...prev].slice(-100));
+          sendCommand('get_connections');
         }
       } catch (_e) {
         // console.error("Error parsing ws message:", e); // Already logged in general RECV
@@ -210,8 +284,11 @@ export default function HomePage() {
     ws.onerror = (error) =>
       setLogs((prev) => [`ERROR: ${JSON.stringify(error)}`, ...prev].slice(-100));
     ws.onclose = () => setLogs((prev) => ['WebSocket disconnected', ...prev].slice(-100));
-    return () => ws.close();
-  }, [wsUrl, fetchAndSetRouters, fetchAndSetConnections]);
+    return () => {
+      wsRef.current = null;
+      ws.close();
+    };
+  }, [wsUrl, sendCommand, applyRouters, applyConnections]);
 
   const handleCreateRouter = async (e: FormEvent) => {
     e.preventDefault();
@@ -237,8 +314,7 @@ export default function HomePage() {
       setNewRouterId('');
       setNewRouterIpCIDR('');
       setNewRouterTunName('');
-      fetchAndSetRouters();
-      fetchAndSetConnections();
+      // ROUTER_CREATED event triggers a get_routers request over WebSocket
     } catch (error) {
       console.error('Error creating router:', error);
       const errorMessage = error instanceof Error ? error.message : String(error);
@@ -269,10 +345,10 @@ export default function HomePage() {
 
   const onNodeClick: NodeMouseHandler = useCallback((_event, node) => {
     setSelectedRouterId(node.id);
-    // Optionally, fetch routing table here if not relying solely on WebSocket updates
-    // Or, if table for node.id is already in routingTables, it will be displayed
+    // Request the current table; later changes arrive as ROUTING_TABLE_UPDATED events
+    sendCommand('get_routing_table', { routerId: node.id });
     setLogs((prev) => [`Node ${node.id} clicked. Displaying its details.`, ...prev].slice(-100));
-  }, []);
+  }, [sendCommand]);
 
   return (
     <div className="flex flex-col h-screen bg-slate-800 text-slate-100">
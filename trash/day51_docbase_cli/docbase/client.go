@@ -0,0 +1,243 @@
+// Package docbase は DocBase API のクライアントです。
+// 5xx / 429 のレスポンスは Retry-After ヘッダーまたは指数バックオフで待ってから自動で再試行し、
+// すべてのリクエストは context でキャンセルできます。
+package docbase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultBaseURL       = "https://api.docbase.io"
+	DefaultTimeout       = 20 * time.Second
+	DefaultSleepDuration = 13 * time.Second // APIレートリミットを考慮 (1分間に5回 -> 1リクエストあたり12秒。マージン含め13秒)
+	DefaultMaxRetries    = 3
+	DefaultRetryWaitMin  = 1 * time.Second
+	DefaultRetryWaitMax  = 30 * time.Second
+	maxPerPage           = 100 // DocBase APIのper_page最大値
+)
+
+// ErrStopPagination は ForEachPostPage のコールバックから返すと、エラーにせずに取得を終了します。
+var ErrStopPagination = errors.New("stop pagination")
+
+// DocBase APIのレスポンス構造体
+type Post struct {
+	ID        int       `json:"id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Draft     bool      `json:"draft"`
+	Archived  bool      `json:"archived"`
+	URL       string    `json:"url"`
+	Scope     string    `json:"scope"`
+	CreatedAt time.Time `json:"created_at"` // time.Timeとして直接パース
+	UpdatedAt time.Time `json:"updated_at"`
+	Tags      []Tag     `json:"tags"`
+	User      User      `json:"user"`
+	Groups    []Group   `json:"groups"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Group struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type PostMeta struct {
+	Total    int     `json:"total"`
+	NextPage *string `json:"next_page"` // nullの場合があるのでポインタ型
+	PrevPage *string `json:"previous_page"`
+}
+
+type PostResponse struct {
+	Posts []Post   `json:"posts"`
+	Meta  PostMeta `json:"meta"`
+}
+
+// PostRequest は記事作成・更新APIのリクエストボディです。
+// 更新時は nil のフィールドを送信しないため、指定した項目だけが変更されます。
+type PostRequest struct {
+	Title  *string   `json:"title,omitempty"`
+	Body   *string   `json:"body,omitempty"`
+	Draft  *bool     `json:"draft,omitempty"`
+	Notice *bool     `json:"notice,omitempty"`
+	Tags   *[]string `json:"tags,omitempty"`
+	Scope  *string   `json:"scope,omitempty"`
+	Groups *[]int    `json:"groups,omitempty"`
+}
+
+// Client は DocBase API のクライアントです。フィールドは NewClient の後で変更できます。
+type Client struct {
+	TeamName      string
+	Token         string
+	BaseURL       string        // テスト時はモックサーバーのURLに差し替える
+	HTTPClient    *http.Client  // タイムアウトは HTTPClient.Timeout で設定する
+	SleepDuration time.Duration // 記事一覧のページを取得する間隔
+	MaxRetries    int           // 5xx / 429 の場合の最大再試行回数 (0 で再試行しない)
+	RetryWaitMin  time.Duration // 1回目の再試行までの待ち時間 (以降は2倍ずつ増やす)
+	RetryWaitMax  time.Duration // 再試行までの待ち時間の上限 (Retry-After の指定は上限を超えても従う)
+}
+
+func NewClient(teamName, token string) *Client {
+	return &Client{
+		TeamName:      teamName,
+		Token:         token,
+		BaseURL:       DefaultBaseURL,
+		HTTPClient:    &http.Client{Timeout: DefaultTimeout},
+		SleepDuration: DefaultSleepDuration,
+		MaxRetries:    DefaultMaxRetries,
+		RetryWaitMin:  DefaultRetryWaitMin,
+		RetryWaitMax:  DefaultRetryWaitMax,
+	}
+}
+
+// postsEndpoint は記事APIのエンドポイント (/teams/:domain/posts) です。
+func (c *Client) postsEndpoint() string {
+	return fmt.Sprintf("%s/teams/%s/posts", c.BaseURL, c.TeamName)
+}
+
+// ListPosts は記事一覧APIの page ページ目を取得します。params には q (検索クエリ) などを指定できます (nil 可)。
+func (c *Client) ListPosts(ctx context.Context, params url.Values, page int) (*PostResponse, error) {
+	u, err := url.Parse(c.postsEndpoint())
+	if err != nil {
+		return nil, fmt.Errorf("APIエンドポイントURLのパースに失敗: %w", err)
+	}
+
+	q := u.Query()
+	for key, values := range params {
+		q[key] = values
+	}
+	q.Set("per_page", strconv.Itoa(maxPerPage))
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+
+	resp, err := c.do(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("APIリクエストに失敗 (page %d): %w", page, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("APIリクエストエラー (page %d, status %d): %s", page, resp.StatusCode, string(bodyBytes))
+	}
+
+	var postResponse PostResponse
+	if err := json.NewDecoder(resp.Body).Decode(&postResponse); err != nil {
+		return nil, fmt.Errorf("レスポンスJSONのデコードに失敗 (page %d): %w", page, err)
+	}
+	return &postResponse, nil
+}
+
+// ForEachPostPage は記事一覧APIをページネーションで最後まで取得し、ページごとに fn を呼び出します。
+// fn がエラーを返すと取得を中断します (ErrStopPagination の場合はエラーにしません)。
+func (c *Client) ForEachPostPage(ctx context.Context, params url.Values, fn func(posts []Post) error) error {
+	for page := 1; ; page++ {
+		postResponse, err := c.ListPosts(ctx, params, page)
+		if err != nil {
+			return err
+		}
+
+		if len(postResponse.Posts) == 0 {
+			return nil // 記事がもうない場合は終了
+		}
+
+		if err := fn(postResponse.Posts); err != nil {
+			if errors.Is(err, ErrStopPagination) {
+				return nil // 必要な件数を取得できたので終了
+			}
+			return err
+		}
+
+		if postResponse.Meta.NextPage == nil {
+			return nil // 次のページがない場合は終了
+		}
+
+		if err := sleep(ctx, c.SleepDuration); err != nil {
+			return err
+		}
+	}
+}
+
+// CreatePost は記事を作成します。
+func (c *Client) CreatePost(ctx context.Context, req PostRequest) (*Post, error) {
+	return c.sendPostRequest(ctx, "POST", c.postsEndpoint(), req, http.StatusCreated)
+}
+
+// UpdatePost は記事を更新します。req の nil でない項目だけが変更されます。
+func (c *Client) UpdatePost(ctx context.Context, id int, req PostRequest) (*Post, error) {
+	return c.sendPostRequest(ctx, "PATCH", c.postsEndpoint()+"/"+strconv.Itoa(id), req, http.StatusOK)
+}
+
+// sendPostRequest は記事の作成・更新APIを呼び出し、レスポンスの記事を返します。
+func (c *Client) sendPostRequest(ctx context.Context, method, endpoint string, body PostRequest, wantStatus int) (*Post, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("リクエストJSONの作成に失敗: %w", err)
+	}
+
+	resp, err := c.do(ctx, method, endpoint, payload)
+	if err != nil {
+		return nil, fmt.Errorf("APIリクエストに失敗 (%s %s): %w", method, endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("APIリクエストエラー (%s %s, status %d): %s", method, endpoint, resp.StatusCode, string(bodyBytes))
+	}
+
+	var post Post
+	if err := json.NewDecoder(resp.Body).Decode(&post); err != nil {
+		return nil, fmt.Errorf("レスポンスJSONのデコードに失敗: %w", err)
+	}
+	return &post, nil
+}
+
+// do はリクエストを送信し、再試行の対象 (shouldRetry) の場合は MaxRetries 回まで待ってから再試行します。
+// 再試行しても失敗した場合は最後のレスポンスを返すため、ステータスコードの確認は呼び出し側で行います。
+func (c *Client) do(ctx context.Context, method, endpoint string, payload []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if payload != nil {
+			body = bytes.NewReader(payload) // 再試行のたびにボディを作り直す
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+		if err != nil {
+			return nil, fmt.Errorf("リクエストの作成に失敗: %w", err)
+		}
+		req.Header.Set("X-DocBaseToken", c.Token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if attempt >= c.MaxRetries || !shouldRetry(method, resp.StatusCode) {
+			return resp, nil
+		}
+
+		wait := c.retryWait(attempt, resp.Header.Get("Retry-After"), time.Now())
+		io.Copy(io.Discard, resp.Body) // コネクションを再利用できるよう読み切ってから閉じる
+		resp.Body.Close()
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
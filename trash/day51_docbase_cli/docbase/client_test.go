@@ -0,0 +1,184 @@
+package docbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient は server に接続したクライアントを作成します。ページ取得の間隔と再試行の待ち時間は0にします。
+func newTestClient(server *httptest.Server) *Client {
+	client := NewClient("testteam", "test_token")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	client.SleepDuration = 0
+	client.RetryWaitMin, client.RetryWaitMax = 0, 0
+	return client
+}
+
+func TestListPosts_RetriesOn5xxAnd429(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[requests]
+		requests++
+		if status != http.StatusOK {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(PostResponse{Posts: []Post{{ID: 1}}})
+	}))
+	defer server.Close()
+
+	resp, err := newTestClient(server).ListPosts(context.Background(), nil, 1)
+	if err != nil {
+		t.Fatalf("ListPosts failed: %v", err)
+	}
+	if len(resp.Posts) != 1 || resp.Posts[0].ID != 1 {
+		t.Errorf("Expected post 1, got %v", resp.Posts)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestListPosts_GivesUpAfterMaxRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+	client.MaxRetries = 2
+	_, err := client.ListPosts(context.Background(), nil, 1)
+	if err == nil || !strings.Contains(err.Error(), "APIリクエストエラー (page 1, status 502)") {
+		t.Errorf("Expected status 502 error, got %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 1 request and 2 retries, got %d requests", requests)
+	}
+}
+
+func TestListPosts_DoesNotRetryOn4xx(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	if _, err := newTestClient(server).ListPosts(context.Background(), nil, 1); err == nil {
+		t.Fatal("Expected an error, but got nil")
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestCreatePost_ResendsBodyOnRetry(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Post{ID: 42})
+	}))
+	defer server.Close()
+
+	title := "title"
+	post, err := newTestClient(server).CreatePost(context.Background(), PostRequest{Title: &title})
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if post.ID != 42 {
+		t.Errorf("Expected post ID 42, got %d", post.ID)
+	}
+	if len(bodies) != 2 || bodies[0] != `{"title":"title"}` || bodies[1] != bodies[0] {
+		t.Errorf("Expected the same body in both requests, got %q", bodies)
+	}
+}
+
+func TestCreateAndUpdatePost_DoNotRetryOnServerError(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout} {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(status)
+		}))
+
+		title := "title"
+		client := newTestClient(server)
+		if _, err := client.CreatePost(context.Background(), PostRequest{Title: &title}); err == nil {
+			t.Errorf("CreatePost with status %d: expected an error, but got nil", status)
+		}
+		if _, err := client.UpdatePost(context.Background(), 1, PostRequest{Title: &title}); err == nil {
+			t.Errorf("UpdatePost with status %d: expected an error, but got nil", status)
+		}
+		if requests != 2 {
+			t.Errorf("Expected 1 request each for CreatePost and UpdatePost with status %d, got %d requests", status, requests)
+		}
+		server.Close()
+	}
+}
+
+func TestListPosts_CancelWhileWaiting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := newTestClient(server).ListPosts(ctx, nil, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected to stop waiting on cancel, took %v", elapsed)
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	client := NewClient("testteam", "test_token")
+	client.RetryWaitMin, client.RetryWaitMax = time.Second, 5*time.Second
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		expected   time.Duration
+	}{
+		{name: "first backoff", attempt: 0, expected: time.Second},
+		{name: "exponential backoff", attempt: 2, expected: 4 * time.Second},
+		{name: "capped backoff", attempt: 10, expected: 5 * time.Second},
+		{name: "retry-after seconds", attempt: 0, retryAfter: "120", expected: 120 * time.Second},
+		{name: "retry-after date", attempt: 0, retryAfter: "Mon, 01 Jan 2024 00:00:30 GMT", expected: 30 * time.Second},
+		{name: "retry-after past date", attempt: 3, retryAfter: "Sun, 31 Dec 2023 23:59:00 GMT", expected: 0},
+		{name: "invalid retry-after", attempt: 1, retryAfter: "soon", expected: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.retryWait(tt.attempt, tt.retryAfter, now); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
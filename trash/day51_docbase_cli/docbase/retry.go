@@ -0,0 +1,82 @@
+package docbase
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// shouldRetry はレスポンスが再試行の対象かを返します。
+// 冪等なメソッドは 5xx / 429 Too Many Requests で再試行します。
+// POST や PATCH はサーバーが処理を終えた後の 5xx で再試行すると記事が重複して作成・更新されるため、
+// リクエストが処理されていないことが明らかな 429 と 503 Service Unavailable の場合だけ再試行します。
+func shouldRetry(method string, status int) bool {
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		return true
+	}
+	return isIdempotent(method) && status >= 500
+}
+
+// isIdempotent は同じリクエストを何度送っても結果が変わらないメソッドかを返します。
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryWait は attempt 回目 (0 始まり) の失敗後に待つ時間です。
+// Retry-After ヘッダーがあればそれに従い、なければ RetryWaitMin から2倍ずつ増やして RetryWaitMax で打ち止めにします。
+func (c *Client) retryWait(attempt int, retryAfter string, now time.Time) time.Duration {
+	if wait, ok := parseRetryAfter(retryAfter, now); ok {
+		return wait
+	}
+
+	wait := c.RetryWaitMin
+	for i := 0; i < attempt && wait < c.RetryWaitMax; i++ {
+		wait *= 2
+	}
+	if wait > c.RetryWaitMax {
+		wait = c.RetryWaitMax
+	}
+	return wait
+}
+
+// parseRetryAfter は Retry-After ヘッダー (秒数または HTTP-date) を待ち時間に変換します。
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := t.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true // 既に過ぎている場合はすぐに再試行する
+	}
+	return 0, false
+}
+
+// sleep は d だけ待ちます。待っている間に ctx がキャンセルされた場合はその時点でエラーを返します。
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+)
+
+// ExportConfig は export サブコマンドの設定です。
+type ExportConfig struct {
+	Config
+	Dir string
+}
+
+// ExportResult はエクスポートした記事数の内訳です。
+type ExportResult struct {
+	Created int // 新しく書き出した記事
+	Updated int // updated_at が新しくなったため書き直した記事
+	Skipped int // 前回のエクスポートから変更がない記事
+}
+
+// Attachment は記事本文から抽出した添付ファイル (画像・ファイル) の情報です。
+type Attachment struct {
+	Name string
+	URL  string
+}
+
+// attachmentLinkPattern は Markdown の画像・リンク記法 (![name](url) / [name](url)) にマッチします。
+var attachmentLinkPattern = regexp.MustCompile(`!?\[([^\]]*)\]\((https?://[^)\s]+)\)`)
+
+func runExport(ctx context.Context, args []string) error {
+	conf, err := parseExportArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client := newClient(&conf.Config)
+
+	fmt.Printf("%s チームの記事を %s にエクスポートします...\n", conf.TeamName, conf.Dir)
+	result, err := client.ExportPosts(ctx, conf.Dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("エクスポートが完了しました。新規: %d件 / 更新: %d件 / 変更なし: %d件\n", result.Created, result.Updated, result.Skipped)
+	return nil
+}
+
+func parseExportArgs(args []string) (*ExportConfig, error) {
+	conf := &ExportConfig{}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	addAuthFlags(fs, &conf.Config)
+	addClientFlags(fs, &conf.Config)
+	fs.StringVar(&conf.Dir, "dir", "docbase_export", "Output directory for exported Markdown files")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := validateAuth(&conf.Config); err != nil {
+		return nil, err
+	}
+	if err := validateClient(&conf.Config); err != nil {
+		return nil, err
+	}
+	if conf.Dir == "" {
+		return nil, fmt.Errorf("出力先ディレクトリが指定されていません")
+	}
+	return conf, nil
+}
+
+// ExportPosts は全記事を dir 以下に <作成年>/<作成月>/<記事ID>.md として書き出します。
+// 既に書き出した記事は、フロントマターの updated_at より新しく更新されている場合だけ書き直します。
+func (c *DocBaseClient) ExportPosts(ctx context.Context, dir string) (ExportResult, error) {
+	var result ExportResult
+
+	err := c.ForEachPostPage(ctx, nil, func(posts []docbase.Post) error {
+		for _, post := range posts {
+			path := exportPath(dir, post)
+
+			// フロントマターが読めないファイルは書き直す
+			exportedAt, err := readExportedUpdatedAt(path)
+			exists := !errors.Is(err, os.ErrNotExist)
+			if err == nil && !post.UpdatedAt.After(exportedAt) {
+				result.Skipped++
+				continue
+			}
+
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("ディレクトリの作成に失敗 (%s): %w", filepath.Dir(path), err)
+			}
+			if err := os.WriteFile(path, []byte(formatPostMarkdown(post)), 0644); err != nil {
+				return fmt.Errorf("記事ファイルの書き込みに失敗 (%s): %w", path, err)
+			}
+
+			if exists {
+				result.Updated++
+			} else {
+				result.Created++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// exportPath は記事の書き出し先のパスです。タイトルは変更され得るため、作成日時と記事IDから決めます。
+func exportPath(dir string, post docbase.Post) string {
+	return filepath.Join(dir, post.CreatedAt.Format("2006"), post.CreatedAt.Format("01"), fmt.Sprintf("%d.md", post.ID))
+}
+
+// formatPostMarkdown は記事をYAMLフロントマター付きのMarkdownに変換します。
+func formatPostMarkdown(post docbase.Post) string {
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Name)
+	}
+	groups := make([]string, 0, len(post.Groups))
+	for _, group := range post.Groups {
+		groups = append(groups, group.Name)
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %d\n", post.ID)
+	fmt.Fprintf(&b, "title: %s\n", yamlString(post.Title))
+	fmt.Fprintf(&b, "url: %s\n", yamlString(post.URL))
+	fmt.Fprintf(&b, "author: %s\n", yamlString(post.User.Name))
+	fmt.Fprintf(&b, "created_at: %s\n", post.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "updated_at: %s\n", post.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "draft: %t\n", post.Draft)
+	fmt.Fprintf(&b, "archived: %t\n", post.Archived)
+	fmt.Fprintf(&b, "scope: %s\n", yamlString(post.Scope))
+	fmt.Fprintf(&b, "tags: %s\n", yamlList(tags))
+	fmt.Fprintf(&b, "groups: %s\n", yamlList(groups))
+	if attachments := extractAttachments(post.Body); len(attachments) > 0 {
+		b.WriteString("attachments:\n")
+		for _, a := range attachments {
+			fmt.Fprintf(&b, "  - name: %s\n", yamlString(a.Name))
+			fmt.Fprintf(&b, "    url: %s\n", yamlString(a.URL))
+		}
+	} else {
+		b.WriteString("attachments: []\n")
+	}
+	b.WriteString("---\n\n")
+	b.WriteString(post.Body)
+	if !strings.HasSuffix(post.Body, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// yamlString は文字列をYAMLのダブルクォート文字列として書き出します (JSONの文字列はYAMLとしても有効)。
+func yamlString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// yamlList は文字列のスライスをYAMLのフローシーケンス (["a", "b"]) として書き出します。
+func yamlList(items []string) string {
+	quoted := make([]string, 0, len(items))
+	for _, item := range items {
+		quoted = append(quoted, yamlString(item))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// extractAttachments は本文中の DocBase にアップロードされた画像・ファイルへのリンクを抽出します。
+// 同じURLは1度だけ返します。
+func extractAttachments(body string) []Attachment {
+	var attachments []Attachment
+	seen := make(map[string]bool)
+	for _, m := range attachmentLinkPattern.FindAllStringSubmatch(body, -1) {
+		name, rawURL := m[1], m[2]
+		u, err := url.Parse(rawURL)
+		if err != nil || !strings.HasSuffix(u.Hostname(), "docbase.io") {
+			continue
+		}
+		if !strings.Contains(u.Path, "/uploads/") && !strings.Contains(u.Path, "/file_attachments/") {
+			continue
+		}
+		if seen[rawURL] {
+			continue
+		}
+		seen[rawURL] = true
+		if name == "" {
+			name = filepath.Base(u.Path)
+		}
+		attachments = append(attachments, Attachment{Name: name, URL: rawURL})
+	}
+	return attachments
+}
+
+// readExportedUpdatedAt は書き出し済みの記事ファイルのフロントマターから updated_at を読み取ります。
+// ファイルがない場合は os.ErrNotExist を返します。
+func readExportedUpdatedAt(path string) (time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || scanner.Text() != "---" {
+		return time.Time{}, fmt.Errorf("フロントマターが見つかりません (%s)", path)
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "---" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "updated_at: "); ok {
+			updatedAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("updated_at のパースに失敗 (%s): %w", path, err)
+			}
+			return updatedAt, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, fmt.Errorf("記事ファイルの読み込みに失敗 (%s): %w", path, err)
+	}
+	return time.Time{}, fmt.Errorf("フロントマターに updated_at がありません (%s)", path)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+)
+
+// createMockExportPost はエクスポートのテスト用に本文などを含むPostオブジェクトを作成します。
+func createMockExportPost(id int, title, body, createdAtStr, updatedAtStr string) docbase.Post {
+	createdAt, _ := time.Parse(time.RFC3339, createdAtStr)
+	updatedAt, _ := time.Parse(time.RFC3339, updatedAtStr)
+	return docbase.Post{
+		ID:        id,
+		Title:     title,
+		Body:      body,
+		URL:       fmt.Sprintf("https://testteam.docbase.io/posts/%d", id),
+		Scope:     "everyone",
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		Tags:      []docbase.Tag{{Name: "go"}, {Name: "cli"}},
+		User:      docbase.User{ID: 1, Name: "lirlia"},
+		Groups:    []docbase.Group{{ID: 10, Name: "dev"}},
+	}
+}
+
+// newExportTestClient は posts を1ページで返すモックサーバーに接続したクライアントを作成します。
+func newExportTestClient(t *testing.T, posts *[]docbase.Post) *DocBaseClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(docbase.PostResponse{Posts: *posts, Meta: docbase.PostMeta{NextPage: nil}})
+	}))
+	t.Cleanup(server.Close)
+	return newTestClient(server)
+}
+
+func TestExportPosts_WritesFrontMatteredMarkdown(t *testing.T) {
+	body := "本文です\n![screen.png](https://image.docbase.io/uploads/abc/screen.png)\n[外部リンク](https://example.com/a.png)"
+	posts := []docbase.Post{createMockExportPost(1, "First", body, "2024-01-10T10:00:00+09:00", "2024-01-11T10:00:00+09:00")}
+	client := newExportTestClient(t, &posts)
+	dir := t.TempDir()
+
+	result, err := client.ExportPosts(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("ExportPosts failed: %v", err)
+	}
+	if result.Created != 1 || result.Updated != 0 || result.Skipped != 0 {
+		t.Errorf("Expected 1 created post, got %+v", result)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "2024", "01", "1.md"))
+	if err != nil {
+		t.Fatalf("Expected exported file: %v", err)
+	}
+	for _, want := range []string{
+		"---\nid: 1\n",
+		`title: "First"`,
+		`author: "lirlia"`,
+		"updated_at: 2024-01-11T10:00:00+09:00",
+		`tags: ["go", "cli"]`,
+		`groups: ["dev"]`,
+		"attachments:\n  - name: \"screen.png\"\n    url: \"https://image.docbase.io/uploads/abc/screen.png\"\n---\n\n本文です\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected exported file to contain %q, got:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), `url: "https://example.com/a.png"`) {
+		t.Errorf("Expected external links not to be listed as attachments, got:\n%s", content)
+	}
+}
+
+func TestExportPosts_Incremental(t *testing.T) {
+	posts := []docbase.Post{
+		createMockExportPost(1, "First", "v1", "2024-01-10T10:00:00+09:00", "2024-01-11T10:00:00+09:00"),
+		createMockExportPost(2, "Second", "v1", "2024-02-10T10:00:00+09:00", "2024-02-11T10:00:00+09:00"),
+	}
+	client := newExportTestClient(t, &posts)
+	dir := t.TempDir()
+
+	if _, err := client.ExportPosts(context.Background(), dir); err != nil {
+		t.Fatalf("First ExportPosts failed: %v", err)
+	}
+
+	// 2件目だけを更新して再エクスポート
+	posts[1].Body = "v2"
+	posts[1].UpdatedAt = posts[1].UpdatedAt.Add(time.Hour)
+	result, err := client.ExportPosts(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Second ExportPosts failed: %v", err)
+	}
+	if result.Created != 0 || result.Updated != 1 || result.Skipped != 1 {
+		t.Errorf("Expected 1 updated and 1 skipped post, got %+v", result)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "2024", "02", "2.md"))
+	if err != nil {
+		t.Fatalf("Expected exported file: %v", err)
+	}
+	if !strings.HasSuffix(string(content), "\n\nv2\n") {
+		t.Errorf("Expected updated body, got:\n%s", content)
+	}
+}
+
+func TestParseExportArgs_MissingToken(t *testing.T) {
+	t.Setenv("DOCBASE_TOKEN", "")
+	_, err := parseExportArgs([]string{"-team", "myteam", "-dir", "out"})
+	if err == nil {
+		t.Fatal("Expected an error for missing token, but got nil")
+	}
+	if !strings.Contains(err.Error(), "APIトークンが指定されていません") {
+		t.Errorf("Expected error message for missing token, got '%s'", err.Error())
+	}
+}
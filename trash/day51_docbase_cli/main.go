@@ -1,25 +1,22 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"time"
-)
 
-var apiEndpointFormat = "https://api.docbase.io/teams/%s/posts" // ユーザー提示の仕様に合わせる
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/output"
+)
 
 const (
 	defaultStartYear  = 2024
 	defaultStartMonth = 1
 	defaultEndYear    = 2025
 	defaultEndMonth   = 12
-	maxPerPage        = 100 // DocBase APIのper_page最大値
 )
 
 type Config struct {
@@ -29,33 +26,32 @@ type Config struct {
 	StartMonth int
 	EndYear    int
 	EndMonth   int
+	Format     output.Format
+	Timeout    time.Duration // 1リクエストあたりのタイムアウト
+	Retries    int           // 5xx / 429 の場合の最大再試行回数
 }
 
-// DocBase APIのレスポンス構造体
-type Post struct {
-	ID        int       `json:"id"`
-	CreatedAt time.Time `json:"created_at"` // time.Timeとして直接パース
-}
-
-type PostMeta struct {
-	Total      int     `json:"total"`
-	NextPage   *string `json:"next_page"` // nullの場合があるのでポインタ型
-	PrevPage   *string `json:"previous_page"`
-}
-
-type PostResponse struct {
-	Posts []Post   `json:"posts"`
-	Meta  PostMeta `json:"meta"`
-}
-
+// DocBaseClient は docbase.Client に、記事数の集計やエクスポートなど CLI の処理を加えたものです。
 type DocBaseClient struct {
-	TeamName      string
-	Token         string
-	Client        *http.Client
-	SleepDuration time.Duration
+	*docbase.Client
 }
 
 func main() {
+	// Ctrl-C で実行中のリクエストや再試行の待機をキャンセルする
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// サブコマンドが指定された場合はそちらを実行する (指定がない場合は従来どおり月別記事数を表示)
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(ctx, os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s に失敗しました: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	config, err := parseArgs()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "引数のパースに失敗しました: %v\n", err)
@@ -63,11 +59,12 @@ func main() {
 		os.Exit(1)
 	}
 
-	client := NewDocBaseClient(config.TeamName, config.Token)
+	client := newClient(config)
 
-	fmt.Printf("%s チームの %d年%d月から%d年%d月までの記事数を取得します...\n", config.TeamName, config.StartYear, config.StartMonth, config.EndYear, config.EndMonth)
+	// JSON / CSV をパイプで渡せるよう、進捗のメッセージは標準エラー出力に出す
+	fmt.Fprintf(os.Stderr, "%s チームの %d年%d月から%d年%d月までの記事数を取得します...\n", config.TeamName, config.StartYear, config.StartMonth, config.EndYear, config.EndMonth)
 
-	monthlyCounts, err := client.GetMonthlyPostCountsViaPagination(config.StartYear, config.StartMonth, config.EndYear, config.EndMonth)
+	monthlyCounts, err := client.GetMonthlyPostCountsViaPagination(ctx, config.StartYear, config.StartMonth, config.EndYear, config.EndMonth)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "記事数の取得に失敗しました: %v\n", err)
 		os.Exit(1)
@@ -77,7 +74,7 @@ func main() {
 	startDate := time.Date(config.StartYear, time.Month(config.StartMonth), 1, 0, 0, 0, 0, time.UTC)
 	endDate := time.Date(config.EndYear, time.Month(config.EndMonth), 1, 0, 0, 0, 0, time.UTC)
 
-	fmt.Println("月別記事数:")
+	table := output.Table{Title: "月別記事数:", Columns: []string{"month", "count"}}
 	current := startDate
 	for !current.After(endDate) {
 		monthKey := current.Format("2006-01")
@@ -85,24 +82,113 @@ func main() {
 		if c, ok := monthlyCounts[monthKey]; ok {
 			count = c
 		}
-		fmt.Printf("%s: %d記事\n", monthKey, count)
+		table.Rows = append(table.Rows, []any{monthKey, count})
 		current = current.AddDate(0, 1, 0)
 	}
 
-	fmt.Println("記事数の集計が完了しました。")
+	if err := output.Write(os.Stdout, config.Format, table); err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "記事数の集計が完了しました。")
+}
+
+// subcommands はサブコマンド名と、その引数 (サブコマンド名より後ろ) を受け取る実行関数です。
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"export": runExport,
+	"report": runReport,
+	"post":   runPost,
+	"search": runSearch,
+}
+
+// addAuthFlags はチーム名とAPIトークンのフラグを登録します。
+func addAuthFlags(fs *flag.FlagSet, conf *Config) {
+	fs.StringVar(&conf.TeamName, "team", os.Getenv("DOCBASE_TEAM"), "DocBase team name (or DOCBASE_TEAM env var)")
+	fs.StringVar(&conf.Token, "token", os.Getenv("DOCBASE_TOKEN"), "DocBase API token (or DOCBASE_TOKEN env var)")
+}
+
+// validateAuth はチーム名とAPIトークンが指定されているかを確認します。
+func validateAuth(conf *Config) error {
+	if conf.TeamName == "" {
+		return fmt.Errorf("チーム名が指定されていません。-team オプションまたは DOCBASE_TEAM 環境変数を設定してください")
+	}
+	if conf.Token == "" {
+		return fmt.Errorf("APIトークンが指定されていません。-token オプションまたは DOCBASE_TOKEN 環境変数を設定してください")
+	}
+	return nil
+}
+
+// addClientFlags はAPIリクエストのタイムアウトと再試行回数のフラグを登録します。
+func addClientFlags(fs *flag.FlagSet, conf *Config) {
+	fs.DurationVar(&conf.Timeout, "timeout", docbase.DefaultTimeout, "Timeout for each API request")
+	fs.IntVar(&conf.Retries, "retries", docbase.DefaultMaxRetries, "Maximum number of retries on 5xx / 429 responses")
+}
+
+// validateClient はタイムアウトと再試行回数が正しいかを確認します。
+func validateClient(conf *Config) error {
+	if conf.Timeout <= 0 {
+		return fmt.Errorf("-timeout は0より大きい値で指定してください")
+	}
+	if conf.Retries < 0 {
+		return fmt.Errorf("-retries は0以上で指定してください")
+	}
+	return nil
+}
+
+// addPeriodFlags は集計期間 (開始年月・終了年月) のフラグを登録します。
+func addPeriodFlags(fs *flag.FlagSet, conf *Config) {
+	fs.IntVar(&conf.StartYear, "start-year", defaultStartYear, "Start year for fetching posts")
+	fs.IntVar(&conf.StartMonth, "start-month", defaultStartMonth, "Start month for fetching posts (1-12)")
+	fs.IntVar(&conf.EndYear, "end-year", defaultEndYear, "End year for fetching posts")
+	fs.IntVar(&conf.EndMonth, "end-month", defaultEndMonth, "End month for fetching posts (1-12)")
+}
+
+// addFormatFlag は出力形式 (table / json / csv) のフラグを登録します。
+func addFormatFlag(fs *flag.FlagSet, conf *Config) {
+	conf.Format = output.FormatTable
+	fs.Func("format", "Output `format`: table, json or csv (default table)", func(s string) error {
+		format, err := output.ParseFormat(s)
+		if err != nil {
+			return err
+		}
+		conf.Format = format
+		return nil
+	})
+}
+
+// validatePeriod は集計期間が正しいかを確認します。
+func validatePeriod(conf *Config) error {
+	if conf.StartMonth < 1 || conf.StartMonth > 12 || conf.EndMonth < 1 || conf.EndMonth > 12 {
+		return fmt.Errorf("月は1から12の間で指定してください")
+	}
+	if conf.StartYear > conf.EndYear || (conf.StartYear == conf.EndYear && conf.StartMonth > conf.EndMonth) {
+		return fmt.Errorf("開始年月が終了年月より後になっています")
+	}
+	return nil
+}
+
+// periodRange は開始年月の初日から終了年月の翌月初日までの範囲 [from, to) を返します。
+func periodRange(startYear, startMonth, endYear, endMonth int) (from, to time.Time) {
+	from = time.Date(startYear, time.Month(startMonth), 1, 0, 0, 0, 0, time.UTC)
+	// endMonthの最終日までを範囲に含めるため、翌月の初日未満とする
+	to = time.Date(endYear, time.Month(endMonth), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	return from, to
 }
 
 func parseArgs() (*Config, error) {
 	conf := &Config{}
-	flag.StringVar(&conf.TeamName, "team", os.Getenv("DOCBASE_TEAM"), "DocBase team name (or DOCBASE_TEAM env var)")
-	flag.StringVar(&conf.Token, "token", os.Getenv("DOCBASE_TOKEN"), "DocBase API token (or DOCBASE_TOKEN env var)")
-	flag.IntVar(&conf.StartYear, "start-year", defaultStartYear, "Start year for fetching posts")
-	flag.IntVar(&conf.StartMonth, "start-month", defaultStartMonth, "Start month for fetching posts (1-12)")
-	flag.IntVar(&conf.EndYear, "end-year", defaultEndYear, "End year for fetching posts")
-	flag.IntVar(&conf.EndMonth, "end-month", defaultEndMonth, "End month for fetching posts (1-12)")
+	addAuthFlags(flag.CommandLine, conf)
+	addClientFlags(flag.CommandLine, conf)
+	addPeriodFlags(flag.CommandLine, conf)
+	addFormatFlag(flag.CommandLine, conf)
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "使用法: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s export [options]  記事をMarkdownファイルとしてエクスポート\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s report -by tag|author|group [options]  タグ・作成者・グループ別の記事数\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s post create -title <title> -body-file <file> [options]  記事を作成\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s post update <id> [options]  記事を更新\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s search <query> [-tag <tag>] [-author <name>] [-since YYYY-MM-DD] [-until YYYY-MM-DD]  記事を検索\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "オプション:")
 		flag.PrintDefaults()
 		fmt.Fprintln(os.Stderr, "\n環境変数:")
@@ -111,112 +197,49 @@ func parseArgs() (*Config, error) {
 	}
 	flag.Parse()
 
-	if conf.TeamName == "" {
-		return nil, fmt.Errorf("チーム名が指定されていません。-team オプションまたは DOCBASE_TEAM 環境変数を設定してください")
+	if err := validateAuth(conf); err != nil {
+		return nil, err
 	}
-	if conf.Token == "" {
-		return nil, fmt.Errorf("APIトークンが指定されていません。-token オプションまたは DOCBASE_TOKEN 環境変数を設定してください")
+	if err := validateClient(conf); err != nil {
+		return nil, err
 	}
-	if conf.StartMonth < 1 || conf.StartMonth > 12 || conf.EndMonth < 1 || conf.EndMonth > 12 {
-		return nil, fmt.Errorf("月は1から12の間で指定してください")
-	}
-	if conf.StartYear > conf.EndYear || (conf.StartYear == conf.EndYear && conf.StartMonth > conf.EndMonth) {
-		return nil, fmt.Errorf("開始年月が終了年月より後になっています")
+	if err := validatePeriod(conf); err != nil {
+		return nil, err
 	}
 	return conf, nil
 }
 
 func NewDocBaseClient(teamName, token string) *DocBaseClient {
-	return &DocBaseClient{
-		TeamName:      teamName,
-		Token:         token,
-		Client:        &http.Client{Timeout: 20 * time.Second},
-		SleepDuration: 13 * time.Second,
-	}
+	return &DocBaseClient{Client: docbase.NewClient(teamName, token)}
+}
+
+// newClient は設定のタイムアウトと再試行回数を反映したクライアントを作成します。
+func newClient(conf *Config) *DocBaseClient {
+	client := NewDocBaseClient(conf.TeamName, conf.Token)
+	client.HTTPClient.Timeout = conf.Timeout
+	client.MaxRetries = conf.Retries
+	return client
 }
 
 // GetMonthlyPostCountsViaPagination はページネーションを使って全記事を取得し、月別に集計します。
-func (c *DocBaseClient) GetMonthlyPostCountsViaPagination(startYear, startMonth, endYear, endMonth int) (map[string]int, error) {
+func (c *DocBaseClient) GetMonthlyPostCountsViaPagination(ctx context.Context, startYear, startMonth, endYear, endMonth int) (map[string]int, error) {
 	monthlyCounts := make(map[string]int)
-	currentPage := 1
-	requestCount := 0 // APIリクエスト回数のカウント（デバッグ用）
 
 	// 集計対象の期間を設定
-	filterStartDate := time.Date(startYear, time.Month(startMonth), 1, 0, 0, 0, 0, time.UTC)
-	// endMonthの最終日までを範囲に含めるため、翌月の初日未満とする
-	filterEndDate := time.Date(endYear, time.Month(endMonth), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
-
-
-	for {
-		requestCount++
-		// fmt.Printf("DEBUG: Requesting page %d...\n", currentPage) // デバッグ用
-
-		endpoint := fmt.Sprintf(apiEndpointFormat, c.TeamName)
-		u, err := url.Parse(endpoint)
-		if err != nil {
-			return nil, fmt.Errorf("APIエンドポイントURLのパースに失敗: %w", err)
-		}
-
-		q := u.Query()
-		q.Set("per_page", fmt.Sprintf("%d", maxPerPage))
-		q.Set("page", fmt.Sprintf("%d", currentPage))
-		// q.Set("q", "created:>=2024-01-01 created:<=2024-01-31") // もし期間指定可能なら検討
-		u.RawQuery = q.Encode()
+	filterStartDate, filterEndDate := periodRange(startYear, startMonth, endYear, endMonth)
 
-		req, err := http.NewRequest("GET", u.String(), nil)
-		if err != nil {
-			return nil, fmt.Errorf("リクエストの作成に失敗: %w", err)
-		}
-		req.Header.Set("X-DocBaseToken", c.Token)
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := c.Client.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("APIリクエストに失敗 (page %d): %w", currentPage, err)
-		}
-
-		// レスポンスボディをデバッグ出力 (必要な場合のみ有効化)
-		bodyBytesForDebug, _ := io.ReadAll(resp.Body)
-		// fmt.Printf("DEBUG: Response Body (Page %d): %s\n", currentPage, string(bodyBytesForDebug))
-		resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytesForDebug))
-
-
-		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			resp.Body.Close() // ここで明示的に閉じる
-			return nil, fmt.Errorf("APIリクエストエラー (page %d, status %d): %s", currentPage, resp.StatusCode, string(bodyBytes))
-		}
-
-		var postResponse PostResponse
-		if err := json.NewDecoder(resp.Body).Decode(&postResponse); err != nil {
-			resp.Body.Close() // デコードエラー時も閉じる
-			return nil, fmt.Errorf("レスポンスJSONのデコードに失敗 (page %d): %w", currentPage, err)
-		}
-		resp.Body.Close() // 正常時もここで閉じる
-
-		if len(postResponse.Posts) == 0 {
-			// fmt.Println("DEBUG: No more posts found.") // デバッグ用
-			break // 記事がもうない場合は終了
-		}
-
-		for _, post := range postResponse.Posts {
+	err := c.ForEachPostPage(ctx, nil, func(posts []docbase.Post) error {
+		for _, post := range posts {
 			// 記事の作成日時が指定された期間内かチェック
 			if (post.CreatedAt.Equal(filterStartDate) || post.CreatedAt.After(filterStartDate)) && post.CreatedAt.Before(filterEndDate) {
 				monthKey := post.CreatedAt.Format("2006-01")
 				monthlyCounts[monthKey]++
 			}
 		}
-
-		if postResponse.Meta.NextPage == nil {
-			// fmt.Println("DEBUG: No next page.") // デバッグ用
-			break // 次のページがない場合は終了
-		}
-
-		currentPage++
-		// APIレートリミットを考慮 (1分間に5回 -> 1リクエストあたり12秒。マージン含め13秒)
-		time.Sleep(c.SleepDuration)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	// fmt.Printf("DEBUG: Total API requests: %d\n", requestCount) // デバッグ用
 	return monthlyCounts, nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -10,31 +11,44 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/output"
 )
 
+// newTestClient は server に接続したクライアントを作成します。ページ取得の間隔と再試行の待ち時間は0にします。
+func newTestClient(server *httptest.Server) *DocBaseClient {
+	client := NewDocBaseClient("testteam", "test_token")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	client.SleepDuration = 0 // テスト時はスリープしない
+	client.RetryWaitMin, client.RetryWaitMax = 0, 0
+	return client
+}
+
 // createMockPost はテスト用のPostオブジェクトを作成します。
-func createMockPost(id int, createdAtStr string) Post {
+func createMockPost(id int, createdAtStr string) docbase.Post {
 	t, _ := time.Parse(time.RFC3339, createdAtStr)
-	return Post{ID: id, CreatedAt: t}
+	return docbase.Post{ID: id, CreatedAt: t}
 }
 
 func TestGetMonthlyPostCountsViaPagination_Success(t *testing.T) {
 	// モックサーバーのハンドラ
-	mockResponses := []PostResponse{
+	mockResponses := []docbase.PostResponse{
 		{ // Page 1
-			Posts: []Post{
+			Posts: []docbase.Post{
 				createMockPost(1, "2024-01-10T10:00:00+09:00"),
 				createMockPost(2, "2024-01-15T10:00:00+09:00"),
 				createMockPost(3, "2024-02-05T10:00:00+09:00"),
 			},
-			Meta: PostMeta{Total: 5, NextPage: func() *string { s := "dummy_next_page_url_for_page_2"; return &s }()},
+			Meta: docbase.PostMeta{Total: 5, NextPage: func() *string { s := "dummy_next_page_url_for_page_2"; return &s }()},
 		},
 		{ // Page 2
-			Posts: []Post{
+			Posts: []docbase.Post{
 				createMockPost(4, "2024-02-20T10:00:00+09:00"),
 				createMockPost(5, "2023-12-01T10:00:00+09:00"), // 期間外
 			},
-			Meta: PostMeta{Total: 5, NextPage: nil}, // Last page
+			Meta: docbase.PostMeta{Total: 5, NextPage: nil}, // Last page
 		},
 	}
 	pageCounter := 0
@@ -57,20 +71,14 @@ func TestGetMonthlyPostCountsViaPagination_Success(t *testing.T) {
 			json.NewEncoder(w).Encode(mockResponses[pageCounter])
 			pageCounter++
 		} else {
-			json.NewEncoder(w).Encode(PostResponse{Posts: []Post{}, Meta: PostMeta{NextPage: nil}})
+			json.NewEncoder(w).Encode(docbase.PostResponse{Posts: []docbase.Post{}, Meta: docbase.PostMeta{NextPage: nil}})
 		}
 	}))
 	defer server.Close()
 
-	originalApiEndpointFormat := apiEndpointFormat
-	apiEndpointFormat = server.URL + "/teams/%s/posts"
-	defer func() { apiEndpointFormat = originalApiEndpointFormat }()
+	client := newTestClient(server)
 
-	client := NewDocBaseClient("testteam", "test_token")
-	client.Client = server.Client()
-	client.SleepDuration = 0 // テスト時はスリープしない
-
-	counts, err := client.GetMonthlyPostCountsViaPagination(2024, 1, 2024, 2)
+	counts, err := client.GetMonthlyPostCountsViaPagination(context.Background(), 2024, 1, 2024, 2)
 	if err != nil {
 		t.Fatalf("GetMonthlyPostCountsViaPagination failed: %v", err)
 	}
@@ -100,15 +108,9 @@ func TestGetMonthlyPostCountsViaPagination_ApiError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	originalApiEndpointFormat := apiEndpointFormat
-	apiEndpointFormat = server.URL + "/teams/%s/posts"
-	defer func() { apiEndpointFormat = originalApiEndpointFormat }()
+	client := newTestClient(server)
 
-	client := NewDocBaseClient("testteam", "test_token")
-	client.Client = server.Client()
-	client.SleepDuration = 0 // テスト時はスリープしない
-
-	_, err := client.GetMonthlyPostCountsViaPagination(2024, 1, 2024, 1)
+	_, err := client.GetMonthlyPostCountsViaPagination(context.Background(), 2024, 1, 2024, 1)
 	if err == nil {
 		t.Fatal("Expected an error, but got nil")
 	}
@@ -125,15 +127,9 @@ func TestGetMonthlyPostCountsViaPagination_JsonDecodeError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	originalApiEndpointFormat := apiEndpointFormat
-	apiEndpointFormat = server.URL + "/teams/%s/posts"
-	defer func() { apiEndpointFormat = originalApiEndpointFormat }()
+	client := newTestClient(server)
 
-	client := NewDocBaseClient("testteam", "test_token")
-	client.Client = server.Client()
-	client.SleepDuration = 0 // テスト時はスリープしない
-
-	_, err := client.GetMonthlyPostCountsViaPagination(2024, 1, 2024, 1)
+	_, err := client.GetMonthlyPostCountsViaPagination(context.Background(), 2024, 1, 2024, 1)
 	if err == nil {
 		t.Fatal("Expected an error, but got nil")
 	}
@@ -230,3 +226,47 @@ func TestParseArgs_StartDateAfterEndDate(t *testing.T) {
         t.Errorf("Expected error message for start date after end date, got '%s'", err.Error())
     }
 }
+
+func TestParseArgs_Format(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+
+	os.Args = []string{"cmd", "-team", "t", "-token", "t", "-format", "csv"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	config, err := parseArgs()
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if config.Format != output.FormatCSV {
+		t.Errorf("Expected format 'csv', got '%s'", config.Format)
+	}
+
+	os.Args = []string{"cmd", "-team", "t", "-token", "t"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	config, err = parseArgs()
+	if err != nil {
+		t.Fatalf("parseArgs failed: %v", err)
+	}
+	if config.Format != output.FormatTable {
+		t.Errorf("Expected default format 'table', got '%s'", config.Format)
+	}
+}
+
+func TestParseArgs_InvalidClientFlags(t *testing.T) {
+	originalArgs := os.Args
+	defer func() { os.Args = originalArgs }()
+
+	os.Args = []string{"cmd", "-team", "t", "-token", "t", "-retries", "-1"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	_, err := parseArgs()
+	if err == nil || !strings.Contains(err.Error(), "-retries は0以上で指定してください") {
+		t.Errorf("Expected error message for invalid -retries, got %v", err)
+	}
+
+	os.Args = []string{"cmd", "-team", "t", "-token", "t", "-timeout", "0s"}
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	_, err = parseArgs()
+	if err == nil || !strings.Contains(err.Error(), "-timeout は0より大きい値で指定してください") {
+		t.Errorf("Expected error message for invalid -timeout, got %v", err)
+	}
+}
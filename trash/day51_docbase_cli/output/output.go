@@ -0,0 +1,116 @@
+// Package output は集計結果を表・JSON・CSV のいずれかの形式で書き出します。
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format は出力形式です。
+type Format string
+
+const (
+	FormatTable Format = "table" // 人が読むための表 (デフォルト)
+	FormatJSON  Format = "json"  // 行ごとに列名をキーとしたオブジェクトの配列
+	FormatCSV   Format = "csv"   // 列名をヘッダー行とした CSV
+)
+
+// Formats は指定可能な出力形式の一覧です。
+var Formats = []Format{FormatTable, FormatJSON, FormatCSV}
+
+// ParseFormat は文字列を出力形式に変換します (大文字・小文字は区別しません)。
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("出力形式は table, json, csv のいずれかを指定してください: %s", s)
+}
+
+// Table は書き出す表形式のデータです。
+type Table struct {
+	Title   string   // table 形式のときだけ先頭に表示する見出し (空の場合は表示しない)
+	Columns []string // 列名 (table・CSV のヘッダー、JSON のキー)
+	Rows    [][]any  // 各行の値 (Columns と同じ順序)
+}
+
+// Write は t を format の形式で w に書き出します。
+func Write(w io.Writer, format Format, t Table) error {
+	switch format {
+	case FormatTable, "":
+		return writeTable(w, t)
+	case FormatJSON:
+		return writeJSON(w, t)
+	case FormatCSV:
+		return writeCSV(w, t)
+	default:
+		return fmt.Errorf("未対応の出力形式です: %s", format)
+	}
+}
+
+func writeTable(w io.Writer, t Table) error {
+	if t.Title != "" {
+		if _, err := fmt.Fprintln(w, t.Title); err != nil {
+			return err
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Columns, "\t"))
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(formatRow(row), "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, t Table) error {
+	// 行がない場合も null ではなく [] を出力する
+	objects := make([]map[string]any, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		object := make(map[string]any, len(t.Columns))
+		for i, column := range t.Columns {
+			if i < len(row) {
+				object[column] = row[i]
+			}
+		}
+		objects = append(objects, object)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(objects); err != nil {
+		return fmt.Errorf("JSONの書き出しに失敗: %w", err)
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, t Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return fmt.Errorf("CSVの書き出しに失敗: %w", err)
+	}
+	for _, row := range t.Rows {
+		if err := cw.Write(formatRow(row)); err != nil {
+			return fmt.Errorf("CSVの書き出しに失敗: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("CSVの書き出しに失敗: %w", err)
+	}
+	return nil
+}
+
+// formatRow は行の値を文字列に変換します。
+func formatRow(row []any) []string {
+	values := make([]string, len(row))
+	for i, v := range row {
+		values[i] = fmt.Sprint(v)
+	}
+	return values
+}
@@ -0,0 +1,54 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	table := Table{
+		Title:   "月別記事数:",
+		Columns: []string{"month", "count"},
+		Rows:    [][]any{{"2024-01", 2}, {"2024-02", 10}},
+	}
+
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{format: FormatTable, expected: "月別記事数:\nmonth    count\n2024-01  2\n2024-02  10\n"},
+		{format: FormatCSV, expected: "month,count\n2024-01,2\n2024-02,10\n"},
+		{format: FormatJSON, expected: "[\n  {\n    \"count\": 2,\n    \"month\": \"2024-01\"\n  },\n  {\n    \"count\": 10,\n    \"month\": \"2024-02\"\n  }\n]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, table); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWrite_EmptyJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, Table{Columns: []string{"tag", "count"}}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("Expected empty JSON array, got %q", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("JSON"); err != nil || f != FormatJSON {
+		t.Errorf("Expected FormatJSON, got %q (%v)", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("Expected an error for unsupported format, but got nil")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/output"
+)
+
+// 記事の公開範囲 (DocBase API の scope)
+var postScopes = []string{"everyone", "group", "private"}
+
+// PostConfig は post create / post update サブコマンドの設定です。
+type PostConfig struct {
+	Config
+	ID      int // post update の対象の記事ID
+	Request docbase.PostRequest
+}
+
+func runPost(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("post create または post update <id> を指定してください")
+	}
+
+	switch args[0] {
+	case "create":
+		conf, err := parsePostArgs("create", args[1:])
+		if err != nil {
+			return err
+		}
+		client := newClient(&conf.Config)
+		post, err := client.CreatePost(ctx, conf.Request)
+		if err != nil {
+			return err
+		}
+		return writePost(conf.Format, "記事を作成しました:", post)
+	case "update":
+		conf, err := parsePostArgs("update", args[1:])
+		if err != nil {
+			return err
+		}
+		client := newClient(&conf.Config)
+		post, err := client.UpdatePost(ctx, conf.ID, conf.Request)
+		if err != nil {
+			return err
+		}
+		return writePost(conf.Format, "記事を更新しました:", post)
+	default:
+		return fmt.Errorf("不明なサブコマンドです: post %s (create または update を指定してください)", args[0])
+	}
+}
+
+// parsePostArgs は post create / post update の引数をパースします。
+// update では記事IDを最初の引数 (post update 123 -title ...) またはフラグの後ろに指定でき、指定したフラグの項目だけを送信します。
+func parsePostArgs(action string, args []string) (*PostConfig, error) {
+	conf := &PostConfig{}
+	var title, bodyFile, tags, scope, groups string
+	var draft, notice bool
+
+	fs := flag.NewFlagSet("post "+action, flag.ContinueOnError)
+	addAuthFlags(fs, &conf.Config)
+	addClientFlags(fs, &conf.Config)
+	addFormatFlag(fs, &conf.Config)
+	fs.StringVar(&title, "title", "", "Post title")
+	fs.StringVar(&bodyFile, "body-file", "", "Markdown file for the post body (- for stdin)")
+	fs.StringVar(&tags, "tags", "", "Comma-separated tags")
+	fs.BoolVar(&draft, "draft", false, "Save the post as a draft")
+	fs.StringVar(&scope, "scope", "everyone", "Post scope: everyone, group or private")
+	fs.StringVar(&groups, "groups", "", "Comma-separated group IDs (required when -scope group)")
+	fs.BoolVar(&notice, "notice", true, "Notify team members of the post")
+
+	// update の記事IDはフラグより前に書けるようにする (flag パッケージは最初の非フラグ引数で解析を止めるため)
+	var idArg string
+	if action == "update" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		idArg, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if action == "update" && idArg == "" {
+		idArg = fs.Arg(0)
+	}
+
+	if err := validateAuth(&conf.Config); err != nil {
+		return nil, err
+	}
+	if err := validateClient(&conf.Config); err != nil {
+		return nil, err
+	}
+
+	// create では全項目を送信し、update では明示的に指定されたフラグだけを送信する
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if action == "create" {
+		if title == "" {
+			return nil, fmt.Errorf("タイトルが指定されていません。-title オプションを指定してください")
+		}
+		if bodyFile == "" {
+			return nil, fmt.Errorf("本文のファイルが指定されていません。-body-file オプションを指定してください")
+		}
+		for _, name := range []string{"title", "body-file", "tags", "draft", "scope", "notice"} {
+			set[name] = true
+		}
+	} else {
+		id, err := strconv.Atoi(idArg)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("更新する記事IDを指定してください: post update <id> [options]")
+		}
+		conf.ID = id
+	}
+
+	req := &conf.Request
+	if set["title"] {
+		req.Title = &title
+	}
+	if set["body-file"] {
+		body, err := readBodyFile(bodyFile)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = &body
+	}
+	if set["tags"] {
+		tagList := splitList(tags)
+		req.Tags = &tagList
+	}
+	if set["draft"] {
+		req.Draft = &draft
+	}
+	if set["notice"] {
+		req.Notice = &notice
+	}
+	if set["scope"] {
+		if !containsString(postScopes, scope) {
+			return nil, fmt.Errorf("-scope には everyone, group, private のいずれかを指定してください: %s", scope)
+		}
+		req.Scope = &scope
+	}
+	if set["groups"] {
+		groupIDs, err := parseGroupIDs(groups)
+		if err != nil {
+			return nil, err
+		}
+		req.Groups = &groupIDs
+	}
+	if set["scope"] && scope == "group" && (req.Groups == nil || len(*req.Groups) == 0) {
+		return nil, fmt.Errorf("-scope group の場合は -groups でグループIDを指定してください")
+	}
+	if action == "update" && *req == (docbase.PostRequest{}) {
+		return nil, fmt.Errorf("更新する項目を -title, -body-file などのオプションで指定してください")
+	}
+	return conf, nil
+}
+
+// readBodyFile は記事本文を読み込みます。path が "-" の場合は標準入力から読み込みます。
+func readBodyFile(path string) (string, error) {
+	var body []byte
+	var err error
+	if path == "-" {
+		body, err = io.ReadAll(os.Stdin)
+	} else {
+		body, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("本文の読み込みに失敗 (%s): %w", path, err)
+	}
+	return string(body), nil
+}
+
+// splitList はカンマ区切りの文字列を分割し、前後の空白と空の要素を取り除きます。
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseGroupIDs(s string) ([]int, error) {
+	ids := []int{}
+	for _, item := range splitList(s) {
+		id, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("グループIDは数値で指定してください: %s", item)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// writePost は作成・更新した記事の ID・タイトル・URL などを出力します。
+func writePost(format output.Format, title string, post *docbase.Post) error {
+	table := output.Table{
+		Title:   title,
+		Columns: []string{"id", "title", "url", "draft", "scope"},
+		Rows:    [][]any{{post.ID, post.Title, post.URL, post.Draft, post.Scope}},
+	}
+	return output.Write(os.Stdout, format, table)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+)
+
+// newPostTestServer はリクエストのメソッド・パス・JSONボディを記録し、status と記事を返すモックサーバーを起動します。
+func newPostTestServer(t *testing.T, status int, got *map[string]any, gotMethod, gotPath *string) *DocBaseClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-DocBaseToken") != "test_token" {
+			t.Errorf("Expected token 'test_token', got '%s'", r.Header.Get("X-DocBaseToken"))
+		}
+		*gotMethod, *gotPath = r.Method, r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, got); err != nil {
+			t.Errorf("Request body is not JSON: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(docbase.Post{ID: 42, Title: "title", URL: "https://testteam.docbase.io/posts/42", Scope: "everyone"})
+	}))
+	t.Cleanup(server.Close)
+	return newTestClient(server)
+}
+
+func TestCreatePost(t *testing.T) {
+	var got map[string]any
+	var method, path string
+	client := newPostTestServer(t, http.StatusCreated, &got, &method, &path)
+
+	bodyFile := filepath.Join(t.TempDir(), "body.md")
+	os.WriteFile(bodyFile, []byte("# 本文"), 0644)
+	conf, err := parsePostArgs("create", []string{"-team", "testteam", "-token", "test_token", "-title", "title", "-body-file", bodyFile, "-tags", "go, cli", "-draft"})
+	if err != nil {
+		t.Fatalf("parsePostArgs failed: %v", err)
+	}
+
+	post, err := client.CreatePost(context.Background(), conf.Request)
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	if post.ID != 42 {
+		t.Errorf("Expected post ID 42, got %d", post.ID)
+	}
+	if method != "POST" || path != "/teams/testteam/posts" {
+		t.Errorf("Expected POST /teams/testteam/posts, got %s %s", method, path)
+	}
+	expected := map[string]any{
+		"title":  "title",
+		"body":   "# 本文",
+		"draft":  true,
+		"notice": true,
+		"tags":   []any{"go", "cli"},
+		"scope":  "everyone",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected request body %v, got %v", expected, got)
+	}
+}
+
+func TestUpdatePost_SendsOnlySpecifiedFields(t *testing.T) {
+	var got map[string]any
+	var method, path string
+	client := newPostTestServer(t, http.StatusOK, &got, &method, &path)
+
+	conf, err := parsePostArgs("update", []string{"42", "-team", "testteam", "-token", "test_token", "-title", "new title", "-draft=false"})
+	if err != nil {
+		t.Fatalf("parsePostArgs failed: %v", err)
+	}
+	if conf.ID != 42 {
+		t.Errorf("Expected post ID 42, got %d", conf.ID)
+	}
+
+	if _, err := client.UpdatePost(context.Background(), conf.ID, conf.Request); err != nil {
+		t.Fatalf("UpdatePost failed: %v", err)
+	}
+	if method != "PATCH" || path != "/teams/testteam/posts/42" {
+		t.Errorf("Expected PATCH /teams/testteam/posts/42, got %s %s", method, path)
+	}
+	expected := map[string]any{"title": "new title", "draft": false}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected request body %v, got %v", expected, got)
+	}
+}
+
+func TestUpdatePost_ApiError(t *testing.T) {
+	var got map[string]any
+	var method, path string
+	client := newPostTestServer(t, http.StatusNotFound, &got, &method, &path)
+
+	title := "title"
+	_, err := client.UpdatePost(context.Background(), 1, docbase.PostRequest{Title: &title})
+	if err == nil {
+		t.Fatal("Expected an error, but got nil")
+	}
+	if !strings.Contains(err.Error(), "APIリクエストエラー") {
+		t.Errorf("Expected error message to contain 'APIリクエストエラー', got '%s'", err.Error())
+	}
+}
+
+func TestParsePostArgs_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		args   []string
+		errMsg string
+	}{
+		{name: "create without title", action: "create", args: []string{"-body-file", "body.md"}, errMsg: "タイトルが指定されていません"},
+		{name: "create without body", action: "create", args: []string{"-title", "t"}, errMsg: "本文のファイルが指定されていません"},
+		{name: "invalid scope", action: "update", args: []string{"1", "-scope", "public"}, errMsg: "-scope には everyone, group, private"},
+		{name: "group scope without groups", action: "update", args: []string{"1", "-scope", "group"}, errMsg: "-scope group の場合は -groups"},
+		{name: "update without id", action: "update", args: []string{"-title", "t"}, errMsg: "更新する記事IDを指定してください"},
+		{name: "update without fields", action: "update", args: []string{"1"}, errMsg: "更新する項目を"},
+	}
+
+	t.Setenv("DOCBASE_TEAM", "t")
+	t.Setenv("DOCBASE_TOKEN", "t")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePostArgs(tt.action, tt.args)
+			if err == nil {
+				t.Fatal("Expected an error, but got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error message to contain '%s', got '%s'", tt.errMsg, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/output"
+)
+
+// 集計の軸 (report -by の値)
+const (
+	reportByTag    = "tag"
+	reportByAuthor = "author"
+	reportByGroup  = "group"
+)
+
+// reportLabels は集計の軸ごとの表示名と、該当する値がない記事をまとめるキーです。
+var reportLabels = map[string]struct{ Title, None string }{
+	reportByTag:    {Title: "タグ", None: "(タグなし)"},
+	reportByAuthor: {Title: "作成者", None: "(不明)"},
+	reportByGroup:  {Title: "グループ", None: "(グループなし)"},
+}
+
+// ReportConfig は report サブコマンドの設定です。
+type ReportConfig struct {
+	Config
+	By string
+}
+
+// ReportRow は集計結果の1行 (タグ名などのキーと記事数) です。
+type ReportRow struct {
+	Key   string
+	Count int
+}
+
+// Report はタグ・作成者・グループ別の記事数の集計結果です。
+type Report struct {
+	By         string
+	TotalPosts int         // 期間内の記事数 (1記事に複数のタグ・グループがある場合も1件)
+	Rows       []ReportRow // 記事数の多い順 (同数の場合はキーの昇順)
+}
+
+func runReport(ctx context.Context, args []string) error {
+	conf, err := parseReportArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client := newClient(&conf.Config)
+	label := reportLabels[conf.By]
+
+	fmt.Fprintf(os.Stderr, "%s チームの %d年%d月から%d年%d月までの%s別記事数を取得します...\n", conf.TeamName, conf.StartYear, conf.StartMonth, conf.EndYear, conf.EndMonth, label.Title)
+
+	report, err := client.GetPostCountReport(ctx, conf.By, conf.StartYear, conf.StartMonth, conf.EndYear, conf.EndMonth)
+	if err != nil {
+		return err
+	}
+
+	table := output.Table{
+		Title:   fmt.Sprintf("%s別記事数 (対象記事数: %d記事):", label.Title, report.TotalPosts),
+		Columns: []string{conf.By, "count"},
+	}
+	for _, row := range report.Rows {
+		table.Rows = append(table.Rows, []any{row.Key, row.Count})
+	}
+	if err := output.Write(os.Stdout, conf.Format, table); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "記事数の集計が完了しました。")
+	return nil
+}
+
+func parseReportArgs(args []string) (*ReportConfig, error) {
+	conf := &ReportConfig{}
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	addAuthFlags(fs, &conf.Config)
+	addClientFlags(fs, &conf.Config)
+	addPeriodFlags(fs, &conf.Config)
+	addFormatFlag(fs, &conf.Config)
+	fs.StringVar(&conf.By, "by", reportByTag, "Aggregate post counts by tag, author or group")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := validateAuth(&conf.Config); err != nil {
+		return nil, err
+	}
+	if err := validateClient(&conf.Config); err != nil {
+		return nil, err
+	}
+	if err := validatePeriod(&conf.Config); err != nil {
+		return nil, err
+	}
+	if _, ok := reportLabels[conf.By]; !ok {
+		return nil, fmt.Errorf("-by には tag, author, group のいずれかを指定してください: %s", conf.By)
+	}
+	return conf, nil
+}
+
+// GetPostCountReport はページネーションで全記事を取得し、期間内の記事数を by (tag / author / group) 別に集計します。
+// 複数のタグ・グループを持つ記事は、それぞれのタグ・グループで1件ずつ数えます。
+func (c *DocBaseClient) GetPostCountReport(ctx context.Context, by string, startYear, startMonth, endYear, endMonth int) (*Report, error) {
+	label, ok := reportLabels[by]
+	if !ok {
+		return nil, fmt.Errorf("未対応の集計軸です: %s", by)
+	}
+
+	filterStartDate, filterEndDate := periodRange(startYear, startMonth, endYear, endMonth)
+	counts := make(map[string]int)
+	report := &Report{By: by}
+
+	err := c.ForEachPostPage(ctx, nil, func(posts []docbase.Post) error {
+		for _, post := range posts {
+			if post.CreatedAt.Before(filterStartDate) || !post.CreatedAt.Before(filterEndDate) {
+				continue
+			}
+			report.TotalPosts++
+
+			keys := reportKeys(by, post)
+			if len(keys) == 0 {
+				keys = []string{label.None}
+			}
+			for _, key := range keys {
+				counts[key]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key, count := range counts {
+		report.Rows = append(report.Rows, ReportRow{Key: key, Count: count})
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].Count != report.Rows[j].Count {
+			return report.Rows[i].Count > report.Rows[j].Count
+		}
+		return report.Rows[i].Key < report.Rows[j].Key
+	})
+	return report, nil
+}
+
+// reportKeys は記事を集計するキー (タグ名・作成者名・グループ名) を返します。
+func reportKeys(by string, post docbase.Post) []string {
+	var keys []string
+	switch by {
+	case reportByTag:
+		for _, tag := range post.Tags {
+			keys = append(keys, tag.Name)
+		}
+	case reportByAuthor:
+		if post.User.Name != "" {
+			keys = append(keys, post.User.Name)
+		}
+	case reportByGroup:
+		for _, group := range post.Groups {
+			keys = append(keys, group.Name)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+)
+
+func TestGetPostCountReport(t *testing.T) {
+	posts := []docbase.Post{
+		createMockPost(1, "2024-01-10T10:00:00+09:00"),
+		createMockPost(2, "2024-01-15T10:00:00+09:00"),
+		createMockPost(3, "2024-02-05T10:00:00+09:00"),
+		createMockPost(4, "2023-12-01T10:00:00+09:00"), // 期間外
+		createMockPost(5, "2024-02-20T10:00:00+09:00"),
+	}
+	posts[0].Tags = []docbase.Tag{{Name: "go"}, {Name: "cli"}}
+	posts[1].Tags = []docbase.Tag{{Name: "go"}}
+	posts[2].Tags = []docbase.Tag{{Name: "cli"}}
+	posts[3].Tags = []docbase.Tag{{Name: "go"}}
+	posts[0].User = docbase.User{Name: "alice"}
+	posts[1].User = docbase.User{Name: "bob"}
+	posts[2].User = docbase.User{Name: "alice"}
+	posts[3].User = docbase.User{Name: "bob"}
+	posts[4].User = docbase.User{Name: "carol"}
+	posts[0].Groups = []docbase.Group{{Name: "dev"}}
+
+	// 2ページに分けて返すモックサーバー
+	pageCounter := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if pageCounter == 0 {
+			next := "page2"
+			json.NewEncoder(w).Encode(docbase.PostResponse{Posts: posts[:3], Meta: docbase.PostMeta{NextPage: &next}})
+		} else {
+			json.NewEncoder(w).Encode(docbase.PostResponse{Posts: posts[3:], Meta: docbase.PostMeta{NextPage: nil}})
+		}
+		pageCounter++
+	}))
+	defer server.Close()
+
+
+	tests := []struct {
+		by       string
+		expected []ReportRow
+	}{
+		{by: reportByTag, expected: []ReportRow{{Key: "cli", Count: 2}, {Key: "go", Count: 2}, {Key: "(タグなし)", Count: 1}}},
+		{by: reportByAuthor, expected: []ReportRow{{Key: "alice", Count: 2}, {Key: "bob", Count: 1}, {Key: "carol", Count: 1}}},
+		{by: reportByGroup, expected: []ReportRow{{Key: "(グループなし)", Count: 3}, {Key: "dev", Count: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			pageCounter = 0
+			client := newTestClient(server)
+
+			report, err := client.GetPostCountReport(context.Background(), tt.by, 2024, 1, 2024, 2)
+			if err != nil {
+				t.Fatalf("GetPostCountReport failed: %v", err)
+			}
+			if report.TotalPosts != 4 {
+				t.Errorf("Expected 4 posts in period, got %d", report.TotalPosts)
+			}
+			if !reflect.DeepEqual(report.Rows, tt.expected) {
+				t.Errorf("Expected rows %v, got %v", tt.expected, report.Rows)
+			}
+		})
+	}
+}
+
+func TestParseReportArgs_InvalidBy(t *testing.T) {
+	_, err := parseReportArgs([]string{"-team", "t", "-token", "t", "-by", "month"})
+	if err == nil {
+		t.Fatal("Expected an error for invalid -by, but got nil")
+	}
+	if !strings.Contains(err.Error(), "-by には tag, author, group のいずれかを指定してください") {
+		t.Errorf("Expected error message for invalid -by, got '%s'", err.Error())
+	}
+}
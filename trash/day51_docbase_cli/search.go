@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/output"
+)
+
+// SearchQuery は DocBase の検索クエリ (q= パラメータ) の組み立て用の条件です。
+type SearchQuery struct {
+	Keywords string   // 自由入力のキーワード (DocBase の検索構文をそのまま書いてもよい)
+	Tags     []string // tag:<name> (複数指定はすべてを含む記事)
+	Author   string   // author:<name>
+	Since    string   // 作成日の下限 (YYYY-MM-DD)
+	Until    string   // 作成日の上限 (YYYY-MM-DD)
+}
+
+// String は条件を DocBase の検索構文に変換します。
+// 例: `設計 tag:go author:lirlia created_at:2024-01-01~2024-12-31`
+func (q SearchQuery) String() string {
+	var terms []string
+	if keywords := strings.TrimSpace(q.Keywords); keywords != "" {
+		terms = append(terms, keywords)
+	}
+	for _, tag := range q.Tags {
+		terms = append(terms, "tag:"+quoteSearchTerm(tag))
+	}
+	if q.Author != "" {
+		terms = append(terms, "author:"+quoteSearchTerm(q.Author))
+	}
+	if q.Since != "" || q.Until != "" {
+		// 片方だけの場合は created_at:2024-01-01~ / created_at:~2024-12-31 のように開いた範囲にする
+		terms = append(terms, "created_at:"+q.Since+"~"+q.Until)
+	}
+	return strings.Join(terms, " ")
+}
+
+// quoteSearchTerm は空白を含む値をダブルクォートで囲みます。
+func quoteSearchTerm(s string) string {
+	if strings.ContainsAny(s, " \t　") {
+		return `"` + strings.ReplaceAll(s, `"`, "") + `"`
+	}
+	return s
+}
+
+// SearchConfig は search サブコマンドの設定です。
+type SearchConfig struct {
+	Config
+	Query SearchQuery
+	Limit int
+}
+
+func runSearch(ctx context.Context, args []string) error {
+	conf, err := parseSearchArgs(args)
+	if err != nil {
+		return err
+	}
+
+	client := newClient(&conf.Config)
+	q := conf.Query.String()
+
+	fmt.Fprintf(os.Stderr, "%s チームの記事を検索します: %s\n", conf.TeamName, q)
+	posts, err := client.SearchPosts(ctx, q, conf.Limit)
+	if err != nil {
+		return err
+	}
+
+	table := output.Table{
+		Title:   fmt.Sprintf("検索結果 (%d件):", len(posts)),
+		Columns: []string{"id", "title", "url"},
+	}
+	for _, post := range posts {
+		table.Rows = append(table.Rows, []any{post.ID, post.Title, post.URL})
+	}
+	return output.Write(os.Stdout, conf.Format, table)
+}
+
+// parseSearchArgs は search の引数をパースします。キーワードはフラグの前後どちらにも書けます。
+func parseSearchArgs(args []string) (*SearchConfig, error) {
+	conf := &SearchConfig{}
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	addAuthFlags(fs, &conf.Config)
+	addClientFlags(fs, &conf.Config)
+	addFormatFlag(fs, &conf.Config)
+	fs.Func("tag", "Filter by tag (repeatable)", func(s string) error {
+		conf.Query.Tags = append(conf.Query.Tags, splitList(s)...)
+		return nil
+	})
+	fs.StringVar(&conf.Query.Author, "author", "", "Filter by author")
+	fs.StringVar(&conf.Query.Since, "since", "", "Only posts created on or after this date (YYYY-MM-DD)")
+	fs.StringVar(&conf.Query.Until, "until", "", "Only posts created on or before this date (YYYY-MM-DD)")
+	fs.IntVar(&conf.Limit, "limit", 100, "Maximum number of results (0 for all)")
+
+	// flag パッケージは最初の非フラグ引数で解析を止めるため、先頭のキーワードは先に取り出す
+	var keywords []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		keywords, args = append(keywords, args[0]), args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	keywords = append(keywords, fs.Args()...)
+	conf.Query.Keywords = strings.Join(keywords, " ")
+
+	if err := validateAuth(&conf.Config); err != nil {
+		return nil, err
+	}
+	if err := validateClient(&conf.Config); err != nil {
+		return nil, err
+	}
+	for _, date := range []string{conf.Query.Since, conf.Query.Until} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("日付は YYYY-MM-DD 形式で指定してください: %s", date)
+		}
+	}
+	if conf.Query.Since != "" && conf.Query.Until != "" && conf.Query.Since > conf.Query.Until {
+		return nil, fmt.Errorf("-since が -until より後になっています")
+	}
+	if conf.Limit < 0 {
+		return nil, fmt.Errorf("-limit は0以上で指定してください")
+	}
+	if conf.Query.String() == "" {
+		return nil, fmt.Errorf("検索キーワードまたは -tag, -author, -since, -until のいずれかを指定してください")
+	}
+	return conf, nil
+}
+
+// SearchPosts は q で記事を検索し、ページネーションで最大 limit 件 (0 の場合はすべて) を返します。
+func (c *DocBaseClient) SearchPosts(ctx context.Context, q string, limit int) ([]docbase.Post, error) {
+	var results []docbase.Post
+	err := c.ForEachPostPage(ctx, url.Values{"q": {q}}, func(posts []docbase.Post) error {
+		for _, post := range posts {
+			results = append(results, post)
+			if limit > 0 && len(results) >= limit {
+				return docbase.ErrStopPagination
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lirlia/100day_challenge_backend/day51_docbase_cli/docbase"
+)
+
+func TestSearchQueryString(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    SearchQuery
+		expected string
+	}{
+		{name: "keywords only", query: SearchQuery{Keywords: "設計 レビュー"}, expected: "設計 レビュー"},
+		{name: "tags and author", query: SearchQuery{Keywords: "API", Tags: []string{"go", "週報"}, Author: "lirlia"}, expected: "API tag:go tag:週報 author:lirlia"},
+		{name: "tag with space", query: SearchQuery{Tags: []string{"team a"}}, expected: `tag:"team a"`},
+		{name: "date range", query: SearchQuery{Since: "2024-01-01", Until: "2024-12-31"}, expected: "created_at:2024-01-01~2024-12-31"},
+		{name: "since only", query: SearchQuery{Since: "2024-01-01"}, expected: "created_at:2024-01-01~"},
+		{name: "until only", query: SearchQuery{Until: "2024-12-31"}, expected: "created_at:~2024-12-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSearchPosts_PaginatesUpToLimit(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("q"))
+		w.Header().Set("Content-Type", "application/json")
+		next := "next"
+		page := r.URL.Query().Get("page")
+		json.NewEncoder(w).Encode(docbase.PostResponse{
+			Posts: []docbase.Post{{ID: len(queries)*10 + 1, Title: "p" + page}, {ID: len(queries)*10 + 2, Title: "p" + page}},
+			Meta:  docbase.PostMeta{NextPage: &next}, // 常に次のページがある
+		})
+	}))
+	defer server.Close()
+
+	client := newTestClient(server)
+
+	posts, err := client.SearchPosts(context.Background(), "tag:go", 3)
+	if err != nil {
+		t.Fatalf("SearchPosts failed: %v", err)
+	}
+	var ids []int
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{11, 12, 21}) {
+		t.Errorf("Expected posts [11 12 21], got %v", ids)
+	}
+	if !reflect.DeepEqual(queries, []string{"tag:go", "tag:go"}) {
+		t.Errorf("Expected 2 requests with q=tag:go, got %v", queries)
+	}
+}
+
+func TestParseSearchArgs(t *testing.T) {
+	t.Setenv("DOCBASE_TEAM", "t")
+	t.Setenv("DOCBASE_TOKEN", "t")
+
+	conf, err := parseSearchArgs([]string{"設計", "-tag", "go", "--tag", "cli,api", "--author", "lirlia", "--since", "2024-01-01", "レビュー"})
+	if err != nil {
+		t.Fatalf("parseSearchArgs failed: %v", err)
+	}
+	if got := conf.Query.String(); got != "設計 レビュー tag:go tag:cli tag:api author:lirlia created_at:2024-01-01~" {
+		t.Errorf("Unexpected query %q", got)
+	}
+
+	errorTests := []struct {
+		args   []string
+		errMsg string
+	}{
+		{args: []string{}, errMsg: "検索キーワードまたは"},
+		{args: []string{"-since", "2024/01/01"}, errMsg: "YYYY-MM-DD 形式"},
+		{args: []string{"-since", "2024-02-01", "-until", "2024-01-01"}, errMsg: "-since が -until より後"},
+	}
+	for _, tt := range errorTests {
+		_, err := parseSearchArgs(tt.args)
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("parseSearchArgs(%v): expected error containing '%s', got %v", tt.args, tt.errMsg, err)
+		}
+	}
+}